@@ -134,3 +134,26 @@ func IsSupportedArchitecture(architectures []string) bool {
 
 	return false
 }
+
+// compatibleArchitectures lists, for a given architecture, the other
+// architectures whose userspace it can run as well.
+var compatibleArchitectures = map[string][]string{
+	"amd64": {"i386"},
+	"arm64": {"armhf"},
+}
+
+// IsCompatibleArchitecture returns true if snaps built for
+// architecture can be run on a system of the primary architecture,
+// i.e. if they are the same or the primary architecture can run the
+// userspace of the other one (e.g. armhf on arm64).
+func IsCompatibleArchitecture(primary, architecture string) bool {
+	if architecture == primary || architecture == "all" {
+		return true
+	}
+	for _, compat := range compatibleArchitectures[primary] {
+		if compat == architecture {
+			return true
+		}
+	}
+	return false
+}
@@ -59,3 +59,13 @@ func (ts *ArchTestSuite) TestSupportedArchitectures(c *C) {
 	c.Check(IsSupportedArchitecture([]string{"amd64", "armhf", "powerpc"}), Equals, true)
 	c.Check(IsSupportedArchitecture([]string{"powerpc"}), Equals, false)
 }
+
+func (ts *ArchTestSuite) TestCompatibleArchitectures(c *C) {
+	c.Check(IsCompatibleArchitecture("arm64", "arm64"), Equals, true)
+	c.Check(IsCompatibleArchitecture("arm64", "armhf"), Equals, true)
+	c.Check(IsCompatibleArchitecture("arm64", "all"), Equals, true)
+	c.Check(IsCompatibleArchitecture("amd64", "i386"), Equals, true)
+	c.Check(IsCompatibleArchitecture("armhf", "arm64"), Equals, false)
+	c.Check(IsCompatibleArchitecture("amd64", "armhf"), Equals, false)
+	c.Check(IsCompatibleArchitecture("s390x", "i386"), Equals, false)
+}
@@ -69,6 +69,8 @@ var (
 	SystemUserType      = &AssertionType{"system-user", []string{"brand-id", "email"}, assembleSystemUser, 0}
 	ValidationType      = &AssertionType{"validation", []string{"series", "snap-id", "approved-snap-id", "approved-snap-revision"}, assembleValidation, 0}
 	StoreType           = &AssertionType{"store", []string{"store"}, assembleStore, 0}
+	ValidationSetType   = &AssertionType{"validation-set", []string{"series", "account-id", "name", "sequence"}, assembleValidationSet, 0}
+	PreseedType         = &AssertionType{"preseed", []string{"series", "brand-id", "model", "system-label"}, assemblePreseed, 0}
 
 // ...
 )
@@ -94,6 +96,8 @@ var typeRegistry = map[string]*AssertionType{
 	ValidationType.Name:      ValidationType,
 	RepairType.Name:          RepairType,
 	StoreType.Name:           StoreType,
+	ValidationSetType.Name:   ValidationSetType,
+	PreseedType.Name:         PreseedType,
 	// no authority
 	DeviceSessionRequestType.Name: DeviceSessionRequestType,
 	SerialRequestType.Name:        SerialRequestType,
@@ -476,6 +480,9 @@ type Decoder struct {
 	maxHeadersSize int
 	maxSigSize     int
 
+	maxHeaderEntries int
+	maxHeaderNesting int
+
 	defaultMaxBodySize int
 	typeMaxBodySize    map[*AssertionType]int
 }
@@ -496,6 +503,8 @@ func NewDecoder(r io.Reader) *Decoder {
 		initialBufSize:     defaultDecoderBufSize,
 		maxHeadersSize:     MaxHeadersSize,
 		maxSigSize:         MaxSignatureSize,
+		maxHeaderEntries:   MaxHeaderEntries,
+		maxHeaderNesting:   MaxHeaderNesting,
 		defaultMaxBodySize: MaxBodySize,
 	}).initBuffer()
 }
@@ -507,11 +516,54 @@ func NewDecoderWithTypeMaxBodySize(r io.Reader, typeMaxBodySize map[*AssertionTy
 		initialBufSize:     defaultDecoderBufSize,
 		maxHeadersSize:     MaxHeadersSize,
 		maxSigSize:         MaxSignatureSize,
+		maxHeaderEntries:   MaxHeaderEntries,
+		maxHeaderNesting:   MaxHeaderNesting,
 		defaultMaxBodySize: MaxBodySize,
 		typeMaxBodySize:    typeMaxBodySize,
 	}).initBuffer()
 }
 
+// DecoderLimits are the limits enforced by a Decoder on the
+// assertions it parses, zero values mean the default limits.
+type DecoderLimits struct {
+	// MaxHeadersSize is the maximum size in bytes of the headers.
+	MaxHeadersSize int
+	// MaxHeaderEntries is the maximum number of header entries,
+	// including the elements of lists and the entries of maps.
+	MaxHeaderEntries int
+	// MaxHeaderNesting is the maximum nesting of lists and maps in
+	// header values.
+	MaxHeaderNesting int
+	// MaxBodySize is the maximum size in bytes of the body.
+	MaxBodySize int
+	// TypeMaxBodySize optionally overrides MaxBodySize per type.
+	TypeMaxBodySize map[*AssertionType]int
+	// MaxSignatureSize is the maximum size in bytes of the
+	// signature.
+	MaxSignatureSize int
+}
+
+func limitOrDefault(limit, def int) int {
+	if limit <= 0 {
+		return def
+	}
+	return limit
+}
+
+// NewDecoderWithLimits returns a Decoder to parse the stream of assertions from the reader enforcing the given limits.
+func NewDecoderWithLimits(r io.Reader, limits DecoderLimits) *Decoder {
+	return (&Decoder{
+		rd:                 r,
+		initialBufSize:     defaultDecoderBufSize,
+		maxHeadersSize:     limitOrDefault(limits.MaxHeadersSize, MaxHeadersSize),
+		maxSigSize:         limitOrDefault(limits.MaxSignatureSize, MaxSignatureSize),
+		maxHeaderEntries:   limitOrDefault(limits.MaxHeaderEntries, MaxHeaderEntries),
+		maxHeaderNesting:   limitOrDefault(limits.MaxHeaderNesting, MaxHeaderNesting),
+		defaultMaxBodySize: limitOrDefault(limits.MaxBodySize, MaxBodySize),
+		typeMaxBodySize:    limits.TypeMaxBodySize,
+	}).initBuffer()
+}
+
 func (d *Decoder) peek(size int) ([]byte, error) {
 	buf, err := d.b.Peek(size)
 	if err == bufio.ErrBufferFull {
@@ -582,7 +634,7 @@ func (d *Decoder) Decode() (Assertion, error) {
 	}
 
 	headLen := len(headAndSep) - len(nlnl)
-	headers, err := parseHeaders(headAndSep[:headLen])
+	headers, err := parseHeadersWithLimits(headAndSep[:headLen], d.maxHeaderEntries, d.maxHeaderNesting)
 	if err != nil {
 		return nil, fmt.Errorf("parsing assertion headers: %v", err)
 	}
@@ -54,6 +54,7 @@ func (as *assertsSuite) TestTypeNames(c *C) {
 		"base-declaration",
 		"device-session-request",
 		"model",
+		"preseed",
 		"repair",
 		"serial",
 		"serial-request",
@@ -68,6 +69,7 @@ func (as *assertsSuite) TestTypeNames(c *C) {
 		"test-only-no-authority",
 		"test-only-no-authority-pk",
 		"validation",
+		"validation-set",
 	})
 }
 
@@ -289,12 +291,12 @@ func (as *assertsSuite) TestDecodeNoSignatureSplit(c *C) {
 func (as *assertsSuite) TestDecodeHeaderParsingErrors(c *C) {
 	headerParsingErrorsTests := []struct{ encoded, expectedErr string }{
 		{string([]byte{255, '\n', '\n'}), "header is not utf8"},
-		{"foo: a\nbar\n\n", `header entry missing ':' separator: "bar"`},
-		{"TYPE: foo\n\n", `invalid header name: "TYPE"`},
-		{"foo: a\nbar:>\n\n", `header entry should have a space or newline \(for multiline\) before value: "bar:>"`},
-		{"foo: a\nbar:\n\n", `expected 4 chars nesting prefix after multiline introduction "bar:": EOF`},
-		{"foo: a\nbar:\nbaz: x\n\n", `expected 4 chars nesting prefix after multiline introduction "bar:": "baz: x"`},
-		{"foo: a:\nbar: b\nfoo: x\n\n", `repeated header: "foo"`},
+		{"foo: a\nbar\n\n", `line 2: header entry missing ':' separator: "bar"`},
+		{"TYPE: foo\n\n", `line 1: invalid header name: "TYPE"`},
+		{"foo: a\nbar:>\n\n", `line 2: header entry should have a space or newline \(for multiline\) before value: "bar:>"`},
+		{"foo: a\nbar:\n\n", `line 3: expected 4 chars nesting prefix after multiline introduction "bar:": EOF`},
+		{"foo: a\nbar:\nbaz: x\n\n", `line 3: expected 4 chars nesting prefix after multiline introduction "bar:": "baz: x"`},
+		{"foo: a:\nbar: b\nfoo: x\n\n", `line 3: repeated header: "foo"`},
 	}
 
 	for _, test := range headerParsingErrorsTests {
@@ -555,6 +557,33 @@ AXNpZw==`
 	c.Assert(err, ErrorMatches, "assertion body length 2097153 exceeds maximum body size")
 }
 
+func (as *assertsSuite) TestDecoderWithLimits(c *C) {
+	ex := strings.Replace(exampleBodyAndExtraHeaders, "header2: value2\n", "header2:\n  -\n    a: b\n", 1)
+
+	decoder := asserts.NewDecoderWithLimits(bytes.NewBufferString(ex), asserts.DecoderLimits{})
+	a, err := decoder.Decode()
+	c.Assert(err, IsNil)
+	c.Check(a.Body(), DeepEquals, []byte("THE-BODY"))
+
+	decoder = asserts.NewDecoderWithLimits(bytes.NewBufferString(ex), asserts.DecoderLimits{
+		MaxHeaderNesting: 1,
+	})
+	_, err = decoder.Decode()
+	c.Check(err, ErrorMatches, `parsing assertion headers: line 9: header values nested too deeply, maximum nesting is 1`)
+
+	decoder = asserts.NewDecoderWithLimits(bytes.NewBufferString(ex), asserts.DecoderLimits{
+		MaxHeaderEntries: 8,
+	})
+	_, err = decoder.Decode()
+	c.Check(err, ErrorMatches, `parsing assertion headers: line 9: too many header entries, maximum is 8`)
+
+	decoder = asserts.NewDecoderWithLimits(bytes.NewBufferString(ex), asserts.DecoderLimits{
+		MaxBodySize: 7,
+	})
+	_, err = decoder.Decode()
+	c.Check(err, ErrorMatches, `assertion body length 8 exceeds maximum body size`)
+}
+
 func (as *assertsSuite) TestEncode(c *C) {
 	encoded := []byte("type: test-only\n" +
 		"authority-id: auth-id2\n" +
@@ -889,6 +918,8 @@ func (as *assertsSuite) TestWithAuthority(c *C) {
 		"system-user",
 		"validation",
 		"repair",
+		"validation-set",
+		"preseed",
 	}
 	c.Check(withAuthority, HasLen, asserts.NumAssertionType-3) // excluding device-session-request, serial-request, account-key-request
 	for _, name := range withAuthority {
@@ -168,8 +168,9 @@ func MockRunGPG(mock func(prev GPGRunner, input []byte, args ...string) ([]byte,
 
 // Headers helpers to test
 var (
-	ParseHeaders = parseHeaders
-	AppendEntry  = appendEntry
+	ParseHeaders           = parseHeaders
+	ParseHeadersWithLimits = parseHeadersWithLimits
+	AppendEntry            = appendEntry
 )
 
 // ParametersForGenerate exposes parametersForGenerate for tests.
@@ -191,3 +192,11 @@ type featureExposer interface {
 func RuleFeature(rule featureExposer, flabel string) bool {
 	return rule.feature(flabel)
 }
+
+func MockMaxFetchDepth(depth int) (restore func()) {
+	old := maxFetchDepth
+	maxFetchDepth = depth
+	return func() {
+		maxFetchDepth = old
+	}
+}
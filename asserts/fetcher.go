@@ -21,6 +21,7 @@ package asserts
 
 import (
 	"fmt"
+	"sync"
 )
 
 type fetchProgress int
@@ -31,6 +32,10 @@ const (
 	fetchSaved
 )
 
+// maxFetchDepth is how deep prerequisites can be nested before a
+// Fetcher gives up, as a protection against runaway chains.
+var maxFetchDepth = 20
+
 // A Fetcher helps fetching assertions and their prerequisites.
 type Fetcher interface {
 	// Fetch retrieves the assertion indicated by ref then its prerequisites
@@ -42,57 +47,176 @@ type Fetcher interface {
 }
 
 type fetcher struct {
-	db       RODatabase
-	retrieve func(*Ref) (Assertion, error)
-	save     func(Assertion) error
+	db          RODatabase
+	retrieve    func(*Ref) (Assertion, error)
+	save        func(Assertion) error
+	parallelism int
 
 	fetched map[string]fetchProgress
 }
 
 // NewFetcher creates a Fetcher which will use trustedDB to determine trusted assertions, will fetch assertions following prerequisites using retrieve, and then will pass them to save, saving prerequisites before dependent assertions.
 func NewFetcher(trustedDB RODatabase, retrieve func(*Ref) (Assertion, error), save func(Assertion) error) Fetcher {
+	return NewParallelFetcher(trustedDB, retrieve, save, 1)
+}
+
+// NewParallelFetcher creates a Fetcher like NewFetcher but that retrieves up to parallelism assertions at the same time, retrieve must then be safe for concurrent use. save is still called from a single goroutine, saving prerequisites before dependent assertions.
+func NewParallelFetcher(trustedDB RODatabase, retrieve func(*Ref) (Assertion, error), save func(Assertion) error, parallelism int) Fetcher {
+	if parallelism < 1 {
+		parallelism = 1
+	}
 	return &fetcher{
-		db:       trustedDB,
-		retrieve: retrieve,
-		save:     save,
-		fetched:  make(map[string]fetchProgress),
+		db:          trustedDB,
+		retrieve:    retrieve,
+		save:        save,
+		parallelism: parallelism,
+		fetched:     make(map[string]fetchProgress),
 	}
 }
 
-func (f *fetcher) chase(ref *Ref, a Assertion) error {
-	// check if ref points to predefined assertion, in which case
-	// there is nothing to do
+// needed returns whether the assertion indicated by ref still needs to
+// be fetched, that is it's neither predefined nor saved already.
+func (f *fetcher) needed(ref *Ref) (bool, error) {
 	_, err := ref.Resolve(f.db.FindPredefined)
 	if err == nil {
-		return nil
+		return false, nil
 	}
 	if !IsNotFound(err) {
-		return err
+		return false, err
+	}
+	return f.fetched[ref.Unique()] != fetchSaved, nil
+}
+
+// retrieveAll retrieves the assertions indicated by refs, up to
+// f.parallelism at the same time.
+func (f *fetcher) retrieveAll(refs []*Ref) ([]Assertion, error) {
+	res := make([]Assertion, len(refs))
+	if f.parallelism == 1 || len(refs) == 1 {
+		for i, ref := range refs {
+			a, err := f.retrieve(ref)
+			if err != nil {
+				return nil, err
+			}
+			res[i] = a
+		}
+		return res, nil
 	}
+
+	errs := make([]error, len(refs))
+	sem := make(chan struct{}, f.parallelism)
+	var wg sync.WaitGroup
+	for i, ref := range refs {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, ref *Ref) {
+			defer wg.Done()
+			res[i], errs[i] = f.retrieve(ref)
+			<-sem
+		}(i, ref)
+	}
+	wg.Wait()
+	// report the first error in order, to be deterministic
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	return res, nil
+}
+
+func (f *fetcher) chase(ref *Ref, a Assertion) error {
+	// retrieve ref and its prerequisites breadth-first, one level
+	// of prerequisites at a time
+	retrieved := make(map[string]Assertion)
+	deps := make(map[string][]*Ref)
+	if a != nil {
+		retrieved[ref.Unique()] = a
+	}
+	level := []*Ref{ref}
+	for depth := 0; len(level) > 0; depth++ {
+		if depth > maxFetchDepth {
+			return fmt.Errorf("cannot fetch %s: prerequisites are nested more than %d levels deep", ref, maxFetchDepth)
+		}
+		var toRetrieve []*Ref
+		var given []*Ref
+		for _, r := range level {
+			needed, err := f.needed(r)
+			if err != nil {
+				return err
+			}
+			if !needed {
+				continue
+			}
+			if _, ok := retrieved[r.Unique()]; ok {
+				// the assertion given to Save or one already
+				// retrieved at an earlier level
+				given = append(given, r)
+				continue
+			}
+			toRetrieve = append(toRetrieve, r)
+		}
+		res, err := f.retrieveAll(toRetrieve)
+		if err != nil {
+			return err
+		}
+		for i, r := range toRetrieve {
+			retrieved[r.Unique()] = res[i]
+		}
+
+		level = nil
+		queued := make(map[string]bool)
+		for _, r := range append(given, toRetrieve...) {
+			u := r.Unique()
+			if _, ok := deps[u]; ok {
+				continue
+			}
+			a := retrieved[u]
+			prereqs := a.Prerequisites()
+			rdeps := make([]*Ref, 0, len(prereqs)+1)
+			rdeps = append(rdeps, prereqs...)
+			rdeps = append(rdeps, &Ref{
+				Type:       AccountKeyType,
+				PrimaryKey: []string{a.SignKeyID()},
+			})
+			deps[u] = rdeps
+			for _, d := range rdeps {
+				du := d.Unique()
+				if _, ok := deps[du]; ok || queued[du] {
+					continue
+				}
+				queued[du] = true
+				level = append(level, d)
+			}
+		}
+	}
+
+	return f.saveRetrieved(ref, retrieved, deps)
+}
+
+// saveRetrieved saves the retrieved assertion indicated by ref after its
+// prerequisites, depth-first.
+func (f *fetcher) saveRetrieved(ref *Ref, retrieved map[string]Assertion, deps map[string][]*Ref) error {
 	u := ref.Unique()
+	a, ok := retrieved[u]
+	if !ok {
+		// predefined or saved already
+		return nil
+	}
 	switch f.fetched[u] {
 	case fetchSaved:
 		return nil // nothing to do
 	case fetchRetrieved:
 		return fmt.Errorf("circular assertions are not expected: %s", ref)
 	}
-	if a == nil {
-		retrieved, err := f.retrieve(ref)
-		if err != nil {
-			return err
-		}
-		a = retrieved
-	}
 	f.fetched[u] = fetchRetrieved
-	for _, preref := range a.Prerequisites() {
-		if err := f.Fetch(preref); err != nil {
+	for _, d := range deps[u] {
+		if err := f.saveRetrieved(d, retrieved, deps); err != nil {
+			delete(f.fetched, u)
 			return err
 		}
 	}
-	if err := f.fetchAccountKey(a.SignKeyID()); err != nil {
-		return err
-	}
 	if err := f.save(a); err != nil {
+		delete(f.fetched, u)
 		return err
 	}
 	f.fetched[u] = fetchSaved
@@ -105,15 +229,6 @@ func (f *fetcher) Fetch(ref *Ref) error {
 	return f.chase(ref, nil)
 }
 
-// fetchAccountKey behaves like Fetch for the account-key with the given key id.
-func (f *fetcher) fetchAccountKey(keyID string) error {
-	keyRef := &Ref{
-		Type:       AccountKeyType,
-		PrimaryKey: []string{keyID},
-	}
-	return f.Fetch(keyRef)
-}
-
 // Save retrieves the prerequisites of the assertion recursively,
 // along the way saving them, and finally saves the assertion.
 func (f *fetcher) Save(a Assertion) error {
@@ -22,6 +22,7 @@ package asserts_test
 import (
 	"crypto"
 	"fmt"
+	"sync"
 	"time"
 
 	"golang.org/x/crypto/sha3"
@@ -165,3 +166,152 @@ func (s *fetcherSuite) TestSave(c *C) {
 	c.Assert(err, IsNil)
 	c.Check(snapDecl.(*asserts.SnapDeclaration).SnapName(), Equals, "foo")
 }
+
+func (s *fetcherSuite) TestFetchParallel(c *C) {
+	s.prereqSnapAssertions(c, 10)
+
+	db, err := asserts.OpenDatabase(&asserts.DatabaseConfig{
+		Backstore: asserts.NewMemoryBackstore(),
+		Trusted:   s.storeSigning.Trusted,
+	})
+	c.Assert(err, IsNil)
+
+	// the snap-declaration, the developer account and the store signing
+	// key are prerequisites at the same level, they are retrieved at
+	// the same time
+	var mu sync.Mutex
+	inFlight := 0
+	bothInFlight := make(chan struct{})
+	var retrieved []string
+	retrieve := func(ref *asserts.Ref) (asserts.Assertion, error) {
+		mu.Lock()
+		retrieved = append(retrieved, ref.Type.Name)
+		if ref.Type != asserts.SnapRevisionType {
+			inFlight++
+			if inFlight == 2 {
+				close(bothInFlight)
+			}
+		}
+		mu.Unlock()
+		if ref.Type != asserts.SnapRevisionType {
+			select {
+			case <-bothInFlight:
+			case <-time.After(5 * time.Second):
+				return nil, fmt.Errorf("prerequisites not retrieved in parallel")
+			}
+		}
+		return ref.Resolve(s.storeSigning.Find)
+	}
+
+	var saved []string
+	save := func(a asserts.Assertion) error {
+		saved = append(saved, a.Type().Name)
+		return db.Add(a)
+	}
+
+	f := asserts.NewParallelFetcher(db, retrieve, save, 4)
+
+	ref := &asserts.Ref{
+		Type:       asserts.SnapRevisionType,
+		PrimaryKey: []string{makeDigest(10)},
+	}
+	err = f.Fetch(ref)
+	c.Assert(err, IsNil)
+
+	c.Check(retrieved, HasLen, 4)
+	c.Check(retrieved[0], Equals, "snap-revision")
+	// prerequisites are saved first
+	c.Check(saved, DeepEquals, []string{"account-key", "account", "snap-declaration", "snap-revision"})
+
+	snapRev, err := ref.Resolve(db.Find)
+	c.Assert(err, IsNil)
+	c.Check(snapRev.(*asserts.SnapRevision).SnapRevision(), Equals, 10)
+
+	// nothing to do the second time around
+	retrieved = nil
+	err = f.Fetch(ref)
+	c.Assert(err, IsNil)
+	c.Check(retrieved, HasLen, 0)
+}
+
+func (s *fetcherSuite) TestFetchParallelError(c *C) {
+	s.prereqSnapAssertions(c, 10)
+
+	db, err := asserts.OpenDatabase(&asserts.DatabaseConfig{
+		Backstore: asserts.NewMemoryBackstore(),
+		Trusted:   s.storeSigning.Trusted,
+	})
+	c.Assert(err, IsNil)
+
+	retrieve := func(ref *asserts.Ref) (asserts.Assertion, error) {
+		if ref.Type == asserts.AccountType {
+			return nil, fmt.Errorf("cannot retrieve account")
+		}
+		return ref.Resolve(s.storeSigning.Find)
+	}
+	f := asserts.NewParallelFetcher(db, retrieve, db.Add, 4)
+
+	err = f.Fetch(&asserts.Ref{
+		Type:       asserts.SnapRevisionType,
+		PrimaryKey: []string{makeDigest(10)},
+	})
+	c.Assert(err, ErrorMatches, "cannot retrieve account")
+
+	// nothing was saved
+	_, err = db.Find(asserts.SnapDeclarationType, map[string]string{
+		"series":  "16",
+		"snap-id": "snap-id-1",
+	})
+	c.Check(asserts.IsNotFound(err), Equals, true)
+}
+
+func (s *fetcherSuite) TestFetchCircular(c *C) {
+	s.prereqSnapAssertions(c, 10)
+
+	db, err := asserts.OpenDatabase(&asserts.DatabaseConfig{
+		Backstore: asserts.NewMemoryBackstore(),
+		Trusted:   s.storeSigning.Trusted,
+	})
+	c.Assert(err, IsNil)
+
+	revRef := &asserts.Ref{
+		Type:       asserts.SnapRevisionType,
+		PrimaryKey: []string{makeDigest(10)},
+	}
+	// a broken source answering with the snap-revision when asked
+	// for its snap-declaration
+	retrieve := func(ref *asserts.Ref) (asserts.Assertion, error) {
+		if ref.Type == asserts.SnapDeclarationType {
+			ref = revRef
+		}
+		return ref.Resolve(s.storeSigning.Find)
+	}
+	f := asserts.NewFetcher(db, retrieve, db.Add)
+
+	err = f.Fetch(revRef)
+	c.Assert(err, ErrorMatches, `circular assertions are not expected: snap-declaration \(snap-id-1; series:16\)`)
+}
+
+func (s *fetcherSuite) TestFetchTooDeep(c *C) {
+	s.prereqSnapAssertions(c, 10)
+	restore := asserts.MockMaxFetchDepth(1)
+	defer restore()
+
+	db, err := asserts.OpenDatabase(&asserts.DatabaseConfig{
+		Backstore: asserts.NewMemoryBackstore(),
+		Trusted:   s.storeSigning.Trusted,
+	})
+	c.Assert(err, IsNil)
+
+	retrieve := func(ref *asserts.Ref) (asserts.Assertion, error) {
+		return ref.Resolve(s.storeSigning.Find)
+	}
+	f := asserts.NewFetcher(db, retrieve, db.Add)
+
+	// snap-revision -> snap-declaration -> account
+	err = f.Fetch(&asserts.Ref{
+		Type:       asserts.SnapRevisionType,
+		PrimaryKey: []string{makeDigest(10)},
+	})
+	c.Assert(err, ErrorMatches, `cannot fetch snap-revision \(.*\): prerequisites are nested more than 1 levels deep`)
+}
@@ -181,16 +181,20 @@ func checkUint(headers map[string]interface{}, name string, bitSize int) (uint64
 }
 
 func checkDigest(headers map[string]interface{}, name string, h crypto.Hash) ([]byte, error) {
-	digestStr, err := checkNotEmptyString(headers, name)
+	return checkDigestWhat(headers, name, h, "header")
+}
+
+func checkDigestWhat(m map[string]interface{}, name string, h crypto.Hash, what string) ([]byte, error) {
+	digestStr, err := checkNotEmptyStringWhat(m, name, what)
 	if err != nil {
 		return nil, err
 	}
 	b, err := base64.RawURLEncoding.DecodeString(digestStr)
 	if err != nil {
-		return nil, fmt.Errorf("%q header cannot be decoded: %v", name, err)
+		return nil, fmt.Errorf("%q %s cannot be decoded: %v", name, what, err)
 	}
 	if len(b) != h.Size() {
-		return nil, fmt.Errorf("%q header does not have the expected bit length: %d", name, len(b)*8)
+		return nil, fmt.Errorf("%q %s does not have the expected bit length: %d", name, what, len(b)*8)
 	}
 
 	return b, nil
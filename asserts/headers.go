@@ -36,33 +36,99 @@ var (
 	headerNameSanity = regexp.MustCompile("^[a-z](?:-?[a-z0-9])*$")
 )
 
+// Default limits on the structure of assertion headers.
+const (
+	// MaxHeaderEntries is the maximum number of header entries,
+	// including the elements of lists and the entries of maps.
+	MaxHeaderEntries = 16 * 1024
+	// MaxHeaderNesting is the maximum nesting of lists and maps in
+	// header values.
+	MaxHeaderNesting = 16
+)
+
+// HeaderError is returned for assertion headers that cannot be
+// parsed, with the position of the problem.
+type HeaderError struct {
+	// Line is the (1-based) line of the headers with the problem.
+	Line int
+	// Offset is the byte offset of the start of the line.
+	Offset int
+
+	Msg string
+}
+
+func (e *HeaderError) Error() string {
+	return fmt.Sprintf("line %d: %s", e.Line, e.Msg)
+}
+
+type headerParser struct {
+	lines []string
+
+	maxEntries int
+	maxNesting int
+	entries    int
+}
+
+func (p *headerParser) errorf(line int, format string, v ...interface{}) error {
+	offset := 0
+	for _, l := range p.lines[:line] {
+		offset += len(l) + 1
+	}
+	return &HeaderError{
+		Line:   line + 1,
+		Offset: offset,
+		Msg:    fmt.Sprintf(format, v...),
+	}
+}
+
+func (p *headerParser) countEntry(line int) error {
+	p.entries++
+	if p.maxEntries > 0 && p.entries > p.maxEntries {
+		return p.errorf(line, "too many header entries, maximum is %d", p.maxEntries)
+	}
+	return nil
+}
+
 func parseHeaders(head []byte) (map[string]interface{}, error) {
+	return parseHeadersWithLimits(head, MaxHeaderEntries, MaxHeaderNesting)
+}
+
+func parseHeadersWithLimits(head []byte, maxEntries, maxNesting int) (map[string]interface{}, error) {
 	if !utf8.Valid(head) {
 		return nil, fmt.Errorf("header is not utf8")
 	}
+	p := &headerParser{
+		lines:      strings.Split(string(head), "\n"),
+		maxEntries: maxEntries,
+		maxNesting: maxNesting,
+	}
+	lines := p.lines
 	headers := make(map[string]interface{})
-	lines := strings.Split(string(head), "\n")
 	for i := 0; i < len(lines); {
 		entry := lines[i]
 		nameValueSplit := strings.Index(entry, ":")
 		if nameValueSplit == -1 {
-			return nil, fmt.Errorf("header entry missing ':' separator: %q", entry)
+			return nil, p.errorf(i, "header entry missing ':' separator: %q", entry)
 		}
 		name := entry[:nameValueSplit]
 		if !headerNameSanity.MatchString(name) {
-			return nil, fmt.Errorf("invalid header name: %q", name)
+			return nil, p.errorf(i, "invalid header name: %q", name)
+		}
+		if err := p.countEntry(i); err != nil {
+			return nil, err
 		}
 
+		first := i
 		consumed := nameValueSplit + 1
 		var value interface{}
 		var err error
-		value, i, err = parseEntry(consumed, i, lines, 0)
+		value, i, err = p.parseEntry(consumed, i, 0, 0)
 		if err != nil {
 			return nil, err
 		}
 
 		if _, ok := headers[name]; ok {
-			return nil, fmt.Errorf("repeated header: %q", name)
+			return nil, p.errorf(first, "repeated header: %q", name)
 		}
 
 		headers[name] = value
@@ -81,7 +147,8 @@ func nestingPrefix(baseIndent int, prefix string) string {
 	return strings.Repeat(" ", baseIndent) + prefix
 }
 
-func parseEntry(consumedByIntro int, first int, lines []string, baseIndent int) (value interface{}, firstAfter int, err error) {
+func (p *headerParser) parseEntry(consumedByIntro int, first int, baseIndent int, depth int) (value interface{}, firstAfter int, err error) {
+	lines := p.lines
 	entry := lines[first]
 	i := first + 1
 	if consumedByIntro == len(entry) {
@@ -89,28 +156,34 @@ func parseEntry(consumedByIntro int, first int, lines []string, baseIndent int)
 		basePrefix := nestingPrefix(baseIndent, commonPrefix)
 		if i < len(lines) && strings.HasPrefix(lines[i], basePrefix) {
 			rest := lines[i][len(basePrefix):]
-			if strings.HasPrefix(rest, listChar) {
+			isList := strings.HasPrefix(rest, listChar)
+			isMap := !isList && len(rest) > 0 && rest[0] != ' '
+			if (isList || isMap) && p.maxNesting > 0 && depth >= p.maxNesting {
+				return nil, -1, p.errorf(i, "header values nested too deeply, maximum nesting is %d", p.maxNesting)
+			}
+			if isList {
 				// list
-				return parseList(i, lines, baseIndent)
+				return p.parseList(i, baseIndent, depth+1)
 			}
-			if len(rest) > 0 && rest[0] != ' ' {
+			if isMap {
 				// map
-				return parseMap(i, lines, baseIndent)
+				return p.parseMap(i, baseIndent, depth+1)
 			}
 		}
 
-		return parseMultilineText(i, lines, baseIndent)
+		return p.parseMultilineText(i, baseIndent)
 	}
 
 	// simple one-line value
 	if entry[consumedByIntro] != ' ' {
-		return nil, -1, fmt.Errorf("header entry should have a space or newline (for multiline) before value: %q", entry)
+		return nil, -1, p.errorf(first, "header entry should have a space or newline (for multiline) before value: %q", entry)
 	}
 
 	return entry[consumedByIntro+1:], i, nil
 }
 
-func parseMultilineText(first int, lines []string, baseIndent int) (value interface{}, firstAfter int, err error) {
+func (p *headerParser) parseMultilineText(first int, baseIndent int) (value interface{}, firstAfter int, err error) {
+	lines := p.lines
 	size := 0
 	i := first
 	j := i
@@ -130,7 +203,7 @@ func parseMultilineText(first int, lines []string, baseIndent int) (value interf
 		} else {
 			cur = fmt.Sprintf("%q", lines[i])
 		}
-		return nil, -1, fmt.Errorf("expected %d chars nesting prefix after multiline introduction %q: %s", len(prefix), lines[i-1], cur)
+		return nil, -1, p.errorf(i, "expected %d chars nesting prefix after multiline introduction %q: %s", len(prefix), lines[i-1], cur)
 	}
 
 	valueBuf := bytes.NewBuffer(make([]byte, 0, size-1))
@@ -145,7 +218,8 @@ func parseMultilineText(first int, lines []string, baseIndent int) (value interf
 	return valueBuf.String(), i, nil
 }
 
-func parseList(first int, lines []string, baseIndent int) (value interface{}, firstAfter int, err error) {
+func (p *headerParser) parseList(first int, baseIndent int, depth int) (value interface{}, firstAfter int, err error) {
+	lines := p.lines
 	lst := []interface{}(nil)
 	j := first
 	prefix := nestingPrefix(baseIndent, listPrefix)
@@ -153,9 +227,12 @@ func parseList(first int, lines []string, baseIndent int) (value interface{}, fi
 		if !strings.HasPrefix(lines[j], prefix) {
 			return lst, j, nil
 		}
+		if err := p.countEntry(j); err != nil {
+			return nil, -1, err
+		}
 		var v interface{}
 		var err error
-		v, j, err = parseEntry(len(prefix), j, lines, baseIndent+len(listPrefix)-1)
+		v, j, err = p.parseEntry(len(prefix), j, baseIndent+len(listPrefix)-1, depth)
 		if err != nil {
 			return nil, -1, err
 		}
@@ -164,7 +241,8 @@ func parseList(first int, lines []string, baseIndent int) (value interface{}, fi
 	return lst, j, nil
 }
 
-func parseMap(first int, lines []string, baseIndent int) (value interface{}, firstAfter int, err error) {
+func (p *headerParser) parseMap(first int, baseIndent int, depth int) (value interface{}, firstAfter int, err error) {
+	lines := p.lines
 	m := make(map[string]interface{})
 	j := first
 	prefix := nestingPrefix(baseIndent, commonPrefix)
@@ -176,23 +254,27 @@ func parseMap(first int, lines []string, baseIndent int) (value interface{}, fir
 		entry := lines[j][len(prefix):]
 		keyValueSplit := strings.Index(entry, ":")
 		if keyValueSplit == -1 {
-			return nil, -1, fmt.Errorf("map entry missing ':' separator: %q", entry)
+			return nil, -1, p.errorf(j, "map entry missing ':' separator: %q", entry)
 		}
 		key := entry[:keyValueSplit]
 		if !headerNameSanity.MatchString(key) {
-			return nil, -1, fmt.Errorf("invalid map entry key: %q", key)
+			return nil, -1, p.errorf(j, "invalid map entry key: %q", key)
+		}
+		if err := p.countEntry(j); err != nil {
+			return nil, -1, err
 		}
 
+		entryLine := j
 		consumed := keyValueSplit + 1
 		var value interface{}
 		var err error
-		value, j, err = parseEntry(len(prefix)+consumed, j, lines, len(prefix))
+		value, j, err = p.parseEntry(len(prefix)+consumed, j, len(prefix), depth)
 		if err != nil {
 			return nil, -1, err
 		}
 
 		if _, ok := m[key]; ok {
-			return nil, -1, fmt.Errorf("repeated map entry: %q", key)
+			return nil, -1, p.errorf(entryLine, "repeated map entry: %q", key)
 		}
 
 		m[key] = value
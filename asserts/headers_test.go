@@ -21,6 +21,8 @@ package asserts_test
 
 import (
 	"bytes"
+	"fmt"
+	"strings"
 
 	. "gopkg.in/check.v1"
 
@@ -222,37 +224,98 @@ func (s *headersSuite) TestParseHeadersMapErrors(c *C) {
 	_, err := asserts.ParseHeaders([]byte(`foo:
   x X
 bar: baz`))
-	c.Check(err, ErrorMatches, `map entry missing ':' separator: "x X"`)
+	c.Check(err, ErrorMatches, `line 2: map entry missing ':' separator: "x X"`)
 
 	_, err = asserts.ParseHeaders([]byte(`foo:
   0x: X
 bar: baz`))
-	c.Check(err, ErrorMatches, `invalid map entry key: "0x"`)
+	c.Check(err, ErrorMatches, `line 2: invalid map entry key: "0x"`)
 
 	_, err = asserts.ParseHeaders([]byte(`foo:
   a: a
   a: b`))
-	c.Check(err, ErrorMatches, `repeated map entry: "a"`)
+	c.Check(err, ErrorMatches, `line 3: repeated map entry: "a"`)
 }
 
 func (s *headersSuite) TestParseHeadersErrors(c *C) {
 	_, err := asserts.ParseHeaders([]byte(`foo: 1
 bar:baz`))
-	c.Check(err, ErrorMatches, `header entry should have a space or newline \(for multiline\) before value: "bar:baz"`)
+	c.Check(err, ErrorMatches, `line 2: header entry should have a space or newline \(for multiline\) before value: "bar:baz"`)
 
 	_, err = asserts.ParseHeaders([]byte(`foo:
  - x
   - y
   - z
 bar: baz`))
-	c.Check(err, ErrorMatches, `expected 4 chars nesting prefix after multiline introduction "foo:": " - x"`)
+	c.Check(err, ErrorMatches, `line 2: expected 4 chars nesting prefix after multiline introduction "foo:": " - x"`)
 
 	_, err = asserts.ParseHeaders([]byte(`foo:
   - x
   - y
   - z
 bar:`))
-	c.Check(err, ErrorMatches, `expected 4 chars nesting prefix after multiline introduction "bar:": EOF`)
+	c.Check(err, ErrorMatches, `line 6: expected 4 chars nesting prefix after multiline introduction "bar:": EOF`)
+}
+
+func (s *headersSuite) TestParseHeadersErrorPosition(c *C) {
+	_, err := asserts.ParseHeaders([]byte(`foo: 1
+bar:
+  a: x
+  b`))
+	c.Assert(err, FitsTypeOf, &asserts.HeaderError{})
+	herr := err.(*asserts.HeaderError)
+	c.Check(herr.Line, Equals, 4)
+	c.Check(herr.Offset, Equals, len("foo: 1\nbar:\n  a: x\n"))
+	c.Check(herr.Msg, Equals, `map entry missing ':' separator: "b"`)
+}
+
+func (s *headersSuite) TestParseHeadersTooManyEntries(c *C) {
+	head := []byte(`foo: 1
+bar:
+  - x
+  - y
+baz: 2`)
+	_, err := asserts.ParseHeadersWithLimits(head, 4, 0)
+	c.Check(err, ErrorMatches, `line 5: too many header entries, maximum is 4`)
+
+	_, err = asserts.ParseHeadersWithLimits(head, 5, 0)
+	c.Check(err, IsNil)
+
+	var buf bytes.Buffer
+	buf.WriteString("foo:")
+	for i := 0; i < asserts.MaxHeaderEntries; i++ {
+		buf.WriteString("\n  - x")
+	}
+	_, err = asserts.ParseHeaders(buf.Bytes())
+	c.Check(err, ErrorMatches, fmt.Sprintf(`line %d: too many header entries, maximum is %d`, asserts.MaxHeaderEntries+1, asserts.MaxHeaderEntries))
+}
+
+func (s *headersSuite) TestParseHeadersTooDeep(c *C) {
+	head := []byte(`foo:
+  a:
+    -
+      b: c`)
+	_, err := asserts.ParseHeadersWithLimits(head, 0, 2)
+	c.Check(err, ErrorMatches, `line 4: header values nested too deeply, maximum nesting is 2`)
+
+	m, err := asserts.ParseHeadersWithLimits(head, 0, 3)
+	c.Assert(err, IsNil)
+	c.Check(m, DeepEquals, map[string]interface{}{
+		"foo": map[string]interface{}{
+			"a": []interface{}{
+				map[string]interface{}{"b": "c"},
+			},
+		},
+	})
+
+	var buf bytes.Buffer
+	buf.WriteString("foo:")
+	for i := 0; i <= asserts.MaxHeaderNesting; i++ {
+		buf.WriteString("\n" + strings.Repeat(" ", 2*(i+1)) + "a:")
+	}
+	buf.WriteString(" b")
+	_, err = asserts.ParseHeaders(buf.Bytes())
+	c.Check(err, ErrorMatches, fmt.Sprintf(`line %d: header values nested too deeply, maximum nesting is %d`, asserts.MaxHeaderNesting+2, asserts.MaxHeaderNesting))
 }
 
 func (s *headersSuite) TestAppendEntrySimple(c *C) {
@@ -0,0 +1,200 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2019 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package asserts
+
+import (
+	"crypto"
+	"fmt"
+	"regexp"
+	"time"
+)
+
+// PreseedArtifact holds the details about an artifact produced by
+// image-time preseeding as recorded by a preseed assertion.
+type PreseedArtifact struct {
+	Name     string
+	SHA3_384 string
+}
+
+// Preseed holds a preseed assertion, which records the digests of
+// the artifacts produced by preseeding an image for a given model
+// so that they can be verified on first boot before the preseeded
+// state is reused.
+type Preseed struct {
+	assertionBase
+	artifacts []*PreseedArtifact
+	timestamp time.Time
+}
+
+// Series returns the series of the preseeded image.
+func (p *Preseed) Series() string {
+	return p.HeaderString("series")
+}
+
+// BrandID returns the brand identifier of the model of the preseeded image.
+func (p *Preseed) BrandID() string {
+	return p.HeaderString("brand-id")
+}
+
+// Model returns the model name identifier of the preseeded image.
+func (p *Preseed) Model() string {
+	return p.HeaderString("model")
+}
+
+// SystemLabel returns the label identifying the preseeded image.
+func (p *Preseed) SystemLabel() string {
+	return p.HeaderString("system-label")
+}
+
+// Artifacts returns the artifacts produced by preseeding with their digests.
+func (p *Preseed) Artifacts() []*PreseedArtifact {
+	return p.artifacts
+}
+
+// Timestamp returns the time when the preseed assertion was issued.
+func (p *Preseed) Timestamp() time.Time {
+	return p.timestamp
+}
+
+// CheckArtifacts checks that the given artifact digests, keyed by
+// artifact name, match exactly the ones recorded by the assertion.
+func (p *Preseed) CheckArtifacts(digests map[string]string) error {
+	for _, artifact := range p.artifacts {
+		digest, ok := digests[artifact.Name]
+		if !ok {
+			return fmt.Errorf("preseed artifact %q is missing", artifact.Name)
+		}
+		if digest != artifact.SHA3_384 {
+			return fmt.Errorf("preseed artifact %q does not have the digest recorded by the preseed assertion", artifact.Name)
+		}
+	}
+	if len(digests) != len(p.artifacts) {
+		for name := range digests {
+			if p.artifact(name) == nil {
+				return fmt.Errorf("preseed artifact %q is not listed by the preseed assertion", name)
+			}
+		}
+	}
+	return nil
+}
+
+func (p *Preseed) artifact(name string) *PreseedArtifact {
+	for _, artifact := range p.artifacts {
+		if artifact.Name == name {
+			return artifact
+		}
+	}
+	return nil
+}
+
+// Implement further consistency checks.
+func (p *Preseed) checkConsistency(db RODatabase, acck *AccountKey) error {
+	_, err := db.Find(ModelType, map[string]string{
+		"series":   p.Series(),
+		"brand-id": p.BrandID(),
+		"model":    p.Model(),
+	})
+	if IsNotFound(err) {
+		return fmt.Errorf("preseed assertion for %q does not have a matching model assertion for %s/%s", p.SystemLabel(), p.BrandID(), p.Model())
+	}
+	return err
+}
+
+// sanity
+var _ consistencyChecker = (*Preseed)(nil)
+
+// Prerequisites returns references to this preseed's prerequisite assertions.
+func (p *Preseed) Prerequisites() []*Ref {
+	return []*Ref{
+		{Type: ModelType, PrimaryKey: []string{p.Series(), p.BrandID(), p.Model()}},
+	}
+}
+
+var (
+	validSystemLabel         = regexp.MustCompile("^[a-z0-9](?:-?[a-z0-9])*$")
+	validPreseedArtifactName = regexp.MustCompile("^[a-z0-9](?:[a-z0-9.-]*[a-z0-9])?$")
+)
+
+func checkPreseedArtifacts(headers map[string]interface{}) ([]*PreseedArtifact, error) {
+	value, ok := headers["artifacts"]
+	if !ok {
+		return nil, fmt.Errorf(`"artifacts" header is mandatory`)
+	}
+	entries, ok := value.([]interface{})
+	if !ok || len(entries) == 0 {
+		return nil, fmt.Errorf(`"artifacts" header must be a non-empty list of artifact maps`)
+	}
+
+	artifacts := make([]*PreseedArtifact, 0, len(entries))
+	seen := make(map[string]bool, len(entries))
+	for i, entry := range entries {
+		artifact, ok := entry.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf(`"artifacts" header must be a non-empty list of artifact maps`)
+		}
+		name, err := checkStringMatchesWhat(artifact, "name", fmt.Sprintf("of artifact %d", i+1), validPreseedArtifactName)
+		if err != nil {
+			return nil, err
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("cannot list the same artifact %q multiple times", name)
+		}
+		seen[name] = true
+		if _, err := checkDigestWhat(artifact, "sha3-384", crypto.SHA3_384, fmt.Sprintf("of artifact %q", name)); err != nil {
+			return nil, err
+		}
+		artifacts = append(artifacts, &PreseedArtifact{
+			Name:     name,
+			SHA3_384: artifact["sha3-384"].(string),
+		})
+	}
+	return artifacts, nil
+}
+
+func assemblePreseed(assert assertionBase) (Assertion, error) {
+	err := checkAuthorityMatchesBrand(&assert)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := checkModel(assert.headers); err != nil {
+		return nil, err
+	}
+
+	if _, err := checkStringMatches(assert.headers, "system-label", validSystemLabel); err != nil {
+		return nil, err
+	}
+
+	artifacts, err := checkPreseedArtifacts(assert.headers)
+	if err != nil {
+		return nil, err
+	}
+
+	timestamp, err := checkRFC3339Date(assert.headers, "timestamp")
+	if err != nil {
+		return nil, err
+	}
+
+	return &Preseed{
+		assertionBase: assert,
+		artifacts:     artifacts,
+		timestamp:     timestamp,
+	}, nil
+}
@@ -0,0 +1,186 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2019 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package asserts_test
+
+import (
+	"strings"
+	"time"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/asserts"
+)
+
+type preseedSuite struct {
+	ts     time.Time
+	tsLine string
+}
+
+var _ = Suite(&preseedSuite{})
+
+func (ps *preseedSuite) SetUpSuite(c *C) {
+	ps.ts = time.Now().Truncate(time.Second).UTC()
+	ps.tsLine = "timestamp: " + ps.ts.Format(time.RFC3339) + "\n"
+}
+
+const (
+	preseedDigest1 = "Jv8_JiHiIzJVcO9M55pPdqSDWUvuhfDIBJUS-3VW7F_idjix7Ffn5qMxB21ZQuij"
+	preseedDigest2 = "KPIl7M4vQ9d4AUjkoU41TGAwtOMLc_bWUCeW8AvdRWD4_xcP60Oo4ABsFNo6BtXj"
+)
+
+const preseedExample = `type: preseed
+authority-id: brand-id1
+series: 16
+brand-id: brand-id1
+model: baz-3000
+system-label: 20191122
+artifacts:
+  -
+    name: preseed.tgz
+    sha3-384: Jv8_JiHiIzJVcO9M55pPdqSDWUvuhfDIBJUS-3VW7F_idjix7Ffn5qMxB21ZQuij
+  -
+    name: state.json
+    sha3-384: KPIl7M4vQ9d4AUjkoU41TGAwtOMLc_bWUCeW8AvdRWD4_xcP60Oo4ABsFNo6BtXj
+TSLINEbody-length: 0
+sign-key-sha3-384: Jv8_JiHiIzJVcO9M55pPdqSDWUvuhfDIBJUS-3VW7F_idjix7Ffn5qMxB21ZQuij
+
+AXNpZw==`
+
+func (ps *preseedSuite) TestDecodeOK(c *C) {
+	encoded := strings.Replace(preseedExample, "TSLINE", ps.tsLine, 1)
+
+	a, err := asserts.Decode([]byte(encoded))
+	c.Assert(err, IsNil)
+	c.Check(a.Type(), Equals, asserts.PreseedType)
+	preseed := a.(*asserts.Preseed)
+	c.Check(preseed.AuthorityID(), Equals, "brand-id1")
+	c.Check(preseed.Timestamp(), Equals, ps.ts)
+	c.Check(preseed.Series(), Equals, "16")
+	c.Check(preseed.BrandID(), Equals, "brand-id1")
+	c.Check(preseed.Model(), Equals, "baz-3000")
+	c.Check(preseed.SystemLabel(), Equals, "20191122")
+	c.Check(preseed.Artifacts(), DeepEquals, []*asserts.PreseedArtifact{
+		{Name: "preseed.tgz", SHA3_384: preseedDigest1},
+		{Name: "state.json", SHA3_384: preseedDigest2},
+	})
+	c.Check(preseed.Prerequisites(), DeepEquals, []*asserts.Ref{
+		{Type: asserts.ModelType, PrimaryKey: []string{"16", "brand-id1", "baz-3000"}},
+	})
+}
+
+const (
+	preseedErrPrefix = "assertion preseed: "
+)
+
+func (ps *preseedSuite) TestDecodeInvalid(c *C) {
+	encoded := strings.Replace(preseedExample, "TSLINE", ps.tsLine, 1)
+
+	artifactsStanza := encoded[strings.Index(encoded, "artifacts:"):strings.Index(encoded, "timestamp:")]
+
+	invalidTests := []struct{ original, invalid, expectedErr string }{
+		{"series: 16\n", "", `"series" header is mandatory`},
+		{"brand-id: brand-id1\n", "brand-id: other\n", `authority-id and brand-id must match, preseed assertions are expected to be signed by the brand: "brand-id1" != "other"`},
+		{"model: baz-3000\n", "", `"model" header is mandatory`},
+		{"model: baz-3000\n", "model: Baz-3000\n", `"model" header cannot contain uppercase letters`},
+		{"system-label: 20191122\n", "", `"system-label" header is mandatory`},
+		{"system-label: 20191122\n", "system-label: -foo\n", `"system-label" header contains invalid characters: "-foo"`},
+		{artifactsStanza, "", `"artifacts" header is mandatory`},
+		{artifactsStanza, "artifacts: foo\n", `"artifacts" header must be a non-empty list of artifact maps`},
+		{"name: preseed.tgz\n", "other: preseed.tgz\n", `"name" of artifact 1 is mandatory`},
+		{"name: preseed.tgz\n", "name: ../preseed.tgz\n", `"name" of artifact 1 contains invalid characters: "../preseed.tgz"`},
+		{"name: state.json\n", "name: preseed.tgz\n", `cannot list the same artifact "preseed.tgz" multiple times`},
+		{"sha3-384: " + preseedDigest1 + "\n", "other: " + preseedDigest1 + "\n", `"sha3-384" of artifact "preseed.tgz" is mandatory`},
+		{"sha3-384: " + preseedDigest1 + "\n", "sha3-384: ~\n", `"sha3-384" of artifact "preseed.tgz" cannot be decoded: .*`},
+		{"sha3-384: " + preseedDigest1 + "\n", "sha3-384: AAAA\n", `"sha3-384" of artifact "preseed.tgz" does not have the expected bit length: 24`},
+		{ps.tsLine, "", `"timestamp" header is mandatory`},
+	}
+
+	for _, test := range invalidTests {
+		invalid := strings.Replace(encoded, test.original, test.invalid, 1)
+		_, err := asserts.Decode([]byte(invalid))
+		c.Check(err, ErrorMatches, preseedErrPrefix+test.expectedErr)
+	}
+}
+
+func (ps *preseedSuite) TestCheckArtifacts(c *C) {
+	encoded := strings.Replace(preseedExample, "TSLINE", ps.tsLine, 1)
+	a, err := asserts.Decode([]byte(encoded))
+	c.Assert(err, IsNil)
+	preseed := a.(*asserts.Preseed)
+
+	err = preseed.CheckArtifacts(map[string]string{
+		"preseed.tgz": preseedDigest1,
+		"state.json":  preseedDigest2,
+	})
+	c.Check(err, IsNil)
+
+	tests := []struct {
+		digests map[string]string
+		err     string
+	}{
+		{map[string]string{"preseed.tgz": preseedDigest1}, `preseed artifact "state.json" is missing`},
+		{map[string]string{"preseed.tgz": preseedDigest2, "state.json": preseedDigest2}, `preseed artifact "preseed.tgz" does not have the digest recorded by the preseed assertion`},
+		{map[string]string{"preseed.tgz": preseedDigest1, "state.json": preseedDigest2, "extra": preseedDigest1}, `preseed artifact "extra" is not listed by the preseed assertion`},
+	}
+	for _, t := range tests {
+		c.Check(preseed.CheckArtifacts(t.digests), ErrorMatches, t.err)
+	}
+}
+
+func (ps *preseedSuite) TestPreseedCheck(c *C) {
+	storeDB, db := makeStoreAndCheckDB(c)
+	brandDB := setup3rdPartySigning(c, "brand-id1", storeDB, db)
+
+	model, err := brandDB.Sign(asserts.ModelType, map[string]interface{}{
+		"series":       "16",
+		"brand-id":     brandDB.AuthorityID,
+		"model":        "baz-3000",
+		"architecture": "amd64",
+		"gadget":       "brand-gadget",
+		"kernel":       "baz-linux",
+		"timestamp":    time.Now().Format(time.RFC3339),
+	}, nil, "")
+	c.Assert(err, IsNil)
+
+	headers := map[string]interface{}{
+		"series":       "16",
+		"brand-id":     brandDB.AuthorityID,
+		"model":        "baz-3000",
+		"system-label": "20191122",
+		"artifacts": []interface{}{
+			map[string]interface{}{
+				"name":     "preseed.tgz",
+				"sha3-384": preseedDigest1,
+			},
+		},
+		"timestamp": time.Now().Format(time.RFC3339),
+	}
+	preseed, err := brandDB.Sign(asserts.PreseedType, headers, nil, "")
+	c.Assert(err, IsNil)
+
+	err = db.Check(preseed)
+	c.Assert(err, ErrorMatches, `preseed assertion for "20191122" does not have a matching model assertion for brand-id1/baz-3000`)
+
+	err = db.Add(model)
+	c.Assert(err, IsNil)
+
+	err = db.Check(preseed)
+	c.Assert(err, IsNil)
+}
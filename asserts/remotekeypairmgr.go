@@ -0,0 +1,195 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2019 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package asserts
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// RemoteSignerConfig configures the access to a remote signing service.
+type RemoteSignerConfig struct {
+	// URL is the base URL of the signing service.
+	URL string
+	// CertFile and KeyFile hold the client certificate and its key
+	// in PEM format used to authenticate to the service (mutual TLS).
+	CertFile string
+	KeyFile  string
+	// CAFile optionally holds the PEM certificates used to verify
+	// the service, the system ones are used otherwise.
+	CAFile string
+}
+
+// RemoteKeypairManager is a KeypairManager that delegates signing to
+// a remote signing service, so that the private keys never need to
+// be held locally.
+//
+// The service is expected to provide:
+//
+//	GET  <url>/v1/keys/<key-id>       the exported OpenPGP public key
+//	GET  <url>/v1/keys?name=<name>    the same, looked up by key name
+//	POST <url>/v1/keys/<key-id>/sign  the detached OpenPGP signature of the body
+type RemoteKeypairManager struct {
+	baseURL *url.URL
+	client  *http.Client
+}
+
+// remoteSignerTimeout is the timeout for requests to the remote signer.
+var remoteSignerTimeout = 60 * time.Second
+
+// NewRemoteKeypairManager creates a new RemoteKeypairManager for the
+// signing service described by config.
+func NewRemoteKeypairManager(config *RemoteSignerConfig) (*RemoteKeypairManager, error) {
+	baseURL, err := url.Parse(config.URL)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse remote signer URL: %v", err)
+	}
+	if baseURL.Scheme != "https" {
+		return nil, fmt.Errorf("remote signer URL must use https, got %q", config.URL)
+	}
+	if config.CertFile == "" || config.KeyFile == "" {
+		return nil, fmt.Errorf("remote signer needs a client certificate and key")
+	}
+	cert, err := tls.LoadX509KeyPair(config.CertFile, config.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("cannot load remote signer client certificate: %v", err)
+	}
+	tlsConfig := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+	}
+	if config.CAFile != "" {
+		pem, err := ioutil.ReadFile(config.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read remote signer CA certificates: %v", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("cannot use remote signer CA certificates: no certificates found in %q", config.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	return &RemoteKeypairManager{
+		baseURL: baseURL,
+		client: &http.Client{
+			Timeout:   remoteSignerTimeout,
+			Transport: &http.Transport{TLSClientConfig: tlsConfig},
+		},
+	}, nil
+}
+
+func (rkm *RemoteKeypairManager) endpoint(path string, query url.Values) string {
+	u := *rkm.baseURL
+	u.Path = strings.TrimSuffix(u.Path, "/") + path
+	u.RawQuery = query.Encode()
+	return u.String()
+}
+
+func (rkm *RemoteKeypairManager) do(method, endpoint string, body []byte) ([]byte, error) {
+	var rd io.Reader
+	if body != nil {
+		rd = bytes.NewReader(body)
+	}
+	req, err := http.NewRequest(method, endpoint, rd)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/octet-stream")
+	}
+	resp, err := rkm.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("cannot talk to remote signer: %v", err)
+	}
+	defer resp.Body.Close()
+
+	out, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read remote signer response: %v", err)
+	}
+	if resp.StatusCode != 200 {
+		return nil, &remoteSignerError{status: resp.StatusCode, msg: strings.TrimSpace(string(out))}
+	}
+	return out, nil
+}
+
+type remoteSignerError struct {
+	status int
+	msg    string
+}
+
+func (e *remoteSignerError) Error() string {
+	if e.msg == "" {
+		return fmt.Sprintf("remote signer returned unexpected status %d", e.status)
+	}
+	return fmt.Sprintf("remote signer returned unexpected status %d: %s", e.status, e.msg)
+}
+
+func (rkm *RemoteKeypairManager) retrieve(endpoint, what string) (PrivateKey, error) {
+	pubKeyBuf, err := rkm.do("GET", endpoint, nil)
+	if e, ok := err.(*remoteSignerError); ok && e.status == 404 {
+		return nil, fmt.Errorf("cannot find key %s in remote signer", what)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var privKey *extPGPPrivateKey
+	privKey, err = newExtPGPPrivateKey(bytes.NewBuffer(pubKeyBuf), "remote signer", func(content []byte) ([]byte, error) {
+		keyID := privKey.PublicKey().ID()
+		return rkm.do("POST", rkm.endpoint("/v1/keys/"+url.PathEscape(keyID)+"/sign", nil), content)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("cannot load remote signer public key %s: %v", what, err)
+	}
+	return privKey, nil
+}
+
+// Put is not supported, keys are managed by the remote signer itself.
+func (rkm *RemoteKeypairManager) Put(privKey PrivateKey) error {
+	return fmt.Errorf("cannot store keys in a remote signer")
+}
+
+// Get returns the key pair with the given key id, of which only the
+// public part is available locally.
+func (rkm *RemoteKeypairManager) Get(keyID string) (PrivateKey, error) {
+	what := fmt.Sprintf("%q", keyID)
+	privKey, err := rkm.retrieve(rkm.endpoint("/v1/keys/"+url.PathEscape(keyID), nil), what)
+	if err != nil {
+		return nil, err
+	}
+	if privKey.PublicKey().ID() != keyID {
+		return nil, fmt.Errorf("cannot use key %s from remote signer: got key %q instead", what, privKey.PublicKey().ID())
+	}
+	return privKey, nil
+}
+
+// GetByName returns the key pair with the given name.
+func (rkm *RemoteKeypairManager) GetByName(name string) (PrivateKey, error) {
+	return rkm.retrieve(rkm.endpoint("/v1/keys", url.Values{"name": {name}}), fmt.Sprintf("with name %q", name))
+}
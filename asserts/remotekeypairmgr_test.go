@@ -0,0 +1,275 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2019 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package asserts_test
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"time"
+
+	. "gopkg.in/check.v1"
+
+	"golang.org/x/crypto/openpgp/packet"
+
+	"github.com/snapcore/snapd/asserts"
+	"github.com/snapcore/snapd/asserts/assertstest"
+)
+
+type remoteKeypairMgrSuite struct {
+	server *httptest.Server
+	config *asserts.RemoteSignerConfig
+
+	pgpPrivKey *packet.PrivateKey
+	pubKey     []byte
+	keyID      string
+	signed     [][]byte
+	refuse     bool
+}
+
+var _ = Suite(&remoteKeypairMgrSuite{})
+
+func writePEM(c *C, fn, typ string, der []byte) {
+	err := ioutil.WriteFile(fn, pem.EncodeToMemory(&pem.Block{Type: typ, Bytes: der}), 0600)
+	c.Assert(err, IsNil)
+}
+
+func (rkms *remoteKeypairMgrSuite) SetUpTest(c *C) {
+	dir := c.MkDir()
+
+	privKey, rsaPrivKey := assertstest.ReadPrivKey(assertstest.DevKey)
+	rkms.keyID = privKey.PublicKey().ID()
+	rkms.pgpPrivKey = packet.NewRSAPrivateKey(time.Unix(1, 0), rsaPrivKey)
+	buf := new(bytes.Buffer)
+	c.Assert(rkms.pgpPrivKey.PublicKey.Serialize(buf), IsNil)
+	rkms.pubKey = buf.Bytes()
+	rkms.signed = nil
+	rkms.refuse = false
+
+	// client certificate for mutual TLS
+	clientKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	c.Assert(err, IsNil)
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "build-farm"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		IsCA:         true,
+
+		BasicConstraintsValid: true,
+	}
+	clientCertDER, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &clientKey.PublicKey, clientKey)
+	c.Assert(err, IsNil)
+	clientCert, err := x509.ParseCertificate(clientCertDER)
+	c.Assert(err, IsNil)
+	clientKeyDER, err := x509.MarshalECPrivateKey(clientKey)
+	c.Assert(err, IsNil)
+
+	certFile := filepath.Join(dir, "client.pem")
+	keyFile := filepath.Join(dir, "client.key")
+	writePEM(c, certFile, "CERTIFICATE", clientCertDER)
+	writePEM(c, keyFile, "EC PRIVATE KEY", clientKeyDER)
+
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(clientCert)
+
+	rkms.server = httptest.NewUnstartedServer(http.HandlerFunc(rkms.handle))
+	rkms.server.TLS = &tls.Config{
+		ClientAuth: tls.RequireAndVerifyClientCert,
+		ClientCAs:  clientCAs,
+	}
+	rkms.server.StartTLS()
+
+	caFile := filepath.Join(dir, "ca.pem")
+	writePEM(c, caFile, "CERTIFICATE", rkms.server.Certificate().Raw)
+
+	rkms.config = &asserts.RemoteSignerConfig{
+		URL:      rkms.server.URL + "/signer/",
+		CertFile: certFile,
+		KeyFile:  keyFile,
+		CAFile:   caFile,
+	}
+}
+
+func (rkms *remoteKeypairMgrSuite) TearDownTest(c *C) {
+	rkms.server.Close()
+}
+
+func (rkms *remoteKeypairMgrSuite) handle(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case r.Method == "GET" && r.URL.Path == "/signer/v1/keys/"+rkms.keyID,
+		r.Method == "GET" && r.URL.Path == "/signer/v1/keys" && r.URL.Query().Get("name") == "default":
+		w.Write(rkms.pubKey)
+	case r.Method == "POST" && r.URL.Path == "/signer/v1/keys/"+rkms.keyID+"/sign" && !rkms.refuse:
+		content, _ := ioutil.ReadAll(r.Body)
+		rkms.signed = append(rkms.signed, content)
+
+		sig := new(packet.Signature)
+		sig.PubKeyAlgo = packet.PubKeyAlgoRSA
+		sig.Hash = crypto.SHA512
+		sig.CreationTime = time.Now()
+		h := sig.Hash.New()
+		h.Write(content)
+		if err := sig.Sign(h, rkms.pgpPrivKey, nil); err != nil {
+			http.Error(w, err.Error(), 500)
+			return
+		}
+		sig.Serialize(w)
+	case r.Method == "POST":
+		http.Error(w, "signing refused", 403)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (rkms *remoteKeypairMgrSuite) TestGet(c *C) {
+	rkm, err := asserts.NewRemoteKeypairManager(rkms.config)
+	c.Assert(err, IsNil)
+
+	privKey, err := rkm.Get(rkms.keyID)
+	c.Assert(err, IsNil)
+	c.Check(privKey.PublicKey().ID(), Equals, rkms.keyID)
+
+	privKey, err = rkm.GetByName("default")
+	c.Assert(err, IsNil)
+	c.Check(privKey.PublicKey().ID(), Equals, rkms.keyID)
+}
+
+func (rkms *remoteKeypairMgrSuite) TestGetNotFound(c *C) {
+	rkm, err := asserts.NewRemoteKeypairManager(rkms.config)
+	c.Assert(err, IsNil)
+
+	_, err = rkm.Get("ffffffffffffffff")
+	c.Check(err, ErrorMatches, `cannot find key "ffffffffffffffff" in remote signer`)
+
+	_, err = rkm.GetByName("other")
+	c.Check(err, ErrorMatches, `cannot find key with name "other" in remote signer`)
+}
+
+func (rkms *remoteKeypairMgrSuite) TestPutUnsupported(c *C) {
+	rkm, err := asserts.NewRemoteKeypairManager(rkms.config)
+	c.Assert(err, IsNil)
+
+	err = rkm.Put(testPrivKey1)
+	c.Check(err, ErrorMatches, `cannot store keys in a remote signer`)
+}
+
+func (rkms *remoteKeypairMgrSuite) TestUseInSigning(c *C) {
+	store := assertstest.NewStoreStack("trusted", nil)
+
+	rkm, err := asserts.NewRemoteKeypairManager(rkms.config)
+	c.Assert(err, IsNil)
+	devKey, err := rkm.Get(rkms.keyID)
+	c.Assert(err, IsNil)
+
+	devAcct := assertstest.NewAccount(store, "devel1", map[string]interface{}{
+		"account-id": "dev1-id",
+	}, "")
+	devAccKey := assertstest.NewAccountKey(store, devAcct, nil, devKey.PublicKey(), "")
+
+	signDB, err := asserts.OpenDatabase(&asserts.DatabaseConfig{
+		KeypairManager: rkm,
+	})
+	c.Assert(err, IsNil)
+
+	checkDB, err := asserts.OpenDatabase(&asserts.DatabaseConfig{
+		Backstore: asserts.NewMemoryBackstore(),
+		Trusted:   store.Trusted,
+	})
+	c.Assert(err, IsNil)
+	c.Assert(checkDB.Add(store.StoreAccountKey("")), IsNil)
+	c.Assert(checkDB.Add(devAcct), IsNil)
+	c.Assert(checkDB.Add(devAccKey), IsNil)
+
+	headers := map[string]interface{}{
+		"authority-id":  "dev1-id",
+		"snap-sha3-384": blobSHA3_384,
+		"snap-id":       "snap-id-1",
+		"grade":         "devel",
+		"snap-size":     "1025",
+		"timestamp":     time.Now().Format(time.RFC3339),
+	}
+	snapBuild, err := signDB.Sign(asserts.SnapBuildType, headers, nil, rkms.keyID)
+	c.Assert(err, IsNil)
+	c.Check(rkms.signed, HasLen, 1)
+
+	err = checkDB.Check(snapBuild)
+	c.Check(err, IsNil)
+}
+
+func (rkms *remoteKeypairMgrSuite) TestSigningRefused(c *C) {
+	rkms.refuse = true
+
+	rkm, err := asserts.NewRemoteKeypairManager(rkms.config)
+	c.Assert(err, IsNil)
+
+	signDB, err := asserts.OpenDatabase(&asserts.DatabaseConfig{
+		KeypairManager: rkm,
+	})
+	c.Assert(err, IsNil)
+
+	headers := map[string]interface{}{
+		"authority-id":  "dev1-id",
+		"snap-sha3-384": blobSHA3_384,
+		"snap-id":       "snap-id-1",
+		"grade":         "devel",
+		"snap-size":     "1025",
+		"timestamp":     time.Now().Format(time.RFC3339),
+	}
+	_, err = signDB.Sign(asserts.SnapBuildType, headers, nil, rkms.keyID)
+	c.Check(err, ErrorMatches, `cannot sign assertion: remote signer returned unexpected status 403: signing refused`)
+}
+
+func (rkms *remoteKeypairMgrSuite) TestNeedsClientCertificate(c *C) {
+	_, err := asserts.NewRemoteKeypairManager(&asserts.RemoteSignerConfig{
+		URL: rkms.config.URL,
+	})
+	c.Check(err, ErrorMatches, `remote signer needs a client certificate and key`)
+}
+
+func (rkms *remoteKeypairMgrSuite) TestVerifiesServer(c *C) {
+	// the test server is not trusted by the system CA certificates
+	other := *rkms.config
+	other.CAFile = ""
+	rkm, err := asserts.NewRemoteKeypairManager(&other)
+	c.Assert(err, IsNil)
+	_, err = rkm.Get(rkms.keyID)
+	c.Check(err, ErrorMatches, `cannot talk to remote signer: .*certificate.*`)
+}
+
+func (rkms *remoteKeypairMgrSuite) TestNeedsHTTPS(c *C) {
+	other := *rkms.config
+	other.URL = "http://signer.example.com"
+	_, err := asserts.NewRemoteKeypairManager(&other)
+	c.Check(err, ErrorMatches, `remote signer URL must use https, got "http://signer.example.com"`)
+}
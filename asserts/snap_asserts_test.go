@@ -1441,7 +1441,7 @@ func (s *baseDeclSuite) TestBuiltinInitErrors(c *C) {
 		headers string
 		err     string
 	}{
-		{"", `header entry missing ':' separator: ""`},
+		{"", `line 1: header entry missing ':' separator: ""`},
 		{"type: foo\n", `the builtin base-declaration "type" header is not set to expected value "base-declaration"`},
 		{"type: base-declaration", `the builtin base-declaration "authority-id" header is not set to expected value "canonical"`},
 		{"type: base-declaration\nauthority-id: canonical", `the builtin base-declaration "series" header is not set to expected value "16"`},
@@ -134,6 +134,16 @@ func FetchSnapAssertions(f asserts.Fetcher, snapSHA3_384 string) error {
 	return f.Fetch(ref)
 }
 
+// FetchSnapBuild fetches the snap-build assertion, if any, and its prerequisites for the given snap digest using the given fetcher.
+func FetchSnapBuild(f asserts.Fetcher, snapSHA3_384 string) error {
+	ref := &asserts.Ref{
+		Type:       asserts.SnapBuildType,
+		PrimaryKey: []string{snapSHA3_384},
+	}
+
+	return f.Fetch(ref)
+}
+
 // FetchSnapDeclaration fetches the snap declaration and its prerequisites for the given snap id using the given fetcher.
 func FetchSnapDeclaration(f asserts.Fetcher, snapID string) error {
 	ref := &asserts.Ref{
@@ -0,0 +1,191 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2019 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package snapasserts
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/snapcore/snapd/asserts"
+	"github.com/snapcore/snapd/snap"
+)
+
+// InstalledSnap holds the details about an installed snap needed to
+// check it against validation-sets.
+type InstalledSnap struct {
+	Name     string
+	SnapID   string
+	Revision snap.Revision
+}
+
+// ValidationSetKey returns the account-id/name key identifying the
+// validation-set independently of its sequence.
+func ValidationSetKey(vs *asserts.ValidationSet) string {
+	return fmt.Sprintf("%s/%s", vs.AccountID(), vs.Name())
+}
+
+// ValidationSetsValidationError describes the ways in which the
+// installed snaps do not comply with a group of validation-sets.
+// All maps go from snap names to the keys of the sets concerned.
+type ValidationSetsValidationError struct {
+	// MissingSnaps lists the required snaps that are not installed.
+	MissingSnaps map[string][]string
+	// InvalidSnaps lists the installed snaps that are marked invalid.
+	InvalidSnaps map[string][]string
+	// WrongRevisionSnaps lists the installed snaps whose revision
+	// differs from the required one, by required revision.
+	WrongRevisionSnaps map[string]map[snap.Revision][]string
+}
+
+func sortedKeys(m map[string][]string) []string {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (e *ValidationSetsValidationError) Error() string {
+	buf := bytes.NewBufferString("validation sets assertions are not met:")
+	if len(e.MissingSnaps) != 0 {
+		buf.WriteString("\n- missing required snaps:")
+		for _, name := range sortedKeys(e.MissingSnaps) {
+			fmt.Fprintf(buf, "\n  - %s (required by sets %s)", name, strings.Join(e.MissingSnaps[name], ","))
+		}
+	}
+	if len(e.InvalidSnaps) != 0 {
+		buf.WriteString("\n- invalid snaps:")
+		for _, name := range sortedKeys(e.InvalidSnaps) {
+			fmt.Fprintf(buf, "\n  - %s (invalid for sets %s)", name, strings.Join(e.InvalidSnaps[name], ","))
+		}
+	}
+	if len(e.WrongRevisionSnaps) != 0 {
+		buf.WriteString("\n- snaps at wrong revisions:")
+		names := make([]string, 0, len(e.WrongRevisionSnaps))
+		for name := range e.WrongRevisionSnaps {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			var revs []string
+			for rev, keys := range e.WrongRevisionSnaps[name] {
+				revs = append(revs, fmt.Sprintf("at revision %s by sets %s", rev, strings.Join(keys, ",")))
+			}
+			sort.Strings(revs)
+			fmt.Fprintf(buf, "\n  - %s (required %s)", name, strings.Join(revs, ", "))
+		}
+	}
+	return buf.String()
+}
+
+func (sn *InstalledSnap) matches(vsnap *asserts.ValidationSetSnap) bool {
+	if sn.SnapID != "" {
+		return sn.SnapID == vsnap.SnapID
+	}
+	return sn.Name == vsnap.Name
+}
+
+// CheckInstalledSnaps checks that the installed snaps comply with all
+// the given validation-sets, it returns a
+// *ValidationSetsValidationError otherwise.
+func CheckInstalledSnaps(sets []*asserts.ValidationSet, snaps []*InstalledSnap) error {
+	var verr ValidationSetsValidationError
+	add := func(m *map[string][]string, name, key string) {
+		if *m == nil {
+			*m = make(map[string][]string)
+		}
+		(*m)[name] = append((*m)[name], key)
+	}
+
+	for _, vs := range sets {
+		key := ValidationSetKey(vs)
+		for _, vsnap := range vs.Snaps() {
+			var installed *InstalledSnap
+			for _, sn := range snaps {
+				if sn.matches(vsnap) {
+					installed = sn
+					break
+				}
+			}
+			switch {
+			case installed == nil:
+				if vsnap.Presence == asserts.PresenceRequired {
+					add(&verr.MissingSnaps, vsnap.Name, key)
+				}
+			case vsnap.Presence == asserts.PresenceInvalid:
+				add(&verr.InvalidSnaps, vsnap.Name, key)
+			case vsnap.Revision != 0 && installed.Revision != snap.R(vsnap.Revision):
+				if verr.WrongRevisionSnaps == nil {
+					verr.WrongRevisionSnaps = make(map[string]map[snap.Revision][]string)
+				}
+				revs := verr.WrongRevisionSnaps[vsnap.Name]
+				if revs == nil {
+					revs = make(map[snap.Revision][]string)
+					verr.WrongRevisionSnaps[vsnap.Name] = revs
+				}
+				rev := snap.R(vsnap.Revision)
+				revs[rev] = append(revs[rev], key)
+			}
+		}
+	}
+
+	if verr.MissingSnaps != nil || verr.InvalidSnaps != nil || verr.WrongRevisionSnaps != nil {
+		return &verr
+	}
+	return nil
+}
+
+// RequiredBy returns the keys of the validation-sets that require the
+// given snap to be installed.
+func RequiredBy(sets []*asserts.ValidationSet, sn *InstalledSnap) []string {
+	var keys []string
+	for _, vs := range sets {
+		for _, vsnap := range vs.Snaps() {
+			if vsnap.Presence == asserts.PresenceRequired && sn.matches(vsnap) {
+				keys = append(keys, ValidationSetKey(vs))
+				break
+			}
+		}
+	}
+	return keys
+}
+
+// RequiredRevision returns the revision of the given snap required by
+// the validation-sets, or an unset revision if no specific revision is
+// required. It errors if the sets require conflicting revisions.
+func RequiredRevision(sets []*asserts.ValidationSet, sn *InstalledSnap) (rev snap.Revision, keys []string, err error) {
+	for _, vs := range sets {
+		for _, vsnap := range vs.Snaps() {
+			if vsnap.Revision == 0 || !sn.matches(vsnap) {
+				continue
+			}
+			r := snap.R(vsnap.Revision)
+			if !rev.Unset() && rev != r {
+				return snap.Revision{}, nil, fmt.Errorf("validation sets %s and %s require different revisions of snap %q", strings.Join(keys, ","), ValidationSetKey(vs), vsnap.Name)
+			}
+			rev = r
+			keys = append(keys, ValidationSetKey(vs))
+		}
+	}
+	return rev, keys, nil
+}
@@ -0,0 +1,133 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2019 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package snapasserts_test
+
+import (
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/asserts"
+	"github.com/snapcore/snapd/asserts/assertstest"
+	"github.com/snapcore/snapd/asserts/snapasserts"
+	"github.com/snapcore/snapd/snap"
+)
+
+type validationSetsSuite struct{}
+
+var _ = Suite(&validationSetsSuite{})
+
+func fakeValidationSet(name string, snaps ...interface{}) *asserts.ValidationSet {
+	return assertstest.FakeAssertion(map[string]interface{}{
+		"type":         "validation-set",
+		"authority-id": "acme",
+		"series":       "16",
+		"account-id":   "acme",
+		"name":         name,
+		"sequence":     "1",
+		"snaps":        snaps,
+	}).(*asserts.ValidationSet)
+}
+
+var (
+	snapAID = "mysnapaaaaaaaaaaaaaaaaaaaaaaaaaa"
+	snapBID = "mysnapbbbbbbbbbbbbbbbbbbbbbbbbbb"
+	snapCID = "mysnapcccccccccccccccccccccccccc"
+)
+
+func (s *validationSetsSuite) sets() []*asserts.ValidationSet {
+	vs1 := fakeValidationSet("one",
+		map[string]interface{}{"name": "snap-a", "id": snapAID, "revision": "3"},
+		map[string]interface{}{"name": "snap-b", "id": snapBID, "presence": "optional"},
+	)
+	vs2 := fakeValidationSet("two",
+		map[string]interface{}{"name": "snap-a", "id": snapAID},
+		map[string]interface{}{"name": "snap-c", "id": snapCID, "presence": "invalid"},
+	)
+	return []*asserts.ValidationSet{vs1, vs2}
+}
+
+func (s *validationSetsSuite) TestCheckInstalledSnapsHappy(c *C) {
+	snaps := []*snapasserts.InstalledSnap{
+		{Name: "snap-a", SnapID: snapAID, Revision: snap.R(3)},
+		{Name: "other", SnapID: "otherid", Revision: snap.R(1)},
+	}
+	c.Check(snapasserts.CheckInstalledSnaps(s.sets(), snaps), IsNil)
+}
+
+func (s *validationSetsSuite) TestCheckInstalledSnapsErrors(c *C) {
+	snaps := []*snapasserts.InstalledSnap{
+		{Name: "snap-b", SnapID: snapBID, Revision: snap.R(1)},
+		{Name: "snap-c", SnapID: snapCID, Revision: snap.R(1)},
+	}
+	err := snapasserts.CheckInstalledSnaps(s.sets(), snaps)
+	c.Assert(err, FitsTypeOf, &snapasserts.ValidationSetsValidationError{})
+	verr := err.(*snapasserts.ValidationSetsValidationError)
+	c.Check(verr.MissingSnaps, DeepEquals, map[string][]string{
+		"snap-a": {"acme/one", "acme/two"},
+	})
+	c.Check(verr.InvalidSnaps, DeepEquals, map[string][]string{
+		"snap-c": {"acme/two"},
+	})
+	c.Check(verr.WrongRevisionSnaps, IsNil)
+	c.Check(err, ErrorMatches, `validation sets assertions are not met:
+- missing required snaps:
+  - snap-a \(required by sets acme/one,acme/two\)
+- invalid snaps:
+  - snap-c \(invalid for sets acme/two\)`)
+
+	snaps = []*snapasserts.InstalledSnap{
+		{Name: "snap-a", SnapID: snapAID, Revision: snap.R(4)},
+	}
+	err = snapasserts.CheckInstalledSnaps(s.sets(), snaps)
+	c.Check(err, ErrorMatches, `validation sets assertions are not met:
+- snaps at wrong revisions:
+  - snap-a \(required at revision 3 by sets acme/one\)`)
+}
+
+func (s *validationSetsSuite) TestCheckInstalledSnapsLocalByName(c *C) {
+	// a locally installed snap without snap id is matched by name
+	snaps := []*snapasserts.InstalledSnap{
+		{Name: "snap-a", Revision: snap.R(-1)},
+	}
+	err := snapasserts.CheckInstalledSnaps(s.sets(), snaps)
+	c.Check(err, ErrorMatches, `(?s).*- snap-a \(required at revision 3 by sets acme/one\)`)
+}
+
+func (s *validationSetsSuite) TestRequiredBy(c *C) {
+	c.Check(snapasserts.RequiredBy(s.sets(), &snapasserts.InstalledSnap{Name: "snap-a", SnapID: snapAID}), DeepEquals, []string{"acme/one", "acme/two"})
+	c.Check(snapasserts.RequiredBy(s.sets(), &snapasserts.InstalledSnap{Name: "snap-b", SnapID: snapBID}), HasLen, 0)
+}
+
+func (s *validationSetsSuite) TestRequiredRevision(c *C) {
+	rev, keys, err := snapasserts.RequiredRevision(s.sets(), &snapasserts.InstalledSnap{Name: "snap-a", SnapID: snapAID})
+	c.Assert(err, IsNil)
+	c.Check(rev, Equals, snap.R(3))
+	c.Check(keys, DeepEquals, []string{"acme/one"})
+
+	rev, keys, err = snapasserts.RequiredRevision(s.sets(), &snapasserts.InstalledSnap{Name: "snap-b", SnapID: snapBID})
+	c.Assert(err, IsNil)
+	c.Check(rev.Unset(), Equals, true)
+	c.Check(keys, HasLen, 0)
+
+	conflicting := append(s.sets(), fakeValidationSet("three",
+		map[string]interface{}{"name": "snap-a", "id": snapAID, "revision": "4"},
+	))
+	_, _, err = snapasserts.RequiredRevision(conflicting, &snapasserts.InstalledSnap{Name: "snap-a", SnapID: snapAID})
+	c.Check(err, ErrorMatches, `validation sets acme/one and acme/three require different revisions of snap "snap-a"`)
+}
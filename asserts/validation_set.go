@@ -0,0 +1,253 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2019 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package asserts
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/snapcore/snapd/snap/naming"
+)
+
+// Presence represents a presence constraint of a snap in a
+// validation-set.
+type Presence string
+
+const (
+	// PresenceRequired means the snap must be installed.
+	PresenceRequired Presence = "required"
+	// PresenceOptional means the snap may or may not be installed.
+	PresenceOptional Presence = "optional"
+	// PresenceInvalid means the snap must not be installed.
+	PresenceInvalid Presence = "invalid"
+)
+
+// ValidationSetSnap holds the details about a snap constrained by
+// a validation-set assertion.
+type ValidationSetSnap struct {
+	Name   string
+	SnapID string
+
+	Presence Presence
+
+	// Revision is the required revision of the snap, 0 means any
+	// revision is acceptable.
+	Revision int
+}
+
+// ValidationSet holds a validation-set assertion, which constrains
+// the presence and revisions of a set of snaps on a device.
+type ValidationSet struct {
+	assertionBase
+	seq       int
+	snaps     []*ValidationSetSnap
+	timestamp time.Time
+}
+
+// Series returns the series for which the validation-set holds.
+func (vs *ValidationSet) Series() string {
+	return vs.HeaderString("series")
+}
+
+// AccountID returns the identifier of the account that issued the
+// validation-set.
+func (vs *ValidationSet) AccountID() string {
+	return vs.HeaderString("account-id")
+}
+
+// Name returns the name of the validation-set.
+func (vs *ValidationSet) Name() string {
+	return vs.HeaderString("name")
+}
+
+// Sequence returns the sequence point of the validation-set.
+func (vs *ValidationSet) Sequence() int {
+	return vs.seq
+}
+
+// Snaps returns the snaps constrained by the validation-set.
+func (vs *ValidationSet) Snaps() []*ValidationSetSnap {
+	return vs.snaps
+}
+
+// Timestamp returns the time when the validation-set was issued.
+func (vs *ValidationSet) Timestamp() time.Time {
+	return vs.timestamp
+}
+
+// Implement further consistency checks.
+func (vs *ValidationSet) checkConsistency(db RODatabase, acck *AccountKey) error {
+	_, err := db.Find(AccountType, map[string]string{
+		"account-id": vs.AccountID(),
+	})
+	if IsNotFound(err) {
+		return fmt.Errorf("validation-set assertion for %q does not have a matching account assertion for %q", vs.Name(), vs.AccountID())
+	}
+	return err
+}
+
+// sanity
+var _ consistencyChecker = (*ValidationSet)(nil)
+
+// Prerequisites returns references to this validation-set's prerequisite assertions.
+func (vs *ValidationSet) Prerequisites() []*Ref {
+	return []*Ref{
+		{Type: AccountType, PrimaryKey: []string{vs.AccountID()}},
+	}
+}
+
+var validValidationSetName = regexp.MustCompile("^[a-z0-9](?:-?[a-z0-9])*$")
+
+// IsValidValidationSetName returns whether name is a valid name
+// for a validation-set.
+func IsValidValidationSetName(name string) bool {
+	return validValidationSetName.MatchString(name)
+}
+
+func checkValidationSetSnap(snap map[string]interface{}, i int) (*ValidationSetSnap, error) {
+	what := fmt.Sprintf(`of snap %d`, i+1)
+	name, err := checkNotEmptyStringWhat(snap, "name", what)
+	if err != nil {
+		return nil, err
+	}
+	if err := naming.ValidateSnap(name); err != nil {
+		return nil, fmt.Errorf("invalid snap name %q", name)
+	}
+
+	what = fmt.Sprintf(`of snap %q`, name)
+	snapID, err := checkStringMatchesWhat(snap, "id", what, validSnapID)
+	if err != nil {
+		return nil, err
+	}
+
+	presence := PresenceRequired
+	if v, ok := snap["presence"]; ok {
+		s, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf(`"presence" %s must be a string`, what)
+		}
+		presence = Presence(s)
+		switch presence {
+		case PresenceRequired, PresenceOptional, PresenceInvalid:
+		default:
+			return nil, fmt.Errorf(`"presence" %s must be one of required|optional|invalid`, what)
+		}
+	}
+
+	revision := 0
+	if v, ok := snap["revision"]; ok {
+		s, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf(`"revision" %s must be a string`, what)
+		}
+		revision, err = strconv.Atoi(s)
+		if err != nil || revision < 1 {
+			return nil, fmt.Errorf(`"revision" %s must be a positive integer: %v`, what, s)
+		}
+		if presence == PresenceInvalid {
+			return nil, fmt.Errorf(`cannot specify revision %s at the same time as stating its presence is invalid`, what)
+		}
+	}
+
+	return &ValidationSetSnap{
+		Name:     name,
+		SnapID:   snapID,
+		Presence: presence,
+		Revision: revision,
+	}, nil
+}
+
+func checkValidationSetSnaps(headers map[string]interface{}) ([]*ValidationSetSnap, error) {
+	value, ok := headers["snaps"]
+	if !ok {
+		return nil, fmt.Errorf(`"snaps" header is mandatory`)
+	}
+	entries, ok := value.([]interface{})
+	if !ok || len(entries) == 0 {
+		return nil, fmt.Errorf(`"snaps" header must be a non-empty list of snap maps`)
+	}
+
+	snaps := make([]*ValidationSetSnap, 0, len(entries))
+	seenNames := make(map[string]bool, len(entries))
+	seenIDs := make(map[string]bool, len(entries))
+	for i, entry := range entries {
+		snap, ok := entry.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf(`"snaps" header must be a non-empty list of snap maps`)
+		}
+		vsnap, err := checkValidationSetSnap(snap, i)
+		if err != nil {
+			return nil, err
+		}
+		if seenNames[vsnap.Name] {
+			return nil, fmt.Errorf("cannot list the same snap %q multiple times", vsnap.Name)
+		}
+		if seenIDs[vsnap.SnapID] {
+			return nil, fmt.Errorf("cannot specify the same snap id %q multiple times", vsnap.SnapID)
+		}
+		seenNames[vsnap.Name] = true
+		seenIDs[vsnap.SnapID] = true
+		snaps = append(snaps, vsnap)
+	}
+	return snaps, nil
+}
+
+func assembleValidationSet(assert assertionBase) (Assertion, error) {
+	authorityID := assert.AuthorityID()
+	accountID := assert.HeaderString("account-id")
+	if accountID != authorityID {
+		return nil, fmt.Errorf("authority-id and account-id must match, validation-set assertions are expected to be signed by the issuer account: %q != %q", authorityID, accountID)
+	}
+
+	if _, err := checkStringMatches(assert.headers, "name", validValidationSetName); err != nil {
+		return nil, err
+	}
+
+	seq, err := checkInt(assert.headers, "sequence")
+	if err != nil {
+		return nil, err
+	}
+	if seq < 1 {
+		return nil, fmt.Errorf(`"sequence" header must be >=1: %d`, seq)
+	}
+	// the primary key must be canonical
+	if strconv.Itoa(seq) != assert.HeaderString("sequence") {
+		return nil, fmt.Errorf(`"sequence" header must not have leading zeros: %s`, assert.HeaderString("sequence"))
+	}
+
+	snaps, err := checkValidationSetSnaps(assert.headers)
+	if err != nil {
+		return nil, err
+	}
+
+	timestamp, err := checkRFC3339Date(assert.headers, "timestamp")
+	if err != nil {
+		return nil, err
+	}
+
+	return &ValidationSet{
+		assertionBase: assert,
+		seq:           seq,
+		snaps:         snaps,
+		timestamp:     timestamp,
+	}, nil
+}
@@ -0,0 +1,146 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2019 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package asserts_test
+
+import (
+	"strings"
+	"time"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/asserts"
+)
+
+type validationSetSuite struct {
+	ts     time.Time
+	tsLine string
+}
+
+var _ = Suite(&validationSetSuite{})
+
+func (vss *validationSetSuite) SetUpSuite(c *C) {
+	vss.ts = time.Now().Truncate(time.Second).UTC()
+	vss.tsLine = "timestamp: " + vss.ts.Format(time.RFC3339) + "\n"
+}
+
+const validationSetExample = `type: validation-set
+authority-id: brand-id1
+series: 16
+account-id: brand-id1
+name: baz-3000-good
+sequence: 2
+snaps:
+  -
+    name: baz-linux
+    id: bazlinuxidididididididididididid
+    revision: 99
+  -
+    name: baz-app
+    id: bazappididididididididididididid
+    presence: optional
+  -
+    name: bad-app
+    id: badappididididididididididididid
+    presence: invalid
+TSLINEbody-length: 0
+sign-key-sha3-384: Jv8_JiHiIzJVcO9M55pPdqSDWUvuhfDIBJUS-3VW7F_idjix7Ffn5qMxB21ZQuij
+
+AXNpZw==`
+
+func (vss *validationSetSuite) TestDecodeOK(c *C) {
+	encoded := strings.Replace(validationSetExample, "TSLINE", vss.tsLine, 1)
+
+	a, err := asserts.Decode([]byte(encoded))
+	c.Assert(err, IsNil)
+	c.Check(a.Type(), Equals, asserts.ValidationSetType)
+	vs := a.(*asserts.ValidationSet)
+	c.Check(vs.AuthorityID(), Equals, "brand-id1")
+	c.Check(vs.Timestamp(), Equals, vss.ts)
+	c.Check(vs.Series(), Equals, "16")
+	c.Check(vs.AccountID(), Equals, "brand-id1")
+	c.Check(vs.Name(), Equals, "baz-3000-good")
+	c.Check(vs.Sequence(), Equals, 2)
+	c.Check(vs.Snaps(), DeepEquals, []*asserts.ValidationSetSnap{
+		{
+			Name:     "baz-linux",
+			SnapID:   "bazlinuxidididididididididididid",
+			Presence: asserts.PresenceRequired,
+			Revision: 99,
+		}, {
+			Name:     "baz-app",
+			SnapID:   "bazappididididididididididididid",
+			Presence: asserts.PresenceOptional,
+		}, {
+			Name:     "bad-app",
+			SnapID:   "badappididididididididididididid",
+			Presence: asserts.PresenceInvalid,
+		},
+	})
+	c.Check(vs.Prerequisites(), DeepEquals, []*asserts.Ref{
+		{Type: asserts.AccountType, PrimaryKey: []string{"brand-id1"}},
+	})
+}
+
+const (
+	validationSetErrPrefix = "assertion validation-set: "
+)
+
+func (vss *validationSetSuite) TestDecodeInvalid(c *C) {
+	encoded := strings.Replace(validationSetExample, "TSLINE", vss.tsLine, 1)
+
+	snapsStanza := encoded[strings.Index(encoded, "snaps:"):strings.Index(encoded, "timestamp:")]
+
+	invalidTests := []struct{ original, invalid, expectedErr string }{
+		{"series: 16\n", "", `"series" header is mandatory`},
+		{"account-id: brand-id1\n", "account-id: other\n", `authority-id and account-id must match, validation-set assertions are expected to be signed by the issuer account: "brand-id1" != "other"`},
+		{"name: baz-3000-good\n", "", `"name" header is mandatory`},
+		{"name: baz-3000-good\n", "name: baz_3000\n", `"name" primary key header cannot contain .*|"name" header contains invalid characters: "baz_3000"`},
+		{"sequence: 2\n", "", `"sequence" header is mandatory`},
+		{"sequence: 2\n", "sequence: x\n", `"sequence" header is not an integer: x`},
+		{"sequence: 2\n", "sequence: 0\n", `"sequence" header must be >=1: 0`},
+		{"sequence: 2\n", "sequence: 02\n", `"sequence" header must not have leading zeros: 02`},
+		{snapsStanza, "", `"snaps" header is mandatory`},
+		{snapsStanza, "snaps: foo\n", `"snaps" header must be a non-empty list of snap maps`},
+		{"name: baz-linux\n", "other: baz-linux\n", `"name" of snap 1 is mandatory`},
+		{"name: baz-linux\n", "name: baz_linux\n", `invalid snap name "baz_linux"`},
+		{"id: bazlinuxidididididididididididid\n", "other: bazlinuxidididididididididididid\n", `"id" of snap "baz-linux" is mandatory`},
+		{"id: bazlinuxidididididididididididid\n", "id: 2\n", `"id" of snap "baz-linux" contains invalid characters: "2"`},
+		{"revision: 99\n", "revision: 0\n", `"revision" of snap "baz-linux" must be a positive integer: 0`},
+		{"presence: optional\n", "presence: maybe\n", `"presence" of snap "baz-app" must be one of required|optional|invalid`},
+		{"presence: invalid\n", "presence: invalid\n    revision: 1\n", `cannot specify revision of snap "bad-app" at the same time as stating its presence is invalid`},
+		{"name: baz-app\n", "name: baz-linux\n", `cannot list the same snap "baz-linux" multiple times`},
+		{"id: bazappididididididididididididid\n", "id: bazlinuxidididididididididididid\n", `cannot specify the same snap id "bazlinuxidididididididididididid" multiple times`},
+		{vss.tsLine, "", `"timestamp" header is mandatory`},
+	}
+
+	for _, test := range invalidTests {
+		invalid := strings.Replace(encoded, test.original, test.invalid, 1)
+		_, err := asserts.Decode([]byte(invalid))
+		c.Check(err, ErrorMatches, validationSetErrPrefix+test.expectedErr)
+	}
+}
+
+func (vss *validationSetSuite) TestIsValidValidationSetName(c *C) {
+	c.Check(asserts.IsValidValidationSetName("baz-3000"), Equals, true)
+	c.Check(asserts.IsValidValidationSetName("a"), Equals, true)
+	c.Check(asserts.IsValidValidationSetName("Baz"), Equals, false)
+	c.Check(asserts.IsValidValidationSetName("-baz"), Equals, false)
+	c.Check(asserts.IsValidValidationSetName(""), Equals, false)
+}
@@ -0,0 +1,295 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2018 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package boot
+
+import (
+	"crypto"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	_ "golang.org/x/crypto/sha3" // expected for digests
+
+	"github.com/snapcore/snapd/bootloader"
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/logger"
+	"github.com/snapcore/snapd/osutil"
+	"github.com/snapcore/snapd/release"
+)
+
+// Kinds of the elements of a boot chain, in boot order.
+const (
+	BootChainBootloaderConfig = "bootloader-config"
+	BootChainKernelAsset      = "kernel-asset"
+	BootChainKernel           = "kernel"
+	BootChainBase             = "base"
+)
+
+// BootChainEntry describes an element of the boot chain and its digest.
+type BootChainEntry struct {
+	Kind string `json:"kind"`
+	// Name is the name of the element, e.g. the file name of the
+	// kernel snap.
+	Name string `json:"name"`
+	Path string `json:"path"`
+	// SHA3_384 is the URL-safe base64 encoded SHA3-384 digest of
+	// the element, as for snap digests.
+	SHA3_384 string `json:"sha3-384"`
+	Size     uint64 `json:"size"`
+}
+
+// BootChainLog is the measurement log of the boot chain used for a boot.
+type BootChainLog struct {
+	BootID     string           `json:"boot-id"`
+	Bootloader string           `json:"bootloader"`
+	Time       time.Time        `json:"time"`
+	Entries    []BootChainEntry `json:"entries"`
+}
+
+var osutilBootID = osutil.BootID
+
+// BootChainLogFile returns the path of the boot chain measurement log of
+// the current boot. It lives under /run so that it does not survive the
+// boot it describes.
+func BootChainLogFile() string {
+	return filepath.Join(dirs.SnapRunDir, "boot-chain.json")
+}
+
+// bootChainDigestsFile returns the path of the cache of the digests of
+// the kernel and base snaps measured so far. Snap files are not modified
+// once installed, so their digest is only computed once per revision.
+func bootChainDigestsFile() string {
+	return filepath.Join(dirs.SnapCacheDir, "boot-chain-digests.json")
+}
+
+type cachedDigest struct {
+	SHA3_384 string    `json:"sha3-384"`
+	Size     uint64    `json:"size"`
+	ModTime  time.Time `json:"mtime"`
+}
+
+func readDigestsCache() map[string]cachedDigest {
+	cache := make(map[string]cachedDigest)
+	data, err := ioutil.ReadFile(bootChainDigestsFile())
+	if err != nil {
+		return cache
+	}
+	// a broken cache is only a missed optimization
+	json.Unmarshal(data, &cache)
+	return cache
+}
+
+func writeDigestsCache(cache map[string]cachedDigest) error {
+	data, err := json.Marshal(cache)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dirs.SnapCacheDir, 0755); err != nil {
+		return err
+	}
+	return osutil.AtomicWriteFile(bootChainDigestsFile(), data, 0644, 0)
+}
+
+func measure(kind, name, path string, cache map[string]cachedDigest) (BootChainEntry, error) {
+	entry := BootChainEntry{
+		Kind: kind,
+		Name: name,
+		Path: path,
+	}
+	var fi os.FileInfo
+	if cache != nil {
+		var err error
+		fi, err = os.Stat(path)
+		if err != nil {
+			return BootChainEntry{}, fmt.Errorf("cannot measure %s %q: %v", kind, name, err)
+		}
+		// local revisions can be reused, the size and modification
+		// time tell them apart
+		if cached, ok := cache[name]; ok && cached.Size == uint64(fi.Size()) && cached.ModTime.Equal(fi.ModTime()) {
+			entry.SHA3_384 = cached.SHA3_384
+			entry.Size = cached.Size
+			return entry, nil
+		}
+	}
+	digest, size, err := osutil.FileDigest(path, crypto.SHA3_384)
+	if err != nil {
+		return BootChainEntry{}, fmt.Errorf("cannot measure %s %q: %v", kind, name, err)
+	}
+	entry.SHA3_384 = base64.RawURLEncoding.EncodeToString(digest)
+	entry.Size = size
+	if cache != nil {
+		cache[name] = cachedDigest{
+			SHA3_384: entry.SHA3_384,
+			Size:     size,
+			ModTime:  fi.ModTime(),
+		}
+	}
+	return entry, nil
+}
+
+// bootedSnaps returns the file names of the kernel and base snaps the
+// system was booted with. They are taken from the kernel command line,
+// on which the bootloader passes them, or from the bootloader
+// environment for bootloaders which do not, where they are the booted
+// ones once the boot was marked successful.
+func bootedSnaps(loader bootloader.Bootloader) (kernel, base string, err error) {
+	if cmdline, err := ioutil.ReadFile(filepath.Join(dirs.GlobalRootDir, "/proc/cmdline")); err == nil {
+		for _, param := range strings.Fields(string(cmdline)) {
+			if strings.HasPrefix(param, "snap_kernel=") {
+				kernel = strings.TrimPrefix(param, "snap_kernel=")
+			}
+			if strings.HasPrefix(param, "snap_core=") {
+				base = strings.TrimPrefix(param, "snap_core=")
+			}
+		}
+		if kernel != "" && base != "" {
+			return kernel, base, nil
+		}
+	}
+	m, err := loader.GetBootVars("snap_kernel", "snap_core")
+	if err != nil {
+		return "", "", err
+	}
+	return m["snap_kernel"], m["snap_core"], nil
+}
+
+// MeasureBootChain computes the measurement log of the boot chain the
+// system was booted with: the bootloader configuration, the kernel
+// assets it loads, the kernel snap and the base snap.
+func MeasureBootChain() (*BootChainLog, error) {
+	if release.OnClassic {
+		return nil, fmt.Errorf("cannot measure the boot chain on classic systems")
+	}
+
+	loader, err := bootloader.Find()
+	if err != nil {
+		return nil, fmt.Errorf("cannot measure the boot chain: %s", err)
+	}
+	kernel, base, err := bootedSnaps(loader)
+	if err != nil {
+		return nil, err
+	}
+	if kernel == "" || base == "" {
+		return nil, fmt.Errorf("cannot measure the boot chain: kernel or base unset in the bootloader")
+	}
+
+	bootID, err := osutilBootID()
+	if err != nil {
+		return nil, err
+	}
+	log := &BootChainLog{
+		BootID:     bootID,
+		Bootloader: loader.Name(),
+		Time:       time.Now(),
+	}
+
+	cache := readDigestsCache()
+	add := func(kind, name, path string, digests map[string]cachedDigest) error {
+		entry, err := measure(kind, name, path, digests)
+		if err != nil {
+			return err
+		}
+		log.Entries = append(log.Entries, entry)
+		return nil
+	}
+
+	configFile := loader.ConfigFile()
+	if osutil.FileExists(configFile) {
+		if err := add(BootChainBootloaderConfig, filepath.Base(configFile), configFile, nil); err != nil {
+			return nil, err
+		}
+	}
+	// bootloaders that cannot read snaps load the kernel assets
+	// extracted next to their configuration
+	assetsDir := filepath.Join(filepath.Dir(configFile), kernel)
+	assets, err := filepath.Glob(filepath.Join(assetsDir, "*"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(assets)
+	for _, asset := range assets {
+		if !osutil.IsDirectory(asset) {
+			if err := add(BootChainKernelAsset, filepath.Base(asset), asset, nil); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if err := add(BootChainKernel, kernel, filepath.Join(dirs.SnapBlobDir, kernel), cache); err != nil {
+		return nil, err
+	}
+	if err := add(BootChainBase, base, filepath.Join(dirs.SnapBlobDir, base), cache); err != nil {
+		return nil, err
+	}
+	// only the digests of the snaps in use are worth keeping
+	cache = map[string]cachedDigest{
+		kernel: cache[kernel],
+		base:   cache[base],
+	}
+	if err := writeDigestsCache(cache); err != nil {
+		logger.Noticef("cannot cache the boot chain digests: %v", err)
+	}
+
+	return log, nil
+}
+
+// ReadBootChainLog returns the boot chain measurement log recorded for
+// the current boot.
+func ReadBootChainLog() (*BootChainLog, error) {
+	data, err := ioutil.ReadFile(BootChainLogFile())
+	if err != nil {
+		return nil, err
+	}
+	var log BootChainLog
+	if err := json.Unmarshal(data, &log); err != nil {
+		return nil, fmt.Errorf("cannot decode boot chain log: %v", err)
+	}
+	return &log, nil
+}
+
+// RecordBootChain measures the boot chain and records it as the log of
+// the current boot, unless a log was already recorded for it.
+func RecordBootChain() error {
+	bootID, err := osutilBootID()
+	if err != nil {
+		return err
+	}
+	if log, err := ReadBootChainLog(); err == nil && log.BootID == bootID {
+		return nil
+	}
+
+	log, err := MeasureBootChain()
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(log)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dirs.SnapRunDir, 0755); err != nil {
+		return err
+	}
+	return osutil.AtomicWriteFile(BootChainLogFile(), data, 0644, 0)
+}
@@ -0,0 +1,192 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2018 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package boot_test
+
+import (
+	"crypto"
+	"encoding/base64"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/boot"
+	"github.com/snapcore/snapd/boot/boottest"
+	"github.com/snapcore/snapd/bootloader"
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/osutil"
+	"github.com/snapcore/snapd/release"
+	"github.com/snapcore/snapd/testutil"
+)
+
+type bootChainSuite struct {
+	baseKernelOSSuite
+
+	loader *boottest.MockBootloader
+	bootID string
+}
+
+var _ = Suite(&bootChainSuite{})
+
+func (s *bootChainSuite) SetUpTest(c *C) {
+	s.baseKernelOSSuite.SetUpTest(c)
+
+	s.loader = boottest.NewMockBootloader("mock", s.bootdir)
+	bootloader.Force(s.loader)
+	s.AddCleanup(func() { bootloader.Force(nil) })
+
+	s.bootID = "boot-id-1"
+	s.AddCleanup(boot.MockBootID(func() (string, error) {
+		return s.bootID, nil
+	}))
+
+	s.loader.BootVars["snap_kernel"] = "pc-kernel_1.snap"
+	s.loader.BootVars["snap_core"] = "core18_2.snap"
+
+	s.mockFile(c, filepath.Join(dirs.SnapBlobDir, "pc-kernel_1.snap"), "kernel")
+	s.mockFile(c, filepath.Join(dirs.SnapBlobDir, "core18_2.snap"), "base")
+}
+
+func (s *bootChainSuite) mockFile(c *C, path, content string) {
+	c.Assert(os.MkdirAll(filepath.Dir(path), 0755), IsNil)
+	c.Assert(ioutil.WriteFile(path, []byte(content), 0644), IsNil)
+}
+
+func (s *bootChainSuite) digest(c *C, path string) string {
+	digest, _, err := osutil.FileDigest(path, crypto.SHA3_384)
+	c.Assert(err, IsNil)
+	return base64.RawURLEncoding.EncodeToString(digest)
+}
+
+func (s *bootChainSuite) TestMeasureBootChain(c *C) {
+	configFile := s.loader.ConfigFile()
+	assetsDir := filepath.Join(filepath.Dir(configFile), "pc-kernel_1.snap")
+	s.mockFile(c, configFile, "config")
+	s.mockFile(c, filepath.Join(assetsDir, "kernel.img"), "kernel.img")
+	s.mockFile(c, filepath.Join(assetsDir, "initrd.img"), "initrd.img")
+	c.Assert(os.MkdirAll(filepath.Join(assetsDir, "dtbs"), 0755), IsNil)
+
+	log, err := boot.MeasureBootChain()
+	c.Assert(err, IsNil)
+	c.Check(log.BootID, Equals, "boot-id-1")
+	c.Check(log.Bootloader, Equals, "mock")
+	c.Check(log.Time.IsZero(), Equals, false)
+
+	kernel := filepath.Join(dirs.SnapBlobDir, "pc-kernel_1.snap")
+	base := filepath.Join(dirs.SnapBlobDir, "core18_2.snap")
+	c.Check(log.Entries, DeepEquals, []boot.BootChainEntry{
+		{Kind: boot.BootChainBootloaderConfig, Name: "mockboot.cfg", Path: configFile, SHA3_384: s.digest(c, configFile), Size: 6},
+		{Kind: boot.BootChainKernelAsset, Name: "initrd.img", Path: filepath.Join(assetsDir, "initrd.img"), SHA3_384: s.digest(c, filepath.Join(assetsDir, "initrd.img")), Size: 10},
+		{Kind: boot.BootChainKernelAsset, Name: "kernel.img", Path: filepath.Join(assetsDir, "kernel.img"), SHA3_384: s.digest(c, filepath.Join(assetsDir, "kernel.img")), Size: 10},
+		{Kind: boot.BootChainKernel, Name: "pc-kernel_1.snap", Path: kernel, SHA3_384: s.digest(c, kernel), Size: 6},
+		{Kind: boot.BootChainBase, Name: "core18_2.snap", Path: base, SHA3_384: s.digest(c, base), Size: 4},
+	})
+}
+
+func (s *bootChainSuite) TestMeasureBootChainNoConfigNoAssets(c *C) {
+	log, err := boot.MeasureBootChain()
+	c.Assert(err, IsNil)
+	c.Assert(log.Entries, HasLen, 2)
+	c.Check(log.Entries[0].Kind, Equals, boot.BootChainKernel)
+	c.Check(log.Entries[1].Kind, Equals, boot.BootChainBase)
+}
+
+func (s *bootChainSuite) TestMeasureBootChainErrors(c *C) {
+	s.loader.BootVars["snap_core"] = ""
+	_, err := boot.MeasureBootChain()
+	c.Check(err, ErrorMatches, "cannot measure the boot chain: kernel or base unset in the bootloader")
+
+	s.loader.BootVars["snap_core"] = "core18_3.snap"
+	_, err = boot.MeasureBootChain()
+	c.Check(err, ErrorMatches, `cannot measure base "core18_3.snap": .*`)
+
+	restore := release.MockOnClassic(true)
+	defer restore()
+	_, err = boot.MeasureBootChain()
+	c.Check(err, ErrorMatches, "cannot measure the boot chain on classic systems")
+}
+
+func (s *bootChainSuite) TestRecordBootChain(c *C) {
+	_, err := boot.ReadBootChainLog()
+	c.Check(os.IsNotExist(err), Equals, true)
+
+	c.Assert(boot.RecordBootChain(), IsNil)
+	log, err := boot.ReadBootChainLog()
+	c.Assert(err, IsNil)
+	c.Check(log.BootID, Equals, "boot-id-1")
+	c.Assert(log.Entries, HasLen, 2)
+	c.Check(log.Entries[0].Name, Equals, "pc-kernel_1.snap")
+
+	// the log of the current boot is not recorded again
+	s.loader.BootVars["snap_kernel"] = "pc-kernel_2.snap"
+	c.Assert(boot.RecordBootChain(), IsNil)
+	log, err = boot.ReadBootChainLog()
+	c.Assert(err, IsNil)
+	c.Check(log.Entries[0].Name, Equals, "pc-kernel_1.snap")
+
+	// but it is for a new boot
+	s.bootID = "boot-id-2"
+	s.mockFile(c, filepath.Join(dirs.SnapBlobDir, "pc-kernel_2.snap"), "kernel")
+	c.Assert(boot.RecordBootChain(), IsNil)
+	log, err = boot.ReadBootChainLog()
+	c.Assert(err, IsNil)
+	c.Check(log.BootID, Equals, "boot-id-2")
+	c.Check(log.Entries[0].Name, Equals, "pc-kernel_2.snap")
+}
+
+func (s *bootChainSuite) TestMeasureBootChainFromKernelCommandLine(c *C) {
+	// the bootloader environment may already point to a new revision,
+	// what was booted is what the bootloader passed to the kernel
+	s.mockFile(c, filepath.Join(dirs.GlobalRootDir, "/proc/cmdline"), "console=ttyS0 snap_core=core18_1.snap snap_kernel=pc-kernel_3.snap quiet\n")
+	s.mockFile(c, filepath.Join(dirs.SnapBlobDir, "pc-kernel_3.snap"), "kernel 3")
+	s.mockFile(c, filepath.Join(dirs.SnapBlobDir, "core18_1.snap"), "base 1")
+
+	log, err := boot.MeasureBootChain()
+	c.Assert(err, IsNil)
+	c.Assert(log.Entries, HasLen, 2)
+	c.Check(log.Entries[0].Name, Equals, "pc-kernel_3.snap")
+	c.Check(log.Entries[1].Name, Equals, "core18_1.snap")
+}
+
+func (s *bootChainSuite) TestMeasureBootChainCachesSnapDigests(c *C) {
+	kernel := filepath.Join(dirs.SnapBlobDir, "pc-kernel_1.snap")
+
+	log, err := boot.MeasureBootChain()
+	c.Assert(err, IsNil)
+	c.Check(log.Entries[0].SHA3_384, Equals, s.digest(c, kernel))
+	c.Check(filepath.Join(dirs.SnapCacheDir, "boot-chain-digests.json"), testutil.FilePresent)
+
+	// the snap file is not read again for the same revision
+	fi, err := os.Stat(kernel)
+	c.Assert(err, IsNil)
+	s.mockFile(c, kernel, "KERNEL")
+	c.Assert(os.Chtimes(kernel, fi.ModTime(), fi.ModTime()), IsNil)
+	cached, err := boot.MeasureBootChain()
+	c.Assert(err, IsNil)
+	c.Check(cached.Entries, DeepEquals, log.Entries)
+
+	// but it is if the file changed
+	s.mockFile(c, kernel, "other kernel")
+	log, err = boot.MeasureBootChain()
+	c.Assert(err, IsNil)
+	c.Check(log.Entries[0].SHA3_384, Equals, s.digest(c, kernel))
+	c.Check(log.Entries[0].Size, Equals, uint64(12))
+}
@@ -0,0 +1,244 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2019 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package boot
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/snapcore/snapd/osutil"
+)
+
+// ModeenvVersion is the version of the modeenv format written by Write.
+// Modeenv files of older versions are migrated when read, newer versions
+// can be read but are not written back. Keys added in a compatible way
+// do not need a new version, unknown keys are preserved by Write.
+const ModeenvVersion = 2
+
+// Modeenv holds the information about the current boot mode of the
+// system that is shared between snap-bootstrap and snapd, stored in
+// /var/lib/snapd/modeenv.
+type Modeenv struct {
+	// Version is the version of the format the modeenv was read as.
+	Version int
+	// Mode is the mode the system is in, i.e. install, run or recover.
+	Mode string
+	// RecoverySystem is the label of the recovery system in use.
+	RecoverySystem string
+	// Base is the current base snap file name.
+	Base string
+	// TryBase is the base snap file name being tried, if any.
+	TryBase string
+	// BaseStatus is the status of trying TryBase, i.e. "", try or trying.
+	BaseStatus string
+	// CurrentKernels lists the kernel snap file names that are
+	// considered good to boot.
+	CurrentKernels []string
+	// BrandID and Model identify the model the system was installed
+	// with.
+	BrandID string
+	Model   string
+	// Grade is the grade of that model.
+	Grade string
+
+	// unknown holds keys from a newer format that are preserved when
+	// writing back.
+	unknown map[string]string
+}
+
+// modeenvLockFile is the lock that serializes access to the modeenv.
+func modeenvLockFile(rootdir string) string {
+	return ModeenvFile(rootdir) + ".lock"
+}
+
+// ModeenvFile returns the path of the modeenv under the given root
+// directory.
+func ModeenvFile(rootdir string) string {
+	return filepath.Join(rootdir, "/var/lib/snapd/modeenv")
+}
+
+// LockModeenv takes the lock on the modeenv under the given root
+// directory, blocking until it is available. It returns a function that
+// releases the lock. Readers and writers that need to perform
+// read-modify-write cycles must hold it.
+func LockModeenv(rootdir string) (unlock func(), err error) {
+	lockFile := modeenvLockFile(rootdir)
+	if err := os.MkdirAll(filepath.Dir(lockFile), 0755); err != nil {
+		return nil, err
+	}
+	flock, err := osutil.NewFileLock(lockFile)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open modeenv lock: %v", err)
+	}
+	if err := flock.Lock(); err != nil {
+		flock.Close()
+		return nil, fmt.Errorf("cannot lock modeenv: %v", err)
+	}
+	return func() { flock.Close() }, nil
+}
+
+// ReadModeenv reads and parses the modeenv under the given root
+// directory, migrating it to the current format if needed.
+func ReadModeenv(rootdir string) (*Modeenv, error) {
+	f, err := os.Open(ModeenvFile(rootdir))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	m := &Modeenv{Version: 1}
+	values := make(map[string]string)
+	scanner := bufio.NewScanner(f)
+	for lineno := 1; scanner.Scan(); lineno++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		l := strings.SplitN(line, "=", 2)
+		if len(l) != 2 {
+			return nil, fmt.Errorf("cannot parse modeenv line %d: %q", lineno, line)
+		}
+		values[strings.TrimSpace(l[0])] = strings.TrimSpace(l[1])
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("cannot read modeenv: %v", err)
+	}
+
+	if v, ok := values["version"]; ok {
+		m.Version, err = strconv.Atoi(v)
+		if err != nil || m.Version < 1 {
+			return nil, fmt.Errorf("cannot parse modeenv version %q", v)
+		}
+		delete(values, "version")
+	}
+
+	if m.Version == 1 {
+		// version 1 only knew about a single kernel
+		if kernel := values["kernel"]; kernel != "" {
+			values["current_kernels"] = kernel
+		}
+		delete(values, "kernel")
+	}
+
+	for key, value := range values {
+		switch key {
+		case "mode":
+			m.Mode = value
+		case "recovery_system":
+			m.RecoverySystem = value
+		case "base":
+			m.Base = value
+		case "try_base":
+			m.TryBase = value
+		case "base_status":
+			m.BaseStatus = value
+		case "current_kernels":
+			m.CurrentKernels = splitModeenvList(value)
+		case "model":
+			l := strings.SplitN(value, "/", 2)
+			if len(l) != 2 {
+				return nil, fmt.Errorf("cannot parse modeenv model %q", value)
+			}
+			m.BrandID, m.Model = l[0], l[1]
+		case "grade":
+			m.Grade = value
+		default:
+			if m.unknown == nil {
+				m.unknown = make(map[string]string)
+			}
+			m.unknown[key] = value
+		}
+	}
+
+	if m.Mode == "" {
+		return nil, fmt.Errorf("internal error: mode is unset")
+	}
+
+	return m, nil
+}
+
+func splitModeenvList(value string) []string {
+	var l []string
+	for _, e := range strings.Split(value, ",") {
+		if e = strings.TrimSpace(e); e != "" {
+			l = append(l, e)
+		}
+	}
+	return l
+}
+
+// Write atomically writes the modeenv under the given root directory in
+// the current format. A modeenv read from a newer format is not written,
+// as that would downgrade it.
+func (m *Modeenv) Write(rootdir string) error {
+	if m.Version > ModeenvVersion {
+		return fmt.Errorf("cannot write modeenv of version %d, only version %d is supported", m.Version, ModeenvVersion)
+	}
+	if m.Mode == "" {
+		return fmt.Errorf("internal error: mode is unset")
+	}
+	if (m.BrandID == "") != (m.Model == "") {
+		return fmt.Errorf("internal error: model and brand must be set together")
+	}
+
+	modeenvPath := ModeenvFile(rootdir)
+	if err := os.MkdirAll(filepath.Dir(modeenvPath), 0755); err != nil {
+		return err
+	}
+
+	buf := bytes.NewBuffer(nil)
+	fmt.Fprintf(buf, "version=%d\n", ModeenvVersion)
+	writeKey := func(key, value string) {
+		if value != "" {
+			fmt.Fprintf(buf, "%s=%s\n", key, value)
+		}
+	}
+	writeKey("mode", m.Mode)
+	writeKey("recovery_system", m.RecoverySystem)
+	writeKey("base", m.Base)
+	writeKey("try_base", m.TryBase)
+	writeKey("base_status", m.BaseStatus)
+	writeKey("current_kernels", strings.Join(m.CurrentKernels, ","))
+	if m.BrandID != "" {
+		writeKey("model", m.BrandID+"/"+m.Model)
+	}
+	writeKey("grade", m.Grade)
+
+	unknown := make([]string, 0, len(m.unknown))
+	for key := range m.unknown {
+		unknown = append(unknown, key)
+	}
+	sort.Strings(unknown)
+	for _, key := range unknown {
+		writeKey(key, m.unknown[key])
+	}
+
+	if err := osutil.AtomicWriteFile(modeenvPath, buf.Bytes(), 0644, 0); err != nil {
+		return err
+	}
+	m.Version = ModeenvVersion
+	return nil
+}
@@ -0,0 +1,188 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2019 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package boot_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/boot"
+	"github.com/snapcore/snapd/testutil"
+)
+
+type modeenvSuite struct {
+	tmpdir string
+}
+
+var _ = Suite(&modeenvSuite{})
+
+func (s *modeenvSuite) SetUpTest(c *C) {
+	s.tmpdir = c.MkDir()
+	c.Assert(os.MkdirAll(filepath.Join(s.tmpdir, "/var/lib/snapd"), 0755), IsNil)
+}
+
+func (s *modeenvSuite) writeModeenv(c *C, content string) {
+	c.Assert(ioutil.WriteFile(boot.ModeenvFile(s.tmpdir), []byte(content), 0644), IsNil)
+}
+
+func (s *modeenvSuite) TestReadMissing(c *C) {
+	_, err := boot.ReadModeenv(c.MkDir())
+	c.Check(os.IsNotExist(err), Equals, true)
+}
+
+func (s *modeenvSuite) TestReadMigratesVersion1(c *C) {
+	s.writeModeenv(c, `# no version means version 1
+mode=run
+recovery_system=20191118
+base=core20_1.snap
+kernel=pc-kernel_3.snap
+`)
+	m, err := boot.ReadModeenv(s.tmpdir)
+	c.Assert(err, IsNil)
+	c.Check(m.Version, Equals, 1)
+	c.Check(m.Mode, Equals, "run")
+	c.Check(m.RecoverySystem, Equals, "20191118")
+	c.Check(m.Base, Equals, "core20_1.snap")
+	c.Check(m.CurrentKernels, DeepEquals, []string{"pc-kernel_3.snap"})
+
+	c.Assert(m.Write(s.tmpdir), IsNil)
+	c.Check(m.Version, Equals, boot.ModeenvVersion)
+	c.Check(boot.ModeenvFile(s.tmpdir), testutil.FileEquals, `version=2
+mode=run
+recovery_system=20191118
+base=core20_1.snap
+current_kernels=pc-kernel_3.snap
+`)
+}
+
+func (s *modeenvSuite) TestReadCurrent(c *C) {
+	s.writeModeenv(c, `version=2
+mode=run
+base=core20_1.snap
+try_base=core20_2.snap
+base_status=try
+current_kernels=pc-kernel_3.snap, pc-kernel_4.snap
+model=canonical/ubuntu-core-20-amd64
+grade=dangerous
+`)
+	m, err := boot.ReadModeenv(s.tmpdir)
+	c.Assert(err, IsNil)
+	c.Check(m, DeepEquals, &boot.Modeenv{
+		Version:        2,
+		Mode:           "run",
+		Base:           "core20_1.snap",
+		TryBase:        "core20_2.snap",
+		BaseStatus:     "try",
+		CurrentKernels: []string{"pc-kernel_3.snap", "pc-kernel_4.snap"},
+		BrandID:        "canonical",
+		Model:          "ubuntu-core-20-amd64",
+		Grade:          "dangerous",
+	})
+}
+
+func (s *modeenvSuite) TestRoundtripPreservesUnknownKeys(c *C) {
+	s.writeModeenv(c, `version=2
+mode=recover
+some_new_key=value
+`)
+	m, err := boot.ReadModeenv(s.tmpdir)
+	c.Assert(err, IsNil)
+	c.Check(m.Version, Equals, 2)
+	c.Check(m.Mode, Equals, "recover")
+
+	m.RecoverySystem = "20191118"
+	c.Assert(m.Write(s.tmpdir), IsNil)
+	c.Check(boot.ModeenvFile(s.tmpdir), testutil.FileEquals, `version=2
+mode=recover
+recovery_system=20191118
+some_new_key=value
+`)
+}
+
+func (s *modeenvSuite) TestWriteRefusesNewerVersion(c *C) {
+	content := `version=3
+mode=recover
+some_new_key=value
+`
+	s.writeModeenv(c, content)
+	m, err := boot.ReadModeenv(s.tmpdir)
+	c.Assert(err, IsNil)
+	c.Check(m.Version, Equals, 3)
+	c.Check(m.Mode, Equals, "recover")
+
+	m.RecoverySystem = "20191118"
+	c.Assert(m.Write(s.tmpdir), ErrorMatches, "cannot write modeenv of version 3, only version 2 is supported")
+	// the modeenv is left untouched
+	c.Check(boot.ModeenvFile(s.tmpdir), testutil.FileEquals, content)
+}
+
+func (s *modeenvSuite) TestReadErrors(c *C) {
+	for _, t := range []struct {
+		content, err string
+	}{
+		{"mode\n", `cannot parse modeenv line 1: "mode"`},
+		{"version=x\nmode=run\n", `cannot parse modeenv version "x"`},
+		{"version=0\nmode=run\n", `cannot parse modeenv version "0"`},
+		{"mode=run\nmodel=foo\n", `cannot parse modeenv model "foo"`},
+		{"base=core20_1.snap\n", `internal error: mode is unset`},
+	} {
+		s.writeModeenv(c, t.content)
+		_, err := boot.ReadModeenv(s.tmpdir)
+		c.Check(err, ErrorMatches, t.err, Commentf(t.content))
+	}
+}
+
+func (s *modeenvSuite) TestWriteErrors(c *C) {
+	m := &boot.Modeenv{}
+	c.Check(m.Write(s.tmpdir), ErrorMatches, "internal error: mode is unset")
+	m = &boot.Modeenv{Mode: "run", Model: "pc"}
+	c.Check(m.Write(s.tmpdir), ErrorMatches, "internal error: model and brand must be set together")
+}
+
+func (s *modeenvSuite) TestLock(c *C) {
+	rootdir := c.MkDir()
+	unlock, err := boot.LockModeenv(rootdir)
+	c.Assert(err, IsNil)
+
+	locked := make(chan struct{})
+	go func() {
+		unlock, err := boot.LockModeenv(rootdir)
+		c.Check(err, IsNil)
+		close(locked)
+		unlock()
+	}()
+
+	select {
+	case <-locked:
+		c.Fatal("modeenv lock taken twice")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	unlock()
+	select {
+	case <-locked:
+	case <-time.After(5 * time.Second):
+		c.Fatal("modeenv lock not released")
+	}
+}
@@ -0,0 +1,54 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2019 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// GadgetAction is a maintenance action of the device, such as
+// blinking a LED, declared and implemented by its gadget.
+type GadgetAction struct {
+	Name    string `json:"name"`
+	Summary string `json:"summary,omitempty"`
+}
+
+// GadgetActions lists the maintenance actions of the device.
+func (client *Client) GadgetActions() ([]GadgetAction, error) {
+	var actions []GadgetAction
+	if _, err := client.doSync("GET", "/v2/accessories", nil, nil, nil, &actions); err != nil {
+		return nil, fmt.Errorf("cannot list gadget actions: %v", err)
+	}
+	return actions, nil
+}
+
+// RunGadgetAction runs the given maintenance action of the device.
+func (client *Client) RunGadgetAction(name string) (changeID string, err error) {
+	data, err := json.Marshal(map[string]string{
+		"action": "run",
+		"name":   name,
+	})
+	if err != nil {
+		return "", fmt.Errorf("cannot marshal gadget action: %v", err)
+	}
+	return client.doAsync("POST", "/v2/accessories", nil, nil, bytes.NewReader(data))
+}
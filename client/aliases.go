@@ -22,6 +22,8 @@ package client
 import (
 	"bytes"
 	"encoding/json"
+	"net/url"
+	"strings"
 )
 
 // aliasAction represents an action performed on aliases.
@@ -100,3 +102,27 @@ func (client *Client) Aliases() (allStatuses map[string]map[string]AliasStatus,
 	_, err = client.doSync("GET", "/v2/aliases", nil, nil, nil, &allStatuses)
 	return
 }
+
+// AliasesPreview describes the changes to the automatic aliases of a
+// snap that a refresh would make.
+type AliasesPreview struct {
+	Added     []string            `json:"added,omitempty"`
+	Removed   []string            `json:"removed,omitempty"`
+	Conflicts map[string][]string `json:"conflicts,omitempty"`
+	// Policy is the aliases.conflict-policy that would resolve the
+	// conflicts, if any.
+	Policy string `json:"policy,omitempty"`
+}
+
+// AliasesRefreshPreview returns a map snap -> AliasesPreview with the
+// changes to automatic aliases a refresh of the given snaps (or all if
+// none are given) would make, without changing anything.
+func (client *Client) AliasesRefreshPreview(snapNames []string) (preview map[string]*AliasesPreview, err error) {
+	q := url.Values{}
+	q.Set("select", "refresh")
+	if len(snapNames) > 0 {
+		q.Set("names", strings.Join(snapNames, ","))
+	}
+	_, err = client.doSync("GET", "/v2/aliases", q, nil, nil, &preview)
+	return
+}
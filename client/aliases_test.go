@@ -21,6 +21,7 @@ package client_test
 
 import (
 	"encoding/json"
+	"net/url"
 
 	"gopkg.in/check.v1"
 
@@ -193,3 +194,33 @@ func (cs *clientSuite) TestClientAliases(c *check.C) {
 		},
 	})
 }
+
+func (cs *clientSuite) TestClientAliasesRefreshPreview(c *check.C) {
+	cs.rsp = `{
+		"type": "sync",
+		"result": {
+                    "foo": {
+                        "added": ["foo0", "foo1"],
+                        "removed": ["foo2"],
+                        "conflicts": {"bar": ["foo1"]},
+                        "policy": "prefer-new"
+                    }
+		}
+	}`
+	preview, err := cs.cli.AliasesRefreshPreview([]string{"foo", "baz"})
+	c.Assert(err, check.IsNil)
+	c.Check(cs.req.Method, check.Equals, "GET")
+	c.Check(cs.req.URL.Path, check.Equals, "/v2/aliases")
+	c.Check(cs.req.URL.Query(), check.DeepEquals, url.Values{
+		"select": []string{"refresh"},
+		"names":  []string{"foo,baz"},
+	})
+	c.Check(preview, check.DeepEquals, map[string]*client.AliasesPreview{
+		"foo": {
+			Added:     []string{"foo0", "foo1"},
+			Removed:   []string{"foo2"},
+			Conflicts: map[string][]string{"bar": {"foo1"}},
+			Policy:    "prefer-new",
+		},
+	})
+}
@@ -21,6 +21,7 @@ package client
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
 	"io"
 	"net/url"
@@ -42,6 +43,35 @@ func (client *Client) Ack(b []byte) error {
 	return nil
 }
 
+// AssertionsAction is used to request an action on the system
+// assertion database.
+type AssertionsAction struct {
+	Action       string `json:"action"`
+	SnapID       string `json:"snap-id,omitempty"`
+	SnapSHA3_384 string `json:"snap-sha3-384,omitempty"`
+}
+
+// AckFromStore fetches from the store and adds to the system assertion
+// database the assertions for the snap with the given snap-id or, if
+// snapSHA3_384 is set instead, for the snap file with that digest.
+func (client *Client) AckFromStore(snapID, snapSHA3_384 string) error {
+	data, err := json.Marshal(&AssertionsAction{
+		Action:       "fetch",
+		SnapID:       snapID,
+		SnapSHA3_384: snapSHA3_384,
+	})
+	if err != nil {
+		return fmt.Errorf("cannot marshal assertions action: %v", err)
+	}
+	headers := map[string]string{
+		"Content-Type": "application/json",
+	}
+	if _, err := client.doSync("POST", "/v2/assertions", nil, headers, bytes.NewReader(data), nil); err != nil {
+		return err
+	}
+	return nil
+}
+
 // AssertionTypes returns a list of assertion type names.
 func (client *Client) AssertionTypes() ([]string, error) {
 	var types struct {
@@ -20,6 +20,7 @@
 package client_test
 
 import (
+	"encoding/json"
 	"errors"
 	"io/ioutil"
 	"net/http"
@@ -45,6 +46,33 @@ func (cs *clientSuite) TestClientAssert(c *C) {
 	c.Check(cs.req.URL.Path, Equals, "/v2/assertions")
 }
 
+func (cs *clientSuite) TestClientAckFromStore(c *C) {
+	cs.rsp = `{
+		"type": "sync",
+		"result": null
+	}`
+	err := cs.cli.AckFromStore("snap-id-1", "")
+	c.Assert(err, IsNil)
+	c.Check(cs.req.Method, Equals, "POST")
+	c.Check(cs.req.URL.Path, Equals, "/v2/assertions")
+	c.Check(cs.req.Header.Get("Content-Type"), Equals, "application/json")
+	var body map[string]interface{}
+	c.Assert(json.NewDecoder(cs.req.Body).Decode(&body), IsNil)
+	c.Check(body, DeepEquals, map[string]interface{}{
+		"action":  "fetch",
+		"snap-id": "snap-id-1",
+	})
+
+	err = cs.cli.AckFromStore("", "digest")
+	c.Assert(err, IsNil)
+	body = nil
+	c.Assert(json.NewDecoder(cs.req.Body).Decode(&body), IsNil)
+	c.Check(body, DeepEquals, map[string]interface{}{
+		"action":        "fetch",
+		"snap-sha3-384": "digest",
+	})
+}
+
 func (cs *clientSuite) TestClientAssertsTypes(c *C) {
 	cs.rsp = `{
     "result": {
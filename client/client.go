@@ -30,6 +30,7 @@ import (
 	"net/url"
 	"os"
 	"path"
+	"strconv"
 	"time"
 
 	"github.com/snapcore/snapd/dirs"
@@ -263,6 +264,35 @@ func (client *Client) Hijack(f func(*http.Request) (*http.Response, error)) {
 // value. It's low-level, for testing/experimenting only; you should
 // usually use a higher level interface that builds on this.
 func (client *Client) do(method, path string, query url.Values, headers map[string]string, body io.Reader, v interface{}) error {
+	start := time.Now()
+	var rsp *http.Response
+	var err error
+	for attempt := 0; ; attempt++ {
+		rsp, err = client.rawWithRetry(method, path, query, headers, body)
+		if err != nil {
+			return err
+		}
+		wait, ok := tooManyRequestsWait(rsp, body, attempt, time.Since(start))
+		if !ok {
+			break
+		}
+		rsp.Body.Close()
+		time.Sleep(wait)
+	}
+	defer rsp.Body.Close()
+
+	if v != nil {
+		if err := decodeInto(rsp.Body, v); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// rawWithRetry performs a request like raw, retrying GET requests that
+// fail to reach snapd for up to doTimeout.
+func (client *Client) rawWithRetry(method, path string, query url.Values, headers map[string]string, body io.Reader) (*http.Response, error) {
 	retry := time.NewTicker(doRetry)
 	defer retry.Stop()
 	timeout := time.After(doTimeout)
@@ -280,18 +310,38 @@ func (client *Client) do(method, path string, query url.Values, headers map[stri
 		}
 		break
 	}
-	if err != nil {
-		return err
-	}
-	defer rsp.Body.Close()
+	return rsp, err
+}
 
-	if v != nil {
-		if err := decodeInto(rsp.Body, v); err != nil {
-			return err
+// tooManyRequestsWait returns how long to wait before retrying a
+// request that snapd rejected because the client is over its rate
+// limits. It honours the Retry-After header, backing off exponentially
+// from doRetry without one, and gives up once the request has been
+// retried for doTimeout or if its body cannot be sent again.
+func tooManyRequestsWait(rsp *http.Response, body io.Reader, attempt int, elapsed time.Duration) (wait time.Duration, retry bool) {
+	if rsp.StatusCode != http.StatusTooManyRequests {
+		return 0, false
+	}
+	if body != nil {
+		seeker, ok := body.(io.Seeker)
+		if !ok {
+			return 0, false
+		}
+		if _, err := seeker.Seek(0, io.SeekStart); err != nil {
+			return 0, false
 		}
 	}
-
-	return nil
+	if elapsed >= doTimeout {
+		return 0, false
+	}
+	wait = doRetry << uint(attempt)
+	if secs, err := strconv.Atoi(rsp.Header.Get("Retry-After")); err == nil && secs > 0 {
+		wait = time.Duration(secs) * time.Second
+	}
+	if remaining := doTimeout - elapsed; wait > remaining {
+		wait = remaining
+	}
+	return wait, true
 }
 
 func decodeInto(reader io.Reader, v interface{}) error {
@@ -438,6 +488,7 @@ const (
 	ErrorKindSnapNeedsClassic       = "snap-needs-classic"
 	ErrorKindSnapNeedsClassicSystem = "snap-needs-classic-system"
 	ErrorKindSnapNotClassic         = "snap-not-classic"
+	ErrorKindSnapNeedsTermsAccepted = "snap-needs-terms-accepted"
 	ErrorKindNoUpdateAvailable      = "snap-no-update-available"
 
 	ErrorKindRevisionNotAvailable     = "snap-revision-not-available"
@@ -458,6 +509,8 @@ const (
 
 	ErrorKindSystemRestart = "system-restart"
 	ErrorKindDaemonRestart = "daemon-restart"
+
+	ErrorKindTooManyRequests = "too-many-requests"
 )
 
 // IsRetryable returns true if the given error is an error
@@ -481,6 +534,22 @@ func IsTwoFactorError(err error) bool {
 	return e.Kind == ErrorKindTwoFactorFailed || e.Kind == ErrorKindTwoFactorRequired
 }
 
+// SecurityKeyChallenge returns the challenge to be answered by a
+// FIDO2/U2F security key if the given error is due to two-factor
+// authentication requiring one, or an empty string otherwise.
+func SecurityKeyChallenge(err error) string {
+	e, ok := err.(*Error)
+	if !ok || e == nil || e.Kind != ErrorKindTwoFactorRequired {
+		return ""
+	}
+	value, ok := e.Value.(map[string]interface{})
+	if !ok {
+		return ""
+	}
+	challenge, _ := value["security-key-challenge"].(string)
+	return challenge
+}
+
 // IsInterfacesUnchangedError returns whether the given error means the requested
 // change to interfaces was not made, because there was nothing to do.
 func IsInterfacesUnchangedError(err error) bool {
@@ -506,6 +575,16 @@ type RefreshInfo struct {
 	Last     string `json:"last,omitempty"`
 	Hold     string `json:"hold,omitempty"`
 	Next     string `json:"next,omitempty"`
+	// Snaps holds the outcomes of the last refreshes of the
+	// installed snaps, by snap name.
+	Snaps map[string]*SnapRefreshOutcome `json:"snaps,omitempty"`
+}
+
+// SnapRefreshOutcome holds the outcome of the last refresh of a snap.
+type SnapRefreshOutcome struct {
+	LastAttempt string `json:"last-attempt,omitempty"`
+	LastSuccess string `json:"last-success,omitempty"`
+	LastError   string `json:"last-error,omitempty"`
 }
 
 // SysInfo holds system information
@@ -522,6 +601,10 @@ type SysInfo struct {
 	Refresh         RefreshInfo         `json:"refresh,omitempty"`
 	Confinement     string              `json:"confinement"`
 	SandboxFeatures map[string][]string `json:"sandbox-features,omitempty"`
+
+	// StoreAuthDegraded is set when the store credentials of some
+	// users could not be refreshed and they need to log in again.
+	StoreAuthDegraded bool `json:"store-auth-degraded,omitempty"`
 }
 
 func (rsp *response) err(cli *Client) error {
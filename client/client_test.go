@@ -112,6 +112,57 @@ func (cs *clientSuite) TestClientDoReportsErrors(c *C) {
 	}
 }
 
+func (cs *clientSuite) TestClientDoRetriesTooManyRequests(c *C) {
+	restore := client.MockDoRetry(time.Millisecond, time.Second)
+	defer restore()
+
+	var bodies []string
+	cs.cli.Hijack(func(req *http.Request) (*http.Response, error) {
+		body, err := ioutil.ReadAll(req.Body)
+		c.Assert(err, IsNil)
+		bodies = append(bodies, string(body))
+		if len(bodies) < 3 {
+			return &http.Response{
+				Body:       ioutil.NopCloser(strings.NewReader(`{"type": "error"}`)),
+				StatusCode: 429,
+			}, nil
+		}
+		return &http.Response{
+			Body:       ioutil.NopCloser(strings.NewReader(`[1,2]`)),
+			StatusCode: 200,
+		}, nil
+	})
+
+	var v []int
+	err := cs.cli.Do("POST", "/this", nil, strings.NewReader("data"), &v)
+	c.Check(err, IsNil)
+	c.Check(v, DeepEquals, []int{1, 2})
+	// the body was sent again on every retry
+	c.Check(bodies, DeepEquals, []string{"data", "data", "data"})
+}
+
+func (cs *clientSuite) TestClientDoTooManyRequestsGivesUp(c *C) {
+	cs.status = 429
+	cs.header = http.Header{"Retry-After": []string{"1"}}
+	cs.rsp = `{"type": "error", "status-code": 429, "result": {"message": "too many requests, retry after 1s"}}`
+
+	var rsp map[string]interface{}
+	err := cs.cli.Do("GET", "/this", nil, nil, &rsp)
+	c.Check(err, IsNil)
+	c.Check(rsp["status-code"], Equals, 429.0)
+	// Retry-After is capped by the timeout, so only retried once
+	c.Check(cs.doCalls, Equals, 2)
+}
+
+func (cs *clientSuite) TestClientDoTooManyRequestsBodyNotRewindable(c *C) {
+	cs.status = 429
+	cs.rsp = `{"type": "error", "status-code": 429, "result": {"message": "too many requests, retry after 1s"}}`
+
+	err := cs.cli.Do("POST", "/this", nil, ioutil.NopCloser(strings.NewReader("data")), nil)
+	c.Check(err, IsNil)
+	c.Check(cs.doCalls, Equals, 1)
+}
+
 func (cs *clientSuite) TestClientWorks(c *C) {
 	var v []int
 	cs.rsp = `[1,2]`
@@ -221,7 +272,8 @@ func (cs *clientSuite) TestClientSysInfo(c *C) {
                       "on-classic": true,
                       "build-id": "1234",
                       "confinement": "strict",
-                      "sandbox-features": {"backend": ["feature-1", "feature-2"]}}}`
+                      "sandbox-features": {"backend": ["feature-1", "feature-2"]},
+                      "store-auth-degraded": true}}`
 	sysInfo, err := cs.cli.SysInfo()
 	c.Check(err, IsNil)
 	c.Check(sysInfo, DeepEquals, &client.SysInfo{
@@ -236,7 +288,8 @@ func (cs *clientSuite) TestClientSysInfo(c *C) {
 		SandboxFeatures: map[string][]string{
 			"backend": {"feature-1", "feature-2"},
 		},
-		BuildID: "1234",
+		BuildID:           "1234",
+		StoreAuthDegraded: true,
 	})
 }
 
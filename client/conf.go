@@ -50,3 +50,30 @@ func (client *Client) Conf(snapName string, keys []string) (configuration map[st
 
 	return configuration, nil
 }
+
+// SetUserConf applies the provided patch to the configuration of a snap
+// scoped to the calling user. The change takes effect immediately.
+func (client *Client) SetUserConf(snapName string, patch map[string]interface{}) error {
+	b, err := json.Marshal(patch)
+	if err != nil {
+		return err
+	}
+	_, err = client.doSync("PUT", "/v2/snaps/"+snapName+"/user-conf", nil, nil, bytes.NewReader(b), nil)
+	return err
+}
+
+// UserConf asks for a snap's current configuration scoped to the calling
+// user.
+//
+// Note that the configuration may include json.Numbers.
+func (client *Client) UserConf(snapName string, keys []string) (configuration map[string]interface{}, err error) {
+	query := url.Values{}
+	query.Set("keys", strings.Join(keys, ","))
+
+	_, err = client.doSync("GET", "/v2/snaps/"+snapName+"/user-conf", query, nil, nil, &configuration)
+	if err != nil {
+		return nil, err
+	}
+
+	return configuration, nil
+}
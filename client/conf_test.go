@@ -102,3 +102,34 @@ func (cs *clientSuite) TestClientGetConfMultipleKeys(c *check.C) {
 		"test-key2": "test-value2",
 	})
 }
+
+func (cs *clientSuite) TestClientSetUserConf(c *check.C) {
+	cs.rsp = `{
+		"type": "sync",
+		"status-code": 200,
+		"result": null
+	}`
+	err := cs.cli.SetUserConf("snap-name", map[string]interface{}{"key": "value"})
+	c.Assert(err, check.IsNil)
+	c.Check(cs.req.Method, check.Equals, "PUT")
+	c.Check(cs.req.URL.Path, check.Equals, "/v2/snaps/snap-name/user-conf")
+	var body map[string]interface{}
+	c.Check(json.NewDecoder(cs.req.Body).Decode(&body), check.IsNil)
+	c.Check(body, check.DeepEquals, map[string]interface{}{
+		"key": "value",
+	})
+}
+
+func (cs *clientSuite) TestClientGetUserConf(c *check.C) {
+	cs.rsp = `{
+		"type": "sync",
+		"status-code": 200,
+		"result": {"test-key": "test-value"}
+	}`
+	value, err := cs.cli.UserConf("snap-name", []string{"test-key"})
+	c.Assert(err, check.IsNil)
+	c.Check(cs.req.Method, check.Equals, "GET")
+	c.Check(cs.req.URL.Path, check.Equals, "/v2/snaps/snap-name/user-conf")
+	c.Check(cs.req.URL.Query().Get("keys"), check.Equals, "test-key")
+	c.Check(value, check.DeepEquals, map[string]interface{}{"test-key": "test-value"})
+}
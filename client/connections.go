@@ -21,6 +21,7 @@ package client
 
 import (
 	"net/url"
+	"strings"
 )
 
 // Connection describes a connection between a plug and a slot.
@@ -60,6 +61,12 @@ type ConnectionOptions struct {
 	// All when true, selects established and undesired connections as well
 	// as all disconnected plugs and slots.
 	All bool
+	// Origin selects connections established "manual"ly, "auto"matically
+	// or by the "gadget".
+	Origin string
+	// Attrs when not nil limits the returned plug and slot attributes to
+	// the given ones.
+	Attrs []string
 }
 
 // Connections returns matching plugs, slots and their connections. Unless
@@ -76,6 +83,12 @@ func (client *Client) Connections(opts *ConnectionOptions) (Connections, error)
 	if opts != nil && opts.All {
 		query.Set("select", "all")
 	}
+	if opts != nil && opts.Origin != "" {
+		query.Set("origin", opts.Origin)
+	}
+	if opts != nil && opts.Attrs != nil {
+		query.Set("attrs", strings.Join(opts.Attrs, ","))
+	}
 	_, err := client.doSync("GET", "/v2/connections", query, nil, nil, &conns)
 	return conns, err
 }
@@ -268,3 +268,29 @@ func (cs *clientSuite) TestClientConnectionsFilter(c *check.C) {
 		"snap":      []string{"foo"},
 	})
 }
+
+func (cs *clientSuite) TestClientConnectionsFilterOriginAttrs(c *check.C) {
+	cs.rsp = `{
+		"type": "sync",
+		"result": {
+			"established": [],
+			"plugs": [],
+			"slots": []
+		}
+	}`
+
+	_, err := cs.cli.Connections(&client.ConnectionOptions{Origin: "gadget"})
+	c.Assert(err, check.IsNil)
+	c.Check(cs.req.URL.RawQuery, check.Equals, "origin=gadget")
+
+	_, err = cs.cli.Connections(&client.ConnectionOptions{Attrs: []string{"content", "target"}})
+	c.Assert(err, check.IsNil)
+	c.Check(cs.req.URL.Query(), check.DeepEquals, url.Values{
+		"attrs": []string{"content,target"},
+	})
+
+	// an empty selection asks for no attributes at all
+	_, err = cs.cli.Connections(&client.ConnectionOptions{Attrs: []string{}})
+	c.Assert(err, check.IsNil)
+	c.Check(cs.req.URL.RawQuery, check.Equals, "attrs=")
+}
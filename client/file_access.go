@@ -0,0 +1,41 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2019 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package client
+
+import (
+	"fmt"
+	"net/url"
+)
+
+// FileAccess asks snapd for the access the given snap has to the given
+// absolute path when run by the calling user. The answer is one of
+// "hidden", "read-only" or "read-write".
+func (client *Client) FileAccess(snapName, path string) (string, error) {
+	query := url.Values{}
+	query.Set("path", path)
+
+	var result struct {
+		Access string `json:"access"`
+	}
+	if _, err := client.doSync("GET", "/v2/snaps/"+snapName+"/file-access", query, nil, nil, &result); err != nil {
+		return "", fmt.Errorf("cannot check file access: %v", err)
+	}
+	return result.Access, nil
+}
@@ -0,0 +1,48 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2019 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package client_test
+
+import (
+	"gopkg.in/check.v1"
+)
+
+func (cs *clientSuite) TestClientFileAccess(c *check.C) {
+	cs.rsp = `{
+		"type": "sync",
+		"status-code": 200,
+		"result": {"access": "read-only"}
+	}`
+	access, err := cs.cli.FileAccess("snap-name", "/home/user/doc.txt")
+	c.Assert(err, check.IsNil)
+	c.Check(access, check.Equals, "read-only")
+	c.Check(cs.req.Method, check.Equals, "GET")
+	c.Check(cs.req.URL.Path, check.Equals, "/v2/snaps/snap-name/file-access")
+	c.Check(cs.req.URL.Query().Get("path"), check.Equals, "/home/user/doc.txt")
+}
+
+func (cs *clientSuite) TestClientFileAccessError(c *check.C) {
+	cs.rsp = `{
+		"type": "error",
+		"status-code": 404,
+		"result": {"message": "snap not installed", "kind": "snap-not-found"}
+	}`
+	_, err := cs.cli.FileAccess("snap-name", "/home/user/doc.txt")
+	c.Assert(err, check.ErrorMatches, "cannot check file access: snap not installed")
+}
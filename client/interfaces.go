@@ -24,6 +24,7 @@ import (
 	"encoding/json"
 	"net/url"
 	"strings"
+	"time"
 )
 
 // Plug represents the potential of a given snap to connect to a slot.
@@ -74,6 +75,8 @@ type InterfaceAction struct {
 	Action string `json:"action"`
 	Plugs  []Plug `json:"plugs,omitempty"`
 	Slots  []Slot `json:"slots,omitempty"`
+	// For makes a connection temporary, it is a duration like "2h0m0s"
+	For string `json:"for,omitempty"`
 }
 
 // InterfaceOptions represents opt-in elements include in responses.
@@ -132,6 +135,17 @@ func (client *Client) Connect(plugSnapName, plugName, slotSnapName, slotName str
 	})
 }
 
+// ConnectFor establishes a temporary connection between a plug and a
+// slot, it is disconnected automatically once the duration has passed.
+func (client *Client) ConnectFor(plugSnapName, plugName, slotSnapName, slotName string, duration time.Duration) (changeID string, err error) {
+	return client.performInterfaceAction(&InterfaceAction{
+		Action: "connect",
+		Plugs:  []Plug{{Snap: plugSnapName, Name: plugName}},
+		Slots:  []Slot{{Snap: slotSnapName, Name: slotName}},
+		For:    duration.String(),
+	})
+}
+
 // Disconnect breaks the connection between a plug and a slot.
 func (client *Client) Disconnect(plugSnapName, plugName, slotSnapName, slotName string) (changeID string, err error) {
 	return client.performInterfaceAction(&InterfaceAction{
@@ -43,15 +43,31 @@ type loginData struct {
 	Email    string `json:"email,omitempty"`
 	Password string `json:"password,omitempty"`
 	Otp      string `json:"otp,omitempty"`
+
+	SecurityKeyResponse string `json:"security-key-response,omitempty"`
 }
 
 // Login logs user in.
 func (client *Client) Login(email, password, otp string) (*User, error) {
-	postData := loginData{
+	return client.login(&loginData{
 		Email:    email,
 		Password: password,
 		Otp:      otp,
-	}
+	})
+}
+
+// LoginWithSecurityKey logs user in, using the response of a FIDO2/U2F
+// security key to the challenge obtained via SecurityKeyChallenge from
+// the error of a previous login attempt.
+func (client *Client) LoginWithSecurityKey(email, password, response string) (*User, error) {
+	return client.login(&loginData{
+		Email:               email,
+		Password:            password,
+		SecurityKeyResponse: response,
+	})
+}
+
+func (client *Client) login(postData *loginData) (*User, error) {
 	var body bytes.Buffer
 	if err := json.NewEncoder(&body).Encode(postData); err != nil {
 		return nil, err
@@ -20,6 +20,7 @@
 package client_test
 
 import (
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"os"
@@ -162,3 +163,52 @@ func (cs *clientSuite) TestReadAuthData(c *check.C) {
 	c.Assert(err, check.IsNil)
 	c.Check(readUser, check.DeepEquals, &authData)
 }
+
+func (cs *clientSuite) TestClientLoginWithSecurityKey(c *check.C) {
+	cs.rsp = `{"type": "sync", "result":
+                     {"username": "the-user-name",
+                      "macaroon": "the-root-macaroon",
+                      "discharges": ["discharge-macaroon"]}}`
+
+	outfile := filepath.Join(c.MkDir(), "json")
+	os.Setenv(client.TestAuthFileEnvKey, outfile)
+	defer os.Unsetenv(client.TestAuthFileEnvKey)
+
+	user, err := cs.cli.LoginWithSecurityKey("username", "pass", "the-response")
+	c.Check(err, check.IsNil)
+	c.Check(user, check.DeepEquals, &client.User{
+		Username:   "the-user-name",
+		Macaroon:   "the-root-macaroon",
+		Discharges: []string{"discharge-macaroon"}})
+
+	var body map[string]string
+	c.Assert(json.NewDecoder(cs.req.Body).Decode(&body), check.IsNil)
+	c.Check(body, check.DeepEquals, map[string]string{
+		"email":                 "username",
+		"password":              "pass",
+		"security-key-response": "the-response",
+	})
+}
+
+func (cs *clientSuite) TestClientLoginSecurityKeyChallenge(c *check.C) {
+	cs.rsp = `{
+		"result": {
+			"kind": "two-factor-required",
+			"message": "two factor authentication with a security key required",
+			"value": {"security-key-challenge": "the-challenge"}
+		},
+		"status": "Unauthorized",
+		"status-code": 401,
+		"type": "error"
+	}`
+
+	outfile := filepath.Join(c.MkDir(), "json")
+	os.Setenv(client.TestAuthFileEnvKey, outfile)
+	defer os.Unsetenv(client.TestAuthFileEnvKey)
+
+	_, err := cs.cli.Login("username", "pass", "")
+	c.Assert(err, check.NotNil)
+	c.Check(client.IsTwoFactorError(err), check.Equals, true)
+	c.Check(client.SecurityKeyChallenge(err), check.Equals, "the-challenge")
+	c.Check(client.SecurityKeyChallenge(fmt.Errorf("other")), check.Equals, "")
+}
@@ -23,6 +23,8 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+
+	"github.com/snapcore/snapd/asserts"
 )
 
 type remodelData struct {
@@ -43,3 +45,57 @@ func (client *Client) Remodel(b []byte) (changeID string, err error) {
 
 	return client.doAsync("POST", "/v2/model", nil, headers, bytes.NewReader(data))
 }
+
+// RemodelPhase holds the progress of one phase of a remodel change, as
+// found under the "remodel-phases" key of the change data.
+type RemodelPhase struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	// Unit is the unit of Done and Total, "bytes" or "tasks".
+	Unit  string `json:"unit"`
+	Done  int64  `json:"done"`
+	Total int64  `json:"total"`
+}
+
+// CurrentModelAssertion returns the current model assertion of the device.
+func (client *Client) CurrentModelAssertion() (*asserts.Model, error) {
+	a, err := currentAssertion(client, "/v2/model")
+	if err != nil {
+		return nil, fmt.Errorf("cannot get model assertion: %v", err)
+	}
+	model, ok := a.(*asserts.Model)
+	if !ok {
+		return nil, fmt.Errorf("unexpected assertion type %q for model", a.Type().Name)
+	}
+	return model, nil
+}
+
+// CurrentSerialAssertion returns the current serial assertion of the device.
+func (client *Client) CurrentSerialAssertion() (*asserts.Serial, error) {
+	a, err := currentAssertion(client, "/v2/model/serial")
+	if err != nil {
+		return nil, fmt.Errorf("cannot get serial assertion: %v", err)
+	}
+	serial, ok := a.(*asserts.Serial)
+	if !ok {
+		return nil, fmt.Errorf("unexpected assertion type %q for serial", a.Type().Name)
+	}
+	return serial, nil
+}
+
+func currentAssertion(client *Client, path string) (asserts.Assertion, error) {
+	response, err := client.raw("GET", path, nil, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != 200 {
+		return nil, parseError(response)
+	}
+	dec := asserts.NewDecoder(response.Body)
+	a, err := dec.Decode()
+	if err != nil {
+		return nil, fmt.Errorf("cannot decode assertion: %v", err)
+	}
+	return a, nil
+}
@@ -22,6 +22,7 @@ package client_test
 import (
 	"encoding/json"
 	"io/ioutil"
+	"net/http"
 
 	. "gopkg.in/check.v1"
 )
@@ -53,3 +54,79 @@ func (cs *clientSuite) TestClientRemodel(c *C) {
 	c.Check(jsonBody, HasLen, 1)
 	c.Check(jsonBody["new-model"], Equals, string(remodelJsonData))
 }
+
+const happyModelAssertionResponse = `type: model
+authority-id: mememe
+series: 16
+brand-id: mememe
+model: test-model
+architecture: amd64
+base: core18
+gadget: pc=18
+kernel: pc-kernel=18
+required-snaps:
+  - core
+  - hello-world
+timestamp: 2017-07-27T00:00:00.0Z
+sign-key-sha3-384: 8B3Wmemeu3H6i4dEV4Q85Q4gIUCHIBCNMHq49e085QeLGHi7v27l3Cqmemer4__t
+
+AcLBcwQAAQoAHRYhBMbX+t6MbKGH5C3nnLZW7+q0g6ELBQJdTdwTAAoJELZW7+q0g6ELEvgQAI3j
+jXTqR6kKOqvw94pArwdMDUaZ++tebASAZgso8ejrW2DQGWSc0Q7SQICIR8bvHxqS1GtupQswOzwS
+U8hBUFQyG+6/iOdpCTjlyotdiEDKWuQ+oxvh6/flhdY+MiLx7YCpETP1WqNvN+8vzq3+cn+7eqvh
+SSkvd4yDVw7zJfBOhFMYRoKXvVvsEjdX6Dt7zqd6vkeLhJutdUiEp1HabSHDwb9uZMW8FRKDCqak
+JOlyrF4M25YyYv3hMiS4atvU6gv2sH7hrXWQd4RRPGsl8/6ZHh6sQCgb6GAvq4xdm9clXkLB2hBZ
+EpBVlNwMzOJm5QpNQUIcYd2kJ2a0hZHy5E61F3V8slIKALtajfMmfmCBVKtS+QZgsHXt51Uj98uo
+oALDh1Gz0a4bNR8tVaRo9cc/kvIaYhI+2C+jCnHNHoNiA+pyI+HtyLIsZnLPpzDV33/xjtahn80g
+KpgEm+HaMjh50zZeN/BzQrA9QIVaX2vAGLO1WXZxnHMwSm7TLJN9WBSDKJDt4Vq+N3a+QO2qB3g6
+3ahlu+d36Slc8AdQOlEdaCzFjdfWmgHL/O8Kf5Ql49hvY39XY7vdsCAR+zlcFy/QpLMGBT3A8h0K
+0hqT6/+99DXmEW1Iok15qkwu8dJ4yPd85p8OIGpZaeGVzZXYlZR7W/5+0QSt4iqdD6h9KxOaU5IO
+pSS2
+`
+
+func (cs *clientSuite) TestCurrentModelAssertion(c *C) {
+	cs.header = http.Header{}
+	cs.header.Add("X-Ubuntu-Assertions-Count", "1")
+	cs.rsp = happyModelAssertionResponse
+	model, err := cs.cli.CurrentModelAssertion()
+	c.Assert(err, IsNil)
+	c.Check(cs.req.Method, Equals, "GET")
+	c.Check(cs.req.URL.Path, Equals, "/v2/model")
+	c.Check(model.BrandID(), Equals, "mememe")
+	c.Check(model.Model(), Equals, "test-model")
+}
+
+func (cs *clientSuite) TestCurrentSerialAssertionNotFound(c *C) {
+	cs.header = http.Header{}
+	cs.header.Add("Content-Type", "application/json")
+	cs.status = 404
+	cs.rsp = `{
+		"type": "error",
+		"status-code": 404,
+		"result": {
+			"message": "no serial assertion yet"
+		}
+	}`
+	_, err := cs.cli.CurrentSerialAssertion()
+	c.Check(cs.req.Method, Equals, "GET")
+	c.Check(cs.req.URL.Path, Equals, "/v2/model/serial")
+	c.Assert(err, ErrorMatches, "cannot get serial assertion: no serial assertion yet")
+}
+
+func (cs *clientSuite) TestCurrentModelAssertionWrongType(c *C) {
+	cs.rsp = `type: snap-revision
+authority-id: store-id1
+snap-sha3-384: P1wNUk5O_5tO5spqOLlqUuAk7gkNYezIMHp5N9hMUg1a6YEjNeaCc4T0BaYz7IWs
+snap-id: snap-id-1
+snap-size: 123
+snap-revision: 1
+developer-id: dev-id1
+revision: 1
+timestamp: 2015-11-25T20:00:00Z
+body-length: 0
+sign-key-sha3-384: Jv8_JiHiIzJVcO9M55pPdqSDWUvuhfDIBJUS-3VW7F_idjix7Ffn5qMxB21ZQuij
+
+openpgp ...
+`
+	_, err := cs.cli.CurrentModelAssertion()
+	c.Assert(err, ErrorMatches, `unexpected assertion type "snap-revision" for model`)
+}
@@ -76,6 +76,28 @@ type Snap struct {
 	Tracks []string `json:"tracks,omitempty"`
 
 	Health *SnapHealth `json:"health,omitempty"`
+
+	// Build holds the build provenance of the snap revision, if a
+	// snap-build assertion for it is known
+	Build *SnapBuild `json:"build,omitempty"`
+
+	// Hooks lists the hooks of an installed snap
+	Hooks []HookInfo `json:"hooks,omitempty"`
+
+	// DisabledServices lists the services of an active snap that
+	// are currently disabled in systemd
+	DisabledServices []string `json:"disabled-services,omitempty"`
+}
+
+// HookInfo describes a hook of an installed snap.
+type HookInfo struct {
+	Name string `json:"name"`
+	// Plugs and Slots are the names of the plugs and slots the hook
+	// is bound to
+	Plugs []string `json:"plugs,omitempty"`
+	Slots []string `json:"slots,omitempty"`
+	// Timeout is how long the hook is allowed to run for
+	Timeout string `json:"timeout"`
 }
 
 type SnapHealth struct {
@@ -86,6 +108,13 @@ type SnapHealth struct {
 	Code      string        `json:"code,omitempty"`
 }
 
+// SnapBuild holds the information from the snap-build assertion of a
+// snap revision.
+type SnapBuild struct {
+	Timestamp time.Time `json:"timestamp"`
+	Grade     string    `json:"grade"`
+}
+
 func (s *Snap) MarshalJSON() ([]byte, error) {
 	type auxSnap Snap // use auxiliary type so that Go does not call Snap.MarshalJSON()
 	// separate type just for marshalling
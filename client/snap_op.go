@@ -27,6 +27,7 @@ import (
 	"mime/multipart"
 	"os"
 	"path/filepath"
+	"reflect"
 )
 
 type SnapOptions struct {
@@ -41,7 +42,21 @@ type SnapOptions struct {
 	IgnoreValidation bool   `json:"ignore-validation,omitempty"`
 	Unaliased        bool   `json:"unaliased,omitempty"`
 	Purge            bool   `json:"purge,omitempty"`
+	KeepDataFor      string `json:"keep-data-for,omitempty"`
 	Amend            bool   `json:"amend,omitempty"`
+	AcceptTerms      bool   `json:"accept-terms,omitempty"`
+	// DownloadOnly requests to only download a refresh, to be
+	// installed later with ApplyPrefetched.
+	DownloadOnly bool `json:"download-only,omitempty"`
+	// ApplyPrefetched requests to install refreshes downloaded
+	// before with DownloadOnly.
+	ApplyPrefetched bool `json:"apply-prefetched,omitempty"`
+	// IgnoreRunning requests to refresh regardless of the running
+	// apps and hooks of the snap.
+	IgnoreRunning bool `json:"ignore-running,omitempty"`
+	// KillRunning requests to terminate the running apps and hooks
+	// of the snap before refreshing it.
+	KillRunning bool `json:"kill-running,omitempty"`
 
 	Users []string `json:"users,omitempty"`
 }
@@ -80,13 +95,41 @@ type actionData struct {
 	Action   string `json:"action"`
 	Name     string `json:"name,omitempty"`
 	SnapPath string `json:"snap-path,omitempty"`
+	DryRun   bool   `json:"dry-run,omitempty"`
 	*SnapOptions
 }
 
 type multiActionData struct {
-	Action string   `json:"action"`
-	Snaps  []string `json:"snaps,omitempty"`
-	Users  []string `json:"users,omitempty"`
+	Action          string   `json:"action"`
+	Snaps           []string `json:"snaps,omitempty"`
+	Users           []string `json:"users,omitempty"`
+	DownloadOnly    bool     `json:"download-only,omitempty"`
+	ApplyPrefetched bool     `json:"apply-prefetched,omitempty"`
+	IgnoreRunning   bool     `json:"ignore-running,omitempty"`
+	KillRunning     bool     `json:"kill-running,omitempty"`
+	AcceptTerms     bool     `json:"accept-terms,omitempty"`
+	DryRun          bool     `json:"dry-run,omitempty"`
+	Parent          uint64   `json:"parent,omitempty"`
+}
+
+// OperationPlan describes the change a snap operation would create.
+type OperationPlan struct {
+	Kind          string         `json:"kind"`
+	Summary       string         `json:"summary"`
+	Tasks         []*PlannedTask `json:"tasks"`
+	AffectedSnaps []string       `json:"affected-snaps"`
+	// Restart is "system" if the operation is expected to reboot
+	// the system, "daemon" if it is expected to restart snapd
+	Restart string `json:"restart,omitempty"`
+}
+
+// PlannedTask is a task of an OperationPlan, WaitFor holds the ids of
+// the tasks of the plan it waits for.
+type PlannedTask struct {
+	ID      string   `json:"id"`
+	Kind    string   `json:"kind"`
+	Summary string   `json:"summary"`
+	WaitFor []string `json:"wait-for,omitempty"`
 }
 
 // Install adds the snap with the given name from the given channel (or
@@ -138,7 +181,27 @@ func (client *Client) Switch(name string, options *SnapOptions) (changeID string
 
 // SnapshotMany snapshots many snaps (all, if names empty) for many users (all, if users is empty).
 func (client *Client) SnapshotMany(names []string, users []string) (setID uint64, changeID string, err error) {
-	result, changeID, err := client.doMultiSnapActionFull("snapshot", names, &SnapOptions{Users: users})
+	return client.snapshotMany(&multiActionData{
+		Action: "snapshot",
+		Snaps:  names,
+		Users:  users,
+	})
+}
+
+// SnapshotManyIncremental snapshots only the data changed since the
+// snapshot set with the given ID was saved, for the given snaps or, if
+// none are given, for the snaps in that set.
+func (client *Client) SnapshotManyIncremental(parent uint64, names []string, users []string) (setID uint64, changeID string, err error) {
+	return client.snapshotMany(&multiActionData{
+		Action: "snapshot",
+		Snaps:  names,
+		Users:  users,
+		Parent: parent,
+	})
+}
+
+func (client *Client) snapshotMany(action *multiActionData) (setID uint64, changeID string, err error) {
+	result, changeID, err := client.doMultiActionFull(action)
 	if err != nil {
 		return 0, "", err
 	}
@@ -154,6 +217,49 @@ func (client *Client) SnapshotMany(names []string, users []string) (setID uint64
 	return x.SetID, changeID, nil
 }
 
+// PlanSnapAction returns the plan of the change that performing the
+// given action (install, refresh or remove) on the named snaps would
+// create, without performing it.
+func (client *Client) PlanSnapAction(actionName string, snaps []string, options *SnapOptions) (*OperationPlan, error) {
+	var data []byte
+	var err error
+	var path string
+	if len(snaps) == 1 {
+		if options != nil && options.Dangerous {
+			return nil, ErrDangerousNotApplicable
+		}
+		data, err = json.Marshal(&actionData{
+			Action:      actionName,
+			DryRun:      true,
+			SnapOptions: options,
+		})
+		path = fmt.Sprintf("/v2/snaps/%s", snaps[0])
+	} else {
+		if options != nil && !reflect.DeepEqual(*options, SnapOptions{}) {
+			return nil, fmt.Errorf("cannot use options for multi-action")
+		}
+		data, err = json.Marshal(&multiActionData{
+			Action: actionName,
+			Snaps:  snaps,
+			DryRun: true,
+		})
+		path = "/v2/snaps"
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cannot marshal snap action: %s", err)
+	}
+
+	headers := map[string]string{
+		"Content-Type": "application/json",
+	}
+
+	var plan OperationPlan
+	if _, err := client.doSync("POST", path, nil, headers, bytes.NewBuffer(data), &plan); err != nil {
+		return nil, err
+	}
+	return &plan, nil
+}
+
 var ErrDangerousNotApplicable = fmt.Errorf("dangerous option only meaningful when installing from a local file")
 
 func (client *Client) doSnapAction(actionName string, snapName string, options *SnapOptions) (changeID string, err error) {
@@ -179,7 +285,18 @@ func (client *Client) doSnapAction(actionName string, snapName string, options *
 
 func (client *Client) doMultiSnapAction(actionName string, snaps []string, options *SnapOptions) (changeID string, err error) {
 	if options != nil {
-		return "", fmt.Errorf("cannot use options for multi-action") // (yet)
+		// only the prefetching, running apps and terms options
+		// are supported (yet)
+		multiOptions := SnapOptions{
+			DownloadOnly:    options.DownloadOnly,
+			ApplyPrefetched: options.ApplyPrefetched,
+			IgnoreRunning:   options.IgnoreRunning,
+			KillRunning:     options.KillRunning,
+			AcceptTerms:     options.AcceptTerms,
+		}
+		if !reflect.DeepEqual(*options, multiOptions) {
+			return "", fmt.Errorf("cannot use options for multi-action")
+		}
 	}
 	_, changeID, err = client.doMultiSnapActionFull(actionName, snaps, options)
 
@@ -193,8 +310,17 @@ func (client *Client) doMultiSnapActionFull(actionName string, snaps []string, o
 	}
 	if options != nil {
 		action.Users = options.Users
+		action.DownloadOnly = options.DownloadOnly
+		action.ApplyPrefetched = options.ApplyPrefetched
+		action.IgnoreRunning = options.IgnoreRunning
+		action.KillRunning = options.KillRunning
+		action.AcceptTerms = options.AcceptTerms
 	}
-	data, err := json.Marshal(&action)
+	return client.doMultiActionFull(&action)
+}
+
+func (client *Client) doMultiActionFull(action *multiActionData) (result json.RawMessage, changeID string, err error) {
+	data, err := json.Marshal(action)
 	if err != nil {
 		return nil, "", fmt.Errorf("cannot marshal multi-snap action: %s", err)
 	}
@@ -177,6 +177,148 @@ func (cs *clientSuite) TestClientMultiOpSnap(c *check.C) {
 	}
 }
 
+func (cs *clientSuite) TestClientRefreshManyDownloadOnly(c *check.C) {
+	cs.rsp = `{
+		"change": "d728",
+		"status-code": 202,
+		"type": "async"
+	}`
+	for _, opts := range []*client.SnapOptions{{DownloadOnly: true}, {ApplyPrefetched: true}} {
+		id, err := cs.cli.RefreshMany([]string{pkgName}, opts)
+		c.Assert(err, check.IsNil)
+		c.Check(id, check.Equals, "d728")
+
+		body, err := ioutil.ReadAll(cs.req.Body)
+		c.Assert(err, check.IsNil)
+		jsonBody := make(map[string]interface{})
+		err = json.Unmarshal(body, &jsonBody)
+		c.Assert(err, check.IsNil)
+		c.Check(jsonBody["action"], check.Equals, "refresh")
+		c.Check(jsonBody["snaps"], check.DeepEquals, []interface{}{pkgName})
+		if opts.DownloadOnly {
+			c.Check(jsonBody["download-only"], check.Equals, true)
+		} else {
+			c.Check(jsonBody["apply-prefetched"], check.Equals, true)
+		}
+		c.Check(jsonBody, check.HasLen, 3)
+	}
+
+	_, err := cs.cli.RefreshMany(nil, &client.SnapOptions{DownloadOnly: true, Channel: "edge"})
+	c.Assert(err, check.ErrorMatches, "cannot use options for multi-action")
+}
+
+func (cs *clientSuite) TestClientRefreshManyKillRunning(c *check.C) {
+	cs.rsp = `{
+		"change": "d728",
+		"status-code": 202,
+		"type": "async"
+	}`
+	id, err := cs.cli.RefreshMany([]string{pkgName}, &client.SnapOptions{KillRunning: true})
+	c.Assert(err, check.IsNil)
+	c.Check(id, check.Equals, "d728")
+
+	body, err := ioutil.ReadAll(cs.req.Body)
+	c.Assert(err, check.IsNil)
+	jsonBody := make(map[string]interface{})
+	err = json.Unmarshal(body, &jsonBody)
+	c.Assert(err, check.IsNil)
+	c.Check(jsonBody, check.DeepEquals, map[string]interface{}{
+		"action":       "refresh",
+		"snaps":        []interface{}{pkgName},
+		"kill-running": true,
+	})
+}
+
+func (cs *clientSuite) TestClientInstallManyAcceptTerms(c *check.C) {
+	cs.rsp = `{
+		"change": "d728",
+		"status-code": 202,
+		"type": "async"
+	}`
+	id, err := cs.cli.InstallMany([]string{pkgName}, &client.SnapOptions{AcceptTerms: true})
+	c.Assert(err, check.IsNil)
+	c.Check(id, check.Equals, "d728")
+
+	body, err := ioutil.ReadAll(cs.req.Body)
+	c.Assert(err, check.IsNil)
+	jsonBody := make(map[string]interface{})
+	err = json.Unmarshal(body, &jsonBody)
+	c.Assert(err, check.IsNil)
+	c.Check(jsonBody, check.DeepEquals, map[string]interface{}{
+		"action":       "install",
+		"snaps":        []interface{}{pkgName},
+		"accept-terms": true,
+	})
+}
+
+func (cs *clientSuite) TestClientPlanSnapAction(c *check.C) {
+	cs.rsp = `{
+		"result": {
+			"kind": "install-snap",
+			"summary": "Install foo snap",
+			"tasks": [
+				{"id": "1", "kind": "prerequisites", "summary": "Ensure prerequisites for foo are available"},
+				{"id": "2", "kind": "download-snap", "summary": "Download snap foo", "wait-for": ["1"]}
+			],
+			"affected-snaps": ["foo"],
+			"restart": "daemon"
+		},
+		"status-code": 200,
+		"type": "sync"
+	}`
+	plan, err := cs.cli.PlanSnapAction("install", []string{pkgName}, &client.SnapOptions{Channel: "edge"})
+	c.Assert(err, check.IsNil)
+	c.Check(plan, check.DeepEquals, &client.OperationPlan{
+		Kind:    "install-snap",
+		Summary: "Install foo snap",
+		Tasks: []*client.PlannedTask{
+			{ID: "1", Kind: "prerequisites", Summary: "Ensure prerequisites for foo are available"},
+			{ID: "2", Kind: "download-snap", Summary: "Download snap foo", WaitFor: []string{"1"}},
+		},
+		AffectedSnaps: []string{"foo"},
+		Restart:       "daemon",
+	})
+
+	c.Check(cs.req.Method, check.Equals, "POST")
+	c.Check(cs.req.URL.Path, check.Equals, fmt.Sprintf("/v2/snaps/%s", pkgName))
+	body, err := ioutil.ReadAll(cs.req.Body)
+	c.Assert(err, check.IsNil)
+	jsonBody := make(map[string]interface{})
+	err = json.Unmarshal(body, &jsonBody)
+	c.Assert(err, check.IsNil)
+	c.Check(jsonBody, check.DeepEquals, map[string]interface{}{
+		"action":  "install",
+		"dry-run": true,
+		"channel": "edge",
+	})
+}
+
+func (cs *clientSuite) TestClientPlanSnapActionMany(c *check.C) {
+	cs.rsp = `{
+		"result": {"kind": "refresh-snap", "summary": "Refresh snaps", "tasks": [], "affected-snaps": ["foo", "bar"]},
+		"status-code": 200,
+		"type": "sync"
+	}`
+	plan, err := cs.cli.PlanSnapAction("refresh", []string{"foo", "bar"}, nil)
+	c.Assert(err, check.IsNil)
+	c.Check(plan.AffectedSnaps, check.DeepEquals, []string{"foo", "bar"})
+
+	c.Check(cs.req.URL.Path, check.Equals, "/v2/snaps")
+	body, err := ioutil.ReadAll(cs.req.Body)
+	c.Assert(err, check.IsNil)
+	jsonBody := make(map[string]interface{})
+	err = json.Unmarshal(body, &jsonBody)
+	c.Assert(err, check.IsNil)
+	c.Check(jsonBody, check.DeepEquals, map[string]interface{}{
+		"action":  "refresh",
+		"snaps":   []interface{}{"foo", "bar"},
+		"dry-run": true,
+	})
+
+	_, err = cs.cli.PlanSnapAction("refresh", []string{"foo", "bar"}, &client.SnapOptions{Channel: "edge"})
+	c.Assert(err, check.ErrorMatches, "cannot use options for multi-action")
+}
+
 func (cs *clientSuite) TestClientMultiSnapshot(c *check.C) {
 	// Note body is essentially the same as TestClientMultiOpSnap; keep in sync
 	cs.rsp = `{
@@ -202,6 +344,31 @@ func (cs *clientSuite) TestClientMultiSnapshot(c *check.C) {
 	c.Check(changeID, check.Equals, "d728")
 }
 
+func (cs *clientSuite) TestClientMultiSnapshotIncremental(c *check.C) {
+	cs.rsp = `{
+		"result": {"set-id": 43},
+		"change": "d729",
+		"status-code": 202,
+		"type": "async"
+	}`
+	setID, changeID, err := cs.cli.SnapshotManyIncremental(42, nil, []string{"a-user"})
+	c.Assert(err, check.IsNil)
+
+	body, err := ioutil.ReadAll(cs.req.Body)
+	c.Assert(err, check.IsNil)
+	jsonBody := make(map[string]interface{})
+	err = json.Unmarshal(body, &jsonBody)
+	c.Assert(err, check.IsNil)
+	c.Check(jsonBody, check.DeepEquals, map[string]interface{}{
+		"action": "snapshot",
+		"users":  []interface{}{"a-user"},
+		"parent": 42.,
+	})
+	c.Check(cs.req.URL.Path, check.Equals, "/v2/snaps")
+	c.Check(setID, check.Equals, uint64(43))
+	c.Check(changeID, check.Equals, "d729")
+}
+
 func (cs *clientSuite) TestClientOpInstallPath(c *check.C) {
 	cs.rsp = `{
 		"change": "66b3",
@@ -403,19 +570,20 @@ func (cs *clientSuite) TestClientOpTryModeDangerous(c *check.C) {
 
 func (cs *clientSuite) TestSnapOptionsSerialises(c *check.C) {
 	tests := map[string]client.SnapOptions{
-		"{}":                         {},
-		`{"channel":"edge"}`:         {Channel: "edge"},
-		`{"revision":"42"}`:          {Revision: "42"},
-		`{"cohort-key":"what"}`:      {CohortKey: "what"},
-		`{"leave-cohort":true}`:      {LeaveCohort: true},
-		`{"devmode":true}`:           {DevMode: true},
-		`{"jailmode":true}`:          {JailMode: true},
-		`{"classic":true}`:           {Classic: true},
-		`{"dangerous":true}`:         {Dangerous: true},
-		`{"ignore-validation":true}`: {IgnoreValidation: true},
-		`{"unaliased":true}`:         {Unaliased: true},
-		`{"purge":true}`:             {Purge: true},
-		`{"amend":true}`:             {Amend: true},
+		"{}":                           {},
+		`{"channel":"edge"}`:           {Channel: "edge"},
+		`{"revision":"42"}`:            {Revision: "42"},
+		`{"cohort-key":"what"}`:        {CohortKey: "what"},
+		`{"leave-cohort":true}`:        {LeaveCohort: true},
+		`{"devmode":true}`:             {DevMode: true},
+		`{"jailmode":true}`:            {JailMode: true},
+		`{"classic":true}`:             {Classic: true},
+		`{"dangerous":true}`:           {Dangerous: true},
+		`{"ignore-validation":true}`:   {IgnoreValidation: true},
+		`{"unaliased":true}`:           {Unaliased: true},
+		`{"purge":true}`:               {Purge: true},
+		`{"keep-data-for":"168h0m0s"}`: {KeepDataFor: "168h0m0s"},
+		`{"amend":true}`:               {Amend: true},
 	}
 	for expected, opts := range tests {
 		buf, err := json.Marshal(&opts)
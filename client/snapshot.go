@@ -75,6 +75,10 @@ type Snapshot struct {
 
 	// set if the snapshot was created automatically on snap removal
 	Auto bool `json:"auto,omitempty"`
+
+	// the ID of the snapshot set this snapshot only holds the changes
+	// relative to, if any
+	Parent uint64 `json:"parent,omitempty"`
 }
 
 // IsValid checks whether the snapshot is missing information that
@@ -0,0 +1,101 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2019 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// ValidationSetResult holds the tracking details and compliance of a
+// validation set.
+type ValidationSetResult struct {
+	AccountID string `json:"account-id"`
+	Name      string `json:"name"`
+	PinnedAt  int    `json:"pinned-at,omitempty"`
+	Mode      string `json:"mode"`
+	Sequence  int    `json:"sequence,omitempty"`
+	Valid     bool   `json:"valid"`
+}
+
+// ValidationSetAction is the request body to apply or forget a
+// validation set.
+type ValidationSetAction struct {
+	Action   string `json:"action"`
+	Mode     string `json:"mode,omitempty"`
+	Sequence int    `json:"sequence,omitempty"`
+}
+
+func validationSetPath(accountID, name string) string {
+	return fmt.Sprintf("/v2/validation-sets/%s/%s", accountID, name)
+}
+
+// ListValidationsSets queries all the validation sets tracked on the
+// system.
+func (client *Client) ListValidationsSets() ([]*ValidationSetResult, error) {
+	var res []*ValidationSetResult
+	if _, err := client.doSync("GET", "/v2/validation-sets", nil, nil, nil, &res); err != nil {
+		return nil, fmt.Errorf("cannot list validation sets: %v", err)
+	}
+	return res, nil
+}
+
+// ValidationSet queries the given tracked validation set.
+func (client *Client) ValidationSet(accountID, name string) (*ValidationSetResult, error) {
+	var res *ValidationSetResult
+	if _, err := client.doSync("GET", validationSetPath(accountID, name), nil, nil, nil, &res); err != nil {
+		return nil, fmt.Errorf("cannot query validation set: %v", err)
+	}
+	return res, nil
+}
+
+// ApplyValidationSet starts tracking the given validation set in the
+// given mode ("monitor" or "enforce"), at the given sequence or the
+// latest one if sequence is 0.
+func (client *Client) ApplyValidationSet(accountID, name, mode string, sequence int) (*ValidationSetResult, error) {
+	data, err := json.Marshal(&ValidationSetAction{
+		Action:   "apply",
+		Mode:     mode,
+		Sequence: sequence,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("cannot marshal validation set action: %v", err)
+	}
+
+	var res *ValidationSetResult
+	if _, err := client.doSync("POST", validationSetPath(accountID, name), nil, nil, bytes.NewReader(data), &res); err != nil {
+		return nil, fmt.Errorf("cannot apply validation set: %v", err)
+	}
+	return res, nil
+}
+
+// ForgetValidationSet stops tracking the given validation set.
+func (client *Client) ForgetValidationSet(accountID, name string) error {
+	data, err := json.Marshal(&ValidationSetAction{Action: "forget"})
+	if err != nil {
+		return fmt.Errorf("cannot marshal validation set action: %v", err)
+	}
+
+	if _, err := client.doSync("POST", validationSetPath(accountID, name), nil, nil, bytes.NewReader(data), nil); err != nil {
+		return fmt.Errorf("cannot forget validation set: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2019 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package client_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+
+	"gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/client"
+)
+
+func (cs *clientSuite) TestListValidationSets(c *check.C) {
+	cs.rsp = `{
+		"type": "sync",
+		"status-code": 200,
+		"result": [{"account-id": "foo", "name": "bar", "pinned-at": 2, "mode": "enforce", "sequence": 2, "valid": true}]
+	}`
+	sets, err := cs.cli.ListValidationsSets()
+	c.Assert(err, check.IsNil)
+	c.Check(cs.req.Method, check.Equals, "GET")
+	c.Check(cs.req.URL.Path, check.Equals, "/v2/validation-sets")
+	c.Check(sets, check.DeepEquals, []*client.ValidationSetResult{
+		{AccountID: "foo", Name: "bar", PinnedAt: 2, Mode: "enforce", Sequence: 2, Valid: true},
+	})
+}
+
+func (cs *clientSuite) TestValidationSet(c *check.C) {
+	cs.rsp = `{
+		"type": "sync",
+		"status-code": 200,
+		"result": {"account-id": "foo", "name": "bar", "mode": "monitor", "sequence": 3, "valid": false}
+	}`
+	res, err := cs.cli.ValidationSet("foo", "bar")
+	c.Assert(err, check.IsNil)
+	c.Check(cs.req.Method, check.Equals, "GET")
+	c.Check(cs.req.URL.Path, check.Equals, "/v2/validation-sets/foo/bar")
+	c.Check(res, check.DeepEquals, &client.ValidationSetResult{AccountID: "foo", Name: "bar", Mode: "monitor", Sequence: 3})
+}
+
+func (cs *clientSuite) TestApplyValidationSet(c *check.C) {
+	cs.rsp = `{
+		"type": "sync",
+		"status-code": 200,
+		"result": {"account-id": "foo", "name": "bar", "pinned-at": 5, "mode": "enforce", "sequence": 5, "valid": true}
+	}`
+	res, err := cs.cli.ApplyValidationSet("foo", "bar", "enforce", 5)
+	c.Assert(err, check.IsNil)
+	c.Check(cs.req.Method, check.Equals, "POST")
+	c.Check(cs.req.URL.Path, check.Equals, "/v2/validation-sets/foo/bar")
+	c.Check(res.Valid, check.Equals, true)
+
+	body, err := ioutil.ReadAll(cs.req.Body)
+	c.Assert(err, check.IsNil)
+	var jsonBody map[string]interface{}
+	c.Assert(json.Unmarshal(body, &jsonBody), check.IsNil)
+	c.Check(jsonBody, check.DeepEquals, map[string]interface{}{
+		"action":   "apply",
+		"mode":     "enforce",
+		"sequence": 5.0,
+	})
+}
+
+func (cs *clientSuite) TestForgetValidationSet(c *check.C) {
+	cs.rsp = `{"type": "sync", "status-code": 200, "result": null}`
+	err := cs.cli.ForgetValidationSet("foo", "bar")
+	c.Assert(err, check.IsNil)
+	c.Check(cs.req.Method, check.Equals, "POST")
+	c.Check(cs.req.URL.Path, check.Equals, "/v2/validation-sets/foo/bar")
+
+	body, err := ioutil.ReadAll(cs.req.Body)
+	c.Assert(err, check.IsNil)
+	var jsonBody map[string]interface{}
+	c.Assert(json.Unmarshal(body, &jsonBody), check.IsNil)
+	c.Check(jsonBody, check.DeepEquals, map[string]interface{}{
+		"action": "forget",
+	})
+}
+
+func (cs *clientSuite) TestApplyValidationSetError(c *check.C) {
+	cs.rsp = `{"type": "error", "status-code": 409, "result": {"message": "boom"}}`
+	_, err := cs.cli.ApplyValidationSet("foo", "bar", "enforce", 0)
+	c.Assert(err, check.ErrorMatches, "cannot apply validation set: boom")
+}
@@ -35,6 +35,12 @@ static void sc_set_ns_dir(const char *dir)
 	sc_ns_dir = dir;
 }
 
+// Set alternate network namespace directory
+static void sc_set_net_ns_dir(const char *dir)
+{
+	sc_net_ns_dir = dir;
+}
+
 // A variant of unsetenv that is compatible with GDestroyNotify
 static void my_unsetenv(const char *k)
 {
@@ -146,9 +152,28 @@ static void test_nsfs_fs_id(void)
 	g_assert_cmpint(buf.f_type, ==, NSFS_MAGIC);
 }
 
+static void test_sc_join_snap_net_ns__missing(void)
+{
+	char *net_ns_dir = g_dir_make_tmp(NULL, NULL);
+	g_assert_nonnull(net_ns_dir);
+	g_test_queue_free(net_ns_dir);
+	g_test_queue_destroy((GDestroyNotify) rmdir, net_ns_dir);
+	sc_set_net_ns_dir(net_ns_dir);
+	g_test_queue_destroy((GDestroyNotify) sc_set_net_ns_dir, SC_NET_NS_DIR);
+
+	// Without a network namespace for the snap nothing happens.
+	sc_join_snap_net_ns("foo");
+
+	// Neither when the directory itself is absent.
+	sc_set_net_ns_dir("/nonexistent");
+	sc_join_snap_net_ns("foo");
+}
+
 static void __attribute__((constructor)) init(void)
 {
 	g_test_add_func("/ns/sc_alloc_mount_ns", test_sc_alloc_mount_ns);
 	g_test_add_func("/ns/sc_open_mount_ns", test_sc_open_mount_ns);
 	g_test_add_func("/ns/nsfs_fs_id", test_nsfs_fs_id);
+	g_test_add_func("/ns/sc_join_snap_net_ns/missing",
+			test_sc_join_snap_net_ns__missing);
 }
@@ -61,6 +61,21 @@
  **/
 static const char *sc_ns_dir = SC_NS_DIR;
 
+/**
+ * Directory where ip-netns(8) keeps named network namespaces.
+ *
+ * Network namespaces of snaps are created there by snapd when the
+ * network-namespace interface is connected.
+ **/
+#define SC_NET_NS_DIR "/run/netns"
+
+/**
+ * Effective value of SC_NET_NS_DIR.
+ *
+ * We use 'const char *' so we can update sc_net_ns_dir in the testsuite
+ **/
+static const char *sc_net_ns_dir = SC_NET_NS_DIR;
+
 enum {
 	HELPER_CMD_EXIT,
 	HELPER_CMD_CAPTURE_MOUNT_NS,
@@ -886,3 +901,47 @@ void sc_store_ns_info(const sc_invocation * inv)
 	}
 	debug("saved mount namespace meta-data to %s", info_path);
 }
+
+void sc_join_snap_net_ns(const char *snap_instance)
+{
+	char net_ns_name[PATH_MAX] = { 0 };
+	sc_must_snprintf(net_ns_name, sizeof net_ns_name, "snap.%s",
+			 snap_instance);
+
+	int dir_fd SC_CLEANUP(sc_cleanup_close) = -1;
+	dir_fd = open(sc_net_ns_dir, O_DIRECTORY | O_PATH | O_CLOEXEC);
+	if (dir_fd < 0) {
+		if (errno == ENOENT) {
+			return;
+		}
+		die("cannot open directory %s", sc_net_ns_dir);
+	}
+	int net_fd SC_CLEANUP(sc_cleanup_close) = -1;
+	net_fd = openat(dir_fd, net_ns_name, O_RDONLY | O_CLOEXEC | O_NOFOLLOW);
+	if (net_fd < 0) {
+		if (errno == ENOENT) {
+			/* The snap does not use a dedicated network namespace. */
+			return;
+		}
+		die("cannot open network namespace %s", net_ns_name);
+	}
+	/* Only join a namespace that was bind mounted there, anything else could
+	 * not have been created by snapd. */
+	struct statfs net_statfs_buf;
+	if (fstatfs(net_fd, &net_statfs_buf) < 0) {
+		die("cannot inspect filesystem of network namespace file");
+	}
+#ifndef NSFS_MAGIC
+	/* Define NSFS_MAGIC for Ubuntu 14.04 and other older systems. */
+#define NSFS_MAGIC 0x6e736673
+#endif
+	if (net_statfs_buf.f_type != NSFS_MAGIC
+	    && net_statfs_buf.f_type != PROC_SUPER_MAGIC) {
+		die("%s/%s is not a network namespace", sc_net_ns_dir,
+		    net_ns_name);
+	}
+	if (setns(net_fd, CLONE_NEWNET) < 0) {
+		die("cannot join network namespace %s", net_ns_name);
+	}
+	debug("joined network namespace %s", net_ns_name);
+}
@@ -148,4 +148,18 @@ void sc_wait_for_helper(struct sc_mount_ns *group);
 
 void sc_store_ns_info(const sc_invocation * inv);
 
+/**
+ * Join the network namespace of the given snap, if there is one.
+ *
+ * Snaps connected to the network-namespace interface have a named network
+ * namespace, as managed by ip-netns(8), created for them by snapd. When it
+ * exists, the current process joins it so that the apps of the snap are only
+ * reachable through the veth pair and port forwards set up by snapd.
+ *
+ * This function must be called while the network namespace directory of the
+ * host is visible, that is before pivoting into the root filesystem of the
+ * snap.
+ **/
+void sc_join_snap_net_ns(const char *snap_instance);
+
 #endif
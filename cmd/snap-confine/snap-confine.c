@@ -572,6 +572,9 @@ static void enter_non_classic_execution_environment(sc_invocation * inv,
 	// Init and check rootfs_dir, apply any fallback behaviors.
 	sc_check_rootfs_dir(inv);
 
+	/** Join the dedicated network namespace of the snap, if any. */
+	sc_join_snap_net_ns(inv->snap_instance);
+
 	/** Populate and join the device control group. */
 	struct snappy_udev udev_s;
 	if (snappy_udev_init(inv->security_tag, &udev_s) == 0)
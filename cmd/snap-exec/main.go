@@ -193,6 +193,11 @@ func execApp(snapApp, revision, command string, args []string) error {
 		}
 		env = append(env, kv)
 	}
+	connEnv, err := snapenv.ConnectionsEnv(snapName)
+	if err != nil {
+		return fmt.Errorf("cannot read environment of %q: %s", snapName, err)
+	}
+	env = append(env, connEnv...)
 	env = append(env, osutil.SubstituteEnv(app.Env())...)
 
 	// strings.Split() is ok here because we validate all app fields and the
@@ -253,7 +258,12 @@ func execHook(snapName, revision, hookName string) error {
 	}
 
 	// build the environment
-	env := append(os.Environ(), osutil.SubstituteEnv(hook.Env())...)
+	connEnv, err := snapenv.ConnectionsEnv(snapName)
+	if err != nil {
+		return fmt.Errorf("cannot read environment of %q: %s", snapName, err)
+	}
+	env := append(os.Environ(), connEnv...)
+	env = append(env, osutil.SubstituteEnv(hook.Env())...)
 
 	// run the hook
 	cmd := append(absoluteCommandChain(hook.Snap, hook.CommandChain), filepath.Join(hook.Snap.HooksDir(), hook.Name))
@@ -238,6 +238,46 @@ func (s *snapExecSuite) TestSnapExecHookIntegration(c *C) {
 	c.Check(execArgs, DeepEquals, []string{execArgv0})
 }
 
+func (s *snapExecSuite) TestSnapExecConnectionsEnvironment(c *C) {
+	dirs.SetRootDir(c.MkDir())
+	snaptest.MockSnap(c, string(mockYaml), &snap.SideInfo{
+		Revision: snap.R("42"),
+	})
+	c.Assert(os.MkdirAll(dirs.SnapMountPolicyDir, 0755), IsNil)
+	envFile := filepath.Join(dirs.SnapMountPolicyDir, "snap.snapname.environment")
+	err := ioutil.WriteFile(envFile, []byte("SNAP_CONTENT_THEMES_PATH=/snap/snapname/42/themes\n"), 0644)
+	c.Assert(err, IsNil)
+
+	execEnv := []string{}
+	restore := snapExec.MockSyscallExec(func(argv0 string, argv []string, env []string) error {
+		execEnv = env
+		return nil
+	})
+	defer restore()
+
+	err = snapExec.ExecApp("snapname.app", "42", "", nil)
+	c.Assert(err, IsNil)
+	c.Check(execEnv, testutil.Contains, "SNAP_CONTENT_THEMES_PATH=/snap/snapname/42/themes")
+	c.Check(execEnv, testutil.Contains, "BASE_PATH=/some/path")
+
+	snaptest.MockSnap(c, string(mockHookYaml), &snap.SideInfo{
+		Revision: snap.R("42"),
+	})
+	execEnv = nil
+	err = snapExec.ExecHook("snapname", "42", "configure")
+	c.Assert(err, IsNil)
+	c.Check(execEnv, testutil.Contains, "SNAP_CONTENT_THEMES_PATH=/snap/snapname/42/themes")
+
+	// no environment without connections
+	c.Assert(os.Remove(envFile), IsNil)
+	execEnv = nil
+	err = snapExec.ExecHook("snapname", "42", "configure")
+	c.Assert(err, IsNil)
+	for _, kv := range execEnv {
+		c.Check(kv, Not(Matches), "SNAP_CONTENT_.*")
+	}
+}
+
 func (s *snapExecSuite) TestSnapExecHookCommandChainIntegration(c *C) {
 	dirs.SetRootDir(c.MkDir())
 	snaptest.MockSnap(c, string(mockHookCommandChainYaml), &snap.SideInfo{
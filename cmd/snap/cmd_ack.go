@@ -25,12 +25,15 @@ import (
 
 	"github.com/jessevdk/go-flags"
 
+	"github.com/snapcore/snapd/asserts"
 	"github.com/snapcore/snapd/client"
 	"github.com/snapcore/snapd/i18n"
+	"github.com/snapcore/snapd/osutil"
 )
 
 type cmdAck struct {
 	clientMixin
+	FromStore  bool `long:"from-store"`
 	AckOptions struct {
 		AssertionFile flags.Filename
 	} `positional-args:"true" required:"true"`
@@ -46,12 +49,20 @@ will replace.
 To succeed the assertion must be valid, its signature verified with a known
 public key and the assertion consistent with and its prerequisite in the
 database.
+
+With --from-store the argument is instead a snap-id or a snap file, and the
+assertions needed to install that snap, or that exact snap file, are fetched
+from the store and added to the system assertion database, so that the snap
+can later be installed without passing the assertions separately.
 `)
 
 func init() {
 	addCommand("ack", shortAckHelp, longAckHelp, func() flags.Commander {
 		return &cmdAck{}
-	}, nil, []argDesc{{
+	}, map[string]string{
+		// TRANSLATORS: This should not start with a lowercase letter.
+		"from-store": i18n.G("Fetch from the store the assertions for the given snap-id or snap file"),
+	}, []argDesc{{
 		// TRANSLATORS: This needs to begin with < and end with >
 		name: i18n.G("<assertion file>"),
 		// TRANSLATORS: This should not start with a lowercase letter.
@@ -68,10 +79,27 @@ func ackFile(cli *client.Client, assertFile string) error {
 	return cli.Ack(assertData)
 }
 
+func ackFromStore(cli *client.Client, snapIDOrFile string) error {
+	if !osutil.FileExists(snapIDOrFile) {
+		return cli.AckFromStore(snapIDOrFile, "")
+	}
+	snapSHA3_384, _, err := asserts.SnapFileSHA3_384(snapIDOrFile)
+	if err != nil {
+		return err
+	}
+	return cli.AckFromStore("", snapSHA3_384)
+}
+
 func (x *cmdAck) Execute(args []string) error {
 	if len(args) > 0 {
 		return ErrExtraArgs
 	}
+	if x.FromStore {
+		if err := ackFromStore(x.client, string(x.AckOptions.AssertionFile)); err != nil {
+			return fmt.Errorf("cannot assert: %v", err)
+		}
+		return nil
+	}
 	if err := ackFile(x.client, string(x.AckOptions.AssertionFile)); err != nil {
 		return fmt.Errorf("cannot assert: %v", err)
 	}
@@ -0,0 +1,91 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2019 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package main_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+
+	"gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/asserts"
+	snap "github.com/snapcore/snapd/cmd/snap"
+)
+
+func (s *SnapSuite) mockAckFromStoreServer(c *check.C, expected map[string]interface{}) {
+	n := 0
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		switch n {
+		case 0:
+			c.Check(r.Method, check.Equals, "POST")
+			c.Check(r.URL.Path, check.Equals, "/v2/assertions")
+			c.Check(r.Header.Get("Content-Type"), check.Equals, "application/json")
+			var body map[string]interface{}
+			c.Assert(json.NewDecoder(r.Body).Decode(&body), check.IsNil)
+			c.Check(body, check.DeepEquals, expected)
+			fmt.Fprintln(w, `{"type": "sync", "result": null}`)
+		default:
+			c.Fatalf("expected to get 1 request, now on %d", n+1)
+		}
+		n++
+	})
+}
+
+func (s *SnapSuite) TestAckFromStoreSnapID(c *check.C) {
+	s.mockAckFromStoreServer(c, map[string]interface{}{
+		"action":  "fetch",
+		"snap-id": "snap-id-1",
+	})
+
+	rest, err := snap.Parser(snap.Client()).ParseArgs([]string{"ack", "--from-store", "snap-id-1"})
+	c.Assert(err, check.IsNil)
+	c.Assert(rest, check.DeepEquals, []string{})
+	c.Check(s.Stdout(), check.Equals, "")
+	c.Check(s.Stderr(), check.Equals, "")
+}
+
+func (s *SnapSuite) TestAckFromStoreSnapFile(c *check.C) {
+	snapFile := filepath.Join(c.MkDir(), "foo_1.snap")
+	c.Assert(ioutil.WriteFile(snapFile, []byte("snap-data"), 0644), check.IsNil)
+	digest, _, err := asserts.SnapFileSHA3_384(snapFile)
+	c.Assert(err, check.IsNil)
+
+	s.mockAckFromStoreServer(c, map[string]interface{}{
+		"action":        "fetch",
+		"snap-sha3-384": digest,
+	})
+
+	rest, err := snap.Parser(snap.Client()).ParseArgs([]string{"ack", "--from-store", snapFile})
+	c.Assert(err, check.IsNil)
+	c.Assert(rest, check.DeepEquals, []string{})
+}
+
+func (s *SnapSuite) TestAckFromStoreError(c *check.C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(404)
+		fmt.Fprintln(w, `{"type": "error", "status-code": 404, "result": {"message": "cannot find snap assertions in the store: snap-declaration (snap-id-1; series:16) not found"}}`)
+	})
+
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"ack", "--from-store", "snap-id-1"})
+	c.Assert(err, check.ErrorMatches, `cannot assert: cannot find snap assertions in the store: .* not found`)
+}
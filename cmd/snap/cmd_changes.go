@@ -21,8 +21,12 @@ package main
 
 import (
 	"fmt"
+	"io"
+	"os"
+	"os/signal"
 	"regexp"
 	"sort"
+	"time"
 
 	"github.com/snapcore/snapd/client"
 	"github.com/snapcore/snapd/i18n"
@@ -34,6 +38,11 @@ var shortChangesHelp = i18n.G("List system changes")
 var shortTasksHelp = i18n.G("List a change's tasks")
 var longChangesHelp = i18n.G(`
 The changes command displays a summary of system changes performed recently.
+
+With --follow, it keeps running after the summary and reports new changes
+and changes of status, with their spawn and ready times, until interrupted.
+As snapd has no way to notify clients of changes, they are found by polling
+it every second.
 `)
 var longTasksHelp = i18n.G(`
 The tasks command displays a summary of tasks associated with an individual
@@ -43,6 +52,7 @@ change.
 type cmdChanges struct {
 	clientMixin
 	timeMixin
+	Follow     bool `long:"follow"`
 	Positional struct {
 		Snap string `positional-arg-name:"<snap>"`
 	} `positional-args:"yes"`
@@ -55,7 +65,10 @@ type cmdTasks struct {
 
 func init() {
 	addCommand("changes", shortChangesHelp, longChangesHelp,
-		func() flags.Commander { return &cmdChanges{} }, timeDescs, nil)
+		func() flags.Commander { return &cmdChanges{} }, timeDescs.also(map[string]string{
+			// TRANSLATORS: This should not start with a lowercase letter.
+			"follow": i18n.G("Keep reporting new changes and changes of status as they happen"),
+		}), nil)
 	addCommand("tasks", shortTasksHelp, longTasksHelp,
 		func() flags.Commander { return &cmdTasks{} },
 		changeIDMixinOptDesc.also(timeDescs),
@@ -107,6 +120,9 @@ func (c *cmdChanges) Execute(args []string) error {
 	}
 
 	if len(changes) == 0 {
+		if c.Follow {
+			return c.follow(&opts, nil)
+		}
 		return fmt.Errorf(i18n.G("no changes found"))
 	}
 
@@ -116,20 +132,84 @@ func (c *cmdChanges) Execute(args []string) error {
 
 	fmt.Fprintf(w, i18n.G("ID\tStatus\tSpawn\tReady\tSummary\n"))
 	for _, chg := range changes {
-		spawnTime := c.fmtTime(chg.SpawnTime)
-		readyTime := c.fmtTime(chg.ReadyTime)
-		if chg.ReadyTime.IsZero() {
-			readyTime = "-"
-		}
-		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", chg.ID, chg.Status, spawnTime, readyTime, chg.Summary)
+		c.printChange(w, chg)
 	}
 
 	w.Flush()
 	fmt.Fprintln(Stdout)
 
+	if c.Follow {
+		return c.follow(&opts, changes)
+	}
+
 	return nil
 }
 
+func (c *cmdChanges) printChange(w io.Writer, chg *client.Change) {
+	spawnTime := c.fmtTime(chg.SpawnTime)
+	readyTime := c.fmtTime(chg.ReadyTime)
+	if chg.ReadyTime.IsZero() {
+		readyTime = "-"
+	}
+	fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", chg.ID, chg.Status, spawnTime, readyTime, chg.Summary)
+}
+
+var followPollTime = time.Second
+
+// follow polls for changes, reporting the ones that are new or whose
+// status differs from what was seen before, until interrupted. snapd
+// cannot notify its clients of changes, so polling is the only way.
+func (c *cmdChanges) follow(opts *client.ChangesOptions, seen []*client.Change) error {
+	status := make(map[string]string, len(seen))
+	for _, chg := range seen {
+		status[chg.ID] = chg.Status
+	}
+
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt)
+	defer signal.Stop(sigs)
+
+	var tMax time.Time
+	for {
+		select {
+		case <-sigs:
+			return nil
+		case <-time.After(followPollTime):
+		}
+
+		changes, err := c.client.Changes(opts)
+		if err != nil {
+			// a client.Error means the server answered
+			if _, ok := err.(*client.Error); ok {
+				return err
+			}
+			// otherwise it most likely went away, e.g. to
+			// restart, give it some time to come back
+			now := time.Now()
+			if tMax.IsZero() {
+				tMax = now.Add(maxGoneTime)
+			}
+			if now.After(tMax) {
+				return err
+			}
+			continue
+		}
+		tMax = time.Time{}
+
+		sort.Sort(changesByTime(changes))
+
+		w := tabWriter()
+		for _, chg := range changes {
+			if status[chg.ID] == chg.Status {
+				continue
+			}
+			status[chg.ID] = chg.Status
+			c.printChange(w, chg)
+		}
+		w.Flush()
+	}
+}
+
 func (c *cmdTasks) Execute([]string) error {
 	chid, err := c.GetChangeID()
 	if err != nil {
@@ -23,6 +23,7 @@ import (
 	"fmt"
 	"net/http"
 	"strings"
+	"time"
 
 	"gopkg.in/check.v1"
 
@@ -231,3 +232,73 @@ Doing +2016-04-21T01:02:03Z +2016-04-21T01:02:04Z +some summary \(50.00%\)
 `)
 	c.Check(s.Stderr(), check.Equals, "")
 }
+
+func (s *SnapSuite) TestChangesFollow(c *check.C) {
+	defer snap.MockFollowPollTime(time.Millisecond)()
+
+	n := 0
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		c.Check(r.Method, check.Equals, "GET")
+		c.Check(r.URL.Path, check.Equals, "/v2/changes")
+		n++
+		switch n {
+		case 1:
+			fmt.Fprintln(w, `{"type": "sync", "result": [
+  {"id": "1", "kind": "install-snap", "summary": "Install foo", "status": "Doing", "spawn-time": "2016-04-21T01:02:03Z"},
+  {"id": "2", "kind": "remove-snap", "summary": "Remove bar", "status": "Done", "ready": true, "spawn-time": "2016-04-21T01:01:03Z", "ready-time": "2016-04-21T01:01:04Z"}
+]}`)
+		case 2:
+			// nothing happened
+			fmt.Fprintln(w, `{"type": "sync", "result": [
+  {"id": "1", "kind": "install-snap", "summary": "Install foo", "status": "Doing", "spawn-time": "2016-04-21T01:02:03Z"},
+  {"id": "2", "kind": "remove-snap", "summary": "Remove bar", "status": "Done", "ready": true, "spawn-time": "2016-04-21T01:01:03Z", "ready-time": "2016-04-21T01:01:04Z"}
+]}`)
+		case 3:
+			fmt.Fprintln(w, `{"type": "sync", "result": [
+  {"id": "1", "kind": "install-snap", "summary": "Install foo", "status": "Done", "ready": true, "spawn-time": "2016-04-21T01:02:03Z", "ready-time": "2016-04-21T01:04:03Z"},
+  {"id": "2", "kind": "remove-snap", "summary": "Remove bar", "status": "Done", "ready": true, "spawn-time": "2016-04-21T01:01:03Z", "ready-time": "2016-04-21T01:01:04Z"},
+  {"id": "3", "kind": "refresh-snap", "summary": "Refresh baz", "status": "Doing", "spawn-time": "2016-04-21T01:04:30Z"}
+]}`)
+		default:
+			w.WriteHeader(500)
+			fmt.Fprintln(w, `{"type": "error", "result": {"message": "boom"}}`)
+		}
+	})
+
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"changes", "--abs-time", "--follow"})
+	c.Assert(err, check.ErrorMatches, "boom")
+	c.Check(n, check.Equals, 4)
+	c.Check(s.Stdout(), check.Equals, `
+ID   Status  Spawn                 Ready                 Summary
+2    Done    2016-04-21T01:01:03Z  2016-04-21T01:01:04Z  Remove bar
+1    Doing   2016-04-21T01:02:03Z  -                     Install foo
+
+1    Done   2016-04-21T01:02:03Z  2016-04-21T01:04:03Z  Install foo
+3    Doing  2016-04-21T01:04:30Z  -                     Refresh baz
+`[1:])
+	c.Check(s.Stderr(), check.Equals, "")
+}
+
+func (s *SnapSuite) TestChangesFollowNoChanges(c *check.C) {
+	defer snap.MockFollowPollTime(time.Millisecond)()
+
+	n := 0
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		n++
+		switch n {
+		case 1:
+			fmt.Fprintln(w, `{"type": "sync", "result": []}`)
+		case 2:
+			fmt.Fprintln(w, `{"type": "sync", "result": [
+  {"id": "1", "kind": "install-snap", "summary": "Install foo", "status": "Doing", "spawn-time": "2016-04-21T01:02:03Z"}
+]}`)
+		default:
+			w.WriteHeader(500)
+			fmt.Fprintln(w, `{"type": "error", "result": {"message": "boom"}}`)
+		}
+	})
+
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"changes", "--abs-time", "--follow"})
+	c.Assert(err, check.ErrorMatches, "boom")
+	c.Check(s.Stdout(), check.Equals, "1    Doing  2016-04-21T01:02:03Z  -    Install foo\n")
+}
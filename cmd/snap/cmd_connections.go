@@ -20,6 +20,7 @@
 package main
 
 import (
+	"encoding/json"
 	"fmt"
 	"sort"
 	"strings"
@@ -32,7 +33,10 @@ import (
 
 type cmdConnections struct {
 	clientMixin
-	All         bool `long:"all"`
+	All         bool   `long:"all"`
+	Interface   string `long:"interface"`
+	Origin      string `long:"origin" choice:"manual" choice:"auto" choice:"gadget"`
+	Wide        bool   `long:"wide"`
 	Positionals struct {
 		Snap installedSnapName
 	} `positional-args:"true"`
@@ -51,6 +55,11 @@ $ snap connections <snap>
 
 Lists connected and unconnected plugs and slots for the specified
 snap.
+
+The listing can be further constrained to plugs and slots of a given
+interface with --interface, and to connections that were established
+manually, automatically or by the gadget with --origin. Pass --wide
+to also show the attributes of the plugs and slots.
 `)
 
 func init() {
@@ -58,6 +67,12 @@ func init() {
 		return &cmdConnections{}
 	}, map[string]string{
 		"all": i18n.G("Show connected and unconnected plugs and slots"),
+		// TRANSLATORS: This should not start with a lowercase letter.
+		"interface": i18n.G("Constrain listing to plugs and slots of the given interface"),
+		// TRANSLATORS: This should not start with a lowercase letter.
+		"origin": i18n.G("Constrain listing to connections established manually, automatically or by the gadget"),
+		// TRANSLATORS: This should not start with a lowercase letter.
+		"wide": i18n.G("Show the attributes of plugs and slots"),
 	}, []argDesc{{
 		// TRANSLATORS: This needs to be wrapped in <>s.
 		name: "<snap>",
@@ -84,6 +99,8 @@ type connection struct {
 	interfaceDeterminant string
 	manual               bool
 	gadget               bool
+	plugAttrs            map[string]interface{}
+	slotAttrs            map[string]interface{}
 }
 
 func (cn connection) String() string {
@@ -100,6 +117,33 @@ func (cn connection) String() string {
 	return strings.Join(opts, ",")
 }
 
+// formatAttrs renders attributes as a sorted, comma separated list of
+// key=value pairs, with non-string values in their JSON form.
+func formatAttrs(attrs map[string]interface{}) string {
+	if len(attrs) == 0 {
+		return "-"
+	}
+	keys := make([]string, 0, len(attrs))
+	for k := range attrs {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	pairs := make([]string, len(keys))
+	for i, k := range keys {
+		value, ok := attrs[k].(string)
+		if !ok {
+			buf, err := json.Marshal(attrs[k])
+			if err != nil {
+				value = fmt.Sprintf("%v", attrs[k])
+			} else {
+				value = string(buf)
+			}
+		}
+		pairs[i] = k + "=" + value
+	}
+	return strings.Join(pairs, ",")
+}
+
 type byConnectionData []connection
 
 func (b byConnectionData) Len() int      { return len(b) }
@@ -137,7 +181,9 @@ func (x *cmdConnections) Execute(args []string) error {
 	}
 
 	opts := client.ConnectionOptions{
-		All: x.All,
+		All:       x.All,
+		Interface: x.Interface,
+		Origin:    x.Origin,
 	}
 	wanted := string(x.Positionals.Snap)
 	if wanted != "" {
@@ -171,11 +217,17 @@ func (x *cmdConnections) Execute(args []string) error {
 			gadget:               conn.Gadget,
 			interfaceName:        conn.Interface,
 			interfaceDeterminant: interfaceDeterminant(&conn),
+			plugAttrs:            conn.PlugAttrs,
+			slotAttrs:            conn.SlotAttrs,
 		})
 	}
 
 	w := tabWriter()
-	fmt.Fprintln(w, i18n.G("Interface\tPlug\tSlot\tNotes"))
+	if x.Wide {
+		fmt.Fprintln(w, i18n.G("Interface\tPlug\tSlot\tNotes\tPlug attributes\tSlot attributes"))
+	} else {
+		fmt.Fprintln(w, i18n.G("Interface\tPlug\tSlot\tNotes"))
+	}
 
 	for _, plug := range connections.Plugs {
 		if len(plug.Connections) == 0 && x.All {
@@ -183,6 +235,7 @@ func (x *cmdConnections) Execute(args []string) error {
 				plug:          endpoint(plug.Snap, plug.Name),
 				slot:          "-",
 				interfaceName: plug.Interface,
+				plugAttrs:     plug.Attrs,
 			})
 		}
 	}
@@ -197,6 +250,7 @@ func (x *cmdConnections) Execute(args []string) error {
 				plug:          "-",
 				slot:          endpoint(slot.Snap, slot.Name),
 				interfaceName: slot.Interface,
+				slotAttrs:     slot.Attrs,
 			})
 		}
 	}
@@ -204,6 +258,10 @@ func (x *cmdConnections) Execute(args []string) error {
 	sort.Sort(byConnectionData(annotatedConns))
 
 	for _, note := range annotatedConns {
+		if x.Wide {
+			fmt.Fprintf(w, "%s%s\t%s\t%s\t%s\t%s\t%s\n", note.interfaceName, note.interfaceDeterminant, note.plug, note.slot, note, formatAttrs(note.plugAttrs), formatAttrs(note.slotAttrs))
+			continue
+		}
 		fmt.Fprintf(w, "%s%s\t%s\t%s\t%s\n", note.interfaceName, note.interfaceDeterminant, note.plug, note.slot, note)
 	}
 
@@ -851,3 +851,88 @@ func (s *SnapSuite) TestConnectionsDefiningAttribute(c *C) {
 	c.Assert(s.Stdout(), Equals, expectedStdout)
 	c.Assert(s.Stderr(), Equals, "")
 }
+
+func (s *SnapSuite) TestConnectionsInterfaceAndOrigin(c *C) {
+	result := client.Connections{}
+	query := url.Values{
+		"interface": []string{"network"},
+		"origin":    []string{"gadget"},
+	}
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		c.Check(r.Method, Equals, "GET")
+		c.Check(r.URL.Path, Equals, "/v2/connections")
+		c.Check(r.URL.Query(), DeepEquals, query)
+		EncodeResponseBody(c, w, map[string]interface{}{
+			"type":   "sync",
+			"result": result,
+		})
+	})
+
+	rest, err := Parser(Client()).ParseArgs([]string{"connections", "--interface=network", "--origin=gadget"})
+	c.Assert(err, IsNil)
+	c.Assert(rest, DeepEquals, []string{})
+
+	_, err = Parser(Client()).ParseArgs([]string{"connections", "--origin=bogus"})
+	c.Assert(err, ErrorMatches, `Invalid value .bogus. for option .--origin.*`)
+}
+
+func (s *SnapSuite) TestConnectionsWide(c *C) {
+	result := client.Connections{
+		Established: []client.Connection{
+			{
+				Plug:      client.PlugRef{Snap: "foo", Name: "a-plug"},
+				Slot:      client.SlotRef{Snap: "a-content-provider", Name: "data"},
+				Interface: "content",
+				Manual:    true,
+				PlugAttrs: map[string]interface{}{
+					"content": "some-data",
+					"target":  "$SNAP/foo",
+				},
+				SlotAttrs: map[string]interface{}{
+					"content": "some-data",
+					"source": map[string]interface{}{
+						"read": []string{"$SNAP/bar"},
+					},
+				},
+			},
+		},
+		Plugs: []client.Plug{
+			{
+				Snap:      "foo",
+				Name:      "a-plug",
+				Interface: "content",
+				Connections: []client.SlotRef{{
+					Snap: "a-content-provider",
+					Name: "data",
+				}},
+			}, {
+				Snap:      "foo",
+				Name:      "network",
+				Interface: "network",
+			},
+		},
+	}
+	query := url.Values{
+		"select": []string{"all"},
+		"snap":   []string{"foo"},
+	}
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		c.Check(r.Method, Equals, "GET")
+		c.Check(r.URL.Path, Equals, "/v2/connections")
+		c.Check(r.URL.Query(), DeepEquals, query)
+		EncodeResponseBody(c, w, map[string]interface{}{
+			"type":   "sync",
+			"result": result,
+		})
+	})
+
+	rest, err := Parser(Client()).ParseArgs([]string{"connections", "--wide", "foo"})
+	c.Assert(err, IsNil)
+	c.Assert(rest, DeepEquals, []string{})
+	expectedStdout := "" +
+		"Interface           Plug         Slot                     Notes   Plug attributes                     Slot attributes\n" +
+		"content[some-data]  foo:a-plug   a-content-provider:data  manual  content=some-data,target=$SNAP/foo  content=some-data,source={\"read\":[\"$SNAP/bar\"]}\n" +
+		"network             foo:network  -                        -       -                                   -\n"
+	c.Assert(s.Stdout(), Equals, expectedStdout)
+	c.Assert(s.Stderr(), Equals, "")
+}
@@ -0,0 +1,110 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2019 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/jessevdk/go-flags"
+
+	"github.com/snapcore/snapd/i18n"
+)
+
+type cmdDebugMounts struct {
+	clientMixin
+	Positional struct {
+		Snap installedSnapName `positional-arg-name:"<snap>" required:"yes"`
+	} `positional-args:"yes"`
+}
+
+func init() {
+	addDebugCommand("mounts",
+		i18n.G("Show the mount table of a snap"),
+		i18n.G(`
+The mounts command shows the mount entries snapd generates for the given
+snap with its current connections and layouts, in the order they are
+performed. Each entry is annotated with the interface or layout that
+produced it and with whether it is applied to the mount namespace of the
+snap. The per-user mount entries are shown with the users whose mount
+namespace they are applied to.
+`),
+		func() flags.Commander {
+			return &cmdDebugMounts{}
+		}, nil, []argDesc{{
+			// TRANSLATORS: This needs to begin with < and end with >
+			name: i18n.G("<snap>"),
+			// TRANSLATORS: This should not start with a lowercase letter.
+			desc: i18n.G("The snap whose mount table to show"),
+		}})
+}
+
+type mountEntry struct {
+	Entry      string `json:"entry"`
+	Origin     string `json:"origin"`
+	Applied    bool   `json:"applied"`
+	AppliedFor []int  `json:"applied-for"`
+}
+
+func (x *cmdDebugMounts) Execute(args []string) error {
+	if len(args) > 0 {
+		return ErrExtraArgs
+	}
+
+	var mounts struct {
+		Mounts     []mountEntry `json:"mounts"`
+		UserMounts []mountEntry `json:"user-mounts"`
+	}
+	params := map[string]string{"snap": string(x.Positional.Snap)}
+	if err := x.client.DebugGet("mounts", &mounts, params); err != nil {
+		return err
+	}
+
+	w := tabWriter()
+	defer w.Flush()
+
+	fmt.Fprintln(w, i18n.G("Origin\tApplied\tEntry"))
+	for _, e := range mounts.Mounts {
+		applied := "-"
+		if e.Applied {
+			applied = i18n.G("yes")
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", e.Origin, applied, e.Entry)
+	}
+	if len(mounts.UserMounts) == 0 {
+		return nil
+	}
+
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, i18n.G("Origin\tApplied-for\tUser entry"))
+	for _, e := range mounts.UserMounts {
+		applied := "-"
+		if len(e.AppliedFor) > 0 {
+			uids := make([]string, len(e.AppliedFor))
+			for i, uid := range e.AppliedFor {
+				uids[i] = strconv.Itoa(uid)
+			}
+			applied = strings.Join(uids, ",")
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", e.Origin, applied, e.Entry)
+	}
+	return nil
+}
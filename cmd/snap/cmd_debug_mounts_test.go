@@ -0,0 +1,80 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2019 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package main_test
+
+import (
+	"fmt"
+	"net/http"
+
+	"gopkg.in/check.v1"
+
+	snap "github.com/snapcore/snapd/cmd/snap"
+)
+
+func (s *SnapSuite) mockDebugMountsServer(c *check.C, result string) *int {
+	n := 0
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		switch n {
+		case 0:
+			c.Check(r.Method, check.Equals, "GET")
+			c.Check(r.URL.Path, check.Equals, "/v2/debug")
+			c.Check(r.URL.Query().Get("aspect"), check.Equals, "mounts")
+			c.Check(r.URL.Query().Get("snap"), check.Equals, "foo")
+			fmt.Fprintf(w, `{"type": "sync", "result": %s}`, result)
+		default:
+			c.Fatalf("expected to get 1 requests, now on %d", n+1)
+		}
+		n++
+	})
+	return &n
+}
+
+func (s *SnapSuite) TestDebugMounts(c *check.C) {
+	n := s.mockDebugMountsServer(c, `{
+		"mounts": [
+			{"entry": "/snap/foo/1/usr/share/foo /usr/share/foo none rbind,rw,x-snapd.origin=layout 0 0", "origin": "layout", "applied": true},
+			{"entry": "/var/snap/bar/common /snap/foo/1/content none bind,ro 0 0", "origin": "content"},
+			{"entry": "tmpfs /usr/share tmpfs x-snapd.synthetic 0 0", "origin": "synthetic", "applied": true}
+		],
+		"user-mounts": [
+			{"entry": "$XDG_RUNTIME_DIR/doc/by-app/snap.foo $XDG_RUNTIME_DIR/doc none bind,rw 0 0", "origin": "desktop", "applied-for": [1000, 1001]}
+		]
+	}`)
+	rest, err := snap.Parser(snap.Client()).ParseArgs([]string{"debug", "mounts", "foo"})
+	c.Assert(err, check.IsNil)
+	c.Assert(rest, check.DeepEquals, []string{})
+	c.Check(s.Stdout(), check.Equals, `Origin     Applied  Entry
+layout     yes      /snap/foo/1/usr/share/foo /usr/share/foo none rbind,rw,x-snapd.origin=layout 0 0
+content    -        /var/snap/bar/common /snap/foo/1/content none bind,ro 0 0
+synthetic  yes      tmpfs /usr/share tmpfs x-snapd.synthetic 0 0
+
+Origin   Applied-for  User entry
+desktop  1000,1001    $XDG_RUNTIME_DIR/doc/by-app/snap.foo $XDG_RUNTIME_DIR/doc none bind,rw 0 0
+`)
+	c.Check(s.Stderr(), check.Equals, "")
+	c.Check(*n, check.Equals, 1)
+}
+
+func (s *SnapSuite) TestDebugMountsNoUserMounts(c *check.C) {
+	s.mockDebugMountsServer(c, `{"mounts": [], "user-mounts": []}`)
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"debug", "mounts", "foo"})
+	c.Assert(err, check.IsNil)
+	c.Check(s.Stdout(), check.Equals, "Origin  Applied  Entry\n")
+}
@@ -0,0 +1,101 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2018 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/jessevdk/go-flags"
+
+	"github.com/snapcore/snapd/i18n"
+)
+
+type cmdSecurityProfiles struct {
+	clientMixin
+	Backend    string `long:"backend"`
+	Positional struct {
+		Snap installedSnapName `positional-arg-name:"<snap>" required:"yes"`
+	} `positional-args:"yes"`
+}
+
+func init() {
+	addDebugCommand("security-profiles",
+		i18n.G("Show the security profiles of a snap"),
+		i18n.G(`
+The security-profiles command shows the exact security profiles snapd
+generates for the given snap with its current connections, without
+writing or loading them. Each profile is preceded by a line with the name
+of its security backend and the path of its file.
+`),
+		func() flags.Commander {
+			return &cmdSecurityProfiles{}
+		}, map[string]string{
+			// TRANSLATORS: This should not start with a lowercase letter.
+			"backend": i18n.G("Only show the profiles of the given security backend (e.g. apparmor)"),
+		}, []argDesc{{
+			// TRANSLATORS: This needs to begin with < and end with >
+			name: i18n.G("<snap>"),
+			// TRANSLATORS: This should not start with a lowercase letter.
+			desc: i18n.G("The snap whose security profiles to show"),
+		}})
+}
+
+func (x *cmdSecurityProfiles) Execute(args []string) error {
+	if len(args) > 0 {
+		return ErrExtraArgs
+	}
+
+	var profiles map[string]map[string]string
+	params := map[string]string{"snap": string(x.Positional.Snap)}
+	if err := x.client.DebugGet("security-profiles", &profiles, params); err != nil {
+		return err
+	}
+
+	backends := make([]string, 0, len(profiles))
+	for backend := range profiles {
+		if x.Backend != "" && backend != x.Backend {
+			continue
+		}
+		backends = append(backends, backend)
+	}
+	if len(backends) == 0 && x.Backend != "" {
+		return fmt.Errorf(i18n.G("no security profiles for backend %q"), x.Backend)
+	}
+	sort.Strings(backends)
+
+	for _, backend := range backends {
+		paths := make([]string, 0, len(profiles[backend]))
+		for path := range profiles[backend] {
+			paths = append(paths, path)
+		}
+		sort.Strings(paths)
+		for _, path := range paths {
+			content := profiles[backend][path]
+			fmt.Fprintf(Stdout, "# %s: %s\n", backend, path)
+			fmt.Fprint(Stdout, content)
+			if !strings.HasSuffix(content, "\n") {
+				fmt.Fprintln(Stdout)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2018 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package main_test
+
+import (
+	"fmt"
+	"net/http"
+
+	"gopkg.in/check.v1"
+
+	snap "github.com/snapcore/snapd/cmd/snap"
+)
+
+func (s *SnapSuite) mockSecurityProfilesServer(c *check.C) *int {
+	n := 0
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		switch n {
+		case 0:
+			c.Check(r.Method, check.Equals, "GET")
+			c.Check(r.URL.Path, check.Equals, "/v2/debug")
+			c.Check(r.URL.Query().Get("aspect"), check.Equals, "security-profiles")
+			c.Check(r.URL.Query().Get("snap"), check.Equals, "foo")
+			fmt.Fprintln(w, `{"type": "sync", "result": {
+				"seccomp": {"/seccomp/snap.foo.foo.src": "seccomp profile\n"},
+				"apparmor": {"/apparmor/snap.foo.foo": "apparmor profile", "/apparmor/snap-update-ns.foo": "update-ns profile\n"}
+			}}`)
+		default:
+			c.Fatalf("expected to get 1 requests, now on %d", n+1)
+		}
+		n++
+	})
+	return &n
+}
+
+func (s *SnapSuite) TestDebugSecurityProfiles(c *check.C) {
+	n := s.mockSecurityProfilesServer(c)
+	rest, err := snap.Parser(snap.Client()).ParseArgs([]string{"debug", "security-profiles", "foo"})
+	c.Assert(err, check.IsNil)
+	c.Assert(rest, check.DeepEquals, []string{})
+	c.Check(s.Stdout(), check.Equals, `# apparmor: /apparmor/snap-update-ns.foo
+update-ns profile
+# apparmor: /apparmor/snap.foo.foo
+apparmor profile
+# seccomp: /seccomp/snap.foo.foo.src
+seccomp profile
+`)
+	c.Check(s.Stderr(), check.Equals, "")
+	c.Check(*n, check.Equals, 1)
+}
+
+func (s *SnapSuite) TestDebugSecurityProfilesBackend(c *check.C) {
+	s.mockSecurityProfilesServer(c)
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"debug", "security-profiles", "--backend=seccomp", "foo"})
+	c.Assert(err, check.IsNil)
+	c.Check(s.Stdout(), check.Equals, "# seccomp: /seccomp/snap.foo.foo.src\nseccomp profile\n")
+}
+
+func (s *SnapSuite) TestDebugSecurityProfilesUnknownBackend(c *check.C) {
+	s.mockSecurityProfilesServer(c)
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"debug", "security-profiles", "--backend=udev", "foo"})
+	c.Assert(err, check.ErrorMatches, `no security profiles for backend "udev"`)
+}
@@ -0,0 +1,134 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2018 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/jessevdk/go-flags"
+
+	"github.com/snapcore/snapd/i18n"
+)
+
+type cmdSeeding struct {
+	clientMixin
+}
+
+func init() {
+	addDebugCommand("seeding",
+		i18n.G("Show details about the seeding of the system"),
+		i18n.G(`
+The seeding command shows whether the system is seeded and, based on the
+timings recorded during the seeding change, how long loading the seed and
+seeding each snap took, how much of that was spent generating security
+profiles, and the first error encountered, if any. For classic images
+it also lists the packages recorded in the seed when it was prepared.
+`),
+		func() flags.Commander {
+			return &cmdSeeding{}
+		}, nil, nil)
+}
+
+type seedingSnapTimings struct {
+	Snap             string        `json:"snap"`
+	Duration         time.Duration `json:"duration"`
+	ProfilesDuration time.Duration `json:"profiles-duration,omitempty"`
+}
+
+type seedingInfo struct {
+	Seeded           bool                     `json:"seeded"`
+	ChangeID         string                   `json:"change-id,omitempty"`
+	SeedStartTime    *time.Time               `json:"seed-start-time,omitempty"`
+	SeedCompletion   *time.Time               `json:"seed-completion-time,omitempty"`
+	LoadTime         time.Duration            `json:"load-time,omitempty"`
+	ProfilesDuration time.Duration            `json:"profiles-duration,omitempty"`
+	SnapTimings      []*seedingSnapTimings    `json:"snap-timings,omitempty"`
+	SeedError        string                   `json:"seed-error,omitempty"`
+	ClassicPackages  []*seedingClassicPackage `json:"classic-packages,omitempty"`
+}
+
+type seedingClassicPackage struct {
+	Name         string `json:"name"`
+	Version      string `json:"version"`
+	Architecture string `json:"architecture,omitempty"`
+}
+
+func (x *cmdSeeding) Execute(args []string) error {
+	if len(args) > 0 {
+		return ErrExtraArgs
+	}
+
+	var info seedingInfo
+	if err := x.client.DebugGet("seeding", &info, nil); err != nil {
+		return err
+	}
+
+	w := tabWriter()
+	fmt.Fprintf(w, "seeded:\t%t\n", info.Seeded)
+	if info.ChangeID != "" {
+		fmt.Fprintf(w, "change-id:\t%s\n", info.ChangeID)
+	}
+	if info.SeedStartTime != nil {
+		fmt.Fprintf(w, "seed-start-time:\t%s\n", info.SeedStartTime.Format(time.RFC3339))
+	}
+	if info.SeedCompletion != nil {
+		fmt.Fprintf(w, "seed-completion-time:\t%s\n", info.SeedCompletion.Format(time.RFC3339))
+	}
+	if info.LoadTime != 0 {
+		fmt.Fprintf(w, "load-time:\t%s\n", formatDuration(info.LoadTime))
+	}
+	if info.ProfilesDuration != 0 {
+		fmt.Fprintf(w, "profiles-time:\t%s\n", formatDuration(info.ProfilesDuration))
+	}
+	if info.SeedError != "" {
+		fmt.Fprintf(w, "seed-error:\t%s\n", info.SeedError)
+	}
+	w.Flush()
+
+	if len(info.SnapTimings) > 0 {
+		fmt.Fprintln(Stdout)
+		w = tabWriter()
+		fmt.Fprintln(w, i18n.G("Snap\tDuration\tProfiles"))
+		for _, tm := range info.SnapTimings {
+			profiles := "-"
+			if tm.ProfilesDuration != 0 {
+				profiles = formatDuration(tm.ProfilesDuration)
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\n", tm.Snap, formatDuration(tm.Duration), profiles)
+		}
+		w.Flush()
+	}
+
+	if len(info.ClassicPackages) > 0 {
+		fmt.Fprintln(Stdout)
+		w = tabWriter()
+		fmt.Fprintln(w, i18n.G("Package\tVersion\tArchitecture"))
+		for _, pkg := range info.ClassicPackages {
+			arch := pkg.Architecture
+			if arch == "" {
+				arch = "-"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\n", pkg.Name, pkg.Version, arch)
+		}
+		w.Flush()
+	}
+	return nil
+}
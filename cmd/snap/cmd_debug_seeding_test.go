@@ -0,0 +1,107 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2018 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package main_test
+
+import (
+	"fmt"
+	"net/http"
+
+	"gopkg.in/check.v1"
+
+	snap "github.com/snapcore/snapd/cmd/snap"
+)
+
+func (s *SnapSuite) mockSeedingServer(c *check.C, result string) *int {
+	n := 0
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		switch n {
+		case 0:
+			c.Check(r.Method, check.Equals, "GET")
+			c.Check(r.URL.Path, check.Equals, "/v2/debug")
+			c.Check(r.URL.Query().Get("aspect"), check.Equals, "seeding")
+			fmt.Fprintf(w, `{"type": "sync", "result": %s}`, result)
+		default:
+			c.Fatalf("expected to get 1 requests, now on %d", n+1)
+		}
+		n++
+	})
+	return &n
+}
+
+func (s *SnapSuite) TestDebugSeeding(c *check.C) {
+	n := s.mockSeedingServer(c, `{
+		"seeded": false,
+		"change-id": "1",
+		"seed-start-time": "2019-11-29T10:00:00Z",
+		"load-time": 3000000000,
+		"profiles-duration": 750000000,
+		"snap-timings": [
+			{"snap": "core", "duration": 2500000000, "profiles-duration": 500000000},
+			{"snap": "some-snap", "duration": 40000000}
+		],
+		"seed-error": "cannot setup profiles (Setup snap \"core\" security profiles)"
+	}`)
+	rest, err := snap.Parser(snap.Client()).ParseArgs([]string{"debug", "seeding"})
+	c.Assert(err, check.IsNil)
+	c.Assert(rest, check.DeepEquals, []string{})
+	c.Check(s.Stdout(), check.Equals, `seeded:           false
+change-id:        1
+seed-start-time:  2019-11-29T10:00:00Z
+load-time:        3000ms
+profiles-time:    750ms
+seed-error:       cannot setup profiles (Setup snap "core" security profiles)
+
+Snap       Duration  Profiles
+core       2500ms    500ms
+some-snap  40ms      -
+`)
+	c.Check(s.Stderr(), check.Equals, "")
+	c.Check(*n, check.Equals, 1)
+}
+
+func (s *SnapSuite) TestDebugSeedingSeeded(c *check.C) {
+	s.mockSeedingServer(c, `{
+		"seeded": true,
+		"seed-completion-time": "2019-11-29T10:05:00Z"
+	}`)
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"debug", "seeding"})
+	c.Assert(err, check.IsNil)
+	c.Check(s.Stdout(), check.Equals, `seeded:                true
+seed-completion-time:  2019-11-29T10:05:00Z
+`)
+}
+
+func (s *SnapSuite) TestDebugSeedingClassicPackages(c *check.C) {
+	s.mockSeedingServer(c, `{
+		"seeded": true,
+		"classic-packages": [
+			{"name": "bash", "version": "5.0-4ubuntu1", "architecture": "amd64"},
+			{"name": "tzdata", "version": "2019c-3ubuntu1"}
+		]
+	}`)
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"debug", "seeding"})
+	c.Assert(err, check.IsNil)
+	c.Check(s.Stdout(), check.Equals, `seeded:  true
+
+Package  Version         Architecture
+bash     5.0-4ubuntu1    amd64
+tzdata   2019c-3ubuntu1  -
+`)
+}
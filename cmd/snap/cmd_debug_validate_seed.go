@@ -20,13 +20,21 @@
 package main
 
 import (
+	"encoding/json"
+
 	"github.com/jessevdk/go-flags"
 
 	"github.com/snapcore/snapd/image"
 )
 
 type cmdValidateSeed struct {
-	Positionals struct {
+	JSON           bool     `long:"json"`
+	CheckIntegrity bool     `long:"check-integrity"`
+	Core           bool     `long:"core"`
+	Strict         bool     `long:"strict"`
+	Warn           []string `long:"warn" value-name:"<code>"`
+	Ignore         []string `long:"ignore" value-name:"<code>"`
+	Positionals    struct {
 		SeedYamlPath string `positional-arg-name:"<seed-yaml-path>"`
 	} `positional-args:"true"`
 }
@@ -37,7 +45,14 @@ func init() {
 		"(internal) validate seed.yaml",
 		func() flags.Commander {
 			return &cmdValidateSeed{}
-		}, nil, nil)
+		}, map[string]string{
+			"json":            "(internal) print a machine-readable report of the findings",
+			"check-integrity": "(internal) also fully check the squashfs of each snap",
+			"core":            "(internal) check the seed as one for an Ubuntu Core image",
+			"strict":          "(internal) reject unknown keys and invalid snap-ids in seed.yaml",
+			"warn":            "(internal) only warn about findings with the given code",
+			"ignore":          "(internal) ignore findings with the given code",
+		}, nil)
 	cmd.hidden = true
 }
 
@@ -46,5 +61,30 @@ func (x *cmdValidateSeed) Execute(args []string) error {
 		return ErrExtraArgs
 	}
 
-	return image.ValidateSeed(x.Positionals.SeedYamlPath)
+	opts := &image.ValidateOptions{
+		CheckIntegrity: x.CheckIntegrity,
+		Ignore:         x.Ignore,
+		Core:           x.Core,
+		Strict:         x.Strict,
+	}
+	if len(x.Warn) > 0 {
+		opts.Severities = make(map[string]string, len(x.Warn))
+		for _, code := range x.Warn {
+			opts.Severities[code] = image.SeedFindingWarning
+		}
+	}
+	if !x.JSON {
+		return image.ValidateSeed(x.Positionals.SeedYamlPath, opts)
+	}
+
+	report, err := image.ValidateSeedReport(x.Positionals.SeedYamlPath, opts)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(report); err != nil {
+		return err
+	}
+	return report.Err()
 }
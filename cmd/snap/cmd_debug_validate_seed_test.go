@@ -20,12 +20,15 @@
 package main_test
 
 import (
+	"encoding/json"
 	"io/ioutil"
+	"os"
 	"path/filepath"
 
 	. "gopkg.in/check.v1"
 
 	snap "github.com/snapcore/snapd/cmd/snap"
+	"github.com/snapcore/snapd/snap/snaptest"
 )
 
 func (s *SnapSuite) TestDebugValidateSeedRegressionLp1825437(c *C) {
@@ -47,3 +50,89 @@ snaps:
 	_, err = snap.Parser(snap.Client()).ParseArgs([]string{"debug", "validate-seed", tmpf})
 	c.Assert(err, ErrorMatches, "cannot read seed yaml: empty element in seed")
 }
+
+func (s *SnapSuite) TestDebugValidateSeedJSON(c *C) {
+	seedDir := c.MkDir()
+	tmpf := filepath.Join(seedDir, "seed.yaml")
+	err := ioutil.WriteFile(tmpf, []byte(`
+snaps:
+ - name: core
+   file: core_1.snap
+`), 0644)
+	c.Assert(err, IsNil)
+
+	_, err = snap.Parser(snap.Client()).ParseArgs([]string{"debug", "validate-seed", "--json", tmpf})
+	c.Assert(err, ErrorMatches, `cannot validate seed:
+- seed has no assertions, snaps cannot be cross-checked
+- cannot open snap: .*
+- the core or snapd snap must be part of the seed`)
+
+	var report map[string]interface{}
+	c.Assert(json.Unmarshal(s.stdout.Bytes(), &report), IsNil)
+	c.Check(report["seed"], Equals, tmpf)
+	findings := report["findings"].([]interface{})
+	c.Assert(findings, HasLen, 3)
+	c.Check(findings[0].(map[string]interface{})["severity"], Equals, "error")
+	c.Check(findings[0].(map[string]interface{})["code"], Equals, "no-assertions")
+	c.Check(findings[1].(map[string]interface{})["severity"], Equals, "error")
+	c.Check(findings[1].(map[string]interface{})["code"], Equals, "snap-missing")
+	c.Check(findings[1].(map[string]interface{})["snap"], Equals, "core")
+	c.Check(findings[1].(map[string]interface{})["path"], Equals, filepath.Join(seedDir, "snaps", "core_1.snap"))
+	c.Check(findings[2].(map[string]interface{})["code"], Equals, "missing-core")
+}
+
+func (s *SnapSuite) TestDebugValidateSeedWarnAndIgnore(c *C) {
+	tmpf := filepath.Join(c.MkDir(), "seed.yaml")
+	err := ioutil.WriteFile(tmpf, []byte(`
+snaps:
+ - name: core
+   file: core_1.snap
+`), 0644)
+	c.Assert(err, IsNil)
+
+	_, err = snap.Parser(snap.Client()).ParseArgs([]string{"debug", "validate-seed", "--warn=snap-missing", "--warn=no-assertions", "--ignore=missing-core", tmpf})
+	c.Assert(err, IsNil)
+
+	_, err = snap.Parser(snap.Client()).ParseArgs([]string{"debug", "validate-seed", "--ignore=foo", tmpf})
+	c.Assert(err, ErrorMatches, `cannot ignore unknown seed validation code "foo"`)
+}
+
+func (s *SnapSuite) TestDebugValidateSeedCore(c *C) {
+	seedDir := c.MkDir()
+	tmpf := filepath.Join(seedDir, "seed.yaml")
+	err := ioutil.WriteFile(tmpf, []byte(`
+snaps:
+ - name: core
+   file: core_1.snap
+`), 0644)
+	c.Assert(err, IsNil)
+	c.Assert(os.MkdirAll(filepath.Join(seedDir, "snaps"), 0755), IsNil)
+	coreSnap := snaptest.MakeTestSnapWithFiles(c, "name: core\nversion: 1\ntype: os\nconfinement: devmode", nil)
+	c.Assert(os.Rename(coreSnap, filepath.Join(seedDir, "snaps", "core_1.snap")), IsNil)
+
+	_, err = snap.Parser(snap.Client()).ParseArgs([]string{"debug", "validate-seed", "--json", "--core", "--warn=no-assertions", tmpf})
+	c.Assert(err, IsNil)
+
+	var report map[string]interface{}
+	c.Assert(json.Unmarshal(s.stdout.Bytes(), &report), IsNil)
+	findings := report["findings"].([]interface{})
+	c.Assert(findings, HasLen, 2)
+	c.Check(findings[0].(map[string]interface{})["severity"], Equals, "warning")
+	c.Check(findings[0].(map[string]interface{})["code"], Equals, "no-assertions")
+	c.Check(findings[1].(map[string]interface{})["severity"], Equals, "warning")
+	c.Check(findings[1].(map[string]interface{})["code"], Equals, "devmode-snap")
+}
+
+func (s *SnapSuite) TestDebugValidateSeedStrict(c *C) {
+	tmpf := filepath.Join(c.MkDir(), "seed.yaml")
+	err := ioutil.WriteFile(tmpf, []byte(`
+snaps:
+ - name: core
+   chanel: stable
+   file: core_1.snap
+`), 0644)
+	c.Assert(err, IsNil)
+
+	_, err = snap.Parser(snap.Client()).ParseArgs([]string{"debug", "validate-seed", "--strict", tmpf})
+	c.Assert(err, ErrorMatches, `(?s)cannot read seed yaml: cannot unmarshal .*field chanel not found.*`)
+}
@@ -424,6 +424,7 @@ func (iw *infoWriter) maybePrintStandaloneVersion() {
 
 func (iw *infoWriter) maybePrintBuildDate() {
 	if iw.diskSnap == nil {
+		iw.maybePrintBuildProvenance()
 		return
 	}
 	if osutil.IsDirectory(iw.path) {
@@ -436,6 +437,19 @@ func (iw *infoWriter) maybePrintBuildDate() {
 	fmt.Fprintf(iw, "build-date:\t%s\n", iw.fmtTime(buildDate))
 }
 
+// maybePrintBuildProvenance prints the details from the snap-build
+// assertion of an installed snap, if there is one.
+func (iw *infoWriter) maybePrintBuildProvenance() {
+	if iw.localSnap == nil || iw.localSnap.Build == nil {
+		return
+	}
+	build := iw.localSnap.Build
+	fmt.Fprintf(iw, "build-date:\t%s\n", iw.fmtTime(build.Timestamp))
+	if build.Grade != "" {
+		fmt.Fprintf(iw, "build-grade:\t%s\n", build.Grade)
+	}
+}
+
 func (iw *infoWriter) maybePrintContact() error {
 	contact := strings.TrimPrefix(iw.theSnap.Contact, "mailto:")
 	if contact == "" {
@@ -299,6 +299,28 @@ func (s *infoSuite) TestMaybePrintBuildDate(c *check.C) {
 	c.Check(buf.String(), check.Equals, "build-date:\t"+buildDate+"\n")
 }
 
+func (s *infoSuite) TestMaybePrintBuildProvenance(c *check.C) {
+	var buf flushBuffer
+	iw := snap.NewInfoWriter(&buf)
+	t0 := time.Date(2019, 7, 1, 10, 24, 0, 0, time.UTC)
+
+	// installed snap without build information
+	snap.SetupSnap(iw, &client.Snap{}, nil, nil)
+	snap.MaybePrintBuildDate(iw)
+	c.Check(buf.String(), check.Equals, "")
+
+	// remote snaps don't carry build information
+	buf.Reset()
+	snap.SetupSnap(iw, nil, &client.Snap{Build: &client.SnapBuild{Timestamp: t0, Grade: "stable"}}, nil)
+	snap.MaybePrintBuildDate(iw)
+	c.Check(buf.String(), check.Equals, "")
+
+	buf.Reset()
+	snap.SetupSnap(iw, &client.Snap{Build: &client.SnapBuild{Timestamp: t0, Grade: "stable"}}, nil, nil)
+	snap.MaybePrintBuildDate(iw)
+	c.Check(buf.String(), check.Equals, "build-date:\t10:24AM\nbuild-grade:\tstable\n")
+}
+
 func (s *infoSuite) TestMaybePrintSum(c *check.C) {
 	var buf flushBuffer
 	// some prep
@@ -22,6 +22,8 @@ package main
 import (
 	"bufio"
 	"fmt"
+	"os"
+	"os/exec"
 	"strings"
 
 	"github.com/jessevdk/go-flags"
@@ -49,6 +51,11 @@ purchasing of snaps using 'snap buy', as well as some some developer-oriented
 features as detailed in the help for the find, install and refresh commands.
 
 An account can be set up at https://login.ubuntu.com
+
+If the account is protected by a FIDO2/U2F security key, the helper program
+named by the SNAP_LOGIN_SECURITY_KEY_HELPER environment variable is used to
+talk to the key: it is given the challenge on its standard input and needs to
+write the response of the key to its standard output.
 `)
 
 func init() {
@@ -65,6 +72,35 @@ func init() {
 		}})
 }
 
+// securityKeyHelperEnv names the environment variable holding the
+// helper program used to answer security key challenges.
+const securityKeyHelperEnv = "SNAP_LOGIN_SECURITY_KEY_HELPER"
+
+var answerSecurityKeyChallenge = func(challenge string) (string, error) {
+	helper := os.Getenv(securityKeyHelperEnv)
+	if helper == "" {
+		return "", fmt.Errorf(i18n.G("cannot use security key: %s is not set"), securityKeyHelperEnv)
+	}
+	cmd := exec.Command(helper)
+	cmd.Stdin = strings.NewReader(challenge)
+	cmd.Stderr = Stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf(i18n.G("cannot use security key: %v"), err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+func requestLoginWithSecurityKey(cli *client.Client, email, password, challenge string) error {
+	fmt.Fprintln(Stdout, i18n.G("Touch your security key to continue."))
+	response, err := answerSecurityKeyChallenge(challenge)
+	if err != nil {
+		return err
+	}
+	_, err = cli.LoginWithSecurityKey(email, password, response)
+	return err
+}
+
 func requestLoginWith2faRetry(cli *client.Client, email, password string) error {
 	var otp []byte
 	var err error
@@ -80,6 +116,9 @@ func requestLoginWith2faRetry(cli *client.Client, email, password string) error
 	for i := 0; ; i++ {
 		// first try is without otp
 		_, err = cli.Login(email, password, string(otp))
+		if challenge := client.SecurityKeyChallenge(err); challenge != "" {
+			return requestLoginWithSecurityKey(cli, email, password, challenge)
+		}
 		if i >= len(msgs) || !client.IsTwoFactorError(err) {
 			return err
 		}
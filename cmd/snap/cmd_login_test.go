@@ -91,3 +91,45 @@ Login successful
 	c.Check(s.Stderr(), Equals, "")
 	c.Check(n, Equals, 1)
 }
+
+func (s *SnapSuite) TestLoginSecurityKey(c *C) {
+	n := 0
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		c.Check(r.URL.Path, Equals, "/v2/login")
+		c.Check(r.Method, Equals, "POST")
+		postData, err := ioutil.ReadAll(r.Body)
+		c.Assert(err, IsNil)
+		switch n {
+		case 0:
+			c.Check(string(postData), Equals, `{"email":"foo@example.com","password":"some-password"}`+"\n")
+			w.WriteHeader(401)
+			fmt.Fprintln(w, `{"type": "error", "status-code": 401, "result": {"kind": "two-factor-required", "message": "two factor authentication with a security key required", "value": {"security-key-challenge": "the-challenge"}}}`)
+		case 1:
+			c.Check(string(postData), Equals, `{"email":"foo@example.com","password":"some-password","security-key-response":"the-response"}`+"\n")
+			fmt.Fprintln(w, mockLoginRsp)
+		default:
+			c.Fatalf("unexpected request %d", n)
+		}
+		n++
+	})
+
+	restore := snap.MockAnswerSecurityKeyChallenge(func(challenge string) (string, error) {
+		c.Check(challenge, Equals, "the-challenge")
+		return "the-response", nil
+	})
+	defer restore()
+
+	s.password = "some-password\n"
+	rest, err := snap.Parser(snap.Client()).ParseArgs([]string{"login", "foo@example.com"})
+	c.Assert(err, IsNil)
+	c.Assert(rest, DeepEquals, []string{})
+	c.Check(s.Stdout(), Equals, `Personal information is handled as per our privacy notice at
+https://www.ubuntu.com/legal/dataprivacy/snap-store
+
+Password of "foo@example.com": 
+Touch your security key to continue.
+Login successful
+`)
+	c.Check(s.Stderr(), Equals, "")
+	c.Check(n, Equals, 2)
+}
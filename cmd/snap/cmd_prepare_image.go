@@ -20,18 +20,40 @@
 package main
 
 import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 
 	"github.com/jessevdk/go-flags"
 
+	"github.com/snapcore/snapd/asserts/signtool"
 	"github.com/snapcore/snapd/i18n"
 	"github.com/snapcore/snapd/image"
 )
 
 type cmdPrepareImage struct {
-	Classic      bool   `long:"classic"`
-	Architecture string `long:"arch"`
+	Classic         bool   `long:"classic"`
+	Architecture    string `long:"arch"`
+	ClassicPackages bool   `long:"classic-packages"`
+
+	RequireAutoConnections bool `long:"require-auto-connections"`
+	RequireFDE             bool `long:"require-fde"`
+
+	Offline    bool     `long:"offline"`
+	Assertions []string `long:"assert" value-name:"<assertion-file>"`
+
+	DownloadConcurrency int    `long:"download-concurrency" value-name:"<n>"`
+	DeltaSource         string `long:"delta-source" value-name:"<dir>"`
+
+	Progress string `long:"progress" default:"bar" choice:"bar" choice:"json"`
+
+	SeedManifest string `long:"seed-manifest" value-name:"<file>"`
+	DryRun       bool   `long:"dry-run"`
 
 	Positional struct {
 		ModelAssertionFn string
@@ -42,6 +64,17 @@ type cmdPrepareImage struct {
 	// TODO: introduce SnapWithChannel?
 	Snaps      []string `long:"snap" value-name:"<snap>[=<channel>]"`
 	ExtraSnaps []string `long:"extra-snaps" hidden:"yes"` // DEPRECATED
+
+	Cohort      string   `long:"cohort" value-name:"<cohort-key>"`
+	SnapCohorts []string `long:"snap-cohort" value-name:"<snap>=<cohort-key>"`
+
+	ValidationSets []string `long:"validation-set" value-name:"<account-id>/<name>[=<seq>]"`
+	Revisions      string   `long:"revisions" value-name:"<file>"`
+
+	SeedAssertions []string `long:"seed-assertion" value-name:"<assertion-file>"`
+
+	KeyName keyName `short:"k" default:"default"`
+	remoteSignerMixin
 }
 
 func init() {
@@ -54,20 +87,56 @@ creating device images.
 For core images it is not invoked directly but usually via
 ubuntu-image.
 
-For preparing classic images it supports a --classic mode`),
+For preparing classic images it supports a --classic mode.
+
+With --remote the model is given by its headers, as for "snap sign
+--type model", and signed by the remote signing service at the given
+https URL, so that the brand key is never needed locally.`),
 		func() flags.Commander { return &cmdPrepareImage{} },
-		map[string]string{
+		remoteSignerDescs.also(map[string]string{
 			// TRANSLATORS: This should not start with a lowercase letter.
 			"classic": i18n.G("Enable classic mode to prepare a classic model image"),
 			// TRANSLATORS: This should not start with a lowercase letter.
 			"arch": i18n.G("Specify an architecture for snaps for --classic when the model does not"),
 			// TRANSLATORS: This should not start with a lowercase letter.
+			"classic-packages": i18n.G("Record in the seed the manifest of the packages installed in the target directory, for --classic"),
+			// TRANSLATORS: This should not start with a lowercase letter.
 			"snap": i18n.G("Include the given snap from the store or a local file and/or specify the channel to track for the given snap"),
 			// TRANSLATORS: This should not start with a lowercase letter.
 			"extra-snaps": i18n.G("Extra snaps to be installed (DEPRECATED)"),
 			// TRANSLATORS: This should not start with a lowercase letter.
 			"channel": i18n.G("The channel to use"),
-		}, []argDesc{
+			// TRANSLATORS: This should not start with a lowercase letter.
+			"cohort": i18n.G("Download the store snaps from the given cohort"),
+			// TRANSLATORS: This should not start with a lowercase letter.
+			"snap-cohort": i18n.G("Download the given snap from the given cohort, instead of the one from --cohort"),
+			// TRANSLATORS: This should not start with a lowercase letter.
+			"validation-set": i18n.G("Seed the snap revisions required by the given validation set, at the given sequence or the latest one, and enforce it on the device"),
+			// TRANSLATORS: This should not start with a lowercase letter.
+			"revisions": i18n.G("Download the store snaps at the revisions given in the file, as a JSON or YAML mapping of snap names to revisions or as a seed manifest"),
+			// TRANSLATORS: This should not start with a lowercase letter.
+			"seed-assertion": i18n.G("Add the assertions from the given file to the seed, together with their prerequisites"),
+			// TRANSLATORS: This should not start with a lowercase letter.
+			"require-auto-connections": i18n.G("Fail instead of warning when plugs of the seeded snaps will not be connected on first boot"),
+			// TRANSLATORS: This should not start with a lowercase letter.
+			"require-fde": i18n.G("Fail unless the kernel or gadget snap implements full disk encryption"),
+			// TRANSLATORS: This should not start with a lowercase letter.
+			"offline": i18n.G("Do not contact the store, all snaps must be given as local files"),
+			// TRANSLATORS: This should not start with a lowercase letter.
+			"assert": i18n.G("Use the assertions from the given file, for --offline"),
+			// TRANSLATORS: This should not start with a lowercase letter.
+			"download-concurrency": i18n.G("Download up to the given number of snaps from the store in parallel"),
+			// TRANSLATORS: This should not start with a lowercase letter.
+			"delta-source": i18n.G("Download deltas against the older revisions of the snaps found in the given directory, e.g. the seed of a previous image"),
+			// TRANSLATORS: This should not start with a lowercase letter.
+			"progress": i18n.G("How to show progress: with progress bars, or as JSON objects, one per line, on standard output"),
+			// TRANSLATORS: This should not start with a lowercase letter.
+			"seed-manifest": i18n.G("Write the list of the seeded snaps with their exact revisions to the given file"),
+			// TRANSLATORS: This should not start with a lowercase letter.
+			"dry-run": i18n.G("Only show the snaps that would be seeded, with their revisions, channels and sizes, without downloading them"),
+			// TRANSLATORS: This should not start with a lowercase letter.
+			"k": i18n.G("Name of the key of the remote signing service to sign the model with, otherwise use the default key"),
+		}), []argDesc{
 			{
 				// TRANSLATORS: This needs to begin with < and end with >
 				name: i18n.G("<model-assertion>"),
@@ -90,6 +159,36 @@ func (x *cmdPrepareImage) Execute(args []string) error {
 		ModelFile:    x.Positional.ModelAssertionFn,
 		Channel:      x.Channel,
 		Architecture: x.Architecture,
+
+		ClassicPackages: x.ClassicPackages,
+
+		RequireAutoConnections: x.RequireAutoConnections,
+		RequireFDE:             x.RequireFDE,
+
+		Offline:        x.Offline,
+		AssertionFiles: x.Assertions,
+
+		DownloadConcurrency: x.DownloadConcurrency,
+		DeltaSourceDir:      x.DeltaSource,
+
+		SeedManifestPath: x.SeedManifest,
+		DryRun:           x.DryRun,
+
+		ValidationSets:     x.ValidationSets,
+		SeedAssertionFiles: x.SeedAssertions,
+	}
+
+	keypairMgr, err := x.remoteKeypairManager()
+	if err != nil {
+		return err
+	}
+	if keypairMgr != nil {
+		modelFile, err := x.signModel(keypairMgr)
+		if err != nil {
+			return err
+		}
+		defer os.Remove(modelFile)
+		opts.ModelFile = modelFile
 	}
 
 	snaps := make([]string, 0, len(x.Snaps)+len(x.ExtraSnaps))
@@ -104,6 +203,26 @@ func (x *cmdPrepareImage) Execute(args []string) error {
 
 	snaps = append(snaps, x.ExtraSnaps...)
 
+	opts.Cohort = x.Cohort
+	for _, snapWCohort := range x.SnapCohorts {
+		snapAndCohort := strings.SplitN(snapWCohort, "=", 2)
+		if len(snapAndCohort) != 2 || snapAndCohort[0] == "" || snapAndCohort[1] == "" {
+			return fmt.Errorf(i18n.G("cannot parse --snap-cohort %q: expected <snap>=<cohort-key>"), snapWCohort)
+		}
+		if opts.SnapCohorts == nil {
+			opts.SnapCohorts = make(map[string]string)
+		}
+		opts.SnapCohorts[snapAndCohort[0]] = snapAndCohort[1]
+	}
+
+	if x.Revisions != "" {
+		revisions, err := image.ReadSnapRevisions(x.Revisions)
+		if err != nil {
+			return err
+		}
+		opts.SnapRevisions = revisions
+	}
+
 	if len(snaps) != 0 {
 		opts.Snaps = snaps
 	}
@@ -119,5 +238,108 @@ func (x *cmdPrepareImage) Execute(args []string) error {
 		opts.GadgetUnpackDir = filepath.Join(x.Positional.Rootdir, "gadget")
 	}
 
+	if x.Progress == "json" {
+		opts.Progress = newJSONProgress(Stdout)
+		// keep standard output for the progress only
+		oldStdout := image.Stdout
+		image.Stdout = Stderr
+		defer func() { image.Stdout = oldStdout }()
+	}
+
 	return imagePrepare(opts)
 }
+
+// signModel signs the model with the headers from the model file with
+// the given keypair manager and returns a temporary file holding the
+// signed model.
+func (x *cmdPrepareImage) signModel(keypairMgr namedKeypairManager) (string, error) {
+	input, err := ioutil.ReadFile(x.Positional.ModelAssertionFn)
+	if err != nil {
+		return "", fmt.Errorf(i18n.G("cannot read model headers: %v"), err)
+	}
+	headers, err := templateHeaders("model", input)
+	if err != nil {
+		return "", err
+	}
+	statement, err := json.Marshal(headers)
+	if err != nil {
+		return "", err
+	}
+	privKey, err := keypairMgr.GetByName(string(x.KeyName))
+	if err != nil {
+		return "", err
+	}
+	encodedModel, err := signtool.Sign(&signtool.Options{
+		KeyID:     privKey.PublicKey().ID(),
+		Statement: statement,
+	}, keypairMgr)
+	if err != nil {
+		return "", err
+	}
+
+	f, err := ioutil.TempFile("", "model")
+	if err != nil {
+		return "", err
+	}
+	_, err = f.Write(encodedModel)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return "", fmt.Errorf(i18n.G("cannot write signed model: %v"), err)
+	}
+	return f.Name(), nil
+}
+
+// jsonProgress is an image.ProgressReporter writing the progress as
+// JSON objects, one per line. Downloads are reported when their
+// percentage changes.
+type jsonProgress struct {
+	mu      sync.Mutex
+	enc     *json.Encoder
+	percent map[string]int
+}
+
+type jsonProgressEvent struct {
+	Phase    image.Phase           `json:"phase,omitempty"`
+	Download *jsonProgressDownload `json:"download,omitempty"`
+}
+
+type jsonProgressDownload struct {
+	Snap    string  `json:"snap"`
+	Current float64 `json:"current"`
+	Total   float64 `json:"total"`
+}
+
+func newJSONProgress(w io.Writer) *jsonProgress {
+	return &jsonProgress{
+		enc:     json.NewEncoder(w),
+		percent: make(map[string]int),
+	}
+}
+
+func (p *jsonProgress) Phase(phase image.Phase) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.enc.Encode(jsonProgressEvent{Phase: phase})
+}
+
+func (p *jsonProgress) SnapDownload(name string, current, total float64) {
+	if total <= 0 {
+		return
+	}
+	percent := int(100 * current / total)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if last, ok := p.percent[name]; ok && last == percent {
+		return
+	}
+	p.percent[name] = percent
+	p.enc.Encode(jsonProgressEvent{Download: &jsonProgressDownload{
+		Snap:    name,
+		Current: current,
+		Total:   total,
+	}})
+}
@@ -20,10 +20,18 @@
 package main_test
 
 import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
 	. "gopkg.in/check.v1"
 
+	"github.com/snapcore/snapd/asserts"
 	snap "github.com/snapcore/snapd/cmd/snap"
 	"github.com/snapcore/snapd/image"
+	snaplib "github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/testutil"
 )
 
 type SnapPrepareImageSuite struct {
@@ -53,6 +61,57 @@ func (s *SnapPrepareImageSuite) TestPrepareImageCore(c *C) {
 	})
 }
 
+func (s *SnapPrepareImageSuite) TestPrepareImageRemoteSignedModel(c *C) {
+	signer, restore := mockRemoteSigner(c)
+	defer restore()
+
+	var opts *image.Options
+	var model asserts.Assertion
+	prep := func(o *image.Options) error {
+		opts = o
+		encoded, err := ioutil.ReadFile(o.ModelFile)
+		c.Assert(err, IsNil)
+		model, err = asserts.Decode(encoded)
+		c.Assert(err, IsNil)
+		return nil
+	}
+	r := snap.MockImagePrepare(prep)
+	defer r()
+
+	modelHeaders := filepath.Join(c.MkDir(), "model.yaml")
+	err := ioutil.WriteFile(modelHeaders, []byte(`
+authority-id: my-brand
+model: my-model
+architecture: amd64
+gadget: pc
+kernel: pc-kernel
+timestamp: 2019-06-01T12:00:00Z
+`), 0644)
+	c.Assert(err, IsNil)
+
+	rest, err := snap.Parser(snap.Client()).ParseArgs([]string{"prepare-image", "--remote", "https://signer.example.com", "--remote-cert", "cert.pem", "--remote-key", "key.pem", modelHeaders, "root-dir"})
+	c.Assert(err, IsNil)
+	c.Assert(rest, DeepEquals, []string{})
+
+	c.Check(model.Type(), Equals, asserts.ModelType)
+	c.Check(model.HeaderString("brand-id"), Equals, "my-brand")
+	c.Check(model.HeaderString("model"), Equals, "my-model")
+	c.Check(model.SignKeyID(), Equals, signer.key.PublicKey().ID())
+	// the signed model does not outlive the preparation
+	c.Check(opts.ModelFile, testutil.FileAbsent)
+}
+
+func (s *SnapPrepareImageSuite) TestPrepareImageRemoteOptionsNeedRemote(c *C) {
+	r := snap.MockImagePrepare(func(o *image.Options) error {
+		c.Fatalf("unexpected call")
+		return nil
+	})
+	defer r()
+
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"prepare-image", "--remote-cert", "cert.pem", "model", "root-dir"})
+	c.Assert(err, ErrorMatches, `cannot use --remote-cert, --remote-key or --remote-ca without --remote`)
+}
+
 func (s *SnapPrepareImageSuite) TestPrepareImageClassic(c *C) {
 	var opts *image.Options
 	prep := func(o *image.Options) error {
@@ -118,3 +177,313 @@ func (s *SnapPrepareImageSuite) TestPrepareImageExtraSnaps(c *C) {
 		SnapChannels:    map[string]string{"bar": "t/edge"},
 	})
 }
+
+func (s *SnapPrepareImageSuite) TestPrepareImageRequireAutoConnections(c *C) {
+	var opts *image.Options
+	prep := func(o *image.Options) error {
+		opts = o
+		return nil
+	}
+	r := snap.MockImagePrepare(prep)
+	defer r()
+
+	rest, err := snap.Parser(snap.Client()).ParseArgs([]string{"prepare-image", "model", "root-dir", "--require-auto-connections"})
+	c.Assert(err, IsNil)
+	c.Assert(rest, DeepEquals, []string{})
+
+	c.Check(opts, DeepEquals, &image.Options{
+		ModelFile:              "model",
+		Channel:                "stable",
+		RootDir:                "root-dir/image",
+		GadgetUnpackDir:        "root-dir/gadget",
+		RequireAutoConnections: true,
+	})
+}
+
+func (s *SnapPrepareImageSuite) TestPrepareImageRequireFDE(c *C) {
+	var opts *image.Options
+	prep := func(o *image.Options) error {
+		opts = o
+		return nil
+	}
+	r := snap.MockImagePrepare(prep)
+	defer r()
+
+	rest, err := snap.Parser(snap.Client()).ParseArgs([]string{"prepare-image", "model", "root-dir", "--require-fde"})
+	c.Assert(err, IsNil)
+	c.Assert(rest, DeepEquals, []string{})
+
+	c.Check(opts, DeepEquals, &image.Options{
+		ModelFile:       "model",
+		Channel:         "stable",
+		RootDir:         "root-dir/image",
+		GadgetUnpackDir: "root-dir/gadget",
+		RequireFDE:      true,
+	})
+}
+
+func (s *SnapPrepareImageSuite) TestPrepareImageSeedManifest(c *C) {
+	var opts *image.Options
+	prep := func(o *image.Options) error {
+		opts = o
+		return nil
+	}
+	r := snap.MockImagePrepare(prep)
+	defer r()
+
+	rest, err := snap.Parser(snap.Client()).ParseArgs([]string{"prepare-image", "model", "root-dir", "--seed-manifest", "seed.manifest"})
+	c.Assert(err, IsNil)
+	c.Assert(rest, DeepEquals, []string{})
+
+	c.Check(opts, DeepEquals, &image.Options{
+		ModelFile:        "model",
+		Channel:          "stable",
+		RootDir:          "root-dir/image",
+		GadgetUnpackDir:  "root-dir/gadget",
+		SeedManifestPath: "seed.manifest",
+	})
+}
+
+func (s *SnapPrepareImageSuite) TestPrepareImageDryRun(c *C) {
+	var opts *image.Options
+	prep := func(o *image.Options) error {
+		opts = o
+		return nil
+	}
+	r := snap.MockImagePrepare(prep)
+	defer r()
+
+	rest, err := snap.Parser(snap.Client()).ParseArgs([]string{"prepare-image", "model", "root-dir", "--dry-run", "--snap", "foo=edge"})
+	c.Assert(err, IsNil)
+	c.Assert(rest, DeepEquals, []string{})
+
+	c.Check(opts, DeepEquals, &image.Options{
+		ModelFile:       "model",
+		Channel:         "stable",
+		RootDir:         "root-dir/image",
+		GadgetUnpackDir: "root-dir/gadget",
+		Snaps:           []string{"foo"},
+		SnapChannels:    map[string]string{"foo": "edge"},
+		DryRun:          true,
+	})
+}
+
+func (s *SnapPrepareImageSuite) TestPrepareImageCohorts(c *C) {
+	var opts *image.Options
+	prep := func(o *image.Options) error {
+		opts = o
+		return nil
+	}
+	r := snap.MockImagePrepare(prep)
+	defer r()
+
+	rest, err := snap.Parser(snap.Client()).ParseArgs([]string{"prepare-image", "model", "root-dir", "--cohort", "COHORT", "--snap-cohort", "foo=FOO-COHORT", "--snap-cohort", "bar=BAR=COHORT"})
+	c.Assert(err, IsNil)
+	c.Assert(rest, DeepEquals, []string{})
+
+	c.Check(opts, DeepEquals, &image.Options{
+		ModelFile:       "model",
+		Channel:         "stable",
+		RootDir:         "root-dir/image",
+		GadgetUnpackDir: "root-dir/gadget",
+		Cohort:          "COHORT",
+		SnapCohorts: map[string]string{
+			"foo": "FOO-COHORT",
+			"bar": "BAR=COHORT",
+		},
+	})
+}
+
+func (s *SnapPrepareImageSuite) TestPrepareImageSnapCohortInvalid(c *C) {
+	r := snap.MockImagePrepare(func(*image.Options) error {
+		c.Fatalf("unexpected call")
+		return nil
+	})
+	defer r()
+
+	for _, arg := range []string{"foo", "foo=", "=COHORT"} {
+		_, err := snap.Parser(snap.Client()).ParseArgs([]string{"prepare-image", "model", "root-dir", "--snap-cohort", arg})
+		c.Check(err, ErrorMatches, fmt.Sprintf(`cannot parse --snap-cohort %q: expected <snap>=<cohort-key>`, arg))
+	}
+}
+
+func (s *SnapPrepareImageSuite) TestPrepareImageValidationSets(c *C) {
+	var opts *image.Options
+	prep := func(o *image.Options) error {
+		opts = o
+		return nil
+	}
+	r := snap.MockImagePrepare(prep)
+	defer r()
+
+	rest, err := snap.Parser(snap.Client()).ParseArgs([]string{"prepare-image", "model", "root-dir", "--validation-set", "acme/base-set", "--validation-set", "acme/other-set=3"})
+	c.Assert(err, IsNil)
+	c.Assert(rest, DeepEquals, []string{})
+
+	c.Check(opts, DeepEquals, &image.Options{
+		ModelFile:       "model",
+		Channel:         "stable",
+		RootDir:         "root-dir/image",
+		GadgetUnpackDir: "root-dir/gadget",
+		ValidationSets:  []string{"acme/base-set", "acme/other-set=3"},
+	})
+}
+
+func (s *SnapPrepareImageSuite) TestPrepareImageSeedAssertions(c *C) {
+	var opts *image.Options
+	prep := func(o *image.Options) error {
+		opts = o
+		return nil
+	}
+	r := snap.MockImagePrepare(prep)
+	defer r()
+
+	rest, err := snap.Parser(snap.Client()).ParseArgs([]string{"prepare-image", "model", "root-dir", "--seed-assertion", "store.assert", "--seed-assertion", "users.assert"})
+	c.Assert(err, IsNil)
+	c.Assert(rest, DeepEquals, []string{})
+
+	c.Check(opts, DeepEquals, &image.Options{
+		ModelFile:          "model",
+		Channel:            "stable",
+		RootDir:            "root-dir/image",
+		GadgetUnpackDir:    "root-dir/gadget",
+		SeedAssertionFiles: []string{"store.assert", "users.assert"},
+	})
+}
+
+func (s *SnapPrepareImageSuite) TestPrepareImageRevisions(c *C) {
+	var opts *image.Options
+	prep := func(o *image.Options) error {
+		opts = o
+		return nil
+	}
+	r := snap.MockImagePrepare(prep)
+	defer r()
+
+	fn := filepath.Join(c.MkDir(), "pins.json")
+	err := ioutil.WriteFile(fn, []byte(`{"pc-kernel": 12, "foo": 3}`), 0644)
+	c.Assert(err, IsNil)
+
+	rest, err := snap.Parser(snap.Client()).ParseArgs([]string{"prepare-image", "model", "root-dir", "--revisions", fn})
+	c.Assert(err, IsNil)
+	c.Assert(rest, DeepEquals, []string{})
+
+	c.Check(opts, DeepEquals, &image.Options{
+		ModelFile:       "model",
+		Channel:         "stable",
+		RootDir:         "root-dir/image",
+		GadgetUnpackDir: "root-dir/gadget",
+		SnapRevisions: map[string]snaplib.Revision{
+			"pc-kernel": snaplib.R(12),
+			"foo":       snaplib.R(3),
+		},
+	})
+}
+
+func (s *SnapPrepareImageSuite) TestPrepareImageRevisionsError(c *C) {
+	r := snap.MockImagePrepare(func(*image.Options) error {
+		c.Fatalf("unexpected call")
+		return nil
+	})
+	defer r()
+
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"prepare-image", "model", "root-dir", "--revisions", filepath.Join(c.MkDir(), "missing")})
+	c.Assert(err, ErrorMatches, "cannot read snap revisions: open .*/missing: no such file or directory")
+}
+
+func (s *SnapPrepareImageSuite) TestPrepareImageOffline(c *C) {
+	var opts *image.Options
+	prep := func(o *image.Options) error {
+		opts = o
+		return nil
+	}
+	r := snap.MockImagePrepare(prep)
+	defer r()
+
+	rest, err := snap.Parser(snap.Client()).ParseArgs([]string{"prepare-image", "model", "root-dir", "--offline", "--snap", "core_1.snap", "--assert", "core.assert", "--assert", "brand.assert"})
+	c.Assert(err, IsNil)
+	c.Assert(rest, DeepEquals, []string{})
+
+	c.Check(opts, DeepEquals, &image.Options{
+		ModelFile:       "model",
+		Channel:         "stable",
+		RootDir:         "root-dir/image",
+		GadgetUnpackDir: "root-dir/gadget",
+		Snaps:           []string{"core_1.snap"},
+		Offline:         true,
+		AssertionFiles:  []string{"core.assert", "brand.assert"},
+	})
+}
+
+func (s *SnapPrepareImageSuite) TestPrepareImageDownloadConcurrency(c *C) {
+	var opts *image.Options
+	prep := func(o *image.Options) error {
+		opts = o
+		return nil
+	}
+	r := snap.MockImagePrepare(prep)
+	defer r()
+
+	rest, err := snap.Parser(snap.Client()).ParseArgs([]string{"prepare-image", "model", "root-dir", "--download-concurrency", "4"})
+	c.Assert(err, IsNil)
+	c.Assert(rest, DeepEquals, []string{})
+
+	c.Check(opts, DeepEquals, &image.Options{
+		ModelFile:           "model",
+		Channel:             "stable",
+		RootDir:             "root-dir/image",
+		GadgetUnpackDir:     "root-dir/gadget",
+		DownloadConcurrency: 4,
+	})
+}
+
+func (s *SnapPrepareImageSuite) TestPrepareImageProgressJSON(c *C) {
+	prep := func(o *image.Options) error {
+		c.Assert(o.Progress, NotNil)
+		o.Progress.Phase(image.PhaseFetchSnaps)
+		o.Progress.SnapDownload("core", 0, 1000)
+		o.Progress.SnapDownload("core", 1, 1000)
+		o.Progress.SnapDownload("core", 500, 1000)
+		o.Progress.SnapDownload("core", 1000, 1000)
+		o.Progress.Phase(image.PhaseWriteSeed)
+		// human readable messages go to stderr
+		c.Check(image.Stdout, Equals, snap.Stderr)
+		return nil
+	}
+	r := snap.MockImagePrepare(prep)
+	defer r()
+
+	rest, err := snap.Parser(snap.Client()).ParseArgs([]string{"prepare-image", "model", "root-dir", "--progress=json"})
+	c.Assert(err, IsNil)
+	c.Assert(rest, DeepEquals, []string{})
+
+	c.Check(s.Stdout(), Equals, `{"phase":"fetch-snaps"}
+{"download":{"snap":"core","current":0,"total":1000}}
+{"download":{"snap":"core","current":500,"total":1000}}
+{"download":{"snap":"core","current":1000,"total":1000}}
+{"phase":"write-seed"}
+`)
+	c.Check(image.Stdout, Equals, os.Stdout)
+}
+
+func (s *SnapPrepareImageSuite) TestPrepareImageDeltaSource(c *C) {
+	var opts *image.Options
+	prep := func(o *image.Options) error {
+		opts = o
+		return nil
+	}
+	r := snap.MockImagePrepare(prep)
+	defer r()
+
+	rest, err := snap.Parser(snap.Client()).ParseArgs([]string{"prepare-image", "model", "root-dir", "--delta-source", "prev/seed/snaps"})
+	c.Assert(err, IsNil)
+	c.Assert(rest, DeepEquals, []string{})
+
+	c.Check(opts, DeepEquals, &image.Options{
+		ModelFile:       "model",
+		Channel:         "stable",
+		RootDir:         "root-dir/image",
+		GadgetUnpackDir: "root-dir/gadget",
+		DeltaSourceDir:  "prev/seed/snaps",
+	})
+}
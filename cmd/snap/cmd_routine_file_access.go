@@ -0,0 +1,97 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2018 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/jessevdk/go-flags"
+
+	"github.com/snapcore/snapd/i18n"
+)
+
+type cmdRoutineFileAccess struct {
+	clientMixin
+	FileAccessOptions struct {
+		Snap installedSnapName
+		Path flags.Filename
+	} `positional-args:"true" required:"true"`
+}
+
+var shortRoutineFileAccessHelp = i18n.G("Return information about file access by a snap")
+var longRoutineFileAccessHelp = i18n.G(`
+The file-access command returns information about a snap's file system access.
+
+This command is used by the xdg-document-portal service and file
+managers to identify files that do not need to be exported to the snap.
+
+The answer is computed by snapd from the data directories of the snap,
+its "home", "removable-media", "personal-files" and "system-files"
+connections, and the bind mounts of its mount namespace, for the
+calling user. Access granted by other interfaces is not evaluated and
+such paths are reported as hidden, so that the caller errs on the side
+of exporting them.
+
+The possible outputs are:
+  read-write: the snap can read and write the path
+  read-only:  the snap can only read the path
+  hidden:     the snap cannot see the path
+`)
+
+func init() {
+	addRoutineCommand("file-access", shortRoutineFileAccessHelp, longRoutineFileAccessHelp, func() flags.Commander {
+		return &cmdRoutineFileAccess{}
+	}, nil, []argDesc{
+		{
+			// TRANSLATORS: This needs to begin with < and end with >
+			name: i18n.G("<snap>"),
+			// TRANSLATORS: This should not start with a lowercase letter.
+			desc: i18n.G("Snap name"),
+		},
+		{
+			// TRANSLATORS: This needs to begin with < and end with >
+			name: i18n.G("<path>"),
+			// TRANSLATORS: This should not start with a lowercase letter.
+			desc: i18n.G("File path"),
+		},
+	})
+}
+
+func (x *cmdRoutineFileAccess) Execute(args []string) error {
+	if len(args) > 0 {
+		return ErrExtraArgs
+	}
+
+	snapName := string(x.FileAccessOptions.Snap)
+	// snapd resolves the path as seen from the host, relative paths
+	// are relative to the working directory of the caller
+	path, err := filepath.Abs(string(x.FileAccessOptions.Path))
+	if err != nil {
+		return err
+	}
+
+	access, err := x.client.FileAccess(snapName, path)
+	if err != nil {
+		return fmt.Errorf(i18n.G("cannot check access of snap %q to %q: %v"), snapName, path, err)
+	}
+	fmt.Fprintln(Stdout, access)
+	return nil
+}
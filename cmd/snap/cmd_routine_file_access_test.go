@@ -0,0 +1,76 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2018 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package main_test
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/check.v1"
+
+	snap "github.com/snapcore/snapd/cmd/snap"
+)
+
+func (s *SnapSuite) TestFileAccess(c *check.C) {
+	n := 0
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		n++
+		c.Check(r.Method, check.Equals, "GET")
+		c.Check(r.URL.Path, check.Equals, "/v2/snaps/hello/file-access")
+		c.Check(r.URL.Query().Get("path"), check.Equals, "/home/user/Documents/foo.txt")
+		fmt.Fprint(w, `{"type": "sync", "status-code": 200, "result": {"access": "read-write"}}`)
+	})
+
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"routine", "file-access", "hello", "/home/user/Documents/../Documents/foo.txt"})
+	c.Assert(err, check.IsNil)
+	c.Check(s.Stdout(), check.Equals, "read-write\n")
+	c.Check(s.Stderr(), check.Equals, "")
+	c.Check(n, check.Equals, 1)
+}
+
+func (s *SnapSuite) TestFileAccessRelativePath(c *check.C) {
+	dir := c.MkDir()
+	oldCwd, err := os.Getwd()
+	c.Assert(err, check.IsNil)
+	c.Assert(os.Chdir(dir), check.IsNil)
+	defer os.Chdir(oldCwd)
+
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		c.Check(r.URL.Query().Get("path"), check.Equals, filepath.Join(dir, "foo.txt"))
+		fmt.Fprint(w, `{"type": "sync", "status-code": 200, "result": {"access": "hidden"}}`)
+	})
+
+	_, err = snap.Parser(snap.Client()).ParseArgs([]string{"routine", "file-access", "hello", "foo.txt"})
+	c.Assert(err, check.IsNil)
+	c.Check(s.Stdout(), check.Equals, "hidden\n")
+}
+
+func (s *SnapSuite) TestFileAccessError(c *check.C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		c.Check(r.URL.Path, check.Equals, "/v2/snaps/hello/file-access")
+		w.WriteHeader(404)
+		fmt.Fprint(w, `{"type": "error", "status-code": 404, "result": {"message": "snap not installed", "kind": "snap-not-found"}}`)
+	})
+
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"routine", "file-access", "hello", "/etc/passwd"})
+	c.Assert(err, check.ErrorMatches, `cannot check access of snap "hello" to "/etc/passwd": cannot check file access: snap not installed`)
+}
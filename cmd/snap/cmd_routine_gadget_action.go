@@ -0,0 +1,84 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2019 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/jessevdk/go-flags"
+
+	"github.com/snapcore/snapd/i18n"
+)
+
+type cmdRoutineGadgetAction struct {
+	waitMixin
+	Positionals struct {
+		Action string
+	} `positional-args:"true"`
+}
+
+var shortRoutineGadgetActionHelp = i18n.G("List or run maintenance actions of the device")
+var longRoutineGadgetActionHelp = i18n.G(`
+The gadget-action command runs the given maintenance action of the device,
+as declared and implemented by its gadget, for example to blink a LED or
+to export diagnostics.
+
+Without an action it lists the available actions, one per line, with
+their name and summary separated by a tab.
+`)
+
+func init() {
+	addRoutineCommand("gadget-action", shortRoutineGadgetActionHelp, longRoutineGadgetActionHelp, func() flags.Commander {
+		return &cmdRoutineGadgetAction{}
+	}, waitDescs.also(nil), []argDesc{
+		{
+			// TRANSLATORS: This needs to begin with < and end with >
+			name: i18n.G("<action>"),
+			// TRANSLATORS: This should not start with a lowercase letter.
+			desc: i18n.G("Gadget action name"),
+		},
+	})
+}
+
+func (x *cmdRoutineGadgetAction) Execute(args []string) error {
+	if len(args) > 0 {
+		return ErrExtraArgs
+	}
+
+	if x.Positionals.Action == "" {
+		actions, err := x.client.GadgetActions()
+		if err != nil {
+			return err
+		}
+		for _, action := range actions {
+			fmt.Fprintf(Stdout, "%s\t%s\n", action.Name, action.Summary)
+		}
+		return nil
+	}
+
+	id, err := x.client.RunGadgetAction(x.Positionals.Action)
+	if err != nil {
+		return err
+	}
+	if _, err := x.wait(id); err != nil && err != noWait {
+		return err
+	}
+	return nil
+}
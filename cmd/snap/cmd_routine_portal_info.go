@@ -0,0 +1,149 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"github.com/jessevdk/go-flags"
+
+	"github.com/snapcore/snapd/client"
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/i18n"
+	"github.com/snapcore/snapd/sandbox/cgroup"
+)
+
+type cmdRoutinePortalInfo struct {
+	clientMixin
+	PortalInfoOptions struct {
+		Pid int
+	} `positional-args:"true" required:"true"`
+}
+
+var shortRoutinePortalInfoHelp = i18n.G("Return information about a process")
+var longRoutinePortalInfoHelp = i18n.G(`
+The portal-info command returns information about a process in keyfile format.
+
+This command is used by the xdg-desktop-portal service to retrieve
+information about snap confined processes.
+`)
+
+func init() {
+	addRoutineCommand("portal-info", shortRoutinePortalInfoHelp, longRoutinePortalInfoHelp, func() flags.Commander {
+		return &cmdRoutinePortalInfo{}
+	}, nil, []argDesc{{
+		// TRANSLATORS: This needs to begin with < and end with >
+		name: i18n.G("<process ID>"),
+		// TRANSLATORS: This should not start with a lowercase letter.
+		desc: i18n.G("Process ID of confined app"),
+	}})
+}
+
+var snapNameFromPid = cgroup.SnapNameFromPid
+
+// appNameFromPid returns the name of the app of the given snap the process
+// runs as, according to its AppArmor label, or "" when the process is not
+// running an app of that snap, e.g. when it is a hook or unconfined.
+func appNameFromPid(pid int, snapName string) string {
+	label, err := ioutil.ReadFile(filepath.Join(dirs.GlobalRootDir, fmt.Sprintf("proc/%d/attr/current", pid)))
+	if err != nil {
+		return ""
+	}
+	// the label looks like "snap.hello-world.app (enforce)"
+	fields := strings.Fields(string(label))
+	if len(fields) == 0 {
+		return ""
+	}
+	parts := strings.SplitN(fields[0], ".", 3)
+	if len(parts) != 3 || parts[0] != "snap" || parts[1] != snapName || strings.HasPrefix(parts[2], "hook.") {
+		return ""
+	}
+	return parts[2]
+}
+
+func findPortalApp(snap *client.Snap, appName string) *client.AppInfo {
+	if appName != "" {
+		for i := range snap.Apps {
+			if snap.Apps[i].Name == appName {
+				return &snap.Apps[i]
+			}
+		}
+	}
+	// the app could not be identified, use the only app with a desktop
+	// file, if any
+	var found *client.AppInfo
+	for i := range snap.Apps {
+		if snap.Apps[i].DesktopFile == "" {
+			continue
+		}
+		if found != nil {
+			return nil
+		}
+		found = &snap.Apps[i]
+	}
+	return found
+}
+
+func (x *cmdRoutinePortalInfo) Execute(args []string) error {
+	if len(args) > 0 {
+		return ErrExtraArgs
+	}
+
+	pid := x.PortalInfoOptions.Pid
+	snapName, err := snapNameFromPid(pid)
+	if err != nil {
+		return err
+	}
+	snap, _, err := x.client.Snap(snapName)
+	if err != nil {
+		return fmt.Errorf(i18n.G("cannot retrieve info for snap %q: %v"), snapName, err)
+	}
+
+	app := findPortalApp(snap, appNameFromPid(pid, snapName))
+
+	connections, err := x.client.Connections(&client.ConnectionOptions{
+		Snap:      snapName,
+		Interface: "network-status",
+	})
+	if err != nil {
+		return fmt.Errorf(i18n.G("cannot get connections for snap %q: %v"), snapName, err)
+	}
+	hasNetworkStatus := false
+	for _, conn := range connections.Established {
+		if conn.Plug.Snap == snapName && conn.Interface == "network-status" {
+			hasNetworkStatus = true
+			break
+		}
+	}
+
+	fmt.Fprintf(Stdout, "[Snap Info]\n")
+	fmt.Fprintf(Stdout, "InstanceName=%s\n", snapName)
+	if app != nil {
+		fmt.Fprintf(Stdout, "AppName=%s\n", app.Name)
+		if app.DesktopFile != "" {
+			fmt.Fprintf(Stdout, "DesktopFile=%s\n", filepath.Base(app.DesktopFile))
+		}
+	}
+	fmt.Fprintf(Stdout, "HasNetworkStatus=%v\n", hasNetworkStatus)
+	return nil
+}
@@ -0,0 +1,196 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2019 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package main_test
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/check.v1"
+
+	snap "github.com/snapcore/snapd/cmd/snap"
+	"github.com/snapcore/snapd/dirs"
+)
+
+// only used for /v2/snaps/hello
+const mockInfoJSONWithApps = `
+{
+  "type": "sync",
+  "status-code": 200,
+  "status": "OK",
+  "result": {
+    "id": "mVyGrEwiqSi5PugCwyH7WgpoQLemtTd6",
+    "title": "hello",
+    "summary": "GNU Hello, the \"hello world\" snap",
+    "description": "GNU hello prints a friendly greeting. This is part of the snapcraft tour at https://snapcraft.io/",
+    "installed-size": 98304,
+    "name": "hello",
+    "publisher": {
+      "id": "canonical",
+      "username": "canonical",
+      "display-name": "Canonical",
+      "validation": "verified"
+    },
+    "developer": "canonical",
+    "status": "active",
+    "type": "app",
+    "version": "2.10",
+    "channel": "stable",
+    "tracking-channel": "stable",
+    "ignore-validation": false,
+    "revision": "38",
+    "confinement": "strict",
+    "private": false,
+    "devmode": false,
+    "jailmode": false,
+    "apps": [
+      {
+        "snap": "hello",
+        "name": "hello",
+        "desktop-file": "/path/to/hello_hello.desktop"
+      },
+      {
+        "snap": "hello",
+        "name": "universe"
+      }
+    ],
+    "contact": "mailto:snaps@canonical.com",
+    "mounted-from": "/var/lib/snapd/snaps/hello_38.snap",
+    "install-date": "2019-10-11T13:21:40.353979931+02:00"
+  }
+}
+`
+
+const mockConnectionsJSON = `
+{
+  "type": "sync",
+  "status-code": 200,
+  "status": "OK",
+  "result": {
+    "established": [
+      {
+        "slot": {"snap": "core", "slot": "network-status"},
+        "plug": {"snap": "hello", "plug": "network-status"},
+        "interface": "network-status"
+      }
+    ]
+  }
+}
+`
+
+func (s *SnapSuite) mockPortalInfoServer(c *check.C, connectionsJSON string) {
+	n := 0
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		switch n {
+		case 0:
+			c.Check(r.Method, check.Equals, "GET")
+			c.Check(r.URL.Path, check.Equals, "/v2/snaps/hello")
+			fmt.Fprint(w, mockInfoJSONWithApps)
+		case 1:
+			c.Check(r.Method, check.Equals, "GET")
+			c.Check(r.URL.Path, check.Equals, "/v2/connections")
+			c.Check(r.URL.Query().Get("snap"), check.Equals, "hello")
+			c.Check(r.URL.Query().Get("interface"), check.Equals, "network-status")
+			fmt.Fprintln(w, connectionsJSON)
+		default:
+			c.Fatalf("expected to get 2 requests, now on %d (%v)", n+1, r)
+		}
+		n++
+	})
+}
+
+func (s *SnapSuite) mockAppArmorLabel(c *check.C, pid int, label string) {
+	procDir := filepath.Join(dirs.GlobalRootDir, fmt.Sprintf("proc/%d/attr", pid))
+	c.Assert(os.MkdirAll(procDir, 0755), check.IsNil)
+	c.Assert(ioutil.WriteFile(filepath.Join(procDir, "current"), []byte(label), 0644), check.IsNil)
+}
+
+func (s *SnapSuite) TestPortalInfo(c *check.C) {
+	restore := snap.MockSnapNameFromPid(func(pid int) (string, error) {
+		c.Check(pid, check.Equals, 42)
+		return "hello", nil
+	})
+	defer restore()
+	s.mockPortalInfoServer(c, mockConnectionsJSON)
+	s.mockAppArmorLabel(c, 42, "snap.hello.hello (enforce)\n")
+
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"routine", "portal-info", "42"})
+	c.Assert(err, check.IsNil)
+	c.Check(s.Stdout(), check.Equals, `[Snap Info]
+InstanceName=hello
+AppName=hello
+DesktopFile=hello_hello.desktop
+HasNetworkStatus=true
+`)
+	c.Check(s.Stderr(), check.Equals, "")
+}
+
+func (s *SnapSuite) TestPortalInfoAppWithoutDesktopFile(c *check.C) {
+	restore := snap.MockSnapNameFromPid(func(pid int) (string, error) {
+		return "hello", nil
+	})
+	defer restore()
+	s.mockPortalInfoServer(c, `{"type": "sync", "result": {"established": []}}`)
+	s.mockAppArmorLabel(c, 42, "snap.hello.universe (enforce)\n")
+
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"routine", "portal-info", "42"})
+	c.Assert(err, check.IsNil)
+	c.Check(s.Stdout(), check.Equals, `[Snap Info]
+InstanceName=hello
+AppName=universe
+HasNetworkStatus=false
+`)
+}
+
+func (s *SnapSuite) TestPortalInfoUnknownApp(c *check.C) {
+	restore := snap.MockSnapNameFromPid(func(pid int) (string, error) {
+		return "hello", nil
+	})
+	defer restore()
+	s.mockPortalInfoServer(c, mockConnectionsJSON)
+	// hooks are not apps, the app with a desktop file is used instead
+	s.mockAppArmorLabel(c, 42, "snap.hello.hook.configure (enforce)\n")
+
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"routine", "portal-info", "42"})
+	c.Assert(err, check.IsNil)
+	c.Check(s.Stdout(), check.Equals, `[Snap Info]
+InstanceName=hello
+AppName=hello
+DesktopFile=hello_hello.desktop
+HasNetworkStatus=true
+`)
+}
+
+func (s *SnapSuite) TestPortalInfoNotASnap(c *check.C) {
+	restore := snap.MockSnapNameFromPid(func(pid int) (string, error) {
+		return "", errors.New("cannot find a snap for pid 42")
+	})
+	defer restore()
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		c.Fatalf("unexpected request")
+	})
+
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"routine", "portal-info", "42"})
+	c.Assert(err, check.ErrorMatches, "cannot find a snap for pid 42")
+}
@@ -29,6 +29,7 @@ import (
 	"os/user"
 	"path/filepath"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 	"syscall"
@@ -75,6 +76,7 @@ type cmdRun struct {
 	Strace    string `long:"strace" optional:"true" optional-value:"with-strace" default:"no-strace" default-mask:"-"`
 	Gdb       bool   `long:"gdb"`
 	TraceExec bool   `long:"trace-exec"`
+	PrintEnv  bool   `long:"print-env"`
 
 	// not a real option, used to check if cmdRun is initialized by
 	// the parser
@@ -108,6 +110,8 @@ and environment.
 			"timer": i18n.G("Run as a timer service with given schedule"),
 			// TRANSLATORS: This should not start with a lowercase letter.
 			"trace-exec": i18n.G("Display exec calls timing data"),
+			// TRANSLATORS: This should not start with a lowercase letter.
+			"print-env":  i18n.G("Print the environment and command chain of the command instead of running it (useful for debugging)"),
 			"parser-ran": "",
 		}, nil)
 }
@@ -182,6 +186,13 @@ func (x *cmdRun) Execute(args []string) error {
 		return fmt.Errorf(i18n.G("too many arguments for hook %q: %s"), x.HookName, strings.Join(args, " "))
 	}
 
+	if x.PrintEnv {
+		if x.Timer != "" || x.Command != "" {
+			return fmt.Errorf(i18n.G("cannot use --print-env with --command or --timer"))
+		}
+		return x.printEnv(snapApp, args)
+	}
+
 	if err := maybeWaitForSecurityProfileRegeneration(x.client); err != nil {
 		return err
 	}
@@ -439,6 +450,110 @@ func (x *cmdRun) snapRunHook(snapName string) error {
 	return x.runSnapConfine(info, hook.SecurityTag(), snapName, hook.Name, nil)
 }
 
+// printEnv prints the environment and the command, including its
+// command chain, that the given app or hook would be run with.
+func (x *cmdRun) printEnv(snapApp string, args []string) error {
+	snapName, appName := snap.SplitSnapApp(snapApp)
+	revision := snap.R(0)
+	if x.HookName != "" {
+		var err error
+		revision, err = snap.ParseRevision(x.Revision)
+		if err != nil {
+			return err
+		}
+	}
+	info, err := getSnapInfo(snapName, revision)
+	if err != nil {
+		return err
+	}
+
+	var app *snap.AppInfo
+	var hook *snap.HookInfo
+	var snapEnv []string
+	if x.HookName != "" {
+		hook = info.Hooks[x.HookName]
+		if hook == nil {
+			return fmt.Errorf(i18n.G("cannot find hook %q in %q"), x.HookName, snapName)
+		}
+		snapEnv = hook.Env()
+	} else {
+		app = info.Apps[appName]
+		if app == nil {
+			return fmt.Errorf(i18n.G("cannot find app %q in %q"), appName, snapName)
+		}
+		snapEnv = app.Env()
+	}
+
+	// this mimics what snap-confine and snap-exec do with the
+	// environment
+	env := osutil.EnvMap(snapenv.ExecEnv(info, nil))
+	for k, v := range env {
+		if strings.HasPrefix(k, snapenv.PreservedUnsafePrefix) {
+			delete(env, k)
+			env[k[len(snapenv.PreservedUnsafePrefix):]] = v
+		}
+	}
+	connEnv, err := snapenv.ConnectionsEnv(snapName)
+	if err != nil {
+		return fmt.Errorf(i18n.G("cannot read environment of %q: %v"), snapName, err)
+	}
+	for k, v := range osutil.EnvMap(connEnv) {
+		env[k] = v
+	}
+	expand := func(s string) string {
+		return os.Expand(s, func(k string) string { return env[k] })
+	}
+	for _, kv := range snapEnv {
+		l := strings.SplitN(kv, "=", 2)
+		if len(l) < 2 {
+			continue
+		}
+		env[l[0]] = expand(l[1])
+	}
+
+	var cmd []string
+	if hook != nil {
+		cmd = absoluteCommandChain(info, hook.CommandChain)
+		cmd = append(cmd, filepath.Join(info.HooksDir(), hook.Name))
+	} else {
+		cmd = absoluteCommandChain(info, app.CommandChain)
+		// the command cannot contain quotes, see snap/validate.go
+		cmdArgs := strings.Split(app.Command, " ")
+		cmd = append(cmd, filepath.Join(info.MountDir(), cmdArgs[0]))
+		for _, arg := range cmdArgs[1:] {
+			if expanded := expand(arg); expanded != "" {
+				cmd = append(cmd, expanded)
+			}
+		}
+		cmd = append(cmd, args...)
+	}
+
+	keys := make([]string, 0, len(env))
+	for k := range env {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	fmt.Fprintln(Stdout, i18n.G("Environment:"))
+	for _, k := range keys {
+		fmt.Fprintf(Stdout, "  %s=%s\n", k, env[k])
+	}
+	fmt.Fprintln(Stdout, i18n.G("Command:"))
+	for _, arg := range cmd {
+		fmt.Fprintf(Stdout, "  %s\n", arg)
+	}
+
+	return nil
+}
+
+func absoluteCommandChain(info *snap.Info, commandChain []string) []string {
+	chain := make([]string, 0, len(commandChain))
+	for _, element := range commandChain {
+		chain = append(chain, filepath.Join(info.MountDir(), element))
+	}
+	return chain
+}
+
 func (x *cmdRun) snapRunTimer(snapApp, timer string, args []string) error {
 	schedule, err := timeutil.ParseSchedule(timer)
 	if err != nil {
@@ -22,6 +22,7 @@ package main_test
 import (
 	"errors"
 	"fmt"
+	"io/ioutil"
 	"os"
 	"os/user"
 	"path/filepath"
@@ -1182,3 +1183,86 @@ func (s *RunSuite) TestSnapRunRestoreSecurityContextFail(c *check.C) {
 	c.Check(verifyCalls, check.Equals, 2)
 	c.Check(restoreCalls, check.Equals, 1)
 }
+
+func (s *RunSuite) TestSnapRunPrintEnv(c *check.C) {
+	snaptest.MockSnapCurrent(c, `name: snapname
+version: 1.0
+environment:
+  FOO: $SNAP/foo
+apps:
+ app:
+  command: run-app --data $SNAP_DATA
+  command-chain: [bin/chain]
+  environment:
+    BAR: $FOO/bar
+`, &snap.SideInfo{
+		Revision: snap.R("x2"),
+	})
+
+	restorer := snaprun.MockSyscallExec(func(arg0 string, args []string, envv []string) error {
+		c.Fatalf("unexpected exec of %q", arg0)
+		return nil
+	})
+	defer restorer()
+
+	rest, err := snaprun.Parser(snaprun.Client()).ParseArgs([]string{"run", "--print-env", "--", "snapname.app", "arg1"})
+	c.Assert(err, check.IsNil)
+	c.Assert(rest, check.DeepEquals, []string{"snapname.app", "arg1"})
+
+	mountDir := filepath.Join(dirs.CoreSnapMountDir, "snapname", "x2")
+	c.Check(s.Stdout(), testutil.Contains, "Environment:\n")
+	c.Check(s.Stdout(), testutil.Contains, "\n  SNAP_REVISION=x2\n")
+	c.Check(s.Stdout(), testutil.Contains, fmt.Sprintf("\n  FOO=%s/foo\n", mountDir))
+	c.Check(s.Stdout(), testutil.Contains, fmt.Sprintf("\n  BAR=%s/foo/bar\n", mountDir))
+	c.Check(s.Stdout(), testutil.Contains, fmt.Sprintf(`Command:
+  %[1]s/bin/chain
+  %[1]s/run-app
+  --data
+  %[2]s
+  arg1
+`, filepath.Join(dirs.SnapMountDir, "snapname", "x2"), filepath.Join(dirs.SnapDataDir, "snapname", "x2")))
+	c.Check(s.Stderr(), check.Equals, "")
+}
+
+func (s *RunSuite) TestSnapRunPrintEnvConnections(c *check.C) {
+	snaptest.MockSnapCurrent(c, `name: snapname
+version: 1.0
+apps:
+ app:
+  command: run-app
+  environment:
+    THEMES: $SNAP_CONTENT_THEMES_PATH/default
+`, &snap.SideInfo{
+		Revision: snap.R("x2"),
+	})
+	c.Assert(os.MkdirAll(dirs.SnapMountPolicyDir, 0755), check.IsNil)
+	envFile := filepath.Join(dirs.SnapMountPolicyDir, "snap.snapname.environment")
+	err := ioutil.WriteFile(envFile, []byte("SNAP_CONTENT_THEMES_PATH=/snap/snapname/x2/themes\n"), 0644)
+	c.Assert(err, check.IsNil)
+
+	_, err = snaprun.Parser(snaprun.Client()).ParseArgs([]string{"run", "--print-env", "snapname.app"})
+	c.Assert(err, check.IsNil)
+	c.Check(s.Stdout(), testutil.Contains, "\n  SNAP_CONTENT_THEMES_PATH=/snap/snapname/x2/themes\n")
+	c.Check(s.Stdout(), testutil.Contains, "\n  THEMES=/snap/snapname/x2/themes/default\n")
+}
+
+func (s *RunSuite) TestSnapRunPrintEnvHook(c *check.C) {
+	snaptest.MockSnapCurrent(c, string(mockYaml), &snap.SideInfo{
+		Revision: snap.R(42),
+	})
+
+	rest, err := snaprun.Parser(snaprun.Client()).ParseArgs([]string{"run", "--print-env", "--hook=configure", "-r=42", "snapname"})
+	c.Assert(err, check.IsNil)
+	c.Assert(rest, check.DeepEquals, []string{"snapname"})
+	c.Check(s.Stdout(), testutil.Contains, "\n  SNAP_REVISION=42\n")
+	c.Check(s.Stdout(), testutil.Contains, fmt.Sprintf("Command:\n  %s\n", filepath.Join(dirs.SnapMountDir, "snapname", "42", "meta", "hooks", "configure")))
+}
+
+func (s *RunSuite) TestSnapRunPrintEnvUnknownApp(c *check.C) {
+	snaptest.MockSnapCurrent(c, string(mockYaml), &snap.SideInfo{
+		Revision: snap.R("x2"),
+	})
+
+	_, err := snaprun.Parser(snaprun.Client()).ParseArgs([]string{"run", "--print-env", "snapname.unknown"})
+	c.Assert(err, check.ErrorMatches, `cannot find app "unknown" in "snapname"`)
+}
@@ -20,6 +20,7 @@
 package main
 
 import (
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
 
@@ -35,19 +36,101 @@ var longSignHelp = i18n.G(`
 The sign command signs an assertion using the specified key, using the
 input for headers from a JSON mapping provided through stdin. The body
 of the assertion can be specified through a "body" pseudo-header.
+
+With --remote the key is looked up in, and the signing delegated to, the
+remote signing service at the given https URL, authenticating with the
+client certificate and key given via --remote-cert and --remote-key.
+
+With --type the input can also be a YAML mapping, defaults are filled in
+for the headers of the given assertion type and the headers are checked
+before signing. With --interactive as well, the headers are instead
+prompted for one by one.
 `)
 
 type cmdSign struct {
 	KeyName keyName `short:"k" default:"default"`
+	remoteSignerMixin
+
+	Type        string `long:"type" choice:"model" choice:"system-user"`
+	Interactive bool   `long:"interactive"`
+}
+
+// namedKeypairManager is a KeypairManager that can also look up keys
+// by name.
+type namedKeypairManager interface {
+	asserts.KeypairManager
+	GetByName(name string) (asserts.PrivateKey, error)
+}
+
+// remoteSignerMixin holds the options to sign with the keys of a
+// remote signing service instead of the local GnuPG ones.
+type remoteSignerMixin struct {
+	Remote     string `long:"remote"`
+	RemoteCert string `long:"remote-cert"`
+	RemoteKey  string `long:"remote-key"`
+	RemoteCA   string `long:"remote-ca"`
+}
+
+var remoteSignerDescs = mixinDescs{
+	// TRANSLATORS: This should not start with a lowercase letter.
+	"remote": i18n.G("URL of a remote signing service to sign with"),
+	// TRANSLATORS: This should not start with a lowercase letter.
+	"remote-cert": i18n.G("Client certificate to authenticate to the remote signing service"),
+	// TRANSLATORS: This should not start with a lowercase letter.
+	"remote-key": i18n.G("Key of the client certificate for the remote signing service"),
+	// TRANSLATORS: This should not start with a lowercase letter.
+	"remote-ca": i18n.G("CA certificates to verify the remote signing service with"),
+}
+
+var newRemoteKeypairManager = func(config *asserts.RemoteSignerConfig) (namedKeypairManager, error) {
+	rkm, err := asserts.NewRemoteKeypairManager(config)
+	if err != nil {
+		return nil, err
+	}
+	return rkm, nil
+}
+
+// remoteKeypairManager returns the keypair manager of the remote
+// signing service given with --remote, or nil without it.
+func (x *remoteSignerMixin) remoteKeypairManager() (namedKeypairManager, error) {
+	if x.Remote == "" {
+		if x.RemoteCert != "" || x.RemoteKey != "" || x.RemoteCA != "" {
+			return nil, fmt.Errorf(i18n.G("cannot use --remote-cert, --remote-key or --remote-ca without --remote"))
+		}
+		return nil, nil
+	}
+	return newRemoteKeypairManager(&asserts.RemoteSignerConfig{
+		URL:      x.Remote,
+		CertFile: x.RemoteCert,
+		KeyFile:  x.RemoteKey,
+		CAFile:   x.RemoteCA,
+	})
+}
+
+// keypairManager returns the keypair manager of the remote signing
+// service given with --remote, or the one of the local GnuPG keys.
+func (x *remoteSignerMixin) keypairManager() (namedKeypairManager, error) {
+	keypairMgr, err := x.remoteKeypairManager()
+	if err != nil {
+		return nil, err
+	}
+	if keypairMgr == nil {
+		return asserts.NewGPGKeypairManager(), nil
+	}
+	return keypairMgr, nil
 }
 
 func init() {
 	cmd := addCommand("sign", shortSignHelp, longSignHelp, func() flags.Commander {
 		return &cmdSign{}
-	}, map[string]string{
+	}, remoteSignerDescs.also(map[string]string{
 		// TRANSLATORS: This should not start with a lowercase letter.
 		"k": i18n.G("Name of the key to use, otherwise use the default key"),
-	}, nil)
+		// TRANSLATORS: This should not start with a lowercase letter.
+		"type": i18n.G("Type of the assertion, to fill in defaults for and check its headers"),
+		// TRANSLATORS: This should not start with a lowercase letter.
+		"interactive": i18n.G("Prompt for the headers of the assertion of the given --type"),
+	}), nil)
 	cmd.hidden = true
 }
 
@@ -56,12 +139,15 @@ func (x *cmdSign) Execute(args []string) error {
 		return ErrExtraArgs
 	}
 
-	statement, err := ioutil.ReadAll(Stdin)
+	statement, err := x.statement()
 	if err != nil {
-		return fmt.Errorf(i18n.G("cannot read assertion input: %v"), err)
+		return err
 	}
 
-	keypairMgr := asserts.NewGPGKeypairManager()
+	keypairMgr, err := x.keypairManager()
+	if err != nil {
+		return err
+	}
 	privKey, err := keypairMgr.GetByName(string(x.KeyName))
 	if err != nil {
 		return err
@@ -83,3 +169,31 @@ func (x *cmdSign) Execute(args []string) error {
 	}
 	return nil
 }
+
+func (x *cmdSign) statement() ([]byte, error) {
+	if x.Interactive && x.Type == "" {
+		return nil, fmt.Errorf(i18n.G("cannot use --interactive without --type"))
+	}
+
+	var headers map[string]interface{}
+	if x.Interactive {
+		var err error
+		headers, err = promptHeaders(x.Type, Stdin, Stderr)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		input, err := ioutil.ReadAll(Stdin)
+		if err != nil {
+			return nil, fmt.Errorf(i18n.G("cannot read assertion input: %v"), err)
+		}
+		if x.Type == "" {
+			return input, nil
+		}
+		headers, err = templateHeaders(x.Type, input)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return json.Marshal(headers)
+}
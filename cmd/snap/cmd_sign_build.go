@@ -41,12 +41,16 @@ type cmdSignBuild struct {
 	SnapID      string  `long:"snap-id" required:"yes"`
 	KeyName     keyName `short:"k" default:"default" `
 	Grade       string  `long:"grade" choice:"devel" choice:"stable" default:"stable"`
+	remoteSignerMixin
 }
 
 var shortSignBuildHelp = i18n.G("Create a snap-build assertion")
 var longSignBuildHelp = i18n.G(`
 The sign-build command creates a snap-build assertion for the provided
 snap file.
+
+With --remote the key is looked up in, and the signing delegated to, the
+remote signing service at the given https URL.
 `)
 
 func init() {
@@ -55,16 +59,16 @@ func init() {
 		longSignBuildHelp,
 		func() flags.Commander {
 			return &cmdSignBuild{}
-		}, map[string]string{
+		}, remoteSignerDescs.also(map[string]string{
 			// TRANSLATORS: This should not start with a lowercase letter.
 			"developer-id": i18n.G("Identifier of the signer"),
 			// TRANSLATORS: This should not start with a lowercase letter.
 			"snap-id": i18n.G("Identifier of the snap package associated with the build"),
 			// TRANSLATORS: This should not start with a lowercase letter.
-			"k": i18n.G("Name of the GnuPG key to use (defaults to 'default' as key name)"),
+			"k": i18n.G("Name of the key to use (defaults to 'default' as key name)"),
 			// TRANSLATORS: This should not start with a lowercase letter.
 			"grade": i18n.G("Grade states the build quality of the snap (defaults to 'stable')"),
-		}, []argDesc{{
+		}), []argDesc{{
 			// TRANSLATORS: This needs to begin with < and end with >
 			name: i18n.G("<filename>"),
 			// TRANSLATORS: This should not start with a lowercase letter.
@@ -83,8 +87,11 @@ func (x *cmdSignBuild) Execute(args []string) error {
 		return err
 	}
 
-	gkm := asserts.NewGPGKeypairManager()
-	privKey, err := gkm.GetByName(string(x.KeyName))
+	keypairMgr, err := x.keypairManager()
+	if err != nil {
+		return err
+	}
+	privKey, err := keypairMgr.GetByName(string(x.KeyName))
 	if err != nil {
 		// TRANSLATORS: %q is the key name, %v the error message
 		return fmt.Errorf(i18n.G("cannot use %q key: %v"), x.KeyName, err)
@@ -104,7 +111,7 @@ func (x *cmdSignBuild) Execute(args []string) error {
 	}
 
 	adb, err := asserts.OpenDatabase(&asserts.DatabaseConfig{
-		KeypairManager: gkm,
+		KeypairManager: keypairMgr,
 	})
 	if err != nil {
 		return fmt.Errorf(i18n.G("cannot open the assertions database: %v"), err)
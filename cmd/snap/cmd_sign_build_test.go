@@ -105,6 +105,34 @@ func (s *SnapSignBuildSuite) TestSignBuildWorks(c *C) {
 	c.Check(s.Stderr(), Equals, "")
 }
 
+func (s *SnapSignBuildSuite) TestSignBuildRemote(c *C) {
+	signer, restore := mockRemoteSigner(c)
+	defer restore()
+
+	snapFilename := "foo_1_amd64.snap"
+	_err := ioutil.WriteFile(snapFilename, []byte("sample"), 0644)
+	c.Assert(_err, IsNil)
+	defer os.Remove(snapFilename)
+
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"sign-build", snapFilename, "--developer-id", "dev-id1", "--snap-id", "snap-id-1", "--remote", "https://signer.example.com", "--remote-cert", "cert.pem", "--remote-key", "key.pem"})
+	c.Assert(err, IsNil)
+
+	assertion, err := asserts.Decode([]byte(s.Stdout()))
+	c.Assert(err, IsNil)
+	c.Check(assertion.Type(), Equals, asserts.SnapBuildType)
+	c.Check(assertion.SignKeyID(), Equals, signer.key.PublicKey().ID())
+}
+
+func (s *SnapSignBuildSuite) TestSignBuildRemoteOptionsNeedRemote(c *C) {
+	snapFilename := "foo_1_amd64.snap"
+	_err := ioutil.WriteFile(snapFilename, []byte("sample"), 0644)
+	c.Assert(_err, IsNil)
+	defer os.Remove(snapFilename)
+
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"sign-build", snapFilename, "--developer-id", "dev-id1", "--snap-id", "snap-id-1", "--remote-key", "key.pem"})
+	c.Assert(err, ErrorMatches, `cannot use --remote-cert, --remote-key or --remote-ca without --remote`)
+}
+
 func (s *SnapSignBuildSuite) TestSignBuildWorksDevelGrade(c *C) {
 	snapFilename := "foo_1_amd64.snap"
 	snapContent := []byte("sample")
@@ -26,6 +26,7 @@ import (
 	. "gopkg.in/check.v1"
 
 	"github.com/snapcore/snapd/asserts"
+	"github.com/snapcore/snapd/asserts/assertstest"
 
 	snap "github.com/snapcore/snapd/cmd/snap"
 )
@@ -63,3 +64,178 @@ func (s *SnapKeysSuite) TestHappyNonDefaultKey(c *C) {
 	c.Assert(err, IsNil)
 	c.Check(a.Type(), Equals, asserts.SnapBuildType)
 }
+
+func (s *SnapKeysSuite) TestSignRemoteOptionsNeedRemote(c *C) {
+	s.stdin.Write(statement)
+
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"sign", "--remote-cert", "cert.pem"})
+	c.Assert(err, ErrorMatches, `cannot use --remote-cert, --remote-key or --remote-ca without --remote`)
+}
+
+// fakeRemoteSigner stands in for a remote signing service holding a
+// single "default" key.
+type fakeRemoteSigner struct {
+	asserts.KeypairManager
+	key    asserts.PrivateKey
+	config *asserts.RemoteSignerConfig
+}
+
+func mockRemoteSigner(c *C) (*fakeRemoteSigner, func()) {
+	key, _ := assertstest.GenerateKey(752)
+	signer := &fakeRemoteSigner{
+		KeypairManager: asserts.NewMemoryKeypairManager(),
+		key:            key,
+	}
+	c.Assert(signer.Put(key), IsNil)
+	restore := snap.MockNewRemoteKeypairManager(func(config *asserts.RemoteSignerConfig) (snap.NamedKeypairManager, error) {
+		signer.config = config
+		return signer, nil
+	})
+	return signer, restore
+}
+
+func (f *fakeRemoteSigner) GetByName(name string) (asserts.PrivateKey, error) {
+	if name != "default" {
+		return nil, fmt.Errorf("cannot find key with name %q in remote signer", name)
+	}
+	return f.key, nil
+}
+
+func (s *SnapKeysSuite) TestSignRemote(c *C) {
+	signer, restore := mockRemoteSigner(c)
+	defer restore()
+
+	s.stdin.Write(statement)
+
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"sign", "--remote", "https://signer.example.com", "--remote-cert", "cert.pem", "--remote-key", "key.pem"})
+	c.Assert(err, IsNil)
+	c.Check(signer.config, DeepEquals, &asserts.RemoteSignerConfig{
+		URL:      "https://signer.example.com",
+		CertFile: "cert.pem",
+		KeyFile:  "key.pem",
+	})
+
+	a, err := asserts.Decode(s.stdout.Bytes())
+	c.Assert(err, IsNil)
+	c.Check(a.SignKeyID(), Equals, signer.key.PublicKey().ID())
+}
+
+func (s *SnapKeysSuite) TestSignRemoteNeedsHTTPS(c *C) {
+	s.stdin.Write(statement)
+
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"sign", "--remote", "http://signer.example.com", "--remote-cert", "cert.pem", "--remote-key", "key.pem"})
+	c.Assert(err, ErrorMatches, `remote signer URL must use https, got "http://signer.example.com"`)
+}
+
+func (s *SnapKeysSuite) TestSignTypeModelFromYAML(c *C) {
+	restore := snap.MockTimeNow(func() time.Time {
+		return time.Date(2019, 6, 1, 12, 0, 0, 0, time.UTC)
+	})
+	defer restore()
+
+	s.stdin.Write([]byte(`
+authority-id: my-brand
+model: my-model
+architecture: amd64
+gadget: pc=18
+kernel: pc-kernel=18
+base: core18
+required-snaps:
+  - foo
+  - bar
+`))
+
+	rest, err := snap.Parser(snap.Client()).ParseArgs([]string{"sign", "--type", "model"})
+	c.Assert(err, IsNil)
+	c.Assert(rest, DeepEquals, []string{})
+
+	a, err := asserts.Decode(s.stdout.Bytes())
+	c.Assert(err, IsNil)
+	c.Assert(a.Type(), Equals, asserts.ModelType)
+	model := a.(*asserts.Model)
+	c.Check(model.BrandID(), Equals, "my-brand")
+	c.Check(model.Series(), Equals, "16")
+	c.Check(model.Model(), Equals, "my-model")
+	c.Check(model.Classic(), Equals, false)
+	c.Check(model.Gadget(), Equals, "pc")
+	c.Check(model.GadgetTrack(), Equals, "18")
+	c.Check(model.RequiredSnaps(), DeepEquals, []string{"foo", "bar"})
+	c.Check(model.Timestamp().Equal(time.Date(2019, 6, 1, 12, 0, 0, 0, time.UTC)), Equals, true)
+}
+
+func (s *SnapKeysSuite) TestSignTypeErrors(c *C) {
+	tests := []struct {
+		input string
+		err   string
+	}{
+		{"authority-id: my-brand\n", `missing required header "model" for model assertion`},
+		{"type: system-user\n", `assertion input type "system-user" does not match --type "model"`},
+		{"authority-id: my-brand\nmodel: my-model\nclassic: maybe\n", `invalid "classic" header: "maybe" is not true or false`},
+		{"authority-id: my-brand\nmodel: my-model\nrequired-snaps: foo\n", `header "required-snaps" must be a list of strings`},
+		{"authority-id: my-brand\nmodel: my-model\nrequired-snaps: [Foo]\n", `invalid "required-snaps" header: invalid snap name: "Foo"`},
+		{"- foo\n", `(?s)cannot parse the assertion input: .*`},
+	}
+
+	for _, t := range tests {
+		s.stdin.Reset()
+		s.stdin.Write([]byte(t.input))
+
+		_, err := snap.Parser(snap.Client()).ParseArgs([]string{"sign", "--type", "model"})
+		c.Check(err, ErrorMatches, t.err, Commentf("%s", t.input))
+	}
+}
+
+func (s *SnapKeysSuite) TestSignInteractiveNeedsType(c *C) {
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"sign", "--interactive"})
+	c.Assert(err, ErrorMatches, `cannot use --interactive without --type`)
+}
+
+func (s *SnapKeysSuite) TestSignInteractiveSystemUser(c *C) {
+	restore := snap.MockTimeNow(func() time.Time {
+		return time.Date(2019, 6, 1, 12, 0, 0, 0, time.UTC)
+	})
+	defer restore()
+
+	s.stdin.Write([]byte(`my-brand
+
+16
+
+my-model, other-model
+Jane Doe
+jane
+not-an-email
+jane@example.com
+
+
+
+
+
+`))
+
+	rest, err := snap.Parser(snap.Client()).ParseArgs([]string{"sign", "--type", "system-user", "--interactive"})
+	c.Assert(err, IsNil)
+	c.Assert(rest, DeepEquals, []string{})
+
+	c.Check(s.Stderr(), Equals, `Account ID of the signer: Account ID of the brand [my-brand]: Series (comma-separated) [16]: Models the user is valid for (comma-separated): error: a value is required
+Models the user is valid for (comma-separated): Full name of the user: Username: Email address: error: invalid "email" header: "not-an-email" is not an email address
+Email address: Hashed password: SSH public keys (comma-separated): Valid since [2019-06-01T12:00:00Z]: Valid until [2020-06-01T12:00:00Z]: Timestamp [2019-06-01T12:00:00Z]: `)
+
+	a, err := asserts.Decode(s.stdout.Bytes())
+	c.Assert(err, IsNil)
+	c.Assert(a.Type(), Equals, asserts.SystemUserType)
+	user := a.(*asserts.SystemUser)
+	c.Check(user.BrandID(), Equals, "my-brand")
+	c.Check(user.Series(), DeepEquals, []string{"16"})
+	c.Check(user.Models(), DeepEquals, []string{"my-model", "other-model"})
+	c.Check(user.Name(), Equals, "Jane Doe")
+	c.Check(user.Username(), Equals, "jane")
+	c.Check(user.Email(), Equals, "jane@example.com")
+	c.Check(user.Until().Equal(time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC)), Equals, true)
+}
+
+func (s *SnapKeysSuite) TestSignInteractiveEOF(c *C) {
+	s.stdin.Write([]byte("my-brand\n"))
+
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"sign", "--type", "model", "--interactive"})
+	c.Assert(err, ErrorMatches, `cannot read "brand-id" header: unexpected end of input`)
+}
@@ -20,11 +20,14 @@
 package main
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"os"
+	"os/signal"
 	"path/filepath"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
 	"unicode/utf8"
@@ -65,6 +68,10 @@ Note a later refresh will typically undo a revision override, taking the snap
 back to the current revision of the channel it's tracking.
 
 Use --name to set the instance name when installing from snap file.
+
+Snaps whose publisher requires their license agreement to be explicitly
+accepted can only be installed once it has been accepted with --accept-terms.
+The acceptance is remembered by the system.
 `)
 
 var longRemoveHelp = i18n.G(`
@@ -73,6 +80,10 @@ The remove command removes the named snap instance from the system.
 By default all the snap revisions are removed, including their data and the
 common data directory. When a --revision option is passed only the specified
 revision is removed.
+
+When a --keep-data-for option is passed the data is saved in a snapshot that
+is kept for the given time, so that it can be restored if the snap is installed
+again before then.
 `)
 
 var longRefreshHelp = i18n.G(`
@@ -87,6 +98,19 @@ have developer access to the snap, either directly or through the
 store's collaboration feature, and to be logged in (see 'snap help login').
 
 Note a later refresh will typically undo a revision override.
+
+With --download-only the refreshes are downloaded, together with their
+assertions, but not installed. A later refresh with --apply-prefetched
+installs the downloaded refreshes without using the network for them.
+
+With --dry-run the tasks the refresh would perform are shown, together with
+whether it is expected to restart snapd or reboot the system, but nothing is
+refreshed.
+
+Refreshes can be held back while apps or hooks of the snaps are running. With
+--ignore-running the snaps are refreshed regardless. With --kill-running their
+running processes are asked to terminate, and killed if they do not exit within
+a few seconds, before refreshing the snaps.
 `)
 
 var longTryHelp = i18n.G(`
@@ -98,6 +122,9 @@ performed in snap.yaml will require reinstallation to go live.
 If snap-dir argument is omitted, the try command will attempt to infer it if
 either snapcraft.yaml file and prime directory or meta/snap.yaml file can be
 found relative to current working directory.
+
+With --watch, the try command keeps running after installation and
+reinstalls the snap whenever its meta/snap.yaml changes, until interrupted.
 `)
 
 var longEnableHelp = i18n.G(`
@@ -113,9 +140,11 @@ and the snap can easily be enabled again.
 type cmdRemove struct {
 	waitMixin
 
-	Revision   string `long:"revision"`
-	Purge      bool   `long:"purge"`
-	Positional struct {
+	Revision    string `long:"revision"`
+	Purge       bool   `long:"purge"`
+	KeepDataFor string `long:"keep-data-for"`
+	DryRun      bool   `long:"dry-run"`
+	Positional  struct {
 		Snaps []installedSnapName `positional-arg-name:"<snap>" required:"1"`
 	} `positional-args:"yes" required:"yes"`
 }
@@ -185,15 +214,89 @@ func (x *cmdRemove) removeMany(opts *client.SnapOptions) error {
 
 }
 
+// showPlan shows the change that performing the given action on the
+// named snaps would create, without performing it.
+func showPlan(cli *client.Client, action string, names []string, opts *client.SnapOptions) error {
+	plan, err := cli.PlanSnapAction(action, names, opts)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(Stdout, i18n.G("Plan: %s\n"), plan.Summary)
+	if len(plan.AffectedSnaps) > 0 {
+		fmt.Fprintf(Stdout, i18n.G("Affected snaps: %s\n"), strings.Join(plan.AffectedSnaps, ", "))
+	}
+	switch plan.Restart {
+	case "system":
+		fmt.Fprintln(Stdout, i18n.G("The system is expected to reboot."))
+	case "daemon":
+		fmt.Fprintln(Stdout, i18n.G("snapd is expected to restart."))
+	}
+	if len(plan.Tasks) == 0 {
+		fmt.Fprintln(Stdout, i18n.G("Nothing to do."))
+		return nil
+	}
+
+	fmt.Fprintln(Stdout)
+	w := tabWriter()
+	fmt.Fprintln(w, i18n.G("ID\tWaits for\tSummary"))
+	for _, t := range plan.Tasks {
+		waitFor := "-"
+		if len(t.WaitFor) > 0 {
+			waitFor = strings.Join(t.WaitFor, ",")
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", t.ID, waitFor, t.Summary)
+	}
+	return w.Flush()
+}
+
+// parseKeepDataFor parses the retention window given to remove
+// --keep-data-for, which is either a number of days like "7d" or a
+// duration like "36h".
+func parseKeepDataFor(s string) (time.Duration, error) {
+	var keepFor time.Duration
+	if days := strings.TrimSuffix(s, "d"); days != s {
+		n, err := strconv.Atoi(days)
+		if err == nil {
+			keepFor = time.Duration(n) * 24 * time.Hour
+		}
+	} else if d, err := time.ParseDuration(s); err == nil {
+		keepFor = d
+	}
+	if keepFor <= 0 {
+		return 0, fmt.Errorf(i18n.G("invalid duration %q for --keep-data-for (try e.g. 7d or 12h)"), s)
+	}
+	return keepFor, nil
+}
+
 func (x *cmdRemove) Execute([]string) error {
 	opts := &client.SnapOptions{Revision: x.Revision, Purge: x.Purge}
+	if x.KeepDataFor != "" {
+		if x.Purge {
+			return errors.New(i18n.G("cannot use --keep-data-for and --purge together"))
+		}
+		keepFor, err := parseKeepDataFor(x.KeepDataFor)
+		if err != nil {
+			return err
+		}
+		opts.KeepDataFor = keepFor.String()
+	}
 	if len(x.Positional.Snaps) == 1 {
+		if x.DryRun {
+			return showPlan(x.client, "remove", installedSnapNames(x.Positional.Snaps), opts)
+		}
 		return x.removeOne(opts)
 	}
 
 	if x.Revision != "" {
 		return errors.New(i18n.G("a single snap name is needed to specify the revision"))
 	}
+	if x.KeepDataFor != "" {
+		return errors.New(i18n.G("a single snap name is needed to keep its data"))
+	}
+	if x.DryRun {
+		return showPlan(x.client, "remove", installedSnapNames(x.Positional.Snaps), nil)
+	}
 	return x.removeMany(nil)
 }
 
@@ -414,8 +517,10 @@ type cmdInstall struct {
 
 	Name string `long:"name"`
 
-	Cohort     string `long:"cohort"`
-	Positional struct {
+	Cohort      string `long:"cohort"`
+	AcceptTerms bool   `long:"accept-terms"`
+	DryRun      bool   `long:"dry-run"`
+	Positional  struct {
 		Snaps []remoteSnapName `positional-arg-name:"<snap>"`
 	} `positional-args:"yes" required:"yes"`
 }
@@ -461,7 +566,19 @@ func (x *cmdInstall) installOne(nameOrPath, desiredName string, opts *client.Sna
 	}
 
 	// TODO: mention details of the install (e.g. like switch does)
-	return showDone(x.client, []string{snapName}, "install", opts, x.getEscapes())
+	if err := showDone(x.client, []string{snapName}, "install", opts, x.getEscapes()); err != nil {
+		return err
+	}
+
+	var kept struct {
+		SetID      uint64    `json:"set-id"`
+		ExpiryTime time.Time `json:"expiry-time"`
+	}
+	if err := chg.Get("kept-data", &kept); err == nil && kept.SetID != 0 {
+		fmt.Fprintf(Stdout, i18n.G("Data of %s from its previous removal is kept in snapshot #%d until %s.\nUse 'snap restore %d' to restore it.\n"),
+			snapName, kept.SetID, kept.ExpiryTime.Format(time.RFC3339), kept.SetID)
+	}
+	return nil
 }
 
 func (x *cmdInstall) installMany(names []string, opts *client.SnapOptions) error {
@@ -531,11 +648,12 @@ func (x *cmdInstall) Execute([]string) error {
 
 	dangerous := x.Dangerous || x.ForceDangerous
 	opts := &client.SnapOptions{
-		Channel:   x.Channel,
-		Revision:  x.Revision,
-		Dangerous: dangerous,
-		Unaliased: x.Unaliased,
-		CohortKey: x.Cohort,
+		Channel:     x.Channel,
+		Revision:    x.Revision,
+		Dangerous:   dangerous,
+		Unaliased:   x.Unaliased,
+		CohortKey:   x.Cohort,
+		AcceptTerms: x.AcceptTerms,
 	}
 	x.setModes(opts)
 
@@ -549,7 +667,21 @@ func (x *cmdInstall) Execute([]string) error {
 		}
 	}
 
+	if x.DryRun {
+		for _, name := range names {
+			if strings.Contains(name, "/") || strings.HasSuffix(name, ".snap") || strings.Contains(name, ".snap.") {
+				return errors.New(i18n.G("cannot use --dry-run when installing from a snap file"))
+			}
+		}
+	}
+
 	if len(names) == 1 {
+		if x.DryRun {
+			if x.Name != "" {
+				return errors.New(i18n.G("cannot use explicit name when installing from store"))
+			}
+			return showPlan(x.client, "install", names, opts)
+		}
 		return x.installOne(names[0], x.Name, opts)
 	}
 
@@ -560,7 +692,14 @@ func (x *cmdInstall) Execute([]string) error {
 	if x.Name != "" {
 		return errors.New(i18n.G("cannot use instance name when installing multiple snaps"))
 	}
-	return x.installMany(names, nil)
+	if x.DryRun {
+		return showPlan(x.client, "install", names, nil)
+	}
+	var manyOpts *client.SnapOptions
+	if x.AcceptTerms {
+		manyOpts = &client.SnapOptions{AcceptTerms: true}
+	}
+	return x.installMany(names, manyOpts)
 }
 
 type cmdRefresh struct {
@@ -576,7 +715,13 @@ type cmdRefresh struct {
 	LeaveCohort      bool   `long:"leave-cohort"`
 	List             bool   `long:"list"`
 	Time             bool   `long:"time"`
+	JSON             bool   `long:"json"`
 	IgnoreValidation bool   `long:"ignore-validation"`
+	DownloadOnly     bool   `long:"download-only"`
+	ApplyPrefetched  bool   `long:"apply-prefetched"`
+	IgnoreRunning    bool   `long:"ignore-running"`
+	KillRunning      bool   `long:"kill-running"`
+	DryRun           bool   `long:"dry-run"`
 	Positional       struct {
 		Snaps []installedSnapName `positional-arg-name:"<snap>"`
 	} `positional-args:"yes"`
@@ -601,10 +746,25 @@ func (x *cmdRefresh) refreshMany(snaps []string, opts *client.SnapOptions) error
 		return err
 	}
 
+	if opts != nil && opts.DownloadOnly {
+		if len(refreshed) == 0 {
+			fmt.Fprintln(Stderr, i18n.G("All snaps up to date."))
+			return nil
+		}
+		for _, name := range refreshed {
+			fmt.Fprintf(Stdout, i18n.G("%s refresh downloaded, use 'snap refresh --apply-prefetched' to install it\n"), name)
+		}
+		return nil
+	}
+
 	if len(refreshed) > 0 {
 		return showDone(x.client, refreshed, "refresh", opts, x.getEscapes())
 	}
 
+	if opts != nil && opts.ApplyPrefetched {
+		fmt.Fprintln(Stderr, i18n.G("No prefetched refreshes to install."))
+		return nil
+	}
 	fmt.Fprintln(Stderr, i18n.G("All snaps up to date."))
 
 	return nil
@@ -647,12 +807,19 @@ func (x *cmdRefresh) showRefreshTimes() error {
 		return err
 	}
 
+	if sysinfo.Refresh.Timer == "" && sysinfo.Refresh.Schedule == "" {
+		return errors.New("internal error: both refresh.timer and refresh.schedule are empty")
+	}
+	if x.JSON {
+		enc := json.NewEncoder(Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(sysinfo.Refresh)
+	}
+
 	if sysinfo.Refresh.Timer != "" {
 		fmt.Fprintf(Stdout, "timer: %s\n", sysinfo.Refresh.Timer)
-	} else if sysinfo.Refresh.Schedule != "" {
-		fmt.Fprintf(Stdout, "schedule: %s\n", sysinfo.Refresh.Schedule)
 	} else {
-		return errors.New("internal error: both refresh.timer and refresh.schedule are empty")
+		fmt.Fprintf(Stdout, "schedule: %s\n", sysinfo.Refresh.Schedule)
 	}
 	last := parseSysinfoTime(sysinfo.Refresh.Last)
 	hold := parseSysinfoTime(sysinfo.Refresh.Hold)
@@ -678,9 +845,38 @@ func (x *cmdRefresh) showRefreshTimes() error {
 	} else {
 		fmt.Fprintf(Stdout, "next: n/a\n")
 	}
+	x.showRefreshOutcomes(sysinfo.Refresh.Snaps)
 	return nil
 }
 
+func (x *cmdRefresh) showRefreshOutcomes(outcomes map[string]*client.SnapRefreshOutcome) {
+	if len(outcomes) == 0 {
+		return
+	}
+	names := make([]string, 0, len(outcomes))
+	for name := range outcomes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fmt.Fprintf(Stdout, "refreshes:\n")
+	for _, name := range names {
+		outcome := outcomes[name]
+		fmt.Fprintf(Stdout, "  %s:\n", name)
+		if attempt := parseSysinfoTime(outcome.LastAttempt); !attempt.IsZero() {
+			fmt.Fprintf(Stdout, "    attempted: %s\n", x.fmtTime(attempt))
+		}
+		if success := parseSysinfoTime(outcome.LastSuccess); !success.IsZero() {
+			fmt.Fprintf(Stdout, "    succeeded: %s\n", x.fmtTime(success))
+		} else {
+			fmt.Fprintf(Stdout, "    succeeded: n/a\n")
+		}
+		if outcome.LastError != "" {
+			fmt.Fprintf(Stdout, "    error: %s\n", outcome.LastError)
+		}
+	}
+}
+
 func (x *cmdRefresh) listRefresh() error {
 	snaps, _, err := x.client.Find(&client.FindOptions{
 		Refresh: true,
@@ -716,6 +912,9 @@ func (x *cmdRefresh) Execute([]string) error {
 		return err
 	}
 
+	if x.JSON && !x.Time {
+		return errors.New(i18n.G("--json can only be used with --time"))
+	}
 	if x.Time {
 		if x.asksForMode() || x.asksForChannel() {
 			return errors.New(i18n.G("--time does not take mode or channel flags"))
@@ -736,7 +935,28 @@ func (x *cmdRefresh) Execute([]string) error {
 		return nil
 	}
 
+	if x.IgnoreRunning && x.KillRunning {
+		return errors.New(i18n.G("cannot use --ignore-running and --kill-running together"))
+	}
+
 	names := installedSnapNames(x.Positional.Snaps)
+	if x.DownloadOnly || x.ApplyPrefetched {
+		if x.DownloadOnly && x.ApplyPrefetched {
+			return errors.New(i18n.G("cannot use --download-only and --apply-prefetched together"))
+		}
+		if x.asksForMode() || x.asksForChannel() || x.Amend || x.Revision != "" || x.Cohort != "" || x.LeaveCohort || x.IgnoreValidation || x.IgnoreRunning || x.KillRunning {
+			return errors.New(i18n.G("--download-only and --apply-prefetched do not take other refresh options"))
+		}
+		if x.DryRun {
+			return errors.New(i18n.G("cannot use --dry-run with --download-only or --apply-prefetched"))
+		}
+		opts := &client.SnapOptions{
+			DownloadOnly:    x.DownloadOnly,
+			ApplyPrefetched: x.ApplyPrefetched,
+		}
+		return x.refreshMany(names, opts)
+	}
+
 	if len(names) == 1 {
 		opts := &client.SnapOptions{
 			Amend:            x.Amend,
@@ -745,8 +965,13 @@ func (x *cmdRefresh) Execute([]string) error {
 			Revision:         x.Revision,
 			CohortKey:        x.Cohort,
 			LeaveCohort:      x.LeaveCohort,
+			IgnoreRunning:    x.IgnoreRunning,
+			KillRunning:      x.KillRunning,
 		}
 		x.setModes(opts)
+		if x.DryRun {
+			return showPlan(x.client, "refresh", names, opts)
+		}
 		return x.refreshOne(names[0], opts)
 	}
 
@@ -758,13 +983,24 @@ func (x *cmdRefresh) Execute([]string) error {
 		return errors.New(i18n.G("a single snap name must be specified when ignoring validation"))
 	}
 
-	return x.refreshMany(names, nil)
+	if x.DryRun {
+		return showPlan(x.client, "refresh", names, nil)
+	}
+	var opts *client.SnapOptions
+	if x.IgnoreRunning || x.KillRunning {
+		opts = &client.SnapOptions{
+			IgnoreRunning: x.IgnoreRunning,
+			KillRunning:   x.KillRunning,
+		}
+	}
+	return x.refreshMany(names, opts)
 }
 
 type cmdTry struct {
 	waitMixin
 
 	modeMixin
+	Watch      bool `long:"watch"`
 	Positional struct {
 		SnapDir string `positional-arg-name:"<snap-dir>"`
 	} `positional-args:"yes"`
@@ -788,6 +1024,9 @@ func (x *cmdTry) Execute([]string) error {
 	if err := x.validateMode(); err != nil {
 		return err
 	}
+	if x.Watch && x.NoWait {
+		return errors.New(i18n.G("cannot use --watch with --no-wait"))
+	}
 	name := x.Positional.SnapDir
 	opts := &client.SnapOptions{}
 	x.setModes(opts)
@@ -811,6 +1050,13 @@ func (x *cmdTry) Execute([]string) error {
 		return fmt.Errorf(i18n.G("cannot get full path for %q: %v"), name, err)
 	}
 
+	if x.Watch {
+		return x.watch(path, name, opts)
+	}
+	return x.try(path, name, opts)
+}
+
+func (x *cmdTry) try(path, name string, opts *client.SnapOptions) error {
 	changeID, err := x.client.Try(path, opts)
 	if err != nil {
 		msg, err := errorToCmdMessage(name, err, opts)
@@ -852,6 +1098,51 @@ func (x *cmdTry) Execute([]string) error {
 	return nil
 }
 
+var tryWatchPollTime = time.Second
+
+// watch tries the snap in the given directory, and tries it again
+// whenever its meta/snap.yaml changes so that the changes to the
+// security profiles and wrappers go live, until interrupted or snapd
+// cannot be reached. Other errors are reported but do not stop the
+// watching, the next change to meta/snap.yaml may fix them.
+func (x *cmdTry) watch(path, name string, opts *client.SnapOptions) error {
+	snapYaml := filepath.Join(path, "meta", "snap.yaml")
+
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt)
+	defer signal.Stop(sigs)
+
+	var last os.FileInfo
+	for {
+		fi, err := os.Stat(snapYaml)
+		switch {
+		case err != nil && last == nil:
+			// TRANSLATORS: %s is a path
+			return fmt.Errorf(i18n.G("cannot watch %s: %v"), snapYaml, err)
+		case err != nil:
+			// probably being rewritten, look again later
+		case last == nil || !fi.ModTime().Equal(last.ModTime()) || fi.Size() != last.Size():
+			if last != nil {
+				// TRANSLATORS: %s is a path
+				fmt.Fprintf(Stdout, i18n.G("%s changed, trying again\n"), snapYaml)
+			}
+			last = fi
+			if err := x.try(path, name, opts); err != nil {
+				if _, ok := err.(client.ConnectionError); ok {
+					return err
+				}
+				fmt.Fprintf(Stderr, errorPrefix, err)
+			}
+		}
+
+		select {
+		case <-sigs:
+			return nil
+		case <-time.After(tryWatchPollTime):
+		}
+	}
+}
+
 type cmdEnable struct {
 	waitMixin
 
@@ -1036,6 +1327,10 @@ func init() {
 			"revision": i18n.G("Remove only the given revision"),
 			// TRANSLATORS: This should not start with a lowercase letter.
 			"purge": i18n.G("Remove the snap without saving a snapshot of its data"),
+			// TRANSLATORS: This should not start with a lowercase letter.
+			"keep-data-for": i18n.G("Keep a snapshot of the snap data for the given time (e.g. 7d), to restore it on reinstall"),
+			// TRANSLATORS: This should not start with a lowercase letter.
+			"dry-run": i18n.G("Show the tasks the removal would perform, without performing it"),
 		}), nil)
 	addCommand("install", shortInstallHelp, longInstallHelp, func() flags.Commander { return &cmdInstall{} },
 		colorDescs.also(waitDescs).also(channelDescs).also(modeDescs).also(map[string]string{
@@ -1051,6 +1346,10 @@ func init() {
 			"name": i18n.G("Install the snap file under the given instance name"),
 			// TRANSLATORS: This should not start with a lowercase letter.
 			"cohort": i18n.G("Install the snap in the given cohort"),
+			// TRANSLATORS: This should not start with a lowercase letter.
+			"accept-terms": i18n.G("Accept the license agreement of the snap"),
+			// TRANSLATORS: This should not start with a lowercase letter.
+			"dry-run": i18n.G("Show the tasks the install would perform, without performing it"),
 		}), nil)
 	addCommand("refresh", shortRefreshHelp, longRefreshHelp, func() flags.Commander { return &cmdRefresh{} },
 		colorDescs.also(waitDescs).also(channelDescs).also(modeDescs).also(timeDescs).also(map[string]string{
@@ -1063,13 +1362,28 @@ func init() {
 			// TRANSLATORS: This should not start with a lowercase letter.
 			"time": i18n.G("Show auto refresh information but do not perform a refresh"),
 			// TRANSLATORS: This should not start with a lowercase letter.
+			"json": i18n.G("Show the auto refresh information from --time as JSON"),
+			// TRANSLATORS: This should not start with a lowercase letter.
 			"ignore-validation": i18n.G("Ignore validation by other snaps blocking the refresh"),
 			// TRANSLATORS: This should not start with a lowercase letter.
 			"cohort": i18n.G("Refresh the snap into the given cohort"),
 			// TRANSLATORS: This should not start with a lowercase letter.
 			"leave-cohort": i18n.G("Refresh the snap out of its cohort"),
+			// TRANSLATORS: This should not start with a lowercase letter.
+			"download-only": i18n.G("Download the refreshes without installing them"),
+			// TRANSLATORS: This should not start with a lowercase letter.
+			"apply-prefetched": i18n.G("Install the refreshes downloaded before with --download-only"),
+			// TRANSLATORS: This should not start with a lowercase letter.
+			"ignore-running": i18n.G("Refresh the snaps even if their apps or hooks are running"),
+			// TRANSLATORS: This should not start with a lowercase letter.
+			"kill-running": i18n.G("Terminate the running apps and hooks of the snaps before refreshing them"),
+			// TRANSLATORS: This should not start with a lowercase letter.
+			"dry-run": i18n.G("Show the tasks the refresh would perform, without performing it"),
 		}), nil)
-	addCommand("try", shortTryHelp, longTryHelp, func() flags.Commander { return &cmdTry{} }, waitDescs.also(modeDescs), nil)
+	addCommand("try", shortTryHelp, longTryHelp, func() flags.Commander { return &cmdTry{} }, waitDescs.also(modeDescs).also(map[string]string{
+		// TRANSLATORS: This should not start with a lowercase letter.
+		"watch": i18n.G("Try the snap again whenever its meta/snap.yaml changes"),
+	}), nil)
 	addCommand("enable", shortEnableHelp, longEnableHelp, func() flags.Commander { return &cmdEnable{} }, waitDescs, nil)
 	addCommand("disable", shortDisableHelp, longDisableHelp, func() flags.Commander { return &cmdDisable{} }, waitDescs, nil)
 	addCommand("revert", shortRevertHelp, longRevertHelp, func() flags.Commander { return &cmdRevert{} }, waitDescs.also(modeDescs).also(map[string]string{
@@ -20,6 +20,7 @@
 package main_test
 
 import (
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"mime"
@@ -208,6 +209,40 @@ func (s *SnapOpSuite) TestInstall(c *check.C) {
 	c.Check(s.srv.n, check.Equals, s.srv.total)
 }
 
+func (s *SnapOpSuite) TestInstallKeptData(c *check.C) {
+	n := 0
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		switch n {
+		case 0:
+			c.Check(r.Method, check.Equals, "POST")
+			c.Check(r.URL.Path, check.Equals, "/v2/snaps/foo")
+			w.WriteHeader(202)
+			fmt.Fprintln(w, `{"type":"async", "change": "42", "status-code": 202}`)
+		case 1:
+			c.Check(r.Method, check.Equals, "GET")
+			c.Check(r.URL.Path, check.Equals, "/v2/changes/42")
+			fmt.Fprintln(w, `{"type": "sync", "result": {"ready": true, "status": "Done", "data": {"kept-data": {"set-id": 7, "expiry-time": "2020-01-08T10:00:00Z"}}}}`)
+		case 2:
+			c.Check(r.Method, check.Equals, "GET")
+			c.Check(r.URL.Path, check.Equals, "/v2/snaps")
+			fmt.Fprintln(w, `{"type": "sync", "result": [{"name": "foo", "status": "active", "version": "1.0", "developer": "bar", "publisher": {"id": "bar-id", "username": "bar", "display-name": "Bar", "validation": "unproven"}, "revision":42, "channel":"stable", "tracking-channel": "stable"}]}`)
+		default:
+			c.Fatalf("expected to get 3 requests, now on %d", n+1)
+		}
+		n++
+	})
+
+	rest, err := snap.Parser(snap.Client()).ParseArgs([]string{"install", "foo"})
+	c.Assert(err, check.IsNil)
+	c.Assert(rest, check.DeepEquals, []string{})
+	c.Check(s.Stdout(), check.Equals, `foo 1.0 from Bar installed
+Data of foo from its previous removal is kept in snapshot #7 until 2020-01-08T10:00:00Z.
+Use 'snap restore 7' to restore it.
+`)
+	c.Check(s.Stderr(), check.Equals, "")
+	c.Check(n, check.Equals, 3)
+}
+
 func (s *SnapOpSuite) TestInstallNoPATH(c *check.C) {
 	// PATH restored by test tear down
 	os.Setenv("PATH", "/bin:/usr/bin:/sbin:/usr/sbin")
@@ -416,6 +451,84 @@ func (s *SnapOpSuite) TestInstallUnaliased(c *check.C) {
 	c.Check(s.srv.n, check.Equals, s.srv.total)
 }
 
+func (s *SnapOpSuite) TestInstallAcceptTerms(c *check.C) {
+	s.srv.checker = func(r *http.Request) {
+		c.Check(r.URL.Path, check.Equals, "/v2/snaps/foo")
+		c.Check(DecodedRequestBody(c, r), check.DeepEquals, map[string]interface{}{
+			"action":       "install",
+			"accept-terms": true,
+		})
+	}
+
+	s.RedirectClientToTestServer(s.srv.handle)
+	rest, err := snap.Parser(snap.Client()).ParseArgs([]string{"install", "--accept-terms", "foo"})
+	c.Assert(err, check.IsNil)
+	c.Assert(rest, check.DeepEquals, []string{})
+	c.Check(s.Stdout(), check.Matches, `(?sm).*foo 1.0 from Bar installed`)
+	c.Check(s.Stderr(), check.Equals, "")
+	// ensure that the fake server api was actually hit
+	c.Check(s.srv.n, check.Equals, s.srv.total)
+}
+
+func (s *SnapOpSuite) TestInstallAcceptTermsMany(c *check.C) {
+	n := 0
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		switch n {
+		case 0:
+			c.Check(r.Method, check.Equals, "POST")
+			c.Check(r.URL.Path, check.Equals, "/v2/snaps")
+			c.Check(DecodedRequestBody(c, r), check.DeepEquals, map[string]interface{}{
+				"action":       "install",
+				"snaps":        []interface{}{"foo", "bar"},
+				"accept-terms": true,
+			})
+			w.WriteHeader(202)
+			fmt.Fprintln(w, `{"type":"async", "change": "42", "status-code": 202}`)
+		case 1:
+			c.Check(r.Method, check.Equals, "GET")
+			c.Check(r.URL.Path, check.Equals, "/v2/changes/42")
+			fmt.Fprintln(w, `{"type": "sync", "result": {"ready": true, "status": "Done", "data": {"snap-names": []}}}`)
+		default:
+			c.Fatalf("expected to get 2 requests, now on %d", n+1)
+		}
+		n++
+	})
+	rest, err := snap.Parser(snap.Client()).ParseArgs([]string{"install", "--accept-terms", "foo", "bar"})
+	c.Assert(err, check.IsNil)
+	c.Assert(rest, check.DeepEquals, []string{})
+	c.Check(n, check.Equals, 2)
+}
+
+func (s *SnapOpSuite) TestInstallNeedsTermsAccepted(c *check.C) {
+	var value string
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(400)
+		fmt.Fprintf(w, `{
+  "type": "error",
+  "result": {
+    "message": "snap \"foo\" requires its terms to be accepted",
+    "value": %s,
+    "kind": "snap-needs-terms-accepted"
+  },
+  "status-code": 400
+}`, value)
+	})
+
+	for _, t := range []struct {
+		value    string
+		expected string
+	}{
+		{`{"snap-name": "foo"}`, `The publisher of snap "foo" requires .* \(license: unknown\)`},
+		{`{"snap-name": "foo", "license": "Proprietary", "license-version": "2"}`, `The publisher of snap "foo" requires .* \(license: Proprietary \(version 2\)\)`},
+	} {
+		value = t.value
+		_, err := snap.Parser(snap.Client()).ParseArgs([]string{"install", "foo"})
+		c.Assert(err, check.NotNil)
+		msg := strings.Join(strings.Fields(err.Error()), " ")
+		c.Check(msg, check.Matches, t.expected+`\. If you accept .*, repeat the command including --accept-terms.`)
+	}
+}
+
 func (s *SnapOpSuite) TestInstallSnapNotFound(c *check.C) {
 	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
 		fmt.Fprintln(w, `{"type": "error", "result": {"message": "snap not found", "value": "foo", "kind": "snap-not-found"}, "status-code": 404}`)
@@ -1138,6 +1251,61 @@ next: 2017-04-26T00:58:00+02:00 (but held)
 	c.Check(n, check.Equals, 1)
 }
 
+func (s *SnapSuite) TestRefreshTimeOutcomes(c *check.C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		c.Check(r.Method, check.Equals, "GET")
+		c.Check(r.URL.Path, check.Equals, "/v2/system-info")
+		fmt.Fprintln(w, `{"type": "sync", "status-code": 200, "result": {"refresh": {"timer": "0:00-24:00/4", "last": "2017-04-25T17:35:00+02:00", "next": "2017-04-26T00:58:00+02:00", "snaps": {"foo": {"last-attempt": "2017-04-25T17:35:00+02:00", "last-success": "2017-04-20T10:00:00+02:00", "last-error": "cannot download snap"}, "bar": {"last-attempt": "2017-04-25T17:35:00+02:00", "last-success": "2017-04-25T17:35:00+02:00"}}}}}`)
+	})
+	rest, err := snap.Parser(snap.Client()).ParseArgs([]string{"refresh", "--time", "--abs-time"})
+	c.Assert(err, check.IsNil)
+	c.Assert(rest, check.DeepEquals, []string{})
+	c.Check(s.Stdout(), check.Equals, `timer: 0:00-24:00/4
+last: 2017-04-25T17:35:00+02:00
+next: 2017-04-26T00:58:00+02:00
+refreshes:
+  bar:
+    attempted: 2017-04-25T17:35:00+02:00
+    succeeded: 2017-04-25T17:35:00+02:00
+  foo:
+    attempted: 2017-04-25T17:35:00+02:00
+    succeeded: 2017-04-20T10:00:00+02:00
+    error: cannot download snap
+`)
+	c.Check(s.Stderr(), check.Equals, "")
+}
+
+func (s *SnapSuite) TestRefreshTimeJSON(c *check.C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		c.Check(r.Method, check.Equals, "GET")
+		c.Check(r.URL.Path, check.Equals, "/v2/system-info")
+		fmt.Fprintln(w, `{"type": "sync", "status-code": 200, "result": {"refresh": {"timer": "0:00-24:00/4", "last": "2017-04-25T17:35:00+02:00", "next": "2017-04-26T00:58:00+02:00", "snaps": {"foo": {"last-attempt": "2017-04-25T17:35:00+02:00", "last-error": "cannot download snap"}}}}}`)
+	})
+	rest, err := snap.Parser(snap.Client()).ParseArgs([]string{"refresh", "--time", "--json"})
+	c.Assert(err, check.IsNil)
+	c.Assert(rest, check.DeepEquals, []string{})
+
+	var refresh map[string]interface{}
+	c.Assert(json.Unmarshal([]byte(s.Stdout()), &refresh), check.IsNil)
+	c.Check(refresh, check.DeepEquals, map[string]interface{}{
+		"timer": "0:00-24:00/4",
+		"last":  "2017-04-25T17:35:00+02:00",
+		"next":  "2017-04-26T00:58:00+02:00",
+		"snaps": map[string]interface{}{
+			"foo": map[string]interface{}{
+				"last-attempt": "2017-04-25T17:35:00+02:00",
+				"last-error":   "cannot download snap",
+			},
+		},
+	})
+	c.Check(s.Stderr(), check.Equals, "")
+}
+
+func (s *SnapSuite) TestRefreshJSONWithoutTime(c *check.C) {
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"refresh", "--json"})
+	c.Assert(err, check.ErrorMatches, `--json can only be used with --time`)
+}
+
 func (s *SnapSuite) TestRefreshNoTimerNoSchedule(c *check.C) {
 	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
 		c.Check(r.Method, check.Equals, "GET")
@@ -1282,6 +1450,58 @@ func (s *SnapOpSuite) TestRefreshOneIgnoreValidation(c *check.C) {
 	c.Assert(err, check.IsNil)
 }
 
+func (s *SnapOpSuite) TestRefreshOneKillRunning(c *check.C) {
+	s.RedirectClientToTestServer(s.srv.handle)
+	s.srv.checker = func(r *http.Request) {
+		c.Check(r.Method, check.Equals, "POST")
+		c.Check(r.URL.Path, check.Equals, "/v2/snaps/one")
+		c.Check(DecodedRequestBody(c, r), check.DeepEquals, map[string]interface{}{
+			"action":       "refresh",
+			"kill-running": true,
+		})
+	}
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"refresh", "--kill-running", "one"})
+	c.Assert(err, check.IsNil)
+}
+
+func (s *SnapOpSuite) TestRefreshManyIgnoreRunning(c *check.C) {
+	total := 2
+	n := 0
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		switch n {
+		case 0:
+			c.Check(r.Method, check.Equals, "POST")
+			c.Check(r.URL.Path, check.Equals, "/v2/snaps")
+			c.Check(DecodedRequestBody(c, r), check.DeepEquals, map[string]interface{}{
+				"action":         "refresh",
+				"snaps":          []interface{}{"one", "two"},
+				"ignore-running": true,
+			})
+			w.WriteHeader(202)
+			fmt.Fprintln(w, `{"type":"async", "change": "42", "status-code": 202}`)
+		case 1:
+			c.Check(r.URL.Path, check.Equals, "/v2/changes/42")
+			fmt.Fprintln(w, `{"type": "sync", "result": {"ready": true, "status": "Done", "data": {"snap-names": []}}}`)
+		default:
+			c.Fatalf("expected to get %d requests, now on %d", total, n+1)
+		}
+
+		n++
+	})
+
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"refresh", "--ignore-running", "one", "two"})
+	c.Assert(err, check.IsNil)
+	c.Check(n, check.Equals, total)
+}
+
+func (s *SnapOpSuite) TestRefreshIgnoreAndKillRunning(c *check.C) {
+	s.RedirectClientToTestServer(nil)
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"refresh", "--ignore-running", "--kill-running", "one"})
+	c.Assert(err, check.ErrorMatches, `cannot use --ignore-running and --kill-running together`)
+	_, err = snap.Parser(snap.Client()).ParseArgs([]string{"refresh", "--download-only", "--kill-running", "one"})
+	c.Assert(err, check.ErrorMatches, `--download-only and --apply-prefetched do not take other refresh options`)
+}
+
 func (s *SnapOpSuite) TestRefreshOneRebooting(c *check.C) {
 	s.RedirectClientToTestServer(s.srv.handle)
 	s.srv.checker = func(r *http.Request) {
@@ -1332,6 +1552,82 @@ func (s *SnapOpSuite) TestRefreshManyIgnoreValidation(c *check.C) {
 	c.Assert(err, check.ErrorMatches, `a single snap name must be specified when ignoring validation`)
 }
 
+func (s *SnapOpSuite) TestRefreshDownloadOnly(c *check.C) {
+	total := 3
+	n := 0
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		switch n {
+		case 0:
+			c.Check(r.URL.Path, check.Equals, "/v2/snaps")
+			c.Check(DecodedRequestBody(c, r), check.DeepEquals, map[string]interface{}{
+				"action":        "refresh",
+				"snaps":         []interface{}{"one"},
+				"download-only": true,
+			})
+			c.Check(r.Method, check.Equals, "POST")
+			w.WriteHeader(202)
+			fmt.Fprintln(w, `{"type":"async", "change": "42", "status-code": 202}`)
+		case 1:
+			c.Check(r.Method, check.Equals, "GET")
+			c.Check(r.URL.Path, check.Equals, "/v2/changes/42")
+			fmt.Fprintln(w, `{"type": "sync", "result": {"status": "Doing"}}`)
+		case 2:
+			c.Check(r.Method, check.Equals, "GET")
+			c.Check(r.URL.Path, check.Equals, "/v2/changes/42")
+			fmt.Fprintln(w, `{"type": "sync", "result": {"ready": true, "status": "Done", "data": {"snap-names": ["one"]}}}`)
+		default:
+			c.Fatalf("expected to get %d requests, now on %d", total, n+1)
+		}
+
+		n++
+	})
+
+	rest, err := snap.Parser(snap.Client()).ParseArgs([]string{"refresh", "--download-only", "one"})
+	c.Assert(err, check.IsNil)
+	c.Assert(rest, check.DeepEquals, []string{})
+	c.Check(s.Stdout(), check.Equals, "one refresh downloaded, use 'snap refresh --apply-prefetched' to install it\n")
+	c.Check(s.Stderr(), check.Equals, "")
+	c.Check(n, check.Equals, total)
+}
+
+func (s *SnapOpSuite) TestRefreshApplyPrefetchedNothing(c *check.C) {
+	total := 2
+	n := 0
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		switch n {
+		case 0:
+			c.Check(r.URL.Path, check.Equals, "/v2/snaps")
+			c.Check(DecodedRequestBody(c, r), check.DeepEquals, map[string]interface{}{
+				"action":           "refresh",
+				"apply-prefetched": true,
+			})
+			w.WriteHeader(202)
+			fmt.Fprintln(w, `{"type":"async", "change": "42", "status-code": 202}`)
+		case 1:
+			c.Check(r.URL.Path, check.Equals, "/v2/changes/42")
+			fmt.Fprintln(w, `{"type": "sync", "result": {"ready": true, "status": "Done", "data": {"snap-names": []}}}`)
+		default:
+			c.Fatalf("expected to get %d requests, now on %d", total, n+1)
+		}
+
+		n++
+	})
+
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"refresh", "--apply-prefetched"})
+	c.Assert(err, check.IsNil)
+	c.Check(s.Stdout(), check.Equals, "")
+	c.Check(s.Stderr(), check.Equals, "No prefetched refreshes to install.\n")
+	c.Check(n, check.Equals, total)
+}
+
+func (s *SnapOpSuite) TestRefreshDownloadOnlyErrors(c *check.C) {
+	s.RedirectClientToTestServer(nil)
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"refresh", "--download-only", "--apply-prefetched"})
+	c.Assert(err, check.ErrorMatches, `cannot use --download-only and --apply-prefetched together`)
+	_, err = snap.Parser(snap.Client()).ParseArgs([]string{"refresh", "--download-only", "--beta", "one"})
+	c.Assert(err, check.ErrorMatches, `--download-only and --apply-prefetched do not take other refresh options`)
+}
+
 func (s *SnapOpSuite) TestRefreshAllModeFlags(c *check.C) {
 	s.RedirectClientToTestServer(nil)
 	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"refresh", "--devmode"})
@@ -1447,6 +1743,72 @@ func (s *SnapOpSuite) TestTryNoSnapDirErrors(c *check.C) {
 Try 'snapcraft prime' in your project directory, then 'snap try' again.`)
 }
 
+func (s *SnapOpSuite) TestTryWatch(c *check.C) {
+	defer snap.MockTryWatchPollTime(time.Millisecond)()
+
+	tryDir := c.MkDir()
+	snapYaml := filepath.Join(tryDir, "meta", "snap.yaml")
+	c.Assert(os.MkdirAll(filepath.Dir(snapYaml), 0755), check.IsNil)
+	c.Assert(ioutil.WriteFile(snapYaml, []byte("name: foo\nversion: 1.0\n"), 0644), check.IsNil)
+
+	s.srv.checker = func(r *http.Request) {
+		c.Check(r.URL.Path, check.Equals, "/v2/snaps")
+		form := testForm(r, c)
+		defer form.RemoveAll()
+		c.Check(form.Value["action"], check.DeepEquals, []string{"try"})
+		c.Check(form.Value["snap-path"], check.DeepEquals, []string{tryDir})
+	}
+	tries := 0
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		if s.srv.n == 0 {
+			tries++
+			switch tries {
+			case 2:
+				// errors do not stop the watching
+				c.Assert(ioutil.WriteFile(snapYaml, []byte("name: foo\nversion: 1.000\n"), 0644), check.IsNil)
+				w.WriteHeader(400)
+				fmt.Fprintln(w, `{"type": "error", "result": {"message": "boom"}, "status-code": 400}`)
+				return
+			case 3:
+				// snapd went away
+				conn, _, err := w.(http.Hijacker).Hijack()
+				c.Assert(err, check.IsNil)
+				conn.Close()
+				return
+			}
+		}
+		s.srv.handle(w, r)
+		if s.srv.n == s.srv.total {
+			s.srv.n = 0
+			c.Assert(ioutil.WriteFile(snapYaml, []byte("name: foo\nversion: 1.00\n"), 0644), check.IsNil)
+		}
+	})
+
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"try", "--watch", tryDir})
+	c.Assert(err, check.ErrorMatches, "cannot communicate with server: .*")
+	c.Check(tries, check.Equals, 3)
+	c.Check(s.Stdout(), check.Equals, fmt.Sprintf(`foo 1.0 mounted from %[1]s
+%[1]s/meta/snap.yaml changed, trying again
+%[1]s/meta/snap.yaml changed, trying again
+`, tryDir))
+	c.Check(s.Stderr(), check.Equals, "error: boom\n")
+}
+
+func (s *SnapOpSuite) TestTryWatchNoSnapYaml(c *check.C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		c.Fatalf("unexpected request to %s", r.URL.Path)
+	})
+
+	tryDir := c.MkDir()
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"try", "--watch", tryDir})
+	c.Assert(err, check.ErrorMatches, `cannot watch .*/meta/snap.yaml: .* no such file or directory`)
+}
+
+func (s *SnapOpSuite) TestTryWatchNoWait(c *check.C) {
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"try", "--watch", "--no-wait", c.MkDir()})
+	c.Assert(err, check.ErrorMatches, "cannot use --watch with --no-wait")
+}
+
 func (s *SnapOpSuite) TestTryMissingOpt(c *check.C) {
 	oldArgs := os.Args
 	defer func() {
@@ -1611,6 +1973,127 @@ func (s *SnapOpSuite) TestRemoveWithPurge(c *check.C) {
 	c.Check(s.srv.n, check.Equals, s.srv.total)
 }
 
+func (s *SnapOpSuite) TestRemoveKeepDataFor(c *check.C) {
+	s.srv.total = 3
+	s.srv.checker = func(r *http.Request) {
+		c.Check(r.URL.Path, check.Equals, "/v2/snaps/foo")
+		c.Check(DecodedRequestBody(c, r), check.DeepEquals, map[string]interface{}{
+			"action":        "remove",
+			"keep-data-for": "168h0m0s",
+		})
+	}
+
+	s.RedirectClientToTestServer(s.srv.handle)
+	rest, err := snap.Parser(snap.Client()).ParseArgs([]string{"remove", "--keep-data-for=7d", "foo"})
+	c.Assert(err, check.IsNil)
+	c.Assert(rest, check.DeepEquals, []string{})
+	c.Check(s.Stdout(), check.Matches, `(?sm).*foo removed`)
+	c.Check(s.Stderr(), check.Equals, "")
+	// ensure that the fake server api was actually hit
+	c.Check(s.srv.n, check.Equals, s.srv.total)
+}
+
+func (s *SnapOpSuite) TestRemoveKeepDataForErrors(c *check.C) {
+	s.RedirectClientToTestServer(nil)
+	for _, t := range []struct {
+		args []string
+		err  string
+	}{
+		{[]string{"remove", "--keep-data-for=7d", "--purge", "foo"}, `cannot use --keep-data-for and --purge together`},
+		{[]string{"remove", "--keep-data-for=7d", "one", "two"}, `a single snap name is needed to keep its data`},
+		{[]string{"remove", "--keep-data-for=week", "foo"}, `invalid duration "week" for --keep-data-for \(try e.g. 7d or 12h\)`},
+		{[]string{"remove", "--keep-data-for=0d", "foo"}, `invalid duration "0d" for --keep-data-for .*`},
+		{[]string{"remove", "--keep-data-for=-3h", "foo"}, `invalid duration "-3h" for --keep-data-for .*`},
+	} {
+		_, err := snap.Parser(snap.Client()).ParseArgs(t.args)
+		c.Check(err, check.ErrorMatches, t.err, check.Commentf("%v", t.args))
+	}
+}
+
+func (s *SnapOpSuite) TestRemoveDryRun(c *check.C) {
+	n := 0
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		n++
+		c.Check(r.Method, check.Equals, "POST")
+		c.Check(r.URL.Path, check.Equals, "/v2/snaps/foo")
+		c.Check(DecodedRequestBody(c, r), check.DeepEquals, map[string]interface{}{
+			"action":  "remove",
+			"dry-run": true,
+		})
+		fmt.Fprintln(w, `{"type": "sync", "result": {
+"kind": "remove-snap",
+"summary": "Remove snap foo",
+"tasks": [
+  {"id": "1", "kind": "stop-snap-services", "summary": "Stop snap foo services"},
+  {"id": "2", "kind": "unlink-snap", "summary": "Make snap foo unavailable to the system", "wait-for": ["1"]},
+  {"id": "3", "kind": "discard-snap", "summary": "Remove snap foo", "wait-for": ["1", "2"]}
+],
+"affected-snaps": ["foo"]
+}}`)
+	})
+
+	rest, err := snap.Parser(snap.Client()).ParseArgs([]string{"remove", "--dry-run", "foo"})
+	c.Assert(err, check.IsNil)
+	c.Assert(rest, check.DeepEquals, []string{})
+	c.Check(s.Stdout(), check.Equals, `Plan: Remove snap foo
+Affected snaps: foo
+
+ID   Waits for  Summary
+1    -          Stop snap foo services
+2    1          Make snap foo unavailable to the system
+3    1,2        Remove snap foo
+`)
+	c.Check(s.Stderr(), check.Equals, "")
+	c.Check(n, check.Equals, 1)
+}
+
+func (s *SnapOpSuite) TestRefreshManyDryRunRestart(c *check.C) {
+	n := 0
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		n++
+		c.Check(r.Method, check.Equals, "POST")
+		c.Check(r.URL.Path, check.Equals, "/v2/snaps")
+		c.Check(DecodedRequestBody(c, r), check.DeepEquals, map[string]interface{}{
+			"action":  "refresh",
+			"snaps":   []interface{}{"core", "foo"},
+			"dry-run": true,
+		})
+		fmt.Fprintln(w, `{"type": "sync", "result": {
+"kind": "refresh-snap",
+"summary": "Refresh snaps core, foo",
+"tasks": [{"id": "1", "kind": "prerequisites", "summary": "Ensure prerequisites for core are available"}],
+"affected-snaps": ["core", "foo"],
+"restart": "system"
+}}`)
+	})
+
+	rest, err := snap.Parser(snap.Client()).ParseArgs([]string{"refresh", "--dry-run", "core", "foo"})
+	c.Assert(err, check.IsNil)
+	c.Assert(rest, check.DeepEquals, []string{})
+	c.Check(s.Stdout(), check.Matches, `(?sm)Plan: Refresh snaps core, foo
+Affected snaps: core, foo
+The system is expected to reboot.
+.*
+1 +- +Ensure prerequisites for core are available
+`)
+	c.Check(n, check.Equals, 1)
+}
+
+func (s *SnapOpSuite) TestDryRunErrors(c *check.C) {
+	s.RedirectClientToTestServer(nil)
+	for _, t := range []struct {
+		args []string
+		err  string
+	}{
+		{[]string{"install", "--dry-run", "./foo.snap"}, `cannot use --dry-run when installing from a snap file`},
+		{[]string{"install", "--dry-run", "--name=foo_bar", "foo"}, `cannot use explicit name when installing from store`},
+		{[]string{"refresh", "--dry-run", "--download-only"}, `cannot use --dry-run with --download-only or --apply-prefetched`},
+	} {
+		_, err := snap.Parser(snap.Client()).ParseArgs(t.args)
+		c.Check(err, check.ErrorMatches, t.err, check.Commentf("%v", t.args))
+	}
+}
+
 func (s *SnapOpSuite) TestRemoveRevision(c *check.C) {
 	s.srv.total = 3
 	s.srv.checker = func(r *http.Request) {
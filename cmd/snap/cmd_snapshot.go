@@ -58,6 +58,11 @@ for which users, or a combination of these.
 If a snap is included in a save operation, excluding its system and
 configuration data from the snapshot is not currently possible. This
 restriction may be lifted in the future.
+
+With --parent, only the data that changed since the given snapshot was
+saved is stored, and the snaps in that snapshot are saved by default.
+Such a snapshot needs its parent to be restored, which therefore cannot
+be forgotten before it.
 `)
 var longForgetHelp = i18n.G(`
 The forget command deletes a snapshot. This operation can not be
@@ -146,6 +151,9 @@ func (x *savedCmd) Execute([]string) error {
 			if sh.Auto {
 				notes = append(notes, "auto")
 			}
+			if sh.Parent != 0 {
+				notes = append(notes, fmt.Sprintf("parent: #%d", sh.Parent))
+			}
 			if sh.Broken != "" {
 				notes = append(notes, "broken: "+sh.Broken)
 			}
@@ -164,7 +172,8 @@ func (x *savedCmd) Execute([]string) error {
 type saveCmd struct {
 	waitMixin
 	durationMixin
-	Users      string `long:"users"`
+	Users      string     `long:"users"`
+	Parent     snapshotID `long:"parent"`
 	Positional struct {
 		Snaps []installedSnapName `positional-arg-name:"<snap>"`
 	} `positional-args:"yes"`
@@ -173,7 +182,18 @@ type saveCmd struct {
 func (x *saveCmd) Execute([]string) error {
 	snaps := installedSnapNames(x.Positional.Snaps)
 	users := strutil.CommaSeparatedList(x.Users)
-	setID, changeID, err := x.client.SnapshotMany(snaps, users)
+	var setID uint64
+	var changeID string
+	var err error
+	if x.Parent != "" {
+		parent, err := x.Parent.ToUint()
+		if err != nil {
+			return err
+		}
+		setID, changeID, err = x.client.SnapshotManyIncremental(parent, snaps, users)
+	} else {
+		setID, changeID, err = x.client.SnapshotMany(snaps, users)
+	}
 	if err != nil {
 		return err
 	}
@@ -326,6 +346,8 @@ func init() {
 		}, durationDescs.also(waitDescs).also(map[string]string{
 			// TRANSLATORS: This should not start with a lowercase letter.
 			"users": i18n.G("Snapshot data of only specific users (comma-separated) (default: all users)"),
+			// TRANSLATORS: This should not start with a lowercase letter.
+			"parent": i18n.G("Only save the data changed since the given snapshot"),
 		}), nil)
 
 	addCommand("restore",
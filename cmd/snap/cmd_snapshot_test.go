@@ -20,6 +20,7 @@
 package main_test
 
 import (
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"strings"
@@ -89,6 +90,39 @@ func (s *SnapSuite) TestSnapSnaphotsTest(c *C) {
 	}
 }
 
+func (s *SnapSuite) TestSaveIncremental(c *C) {
+	n := 0
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		n++
+		switch r.URL.Path {
+		case "/v2/snaps":
+			c.Check(r.Method, Equals, "POST")
+			c.Check(DecodedRequestBody(c, r), DeepEquals, map[string]interface{}{
+				"action": "snapshot",
+				"snaps":  []interface{}{"htop"},
+				"parent": json.Number("3"),
+			})
+			fmt.Fprintln(w, `{"type":"async", "status-code": 202, "change": "9", "result": {"set-id": 4}}`)
+		case "/v2/changes/9":
+			fmt.Fprintln(w, `{"type": "sync", "result": {"ready": true, "status": "Done", "data": {}}}`)
+		case "/v2/snapshots":
+			c.Check(r.URL.Query().Get("set"), Equals, "4")
+			fmt.Fprintf(w, `{"type":"sync","status-code":200,"status":"OK","result":[{"id":4,"snapshots":[{"set":4,"time":%q,"snap":"htop","revision":"1168","snap-id":"Z","parent":3,"epoch":{"read":[0],"write":[0]},"summary":"","version":"2","sha3-384":{"archive.tgz":""},"size":1}]}]}`, time.Now().Format(time.RFC3339))
+		default:
+			c.Errorf("unexpected path %q", r.URL.Path)
+		}
+	})
+
+	_, err := main.Parser(main.Client()).ParseArgs([]string{"save", "--parent=x", "htop"})
+	c.Check(err, ErrorMatches, "invalid argument for set id: expected a non-negative integer argument")
+	c.Check(n, Equals, 0)
+
+	_, err = main.Parser(main.Client()).ParseArgs([]string{"save", "--parent=3", "htop"})
+	c.Assert(err, IsNil)
+	c.Check(s.Stdout(), Matches, "Set  Snap  Age    Version  Rev   Size    Notes\n4    htop  .*  2        1168      1B  parent: #3\n")
+	c.Check(n, Equals, 3)
+}
+
 func (s *SnapSuite) mockSnapshotsServer(c *C) {
 	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
 		switch r.URL.Path {
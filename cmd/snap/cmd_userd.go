@@ -35,6 +35,7 @@ type cmdUserd struct {
 	userd userd.Userd
 
 	Autostart bool `long:"autostart"`
+	System    bool `long:"system"`
 }
 
 var shortUserdHelp = i18n.G("Start the userd service")
@@ -51,6 +52,8 @@ func init() {
 		}, map[string]string{
 			// TRANSLATORS: This should not start with a lowercase letter.
 			"autostart": i18n.G("Autostart user applications"),
+			// TRANSLATORS: This should not start with a lowercase letter.
+			"system": i18n.G("Serve the system-wide services on the system bus"),
 		}, nil)
 	cmd.hidden = true
 }
@@ -64,7 +67,11 @@ func (x *cmdUserd) Execute(args []string) error {
 		return x.runAutostart()
 	}
 
-	if err := x.userd.Init(); err != nil {
+	initBus := x.userd.Init
+	if x.System {
+		initBus = x.userd.InitSystem
+	}
+	if err := initBus(); err != nil {
 		return err
 	}
 	x.userd.Start()
@@ -0,0 +1,176 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2019 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+
+	"github.com/jessevdk/go-flags"
+
+	"github.com/snapcore/snapd/client"
+	"github.com/snapcore/snapd/i18n"
+)
+
+type cmdValidate struct {
+	clientMixin
+	Monitor    bool `long:"monitor"`
+	Enforce    bool `long:"enforce"`
+	Forget     bool `long:"forget"`
+	Positional struct {
+		ValidationSet string `positional-arg-name:"<validation-set>"`
+	} `positional-args:"yes"`
+}
+
+var shortValidateHelp = i18n.G("List or apply validation sets")
+var longValidateHelp = i18n.G(`
+The validate command lists or applies validation sets that state which snaps
+are required or permitted to be installed together, optionally constrained to
+fixed revisions.
+
+A validation set can either be in monitoring mode, in which case its
+constraints are only checked and reported, or in enforcing mode, in which case
+snapd refuses to apply it unless the installed snaps comply, and blocks
+refreshes and removals of snaps that would violate it.
+
+Without arguments the tracked validation sets are listed, with a validation set
+argument but no mode option the given tracked set is checked.
+`)
+
+func init() {
+	addCommand("validate", shortValidateHelp, longValidateHelp, func() flags.Commander { return &cmdValidate{} }, map[string]string{
+		// TRANSLATORS: This should not start with a lowercase letter.
+		"monitor": i18n.G("Monitor the given validation set"),
+		// TRANSLATORS: This should not start with a lowercase letter.
+		"enforce": i18n.G("Enforce the given validation set"),
+		// TRANSLATORS: This should not start with a lowercase letter.
+		"forget": i18n.G("Forget the given validation set"),
+	}, []argDesc{{
+		// TRANSLATORS: This needs to begin with < and end with >
+		name: i18n.G("<validation-set>"),
+		// TRANSLATORS: This should not start with a lowercase letter.
+		desc: i18n.G("Validation set with an optional pinned sequence point, i.e. account-id/name[=seq]"),
+	}})
+}
+
+var validationSetRefRx = regexp.MustCompile("^([a-zA-Z0-9]+)/([a-z0-9](?:-?[a-z0-9])*)(?:=([0-9]+))?$")
+
+func splitValidationSetRef(ref string) (accountID, name string, seq int, err error) {
+	parts := validationSetRefRx.FindStringSubmatch(ref)
+	if parts == nil {
+		return "", "", 0, fmt.Errorf(i18n.G("cannot parse validation set %q: expected account-id/name[=seq]"), ref)
+	}
+	if parts[3] != "" {
+		seq, err = strconv.Atoi(parts[3])
+		if err != nil || seq < 1 {
+			return "", "", 0, fmt.Errorf(i18n.G("cannot parse validation set %q: invalid sequence"), ref)
+		}
+	}
+	return parts[1], parts[2], seq, nil
+}
+
+func fmtValid(res *client.ValidationSetResult) string {
+	if res.Valid {
+		return i18n.G("valid")
+	}
+	return i18n.G("invalid")
+}
+
+func (cmd *cmdValidate) Execute(args []string) error {
+	if len(args) > 0 {
+		return ErrExtraArgs
+	}
+
+	modes := 0
+	for _, set := range []bool{cmd.Monitor, cmd.Enforce, cmd.Forget} {
+		if set {
+			modes++
+		}
+	}
+	if modes > 1 {
+		return fmt.Errorf(i18n.G("cannot use --monitor, --enforce and --forget together"))
+	}
+
+	if cmd.Positional.ValidationSet == "" {
+		if modes > 0 {
+			return fmt.Errorf(i18n.G("missing validation set argument"))
+		}
+		return cmd.list()
+	}
+
+	accountID, name, seq, err := splitValidationSetRef(cmd.Positional.ValidationSet)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case cmd.Forget:
+		if seq != 0 {
+			return fmt.Errorf(i18n.G("cannot specify a sequence with --forget"))
+		}
+		return cmd.client.ForgetValidationSet(accountID, name)
+	case cmd.Monitor, cmd.Enforce:
+		mode := "monitor"
+		if cmd.Enforce {
+			mode = "enforce"
+		}
+		res, err := cmd.client.ApplyValidationSet(accountID, name, mode, seq)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(Stdout, fmtValid(res))
+		return nil
+	default:
+		if seq != 0 {
+			return fmt.Errorf(i18n.G("cannot specify a sequence without --monitor or --enforce"))
+		}
+		res, err := cmd.client.ValidationSet(accountID, name)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(Stdout, fmtValid(res))
+		return nil
+	}
+}
+
+func (cmd *cmdValidate) list() error {
+	sets, err := cmd.client.ListValidationsSets()
+	if err != nil {
+		return err
+	}
+	if len(sets) == 0 {
+		fmt.Fprintln(Stderr, i18n.G("No validations are available"))
+		return nil
+	}
+
+	w := tabWriter()
+	defer w.Flush()
+
+	fmt.Fprintln(w, i18n.G("Validation\tMode\tSeq\tCurrent"))
+	for _, res := range sets {
+		ref := fmt.Sprintf("%s/%s", res.AccountID, res.Name)
+		if res.PinnedAt != 0 {
+			ref = fmt.Sprintf("%s=%d", ref, res.PinnedAt)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%d\t%s\n", ref, res.Mode, res.Sequence, fmtValid(res))
+	}
+	return nil
+}
@@ -0,0 +1,138 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2019 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package main_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"gopkg.in/check.v1"
+
+	snap "github.com/snapcore/snapd/cmd/snap"
+)
+
+func (s *SnapSuite) mockValidateServer(c *check.C, method, path, body, rsp string) *int {
+	n := 0
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		n++
+		c.Check(r.Method, check.Equals, method)
+		c.Check(r.URL.Path, check.Equals, path)
+		if body != "" {
+			var got, expected map[string]interface{}
+			c.Assert(json.NewDecoder(r.Body).Decode(&got), check.IsNil)
+			c.Assert(json.Unmarshal([]byte(body), &expected), check.IsNil)
+			c.Check(got, check.DeepEquals, expected)
+		}
+		fmt.Fprintln(w, rsp)
+	})
+	return &n
+}
+
+func (s *SnapSuite) TestValidateList(c *check.C) {
+	n := s.mockValidateServer(c, "GET", "/v2/validation-sets", "", `{"type": "sync", "status-code": 200, "result": [
+		{"account-id": "foo", "name": "bar", "mode": "monitor", "sequence": 3, "valid": true},
+		{"account-id": "foo", "name": "baz", "pinned-at": 2, "mode": "enforce", "sequence": 2, "valid": false}
+	]}`)
+
+	rest, err := snap.Parser(snap.Client()).ParseArgs([]string{"validate"})
+	c.Assert(err, check.IsNil)
+	c.Check(rest, check.HasLen, 0)
+	c.Check(s.Stdout(), check.Equals, `Validation  Mode     Seq  Current
+foo/bar     monitor  3    valid
+foo/baz=2   enforce  2    invalid
+`)
+	c.Check(s.Stderr(), check.Equals, "")
+	c.Check(*n, check.Equals, 1)
+}
+
+func (s *SnapSuite) TestValidateListEmpty(c *check.C) {
+	s.mockValidateServer(c, "GET", "/v2/validation-sets", "", `{"type": "sync", "status-code": 200, "result": []}`)
+
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"validate"})
+	c.Assert(err, check.IsNil)
+	c.Check(s.Stdout(), check.Equals, "")
+	c.Check(s.Stderr(), check.Equals, "No validations are available\n")
+}
+
+func (s *SnapSuite) TestValidateQuery(c *check.C) {
+	s.mockValidateServer(c, "GET", "/v2/validation-sets/foo/bar", "", `{"type": "sync", "status-code": 200, "result":
+		{"account-id": "foo", "name": "bar", "mode": "monitor", "sequence": 3, "valid": false}}`)
+
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"validate", "foo/bar"})
+	c.Assert(err, check.IsNil)
+	c.Check(s.Stdout(), check.Equals, "invalid\n")
+}
+
+func (s *SnapSuite) TestValidateMonitor(c *check.C) {
+	s.mockValidateServer(c, "POST", "/v2/validation-sets/foo/bar", `{"action": "apply", "mode": "monitor"}`, `{"type": "sync", "status-code": 200, "result":
+		{"account-id": "foo", "name": "bar", "mode": "monitor", "sequence": 3, "valid": true}}`)
+
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"validate", "--monitor", "foo/bar"})
+	c.Assert(err, check.IsNil)
+	c.Check(s.Stdout(), check.Equals, "valid\n")
+}
+
+func (s *SnapSuite) TestValidateEnforcePinned(c *check.C) {
+	s.mockValidateServer(c, "POST", "/v2/validation-sets/foo/bar", `{"action": "apply", "mode": "enforce", "sequence": 5}`, `{"type": "sync", "status-code": 200, "result":
+		{"account-id": "foo", "name": "bar", "pinned-at": 5, "mode": "enforce", "sequence": 5, "valid": true}}`)
+
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"validate", "--enforce", "foo/bar=5"})
+	c.Assert(err, check.IsNil)
+	c.Check(s.Stdout(), check.Equals, "valid\n")
+}
+
+func (s *SnapSuite) TestValidateEnforceError(c *check.C) {
+	s.mockValidateServer(c, "POST", "/v2/validation-sets/foo/bar", `{"action": "apply", "mode": "enforce"}`, `{"type": "error", "status-code": 409, "result": {"message": "cannot enforce validation set foo/bar: validation sets assertions are not met"}}`)
+
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"validate", "--enforce", "foo/bar"})
+	c.Assert(err, check.ErrorMatches, "cannot apply validation set: cannot enforce validation set foo/bar: .*")
+}
+
+func (s *SnapSuite) TestValidateForget(c *check.C) {
+	n := s.mockValidateServer(c, "POST", "/v2/validation-sets/foo/bar", `{"action": "forget"}`, `{"type": "sync", "status-code": 200, "result": null}`)
+
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"validate", "--forget", "foo/bar"})
+	c.Assert(err, check.IsNil)
+	c.Check(s.Stdout(), check.Equals, "")
+	c.Check(*n, check.Equals, 1)
+}
+
+func (s *SnapSuite) TestValidateInvalidArgs(c *check.C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		c.Fatalf("unexpected request")
+	})
+
+	for _, t := range []struct {
+		args []string
+		err  string
+	}{
+		{[]string{"validate", "--monitor", "--enforce", "foo/bar"}, "cannot use --monitor, --enforce and --forget together"},
+		{[]string{"validate", "--monitor"}, "missing validation set argument"},
+		{[]string{"validate", "foo"}, `cannot parse validation set "foo": expected account-id/name\[=seq\]`},
+		{[]string{"validate", "--enforce", "foo/Bar"}, `cannot parse validation set "foo/Bar": expected account-id/name\[=seq\]`},
+		{[]string{"validate", "--enforce", "foo/bar=0"}, `cannot parse validation set "foo/bar=0": invalid sequence`},
+		{[]string{"validate", "--forget", "foo/bar=1"}, "cannot specify a sequence with --forget"},
+		{[]string{"validate", "foo/bar=1"}, "cannot specify a sequence without --monitor or --enforce"},
+	} {
+		_, err := snap.Parser(snap.Client()).ParseArgs(t.args)
+		c.Check(err, check.ErrorMatches, t.err, check.Commentf("%v", t.args))
+	}
+}
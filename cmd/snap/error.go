@@ -195,6 +195,28 @@ If you understand and want to proceed repeat the command including --classic.
 `)
 	case client.ErrorKindSnapNotClassic:
 		msg = i18n.G(`snap %q is not compatible with --classic`)
+	case client.ErrorKindSnapNeedsTermsAccepted:
+		usesSnapName = false
+		values, _ := err.Value.(map[string]interface{})
+		if name, _ := values["snap-name"].(string); name != "" {
+			snapName = name
+		}
+		license, _ := values["license"].(string)
+		licenseVersion, _ := values["license-version"].(string)
+		if license == "" {
+			license = i18n.G("unknown")
+		}
+		if licenseVersion != "" {
+			// TRANSLATORS: the first %s is a license (e.g. "Proprietary"), the second its version
+			license = fmt.Sprintf(i18n.G("%s (version %s)"), license, licenseVersion)
+		}
+		// TRANSLATORS: %q is the snap name, %s is its license
+		msg = fmt.Sprintf(i18n.G(`
+The publisher of snap %q requires its license agreement to be explicitly
+accepted before it can be installed (license: %s).
+
+If you accept it, repeat the command including --accept-terms.
+`), snapName, license)
 	case client.ErrorKindLoginRequired:
 		usesSnapName = false
 		u, _ := user.Current()
@@ -25,6 +25,7 @@ import (
 
 	"github.com/jessevdk/go-flags"
 
+	"github.com/snapcore/snapd/asserts"
 	"github.com/snapcore/snapd/client"
 	"github.com/snapcore/snapd/image"
 	"github.com/snapcore/snapd/selinux"
@@ -128,6 +129,22 @@ func MockMaxGoneTime(d time.Duration) (restore func()) {
 	}
 }
 
+func MockTryWatchPollTime(d time.Duration) (restore func()) {
+	d0 := tryWatchPollTime
+	tryWatchPollTime = d
+	return func() {
+		tryWatchPollTime = d0
+	}
+}
+
+func MockFollowPollTime(d time.Duration) (restore func()) {
+	d0 := followPollTime
+	followPollTime = d
+	return func() {
+		followPollTime = d0
+	}
+}
+
 func MockSyscallExec(f func(string, []string, []string) error) (restore func()) {
 	syscallExecOrig := syscallExec
 	syscallExec = f
@@ -214,6 +231,14 @@ func MockIsStdinTTY(t bool) (restore func()) {
 	}
 }
 
+func MockAnswerSecurityKeyChallenge(f func(challenge string) (string, error)) (restore func()) {
+	old := answerSecurityKeyChallenge
+	answerSecurityKeyChallenge = f
+	return func() {
+		answerSecurityKeyChallenge = old
+	}
+}
+
 func MockTimeNow(newTimeNow func() time.Time) (restore func()) {
 	oldTimeNow := timeNow
 	timeNow = newTimeNow
@@ -295,4 +320,22 @@ func MockImagePrepare(newImagePrepare func(*image.Options) error) (restore func(
 	}
 }
 
+type NamedKeypairManager = namedKeypairManager
+
+func MockNewRemoteKeypairManager(f func(config *asserts.RemoteSignerConfig) (NamedKeypairManager, error)) (restore func()) {
+	old := newRemoteKeypairManager
+	newRemoteKeypairManager = f
+	return func() {
+		newRemoteKeypairManager = old
+	}
+}
+
 type ServiceName = serviceName
+
+func MockSnapNameFromPid(f func(pid int) (string, error)) (restore func()) {
+	old := snapNameFromPid
+	snapNameFromPid = f
+	return func() {
+		snapNameFromPid = old
+	}
+}
@@ -100,6 +100,9 @@ var commands []*cmdInfo
 // debugCommands holds information about all debug commands.
 var debugCommands []*cmdInfo
 
+// routineCommands holds information about all internal commands.
+var routineCommands []*cmdInfo
+
 // addCommand replaces parser.addCommand() in a way that is compatible with
 // re-constructing a pristine parser.
 func addCommand(name, shortHelp, longHelp string, builder func() flags.Commander, optDescs map[string]string, argDescs []argDesc) *cmdInfo {
@@ -131,6 +134,22 @@ func addDebugCommand(name, shortHelp, longHelp string, builder func() flags.Comm
 	return info
 }
 
+// addRoutineCommand replaces parser.addCommand() in a way that is
+// compatible with re-constructing a pristine parser. It is meant for
+// adding "routine" commands.
+func addRoutineCommand(name, shortHelp, longHelp string, builder func() flags.Commander, optDescs map[string]string, argDescs []argDesc) *cmdInfo {
+	info := &cmdInfo{
+		name:      name,
+		shortHelp: shortHelp,
+		longHelp:  longHelp,
+		builder:   builder,
+		optDescs:  optDescs,
+		argDescs:  argDescs,
+	}
+	routineCommands = append(routineCommands, info)
+	return info
+}
+
 type parserSetter interface {
 	setParser(*flags.Parser)
 }
@@ -290,14 +309,30 @@ func Parser(cli *client.Client) *flags.Parser {
 		logger.Panicf("cannot add command %q: %v", "debug", err)
 	}
 	// Add all the sub-commands of the debug command
-	for _, c := range debugCommands {
+	addSubCommands(debugCommand, "debug", debugCommands, cli)
+
+	// Add the routine command
+	routineCommand, err := parser.AddCommand("routine", shortRoutineHelp, longRoutineHelp, &cmdRoutine{})
+	routineCommand.Hidden = true
+	if err != nil {
+		logger.Panicf("cannot add command %q: %v", "routine", err)
+	}
+	// Add all the sub-commands of the routine command
+	addSubCommands(routineCommand, "routine", routineCommands, cli)
+	return parser
+}
+
+// addSubCommands adds the given sub-commands to the parent command, kind is
+// used to identify the parent in error messages.
+func addSubCommands(parent *flags.Command, kind string, cmds []*cmdInfo, cli *client.Client) {
+	for _, c := range cmds {
 		obj := c.builder()
 		if x, ok := obj.(clientSetter); ok {
 			x.setClient(cli)
 		}
-		cmd, err := debugCommand.AddCommand(c.name, c.shortHelp, strings.TrimSpace(c.longHelp), obj)
+		cmd, err := parent.AddCommand(c.name, c.shortHelp, strings.TrimSpace(c.longHelp), obj)
 		if err != nil {
-			logger.Panicf("cannot add debug command %q: %v", c.name, err)
+			logger.Panicf("cannot add %s command %q: %v", kind, c.name, err)
 		}
 		cmd.Hidden = c.hidden
 		opts := cmd.Options()
@@ -335,7 +370,6 @@ func Parser(cli *client.Client) *flags.Parser {
 			arg.Description = desc
 		}
 	}
-	return parser
 }
 
 var isStdinTTY = terminal.IsTerminal(0)
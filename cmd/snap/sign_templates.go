@@ -0,0 +1,309 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2018 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/mail"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/snapcore/snapd/i18n"
+	"github.com/snapcore/snapd/snap/naming"
+)
+
+// signTemplateHeader describes a header of an assertion type that
+// snap sign knows how to prompt for and check.
+type signTemplateHeader struct {
+	name   string
+	prompt string
+	// required headers must be given or have a default
+	required bool
+	// list headers are entered as comma-separated values
+	list bool
+	// dflt computes the default from the headers so far, if any
+	dflt func(headers map[string]interface{}) string
+	// validate checks a single value
+	validate func(v string) error
+}
+
+func defaultTo(v string) func(map[string]interface{}) string {
+	return func(map[string]interface{}) string { return v }
+}
+
+func defaultToHeader(name string) func(map[string]interface{}) string {
+	return func(headers map[string]interface{}) string {
+		v, _ := headers[name].(string)
+		return v
+	}
+}
+
+func defaultToNow(map[string]interface{}) string {
+	return timeNow().UTC().Format(time.RFC3339)
+}
+
+func defaultToNextYear(map[string]interface{}) string {
+	return timeNow().UTC().AddDate(1, 0, 0).Format(time.RFC3339)
+}
+
+func validateSnapWithTrack(v string) error {
+	name := strings.SplitN(v, "=", 2)[0]
+	return naming.ValidateSnap(name)
+}
+
+func validateRFC3339(v string) error {
+	if _, err := time.Parse(time.RFC3339, v); err != nil {
+		return fmt.Errorf(i18n.G("%q is not a RFC3339 date"), v)
+	}
+	return nil
+}
+
+func validateBool(v string) error {
+	if v != "true" && v != "false" {
+		return fmt.Errorf(i18n.G("%q is not true or false"), v)
+	}
+	return nil
+}
+
+func validateEmail(v string) error {
+	if _, err := mail.ParseAddress(v); err != nil {
+		return fmt.Errorf(i18n.G("%q is not an email address"), v)
+	}
+	return nil
+}
+
+var signTemplates = map[string][]*signTemplateHeader{
+	"model": {
+		{name: "authority-id", prompt: i18n.G("Account ID of the signer"), required: true},
+		{name: "brand-id", prompt: i18n.G("Account ID of the brand"), required: true, dflt: defaultToHeader("authority-id")},
+		{name: "series", prompt: i18n.G("Series"), required: true, dflt: defaultTo("16")},
+		{name: "model", prompt: i18n.G("Model name"), required: true},
+		{name: "display-name", prompt: i18n.G("Display name")},
+		{name: "classic", prompt: i18n.G("Classic model (true or false)"), dflt: defaultTo("false"), validate: validateBool},
+		{name: "architecture", prompt: i18n.G("Architecture")},
+		{name: "base", prompt: i18n.G("Base snap"), validate: naming.ValidateSnap},
+		{name: "gadget", prompt: i18n.G("Gadget snap"), validate: validateSnapWithTrack},
+		{name: "kernel", prompt: i18n.G("Kernel snap"), validate: validateSnapWithTrack},
+		{name: "store", prompt: i18n.G("Store ID")},
+		{name: "required-snaps", prompt: i18n.G("Required snaps"), list: true, validate: naming.ValidateSnap},
+		{name: "timestamp", prompt: i18n.G("Timestamp"), required: true, dflt: defaultToNow, validate: validateRFC3339},
+	},
+	"system-user": {
+		{name: "authority-id", prompt: i18n.G("Account ID of the signer"), required: true},
+		{name: "brand-id", prompt: i18n.G("Account ID of the brand"), required: true, dflt: defaultToHeader("authority-id")},
+		{name: "series", prompt: i18n.G("Series"), required: true, list: true, dflt: defaultTo("16")},
+		{name: "models", prompt: i18n.G("Models the user is valid for"), required: true, list: true},
+		{name: "name", prompt: i18n.G("Full name of the user")},
+		{name: "username", prompt: i18n.G("Username"), required: true},
+		{name: "email", prompt: i18n.G("Email address"), required: true, validate: validateEmail},
+		{name: "password", prompt: i18n.G("Hashed password")},
+		{name: "ssh-keys", prompt: i18n.G("SSH public keys"), list: true},
+		{name: "since", prompt: i18n.G("Valid since"), required: true, dflt: defaultToNow, validate: validateRFC3339},
+		{name: "until", prompt: i18n.G("Valid until"), required: true, dflt: defaultToNextYear, validate: validateRFC3339},
+		{name: "timestamp", prompt: i18n.G("Timestamp"), required: true, dflt: defaultToNow, validate: validateRFC3339},
+	},
+}
+
+func (h *signTemplateHeader) check(value interface{}) error {
+	var values []string
+	switch x := value.(type) {
+	case string:
+		if h.list {
+			return fmt.Errorf(i18n.G("header %q must be a list of strings"), h.name)
+		}
+		values = []string{x}
+	case []interface{}:
+		if !h.list {
+			return fmt.Errorf(i18n.G("header %q must be a string"), h.name)
+		}
+		for _, el := range x {
+			s, ok := el.(string)
+			if !ok {
+				return fmt.Errorf(i18n.G("header %q must be a list of strings"), h.name)
+			}
+			values = append(values, s)
+		}
+	default:
+		return fmt.Errorf(i18n.G("header %q has an unexpected value: %v"), h.name, value)
+	}
+	if h.validate == nil {
+		return nil
+	}
+	for _, v := range values {
+		if err := h.validate(v); err != nil {
+			return fmt.Errorf(i18n.G("invalid %q header: %v"), h.name, err)
+		}
+	}
+	return nil
+}
+
+// parse turns an interactively entered answer into a header value.
+func (h *signTemplateHeader) parse(answer string) interface{} {
+	if !h.list {
+		return answer
+	}
+	l := []interface{}{}
+	for _, v := range strings.Split(answer, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			l = append(l, v)
+		}
+	}
+	return l
+}
+
+// stringifyHeaders turns the scalars of a YAML (or JSON) mapping into
+// strings, as assertion headers are only strings, lists and maps.
+func stringifyHeaders(v interface{}) (interface{}, error) {
+	switch x := v.(type) {
+	case string:
+		return x, nil
+	case bool:
+		return strconv.FormatBool(x), nil
+	case int:
+		return strconv.Itoa(x), nil
+	case float64:
+		return strconv.FormatFloat(x, 'f', -1, 64), nil
+	case []interface{}:
+		l := make([]interface{}, len(x))
+		for i, el := range x {
+			el, err := stringifyHeaders(el)
+			if err != nil {
+				return nil, err
+			}
+			l[i] = el
+		}
+		return l, nil
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(x))
+		for k, item := range x {
+			kStr, ok := k.(string)
+			if !ok {
+				return nil, fmt.Errorf(i18n.G("non-string key: %v"), k)
+			}
+			item, err := stringifyHeaders(item)
+			if err != nil {
+				return nil, err
+			}
+			m[kStr] = item
+		}
+		return m, nil
+	default:
+		return nil, fmt.Errorf(i18n.G("unexpected value: %v"), v)
+	}
+}
+
+// templateHeaders reads the headers of an assertion of the given type
+// as a YAML (or JSON) mapping from input, filling in defaults and
+// checking them against the template for the type.
+func templateHeaders(assertType string, input []byte) (map[string]interface{}, error) {
+	var raw map[interface{}]interface{}
+	if err := yaml.Unmarshal(input, &raw); err != nil {
+		return nil, fmt.Errorf(i18n.G("cannot parse the assertion input: %v"), err)
+	}
+	v, err := stringifyHeaders(raw)
+	if err != nil {
+		return nil, fmt.Errorf(i18n.G("cannot parse the assertion input: %v"), err)
+	}
+	headers := v.(map[string]interface{})
+
+	if typ, ok := headers["type"]; ok && typ != assertType {
+		return nil, fmt.Errorf(i18n.G("assertion input type %q does not match --type %q"), typ, assertType)
+	}
+	headers["type"] = assertType
+
+	for _, h := range signTemplates[assertType] {
+		value, ok := headers[h.name]
+		if !ok && h.dflt != nil {
+			if dflt := h.dflt(headers); dflt != "" {
+				value = h.parse(dflt)
+				headers[h.name] = value
+				ok = true
+			}
+		}
+		if !ok {
+			if h.required {
+				return nil, fmt.Errorf(i18n.G("missing required header %q for %s assertion"), h.name, assertType)
+			}
+			continue
+		}
+		if err := h.check(value); err != nil {
+			return nil, err
+		}
+	}
+	return headers, nil
+}
+
+// promptHeaders prompts on out for the headers of an assertion of the
+// given type, reading the answers from in and checking them against the
+// template for the type.
+func promptHeaders(assertType string, in io.Reader, out io.Writer) (map[string]interface{}, error) {
+	headers := map[string]interface{}{
+		"type": assertType,
+	}
+	scanner := bufio.NewScanner(in)
+	for _, h := range signTemplates[assertType] {
+		var dflt string
+		if h.dflt != nil {
+			dflt = h.dflt(headers)
+		}
+		for {
+			prompt := h.prompt
+			if h.list {
+				// TRANSLATORS: %s is the prompt for an assertion header
+				prompt = fmt.Sprintf(i18n.G("%s (comma-separated)"), prompt)
+			}
+			if dflt != "" {
+				fmt.Fprintf(out, "%s [%s]: ", prompt, dflt)
+			} else {
+				fmt.Fprintf(out, "%s: ", prompt)
+			}
+			if !scanner.Scan() {
+				if err := scanner.Err(); err != nil {
+					return nil, err
+				}
+				return nil, fmt.Errorf(i18n.G("cannot read %q header: unexpected end of input"), h.name)
+			}
+			answer := strings.TrimSpace(scanner.Text())
+			if answer == "" {
+				answer = dflt
+			}
+			if answer == "" {
+				if h.required {
+					fmt.Fprintf(out, i18n.G("error: a value is required\n"))
+					continue
+				}
+				break
+			}
+			value := h.parse(answer)
+			if err := h.check(value); err != nil {
+				fmt.Fprintf(out, i18n.G("error: %v\n"), err)
+				continue
+			}
+			headers[h.name] = value
+			break
+		}
+	}
+	return headers, nil
+}
@@ -81,7 +81,10 @@ var api = []*Command{
 	snapCmd,
 	snapFileCmd,
 	snapDownloadCmd,
+	termsCmd,
 	snapConfCmd,
+	snapUserConfCmd,
+	snapFileAccessCmd,
 	interfacesCmd,
 	assertsCmd,
 	assertsFindManyCmd,
@@ -102,7 +105,12 @@ var api = []*Command{
 	snapshotCmd,
 	connectionsCmd,
 	modelCmd,
+	serialModelCmd,
 	cohortsCmd,
+	validationSetsListCmd,
+	validationSetsCmd,
+	bootChainCmd,
+	accessoriesCmd,
 }
 
 var (
@@ -274,6 +282,23 @@ func sysInfo(c *Command, r *http.Request, user *auth.UserState) Response {
 	} else {
 		refreshInfo.Schedule = refreshScheduleStr
 	}
+	outcomes, err := snapstate.RefreshOutcomes(st)
+	if err != nil {
+		return InternalError("cannot get refresh outcomes: %s", err)
+	}
+	for name, outcome := range outcomes {
+		if refreshInfo.Snaps == nil {
+			refreshInfo.Snaps = make(map[string]*client.SnapRefreshOutcome, len(outcomes))
+		}
+		snapOutcome := &client.SnapRefreshOutcome{
+			LastAttempt: formatRefreshTime(outcome.LastAttempt),
+			LastError:   outcome.LastError,
+		}
+		if outcome.LastSuccess != nil {
+			snapOutcome.LastSuccess = formatRefreshTime(*outcome.LastSuccess)
+		}
+		refreshInfo.Snaps[name] = snapOutcome
+	}
 
 	m := map[string]interface{}{
 		"series":         release.Series,
@@ -300,6 +325,10 @@ func sysInfo(c *Command, r *http.Request, user *auth.UserState) Response {
 		m["confinement"] = "strict"
 	}
 
+	if snapstate.StoreAuthDegraded(st) {
+		m["store-auth-degraded"] = true
+	}
+
 	// Convey richer information about features of available security backends.
 	if features := sandboxFeatures(c.d.overlord.InterfaceManager().Repository().Backends()); features != nil {
 		m["sandbox-features"] = features
@@ -380,6 +409,9 @@ func getSnapInfo(c *Command, r *http.Request, user *auth.UserState) Response {
 
 		return InternalError("%v", err)
 	}
+	if about.build == nil {
+		about.build = fetchSnapBuild(c.d.overlord.State(), about.info, user)
+	}
 
 	route := c.d.router.Get(c.Path)
 	if route == nil {
@@ -744,6 +776,18 @@ type snapInstruction struct {
 	IgnoreValidation bool          `json:"ignore-validation"`
 	Unaliased        bool          `json:"unaliased"`
 	Purge            bool          `json:"purge,omitempty"`
+	KeepDataFor      string        `json:"keep-data-for,omitempty"`
+	AcceptTerms      bool          `json:"accept-terms,omitempty"`
+	DownloadOnly     bool          `json:"download-only,omitempty"`
+	ApplyPrefetched  bool          `json:"apply-prefetched,omitempty"`
+	IgnoreRunning    bool          `json:"ignore-running,omitempty"`
+	KillRunning      bool          `json:"kill-running,omitempty"`
+	// DryRun requests the plan of the change the operation would
+	// create instead of performing it
+	DryRun bool `json:"dry-run,omitempty"`
+	// Parent is the ID of the snapshot set to only snapshot the
+	// changes relative to
+	Parent uint64 `json:"parent,omitempty"`
 	// dropping support temporarely until flag confusion is sorted,
 	// this isn't supported by client atm anyway
 	LeaveOld bool         `json:"temp-dropped-leave-old"`
@@ -765,6 +809,17 @@ func (inst *snapInstruction) revnoOpts() *snapstate.RevisionOptions {
 	}
 }
 
+func (inst *snapInstruction) keepDataFor() (time.Duration, error) {
+	if inst.KeepDataFor == "" {
+		return 0, nil
+	}
+	keepFor, err := time.ParseDuration(inst.KeepDataFor)
+	if err != nil || keepFor <= 0 {
+		return 0, fmt.Errorf("invalid keep-data-for duration %q", inst.KeepDataFor)
+	}
+	return keepFor, nil
+}
+
 func (inst *snapInstruction) modeFlags() (snapstate.Flags, error) {
 	return modeFlags(inst.DevMode, inst.JailMode, inst.Classic)
 }
@@ -777,6 +832,11 @@ func (inst *snapInstruction) installFlags() (snapstate.Flags, error) {
 	if inst.Unaliased {
 		flags.Unaliased = true
 	}
+	// the user needs to accept the license agreement of the snap
+	flags.CheckTerms = true
+	if inst.AcceptTerms || (inst.License != nil && inst.License.Agreed) {
+		flags.AcceptTerms = true
+	}
 	return flags, nil
 }
 
@@ -794,6 +854,8 @@ var (
 	snapstateTryPath           = snapstate.TryPath
 	snapstateUpdate            = snapstate.Update
 	snapstateUpdateMany        = snapstate.UpdateMany
+	snapstateDownloadMany      = snapstate.DownloadMany
+	snapstateApplyPrefetched   = snapstate.ApplyPrefetched
 	snapstateInstallMany       = snapstate.InstallMany
 	snapstateRemoveMany        = snapstate.RemoveMany
 	snapstateRevert            = snapstate.Revert
@@ -806,6 +868,10 @@ var (
 	snapshotRestore = snapshotstate.Restore
 	snapshotSave    = snapshotstate.Save
 
+	snapshotSaveIncremental = snapshotstate.SaveIncremental
+
+	snapshotKeptData = snapshotstate.KeptDataSnapshot
+
 	assertstateRefreshSnapDeclarations = assertstate.RefreshSnapDeclarations
 )
 
@@ -840,13 +906,32 @@ func modeFlags(devMode, jailMode, classic bool) (snapstate.Flags, error) {
 }
 
 func snapUpdateMany(inst *snapInstruction, st *state.State) (*snapInstructionResult, error) {
+	if inst.ApplyPrefetched {
+		return snapApplyPrefetchedMany(inst, st)
+	}
+
 	// we need refreshed snap-declarations to enforce refresh-control as best as we can, this also ensures that snap-declarations and their prerequisite assertions are updated regularly
-	if err := assertstateRefreshSnapDeclarations(st, inst.userID); err != nil {
-		return nil, err
+	// (a dry-run must not change the assertions database though)
+	if !inst.DryRun {
+		if err := assertstateRefreshSnapDeclarations(st, inst.userID); err != nil {
+			return nil, err
+		}
+	}
+
+	if inst.DownloadOnly {
+		return snapDownloadMany(inst, st)
+	}
+
+	var flags *snapstate.Flags
+	if inst.IgnoreRunning || inst.KillRunning {
+		flags = &snapstate.Flags{
+			IgnoreRunning: inst.IgnoreRunning,
+			KillRunning:   inst.KillRunning,
+		}
 	}
 
 	// TODO: use a per-request context
-	updated, tasksets, err := snapstateUpdateMany(context.TODO(), st, inst.Snaps, inst.userID, nil)
+	updated, tasksets, err := snapstateUpdateMany(context.TODO(), st, inst.Snaps, inst.userID, flags)
 	if err != nil {
 		return nil, err
 	}
@@ -875,6 +960,62 @@ func snapUpdateMany(inst *snapInstruction, st *state.State) (*snapInstructionRes
 	}, nil
 }
 
+func snapDownloadMany(inst *snapInstruction, st *state.State) (*snapInstructionResult, error) {
+	// TODO: use a per-request context
+	downloaded, tasksets, err := snapstateDownloadMany(context.TODO(), st, inst.Snaps, inst.userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var msg string
+	switch len(downloaded) {
+	case 0:
+		if len(inst.Snaps) != 0 {
+			// TRANSLATORS: the %s is a comma-separated list of quoted snap names
+			msg = fmt.Sprintf(i18n.G("Download refreshes of snaps %s: no updates"), strutil.Quoted(inst.Snaps))
+		} else {
+			msg = i18n.G("Download refreshes of all snaps: no updates")
+		}
+	case 1:
+		msg = fmt.Sprintf(i18n.G("Download refresh of snap %q"), downloaded[0])
+	default:
+		quoted := strutil.Quoted(downloaded)
+		// TRANSLATORS: the %s is a comma-separated list of quoted snap names
+		msg = fmt.Sprintf(i18n.G("Download refreshes of snaps %s"), quoted)
+	}
+
+	return &snapInstructionResult{
+		Summary:  msg,
+		Affected: downloaded,
+		Tasksets: tasksets,
+	}, nil
+}
+
+func snapApplyPrefetchedMany(inst *snapInstruction, st *state.State) (*snapInstructionResult, error) {
+	applied, tasksets, err := snapstateApplyPrefetched(st, inst.Snaps)
+	if err != nil {
+		return nil, err
+	}
+
+	var msg string
+	switch len(applied) {
+	case 0:
+		msg = i18n.G("Refresh snaps from prefetched downloads: no updates")
+	case 1:
+		msg = fmt.Sprintf(i18n.G("Refresh snap %q from prefetched download"), applied[0])
+	default:
+		quoted := strutil.Quoted(applied)
+		// TRANSLATORS: the %s is a comma-separated list of quoted snap names
+		msg = fmt.Sprintf(i18n.G("Refresh snaps %s from prefetched downloads"), quoted)
+	}
+
+	return &snapInstructionResult{
+		Summary:  msg,
+		Affected: applied,
+		Tasksets: tasksets,
+	}, nil
+}
+
 func verifySnapInstructions(inst *snapInstruction) error {
 	if inst.CohortKey != "" {
 		if inst.LeaveCohort {
@@ -892,6 +1033,39 @@ func verifySnapInstructions(inst *snapInstruction) error {
 			return fmt.Errorf("leave-cohort can only be specified for refresh or switch")
 		}
 	}
+	if inst.KeepDataFor != "" {
+		if inst.Action != "remove" {
+			return fmt.Errorf("keep-data-for can only be specified for remove")
+		}
+		if _, err := inst.keepDataFor(); err != nil {
+			return err
+		}
+	}
+	if inst.AcceptTerms && inst.Action != "install" {
+		return fmt.Errorf("accept-terms can only be specified for install")
+	}
+	if inst.IgnoreRunning || inst.KillRunning {
+		if inst.Action != "refresh" {
+			return fmt.Errorf("ignore-running and kill-running can only be specified for refresh")
+		}
+		if inst.IgnoreRunning && inst.KillRunning {
+			return fmt.Errorf("cannot specify both ignore-running and kill-running")
+		}
+		if inst.DownloadOnly || inst.ApplyPrefetched {
+			return fmt.Errorf("ignore-running and kill-running cannot be specified with download-only or apply-prefetched")
+		}
+	}
+	if inst.DownloadOnly || inst.ApplyPrefetched {
+		if inst.Action != "refresh" {
+			return fmt.Errorf("download-only and apply-prefetched can only be specified for refresh")
+		}
+		if inst.DownloadOnly && inst.ApplyPrefetched {
+			return fmt.Errorf("cannot specify both download-only and apply-prefetched")
+		}
+		if inst.Channel != "" || !inst.Revision.Unset() || inst.CohortKey != "" || inst.LeaveCohort || inst.Amend {
+			return fmt.Errorf("cannot change the tracked snap when using download-only or apply-prefetched")
+		}
+	}
 	switch inst.Action {
 	case "install":
 		for _, snapName := range inst.Snaps {
@@ -912,7 +1086,11 @@ func snapInstallMany(inst *snapInstruction, st *state.State) (*snapInstructionRe
 			return nil, fmt.Errorf(i18n.G("cannot install snap with empty name"))
 		}
 	}
-	installed, tasksets, err := snapstateInstallMany(st, inst.Snaps, inst.userID)
+	flags := &snapstate.Flags{
+		CheckTerms:  true,
+		AcceptTerms: inst.AcceptTerms,
+	}
+	installed, tasksets, err := snapstateInstallMany(st, inst.Snaps, inst.userID, flags)
 	if err != nil {
 		return nil, err
 	}
@@ -969,6 +1147,17 @@ func snapInstall(inst *snapInstruction, st *state.State) (string, []*state.TaskS
 }
 
 func snapUpdate(inst *snapInstruction, st *state.State) (string, []*state.TaskSet, error) {
+	if inst.DownloadOnly || inst.ApplyPrefetched {
+		res, err := snapUpdateMany(inst, st)
+		if err != nil {
+			return "", nil, err
+		}
+		if len(res.Tasksets) == 0 {
+			return "", nil, store.ErrNoUpdateAvailable
+		}
+		return res.Summary, res.Tasksets, nil
+	}
+
 	// TODO: bail if revision is given (and != current?), *or* behave as with install --revision?
 	flags, err := inst.modeFlags()
 	if err != nil {
@@ -980,10 +1169,15 @@ func snapUpdate(inst *snapInstruction, st *state.State) (string, []*state.TaskSe
 	if inst.Amend {
 		flags.Amend = true
 	}
+	flags.IgnoreRunning = inst.IgnoreRunning
+	flags.KillRunning = inst.KillRunning
 
 	// we need refreshed snap-declarations to enforce refresh-control as best as we can
-	if err = assertstateRefreshSnapDeclarations(st, inst.userID); err != nil {
-		return "", nil, err
+	// (a dry-run must not change the assertions database though)
+	if !inst.DryRun {
+		if err = assertstateRefreshSnapDeclarations(st, inst.userID); err != nil {
+			return "", nil, err
+		}
 	}
 
 	ts, err := snapstateUpdate(st, inst.Snaps[0], inst.revnoOpts(), inst.userID, flags)
@@ -1025,7 +1219,11 @@ func snapRemoveMany(inst *snapInstruction, st *state.State) (*snapInstructionRes
 }
 
 func snapRemove(inst *snapInstruction, st *state.State) (string, []*state.TaskSet, error) {
-	ts, err := snapstate.Remove(st, inst.Snaps[0], inst.Revision, &snapstate.RemoveFlags{Purge: inst.Purge})
+	keepFor, err := inst.keepDataFor()
+	if err != nil {
+		return "", nil, err
+	}
+	ts, err := snapstate.Remove(st, inst.Snaps[0], inst.Revision, &snapstate.RemoveFlags{Purge: inst.Purge, KeepDataFor: keepFor})
 	if err != nil {
 		return "", nil, err
 	}
@@ -1107,17 +1305,29 @@ func snapSwitch(inst *snapInstruction, st *state.State) (string, []*state.TaskSe
 }
 
 func snapshotMany(inst *snapInstruction, st *state.State) (*snapInstructionResult, error) {
-	setID, snapshotted, ts, err := snapshotSave(st, inst.Snaps, inst.Users)
+	var setID uint64
+	var snapshotted []string
+	var ts *state.TaskSet
+	var err error
+	if inst.Parent != 0 {
+		setID, snapshotted, ts, err = snapshotSaveIncremental(st, inst.Parent, inst.Snaps, inst.Users)
+	} else {
+		setID, snapshotted, ts, err = snapshotSave(st, inst.Snaps, inst.Users)
+	}
 	if err != nil {
 		return nil, err
 	}
 
 	var msg string
-	if len(inst.Snaps) == 0 {
-		msg = i18n.G("Snapshot all snaps")
-	} else {
+	switch {
+	case len(inst.Snaps) != 0:
 		// TRANSLATORS: the %s is a comma-separated list of quoted snap names
 		msg = fmt.Sprintf(i18n.G("Snapshot snaps %s"), strutil.Quoted(inst.Snaps))
+	case inst.Parent != 0:
+		// TRANSLATORS: the %d is the ID of a snapshot set
+		msg = fmt.Sprintf(i18n.G("Snapshot the snaps of snapshot set #%d"), inst.Parent)
+	default:
+		msg = i18n.G("Snapshot all snaps")
 	}
 
 	return &snapInstructionResult{
@@ -1188,12 +1398,43 @@ func postSnap(c *Command, r *http.Request, user *auth.UserState) Response {
 		return BadRequest("unknown action %s", inst.Action)
 	}
 
+	if inst.DryRun {
+		switch inst.Action {
+		case "install", "refresh", "remove":
+		default:
+			return BadRequest("cannot use dry-run with action %q", inst.Action)
+		}
+	}
+
+	if inst.DryRun {
+		plan, err := planSnapAction(state, &inst, impl)
+		if err != nil {
+			return inst.errToResponse(err)
+		}
+		return SyncResponse(plan, nil)
+	}
+
 	msg, tsets, err := impl(&inst, state)
 	if err != nil {
 		return inst.errToResponse(err)
 	}
 
 	chg := newChange(state, inst.Action+"-snap", msg, tsets, inst.Snaps)
+	if inst.Action == "install" {
+		// let the client offer restoring data kept from a previous removal
+		setID, expiry, err := snapshotKeptData(state, inst.Snaps[0])
+		if err != nil {
+			logger.Noticef("cannot look up kept data of snap %q: %v", inst.Snaps[0], err)
+		}
+		if setID != 0 {
+			chg.Set("api-data", map[string]interface{}{
+				"kept-data": map[string]interface{}{
+					"set-id":      setID,
+					"expiry-time": expiry,
+				},
+			})
+		}
+	}
 
 	ensureStateSoon(state)
 
@@ -1281,7 +1522,7 @@ func snapsOp(c *Command, r *http.Request, user *auth.UserState) Response {
 	}
 
 	// TODO: inst.Amend, etc?
-	if inst.Channel != "" || !inst.Revision.Unset() || inst.DevMode || inst.JailMode || inst.CohortKey != "" || inst.LeaveCohort {
+	if inst.Channel != "" || !inst.Revision.Unset() || inst.DevMode || inst.JailMode || inst.CohortKey != "" || inst.LeaveCohort || inst.KeepDataFor != "" {
 		return BadRequest("unsupported option provided for multi-snap operation")
 	}
 	if err := verifySnapInstructions(&inst); err != nil {
@@ -1310,6 +1551,20 @@ func snapsOp(c *Command, r *http.Request, user *auth.UserState) Response {
 	default:
 		return BadRequest("unsupported multi-snap operation %q", inst.Action)
 	}
+	if inst.DryRun && (inst.Action == "snapshot" || inst.DownloadOnly || inst.ApplyPrefetched) {
+		return BadRequest("cannot use dry-run with this multi-snap operation")
+	}
+	if inst.Parent != 0 && inst.Action != "snapshot" {
+		return BadRequest("cannot use a parent snapshot set with this multi-snap operation")
+	}
+	if inst.DryRun {
+		plan, err := planSnapsOp(st, &inst, op)
+		if err != nil {
+			return inst.errToResponse(err)
+		}
+		return SyncResponse(plan, nil)
+	}
+
 	res, err := op(&inst, st)
 	if err != nil {
 		return inst.errToResponse(err)
@@ -1558,6 +1813,13 @@ func getSnapConf(c *Command, r *http.Request, user *auth.UserState) Response {
 	tr := config.NewTransaction(s)
 	s.Unlock()
 
+	return confValuesResponse(tr, snapName, keys)
+}
+
+// confValuesResponse returns the values of the given configuration keys
+// of the snap as seen by the transaction, or the whole configuration
+// document if no keys are given.
+func confValuesResponse(tr *config.Transaction, snapName string, keys []string) Response {
 	currentConfValues := make(map[string]interface{})
 	// Special case - return root document
 	if len(keys) == 0 {
@@ -1739,6 +2001,20 @@ func changeInterfaces(c *Command, r *http.Request, user *auth.UserState) Respons
 	if len(a.Plugs) == 0 || len(a.Slots) == 0 {
 		return BadRequest("at least one plug and slot is required")
 	}
+	var duration time.Duration
+	if a.For != "" {
+		if a.Action != "connect" {
+			return BadRequest("a duration can only be specified when connecting")
+		}
+		var err error
+		duration, err = time.ParseDuration(a.For)
+		if err != nil {
+			return BadRequest("cannot parse duration: %v", err)
+		}
+		if duration <= 0 {
+			return BadRequest("duration must be positive, not %q", a.For)
+		}
+	}
 
 	var summary string
 	var err error
@@ -1766,7 +2042,11 @@ func changeInterfaces(c *Command, r *http.Request, user *auth.UserState) Respons
 			var ts *state.TaskSet
 			affected = snapNamesFromConns([]*interfaces.ConnRef{connRef})
 			summary = fmt.Sprintf("Connect %s:%s to %s:%s", connRef.PlugRef.Snap, connRef.PlugRef.Name, connRef.SlotRef.Snap, connRef.SlotRef.Name)
-			ts, err = ifacestate.Connect(st, connRef.PlugRef.Snap, connRef.PlugRef.Name, connRef.SlotRef.Snap, connRef.SlotRef.Name)
+			if duration > 0 {
+				ts, err = ifacestate.ConnectFor(st, connRef.PlugRef.Snap, connRef.PlugRef.Name, connRef.SlotRef.Snap, connRef.SlotRef.Name, duration)
+			} else {
+				ts, err = ifacestate.Connect(st, connRef.PlugRef.Snap, connRef.PlugRef.Name, connRef.SlotRef.Snap, connRef.SlotRef.Name)
+			}
 			if _, ok := err.(*ifacestate.ErrAlreadyConnected); ok {
 				change := newChange(st, a.Action+"-snap", summary, nil, affected)
 				change.SetStatus(state.DoneStatus)
@@ -1892,6 +2172,17 @@ func change2changeInfo(chg *state.Change) *changeInfo {
 	if chg.Get("api-data", &data) == nil {
 		chgInfo.Data = data
 	}
+	if chg.Kind() == "remodel" {
+		// the phases of a remodel change and their progress are
+		// computed as the change is queried, the tasks can change
+		if raw, err := json.Marshal(remodelPhases(chg)); err == nil {
+			if chgInfo.Data == nil {
+				chgInfo.Data = make(map[string]*json.RawMessage)
+			}
+			rawMsg := json.RawMessage(raw)
+			chgInfo.Data["remodel-phases"] = &rawMsg
+		}
+	}
 
 	return chgInfo
 }
@@ -2215,7 +2506,19 @@ type aliasStatus struct {
 }
 
 // getAliases produces a response with a map snap -> alias -> aliasStatus
+// or, with select=refresh, a preview of the automatic alias changes a
+// refresh of the snaps would make
 func getAliases(c *Command, r *http.Request, user *auth.UserState) Response {
+	query := r.URL.Query()
+	switch sel := query.Get("select"); sel {
+	case "":
+		// nothing to do
+	case "refresh":
+		return getAliasesRefreshPreview(c, strutil.CommaSeparatedList(query.Get("names")))
+	default:
+		return BadRequest("invalid select parameter: %q", sel)
+	}
+
 	state := c.d.overlord.State()
 	state.Lock()
 	defer state.Unlock()
@@ -2258,6 +2561,31 @@ func getAliases(c *Command, r *http.Request, user *auth.UserState) Response {
 	return SyncResponse(res, nil)
 }
 
+func getAliasesRefreshPreview(c *Command, names []string) Response {
+	st := c.d.overlord.State()
+	for _, name := range names {
+		if err := checkSnapInstalled(st, name); err != nil {
+			if err == state.ErrNoState {
+				return SnapNotFound(name, err)
+			}
+			return InternalError("cannot access snap state: %v", err)
+		}
+	}
+
+	st.Lock()
+	defer st.Unlock()
+
+	preview, err := snapstate.PreviewAutoAliasesRefresh(st, names)
+	if err != nil {
+		if _, ok := err.(*snapstate.AliasConflictError); ok {
+			return Conflict("%v", err)
+		}
+		return InternalError("cannot preview aliases changes: %v", err)
+	}
+
+	return SyncResponse(preview, nil)
+}
+
 func getAppsInfo(c *Command, r *http.Request, user *auth.UserState) Response {
 	query := r.URL.Query()
 
@@ -2330,6 +2658,10 @@ func getLogs(c *Command, r *http.Request, user *auth.UserState) Response {
 	}
 }
 
+// postApps starts, stops or restarts services of snaps. Snap services
+// are all system services, there is no user-scoped variant of this
+// operation: unlike the per-user configuration of snapUserConfCmd, it
+// always acts on the system-wide instance of the services.
 func postApps(c *Command, r *http.Request, user *auth.UserState) Response {
 	var inst servicestate.Instruction
 	decoder := json.NewDecoder(r.Body)
@@ -0,0 +1,106 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2019 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package daemon
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/snapcore/snapd/client"
+	"github.com/snapcore/snapd/i18n"
+	"github.com/snapcore/snapd/overlord/auth"
+	"github.com/snapcore/snapd/overlord/devicestate"
+	"github.com/snapcore/snapd/overlord/state"
+)
+
+var accessoriesCmd = &Command{
+	Path:   "/v2/accessories",
+	UserOK: true,
+	GET:    getAccessories,
+	POST:   postAccessories,
+}
+
+var (
+	devicestateGadgetActions   = devicestate.GadgetActions
+	devicestateRunGadgetAction = devicestate.RunGadgetAction
+)
+
+// getAccessories returns the maintenance actions of the device
+// declared and implemented by its gadget.
+func getAccessories(c *Command, r *http.Request, _ *auth.UserState) Response {
+	st := c.d.overlord.State()
+	st.Lock()
+	defer st.Unlock()
+
+	actions, err := devicestateGadgetActions(st)
+	if err == state.ErrNoState {
+		return NotFound("device has no gadget")
+	}
+	if err != nil {
+		return InternalError("cannot get gadget actions: %v", err)
+	}
+
+	result := make([]client.GadgetAction, len(actions))
+	for i, action := range actions {
+		result[i] = client.GadgetAction{
+			Name:    action.Name,
+			Summary: action.Summary,
+		}
+	}
+	return SyncResponse(result, nil)
+}
+
+type accessoriesAction struct {
+	Action string `json:"action"`
+	Name   string `json:"name"`
+}
+
+// postAccessories runs the given maintenance action of the device.
+func postAccessories(c *Command, r *http.Request, _ *auth.UserState) Response {
+	var action accessoriesAction
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&action); err != nil {
+		return BadRequest("cannot decode request body into accessories action: %v", err)
+	}
+	if action.Action != "run" {
+		return BadRequest("invalid action %q", action.Action)
+	}
+	if action.Name == "" {
+		return BadRequest("gadget action name is required")
+	}
+
+	st := c.d.overlord.State()
+	st.Lock()
+	defer st.Unlock()
+
+	ts, err := devicestateRunGadgetAction(st, action.Name)
+	if err == state.ErrNoState {
+		return NotFound("device has no gadget")
+	}
+	if err != nil {
+		return errToResponse(err, nil, BadRequest, "cannot run gadget action %q: %v", action.Name)
+	}
+
+	chg := newChange(st, "gadget-action", fmt.Sprintf(i18n.G("Run gadget action %q"), action.Name), []*state.TaskSet{ts}, nil)
+	ensureStateSoon(st)
+
+	return AsyncResponse(nil, &Meta{Change: chg.ID()})
+}
@@ -0,0 +1,126 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2019 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package daemon
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/client"
+	"github.com/snapcore/snapd/overlord/devicestate"
+	"github.com/snapcore/snapd/overlord/state"
+)
+
+func (s *apiSuite) TestGetAccessories(c *check.C) {
+	s.daemonWithOverlordMock(c)
+	restore := MockDevicestateGadgetActions(func(st *state.State) ([]*devicestate.GadgetAction, error) {
+		return []*devicestate.GadgetAction{
+			{Name: "blink-led", Summary: "Blink the identification LED"},
+			{Name: "export-diagnostics"},
+		}, nil
+	})
+	defer restore()
+
+	req, err := http.NewRequest("GET", "/v2/accessories", nil)
+	c.Assert(err, check.IsNil)
+	rsp := getAccessories(accessoriesCmd, req, nil).(*resp)
+	c.Assert(rsp.Status, check.Equals, 200)
+	c.Check(rsp.Result, check.DeepEquals, []client.GadgetAction{
+		{Name: "blink-led", Summary: "Blink the identification LED"},
+		{Name: "export-diagnostics"},
+	})
+}
+
+func (s *apiSuite) TestGetAccessoriesErrors(c *check.C) {
+	s.daemonWithOverlordMock(c)
+	var actionsErr error
+	restore := MockDevicestateGadgetActions(func(st *state.State) ([]*devicestate.GadgetAction, error) {
+		return nil, actionsErr
+	})
+	defer restore()
+
+	req, err := http.NewRequest("GET", "/v2/accessories", nil)
+	c.Assert(err, check.IsNil)
+
+	actionsErr = state.ErrNoState
+	rsp := getAccessories(accessoriesCmd, req, nil).(*resp)
+	c.Check(rsp.Status, check.Equals, 404)
+	c.Check(rsp.Result.(*errorResult).Message, check.Equals, "device has no gadget")
+
+	actionsErr = fmt.Errorf("boom")
+	rsp = getAccessories(accessoriesCmd, req, nil).(*resp)
+	c.Check(rsp.Status, check.Equals, 500)
+	c.Check(rsp.Result.(*errorResult).Message, check.Equals, "cannot get gadget actions: boom")
+}
+
+func (s *apiSuite) TestPostAccessoriesRun(c *check.C) {
+	d := s.daemonWithOverlordMock(c)
+	restore := MockDevicestateRunGadgetAction(func(st *state.State, name string) (*state.TaskSet, error) {
+		c.Check(name, check.Equals, "blink-led")
+		return state.NewTaskSet(st.NewTask("run-hook", "...")), nil
+	})
+	defer restore()
+
+	req, err := http.NewRequest("POST", "/v2/accessories", strings.NewReader(`{"action": "run", "name": "blink-led"}`))
+	c.Assert(err, check.IsNil)
+	rsp := postAccessories(accessoriesCmd, req, nil).(*resp)
+	c.Assert(rsp.Status, check.Equals, 202)
+
+	st := d.overlord.State()
+	st.Lock()
+	defer st.Unlock()
+	chg := st.Change(rsp.Change)
+	c.Assert(chg, check.NotNil)
+	c.Check(chg.Kind(), check.Equals, "gadget-action")
+	c.Check(chg.Summary(), check.Equals, `Run gadget action "blink-led"`)
+	c.Check(chg.Tasks(), check.HasLen, 1)
+}
+
+func (s *apiSuite) TestPostAccessoriesErrors(c *check.C) {
+	s.daemonWithOverlordMock(c)
+	restore := MockDevicestateRunGadgetAction(func(st *state.State, name string) (*state.TaskSet, error) {
+		if name == "no-gadget" {
+			return nil, state.ErrNoState
+		}
+		return nil, fmt.Errorf(`gadget "pc" has no action %q`, name)
+	})
+	defer restore()
+
+	for _, t := range []struct {
+		body    string
+		status  int
+		message string
+	}{
+		{`garbage`, 400, `cannot decode request body into accessories action: .*`},
+		{`{"action": "list"}`, 400, `invalid action "list"`},
+		{`{"action": "run"}`, 400, `gadget action name is required`},
+		{`{"action": "run", "name": "reboot-bmc"}`, 400, `cannot run gadget action "reboot-bmc": gadget "pc" has no action "reboot-bmc"`},
+		{`{"action": "run", "name": "no-gadget"}`, 404, `device has no gadget`},
+	} {
+		req, err := http.NewRequest("POST", "/v2/accessories", strings.NewReader(t.body))
+		c.Assert(err, check.IsNil)
+		rsp := postAccessories(accessoriesCmd, req, nil).(*resp)
+		c.Check(rsp.Status, check.Equals, t.status, check.Commentf(t.body))
+		c.Check(rsp.Result.(*errorResult).Message, check.Matches, t.message, check.Commentf(t.body))
+	}
+}
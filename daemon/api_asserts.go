@@ -20,9 +20,11 @@
 package daemon
 
 import (
+	"encoding/json"
 	"net/http"
 
 	"github.com/snapcore/snapd/asserts"
+	"github.com/snapcore/snapd/client"
 	"github.com/snapcore/snapd/overlord/assertstate"
 	"github.com/snapcore/snapd/overlord/auth"
 )
@@ -50,6 +52,10 @@ func getAssertTypeNames(c *Command, r *http.Request, user *auth.UserState) Respo
 }
 
 func doAssert(c *Command, r *http.Request, user *auth.UserState) Response {
+	if r.Header.Get("Content-Type") == "application/json" {
+		return assertsAction(c, r, user)
+	}
+
 	batch := assertstate.NewBatch()
 	_, err := batch.AddStream(r.Body)
 	if err != nil {
@@ -70,6 +76,39 @@ func doAssert(c *Command, r *http.Request, user *auth.UserState) Response {
 	}
 }
 
+var assertstateFetchSnapAssertions = assertstate.FetchSnapAssertions
+
+func assertsAction(c *Command, r *http.Request, user *auth.UserState) Response {
+	var action client.AssertionsAction
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&action); err != nil {
+		return BadRequest("cannot decode request body into assertions action: %v", err)
+	}
+	if action.Action != "fetch" {
+		return BadRequest("invalid action %q", action.Action)
+	}
+	if (action.SnapID == "") == (action.SnapSHA3_384 == "") {
+		return BadRequest("exactly one of snap-id and snap-sha3-384 must be given")
+	}
+
+	st := c.d.overlord.State()
+	st.Lock()
+	defer st.Unlock()
+
+	userID := 0
+	if user != nil {
+		userID = user.ID
+	}
+	err := assertstateFetchSnapAssertions(st, action.SnapID, action.SnapSHA3_384, userID)
+	if asserts.IsNotFound(err) {
+		return NotFound("cannot find snap assertions in the store: %v", err)
+	}
+	if err != nil {
+		return BadRequest("cannot fetch snap assertions: %v", err)
+	}
+	return SyncResponse(nil, nil)
+}
+
 func assertsFindMany(c *Command, r *http.Request, user *auth.UserState) Response {
 	assertTypeName := muxVars(r)["assertType"]
 	assertType := asserts.Type(assertTypeName)
@@ -22,6 +22,7 @@ package daemon_test
 import (
 	"bytes"
 	"encoding/json"
+	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
@@ -38,6 +39,8 @@ import (
 	"github.com/snapcore/snapd/overlord"
 	"github.com/snapcore/snapd/overlord/assertstate"
 	"github.com/snapcore/snapd/overlord/assertstate/assertstatetest"
+	"github.com/snapcore/snapd/overlord/auth"
+	"github.com/snapcore/snapd/overlord/state"
 	"github.com/snapcore/snapd/testutil"
 )
 
@@ -164,6 +167,59 @@ func (s *assertsSuite) TestAssertError(c *check.C) {
 	c.Check(rec.Body.String(), testutil.Contains, "assert failed")
 }
 
+func (s *assertsSuite) TestAssertFetchFromStore(c *check.C) {
+	var calls []string
+	restore := daemon.MockAssertstateFetchSnapAssertions(func(st *state.State, snapID, snapSHA3_384 string, userID int) error {
+		calls = append(calls, fmt.Sprintf("%s:%s:%d", snapID, snapSHA3_384, userID))
+		return nil
+	})
+	defer restore()
+
+	for _, body := range []string{
+		`{"action": "fetch", "snap-id": "snap-id-1"}`,
+		`{"action": "fetch", "snap-sha3-384": "digest"}`,
+	} {
+		req, err := http.NewRequest("POST", "/v2/assertions", bytes.NewBufferString(body))
+		c.Assert(err, check.IsNil)
+		req.Header.Set("Content-Type", "application/json")
+		rsp := daemon.DoAssert(daemon.AssertsCmd, req, &auth.UserState{ID: 42})
+		c.Check(rsp.Type, check.Equals, daemon.ResponseTypeSync)
+		c.Check(rsp.Status, check.Equals, 200)
+	}
+	c.Check(calls, check.DeepEquals, []string{"snap-id-1::42", ":digest:42"})
+}
+
+func (s *assertsSuite) TestAssertFetchFromStoreErrors(c *check.C) {
+	restore := daemon.MockAssertstateFetchSnapAssertions(func(st *state.State, snapID, snapSHA3_384 string, userID int) error {
+		if snapID == "unknown-id" {
+			return &asserts.NotFoundError{Type: asserts.SnapDeclarationType}
+		}
+		return fmt.Errorf("boom")
+	})
+	defer restore()
+
+	for _, t := range []struct {
+		body   string
+		status int
+		err    string
+	}{
+		{`{"action": "fetch"`, 400, `cannot decode request body into assertions action: .*`},
+		{`{"action": "frob", "snap-id": "snap-id-1"}`, 400, `invalid action "frob"`},
+		{`{"action": "fetch"}`, 400, `exactly one of snap-id and snap-sha3-384 must be given`},
+		{`{"action": "fetch", "snap-id": "snap-id-1", "snap-sha3-384": "digest"}`, 400, `exactly one of snap-id and snap-sha3-384 must be given`},
+		{`{"action": "fetch", "snap-id": "unknown-id"}`, 404, `cannot find snap assertions in the store: snap-declaration .*not found`},
+		{`{"action": "fetch", "snap-id": "snap-id-1"}`, 400, `cannot fetch snap assertions: boom`},
+	} {
+		req, err := http.NewRequest("POST", "/v2/assertions", bytes.NewBufferString(t.body))
+		c.Assert(err, check.IsNil)
+		req.Header.Set("Content-Type", "application/json")
+		rsp := daemon.DoAssert(daemon.AssertsCmd, req, nil)
+		c.Check(rsp.Type, check.Equals, daemon.ResponseTypeError, check.Commentf(t.body))
+		c.Check(rsp.Status, check.Equals, t.status, check.Commentf(t.body))
+		c.Check(rsp.Result.(*daemon.ErrorResult).Message, check.Matches, t.err, check.Commentf(t.body))
+	}
+}
+
 func (s *assertsSuite) TestAssertsFindManyAll(c *check.C) {
 	acct := assertstest.NewAccount(s.storeSigning, "developer1", map[string]interface{}{
 		"account-id": "developer1-id",
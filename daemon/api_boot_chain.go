@@ -0,0 +1,46 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2018 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package daemon
+
+import (
+	"net/http"
+	"os"
+
+	"github.com/snapcore/snapd/boot"
+	"github.com/snapcore/snapd/overlord/auth"
+)
+
+var bootChainCmd = &Command{
+	Path: "/v2/boot-chain",
+	GET:  getBootChain,
+}
+
+// getBootChain returns the measurement log of the boot chain used for
+// the current boot.
+func getBootChain(c *Command, r *http.Request, _ *auth.UserState) Response {
+	log, err := boot.ReadBootChainLog()
+	if os.IsNotExist(err) {
+		return NotFound("no boot chain recorded for the current boot")
+	}
+	if err != nil {
+		return InternalError("cannot get boot chain: %v", err)
+	}
+	return SyncResponse(log, nil)
+}
@@ -0,0 +1,74 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2018 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package daemon
+
+import (
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/boot"
+)
+
+func (s *apiSuite) TestGetBootChain(c *check.C) {
+	req, err := http.NewRequest("GET", "/v2/boot-chain", nil)
+	c.Assert(err, check.IsNil)
+
+	// nothing recorded yet
+	rsp := getBootChain(bootChainCmd, req, nil).(*resp)
+	c.Check(rsp.Type, check.Equals, ResponseTypeError)
+	c.Check(rsp.Status, check.Equals, 404)
+
+	c.Assert(os.MkdirAll(filepath.Dir(boot.BootChainLogFile()), 0755), check.IsNil)
+	c.Assert(ioutil.WriteFile(boot.BootChainLogFile(), []byte(`{
+"boot-id": "some-boot-id",
+"bootloader": "grub",
+"time": "2019-10-10T10:10:10Z",
+"entries": [{"kind": "kernel", "name": "pc-kernel_1.snap", "path": "/var/lib/snapd/snaps/pc-kernel_1.snap", "sha3-384": "digest", "size": 42}]
+}`), 0644), check.IsNil)
+
+	rsp = getBootChain(bootChainCmd, req, nil).(*resp)
+	c.Assert(rsp.Type, check.Equals, ResponseTypeSync)
+	c.Assert(rsp.Status, check.Equals, 200)
+	log := rsp.Result.(*boot.BootChainLog)
+	c.Check(log.BootID, check.Equals, "some-boot-id")
+	c.Check(log.Entries, check.DeepEquals, []boot.BootChainEntry{{
+		Kind:     boot.BootChainKernel,
+		Name:     "pc-kernel_1.snap",
+		Path:     "/var/lib/snapd/snaps/pc-kernel_1.snap",
+		SHA3_384: "digest",
+		Size:     42,
+	}})
+}
+
+func (s *apiSuite) TestGetBootChainError(c *check.C) {
+	c.Assert(os.MkdirAll(filepath.Dir(boot.BootChainLogFile()), 0755), check.IsNil)
+	c.Assert(ioutil.WriteFile(boot.BootChainLogFile(), []byte(`garbage`), 0644), check.IsNil)
+
+	req, err := http.NewRequest("GET", "/v2/boot-chain", nil)
+	c.Assert(err, check.IsNil)
+	rsp := getBootChain(bootChainCmd, req, nil).(*resp)
+	c.Check(rsp.Type, check.Equals, ResponseTypeError)
+	c.Check(rsp.Status, check.Equals, 500)
+	c.Check(rsp.Result.(*errorResult).Message, check.Matches, "cannot get boot chain: cannot decode boot chain log: .*")
+}
@@ -22,6 +22,7 @@ package daemon
 import (
 	"net/http"
 	"sort"
+	"strings"
 
 	"github.com/snapcore/snapd/interfaces"
 	"github.com/snapcore/snapd/overlord/auth"
@@ -40,6 +41,11 @@ type collectFilter struct {
 	snapName  string
 	ifaceName string
 	connected bool
+	// origin selects connections that were established manually,
+	// automatically or by the gadget
+	origin string
+	// attrs, when set, limits the reported attributes to the given ones
+	attrs []string
 }
 
 func (c *collectFilter) plugOrConnectedSlotMatches(plug *interfaces.PlugRef, connectedSlots []interfaces.SlotRef) bool {
@@ -73,6 +79,31 @@ func (c *collectFilter) ifaceMatches(ifaceName string) bool {
 	return true
 }
 
+func (c *collectFilter) originMatches(manual, gadget bool) bool {
+	switch c.origin {
+	case "manual":
+		return manual
+	case "gadget":
+		return gadget
+	case "auto":
+		return !manual && !gadget
+	}
+	return true
+}
+
+func (c *collectFilter) selectAttrs(attrs map[string]interface{}) map[string]interface{} {
+	if c.attrs == nil || attrs == nil {
+		return attrs
+	}
+	selected := make(map[string]interface{}, len(c.attrs))
+	for _, name := range c.attrs {
+		if v, ok := attrs[name]; ok {
+			selected[name] = v
+		}
+	}
+	return selected
+}
+
 type bySlotRef []interfaces.SlotRef
 
 func (b bySlotRef) Len() int      { return len(b) }
@@ -151,12 +182,17 @@ func collectConnections(ifaceMgr *ifacestate.InterfaceManager, filter collectFil
 			Manual:    cstate.Auto == false,
 			Gadget:    cstate.ByGadget,
 			Interface: cstate.Interface,
-			PlugAttrs: mergeAttrs(cstate.StaticPlugAttrs, cstate.DynamicPlugAttrs),
-			SlotAttrs: mergeAttrs(cstate.StaticSlotAttrs, cstate.DynamicSlotAttrs),
+			PlugAttrs: filter.selectAttrs(mergeAttrs(cstate.StaticPlugAttrs, cstate.DynamicPlugAttrs)),
+			SlotAttrs: filter.selectAttrs(mergeAttrs(cstate.StaticSlotAttrs, cstate.DynamicSlotAttrs)),
 		}
 		if cstate.Undesired {
 			// explicitly disconnected are always manual
 			cj.Manual = true
+		}
+		if !filter.originMatches(cj.Manual, cj.Gadget) {
+			continue
+		}
+		if cstate.Undesired {
 			connsjson.Undesired = append(connsjson.Undesired, cj)
 		} else {
 			plugConns[plugID] = append(plugConns[plugID], slotRef)
@@ -184,7 +220,7 @@ func collectConnections(ifaceMgr *ifacestate.InterfaceManager, filter collectFil
 			Snap:        plugRef.Snap,
 			Name:        plugRef.Name,
 			Interface:   plug.Interface,
-			Attrs:       plug.Attrs,
+			Attrs:       filter.selectAttrs(plug.Attrs),
 			Apps:        apps,
 			Label:       plug.Label,
 			Connections: connectedSlots,
@@ -209,7 +245,7 @@ func collectConnections(ifaceMgr *ifacestate.InterfaceManager, filter collectFil
 			Snap:        slotRef.Snap,
 			Name:        slotRef.Name,
 			Interface:   slot.Interface,
-			Attrs:       slot.Attrs,
+			Attrs:       filter.selectAttrs(slot.Attrs),
 			Apps:        apps,
 			Label:       slot.Label,
 			Connections: connectedPlugs,
@@ -249,6 +285,23 @@ func getConnections(c *Command, r *http.Request, user *auth.UserState) Response
 		return BadRequest("unsupported select qualifier")
 	}
 	onlyConnected := qselect == ""
+	origin := query.Get("origin")
+	switch origin {
+	case "", "manual", "auto", "gadget":
+	default:
+		return BadRequest("unsupported origin qualifier")
+	}
+	var attrs []string
+	if qattrs, ok := query["attrs"]; ok {
+		attrs = []string{}
+		for _, names := range qattrs {
+			for _, name := range strings.Split(names, ",") {
+				if name != "" {
+					attrs = append(attrs, name)
+				}
+			}
+		}
+	}
 
 	snapName = ifacestate.RemapSnapFromRequest(snapName)
 	if snapName != "" {
@@ -264,6 +317,8 @@ func getConnections(c *Command, r *http.Request, user *auth.UserState) Response
 		snapName:  snapName,
 		ifaceName: ifaceName,
 		connected: onlyConnected,
+		origin:    origin,
+		attrs:     attrs,
 	})
 	if err != nil {
 		return InternalError("collecting connection information failed: %v", err)
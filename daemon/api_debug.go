@@ -23,15 +23,21 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"path/filepath"
 	"sort"
+	"strings"
 	"time"
 
 	"github.com/snapcore/snapd/asserts"
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/interfaces/mount"
+	"github.com/snapcore/snapd/osutil"
 	"github.com/snapcore/snapd/overlord/assertstate"
 	"github.com/snapcore/snapd/overlord/auth"
 	"github.com/snapcore/snapd/overlord/devicestate"
 	"github.com/snapcore/snapd/overlord/snapstate"
 	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/snap"
 	"github.com/snapcore/snapd/timings"
 )
 
@@ -236,6 +242,231 @@ func getChangeTimings(st *state.State, changeID, ensureTag, startupTag string, a
 	return SyncResponse(responseData, nil)
 }
 
+func getSecurityProfiles(c *Command, st *state.State, snapName string) Response {
+	if snapName == "" {
+		return BadRequest("missing snap name")
+	}
+	profiles, err := c.d.overlord.InterfaceManager().SecurityProfiles(snapName)
+	if err == state.ErrNoState {
+		return SnapNotFound(snapName, err)
+	}
+	if err != nil {
+		return InternalError("cannot get security profiles of snap %q: %v", snapName, err)
+	}
+	return SyncResponse(profiles, nil)
+}
+
+type mountEntryJSON struct {
+	Entry      string `json:"entry"`
+	Origin     string `json:"origin"`
+	Applied    bool   `json:"applied,omitempty"`
+	AppliedFor []int  `json:"applied-for,omitempty"`
+}
+
+type mountsJSON struct {
+	Mounts     []mountEntryJSON `json:"mounts"`
+	UserMounts []mountEntryJSON `json:"user-mounts"`
+}
+
+func mountEntriesJSON(entries []*mount.AuditEntry) []mountEntryJSON {
+	res := make([]mountEntryJSON, 0, len(entries))
+	for _, e := range entries {
+		res = append(res, mountEntryJSON{
+			Entry:      e.Entry.String(),
+			Origin:     e.Origin,
+			Applied:    e.Applied,
+			AppliedFor: e.AppliedFor,
+		})
+	}
+	return res
+}
+
+func getMounts(c *Command, st *state.State, snapName string) Response {
+	if snapName == "" {
+		return BadRequest("missing snap name")
+	}
+	audit, err := c.d.overlord.InterfaceManager().MountAudit(snapName)
+	if err == state.ErrNoState {
+		return SnapNotFound(snapName, err)
+	}
+	if err != nil {
+		return InternalError("cannot get mount entries of snap %q: %v", snapName, err)
+	}
+	return SyncResponse(&mountsJSON{
+		Mounts:     mountEntriesJSON(audit.Mounts),
+		UserMounts: mountEntriesJSON(audit.UserMounts),
+	}, nil)
+}
+
+type seedingSnapTimings struct {
+	Snap string `json:"snap"`
+	// Duration is the time spent in all the tasks seeding the snap
+	Duration time.Duration `json:"duration"`
+	// ProfilesDuration is the part of Duration spent generating
+	// security profiles
+	ProfilesDuration time.Duration `json:"profiles-duration,omitempty"`
+}
+
+type seedingInfo struct {
+	Seeded         bool       `json:"seeded"`
+	ChangeID       string     `json:"change-id,omitempty"`
+	SeedStartTime  *time.Time `json:"seed-start-time,omitempty"`
+	SeedCompletion *time.Time `json:"seed-completion-time,omitempty"`
+	// LoadTime is the time spent populating the state from the seed
+	LoadTime         time.Duration         `json:"load-time,omitempty"`
+	ProfilesDuration time.Duration         `json:"profiles-duration,omitempty"`
+	SnapTimings      []*seedingSnapTimings `json:"snap-timings,omitempty"`
+	SeedError        string                `json:"seed-error,omitempty"`
+	// ClassicPackages is the manifest of the packages of the classic
+	// image, as recorded in its seed
+	ClassicPackages []*seedingClassicPackage `json:"classic-packages,omitempty"`
+}
+
+type seedingClassicPackage struct {
+	Name         string `json:"name"`
+	Version      string `json:"version"`
+	Architecture string `json:"architecture,omitempty"`
+}
+
+// seedClassicPackages returns the classic packages manifest recorded
+// in the seed, if any.
+func seedClassicPackages() ([]*seedingClassicPackage, error) {
+	seedYamlFile := filepath.Join(dirs.SnapSeedDir, "seed.yaml")
+	if !osutil.FileExists(seedYamlFile) {
+		return nil, nil
+	}
+	seed, err := snap.ReadSeedYaml(seedYamlFile)
+	if err != nil {
+		return nil, err
+	}
+	var pkgs []*seedingClassicPackage
+	for _, pkg := range seed.ClassicPackages {
+		pkgs = append(pkgs, &seedingClassicPackage{
+			Name:         pkg.Name,
+			Version:      pkg.Version,
+			Architecture: pkg.Architecture,
+		})
+	}
+	return pkgs, nil
+}
+
+// seedChange returns the most recent seeding change, if any.
+func seedChange(st *state.State) *state.Change {
+	var seedChg *state.Change
+	for _, chg := range st.Changes() {
+		if chg.Kind() != "seed" {
+			continue
+		}
+		if seedChg == nil || chg.SpawnTime().After(seedChg.SpawnTime()) {
+			seedChg = chg
+		}
+	}
+	return seedChg
+}
+
+// firstTaskError returns the first error logged by a failed task of the
+// given change.
+func firstTaskError(chg *state.Change) string {
+	for _, t := range chg.Tasks() {
+		if t.Status() != state.ErrorStatus {
+			continue
+		}
+		for _, msg := range t.Log() {
+			// log entries are "<time> <kind> <message>"
+			l := strings.SplitN(msg, " ", 3)
+			if len(l) == 3 && l[1] == state.LogError {
+				return fmt.Sprintf("%s (%s)", l[2], t.Summary())
+			}
+		}
+	}
+	return ""
+}
+
+func sumTopLevel(tms []*timings.TimingJSON) time.Duration {
+	var dur time.Duration
+	for _, tm := range tms {
+		if tm.Level == 0 {
+			dur += tm.Duration
+		}
+	}
+	return dur
+}
+
+func getSeedingInfo(st *state.State) Response {
+	var info seedingInfo
+	if err := st.Get("seeded", &info.Seeded); err != nil && err != state.ErrNoState {
+		return InternalError("cannot get seeded status: %v", err)
+	}
+	var seedTime time.Time
+	if err := st.Get("seed-time", &seedTime); err != nil && err != state.ErrNoState {
+		return InternalError("cannot get seed time: %v", err)
+	}
+	if !seedTime.IsZero() {
+		info.SeedCompletion = &seedTime
+	}
+	pkgs, err := seedClassicPackages()
+	if err != nil {
+		return InternalError("cannot get classic packages of the seed: %v", err)
+	}
+	info.ClassicPackages = pkgs
+
+	chg := seedChange(st)
+	if chg == nil {
+		return SyncResponse(&info, nil)
+	}
+	info.ChangeID = chg.ID()
+	spawnTime := chg.SpawnTime()
+	info.SeedStartTime = &spawnTime
+	info.SeedError = firstTaskError(chg)
+
+	stateTimings, err := timings.Get(st, -1, func(tags map[string]string) bool {
+		return tags["change-id"] == chg.ID()
+	})
+	if err != nil {
+		return InternalError("cannot get timings of seeding: %v", err)
+	}
+
+	bySnap := make(map[string]*seedingSnapTimings)
+	var snapNames []string
+	for _, tm := range stateTimings {
+		if tm.Tags["ensure"] == "seed" {
+			info.LoadTime += sumTopLevel(tm.NestedTimings)
+			continue
+		}
+		if tm.Tags["task-status"] != state.DoingStatus.String() {
+			continue
+		}
+		t := st.Task(tm.Tags["task-id"])
+		if t == nil {
+			continue
+		}
+		snapsup, err := snapstate.TaskSnapSetup(t)
+		if err != nil {
+			// not a task about a snap
+			continue
+		}
+		name := snapsup.InstanceName()
+		snapTm := bySnap[name]
+		if snapTm == nil {
+			snapTm = &seedingSnapTimings{Snap: name}
+			bySnap[name] = snapTm
+			snapNames = append(snapNames, name)
+		}
+		dur := sumTopLevel(tm.NestedTimings)
+		snapTm.Duration += dur
+		if tm.Tags["task-kind"] == "setup-profiles" {
+			snapTm.ProfilesDuration += dur
+			info.ProfilesDuration += dur
+		}
+	}
+	sort.Strings(snapNames)
+	for _, name := range snapNames {
+		info.SnapTimings = append(info.SnapTimings, bySnap[name])
+	}
+
+	return SyncResponse(&info, nil)
+}
+
 func getDebug(c *Command, r *http.Request, user *auth.UserState) Response {
 	query := r.URL.Query()
 	aspect := query.Get("aspect")
@@ -255,6 +486,12 @@ func getDebug(c *Command, r *http.Request, user *auth.UserState) Response {
 		return SyncResponse(map[string]interface{}{
 			"model": string(asserts.Encode(model)),
 		}, nil)
+	case "seeding":
+		return getSeedingInfo(st)
+	case "security-profiles":
+		return getSecurityProfiles(c, st, query.Get("snap"))
+	case "mounts":
+		return getMounts(c, st, query.Get("snap"))
 	case "change-timings":
 		chgID := query.Get("change-id")
 		ensureTag := query.Get("ensure")
@@ -22,11 +22,21 @@ package daemon
 import (
 	"bytes"
 	"encoding/json"
+	"io/ioutil"
 	"net/http"
+	"os"
+	"path/filepath"
+	"time"
 
 	"gopkg.in/check.v1"
 
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/interfaces"
+	"github.com/snapcore/snapd/interfaces/ifacetest"
+	"github.com/snapcore/snapd/interfaces/mount"
+	"github.com/snapcore/snapd/overlord/snapstate"
 	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/snap"
 	"github.com/snapcore/snapd/testutil"
 	"github.com/snapcore/snapd/timings"
 )
@@ -157,6 +167,204 @@ func (s *postDebugSuite) TestGetDebugBaseDeclaration(c *check.C) {
 		testutil.Contains, "type: base-declaration")
 }
 
+func (s *postDebugSuite) TestGetDebugSecurityProfiles(c *check.C) {
+	d := s.daemon(c)
+	s.mkInstalledInState(c, d, "foo", "bar", "v1", snap.R(10), true, "")
+	err := d.overlord.InterfaceManager().Repository().AddBackend(&ifacetest.TestSecurityBackend{
+		BackendName: "apparmor",
+		ProfilesDryRunCallback: func(snapInfo *snap.Info, opts interfaces.ConfinementOptions, repo *interfaces.Repository) (map[string]string, error) {
+			return map[string]string{"/profiles/snap.foo.foo": "profile of " + snapInfo.InstanceName()}, nil
+		},
+	})
+	c.Assert(err, check.IsNil)
+
+	req, err := http.NewRequest("GET", "/v2/debug?aspect=security-profiles&snap=foo", nil)
+	c.Assert(err, check.IsNil)
+	rsp := getDebug(debugCmd, req, nil).(*resp)
+	c.Check(rsp.Type, check.Equals, ResponseTypeSync)
+	c.Check(rsp.Result, check.DeepEquals, map[string]map[string]string{
+		"apparmor": {"/profiles/snap.foo.foo": "profile of foo"},
+	})
+}
+
+func (s *postDebugSuite) TestGetDebugSecurityProfilesErrors(c *check.C) {
+	s.daemon(c)
+
+	req, err := http.NewRequest("GET", "/v2/debug?aspect=security-profiles", nil)
+	c.Assert(err, check.IsNil)
+	rsp := getDebug(debugCmd, req, nil).(*resp)
+	c.Check(rsp.Status, check.Equals, 400)
+	c.Check(rsp.Result.(*errorResult).Message, check.Equals, "missing snap name")
+
+	req, err = http.NewRequest("GET", "/v2/debug?aspect=security-profiles&snap=unknown", nil)
+	c.Assert(err, check.IsNil)
+	rsp = getDebug(debugCmd, req, nil).(*resp)
+	c.Check(rsp.Status, check.Equals, 404)
+	c.Check(rsp.Result.(*errorResult).Kind, check.Equals, errorKindSnapNotFound)
+}
+
+func (s *postDebugSuite) TestGetDebugMounts(c *check.C) {
+	d := s.daemon(c)
+	s.mkInstalledInState(c, d, "foo", "bar", "v1", snap.R(10), true, `
+layout:
+  /usr/share/foo:
+    bind: $SNAP/usr/share/foo
+`)
+	err := d.overlord.InterfaceManager().Repository().AddBackend(&mount.Backend{})
+	c.Assert(err, check.IsNil)
+
+	c.Assert(os.MkdirAll(dirs.SnapRunNsDir, 0755), check.IsNil)
+	err = ioutil.WriteFile(filepath.Join(dirs.SnapRunNsDir, "snap.foo.fstab"), []byte("tmpfs /usr/share tmpfs x-snapd.synthetic 0 0\n"), 0644)
+	c.Assert(err, check.IsNil)
+
+	req, err := http.NewRequest("GET", "/v2/debug?aspect=mounts&snap=foo", nil)
+	c.Assert(err, check.IsNil)
+	rsp := getDebug(debugCmd, req, nil).(*resp)
+	c.Check(rsp.Type, check.Equals, ResponseTypeSync)
+	c.Check(rsp.Result, check.DeepEquals, &mountsJSON{
+		Mounts: []mountEntryJSON{{
+			Entry:  "/snap/foo/10/usr/share/foo /usr/share/foo none rbind,rw,x-snapd.origin=layout 0 0",
+			Origin: "layout",
+		}, {
+			Entry:   "tmpfs /usr/share tmpfs x-snapd.synthetic 0 0",
+			Origin:  "synthetic",
+			Applied: true,
+		}},
+		UserMounts: []mountEntryJSON{},
+	})
+}
+
+func (s *postDebugSuite) TestGetDebugMountsErrors(c *check.C) {
+	s.daemon(c)
+
+	req, err := http.NewRequest("GET", "/v2/debug?aspect=mounts", nil)
+	c.Assert(err, check.IsNil)
+	rsp := getDebug(debugCmd, req, nil).(*resp)
+	c.Check(rsp.Status, check.Equals, 400)
+	c.Check(rsp.Result.(*errorResult).Message, check.Equals, "missing snap name")
+
+	req, err = http.NewRequest("GET", "/v2/debug?aspect=mounts&snap=unknown", nil)
+	c.Assert(err, check.IsNil)
+	rsp = getDebug(debugCmd, req, nil).(*resp)
+	c.Check(rsp.Status, check.Equals, 404)
+	c.Check(rsp.Result.(*errorResult).Kind, check.Equals, errorKindSnapNotFound)
+}
+
+func (s *postDebugSuite) TestGetDebugSeeding(c *check.C) {
+	s.daemonWithOverlordMock(c)
+
+	st := s.d.overlord.State()
+	st.Lock()
+	seedTime := time.Date(2019, 11, 29, 10, 0, 0, 0, time.UTC)
+	st.Set("seeded", true)
+	st.Set("seed-time", seedTime)
+
+	chg := st.NewChange("seed", "...")
+	var tasks []*state.Task
+	for _, k := range []struct{ kind, snap string }{
+		{"mount-snap", "core"},
+		{"setup-profiles", "core"},
+		{"mount-snap", "foo"},
+		{"setup-profiles", "foo"},
+		{"mark-seeded", ""},
+	} {
+		t := st.NewTask(k.kind, "...")
+		if k.snap != "" {
+			t.Set("snap-setup", &snapstate.SnapSetup{SideInfo: &snap.SideInfo{RealName: k.snap}})
+		}
+		chg.AddTask(t)
+		tasks = append(tasks, t)
+	}
+	tasks[3].SetStatus(state.ErrorStatus)
+	tasks[3].Errorf("cannot setup profiles")
+
+	taskTiming := func(t *state.Task, durs ...time.Duration) map[string]interface{} {
+		var nested []*timings.TimingJSON
+		for i, d := range durs {
+			// second duration is nested in the first
+			nested = append(nested, &timings.TimingJSON{Level: i, Label: "x", Duration: d})
+		}
+		return map[string]interface{}{
+			"tags": map[string]string{
+				"task-id": t.ID(), "change-id": chg.ID(), "task-kind": t.Kind(), "task-status": "Doing",
+			},
+			"timings": nested,
+		}
+	}
+	st.Set("timings", []map[string]interface{}{
+		{
+			"tags":    map[string]string{"ensure": "seed", "change-id": chg.ID()},
+			"timings": []*timings.TimingJSON{{Label: "state-from-seed", Duration: 3 * time.Second}},
+		},
+		taskTiming(tasks[0], 2*time.Second, time.Second),
+		taskTiming(tasks[1], 500*time.Millisecond),
+		taskTiming(tasks[2], time.Second),
+		taskTiming(tasks[3], 250*time.Millisecond),
+		taskTiming(tasks[4], time.Millisecond),
+		// some other change
+		{
+			"tags":    map[string]string{"task-id": "99", "change-id": "99", "task-status": "Doing"},
+			"timings": []*timings.TimingJSON{{Label: "x", Duration: time.Hour}},
+		},
+	})
+	spawnTime := chg.SpawnTime()
+	st.Unlock()
+
+	req, err := http.NewRequest("GET", "/v2/debug?aspect=seeding", nil)
+	c.Assert(err, check.IsNil)
+	rsp := getDebug(debugCmd, req, nil).(*resp)
+	c.Assert(rsp.Type, check.Equals, ResponseTypeSync)
+	c.Check(rsp.Result, check.DeepEquals, &seedingInfo{
+		Seeded:           true,
+		ChangeID:         chg.ID(),
+		SeedStartTime:    &spawnTime,
+		SeedCompletion:   &seedTime,
+		LoadTime:         3 * time.Second,
+		ProfilesDuration: 750 * time.Millisecond,
+		SnapTimings: []*seedingSnapTimings{
+			{Snap: "core", Duration: 2500 * time.Millisecond, ProfilesDuration: 500 * time.Millisecond},
+			{Snap: "foo", Duration: 1250 * time.Millisecond, ProfilesDuration: 250 * time.Millisecond},
+		},
+		SeedError: "cannot setup profiles (...)",
+	})
+}
+
+func (s *postDebugSuite) TestGetDebugSeedingNotStarted(c *check.C) {
+	s.daemonWithOverlordMock(c)
+
+	req, err := http.NewRequest("GET", "/v2/debug?aspect=seeding", nil)
+	c.Assert(err, check.IsNil)
+	rsp := getDebug(debugCmd, req, nil).(*resp)
+	c.Assert(rsp.Type, check.Equals, ResponseTypeSync)
+	c.Check(rsp.Result, check.DeepEquals, &seedingInfo{})
+}
+
+func (s *postDebugSuite) TestGetDebugSeedingClassicPackages(c *check.C) {
+	s.daemonWithOverlordMock(c)
+
+	c.Assert(os.MkdirAll(dirs.SnapSeedDir, 0755), check.IsNil)
+	c.Assert(ioutil.WriteFile(filepath.Join(dirs.SnapSeedDir, "seed.yaml"), []byte(`
+snaps:
+classic-packages:
+  - name: bash
+    version: 5.0-4ubuntu1
+    architecture: amd64
+  - name: tzdata
+    version: 2019c-3ubuntu1
+`), 0644), check.IsNil)
+
+	req, err := http.NewRequest("GET", "/v2/debug?aspect=seeding", nil)
+	c.Assert(err, check.IsNil)
+	rsp := getDebug(debugCmd, req, nil).(*resp)
+	c.Assert(rsp.Type, check.Equals, ResponseTypeSync)
+	c.Check(rsp.Result, check.DeepEquals, &seedingInfo{
+		ClassicPackages: []*seedingClassicPackage{
+			{Name: "bash", Version: "5.0-4ubuntu1", Architecture: "amd64"},
+			{Name: "tzdata", Version: "2019c-3ubuntu1"},
+		},
+	})
+}
+
 func mockDurationThreshold() func() {
 	oldDurationThreshold := timings.DurationThreshold
 	restore := func() {
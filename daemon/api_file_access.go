@@ -0,0 +1,74 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2019 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package daemon
+
+import (
+	"net/http"
+	"os/user"
+	"path/filepath"
+	"strconv"
+
+	"github.com/snapcore/snapd/overlord/auth"
+	"github.com/snapcore/snapd/overlord/state"
+)
+
+// snapFileAccessCmd reports the access a snap has to a host path, as
+// used by "snap routine file-access" on behalf of the document portal
+// and file managers. The home directory considered is the one of the
+// user making the request.
+var snapFileAccessCmd = &Command{
+	Path:   "/v2/snaps/{name}/file-access",
+	UserOK: true,
+	GET:    getSnapFileAccess,
+}
+
+var userLookupId = user.LookupId
+
+func getSnapFileAccess(c *Command, r *http.Request, user *auth.UserState) Response {
+	vars := muxVars(r)
+	snapName := vars["name"]
+
+	path := r.URL.Query().Get("path")
+	if !filepath.IsAbs(path) {
+		return BadRequest("cannot check file access to %q: path is not absolute", path)
+	}
+
+	_, uid, _, err := ucrednetGet(r.RemoteAddr)
+	if err != nil {
+		return Forbidden("cannot check file access without a peer uid")
+	}
+	usr, err := userLookupId(strconv.FormatUint(uint64(uid), 10))
+	if err != nil {
+		return InternalError("cannot look up user %d: %v", uid, err)
+	}
+
+	st := c.d.overlord.State()
+	st.Lock()
+	defer st.Unlock()
+
+	access, err := c.d.overlord.InterfaceManager().FileAccess(snapName, path, usr.HomeDir)
+	if err == state.ErrNoState {
+		return SnapNotFound(snapName, err)
+	}
+	if err != nil {
+		return InternalError("cannot check file access of snap %q: %v", snapName, err)
+	}
+	return SyncResponse(map[string]string{"access": string(access)}, nil)
+}
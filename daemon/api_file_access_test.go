@@ -0,0 +1,102 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2019 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package daemon
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os/user"
+	"path/filepath"
+
+	"gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/dirs"
+)
+
+func (s *apiSuite) runFileAccess(c *check.C, snapName, path, uid string, statusCode int) interface{} {
+	s.vars = map[string]string{"name": snapName}
+	req, err := http.NewRequest("GET", "/v2/snaps/"+snapName+"/file-access?path="+url.QueryEscape(path), nil)
+	c.Assert(err, check.IsNil)
+	if uid != "" {
+		req.RemoteAddr = "pid=100;uid=" + uid + ";socket=;"
+	}
+	rec := httptest.NewRecorder()
+	snapFileAccessCmd.GET(snapFileAccessCmd, req, nil).ServeHTTP(rec, req)
+	c.Check(rec.Code, check.Equals, statusCode)
+
+	var rspBody map[string]interface{}
+	c.Assert(json.Unmarshal(rec.Body.Bytes(), &rspBody), check.IsNil)
+	return rspBody["result"]
+}
+
+func mockUserLookupId(c *check.C, home string) (restore func()) {
+	old := userLookupId
+	userLookupId = func(uid string) (*user.User, error) {
+		c.Check(uid, check.Equals, "1000")
+		return &user.User{Uid: uid, HomeDir: home}, nil
+	}
+	return func() { userLookupId = old }
+}
+
+func (s *apiSuite) TestSnapFileAccess(c *check.C) {
+	s.daemon(c)
+	s.mockSnap(c, consumerYaml)
+	defer mockUserLookupId(c, "/home/user")()
+
+	for _, t := range []struct {
+		path   string
+		access string
+	}{
+		{filepath.Join(dirs.SnapDataDir, "consumer/common/foo"), "read-write"},
+		{filepath.Join(dirs.SnapDataDir, "consumer/x2/foo"), "read-only"},
+		{"/home/user/snap/consumer/current", "read-write"},
+		{"/home/user/snap/other/current", "hidden"},
+		{"/home/user/Documents", "hidden"},
+	} {
+		result := s.runFileAccess(c, "consumer", t.path, "1000", 200)
+		c.Check(result, check.DeepEquals, map[string]interface{}{"access": t.access}, check.Commentf("%s", t.path))
+	}
+}
+
+func (s *apiSuite) TestSnapFileAccessRelativePath(c *check.C) {
+	s.daemon(c)
+	s.mockSnap(c, consumerYaml)
+
+	result := s.runFileAccess(c, "consumer", "foo/bar", "1000", 400)
+	c.Check(result.(map[string]interface{})["message"], check.Equals, `cannot check file access to "foo/bar": path is not absolute`)
+}
+
+func (s *apiSuite) TestSnapFileAccessNoPeerUid(c *check.C) {
+	s.daemon(c)
+	s.mockSnap(c, consumerYaml)
+
+	result := s.runFileAccess(c, "consumer", "/home/user", "", 403)
+	c.Check(result.(map[string]interface{})["message"], check.Equals, "cannot check file access without a peer uid")
+}
+
+func (s *apiSuite) TestSnapFileAccessSnapNotInstalled(c *check.C) {
+	s.daemon(c)
+	defer mockUserLookupId(c, "/home/user")()
+
+	result := s.runFileAccess(c, "consumer", "/home/user", "1000", 404)
+	c.Check(result.(map[string]interface{})["kind"], check.Equals, "snap-not-found")
+}
@@ -61,6 +61,8 @@ type interfaceAction struct {
 	Action string     `json:"action"`
 	Plugs  []plugJSON `json:"plugs,omitempty"`
 	Slots  []slotJSON `json:"slots,omitempty"`
+	// For makes a connection temporary, it is a duration like "2h"
+	For string `json:"for,omitempty"`
 }
 
 // connectionsJSON aids in marshalling information about a single connection
@@ -24,15 +24,24 @@ import (
 	"net/http"
 
 	"github.com/snapcore/snapd/asserts"
+	"github.com/snapcore/snapd/client"
 	"github.com/snapcore/snapd/overlord/auth"
 	"github.com/snapcore/snapd/overlord/devicestate"
+	"github.com/snapcore/snapd/overlord/state"
 )
 
-var modelCmd = &Command{
-	Path: "/v2/model",
-	POST: postModel,
-	// TODO: provide GET here too once we decided on the details of the API
-}
+var (
+	modelCmd = &Command{
+		Path: "/v2/model",
+		GET:  getModel,
+		POST: postModel,
+	}
+
+	serialModelCmd = &Command{
+		Path: "/v2/model/serial",
+		GET:  getSerial,
+	}
+)
 
 var devicestateRemodel = devicestate.Remodel
 
@@ -69,3 +78,57 @@ func postModel(c *Command, r *http.Request, _ *auth.UserState) Response {
 	return AsyncResponse(nil, &Meta{Change: chg.ID()})
 
 }
+
+// getModel returns the current model assertion.
+func getModel(c *Command, r *http.Request, _ *auth.UserState) Response {
+	st := c.d.overlord.State()
+	st.Lock()
+	defer st.Unlock()
+
+	model, err := c.d.overlord.DeviceManager().Model()
+	if err == state.ErrNoState {
+		return NotFound("no model assertion yet")
+	}
+	if err != nil {
+		return InternalError("cannot get model assertion: %v", err)
+	}
+
+	return AssertResponse([]asserts.Assertion{model}, false)
+}
+
+// getSerial returns the current serial assertion.
+func getSerial(c *Command, r *http.Request, _ *auth.UserState) Response {
+	st := c.d.overlord.State()
+	st.Lock()
+	defer st.Unlock()
+
+	serial, err := c.d.overlord.DeviceManager().Serial()
+	if err == state.ErrNoState {
+		return NotFound("no serial assertion yet")
+	}
+	if err != nil {
+		return InternalError("cannot get serial assertion: %v", err)
+	}
+
+	return AssertResponse([]asserts.Assertion{serial}, false)
+}
+
+// remodelPhases returns the progress of the phases of a remodel change in
+// the form used in the change api data.
+func remodelPhases(chg *state.Change) []*client.RemodelPhase {
+	phases, err := devicestate.RemodelPhases(chg)
+	if err != nil {
+		return nil
+	}
+	result := make([]*client.RemodelPhase, len(phases))
+	for i, phase := range phases {
+		result[i] = &client.RemodelPhase{
+			Name:   phase.Name,
+			Status: phase.Status.String(),
+			Unit:   phase.Unit,
+			Done:   phase.Done,
+			Total:  phase.Total,
+		}
+	}
+	return result
+}
@@ -23,6 +23,7 @@ import (
 	"bytes"
 	"encoding/json"
 	"net/http"
+	"net/http/httptest"
 
 	"gopkg.in/check.v1"
 
@@ -105,3 +106,104 @@ func (s *apiSuite) TestPostRemodel(c *check.C) {
 
 	c.Assert(soon, check.Equals, 1)
 }
+
+func (s *apiSuite) TestGetModel(c *check.C) {
+	d := s.daemonWithOverlordMock(c)
+	hookMgr, err := hookstate.Manager(d.overlord.State(), d.overlord.TaskRunner())
+	c.Assert(err, check.IsNil)
+	deviceMgr, err := devicestate.Manager(d.overlord.State(), hookMgr, d.overlord.TaskRunner(), nil)
+	c.Assert(err, check.IsNil)
+	d.overlord.AddManager(deviceMgr)
+
+	model := s.brands.Model("my-brand", "my-model", modelDefaults)
+	st := d.overlord.State()
+	st.Lock()
+	assertstatetest.AddMany(st, s.storeSigning.StoreAccountKey(""))
+	assertstatetest.AddMany(st, s.brands.AccountsAndKeys("my-brand")...)
+	s.mockModel(c, st, model)
+	st.Unlock()
+
+	req, err := http.NewRequest("GET", "/v2/model", nil)
+	c.Assert(err, check.IsNil)
+	rec := httptest.NewRecorder()
+	rsp := getModel(modelCmd, req, nil)
+	rsp.ServeHTTP(rec, req)
+	c.Assert(rec.Code, check.Equals, 200)
+	c.Check(rec.HeaderMap.Get("Content-Type"), check.Equals, "application/x.ubuntu.assertion")
+	c.Check(rec.HeaderMap.Get("X-Ubuntu-Assertions-Count"), check.Equals, "1")
+
+	dec := asserts.NewDecoder(rec.Body)
+	a, err := dec.Decode()
+	c.Assert(err, check.IsNil)
+	c.Check(a, check.DeepEquals, asserts.Assertion(model))
+}
+
+func (s *apiSuite) TestGetModelNoModel(c *check.C) {
+	d := s.daemonWithOverlordMock(c)
+	hookMgr, err := hookstate.Manager(d.overlord.State(), d.overlord.TaskRunner())
+	c.Assert(err, check.IsNil)
+	deviceMgr, err := devicestate.Manager(d.overlord.State(), hookMgr, d.overlord.TaskRunner(), nil)
+	c.Assert(err, check.IsNil)
+	d.overlord.AddManager(deviceMgr)
+
+	req, err := http.NewRequest("GET", "/v2/model", nil)
+	c.Assert(err, check.IsNil)
+	rsp := getModel(modelCmd, req, nil).(*resp)
+	c.Check(rsp.Type, check.Equals, ResponseTypeError)
+	c.Check(rsp.Status, check.Equals, 404)
+	c.Check(rsp.Result.(*errorResult).Message, check.Equals, "no model assertion yet")
+}
+
+func (s *apiSuite) TestGetSerialNoSerial(c *check.C) {
+	d := s.daemonWithOverlordMock(c)
+	hookMgr, err := hookstate.Manager(d.overlord.State(), d.overlord.TaskRunner())
+	c.Assert(err, check.IsNil)
+	deviceMgr, err := devicestate.Manager(d.overlord.State(), hookMgr, d.overlord.TaskRunner(), nil)
+	c.Assert(err, check.IsNil)
+	d.overlord.AddManager(deviceMgr)
+
+	st := d.overlord.State()
+	st.Lock()
+	assertstatetest.AddMany(st, s.storeSigning.StoreAccountKey(""))
+	assertstatetest.AddMany(st, s.brands.AccountsAndKeys("my-brand")...)
+	s.mockModel(c, st, s.brands.Model("my-brand", "my-model", modelDefaults))
+	st.Unlock()
+
+	req, err := http.NewRequest("GET", "/v2/model/serial", nil)
+	c.Assert(err, check.IsNil)
+	rsp := getSerial(serialModelCmd, req, nil).(*resp)
+	c.Check(rsp.Type, check.Equals, ResponseTypeError)
+	c.Check(rsp.Status, check.Equals, 404)
+	c.Check(rsp.Result.(*errorResult).Message, check.Equals, "no serial assertion yet")
+}
+
+func (s *apiSuite) TestRemodelChangeHasPhases(c *check.C) {
+	d := s.daemonWithOverlordMock(c)
+	st := d.overlord.State()
+	st.Lock()
+	chg := st.NewChange("remodel", "...")
+	t1 := st.NewTask("prepare-remodeling", "...")
+	t1.SetStatus(state.DoneStatus)
+	chg.AddTask(t1)
+	t2 := st.NewTask("set-model", "...")
+	t2.WaitFor(t1)
+	chg.AddTask(t2)
+	st.Unlock()
+	s.vars = map[string]string{"id": chg.ID()}
+
+	req, err := http.NewRequest("GET", "/v2/changes/"+chg.ID(), nil)
+	c.Assert(err, check.IsNil)
+	rsp := getChange(stateChangeCmd, req, nil).(*resp)
+	rec := httptest.NewRecorder()
+	rsp.ServeHTTP(rec, req)
+	c.Assert(rec.Code, check.Equals, 200)
+
+	var body map[string]interface{}
+	err = json.Unmarshal(rec.Body.Bytes(), &body)
+	c.Assert(err, check.IsNil)
+	data := body["result"].(map[string]interface{})["data"].(map[string]interface{})
+	c.Check(data["remodel-phases"], check.DeepEquals, []interface{}{
+		map[string]interface{}{"name": "registration", "status": "Done", "unit": "tasks", "done": 1., "total": 1.},
+		map[string]interface{}{"name": "set-model", "status": "Do", "unit": "tasks", "done": 0., "total": 1.},
+	})
+}
@@ -0,0 +1,223 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2018 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package daemon
+
+import (
+	"sort"
+	"strconv"
+
+	"github.com/snapcore/snapd/overlord/snapstate"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/release"
+	"github.com/snapcore/snapd/snap"
+)
+
+// operationPlan describes the change an operation would create,
+// as returned for a dry-run of the operation.
+type operationPlan struct {
+	Kind          string         `json:"kind"`
+	Summary       string         `json:"summary"`
+	Tasks         []*plannedTask `json:"tasks"`
+	AffectedSnaps []string       `json:"affected-snaps"`
+	// Restart is "system" if the operation is expected to reboot
+	// the system, "daemon" if it is expected to restart snapd
+	Restart string `json:"restart,omitempty"`
+}
+
+type plannedTask struct {
+	ID      string   `json:"id"`
+	Kind    string   `json:"kind"`
+	Summary string   `json:"summary"`
+	WaitFor []string `json:"wait-for,omitempty"`
+}
+
+// simulate runs the given operation against a throwaway copy of the
+// state, so that whatever the operation records in it while building
+// its tasks is discarded. The state, which must be locked, is unlocked
+// meanwhile as store requests done by the operation may need to lock it.
+func simulate(st *state.State, op func(st *state.State) error) error {
+	cpy, err := st.Copy()
+	if err != nil {
+		return err
+	}
+	st.Unlock()
+	defer st.Lock()
+
+	cpy.Lock()
+	defer cpy.Unlock()
+	return op(cpy)
+}
+
+// planSnapAction returns the plan of the change the given single-snap
+// action would create.
+func planSnapAction(st *state.State, inst *snapInstruction, impl snapActionFunc) (*operationPlan, error) {
+	var plan *operationPlan
+	err := simulate(st, func(st *state.State) error {
+		msg, tsets, err := impl(inst, st)
+		if err != nil {
+			return err
+		}
+		plan = planChange(st, inst.Action+"-snap", msg, tsets, inst.Snaps)
+		return nil
+	})
+	return plan, err
+}
+
+// planSnapsOp returns the plan of the change the given multi-snap
+// operation would create.
+func planSnapsOp(st *state.State, inst *snapInstruction, op func(*snapInstruction, *state.State) (*snapInstructionResult, error)) (*operationPlan, error) {
+	var plan *operationPlan
+	err := simulate(st, func(st *state.State) error {
+		res, err := op(inst, st)
+		if err != nil {
+			return err
+		}
+		plan = planChange(st, inst.Action+"-snap", res.Summary, res.Tasksets, res.Affected)
+		return nil
+	})
+	return plan, err
+}
+
+// planChange returns the plan of the change that would be created
+// for the given task sets and discards their tasks.
+func planChange(st *state.State, kind, summary string, tsets []*state.TaskSet, snapNames []string) *operationPlan {
+	var tasks []*state.Task
+	for _, ts := range tsets {
+		tasks = append(tasks, ts.Tasks()...)
+	}
+	defer st.DiscardTasks(tasks)
+
+	// tasks are identified by their position in the plan as
+	// their state ids are discarded
+	ids := make(map[*state.Task]string, len(tasks))
+	for i, t := range tasks {
+		ids[t] = strconv.Itoa(i + 1)
+	}
+
+	affected := make(map[string]bool, len(snapNames))
+	for _, name := range snapNames {
+		affected[name] = true
+	}
+
+	plan := &operationPlan{
+		Kind:    kind,
+		Summary: summary,
+		Tasks:   make([]*plannedTask, 0, len(tasks)),
+	}
+	for _, t := range tasks {
+		pt := &plannedTask{
+			ID:      ids[t],
+			Kind:    t.Kind(),
+			Summary: t.Summary(),
+		}
+		for _, wt := range t.WaitTasks() {
+			// waiting on tasks of other changes is not part
+			// of the plan
+			if id, ok := ids[wt]; ok {
+				pt.WaitFor = append(pt.WaitFor, id)
+			}
+		}
+		plan.Tasks = append(plan.Tasks, pt)
+	}
+
+	for _, t := range tasks {
+		snapsup := plannedSnapSetup(t, tasks)
+		if snapsup == nil {
+			continue
+		}
+		affected[snapsup.InstanceName()] = true
+		if t.Kind() == "link-snap" {
+			plan.Restart = mergeRestart(plan.Restart, expectedRestart(st, snapsup))
+		}
+	}
+
+	plan.AffectedSnaps = make([]string, 0, len(affected))
+	for name := range affected {
+		plan.AffectedSnaps = append(plan.AffectedSnaps, name)
+	}
+	sort.Strings(plan.AffectedSnaps)
+
+	return plan
+}
+
+// plannedSnapSetup returns the snap setup of the task, which is held
+// by the task itself or by the planned task it refers to, or nil.
+func plannedSnapSetup(t *state.Task, tasks []*state.Task) *snapstate.SnapSetup {
+	var snapsup snapstate.SnapSetup
+	if err := t.Get("snap-setup", &snapsup); err == nil {
+		return &snapsup
+	}
+	// snapstate.TaskSnapSetup cannot be used as it only finds
+	// tasks linked to a change
+	var id string
+	if err := t.Get("snap-setup-task", &id); err != nil {
+		return nil
+	}
+	for _, ts := range tasks {
+		if ts.ID() == id {
+			if err := ts.Get("snap-setup", &snapsup); err == nil {
+				return &snapsup
+			}
+			break
+		}
+	}
+	return nil
+}
+
+// expectedRestart returns the restart that linking the snap of the
+// given setup is expected to trigger, if any.
+func expectedRestart(st *state.State, snapsup *snapstate.SnapSetup) string {
+	snapdInstalled := false
+	var snapst snapstate.SnapState
+	if err := snapstate.Get(st, "snapd", &snapst); err == nil {
+		snapdInstalled = true
+	}
+
+	if release.OnClassic {
+		if snapsup.Type == snap.TypeSnapd || (snapsup.Type == snap.TypeOS && !snapdInstalled) {
+			return "daemon"
+		}
+		return ""
+	}
+
+	switch snapsup.Type {
+	case snap.TypeKernel, snap.TypeOS:
+		return "system"
+	case snap.TypeBase:
+		deviceCtx, err := snapstate.DeviceCtxFromState(st, nil)
+		if err == nil && deviceCtx.Model().Base() == snapsup.InstanceName() {
+			return "system"
+		}
+	case snap.TypeSnapd:
+		return "daemon"
+	}
+	return ""
+}
+
+// mergeRestart returns the more disruptive of the two restarts.
+func mergeRestart(r1, r2 string) string {
+	if r1 == "system" || r2 == "system" {
+		return "system"
+	}
+	if r1 == "daemon" || r2 == "daemon" {
+		return "daemon"
+	}
+	return ""
+}
@@ -0,0 +1,210 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2018 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package daemon
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+
+	"gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/overlord/snapstate"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/release"
+	"github.com/snapcore/snapd/snap"
+)
+
+func mockPlannedSnapTasks(st *state.State, name string, typ snap.Type) *state.TaskSet {
+	prereq := st.NewTask("prerequisites", "Ensure prerequisites for "+name)
+	prereq.Set("snap-setup", &snapstate.SnapSetup{
+		SideInfo: &snap.SideInfo{RealName: name},
+		Type:     typ,
+	})
+	link := st.NewTask("link-snap", "Make snap "+name+" available")
+	link.Set("snap-setup-task", prereq.ID())
+	link.WaitFor(prereq)
+	return state.NewTaskSet(prereq, link)
+}
+
+func (s *apiSuite) TestPostSnapDryRun(c *check.C) {
+	d := s.daemonWithOverlordMock(c)
+	restore := release.MockOnClassic(false)
+	defer restore()
+
+	ensureStateSoon = func(st *state.State) {
+		c.Fatalf("unexpected ensure")
+	}
+
+	s.vars = map[string]string{"name": "pc-kernel"}
+
+	snapInstructionDispTable["refresh"] = func(inst *snapInstruction, st *state.State) (string, []*state.TaskSet, error) {
+		// side effects of building the tasks are discarded
+		st.Set("terms-accepted", map[string]string{"pc-kernel-id": "1"})
+		return `Refresh "pc-kernel" snap`, []*state.TaskSet{mockPlannedSnapTasks(st, "pc-kernel", snap.TypeKernel)}, nil
+	}
+	defer func() {
+		snapInstructionDispTable["refresh"] = snapUpdate
+	}()
+
+	buf := bytes.NewBufferString(`{"action": "refresh", "dry-run": true}`)
+	req, err := http.NewRequest("POST", "/v2/snaps/pc-kernel", buf)
+	c.Assert(err, check.IsNil)
+
+	rsp := postSnap(snapCmd, req, nil).(*resp)
+	c.Assert(rsp.Type, check.Equals, ResponseTypeSync)
+	c.Check(rsp.Result, check.DeepEquals, &operationPlan{
+		Kind:    "refresh-snap",
+		Summary: `Refresh "pc-kernel" snap`,
+		Tasks: []*plannedTask{
+			{ID: "1", Kind: "prerequisites", Summary: "Ensure prerequisites for pc-kernel"},
+			{ID: "2", Kind: "link-snap", Summary: "Make snap pc-kernel available", WaitFor: []string{"1"}},
+		},
+		AffectedSnaps: []string{"pc-kernel"},
+		Restart:       "system",
+	})
+
+	// no change was created and the state was left untouched
+	st := d.overlord.State()
+	st.Lock()
+	defer st.Unlock()
+	c.Check(st.Changes(), check.HasLen, 0)
+	c.Check(st.TaskCount(), check.Equals, 0)
+	var terms map[string]string
+	c.Check(st.Get("terms-accepted", &terms), check.Equals, state.ErrNoState)
+}
+
+func (s *apiSuite) TestPostSnapDryRunNoDeclarationsRefresh(c *check.C) {
+	s.daemonWithOverlordMock(c)
+
+	assertstateRefreshSnapDeclarations = func(*state.State, int) error {
+		c.Fatalf("unexpected refresh of the snap declarations")
+		return nil
+	}
+	snapstateUpdate = func(st *state.State, name string, opts *snapstate.RevisionOptions, userID int, flags snapstate.Flags) (*state.TaskSet, error) {
+		return mockPlannedSnapTasks(st, name, snap.TypeApp), nil
+	}
+
+	s.vars = map[string]string{"name": "foo"}
+	buf := bytes.NewBufferString(`{"action": "refresh", "dry-run": true}`)
+	req, err := http.NewRequest("POST", "/v2/snaps/foo", buf)
+	c.Assert(err, check.IsNil)
+
+	rsp := postSnap(snapCmd, req, nil).(*resp)
+	c.Assert(rsp.Type, check.Equals, ResponseTypeSync)
+	c.Check(rsp.Result.(*operationPlan).Tasks, check.HasLen, 2)
+
+	buf = bytes.NewBufferString(`{"action": "refresh", "snaps": ["foo"], "dry-run": true}`)
+	req, err = http.NewRequest("POST", "/v2/snaps", buf)
+	c.Assert(err, check.IsNil)
+	req.Header.Set("Content-Type", "application/json")
+
+	snapstateUpdateMany = func(_ context.Context, st *state.State, names []string, userID int, flags *snapstate.Flags) ([]string, []*state.TaskSet, error) {
+		return names, []*state.TaskSet{mockPlannedSnapTasks(st, "foo", snap.TypeApp)}, nil
+	}
+	rsp = postSnaps(snapsCmd, req, nil).(*resp)
+	c.Assert(rsp.Type, check.Equals, ResponseTypeSync)
+	c.Check(rsp.Result.(*operationPlan).Tasks, check.HasLen, 2)
+}
+
+func (s *apiSuite) TestPostSnapDryRunUnsupportedAction(c *check.C) {
+	s.daemonWithOverlordMock(c)
+	s.vars = map[string]string{"name": "foo"}
+
+	buf := bytes.NewBufferString(`{"action": "enable", "dry-run": true}`)
+	req, err := http.NewRequest("POST", "/v2/snaps/foo", buf)
+	c.Assert(err, check.IsNil)
+
+	rsp := postSnap(snapCmd, req, nil).(*resp)
+	c.Check(rsp.Status, check.Equals, 400)
+	c.Check(rsp.Result.(*errorResult).Message, check.Equals, `cannot use dry-run with action "enable"`)
+}
+
+func (s *apiSuite) TestSnapsOpDryRun(c *check.C) {
+	d := s.daemonWithOverlordMock(c)
+	restore := release.MockOnClassic(true)
+	defer restore()
+
+	snapstateRemoveMany = func(st *state.State, names []string) ([]string, []*state.TaskSet, error) {
+		c.Check(names, check.DeepEquals, []string{"foo", "bar"})
+		var tsets []*state.TaskSet
+		for _, name := range names {
+			// side effects of building the tasks are discarded
+			st.Set("last-snapshot-set-id", 1)
+			t := st.NewTask("unlink-snap", "Make snap "+name+" unavailable")
+			t.Set("snap-setup", &snapstate.SnapSetup{
+				SideInfo: &snap.SideInfo{RealName: name},
+			})
+			tsets = append(tsets, state.NewTaskSet(t))
+		}
+		return names, tsets, nil
+	}
+
+	buf := bytes.NewBufferString(`{"action": "remove", "snaps": ["foo", "bar"], "dry-run": true}`)
+	req, err := http.NewRequest("POST", "/v2/snaps", buf)
+	c.Assert(err, check.IsNil)
+	req.Header.Set("Content-Type", "application/json")
+
+	rsp := postSnaps(snapsCmd, req, nil).(*resp)
+	c.Assert(rsp.Type, check.Equals, ResponseTypeSync)
+	c.Check(rsp.Result, check.DeepEquals, &operationPlan{
+		Kind:    "remove-snap",
+		Summary: `Remove snaps "foo", "bar"`,
+		Tasks: []*plannedTask{
+			{ID: "1", Kind: "unlink-snap", Summary: "Make snap foo unavailable"},
+			{ID: "2", Kind: "unlink-snap", Summary: "Make snap bar unavailable"},
+		},
+		AffectedSnaps: []string{"bar", "foo"},
+	})
+
+	st := d.overlord.State()
+	st.Lock()
+	defer st.Unlock()
+	c.Check(st.Changes(), check.HasLen, 0)
+	c.Check(st.TaskCount(), check.Equals, 0)
+	var setID int
+	c.Check(st.Get("last-snapshot-set-id", &setID), check.Equals, state.ErrNoState)
+}
+
+func (s *apiSuite) TestPlanChangeRestarts(c *check.C) {
+	d := s.daemonWithOverlordMock(c)
+	st := d.overlord.State()
+	st.Lock()
+	defer st.Unlock()
+
+	for _, t := range []struct {
+		classic bool
+		typ     snap.Type
+		restart string
+	}{
+		{false, snap.TypeKernel, "system"},
+		{false, snap.TypeOS, "system"},
+		{false, snap.TypeSnapd, "daemon"},
+		{false, snap.TypeApp, ""},
+		{true, snap.TypeOS, "daemon"},
+		{true, snap.TypeSnapd, "daemon"},
+		{true, snap.TypeKernel, ""},
+	} {
+		restore := release.MockOnClassic(t.classic)
+		plan := planChange(st, "install-snap", "...", []*state.TaskSet{mockPlannedSnapTasks(st, "foo", t.typ)}, []string{"foo"})
+		restore()
+		c.Check(plan.Restart, check.Equals, t.restart, check.Commentf("%v %s", t.classic, t.typ))
+	}
+}
@@ -80,6 +80,29 @@ func (s *snapshotSuite) TestSnapshotMany(c *check.C) {
 	c.Check(res.Affected, check.DeepEquals, inst.Snaps)
 }
 
+func (s *snapshotSuite) TestSnapshotManyIncremental(c *check.C) {
+	defer daemon.MockSnapshotSave(func(*state.State, []string, []string) (uint64, []string, *state.TaskSet, error) {
+		c.Fatalf("unexpected call to the full snapshot save")
+		return 0, nil, nil, nil
+	})()
+	defer daemon.MockSnapshotSaveIncremental(func(s *state.State, parent uint64, snaps, users []string) (uint64, []string, *state.TaskSet, error) {
+		c.Check(parent, check.Equals, uint64(12))
+		c.Check(snaps, check.HasLen, 0)
+		t := s.NewTask("fake-snapshot-2", "Snapshot two")
+		return 13, []string{"foo", "bar"}, state.NewTaskSet(t), nil
+	})()
+
+	inst := daemon.MustUnmarshalSnapInstruction(c, `{"action": "snapshot", "parent": 12}`)
+	st := s.o.State()
+	st.Lock()
+	res, err := daemon.SnapshotMany(inst, st)
+	st.Unlock()
+	c.Assert(err, check.IsNil)
+	c.Check(res.Summary, check.Equals, `Snapshot the snaps of snapshot set #12`)
+	c.Check(res.Affected, check.DeepEquals, []string{"foo", "bar"})
+	c.Check(res.Result, check.DeepEquals, map[string]interface{}{"set-id": uint64(13)})
+}
+
 func (s *snapshotSuite) TestListSnapshots(c *check.C) {
 	snapshots := []client.SnapshotSet{{ID: 1}, {ID: 42}}
 
@@ -0,0 +1,80 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2019 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package daemon
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/snapcore/snapd/overlord/auth"
+	"github.com/snapcore/snapd/overlord/snapstate"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/store"
+)
+
+var termsCmd = &Command{
+	Path:     "/v2/terms",
+	PolkitOK: "io.snapcraft.snapd.manage",
+	POST:     postTerms,
+}
+
+type termsAction struct {
+	Action string   `json:"action"`
+	Snaps  []string `json:"snaps,omitempty"`
+}
+
+// postTerms records the acceptance of the license agreement of the
+// given snaps, so that they can be installed later on without
+// --accept-terms.
+func postTerms(c *Command, r *http.Request, user *auth.UserState) Response {
+	var action termsAction
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&action); err != nil {
+		return BadRequest("cannot decode request body into terms action: %v", err)
+	}
+	if action.Action != "accept" {
+		return BadRequest("invalid terms action %q", action.Action)
+	}
+	if len(action.Snaps) == 0 {
+		return BadRequest("terms action requires at least one snap name")
+	}
+
+	// the store is queried without holding the state lock
+	infos := make([]*snap.Info, 0, len(action.Snaps))
+	for _, name := range action.Snaps {
+		info, err := getStore(c).SnapInfo(context.TODO(), store.SnapSpec{Name: name}, user)
+		if err != nil {
+			return SnapNotFound(name, err)
+		}
+		infos = append(infos, info)
+	}
+
+	st := c.d.overlord.State()
+	st.Lock()
+	defer st.Unlock()
+
+	for _, info := range infos {
+		if err := snapstate.AcceptTerms(st, info); err != nil {
+			return InternalError("cannot record the acceptance of the terms of %q: %v", info.InstanceName(), err)
+		}
+	}
+	return SyncResponse(nil, nil)
+}
@@ -0,0 +1,75 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2019 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package daemon
+
+import (
+	"net/http"
+	"strings"
+
+	"gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/overlord/snapstate"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/store"
+)
+
+func (s *apiSuite) TestPostTermsAccept(c *check.C) {
+	d := s.daemon(c)
+	info := &snap.Info{
+		SideInfo:         snap.SideInfo{RealName: "foo", SnapID: "foo-id"},
+		LicenseAgreement: "explicit",
+		LicenseVersion:   "2",
+	}
+	s.rsnaps = []*snap.Info{info}
+
+	req, err := http.NewRequest("POST", "/v2/terms", strings.NewReader(`{"action": "accept", "snaps": ["foo"]}`))
+	c.Assert(err, check.IsNil)
+	rsp := postTerms(termsCmd, req, nil).(*resp)
+	c.Assert(rsp.Status, check.Equals, 200)
+
+	st := d.overlord.State()
+	st.Lock()
+	defer st.Unlock()
+	accepted, err := snapstate.TermsAccepted(st, info)
+	c.Assert(err, check.IsNil)
+	c.Check(accepted, check.Equals, true)
+}
+
+func (s *apiSuite) TestPostTermsErrors(c *check.C) {
+	s.daemon(c)
+
+	for _, t := range []struct {
+		body   string
+		status int
+		errmsg string
+	}{
+		{`}`, 400, `cannot decode request body into terms action: .*`},
+		{`{"action": "reject", "snaps": ["foo"]}`, 400, `invalid terms action "reject"`},
+		{`{"action": "accept"}`, 400, `terms action requires at least one snap name`},
+		{`{"action": "accept", "snaps": ["foo"]}`, 404, `snap not found`},
+	} {
+		s.err = store.ErrSnapNotFound
+		req, err := http.NewRequest("POST", "/v2/terms", strings.NewReader(t.body))
+		c.Assert(err, check.IsNil)
+		rsp := postTerms(termsCmd, req, nil).(*resp)
+		c.Check(rsp.Status, check.Equals, t.status, check.Commentf(t.body))
+		c.Check(rsp.Result.(*errorResult).Message, check.Matches, t.errmsg, check.Commentf(t.body))
+	}
+}
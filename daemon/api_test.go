@@ -115,6 +115,7 @@ type apiBaseSuite struct {
 	connectivityResult     map[string]bool
 	loginUserStoreMacaroon string
 	loginUserDischarge     string
+	loginUserSecurityKey   string
 	userInfoResult         *store.User
 	userInfoExpectedEmail  string
 
@@ -195,6 +196,13 @@ func (s *apiBaseSuite) LoginUser(username, password, otp string) (string, string
 	return s.loginUserStoreMacaroon, s.loginUserDischarge, s.err
 }
 
+func (s *apiBaseSuite) LoginUserWithSecurityKey(username, password, response string) (string, string, error) {
+	s.pokeStateLock()
+
+	s.loginUserSecurityKey = response
+	return s.loginUserStoreMacaroon, s.loginUserDischarge, s.err
+}
+
 func (s *apiBaseSuite) UserInfo(email string) (userinfo *store.User, err error) {
 	s.pokeStateLock()
 
@@ -286,6 +294,7 @@ func (s *apiBaseSuite) SetUpTest(c *check.C) {
 
 	s.buyOptions = nil
 	s.buyResult = nil
+	s.loginUserSecurityKey = ""
 
 	s.storeSigning = assertstest.NewStoreStack("can0nical", nil)
 	s.trustedRestorer = sysdb.InjectTrusted(s.storeSigning.Trusted)
@@ -294,6 +303,9 @@ func (s *apiBaseSuite) SetUpTest(c *check.C) {
 	s.brands.Register("my-brand", brandPrivKey, nil)
 
 	assertstateRefreshSnapDeclarations = nil
+	assertstateFetchSnapBuild = func(*state.State, string, snap.Revision, int) error {
+		return &asserts.NotFoundError{Type: asserts.SnapBuildType}
+	}
 	snapstateInstall = nil
 	snapstateInstallMany = nil
 	snapstateInstallPath = nil
@@ -304,6 +316,8 @@ func (s *apiBaseSuite) SetUpTest(c *check.C) {
 	snapstateTryPath = nil
 	snapstateUpdate = nil
 	snapstateUpdateMany = nil
+	snapstateDownloadMany = nil
+	snapstateApplyPrefetched = nil
 	snapstateSwitch = nil
 
 	devicestateRemodel = nil
@@ -319,6 +333,7 @@ func (s *apiBaseSuite) TearDownTest(c *check.C) {
 	dirs.SetRootDir("")
 
 	assertstateRefreshSnapDeclarations = assertstate.RefreshSnapDeclarations
+	assertstateFetchSnapBuild = assertstate.FetchSnapBuild
 	snapstateInstall = snapstate.Install
 	snapstateInstallMany = snapstate.InstallMany
 	snapstateInstallPath = snapstate.InstallPath
@@ -329,6 +344,8 @@ func (s *apiBaseSuite) TearDownTest(c *check.C) {
 	snapstateTryPath = snapstate.TryPath
 	snapstateUpdate = snapstate.Update
 	snapstateUpdateMany = snapstate.UpdateMany
+	snapstateDownloadMany = snapstate.DownloadMany
+	snapstateApplyPrefetched = snapstate.ApplyPrefetched
 	snapstateSwitch = snapstate.Switch
 }
 
@@ -556,6 +573,11 @@ var _ = check.Suite(&apiSuite{})
 func (s *apiSuite) TestUsersOnlyRoot(c *check.C) {
 	for _, cmd := range api {
 		if strings.Contains(cmd.Path, "user") {
+			if cmd.UserScopeOK {
+				// scoped to the peer uid by design, see api_user_conf.go
+				c.Check(cmd.Path, check.Equals, "/v2/snaps/{name}/user-conf")
+				continue
+			}
 			c.Check(cmd.RootOnly, check.Equals, true, check.Commentf(cmd.Path))
 		}
 	}
@@ -675,6 +697,8 @@ UnitFileState=enabled
 	snapst.Channel = "beta"
 	snapst.IgnoreValidation = true
 	snapst.CohortKey = "some-long-cohort-key"
+	// stale, the disabled services are reported as per systemd
+	snapst.LastActiveDisabledServices = []string{"svc1"}
 	st.Lock()
 	snapstate.Set(st, "foo", &snapst)
 	st.Unlock()
@@ -786,12 +810,13 @@ UnitFileState=enabled
 					},
 				},
 			},
-			Broken:      "",
-			Contact:     "",
-			License:     "GPL-3.0",
-			CommonIDs:   []string{"org.foo.cmd"},
-			Screenshots: []snap.ScreenshotInfo{{Note: snap.ScreenshotsDeprecationNotice}},
-			CohortKey:   "some-long-cohort-key",
+			Broken:           "",
+			Contact:          "",
+			License:          "GPL-3.0",
+			CommonIDs:        []string{"org.foo.cmd"},
+			Screenshots:      []snap.ScreenshotInfo{{Note: snap.ScreenshotsDeprecationNotice}},
+			CohortKey:        "some-long-cohort-key",
+			DisabledServices: []string{"svc2", "svc4"},
 		},
 		Meta: meta,
 	}
@@ -799,6 +824,114 @@ UnitFileState=enabled
 	c.Check(rsp.Result, check.DeepEquals, expected.Result)
 }
 
+func (s *apiSuite) TestSnapInfoWithSnapBuild(c *check.C) {
+	d := s.daemon(c)
+	s.vars = map[string]string{"name": "foo"}
+
+	s.mkInstalledInState(c, d, "foo", "bar", "v1", snap.R(10), true, "")
+
+	st := d.overlord.State()
+	st.Lock()
+	db := assertstate.DB(st)
+	a, err := db.FindMany(asserts.SnapRevisionType, map[string]string{
+		"snap-id": "foo-id",
+	})
+	c.Assert(err, check.IsNil)
+	devAcct, err := db.Find(asserts.AccountType, map[string]string{
+		"account-id": "bar-id",
+	})
+	c.Assert(err, check.IsNil)
+	st.Unlock()
+
+	devPrivKey, _ := assertstest.GenerateKey(752)
+	devAccKey := assertstest.NewAccountKey(s.storeSigning, devAcct.(*asserts.Account), nil, devPrivKey.PublicKey(), "")
+	devSigning := assertstest.NewSigningDB("bar-id", devPrivKey)
+	// the timestamp must be within the validity of the signing key
+	t0 := time.Now().UTC().Add(time.Minute).Truncate(time.Second)
+	snapBuild, err := devSigning.Sign(asserts.SnapBuildType, map[string]interface{}{
+		"snap-sha3-384": a[0].(*asserts.SnapRevision).SnapSHA3_384(),
+		"snap-id":       "foo-id",
+		"snap-size":     "999",
+		"grade":         "stable",
+		"timestamp":     t0.Format(time.RFC3339),
+	}, nil, "")
+	c.Assert(err, check.IsNil)
+
+	fetched := 0
+	assertstateFetchSnapBuild = func(st *state.State, snapID string, revision snap.Revision, userID int) error {
+		fetched++
+		c.Check(snapID, check.Equals, "foo-id")
+		c.Check(revision, check.Equals, snap.R(10))
+		if fetched == 1 {
+			return &asserts.NotFoundError{Type: asserts.SnapBuildType}
+		}
+		assertstatetest.AddMany(st, devAccKey, snapBuild)
+		return nil
+	}
+
+	req, err := http.NewRequest("GET", "/v2/snaps/foo", nil)
+	c.Assert(err, check.IsNil)
+	rsp := getSnapInfo(snapCmd, req, nil).(*resp)
+	c.Assert(rsp.Status, check.Equals, 200)
+	c.Check(rsp.Result.(*client.Snap).Build, check.IsNil)
+	c.Check(fetched, check.Equals, 1)
+
+	// the store is not asked again for the same revision
+	rsp = getSnapInfo(snapCmd, req, nil).(*resp)
+	c.Assert(rsp.Status, check.Equals, 200)
+	c.Check(rsp.Result.(*client.Snap).Build, check.IsNil)
+	c.Check(fetched, check.Equals, 1)
+
+	// unless it is forgotten, as when snapd restarts
+	st.Lock()
+	st.Cache(snapBuildFetchedKey{snapID: "foo-id", revision: snap.R(10)}, nil)
+	st.Unlock()
+	rsp = getSnapInfo(snapCmd, req, nil).(*resp)
+	c.Assert(rsp.Status, check.Equals, 200)
+	c.Check(rsp.Result.(*client.Snap).Build, check.DeepEquals, &client.SnapBuild{
+		Timestamp: t0,
+		Grade:     "stable",
+	})
+	c.Check(fetched, check.Equals, 2)
+
+	// and once known it is not fetched anymore
+	rsp = getSnapInfo(snapCmd, req, nil).(*resp)
+	c.Check(rsp.Result.(*client.Snap).Build, check.NotNil)
+	c.Check(fetched, check.Equals, 2)
+}
+
+func (s *apiSuite) TestSnapInfoWithSnapBuildNotByPublisher(c *check.C) {
+	d := s.daemon(c)
+	s.vars = map[string]string{"name": "foo"}
+
+	s.mkInstalledInState(c, d, "foo", "bar", "v1", snap.R(10), true, "")
+
+	st := d.overlord.State()
+	st.Lock()
+	a, err := assertstate.DB(st).FindMany(asserts.SnapRevisionType, map[string]string{
+		"snap-id": "foo-id",
+	})
+	st.Unlock()
+	c.Assert(err, check.IsNil)
+	snapBuild, err := s.storeSigning.Sign(asserts.SnapBuildType, map[string]interface{}{
+		"snap-sha3-384": a[0].(*asserts.SnapRevision).SnapSHA3_384(),
+		"snap-id":       "foo-id",
+		"snap-size":     "999",
+		"grade":         "stable",
+		"timestamp":     time.Now().UTC().Add(time.Minute).Format(time.RFC3339),
+	}, nil, "")
+	c.Assert(err, check.IsNil)
+	st.Lock()
+	assertstatetest.AddMany(st, snapBuild)
+	st.Unlock()
+
+	req, err := http.NewRequest("GET", "/v2/snaps/foo", nil)
+	c.Assert(err, check.IsNil)
+	rsp := getSnapInfo(snapCmd, req, nil).(*resp)
+	c.Assert(rsp.Status, check.Equals, 200)
+	c.Check(rsp.Result.(*client.Snap).Build, check.IsNil)
+}
+
 func (s *apiSuite) TestSnapInfoWithAuth(c *check.C) {
 	s.daemon(c)
 
@@ -902,6 +1035,12 @@ func (s *apiSuite) TestMapLocalFields(c *check.C) {
 		"foo": {Snap: info, Name: "foo", Command: "foo"},
 		"bar": {Snap: info, Name: "bar", Command: "bar"},
 	}
+	info.Hooks = map[string]*snap.HookInfo{
+		"configure": {Snap: info, Name: "configure"},
+		"connect-plug-foo": {Snap: info, Name: "connect-plug-foo", Plugs: map[string]*snap.PlugInfo{
+			"foo": {Snap: info, Name: "foo", Interface: "foo"},
+		}},
+	}
 	about := aboutSnap{
 		info: info,
 		snapst: &snapstate.SnapState{
@@ -950,6 +1089,10 @@ func (s *apiSuite) TestMapLocalFields(c *check.C) {
 			{Snap: "some-snap_instance", Name: "bar"},
 			{Snap: "some-snap_instance", Name: "foo"},
 		},
+		Hooks: []client.HookInfo{
+			{Name: "configure", Timeout: "5m0s"},
+			{Name: "connect-plug-foo", Plugs: []string{"foo"}, Timeout: "10m0s"},
+		},
 	}
 	c.Check(mapLocal(about), check.DeepEquals, expected)
 }
@@ -1149,6 +1292,56 @@ func (s *apiSuite) TestSysInfoLegacyRefresh(c *check.C) {
 	c.Check(rsp.Result, check.DeepEquals, expected)
 }
 
+func (s *apiSuite) TestSysInfoStoreAuthDegraded(c *check.C) {
+	d := s.daemon(c)
+
+	st := d.overlord.State()
+	st.Lock()
+	st.Set("store-auth-degraded", map[int]string{1: "boom"})
+	st.Unlock()
+
+	rec := httptest.NewRecorder()
+	sysInfoCmd.GET(sysInfoCmd, nil, nil).ServeHTTP(rec, nil)
+	c.Check(rec.Code, check.Equals, 200)
+
+	var rsp resp
+	c.Assert(json.Unmarshal(rec.Body.Bytes(), &rsp), check.IsNil)
+	c.Check(rsp.Result.(map[string]interface{})["store-auth-degraded"], check.Equals, true)
+}
+
+func (s *apiSuite) TestSysInfoRefreshOutcomes(c *check.C) {
+	d := s.daemon(c)
+
+	attempt := time.Date(2019, 6, 10, 12, 30, 15, 0, time.UTC)
+	success := time.Date(2019, 6, 3, 8, 0, 0, 0, time.UTC)
+	st := d.overlord.State()
+	st.Lock()
+	st.Set("refresh-outcomes", map[string]*snapstate.RefreshOutcome{
+		"foo": {LastAttempt: attempt, LastSuccess: &success, LastError: "boom"},
+		"bar": {LastAttempt: attempt, LastSuccess: &attempt},
+	})
+	st.Unlock()
+
+	rec := httptest.NewRecorder()
+	sysInfoCmd.GET(sysInfoCmd, nil, nil).ServeHTTP(rec, nil)
+	c.Check(rec.Code, check.Equals, 200)
+
+	var rsp resp
+	c.Assert(json.Unmarshal(rec.Body.Bytes(), &rsp), check.IsNil)
+	refresh := rsp.Result.(map[string]interface{})["refresh"].(map[string]interface{})
+	c.Check(refresh["snaps"], check.DeepEquals, map[string]interface{}{
+		"foo": map[string]interface{}{
+			"last-attempt": "2019-06-10T12:30:00Z",
+			"last-success": "2019-06-03T08:00:00Z",
+			"last-error":   "boom",
+		},
+		"bar": map[string]interface{}{
+			"last-attempt": "2019-06-10T12:30:00Z",
+			"last-success": "2019-06-10T12:30:00Z",
+		},
+	})
+}
+
 func (s *apiSuite) TestLoginUser(c *check.C) {
 	d := s.daemon(c)
 	state := d.overlord.State()
@@ -1431,6 +1624,45 @@ func (s *apiSuite) TestLoginUserTwoFactorRequiredError(c *check.C) {
 	c.Check(rsp.Result.(*errorResult).Kind, check.Equals, errorKindTwoFactorRequired)
 }
 
+func (s *apiSuite) TestLoginUserSecurityKeyRequiredError(c *check.C) {
+	s.daemon(c)
+
+	s.err = &store.SecurityKeyRequiredError{Challenge: "the-challenge"}
+	buf := bytes.NewBufferString(`{"username": "email@.com", "password": "password"}`)
+	req, err := http.NewRequest("POST", "/v2/login", buf)
+	c.Assert(err, check.IsNil)
+
+	rsp := loginUser(snapCmd, req, nil).(*resp)
+
+	c.Check(rsp.Type, check.Equals, ResponseTypeError)
+	c.Check(rsp.Status, check.Equals, 401)
+	c.Check(rsp.Result.(*errorResult).Kind, check.Equals, errorKindTwoFactorRequired)
+	c.Check(rsp.Result.(*errorResult).Value, check.DeepEquals, map[string]string{
+		"security-key-challenge": "the-challenge",
+	})
+}
+
+func (s *apiSuite) TestLoginUserWithSecurityKey(c *check.C) {
+	d := s.daemon(c)
+	state := d.overlord.State()
+
+	s.loginUserStoreMacaroon = "user-macaroon"
+	s.loginUserDischarge = "the-discharge-macaroon-serialized-data"
+	buf := bytes.NewBufferString(`{"username": "email@.com", "password": "password", "security-key-response": "the-response"}`)
+	req, err := http.NewRequest("POST", "/v2/login", buf)
+	c.Assert(err, check.IsNil)
+
+	rsp := loginUser(loginCmd, req, nil).(*resp)
+	c.Check(rsp.Status, check.Equals, 200)
+	c.Check(s.loginUserSecurityKey, check.Equals, "the-response")
+
+	state.Lock()
+	user, err := auth.User(state, 1)
+	state.Unlock()
+	c.Assert(err, check.IsNil)
+	c.Check(user.StoreDischarges, check.DeepEquals, []string{"the-discharge-macaroon-serialized-data"})
+}
+
 func (s *apiSuite) TestLoginUserTwoFactorFailedError(c *check.C) {
 	s.daemon(c)
 
@@ -2450,6 +2682,78 @@ func (s *apiSuite) TestPostSnap(c *check.C) {
 	c.Check(soon, check.Equals, 1)
 }
 
+func (s *apiSuite) TestPostSnapInstallKeptData(c *check.C) {
+	d := s.daemonWithOverlordMock(c)
+	ensureStateSoon = func(st *state.State) {}
+
+	s.vars = map[string]string{"name": "foo"}
+
+	snapInstructionDispTable["install"] = func(*snapInstruction, *state.State) (string, []*state.TaskSet, error) {
+		return "foooo", nil, nil
+	}
+	defer func() {
+		snapInstructionDispTable["install"] = snapInstall
+	}()
+
+	expiry := time.Now().Add(24 * time.Hour).UTC().Truncate(time.Second)
+	st := d.overlord.State()
+	st.Lock()
+	st.Set("snapshots", map[uint64]interface{}{
+		7: map[string]interface{}{"expiry-time": expiry, "kept-data-of": "foo"},
+	})
+	st.Unlock()
+
+	buf := bytes.NewBufferString(`{"action": "install"}`)
+	req, err := http.NewRequest("POST", "/v2/snaps/foo", buf)
+	c.Assert(err, check.IsNil)
+
+	rsp := postSnap(snapCmd, req, nil).(*resp)
+	c.Assert(rsp.Type, check.Equals, ResponseTypeAsync)
+
+	st.Lock()
+	defer st.Unlock()
+	chg := st.Change(rsp.Change)
+	c.Assert(chg, check.NotNil)
+	var apiData map[string]interface{}
+	c.Assert(chg.Get("api-data", &apiData), check.IsNil)
+	c.Check(apiData, check.DeepEquals, map[string]interface{}{
+		"kept-data": map[string]interface{}{
+			"set-id":      7.,
+			"expiry-time": expiry.Format(time.RFC3339),
+		},
+	})
+}
+
+func (s *apiSuite) TestPostSnapKeepDataForErrors(c *check.C) {
+	s.daemonWithOverlordMock(c)
+	s.vars = map[string]string{"name": "some-snap"}
+
+	for _, t := range []struct {
+		body   string
+		errmsg string
+	}{
+		{`{"action": "install", "keep-data-for": "24h"}`, `keep-data-for can only be specified for remove`},
+		{`{"action": "remove", "keep-data-for": "7d"}`, `invalid keep-data-for duration "7d"`},
+		{`{"action": "remove", "keep-data-for": "-1h"}`, `invalid keep-data-for duration "-1h"`},
+		{`{"action": "refresh", "accept-terms": true}`, `accept-terms can only be specified for install`},
+		{`{"action": "install", "download-only": true}`, `download-only and apply-prefetched can only be specified for refresh`},
+		{`{"action": "refresh", "download-only": true, "apply-prefetched": true}`, `cannot specify both download-only and apply-prefetched`},
+		{`{"action": "refresh", "apply-prefetched": true, "channel": "edge"}`, `cannot change the tracked snap when using download-only or apply-prefetched`},
+		{`{"action": "install", "kill-running": true}`, `ignore-running and kill-running can only be specified for refresh`},
+		{`{"action": "refresh", "ignore-running": true, "kill-running": true}`, `cannot specify both ignore-running and kill-running`},
+		{`{"action": "refresh", "kill-running": true, "download-only": true}`, `ignore-running and kill-running cannot be specified with download-only or apply-prefetched`},
+	} {
+		req, err := http.NewRequest("POST", "/v2/snaps/some-snap", strings.NewReader(t.body))
+		c.Assert(err, check.IsNil)
+
+		rsp := postSnap(snapCmd, req, nil).(*resp)
+
+		c.Check(rsp.Type, check.Equals, ResponseTypeError, check.Commentf(t.body))
+		c.Check(rsp.Status, check.Equals, 400, check.Commentf(t.body))
+		c.Check(rsp.Result.(*errorResult).Message, check.Equals, t.errmsg, check.Commentf(t.body))
+	}
+}
+
 func (s *apiSuite) TestPostSnapVerifySnapInstruction(c *check.C) {
 	s.daemonWithOverlordMock(c)
 
@@ -2548,12 +2852,13 @@ func (s *apiSuite) TestPostSnapsNoWeirdses(c *check.C) {
 	// one could add more actions here ... 🤷
 	for _, action := range []string{"install", "refresh", "remove"} {
 		for weird, v := range map[string]string{
-			"channel":      `"beta"`,
-			"revision":     `"1"`,
-			"devmode":      "true",
-			"jailmode":     "true",
-			"cohort-key":   `"what"`,
-			"leave-cohort": "true",
+			"channel":       `"beta"`,
+			"revision":      `"1"`,
+			"devmode":       "true",
+			"jailmode":      "true",
+			"cohort-key":    `"what"`,
+			"leave-cohort":  "true",
+			"keep-data-for": `"24h"`,
 		} {
 			buf := strings.NewReader(fmt.Sprintf(`{"action": "%s","snaps":["foo","bar"], "%s": %s}`, action, weird, v))
 			req, err := http.NewRequest("POST", "/v2/snaps", buf)
@@ -3593,13 +3898,13 @@ func (s *apiSuite) TestNotInstalledSnapIcon(c *check.C) {
 }
 
 func (s *apiSuite) TestInstallOnNonDevModeDistro(c *check.C) {
-	s.testInstall(c, false, snapstate.Flags{}, snap.R(0))
+	s.testInstall(c, false, snapstate.Flags{CheckTerms: true}, snap.R(0))
 }
 func (s *apiSuite) TestInstallOnDevModeDistro(c *check.C) {
-	s.testInstall(c, true, snapstate.Flags{}, snap.R(0))
+	s.testInstall(c, true, snapstate.Flags{CheckTerms: true}, snap.R(0))
 }
 func (s *apiSuite) TestInstallRevision(c *check.C) {
-	s.testInstall(c, false, snapstate.Flags{}, snap.R(42))
+	s.testInstall(c, false, snapstate.Flags{CheckTerms: true}, snap.R(42))
 }
 
 func (s *apiSuite) testInstall(c *check.C, forcedDevmode bool, flags snapstate.Flags, revision snap.Revision) {
@@ -3832,6 +4137,35 @@ func (s *apiSuite) TestRefreshIgnoreValidation(c *check.C) {
 	c.Check(summary, check.Equals, `Refresh "some-snap" snap`)
 }
 
+func (s *apiSuite) TestRefreshKillRunning(c *check.C) {
+	var calledFlags snapstate.Flags
+
+	snapstateUpdate = func(s *state.State, name string, opts *snapstate.RevisionOptions, userID int, flags snapstate.Flags) (*state.TaskSet, error) {
+		calledFlags = flags
+
+		t := s.NewTask("fake-refresh-snap", "Doing a fake install")
+		return state.NewTaskSet(t), nil
+	}
+	assertstateRefreshSnapDeclarations = func(s *state.State, userID int) error {
+		return nil
+	}
+
+	d := s.daemon(c)
+	inst := &snapInstruction{
+		Action:      "refresh",
+		KillRunning: true,
+		Snaps:       []string{"some-snap"},
+	}
+
+	st := d.overlord.State()
+	st.Lock()
+	defer st.Unlock()
+	_, _, err := inst.dispatch()(inst, st)
+	c.Check(err, check.IsNil)
+
+	c.Check(calledFlags, check.DeepEquals, snapstate.Flags{KillRunning: true})
+}
+
 func (s *apiSuite) TestRefreshCohort(c *check.C) {
 	cohort := ""
 
@@ -4056,6 +4390,89 @@ func (s *apiSuite) TestRefreshMany(c *check.C) {
 	c.Check(refreshSnapDecls, check.Equals, true)
 }
 
+func (s *apiSuite) TestRefreshManyIgnoreRunning(c *check.C) {
+	assertstateRefreshSnapDeclarations = func(s *state.State, userID int) error {
+		return nil
+	}
+
+	var calledFlags *snapstate.Flags
+	snapstateUpdateMany = func(_ context.Context, s *state.State, names []string, userID int, flags *snapstate.Flags) ([]string, []*state.TaskSet, error) {
+		calledFlags = flags
+		t := s.NewTask("fake-refresh-2", "Refreshing two")
+		return names, []*state.TaskSet{state.NewTaskSet(t)}, nil
+	}
+
+	d := s.daemon(c)
+	inst := &snapInstruction{Action: "refresh", IgnoreRunning: true, Snaps: []string{"foo", "bar"}}
+	st := d.overlord.State()
+	st.Lock()
+	_, err := snapUpdateMany(inst, st)
+	st.Unlock()
+	c.Assert(err, check.IsNil)
+	c.Check(calledFlags, check.DeepEquals, &snapstate.Flags{IgnoreRunning: true})
+}
+
+func (s *apiSuite) TestRefreshManyDownloadOnly(c *check.C) {
+	refreshSnapDecls := false
+	assertstateRefreshSnapDeclarations = func(s *state.State, userID int) error {
+		refreshSnapDecls = true
+		return nil
+	}
+
+	snapstateDownloadMany = func(_ context.Context, s *state.State, names []string, userID int) ([]string, []*state.TaskSet, error) {
+		c.Check(names, check.HasLen, 2)
+		t := s.NewTask("fake-download-2", "Downloading two")
+		return names, []*state.TaskSet{state.NewTaskSet(t)}, nil
+	}
+
+	d := s.daemon(c)
+	inst := &snapInstruction{Action: "refresh", DownloadOnly: true, Snaps: []string{"foo", "bar"}}
+	st := d.overlord.State()
+	st.Lock()
+	res, err := snapUpdateMany(inst, st)
+	st.Unlock()
+	c.Assert(err, check.IsNil)
+	c.Check(res.Summary, check.Equals, `Download refreshes of snaps "foo", "bar"`)
+	c.Check(res.Affected, check.DeepEquals, inst.Snaps)
+	c.Check(refreshSnapDecls, check.Equals, true)
+}
+
+func (s *apiSuite) TestRefreshManyApplyPrefetched(c *check.C) {
+	snapstateApplyPrefetched = func(s *state.State, names []string) ([]string, []*state.TaskSet, error) {
+		c.Check(names, check.HasLen, 0)
+		t := s.NewTask("fake-refresh-1", "Refreshing one")
+		return []string{"foo"}, []*state.TaskSet{state.NewTaskSet(t)}, nil
+	}
+
+	d := s.daemon(c)
+	inst := &snapInstruction{Action: "refresh", ApplyPrefetched: true}
+	st := d.overlord.State()
+	st.Lock()
+	res, err := snapUpdateMany(inst, st)
+	st.Unlock()
+	c.Assert(err, check.IsNil)
+	c.Check(res.Summary, check.Equals, `Refresh snap "foo" from prefetched download`)
+	c.Check(res.Affected, check.DeepEquals, []string{"foo"})
+}
+
+func (s *apiSuite) TestRefreshDownloadOnlyNoUpdates(c *check.C) {
+	assertstateRefreshSnapDeclarations = func(s *state.State, userID int) error {
+		return nil
+	}
+	snapstateDownloadMany = func(_ context.Context, s *state.State, names []string, userID int) ([]string, []*state.TaskSet, error) {
+		c.Check(names, check.DeepEquals, []string{"foo"})
+		return nil, nil, nil
+	}
+
+	d := s.daemon(c)
+	inst := &snapInstruction{Action: "refresh", DownloadOnly: true, Snaps: []string{"foo"}}
+	st := d.overlord.State()
+	st.Lock()
+	_, _, err := snapUpdate(inst, st)
+	st.Unlock()
+	c.Assert(err, check.Equals, store.ErrNoUpdateAvailable)
+}
+
 func (s *apiSuite) TestRefreshMany1(c *check.C) {
 	refreshSnapDecls := false
 	assertstateRefreshSnapDeclarations = func(s *state.State, userID int) error {
@@ -4082,8 +4499,9 @@ func (s *apiSuite) TestRefreshMany1(c *check.C) {
 }
 
 func (s *apiSuite) TestInstallMany(c *check.C) {
-	snapstateInstallMany = func(s *state.State, names []string, userID int) ([]string, []*state.TaskSet, error) {
+	snapstateInstallMany = func(s *state.State, names []string, userID int, flags *snapstate.Flags) ([]string, []*state.TaskSet, error) {
 		c.Check(names, check.HasLen, 2)
+		c.Check(flags, check.DeepEquals, &snapstate.Flags{CheckTerms: true})
 		t := s.NewTask("fake-install-2", "Install two")
 		return names, []*state.TaskSet{state.NewTaskSet(t)}, nil
 	}
@@ -4099,8 +4517,51 @@ func (s *apiSuite) TestInstallMany(c *check.C) {
 	c.Check(res.Affected, check.DeepEquals, inst.Snaps)
 }
 
+func (s *apiSuite) TestInstallManyAcceptTerms(c *check.C) {
+	var calledFlags *snapstate.Flags
+	snapstateInstallMany = func(s *state.State, names []string, userID int, flags *snapstate.Flags) ([]string, []*state.TaskSet, error) {
+		calledFlags = flags
+		t := s.NewTask("fake-install-2", "Install two")
+		return names, []*state.TaskSet{state.NewTaskSet(t)}, nil
+	}
+
+	d := s.daemon(c)
+	ensureStateSoon = func(st *state.State) {}
+	buf := strings.NewReader(`{"action": "install", "snaps": ["foo", "bar"], "accept-terms": true}`)
+	req, err := http.NewRequest("POST", "/v2/snaps", buf)
+	c.Assert(err, check.IsNil)
+	req.Header.Set("Content-Type", "application/json")
+
+	rsp := postSnaps(snapsCmd, req, nil).(*resp)
+	c.Assert(rsp.Type, check.Equals, ResponseTypeAsync)
+	c.Check(calledFlags, check.DeepEquals, &snapstate.Flags{CheckTerms: true, AcceptTerms: true})
+
+	st := d.overlord.State()
+	st.Lock()
+	defer st.Unlock()
+	chg := st.Change(rsp.Change)
+	c.Assert(chg, check.NotNil)
+	c.Check(chg.Summary(), check.Equals, `Install snaps "foo", "bar"`)
+}
+
+func (s *apiSuite) TestPostSnapsAcceptTermsOnlyForInstall(c *check.C) {
+	s.daemonWithOverlordMock(c)
+
+	for _, action := range []string{"refresh", "remove"} {
+		buf := strings.NewReader(fmt.Sprintf(`{"action": "%s", "snaps": ["foo", "bar"], "accept-terms": true}`, action))
+		req, err := http.NewRequest("POST", "/v2/snaps", buf)
+		c.Assert(err, check.IsNil)
+		req.Header.Set("Content-Type", "application/json")
+
+		rsp := postSnaps(snapsCmd, req, nil).(*resp)
+		c.Check(rsp.Type, check.Equals, ResponseTypeError)
+		c.Check(rsp.Status, check.Equals, 400)
+		c.Check(rsp.Result.(*errorResult).Message, testutil.Contains, `accept-terms can only be specified for install`)
+	}
+}
+
 func (s *apiSuite) TestInstallManyEmptyName(c *check.C) {
-	snapstateInstallMany = func(_ *state.State, _ []string, _ int) ([]string, []*state.TaskSet, error) {
+	snapstateInstallMany = func(_ *state.State, _ []string, _ int, _ *snapstate.Flags) ([]string, []*state.TaskSet, error) {
 		return nil, nil, errors.New("should not be called")
 	}
 	d := s.daemon(c)
@@ -4297,6 +4758,37 @@ func (s *apiSuite) TestInstallJailMode(c *check.C) {
 	c.Check(calledFlags.JailMode, check.Equals, true)
 }
 
+func (s *apiSuite) TestInstallAcceptTerms(c *check.C) {
+	var calledFlags []snapstate.Flags
+
+	snapstateInstall = func(ctx context.Context, s *state.State, name string, opts *snapstate.RevisionOptions, userID int, flags snapstate.Flags) (*state.TaskSet, error) {
+		calledFlags = append(calledFlags, flags)
+
+		t := s.NewTask("fake-install-snap", "Doing a fake install")
+		return state.NewTaskSet(t), nil
+	}
+
+	d := s.daemon(c)
+	st := d.overlord.State()
+	st.Lock()
+	defer st.Unlock()
+
+	for _, inst := range []*snapInstruction{
+		{Action: "install", AcceptTerms: true, Snaps: []string{"fake"}},
+		// the legacy license agreement is honoured as well
+		{Action: "install", License: &licenseData{Agreed: true}, Snaps: []string{"fake"}},
+	} {
+		_, _, err := inst.dispatch()(inst, st)
+		c.Check(err, check.IsNil)
+	}
+
+	c.Assert(calledFlags, check.HasLen, 2)
+	c.Check(calledFlags[0].AcceptTerms, check.Equals, true)
+	c.Check(calledFlags[1].AcceptTerms, check.Equals, true)
+	// the terms are checked when installing on behalf of a user
+	c.Check(calledFlags[0].CheckTerms, check.Equals, true)
+}
+
 func (s *apiSuite) TestInstallJailModeDevModeOS(c *check.C) {
 	restore := release.MockForcedDevmode(true)
 	defer restore()
@@ -4680,6 +5172,69 @@ func (s *apiSuite) TestConnectPlugSuccess(c *check.C) {
 	}})
 }
 
+func (s *apiSuite) TestConnectPlugForDuration(c *check.C) {
+	d := s.daemon(c)
+
+	s.mockIface(c, &ifacetest.TestInterface{InterfaceName: "test"})
+	s.mockSnap(c, consumerYaml)
+	s.mockSnap(c, producerYaml)
+
+	d.overlord.Loop()
+	defer d.overlord.Stop()
+
+	action := &interfaceAction{
+		Action: "connect",
+		Plugs:  []plugJSON{{Snap: "consumer", Name: "plug"}},
+		Slots:  []slotJSON{{Snap: "producer", Name: "slot"}},
+		For:    "2h",
+	}
+	text, err := json.Marshal(action)
+	c.Assert(err, check.IsNil)
+	req, err := http.NewRequest("POST", "/v2/interfaces", bytes.NewBuffer(text))
+	c.Assert(err, check.IsNil)
+	rsp := changeInterfaces(interfacesCmd, req, nil).(*resp)
+	c.Assert(rsp.Type, check.Equals, ResponseTypeAsync)
+
+	st := d.overlord.State()
+	st.Lock()
+	defer st.Unlock()
+	chg := st.Change(rsp.Change)
+	c.Assert(chg, check.NotNil)
+	var duration time.Duration
+	for _, t := range chg.Tasks() {
+		if t.Kind() == "connect" {
+			c.Assert(t.Get("duration", &duration), check.IsNil)
+		}
+	}
+	c.Check(duration, check.Equals, 2*time.Hour)
+}
+
+func (s *apiSuite) TestConnectPlugForDurationErrors(c *check.C) {
+	s.daemon(c)
+
+	for _, t := range []struct {
+		action, duration, err string
+	}{
+		{"disconnect", "2h", "a duration can only be specified when connecting"},
+		{"connect", "forever", `cannot parse duration: time: invalid duration "?forever"?`},
+		{"connect", "-2h", `duration must be positive, not "-2h"`},
+	} {
+		action := &interfaceAction{
+			Action: t.action,
+			Plugs:  []plugJSON{{Snap: "consumer", Name: "plug"}},
+			Slots:  []slotJSON{{Snap: "producer", Name: "slot"}},
+			For:    t.duration,
+		}
+		text, err := json.Marshal(action)
+		c.Assert(err, check.IsNil)
+		req, err := http.NewRequest("POST", "/v2/interfaces", bytes.NewBuffer(text))
+		c.Assert(err, check.IsNil)
+		rsp := changeInterfaces(interfacesCmd, req, nil).(*resp)
+		c.Check(rsp.Status, check.Equals, 400)
+		c.Check(rsp.Result.(*errorResult).Message, check.Matches, t.err)
+	}
+}
+
 func (s *apiSuite) TestConnectPlugFailureInterfaceMismatch(c *check.C) {
 	d := s.daemon(c)
 
@@ -6407,6 +6962,70 @@ func (s *apiSuite) TestAliases(c *check.C) {
 
 }
 
+func (s *apiSuite) TestAliasesRefreshPreview(c *check.C) {
+	d := s.daemon(c)
+
+	s.mockSnap(c, aliasYaml)
+
+	oldAutoAliases := snapstate.AutoAliases
+	snapstate.AutoAliases = func(*state.State, *snap.Info) (map[string]string, error) {
+		return map[string]string{"alias1": "app"}, nil
+	}
+	defer func() { snapstate.AutoAliases = oldAutoAliases }()
+
+	st := d.overlord.State()
+	st.Lock()
+	snapstate.Set(st, "other-snap", &snapstate.SnapState{
+		Sequence: []*snap.SideInfo{
+			{RealName: "other-snap", Revision: snap.R(3)},
+		},
+		Current: snap.R(3),
+		Active:  true,
+		Aliases: map[string]*snapstate.AliasTarget{
+			"alias1": {Manual: "cmd1"},
+		},
+	})
+	st.Unlock()
+
+	req, err := http.NewRequest("GET", "/v2/aliases?select=refresh&names=alias-snap", nil)
+	c.Assert(err, check.IsNil)
+
+	rsp := getAliases(aliasesCmd, req, nil).(*resp)
+	c.Check(rsp.Type, check.Equals, ResponseTypeSync)
+	c.Check(rsp.Status, check.Equals, 200)
+	c.Check(rsp.Result, check.DeepEquals, map[string]*snapstate.AliasesPreview{
+		"alias-snap": {
+			Added:     []string{"alias1"},
+			Conflicts: map[string][]string{"other-snap": {"alias1"}},
+			Policy:    "fail",
+		},
+	})
+}
+
+func (s *apiSuite) TestAliasesRefreshPreviewSnapNotFound(c *check.C) {
+	s.daemon(c)
+
+	req, err := http.NewRequest("GET", "/v2/aliases?select=refresh&names=no-such-snap", nil)
+	c.Assert(err, check.IsNil)
+
+	rsp := getAliases(aliasesCmd, req, nil).(*resp)
+	c.Check(rsp.Type, check.Equals, ResponseTypeError)
+	c.Check(rsp.Status, check.Equals, 404)
+	c.Check(rsp.Result.(*errorResult).Kind, check.Equals, errorKindSnapNotFound)
+}
+
+func (s *apiSuite) TestAliasesBadSelect(c *check.C) {
+	s.daemon(c)
+
+	req, err := http.NewRequest("GET", "/v2/aliases?select=foo", nil)
+	c.Assert(err, check.IsNil)
+
+	rsp := getAliases(aliasesCmd, req, nil).(*resp)
+	c.Check(rsp.Type, check.Equals, ResponseTypeError)
+	c.Check(rsp.Status, check.Equals, 400)
+	c.Check(rsp.Result.(*errorResult).Message, check.Equals, `invalid select parameter: "foo"`)
+}
+
 func (s *apiSuite) TestInstallUnaliased(c *check.C) {
 	var calledFlags snapstate.Flags
 
@@ -7300,6 +7919,7 @@ func (s *apiSuite) TestErrToResponse(c *check.C) {
 	nc := &snapstate.SnapNotClassicError{Snap: "foo"}
 	nce := &snapstate.SnapNeedsClassicError{Snap: "foo"}
 	ncse := &snapstate.SnapNeedsClassicSystemError{Snap: "foo"}
+	nta := &snapstate.SnapNeedsTermsAcceptedError{Snap: "foo", License: "Proprietary", LicenseVersion: "2"}
 	netoe := fakeNetError{message: "other"}
 	nettoute := fakeNetError{message: "timeout", timeout: true}
 	nettmpe := fakeNetError{message: "temp", temporary: true}
@@ -7327,6 +7947,19 @@ func (s *apiSuite) TestErrToResponse(c *check.C) {
 		{nc, makeErrorRsp(errorKindSnapNotClassic, nc, "foo")},
 		{nce, makeErrorRsp(errorKindSnapNeedsClassic, nce, "foo")},
 		{ncse, makeErrorRsp(errorKindSnapNeedsClassicSystem, ncse, "foo")},
+		{nta, SyncResponse(&resp{
+			Type: ResponseTypeError,
+			Result: &errorResult{
+				Message: `snap "foo" requires its terms to be accepted`,
+				Kind:    errorKindSnapNeedsTermsAccepted,
+				Value: map[string]interface{}{
+					"snap-name":       "foo",
+					"license":         "Proprietary",
+					"license-version": "2",
+				},
+			},
+			Status: 400,
+		}, nil)},
 		{cce, SnapChangeConflict(cce)},
 		{nettoute, makeErrorRsp(errorKindNetworkTimeout, nettoute, "")},
 		{netoe, BadRequest("ERR: %v", netoe)},
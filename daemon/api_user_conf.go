@@ -0,0 +1,105 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2020 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package daemon
+
+import (
+	"net/http"
+
+	"github.com/snapcore/snapd/jsonutil"
+	"github.com/snapcore/snapd/overlord/auth"
+	"github.com/snapcore/snapd/overlord/configstate"
+	"github.com/snapcore/snapd/overlord/configstate/config"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/strutil"
+)
+
+// snapUserConfCmd gives access to the configuration of a snap scoped to
+// the user making the request, as also seen via "snapctl get --user".
+// Changes to it are not propagated to services of the snap: those all run
+// as system services, which only ever see the system-wide configuration.
+var snapUserConfCmd = &Command{
+	Path:        "/v2/snaps/{name}/user-conf",
+	UserScopeOK: true,
+	GET:         getSnapUserConf,
+	PUT:         setSnapUserConf,
+}
+
+// userConfTransaction returns a configuration transaction scoped to the
+// peer uid of the request, after checking that the snap is installed.
+func userConfTransaction(st *state.State, r *http.Request, snapName string) (*config.Transaction, Response) {
+	_, uid, _, err := ucrednetGet(r.RemoteAddr)
+	if err != nil {
+		return nil, Forbidden("cannot get the configuration of a user without a peer uid")
+	}
+	if err := checkSnapInstalled(st, snapName); err != nil {
+		if err == state.ErrNoState {
+			return nil, SnapNotFound(snapName, err)
+		}
+		return nil, InternalError("cannot access snap state: %v", err)
+	}
+	st.Lock()
+	defer st.Unlock()
+	return config.NewUserTransaction(st, uid), nil
+}
+
+func getSnapUserConf(c *Command, r *http.Request, user *auth.UserState) Response {
+	vars := muxVars(r)
+	snapName := configstate.RemapSnapFromRequest(vars["name"])
+
+	keys := strutil.CommaSeparatedList(r.URL.Query().Get("keys"))
+
+	tr, rsp := userConfTransaction(c.d.overlord.State(), r, snapName)
+	if rsp != nil {
+		return rsp
+	}
+
+	return confValuesResponse(tr, snapName, keys)
+}
+
+func setSnapUserConf(c *Command, r *http.Request, user *auth.UserState) Response {
+	vars := muxVars(r)
+	snapName := configstate.RemapSnapFromRequest(vars["name"])
+
+	var patchValues map[string]interface{}
+	if err := jsonutil.DecodeWithNumber(r.Body, &patchValues); err != nil {
+		return BadRequest("cannot decode request body into patch values: %v", err)
+	}
+
+	st := c.d.overlord.State()
+	tr, rsp := userConfTransaction(st, r, snapName)
+	if rsp != nil {
+		return rsp
+	}
+	for key, value := range patchValues {
+		if err := tr.Set(snapName, key, value); err != nil {
+			return BadRequest("%v", err)
+		}
+	}
+	if err := tr.CheckUserLimits(snapName); err != nil {
+		return BadRequest("%v", err)
+	}
+	// user configuration does not involve the configure hook, so
+	// the changes are committed right away
+	st.Lock()
+	tr.Commit()
+	st.Unlock()
+
+	return SyncResponse(nil, nil)
+}
@@ -0,0 +1,113 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2020 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package daemon
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	"gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/overlord/configstate/config"
+)
+
+func (s *apiSuite) runUserConf(c *check.C, method, query string, body []byte, uid string, statusCode int) interface{} {
+	s.vars = map[string]string{"name": "config-snap"}
+	req, err := http.NewRequest(method, "/v2/snaps/config-snap/user-conf"+query, bytes.NewBuffer(body))
+	c.Assert(err, check.IsNil)
+	if uid != "" {
+		req.RemoteAddr = "pid=100;uid=" + uid + ";socket=;"
+	}
+	rec := httptest.NewRecorder()
+	var rsp Response
+	if method == "GET" {
+		rsp = snapUserConfCmd.GET(snapUserConfCmd, req, nil)
+	} else {
+		rsp = snapUserConfCmd.PUT(snapUserConfCmd, req, nil)
+	}
+	rsp.ServeHTTP(rec, req)
+	c.Check(rec.Code, check.Equals, statusCode)
+
+	var rspBody map[string]interface{}
+	c.Assert(json.Unmarshal(rec.Body.Bytes(), &rspBody), check.IsNil)
+	return rspBody["result"]
+}
+
+func (s *apiSuite) TestSetGetUserConf(c *check.C) {
+	d := s.daemon(c)
+	s.mockSnap(c, configYaml)
+
+	s.runUserConf(c, "PUT", "", []byte(`{"theme": "dark", "size": 12}`), "1000", 200)
+
+	st := d.overlord.State()
+	st.Lock()
+	tr := config.NewUserTransaction(st, 1000)
+	var theme string
+	c.Check(tr.Get("config-snap", "theme", &theme), check.IsNil)
+	c.Check(theme, check.Equals, "dark")
+	// the system configuration is untouched
+	tr = config.NewTransaction(st)
+	c.Check(tr.Get("config-snap", "theme", &theme), check.ErrorMatches, `.*snap "config-snap" has no "theme" configuration option`)
+	st.Unlock()
+
+	result := s.runUserConf(c, "GET", "?keys=theme,size", nil, "1000", 200)
+	c.Check(result, check.DeepEquals, map[string]interface{}{"theme": "dark", "size": 12.0})
+
+	// other users have their own configuration
+	result = s.runUserConf(c, "GET", "", nil, "1001", 200)
+	c.Check(result, check.DeepEquals, map[string]interface{}{})
+}
+
+func (s *apiSuite) TestUserConfNoPeerUid(c *check.C) {
+	s.daemon(c)
+	s.mockSnap(c, configYaml)
+
+	result := s.runUserConf(c, "GET", "", nil, "", 403)
+	c.Check(result.(map[string]interface{})["message"], check.Equals, "cannot get the configuration of a user without a peer uid")
+}
+
+func (s *apiSuite) TestUserConfSnapNotInstalled(c *check.C) {
+	s.daemon(c)
+
+	result := s.runUserConf(c, "PUT", "", []byte(`{"theme": "dark"}`), "1000", 404)
+	c.Check(result.(map[string]interface{})["kind"], check.Equals, "snap-not-found")
+}
+
+func (s *apiSuite) TestSetUserConfTooLarge(c *check.C) {
+	d := s.daemon(c)
+	s.mockSnap(c, configYaml)
+
+	value := strings.Repeat("x", 64*1024)
+	body, err := json.Marshal(map[string]interface{}{"theme": value})
+	c.Assert(err, check.IsNil)
+	result := s.runUserConf(c, "PUT", "", body, "1000", 400)
+	c.Check(result.(map[string]interface{})["message"], check.Equals, `cannot store more than 65536 bytes of configuration of snap "config-snap" for a single user`)
+
+	// nothing was committed
+	st := d.overlord.State()
+	st.Lock()
+	defer st.Unlock()
+	var theme string
+	tr := config.NewUserTransaction(st, 1000)
+	c.Check(config.IsNoOption(tr.Get("config-snap", "theme", &theme)), check.Equals, true)
+}
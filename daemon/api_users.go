@@ -86,6 +86,9 @@ func loginUser(c *Command, r *http.Request, user *auth.UserState) Response {
 		Email    string `json:"email"`
 		Password string `json:"password"`
 		Otp      string `json:"otp"`
+		// SecurityKeyResponse is the response of a FIDO2/U2F
+		// security key to the challenge of a previous attempt
+		SecurityKeyResponse string `json:"security-key-response"`
 	}
 
 	decoder := json.NewDecoder(r.Body)
@@ -119,7 +122,13 @@ func loginUser(c *Command, r *http.Request, user *auth.UserState) Response {
 	overlord := c.d.overlord
 	st := overlord.State()
 	theStore := getStore(c)
-	macaroon, discharge, err := theStore.LoginUser(loginData.Email, loginData.Password, loginData.Otp)
+	var macaroon, discharge string
+	var err error
+	if loginData.SecurityKeyResponse != "" {
+		macaroon, discharge, err = theStore.LoginUserWithSecurityKey(loginData.Email, loginData.Password, loginData.SecurityKeyResponse)
+	} else {
+		macaroon, discharge, err = theStore.LoginUser(loginData.Email, loginData.Password, loginData.Otp)
+	}
 	switch err {
 	case store.ErrAuthenticationNeeds2fa:
 		return SyncResponse(&resp{
@@ -141,6 +150,18 @@ func loginUser(c *Command, r *http.Request, user *auth.UserState) Response {
 		}, nil)
 	default:
 		switch err := err.(type) {
+		case *store.SecurityKeyRequiredError:
+			return SyncResponse(&resp{
+				Type: ResponseTypeError,
+				Result: &errorResult{
+					Kind:    errorKindTwoFactorRequired,
+					Message: err.Error(),
+					Value: map[string]string{
+						"security-key-challenge": err.Challenge,
+					},
+				},
+				Status: 401,
+			}, nil)
 		case store.InvalidAuthDataError:
 			return SyncResponse(&resp{
 				Type: ResponseTypeError,
@@ -0,0 +1,198 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2019 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package daemon
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+
+	"github.com/snapcore/snapd/asserts"
+	"github.com/snapcore/snapd/asserts/snapasserts"
+	"github.com/snapcore/snapd/client"
+	"github.com/snapcore/snapd/overlord/assertstate"
+	"github.com/snapcore/snapd/overlord/auth"
+	"github.com/snapcore/snapd/overlord/state"
+)
+
+var (
+	validationSetsListCmd = &Command{
+		Path:   "/v2/validation-sets",
+		UserOK: true,
+		GET:    listValidationSets,
+	}
+
+	validationSetsCmd = &Command{
+		Path:   "/v2/validation-sets/{account}/{name}",
+		UserOK: true,
+		GET:    getValidationSet,
+		POST:   applyValidationSet,
+	}
+)
+
+var assertstateApplyValidationSet = assertstate.ApplyValidationSet
+
+func validationSetResult(st *state.State, tr *assertstate.ValidationSetTracking) (*client.ValidationSetResult, error) {
+	vs, err := assertstate.ValidationSetAssertion(st, tr)
+	if err != nil {
+		return nil, err
+	}
+	snaps, err := assertstate.InstalledSnaps(st)
+	if err != nil {
+		return nil, err
+	}
+	err = snapasserts.CheckInstalledSnaps([]*asserts.ValidationSet{vs}, snaps)
+	if _, ok := err.(*snapasserts.ValidationSetsValidationError); err != nil && !ok {
+		return nil, err
+	}
+	return &client.ValidationSetResult{
+		AccountID: tr.AccountID,
+		Name:      tr.Name,
+		PinnedAt:  tr.PinnedAt,
+		Mode:      tr.Mode.String(),
+		Sequence:  tr.Current,
+		Valid:     err == nil,
+	}, nil
+}
+
+func listValidationSets(c *Command, r *http.Request, _ *auth.UserState) Response {
+	st := c.d.overlord.State()
+	st.Lock()
+	defer st.Unlock()
+
+	vsmap, err := assertstate.ValidationSets(st)
+	if err != nil {
+		return InternalError("cannot list validation sets: %v", err)
+	}
+
+	keys := make([]string, 0, len(vsmap))
+	for key := range vsmap {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	results := make([]*client.ValidationSetResult, 0, len(keys))
+	for _, key := range keys {
+		res, err := validationSetResult(st, vsmap[key])
+		if err != nil {
+			return InternalError("cannot check validation set %q: %v", key, err)
+		}
+		results = append(results, res)
+	}
+	return SyncResponse(results, nil)
+}
+
+func validationSetKeyFromRequest(r *http.Request) (accountID, name string, rsp Response) {
+	vars := muxVars(r)
+	accountID = vars["account"]
+	name = vars["name"]
+	if accountID == "" {
+		return "", "", BadRequest("invalid account id %q", accountID)
+	}
+	if !asserts.IsValidValidationSetName(name) {
+		return "", "", BadRequest("invalid validation set name %q", name)
+	}
+	return accountID, name, nil
+}
+
+func getValidationSet(c *Command, r *http.Request, _ *auth.UserState) Response {
+	accountID, name, rsp := validationSetKeyFromRequest(r)
+	if rsp != nil {
+		return rsp
+	}
+
+	st := c.d.overlord.State()
+	st.Lock()
+	defer st.Unlock()
+
+	var tr assertstate.ValidationSetTracking
+	err := assertstate.GetValidationSet(st, accountID, name, &tr)
+	if err == state.ErrNoState {
+		return NotFound("validation set %s/%s is not tracked", accountID, name)
+	}
+	if err != nil {
+		return InternalError("cannot get validation set %s/%s: %v", accountID, name, err)
+	}
+	res, err := validationSetResult(st, &tr)
+	if err != nil {
+		return InternalError("cannot check validation set %s/%s: %v", accountID, name, err)
+	}
+	return SyncResponse(res, nil)
+}
+
+func applyValidationSet(c *Command, r *http.Request, user *auth.UserState) Response {
+	accountID, name, rsp := validationSetKeyFromRequest(r)
+	if rsp != nil {
+		return rsp
+	}
+
+	var action client.ValidationSetAction
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&action); err != nil {
+		return BadRequest("cannot decode request body into validation set action: %v", err)
+	}
+	if action.Sequence < 0 {
+		return BadRequest("invalid sequence %d", action.Sequence)
+	}
+
+	st := c.d.overlord.State()
+	st.Lock()
+	defer st.Unlock()
+
+	switch action.Action {
+	case "forget":
+		if err := assertstate.DeleteValidationSet(st, accountID, name); err != nil {
+			return InternalError("cannot forget validation set %s/%s: %v", accountID, name, err)
+		}
+		return SyncResponse(nil, nil)
+	case "apply":
+		var mode assertstate.ValidationSetMode
+		switch action.Mode {
+		case "monitor":
+			mode = assertstate.Monitor
+		case "enforce":
+			mode = assertstate.Enforce
+		default:
+			return BadRequest("invalid mode %q", action.Mode)
+		}
+
+		userID := 0
+		if user != nil {
+			userID = user.ID
+		}
+		tr, err := assertstateApplyValidationSet(st, accountID, name, action.Sequence, mode, userID)
+		if verr, ok := err.(*snapasserts.ValidationSetsValidationError); ok {
+			return Conflict("cannot enforce validation set %s/%s: %v", accountID, name, verr)
+		}
+		if asserts.IsNotFound(err) {
+			return NotFound("cannot find validation set %s/%s: %v", accountID, name, err)
+		}
+		if err != nil {
+			return InternalError("cannot apply validation set %s/%s: %v", accountID, name, err)
+		}
+		res, err := validationSetResult(st, tr)
+		if err != nil {
+			return InternalError("cannot check validation set %s/%s: %v", accountID, name, err)
+		}
+		return SyncResponse(res, nil)
+	default:
+		return BadRequest("invalid action %q", action.Action)
+	}
+}
@@ -0,0 +1,203 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2019 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package daemon
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/asserts"
+	"github.com/snapcore/snapd/asserts/snapasserts"
+	"github.com/snapcore/snapd/client"
+	"github.com/snapcore/snapd/overlord/assertstate"
+	"github.com/snapcore/snapd/overlord/assertstate/assertstatetest"
+	"github.com/snapcore/snapd/overlord/state"
+)
+
+func (s *apiSuite) mockValidationSet(c *check.C, st *state.State, mode assertstate.ValidationSetMode) {
+	vs, err := s.brands.Signing("my-brand").Sign(asserts.ValidationSetType, map[string]interface{}{
+		"authority-id": "my-brand",
+		"account-id":   "my-brand",
+		"series":       "16",
+		"name":         "base",
+		"sequence":     "3",
+		"snaps": []interface{}{
+			map[string]interface{}{"name": "foo", "id": "fooididididididididididididididi"},
+		},
+		"timestamp": time.Now().Format(time.RFC3339),
+	}, nil, "")
+	c.Assert(err, check.IsNil)
+
+	assertstatetest.AddMany(st, s.storeSigning.StoreAccountKey(""))
+	assertstatetest.AddMany(st, s.brands.AccountsAndKeys("my-brand")...)
+	assertstatetest.AddMany(st, vs)
+	c.Assert(assertstate.UpdateValidationSet(st, &assertstate.ValidationSetTracking{
+		AccountID: "my-brand",
+		Name:      "base",
+		Mode:      mode,
+		Current:   3,
+	}), check.IsNil)
+}
+
+func (s *apiSuite) TestListValidationSets(c *check.C) {
+	d := s.daemonWithOverlordMock(c)
+	st := d.overlord.State()
+
+	req, err := http.NewRequest("GET", "/v2/validation-sets", nil)
+	c.Assert(err, check.IsNil)
+	rsp := listValidationSets(validationSetsListCmd, req, nil).(*resp)
+	c.Assert(rsp.Status, check.Equals, 200)
+	c.Check(rsp.Result, check.DeepEquals, []*client.ValidationSetResult{})
+
+	st.Lock()
+	s.mockValidationSet(c, st, assertstate.Monitor)
+	st.Unlock()
+
+	rsp = listValidationSets(validationSetsListCmd, req, nil).(*resp)
+	c.Assert(rsp.Status, check.Equals, 200)
+	// foo is required but not installed
+	c.Check(rsp.Result, check.DeepEquals, []*client.ValidationSetResult{
+		{AccountID: "my-brand", Name: "base", Mode: "monitor", Sequence: 3, Valid: false},
+	})
+}
+
+func (s *apiSuite) TestGetValidationSet(c *check.C) {
+	d := s.daemonWithOverlordMock(c)
+	st := d.overlord.State()
+
+	s.vars = map[string]string{"account": "my-brand", "name": "base"}
+	req, err := http.NewRequest("GET", "/v2/validation-sets/my-brand/base", nil)
+	c.Assert(err, check.IsNil)
+	rsp := getValidationSet(validationSetsCmd, req, nil).(*resp)
+	c.Check(rsp.Status, check.Equals, 404)
+	c.Check(rsp.Result.(*errorResult).Message, check.Equals, "validation set my-brand/base is not tracked")
+
+	st.Lock()
+	s.mockValidationSet(c, st, assertstate.Enforce)
+	st.Unlock()
+
+	rsp = getValidationSet(validationSetsCmd, req, nil).(*resp)
+	c.Assert(rsp.Status, check.Equals, 200)
+	c.Check(rsp.Result, check.DeepEquals, &client.ValidationSetResult{
+		AccountID: "my-brand", Name: "base", Mode: "enforce", Sequence: 3, Valid: false,
+	})
+
+	s.vars = map[string]string{"account": "my-brand", "name": "Base"}
+	rsp = getValidationSet(validationSetsCmd, req, nil).(*resp)
+	c.Check(rsp.Status, check.Equals, 400)
+	c.Check(rsp.Result.(*errorResult).Message, check.Equals, `invalid validation set name "Base"`)
+}
+
+func (s *apiSuite) TestForgetValidationSet(c *check.C) {
+	d := s.daemonWithOverlordMock(c)
+	st := d.overlord.State()
+	st.Lock()
+	s.mockValidationSet(c, st, assertstate.Monitor)
+	st.Unlock()
+
+	s.vars = map[string]string{"account": "my-brand", "name": "base"}
+	req, err := http.NewRequest("POST", "/v2/validation-sets/my-brand/base", strings.NewReader(`{"action": "forget"}`))
+	c.Assert(err, check.IsNil)
+	rsp := applyValidationSet(validationSetsCmd, req, nil).(*resp)
+	c.Assert(rsp.Status, check.Equals, 200)
+
+	st.Lock()
+	defer st.Unlock()
+	var tr assertstate.ValidationSetTracking
+	c.Check(assertstate.GetValidationSet(st, "my-brand", "base", &tr), check.Equals, state.ErrNoState)
+}
+
+func (s *apiSuite) TestApplyValidationSet(c *check.C) {
+	d := s.daemonWithOverlordMock(c)
+	st := d.overlord.State()
+
+	var gotSeq int
+	var gotMode assertstate.ValidationSetMode
+	restore := MockAssertstateApplyValidationSet(func(st *state.State, accountID, name string, sequence int, mode assertstate.ValidationSetMode, userID int) (*assertstate.ValidationSetTracking, error) {
+		c.Check(accountID, check.Equals, "my-brand")
+		c.Check(name, check.Equals, "base")
+		gotSeq = sequence
+		gotMode = mode
+		s.mockValidationSet(c, st, mode)
+		var tr assertstate.ValidationSetTracking
+		c.Assert(assertstate.GetValidationSet(st, accountID, name, &tr), check.IsNil)
+		return &tr, nil
+	})
+	defer restore()
+
+	s.vars = map[string]string{"account": "my-brand", "name": "base"}
+	req, err := http.NewRequest("POST", "/v2/validation-sets/my-brand/base", strings.NewReader(`{"action": "apply", "mode": "monitor", "sequence": 3}`))
+	c.Assert(err, check.IsNil)
+	rsp := applyValidationSet(validationSetsCmd, req, nil).(*resp)
+	c.Assert(rsp.Status, check.Equals, 200)
+	c.Check(gotSeq, check.Equals, 3)
+	c.Check(gotMode, check.Equals, assertstate.Monitor)
+	c.Check(rsp.Result, check.DeepEquals, &client.ValidationSetResult{
+		AccountID: "my-brand", Name: "base", Mode: "monitor", Sequence: 3, Valid: false,
+	})
+
+	st.Lock()
+	defer st.Unlock()
+	var tr assertstate.ValidationSetTracking
+	c.Check(assertstate.GetValidationSet(st, "my-brand", "base", &tr), check.IsNil)
+}
+
+func (s *apiSuite) TestApplyValidationSetEnforceNotMet(c *check.C) {
+	s.daemonWithOverlordMock(c)
+
+	restore := MockAssertstateApplyValidationSet(func(st *state.State, accountID, name string, sequence int, mode assertstate.ValidationSetMode, userID int) (*assertstate.ValidationSetTracking, error) {
+		c.Check(mode, check.Equals, assertstate.Enforce)
+		return nil, &snapasserts.ValidationSetsValidationError{
+			MissingSnaps: map[string][]string{"foo": {"my-brand/base"}},
+		}
+	})
+	defer restore()
+
+	s.vars = map[string]string{"account": "my-brand", "name": "base"}
+	req, err := http.NewRequest("POST", "/v2/validation-sets/my-brand/base", strings.NewReader(`{"action": "apply", "mode": "enforce"}`))
+	c.Assert(err, check.IsNil)
+	rsp := applyValidationSet(validationSetsCmd, req, nil).(*resp)
+	c.Assert(rsp.Status, check.Equals, 409)
+	c.Check(rsp.Result.(*errorResult).Message, check.Matches, `(?s)cannot enforce validation set my-brand/base: validation sets assertions are not met:.*foo.*`)
+}
+
+func (s *apiSuite) TestApplyValidationSetBadRequests(c *check.C) {
+	s.daemonWithOverlordMock(c)
+	s.vars = map[string]string{"account": "my-brand", "name": "base"}
+
+	for _, t := range []struct {
+		body string
+		err  string
+	}{
+		{`{"action": "frob"}`, `invalid action "frob"`},
+		{`{"action": "apply", "mode": "frob"}`, `invalid mode "frob"`},
+		{`{"action": "apply", "mode": "enforce", "sequence": -1}`, `invalid sequence -1`},
+		{`garbage`, `cannot decode request body into validation set action: .*`},
+	} {
+		req, err := http.NewRequest("POST", "/v2/validation-sets/my-brand/base", strings.NewReader(t.body))
+		c.Assert(err, check.IsNil)
+		rsp := applyValidationSet(validationSetsCmd, req, nil).(*resp)
+		c.Check(rsp.Status, check.Equals, 400)
+		c.Check(rsp.Result.(*errorResult).Message, check.Matches, t.err)
+	}
+}
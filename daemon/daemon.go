@@ -67,6 +67,7 @@ type Daemon struct {
 	tomb            tomb.Tomb
 	router          *mux.Router
 	standbyOpinions *standby.StandbyOpinions
+	requestLimiter  *requestLimiter
 
 	// set to remember we need to restart the system
 	restartSystem bool
@@ -101,6 +102,9 @@ type Command struct {
 	SnapOK bool
 	// this path is only accessible to root
 	RootOnly bool
+	// can any uid use all verbs? the handlers must scope what they
+	// do to the peer uid
+	UserScopeOK bool
 
 	// can polkit grant access? set to polkit action ID if so
 	PolkitOK string
@@ -130,8 +134,11 @@ var polkitCheckAuthorization = polkit.CheckAuthorization
 // - UserOK: any uid on the local system can access GET
 // - RootOnly: only root can access this
 // - SnapOK: a snap can access this via `snapctl`
+//
+// UserScopeOK allows any uid to use all verbs, relying on the handlers
+// to only act on behalf of the peer uid.
 func (c *Command) canAccess(r *http.Request, user *auth.UserState) accessResult {
-	if c.RootOnly && (c.UserOK || c.GuestOK || c.SnapOK) {
+	if c.RootOnly && (c.UserOK || c.GuestOK || c.SnapOK || c.UserScopeOK) {
 		// programming error
 		logger.Panicf("Command can't have RootOnly together with any *OK flag")
 	}
@@ -182,6 +189,11 @@ func (c *Command) canAccess(r *http.Request, user *auth.UserState) accessResult
 		return accessOK
 	}
 
+	if c.UserScopeOK {
+		// the handlers only act on behalf of the peer uid
+		return accessOK
+	}
+
 	if c.RootOnly {
 		return accessUnauthorized
 	}
@@ -239,6 +251,18 @@ func (c *Command) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if key, limited := requestClientKey(r); limited && c.d.requestLimiter != nil {
+		st.Lock()
+		limits := requestLimitsFromConfig(st)
+		st.Unlock()
+		release, retryAfter := c.d.requestLimiter.acquire(key, limits)
+		if release == nil {
+			tooManyRequests(w, retryAfter).ServeHTTP(w, r)
+			return
+		}
+		defer release()
+	}
+
 	ctx := store.WithClientUserAgent(r.Context(), r)
 	r = r.WithContext(ctx)
 
@@ -384,8 +408,9 @@ var (
 )
 
 type connTracker struct {
-	mu    sync.Mutex
-	conns map[net.Conn]struct{}
+	mu           sync.Mutex
+	conns        map[net.Conn]struct{}
+	lastActivity time.Time
 }
 
 func (ct *connTracker) CanStandby() bool {
@@ -395,9 +420,18 @@ func (ct *connTracker) CanStandby() bool {
 	return len(ct.conns) == 0
 }
 
+// LastActivity returns when a connection last changed state.
+func (ct *connTracker) LastActivity() time.Time {
+	ct.mu.Lock()
+	defer ct.mu.Unlock()
+
+	return ct.lastActivity
+}
+
 func (ct *connTracker) trackConn(conn net.Conn, state http.ConnState) {
 	ct.mu.Lock()
 	defer ct.mu.Unlock()
+	ct.lastActivity = time.Now()
 	// we ignore hijacked connections, if we do things with websockets
 	// we'll need custom shutdown handling for them
 	if state == http.StateNew || state == http.StateActive {
@@ -693,7 +727,9 @@ func (d *Daemon) RebootDidNotHappen(st *state.State) error {
 
 // New Daemon
 func New() (*Daemon, error) {
-	d := &Daemon{}
+	d := &Daemon{
+		requestLimiter: newRequestLimiter(),
+	}
 	ovld, err := overlord.New(d)
 	if err == state.ErrExpectedReboot {
 		// we proceed without overlord until we reach Stop
@@ -332,6 +332,14 @@ func (s *daemonSuite) TestUserAccess(c *check.C) {
 	cmd = &Command{d: newTestDaemon(c), SnapOK: true}
 	c.Check(cmd.canAccess(get, nil), check.Equals, accessUnauthorized)
 	c.Check(cmd.canAccess(put, nil), check.Equals, accessUnauthorized)
+
+	cmd = &Command{d: newTestDaemon(c), UserScopeOK: true}
+	c.Check(cmd.canAccess(get, nil), check.Equals, accessOK)
+	c.Check(cmd.canAccess(put, nil), check.Equals, accessOK)
+
+	// but not without a peer uid
+	put = &http.Request{Method: "PUT", RemoteAddr: ""}
+	c.Check(cmd.canAccess(put, nil), check.Equals, accessUnauthorized)
 }
 
 func (s *daemonSuite) TestLoggedInUserAccess(c *check.C) {
@@ -1112,6 +1120,20 @@ func (s *daemonSuite) TestConnTrackerCanShutdown(c *check.C) {
 	c.Check(ct.CanStandby(), check.Equals, true)
 }
 
+func (s *daemonSuite) TestConnTrackerLastActivity(c *check.C) {
+	ct := &connTracker{conns: make(map[net.Conn]struct{})}
+	c.Check(ct.LastActivity().IsZero(), check.Equals, true)
+
+	before := time.Now()
+	con := &net.IPConn{}
+	ct.trackConn(con, http.StateActive)
+	active := ct.LastActivity()
+	c.Check(active.Before(before), check.Equals, false)
+
+	ct.trackConn(con, http.StateIdle)
+	c.Check(ct.LastActivity().Before(active), check.Equals, false)
+}
+
 func doTestReq(c *check.C, cmd *Command, mth string) *httptest.ResponseRecorder {
 	req, err := http.NewRequest(mth, "", nil)
 	c.Assert(err, check.IsNil)
@@ -0,0 +1,41 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2019 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package daemon
+
+import (
+	"github.com/snapcore/snapd/overlord/devicestate"
+	"github.com/snapcore/snapd/overlord/state"
+)
+
+func MockDevicestateGadgetActions(f func(st *state.State) ([]*devicestate.GadgetAction, error)) (restore func()) {
+	old := devicestateGadgetActions
+	devicestateGadgetActions = f
+	return func() {
+		devicestateGadgetActions = old
+	}
+}
+
+func MockDevicestateRunGadgetAction(f func(st *state.State, name string) (*state.TaskSet, error)) (restore func()) {
+	old := devicestateRunGadgetAction
+	devicestateRunGadgetAction = f
+	return func() {
+		devicestateRunGadgetAction = old
+	}
+}
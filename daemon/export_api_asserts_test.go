@@ -23,6 +23,7 @@ import (
 	"net/http"
 
 	"github.com/snapcore/snapd/overlord/auth"
+	"github.com/snapcore/snapd/overlord/state"
 )
 
 func GetAssertTypeNames(c *Command, r *http.Request, user *auth.UserState) *resp {
@@ -37,3 +38,11 @@ var (
 	AssertsCmd         = assertsCmd
 	AssertsFindManyCmd = assertsFindManyCmd
 )
+
+func MockAssertstateFetchSnapAssertions(f func(st *state.State, snapID, snapSHA3_384 string, userID int) error) (restore func()) {
+	old := assertstateFetchSnapAssertions
+	assertstateFetchSnapAssertions = f
+	return func() {
+		assertstateFetchSnapAssertions = old
+	}
+}
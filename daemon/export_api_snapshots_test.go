@@ -39,6 +39,14 @@ func MockSnapshotSave(newSave func(*state.State, []string, []string) (uint64, []
 	}
 }
 
+func MockSnapshotSaveIncremental(newSave func(*state.State, uint64, []string, []string) (uint64, []string, *state.TaskSet, error)) (restore func()) {
+	oldSave := snapshotSaveIncremental
+	snapshotSaveIncremental = newSave
+	return func() {
+		snapshotSaveIncremental = oldSave
+	}
+}
+
 func MockSnapshotList(newList func(context.Context, uint64, []string) ([]client.SnapshotSet, error)) (restore func()) {
 	oldList := snapshotList
 	snapshotList = newList
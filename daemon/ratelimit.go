@@ -0,0 +1,204 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2019 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package daemon
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/overlord/configstate/config"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/sandbox/cgroup"
+)
+
+var (
+	// maxConcurrentRequestsPerClient is how many requests a single
+	// client can have in flight at any time, by default
+	maxConcurrentRequestsPerClient = 10
+	// requestRatePerClient is the sustained number of requests per
+	// second a single client can make, by default
+	requestRatePerClient = 20.0
+	// requestBurstPerClient is how many requests above the sustained
+	// rate a single client can make in a short burst, by default
+	requestBurstPerClient = 50.0
+
+	requestLimiterNow = time.Now
+
+	snapNameFromPid = cgroup.SnapNameFromPid
+)
+
+// requestLimits are the limits applied to the requests of every
+// client. Zero values mean the defaults.
+type requestLimits struct {
+	maxConcurrent int
+	rate          float64
+	burst         float64
+}
+
+func (rl requestLimits) withDefaults() requestLimits {
+	if rl.maxConcurrent == 0 {
+		rl.maxConcurrent = maxConcurrentRequestsPerClient
+	}
+	if rl.rate == 0 {
+		rl.rate = requestRatePerClient
+	}
+	if rl.burst == 0 {
+		rl.burst = requestBurstPerClient
+	}
+	return rl
+}
+
+// requestLimitsFromConfig returns the limits set via the
+// daemon.api-max-concurrent, daemon.api-rate and daemon.api-burst
+// system options. The state must be locked by the caller.
+func requestLimitsFromConfig(st *state.State) requestLimits {
+	tr := config.NewTransaction(st)
+	get := func(name string) int {
+		var value interface{}
+		if err := tr.Get("core", name, &value); err != nil || value == nil {
+			return 0
+		}
+		n, err := strconv.Atoi(fmt.Sprint(value))
+		if err != nil || n <= 0 {
+			return 0
+		}
+		return n
+	}
+	return requestLimits{
+		maxConcurrent: get("daemon.api-max-concurrent"),
+		rate:          float64(get("daemon.api-rate")),
+		burst:         float64(get("daemon.api-burst")),
+	}
+}
+
+// requestLimiter shapes the requests of local clients so that a
+// single misbehaving client cannot starve snapd on constrained
+// devices. Every client has a token bucket refilled at the sustained
+// rate, and a cap on its concurrent requests.
+type requestLimiter struct {
+	mu      sync.Mutex
+	clients map[string]*clientRequests
+}
+
+type clientRequests struct {
+	inFlight int
+	tokens   float64
+	last     time.Time
+}
+
+func newRequestLimiter() *requestLimiter {
+	return &requestLimiter{
+		clients: make(map[string]*clientRequests),
+	}
+}
+
+// requestClientKey identifies the client a request comes from for
+// the purpose of limiting it. Requests from root are never limited
+// and neither are those for which the peer cannot be identified.
+// Requests coming from a snap, as found from the control group of the
+// peer, are accounted to the snap whatever the uid and socket they
+// come with. Other requests over the snap socket are accounted
+// separately from the ones of the same uid over the main socket.
+func requestClientKey(r *http.Request) (key string, limited bool) {
+	pid, uid, socket, err := ucrednetGet(r.RemoteAddr)
+	if err != nil || uid == 0 {
+		return "", false
+	}
+	if snapName, err := snapNameFromPid(int(pid)); err == nil && snapName != "" {
+		return "snap:" + snapName, true
+	}
+	if socket == dirs.SnapSocket {
+		return fmt.Sprintf("snap:%d", uid), true
+	}
+	return fmt.Sprintf("uid:%d", uid), true
+}
+
+// refill adds the tokens accrued since the last request of the client.
+func (cr *clientRequests) refill(now time.Time, limits requestLimits) {
+	elapsed := now.Sub(cr.last).Seconds()
+	if elapsed > 0 {
+		cr.tokens = math.Min(limits.burst, cr.tokens+elapsed*limits.rate)
+	}
+	cr.last = now
+}
+
+// gc forgets idle clients whose buckets are full again.
+func (l *requestLimiter) gc(now time.Time, limits requestLimits) {
+	for key, cr := range l.clients {
+		if cr.inFlight > 0 {
+			continue
+		}
+		cr.refill(now, limits)
+		if cr.tokens >= limits.burst {
+			delete(l.clients, key)
+		}
+	}
+}
+
+// acquire accounts for a new request of the given client under the
+// given limits. If the client is over its limits it returns how long
+// it should wait before retrying, otherwise it returns a function to
+// call when the request is done.
+func (l *requestLimiter) acquire(key string, limits requestLimits) (release func(), retryAfter time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	limits = limits.withDefaults()
+	now := requestLimiterNow()
+	cr := l.clients[key]
+	if cr == nil {
+		l.gc(now, limits)
+		cr = &clientRequests{tokens: limits.burst, last: now}
+		l.clients[key] = cr
+	}
+	cr.refill(now, limits)
+
+	if cr.inFlight >= limits.maxConcurrent {
+		return nil, time.Second
+	}
+	if cr.tokens < 1 {
+		wait := time.Duration((1 - cr.tokens) / limits.rate * float64(time.Second))
+		if wait < time.Second {
+			wait = time.Second
+		}
+		return nil, wait
+	}
+
+	cr.tokens--
+	cr.inFlight++
+	return func() {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		cr.inFlight--
+	}, 0
+}
+
+// tooManyRequests builds the response for a client over its limits,
+// telling it when to retry.
+func tooManyRequests(w http.ResponseWriter, retryAfter time.Duration) Response {
+	secs := int(math.Ceil(retryAfter.Seconds()))
+	w.Header().Set("Retry-After", strconv.Itoa(secs))
+	return TooManyRequests("too many requests, retry after %ds", secs)
+}
@@ -0,0 +1,241 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2019 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package daemon
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/overlord/auth"
+	"github.com/snapcore/snapd/overlord/configstate/config"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/sandbox/cgroup"
+)
+
+type requestLimiterSuite struct {
+	now time.Time
+}
+
+var _ = check.Suite(&requestLimiterSuite{})
+
+func (s *requestLimiterSuite) SetUpTest(c *check.C) {
+	s.now = time.Date(2019, 7, 1, 10, 0, 0, 0, time.UTC)
+	requestLimiterNow = func() time.Time { return s.now }
+	maxConcurrentRequestsPerClient = 2
+	requestRatePerClient = 2
+	requestBurstPerClient = 4
+	snapNameFromPid = func(pid int) (string, error) {
+		return "", errors.New("not a snap")
+	}
+}
+
+func (s *requestLimiterSuite) TearDownTest(c *check.C) {
+	requestLimiterNow = time.Now
+	maxConcurrentRequestsPerClient = 10
+	requestRatePerClient = 20
+	requestBurstPerClient = 50
+	snapNameFromPid = cgroup.SnapNameFromPid
+}
+
+func (s *requestLimiterSuite) TestConcurrency(c *check.C) {
+	l := newRequestLimiter()
+
+	release1, _ := l.acquire("uid:1000", requestLimits{})
+	c.Assert(release1, check.NotNil)
+	release2, _ := l.acquire("uid:1000", requestLimits{})
+	c.Assert(release2, check.NotNil)
+
+	release3, retryAfter := l.acquire("uid:1000", requestLimits{})
+	c.Check(release3, check.IsNil)
+	c.Check(retryAfter, check.Equals, time.Second)
+
+	// other clients are not affected
+	release4, _ := l.acquire("uid:1001", requestLimits{})
+	c.Check(release4, check.NotNil)
+
+	release1()
+	release3, _ = l.acquire("uid:1000", requestLimits{})
+	c.Check(release3, check.NotNil)
+}
+
+func (s *requestLimiterSuite) TestRate(c *check.C) {
+	l := newRequestLimiter()
+
+	// the burst can be used right away
+	for i := 0; i < 4; i++ {
+		release, _ := l.acquire("uid:1000", requestLimits{})
+		c.Assert(release, check.NotNil, check.Commentf("%d", i))
+		release()
+	}
+	release, retryAfter := l.acquire("uid:1000", requestLimits{})
+	c.Check(release, check.IsNil)
+	c.Check(retryAfter, check.Equals, time.Second)
+
+	// tokens refill at the sustained rate
+	s.now = s.now.Add(500 * time.Millisecond)
+	release, _ = l.acquire("uid:1000", requestLimits{})
+	c.Assert(release, check.NotNil)
+	release()
+	release, _ = l.acquire("uid:1000", requestLimits{})
+	c.Check(release, check.IsNil)
+
+	// a slower rate means longer waits
+	requestRatePerClient = 0.1
+	release, retryAfter = l.acquire("uid:1000", requestLimits{})
+	c.Check(release, check.IsNil)
+	c.Check(retryAfter, check.Equals, 10*time.Second)
+}
+
+func (s *requestLimiterSuite) TestConfiguredLimits(c *check.C) {
+	l := newRequestLimiter()
+	limits := requestLimits{maxConcurrent: 1, rate: 1, burst: 2}
+
+	release1, _ := l.acquire("uid:1000", limits)
+	c.Assert(release1, check.NotNil)
+	release2, retryAfter := l.acquire("uid:1000", limits)
+	c.Check(release2, check.IsNil)
+	c.Check(retryAfter, check.Equals, time.Second)
+	release1()
+
+	release2, _ = l.acquire("uid:1000", limits)
+	c.Assert(release2, check.NotNil)
+	release2()
+	release3, retryAfter := l.acquire("uid:1000", limits)
+	c.Check(release3, check.IsNil)
+	c.Check(retryAfter, check.Equals, time.Second)
+}
+
+func (s *requestLimiterSuite) TestRequestLimitsFromConfig(c *check.C) {
+	st := state.New(nil)
+	st.Lock()
+	defer st.Unlock()
+
+	c.Check(requestLimitsFromConfig(st), check.Equals, requestLimits{})
+
+	tr := config.NewTransaction(st)
+	c.Assert(tr.Set("core", "daemon.api-max-concurrent", 5), check.IsNil)
+	c.Assert(tr.Set("core", "daemon.api-rate", "10"), check.IsNil)
+	c.Assert(tr.Set("core", "daemon.api-burst", "lots"), check.IsNil)
+	tr.Commit()
+
+	c.Check(requestLimitsFromConfig(st), check.Equals, requestLimits{
+		maxConcurrent: 5,
+		rate:          10,
+	})
+}
+
+func (s *requestLimiterSuite) TestIdleClientsForgotten(c *check.C) {
+	l := newRequestLimiter()
+
+	release, _ := l.acquire("uid:1000", requestLimits{})
+	release()
+	inFlight, _ := l.acquire("uid:1001", requestLimits{})
+	c.Assert(inFlight, check.NotNil)
+	c.Check(l.clients, check.HasLen, 2)
+
+	s.now = s.now.Add(time.Minute)
+	release, _ = l.acquire("uid:1002", requestLimits{})
+	release()
+	c.Check(l.clients, check.HasLen, 2)
+	c.Check(l.clients["uid:1000"], check.IsNil)
+	c.Check(l.clients["uid:1001"], check.NotNil)
+}
+
+func (s *requestLimiterSuite) TestRequestClientKey(c *check.C) {
+	snapNameFromPid = func(pid int) (string, error) {
+		if pid == 200 {
+			return "foo", nil
+		}
+		return "", errors.New("not a snap")
+	}
+
+	for _, t := range []struct {
+		remoteAddr string
+		key        string
+		limited    bool
+	}{
+		{"", "", false},
+		{"pid=100;uid=0;socket=;", "", false},
+		{"pid=100;uid=1000;socket=;", "uid:1000", true},
+		{"pid=100;uid=1000;socket=" + dirs.SnapSocket + ";", "snap:1000", true},
+		{"pid=200;uid=1000;socket=;", "snap:foo", true},
+		{"pid=200;uid=1001;socket=" + dirs.SnapSocket + ";", "snap:foo", true},
+		{"pid=200;uid=0;socket=;", "", false},
+	} {
+		req, err := http.NewRequest("GET", "/v2/snaps", nil)
+		c.Assert(err, check.IsNil)
+		req.RemoteAddr = t.remoteAddr
+		key, limited := requestClientKey(req)
+		c.Check(key, check.Equals, t.key, check.Commentf("%q", t.remoteAddr))
+		c.Check(limited, check.Equals, t.limited, check.Commentf("%q", t.remoteAddr))
+	}
+}
+
+func (s *requestLimiterSuite) TestServeHTTPTooManyRequests(c *check.C) {
+	block := make(chan struct{})
+	cmd := &Command{
+		d:      &Daemon{requestLimiter: newRequestLimiter()},
+		UserOK: true,
+	}
+	cmd.d.state = state.New(nil)
+	started := make(chan struct{}, 2)
+	cmd.GET = func(*Command, *http.Request, *auth.UserState) Response {
+		started <- struct{}{}
+		<-block
+		return SyncResponse(nil, nil)
+	}
+
+	serve := func() *httptest.ResponseRecorder {
+		req, err := http.NewRequest("GET", "/v2/snaps", nil)
+		c.Assert(err, check.IsNil)
+		req.RemoteAddr = "pid=100;uid=1000;socket=;"
+		rec := httptest.NewRecorder()
+		cmd.ServeHTTP(rec, req)
+		return rec
+	}
+
+	done := make(chan *httptest.ResponseRecorder, 2)
+	for i := 0; i < 2; i++ {
+		go func() { done <- serve() }()
+		<-started
+	}
+
+	rec := serve()
+	c.Check(rec.Code, check.Equals, 429)
+	c.Check(rec.Header().Get("Retry-After"), check.Equals, "1")
+	var body map[string]interface{}
+	c.Assert(json.Unmarshal(rec.Body.Bytes(), &body), check.IsNil)
+	c.Check(body["result"], check.DeepEquals, map[string]interface{}{
+		"message": "too many requests, retry after 1s",
+		"kind":    "too-many-requests",
+	})
+
+	close(block)
+	for i := 0; i < 2; i++ {
+		c.Check((<-done).Code, check.Equals, 200)
+	}
+	c.Check(serve().Code, check.Equals, 200)
+}
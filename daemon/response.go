@@ -178,6 +178,8 @@ const (
 	errorKindSnapNeedsClassicSystem = errorKind("snap-needs-classic-system")
 	errorKindSnapNotClassic         = errorKind("snap-not-classic")
 
+	errorKindSnapNeedsTermsAccepted = errorKind("snap-needs-terms-accepted")
+
 	errorKindBadQuery = errorKind("bad-query")
 
 	errorKindNetworkTimeout      = errorKind("network-timeout")
@@ -188,6 +190,8 @@ const (
 
 	errorKindDaemonRestart = errorKind("daemon-restart")
 	errorKindSystemRestart = errorKind("system-restart")
+
+	errorKindTooManyRequests = errorKind("too-many-requests")
 )
 
 type errorValue interface{}
@@ -235,8 +239,11 @@ func makeErrorResponder(status int) errorResponder {
 		} else {
 			res.Message = fmt.Sprintf(format, v...)
 		}
-		if status == 401 {
+		switch status {
+		case 401:
 			res.Kind = errorKindLoginRequired
+		case 429:
+			res.Kind = errorKindTooManyRequests
 		}
 		return &resp{
 			Type:   ResponseTypeError,
@@ -393,6 +400,7 @@ var (
 	NotImplemented   = makeErrorResponder(501)
 	Forbidden        = makeErrorResponder(403)
 	Conflict         = makeErrorResponder(409)
+	TooManyRequests  = makeErrorResponder(429)
 )
 
 // SnapNotFound is an error responder used when an operation is
@@ -409,6 +417,29 @@ func SnapNotFound(snapName string, err error) Response {
 	}
 }
 
+// SnapNeedsTermsAccepted is an error responder used when installing a
+// snap requires its license agreement to be accepted first.
+func SnapNeedsTermsAccepted(err *snapstate.SnapNeedsTermsAcceptedError) Response {
+	value := map[string]interface{}{
+		"snap-name": err.Snap,
+	}
+	if err.License != "" {
+		value["license"] = err.License
+	}
+	if err.LicenseVersion != "" {
+		value["license-version"] = err.LicenseVersion
+	}
+	return SyncResponse(&resp{
+		Type: ResponseTypeError,
+		Result: &errorResult{
+			Message: err.Error(),
+			Kind:    errorKindSnapNeedsTermsAccepted,
+			Value:   value,
+		},
+		Status: 400,
+	}, nil)
+}
+
 // SnapRevisionNotAvailable is an error responder used when an
 // operation is requested for which no revivision can be found
 // in the given context (e.g. request an install from a stable
@@ -579,6 +610,8 @@ func errToResponse(err error, snaps []string, fallback func(format string, v ...
 		case *snapstate.SnapNotClassicError:
 			kind = errorKindSnapNotClassic
 			snapName = err.Snap
+		case *snapstate.SnapNeedsTermsAcceptedError:
+			return SnapNeedsTermsAccepted(err)
 		case net.Error:
 			if err.Timeout() {
 				kind = errorKindNetworkTimeout
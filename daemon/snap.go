@@ -27,11 +27,14 @@ import (
 	"sort"
 	"strings"
 
+	"github.com/snapcore/snapd/asserts"
 	"github.com/snapcore/snapd/client"
 	"github.com/snapcore/snapd/cmd"
 	"github.com/snapcore/snapd/logger"
 	"github.com/snapcore/snapd/overlord/assertstate"
+	"github.com/snapcore/snapd/overlord/auth"
 	"github.com/snapcore/snapd/overlord/healthstate"
+	"github.com/snapcore/snapd/overlord/hookstate"
 	"github.com/snapcore/snapd/overlord/snapstate"
 	"github.com/snapcore/snapd/overlord/state"
 	"github.com/snapcore/snapd/snap"
@@ -70,6 +73,62 @@ type aboutSnap struct {
 	info   *snap.Info
 	snapst *snapstate.SnapState
 	health *client.SnapHealth
+	build  *client.SnapBuild
+}
+
+// snapBuild returns the build provenance of the given snap revision
+// if a snap-build assertion for it is known, or nil otherwise.
+func snapBuild(st *state.State, info *snap.Info) *client.SnapBuild {
+	if info.SnapID == "" {
+		return nil
+	}
+	snapBuild, err := assertstate.SnapBuild(st, info.SnapID, info.Revision)
+	if err != nil {
+		if _, ok := err.(*asserts.NotFoundError); !ok {
+			logger.Noticef("cannot get build information for snap %q: %v", info.InstanceName(), err)
+		}
+		return nil
+	}
+	return &client.SnapBuild{
+		Timestamp: snapBuild.Timestamp(),
+		Grade:     snapBuild.Grade(),
+	}
+}
+
+var assertstateFetchSnapBuild = assertstate.FetchSnapBuild
+
+type snapBuildFetchedKey struct {
+	snapID   string
+	revision snap.Revision
+}
+
+// fetchSnapBuild fetches from the store the snap-build assertion of the
+// given snap revision and returns the build provenance it carries, or nil.
+// The store is asked at most once per snap revision while snapd runs.
+func fetchSnapBuild(st *state.State, info *snap.Info, user *auth.UserState) *client.SnapBuild {
+	if info.SnapID == "" {
+		return nil
+	}
+	key := snapBuildFetchedKey{snapID: info.SnapID, revision: info.Revision}
+
+	st.Lock()
+	defer st.Unlock()
+	if st.Cached(key) != nil {
+		return nil
+	}
+	st.Cache(key, true)
+
+	userID := 0
+	if user != nil {
+		userID = user.ID
+	}
+	if err := assertstateFetchSnapBuild(st, info.SnapID, info.Revision, userID); err != nil {
+		if !asserts.IsNotFound(err) {
+			logger.Debugf("cannot fetch build information for snap %q: %v", info.InstanceName(), err)
+		}
+		return nil
+	}
+	return snapBuild(st, info)
 }
 
 func clientHealthFromHealthstate(h *healthstate.HealthState) *client.SnapHealth {
@@ -118,6 +177,7 @@ func localSnapInfo(st *state.State, name string) (aboutSnap, error) {
 		info:   info,
 		snapst: &snapst,
 		health: clientHealthFromHealthstate(health),
+		build:  snapBuild(st, info),
 	}, nil
 }
 
@@ -164,13 +224,13 @@ func allLocalSnapInfos(st *state.State, all bool, wanted map[string]bool) ([]abo
 				if err != nil && firstErr == nil {
 					firstErr = err
 				}
-				aboutThis = append(aboutThis, aboutSnap{info, snapst, health})
+				aboutThis = append(aboutThis, aboutSnap{info, snapst, health, snapBuild(st, info)})
 			}
 		} else {
 			info, err = snapst.CurrentInfo()
 			if err == nil {
 				info.Publisher, err = publisherAccount(st, info.SnapID)
-				aboutThis = append(aboutThis, aboutSnap{info, snapst, health})
+				aboutThis = append(aboutThis, aboutSnap{info, snapst, health, snapBuild(st, info)})
 			}
 		}
 
@@ -318,10 +378,56 @@ func mapLocal(about aboutSnap) *client.Snap {
 		result.MountedFrom, _ = os.Readlink(result.MountedFrom)
 	}
 	result.Health = about.health
+	result.Build = about.build
+	result.Hooks = mapHooks(localSnap)
+	result.DisabledServices = disabledServices(result.Apps)
 
 	return result
 }
 
+// disabledServices returns the names of the services that systemd
+// reports as disabled, as queried when mapping the apps of an active
+// snap.
+func disabledServices(apps []client.AppInfo) []string {
+	var disabled []string
+	for _, app := range apps {
+		if app.IsService() && !app.Enabled {
+			disabled = append(disabled, app.Name)
+		}
+	}
+	return disabled
+}
+
+func mapHooks(info *snap.Info) []client.HookInfo {
+	if len(info.Hooks) == 0 {
+		return nil
+	}
+	hooks := make([]client.HookInfo, 0, len(info.Hooks))
+	for _, hook := range info.Hooks {
+		h := client.HookInfo{
+			Name:    hook.Name,
+			Timeout: hookstate.HookTimeout(hook.Name).String(),
+		}
+		for plugName := range hook.Plugs {
+			h.Plugs = append(h.Plugs, plugName)
+		}
+		for slotName := range hook.Slots {
+			h.Slots = append(h.Slots, slotName)
+		}
+		sort.Strings(h.Plugs)
+		sort.Strings(h.Slots)
+		hooks = append(hooks, h)
+	}
+	sort.Sort(byHookName(hooks))
+	return hooks
+}
+
+type byHookName []client.HookInfo
+
+func (b byHookName) Len() int           { return len(b) }
+func (b byHookName) Swap(i, j int)      { b[i], b[j] = b[j], b[i] }
+func (b byHookName) Less(i, j int) bool { return b[i].Name < b[j].Name }
+
 func mapRemote(remoteSnap *snap.Info) *client.Snap {
 	result, err := cmd.ClientSnapFromSnapInfo(remoteSnap)
 	if err != nil {
@@ -89,6 +89,8 @@ var (
 	SnapDesktopFilesDir string
 	SnapBusPolicyDir    string
 
+	SnapDesktopCacheGenerationFile string
+
 	SystemApparmorDir      string
 	SystemApparmorCacheDir string
 
@@ -109,6 +111,7 @@ var (
 
 	FreezerCgroupDir string
 	PidsCgroupDir    string
+	SystemdCgroupDir string
 
 	SnapshotsDir string
 
@@ -232,6 +235,7 @@ func SetRootDir(rootdir string) {
 	SnapMetaDir = filepath.Join(rootdir, snappyDir, "meta")
 	SnapBlobDir = filepath.Join(rootdir, snappyDir, "snaps")
 	SnapDesktopFilesDir = filepath.Join(rootdir, snappyDir, "desktop", "applications")
+	SnapDesktopCacheGenerationFile = filepath.Join(rootdir, snappyDir, "desktop", "cache-generation")
 	SnapRunDir = filepath.Join(rootdir, "/run/snapd")
 	SnapRunNsDir = filepath.Join(SnapRunDir, "/ns")
 	SnapRunLockDir = filepath.Join(SnapRunDir, "/lock")
@@ -325,6 +329,7 @@ func SetRootDir(rootdir string) {
 
 	FreezerCgroupDir = filepath.Join(rootdir, "/sys/fs/cgroup/freezer/")
 	PidsCgroupDir = filepath.Join(rootdir, "/sys/fs/cgroup/pids/")
+	SystemdCgroupDir = filepath.Join(rootdir, "/sys/fs/cgroup/systemd/")
 	SnapshotsDir = filepath.Join(rootdir, snappyDir, "snapshots")
 
 	ErrtrackerDbDir = filepath.Join(rootdir, snappyDir, "errtracker.db")
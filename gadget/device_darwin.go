@@ -31,3 +31,7 @@ func FindDeviceForStructure(ps *PositionedStructure) (string, error) {
 func FindMountPointForStructure(ps *PositionedStructure) (string, error) {
 	return "", errNotImplemented
 }
+
+func FindDeviceForStructureWithFallback(ps *PositionedStructure) (dev string, offs Size, err error) {
+	return "", 0, errNotImplemented
+}
@@ -25,6 +25,7 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"reflect"
 	"regexp"
 	"sort"
 	"strconv"
@@ -56,6 +57,7 @@ var (
 	validVolumeName = regexp.MustCompile("^[a-zA-Z0-9][a-zA-Z0-9-]+$")
 	validTypeID     = regexp.MustCompile("^[0-9A-F]{2}$")
 	validGUUID      = regexp.MustCompile("^(?i)[0-9A-F]{8}-[0-9A-F]{4}-[0-9A-F]{4}-[0-9A-F]{4}-[0-9A-F]{12}$")
+	validActionName = regexp.MustCompile("^[a-z0-9](?:-?[a-z0-9])*$")
 )
 
 type Info struct {
@@ -65,6 +67,15 @@ type Info struct {
 	Defaults map[string]map[string]interface{} `yaml:"defaults,omitempty"`
 
 	Connections []Connection `yaml:"connections"`
+
+	// Actions are the maintenance actions of the device, keyed by
+	// name, implemented by the action-<name> hooks of the gadget.
+	Actions map[string]Action `yaml:"actions,omitempty"`
+
+	// Provisioning are the device-specific steps run before the
+	// device registers, keyed by name, implemented by the
+	// task-<name> hooks of the gadget.
+	Provisioning map[string]ProvisioningStep `yaml:"provisioning,omitempty"`
 }
 
 // Volume defines the structure and content for the image to be written into a
@@ -193,13 +204,57 @@ type VolumeUpdate struct {
 //
 //  plug: (<plug-snap-id>|system):plug
 //  [slot: (<slot-snap-id>|system):slot]
+//  [slot-attributes:
+//    <attr>: <value>]
 //
 // "system" indicates a system plug or slot.
 // Fully omitting the slot part indicates a system slot with the same name
 // as the plug.
+// slot-attributes optionally lists attribute values the slot is
+// expected to have for the connection to be made.
 type Connection struct {
-	Plug ConnectionPlug `yaml:"plug"`
-	Slot ConnectionSlot `yaml:"slot"`
+	Plug           ConnectionPlug         `yaml:"plug"`
+	Slot           ConnectionSlot         `yaml:"slot"`
+	SlotAttributes map[string]interface{} `yaml:"slot-attributes"`
+}
+
+// CheckSlotAttributes checks that the given slot attributes have the
+// values required by the connection.
+func (gconn *Connection) CheckSlotAttributes(attrs map[string]interface{}) error {
+	names := make([]string, 0, len(gconn.SlotAttributes))
+	for name := range gconn.SlotAttributes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		expected := gconn.SlotAttributes[name]
+		v, ok := attrs[name]
+		if !ok {
+			return fmt.Errorf("slot attribute %q is not set, expected %v", name, expected)
+		}
+		if !reflect.DeepEqual(v, expected) {
+			return fmt.Errorf("slot attribute %q is %v, expected %v", name, v, expected)
+		}
+	}
+	return nil
+}
+
+// Action describes a named maintenance action of the device, for
+// example blinking a LED or exporting diagnostics, that is implemented
+// by the action-<name> hook of the gadget. Actions are declared under
+// "actions" keyed by name, with an optional summary.
+type Action struct {
+	Summary string `yaml:"summary"`
+}
+
+// ProvisioningStep describes a named provisioning step of the device,
+// for example setting up a modem, run as part of the registration of
+// the device by the task-<name> hook of the gadget. With undo set, the
+// task-<name>-undo hook of the gadget reverts the step when the
+// registration fails.
+type ProvisioningStep struct {
+	Summary string `yaml:"summary"`
+	Undo    bool   `yaml:"undo"`
 }
 
 type ConnectionPlug struct {
@@ -306,6 +361,25 @@ func ReadInfo(gadgetSnapRootDir string, classic bool) (*Info, error) {
 			gi.Connections[i].Slot.SnapID = "system"
 			gi.Connections[i].Slot.Slot = gconn.Plug.Plug
 		}
+		for k, v := range gconn.SlotAttributes {
+			attr, err := metautil.NormalizeValue(v)
+			if err != nil {
+				return nil, fmt.Errorf("gadget connection slot attribute %q: %v", k, err)
+			}
+			gconn.SlotAttributes[k] = attr
+		}
+	}
+
+	for name := range gi.Actions {
+		if !validActionName.MatchString(name) {
+			return nil, fmt.Errorf("invalid gadget action name %q", name)
+		}
+	}
+
+	for name := range gi.Provisioning {
+		if !validActionName.MatchString(name) {
+			return nil, fmt.Errorf("invalid gadget provisioning step name %q", name)
+		}
 	}
 
 	if classic && len(gi.Volumes) == 0 {
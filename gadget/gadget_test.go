@@ -441,6 +441,135 @@ connections:
 	}
 }
 
+func (s *gadgetYamlTestSuite) TestReadGadgetYamlConnectionSlotAttributes(c *C) {
+	err := ioutil.WriteFile(s.gadgetYamlPath, []byte(`
+connections:
+  - plug: snapid1:plg1
+    slot: snapid2:slot
+    slot-attributes:
+      path: /dev/ttyS0
+      usb-vendor: 123
+      extra:
+        - a
+        - b
+`), 0644)
+	c.Assert(err, IsNil)
+
+	ginfo, err := gadget.ReadInfo(s.dir, true)
+	c.Assert(err, IsNil)
+	c.Check(ginfo.Connections, DeepEquals, []gadget.Connection{
+		{
+			Plug: gadget.ConnectionPlug{SnapID: "snapid1", Plug: "plg1"},
+			Slot: gadget.ConnectionSlot{SnapID: "snapid2", Slot: "slot"},
+			SlotAttributes: map[string]interface{}{
+				"path":       "/dev/ttyS0",
+				"usb-vendor": int64(123),
+				"extra":      []interface{}{"a", "b"},
+			},
+		},
+	})
+}
+
+func (s *gadgetYamlTestSuite) TestReadGadgetYamlConnectionSlotAttributesInvalid(c *C) {
+	err := ioutil.WriteFile(s.gadgetYamlPath, []byte(`
+connections:
+  - plug: snapid1:plg1
+    slot-attributes:
+      path:
+`), 0644)
+	c.Assert(err, IsNil)
+
+	_, err = gadget.ReadInfo(s.dir, true)
+	c.Check(err, ErrorMatches, `gadget connection slot attribute "path": invalid scalar: <nil>`)
+}
+
+func (s *gadgetYamlTestSuite) TestReadGadgetYamlActions(c *C) {
+	err := ioutil.WriteFile(s.gadgetYamlPath, []byte(`
+actions:
+  blink-led:
+    summary: Blink the identification LED
+  export-diagnostics:
+`), 0644)
+	c.Assert(err, IsNil)
+
+	ginfo, err := gadget.ReadInfo(s.dir, true)
+	c.Assert(err, IsNil)
+	c.Check(ginfo.Actions, DeepEquals, map[string]gadget.Action{
+		"blink-led":          {Summary: "Blink the identification LED"},
+		"export-diagnostics": {},
+	})
+}
+
+func (s *gadgetYamlTestSuite) TestReadGadgetYamlActionsInvalidName(c *C) {
+	err := ioutil.WriteFile(s.gadgetYamlPath, []byte(`
+actions:
+  Blink_LED:
+    summary: Blink the identification LED
+`), 0644)
+	c.Assert(err, IsNil)
+
+	_, err = gadget.ReadInfo(s.dir, true)
+	c.Check(err, ErrorMatches, `invalid gadget action name "Blink_LED"`)
+}
+
+func (s *gadgetYamlTestSuite) TestReadGadgetYamlProvisioning(c *C) {
+	err := ioutil.WriteFile(s.gadgetYamlPath, []byte(`
+provisioning:
+  setup-modem:
+    summary: Set up the modem
+    undo: true
+  calibrate:
+`), 0644)
+	c.Assert(err, IsNil)
+
+	ginfo, err := gadget.ReadInfo(s.dir, true)
+	c.Assert(err, IsNil)
+	c.Check(ginfo.Provisioning, DeepEquals, map[string]gadget.ProvisioningStep{
+		"setup-modem": {Summary: "Set up the modem", Undo: true},
+		"calibrate":   {},
+	})
+}
+
+func (s *gadgetYamlTestSuite) TestReadGadgetYamlProvisioningInvalidName(c *C) {
+	err := ioutil.WriteFile(s.gadgetYamlPath, []byte(`
+provisioning:
+  Setup_Modem:
+`), 0644)
+	c.Assert(err, IsNil)
+
+	_, err = gadget.ReadInfo(s.dir, true)
+	c.Check(err, ErrorMatches, `invalid gadget provisioning step name "Setup_Modem"`)
+}
+
+func (s *gadgetYamlTestSuite) TestConnectionCheckSlotAttributes(c *C) {
+	gconn := &gadget.Connection{
+		SlotAttributes: map[string]interface{}{
+			"path":       "/dev/ttyS0",
+			"usb-vendor": int64(123),
+		},
+	}
+
+	err := gconn.CheckSlotAttributes(map[string]interface{}{
+		"path":       "/dev/ttyS0",
+		"usb-vendor": int64(123),
+		"other":      "value",
+	})
+	c.Check(err, IsNil)
+
+	err = gconn.CheckSlotAttributes(map[string]interface{}{
+		"path": "/dev/ttyS0",
+	})
+	c.Check(err, ErrorMatches, `slot attribute "usb-vendor" is not set, expected 123`)
+
+	err = gconn.CheckSlotAttributes(map[string]interface{}{
+		"path":       "/dev/ttyS1",
+		"usb-vendor": int64(123),
+	})
+	c.Check(err, ErrorMatches, `slot attribute "path" is /dev/ttyS1, expected /dev/ttyS0`)
+
+	c.Check((&gadget.Connection{}).CheckSlotAttributes(nil), IsNil)
+}
+
 func (s *gadgetYamlTestSuite) TestReadGadgetYamlVolumeUpdate(c *C) {
 	err := ioutil.WriteFile(s.gadgetYamlPath, mockVolumeUpdateGadgetYaml, 0644)
 	c.Assert(err, IsNil)
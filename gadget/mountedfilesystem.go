@@ -29,6 +29,7 @@ import (
 	"path/filepath"
 	"sort"
 	"strings"
+	"syscall"
 
 	"github.com/snapcore/snapd/logger"
 	"github.com/snapcore/snapd/osutil"
@@ -181,17 +182,36 @@ func writeFile(src, dst string, preserveInDst []string) error {
 		return fmt.Errorf("cannot create prefix directory: %v", err)
 	}
 
-	// overwrite & sync by default
-	copyFlags := osutil.CopyFlagOverwrite | osutil.CopyFlagSync
-
-	// TODO use osutil.AtomicFile
 	// TODO try to preserve ownership and permission bits
-	if err := osutil.CopyFile(src, dst, copyFlags); err != nil {
+	if err := stageAndCopyFile(src, dst); err != nil {
 		return fmt.Errorf("cannot copy %s: %v", src, err)
 	}
 	return nil
 }
 
+// stageAndCopyFile writes the source file to a staging file next to the
+// destination and renames it over the destination once the data was synced, so
+// that an interrupted write never leaves a partially written asset behind.
+func stageAndCopyFile(src, dst string) error {
+	if osutil.IsDirectory(dst) {
+		// the staging file would not be renamed over a directory
+		return fmt.Errorf("unable to create %s: %v", dst, &os.PathError{Op: "open", Path: dst, Err: syscall.EISDIR})
+	}
+
+	in, err := os.Open(src)
+	if err != nil {
+		return fmt.Errorf("unable to open %s: %v", src, err)
+	}
+	defer in.Close()
+
+	fi, err := in.Stat()
+	if err != nil {
+		return fmt.Errorf("unable to stat %s: %v", src, err)
+	}
+
+	return osutil.AtomicWrite(dst, in, fi.Mode().Perm(), 0)
+}
+
 func (m *MountedFilesystemWriter) writeVolumeContent(volumeRoot string, content *VolumeContent, preserveInDst []string) error {
 	if err := checkContent(content); err != nil {
 		return err
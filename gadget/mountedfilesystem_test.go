@@ -21,6 +21,7 @@ package gadget_test
 import (
 	"errors"
 	"fmt"
+	"io/ioutil"
 	"os"
 	"path/filepath"
 	"strings"
@@ -143,6 +144,26 @@ func (s *mountedfilesystemTestSuite) TestWriteFile(c *C) {
 	c.Assert(err, ErrorMatches, "cannot copy .*: unable to open .*/not-found: .* no such file or directory")
 }
 
+func (s *mountedfilesystemTestSuite) TestWriteFileStaged(c *C) {
+	makeSizedFile(c, filepath.Join(s.dir, "grubx64.efi"), 0, []byte("new grub"))
+	err := os.Chmod(filepath.Join(s.dir, "grubx64.efi"), 0600)
+	c.Assert(err, IsNil)
+
+	outDir := c.MkDir()
+	makeSizedFile(c, filepath.Join(outDir, "EFI/boot/grubx64.efi"), 0, []byte("old grub"))
+
+	err = gadget.WriteFile(filepath.Join(s.dir, "grubx64.efi"), filepath.Join(outDir, "EFI/boot")+"/", nil)
+	c.Assert(err, IsNil)
+	c.Check(filepath.Join(outDir, "EFI/boot/grubx64.efi"), testutil.FileEquals, []byte("new grub"))
+
+	// the staging file was renamed over the destination
+	fis, err := ioutil.ReadDir(filepath.Join(outDir, "EFI/boot"))
+	c.Assert(err, IsNil)
+	c.Assert(fis, HasLen, 1)
+	c.Check(fis[0].Name(), Equals, "grubx64.efi")
+	c.Check(fis[0].Mode().Perm(), Equals, os.FileMode(0600))
+}
+
 func (s *mountedfilesystemTestSuite) TestWriteDirectoryContents(c *C) {
 	gd := []gadgetData{
 		{name: "boot-assets/splash", target: "splash", content: "splash"},
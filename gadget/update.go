@@ -56,46 +56,92 @@ type GadgetData struct {
 // Data that would be modified during the update is first backed up inside the
 // rollback directory. Should the apply step fail, the modified data is
 // recovered.
-func Update(old, new GadgetData, rollbackDirPath string) error {
-	oldVol, newVol, err := resolveVolume(old.Info, new.Info)
+//
+// The optional observer is notified once the backup is complete and before
+// any data is written, as well as when the update is rolled back.
+func Update(old, new GadgetData, rollbackDirPath string, observer UpdateObserver) error {
+	updates, err := prepareUpdate(old, new)
+	if err != nil {
+		return err
+	}
+	return applyUpdates(new, updates, rollbackDirPath, observer)
+}
+
+// Rollback restores the data modified by an earlier successful Update
+// between the same gadget revisions, using the backup copies kept in
+// the rollback directory. The optional observer is notified once the
+// data was restored, as when an update fails.
+func Rollback(old, new GadgetData, rollbackDirPath string, observer UpdateObserver) error {
+	updates, err := prepareUpdate(old, new)
 	if err != nil {
 		return err
 	}
 
+	var firstErr error
+	for _, one := range updates {
+		up, err := updaterForStructure(one.to, new.RootDir, rollbackDirPath)
+		if err == nil {
+			err = up.Rollback()
+		}
+		if err != nil {
+			logger.Noticef("cannot rollback volume structure %v update: %v", one.to, err)
+			if firstErr == nil {
+				firstErr = fmt.Errorf("cannot rollback volume structure %v update: %v", one.to, err)
+			}
+		}
+	}
+
+	if observer != nil {
+		if err := observer.Canceled(); err != nil {
+			logger.Noticef("cannot observe canceled update: %v", err)
+		}
+	}
+
+	return firstErr
+}
+
+// prepareUpdate finds the structures updated between the given gadget
+// revisions, checking that the update is possible.
+func prepareUpdate(old, new GadgetData) ([]updatePair, error) {
+	oldVol, newVol, err := resolveVolume(old.Info, new.Info)
+	if err != nil {
+		return nil, err
+	}
+
 	// layout old
 	pOld, err := PositionVolume(old.RootDir, oldVol, defaultConstraints)
 	if err != nil {
-		return fmt.Errorf("cannot lay out the old volume: %v", err)
+		return nil, fmt.Errorf("cannot lay out the old volume: %v", err)
 	}
 
 	// layout new
 	pNew, err := PositionVolume(new.RootDir, newVol, defaultConstraints)
 	if err != nil {
-		return fmt.Errorf("cannot lay out the new volume: %v", err)
+		return nil, fmt.Errorf("cannot lay out the new volume: %v", err)
 	}
 
 	if err := canUpdateVolume(pOld, pNew); err != nil {
-		return fmt.Errorf("cannot apply update to volume: %v", err)
+		return nil, fmt.Errorf("cannot apply update to volume: %v", err)
 	}
 
 	// now we know which structure is which, find which ones need an update
 	updates, err := resolveUpdate(pOld, pNew)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	if len(updates) == 0 {
 		// nothing to update
-		return ErrNoUpdate
+		return nil, ErrNoUpdate
 	}
 
 	// can update old layout to new layout
 	for _, update := range updates {
 		if err := canUpdateStructure(update.from, update.to); err != nil {
-			return fmt.Errorf("cannot update volume structure %v: %v", update.to, err)
+			return nil, fmt.Errorf("cannot update volume structure %v: %v", update.to, err)
 		}
 	}
 
-	return applyUpdates(new, updates, rollbackDirPath)
+	return updates, nil
 }
 
 func resolveVolume(old *Info, new *Info) (oldVol, newVol *Volume, err error) {
@@ -239,24 +285,37 @@ type Updater interface {
 	Rollback() error
 }
 
-type nopUpdater struct{}
-
-func (n *nopUpdater) Update() error {
-	return nil
-}
-
-func (n *nopUpdater) Backup() error {
-	return nil
+// UpdateObserver is notified about the progress of an update of gadget assets,
+// such as the bootloader binaries. It allows, for instance, to reseal the disk
+// encryption keys to both the current and the updated boot assets before the
+// latter are written, and to undo that if the update is rolled back.
+type UpdateObserver interface {
+	// BeforeWrite is called once the data modified by the update has been
+	// backed up, but before any of the update is written
+	BeforeWrite() error
+	// Canceled is called when the update failed and the modified data was
+	// restored from the backup copies
+	Canceled() error
 }
 
-func (n *nopUpdater) Rollback() error {
-	return nil
+func updaterForStructureImpl(ps *PositionedStructure, newRootDir, rollbackDir string) (Updater, error) {
+	if ps.IsBare() {
+		updater, err := NewRawStructureUpdater(newRootDir, ps, rollbackDir, FindDeviceForStructureWithFallback)
+		if err != nil {
+			return nil, err
+		}
+		return updater, nil
+	}
+	updater, err := NewMountedFilesystemUpdater(newRootDir, ps, rollbackDir, FindMountPointForStructure)
+	if err != nil {
+		return nil, err
+	}
+	return updater, nil
 }
 
-// TODO: replace placeholder implementation with real one
-var updaterForStructure = func(_ *PositionedStructure, rootDir, rollbackDir string) (Updater, error) { return &nopUpdater{}, nil }
+var updaterForStructure = updaterForStructureImpl
 
-func applyUpdates(new GadgetData, updates []updatePair, rollbackDir string) error {
+func applyUpdates(new GadgetData, updates []updatePair, rollbackDir string, observer UpdateObserver) error {
 	updaters := make([]Updater, len(updates))
 
 	for i, one := range updates {
@@ -273,6 +332,13 @@ func applyUpdates(new GadgetData, updates []updatePair, rollbackDir string) erro
 		}
 	}
 
+	if observer != nil {
+		// nothing has been written yet, no need to rollback
+		if err := observer.BeforeWrite(); err != nil {
+			return fmt.Errorf("cannot observe prepared update: %v", err)
+		}
+	}
+
 	var updateErr error
 	var updateLastAttempted int
 	for i, one := range updaters {
@@ -298,5 +364,11 @@ func applyUpdates(new GadgetData, updates []updatePair, rollbackDir string) erro
 		}
 	}
 
+	if observer != nil {
+		if err := observer.Canceled(); err != nil {
+			logger.Noticef("cannot observe canceled update: %v", err)
+		}
+	}
+
 	return updateErr
 }
@@ -604,6 +604,22 @@ func (m *mockUpdater) Update() error {
 	return callOrNil(m.updateCb)
 }
 
+type mockUpdateObserver struct {
+	beforeWriteCalls int
+	canceledCalls    int
+	beforeWriteErr   error
+}
+
+func (m *mockUpdateObserver) BeforeWrite() error {
+	m.beforeWriteCalls++
+	return m.beforeWriteErr
+}
+
+func (m *mockUpdateObserver) Canceled() error {
+	m.canceledCalls++
+	return errors.New("canceled failed")
+}
+
 func updateDataSet(c *C) (oldData gadget.GadgetData, newData gadget.GadgetData, rollbackDir string) {
 	// prepare the stage
 	bareStruct := gadget.VolumeStructure{
@@ -723,7 +739,7 @@ func (u *updateTestSuite) TestUpdateApplyHappy(c *C) {
 	defer restore()
 
 	// go go go
-	err := gadget.Update(oldData, newData, rollbackDir)
+	err := gadget.Update(oldData, newData, rollbackDir, nil)
 	c.Assert(err, IsNil)
 	c.Assert(backupCalls, DeepEquals, map[string]bool{
 		"first":  true,
@@ -772,7 +788,7 @@ func (u *updateTestSuite) TestUpdateApplyOnlyWhenNeeded(c *C) {
 	defer restore()
 
 	// go go go
-	err := gadget.Update(oldData, newData, rollbackDir)
+	err := gadget.Update(oldData, newData, rollbackDir, nil)
 	c.Assert(err, IsNil)
 }
 
@@ -815,13 +831,13 @@ func (u *updateTestSuite) TestUpdateApplyErrorPosition(c *C) {
 	rollbackDir := c.MkDir()
 
 	// cannot position the old volume without bare struct data
-	err := gadget.Update(oldData, newData, rollbackDir)
+	err := gadget.Update(oldData, newData, rollbackDir, nil)
 	c.Assert(err, ErrorMatches, `cannot lay out the old volume: cannot position structure #0 \("foo"\): content "first.img": .* no such file or directory`)
 
 	makeSizedFile(c, filepath.Join(oldRootDir, "first.img"), gadget.SizeMiB, nil)
 
 	// cannot position the new volume
-	err = gadget.Update(oldData, newData, rollbackDir)
+	err = gadget.Update(oldData, newData, rollbackDir, nil)
 	c.Assert(err, ErrorMatches, `cannot lay out the new volume: cannot position structure #0 \("foo"\): content "first.img": .* no such file or directory`)
 }
 
@@ -868,7 +884,7 @@ func (u *updateTestSuite) TestUpdateApplyErrorIllegalVolumeUpdate(c *C) {
 	makeSizedFile(c, filepath.Join(oldRootDir, "first.img"), gadget.SizeMiB, nil)
 	makeSizedFile(c, filepath.Join(newRootDir, "first.img"), 900*gadget.SizeKiB, nil)
 
-	err := gadget.Update(oldData, newData, rollbackDir)
+	err := gadget.Update(oldData, newData, rollbackDir, nil)
 	c.Assert(err, ErrorMatches, `cannot apply update to volume: cannot change the number of structures within volume from 1 to 2`)
 }
 
@@ -920,7 +936,7 @@ func (u *updateTestSuite) TestUpdateApplyErrorIllegalStructureUpdate(c *C) {
 
 	makeSizedFile(c, filepath.Join(oldRootDir, "first.img"), gadget.SizeMiB, nil)
 
-	err := gadget.Update(oldData, newData, rollbackDir)
+	err := gadget.Update(oldData, newData, rollbackDir, nil)
 	c.Assert(err, ErrorMatches, `cannot update volume structure #0 \("foo"\): cannot change a bare structure to filesystem one`)
 }
 
@@ -959,7 +975,7 @@ func (u *updateTestSuite) TestUpdateApplyErrorDifferentVolume(c *C) {
 	})
 	defer restore()
 
-	err := gadget.Update(oldData, newData, rollbackDir)
+	err := gadget.Update(oldData, newData, rollbackDir, nil)
 	c.Assert(err, ErrorMatches, `cannot find entry for volume "foo" in updated gadget info`)
 }
 
@@ -1003,7 +1019,7 @@ func (u *updateTestSuite) TestUpdateApplyUpdatesAreOptIn(c *C) {
 	})
 	defer restore()
 
-	err := gadget.Update(oldData, newData, rollbackDir)
+	err := gadget.Update(oldData, newData, rollbackDir, nil)
 	c.Assert(err, Equals, gadget.ErrNoUpdate)
 }
 
@@ -1038,7 +1054,7 @@ func (u *updateTestSuite) TestUpdateApplyBackupFails(c *C) {
 	defer restore()
 
 	// go go go
-	err := gadget.Update(oldData, newData, rollbackDir)
+	err := gadget.Update(oldData, newData, rollbackDir, nil)
 	c.Assert(err, ErrorMatches, `cannot backup volume structure #1 \("second"\): failed`)
 }
 
@@ -1082,7 +1098,7 @@ func (u *updateTestSuite) TestUpdateApplyUpdateFailsThenRollback(c *C) {
 	defer restore()
 
 	// go go go
-	err := gadget.Update(oldData, newData, rollbackDir)
+	err := gadget.Update(oldData, newData, rollbackDir, nil)
 	c.Assert(err, ErrorMatches, `cannot update volume structure #1 \("second"\): failed`)
 	c.Assert(backupCalls, DeepEquals, map[string]bool{
 		// all were backed up
@@ -1153,7 +1169,7 @@ func (u *updateTestSuite) TestUpdateApplyUpdateErrorRollbackFail(c *C) {
 	defer restore()
 
 	// go go go
-	err := gadget.Update(oldData, newData, rollbackDir)
+	err := gadget.Update(oldData, newData, rollbackDir, nil)
 	// preserves update error
 	c.Assert(err, ErrorMatches, `cannot update volume structure #2 \("third"\): update error`)
 	c.Assert(backupCalls, DeepEquals, map[string]bool{
@@ -1190,6 +1206,189 @@ func (u *updateTestSuite) TestUpdateApplyBadUpdater(c *C) {
 	defer restore()
 
 	// go go go
-	err := gadget.Update(oldData, newData, rollbackDir)
+	err := gadget.Update(oldData, newData, rollbackDir, nil)
 	c.Assert(err, ErrorMatches, `cannot prepare update for volume structure #0 \("first"\): bad updater for structure`)
 }
+
+func (u *updateTestSuite) TestUpdateApplyObserver(c *C) {
+	oldData, newData, rollbackDir := updateDataSet(c)
+	newData.Info.Volumes["foo"].Structure[1].Update.Edition = 1
+
+	observer := &mockUpdateObserver{}
+	updated := false
+	restore := gadget.MockUpdaterForStructure(func(ps *gadget.PositionedStructure, psRootDir, psRollbackDir string) (gadget.Updater, error) {
+		return &mockUpdater{
+			backupCb: func() error {
+				c.Check(observer.beforeWriteCalls, Equals, 0)
+				return nil
+			},
+			updateCb: func() error {
+				// the observer is notified before any writes
+				c.Check(observer.beforeWriteCalls, Equals, 1)
+				updated = true
+				return nil
+			},
+		}, nil
+	})
+	defer restore()
+
+	err := gadget.Update(oldData, newData, rollbackDir, observer)
+	c.Assert(err, IsNil)
+	c.Check(updated, Equals, true)
+	c.Check(observer.beforeWriteCalls, Equals, 1)
+	c.Check(observer.canceledCalls, Equals, 0)
+}
+
+func (u *updateTestSuite) TestUpdateApplyObserverBeforeWriteErr(c *C) {
+	oldData, newData, rollbackDir := updateDataSet(c)
+	newData.Info.Volumes["foo"].Structure[1].Update.Edition = 1
+
+	observer := &mockUpdateObserver{beforeWriteErr: errors.New("cannot reseal")}
+	restore := gadget.MockUpdaterForStructure(func(ps *gadget.PositionedStructure, psRootDir, psRollbackDir string) (gadget.Updater, error) {
+		return &mockUpdater{
+			updateCb: func() error {
+				c.Fatalf("unexpected call")
+				return nil
+			},
+			rollbackCb: func() error {
+				c.Fatalf("unexpected call")
+				return nil
+			},
+		}, nil
+	})
+	defer restore()
+
+	err := gadget.Update(oldData, newData, rollbackDir, observer)
+	c.Assert(err, ErrorMatches, "cannot observe prepared update: cannot reseal")
+	c.Check(observer.canceledCalls, Equals, 0)
+}
+
+func (u *updateTestSuite) TestUpdateApplyObserverCanceled(c *C) {
+	logbuf, restore := logger.MockLogger()
+	defer restore()
+
+	oldData, newData, rollbackDir := updateDataSet(c)
+	newData.Info.Volumes["foo"].Structure[1].Update.Edition = 1
+
+	observer := &mockUpdateObserver{}
+	rolledBack := false
+	restore = gadget.MockUpdaterForStructure(func(ps *gadget.PositionedStructure, psRootDir, psRollbackDir string) (gadget.Updater, error) {
+		return &mockUpdater{
+			updateCb: func() error {
+				return errors.New("failed")
+			},
+			rollbackCb: func() error {
+				rolledBack = true
+				return nil
+			},
+		}, nil
+	})
+	defer restore()
+
+	err := gadget.Update(oldData, newData, rollbackDir, observer)
+	c.Assert(err, ErrorMatches, `cannot update volume structure #1 \("second"\): failed`)
+	c.Check(rolledBack, Equals, true)
+	c.Check(observer.beforeWriteCalls, Equals, 1)
+	c.Check(observer.canceledCalls, Equals, 1)
+	c.Check(logbuf.String(), testutil.Contains, "cannot observe canceled update: canceled failed")
+}
+
+func (u *updateTestSuite) TestRollbackHappy(c *C) {
+	oldData, newData, rollbackDir := updateDataSet(c)
+	newData.Info.Volumes["foo"].Structure[0].Update.Edition = 1
+	newData.Info.Volumes["foo"].Structure[2].Update.Edition = 1
+
+	observer := &mockUpdateObserver{}
+	var rolledBack []string
+	restore := gadget.MockUpdaterForStructure(func(ps *gadget.PositionedStructure, psRootDir, psRollbackDir string) (gadget.Updater, error) {
+		c.Check(psRootDir, Equals, newData.RootDir)
+		c.Check(psRollbackDir, Equals, rollbackDir)
+		return &mockUpdater{
+			backupCb: func() error {
+				c.Fatalf("unexpected call")
+				return nil
+			},
+			updateCb: func() error {
+				c.Fatalf("unexpected call")
+				return nil
+			},
+			rollbackCb: func() error {
+				rolledBack = append(rolledBack, ps.Name)
+				return nil
+			},
+		}, nil
+	})
+	defer restore()
+
+	err := gadget.Rollback(oldData, newData, rollbackDir, observer)
+	c.Assert(err, IsNil)
+	c.Check(rolledBack, DeepEquals, []string{"first", "third"})
+	c.Check(observer.beforeWriteCalls, Equals, 0)
+	c.Check(observer.canceledCalls, Equals, 1)
+}
+
+func (u *updateTestSuite) TestRollbackErrors(c *C) {
+	logbuf, restore := logger.MockLogger()
+	defer restore()
+
+	oldData, newData, rollbackDir := updateDataSet(c)
+
+	// nothing was updated
+	err := gadget.Rollback(oldData, newData, rollbackDir, nil)
+	c.Assert(err, Equals, gadget.ErrNoUpdate)
+
+	newData.Info.Volumes["foo"].Structure[0].Update.Edition = 1
+	newData.Info.Volumes["foo"].Structure[1].Update.Edition = 1
+
+	var rolledBack []string
+	restore = gadget.MockUpdaterForStructure(func(ps *gadget.PositionedStructure, psRootDir, psRollbackDir string) (gadget.Updater, error) {
+		return &mockUpdater{
+			rollbackCb: func() error {
+				rolledBack = append(rolledBack, ps.Name)
+				if ps.Name == "first" {
+					return errors.New("failed")
+				}
+				return nil
+			},
+		}, nil
+	})
+	defer restore()
+
+	// all structures are attempted
+	err = gadget.Rollback(oldData, newData, rollbackDir, nil)
+	c.Assert(err, ErrorMatches, `cannot rollback volume structure #0 \("first"\) update: failed`)
+	c.Check(rolledBack, DeepEquals, []string{"first", "second"})
+	c.Check(logbuf.String(), testutil.Contains, `cannot rollback volume structure #0 ("first") update: failed`)
+}
+
+func (u *updateTestSuite) TestUpdaterForStructure(c *C) {
+	rootDir := c.MkDir()
+	rollbackDir := c.MkDir()
+
+	psBare := &gadget.PositionedStructure{
+		VolumeStructure: &gadget.VolumeStructure{
+			Filesystem: "none",
+			Size:       10 * gadget.SizeMiB,
+		},
+		StartOffset: 1 * gadget.SizeMiB,
+	}
+	updater, err := gadget.UpdaterForStructure(psBare, rootDir, rollbackDir)
+	c.Assert(err, IsNil)
+	c.Check(updater, FitsTypeOf, &gadget.RawStructureUpdater{})
+
+	psFs := &gadget.PositionedStructure{
+		VolumeStructure: &gadget.VolumeStructure{
+			Filesystem: "ext4",
+			Size:       10 * gadget.SizeMiB,
+		},
+		StartOffset: 1 * gadget.SizeMiB,
+	}
+	updater, err = gadget.UpdaterForStructure(psFs, rootDir, rollbackDir)
+	c.Assert(err, IsNil)
+	c.Check(updater, FitsTypeOf, &gadget.MountedFilesystemUpdater{})
+
+	// still errors out on invalid parameters
+	updater, err = gadget.UpdaterForStructure(psFs, "", rollbackDir)
+	c.Assert(err, ErrorMatches, "internal error: gadget content directory cannot be unset")
+	c.Check(updater, IsNil)
+}
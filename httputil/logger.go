@@ -29,6 +29,10 @@ import (
 	"github.com/snapcore/snapd/logger"
 )
 
+// httpLog records the debug messages about HTTP requests, which can be
+// enabled with the "http" subsystem.
+var httpLog = logger.Subsystem("http")
+
 type debugflag uint
 
 // set these via the Key environ
@@ -64,14 +68,14 @@ func (tr *LoggedTransport) RoundTrip(req *http.Request) (*http.Response, error)
 
 	if flags.debugRequest() {
 		buf, _ := httputil.DumpRequestOut(req, tr.body && flags.debugBody())
-		logger.Debugf("> %q", buf)
+		httpLog.Debugf("> %q", buf)
 	}
 
 	rsp, err := tr.Transport.RoundTrip(req)
 
 	if err == nil && flags.debugResponse() {
 		buf, _ := httputil.DumpResponse(rsp, tr.body && flags.debugBody())
-		logger.Debugf("< %q", buf)
+		httpLog.Debugf("< %q", buf)
 	}
 
 	return rsp, err
@@ -33,7 +33,6 @@ import (
 	"gopkg.in/retry.v1"
 
 	"github.com/snapcore/snapd/logger"
-	"github.com/snapcore/snapd/osutil"
 )
 
 type PerstistentNetworkError struct {
@@ -45,20 +44,20 @@ func (e *PerstistentNetworkError) Error() string {
 }
 
 func MaybeLogRetryAttempt(url string, attempt *retry.Attempt, startTime time.Time) {
-	if osutil.GetenvBool("SNAPD_DEBUG") || attempt.Count() > 1 {
-		logger.Debugf("Retrying %s, attempt %d, elapsed time=%v", url, attempt.Count(), time.Since(startTime))
+	if logger.DebugEnabled("http") || attempt.Count() > 1 {
+		httpLog.Debugf("Retrying %s, attempt %d, elapsed time=%v", url, attempt.Count(), time.Since(startTime))
 	}
 }
 
 func maybeLogRetrySummary(startTime time.Time, url string, attempt *retry.Attempt, resp *http.Response, err error) {
-	if osutil.GetenvBool("SNAPD_DEBUG") || attempt.Count() > 1 {
+	if logger.DebugEnabled("http") || attempt.Count() > 1 {
 		var status string
 		if err != nil {
 			status = err.Error()
 		} else if resp != nil {
 			status = fmt.Sprintf("%d", resp.StatusCode)
 		}
-		logger.Debugf("The retry loop for %s finished after %d retries, elapsed time=%v, status: %s", url, attempt.Count(), time.Since(startTime), status)
+		httpLog.Debugf("The retry loop for %s finished after %d retries, elapsed time=%v, status: %s", url, attempt.Count(), time.Since(startTime), status)
 	}
 }
 
@@ -78,7 +77,7 @@ func ShouldRetryError(attempt *retry.Attempt, err error) bool {
 	}
 	if netErr, ok := err.(net.Error); ok {
 		if netErr.Timeout() {
-			logger.Debugf("Retrying because of: %s", netErr)
+			httpLog.Debugf("Retrying because of: %s", netErr)
 			return true
 		}
 	}
@@ -92,17 +91,17 @@ func ShouldRetryError(attempt *retry.Attempt, err error) bool {
 		// peeling the onion
 		if syscallErr, ok := opErr.Err.(*os.SyscallError); ok {
 			if syscallErr.Err == syscall.ECONNRESET {
-				logger.Debugf("Retrying because of: %s", opErr)
+				httpLog.Debugf("Retrying because of: %s", opErr)
 				return true
 			}
 			// FIXME: code below is not (unit) tested and
 			// it is unclear if we need it with the new
 			// opErr.Temporary() "if" below
 			if opErr.Op == "dial" {
-				logger.Debugf("Retrying because of: %#v (syscall error: %#v)", opErr, syscallErr.Err)
+				httpLog.Debugf("Retrying because of: %#v (syscall error: %#v)", opErr, syscallErr.Err)
 				return true
 			}
-			logger.Debugf("Encountered syscall error: %#v", syscallErr)
+			httpLog.Debugf("Encountered syscall error: %#v", syscallErr)
 		}
 
 		// If we are unable to talk to a DNS go1.9+ will set
@@ -114,26 +113,26 @@ func ShouldRetryError(attempt *retry.Attempt, err error) bool {
 			// TODO: stop Arch to use the cgo resolver
 			// which requires the right side of the OR
 			if strings.Contains(dnsErr.Err, "connection refused") || strings.Contains(dnsErr.Err, "Temporary failure in name resolution") {
-				logger.Debugf("Retrying because of temporary net error (DNS): %#v", dnsErr)
+				httpLog.Debugf("Retrying because of temporary net error (DNS): %#v", dnsErr)
 				return true
 			}
 		}
 
 		// Retry for temporary network errors (like dns errors in 1.9+)
 		if opErr.Temporary() {
-			logger.Debugf("Retrying because of temporary net error: %#v", opErr)
+			httpLog.Debugf("Retrying because of temporary net error: %#v", opErr)
 			return true
 		}
-		logger.Debugf("Encountered non temporary net.OpError: %#v", opErr)
+		httpLog.Debugf("Encountered non temporary net.OpError: %#v", opErr)
 	}
 
 	if err == io.ErrUnexpectedEOF || err == io.EOF {
-		logger.Debugf("Retrying because of: %s", err)
+		httpLog.Debugf("Retrying because of: %s", err)
 		return true
 	}
 
-	if osutil.GetenvBool("SNAPD_DEBUG") {
-		logger.Debugf("Not retrying: %#v", err)
+	if logger.DebugEnabled("http") {
+		httpLog.Debugf("Not retrying: %#v", err)
 	}
 
 	return false
@@ -0,0 +1,283 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2018 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package image
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/snapcore/snapd/asserts"
+	"github.com/snapcore/snapd/gadget"
+	"github.com/snapcore/snapd/interfaces"
+	"github.com/snapcore/snapd/interfaces/builtin"
+	"github.com/snapcore/snapd/interfaces/policy"
+	"github.com/snapcore/snapd/osutil"
+	"github.com/snapcore/snapd/release"
+	"github.com/snapcore/snapd/snap"
+)
+
+// autoConnectChecker evaluates the auto-connection policy for the snaps
+// being seeded, mimicking what ifacestate will do on first boot.
+type autoConnectChecker struct {
+	db      asserts.RODatabase
+	model   *asserts.Model
+	store   *asserts.Store
+	decls   map[string]*asserts.SnapDeclaration
+	baseDcl *asserts.BaseDeclaration
+}
+
+func (c *autoConnectChecker) snapDeclaration(snapID string) (*asserts.SnapDeclaration, error) {
+	if decl := c.decls[snapID]; decl != nil {
+		return decl, nil
+	}
+	a, err := c.db.Find(asserts.SnapDeclarationType, map[string]string{
+		"series":  release.Series,
+		"snap-id": snapID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	decl := a.(*asserts.SnapDeclaration)
+	c.decls[snapID] = decl
+	return decl, nil
+}
+
+func (c *autoConnectChecker) check(plug *interfaces.ConnectedPlug, slot *interfaces.ConnectedSlot) (bool, error) {
+	var plugDecl, slotDecl *asserts.SnapDeclaration
+	if plug.Snap().SnapID != "" {
+		var err error
+		plugDecl, err = c.snapDeclaration(plug.Snap().SnapID)
+		if err != nil {
+			return false, nil
+		}
+	}
+	if slot.Snap().SnapID != "" {
+		var err error
+		slotDecl, err = c.snapDeclaration(slot.Snap().SnapID)
+		if err != nil {
+			return false, nil
+		}
+	}
+
+	ic := policy.ConnectCandidate{
+		Plug:                plug,
+		PlugSnapDeclaration: plugDecl,
+		Slot:                slot,
+		SlotSnapDeclaration: slotDecl,
+		BaseDeclaration:     c.baseDcl,
+		Model:               c.model,
+		Store:               c.store,
+	}
+	return ic.CheckAutoConnect() == nil, nil
+}
+
+// addImplicitSlots adds the implicit slots of the system to the snapd
+// snap if present, or to the core snap otherwise, like ifacestate does.
+// It returns the snap the slots were added to, if any.
+func addImplicitSlots(infos []*snap.Info, classic bool) *snap.Info {
+	var system *snap.Info
+	for _, info := range infos {
+		switch info.GetType() {
+		case snap.TypeSnapd:
+			system = info
+		case snap.TypeOS:
+			if system == nil {
+				system = info
+			}
+		}
+	}
+	if system == nil {
+		return nil
+	}
+	for _, iface := range builtin.Interfaces() {
+		si := interfaces.StaticInfoOf(iface)
+		if (classic && si.ImplicitOnClassic) || (!classic && si.ImplicitOnCore) {
+			ifaceName := iface.Name()
+			if _, ok := system.Slots[ifaceName]; !ok {
+				system.Slots[ifaceName] = &snap.SlotInfo{
+					Name:      ifaceName,
+					Snap:      system,
+					Interface: ifaceName,
+				}
+			}
+		}
+	}
+	return system
+}
+
+// seedRepository returns an interface repository with the given seeded
+// snaps, and the snap carrying the implicit system slots.
+func seedRepository(infos []*snap.Info, classic bool) (*interfaces.Repository, *snap.Info, error) {
+	repo := interfaces.NewRepository()
+	for _, iface := range builtin.Interfaces() {
+		if err := repo.AddInterface(iface); err != nil {
+			return nil, nil, err
+		}
+	}
+	system := addImplicitSlots(infos, classic)
+	for _, info := range infos {
+		if err := repo.AddSnap(info); err != nil {
+			return nil, nil, fmt.Errorf("cannot check the interfaces of snap %q: %v", info.InstanceName(), err)
+		}
+	}
+	return repo, system, nil
+}
+
+// checkGadgetConnections checks the gadget connections between seeded
+// snaps against the plugs and slots of those snaps, so that mistakes are
+// caught when building the image instead of the connections being
+// silently skipped on first boot.
+func checkGadgetConnections(repo *interfaces.Repository, infos []*snap.Info, system *snap.Info, gadgetConns []gadget.Connection) error {
+	byID := make(map[string]*snap.Info, len(infos)+1)
+	for _, info := range infos {
+		if info.SnapID != "" {
+			byID[info.SnapID] = info
+		}
+	}
+	if system != nil {
+		byID["system"] = system
+	}
+
+	for _, gconn := range gadgetConns {
+		plugSnap := byID[gconn.Plug.SnapID]
+		slotSnap := byID[gconn.Slot.SnapID]
+		if plugSnap == nil || slotSnap == nil {
+			// not about snaps in this image
+			continue
+		}
+		connErr := func(format string, args ...interface{}) error {
+			return fmt.Errorf("cannot use gadget connection of plug %s:%s to slot %s:%s: %s", gconn.Plug.SnapID, gconn.Plug.Plug, gconn.Slot.SnapID, gconn.Slot.Slot, fmt.Sprintf(format, args...))
+		}
+		plug := repo.Plug(plugSnap.InstanceName(), gconn.Plug.Plug)
+		if plug == nil {
+			return connErr("snap %q has no plug %q", plugSnap.InstanceName(), gconn.Plug.Plug)
+		}
+		slot := repo.Slot(slotSnap.InstanceName(), gconn.Slot.Slot)
+		if slot == nil {
+			return connErr("snap %q has no slot %q", slotSnap.InstanceName(), gconn.Slot.Slot)
+		}
+		if plug.Interface != slot.Interface {
+			return connErr("plug interface %q does not match slot interface %q", plug.Interface, slot.Interface)
+		}
+		if err := gconn.CheckSlotAttributes(slot.Attrs); err != nil {
+			return connErr("%v", err)
+		}
+	}
+	return nil
+}
+
+// unconnectedPlugs returns, for the plugs of the given seeded snaps that
+// will not be auto-connected on first boot nor connected by the gadget,
+// a description of the plug and why.
+func unconnectedPlugs(repo *interfaces.Repository, infos []*snap.Info, db asserts.RODatabase, model *asserts.Model, gadgetConns []gadget.Connection) ([]string, error) {
+	checker := &autoConnectChecker{
+		db:      db,
+		model:   model,
+		decls:   make(map[string]*asserts.SnapDeclaration),
+		baseDcl: asserts.BuiltinBaseDeclaration(),
+	}
+	if model.Store() != "" {
+		a, err := db.Find(asserts.StoreType, map[string]string{"store": model.Store()})
+		if err != nil && !asserts.IsNotFound(err) {
+			return nil, err
+		}
+		if a != nil {
+			checker.store = a.(*asserts.Store)
+		}
+	}
+
+	byGadget := make(map[string]bool, len(gadgetConns))
+	for _, gconn := range gadgetConns {
+		byGadget[gconn.Plug.SnapID+":"+gconn.Plug.Plug] = true
+	}
+
+	var unconnected []string
+	for _, info := range infos {
+		plugs := repo.Plugs(info.InstanceName())
+		for _, plug := range plugs {
+			if info.SnapID != "" && byGadget[info.SnapID+":"+plug.Name] {
+				continue
+			}
+			candidates := repo.AutoConnectCandidateSlots(info.InstanceName(), plug.Name, checker.check)
+			switch len(candidates) {
+			case 1:
+				continue
+			case 0:
+				unconnected = append(unconnected, fmt.Sprintf("plug %s (interface %q): no slot it can be auto-connected to", plug, plug.Interface))
+			default:
+				crefs := make([]string, len(candidates))
+				for i, candidate := range candidates {
+					crefs[i] = candidate.String()
+				}
+				sort.Strings(crefs)
+				unconnected = append(unconnected, fmt.Sprintf("plug %s (interface %q): several candidate slots: %s", plug, plug.Interface, strings.Join(crefs, ", ")))
+			}
+		}
+	}
+	return unconnected, nil
+}
+
+func gadgetConnections(model *asserts.Model, opts *Options) ([]gadget.Connection, error) {
+	if opts.GadgetUnpackDir == "" {
+		return nil, nil
+	}
+	if !osutil.FileExists(filepath.Join(opts.GadgetUnpackDir, "meta", "gadget.yaml")) {
+		return nil, nil
+	}
+	gadgetInfo, err := gadget.ReadInfo(opts.GadgetUnpackDir, model.Classic())
+	if err != nil {
+		return nil, fmt.Errorf("cannot read gadget connections: %v", err)
+	}
+	return gadgetInfo.Connections, nil
+}
+
+// checkAutoConnections checks the gadget connections between the
+// seeded snaps, and warns about, or with opts.RequireAutoConnections
+// fails on, plugs of the seeded snaps that will be left disconnected on
+// first boot.
+func checkAutoConnections(infos []*snap.Info, db asserts.RODatabase, model *asserts.Model, opts *Options) error {
+	gadgetConns, err := gadgetConnections(model, opts)
+	if err != nil {
+		return err
+	}
+	repo, system, err := seedRepository(infos, model.Classic())
+	if err != nil {
+		return err
+	}
+	if err := checkGadgetConnections(repo, infos, system, gadgetConns); err != nil {
+		return err
+	}
+	unconnected, err := unconnectedPlugs(repo, infos, db, model, gadgetConns)
+	if err != nil {
+		return err
+	}
+	if len(unconnected) == 0 {
+		return nil
+	}
+	if opts.RequireAutoConnections {
+		return fmt.Errorf("cannot prepare image with plugs that will not be connected on first boot:\n- %s", strings.Join(unconnected, "\n- "))
+	}
+	for _, desc := range unconnected {
+		fmt.Fprintf(Stderr, "WARNING: %s\n", desc)
+	}
+	return nil
+}
@@ -0,0 +1,94 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2019 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package image
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/snapcore/snapd/snap"
+)
+
+// classicPackagesManifest returns the manifest of the packages installed
+// under rootDir, as recorded in the dpkg status database.
+func classicPackagesManifest(rootDir string) ([]*snap.SeedClassicPackage, error) {
+	statusFn := filepath.Join(rootDir, "var/lib/dpkg/status")
+	f, err := os.Open(statusFn)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read classic packages manifest: %v", err)
+	}
+	defer f.Close()
+
+	var pkgs []*snap.SeedClassicPackage
+	fields := make(map[string]string)
+	flush := func() {
+		// only keep fully installed packages
+		if fields["Package"] != "" && strings.HasSuffix(fields["Status"], " installed") {
+			pkgs = append(pkgs, &snap.SeedClassicPackage{
+				Name:         fields["Package"],
+				Version:      fields["Version"],
+				Architecture: fields["Architecture"],
+			})
+		}
+		fields = make(map[string]string)
+	}
+
+	scanner := bufio.NewScanner(f)
+	// some fields like Description or Conffiles can have long lines
+	scanner.Buffer(nil, 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			flush()
+			continue
+		}
+		if line[0] == ' ' || line[0] == '\t' {
+			// continuation of a multi-line field
+			continue
+		}
+		l := strings.SplitN(line, ":", 2)
+		if len(l) != 2 {
+			return nil, fmt.Errorf("cannot parse classic packages manifest %s: unexpected line %q", statusFn, line)
+		}
+		fields[l[0]] = strings.TrimSpace(l[1])
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("cannot read classic packages manifest: %v", err)
+	}
+	flush()
+
+	sort.Sort(byPackageNameArch(pkgs))
+	return pkgs, nil
+}
+
+type byPackageNameArch []*snap.SeedClassicPackage
+
+func (b byPackageNameArch) Len() int      { return len(b) }
+func (b byPackageNameArch) Swap(i, j int) { b[i], b[j] = b[j], b[i] }
+func (b byPackageNameArch) Less(i, j int) bool {
+	if b[i].Name != b[j].Name {
+		return b[i].Name < b[j].Name
+	}
+	return b[i].Architecture < b[j].Architecture
+}
@@ -0,0 +1,94 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2019 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package image
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/snapcore/snapd/asserts"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/strutil"
+)
+
+// dryRun prints the snaps that would be seeded for the model, with
+// their revisions, channels and sizes, without downloading or writing
+// anything.
+func dryRun(tsto *ToolingStore, model *asserts.Model, opts *Options, local *localInfos) error {
+	_, snaps := seedSnaps(model, opts, local)
+
+	w := tabwriter.NewWriter(Stdout, 5, 3, 2, ' ', 0)
+	fmt.Fprintln(w, "Name\tRevision\tChannel\tSize\tNotes")
+	seen := make(map[string]bool)
+	var total int64
+	var infos []*snap.Info
+	for _, snapName := range snaps {
+		name := local.Name(snapName)
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+
+		if local.IsLocal(name) {
+			fi, err := os.Stat(local.Path(name))
+			if err != nil {
+				return err
+			}
+			total += fi.Size()
+			info := local.Info(name)
+			infos = append(infos, info)
+			fmt.Fprintf(w, "%s\t%s\t-\t%s\tlocal\n", name, info.Revision, strutil.SizeToStr(fi.Size()))
+			continue
+		}
+
+		dlOpts, err := snapDownloadOptions(name, "", model, opts, local)
+		if err != nil {
+			return err
+		}
+		snapChannel := dlOpts.Channel
+		notes := "-"
+		if !dlOpts.Revision.Unset() {
+			// the revision wins over the channel, as when
+			// downloading
+			dlOpts.Channel = ""
+			notes = "validation-set"
+			if _, ok := opts.SnapRevisions[name]; ok {
+				notes = "pinned"
+			}
+		}
+		info, err := tsto.snapInfo(name, dlOpts)
+		if err != nil {
+			return err
+		}
+		infos = append(infos, info)
+		if snapChannel == "" {
+			snapChannel = "-"
+		}
+		total += info.Size
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", name, info.Revision, snapChannel, strutil.SizeToStr(info.Size), notes)
+	}
+	w.Flush()
+	if err := checkValidationSets(infos, opts.validationSets); err != nil {
+		return err
+	}
+	fmt.Fprintf(Stdout, "Total size: %s (%d bytes)\n", strutil.SizeToStr(total), total)
+	return nil
+}
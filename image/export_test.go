@@ -29,12 +29,16 @@ func MockToolingStore(sto Store) *ToolingStore {
 }
 
 var (
-	LocalSnaps           = localSnaps
-	DecodeModelAssertion = decodeModelAssertion
-	DownloadUnpackGadget = downloadUnpackGadget
-	SetupSeed            = setupSeed
-	InstallCloudConfig   = installCloudConfig
-	SnapChannel          = snapChannel
+	LocalSnaps            = localSnaps
+	DecodeModelAssertion  = decodeModelAssertion
+	DownloadUnpackGadget  = downloadUnpackGadget
+	SetupSeed             = setupSeed
+	InstallCloudConfig    = installCloudConfig
+	SnapChannel           = snapChannel
+	CheckAutoConnections  = checkAutoConnections
+	DryRun                = dryRun
+	ResolveValidationSets = resolveValidationSets
+	CheckSnapRevisions    = checkSnapRevisions
 )
 
 func (tsto *ToolingStore) User() *auth.UserState {
@@ -57,3 +61,11 @@ var (
 	ErrRevisionAndCohort = errRevisionAndCohort
 	ErrPathInBase        = errPathInBase
 )
+
+func MockCheckSnapIntegrity(f func(fn string) error) (restore func()) {
+	old := checkSnapIntegrity
+	checkSnapIntegrity = f
+	return func() {
+		checkSnapIntegrity = old
+	}
+}
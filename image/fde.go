@@ -0,0 +1,68 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2019 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package image
+
+import (
+	"fmt"
+
+	"github.com/snapcore/snapd/asserts"
+	"github.com/snapcore/snapd/snap"
+)
+
+// CheckFDESupport checks that a device of the given model can use
+// full disk encryption, that is that its kernel or gadget snap
+// implements it with an "fde" hook, as snapd on the device expects.
+func CheckFDESupport(model *asserts.Model, kernel, gadget *snap.Info) error {
+	if model.Classic() {
+		return fmt.Errorf("cannot use full disk encryption with classic model %s/%s", model.BrandID(), model.Model())
+	}
+	if kernel == nil {
+		return fmt.Errorf("internal error: cannot check full disk encryption support without the kernel snap")
+	}
+	if kernel.Hooks["fde"] != nil {
+		return nil
+	}
+	if gadget != nil && gadget.Hooks["fde"] != nil {
+		return nil
+	}
+	gadgetName := model.Gadget()
+	if gadget != nil {
+		gadgetName = gadget.InstanceName()
+	}
+	return fmt.Errorf(`cannot prepare image for model %s/%s with full disk encryption: neither the kernel snap %q nor the gadget snap %q has an "fde" hook, use a revision of one of them that implements it`, model.BrandID(), model.Model(), kernel.InstanceName(), gadgetName)
+}
+
+// checkFDESupport checks, with opts.RequireFDE, that the seeded
+// kernel or gadget snap implements full disk encryption.
+func checkFDESupport(infos []*snap.Info, model *asserts.Model, opts *Options) error {
+	if !opts.RequireFDE {
+		return nil
+	}
+	var kernel, gadget *snap.Info
+	for _, info := range infos {
+		switch info.GetType() {
+		case snap.TypeKernel:
+			kernel = info
+		case snap.TypeGadget:
+			gadget = info
+		}
+	}
+	return CheckFDESupport(model, kernel, gadget)
+}
@@ -28,12 +28,14 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net/url"
 	"os"
 	"os/signal"
 	"path/filepath"
 	"strings"
+	"sync"
 	"syscall"
 
 	"github.com/mvo5/goconfigparser"
@@ -207,6 +209,69 @@ func NewToolingStore() (*ToolingStore, error) {
 	return newToolingStore(arch, storeID)
 }
 
+// NewOfflineToolingStore returns a ToolingStore that never contacts
+// the store: assertions are served only from the given assertion
+// files, and snaps cannot be downloaded at all, they must be
+// provided locally instead.
+func NewOfflineToolingStore(assertFiles []string) (*ToolingStore, error) {
+	bs := asserts.NewMemoryBackstore()
+	for _, fn := range assertFiles {
+		if err := addAssertionsFromFile(bs, fn); err != nil {
+			return nil, err
+		}
+	}
+	return &ToolingStore{
+		sto: &offlineStore{bs: bs},
+	}, nil
+}
+
+func addAssertionsFromFile(bs asserts.Backstore, fn string) error {
+	f, err := os.Open(fn)
+	if err != nil {
+		return fmt.Errorf("cannot read assertion file: %v", err)
+	}
+	defer f.Close()
+
+	dec := asserts.NewDecoder(f)
+	for {
+		a, err := dec.Decode()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("cannot decode assertions from %q: %v", fn, err)
+		}
+		if err := bs.Put(a.Type(), a); err != nil {
+			if _, ok := err.(*asserts.RevisionError); ok {
+				// same or newer revision already provided
+				continue
+			}
+			return fmt.Errorf("cannot use assertion %v from %q: %v", a.Ref(), fn, err)
+		}
+	}
+}
+
+// offlineStore implements Store serving assertions from memory and
+// refusing any snap download.
+type offlineStore struct {
+	bs asserts.Backstore
+}
+
+func (s *offlineStore) SnapAction(_ context.Context, _ []*store.CurrentSnap, actions []*store.SnapAction, _ *auth.UserState, _ *store.RefreshOptions) ([]*snap.Info, error) {
+	if len(actions) == 0 {
+		return nil, nil
+	}
+	return nil, fmt.Errorf("cannot download snap %q in offline mode, it must be provided as a local snap file", actions[0].InstanceName)
+}
+
+func (s *offlineStore) Download(_ context.Context, name, _ string, _ *snap.DownloadInfo, _ progress.Meter, _ *auth.UserState, _ *store.DownloadOptions) error {
+	return fmt.Errorf("cannot download snap %q in offline mode", name)
+}
+
+func (s *offlineStore) Assertion(assertType *asserts.AssertionType, primaryKey []string, _ *auth.UserState) (asserts.Assertion, error) {
+	return s.bs.Get(assertType, primaryKey, assertType.MaxSupportedFormat())
+}
+
 // DownloadOptions carries options for downloading snaps plus assertions.
 type DownloadOptions struct {
 	Revision  snap.Revision
@@ -214,6 +279,16 @@ type DownloadOptions struct {
 	Channel   string
 	CohortKey string
 	Basename  string
+
+	// Progress, if set, is used to report the progress of the
+	// download instead of a progress bar of its own.
+	Progress progress.Meter
+
+	// DeltaSourceDir, if set, is a directory with snaps from a
+	// previous build named <name>_<revision>.snap (as in a seed).
+	// When it holds an older revision of the snap a delta against
+	// it is requested from the store instead of the full snap.
+	DeltaSourceDir string
 }
 
 var (
@@ -277,20 +352,10 @@ func (tsto *ToolingStore) DownloadSnap(name string, opts DownloadOptions) (targe
 
 	logger.Debugf("Going to download snap %q %s.", name, &opts)
 
-	actions := []*store.SnapAction{{
-		Action:       "download",
-		InstanceName: name,
-		Revision:     opts.Revision,
-		CohortKey:    opts.CohortKey,
-		Channel:      opts.Channel,
-	}}
-
-	snaps, err := sto.SnapAction(context.TODO(), nil, actions, tsto.user, nil)
+	snap, err := tsto.snapInfo(name, &opts)
 	if err != nil {
-		// err will be 'cannot download snap "foo": <reasons>'
 		return "", nil, err
 	}
-	snap := snaps[0]
 
 	baseName := opts.Basename
 	if baseName == "" {
@@ -302,18 +367,121 @@ func (tsto *ToolingStore) DownloadSnap(name string, opts DownloadOptions) (targe
 
 	// check if we already have the right file
 	if osutil.FileExists(targetFn) {
-		sha3_384Dgst, size, err := osutil.FileDigest(targetFn, crypto.SHA3_384)
-		if err == nil && size == uint64(snap.DownloadInfo.Size) && fmt.Sprintf("%x", sha3_384Dgst) == snap.DownloadInfo.Sha3_384 {
+		if matchesDownload(targetFn, &snap.DownloadInfo) {
 			logger.Debugf("not downloading, using existing file %s", targetFn)
 			return targetFn, snap, nil
 		}
 		logger.Debugf("File exists but has wrong hash, ignoring (here).")
 	}
 
-	pb := progress.MakeProgressBar()
-	defer pb.Finished()
+	pb := opts.Progress
+	if pb == nil {
+		pb = progress.MakeProgressBar()
+		defer pb.Finished()
+		defer interceptSigint(pb)()
+	}
+
+	// an interrupted download is resumed when trying again
+	dlOpts := &store.DownloadOptions{LeavePartialOnError: true}
+	dlInfo := &snap.DownloadInfo
+	if opts.DeltaSourceDir != "" {
+		if sourceFn, sourceRev := findDeltaSource(opts.DeltaSourceDir, name, snap.Revision); sourceFn != "" {
+			deltaInfo, err := tsto.deltaDownloadInfo(snap, sourceRev, opts.Channel)
+			if err != nil {
+				logger.Noticef("Cannot get deltas for %q from revision %s: %v", name, sourceRev, err)
+			} else if deltaInfo.Sha3_384 == dlInfo.Sha3_384 && len(deltaInfo.Deltas) == 1 {
+				dlInfo = deltaInfo
+				dlOpts.DeltaSource = sourceFn
+			}
+		}
+	}
+	if err = sto.Download(context.TODO(), name, targetFn, dlInfo, pb, tsto.user, dlOpts); err != nil {
+		return "", nil, err
+	}
+
+	// make sure a resumed download was put together correctly
+	if snap.DownloadInfo.Sha3_384 != "" && !matchesDownload(targetFn, &snap.DownloadInfo) {
+		os.Remove(targetFn)
+		return "", nil, fmt.Errorf("cannot use downloaded snap %q: size or sha3-384 mismatch", name)
+	}
 
-	// Intercept sigint
+	return targetFn, snap, nil
+}
+
+// snapInfo returns the store information about the snap revision
+// that would be downloaded with the given options.
+func (tsto *ToolingStore) snapInfo(name string, opts *DownloadOptions) (*snap.Info, error) {
+	actions := []*store.SnapAction{{
+		Action:       "download",
+		InstanceName: name,
+		Revision:     opts.Revision,
+		CohortKey:    opts.CohortKey,
+		Channel:      opts.Channel,
+	}}
+
+	snaps, err := tsto.sto.SnapAction(context.TODO(), nil, actions, tsto.user, nil)
+	if err != nil {
+		// err will be 'cannot download snap "foo": <reasons>'
+		return nil, err
+	}
+	return snaps[0], nil
+}
+
+// findDeltaSource returns the path and revision of the most recent
+// store revision of the snap in dir that is older than rev, if any.
+func findDeltaSource(dir, name string, rev snap.Revision) (string, snap.Revision) {
+	var sourceFn string
+	var sourceRev snap.Revision
+	matches, _ := filepath.Glob(filepath.Join(dir, name+"_*.snap"))
+	for _, fn := range matches {
+		revStr := strings.TrimSuffix(strings.TrimPrefix(filepath.Base(fn), name+"_"), ".snap")
+		r, err := snap.ParseRevision(revStr)
+		if err != nil || !r.Store() || r.N >= rev.N || r.N <= sourceRev.N {
+			continue
+		}
+		sourceFn, sourceRev = fn, r
+	}
+	return sourceFn, sourceRev
+}
+
+// deltaDownloadInfo asks the store for the download info of the
+// revision of the given snap as a refresh from sourceRev, which
+// carries the deltas available between the two.
+func (tsto *ToolingStore) deltaDownloadInfo(info *snap.Info, sourceRev snap.Revision, channel string) (*snap.DownloadInfo, error) {
+	if channel == "" {
+		channel = "stable"
+	}
+	current := []*store.CurrentSnap{{
+		InstanceName:    info.InstanceName(),
+		SnapID:          info.SnapID,
+		Revision:        sourceRev,
+		TrackingChannel: channel,
+		Epoch:           info.Epoch,
+	}}
+	actions := []*store.SnapAction{{
+		Action:       "refresh",
+		InstanceName: info.InstanceName(),
+		SnapID:       info.SnapID,
+		Revision:     info.Revision,
+	}}
+	snaps, err := tsto.sto.SnapAction(context.TODO(), current, actions, tsto.user, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &snaps[0].DownloadInfo, nil
+}
+
+// matchesDownload returns whether the file has the size and sha3-384
+// digest expected for the download.
+func matchesDownload(fn string, dlInfo *snap.DownloadInfo) bool {
+	sha3_384Dgst, size, err := osutil.FileDigest(fn, crypto.SHA3_384)
+	return err == nil && size == uint64(dlInfo.Size) && fmt.Sprintf("%x", sha3_384Dgst) == dlInfo.Sha3_384
+}
+
+// interceptSigint makes an interrupt finish the given progress bar
+// before exiting, instead of leaving the terminal in a bad state. The
+// returned function restores the default handling of SIGINT.
+func interceptSigint(pb progress.Meter) (restore func()) {
 	c := make(chan os.Signal, 3)
 	signal.Notify(c, syscall.SIGINT)
 	go func() {
@@ -321,16 +489,95 @@ func (tsto *ToolingStore) DownloadSnap(name string, opts DownloadOptions) (targe
 		pb.Finished()
 		os.Exit(1)
 	}()
-
-	if err = sto.Download(context.TODO(), name, targetFn, &snap.DownloadInfo, pb, tsto.user, nil); err != nil {
-		return "", nil, err
+	return func() {
+		signal.Reset(syscall.SIGINT)
 	}
+}
 
-	signal.Reset(syscall.SIGINT)
+// aggregateMeter shows the combined progress of concurrent downloads
+// on a single progress.Meter.
+type aggregateMeter struct {
+	mu      sync.Mutex
+	pb      progress.Meter
+	total   float64
+	current float64
+}
 
-	return targetFn, snap, nil
+func (m *aggregateMeter) add(total, current float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.total += total
+	m.current += current
+	m.pb.SetTotal(m.total)
+	m.pb.Set(m.current)
+}
+
+// part returns a progress.Meter for one of the downloads.
+func (m *aggregateMeter) part() progress.Meter {
+	return &partMeter{parent: m}
+}
+
+// partMeter reports the progress of one download to its aggregateMeter.
+type partMeter struct {
+	progress.NullMeter
+	parent  *aggregateMeter
+	total   float64
+	current float64
+}
+
+func (p *partMeter) Start(label string, total float64) {
+	p.SetTotal(total)
+}
+
+func (p *partMeter) SetTotal(total float64) {
+	p.parent.add(total-p.total, 0)
+	p.total = total
+}
+
+func (p *partMeter) Set(current float64) {
+	p.parent.add(0, current-p.current)
+	p.current = current
 }
 
+func (p *partMeter) Write(bs []byte) (int, error) {
+	p.Set(p.current + float64(len(bs)))
+	return len(bs), nil
+}
+
+// reporterMeter passes the progress of a snap download on to a
+// ProgressReporter.
+type reporterMeter struct {
+	progress.NullMeter
+	name     string
+	reporter ProgressReporter
+	total    float64
+	current  float64
+}
+
+func (m *reporterMeter) Start(label string, total float64) {
+	m.total = total
+	m.reporter.SnapDownload(m.name, m.current, m.total)
+}
+
+func (m *reporterMeter) SetTotal(total float64) {
+	m.total = total
+	m.reporter.SnapDownload(m.name, m.current, m.total)
+}
+
+func (m *reporterMeter) Set(current float64) {
+	m.current = current
+	m.reporter.SnapDownload(m.name, m.current, m.total)
+}
+
+func (m *reporterMeter) Write(bs []byte) (int, error) {
+	m.Set(m.current + float64(len(bs)))
+	return len(bs), nil
+}
+
+// assertionFetchParallelism is how many assertions are retrieved from the
+// store at the same time while fetching prerequisites.
+const assertionFetchParallelism = 8
+
 // AssertionFetcher creates an asserts.Fetcher for assertions against the given store using dlOpts for authorization, the fetcher will add assertions in the given database and after that also call save for each of them.
 func (tsto *ToolingStore) AssertionFetcher(db *asserts.Database, save func(asserts.Assertion) error) asserts.Fetcher {
 	retrieve := func(ref *asserts.Ref) (asserts.Assertion, error) {
@@ -347,7 +594,7 @@ func (tsto *ToolingStore) AssertionFetcher(db *asserts.Database, save func(asser
 		}
 		return save(a)
 	}
-	return asserts.NewFetcher(db, retrieve, save2)
+	return asserts.NewParallelFetcher(db, retrieve, save2, assertionFetchParallelism)
 }
 
 // FetchAndCheckSnapAssertions fetches and cross checks the snap assertions matching the given snap file using the provided asserts.Fetcher and assertion database.
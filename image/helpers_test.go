@@ -20,15 +20,25 @@
 package image_test
 
 import (
+	"bytes"
+	"context"
+	"crypto"
+	"fmt"
+	"io/ioutil"
 	"os"
 	"path/filepath"
 	"runtime"
 
 	"gopkg.in/check.v1"
 
+	"github.com/snapcore/snapd/asserts"
 	"github.com/snapcore/snapd/image"
 	"github.com/snapcore/snapd/logger"
+	"github.com/snapcore/snapd/osutil"
+	"github.com/snapcore/snapd/overlord/auth"
+	"github.com/snapcore/snapd/progress"
 	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/store"
 )
 
 func (s *imageSuite) TestDownloadOptionsString(c *check.C) {
@@ -115,3 +125,145 @@ func (s *imageSuite) TestDownloadSnap(c *check.C) {
 
 	c.Check(logbuf.String(), check.Matches, `.* DEBUG: Going to download snap "core" `+opts.String()+".\n")
 }
+
+func (s *imageSuite) TestOfflineToolingStore(c *check.C) {
+	var buf bytes.Buffer
+	enc := asserts.NewEncoder(&buf)
+	storeAccKey := s.storeSigning.StoreAccountKey("")
+	c.Assert(enc.Encode(storeAccKey), check.IsNil)
+	// repeated assertions are fine
+	c.Assert(enc.Encode(storeAccKey), check.IsNil)
+
+	fn := filepath.Join(c.MkDir(), "store.assert")
+	err := ioutil.WriteFile(fn, buf.Bytes(), 0644)
+	c.Assert(err, check.IsNil)
+
+	tsto, err := image.NewOfflineToolingStore([]string{fn})
+	c.Assert(err, check.IsNil)
+
+	a, err := tsto.Find(asserts.AccountKeyType, map[string]string{
+		"public-key-sha3-384": storeAccKey.PublicKeyID(),
+	})
+	c.Assert(err, check.IsNil)
+	c.Check(a.Ref(), check.DeepEquals, storeAccKey.Ref())
+
+	_, err = tsto.Find(asserts.AccountType, map[string]string{
+		"account-id": "other",
+	})
+	c.Check(asserts.IsNotFound(err), check.Equals, true)
+
+	_, _, err = tsto.DownloadSnap("core", image.DownloadOptions{TargetDir: c.MkDir()})
+	c.Check(err, check.ErrorMatches, `cannot download snap "core" in offline mode, it must be provided as a local snap file`)
+}
+
+func (s *imageSuite) TestOfflineToolingStoreErrors(c *check.C) {
+	_, err := image.NewOfflineToolingStore([]string{filepath.Join(c.MkDir(), "missing.assert")})
+	c.Check(err, check.ErrorMatches, `cannot read assertion file: open .*/missing.assert: no such file or directory`)
+
+	fn := filepath.Join(c.MkDir(), "broken.assert")
+	err = ioutil.WriteFile(fn, []byte("type: account\n"), 0644)
+	c.Assert(err, check.IsNil)
+	_, err = image.NewOfflineToolingStore([]string{fn})
+	c.Check(err, check.ErrorMatches, `cannot decode assertions from ".*/broken.assert": .*`)
+}
+
+// contentStore serves a single snap with the given content, while
+// announcing the download info of the expected content.
+type contentStore struct {
+	expected, content []byte
+	revision          snap.Revision
+
+	current []*store.CurrentSnap
+	dlInfo  *snap.DownloadInfo
+	dlOpts  *store.DownloadOptions
+}
+
+func (s *contentStore) SnapAction(_ context.Context, current []*store.CurrentSnap, actions []*store.SnapAction, _ *auth.UserState, _ *store.RefreshOptions) ([]*snap.Info, error) {
+	h := crypto.SHA3_384.New()
+	h.Write(s.expected)
+	info := &snap.Info{}
+	info.RealName = actions[0].InstanceName
+	info.Revision = snap.R(1)
+	if !s.revision.Unset() {
+		info.Revision = s.revision
+	}
+	info.Sha3_384 = fmt.Sprintf("%x", h.Sum(nil))
+	info.Size = int64(len(s.expected))
+	if actions[0].Action == "refresh" {
+		s.current = current
+		info.Deltas = []snap.DeltaInfo{{
+			FromRevision: current[0].Revision.N,
+			ToRevision:   info.Revision.N,
+			Format:       "xdelta3",
+		}}
+	}
+	return []*snap.Info{info}, nil
+}
+
+func (s *contentStore) Download(_ context.Context, _, targetFn string, dlInfo *snap.DownloadInfo, _ progress.Meter, _ *auth.UserState, dlOpts *store.DownloadOptions) error {
+	s.dlInfo = dlInfo
+	s.dlOpts = dlOpts
+	return ioutil.WriteFile(targetFn, s.content, 0644)
+}
+
+func (s *contentStore) Assertion(*asserts.AssertionType, []string, *auth.UserState) (asserts.Assertion, error) {
+	return nil, fmt.Errorf("unexpected assertion request")
+}
+
+func (s *imageSuite) TestDownloadSnapLeavesPartialOnError(c *check.C) {
+	sto := &contentStore{expected: []byte("snap"), content: []byte("snap")}
+	tsto := image.MockToolingStore(sto)
+
+	dlDir := c.MkDir()
+	fn, _, err := tsto.DownloadSnap("foo", image.DownloadOptions{TargetDir: dlDir})
+	c.Assert(err, check.IsNil)
+	c.Check(fn, check.Equals, filepath.Join(dlDir, "foo_1.snap"))
+	c.Check(sto.dlOpts, check.DeepEquals, &store.DownloadOptions{LeavePartialOnError: true})
+}
+
+func (s *imageSuite) TestDownloadSnapChecksDigest(c *check.C) {
+	sto := &contentStore{expected: []byte("snap"), content: []byte("pans")}
+	tsto := image.MockToolingStore(sto)
+
+	dlDir := c.MkDir()
+	_, _, err := tsto.DownloadSnap("foo", image.DownloadOptions{TargetDir: dlDir})
+	c.Assert(err, check.ErrorMatches, `cannot use downloaded snap "foo": size or sha3-384 mismatch`)
+	c.Check(osutil.FileExists(filepath.Join(dlDir, "foo_1.snap")), check.Equals, false)
+}
+
+func (s *imageSuite) TestDownloadSnapWithDeltaSource(c *check.C) {
+	sto := &contentStore{expected: []byte("snap"), content: []byte("snap"), revision: snap.R(5)}
+	tsto := image.MockToolingStore(sto)
+
+	deltaDir := c.MkDir()
+	for _, base := range []string{"foo_2.snap", "foo_3.snap", "foo_x4.snap", "foo_7.snap", "foo_bar_4.snap", "other_4.snap"} {
+		c.Assert(ioutil.WriteFile(filepath.Join(deltaDir, base), nil, 0644), check.IsNil)
+	}
+
+	dlDir := c.MkDir()
+	fn, _, err := tsto.DownloadSnap("foo", image.DownloadOptions{TargetDir: dlDir, DeltaSourceDir: deltaDir})
+	c.Assert(err, check.IsNil)
+	c.Check(fn, check.Equals, filepath.Join(dlDir, "foo_5.snap"))
+	c.Assert(sto.current, check.HasLen, 1)
+	c.Check(sto.current[0].Revision, check.Equals, snap.R(3))
+	c.Check(sto.current[0].TrackingChannel, check.Equals, "stable")
+	c.Check(sto.dlInfo.Deltas, check.DeepEquals, []snap.DeltaInfo{{FromRevision: 3, ToRevision: 5, Format: "xdelta3"}})
+	c.Check(sto.dlOpts, check.DeepEquals, &store.DownloadOptions{
+		LeavePartialOnError: true,
+		DeltaSource:         filepath.Join(deltaDir, "foo_3.snap"),
+	})
+}
+
+func (s *imageSuite) TestDownloadSnapWithDeltaSourceNoOlderRevision(c *check.C) {
+	sto := &contentStore{expected: []byte("snap"), content: []byte("snap"), revision: snap.R(5)}
+	tsto := image.MockToolingStore(sto)
+
+	deltaDir := c.MkDir()
+	c.Assert(ioutil.WriteFile(filepath.Join(deltaDir, "foo_5.snap"), nil, 0644), check.IsNil)
+
+	_, _, err := tsto.DownloadSnap("foo", image.DownloadOptions{TargetDir: c.MkDir(), DeltaSourceDir: deltaDir})
+	c.Assert(err, check.IsNil)
+	c.Check(sto.current, check.IsNil)
+	c.Check(sto.dlInfo.Deltas, check.HasLen, 0)
+	c.Check(sto.dlOpts, check.DeepEquals, &store.DownloadOptions{LeavePartialOnError: true})
+}
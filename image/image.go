@@ -20,15 +20,17 @@
 package image
 
 import (
-	"bytes"
+	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"syscall"
 
+	"github.com/snapcore/snapd/arch"
 	"github.com/snapcore/snapd/asserts"
 	"github.com/snapcore/snapd/asserts/snapasserts"
 	"github.com/snapcore/snapd/asserts/sysdb"
@@ -36,6 +38,7 @@ import (
 	"github.com/snapcore/snapd/bootloader"
 	"github.com/snapcore/snapd/dirs"
 	"github.com/snapcore/snapd/osutil"
+	"github.com/snapcore/snapd/progress"
 	"github.com/snapcore/snapd/release"
 	"github.com/snapcore/snapd/snap"
 	"github.com/snapcore/snapd/snap/squashfs"
@@ -59,6 +62,117 @@ type Options struct {
 	// Architecture to use if none is specified by the model,
 	// useful only for classic mode. If set must match the model otherwise.
 	Architecture string
+
+	// ClassicPackages records in the seed the manifest of the
+	// packages installed under RootDir, only for classic mode.
+	ClassicPackages bool
+
+	// RequireAutoConnections turns the warning about plugs of the
+	// seeded snaps that will not be connected on first boot into an
+	// error.
+	RequireAutoConnections bool
+
+	// Offline prepares the image without contacting the store:
+	// all snaps must be provided as local snap files and the
+	// needed assertions must come from the model file or
+	// AssertionFiles.
+	Offline bool
+	// AssertionFiles lists files with assertions (e.g. the
+	// account, account-key, snap-declaration and snap-revision
+	// assertions) for the local snaps, only for offline mode.
+	AssertionFiles []string
+
+	// DownloadConcurrency is the number of snaps downloaded from
+	// the store in parallel, by default they are downloaded one
+	// at a time.
+	DownloadConcurrency int
+
+	// DeltaSourceDir is a directory with the snaps of a previous
+	// build (e.g. the seed snaps directory of a previous image),
+	// deltas against the older revisions found there are
+	// downloaded instead of the full snaps where possible.
+	DeltaSourceDir string
+
+	// Progress, if set, receives the progress of preparing the
+	// image instead of it being shown with progress bars.
+	Progress ProgressReporter
+	// RequireFDE fails preparing the image unless the kernel or
+	// gadget snap implements full disk encryption, only for core
+	// models.
+	RequireFDE bool
+
+	// SeedManifestPath, if set, is where to write the manifest of
+	// the seeded snaps with their exact revisions and digests.
+	SeedManifestPath string
+
+	// Cohort is the cohort key to download the store snaps from,
+	// SnapCohorts overrides it per snap name.
+	Cohort      string
+	SnapCohorts map[string]string
+
+	// DryRun only prints the snaps that would be seeded, with their
+	// revisions, channels and sizes, nothing is downloaded or
+	// written.
+	DryRun bool
+
+	// ValidationSets lists validation sets as account-id/name[=seq]
+	// that the seeded snaps must satisfy, the revisions they require
+	// are the ones downloaded. Their assertions are put in the seed
+	// and enforced on the first-boot system, pinned to the sequence
+	// if one was given.
+	ValidationSets []string
+
+	// SeedAssertionFiles lists files with extra assertions to put
+	// in the seed (e.g. store, account-key or system-user
+	// assertions), together with their prerequisites.
+	SeedAssertionFiles []string
+
+	// SnapRevisions pins store snaps to the given revisions, they
+	// are downloaded instead of the heads of their channels.
+	SnapRevisions map[string]snap.Revision
+
+	// validationSets are the resolved ValidationSets
+	validationSets []*validationSet
+}
+
+// Phase is one of the phases of preparing an image.
+type Phase string
+
+const (
+	// PhaseFetchAssertions is fetching the prerequisites of the
+	// model assertion.
+	PhaseFetchAssertions Phase = "fetch-assertions"
+	// PhaseFetchSnaps is downloading (or copying) the seed snaps
+	// and fetching their assertions.
+	PhaseFetchSnaps Phase = "fetch-snaps"
+	// PhaseWriteSeed is writing the seed and the boot
+	// configuration.
+	PhaseWriteSeed Phase = "write-seed"
+)
+
+// ProgressReporter receives the progress of preparing an image. With
+// parallel downloads SnapDownload is called concurrently.
+type ProgressReporter interface {
+	// Phase is called when the given phase starts.
+	Phase(phase Phase)
+	// SnapDownload is called as the given snap is downloaded with
+	// the number of bytes downloaded so far and the total.
+	SnapDownload(name string, current, total float64)
+}
+
+func reportPhase(opts *Options, phase Phase) {
+	if opts.Progress != nil {
+		opts.Progress.Phase(phase)
+	}
+}
+
+// downloadProgress returns the progress.Meter to download the given
+// snap with, or nil to use a progress bar.
+func downloadProgress(opts *Options, name string) progress.Meter {
+	if opts.Progress == nil {
+		return nil
+	}
+	return &reporterMeter{name: name, reporter: opts.Progress}
 }
 
 type localInfos struct {
@@ -204,6 +318,12 @@ func Prepare(opts *Options) error {
 			return fmt.Errorf("cannot have snaps for a classic image without an architecture in the model or from --arch")
 		}
 	}
+	if opts.ClassicPackages && !opts.Classic {
+		return fmt.Errorf("cannot record the classic packages manifest without --classic mode")
+	}
+	if opts.RequireFDE && opts.Classic {
+		return fmt.Errorf("cannot require full disk encryption with --classic mode")
+	}
 
 	if err := validateNonLocalSnaps(opts.Snaps); err != nil {
 		return err
@@ -212,7 +332,26 @@ func Prepare(opts *Options) error {
 		return fmt.Errorf("cannot use channel: %v", err)
 	}
 
-	tsto, err := NewToolingStoreFromModel(model, opts.Architecture)
+	if len(opts.AssertionFiles) != 0 && !opts.Offline {
+		return fmt.Errorf("cannot use assertion files without offline mode")
+	}
+	for _, ref := range opts.ValidationSets {
+		if _, _, _, err := parseValidationSet(ref); err != nil {
+			return err
+		}
+	}
+
+	var tsto *ToolingStore
+	if opts.Offline {
+		for _, snapName := range opts.Snaps {
+			if !strings.HasSuffix(snapName, ".snap") {
+				return fmt.Errorf("cannot use snap %q in offline mode, it must be provided as a local snap file", snapName)
+			}
+		}
+		tsto, err = NewOfflineToolingStore(opts.AssertionFiles)
+	} else {
+		tsto, err = NewToolingStoreFromModel(model, opts.Architecture)
+	}
 	if err != nil {
 		return err
 	}
@@ -221,12 +360,28 @@ func Prepare(opts *Options) error {
 	if err != nil {
 		return err
 	}
+	for name := range opts.SnapCohorts {
+		if local.IsLocal(name) {
+			return fmt.Errorf("cannot use a cohort for local snap %q", name)
+		}
+	}
+	if err := checkSnapRevisions(model, opts, local); err != nil {
+		return err
+	}
 
 	// FIXME: limitation until we can pass series parametrized much more
 	if model.Series() != release.Series {
 		return fmt.Errorf("model with series %q != %q unsupported", model.Series(), release.Series)
 	}
 
+	if err := resolveValidationSets(tsto, opts); err != nil {
+		return err
+	}
+
+	if opts.DryRun {
+		return dryRun(tsto, model, opts, local)
+	}
+
 	if !opts.Classic {
 		// unpacking the gadget for core models
 		if err := downloadUnpackGadget(tsto, model, opts, local); err != nil {
@@ -296,6 +451,39 @@ func snapChannel(name string, model *asserts.Model, opts *Options, local *localI
 	return snapChannel, nil
 }
 
+// snapCohort returns the cohort key to download the given store snap
+// from, if any.
+func snapCohort(name string, opts *Options, local *localInfos) string {
+	if local.IsLocal(name) {
+		return ""
+	}
+	if cohort := opts.SnapCohorts[name]; cohort != "" {
+		return cohort
+	}
+	return opts.Cohort
+}
+
+// snapDownloadOptions returns the options to download the named snap
+// of the seed into targetDir, with the channel, cohort and revision
+// that were asked for it.
+func snapDownloadOptions(name, targetDir string, model *asserts.Model, opts *Options, local *localInfos) (*DownloadOptions, error) {
+	snapChannel, err := snapChannel(name, model, opts, local)
+	if err != nil {
+		return nil, err
+	}
+	dlOpts := &DownloadOptions{
+		TargetDir:      targetDir,
+		Channel:        snapChannel,
+		CohortKey:      snapCohort(name, opts, local),
+		DeltaSourceDir: opts.DeltaSourceDir,
+		Progress:       downloadProgress(opts, name),
+	}
+	if err := setSnapRevision(name, dlOpts, opts, local); err != nil {
+		return nil, err
+	}
+	return dlOpts, nil
+}
+
 func makeChannelFromTrack(what, track, snapChannel string) (string, error) {
 	mch, err := snap.ParseChannel(track, "")
 	if err != nil {
@@ -320,15 +508,10 @@ func downloadUnpackGadget(tsto *ToolingStore, model *asserts.Model, opts *Option
 	}
 
 	gadgetName := model.Gadget()
-	gadgetChannel, err := snapChannel(gadgetName, model, opts, local)
+	dlOpts, err := snapDownloadOptions(gadgetName, opts.GadgetUnpackDir, model, opts, local)
 	if err != nil {
 		return err
 	}
-
-	dlOpts := &DownloadOptions{
-		TargetDir: opts.GadgetUnpackDir,
-		Channel:   gadgetChannel,
-	}
 	snapFn, _, err := acquireSnap(tsto, gadgetName, dlOpts, local)
 	if err != nil {
 		return err
@@ -352,6 +535,69 @@ func acquireSnap(tsto *ToolingStore, name string, dlOpts *DownloadOptions, local
 	return tsto.DownloadSnap(name, *dlOpts)
 }
 
+type acquiredSnap struct {
+	fn   string
+	info *snap.Info
+}
+
+// downloadSnaps downloads the given snaps from the store using up to
+// concurrency parallel downloads, showing their combined progress
+// unless the download options carry their own progress.Meter.
+func downloadSnaps(tsto *ToolingStore, names []string, dlOpts map[string]*DownloadOptions, concurrency int) (map[string]*acquiredSnap, error) {
+	var agg *aggregateMeter
+	for _, name := range names {
+		if dlOpts[name].Progress == nil {
+			pb := progress.MakeProgressBar()
+			defer pb.Finished()
+			defer interceptSigint(pb)()
+			pb.Start(fmt.Sprintf("Fetching %d snaps", len(names)), 0)
+			agg = &aggregateMeter{pb: pb}
+			break
+		}
+	}
+
+	var mu sync.Mutex
+	acquired := make(map[string]*acquiredSnap, len(names))
+	errs := make([]error, len(names))
+
+	queue := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range queue {
+				name := names[i]
+				opts := *dlOpts[name]
+				if opts.Progress == nil {
+					opts.Progress = agg.part()
+				}
+				fn, info, err := tsto.DownloadSnap(name, opts)
+				mu.Lock()
+				if err != nil {
+					errs[i] = err
+				} else {
+					acquired[name] = &acquiredSnap{fn: fn, info: info}
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	for i := range names {
+		queue <- i
+	}
+	close(queue)
+	wg.Wait()
+
+	// report the first error in download order
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	return acquired, nil
+}
+
 type addingFetcher struct {
 	asserts.Fetcher
 	addedRefs []*asserts.Ref
@@ -432,6 +678,53 @@ func hasBase(snap *snap.Info, local *localInfos, snaps []string) error {
 	return fmt.Errorf("cannot add snap %q without also adding its base %q explicitly", snap.InstanceName(), snap.Base)
 }
 
+// seedSnaps returns the name of the boot base of the model and the
+// names or paths of the snaps to seed, in order.
+func seedSnaps(model *asserts.Model, opts *Options, local *localInfos) (baseName string, snaps []string) {
+	baseName = defaultCore
+	if model.Base() != "" {
+		baseName = model.Base()
+	}
+
+	snaps = []string{}
+	// always add an implicit snapd first when a base is used
+	if model.Base() != "" {
+		snaps = append(snaps, "snapd")
+		// TODO: once we order snaps by what they need this
+		//       can go aways
+		// Here we ensure that "core" is seeded very early
+		// when bases are in use. This fixes the issue
+		// that when people use model assertions with
+		// required snaps like bluez which at this point
+		// still requires core will hang forever in seeding.
+		if strutil.ListContains(model.RequiredSnaps(), "core") || local.hasName(opts.Snaps, "core") {
+			snaps = append(snaps, "core")
+		}
+	}
+
+	if !opts.Classic {
+		// core/base,kernel,gadget first
+		snaps = append(snaps, baseName)
+		snaps = append(snaps, model.Kernel())
+		snaps = append(snaps, model.Gadget())
+	} else {
+		// classic image case: first core as needed and gadget
+		if classicHasSnaps(model, opts) {
+			// TODO: later use snapd+core16 or core18 if specified
+			snaps = append(snaps, "core")
+		}
+		if model.Gadget() != "" {
+			snaps = append(snaps, model.Gadget())
+		}
+	}
+
+	// then required and the user requested stuff
+	snaps = append(snaps, model.RequiredSnaps()...)
+	snaps = append(snaps, opts.Snaps...)
+
+	return baseName, snaps
+}
+
 func setupSeed(tsto *ToolingStore, model *asserts.Model, opts *Options, local *localInfos) error {
 	if model.Classic() != opts.Classic {
 		return fmt.Errorf("internal error: classic model but classic mode not set")
@@ -462,6 +755,7 @@ func setupSeed(tsto *ToolingStore, model *asserts.Model, opts *Options, local *l
 	}
 	f := makeFetcher(tsto, &DownloadOptions{}, db)
 
+	reportPhase(opts, PhaseFetchAssertions)
 	if err := f.Save(model); err != nil {
 		if !osutil.GetenvBool("UBUNTU_IMAGE_SKIP_COPY_UNVERIFIED_MODEL") {
 			return fmt.Errorf("cannot fetch and check prerequisites for the model assertion: %v", err)
@@ -470,6 +764,14 @@ func setupSeed(tsto *ToolingStore, model *asserts.Model, opts *Options, local *l
 			f.addedRefs = nil
 		}
 	}
+	for _, vs := range opts.validationSets {
+		if err := f.Save(vs.ValidationSet); err != nil {
+			return fmt.Errorf("cannot fetch and check prerequisites for validation set %s: %v", snapasserts.ValidationSetKey(vs.ValidationSet), err)
+		}
+	}
+	if err := addSeedAssertions(tsto, db, f, opts.SeedAssertionFiles); err != nil {
+		return err
+	}
 
 	// put snaps in place
 	snapSeedDir := filepath.Join(dirs.SnapSeedDir, "snaps")
@@ -480,49 +782,34 @@ func setupSeed(tsto *ToolingStore, model *asserts.Model, opts *Options, local *l
 		}
 	}
 
-	baseName := defaultCore
-	if model.Base() != "" {
-		baseName = model.Base()
-	}
-
-	snaps := []string{}
-	// always add an implicit snapd first when a base is used
-	if model.Base() != "" {
-		snaps = append(snaps, "snapd")
-		// TODO: once we order snaps by what they need this
-		//       can go aways
-		// Here we ensure that "core" is seeded very early
-		// when bases are in use. This fixes the issue
-		// that when people use model assertions with
-		// required snaps like bluez which at this point
-		// still requires core will hang forever in seeding.
-		if strutil.ListContains(model.RequiredSnaps(), "core") || local.hasName(opts.Snaps, "core") {
-			snaps = append(snaps, "core")
-		}
-	}
+	baseName, snaps := seedSnaps(model, opts, local)
 
 	if !opts.Classic {
-		// core/base,kernel,gadget first
-		snaps = append(snaps, baseName)
-		snaps = append(snaps, model.Kernel())
-		snaps = append(snaps, model.Gadget())
-	} else {
-		// classic image case: first core as needed and gadget
-		if classicHasSnaps(model, opts) {
-			// TODO: later use snapd+core16 or core18 if specified
-			snaps = append(snaps, "core")
-		}
-		if model.Gadget() != "" {
-			snaps = append(snaps, model.Gadget())
+		if err := os.MkdirAll(dirs.SnapBlobDir, 0755); err != nil {
+			return err
 		}
 	}
 
-	// then required and the user requested stuff
-	snaps = append(snaps, model.RequiredSnaps()...)
-	snaps = append(snaps, opts.Snaps...)
-
-	if !opts.Classic {
-		if err := os.MkdirAll(dirs.SnapBlobDir, 0755); err != nil {
+	reportPhase(opts, PhaseFetchSnaps)
+	var downloaded map[string]*acquiredSnap
+	if opts.DownloadConcurrency > 1 {
+		var toDownload []string
+		dlOpts := make(map[string]*DownloadOptions)
+		for _, snapName := range snaps {
+			name := local.Name(snapName)
+			if local.IsLocal(name) || dlOpts[name] != nil {
+				continue
+			}
+			snapDlOpts, err := snapDownloadOptions(name, snapSeedDir, model, opts, local)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(Stdout, "Fetching %s\n", name)
+			toDownload = append(toDownload, name)
+			dlOpts[name] = snapDlOpts
+		}
+		downloaded, err = downloadSnaps(tsto, toDownload, dlOpts, opts.DownloadConcurrency)
+		if err != nil {
 			return err
 		}
 	}
@@ -531,6 +818,8 @@ func setupSeed(tsto *ToolingStore, model *asserts.Model, opts *Options, local *l
 	var locals []string
 	downloadedSnapsInfoForBootConfig := map[string]*snap.Info{}
 	var seedYaml snap.Seed
+	var seededInfos []*snap.Info
+	var manifest SeedManifest
 	for _, snapName := range snaps {
 		name := local.Name(snapName)
 		if seen[name] {
@@ -540,22 +829,25 @@ func setupSeed(tsto *ToolingStore, model *asserts.Model, opts *Options, local *l
 
 		if local.IsLocal(name) {
 			fmt.Fprintf(Stdout, "Copying %q (%s)\n", local.Path(name), name)
-		} else {
+		} else if downloaded[name] == nil {
 			fmt.Fprintf(Stdout, "Fetching %s\n", name)
 		}
 
-		snapChannel, err := snapChannel(name, model, opts, local)
+		dlOpts, err := snapDownloadOptions(name, snapSeedDir, model, opts, local)
 		if err != nil {
 			return err
 		}
+		snapChannel := dlOpts.Channel
 
-		dlOpts := &DownloadOptions{
-			TargetDir: snapSeedDir,
-			Channel:   snapChannel,
-		}
-		fn, info, err := acquireSnap(tsto, name, dlOpts, local)
-		if err != nil {
-			return err
+		var fn string
+		var info *snap.Info
+		if dl := downloaded[name]; dl != nil {
+			fn, info = dl.fn, dl.info
+		} else {
+			fn, info, err = acquireSnap(tsto, name, dlOpts, local)
+			if err != nil {
+				return err
+			}
 		}
 
 		// Sanity check, note that we could support this case
@@ -576,6 +868,7 @@ func setupSeed(tsto *ToolingStore, model *asserts.Model, opts *Options, local *l
 		}
 
 		seen[name] = true
+		seededInfos = append(seededInfos, info)
 		typ := info.GetType()
 
 		needsClassic := info.NeedsClassic()
@@ -628,16 +921,22 @@ func setupSeed(tsto *ToolingStore, model *asserts.Model, opts *Options, local *l
 
 		// set seed.yaml
 		seedYaml.Snaps = append(seedYaml.Snaps, &snap.SeedSnap{
-			Name:    info.InstanceName(),
-			SnapID:  info.SnapID, // cross-ref
-			Channel: snapChannel,
-			File:    filepath.Base(fn),
-			DevMode: info.NeedsDevMode(),
-			Classic: needsClassic,
-			Contact: info.Contact,
+			Name:      info.InstanceName(),
+			SnapID:    info.SnapID, // cross-ref
+			Channel:   snapChannel,
+			CohortKey: dlOpts.CohortKey,
+			File:      filepath.Base(fn),
+			DevMode:   info.NeedsDevMode(),
+			Classic:   needsClassic,
+			Contact:   info.Contact,
 			// no assertions for this snap were put in the seed
 			Unasserted: info.SnapID == "",
 		})
+		if opts.SeedManifestPath != "" {
+			if err := manifest.add(fn, info, snapChannel, local.IsLocal(name)); err != nil {
+				return err
+			}
+		}
 	}
 	if len(locals) > 0 {
 		fmt.Fprintf(Stderr, "WARNING: %s were installed from local snaps disconnected from a store and cannot be refreshed subsequently!\n", strutil.Quoted(locals))
@@ -653,6 +952,17 @@ func setupSeed(tsto *ToolingStore, model *asserts.Model, opts *Options, local *l
 		}
 	}
 
+	if err := checkValidationSets(seededInfos, opts.validationSets); err != nil {
+		return err
+	}
+	if err := checkFDESupport(seededInfos, model, opts); err != nil {
+		return err
+	}
+	if err := checkAutoConnections(seededInfos, db, model, opts); err != nil {
+		return err
+	}
+
+	reportPhase(opts, PhaseWriteSeed)
 	for _, aRef := range f.addedRefs {
 		var afn string
 		// the names don't matter in practice as long as they don't conflict
@@ -673,10 +983,24 @@ func setupSeed(tsto *ToolingStore, model *asserts.Model, opts *Options, local *l
 
 	// TODO: add the refs as an assertions list of maps section to seed.yaml
 
+	if opts.ClassicPackages {
+		pkgs, err := classicPackagesManifest(dirs.GlobalRootDir)
+		if err != nil {
+			return err
+		}
+		seedYaml.ClassicPackages = pkgs
+	}
+	seedYaml.ValidationSets = seedValidationSets(opts.validationSets)
+
 	seedFn := filepath.Join(dirs.SnapSeedDir, "seed.yaml")
 	if err := seedYaml.Write(seedFn); err != nil {
 		return fmt.Errorf("cannot write seed.yaml: %s", err)
 	}
+	if opts.SeedManifestPath != "" {
+		if err := manifest.write(opts.SeedManifestPath); err != nil {
+			return err
+		}
+	}
 
 	if opts.Classic {
 		// warn about ownership if not root:root
@@ -789,66 +1113,128 @@ func copyLocalSnapFile(snapPath, targetDir string, info *snap.Info) (dstPath str
 	return dst, osutil.CopyFile(snapPath, dst, 0)
 }
 
-func ValidateSeed(seedFile string) error {
-	seed, err := snap.ReadSeedYaml(seedFile)
+var errNoSeedAssertions = errors.New("seed has no assertions, snaps cannot be cross-checked")
+
+// readSeedAssertions loads the assertions found in the seed
+// assertions directory into a database checked against the trusted
+// assertions, and returns it together with the model assertion.
+// It returns errNoSeedAssertions if the directory does not exist.
+func readSeedAssertions(assertSeedDir string) (*asserts.Database, *asserts.Model, error) {
+	dc, err := ioutil.ReadDir(assertSeedDir)
+	if os.IsNotExist(err) {
+		return nil, nil, errNoSeedAssertions
+	}
 	if err != nil {
-		return err
+		return nil, nil, fmt.Errorf("cannot read assertions seed directory: %v", err)
 	}
 
-	var errs []error
-	// read the snaps info
-	snapInfos := make(map[string]*snap.Info)
-	for _, seedSnap := range seed.Snaps {
-		fn := filepath.Join(filepath.Dir(seedFile), "snaps", seedSnap.File)
-		snapf, err := snap.Open(fn)
+	// collect
+	bs := asserts.NewMemoryBackstore()
+	var refs []*asserts.Ref
+	var modelRef *asserts.Ref
+	for _, fi := range dc {
+		f, err := os.Open(filepath.Join(assertSeedDir, fi.Name()))
 		if err != nil {
-			errs = append(errs, err)
-		} else {
-			info, err := snap.ReadInfoFromSnapFile(snapf, nil)
+			return nil, nil, fmt.Errorf("cannot read assertions: %v", err)
+		}
+		dec := asserts.NewDecoder(f)
+		for {
+			a, err := dec.Decode()
+			if err == io.EOF {
+				break
+			}
 			if err != nil {
-				errs = append(errs, fmt.Errorf("cannot use snap %s: %v", fn, err))
-			} else {
-				snapInfos[info.InstanceName()] = info
+				f.Close()
+				return nil, nil, fmt.Errorf("cannot decode assertions in %q: %v", fi.Name(), err)
+			}
+			if err := bs.Put(a.Type(), a); err != nil {
+				if revErr, ok := err.(*asserts.RevisionError); !ok || revErr.Current < a.Revision() {
+					f.Close()
+					return nil, nil, fmt.Errorf("cannot read assertions in %q: %v", fi.Name(), err)
+				}
+				// we already got something more recent
+				continue
+			}
+			ref := a.Ref()
+			if ref.Type == asserts.ModelType {
+				if modelRef != nil && modelRef.Unique() != ref.Unique() {
+					f.Close()
+					return nil, nil, fmt.Errorf("cannot have more than one model assertion in the seed")
+				}
+				modelRef = ref
 			}
+			refs = append(refs, ref)
 		}
+		f.Close()
 	}
-
-	// ensure we have either "core" or "snapd"
-	_, haveCore := snapInfos["core"]
-	_, haveSnapd := snapInfos["snapd"]
-	if !(haveCore || haveSnapd) {
-		errs = append(errs, fmt.Errorf("the core or snapd snap must be part of the seed"))
+	if modelRef == nil {
+		return nil, nil, fmt.Errorf("cannot find a model assertion in the seed")
 	}
 
-	// check that all bases/default-providers are part of the seed
-	for _, info := range snapInfos {
-		// ensure base is available
-		if info.Base != "" && info.Base != "none" {
-			if _, ok := snapInfos[info.Base]; !ok {
-				errs = append(errs, fmt.Errorf("cannot use snap %q: base %q is missing", info.InstanceName(), info.Base))
-			}
-		}
-		// ensure core is available
-		if info.Base == "" && info.SnapType == snap.TypeApp && info.InstanceName() != "snapd" {
-			if _, ok := snapInfos["core"]; !ok {
-				errs = append(errs, fmt.Errorf(`cannot use snap %q: required snap "core" missing`, info.InstanceName()))
-			}
+	// add them in prerequisite order, verifying their signatures
+	// along the way
+	db, err := asserts.OpenDatabase(&asserts.DatabaseConfig{
+		Backstore: asserts.NewMemoryBackstore(),
+		Trusted:   trusted,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	retrieve := func(ref *asserts.Ref) (asserts.Assertion, error) {
+		a, err := bs.Get(ref.Type, ref.PrimaryKey, ref.Type.MaxSupportedFormat())
+		if asserts.IsNotFound(err) {
+			return nil, fmt.Errorf("cannot find %s in the seed", ref)
 		}
-		// ensure default-providers are available
-		for _, dp := range neededDefaultProviders(info) {
-			if _, ok := snapInfos[dp]; !ok {
-				errs = append(errs, fmt.Errorf("cannot use snap %q: default provider %q is missing", info.InstanceName(), dp))
-			}
+		return a, err
+	}
+	f := asserts.NewFetcher(db, retrieve, db.Add)
+	for _, ref := range refs {
+		if err := f.Fetch(ref); err != nil {
+			return nil, nil, fmt.Errorf("cannot verify seed assertions: %v", err)
 		}
 	}
 
-	if errs != nil {
-		var buf bytes.Buffer
-		for _, err := range errs {
-			fmt.Fprintf(&buf, "\n- %s", err)
-		}
-		return fmt.Errorf("cannot validate seed:%s", buf.Bytes())
+	a, err := modelRef.Resolve(db.Find)
+	if err != nil {
+		return nil, nil, fmt.Errorf("internal error: cannot find just added assertion %v: %v", modelRef, err)
 	}
+	return db, a.(*asserts.Model), nil
+}
 
+// validateSeedSnapAssertions checks that the snap in the seed has
+// matching snap-declaration and snap-revision assertions, and
+// returns its snap-id.
+func validateSeedSnapAssertions(seedSnap *snap.SeedSnap, fn string, db *asserts.Database) (snapID string, err error) {
+	si, err := snapasserts.DeriveSideInfo(fn, db)
+	if asserts.IsNotFound(err) {
+		return "", fmt.Errorf("cannot use snap %q: no snap-revision or snap-declaration assertion matching the snap file", seedSnap.Name)
+	}
+	if err != nil {
+		return "", fmt.Errorf("cannot use snap %q: %v", seedSnap.Name, err)
+	}
+	if si.RealName != seedSnap.Name {
+		return "", fmt.Errorf("cannot use snap %q: assertions are for snap %q", seedSnap.Name, si.RealName)
+	}
+	return si.SnapID, nil
+}
+
+// validateSeedSnapArchitecture checks that the snap supports the
+// architecture it was declared for in the seed, and that that
+// architecture can be run on devices of the model.
+func validateSeedSnapArchitecture(seedSnap *snap.SeedSnap, info *snap.Info, model *asserts.Model) error {
+	declared := seedSnap.Architecture
+	if declared == "" {
+		if model == nil {
+			// nothing to check against
+			return nil
+		}
+		declared = model.Architecture()
+	}
+	if model != nil && !arch.IsCompatibleArchitecture(model.Architecture(), declared) {
+		return fmt.Errorf("cannot use snap %q: architecture %q is incompatible with the model architecture %q", info.InstanceName(), declared, model.Architecture())
+	}
+	if !strutil.ListContains(info.Architectures, declared) && !strutil.ListContains(info.Architectures, "all") {
+		return fmt.Errorf("cannot use snap %q: supported architectures (%s) do not include %q", info.InstanceName(), strings.Join(info.Architectures, ", "), declared)
+	}
 	return nil
 }
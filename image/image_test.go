@@ -27,11 +27,15 @@ import (
 	"net/url"
 	"os"
 	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	. "gopkg.in/check.v1"
+	"gopkg.in/yaml.v2"
 
 	"github.com/snapcore/snapd/asserts"
 	"github.com/snapcore/snapd/asserts/assertstest"
@@ -45,6 +49,7 @@ import (
 	"github.com/snapcore/snapd/snap"
 	"github.com/snapcore/snapd/snap/snaptest"
 	"github.com/snapcore/snapd/store"
+	"github.com/snapcore/snapd/strutil"
 	"github.com/snapcore/snapd/testutil"
 )
 
@@ -74,6 +79,7 @@ type imageSuite struct {
 
 	downloadedSnaps map[string]string
 	storeSnapInfo   map[string]*snap.Info
+	storeActionsMu  sync.Mutex
 	storeActions    []*store.SnapAction
 	tsto            *image.ToolingStore
 
@@ -184,7 +190,9 @@ func (s *imageSuite) SnapAction(_ context.Context, _ []*store.CurrentSnap, actio
 		return nil, fmt.Errorf("unexpected instance key in %q", actions[0].InstanceName)
 	}
 	// record
+	s.storeActionsMu.Lock()
 	s.storeActions = append(s.storeActions, actions[0])
+	s.storeActionsMu.Unlock()
 
 	if info, ok := s.storeSnapInfo[actions[0].InstanceName]; ok {
 		info.Channel = actions[0].Channel
@@ -194,10 +202,38 @@ func (s *imageSuite) SnapAction(_ context.Context, _ []*store.CurrentSnap, actio
 }
 
 func (s *imageSuite) Download(ctx context.Context, name, targetFn string, downloadInfo *snap.DownloadInfo, pbar progress.Meter, user *auth.UserState, dlOpts *store.DownloadOptions) error {
-	return osutil.CopyFile(s.downloadedSnaps[name], targetFn, 0)
+	fi, err := os.Stat(s.downloadedSnaps[name])
+	if err != nil {
+		return err
+	}
+	pbar.Start(name, float64(fi.Size()))
+	defer pbar.Finished()
+	if err := osutil.CopyFile(s.downloadedSnaps[name], targetFn, 0); err != nil {
+		return err
+	}
+	pbar.Set(float64(fi.Size()))
+	return nil
 }
 
 func (s *imageSuite) Assertion(assertType *asserts.AssertionType, primaryKey []string, user *auth.UserState) (asserts.Assertion, error) {
+	if assertType == asserts.ValidationSetType && len(primaryKey) == 3 {
+		// the latest sequence
+		as, err := s.storeSigning.FindMany(assertType, map[string]string{
+			"series":     primaryKey[0],
+			"account-id": primaryKey[1],
+			"name":       primaryKey[2],
+		})
+		if err != nil {
+			return nil, err
+		}
+		var latest *asserts.ValidationSet
+		for _, a := range as {
+			if vs := a.(*asserts.ValidationSet); latest == nil || vs.Sequence() > latest.Sequence() {
+				latest = vs
+			}
+		}
+		return latest, nil
+	}
 	ref := &asserts.Ref{Type: assertType, PrimaryKey: primaryKey}
 	return ref.Resolve(s.storeSigning.Find)
 }
@@ -578,58 +614,855 @@ func (s *imageSuite) setupSnaps(c *C, gadgetUnpackDir string, publishers map[str
 		s.addSystemSnapAssertions(c, "classic-gadget", publishers["classic-gadget"])
 	}
 
-	if _, ok := publishers["pc-kernel"]; ok {
-		s.downloadedSnaps["pc-kernel"] = snaptest.MakeTestSnapWithFiles(c, packageKernel, nil)
-		s.storeSnapInfo["pc-kernel"] = infoFromSnapYaml(c, packageKernel, snap.R(2))
-		s.addSystemSnapAssertions(c, "pc-kernel", publishers["pc-kernel"])
-	}
+	if _, ok := publishers["pc-kernel"]; ok {
+		s.downloadedSnaps["pc-kernel"] = snaptest.MakeTestSnapWithFiles(c, packageKernel, nil)
+		s.storeSnapInfo["pc-kernel"] = infoFromSnapYaml(c, packageKernel, snap.R(2))
+		s.addSystemSnapAssertions(c, "pc-kernel", publishers["pc-kernel"])
+	}
+
+	s.downloadedSnaps["core"] = snaptest.MakeTestSnapWithFiles(c, packageCore, nil)
+	s.storeSnapInfo["core"] = infoFromSnapYaml(c, packageCore, snap.R(3))
+	s.addSystemSnapAssertions(c, "core", "canonical")
+
+	s.downloadedSnaps["core18"] = snaptest.MakeTestSnapWithFiles(c, packageCore18, nil)
+	s.storeSnapInfo["core18"] = infoFromSnapYaml(c, packageCore18, snap.R(18))
+	s.addSystemSnapAssertions(c, "core18", "canonical")
+
+	s.downloadedSnaps["snapd"] = snaptest.MakeTestSnapWithFiles(c, snapdSnap, nil)
+	s.storeSnapInfo["snapd"] = infoFromSnapYaml(c, snapdSnap, snap.R(18))
+	s.addSystemSnapAssertions(c, "snapd", "canonical")
+
+	s.downloadedSnaps["other-base"] = snaptest.MakeTestSnapWithFiles(c, otherBase, nil)
+	s.storeSnapInfo["other-base"] = infoFromSnapYaml(c, otherBase, snap.R(18))
+	s.addSystemSnapAssertions(c, "other-base", "other")
+
+	s.downloadedSnaps["snap-req-core16-base"] = snaptest.MakeTestSnapWithFiles(c, snapReqCore16Base, nil)
+	s.storeSnapInfo["snap-req-core16-base"] = infoFromSnapYaml(c, snapReqCore16Base, snap.R(16))
+	s.addSystemSnapAssertions(c, "snap-req-core16-base", "other")
+
+	s.downloadedSnaps["required-snap1"] = snaptest.MakeTestSnapWithFiles(c, requiredSnap1, nil)
+	s.storeSnapInfo["required-snap1"] = infoFromSnapYaml(c, requiredSnap1, snap.R(3))
+	s.storeSnapInfo["required-snap1"].Contact = "foo@example.com"
+	s.addSystemSnapAssertions(c, "required-snap1", "other")
+
+	s.downloadedSnaps["snap-req-other-base"] = snaptest.MakeTestSnapWithFiles(c, snapReqOtherBase, nil)
+	s.storeSnapInfo["snap-req-other-base"] = infoFromSnapYaml(c, snapReqOtherBase, snap.R(5))
+	s.addSystemSnapAssertions(c, "snap-req-other-base", "other")
+
+	s.downloadedSnaps["snap-req-content-provider"] = snaptest.MakeTestSnapWithFiles(c, snapReqContentProvider, nil)
+	s.storeSnapInfo["snap-req-content-provider"] = infoFromSnapYaml(c, snapReqContentProvider, snap.R(5))
+	s.addSystemSnapAssertions(c, "snap-req-content-provider", "other")
+
+	s.downloadedSnaps["snap-base-none"] = snaptest.MakeTestSnapWithFiles(c, snapBaseNone, nil)
+	s.storeSnapInfo["snap-base-none"] = infoFromSnapYaml(c, snapBaseNone, snap.R(1))
+	s.addSystemSnapAssertions(c, "snap-base-none", "other")
+}
+
+func (s *imageSuite) TestSetupSeed(c *C) {
+	restore := image.MockTrusted(s.storeSigning.Trusted)
+	defer restore()
+
+	rootdir := filepath.Join(c.MkDir(), "imageroot")
+	seeddir := filepath.Join(rootdir, "var/lib/snapd/seed")
+	seedsnapsdir := filepath.Join(seeddir, "snaps")
+	seedassertsdir := filepath.Join(seeddir, "assertions")
+
+	gadgetUnpackDir := c.MkDir()
+	s.setupSnaps(c, gadgetUnpackDir, map[string]string{
+		"pc":        "canonical",
+		"pc-kernel": "canonical",
+	})
+
+	opts := &image.Options{
+		RootDir:         rootdir,
+		GadgetUnpackDir: gadgetUnpackDir,
+	}
+	local, err := image.LocalSnaps(s.tsto, opts)
+	c.Assert(err, IsNil)
+
+	err = image.SetupSeed(s.tsto, s.model, opts, local)
+	c.Assert(err, IsNil)
+
+	// check seed yaml
+	seed, err := snap.ReadSeedYaml(filepath.Join(seeddir, "seed.yaml"))
+	c.Assert(err, IsNil)
+
+	c.Check(seed.Snaps, HasLen, 4)
+
+	// check the files are in place
+	for i, name := range []string{"core", "pc-kernel", "pc"} {
+		info := s.storeSnapInfo[name]
+		fn := filepath.Base(info.MountFile())
+		p := filepath.Join(seedsnapsdir, fn)
+		c.Check(osutil.FileExists(p), Equals, true)
+
+		c.Check(seed.Snaps[i], DeepEquals, &snap.SeedSnap{
+			Name:   name,
+			SnapID: name + "-Id",
+			File:   fn,
+		})
+	}
+	c.Check(seed.Snaps[3].Name, Equals, "required-snap1")
+	c.Check(seed.Snaps[3].Contact, Equals, "foo@example.com")
+
+	storeAccountKey := s.storeSigning.StoreAccountKey("")
+	brandPubKey := s.brands.PublicKey("my-brand")
+
+	// check the assertions are in place
+	for _, fn := range []string{"model", brandPubKey.ID() + ".account-key", "my-brand.account", storeAccountKey.PublicKeyID() + ".account-key"} {
+		p := filepath.Join(seedassertsdir, fn)
+		c.Check(osutil.FileExists(p), Equals, true)
+	}
+
+	c.Check(filepath.Join(seedassertsdir, "model"), testutil.FileEquals, asserts.Encode(s.model))
+	b, err := ioutil.ReadFile(filepath.Join(seedassertsdir, "my-brand.account"))
+	c.Assert(err, IsNil)
+	a, err := asserts.Decode(b)
+	c.Assert(err, IsNil)
+	c.Check(a.Type(), Equals, asserts.AccountType)
+	c.Check(a.HeaderString("account-id"), Equals, "my-brand")
+
+	// check the snap assertions are also in place
+	for _, snapId := range []string{"pc-Id", "pc-kernel-Id", "core-Id"} {
+		p := filepath.Join(seedassertsdir, fmt.Sprintf("16,%s.snap-declaration", snapId))
+		c.Check(osutil.FileExists(p), Equals, true)
+	}
+
+	// check the bootloader config
+	m, err := s.bootloader.GetBootVars("snap_kernel", "snap_core", "snap_menuentry")
+	c.Assert(err, IsNil)
+	c.Check(m["snap_kernel"], Equals, "pc-kernel_2.snap")
+	c.Check(m["snap_core"], Equals, "core_3.snap")
+	c.Check(m["snap_menuentry"], Equals, "my display name")
+
+	c.Check(s.stderr.String(), Equals, "")
+}
+
+func (s *imageSuite) TestSetupSeedManifest(c *C) {
+	restore := image.MockTrusted(s.storeSigning.Trusted)
+	defer restore()
+
+	rootdir := filepath.Join(c.MkDir(), "imageroot")
+	manifestFn := filepath.Join(c.MkDir(), "seed.manifest")
+
+	gadgetUnpackDir := c.MkDir()
+	s.setupSnaps(c, gadgetUnpackDir, map[string]string{
+		"pc":        "canonical",
+		"pc-kernel": "canonical",
+	})
+
+	opts := &image.Options{
+		RootDir:          rootdir,
+		GadgetUnpackDir:  gadgetUnpackDir,
+		Channel:          "beta",
+		SeedManifestPath: manifestFn,
+	}
+	local, err := image.LocalSnaps(s.tsto, opts)
+	c.Assert(err, IsNil)
+
+	err = image.SetupSeed(s.tsto, s.model, opts, local)
+	c.Assert(err, IsNil)
+
+	data, err := ioutil.ReadFile(manifestFn)
+	c.Assert(err, IsNil)
+	var manifest image.SeedManifest
+	err = yaml.Unmarshal(data, &manifest)
+	c.Assert(err, IsNil)
+
+	c.Assert(manifest.Snaps, HasLen, 4)
+	for i, name := range []string{"core", "pc-kernel", "pc", "required-snap1"} {
+		info := s.storeSnapInfo[name]
+		digest, _, err := asserts.SnapFileSHA3_384(s.downloadedSnaps[name])
+		c.Assert(err, IsNil)
+		c.Check(manifest.Snaps[i], DeepEquals, &image.SeedManifestSnap{
+			Name:     name,
+			SnapID:   name + "-Id",
+			Revision: info.Revision,
+			Channel:  "beta",
+			SHA3_384: digest,
+		})
+	}
+}
+
+func (s *imageSuite) TestSetupSeedManifestLocalSnaps(c *C) {
+	restore := image.MockTrusted(s.storeSigning.Trusted)
+	defer restore()
+
+	rootdir := filepath.Join(c.MkDir(), "imageroot")
+	manifestFn := filepath.Join(c.MkDir(), "seed.manifest")
+
+	gadgetUnpackDir := c.MkDir()
+	s.setupSnaps(c, gadgetUnpackDir, map[string]string{
+		"pc":        "canonical",
+		"pc-kernel": "canonical",
+	})
+
+	// local snaps with store assertions, so with a store revision
+	opts := &image.Options{
+		Snaps: []string{
+			s.downloadedSnaps["core"],
+			s.downloadedSnaps["required-snap1"],
+		},
+		RootDir:          rootdir,
+		GadgetUnpackDir:  gadgetUnpackDir,
+		Channel:          "beta",
+		SeedManifestPath: manifestFn,
+	}
+	local, err := image.LocalSnaps(s.tsto, opts)
+	c.Assert(err, IsNil)
+
+	err = image.SetupSeed(s.tsto, s.model, opts, local)
+	c.Assert(err, IsNil)
+
+	data, err := ioutil.ReadFile(manifestFn)
+	c.Assert(err, IsNil)
+	var manifest image.SeedManifest
+	err = yaml.Unmarshal(data, &manifest)
+	c.Assert(err, IsNil)
+
+	c.Assert(manifest.Snaps, HasLen, 4)
+	for _, sn := range manifest.Snaps {
+		switch sn.Name {
+		case "core", "required-snap1":
+			c.Check(sn.Local, Equals, true, Commentf(sn.Name))
+			c.Check(sn.Channel, Equals, "", Commentf(sn.Name))
+			c.Check(sn.Revision, Equals, snap.R(3), Commentf(sn.Name))
+		default:
+			c.Check(sn.Local, Equals, false, Commentf(sn.Name))
+			c.Check(sn.Channel, Equals, "beta", Commentf(sn.Name))
+		}
+	}
+
+	// the manifest can be used to pin the revisions of the store
+	// snaps of another image built from the same local snaps
+	revs, err := image.ReadSnapRevisions(manifestFn)
+	c.Assert(err, IsNil)
+	c.Check(revs, DeepEquals, map[string]snap.Revision{
+		"pc-kernel": s.storeSnapInfo["pc-kernel"].Revision,
+		"pc":        s.storeSnapInfo["pc"].Revision,
+	})
+	opts.SnapRevisions = revs
+	c.Check(image.CheckSnapRevisions(s.model, opts, local), IsNil)
+}
+
+func (s *imageSuite) TestDryRun(c *C) {
+	rootdir := filepath.Join(c.MkDir(), "imageroot")
+
+	s.setupSnaps(c, "", map[string]string{
+		"pc":        "canonical",
+		"pc-kernel": "canonical",
+	})
+	s.storeSnapInfo["core"].Size = 90 * 1000 * 1000
+	s.storeSnapInfo["pc-kernel"].Size = 150 * 1000 * 1000
+	s.storeSnapInfo["pc"].Size = 2 * 1000 * 1000
+
+	localFn := snaptest.MakeTestSnapWithFiles(c, "name: local-snap\nversion: 1", nil)
+	fi, err := os.Stat(localFn)
+	c.Assert(err, IsNil)
+
+	opts := &image.Options{
+		RootDir:      rootdir,
+		Channel:      "stable",
+		Snaps:        []string{localFn},
+		SnapChannels: map[string]string{"pc-kernel": "edge"},
+	}
+	local, err := image.LocalSnaps(s.tsto, opts)
+	c.Assert(err, IsNil)
+
+	err = image.DryRun(s.tsto, s.model, opts, local)
+	c.Assert(err, IsNil)
+
+	total := 242*1000*1000 + s.storeSnapInfo["required-snap1"].Size + fi.Size()
+	c.Check(s.stdout.String(), Equals, fmt.Sprintf(`Name            Revision  Channel  Size   Notes
+core            3         stable   90MB   -
+pc-kernel       2         edge     150MB  -
+pc              1         stable   2MB    -
+required-snap1  3         stable   0B     -
+local-snap      x1        -        %-7slocal
+Total size: %s (%d bytes)
+`, strutil.SizeToStr(fi.Size()), strutil.SizeToStr(total), total))
+	// nothing was downloaded or written
+	c.Check(rootdir, testutil.FileAbsent)
+}
+
+func (s *imageSuite) TestDryRunSnapRevisions(c *C) {
+	s.setupSnaps(c, "", map[string]string{
+		"pc":        "canonical",
+		"pc-kernel": "canonical",
+	})
+
+	opts := &image.Options{
+		RootDir:       filepath.Join(c.MkDir(), "imageroot"),
+		Channel:       "stable",
+		SnapChannels:  map[string]string{"pc-kernel": "edge"},
+		SnapRevisions: map[string]snap.Revision{"pc-kernel": snap.R(2)},
+	}
+	local, err := image.LocalSnaps(s.tsto, opts)
+	c.Assert(err, IsNil)
+
+	err = image.DryRun(s.tsto, s.model, opts, local)
+	c.Assert(err, IsNil)
+	c.Check(s.stdout.String(), Matches, `(?s)Name            Revision  Channel  Size  Notes
+core            3         stable   0B    -
+pc-kernel       2         edge     0B    pinned
+.*`)
+	// the revision was asked for instead of the channel
+	c.Assert(s.storeActions, HasLen, 4)
+	c.Check(s.storeActions[1].InstanceName, Equals, "pc-kernel")
+	c.Check(s.storeActions[1].Revision, Equals, snap.R(2))
+	c.Check(s.storeActions[1].Channel, Equals, "")
+}
+
+func (s *imageSuite) TestSetupSeedCohorts(c *C) {
+	restore := image.MockTrusted(s.storeSigning.Trusted)
+	defer restore()
+
+	rootdir := filepath.Join(c.MkDir(), "imageroot")
+
+	gadgetUnpackDir := c.MkDir()
+	s.setupSnaps(c, gadgetUnpackDir, map[string]string{
+		"pc":        "canonical",
+		"pc-kernel": "canonical",
+	})
+
+	opts := &image.Options{
+		RootDir:         rootdir,
+		GadgetUnpackDir: gadgetUnpackDir,
+		Cohort:          "COHORT",
+		SnapCohorts:     map[string]string{"pc-kernel": "KERNEL-COHORT"},
+	}
+	local, err := image.LocalSnaps(s.tsto, opts)
+	c.Assert(err, IsNil)
+
+	err = image.SetupSeed(s.tsto, s.model, opts, local)
+	c.Assert(err, IsNil)
+
+	cohorts := make(map[string]string)
+	for _, a := range s.storeActions {
+		cohorts[a.InstanceName] = a.CohortKey
+	}
+	c.Check(cohorts, DeepEquals, map[string]string{
+		"core":           "COHORT",
+		"pc-kernel":      "KERNEL-COHORT",
+		"pc":             "COHORT",
+		"required-snap1": "COHORT",
+	})
+
+	// the cohorts are recorded in the seed for first boot
+	seed, err := snap.ReadSeedYaml(filepath.Join(rootdir, "var/lib/snapd/seed/seed.yaml"))
+	c.Assert(err, IsNil)
+	seedCohorts := make(map[string]string)
+	for _, sn := range seed.Snaps {
+		seedCohorts[sn.Name] = sn.CohortKey
+	}
+	c.Check(seedCohorts, DeepEquals, cohorts)
+}
+
+func (s *imageSuite) addValidationSet(c *C, name string, seq int, snaps ...map[string]interface{}) {
+	snapList := make([]interface{}, len(snaps))
+	for i, sn := range snaps {
+		snapList[i] = sn
+	}
+	vs, err := s.storeSigning.Sign(asserts.ValidationSetType, map[string]interface{}{
+		"series":     "16",
+		"account-id": "canonical",
+		"name":       name,
+		"sequence":   strconv.Itoa(seq),
+		"snaps":      snapList,
+		"timestamp":  time.Now().UTC().Format(time.RFC3339),
+	}, nil, "")
+	c.Assert(err, IsNil)
+	err = s.storeSigning.Add(vs)
+	c.Assert(err, IsNil)
+}
+
+func (s *imageSuite) TestSetupSeedSnapRevisions(c *C) {
+	restore := image.MockTrusted(s.storeSigning.Trusted)
+	defer restore()
+
+	rootdir := filepath.Join(c.MkDir(), "imageroot")
+
+	gadgetUnpackDir := c.MkDir()
+	s.setupSnaps(c, gadgetUnpackDir, map[string]string{
+		"pc":        "canonical",
+		"pc-kernel": "canonical",
+	})
+
+	opts := &image.Options{
+		RootDir:         rootdir,
+		GadgetUnpackDir: gadgetUnpackDir,
+		Cohort:          "COHORT",
+		SnapRevisions: map[string]snap.Revision{
+			"pc-kernel":      snap.R(2),
+			"required-snap1": snap.R(3),
+		},
+	}
+	local, err := image.LocalSnaps(s.tsto, opts)
+	c.Assert(err, IsNil)
+
+	// a pinned snap cannot come from a cohort
+	err = image.SetupSeed(s.tsto, s.model, opts, local)
+	c.Assert(err, ErrorMatches, `cannot use a cohort for snap "pc-kernel", it is pinned to revision 2`)
+
+	opts.Cohort = ""
+	opts.RootDir = filepath.Join(c.MkDir(), "imageroot")
+	s.storeActions = nil
+	err = image.SetupSeed(s.tsto, s.model, opts, local)
+	c.Assert(err, IsNil)
+
+	// the pinned revisions were asked for
+	revs := make(map[string]snap.Revision)
+	for _, a := range s.storeActions {
+		revs[a.InstanceName] = a.Revision
+	}
+	c.Check(revs, DeepEquals, map[string]snap.Revision{
+		"core":           {},
+		"pc-kernel":      snap.R(2),
+		"pc":             {},
+		"required-snap1": snap.R(3),
+	})
+}
+
+func (s *imageSuite) TestSetupSeedSnapRevisionsConflictWithValidationSets(c *C) {
+	restore := image.MockTrusted(s.storeSigning.Trusted)
+	defer restore()
+
+	gadgetUnpackDir := c.MkDir()
+	s.setupSnaps(c, gadgetUnpackDir, map[string]string{
+		"pc":        "canonical",
+		"pc-kernel": "canonical",
+	})
+	s.addValidationSet(c, "base-set", 1, map[string]interface{}{
+		"name":     "pc-kernel",
+		"id":       "pckernelidididididididididididid",
+		"revision": "2",
+	})
+
+	opts := &image.Options{
+		RootDir:         filepath.Join(c.MkDir(), "imageroot"),
+		GadgetUnpackDir: gadgetUnpackDir,
+		ValidationSets:  []string{"canonical/base-set"},
+		SnapRevisions:   map[string]snap.Revision{"pc-kernel": snap.R(5)},
+	}
+	local, err := image.LocalSnaps(s.tsto, opts)
+	c.Assert(err, IsNil)
+	err = image.ResolveValidationSets(s.tsto, opts)
+	c.Assert(err, IsNil)
+
+	err = image.SetupSeed(s.tsto, s.model, opts, local)
+	c.Assert(err, ErrorMatches, `cannot pin snap "pc-kernel" to revision 5, validation sets require revision 2`)
+}
+
+func (s *imageSuite) TestCheckSnapRevisions(c *C) {
+	coreFn := snaptest.MakeTestSnapWithFiles(c, packageCore, [][]string{{"local", ""}})
+
+	opts := &image.Options{
+		Snaps:         []string{coreFn},
+		SnapRevisions: map[string]snap.Revision{"pc": snap.R(1), "required-snap1": snap.R(2)},
+	}
+	local, err := image.LocalSnaps(s.tsto, opts)
+	c.Assert(err, IsNil)
+	c.Check(image.CheckSnapRevisions(s.model, opts, local), IsNil)
+
+	opts.SnapRevisions["other-snap"] = snap.R(3)
+	c.Check(image.CheckSnapRevisions(s.model, opts, local), ErrorMatches, `cannot pin the revision of snap "other-snap", it is not seeded`)
+
+	opts.SnapRevisions = map[string]snap.Revision{"core": snap.R(3)}
+	c.Check(image.CheckSnapRevisions(s.model, opts, local), ErrorMatches, `cannot pin the revision of local snap "core"`)
+}
+
+func (s *imageSuite) TestReadSnapRevisions(c *C) {
+	dir := c.MkDir()
+
+	fn := filepath.Join(dir, "pins.json")
+	err := ioutil.WriteFile(fn, []byte(`{"core": 12, "pc-kernel": "34"}`), 0644)
+	c.Assert(err, IsNil)
+	revs, err := image.ReadSnapRevisions(fn)
+	c.Assert(err, IsNil)
+	c.Check(revs, DeepEquals, map[string]snap.Revision{
+		"core":      snap.R(12),
+		"pc-kernel": snap.R(34),
+	})
+
+	// a seed manifest pins its store snaps
+	fn = filepath.Join(dir, "seed.manifest")
+	err = ioutil.WriteFile(fn, []byte(`snaps:
+- name: core
+  snap-id: core-id
+  revision: "12"
+  channel: stable
+  sha3-384: abc
+- name: local-snap
+  revision: x1
+  sha3-384: def
+- name: asserted-local-snap
+  snap-id: asserted-local-snap-id
+  revision: "7"
+  sha3-384: ghi
+  local: true
+`), 0644)
+	c.Assert(err, IsNil)
+	revs, err = image.ReadSnapRevisions(fn)
+	c.Assert(err, IsNil)
+	c.Check(revs, DeepEquals, map[string]snap.Revision{
+		"core": snap.R(12),
+	})
+
+	for _, t := range []struct {
+		content string
+		err     string
+	}{
+		{`["core"]`, `.*expected a mapping of snap names to revisions or a seed manifest: .*`},
+		{`{"core": "foo"}`, `.*expected a mapping of snap names to revisions or a seed manifest: .*`},
+		{`{"core": "x1"}`, `.*snap "core" must be pinned to a store revision, not x1`},
+		{`{"Core": 1}`, `.*invalid snap name: "Core"`},
+		{"snaps:\n- name: core\n  revision: 1\n- name: core\n  revision: 2\n", `.*snap "core" is listed more than once`},
+	} {
+		err := ioutil.WriteFile(fn, []byte(t.content), 0644)
+		c.Assert(err, IsNil)
+		_, err = image.ReadSnapRevisions(fn)
+		c.Check(err, ErrorMatches, `(?s)cannot read snap revisions from ".*/seed.manifest": `+t.err, Commentf(t.content))
+	}
+
+	_, err = image.ReadSnapRevisions(filepath.Join(dir, "missing"))
+	c.Check(err, ErrorMatches, `cannot read snap revisions: open .*/missing: no such file or directory`)
+}
+
+func (s *imageSuite) TestSetupSeedValidationSets(c *C) {
+	restore := image.MockTrusted(s.storeSigning.Trusted)
+	defer restore()
+
+	rootdir := filepath.Join(c.MkDir(), "imageroot")
+
+	gadgetUnpackDir := c.MkDir()
+	s.setupSnaps(c, gadgetUnpackDir, map[string]string{
+		"pc":        "canonical",
+		"pc-kernel": "canonical",
+	})
+	s.addValidationSet(c, "base-set", 1, map[string]interface{}{
+		"name":     "pc-kernel",
+		"id":       "pckernelidididididididididididid",
+		"revision": "2",
+	})
+	// only the latest sequence is used
+	s.addValidationSet(c, "base-set", 2, map[string]interface{}{
+		"name":     "pc-kernel",
+		"id":       "pckernelidididididididididididid",
+		"revision": "2",
+	}, map[string]interface{}{
+		"name":     "pc",
+		"id":       "pcidididididididididididididid01",
+		"presence": "optional",
+		"revision": "1",
+	})
+	s.addValidationSet(c, "other-set", 1, map[string]interface{}{
+		"name":     "required-snap1",
+		"id":       "requiredsnap1idididididididididi",
+		"revision": "3",
+	})
+
+	opts := &image.Options{
+		RootDir:         rootdir,
+		GadgetUnpackDir: gadgetUnpackDir,
+		ValidationSets:  []string{"canonical/base-set", "canonical/other-set=1"},
+	}
+	local, err := image.LocalSnaps(s.tsto, opts)
+	c.Assert(err, IsNil)
+	err = image.ResolveValidationSets(s.tsto, opts)
+	c.Assert(err, IsNil)
+
+	err = image.SetupSeed(s.tsto, s.model, opts, local)
+	c.Assert(err, IsNil)
+
+	// the required revisions were asked for
+	revs := make(map[string]snap.Revision)
+	for _, a := range s.storeActions {
+		revs[a.InstanceName] = a.Revision
+	}
+	c.Check(revs, DeepEquals, map[string]snap.Revision{
+		"core":           {},
+		"pc-kernel":      snap.R(2),
+		"pc":             snap.R(1),
+		"required-snap1": snap.R(3),
+	})
+
+	// the assertions are in the seed
+	seeddir := filepath.Join(rootdir, "var/lib/snapd/seed")
+	c.Check(filepath.Join(seeddir, "assertions", "16,canonical,base-set,2.validation-set"), testutil.FilePresent)
+	c.Check(filepath.Join(seeddir, "assertions", "16,canonical,other-set,1.validation-set"), testutil.FilePresent)
+	c.Check(filepath.Join(seeddir, "assertions", "16,canonical,base-set,1.validation-set"), testutil.FileAbsent)
+
+	seed, err := snap.ReadSeedYaml(filepath.Join(seeddir, "seed.yaml"))
+	c.Assert(err, IsNil)
+	c.Check(seed.ValidationSets, DeepEquals, []*snap.SeedValidationSet{
+		{AccountID: "canonical", Name: "base-set", Sequence: 2},
+		{AccountID: "canonical", Name: "other-set", Sequence: 1, Pinned: true},
+	})
+}
+
+func (s *imageSuite) TestSetupSeedValidationSetsUnsatisfied(c *C) {
+	restore := image.MockTrusted(s.storeSigning.Trusted)
+	defer restore()
+
+	rootdir := filepath.Join(c.MkDir(), "imageroot")
+
+	gadgetUnpackDir := c.MkDir()
+	s.setupSnaps(c, gadgetUnpackDir, map[string]string{
+		"pc":        "canonical",
+		"pc-kernel": "canonical",
+	})
+	s.addValidationSet(c, "base-set", 1, map[string]interface{}{
+		"name": "missing-snap",
+		"id":   "missingsnapidididididididididid1",
+	}, map[string]interface{}{
+		"name":     "required-snap1",
+		"id":       "requiredsnap1idididididididididi",
+		"presence": "invalid",
+	})
+
+	opts := &image.Options{
+		RootDir:         rootdir,
+		GadgetUnpackDir: gadgetUnpackDir,
+		ValidationSets:  []string{"canonical/base-set"},
+	}
+	local, err := image.LocalSnaps(s.tsto, opts)
+	c.Assert(err, IsNil)
+	err = image.ResolveValidationSets(s.tsto, opts)
+	c.Assert(err, IsNil)
+
+	err = image.SetupSeed(s.tsto, s.model, opts, local)
+	c.Check(err, ErrorMatches, `cannot seed snaps: validation sets assertions are not met:
+- missing required snaps:
+  - missing-snap \(required by sets canonical/base-set\)
+- invalid snaps:
+  - required-snap1 \(invalid for sets canonical/base-set\)`)
+	c.Check(filepath.Join(rootdir, "var/lib/snapd/seed/seed.yaml"), testutil.FileAbsent)
+}
+
+func (s *imageSuite) writeSeedAssertionFile(c *C, as ...asserts.Assertion) string {
+	buf := bytes.NewBuffer(nil)
+	enc := asserts.NewEncoder(buf)
+	for _, a := range as {
+		c.Assert(enc.Encode(a), IsNil)
+	}
+	fn := filepath.Join(c.MkDir(), "extra.assert")
+	err := ioutil.WriteFile(fn, buf.Bytes(), 0644)
+	c.Assert(err, IsNil)
+	return fn
+}
+
+func (s *imageSuite) TestSetupSeedSeedAssertions(c *C) {
+	restore := image.MockTrusted(s.storeSigning.Trusted)
+	defer restore()
+
+	rootdir := filepath.Join(c.MkDir(), "imageroot")
+
+	gadgetUnpackDir := c.MkDir()
+	s.setupSnaps(c, gadgetUnpackDir, map[string]string{
+		"pc":        "canonical",
+		"pc-kernel": "canonical",
+	})
+
+	// the operator account and key are known only from the file
+	acmeKey, _ := assertstest.GenerateKey(752)
+	acmeAcct := assertstest.NewAccount(s.storeSigning, "acme", map[string]interface{}{
+		"account-id": "acme",
+	}, "")
+	acmeAcctKey := assertstest.NewAccountKey(s.storeSigning, acmeAcct, nil, acmeKey.PublicKey(), "")
+	store, err := s.storeSigning.Sign(asserts.StoreType, map[string]interface{}{
+		"store":       "acme-store",
+		"operator-id": "acme",
+		"url":         "https://store.acme.example.com",
+		"timestamp":   time.Now().UTC().Format(time.RFC3339),
+	}, nil, "")
+	c.Assert(err, IsNil)
+	// the prerequisites of the system-user come from the store
+	user, err := s.brands.Signing("my-brand").Sign(asserts.SystemUserType, map[string]interface{}{
+		"authority-id": "my-brand",
+		"brand-id":     "my-brand",
+		"email":        "foo@bar.com",
+		"series":       []interface{}{"16"},
+		"models":       []interface{}{"my-model"},
+		"name":         "Boring Guy",
+		"username":     "guy",
+		"password":     "$6$salt$hash",
+		"since":        time.Now().UTC().Format(time.RFC3339),
+		"until":        time.Now().Add(24 * time.Hour).UTC().Format(time.RFC3339),
+	}, nil, "")
+	c.Assert(err, IsNil)
+
+	opts := &image.Options{
+		RootDir:         rootdir,
+		GadgetUnpackDir: gadgetUnpackDir,
+		SeedAssertionFiles: []string{
+			s.writeSeedAssertionFile(c, store, acmeAcctKey, acmeAcct),
+			s.writeSeedAssertionFile(c, user, store),
+		},
+	}
+	local, err := image.LocalSnaps(s.tsto, opts)
+	c.Assert(err, IsNil)
+
+	err = image.SetupSeed(s.tsto, s.model, opts, local)
+	c.Assert(err, IsNil)
+
+	assertsdir := filepath.Join(rootdir, "var/lib/snapd/seed/assertions")
+	c.Check(filepath.Join(assertsdir, "acme-store.store"), testutil.FileEquals, string(asserts.Encode(store)))
+	c.Check(filepath.Join(assertsdir, "acme.account"), testutil.FilePresent)
+	c.Check(filepath.Join(assertsdir, acmeAcctKey.PublicKeyID()+".account-key"), testutil.FilePresent)
+	c.Check(filepath.Join(assertsdir, "my-brand,foo@bar.com.system-user"), testutil.FileEquals, string(asserts.Encode(user)))
+	c.Check(filepath.Join(assertsdir, "my-brand.account"), testutil.FilePresent)
+}
+
+func (s *imageSuite) TestSetupSeedSeedAssertionsErrors(c *C) {
+	restore := image.MockTrusted(s.storeSigning.Trusted)
+	defer restore()
+
+	gadgetUnpackDir := c.MkDir()
+	s.setupSnaps(c, gadgetUnpackDir, map[string]string{
+		"pc":        "canonical",
+		"pc-kernel": "canonical",
+	})
+
+	// the key of the operator is not known anywhere
+	acmeKey, _ := assertstest.GenerateKey(752)
+	acmeSigning := assertstest.NewSigningDB("acme", acmeKey)
+	acmeAcct := assertstest.NewAccount(s.storeSigning, "acme", map[string]interface{}{
+		"account-id": "acme",
+	}, "")
+	unknownUser, err := acmeSigning.Sign(asserts.SystemUserType, map[string]interface{}{
+		"authority-id": "acme",
+		"brand-id":     "acme",
+		"email":        "foo@bar.com",
+		"series":       []interface{}{"16"},
+		"models":       []interface{}{"my-model"},
+		"name":         "Boring Guy",
+		"username":     "guy",
+		"password":     "$6$salt$hash",
+		"since":        time.Now().UTC().Format(time.RFC3339),
+		"until":        time.Now().Add(24 * time.Hour).UTC().Format(time.RFC3339),
+	}, nil, "")
+	c.Assert(err, IsNil)
+
+	garbage := filepath.Join(c.MkDir(), "garbage")
+	err = ioutil.WriteFile(garbage, []byte("garbage"), 0644)
+	c.Assert(err, IsNil)
+
+	for _, t := range []struct {
+		fn  string
+		err string
+	}{
+		{s.writeSeedAssertionFile(c, acmeAcct, unknownUser), `cannot add assertion system-user \(foo@bar.com; brand-id:acme\) to the seed: .*`},
+		{s.writeSeedAssertionFile(c, s.model), `cannot add model assertion from ".*/extra.assert" to the seed, the model is the one given to prepare the image`},
+		{garbage, `cannot decode assertions from ".*/garbage": .*`},
+		{filepath.Join(c.MkDir(), "missing"), `cannot read seed assertion file: open .*/missing: no such file or directory`},
+	} {
+		opts := &image.Options{
+			RootDir:            filepath.Join(c.MkDir(), "imageroot"),
+			GadgetUnpackDir:    gadgetUnpackDir,
+			SeedAssertionFiles: []string{t.fn},
+		}
+		local, err := image.LocalSnaps(s.tsto, opts)
+		c.Assert(err, IsNil)
+
+		err = image.SetupSeed(s.tsto, s.model, opts, local)
+		c.Check(err, ErrorMatches, t.err)
+	}
+}
+
+func (s *imageSuite) TestResolveValidationSetsErrors(c *C) {
+	restore := image.MockTrusted(s.storeSigning.Trusted)
+	defer restore()
+
+	s.addValidationSet(c, "base-set", 1, map[string]interface{}{
+		"name": "core",
+		"id":   "coreidididididididididididididid",
+	})
+
+	for _, t := range []struct {
+		sets    []string
+		offline bool
+		err     string
+	}{
+		{[]string{"canonical"}, false, `cannot parse validation set "canonical": expected account-id/name\[=seq\]`},
+		{[]string{"canonical/base-set=0"}, false, `cannot parse validation set "canonical/base-set=0": invalid sequence`},
+		{[]string{"canonical/base-set", "canonical/base-set=1"}, false, `cannot use validation set canonical/base-set more than once`},
+		{[]string{"canonical/base-set=2"}, false, `cannot fetch validation set canonical/base-set: .*not found`},
+		{[]string{"canonical/base-set"}, true, `cannot use validation set canonical/base-set without a sequence in offline mode`},
+	} {
+		opts := &image.Options{ValidationSets: t.sets, Offline: t.offline}
+		err := image.ResolveValidationSets(s.tsto, opts)
+		c.Check(err, ErrorMatches, t.err, Commentf("%v", t.sets))
+	}
+}
+
+func (s *imageSuite) TestSetupSeedRequireFDE(c *C) {
+	restore := image.MockTrusted(s.storeSigning.Trusted)
+	defer restore()
+
+	rootdir := filepath.Join(c.MkDir(), "imageroot")
+
+	gadgetUnpackDir := c.MkDir()
+	s.setupSnaps(c, gadgetUnpackDir, map[string]string{
+		"pc":        "canonical",
+		"pc-kernel": "canonical",
+	})
 
-	s.downloadedSnaps["core"] = snaptest.MakeTestSnapWithFiles(c, packageCore, nil)
-	s.storeSnapInfo["core"] = infoFromSnapYaml(c, packageCore, snap.R(3))
-	s.addSystemSnapAssertions(c, "core", "canonical")
+	opts := &image.Options{
+		RootDir:         rootdir,
+		GadgetUnpackDir: gadgetUnpackDir,
+		RequireFDE:      true,
+	}
+	local, err := image.LocalSnaps(s.tsto, opts)
+	c.Assert(err, IsNil)
 
-	s.downloadedSnaps["core18"] = snaptest.MakeTestSnapWithFiles(c, packageCore18, nil)
-	s.storeSnapInfo["core18"] = infoFromSnapYaml(c, packageCore18, snap.R(18))
-	s.addSystemSnapAssertions(c, "core18", "canonical")
+	err = image.SetupSeed(s.tsto, s.model, opts, local)
+	c.Check(err, ErrorMatches, `cannot prepare image for model my-brand/my-model with full disk encryption: neither the kernel snap "pc-kernel" nor the gadget snap "pc" has an "fde" hook, use a revision of one of them that implements it`)
+	c.Check(filepath.Join(rootdir, "var/lib/snapd/seed/seed.yaml"), testutil.FileAbsent)
+}
 
-	s.downloadedSnaps["snapd"] = snaptest.MakeTestSnapWithFiles(c, snapdSnap, nil)
-	s.storeSnapInfo["snapd"] = infoFromSnapYaml(c, snapdSnap, snap.R(18))
-	s.addSystemSnapAssertions(c, "snapd", "canonical")
+func (s *imageSuite) TestSetupSeedRequireFDEKernelHook(c *C) {
+	restore := image.MockTrusted(s.storeSigning.Trusted)
+	defer restore()
 
-	s.downloadedSnaps["other-base"] = snaptest.MakeTestSnapWithFiles(c, otherBase, nil)
-	s.storeSnapInfo["other-base"] = infoFromSnapYaml(c, otherBase, snap.R(18))
-	s.addSystemSnapAssertions(c, "other-base", "other")
+	rootdir := filepath.Join(c.MkDir(), "imageroot")
 
-	s.downloadedSnaps["snap-req-core16-base"] = snaptest.MakeTestSnapWithFiles(c, snapReqCore16Base, nil)
-	s.storeSnapInfo["snap-req-core16-base"] = infoFromSnapYaml(c, snapReqCore16Base, snap.R(16))
-	s.addSystemSnapAssertions(c, "snap-req-core16-base", "other")
+	gadgetUnpackDir := c.MkDir()
+	s.setupSnaps(c, gadgetUnpackDir, map[string]string{
+		"pc": "canonical",
+	})
+	kernelYaml := packageKernel + "hooks:\n  fde:\n"
+	s.downloadedSnaps["pc-kernel"] = snaptest.MakeTestSnapWithFiles(c, kernelYaml, nil)
+	s.storeSnapInfo["pc-kernel"] = infoFromSnapYaml(c, kernelYaml, snap.R(2))
+	s.addSystemSnapAssertions(c, "pc-kernel", "canonical")
 
-	s.downloadedSnaps["required-snap1"] = snaptest.MakeTestSnapWithFiles(c, requiredSnap1, nil)
-	s.storeSnapInfo["required-snap1"] = infoFromSnapYaml(c, requiredSnap1, snap.R(3))
-	s.storeSnapInfo["required-snap1"].Contact = "foo@example.com"
-	s.addSystemSnapAssertions(c, "required-snap1", "other")
+	opts := &image.Options{
+		RootDir:         rootdir,
+		GadgetUnpackDir: gadgetUnpackDir,
+		RequireFDE:      true,
+	}
+	local, err := image.LocalSnaps(s.tsto, opts)
+	c.Assert(err, IsNil)
 
-	s.downloadedSnaps["snap-req-other-base"] = snaptest.MakeTestSnapWithFiles(c, snapReqOtherBase, nil)
-	s.storeSnapInfo["snap-req-other-base"] = infoFromSnapYaml(c, snapReqOtherBase, snap.R(5))
-	s.addSystemSnapAssertions(c, "snap-req-other-base", "other")
+	err = image.SetupSeed(s.tsto, s.model, opts, local)
+	c.Assert(err, IsNil)
+	c.Check(filepath.Join(rootdir, "var/lib/snapd/seed/seed.yaml"), testutil.FilePresent)
+}
 
-	s.downloadedSnaps["snap-req-content-provider"] = snaptest.MakeTestSnapWithFiles(c, snapReqContentProvider, nil)
-	s.storeSnapInfo["snap-req-content-provider"] = infoFromSnapYaml(c, snapReqContentProvider, snap.R(5))
-	s.addSystemSnapAssertions(c, "snap-req-content-provider", "other")
+func (s *imageSuite) TestCheckFDESupportGadgetHook(c *C) {
+	kernel := snaptest.MockInfo(c, packageKernel, nil)
+	gadget := snaptest.MockInfo(c, packageGadget+"hooks:\n  fde:\n", nil)
 
-	s.downloadedSnaps["snap-base-none"] = snaptest.MakeTestSnapWithFiles(c, snapBaseNone, nil)
-	s.storeSnapInfo["snap-base-none"] = infoFromSnapYaml(c, snapBaseNone, snap.R(1))
-	s.addSystemSnapAssertions(c, "snap-base-none", "other")
+	c.Check(image.CheckFDESupport(s.model, kernel, gadget), IsNil)
+	c.Check(image.CheckFDESupport(s.model, kernel, nil), ErrorMatches, `cannot prepare image for model my-brand/my-model with full disk encryption: neither the kernel snap "pc-kernel" nor the gadget snap "pc" has an "fde" hook, .*`)
 }
 
-func (s *imageSuite) TestSetupSeed(c *C) {
+func (s *imageSuite) TestSetupSeedParallelDownloads(c *C) {
 	restore := image.MockTrusted(s.storeSigning.Trusted)
 	defer restore()
 
 	rootdir := filepath.Join(c.MkDir(), "imageroot")
 	seeddir := filepath.Join(rootdir, "var/lib/snapd/seed")
 	seedsnapsdir := filepath.Join(seeddir, "snaps")
-	seedassertsdir := filepath.Join(seeddir, "assertions")
 
 	gadgetUnpackDir := c.MkDir()
 	s.setupSnaps(c, gadgetUnpackDir, map[string]string{
@@ -638,8 +1471,10 @@ func (s *imageSuite) TestSetupSeed(c *C) {
 	})
 
 	opts := &image.Options{
-		RootDir:         rootdir,
-		GadgetUnpackDir: gadgetUnpackDir,
+		RootDir:             rootdir,
+		GadgetUnpackDir:     gadgetUnpackDir,
+		Snaps:               []string{"core", "snap-req-other-base", "other-base"},
+		DownloadConcurrency: 3,
 	}
 	local, err := image.LocalSnaps(s.tsto, opts)
 	c.Assert(err, IsNil)
@@ -647,59 +1482,119 @@ func (s *imageSuite) TestSetupSeed(c *C) {
 	err = image.SetupSeed(s.tsto, s.model, opts, local)
 	c.Assert(err, IsNil)
 
-	// check seed yaml
+	// every snap was downloaded exactly once
+	var downloaded []string
+	for _, a := range s.storeActions {
+		downloaded = append(downloaded, a.InstanceName)
+	}
+	sort.Strings(downloaded)
+	c.Check(downloaded, DeepEquals, []string{"core", "other-base", "pc", "pc-kernel", "required-snap1", "snap-req-other-base"})
+
+	// the seed keeps the usual order
 	seed, err := snap.ReadSeedYaml(filepath.Join(seeddir, "seed.yaml"))
 	c.Assert(err, IsNil)
-
-	c.Check(seed.Snaps, HasLen, 4)
-
-	// check the files are in place
-	for i, name := range []string{"core", "pc-kernel", "pc"} {
+	c.Assert(seed.Snaps, HasLen, 6)
+	for i, name := range []string{"core", "pc-kernel", "pc", "required-snap1", "snap-req-other-base", "other-base"} {
 		info := s.storeSnapInfo[name]
 		fn := filepath.Base(info.MountFile())
-		p := filepath.Join(seedsnapsdir, fn)
-		c.Check(osutil.FileExists(p), Equals, true)
-
-		c.Check(seed.Snaps[i], DeepEquals, &snap.SeedSnap{
-			Name:   name,
-			SnapID: name + "-Id",
-			File:   fn,
-		})
+		c.Check(osutil.FileExists(filepath.Join(seedsnapsdir, fn)), Equals, true)
+		c.Check(seed.Snaps[i].Name, Equals, name)
+		c.Check(seed.Snaps[i].SnapID, Equals, name+"-Id")
+		c.Check(seed.Snaps[i].File, Equals, fn)
 	}
-	c.Check(seed.Snaps[3].Name, Equals, "required-snap1")
-	c.Check(seed.Snaps[3].Contact, Equals, "foo@example.com")
 
-	storeAccountKey := s.storeSigning.StoreAccountKey("")
-	brandPubKey := s.brands.PublicKey("my-brand")
+	c.Check(s.stdout.String(), Equals, `Fetching core
+Fetching pc-kernel
+Fetching pc
+Fetching required-snap1
+Fetching snap-req-other-base
+Fetching other-base
+core already prepared, skipping
+`)
+}
 
-	// check the assertions are in place
-	for _, fn := range []string{"model", brandPubKey.ID() + ".account-key", "my-brand.account", storeAccountKey.PublicKeyID() + ".account-key"} {
-		p := filepath.Join(seedassertsdir, fn)
-		c.Check(osutil.FileExists(p), Equals, true)
-	}
+func (s *imageSuite) TestSetupSeedParallelDownloadsError(c *C) {
+	restore := image.MockTrusted(s.storeSigning.Trusted)
+	defer restore()
 
-	c.Check(filepath.Join(seedassertsdir, "model"), testutil.FileEquals, asserts.Encode(s.model))
-	b, err := ioutil.ReadFile(filepath.Join(seedassertsdir, "my-brand.account"))
-	c.Assert(err, IsNil)
-	a, err := asserts.Decode(b)
+	rootdir := filepath.Join(c.MkDir(), "imageroot")
+	gadgetUnpackDir := c.MkDir()
+	s.setupSnaps(c, gadgetUnpackDir, map[string]string{
+		"pc":        "canonical",
+		"pc-kernel": "canonical",
+	})
+
+	opts := &image.Options{
+		RootDir:             rootdir,
+		GadgetUnpackDir:     gadgetUnpackDir,
+		Snaps:               []string{"not-in-store"},
+		DownloadConcurrency: 2,
+	}
+	local, err := image.LocalSnaps(s.tsto, opts)
 	c.Assert(err, IsNil)
-	c.Check(a.Type(), Equals, asserts.AccountType)
-	c.Check(a.HeaderString("account-id"), Equals, "my-brand")
 
-	// check the snap assertions are also in place
-	for _, snapId := range []string{"pc-Id", "pc-kernel-Id", "core-Id"} {
-		p := filepath.Join(seedassertsdir, fmt.Sprintf("16,%s.snap-declaration", snapId))
-		c.Check(osutil.FileExists(p), Equals, true)
+	err = image.SetupSeed(s.tsto, s.model, opts, local)
+	c.Assert(err, ErrorMatches, `no "not-in-store" in the fake store`)
+}
+
+type recordingReporter struct {
+	mu     sync.Mutex
+	phases []image.Phase
+	done   map[string]bool
+}
+
+func (r *recordingReporter) Phase(phase image.Phase) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.phases = append(r.phases, phase)
+}
+
+func (r *recordingReporter) SnapDownload(name string, current, total float64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.done == nil {
+		r.done = make(map[string]bool)
 	}
+	r.done[name] = total > 0 && current == total
+}
 
-	// check the bootloader config
-	m, err := s.bootloader.GetBootVars("snap_kernel", "snap_core", "snap_menuentry")
-	c.Assert(err, IsNil)
-	c.Check(m["snap_kernel"], Equals, "pc-kernel_2.snap")
-	c.Check(m["snap_core"], Equals, "core_3.snap")
-	c.Check(m["snap_menuentry"], Equals, "my display name")
+func (s *imageSuite) TestSetupSeedProgressReporter(c *C) {
+	restore := image.MockTrusted(s.storeSigning.Trusted)
+	defer restore()
 
-	c.Check(s.stderr.String(), Equals, "")
+	gadgetUnpackDir := c.MkDir()
+	s.setupSnaps(c, gadgetUnpackDir, map[string]string{
+		"pc":        "canonical",
+		"pc-kernel": "canonical",
+	})
+
+	for _, concurrency := range []int{0, 3} {
+		rootdir := filepath.Join(c.MkDir(), "imageroot")
+		reporter := &recordingReporter{}
+		opts := &image.Options{
+			RootDir:             rootdir,
+			GadgetUnpackDir:     gadgetUnpackDir,
+			DownloadConcurrency: concurrency,
+			Progress:            reporter,
+		}
+		local, err := image.LocalSnaps(s.tsto, opts)
+		c.Assert(err, IsNil)
+
+		err = image.SetupSeed(s.tsto, s.model, opts, local)
+		c.Assert(err, IsNil)
+
+		c.Check(reporter.phases, DeepEquals, []image.Phase{
+			image.PhaseFetchAssertions,
+			image.PhaseFetchSnaps,
+			image.PhaseWriteSeed,
+		})
+		c.Check(reporter.done, DeepEquals, map[string]bool{
+			"core":           true,
+			"pc-kernel":      true,
+			"pc":             true,
+			"required-snap1": true,
+		})
+	}
 }
 
 func (s *imageSuite) TestSetupSeedLocalCoreBrandKernel(c *C) {
@@ -1383,6 +2278,33 @@ func (s *imageSuite) TestPrepareClassicModelSnapsButNoArchFails(c *C) {
 	c.Assert(err, ErrorMatches, "cannot have snaps for a classic image without an architecture in the model or from --arch")
 }
 
+func (s *imageSuite) TestPrepareOfflineNeedsLocalSnaps(c *C) {
+	fn := filepath.Join(c.MkDir(), "model.assertion")
+	err := ioutil.WriteFile(fn, asserts.Encode(s.model), 0644)
+	c.Assert(err, IsNil)
+
+	err = image.Prepare(&image.Options{
+		ModelFile: fn,
+		Channel:   "stable",
+		Offline:   true,
+		Snaps:     []string{"foo"},
+	})
+	c.Assert(err, ErrorMatches, `cannot use snap "foo" in offline mode, it must be provided as a local snap file`)
+}
+
+func (s *imageSuite) TestPrepareAssertionFilesNeedOffline(c *C) {
+	fn := filepath.Join(c.MkDir(), "model.assertion")
+	err := ioutil.WriteFile(fn, asserts.Encode(s.model), 0644)
+	c.Assert(err, IsNil)
+
+	err = image.Prepare(&image.Options{
+		ModelFile:      fn,
+		Channel:        "stable",
+		AssertionFiles: []string{"foo.assert"},
+	})
+	c.Assert(err, ErrorMatches, `cannot use assertion files without offline mode`)
+}
+
 func (s *imageSuite) TestSetupSeedWithKernelAndGadgetTrack(c *C) {
 	restore := image.MockTrusted(s.storeSigning.Trusted)
 	defer restore()
@@ -2118,6 +3040,97 @@ func (s *imageSuite) TestSetupSeedClassicNoSnaps(c *C) {
 	c.Check(osutil.FileExists(blobdir), Equals, false)
 }
 
+const mockDpkgStatus = `Package: zlib1g
+Status: install ok installed
+Priority: required
+Architecture: amd64
+Version: 1:1.2.11.dfsg-1ubuntu2
+Description: compression library - runtime
+ zlib is a library implementing the deflate compression method found
+ in gzip and PKZIP.
+
+Package: bash
+Status: install ok installed
+Architecture: amd64
+Version: 4.4.18-2ubuntu1
+
+Package: removed-pkg
+Status: deinstall ok config-files
+Architecture: amd64
+Version: 1.0
+
+Package: libc6
+Status: install ok installed
+Architecture: i386
+Version: 2.27-3ubuntu1
+`
+
+func (s *imageSuite) TestSetupSeedClassicPackages(c *C) {
+	restore := image.MockTrusted(s.storeSigning.Trusted)
+	defer restore()
+
+	model := s.brands.Model("my-brand", "my-model", map[string]interface{}{
+		"classic": "true",
+	})
+
+	rootdir := filepath.Join(c.MkDir(), "classic-image-root")
+	c.Assert(os.MkdirAll(filepath.Join(rootdir, "var/lib/dpkg"), 0755), IsNil)
+	c.Assert(ioutil.WriteFile(filepath.Join(rootdir, "var/lib/dpkg/status"), []byte(mockDpkgStatus), 0644), IsNil)
+
+	opts := &image.Options{
+		Classic:         true,
+		RootDir:         rootdir,
+		ClassicPackages: true,
+	}
+	local, err := image.LocalSnaps(s.tsto, opts)
+	c.Assert(err, IsNil)
+
+	err = image.SetupSeed(s.tsto, model, opts, local)
+	c.Assert(err, IsNil)
+
+	seed, err := snap.ReadSeedYaml(filepath.Join(rootdir, "var/lib/snapd/seed/seed.yaml"))
+	c.Assert(err, IsNil)
+	c.Check(seed.Snaps, HasLen, 0)
+	c.Check(seed.ClassicPackages, DeepEquals, []*snap.SeedClassicPackage{
+		{Name: "bash", Version: "4.4.18-2ubuntu1", Architecture: "amd64"},
+		{Name: "libc6", Version: "2.27-3ubuntu1", Architecture: "i386"},
+		{Name: "zlib1g", Version: "1:1.2.11.dfsg-1ubuntu2", Architecture: "amd64"},
+	})
+}
+
+func (s *imageSuite) TestSetupSeedClassicPackagesNoDpkg(c *C) {
+	restore := image.MockTrusted(s.storeSigning.Trusted)
+	defer restore()
+
+	model := s.brands.Model("my-brand", "my-model", map[string]interface{}{
+		"classic": "true",
+	})
+
+	rootdir := filepath.Join(c.MkDir(), "classic-image-root")
+	opts := &image.Options{
+		Classic:         true,
+		RootDir:         rootdir,
+		ClassicPackages: true,
+	}
+	local, err := image.LocalSnaps(s.tsto, opts)
+	c.Assert(err, IsNil)
+
+	err = image.SetupSeed(s.tsto, model, opts, local)
+	c.Assert(err, ErrorMatches, "cannot read classic packages manifest: .*/var/lib/dpkg/status: no such file or directory")
+}
+
+func (s *imageSuite) TestPrepareClassicPackagesNeedsClassic(c *C) {
+	fn := filepath.Join(c.MkDir(), "model.assertion")
+	err := ioutil.WriteFile(fn, asserts.Encode(s.model), 0644)
+	c.Assert(err, IsNil)
+
+	err = image.Prepare(&image.Options{
+		ModelFile:       fn,
+		ClassicPackages: true,
+	})
+	c.Assert(err, ErrorMatches, "cannot record the classic packages manifest without --classic mode")
+}
+
 func (s *imageSuite) TestSnapChannel(c *C) {
 	model := s.brands.Model("my-brand", "my-model", map[string]interface{}{
 		"architecture": "amd64",
@@ -2198,6 +3211,119 @@ func (s *imageSuite) TestSetupSeedLocalSnapd(c *C) {
 	c.Assert(s.stdout.String(), Matches, `(?m)Copying ".*/snapd_3.14_all.snap" \(snapd\)`)
 }
 
+const packageAutoConnect = `
+name: auto-connect
+version: 1.0
+plugs:
+  network:
+  camera:
+`
+
+func (s *imageSuite) setupAutoConnect(c *C) ([]*snap.Info, *asserts.Database) {
+	decl, err := s.storeSigning.Sign(asserts.SnapDeclarationType, map[string]interface{}{
+		"series":       "16",
+		"snap-id":      "auto-connect-Id",
+		"snap-name":    "auto-connect",
+		"publisher-id": "other",
+		"timestamp":    time.Now().UTC().Format(time.RFC3339),
+	}, nil, "")
+	c.Assert(err, IsNil)
+	err = s.storeSigning.Add(decl)
+	c.Assert(err, IsNil)
+
+	infos := []*snap.Info{
+		infoFromSnapYaml(c, packageCore, snap.R(0)),
+		infoFromSnapYaml(c, packageAutoConnect, snap.R(1)),
+	}
+	return infos, s.storeSigning.Database
+}
+
+func (s *imageSuite) TestCheckAutoConnectionsWarns(c *C) {
+	infos, db := s.setupAutoConnect(c)
+
+	err := image.CheckAutoConnections(infos, db, s.model, &image.Options{})
+	c.Assert(err, IsNil)
+	// network is auto-connected, camera is not
+	c.Check(s.stderr.String(), Equals, `WARNING: plug auto-connect:camera (interface "camera"): no slot it can be auto-connected to
+`)
+}
+
+func (s *imageSuite) TestCheckAutoConnectionsRequired(c *C) {
+	infos, db := s.setupAutoConnect(c)
+
+	err := image.CheckAutoConnections(infos, db, s.model, &image.Options{
+		RequireAutoConnections: true,
+	})
+	c.Assert(err, ErrorMatches, `cannot prepare image with plugs that will not be connected on first boot:
+- plug auto-connect:camera \(interface "camera"\): no slot it can be auto-connected to`)
+	c.Check(s.stderr.String(), Equals, "")
+}
+
+func writeGadgetConnections(c *C, connections string) (gadgetUnpackDir string) {
+	gadgetUnpackDir = c.MkDir()
+	err := os.MkdirAll(filepath.Join(gadgetUnpackDir, "meta"), 0755)
+	c.Assert(err, IsNil)
+	err = ioutil.WriteFile(filepath.Join(gadgetUnpackDir, "meta", "gadget.yaml"), []byte(`
+volumes:
+  pc:
+    bootloader: grub
+connections:
+`+connections), 0644)
+	c.Assert(err, IsNil)
+	return gadgetUnpackDir
+}
+
+func (s *imageSuite) TestCheckAutoConnectionsGadgetConnections(c *C) {
+	infos, db := s.setupAutoConnect(c)
+
+	gadgetUnpackDir := writeGadgetConnections(c, `
+  - plug: auto-connect-Id:camera
+  - plug: other-snap-Id:camera
+`)
+
+	err := image.CheckAutoConnections(infos, db, s.model, &image.Options{
+		GadgetUnpackDir:        gadgetUnpackDir,
+		RequireAutoConnections: true,
+	})
+	c.Assert(err, IsNil)
+	c.Check(s.stderr.String(), Equals, "")
+}
+
+func (s *imageSuite) TestCheckAutoConnectionsGadgetConnectionsInvalid(c *C) {
+	infos, db := s.setupAutoConnect(c)
+
+	tests := []struct {
+		connections string
+		err         string
+	}{
+		{`
+  - plug: auto-connect-Id:unknown
+    slot: system:camera
+`, `cannot use gadget connection of plug auto-connect-Id:unknown to slot system:camera: snap "auto-connect" has no plug "unknown"`},
+		{`
+  - plug: auto-connect-Id:camera
+    slot: system:unknown
+`, `cannot use gadget connection of plug auto-connect-Id:camera to slot system:unknown: snap "core" has no slot "unknown"`},
+		{`
+  - plug: auto-connect-Id:camera
+    slot: system:network
+`, `cannot use gadget connection of plug auto-connect-Id:camera to slot system:network: plug interface "camera" does not match slot interface "network"`},
+		{`
+  - plug: auto-connect-Id:camera
+    slot-attributes:
+      path: /dev/video0
+`, `cannot use gadget connection of plug auto-connect-Id:camera to slot system:camera: slot attribute "path" is not set, expected /dev/video0`},
+	}
+
+	for _, t := range tests {
+		gadgetUnpackDir := writeGadgetConnections(c, t.connections)
+		err := image.CheckAutoConnections(infos, db, s.model, &image.Options{
+			GadgetUnpackDir: gadgetUnpackDir,
+		})
+		c.Check(err, ErrorMatches, t.err, Commentf(t.connections))
+	}
+}
+
 type toolingStoreContextSuite struct {
 	sc store.DeviceAndAuthContext
 }
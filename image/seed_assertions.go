@@ -0,0 +1,113 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2019 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package image
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/snapcore/snapd/asserts"
+)
+
+// readSeedAssertionFiles reads the assertions from the given files,
+// keeping only the latest revision of each.
+func readSeedAssertionFiles(files []string) (asserts.Backstore, []*asserts.Ref, error) {
+	bs := asserts.NewMemoryBackstore()
+	var refs []*asserts.Ref
+	seen := make(map[string]bool)
+	for _, fn := range files {
+		err := decodeSeedAssertionFile(bs, fn, func(ref *asserts.Ref) {
+			if !seen[ref.Unique()] {
+				seen[ref.Unique()] = true
+				refs = append(refs, ref)
+			}
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	return bs, refs, nil
+}
+
+func decodeSeedAssertionFile(bs asserts.Backstore, fn string, add func(*asserts.Ref)) error {
+	f, err := os.Open(fn)
+	if err != nil {
+		return fmt.Errorf("cannot read seed assertion file: %v", err)
+	}
+	defer f.Close()
+
+	dec := asserts.NewDecoder(f)
+	for {
+		a, err := dec.Decode()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("cannot decode assertions from %q: %v", fn, err)
+		}
+		if a.Type() == asserts.ModelType {
+			return fmt.Errorf("cannot add model assertion from %q to the seed, the model is the one given to prepare the image", fn)
+		}
+		if err := bs.Put(a.Type(), a); err != nil {
+			if _, ok := err.(*asserts.RevisionError); ok {
+				// same or newer revision already provided
+				continue
+			}
+			return fmt.Errorf("cannot use assertion %v from %q: %v", a.Ref(), fn, err)
+		}
+		add(a.Ref())
+	}
+}
+
+// addSeedAssertions adds to the seed the assertions from the given
+// files together with their prerequisites, which are taken from the
+// files themselves or fetched. The signatures of all of them are
+// checked along the way.
+func addSeedAssertions(tsto *ToolingStore, db *asserts.Database, f *addingFetcher, files []string) error {
+	bs, refs, err := readSeedAssertionFiles(files)
+	if err != nil {
+		return err
+	}
+	retrieve := func(ref *asserts.Ref) (asserts.Assertion, error) {
+		a, err := bs.Get(ref.Type, ref.PrimaryKey, ref.Type.MaxSupportedFormat())
+		if asserts.IsNotFound(err) {
+			return tsto.sto.Assertion(ref.Type, ref.PrimaryKey, tsto.user)
+		}
+		return a, err
+	}
+	save := func(a asserts.Assertion) error {
+		if err := db.Add(a); err != nil {
+			if _, ok := err.(*asserts.RevisionError); ok {
+				return nil
+			}
+			return err
+		}
+		f.addedRefs = append(f.addedRefs, a.Ref())
+		return nil
+	}
+	sf := asserts.NewFetcher(db, retrieve, save)
+	for _, ref := range refs {
+		if err := sf.Fetch(ref); err != nil {
+			return fmt.Errorf("cannot add assertion %v to the seed: %v", ref, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2019 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package image
+
+import (
+	"fmt"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/snapcore/snapd/asserts"
+	"github.com/snapcore/snapd/osutil"
+	"github.com/snapcore/snapd/snap"
+)
+
+// SeedManifest records the exact snaps that were seeded into an
+// image.
+type SeedManifest struct {
+	Snaps []*SeedManifestSnap `yaml:"snaps"`
+}
+
+// SeedManifestSnap is a seeded snap as recorded in the seed manifest.
+type SeedManifestSnap struct {
+	Name     string        `yaml:"name"`
+	SnapID   string        `yaml:"snap-id,omitempty"`
+	Revision snap.Revision `yaml:"revision"`
+	// Channel is empty for local snaps.
+	Channel  string `yaml:"channel,omitempty"`
+	SHA3_384 string `yaml:"sha3-384"`
+	// Local is set for snaps provided as files, even the ones with
+	// assertions and so a store revision.
+	Local bool `yaml:"local,omitempty"`
+}
+
+func (m *SeedManifest) add(fn string, info *snap.Info, channel string, local bool) error {
+	sha3_384, _, err := asserts.SnapFileSHA3_384(fn)
+	if err != nil {
+		return fmt.Errorf("cannot compute digest of snap %q for the seed manifest: %v", info.InstanceName(), err)
+	}
+	sn := &SeedManifestSnap{
+		Name:     info.InstanceName(),
+		SnapID:   info.SnapID,
+		Revision: info.Revision,
+		SHA3_384: sha3_384,
+		Local:    local,
+	}
+	if !local {
+		sn.Channel = channel
+	}
+	m.Snaps = append(m.Snaps, sn)
+	return nil
+}
+
+func (m *SeedManifest) write(fn string) error {
+	data, err := yaml.Marshal(m)
+	if err != nil {
+		return err
+	}
+	if err := osutil.AtomicWriteFile(fn, data, 0644, 0); err != nil {
+		return fmt.Errorf("cannot write seed manifest: %v", err)
+	}
+	return nil
+}
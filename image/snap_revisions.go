@@ -0,0 +1,94 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2019 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package image
+
+import (
+	"fmt"
+	"io/ioutil"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/snapcore/snapd/asserts"
+	"github.com/snapcore/snapd/snap"
+)
+
+// ReadSnapRevisions reads the revisions to pin the store snaps to from
+// the given file. The file maps snap names to revisions, in JSON or
+// YAML, or it is a seed manifest as written for
+// Options.SeedManifestPath, in which case its store snaps are pinned
+// to the revisions it records.
+func ReadSnapRevisions(fn string) (map[string]snap.Revision, error) {
+	data, err := ioutil.ReadFile(fn)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read snap revisions: %v", err)
+	}
+
+	var manifest SeedManifest
+	if err := yaml.UnmarshalStrict(data, &manifest); err == nil && len(manifest.Snaps) != 0 {
+		revisions := make(map[string]snap.Revision, len(manifest.Snaps))
+		for _, sn := range manifest.Snaps {
+			if sn.Local || sn.Revision.Local() {
+				// local snaps are provided again as files
+				continue
+			}
+			if _, ok := revisions[sn.Name]; ok {
+				return nil, fmt.Errorf("cannot read snap revisions from %q: snap %q is listed more than once", fn, sn.Name)
+			}
+			revisions[sn.Name] = sn.Revision
+		}
+		return revisions, nil
+	}
+
+	var revisions map[string]snap.Revision
+	if err := yaml.UnmarshalStrict(data, &revisions); err != nil {
+		return nil, fmt.Errorf("cannot read snap revisions from %q: expected a mapping of snap names to revisions or a seed manifest: %v", fn, err)
+	}
+	for name, rev := range revisions {
+		if err := snap.ValidateInstanceName(name); err != nil {
+			return nil, fmt.Errorf("cannot read snap revisions from %q: %v", fn, err)
+		}
+		if !rev.Store() {
+			return nil, fmt.Errorf("cannot read snap revisions from %q: snap %q must be pinned to a store revision, not %s", fn, name, rev)
+		}
+	}
+	return revisions, nil
+}
+
+// checkSnapRevisions checks that the snaps with pinned revisions are
+// seeded and come from the store.
+func checkSnapRevisions(model *asserts.Model, opts *Options, local *localInfos) error {
+	if len(opts.SnapRevisions) == 0 {
+		return nil
+	}
+	_, snaps := seedSnaps(model, opts, local)
+	seeded := make(map[string]bool, len(snaps))
+	for _, snapName := range snaps {
+		seeded[local.Name(snapName)] = true
+	}
+	for name := range opts.SnapRevisions {
+		if local.IsLocal(name) {
+			return fmt.Errorf("cannot pin the revision of local snap %q", name)
+		}
+		if !seeded[name] {
+			return fmt.Errorf("cannot pin the revision of snap %q, it is not seeded", name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,622 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2019 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package image
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/snapcore/snapd/arch"
+	"github.com/snapcore/snapd/asserts"
+	"github.com/snapcore/snapd/gadget"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/snap/squashfs"
+	"github.com/snapcore/snapd/strutil"
+)
+
+// Severities of seed validation findings.
+const (
+	SeedFindingError   = "error"
+	SeedFindingWarning = "warning"
+)
+
+// Codes of seed validation findings.
+const (
+	// the seed assertions cannot be read or verified
+	SeedCodeInvalidAssertions = "invalid-assertions"
+	// the seed has no assertions, snaps cannot be cross-checked
+	SeedCodeNoAssertions = "no-assertions"
+	// a snap of the seed cannot be opened
+	SeedCodeSnapMissing = "snap-missing"
+	// a snap of the seed has invalid metadata
+	SeedCodeSnapInvalid = "snap-invalid"
+	// a snap does not support the architecture it is used for
+	SeedCodeArchitectureMismatch = "architecture-mismatch"
+	// a snap does not match its assertions
+	SeedCodeAssertionsMismatch = "assertions-mismatch"
+	// a snap is not backed by assertions
+	SeedCodeSnapUnasserted = "snap-unasserted"
+	// neither the core nor the snapd snap are in the seed
+	SeedCodeMissingCore = "missing-core"
+	// the base of a snap is not in the seed
+	SeedCodeMissingBase = "missing-base"
+	// a default provider of a snap is not in the seed
+	SeedCodeMissingDefaultProvider = "missing-default-provider"
+	// no snap of the seed has a content slot matching a content plug
+	SeedCodeMissingContentSlot = "missing-content-slot"
+	// a snap of the seed failed the integrity check
+	SeedCodeSnapCorrupted = "snap-corrupted"
+	// a snap is listed more than once in the seed
+	SeedCodeDuplicateSnap = "duplicate-snap"
+	// a file is used by more than one snap of the seed
+	SeedCodeDuplicateFile = "duplicate-file"
+	// a file in the snaps directory is not used by the seed
+	SeedCodeOrphanedFile = "orphaned-file"
+	// assertions in the seed are for a snap not in the seed
+	SeedCodeOrphanedAssertions = "orphaned-assertions"
+	// the kernel or gadget of the model is not in the seed
+	SeedCodeMissingModelSnap = "missing-model-snap"
+	// a kernel or gadget snap of the seed does not match the model
+	SeedCodeModelSnapMismatch = "model-snap-mismatch"
+	// the gadget.yaml of the gadget snap is invalid
+	SeedCodeInvalidGadget = "invalid-gadget"
+	// the gadget defaults are for a snap not in the seed
+	SeedCodeGadgetDefaultsMismatch = "gadget-defaults-mismatch"
+	// a snap of an Ubuntu Core seed uses classic confinement
+	SeedCodeClassicSnap = "classic-snap"
+	// a snap of an Ubuntu Core seed uses devmode confinement
+	SeedCodeDevModeSnap = "devmode-snap"
+)
+
+// knownSeedCodes are all the codes of seed validation findings.
+var knownSeedCodes = map[string]bool{
+	SeedCodeInvalidAssertions:      true,
+	SeedCodeNoAssertions:           true,
+	SeedCodeSnapMissing:            true,
+	SeedCodeSnapInvalid:            true,
+	SeedCodeArchitectureMismatch:   true,
+	SeedCodeAssertionsMismatch:     true,
+	SeedCodeSnapUnasserted:         true,
+	SeedCodeMissingCore:            true,
+	SeedCodeMissingBase:            true,
+	SeedCodeMissingDefaultProvider: true,
+	SeedCodeMissingContentSlot:     true,
+	SeedCodeSnapCorrupted:          true,
+	SeedCodeDuplicateSnap:          true,
+	SeedCodeDuplicateFile:          true,
+	SeedCodeOrphanedFile:           true,
+	SeedCodeOrphanedAssertions:     true,
+	SeedCodeMissingModelSnap:       true,
+	SeedCodeModelSnapMismatch:      true,
+	SeedCodeInvalidGadget:          true,
+	SeedCodeGadgetDefaultsMismatch: true,
+	SeedCodeClassicSnap:            true,
+	SeedCodeDevModeSnap:            true,
+}
+
+// SeedFinding is a problem found while validating a seed.
+type SeedFinding struct {
+	Severity string `json:"severity"`
+	Code     string `json:"code"`
+	// Snap is the name of the offending snap, if any.
+	Snap string `json:"snap,omitempty"`
+	// Path is the offending file, if any.
+	Path    string `json:"path,omitempty"`
+	Message string `json:"message"`
+}
+
+// SeedReport holds the findings of validating a seed.
+type SeedReport struct {
+	Seed     string         `json:"seed"`
+	Findings []*SeedFinding `json:"findings"`
+
+	opts *ValidateOptions
+}
+
+func (r *SeedReport) add(severity, code, snapName, path string, err error) {
+	if r.opts != nil {
+		if strutil.ListContains(r.opts.Ignore, code) {
+			return
+		}
+		if override, ok := r.opts.Severities[code]; ok {
+			severity = override
+		}
+	}
+	r.Findings = append(r.Findings, &SeedFinding{
+		Severity: severity,
+		Code:     code,
+		Snap:     snapName,
+		Path:     path,
+		Message:  err.Error(),
+	})
+}
+
+func (r *SeedReport) withSeverity(severity string) []*SeedFinding {
+	var findings []*SeedFinding
+	for _, f := range r.Findings {
+		if f.Severity == severity {
+			findings = append(findings, f)
+		}
+	}
+	return findings
+}
+
+// Errors returns the findings of error severity.
+func (r *SeedReport) Errors() []*SeedFinding {
+	return r.withSeverity(SeedFindingError)
+}
+
+// Warnings returns the findings of warning severity.
+func (r *SeedReport) Warnings() []*SeedFinding {
+	return r.withSeverity(SeedFindingWarning)
+}
+
+// Err returns an error summarizing the findings of error severity,
+// or nil if there are none.
+func (r *SeedReport) Err() error {
+	errs := r.Errors()
+	if len(errs) == 0 {
+		return nil
+	}
+	var buf bytes.Buffer
+	for _, f := range errs {
+		fmt.Fprintf(&buf, "\n- %s", f.Message)
+	}
+	return fmt.Errorf("cannot validate seed:%s", buf.Bytes())
+}
+
+// ValidateOptions holds options for seed validation.
+type ValidateOptions struct {
+	// CheckIntegrity requests a consistency check of the squashfs
+	// metadata of each snap of the seed, on top of the check of its
+	// header done when opening it. Snaps backed by assertions are
+	// always checked against the size and digest of their
+	// snap-revision, which covers their whole content.
+	CheckIntegrity bool
+	// Severities overrides the severity of the findings with the
+	// given codes, e.g. to only warn about missing default providers.
+	Severities map[string]string
+	// Ignore lists the codes of the findings to leave out of the
+	// report.
+	Ignore []string
+	// Core requests checking the seed as one for an Ubuntu Core
+	// image even if it has no model, or a classic one.
+	Core bool
+	// Strict requests rejecting a seed.yaml with unknown keys or
+	// asserted snaps without a well-formed snap-id.
+	Strict bool
+}
+
+func (opts *ValidateOptions) validate() error {
+	for code, severity := range opts.Severities {
+		if !knownSeedCodes[code] {
+			return fmt.Errorf("cannot override the severity of unknown seed validation code %q", code)
+		}
+		if severity != SeedFindingError && severity != SeedFindingWarning {
+			return fmt.Errorf("invalid severity %q for seed validation code %q", severity, code)
+		}
+	}
+	for _, code := range opts.Ignore {
+		if !knownSeedCodes[code] {
+			return fmt.Errorf("cannot ignore unknown seed validation code %q", code)
+		}
+	}
+	return nil
+}
+
+// ValidateSeed validates the seed described by the given seed.yaml,
+// returning an error listing all the problems found.
+func ValidateSeed(seedFile string, opts *ValidateOptions) error {
+	report, err := ValidateSeedReport(seedFile, opts)
+	if err != nil {
+		return err
+	}
+	return report.Err()
+}
+
+var checkSnapIntegrity = func(fn string) error {
+	return squashfs.New(fn).Check()
+}
+
+type seedSnapWithInfo struct {
+	*snap.SeedSnap
+	info *snap.Info
+	path string
+}
+
+// ValidateSeedReport validates the seed described by the given
+// seed.yaml and returns a report of all the problems found, each
+// with its severity, code and the offending snap and file. An error
+// is returned only if the options are invalid or the seed.yaml itself
+// cannot be read.
+func ValidateSeedReport(seedFile string, opts *ValidateOptions) (*SeedReport, error) {
+	if opts == nil {
+		opts = &ValidateOptions{}
+	}
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+	readSeedYaml := snap.ReadSeedYaml
+	if opts.Strict {
+		readSeedYaml = snap.ReadSeedYamlStrict
+	}
+	seed, err := readSeedYaml(seedFile)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &SeedReport{
+		Seed:     seedFile,
+		Findings: []*SeedFinding{},
+		opts:     opts,
+	}
+	assertSeedDir := filepath.Join(filepath.Dir(seedFile), "assertions")
+	db, model, err := readSeedAssertions(assertSeedDir)
+	switch {
+	case err == errNoSeedAssertions:
+		report.add(SeedFindingError, SeedCodeNoAssertions, "", assertSeedDir, err)
+	case err != nil:
+		report.add(SeedFindingError, SeedCodeInvalidAssertions, "", assertSeedDir, err)
+	}
+
+	// read the snaps info
+	var snaps []seedSnapWithInfo
+	snapInfos := make(map[string]*snap.Info)
+	seedSnaps := make(map[string]bool)
+	fileSnaps := make(map[string]string)
+	assertedSnapIDs := make(map[string]bool)
+	snapsDir := filepath.Join(filepath.Dir(seedFile), "snaps")
+	for _, seedSnap := range seed.Snaps {
+		fn := filepath.Join(snapsDir, seedSnap.File)
+		if seedSnaps[seedSnap.Name] {
+			report.add(SeedFindingError, SeedCodeDuplicateSnap, seedSnap.Name, fn, fmt.Errorf("snap %q is listed more than once in the seed", seedSnap.Name))
+			continue
+		}
+		seedSnaps[seedSnap.Name] = true
+		if other, ok := fileSnaps[seedSnap.File]; ok {
+			report.add(SeedFindingError, SeedCodeDuplicateFile, seedSnap.Name, fn, fmt.Errorf("cannot use snap %q: file %q is already used by snap %q", seedSnap.Name, seedSnap.File, other))
+			continue
+		}
+		fileSnaps[seedSnap.File] = seedSnap.Name
+		snapf, err := snap.Open(fn)
+		if err != nil {
+			report.add(SeedFindingError, SeedCodeSnapMissing, seedSnap.Name, fn, err)
+			continue
+		}
+		if opts.CheckIntegrity {
+			if err := checkSnapIntegrity(fn); err != nil {
+				report.add(SeedFindingError, SeedCodeSnapCorrupted, seedSnap.Name, fn, fmt.Errorf("cannot use snap %q: %v", seedSnap.Name, err))
+				continue
+			}
+		}
+		info, err := snap.ReadInfoFromSnapFile(snapf, nil)
+		if err != nil {
+			report.add(SeedFindingError, SeedCodeSnapInvalid, seedSnap.Name, fn, fmt.Errorf("cannot use snap %s: %v", fn, err))
+			continue
+		}
+		snaps = append(snaps, seedSnapWithInfo{SeedSnap: seedSnap, info: info, path: fn})
+		snapInfos[info.InstanceName()] = info
+		if err := validateSeedSnapArchitecture(seedSnap, info, model); err != nil {
+			report.add(SeedFindingError, SeedCodeArchitectureMismatch, seedSnap.Name, fn, err)
+		}
+		if db == nil {
+			continue
+		}
+		if seedSnap.Unasserted {
+			report.add(SeedFindingWarning, SeedCodeSnapUnasserted, seedSnap.Name, fn, fmt.Errorf("snap %q is not backed by assertions", seedSnap.Name))
+			continue
+		}
+		snapID, err := validateSeedSnapAssertions(seedSnap, fn, db)
+		if err != nil {
+			report.add(SeedFindingError, SeedCodeAssertionsMismatch, seedSnap.Name, fn, err)
+			continue
+		}
+		assertedSnapIDs[snapID] = true
+	}
+
+	validateSeedSnapsDir(report, snapsDir, fileSnaps)
+	if db != nil {
+		validateSeedSnapAssertionsUsed(report, db, seedSnaps, assertedSnapIDs)
+	}
+
+	// ensure we have either "core" or "snapd"
+	_, haveCore := snapInfos["core"]
+	_, haveSnapd := snapInfos["snapd"]
+	if !(haveCore || haveSnapd) {
+		report.add(SeedFindingError, SeedCodeMissingCore, "", "", fmt.Errorf("the core or snapd snap must be part of the seed"))
+	}
+
+	if model != nil {
+		validateSeedModelSnaps(report, model, snaps)
+	}
+
+	for _, sn := range snaps {
+		if sn.info.SnapType == snap.TypeGadget {
+			validateSeedGadget(report, sn, model, db, assertedSnapIDs)
+		}
+	}
+
+	// check that all bases are part of the seed
+	for _, sn := range snaps {
+		info := sn.info
+		// ensure base is available
+		if info.Base != "" && info.Base != "none" {
+			if _, ok := snapInfos[info.Base]; !ok {
+				report.add(SeedFindingError, SeedCodeMissingBase, sn.Name, sn.path, fmt.Errorf("cannot use snap %q: base %q is missing", info.InstanceName(), info.Base))
+			}
+		}
+		// ensure core is available
+		if info.Base == "" && info.SnapType == snap.TypeApp && info.InstanceName() != "snapd" {
+			if _, ok := snapInfos["core"]; !ok {
+				report.add(SeedFindingError, SeedCodeMissingBase, sn.Name, sn.path, fmt.Errorf(`cannot use snap %q: required snap "core" missing`, info.InstanceName()))
+			}
+		}
+	}
+
+	validateSeedContentProviders(report, snaps, snapInfos)
+
+	if opts.Core || (model != nil && !model.Classic()) {
+		validateSeedConfinement(report, snaps)
+	}
+
+	return report, nil
+}
+
+// validateSeedConfinement checks that the snaps of a seed for an
+// Ubuntu Core image can be installed there, i.e. that none uses
+// classic confinement. Snaps using devmode are installed, but
+// without the sandbox, which is only worth a warning.
+func validateSeedConfinement(report *SeedReport, snaps []seedSnapWithInfo) {
+	for _, sn := range snaps {
+		info := sn.info
+		switch {
+		case info.NeedsClassic():
+			report.add(SeedFindingError, SeedCodeClassicSnap, sn.Name, sn.path, fmt.Errorf("cannot use classic snap %q in a core system", info.InstanceName()))
+		case info.NeedsDevMode():
+			report.add(SeedFindingWarning, SeedCodeDevModeSnap, sn.Name, sn.path, fmt.Errorf("snap %q uses devmode confinement and will not be confined in a core system", info.InstanceName()))
+		}
+	}
+}
+
+// contentTag returns the content attribute of a content plug or slot,
+// which defaults to its name.
+func contentTag(name string, attrs map[string]interface{}) string {
+	if content, ok := attrs["content"].(string); ok && content != "" {
+		return content
+	}
+	return name
+}
+
+// validateSeedContentProviders checks that the content plugs with a
+// default-provider can be satisfied by the content slots of the seed
+// snaps, be it by the default provider or any other snap.
+func validateSeedContentProviders(report *SeedReport, snaps []seedSnapWithInfo, snapInfos map[string]*snap.Info) {
+	provided := make(map[string]bool)
+	for _, sn := range snaps {
+		for _, slot := range sn.info.Slots {
+			if slot.Interface == "content" {
+				provided[contentTag(slot.Name, slot.Attrs)] = true
+			}
+		}
+	}
+
+	for _, sn := range snaps {
+		info := sn.info
+		plugNames := make([]string, 0, len(info.Plugs))
+		for name := range info.Plugs {
+			plugNames = append(plugNames, name)
+		}
+		sort.Strings(plugNames)
+		for _, name := range plugNames {
+			plug := info.Plugs[name]
+			if plug.Interface != "content" {
+				continue
+			}
+			var dprovider string
+			if err := plug.Attr("default-provider", &dprovider); err != nil || dprovider == "" {
+				continue
+			}
+			// old documentation said the default-provider is
+			// "snapname:ifname", only the snap name matters
+			dprovider = strings.SplitN(dprovider, ":", 2)[0]
+			content := contentTag(plug.Name, plug.Attrs)
+			if provided[content] {
+				continue
+			}
+			if _, ok := snapInfos[dprovider]; !ok {
+				report.add(SeedFindingError, SeedCodeMissingDefaultProvider, sn.Name, sn.path, fmt.Errorf("cannot use snap %q: default provider %q is missing", info.InstanceName(), dprovider))
+				continue
+			}
+			report.add(SeedFindingError, SeedCodeMissingContentSlot, sn.Name, sn.path, fmt.Errorf("cannot use snap %q: default provider %q has no content slot for %q", info.InstanceName(), dprovider, content))
+		}
+	}
+}
+
+// validateSeedModelSnaps checks that the kernel and gadget snaps of
+// the seed are the ones declared by the model, and that they are for
+// exactly the architecture of the model.
+func validateSeedModelSnaps(report *SeedReport, model *asserts.Model, snaps []seedSnapWithInfo) {
+	modelSnaps := []struct {
+		what     string
+		name     string
+		snapType snap.Type
+	}{
+		{"kernel", model.Kernel(), snap.TypeKernel},
+		{"gadget", model.Gadget(), snap.TypeGadget},
+	}
+	for _, ms := range modelSnaps {
+		found := false
+		for _, sn := range snaps {
+			info := sn.info
+			if info.InstanceName() != ms.name {
+				if info.SnapType == ms.snapType {
+					report.add(SeedFindingError, SeedCodeModelSnapMismatch, sn.Name, sn.path, fmt.Errorf("cannot use %s snap %q: the model %s is %q", ms.what, info.InstanceName(), ms.what, ms.name))
+				}
+				continue
+			}
+			found = true
+			if info.SnapType != ms.snapType {
+				report.add(SeedFindingError, SeedCodeModelSnapMismatch, sn.Name, sn.path, fmt.Errorf("cannot use snap %q as the model %s: snap type is %q", ms.name, ms.what, info.SnapType))
+				continue
+			}
+			// unlike for other snaps, a compatible architecture
+			// is not good enough, incompatible ones are already
+			// reported
+			declared := sn.Architecture
+			if declared != "" && declared != "all" && declared != model.Architecture() && arch.IsCompatibleArchitecture(model.Architecture(), declared) {
+				report.add(SeedFindingError, SeedCodeArchitectureMismatch, sn.Name, sn.path, fmt.Errorf("cannot use snap %q as the model %s: architecture %q does not match the model architecture %q", ms.name, ms.what, declared, model.Architecture()))
+			}
+		}
+		if !found && ms.name != "" {
+			report.add(SeedFindingError, SeedCodeMissingModelSnap, ms.name, "", fmt.Errorf("model %s snap %q is missing from the seed", ms.what, ms.name))
+		}
+	}
+}
+
+// validateSeedGadget checks the gadget.yaml of the given gadget snap
+// and, if the seed is asserted, that its defaults are for snaps in the
+// seed.
+func validateSeedGadget(report *SeedReport, sn seedSnapWithInfo, model *asserts.Model, db *asserts.Database, assertedSnapIDs map[string]bool) {
+	snapf, err := snap.Open(sn.path)
+	if err != nil {
+		// already reported
+		return
+	}
+	// without a model classic rules are the most lenient ones
+	classic := model == nil || model.Classic()
+	gadgetInfo, err := readGadgetInfo(snapf, classic)
+	if err != nil {
+		report.add(SeedFindingError, SeedCodeInvalidGadget, sn.Name, sn.path, fmt.Errorf("cannot use gadget snap %q: %v", sn.info.InstanceName(), err))
+		return
+	}
+	if db == nil {
+		return
+	}
+	var ids []string
+	for id := range gadgetInfo.Defaults {
+		if id != "system" && !assertedSnapIDs[id] {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		report.add(SeedFindingError, SeedCodeGadgetDefaultsMismatch, sn.Name, sn.path, fmt.Errorf("cannot use gadget snap %q: defaults are for snap id %q which is not in the seed", sn.info.InstanceName(), id))
+	}
+}
+
+// readGadgetInfo reads and validates the gadget.yaml of the given
+// gadget snap.
+func readGadgetInfo(snapf snap.Container, classic bool) (*gadget.Info, error) {
+	gadgetYaml, err := snapf.ReadFile("meta/gadget.yaml")
+	switch {
+	case os.IsNotExist(err):
+		if !classic {
+			return nil, fmt.Errorf("meta/gadget.yaml is missing")
+		}
+		// gadget.yaml is optional for classic gadgets
+	case err != nil:
+		return nil, err
+	}
+	tmpDir, err := ioutil.TempDir("", "validate-seed-gadget")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmpDir)
+	if gadgetYaml != nil {
+		if err := os.MkdirAll(filepath.Join(tmpDir, "meta"), 0755); err != nil {
+			return nil, err
+		}
+		if err := ioutil.WriteFile(filepath.Join(tmpDir, "meta", "gadget.yaml"), gadgetYaml, 0644); err != nil {
+			return nil, err
+		}
+	}
+	return gadget.ReadInfo(tmpDir, classic)
+}
+
+// validateSeedSnapsDir reports the files in the seed snaps directory
+// that are not used by any snap of the seed.
+func validateSeedSnapsDir(report *SeedReport, snapsDir string, fileSnaps map[string]string) {
+	dc, err := ioutil.ReadDir(snapsDir)
+	if err != nil {
+		// missing snaps are reported on their own
+		return
+	}
+	for _, fi := range dc {
+		if fi.IsDir() {
+			continue
+		}
+		if _, ok := fileSnaps[fi.Name()]; ok {
+			continue
+		}
+		fn := filepath.Join(snapsDir, fi.Name())
+		report.add(SeedFindingWarning, SeedCodeOrphanedFile, "", fn, fmt.Errorf("file %q is not used by any snap of the seed", fi.Name()))
+	}
+}
+
+// validateSeedSnapAssertionsUsed reports the snaps for which the seed
+// has snap-declaration or snap-revision assertions but which are not
+// in the seed.
+func validateSeedSnapAssertionsUsed(report *SeedReport, db *asserts.Database, seedSnaps map[string]bool, assertedSnapIDs map[string]bool) {
+	names := make(map[string]string)
+	decls, err := db.FindMany(asserts.SnapDeclarationType, nil)
+	if err != nil && !asserts.IsNotFound(err) {
+		report.add(SeedFindingError, SeedCodeInvalidAssertions, "", "", err)
+		return
+	}
+	for _, a := range decls {
+		decl := a.(*asserts.SnapDeclaration)
+		names[decl.SnapID()] = decl.SnapName()
+	}
+	revs, err := db.FindMany(asserts.SnapRevisionType, nil)
+	if err != nil && !asserts.IsNotFound(err) {
+		report.add(SeedFindingError, SeedCodeInvalidAssertions, "", "", err)
+		return
+	}
+	for _, a := range revs {
+		rev := a.(*asserts.SnapRevision)
+		if _, ok := names[rev.SnapID()]; !ok {
+			names[rev.SnapID()] = ""
+		}
+	}
+
+	snapIDs := make([]string, 0, len(names))
+	for snapID := range names {
+		snapIDs = append(snapIDs, snapID)
+	}
+	sort.Strings(snapIDs)
+	for _, snapID := range snapIDs {
+		name := names[snapID]
+		// assertions of seed snaps not matching them are
+		// reported on their own
+		if assertedSnapIDs[snapID] || seedSnaps[name] {
+			continue
+		}
+		what := fmt.Sprintf("snap-id %q", snapID)
+		if name != "" {
+			what = fmt.Sprintf("snap %q", name)
+		}
+		report.add(SeedFindingWarning, SeedCodeOrphanedAssertions, name, "", fmt.Errorf("seed has assertions for %s which is not in the seed", what))
+	}
+}
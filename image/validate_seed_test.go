@@ -20,13 +20,17 @@
 package image_test
 
 import (
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"time"
 
 	. "gopkg.in/check.v1"
 
+	"github.com/snapcore/snapd/asserts"
+	"github.com/snapcore/snapd/asserts/assertstest"
 	"github.com/snapcore/snapd/image"
 	"github.com/snapcore/snapd/snap"
 	"github.com/snapcore/snapd/snap/snaptest"
@@ -35,6 +39,10 @@ import (
 
 type validateSuite struct {
 	imageSuite
+
+	// classicModel has no kernel or gadget that would need to be in
+	// the seed
+	classicModel *asserts.Model
 }
 
 var _ = Suite(&validateSuite{})
@@ -52,12 +60,21 @@ func (s *validateSuite) SetUpTest(c *C) {
 
 	err := os.MkdirAll(filepath.Join(s.root, "snaps"), 0755)
 	c.Assert(err, IsNil)
+
+	s.classicModel = s.brands.Model("my-brand", "my-classic-model", map[string]interface{}{
+		"classic":      "true",
+		"architecture": "amd64",
+	})
 }
 
 func (s *validateSuite) makeSnapInSeed(c *C, snapYaml string) {
+	s.makeSnapInSeedWithFiles(c, snapYaml, nil)
+}
+
+func (s *validateSuite) makeSnapInSeedWithFiles(c *C, snapYaml string, files [][]string) {
 	info := infoFromSnapYaml(c, snapYaml, snap.R(1))
 
-	src := snaptest.MakeTestSnapWithFiles(c, snapYaml, nil)
+	src := snaptest.MakeTestSnapWithFiles(c, snapYaml, files)
 	dst := filepath.Join(s.root, "snaps", fmt.Sprintf("%s_%s.snap", info.InstanceName(), info.Revision.String()))
 
 	err := os.Rename(src, dst)
@@ -71,6 +88,12 @@ func (s *validateSuite) makeSeedYaml(c *C, seedYaml string) string {
 	return tmpf
 }
 
+// unassertedSeedOpts are the options to validate seeds without
+// assertions, for the checks that do not need them
+var unassertedSeedOpts = &image.ValidateOptions{
+	Ignore: []string{image.SeedCodeNoAssertions},
+}
+
 func (s *validateSuite) TestValidateSnapHappy(c *C) {
 	s.makeSnapInSeed(c, coreYaml)
 	s.makeSnapInSeed(c, `name: gtk-common-themes
@@ -85,7 +108,7 @@ snaps:
    file: gtk-common-themes_1.snap
 `)
 
-	err := image.ValidateSeed(seedFn)
+	err := image.ValidateSeed(seedFn, unassertedSeedOpts)
 	c.Assert(err, IsNil)
 }
 
@@ -102,7 +125,7 @@ snaps:
    file: need-base_1.snap
 `)
 
-	err := image.ValidateSeed(seedFn)
+	err := image.ValidateSeed(seedFn, unassertedSeedOpts)
 	c.Assert(err, ErrorMatches, `cannot validate seed:
 - cannot use snap "need-base": base "some-base" is missing`)
 }
@@ -124,11 +147,84 @@ snaps:
    file: need-df_1.snap
 `)
 
-	err := image.ValidateSeed(seedFn)
+	err := image.ValidateSeed(seedFn, unassertedSeedOpts)
 	c.Assert(err, ErrorMatches, `cannot validate seed:
 - cannot use snap "need-df": default provider "gtk-common-themes" is missing`)
 }
 
+func (s *validateSuite) TestValidateSnapDefaultProviderWithoutContentSlot(c *C) {
+	s.makeSnapInSeed(c, coreYaml)
+	s.makeSnapInSeed(c, `name: need-df
+version: 1.0
+plugs:
+ gtk-3-themes:
+  interface: content
+  default-provider: gtk-common-themes
+`)
+	s.makeSnapInSeed(c, `name: gtk-common-themes
+version: 1.0
+slots:
+ icon-themes:
+  interface: content
+  read: [$SNAP/share/icons]
+`)
+	seedFn := s.makeSeedYaml(c, `
+snaps:
+ - name: core
+   file: core_1.snap
+ - name: need-df
+   file: need-df_1.snap
+ - name: gtk-common-themes
+   file: gtk-common-themes_1.snap
+`)
+
+	report, err := image.ValidateSeedReport(seedFn, &image.ValidateOptions{
+		Ignore: []string{image.SeedCodeNoAssertions},
+	})
+	c.Assert(err, IsNil)
+	c.Check(report.Findings, DeepEquals, []*image.SeedFinding{
+		{
+			Severity: image.SeedFindingError,
+			Code:     image.SeedCodeMissingContentSlot,
+			Snap:     "need-df",
+			Path:     filepath.Join(s.root, "snaps", "need-df_1.snap"),
+			Message:  `cannot use snap "need-df": default provider "gtk-common-themes" has no content slot for "gtk-3-themes"`,
+		},
+	})
+}
+
+func (s *validateSuite) TestValidateSnapContentProvidedByOtherSnap(c *C) {
+	s.makeSnapInSeed(c, coreYaml)
+	s.makeSnapInSeed(c, `name: need-df
+version: 1.0
+plugs:
+ themes:
+  interface: content
+  content: gtk-3-themes
+  default-provider: gtk-common-themes:gtk-3-themes
+`)
+	// not the default provider but providing the same content
+	s.makeSnapInSeed(c, `name: other-themes
+version: 1.0
+slots:
+ gtk-3-themes:
+  interface: content
+  read: [$SNAP/share/themes]
+`)
+	seedFn := s.makeSeedYaml(c, `
+snaps:
+ - name: core
+   file: core_1.snap
+ - name: need-df
+   file: need-df_1.snap
+ - name: other-themes
+   file: other-themes_1.snap
+`)
+
+	err := image.ValidateSeed(seedFn, unassertedSeedOpts)
+	c.Assert(err, IsNil)
+}
+
 func (s *validateSuite) TestValidateSnapSnapdHappy(c *C) {
 	s.makeSnapInSeed(c, snapdYaml)
 	s.makeSnapInSeed(c, packageCore18)
@@ -146,7 +242,7 @@ snaps:
    file: core18_1.snap
 `)
 
-	err := image.ValidateSeed(seedFn)
+	err := image.ValidateSeed(seedFn, unassertedSeedOpts)
 	c.Assert(err, IsNil)
 }
 
@@ -162,7 +258,7 @@ snaps:
    file: some-snap_1.snap
 `)
 
-	err := image.ValidateSeed(seedFn)
+	err := image.ValidateSeed(seedFn, unassertedSeedOpts)
 	c.Assert(err, ErrorMatches, `cannot validate seed:
 - cannot use snap "some-snap": required snap "core" missing`)
 }
@@ -180,7 +276,7 @@ snaps:
    file: core18_1.snap
 `)
 
-	err := image.ValidateSeed(seedFn)
+	err := image.ValidateSeed(seedFn, unassertedSeedOpts)
 	c.Assert(err, ErrorMatches, `cannot validate seed:
 - the core or snapd snap must be part of the seed`)
 }
@@ -194,7 +290,7 @@ snaps:
    file: some-snap_1.snap
 `)
 
-	err := image.ValidateSeed(seedFn)
+	err := image.ValidateSeed(seedFn, unassertedSeedOpts)
 	c.Assert(err, ErrorMatches, `cannot validate seed:
 - the core or snapd snap must be part of the seed
 - cannot use snap "some-snap": required snap "core" missing`)
@@ -210,7 +306,7 @@ snaps:
    file: some-snap_1.snap
 `)
 
-	err := image.ValidateSeed(seedFn)
+	err := image.ValidateSeed(seedFn, unassertedSeedOpts)
 	c.Assert(err, ErrorMatches, `cannot validate seed:
 - cannot open snap: open /.*/snaps/some-snap_1.snap: no such file or directory`)
 }
@@ -246,7 +342,861 @@ snaps:
    file: some-snap-invalid-yaml_1.snap
 `)
 
-	err = image.ValidateSeed(seedFn)
+	err = image.ValidateSeed(seedFn, unassertedSeedOpts)
 	c.Assert(err, ErrorMatches, `cannot validate seed:
 - cannot use snap /.*/snaps/some-snap-invalid-yaml_1.snap: invalid snap version: cannot be empty`)
 }
+
+func (s *validateSuite) writeSeedAssertion(c *C, name string, a asserts.Assertion) {
+	assertsDir := filepath.Join(s.root, "assertions")
+	c.Assert(os.MkdirAll(assertsDir, 0755), IsNil)
+	err := ioutil.WriteFile(filepath.Join(assertsDir, name), asserts.Encode(a), 0644)
+	c.Assert(err, IsNil)
+}
+
+func (s *validateSuite) writeSeedModel(c *C, model *asserts.Model) {
+	restore := image.MockTrusted(s.storeSigning.Trusted)
+	s.AddCleanup(restore)
+
+	s.writeSeedAssertion(c, "model", model)
+	for _, a := range s.brands.AccountsAndKeys("my-brand") {
+		s.writeSeedAssertion(c, a.Type().Name+"-my-brand", a)
+	}
+	s.writeSeedAssertion(c, "store-key", s.storeSigning.StoreAccountKey(""))
+}
+
+// writeSeedSnapAssertions signs and writes the snap-declaration and
+// snap-revision assertions for the given snap already in the seed.
+func (s *validateSuite) writeSeedSnapAssertions(c *C, snapName string) {
+	snapID := snapName + "-id"
+	decl, err := s.storeSigning.Sign(asserts.SnapDeclarationType, map[string]interface{}{
+		"series":       "16",
+		"snap-id":      snapID,
+		"snap-name":    snapName,
+		"publisher-id": "canonical",
+		"timestamp":    time.Now().UTC().Format(time.RFC3339),
+	}, nil, "")
+	c.Assert(err, IsNil)
+	s.writeSeedAssertion(c, snapName+".snap-declaration", decl)
+
+	snapSHA3_384, snapSize, err := asserts.SnapFileSHA3_384(filepath.Join(s.root, "snaps", snapName+"_1.snap"))
+	c.Assert(err, IsNil)
+	snapRev, err := s.storeSigning.Sign(asserts.SnapRevisionType, map[string]interface{}{
+		"snap-sha3-384": snapSHA3_384,
+		"snap-size":     fmt.Sprintf("%d", snapSize),
+		"snap-id":       snapID,
+		"snap-revision": "1",
+		"developer-id":  "canonical",
+		"timestamp":     time.Now().UTC().Format(time.RFC3339),
+	}, nil, "")
+	c.Assert(err, IsNil)
+	s.writeSeedAssertion(c, snapName+".snap-revision", snapRev)
+}
+
+func (s *validateSuite) TestValidateSnapMixedArchitectures(c *C) {
+	// the model is amd64
+	s.writeSeedModel(c, s.classicModel)
+	s.makeSnapInSeed(c, coreYaml)
+	s.makeSnapInSeed(c, `name: native
+version: 1.0
+architectures: [amd64]`)
+	s.makeSnapInSeed(c, `name: legacy
+version: 1.0
+architectures: [i386]`)
+	for _, name := range []string{"core", "native", "legacy"} {
+		s.writeSeedSnapAssertions(c, name)
+	}
+	seedFn := s.makeSeedYaml(c, `
+snaps:
+ - name: core
+   file: core_1.snap
+ - name: native
+   file: native_1.snap
+ - name: legacy
+   file: legacy_1.snap
+   architecture: i386
+`)
+
+	err := image.ValidateSeed(seedFn, nil)
+	c.Assert(err, IsNil)
+}
+
+func (s *validateSuite) TestValidateSnapArchitectureMismatch(c *C) {
+	s.writeSeedModel(c, s.classicModel)
+	s.makeSnapInSeed(c, coreYaml)
+	s.makeSnapInSeed(c, `name: native
+version: 1.0
+architectures: [i386]`)
+	s.makeSnapInSeed(c, `name: foreign
+version: 1.0
+architectures: [armhf]`)
+	for _, name := range []string{"core", "native", "foreign"} {
+		s.writeSeedSnapAssertions(c, name)
+	}
+	seedFn := s.makeSeedYaml(c, `
+snaps:
+ - name: core
+   file: core_1.snap
+ - name: native
+   file: native_1.snap
+ - name: foreign
+   file: foreign_1.snap
+   architecture: armhf
+`)
+
+	err := image.ValidateSeed(seedFn, nil)
+	c.Assert(err, ErrorMatches, `cannot validate seed:
+- cannot use snap "native": supported architectures \(i386\) do not include "amd64"
+- cannot use snap "foreign": architecture "armhf" is incompatible with the model architecture "amd64"`)
+}
+
+func (s *validateSuite) TestValidateSnapDeclaredArchitectureNoModel(c *C) {
+	s.makeSnapInSeed(c, coreYaml)
+	s.makeSnapInSeed(c, `name: foreign
+version: 1.0
+architectures: [arm64]`)
+	seedFn := s.makeSeedYaml(c, `
+snaps:
+ - name: core
+   file: core_1.snap
+ - name: foreign
+   file: foreign_1.snap
+   architecture: armhf
+`)
+
+	err := image.ValidateSeed(seedFn, unassertedSeedOpts)
+	c.Assert(err, ErrorMatches, `cannot validate seed:
+- cannot use snap "foreign": supported architectures \(arm64\) do not include "armhf"`)
+}
+
+func (s *validateSuite) TestValidateSeedAssertionsHappy(c *C) {
+	s.writeSeedModel(c, s.classicModel)
+	s.makeSnapInSeed(c, coreYaml)
+	s.makeSnapInSeed(c, `name: local
+version: 1.0`)
+	s.writeSeedSnapAssertions(c, "core")
+	seedFn := s.makeSeedYaml(c, `
+snaps:
+ - name: core
+   file: core_1.snap
+ - name: local
+   file: local_1.snap
+   unasserted: true
+`)
+
+	err := image.ValidateSeed(seedFn, nil)
+	c.Assert(err, IsNil)
+}
+
+func (s *validateSuite) TestValidateSeedAssertionsNoModel(c *C) {
+	s.makeSnapInSeed(c, coreYaml)
+	s.writeSeedAssertion(c, "store-key", s.storeSigning.StoreAccountKey(""))
+	seedFn := s.makeSeedYaml(c, `
+snaps:
+ - name: core
+   file: core_1.snap
+`)
+
+	err := image.ValidateSeed(seedFn, nil)
+	c.Assert(err, ErrorMatches, `cannot validate seed:
+- cannot find a model assertion in the seed`)
+}
+
+func (s *validateSuite) TestValidateSeedAssertionsUntrustedModel(c *C) {
+	s.writeSeedModel(c, s.classicModel)
+	// not signed by a key chaining to the trusted ones
+	otherStore := assertstest.NewStoreStack("other", nil)
+	restore := image.MockTrusted(otherStore.Trusted)
+	defer restore()
+	s.makeSnapInSeed(c, coreYaml)
+	s.writeSeedSnapAssertions(c, "core")
+	seedFn := s.makeSeedYaml(c, `
+snaps:
+ - name: core
+   file: core_1.snap
+`)
+
+	err := image.ValidateSeed(seedFn, nil)
+	c.Assert(err, ErrorMatches, `cannot validate seed:
+- cannot verify seed assertions: cannot find .* in the seed`)
+}
+
+func (s *validateSuite) TestValidateSeedAssertionsMissingSnapAssertions(c *C) {
+	s.writeSeedModel(c, s.classicModel)
+	s.makeSnapInSeed(c, coreYaml)
+	s.makeSnapInSeed(c, `name: some-snap
+version: 1.0`)
+	s.writeSeedSnapAssertions(c, "core")
+	seedFn := s.makeSeedYaml(c, `
+snaps:
+ - name: core
+   file: core_1.snap
+ - name: some-snap
+   file: some-snap_1.snap
+`)
+
+	err := image.ValidateSeed(seedFn, nil)
+	c.Assert(err, ErrorMatches, `cannot validate seed:
+- cannot use snap "some-snap": no snap-revision or snap-declaration assertion matching the snap file`)
+}
+
+func (s *validateSuite) TestValidateSeedAssertionsDigestMismatch(c *C) {
+	s.writeSeedModel(c, s.classicModel)
+	s.makeSnapInSeed(c, coreYaml)
+	s.writeSeedSnapAssertions(c, "core")
+	// replace the asserted snap with a different one
+	s.makeSnapInSeed(c, coreYaml+`
+summary: tampered`)
+	seedFn := s.makeSeedYaml(c, `
+snaps:
+ - name: core
+   file: core_1.snap
+`)
+
+	err := image.ValidateSeed(seedFn, nil)
+	c.Assert(err, ErrorMatches, `cannot validate seed:
+- cannot use snap "core": no snap-revision or snap-declaration assertion matching the snap file`)
+}
+
+func (s *validateSuite) TestValidateSeedAssertionsWrongName(c *C) {
+	s.writeSeedModel(c, s.classicModel)
+	s.makeSnapInSeed(c, coreYaml)
+	s.writeSeedSnapAssertions(c, "core")
+	err := os.Rename(filepath.Join(s.root, "snaps", "core_1.snap"), filepath.Join(s.root, "snaps", "snapd_1.snap"))
+	c.Assert(err, IsNil)
+	seedFn := s.makeSeedYaml(c, `
+snaps:
+ - name: snapd
+   file: snapd_1.snap
+`)
+
+	err = image.ValidateSeed(seedFn, nil)
+	c.Assert(err, ErrorMatches, `cannot validate seed:
+- cannot use snap "snapd": assertions are for snap "core"`)
+}
+
+func (s *validateSuite) TestValidateSeedReport(c *C) {
+	s.makeSnapInSeed(c, `name: some-snap
+version: 1.0
+base: some-base`)
+	seedFn := s.makeSeedYaml(c, `
+snaps:
+ - name: some-snap
+   file: some-snap_1.snap
+ - name: other-snap
+   file: other-snap_1.snap
+`)
+
+	report, err := image.ValidateSeedReport(seedFn, nil)
+	c.Assert(err, IsNil)
+	c.Check(report.Seed, Equals, seedFn)
+	c.Assert(report.Findings, HasLen, 4)
+	c.Check(report.Findings[0], DeepEquals, &image.SeedFinding{
+		Severity: image.SeedFindingError,
+		Code:     image.SeedCodeNoAssertions,
+		Path:     filepath.Join(s.root, "assertions"),
+		Message:  "seed has no assertions, snaps cannot be cross-checked",
+	})
+	c.Check(report.Findings[1].Severity, Equals, image.SeedFindingError)
+	c.Check(report.Findings[1].Code, Equals, image.SeedCodeSnapMissing)
+	c.Check(report.Findings[1].Snap, Equals, "other-snap")
+	c.Check(report.Findings[1].Path, Equals, filepath.Join(s.root, "snaps", "other-snap_1.snap"))
+	c.Check(report.Findings[2], DeepEquals, &image.SeedFinding{
+		Severity: image.SeedFindingError,
+		Code:     image.SeedCodeMissingCore,
+		Message:  "the core or snapd snap must be part of the seed",
+	})
+	c.Check(report.Findings[3], DeepEquals, &image.SeedFinding{
+		Severity: image.SeedFindingError,
+		Code:     image.SeedCodeMissingBase,
+		Snap:     "some-snap",
+		Path:     filepath.Join(s.root, "snaps", "some-snap_1.snap"),
+		Message:  `cannot use snap "some-snap": base "some-base" is missing`,
+	})
+	c.Check(report.Errors(), DeepEquals, report.Findings)
+	c.Check(report.Err(), ErrorMatches, `cannot validate seed:
+- seed has no assertions, snaps cannot be cross-checked
+- cannot open snap: .*
+- the core or snapd snap must be part of the seed
+- cannot use snap "some-snap": base "some-base" is missing`)
+
+	data, err := json.Marshal(report.Findings[3])
+	c.Assert(err, IsNil)
+	c.Check(string(data), Equals, fmt.Sprintf(`{"severity":"error","code":"missing-base","snap":"some-snap","path":%q,"message":"cannot use snap \"some-snap\": base \"some-base\" is missing"}`, filepath.Join(s.root, "snaps", "some-snap_1.snap")))
+}
+
+func (s *validateSuite) TestValidateSeedReportWarningsOnly(c *C) {
+	s.writeSeedModel(c, s.classicModel)
+	s.makeSnapInSeed(c, coreYaml)
+	s.makeSnapInSeed(c, `name: local
+version: 1.0`)
+	s.writeSeedSnapAssertions(c, "core")
+	seedFn := s.makeSeedYaml(c, `
+snaps:
+ - name: core
+   file: core_1.snap
+ - name: local
+   file: local_1.snap
+   unasserted: true
+`)
+
+	report, err := image.ValidateSeedReport(seedFn, nil)
+	c.Assert(err, IsNil)
+	c.Check(report.Findings, DeepEquals, []*image.SeedFinding{{
+		Severity: image.SeedFindingWarning,
+		Code:     image.SeedCodeSnapUnasserted,
+		Snap:     "local",
+		Path:     filepath.Join(s.root, "snaps", "local_1.snap"),
+		Message:  `snap "local" is not backed by assertions`,
+	}})
+	c.Check(report.Errors(), HasLen, 0)
+	c.Check(report.Err(), IsNil)
+}
+
+func (s *validateSuite) TestValidateSeedStrict(c *C) {
+	s.makeSnapInSeed(c, coreYaml)
+	seedFn := s.makeSeedYaml(c, `
+snaps:
+ - name: core
+   chanel: stable
+   file: core_1.snap
+   unasserted: true
+`)
+
+	// the typo is ignored by default
+	err := image.ValidateSeed(seedFn, unassertedSeedOpts)
+	c.Assert(err, IsNil)
+
+	err = image.ValidateSeed(seedFn, &image.ValidateOptions{Strict: true, Ignore: unassertedSeedOpts.Ignore})
+	c.Assert(err, ErrorMatches, `(?s)cannot read seed yaml: cannot unmarshal .*field chanel not found.*`)
+}
+
+func (s *validateSuite) TestValidateSeedReportBrokenSeedYaml(c *C) {
+	seedFn := s.makeSeedYaml(c, `snaps: garbage`)
+
+	_, err := image.ValidateSeedReport(seedFn, nil)
+	c.Assert(err, ErrorMatches, `(?s)cannot read seed yaml: cannot unmarshal .*`)
+}
+
+var pcKernelYaml = `name: pc-kernel
+version: 1.0
+type: kernel
+architectures: [amd64]`
+
+var pcGadgetYaml = `name: pc
+version: 1.0
+type: gadget
+architectures: [amd64]`
+
+var pcGadgetFiles = [][]string{
+	{"meta/gadget.yaml", `
+volumes:
+  pc:
+    bootloader: grub
+`},
+}
+
+func (s *validateSuite) TestValidateSeedModelSnapsHappy(c *C) {
+	s.writeSeedModel(c, s.model)
+	s.makeSnapInSeed(c, coreYaml)
+	s.makeSnapInSeed(c, pcKernelYaml)
+	s.makeSnapInSeedWithFiles(c, pcGadgetYaml, pcGadgetFiles)
+	for _, name := range []string{"core", "pc-kernel", "pc"} {
+		s.writeSeedSnapAssertions(c, name)
+	}
+	seedFn := s.makeSeedYaml(c, `
+snaps:
+ - name: core
+   file: core_1.snap
+ - name: pc-kernel
+   file: pc-kernel_1.snap
+ - name: pc
+   file: pc_1.snap
+   architecture: amd64
+`)
+
+	err := image.ValidateSeed(seedFn, nil)
+	c.Assert(err, IsNil)
+}
+
+func (s *validateSuite) TestValidateSeedCoreConfinement(c *C) {
+	s.writeSeedModel(c, s.model)
+	s.makeSnapInSeed(c, coreYaml)
+	s.makeSnapInSeed(c, pcKernelYaml)
+	s.makeSnapInSeedWithFiles(c, pcGadgetYaml, pcGadgetFiles)
+	s.makeSnapInSeed(c, `name: classic-snap
+version: 1.0
+confinement: classic`)
+	s.makeSnapInSeed(c, `name: devmode-snap
+version: 1.0
+confinement: devmode`)
+	for _, name := range []string{"core", "pc-kernel", "pc", "classic-snap", "devmode-snap"} {
+		s.writeSeedSnapAssertions(c, name)
+	}
+	seedFn := s.makeSeedYaml(c, `
+snaps:
+ - name: core
+   file: core_1.snap
+ - name: pc-kernel
+   file: pc-kernel_1.snap
+ - name: pc
+   file: pc_1.snap
+ - name: classic-snap
+   file: classic-snap_1.snap
+   classic: true
+ - name: devmode-snap
+   file: devmode-snap_1.snap
+   devmode: true
+`)
+
+	report, err := image.ValidateSeedReport(seedFn, nil)
+	c.Assert(err, IsNil)
+	c.Check(report.Findings, DeepEquals, []*image.SeedFinding{{
+		Severity: image.SeedFindingError,
+		Code:     image.SeedCodeClassicSnap,
+		Snap:     "classic-snap",
+		Path:     filepath.Join(s.root, "snaps", "classic-snap_1.snap"),
+		Message:  `cannot use classic snap "classic-snap" in a core system`,
+	}, {
+		Severity: image.SeedFindingWarning,
+		Code:     image.SeedCodeDevModeSnap,
+		Snap:     "devmode-snap",
+		Path:     filepath.Join(s.root, "snaps", "devmode-snap_1.snap"),
+		Message:  `snap "devmode-snap" uses devmode confinement and will not be confined in a core system`,
+	}})
+}
+
+func (s *validateSuite) TestValidateSeedCoreConfinementOption(c *C) {
+	s.makeSnapInSeed(c, coreYaml)
+	s.makeSnapInSeed(c, `name: classic-snap
+version: 1.0
+confinement: classic`)
+	seedFn := s.makeSeedYaml(c, `
+snaps:
+ - name: core
+   file: core_1.snap
+ - name: classic-snap
+   file: classic-snap_1.snap
+   classic: true
+`)
+
+	// without a model the seed is not checked as a core one
+	err := image.ValidateSeed(seedFn, unassertedSeedOpts)
+	c.Assert(err, IsNil)
+
+	err = image.ValidateSeed(seedFn, &image.ValidateOptions{Core: true, Ignore: unassertedSeedOpts.Ignore})
+	c.Assert(err, ErrorMatches, `cannot validate seed:
+- cannot use classic snap "classic-snap" in a core system`)
+}
+
+func (s *validateSuite) TestValidateSeedClassicModelConfinement(c *C) {
+	s.writeSeedModel(c, s.classicModel)
+	s.makeSnapInSeed(c, coreYaml)
+	s.makeSnapInSeed(c, `name: classic-snap
+version: 1.0
+confinement: classic`)
+	for _, name := range []string{"core", "classic-snap"} {
+		s.writeSeedSnapAssertions(c, name)
+	}
+	seedFn := s.makeSeedYaml(c, `
+snaps:
+ - name: core
+   file: core_1.snap
+ - name: classic-snap
+   file: classic-snap_1.snap
+   classic: true
+`)
+
+	err := image.ValidateSeed(seedFn, nil)
+	c.Assert(err, IsNil)
+}
+
+func (s *validateSuite) TestValidateSeedModelSnapsMissing(c *C) {
+	s.writeSeedModel(c, s.model)
+	s.makeSnapInSeed(c, coreYaml)
+	s.writeSeedSnapAssertions(c, "core")
+	seedFn := s.makeSeedYaml(c, `
+snaps:
+ - name: core
+   file: core_1.snap
+`)
+
+	report, err := image.ValidateSeedReport(seedFn, nil)
+	c.Assert(err, IsNil)
+	c.Check(report.Findings, DeepEquals, []*image.SeedFinding{
+		{
+			Severity: image.SeedFindingError,
+			Code:     image.SeedCodeMissingModelSnap,
+			Snap:     "pc-kernel",
+			Message:  `model kernel snap "pc-kernel" is missing from the seed`,
+		}, {
+			Severity: image.SeedFindingError,
+			Code:     image.SeedCodeMissingModelSnap,
+			Snap:     "pc",
+			Message:  `model gadget snap "pc" is missing from the seed`,
+		},
+	})
+}
+
+func (s *validateSuite) TestValidateSeedModelSnapsMismatch(c *C) {
+	s.writeSeedModel(c, s.model)
+	s.makeSnapInSeed(c, coreYaml)
+	s.makeSnapInSeed(c, `name: other-kernel
+version: 1.0
+type: kernel
+architectures: [amd64]`)
+	// not a kernel
+	s.makeSnapInSeed(c, `name: pc-kernel
+version: 1.0
+architectures: [amd64]`)
+	s.makeSnapInSeedWithFiles(c, pcGadgetYaml, pcGadgetFiles)
+	for _, name := range []string{"core", "other-kernel", "pc-kernel", "pc"} {
+		s.writeSeedSnapAssertions(c, name)
+	}
+	seedFn := s.makeSeedYaml(c, `
+snaps:
+ - name: core
+   file: core_1.snap
+ - name: other-kernel
+   file: other-kernel_1.snap
+ - name: pc-kernel
+   file: pc-kernel_1.snap
+ - name: pc
+   file: pc_1.snap
+`)
+
+	err := image.ValidateSeed(seedFn, nil)
+	c.Assert(err, ErrorMatches, `cannot validate seed:
+- cannot use kernel snap "other-kernel": the model kernel is "pc-kernel"
+- cannot use snap "pc-kernel" as the model kernel: snap type is "app"`)
+}
+
+func (s *validateSuite) TestValidateSeedModelSnapsArchitectureMismatch(c *C) {
+	s.writeSeedModel(c, s.model)
+	s.makeSnapInSeed(c, coreYaml)
+	s.makeSnapInSeed(c, `name: pc-kernel
+version: 1.0
+type: kernel
+architectures: [i386]`)
+	s.makeSnapInSeedWithFiles(c, `name: pc
+version: 1.0
+type: gadget
+architectures: [armhf]`, pcGadgetFiles)
+	for _, name := range []string{"core", "pc-kernel", "pc"} {
+		s.writeSeedSnapAssertions(c, name)
+	}
+	seedFn := s.makeSeedYaml(c, `
+snaps:
+ - name: core
+   file: core_1.snap
+ - name: pc-kernel
+   file: pc-kernel_1.snap
+   architecture: i386
+ - name: pc
+   file: pc_1.snap
+`)
+
+	// a compatible architecture is not enough for the kernel
+	err := image.ValidateSeed(seedFn, nil)
+	c.Assert(err, ErrorMatches, `cannot validate seed:
+- cannot use snap "pc": supported architectures \(armhf\) do not include "amd64"
+- cannot use snap "pc-kernel" as the model kernel: architecture "i386" does not match the model architecture "amd64"`)
+}
+
+func (s *validateSuite) TestValidateSeedCheckIntegrity(c *C) {
+	s.makeSnapInSeed(c, coreYaml)
+	s.makeSnapInSeed(c, `name: corrupted
+version: 1.0`)
+	seedFn := s.makeSeedYaml(c, `
+snaps:
+ - name: core
+   file: core_1.snap
+ - name: corrupted
+   file: corrupted_1.snap
+`)
+
+	var checked []string
+	restore := image.MockCheckSnapIntegrity(func(fn string) error {
+		checked = append(checked, filepath.Base(fn))
+		if filepath.Base(fn) == "corrupted_1.snap" {
+			return fmt.Errorf("cannot check %q: failed: \"read_block: failed to read block\"", fn)
+		}
+		return nil
+	})
+	defer restore()
+
+	// not checked by default
+	err := image.ValidateSeed(seedFn, unassertedSeedOpts)
+	c.Assert(err, IsNil)
+	c.Check(checked, HasLen, 0)
+
+	report, err := image.ValidateSeedReport(seedFn, &image.ValidateOptions{CheckIntegrity: true, Ignore: unassertedSeedOpts.Ignore})
+	c.Assert(err, IsNil)
+	c.Check(checked, DeepEquals, []string{"core_1.snap", "corrupted_1.snap"})
+	c.Assert(report.Errors(), HasLen, 1)
+	c.Check(report.Errors()[0].Code, Equals, image.SeedCodeSnapCorrupted)
+	c.Check(report.Errors()[0].Snap, Equals, "corrupted")
+	c.Check(report.Err(), ErrorMatches, `cannot validate seed:
+- cannot use snap "corrupted": cannot check ".*/snaps/corrupted_1.snap": failed: "read_block: failed to read block"`)
+}
+
+func (s *validateSuite) TestValidateSeedDuplicates(c *C) {
+	s.makeSnapInSeed(c, coreYaml)
+	s.makeSnapInSeed(c, `name: foo
+version: 1.0`)
+	seedFn := s.makeSeedYaml(c, `
+snaps:
+ - name: core
+   file: core_1.snap
+ - name: foo
+   file: foo_1.snap
+ - name: foo
+   file: foo_1.snap
+ - name: bar
+   file: foo_1.snap
+`)
+
+	report, err := image.ValidateSeedReport(seedFn, unassertedSeedOpts)
+	c.Assert(err, IsNil)
+	c.Assert(report.Errors(), HasLen, 2)
+	c.Check(report.Errors()[0].Code, Equals, image.SeedCodeDuplicateSnap)
+	c.Check(report.Errors()[0].Snap, Equals, "foo")
+	c.Check(report.Errors()[1].Code, Equals, image.SeedCodeDuplicateFile)
+	c.Check(report.Errors()[1].Snap, Equals, "bar")
+	c.Check(report.Err(), ErrorMatches, `cannot validate seed:
+- snap "foo" is listed more than once in the seed
+- cannot use snap "bar": file "foo_1.snap" is already used by snap "foo"`)
+}
+
+func (s *validateSuite) TestValidateSeedOrphanedFiles(c *C) {
+	s.makeSnapInSeed(c, coreYaml)
+	s.makeSnapInSeed(c, `name: foo
+version: 1.0`)
+	seedFn := s.makeSeedYaml(c, `
+snaps:
+ - name: core
+   file: core_1.snap
+`)
+
+	report, err := image.ValidateSeedReport(seedFn, unassertedSeedOpts)
+	c.Assert(err, IsNil)
+	c.Check(report.Err(), IsNil)
+	c.Check(report.Findings, DeepEquals, []*image.SeedFinding{
+		{
+			Severity: image.SeedFindingWarning,
+			Code:     image.SeedCodeOrphanedFile,
+			Path:     filepath.Join(s.root, "snaps", "foo_1.snap"),
+			Message:  `file "foo_1.snap" is not used by any snap of the seed`,
+		},
+	})
+}
+
+func (s *validateSuite) TestValidateSeedOrphanedAssertions(c *C) {
+	s.writeSeedModel(c, s.classicModel)
+	s.makeSnapInSeed(c, coreYaml)
+	s.makeSnapInSeed(c, `name: foo
+version: 1.0`)
+	s.writeSeedSnapAssertions(c, "core")
+	s.writeSeedSnapAssertions(c, "foo")
+	// only the snap file is dropped
+	c.Assert(os.Remove(filepath.Join(s.root, "snaps", "foo_1.snap")), IsNil)
+	seedFn := s.makeSeedYaml(c, `
+snaps:
+ - name: core
+   file: core_1.snap
+`)
+
+	report, err := image.ValidateSeedReport(seedFn, nil)
+	c.Assert(err, IsNil)
+	c.Check(report.Err(), IsNil)
+	c.Check(report.Findings, DeepEquals, []*image.SeedFinding{
+		{
+			Severity: image.SeedFindingWarning,
+			Code:     image.SeedCodeOrphanedAssertions,
+			Snap:     "foo",
+			Message:  `seed has assertions for snap "foo" which is not in the seed`,
+		},
+	})
+}
+
+func (s *validateSuite) TestValidateSeedSeveritiesAndIgnore(c *C) {
+	s.makeSnapInSeed(c, coreYaml)
+	s.makeSnapInSeed(c, `name: need-df
+version: 1.0
+plugs:
+ gtk-3-themes:
+  interface: content
+  default-provider: gtk-common-themes
+`)
+	seedFn := s.makeSeedYaml(c, `
+snaps:
+ - name: core
+   file: core_1.snap
+ - name: need-df
+   file: need-df_1.snap
+`)
+
+	opts := &image.ValidateOptions{
+		Severities: map[string]string{
+			image.SeedCodeMissingDefaultProvider: image.SeedFindingWarning,
+		},
+		Ignore: []string{image.SeedCodeNoAssertions},
+	}
+	report, err := image.ValidateSeedReport(seedFn, opts)
+	c.Assert(err, IsNil)
+	c.Check(report.Errors(), HasLen, 0)
+	c.Check(report.Warnings(), DeepEquals, []*image.SeedFinding{
+		{
+			Severity: image.SeedFindingWarning,
+			Code:     image.SeedCodeMissingDefaultProvider,
+			Snap:     "need-df",
+			Path:     filepath.Join(s.root, "snaps", "need-df_1.snap"),
+			Message:  `cannot use snap "need-df": default provider "gtk-common-themes" is missing`,
+		},
+	})
+	c.Check(image.ValidateSeed(seedFn, opts), IsNil)
+
+	// or skipped entirely
+	opts = &image.ValidateOptions{
+		Ignore: []string{image.SeedCodeNoAssertions, image.SeedCodeMissingDefaultProvider},
+	}
+	report, err = image.ValidateSeedReport(seedFn, opts)
+	c.Assert(err, IsNil)
+	c.Check(report.Findings, HasLen, 0)
+}
+
+func (s *validateSuite) TestValidateSeedInvalidOptions(c *C) {
+	seedFn := s.makeSeedYaml(c, `
+snaps:
+ - name: core
+   file: core_1.snap
+`)
+
+	for _, t := range []struct {
+		opts *image.ValidateOptions
+		err  string
+	}{
+		{&image.ValidateOptions{Severities: map[string]string{"foo": image.SeedFindingWarning}}, `cannot override the severity of unknown seed validation code "foo"`},
+		{&image.ValidateOptions{Severities: map[string]string{image.SeedCodeMissingCore: "fatal"}}, `invalid severity "fatal" for seed validation code "missing-core"`},
+		{&image.ValidateOptions{Ignore: []string{"foo"}}, `cannot ignore unknown seed validation code "foo"`},
+	} {
+		_, err := image.ValidateSeedReport(seedFn, t.opts)
+		c.Check(err, ErrorMatches, t.err)
+	}
+}
+
+func (s *validateSuite) TestValidateSeedGadgetInvalid(c *C) {
+	s.writeSeedModel(c, s.model)
+	s.makeSnapInSeed(c, coreYaml)
+	s.makeSnapInSeed(c, pcKernelYaml)
+	s.makeSnapInSeedWithFiles(c, pcGadgetYaml, [][]string{
+		{"meta/gadget.yaml", `
+volumes:
+  pc:
+    bootloader: grub
+    structure:
+      - name: one
+        type: bare
+        size: 2M
+        offset: 1M
+      - name: two
+        type: bare
+        size: 1M
+        offset: 2M
+`},
+	})
+	for _, name := range []string{"core", "pc-kernel", "pc"} {
+		s.writeSeedSnapAssertions(c, name)
+	}
+	seedFn := s.makeSeedYaml(c, `
+snaps:
+ - name: core
+   file: core_1.snap
+ - name: pc-kernel
+   file: pc-kernel_1.snap
+ - name: pc
+   file: pc_1.snap
+`)
+
+	report, err := image.ValidateSeedReport(seedFn, nil)
+	c.Assert(err, IsNil)
+	c.Assert(report.Findings, HasLen, 1)
+	c.Check(report.Findings[0].Code, Equals, image.SeedCodeInvalidGadget)
+	c.Check(report.Findings[0].Snap, Equals, "pc")
+	c.Check(report.Findings[0].Path, Equals, filepath.Join(s.root, "snaps", "pc_1.snap"))
+	c.Check(report.Findings[0].Message, Matches, `cannot use gadget snap "pc": invalid volume "pc": structure #1 \("two"\) overlaps with the preceding structure #0 \("one"\)`)
+}
+
+func (s *validateSuite) TestValidateSeedGadgetMissingGadgetYaml(c *C) {
+	s.writeSeedModel(c, s.model)
+	s.makeSnapInSeed(c, coreYaml)
+	s.makeSnapInSeed(c, pcKernelYaml)
+	s.makeSnapInSeed(c, pcGadgetYaml)
+	for _, name := range []string{"core", "pc-kernel", "pc"} {
+		s.writeSeedSnapAssertions(c, name)
+	}
+	seedFn := s.makeSeedYaml(c, `
+snaps:
+ - name: core
+   file: core_1.snap
+ - name: pc-kernel
+   file: pc-kernel_1.snap
+ - name: pc
+   file: pc_1.snap
+`)
+
+	err := image.ValidateSeed(seedFn, nil)
+	c.Assert(err, ErrorMatches, `cannot validate seed:
+- cannot use gadget snap "pc": meta/gadget.yaml is missing`)
+}
+
+func (s *validateSuite) TestValidateSeedGadgetDefaults(c *C) {
+	s.writeSeedModel(c, s.model)
+	s.makeSnapInSeed(c, coreYaml)
+	s.makeSnapInSeed(c, pcKernelYaml)
+	// snap ids in gadget defaults must be 32 characters long
+	s.makeSnapInSeed(c, `name: snap-with-gadget-defaults-abc
+version: 1.0`)
+	s.makeSnapInSeedWithFiles(c, pcGadgetYaml, [][]string{
+		{"meta/gadget.yaml", `
+defaults:
+  system:
+    service:
+      rsync:
+        disable: true
+  snap-with-gadget-defaults-abc-id:
+    foo: bar
+  other-snap-ididididididididididi:
+    foo: baz
+volumes:
+  pc:
+    bootloader: grub
+`},
+	})
+	for _, name := range []string{"core", "pc-kernel", "pc", "snap-with-gadget-defaults-abc"} {
+		s.writeSeedSnapAssertions(c, name)
+	}
+	seedFn := s.makeSeedYaml(c, `
+snaps:
+ - name: core
+   file: core_1.snap
+ - name: pc-kernel
+   file: pc-kernel_1.snap
+ - name: pc
+   file: pc_1.snap
+ - name: snap-with-gadget-defaults-abc
+   file: snap-with-gadget-defaults-abc_1.snap
+`)
+
+	report, err := image.ValidateSeedReport(seedFn, nil)
+	c.Assert(err, IsNil)
+	c.Check(report.Findings, DeepEquals, []*image.SeedFinding{
+		{
+			Severity: image.SeedFindingError,
+			Code:     image.SeedCodeGadgetDefaultsMismatch,
+			Snap:     "pc",
+			Path:     filepath.Join(s.root, "snaps", "pc_1.snap"),
+			Message:  `cannot use gadget snap "pc": defaults are for snap id "other-snap-ididididididididididi" which is not in the seed`,
+		},
+	})
+}
@@ -0,0 +1,200 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2019 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package image
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+
+	"github.com/snapcore/snapd/asserts"
+	"github.com/snapcore/snapd/asserts/snapasserts"
+	"github.com/snapcore/snapd/release"
+	"github.com/snapcore/snapd/snap"
+)
+
+var validationSetRefRx = regexp.MustCompile("^([a-zA-Z0-9]+)/([a-z0-9](?:-?[a-z0-9])*)(?:=([0-9]+))?$")
+
+// parseValidationSet splits a validation set reference of the form
+// account-id/name[=seq], seq is 0 if not specified.
+func parseValidationSet(ref string) (accountID, name string, seq int, err error) {
+	parts := validationSetRefRx.FindStringSubmatch(ref)
+	if parts == nil {
+		return "", "", 0, fmt.Errorf("cannot parse validation set %q: expected account-id/name[=seq]", ref)
+	}
+	if parts[3] != "" {
+		seq, err = strconv.Atoi(parts[3])
+		if err != nil || seq < 1 {
+			return "", "", 0, fmt.Errorf("cannot parse validation set %q: invalid sequence", ref)
+		}
+	}
+	return parts[1], parts[2], seq, nil
+}
+
+// validationSet is a validation-set resolved for the image.
+type validationSet struct {
+	*asserts.ValidationSet
+	// pinned is whether a sequence was requested
+	pinned bool
+}
+
+// fetchValidationSets fetches the validation-set assertions named in
+// opts, at the requested sequence or the latest one, together with
+// their prerequisites using f.
+func fetchValidationSets(tsto *ToolingStore, f asserts.Fetcher, opts *Options) ([]*validationSet, error) {
+	var sets []*validationSet
+	seen := make(map[string]bool, len(opts.ValidationSets))
+	for _, ref := range opts.ValidationSets {
+		accountID, name, seq, err := parseValidationSet(ref)
+		if err != nil {
+			return nil, err
+		}
+		key := fmt.Sprintf("%s/%s", accountID, name)
+		if seen[key] {
+			return nil, fmt.Errorf("cannot use validation set %s more than once", key)
+		}
+		seen[key] = true
+
+		// a primary key without the sequence asks the store for
+		// the latest one
+		primaryKey := []string{release.Series, accountID, name}
+		if seq > 0 {
+			primaryKey = append(primaryKey, strconv.Itoa(seq))
+		} else if opts.Offline {
+			return nil, fmt.Errorf("cannot use validation set %s without a sequence in offline mode", key)
+		}
+		a, err := tsto.sto.Assertion(asserts.ValidationSetType, primaryKey, tsto.user)
+		if err != nil {
+			return nil, fmt.Errorf("cannot fetch validation set %s: %v", key, err)
+		}
+		vs, ok := a.(*asserts.ValidationSet)
+		if !ok || vs.AccountID() != accountID || vs.Name() != name || (seq > 0 && vs.Sequence() != seq) {
+			return nil, fmt.Errorf("internal error: store returned unexpected assertion %v", a.Ref())
+		}
+		if err := f.Save(vs); err != nil {
+			return nil, fmt.Errorf("cannot fetch and check prerequisites for validation set %s: %v", key, err)
+		}
+		sets = append(sets, &validationSet{ValidationSet: vs, pinned: seq > 0})
+	}
+	return sets, nil
+}
+
+func validationSetAssertions(sets []*validationSet) []*asserts.ValidationSet {
+	vss := make([]*asserts.ValidationSet, len(sets))
+	for i, vs := range sets {
+		vss[i] = vs.ValidationSet
+	}
+	return vss
+}
+
+// Snaps are matched to the validation sets by name only: that is how
+// they are picked from the store for the image, and local snaps have no
+// snap-id to go by.
+
+// snapRevision returns the revision of the given store snap required
+// by the validation sets, if any.
+func snapRevision(name string, sets []*validationSet) (snap.Revision, error) {
+	rev, _, err := snapasserts.RequiredRevision(validationSetAssertions(sets), &snapasserts.InstalledSnap{Name: name})
+	if err != nil {
+		return snap.Revision{}, fmt.Errorf("cannot pick a revision of snap %q: %v", name, err)
+	}
+	return rev, nil
+}
+
+// checkValidationSets checks that the snaps to be seeded satisfy all
+// the validation sets.
+func checkValidationSets(infos []*snap.Info, sets []*validationSet) error {
+	if len(sets) == 0 {
+		return nil
+	}
+	snaps := make([]*snapasserts.InstalledSnap, 0, len(infos))
+	for _, info := range infos {
+		snaps = append(snaps, &snapasserts.InstalledSnap{
+			Name:     info.SnapName(),
+			Revision: info.Revision,
+		})
+	}
+	if err := snapasserts.CheckInstalledSnaps(validationSetAssertions(sets), snaps); err != nil {
+		return fmt.Errorf("cannot seed snaps: %v", err)
+	}
+	return nil
+}
+
+func seedValidationSets(sets []*validationSet) []*snap.SeedValidationSet {
+	var seedSets []*snap.SeedValidationSet
+	for _, vs := range sets {
+		seedSets = append(seedSets, &snap.SeedValidationSet{
+			AccountID: vs.AccountID(),
+			Name:      vs.Name(),
+			Sequence:  vs.Sequence(),
+			Pinned:    vs.pinned,
+		})
+	}
+	return seedSets
+}
+
+// resolveValidationSets fetches and checks the validation sets named in
+// opts, recording them for the following steps of preparing the image.
+func resolveValidationSets(tsto *ToolingStore, opts *Options) error {
+	if len(opts.ValidationSets) == 0 {
+		return nil
+	}
+	db, err := asserts.OpenDatabase(&asserts.DatabaseConfig{
+		Backstore: asserts.NewMemoryBackstore(),
+		Trusted:   trusted,
+	})
+	if err != nil {
+		return err
+	}
+	f := makeFetcher(tsto, &DownloadOptions{}, db)
+	opts.validationSets, err = fetchValidationSets(tsto, f, opts)
+	return err
+}
+
+// setSnapRevision sets in dlOpts the revision the given snap is pinned
+// to or the one required by the validation sets, if any. Local snaps
+// are left alone, they are only checked against the sets.
+func setSnapRevision(name string, dlOpts *DownloadOptions, opts *Options, local *localInfos) error {
+	if local.IsLocal(name) {
+		return nil
+	}
+	rev, err := snapRevision(name, opts.validationSets)
+	if err != nil {
+		return err
+	}
+	if pinned, ok := opts.SnapRevisions[name]; ok {
+		if !rev.Unset() && rev != pinned {
+			return fmt.Errorf("cannot pin snap %q to revision %s, validation sets require revision %s", name, pinned, rev)
+		}
+		if dlOpts.CohortKey != "" {
+			return fmt.Errorf("cannot use a cohort for snap %q, it is pinned to revision %s", name, pinned)
+		}
+		dlOpts.Revision = pinned
+		return nil
+	}
+	if rev.Unset() {
+		return nil
+	}
+	if dlOpts.CohortKey != "" {
+		return fmt.Errorf("cannot use a cohort for snap %q, its revision %s is required by validation sets", name, rev)
+	}
+	dlOpts.Revision = rev
+	return nil
+}
@@ -320,17 +320,11 @@ func profileIsRemovableOnCoreSetup(fn string) bool {
 // them or application present in the snap.
 func (b *Backend) Setup(snapInfo *snap.Info, opts interfaces.ConfinementOptions, repo *interfaces.Repository, tm timings.Measurer) error {
 	snapName := snapInfo.InstanceName()
-	spec, err := repo.SnapSpecification(b.Name(), snapName)
+	spec, err := b.snapSpecification(snapInfo, repo)
 	if err != nil {
-		return fmt.Errorf("cannot obtain apparmor specification for snap %q: %s", snapName, err)
+		return err
 	}
 
-	// Add snippets for parallel snap installation mapping
-	spec.(*Specification).AddOvername(snapInfo)
-
-	// Add snippets derived from the layout definition.
-	spec.(*Specification).AddLayout(snapInfo)
-
 	// core on classic is special
 	if snapName == "core" && release.OnClassic && release.AppArmorLevel() != release.NoAppArmor {
 		if err := setupSnapConfineReexec(snapInfo); err != nil {
@@ -365,7 +359,7 @@ func (b *Backend) Setup(snapInfo *snap.Info, opts interfaces.ConfinementOptions,
 	}
 
 	// Get the files that this snap should have
-	content, err := b.deriveContent(spec.(*Specification), snapInfo, opts)
+	content, err := b.deriveContent(spec, snapInfo, opts)
 	if err != nil {
 		return fmt.Errorf("cannot obtain expected security files for snap %q: %s", snapName, err)
 	}
@@ -426,6 +420,42 @@ func (b *Backend) Setup(snapInfo *snap.Info, opts interfaces.ConfinementOptions,
 	return errUnload
 }
 
+// snapSpecification returns the apparmor specification of the given snap,
+// including the snippets derived from the snap itself.
+func (b *Backend) snapSpecification(snapInfo *snap.Info, repo *interfaces.Repository) (*Specification, error) {
+	snapName := snapInfo.InstanceName()
+	spec, err := repo.SnapSpecification(b.Name(), snapName)
+	if err != nil {
+		return nil, fmt.Errorf("cannot obtain apparmor specification for snap %q: %s", snapName, err)
+	}
+
+	// Add snippets for parallel snap installation mapping
+	spec.(*Specification).AddOvername(snapInfo)
+
+	// Add snippets derived from the layout definition.
+	spec.(*Specification).AddLayout(snapInfo)
+
+	return spec.(*Specification), nil
+}
+
+// ProfilesDryRun returns the apparmor profiles that Setup would write for
+// the given snap, without writing or loading them.
+func (b *Backend) ProfilesDryRun(snapInfo *snap.Info, opts interfaces.ConfinementOptions, repo *interfaces.Repository) (map[string]string, error) {
+	spec, err := b.snapSpecification(snapInfo, repo)
+	if err != nil {
+		return nil, err
+	}
+	content, err := b.deriveContent(spec, snapInfo, opts)
+	if err != nil {
+		return nil, fmt.Errorf("cannot obtain expected security files for snap %q: %s", snapInfo.InstanceName(), err)
+	}
+	profiles := make(map[string]string, len(content))
+	for name, fileState := range content {
+		profiles[filepath.Join(dirs.SnapAppArmorDir, name)] = string(fileState.Content)
+	}
+	return profiles, nil
+}
+
 // Remove removes and unloads apparmor profiles of a given snap.
 func (b *Backend) Remove(snapName string) error {
 	dir := dirs.SnapAppArmorDir
@@ -517,6 +547,11 @@ func downgradeConfinement() bool {
 	return true
 }
 
+func parserSupportsUnconfined() bool {
+	features, _ := parserFeatures()
+	return strutil.ListContains(features, "unconfined")
+}
+
 func addContent(securityTag string, snapInfo *snap.Info, opts interfaces.ConfinementOptions, snippetForTag string, content map[string]*osutil.FileState, spec *Specification) {
 	// Normally we use a specific apparmor template for all snap programs.
 	policy := defaultTemplate
@@ -526,6 +561,11 @@ func addContent(securityTag string, snapInfo *snap.Info, opts interfaces.Confine
 	if opts.Classic && !opts.JailMode {
 		policy = classicTemplate
 		ignoreSnippets = true
+		// If the parser supports it, just label the processes of the
+		// snap with a profile in unconfined mode.
+		if parserSupportsUnconfined() {
+			policy = classicUnconfinedTemplate
+		}
 	}
 	// When partial AppArmor is detected, use the classic template for now. We could
 	// use devmode, but that could generate confusing log entries for users running
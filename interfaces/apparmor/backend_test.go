@@ -219,6 +219,28 @@ func (s *backendSuite) TestTimings(c *C) {
 	}
 }
 
+func (s *backendSuite) TestProfilesDryRun(c *C) {
+	snapInfo := s.InstallSnap(c, interfaces.ConfinementOptions{}, "", ifacetest.SambaYamlV1, 1)
+	updateNSProfile := filepath.Join(dirs.SnapAppArmorDir, "snap-update-ns.samba")
+	profile := filepath.Join(dirs.SnapAppArmorDir, "snap.samba.smbd")
+	s.parserCmd.ForgetCalls()
+
+	profiles, err := s.Backend.(interfaces.SecurityBackendDryRun).ProfilesDryRun(snapInfo, interfaces.ConfinementOptions{}, s.Repo)
+	c.Assert(err, IsNil)
+	c.Check(profiles, HasLen, 2)
+	// the profiles are the ones written by Setup
+	c.Check(profile, testutil.FileEquals, profiles[profile])
+	c.Check(updateNSProfile, testutil.FileEquals, profiles[updateNSProfile])
+	// nothing was loaded
+	c.Check(s.parserCmd.Calls(), HasLen, 0)
+
+	// and nothing is written
+	c.Assert(os.Remove(profile), IsNil)
+	_, err = s.Backend.(interfaces.SecurityBackendDryRun).ProfilesDryRun(snapInfo, interfaces.ConfinementOptions{DevMode: true}, s.Repo)
+	c.Assert(err, IsNil)
+	c.Check(profile, testutil.FileAbsent)
+}
+
 func (s *backendSuite) TestProfilesAreAlwaysLoaded(c *C) {
 	for _, opts := range testedConfinementOpts {
 		snapInfo := s.InstallSnap(c, opts, "", ifacetest.SambaYamlV1, 1)
@@ -586,6 +608,43 @@ func (s *backendSuite) TestCombineSnippetsChangeProfile(c *C) {
 	}
 }
 
+func (s *backendSuite) TestClassicUnconfinedWhenSupported(c *C) {
+	restore := release.MockAppArmorLevel(release.FullAppArmor)
+	defer restore()
+	restore = apparmor.MockIsHomeUsingNFS(func() (bool, error) { return false, nil })
+	defer restore()
+	restore = apparmor.MockIsRootWritableOverlay(func() (string, error) { return "", nil })
+	defer restore()
+	restore = apparmor.MockParserFeatures(func() ([]string, error) { return []string{"unconfined", "unsafe"}, nil })
+	defer restore()
+
+	s.Iface.AppArmorPermanentSlotCallback = func(spec *apparmor.Specification, slot *snap.SlotInfo) error {
+		spec.AddSnippet("snippet")
+		return nil
+	}
+
+	for _, opts := range []interfaces.ConfinementOptions{
+		{Classic: true},
+		{Classic: true, DevMode: true},
+	} {
+		snapInfo := s.InstallSnap(c, opts, "", ifacetest.SambaYamlV1, 1)
+		profile := filepath.Join(dirs.SnapAppArmorDir, "snap.samba.smbd")
+		c.Check(profile, testutil.FileContains, `profile "snap.samba.smbd" (attach_disconnected,mediate_deleted,unconfined) {
+}
+`)
+		c.Check(profile, Not(testutil.FileContains), "complain")
+		c.Check(profile, Not(testutil.FileContains), "snippet")
+		s.RemoveSnap(c, snapInfo)
+	}
+
+	// jailmode is still confined
+	snapInfo := s.InstallSnap(c, interfaces.ConfinementOptions{Classic: true, JailMode: true}, "", ifacetest.SambaYamlV1, 1)
+	profile := filepath.Join(dirs.SnapAppArmorDir, "snap.samba.smbd")
+	c.Check(profile, Not(testutil.FileContains), "unconfined)")
+	c.Check(profile, testutil.FileContains, "snippet")
+	s.RemoveSnap(c, snapInfo)
+}
+
 func (s *backendSuite) TestParallelInstallCombineSnippets(c *C) {
 	restore := release.MockAppArmorLevel(release.FullAppArmor)
 	defer restore()
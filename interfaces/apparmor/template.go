@@ -384,6 +384,9 @@ var defaultTemplate = `
   # Read-only of this snap
   /var/lib/snapd/snaps/@{SNAP_NAME}_*.snap r,
 
+  # Read-only of the environment derived from the connections of this snap
+  /var/lib/snapd/mount/snap.@{SNAP_INSTANCE_NAME}.environment r,
+
   # Read-only for the install directory
   # bind mount used here (see 'parallel installs', above)
   @{INSTALL_DIR}/{@{SNAP_NAME},@{SNAP_INSTANCE_NAME}}/                   r,
@@ -547,6 +550,22 @@ var classicTemplate = `
 }
 `
 
+// classicUnconfinedTemplate contains the apparmor template used for snaps
+// with classic confinement when the parser supports the unconfined profile
+// mode.
+//
+// Like the classic template it provides no confinement and only ensures
+// that processes carry the command-specific security label, but without
+// the cost of mediating, or logging, anything.
+var classicUnconfinedTemplate = `
+#include <tunables/global>
+
+###VAR###
+
+###PROFILEATTACH### (attach_disconnected,mediate_deleted,unconfined) {
+}
+`
+
 // classicJailmodeSnippet contains extra rules that allow snaps using classic
 // confinement, that were put in to jailmode, to execute by at least having
 // access to the core snap (e.g. for the dynamic linker and libc).
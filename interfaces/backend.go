@@ -96,3 +96,12 @@ type SecurityBackend interface {
 	// SandboxFeatures returns a list of tags that identify sandbox features.
 	SandboxFeatures() []string
 }
+
+// SecurityBackendDryRun is implemented by security backends that can
+// compute the security profiles of a snap without writing or loading
+// them, for instance to review them.
+type SecurityBackendDryRun interface {
+	// ProfilesDryRun returns the content of the profiles that Setup
+	// would write for the given snap, keyed by the path of their file.
+	ProfilesDryRun(snapInfo *snap.Info, opts ConfinementOptions, repo *Repository) (map[string]string, error)
+}
@@ -0,0 +1,63 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2019 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package builtin
+
+const connectivityCheckSummary = `allows querying network connectivity via the snapd provided service`
+
+const connectivityCheckBaseDeclarationSlots = `
+  connectivity-check:
+    allow-installation:
+      slot-snap-type:
+        - core
+`
+
+const connectivityCheckConnectedPlugAppArmor = `
+# Description: Can ask the snapd provided io.snapcraft.NetworkStatus service
+# whether the system is online, offline or behind a captive portal, without
+# needing to probe the network (or observe it) itself.
+
+#include <abstractions/dbus-strict>
+
+dbus (send)
+    bus=system
+    path=/io/snapcraft/NetworkStatus
+    interface=io.snapcraft.NetworkStatus
+    member=GetConnectivity
+    peer=(label=unconfined),
+
+dbus (send)
+    bus=system
+    path=/io/snapcraft/NetworkStatus
+    interface=org.freedesktop.DBus.Introspectable
+    member=Introspect
+    peer=(label=unconfined),
+`
+
+func init() {
+	registerIface(&commonInterface{
+		name:                  "connectivity-check",
+		summary:               connectivityCheckSummary,
+		implicitOnCore:        true,
+		implicitOnClassic:     true,
+		baseDeclarationSlots:  connectivityCheckBaseDeclarationSlots,
+		connectedPlugAppArmor: connectivityCheckConnectedPlugAppArmor,
+		reservedForOS:         true,
+	})
+}
@@ -309,9 +309,19 @@ func (iface *contentInterface) MountConnectedPlug(spec *mount.Specification, plu
 			return err
 		}
 	}
+	// Let the apps find the content without hardcoding the target.
+	var target string
+	_ = plug.Attr("target", &target)
+	spec.AddEnvironment(contentPathVariable(slot.Name()), resolveSpecialVariable(target, plug.Snap()))
 	return nil
 }
 
+// contentPathVariable returns the name of the environment variable
+// holding the path where the content of the given slot is mounted.
+func contentPathVariable(slotName string) string {
+	return fmt.Sprintf("SNAP_CONTENT_%s_PATH", strings.ToUpper(strings.Replace(slotName, "-", "_", -1)))
+}
+
 func init() {
 	registerIface(&contentInterface{})
 }
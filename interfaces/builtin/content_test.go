@@ -271,6 +271,49 @@ slots:
 }
 
 // Check that sharing of read-only snap content is possible
+func (s *ContentSuite) TestConnectedPlugEnvironment(c *C) {
+	const consumerYaml = `name: consumer
+version: 0
+plugs:
+ themes:
+  interface: content
+  target: $SNAP_DATA/themes
+ fonts:
+  interface: content
+  target: fonts
+apps:
+ app:
+  command: foo
+`
+	consumerInfo := snaptest.MockInfo(c, consumerYaml, &snap.SideInfo{Revision: snap.R(7)})
+	const producerYaml = `name: producer
+version: 0
+slots:
+ gtk-3-themes:
+  interface: content
+  source:
+   read:
+    - $SNAP/share/themes
+ fonts:
+  interface: content
+  read:
+   - $SNAP/fonts
+`
+	producerInfo := snaptest.MockInfo(c, producerYaml, &snap.SideInfo{Revision: snap.R(5)})
+
+	spec := &mount.Specification{}
+	for plugName, slotName := range map[string]string{"themes": "gtk-3-themes", "fonts": "fonts"} {
+		plug := interfaces.NewConnectedPlug(consumerInfo.Plugs[plugName], nil, nil)
+		slot := interfaces.NewConnectedSlot(producerInfo.Slots[slotName], nil, nil)
+		c.Assert(spec.AddConnectedPlug(s.iface, plug, slot), IsNil)
+	}
+	// the variables point to the target, not to the sources mounted in it
+	c.Assert(spec.Environment(), DeepEquals, []string{
+		"SNAP_CONTENT_FONTS_PATH=" + filepath.Join(dirs.CoreSnapMountDir, "consumer/7/fonts"),
+		"SNAP_CONTENT_GTK_3_THEMES_PATH=" + filepath.Join(dirs.SnapDataDir, "consumer/7/themes"),
+	})
+}
+
 func (s *ContentSuite) TestConnectedPlugSnippetSharingSnap(c *C) {
 	const consumerYaml = `name: consumer
 version: 0
@@ -301,6 +344,9 @@ slots:
 		Options: []string{"bind", "ro"},
 	}}
 	c.Assert(spec.MountEntries(), DeepEquals, expectedMnt)
+	c.Assert(spec.Environment(), DeepEquals, []string{
+		"SNAP_CONTENT_CONTENT_PATH=" + filepath.Join(dirs.CoreSnapMountDir, "consumer/7/import"),
+	})
 
 	apparmorSpec := &apparmor.Specification{}
 	err := apparmorSpec.AddConnectedPlug(s.iface, plug, slot)
@@ -0,0 +1,296 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2019 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package builtin
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/snapcore/snapd/interfaces"
+	"github.com/snapcore/snapd/interfaces/apparmor"
+	"github.com/snapcore/snapd/interfaces/udev"
+	"github.com/snapcore/snapd/snap"
+)
+
+const customDeviceSummary = `allows access to custom devices specified by the gadget`
+
+const customDeviceBaseDeclarationSlots = `
+  custom-device:
+    allow-installation:
+      slot-snap-type:
+        - gadget
+    allow-connection:
+      plug-attributes:
+        custom-device: $SLOT(custom-device)
+    deny-auto-connection: true
+`
+
+const customDeviceConnectedPlugAppArmor = `
+# Description: Can access the custom device %s specified by the gadget
+`
+
+// customDeviceInterface lets the gadget describe, under a named slot, the
+// device nodes and udev rules of devices specific to its board.
+type customDeviceInterface struct{}
+
+func (iface *customDeviceInterface) Name() string {
+	return "custom-device"
+}
+
+func (iface *customDeviceInterface) StaticInfo() interfaces.StaticInfo {
+	return interfaces.StaticInfo{
+		Summary:              customDeviceSummary,
+		BaseDeclarationSlots: customDeviceBaseDeclarationSlots,
+	}
+}
+
+var (
+	customDevicePathPattern      = regexp.MustCompile(`^/dev/[a-zA-Z0-9_.:/-]+$`)
+	customDeviceUDevKeyPattern   = regexp.MustCompile(`^[a-zA-Z0-9_]+$`)
+	customDeviceUDevValuePattern = regexp.MustCompile(`^[^"\\\n]+$`)
+)
+
+// customDeviceRule is a udev match rule for one of the custom devices.
+type customDeviceRule struct {
+	kernel     string
+	subsystem  string
+	attributes map[string]string
+}
+
+func (r *customDeviceRule) String() string {
+	parts := []string{fmt.Sprintf(`KERNEL=="%s"`, r.kernel)}
+	if r.subsystem != "" {
+		parts = append(parts, fmt.Sprintf(`SUBSYSTEM=="%s"`, r.subsystem))
+	}
+	keys := make([]string, 0, len(r.attributes))
+	for k := range r.attributes {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		parts = append(parts, fmt.Sprintf(`ATTRS{%s}=="%s"`, k, r.attributes[k]))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// customDevice is the validated description of the devices given by the
+// attributes of a custom-device slot.
+type customDevice struct {
+	name        string
+	devices     []string
+	readDevices []string
+	rules       []*customDeviceRule
+}
+
+func customDevicePaths(attrs interfaces.Attrer, attr string, seen map[string]bool) ([]string, error) {
+	value, ok := attrs.Lookup(attr)
+	if !ok {
+		return nil, nil
+	}
+	rawPaths, ok := value.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%q must be a list of strings", attr)
+	}
+	paths := make([]string, 0, len(rawPaths))
+	for _, rawPath := range rawPaths {
+		path, ok := rawPath.(string)
+		if !ok {
+			return nil, fmt.Errorf("%q must be a list of strings", attr)
+		}
+		if filepath.Clean(path) != path || !customDevicePathPattern.MatchString(path) {
+			return nil, fmt.Errorf("%q is not a valid device path", path)
+		}
+		if seen[path] {
+			return nil, fmt.Errorf("cannot specify device %q more than once", path)
+		}
+		seen[path] = true
+		paths = append(paths, path)
+	}
+	return paths, nil
+}
+
+func customDeviceUDevValue(key string, value interface{}) (string, error) {
+	s, ok := value.(string)
+	if !ok || !customDeviceUDevValuePattern.MatchString(s) {
+		return "", fmt.Errorf("invalid udev-tagging %s value %v", key, value)
+	}
+	return s, nil
+}
+
+func customDeviceRuleFromAttrs(rawRule interface{}, kernels map[string]bool) (*customDeviceRule, error) {
+	attrs, ok := rawRule.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf(`"udev-tagging" must be a list of maps`)
+	}
+	rule := &customDeviceRule{}
+	for key, value := range attrs {
+		var err error
+		switch key {
+		case "kernel":
+			rule.kernel, err = customDeviceUDevValue(key, value)
+		case "subsystem":
+			rule.subsystem, err = customDeviceUDevValue(key, value)
+		case "attributes":
+			rawAttributes, ok := value.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf(`udev-tagging "attributes" must be a map`)
+			}
+			rule.attributes = make(map[string]string, len(rawAttributes))
+			for attrKey, attrValue := range rawAttributes {
+				if !customDeviceUDevKeyPattern.MatchString(attrKey) {
+					return nil, fmt.Errorf("invalid udev-tagging attribute name %q", attrKey)
+				}
+				rule.attributes[attrKey], err = customDeviceUDevValue(attrKey, attrValue)
+				if err != nil {
+					return nil, err
+				}
+			}
+		default:
+			return nil, fmt.Errorf("unknown udev-tagging key %q", key)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	if rule.kernel == "" {
+		return nil, fmt.Errorf(`udev-tagging entries must have a "kernel" key`)
+	}
+	if !kernels[rule.kernel] {
+		return nil, fmt.Errorf("udev-tagging kernel %q does not match any of the devices", rule.kernel)
+	}
+	return rule, nil
+}
+
+func (iface *customDeviceInterface) device(slotRef *interfaces.SlotRef, attrs interfaces.Attrer) (*customDevice, error) {
+	var dev customDevice
+	if err := attrs.Attr("custom-device", &dev.name); err != nil {
+		return nil, fmt.Errorf("custom-device slot %q must have a custom-device attribute", slotRef)
+	}
+
+	seen := make(map[string]bool)
+	var err error
+	dev.devices, err = customDevicePaths(attrs, "devices", seen)
+	if err != nil {
+		return nil, fmt.Errorf("invalid custom-device slot %q: %v", slotRef, err)
+	}
+	dev.readDevices, err = customDevicePaths(attrs, "read-devices", seen)
+	if err != nil {
+		return nil, fmt.Errorf("invalid custom-device slot %q: %v", slotRef, err)
+	}
+	if len(seen) == 0 {
+		return nil, fmt.Errorf(`custom-device slot %q must have a "devices" or "read-devices" attribute`, slotRef)
+	}
+
+	// udev identifies devices by their kernel name, which is
+	// usually the base name of the device node
+	kernels := make(map[string]bool, len(seen))
+	for _, path := range append(dev.devices, dev.readDevices...) {
+		kernels[filepath.Base(path)] = true
+	}
+
+	value, ok := attrs.Lookup("udev-tagging")
+	if !ok {
+		// tag all the devices by their kernel name by default
+		for _, path := range append(dev.devices, dev.readDevices...) {
+			dev.rules = append(dev.rules, &customDeviceRule{kernel: filepath.Base(path)})
+		}
+		return &dev, nil
+	}
+	rawRules, ok := value.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf(`invalid custom-device slot %q: "udev-tagging" must be a list of maps`, slotRef)
+	}
+	for _, rawRule := range rawRules {
+		rule, err := customDeviceRuleFromAttrs(rawRule, kernels)
+		if err != nil {
+			return nil, fmt.Errorf("invalid custom-device slot %q: %v", slotRef, err)
+		}
+		dev.rules = append(dev.rules, rule)
+	}
+	return &dev, nil
+}
+
+func (iface *customDeviceInterface) BeforePrepareSlot(slot *snap.SlotInfo) error {
+	if slot.Snap.GetType() != snap.TypeGadget {
+		return fmt.Errorf("%s slots are reserved for gadget snaps", iface.Name())
+	}
+	name, ok := slot.Attrs["custom-device"].(string)
+	if !ok || name == "" {
+		if slot.Attrs == nil {
+			slot.Attrs = make(map[string]interface{})
+		}
+		// custom-device defaults to the slot name if unspecified
+		slot.Attrs["custom-device"] = slot.Name
+	}
+	_, err := iface.device(&interfaces.SlotRef{Snap: slot.Snap.InstanceName(), Name: slot.Name}, slot)
+	return err
+}
+
+func (iface *customDeviceInterface) BeforePreparePlug(plug *snap.PlugInfo) error {
+	name, ok := plug.Attrs["custom-device"].(string)
+	if !ok || name == "" {
+		if plug.Attrs == nil {
+			plug.Attrs = make(map[string]interface{})
+		}
+		// custom-device defaults to the plug name if unspecified
+		plug.Attrs["custom-device"] = plug.Name
+	}
+	return nil
+}
+
+func (iface *customDeviceInterface) AppArmorConnectedPlug(spec *apparmor.Specification, plug *interfaces.ConnectedPlug, slot *interfaces.ConnectedSlot) error {
+	dev, err := iface.device(slot.Ref(), slot)
+	if err != nil {
+		return nil
+	}
+	buf := bytes.NewBufferString(fmt.Sprintf(customDeviceConnectedPlugAppArmor, dev.name))
+	for _, path := range dev.devices {
+		fmt.Fprintf(buf, "%s rw,\n", path)
+	}
+	for _, path := range dev.readDevices {
+		fmt.Fprintf(buf, "%s r,\n", path)
+	}
+	spec.AddSnippet(buf.String())
+	return nil
+}
+
+func (iface *customDeviceInterface) UDevConnectedPlug(spec *udev.Specification, plug *interfaces.ConnectedPlug, slot *interfaces.ConnectedSlot) error {
+	dev, err := iface.device(slot.Ref(), slot)
+	if err != nil {
+		return nil
+	}
+	for _, rule := range dev.rules {
+		spec.TagDevice(rule.String())
+	}
+	return nil
+}
+
+func (iface *customDeviceInterface) AutoConnect(*snap.PlugInfo, *snap.SlotInfo) bool {
+	// Allow what is allowed in the declarations
+	return true
+}
+
+func init() {
+	registerIface(&customDeviceInterface{})
+}
@@ -0,0 +1,206 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2019 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package builtin_test
+
+import (
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/interfaces"
+	"github.com/snapcore/snapd/interfaces/apparmor"
+	"github.com/snapcore/snapd/interfaces/builtin"
+	"github.com/snapcore/snapd/interfaces/udev"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/snap/snaptest"
+	"github.com/snapcore/snapd/testutil"
+)
+
+type customDeviceInterfaceSuite struct {
+	iface interfaces.Interface
+
+	slotInfo      *snap.SlotInfo
+	slot          *interfaces.ConnectedSlot
+	slotTagInfo   *snap.SlotInfo
+	slotTag       *interfaces.ConnectedSlot
+	plugInfo      *snap.PlugInfo
+	plug          *interfaces.ConnectedPlug
+	plugNamedInfo *snap.PlugInfo
+}
+
+var _ = Suite(&customDeviceInterfaceSuite{
+	iface: builtin.MustInterface("custom-device"),
+})
+
+const customDeviceConsumerYaml = `name: consumer
+version: 0
+plugs:
+  board-leds:
+    interface: custom-device
+  leds:
+    interface: custom-device
+    custom-device: board-leds
+apps:
+ app:
+  plugs: [board-leds, leds]
+`
+
+const customDeviceGadgetYaml = `name: gadget
+version: 0
+type: gadget
+slots:
+  board-leds:
+    interface: custom-device
+    devices:
+      - /dev/leds0
+      - /dev/leds/ctrl
+    read-devices:
+      - /dev/leds-status
+  board-tagged:
+    interface: custom-device
+    custom-device: tagged
+    devices:
+      - /dev/ttyBOARD0
+    udev-tagging:
+      - kernel: ttyBOARD0
+        subsystem: tty
+        attributes:
+          idVendor: "1234"
+          idProduct: "5678"
+`
+
+func (s *customDeviceInterfaceSuite) SetUpTest(c *C) {
+	gadget := snaptest.MockInfo(c, customDeviceGadgetYaml, nil)
+	s.slotInfo = gadget.Slots["board-leds"]
+	c.Assert(interfaces.BeforePrepareSlot(s.iface, s.slotInfo), IsNil)
+	s.slot = interfaces.NewConnectedSlot(s.slotInfo, nil, nil)
+	s.slotTagInfo = gadget.Slots["board-tagged"]
+	c.Assert(interfaces.BeforePrepareSlot(s.iface, s.slotTagInfo), IsNil)
+	s.slotTag = interfaces.NewConnectedSlot(s.slotTagInfo, nil, nil)
+
+	consumer := snaptest.MockInfo(c, customDeviceConsumerYaml, nil)
+	s.plugInfo = consumer.Plugs["board-leds"]
+	c.Assert(interfaces.BeforePreparePlug(s.iface, s.plugInfo), IsNil)
+	s.plug = interfaces.NewConnectedPlug(s.plugInfo, nil, nil)
+	s.plugNamedInfo = consumer.Plugs["leds"]
+	c.Assert(interfaces.BeforePreparePlug(s.iface, s.plugNamedInfo), IsNil)
+}
+
+func (s *customDeviceInterfaceSuite) TestName(c *C) {
+	c.Assert(s.iface.Name(), Equals, "custom-device")
+}
+
+func (s *customDeviceInterfaceSuite) TestDefaultNames(c *C) {
+	c.Check(s.slotInfo.Attrs["custom-device"], Equals, "board-leds")
+	c.Check(s.slotTagInfo.Attrs["custom-device"], Equals, "tagged")
+	c.Check(s.plugInfo.Attrs["custom-device"], Equals, "board-leds")
+	c.Check(s.plugNamedInfo.Attrs["custom-device"], Equals, "board-leds")
+}
+
+func (s *customDeviceInterfaceSuite) TestSanitizeSlotNotGadget(c *C) {
+	slot := &snap.SlotInfo{
+		Snap:      &snap.Info{SuggestedName: "some-snap"},
+		Name:      "board-leds",
+		Interface: "custom-device",
+		Attrs:     map[string]interface{}{"devices": []interface{}{"/dev/leds0"}},
+	}
+	c.Assert(interfaces.BeforePrepareSlot(s.iface, slot), ErrorMatches,
+		"custom-device slots are reserved for gadget snaps")
+}
+
+func (s *customDeviceInterfaceSuite) TestSanitizeSlotErrors(c *C) {
+	for _, t := range []struct {
+		attrs string
+		err   string
+	}{
+		{``, `custom-device slot "gadget:slot" must have a "devices" or "read-devices" attribute`},
+		{`devices: /dev/foo`, `invalid custom-device slot "gadget:slot": "devices" must be a list of strings`},
+		{`devices: [1]`, `invalid custom-device slot "gadget:slot": "devices" must be a list of strings`},
+		{`devices: [/sys/foo]`, `invalid custom-device slot "gadget:slot": "/sys/foo" is not a valid device path`},
+		{`devices: [/dev/../etc/passwd]`, `invalid custom-device slot "gadget:slot": "/dev/../etc/passwd" is not a valid device path`},
+		{`devices: ["/dev/foo*"]`, `invalid custom-device slot "gadget:slot": "/dev/foo\*" is not a valid device path`},
+		{"devices: [/dev/foo]\n    read-devices: [/dev/foo]", `invalid custom-device slot "gadget:slot": cannot specify device "/dev/foo" more than once`},
+		{"devices: [/dev/foo]\n    udev-tagging: foo", `invalid custom-device slot "gadget:slot": "udev-tagging" must be a list of maps`},
+		{"devices: [/dev/foo]\n    udev-tagging: [foo]", `invalid custom-device slot "gadget:slot": "udev-tagging" must be a list of maps`},
+		{"devices: [/dev/foo]\n    udev-tagging: [{subsystem: tty}]", `invalid custom-device slot "gadget:slot": udev-tagging entries must have a "kernel" key`},
+		{"devices: [/dev/foo]\n    udev-tagging: [{kernel: bar}]", `invalid custom-device slot "gadget:slot": udev-tagging kernel "bar" does not match any of the devices`},
+		{"devices: [/dev/foo]\n    udev-tagging: [{kernel: foo, mode: 0666}]", `invalid custom-device slot "gadget:slot": unknown udev-tagging key "mode"`},
+		{"devices: [/dev/foo]\n    udev-tagging: [{kernel: foo, subsystem: 'tty\", RUN+=\"/bin/sh'}]", `invalid custom-device slot "gadget:slot": invalid udev-tagging subsystem value .*`},
+		{"devices: [/dev/foo]\n    udev-tagging: [{kernel: foo, attributes: [a]}]", `invalid custom-device slot "gadget:slot": udev-tagging "attributes" must be a map`},
+		{"devices: [/dev/foo]\n    udev-tagging: [{kernel: foo, attributes: {'a}': b}}]", `invalid custom-device slot "gadget:slot": invalid udev-tagging attribute name "a}"`},
+	} {
+		info := snaptest.MockInfo(c, `name: gadget
+version: 0
+type: gadget
+slots:
+  slot:
+    interface: custom-device
+    `+t.attrs+`
+`, nil)
+		c.Check(interfaces.BeforePrepareSlot(s.iface, info.Slots["slot"]), ErrorMatches, t.err, Commentf(t.attrs))
+	}
+}
+
+func (s *customDeviceInterfaceSuite) TestAppArmorSpec(c *C) {
+	spec := &apparmor.Specification{}
+	c.Assert(spec.AddConnectedPlug(s.iface, s.plug, s.slot), IsNil)
+	c.Assert(spec.SecurityTags(), DeepEquals, []string{"snap.consumer.app"})
+	c.Check(spec.SnippetForTag("snap.consumer.app"), Equals, `
+# Description: Can access the custom device board-leds specified by the gadget
+/dev/leds0 rw,
+/dev/leds/ctrl rw,
+/dev/leds-status r,
+`)
+}
+
+func (s *customDeviceInterfaceSuite) TestUDevSpec(c *C) {
+	spec := &udev.Specification{}
+	c.Assert(spec.AddConnectedPlug(s.iface, s.plug, s.slot), IsNil)
+	c.Assert(spec.Snippets(), HasLen, 4)
+	c.Check(spec.Snippets(), testutil.Contains, `# custom-device
+KERNEL=="leds0", TAG+="snap_consumer_app"`)
+	c.Check(spec.Snippets(), testutil.Contains, `# custom-device
+KERNEL=="ctrl", TAG+="snap_consumer_app"`)
+	c.Check(spec.Snippets(), testutil.Contains, `# custom-device
+KERNEL=="leds-status", TAG+="snap_consumer_app"`)
+	c.Check(spec.Snippets(), testutil.Contains, `TAG=="snap_consumer_app", RUN+="/usr/lib/snapd/snap-device-helper $env{ACTION} snap_consumer_app $devpath $major:$minor"`)
+}
+
+func (s *customDeviceInterfaceSuite) TestUDevSpecTagging(c *C) {
+	spec := &udev.Specification{}
+	c.Assert(spec.AddConnectedPlug(s.iface, s.plug, s.slotTag), IsNil)
+	c.Assert(spec.Snippets(), HasLen, 2)
+	c.Check(spec.Snippets(), testutil.Contains, `# custom-device
+KERNEL=="ttyBOARD0", SUBSYSTEM=="tty", ATTRS{idProduct}=="5678", ATTRS{idVendor}=="1234", TAG+="snap_consumer_app"`)
+}
+
+func (s *customDeviceInterfaceSuite) TestStaticInfo(c *C) {
+	si := interfaces.StaticInfoOf(s.iface)
+	c.Assert(si.ImplicitOnCore, Equals, false)
+	c.Assert(si.ImplicitOnClassic, Equals, false)
+	c.Assert(si.Summary, Equals, "allows access to custom devices specified by the gadget")
+	c.Assert(si.BaseDeclarationSlots, testutil.Contains, "custom-device")
+}
+
+func (s *customDeviceInterfaceSuite) TestAutoConnect(c *C) {
+	c.Check(s.iface.AutoConnect(nil, nil), Equals, true)
+}
+
+func (s *customDeviceInterfaceSuite) TestInterfaces(c *C) {
+	c.Check(builtin.Interfaces(), testutil.DeepContains, s.iface)
+}
@@ -0,0 +1,138 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2019 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package builtin
+
+import (
+	"fmt"
+
+	"github.com/snapcore/snapd/interfaces"
+	"github.com/snapcore/snapd/interfaces/apparmor"
+	"github.com/snapcore/snapd/snap"
+)
+
+const dnsResolutionSummary = `allows name resolution via systemd-resolved`
+
+const dnsResolutionBaseDeclarationSlots = `
+  dns-resolution:
+    allow-installation:
+      slot-snap-type:
+        - core
+    deny-connection:
+      plug-attributes:
+        custom-dns: true
+    deny-auto-connection:
+      plug-attributes:
+        custom-dns: true
+`
+
+const dnsResolutionConnectedPlugAppArmor = `
+# Description: Can resolve names via systemd-resolved, either directly over its
+# D-Bus and varlink APIs or indirectly via the nss-resolve plugin. Name
+# resolution is mediated by the resolver of the system, the snap does not get
+# to choose which DNS servers are used.
+/etc/resolv.conf r,
+/run/systemd/resolve/stub-resolv.conf r,
+/run/systemd/resolve/resolv.conf r,
+
+# varlink API used by nss-resolve
+/run/systemd/resolve/io.systemd.Resolve rw,
+
+#include <abstractions/dbus-strict>
+dbus send
+     bus=system
+     path="/org/freedesktop/resolve1"
+     interface="org.freedesktop.resolve1.Manager"
+     member="Resolve{Address,Hostname,Record,Service}"
+     peer=(name="org.freedesktop.resolve1"),
+
+dbus send
+     bus=system
+     path="/org/freedesktop/resolve1{,/**}"
+     interface="org.freedesktop.DBus.Properties"
+     member="Get{,All}"
+     peer=(name="org.freedesktop.resolve1"),
+`
+
+const dnsResolutionConnectedPlugAppArmorWithCustomDNS = `
+# Description: Can configure the DNS servers and search domains that
+# systemd-resolved uses for the network links. This is privileged as it allows
+# redirecting the name resolution of the whole system.
+dbus send
+     bus=system
+     path="/org/freedesktop/resolve1"
+     interface="org.freedesktop.resolve1.Manager"
+     member="{SetLink{DNS,Domains,DefaultRoute,LLMNR,MulticastDNS,DNSOverTLS,DNSSEC,DNSSECNegativeTrustAnchors},RevertLink,FlushCaches,ResetServerFeatures}"
+     peer=(name="org.freedesktop.resolve1"),
+
+dbus send
+     bus=system
+     path="/org/freedesktop/resolve1/link/*"
+     interface="org.freedesktop.resolve1.Link"
+     member="{Set{DNS,Domains,DefaultRoute,LLMNR,MulticastDNS,DNSOverTLS,DNSSEC,DNSSECNegativeTrustAnchors},Revert}"
+     peer=(name="org.freedesktop.resolve1"),
+`
+
+type dnsResolutionInterface struct{}
+
+func (iface *dnsResolutionInterface) Name() string {
+	return "dns-resolution"
+}
+
+func (iface *dnsResolutionInterface) StaticInfo() interfaces.StaticInfo {
+	return interfaces.StaticInfo{
+		Summary:              dnsResolutionSummary,
+		ImplicitOnCore:       true,
+		ImplicitOnClassic:    true,
+		BaseDeclarationSlots: dnsResolutionBaseDeclarationSlots,
+	}
+}
+
+func (iface *dnsResolutionInterface) BeforePrepareSlot(slot *snap.SlotInfo) error {
+	return sanitizeSlotReservedForOS(iface, slot)
+}
+
+func (iface *dnsResolutionInterface) BeforePreparePlug(plug *snap.PlugInfo) error {
+	// It's fine if custom-dns isn't specified, but if it is,
+	// it needs to be bool
+	if v, ok := plug.Attrs["custom-dns"]; ok {
+		if _, ok = v.(bool); !ok {
+			return fmt.Errorf("dns-resolution plug requires bool with 'custom-dns'")
+		}
+	}
+	return nil
+}
+
+func (iface *dnsResolutionInterface) AppArmorConnectedPlug(spec *apparmor.Specification, plug *interfaces.ConnectedPlug, slot *interfaces.ConnectedSlot) error {
+	var customDNS bool
+	_ = plug.Attr("custom-dns", &customDNS)
+	spec.AddSnippet(dnsResolutionConnectedPlugAppArmor)
+	if customDNS {
+		spec.AddSnippet(dnsResolutionConnectedPlugAppArmorWithCustomDNS)
+	}
+	return nil
+}
+
+func (iface *dnsResolutionInterface) AutoConnect(*snap.PlugInfo, *snap.SlotInfo) bool {
+	return true
+}
+
+func init() {
+	registerIface(&dnsResolutionInterface{})
+}
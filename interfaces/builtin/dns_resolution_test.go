@@ -0,0 +1,132 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2019 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package builtin_test
+
+import (
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/interfaces"
+	"github.com/snapcore/snapd/interfaces/apparmor"
+	"github.com/snapcore/snapd/interfaces/builtin"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/testutil"
+)
+
+type DNSResolutionInterfaceSuite struct {
+	iface    interfaces.Interface
+	slotInfo *snap.SlotInfo
+	slot     *interfaces.ConnectedSlot
+	plugInfo *snap.PlugInfo
+	plug     *interfaces.ConnectedPlug
+}
+
+var _ = Suite(&DNSResolutionInterfaceSuite{
+	iface: builtin.MustInterface("dns-resolution"),
+})
+
+const dnsResolutionConsumerYaml = `name: consumer
+version: 0
+apps:
+ app:
+  plugs: [dns-resolution]
+`
+
+const dnsResolutionCustomConsumerYaml = `name: consumer
+version: 0
+plugs:
+ dns-resolution:
+  custom-dns: true
+apps:
+ app:
+  plugs: [dns-resolution]
+`
+
+const dnsResolutionCoreYaml = `name: core
+version: 0
+type: os
+slots:
+  dns-resolution:
+`
+
+func (s *DNSResolutionInterfaceSuite) SetUpTest(c *C) {
+	s.plug, s.plugInfo = MockConnectedPlug(c, dnsResolutionConsumerYaml, nil, "dns-resolution")
+	s.slot, s.slotInfo = MockConnectedSlot(c, dnsResolutionCoreYaml, nil, "dns-resolution")
+}
+
+func (s *DNSResolutionInterfaceSuite) TestName(c *C) {
+	c.Assert(s.iface.Name(), Equals, "dns-resolution")
+}
+
+func (s *DNSResolutionInterfaceSuite) TestSanitizeSlot(c *C) {
+	c.Assert(interfaces.BeforePrepareSlot(s.iface, s.slotInfo), IsNil)
+	slot := &snap.SlotInfo{
+		Snap:      &snap.Info{SuggestedName: "some-snap"},
+		Name:      "dns-resolution",
+		Interface: "dns-resolution",
+	}
+	c.Assert(interfaces.BeforePrepareSlot(s.iface, slot), ErrorMatches,
+		"dns-resolution slots are reserved for the core snap")
+}
+
+func (s *DNSResolutionInterfaceSuite) TestSanitizePlug(c *C) {
+	c.Assert(interfaces.BeforePreparePlug(s.iface, s.plugInfo), IsNil)
+
+	_, plugInfo := MockConnectedPlug(c, dnsResolutionCustomConsumerYaml, nil, "dns-resolution")
+	c.Assert(interfaces.BeforePreparePlug(s.iface, plugInfo), IsNil)
+
+	plugInfo.Attrs["custom-dns"] = "yes"
+	c.Assert(interfaces.BeforePreparePlug(s.iface, plugInfo), ErrorMatches,
+		"dns-resolution plug requires bool with 'custom-dns'")
+}
+
+func (s *DNSResolutionInterfaceSuite) TestAppArmorSpec(c *C) {
+	spec := &apparmor.Specification{}
+	c.Assert(spec.AddConnectedPlug(s.iface, s.plug, s.slot), IsNil)
+	c.Assert(spec.SecurityTags(), DeepEquals, []string{"snap.consumer.app"})
+	snippet := spec.SnippetForTag("snap.consumer.app")
+	c.Check(snippet, testutil.Contains, `member="Resolve{Address,Hostname,Record,Service}"`)
+	c.Check(snippet, testutil.Contains, "/run/systemd/resolve/io.systemd.Resolve rw,\n")
+	c.Check(snippet, Not(testutil.Contains), "SetLink")
+}
+
+func (s *DNSResolutionInterfaceSuite) TestAppArmorSpecCustomDNS(c *C) {
+	plug, _ := MockConnectedPlug(c, dnsResolutionCustomConsumerYaml, nil, "dns-resolution")
+	spec := &apparmor.Specification{}
+	c.Assert(spec.AddConnectedPlug(s.iface, plug, s.slot), IsNil)
+	snippet := spec.SnippetForTag("snap.consumer.app")
+	c.Check(snippet, testutil.Contains, `member="Resolve{Address,Hostname,Record,Service}"`)
+	c.Check(snippet, testutil.Contains, "SetLink{DNS,Domains,")
+}
+
+func (s *DNSResolutionInterfaceSuite) TestStaticInfo(c *C) {
+	si := interfaces.StaticInfoOf(s.iface)
+	c.Assert(si.ImplicitOnCore, Equals, true)
+	c.Assert(si.ImplicitOnClassic, Equals, true)
+	c.Assert(si.Summary, Equals, `allows name resolution via systemd-resolved`)
+	c.Assert(si.BaseDeclarationSlots, testutil.Contains, "dns-resolution")
+}
+
+func (s *DNSResolutionInterfaceSuite) TestAutoConnect(c *C) {
+	c.Assert(s.iface.AutoConnect(s.plugInfo, s.slotInfo), Equals, true)
+}
+
+func (s *DNSResolutionInterfaceSuite) TestInterfaces(c *C) {
+	c.Check(builtin.Interfaces(), testutil.DeepContains, s.iface)
+}
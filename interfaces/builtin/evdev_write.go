@@ -0,0 +1,175 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2018 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package builtin
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/snapcore/snapd/interfaces"
+	"github.com/snapcore/snapd/interfaces/apparmor"
+	"github.com/snapcore/snapd/interfaces/udev"
+	"github.com/snapcore/snapd/snap"
+)
+
+const evdevWriteSummary = `allows writing to specific evdev input devices`
+
+const evdevWriteBaseDeclarationSlots = `
+  evdev-write:
+    allow-installation:
+      slot-snap-type:
+        - core
+        - gadget
+    deny-auto-connection: true
+`
+
+const evdevWriteConnectedPlugAppArmor = `
+# Description: Allow reading and writing to the evdev input devices selected
+# by the slot, which allows injecting input events into them.
+
+# Allow reading for supported event reports of the input devices. See
+# https://www.kernel.org/doc/Documentation/input/event-codes.txt
+/sys/devices/**/input[0-9]*/capabilities/* r,
+/run/udev/data/c13:{6[5-9],[7-9][0-9],[1-9][0-9][0-9]*} r,
+`
+
+// evdevWriteInterface is the type for evdev-write interfaces.
+type evdevWriteInterface struct{}
+
+// Name of the evdev-write interface.
+func (iface *evdevWriteInterface) Name() string {
+	return "evdev-write"
+}
+
+func (iface *evdevWriteInterface) StaticInfo() interfaces.StaticInfo {
+	return interfaces.StaticInfo{
+		Summary:              evdevWriteSummary,
+		BaseDeclarationSlots: evdevWriteBaseDeclarationSlots,
+	}
+}
+
+func (iface *evdevWriteInterface) String() string {
+	return iface.Name()
+}
+
+// Pattern to match allowed evdev device nodes, path attributes will be
+// compared to this for validity
+var evdevDeviceNodePattern = regexp.MustCompile("^/dev/input/event[0-9]{1,3}$")
+
+// BeforePrepareSlot checks validity of the defined slot, which selects
+// the input devices either by their device node (path attribute) or by
+// the usb vendor and product identifiers of the device they belong to
+func (iface *evdevWriteInterface) BeforePrepareSlot(slot *snap.SlotInfo) error {
+	if err := sanitizeSlotReservedForOSOrGadget(iface, slot); err != nil {
+		return err
+	}
+
+	if iface.hasUsbAttrs(slot) {
+		if _, ok := slot.Attrs["path"]; ok {
+			return fmt.Errorf("evdev-write slots cannot have both a path and usb attributes")
+		}
+		usbVendor, ok := slot.Attrs["usb-vendor"].(int64)
+		if !ok {
+			return fmt.Errorf("evdev-write slot failed to find usb-vendor attribute")
+		}
+		if (usbVendor < 0x1) || (usbVendor > 0xFFFF) {
+			return fmt.Errorf("evdev-write usb-vendor attribute not valid: %d", usbVendor)
+		}
+		usbProduct, ok := slot.Attrs["usb-product"].(int64)
+		if !ok {
+			return fmt.Errorf("evdev-write slot failed to find usb-product attribute")
+		}
+		if (usbProduct < 0x0) || (usbProduct > 0xFFFF) {
+			return fmt.Errorf("evdev-write usb-product attribute not valid: %d", usbProduct)
+		}
+		return nil
+	}
+
+	path, ok := slot.Attrs["path"].(string)
+	if !ok || path == "" {
+		return fmt.Errorf("evdev-write slots must have a path attribute or usb-vendor and usb-product attributes")
+	}
+	if !evdevDeviceNodePattern.MatchString(filepath.Clean(path)) {
+		return fmt.Errorf("evdev-write path attribute must be a valid device node")
+	}
+	return nil
+}
+
+func (iface *evdevWriteInterface) AppArmorConnectedPlug(spec *apparmor.Specification, plug *interfaces.ConnectedPlug, slot *interfaces.ConnectedSlot) error {
+	spec.AddSnippet(evdevWriteConnectedPlugAppArmor)
+	if iface.hasUsbAttrs(slot) {
+		// This apparmor rule must match evdevDeviceNodePattern
+		// UDev tagging and device cgroups will restrict down to the specific devices
+		spec.AddSnippet("/dev/input/event[0-9]{,[0-9],[0-9][0-9]} rw,")
+		return nil
+	}
+
+	// Path to fixed device node
+	var path string
+	if err := slot.Attr("path", &path); err != nil {
+		return err
+	}
+	spec.AddSnippet(fmt.Sprintf("%s rw,", filepath.Clean(path)))
+	return nil
+}
+
+func (iface *evdevWriteInterface) UDevConnectedPlug(spec *udev.Specification, plug *interfaces.ConnectedPlug, slot *interfaces.ConnectedSlot) error {
+	if iface.hasUsbAttrs(slot) {
+		var usbVendor, usbProduct int64
+		if err := slot.Attr("usb-vendor", &usbVendor); err != nil {
+			return nil
+		}
+		if err := slot.Attr("usb-product", &usbProduct); err != nil {
+			return nil
+		}
+		spec.TagDevice(fmt.Sprintf(`IMPORT{builtin}="usb_id"
+SUBSYSTEM=="input", KERNEL=="event[0-9]*", SUBSYSTEMS=="usb", ATTRS{idVendor}=="%04x", ATTRS{idProduct}=="%04x"`, usbVendor, usbProduct))
+		return nil
+	}
+
+	var path string
+	if err := slot.Attr("path", &path); err != nil {
+		return nil
+	}
+	spec.TagDevice(fmt.Sprintf(`SUBSYSTEM=="input", KERNEL=="%s"`, strings.TrimPrefix(filepath.Clean(path), "/dev/input/")))
+	return nil
+}
+
+func (iface *evdevWriteInterface) AutoConnect(*snap.PlugInfo, *snap.SlotInfo) bool {
+	// allow what declarations allowed
+	return true
+}
+
+func (iface *evdevWriteInterface) hasUsbAttrs(attrs interfaces.Attrer) bool {
+	var v int64
+	if err := attrs.Attr("usb-vendor", &v); err == nil {
+		return true
+	}
+	if err := attrs.Attr("usb-product", &v); err == nil {
+		return true
+	}
+	return false
+}
+
+func init() {
+	registerIface(&evdevWriteInterface{})
+}
@@ -0,0 +1,195 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2018 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package builtin_test
+
+import (
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/interfaces"
+	"github.com/snapcore/snapd/interfaces/apparmor"
+	"github.com/snapcore/snapd/interfaces/builtin"
+	"github.com/snapcore/snapd/interfaces/udev"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/snap/snaptest"
+	"github.com/snapcore/snapd/testutil"
+)
+
+type EvdevWriteInterfaceSuite struct {
+	iface interfaces.Interface
+
+	osSnapInfo     *snap.Info
+	gadgetSnapInfo *snap.Info
+
+	pathSlot *interfaces.ConnectedSlot
+	usbSlot  *interfaces.ConnectedSlot
+
+	plug     *interfaces.ConnectedPlug
+	plugInfo *snap.PlugInfo
+}
+
+var _ = Suite(&EvdevWriteInterfaceSuite{
+	iface: builtin.MustInterface("evdev-write"),
+})
+
+func (s *EvdevWriteInterfaceSuite) SetUpTest(c *C) {
+	s.osSnapInfo = snaptest.MockInfo(c, `
+name: core
+version: 0
+type: os
+slots:
+    test-path:
+        interface: evdev-write
+        path: /dev/input/event4
+    missing-path: evdev-write
+    bad-path-1:
+        interface: evdev-write
+        path: /dev/input/mice
+    bad-path-2:
+        interface: evdev-write
+        path: /dev/input/event1234
+`, nil)
+	s.pathSlot = interfaces.NewConnectedSlot(s.osSnapInfo.Slots["test-path"], nil, nil)
+
+	s.gadgetSnapInfo = snaptest.MockInfo(c, `
+name: some-device
+version: 0
+type: gadget
+slots:
+    test-usb:
+        interface: evdev-write
+        usb-vendor: 0x046d
+        usb-product: 0xc52b
+    bad-usb-1:
+        interface: evdev-write
+        usb-vendor: 0
+        usb-product: 0xc52b
+    bad-usb-2:
+        interface: evdev-write
+        usb-vendor: 0x046d
+        usb-product: 0x10000
+    bad-usb-3:
+        interface: evdev-write
+        usb-vendor: 0x046d
+    bad-usb-4:
+        interface: evdev-write
+        usb-vendor: 0x046d
+        usb-product: 0xc52b
+        path: /dev/input/event4
+`, nil)
+	s.usbSlot = interfaces.NewConnectedSlot(s.gadgetSnapInfo.Slots["test-usb"], nil, nil)
+
+	consumingSnapInfo := snaptest.MockInfo(c, `
+name: client-snap
+version: 0
+plugs:
+    plug-for-evdev:
+        interface: evdev-write
+apps:
+    app:
+        command: foo
+        plugs: [plug-for-evdev]
+`, nil)
+	s.plugInfo = consumingSnapInfo.Plugs["plug-for-evdev"]
+	s.plug = interfaces.NewConnectedPlug(s.plugInfo, nil, nil)
+}
+
+func (s *EvdevWriteInterfaceSuite) TestName(c *C) {
+	c.Assert(s.iface.Name(), Equals, "evdev-write")
+}
+
+func (s *EvdevWriteInterfaceSuite) TestSanitizeSlots(c *C) {
+	c.Assert(interfaces.BeforePrepareSlot(s.iface, s.osSnapInfo.Slots["test-path"]), IsNil)
+	c.Assert(interfaces.BeforePrepareSlot(s.iface, s.gadgetSnapInfo.Slots["test-usb"]), IsNil)
+}
+
+func (s *EvdevWriteInterfaceSuite) TestSanitizeBadSlots(c *C) {
+	for name, err := range map[string]string{
+		"missing-path": `evdev-write slots must have a path attribute or usb-vendor and usb-product attributes`,
+		"bad-path-1":   `evdev-write path attribute must be a valid device node`,
+		"bad-path-2":   `evdev-write path attribute must be a valid device node`,
+	} {
+		c.Check(interfaces.BeforePrepareSlot(s.iface, s.osSnapInfo.Slots[name]), ErrorMatches, err, Commentf(name))
+	}
+	for name, err := range map[string]string{
+		"bad-usb-1": `evdev-write usb-vendor attribute not valid: 0`,
+		"bad-usb-2": `evdev-write usb-product attribute not valid: 65536`,
+		"bad-usb-3": `evdev-write slot failed to find usb-product attribute`,
+		"bad-usb-4": `evdev-write slots cannot have both a path and usb attributes`,
+	} {
+		c.Check(interfaces.BeforePrepareSlot(s.iface, s.gadgetSnapInfo.Slots[name]), ErrorMatches, err, Commentf(name))
+	}
+
+	appSnapInfo := snaptest.MockInfo(c, `
+name: app-snap
+version: 0
+slots:
+    evdev:
+        interface: evdev-write
+        path: /dev/input/event4
+`, nil)
+	c.Check(interfaces.BeforePrepareSlot(s.iface, appSnapInfo.Slots["evdev"]), ErrorMatches,
+		`evdev-write slots are reserved for the core and gadget snaps`)
+}
+
+func (s *EvdevWriteInterfaceSuite) TestConnectedPlugAppArmorSnippets(c *C) {
+	spec := &apparmor.Specification{}
+	c.Assert(spec.AddConnectedPlug(s.iface, s.plug, s.pathSlot), IsNil)
+	c.Assert(spec.SecurityTags(), DeepEquals, []string{"snap.client-snap.app"})
+	snippet := spec.SnippetForTag("snap.client-snap.app")
+	c.Check(snippet, testutil.Contains, "/dev/input/event4 rw,")
+	c.Check(snippet, testutil.Contains, "/sys/devices/**/input[0-9]*/capabilities/* r,")
+	c.Check(snippet, Not(testutil.Contains), "/dev/input/event[0-9]")
+
+	spec = &apparmor.Specification{}
+	c.Assert(spec.AddConnectedPlug(s.iface, s.plug, s.usbSlot), IsNil)
+	snippet = spec.SnippetForTag("snap.client-snap.app")
+	c.Check(snippet, testutil.Contains, "/dev/input/event[0-9]{,[0-9],[0-9][0-9]} rw,")
+}
+
+func (s *EvdevWriteInterfaceSuite) TestConnectedPlugUDevSnippets(c *C) {
+	expectedExtraSnippet := `TAG=="snap_client-snap_app", RUN+="/usr/lib/snapd/snap-device-helper $env{ACTION} snap_client-snap_app $devpath $major:$minor"`
+
+	spec := &udev.Specification{}
+	c.Assert(spec.AddConnectedPlug(s.iface, s.plug, s.pathSlot), IsNil)
+	c.Assert(spec.Snippets(), DeepEquals, []string{`# evdev-write
+SUBSYSTEM=="input", KERNEL=="event4", TAG+="snap_client-snap_app"`, expectedExtraSnippet})
+
+	spec = &udev.Specification{}
+	c.Assert(spec.AddConnectedPlug(s.iface, s.plug, s.usbSlot), IsNil)
+	c.Assert(spec.Snippets(), DeepEquals, []string{`# evdev-write
+IMPORT{builtin}="usb_id"
+SUBSYSTEM=="input", KERNEL=="event[0-9]*", SUBSYSTEMS=="usb", ATTRS{idVendor}=="046d", ATTRS{idProduct}=="c52b", TAG+="snap_client-snap_app"`, expectedExtraSnippet})
+}
+
+func (s *EvdevWriteInterfaceSuite) TestStaticInfo(c *C) {
+	si := interfaces.StaticInfoOf(s.iface)
+	c.Assert(si.ImplicitOnCore, Equals, false)
+	c.Assert(si.ImplicitOnClassic, Equals, false)
+	c.Assert(si.Summary, Equals, `allows writing to specific evdev input devices`)
+	c.Assert(si.BaseDeclarationSlots, testutil.Contains, "evdev-write")
+}
+
+func (s *EvdevWriteInterfaceSuite) TestAutoConnect(c *C) {
+	c.Check(s.iface.AutoConnect(nil, nil), Equals, true)
+}
+
+func (s *EvdevWriteInterfaceSuite) TestInterfaces(c *C) {
+	c.Check(builtin.Interfaces(), testutil.DeepContains, s.iface)
+}
@@ -134,12 +134,41 @@ unix (bind, listen) type=stream addr="@xtables",
 @{PROC}/sys/net/netfilter/nf_conntrack_tcp_timeout_established rw,
 `
 
+// nftables support, for the nft tool and for the iptables compatibility
+// tools built on top of nftables. These talk to the kernel exclusively
+// over raw netfilter netlink sockets.
+const firewallControlNftablesConnectedPlugAppArmor = `
+# nftables
+/{,usr/}{,s}bin/nft ixr,
+/{,usr/}{,s}bin/xtables-nft-multi ixr,
+/{,usr/}{,s}bin/xtables-legacy-multi ixr,
+/{,usr/}{,s}bin/{arp,eb,ip,ip6}tables-nft{,-save,-restore} ixr,
+/{,usr/}{,s}bin/{ip,ip6}tables-legacy{,-save,-restore} ixr,
+/{,usr/}{,s}bin/{ip,ip6}tables-{restore-translate,translate} ixr,
+network netlink raw,
+
+# rulesets and the files they may include
+/etc/nftables.conf r,
+/etc/nftables/{,**} r,
+/usr/share/nftables/{,**} r,
+# symbolic names used by nft for realms, protocols and services
+/etc/iproute2/{,**} r,
+/etc/protocols r,
+/etc/services r,
+
+/sys/module/nf_tables/             r,
+/sys/module/nf_tables/initstate    r,
+/sys/module/nft_*/                 r,
+/sys/module/nft_*/initstate        r,
+`
+
 // http://bazaar.launchpad.net/~ubuntu-security/ubuntu-core-security/trunk/view/head:/data/seccomp/policygroups/ubuntu-core/16.04/firewall-control
 const firewallControlConnectedPlugSecComp = `
 # Description: Can configure firewall. This is restricted because it gives
 # privileged access to networking and should only be used with trusted apps.
 
-# for connecting to xtables abstract and netlink sockets
+# for connecting to xtables abstract and netlink sockets, nftables and the
+# iptables tools built on top of it only need NETLINK_NETFILTER
 bind
 socket AF_NETLINK - NETLINK_FIREWALL
 socket AF_NETLINK - NETLINK_NFLOG
@@ -158,6 +187,7 @@ var firewallControlConnectedPlugKmod = []string{
 	"br_netfilter",
 	"ip6table_filter",
 	"iptable_filter",
+	"nf_tables",
 }
 
 func init() {
@@ -167,7 +197,7 @@ func init() {
 		implicitOnCore:           true,
 		implicitOnClassic:        true,
 		baseDeclarationSlots:     firewallControlBaseDeclarationSlots,
-		connectedPlugAppArmor:    firewallControlConnectedPlugAppArmor,
+		connectedPlugAppArmor:    firewallControlConnectedPlugAppArmor + firewallControlNftablesConnectedPlugAppArmor,
 		connectedPlugSecComp:     firewallControlConnectedPlugSecComp,
 		connectedPlugKModModules: firewallControlConnectedPlugKmod,
 		reservedForOS:            true,
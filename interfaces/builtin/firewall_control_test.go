@@ -86,6 +86,17 @@ func (s *FirewallControlInterfaceSuite) TestAppArmorSpec(c *C) {
 	c.Assert(spec.AddConnectedPlug(s.iface, s.plug, s.slot), IsNil)
 	c.Assert(spec.SecurityTags(), DeepEquals, []string{"snap.consumer.app"})
 	c.Assert(spec.SnippetForTag("snap.consumer.app"), testutil.Contains, `capability net_raw`)
+	c.Assert(spec.SnippetForTag("snap.consumer.app"), testutil.Contains, "/{,usr/}{,s}bin/iptables{,-save,-restore} ixr,\n")
+}
+
+func (s *FirewallControlInterfaceSuite) TestAppArmorSpecNftables(c *C) {
+	spec := &apparmor.Specification{}
+	c.Assert(spec.AddConnectedPlug(s.iface, s.plug, s.slot), IsNil)
+	snippet := spec.SnippetForTag("snap.consumer.app")
+	c.Check(snippet, testutil.Contains, "/{,usr/}{,s}bin/nft ixr,\n")
+	c.Check(snippet, testutil.Contains, "/{,usr/}{,s}bin/xtables-nft-multi ixr,\n")
+	c.Check(snippet, testutil.Contains, "network netlink raw,\n")
+	c.Check(snippet, testutil.Contains, "/etc/nftables.conf r,\n")
 }
 
 func (s *FirewallControlInterfaceSuite) TestSecCompSpec(c *C) {
@@ -93,6 +104,7 @@ func (s *FirewallControlInterfaceSuite) TestSecCompSpec(c *C) {
 	c.Assert(spec.AddConnectedPlug(s.iface, s.plug, s.slot), IsNil)
 	c.Assert(spec.SecurityTags(), DeepEquals, []string{"snap.consumer.app"})
 	c.Assert(spec.SnippetForTag("snap.consumer.app"), testutil.Contains, "bind\n")
+	c.Assert(spec.SnippetForTag("snap.consumer.app"), testutil.Contains, "socket AF_NETLINK - NETLINK_NETFILTER\n")
 }
 
 func (s *FirewallControlInterfaceSuite) TestKModSpec(c *C) {
@@ -103,6 +115,7 @@ func (s *FirewallControlInterfaceSuite) TestKModSpec(c *C) {
 		"br_netfilter":    true,
 		"ip6table_filter": true,
 		"iptable_filter":  true,
+		"nf_tables":       true,
 	})
 }
 
@@ -0,0 +1,122 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2018 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package builtin
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+
+	"github.com/snapcore/snapd/interfaces"
+	"github.com/snapcore/snapd/interfaces/apparmor"
+	"github.com/snapcore/snapd/snap"
+)
+
+const hostFilesReadSummary = `allows read-only access to specific files or directories of the host`
+
+// The paths a snap may read are listed with the "read" plug attribute and
+// need to be allowed by its snap-declaration, like:
+//
+//	plugs:
+//	  host-files-read:
+//	    allow-installation:
+//	      plug-attributes:
+//	        read:
+//	          - /usr/share/doc
+const hostFilesReadBaseDeclarationPlugs = `
+  host-files-read:
+    allow-installation: false
+    deny-auto-connection: true
+`
+
+const hostFilesReadBaseDeclarationSlots = `
+  host-files-read:
+    allow-installation:
+      slot-snap-type:
+        - core
+    deny-auto-connection: true
+`
+
+const hostFilesReadConnectedPlugAppArmor = `
+# Description: Can read specific files or directories of the host, either at
+# their location or through the host filesystem when using a different base.
+# This is restricted because it gives read access to arbitrary locations.
+`
+
+// hostfsDir is where the root filesystem of the host is visible in the mount
+// namespace of snaps.
+const hostfsDir = "/var/lib/snapd/hostfs"
+
+type hostFilesReadInterface struct {
+	commonFilesInterface
+}
+
+func (iface *hostFilesReadInterface) BeforePreparePlug(plug *snap.PlugInfo) error {
+	if _, ok := plug.Attrs["write"]; ok {
+		return fmt.Errorf(`cannot add %s plug: "write" attribute is not supported, access is read-only`, iface.name)
+	}
+	if _, ok := plug.Attrs["read"]; !ok {
+		return fmt.Errorf(`cannot add %s plug: needs valid "read" attribute`, iface.name)
+	}
+	return iface.commonFilesInterface.BeforePreparePlug(plug)
+}
+
+func (iface *hostFilesReadInterface) AppArmorConnectedPlug(spec *apparmor.Specification, plug *interfaces.ConnectedPlug, slot *interfaces.ConnectedSlot) error {
+	var reads []interface{}
+	_ = plug.Attr("read", &reads)
+
+	hostReads := make([]interface{}, 0, len(reads))
+	for _, rawPath := range reads {
+		p, ok := rawPath.(string)
+		if !ok {
+			return fmt.Errorf(`cannot connect plug %s: %[2]v (%[2]T) is not a string`, plug.Name(), rawPath)
+		}
+		hostReads = append(hostReads, filepath.Join(hostfsDir, p))
+	}
+
+	buf := bytes.NewBufferString(iface.apparmorHeader)
+	if err := allowPathAccess(buf, filesRead, reads); err != nil {
+		return fmt.Errorf("cannot connect plug %s: %v", plug.Name(), err)
+	}
+	if err := allowPathAccess(buf, filesRead, hostReads); err != nil {
+		return fmt.Errorf("cannot connect plug %s: %v", plug.Name(), err)
+	}
+	spec.AddSnippet(buf.String())
+
+	return nil
+}
+
+func init() {
+	registerIface(&hostFilesReadInterface{
+		commonFilesInterface{
+			commonInterface: commonInterface{
+				name:                 "host-files-read",
+				summary:              hostFilesReadSummary,
+				implicitOnCore:       true,
+				implicitOnClassic:    true,
+				baseDeclarationPlugs: hostFilesReadBaseDeclarationPlugs,
+				baseDeclarationSlots: hostFilesReadBaseDeclarationSlots,
+				reservedForOS:        true,
+			},
+			apparmorHeader:    hostFilesReadConnectedPlugAppArmor,
+			extraPathValidate: validateSinglePathSystem,
+		},
+	})
+}
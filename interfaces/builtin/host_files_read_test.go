@@ -0,0 +1,156 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2018 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package builtin_test
+
+import (
+	"strings"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/interfaces"
+	"github.com/snapcore/snapd/interfaces/apparmor"
+	"github.com/snapcore/snapd/interfaces/builtin"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/snap/snaptest"
+	"github.com/snapcore/snapd/testutil"
+)
+
+type hostFilesReadInterfaceSuite struct {
+	iface    interfaces.Interface
+	slot     *interfaces.ConnectedSlot
+	slotInfo *snap.SlotInfo
+	plug     *interfaces.ConnectedPlug
+	plugInfo *snap.PlugInfo
+}
+
+var _ = Suite(&hostFilesReadInterfaceSuite{
+	iface: builtin.MustInterface("host-files-read"),
+})
+
+func (s *hostFilesReadInterfaceSuite) SetUpTest(c *C) {
+	const mockPlugSnapInfo = `name: other
+version: 1.0
+plugs:
+ host-files-read:
+  read: [/usr/share/doc, /etc/os-release]
+apps:
+ app:
+  command: foo
+  plugs: [host-files-read]
+`
+	s.slotInfo = &snap.SlotInfo{
+		Snap:      &snap.Info{SuggestedName: "core", SnapType: snap.TypeOS},
+		Name:      "host-files-read",
+		Interface: "host-files-read",
+	}
+	s.slot = interfaces.NewConnectedSlot(s.slotInfo, nil, nil)
+	plugSnap := snaptest.MockInfo(c, mockPlugSnapInfo, nil)
+	s.plugInfo = plugSnap.Plugs["host-files-read"]
+	s.plug = interfaces.NewConnectedPlug(s.plugInfo, nil, nil)
+}
+
+func (s *hostFilesReadInterfaceSuite) TestName(c *C) {
+	c.Assert(s.iface.Name(), Equals, "host-files-read")
+}
+
+func (s *hostFilesReadInterfaceSuite) TestConnectedPlugAppArmor(c *C) {
+	apparmorSpec := &apparmor.Specification{}
+	err := apparmorSpec.AddConnectedPlug(s.iface, s.plug, s.slot)
+	c.Assert(err, IsNil)
+	c.Assert(apparmorSpec.SecurityTags(), DeepEquals, []string{"snap.other.app"})
+	c.Check(apparmorSpec.SnippetForTag("snap.other.app"), Equals, `
+# Description: Can read specific files or directories of the host, either at
+# their location or through the host filesystem when using a different base.
+# This is restricted because it gives read access to arbitrary locations.
+"/usr/share/doc{,/,/**}" rk,
+"/etc/os-release{,/,/**}" rk,
+"/var/lib/snapd/hostfs/usr/share/doc{,/,/**}" rk,
+"/var/lib/snapd/hostfs/etc/os-release{,/,/**}" rk,
+`)
+}
+
+func (s *hostFilesReadInterfaceSuite) TestSanitizeSlot(c *C) {
+	c.Assert(interfaces.BeforePrepareSlot(s.iface, s.slotInfo), IsNil)
+	slot := &snap.SlotInfo{
+		Snap:      &snap.Info{SuggestedName: "some-snap"},
+		Name:      "host-files-read",
+		Interface: "host-files-read",
+	}
+	c.Assert(interfaces.BeforePrepareSlot(s.iface, slot), ErrorMatches,
+		"host-files-read slots are reserved for the core snap")
+}
+
+func (s *hostFilesReadInterfaceSuite) TestSanitizePlug(c *C) {
+	c.Assert(interfaces.BeforePreparePlug(s.iface, s.plugInfo), IsNil)
+}
+
+func (s *hostFilesReadInterfaceSuite) TestSanitizePlugUnhappy(c *C) {
+	const mockSnapYaml = `name: host-files-read-plug-snap
+version: 1.0
+plugs:
+ host-files-read:
+  $t
+`
+	errPrefix := `cannot add host-files-read plug: `
+	var testCases = []struct {
+		inp    string
+		errStr string
+	}{
+		{`read: ""`, `"read" must be a list of strings`},
+		{`read: [ 123 ]`, `"read" must be a list of strings`},
+		{`read: [ "/foo/./bar" ]`, `cannot use "/foo/./bar": try "/foo/bar"`},
+		{`read: [ "../foo" ]`, `"../foo" must start with "/"`},
+		{`read: [ "/foo[" ]`, `"/foo\[" contains a reserved apparmor char from .*`},
+		{`read: [ "/home/$HOME/foo" ]`, `\$HOME cannot be used in "/home/\$HOME/foo"`},
+		{`write: [ "/etc/foo" ]`, `"write" attribute is not supported, access is read-only`},
+		{`other: [ "/etc/foo" ]`, `needs valid "read" attribute`},
+	}
+
+	for _, t := range testCases {
+		yml := strings.Replace(mockSnapYaml, "$t", t.inp, -1)
+		info := snaptest.MockInfo(c, yml, nil)
+		plug := info.Plugs["host-files-read"]
+
+		c.Check(interfaces.BeforePreparePlug(s.iface, plug), ErrorMatches, errPrefix+t.errStr, Commentf("unexpected error for %q", t.inp))
+	}
+}
+
+func (s *hostFilesReadInterfaceSuite) TestConnectedPlugAppArmorInternalError(c *C) {
+	const mockPlugSnapInfo = `name: other
+version: 1.0
+plugs:
+ host-files-read:
+  read: [ 123 , 345 ]
+apps:
+ app:
+  command: foo
+  plugs: [host-files-read]
+`
+	plugSnap := snaptest.MockInfo(c, mockPlugSnapInfo, nil)
+	plug := interfaces.NewConnectedPlug(plugSnap.Plugs["host-files-read"], nil, nil)
+
+	apparmorSpec := &apparmor.Specification{}
+	err := apparmorSpec.AddConnectedPlug(s.iface, plug, s.slot)
+	c.Assert(err, ErrorMatches, `cannot connect plug host-files-read: 123 \(int64\) is not a string`)
+}
+
+func (s *hostFilesReadInterfaceSuite) TestInterfaces(c *C) {
+	c.Check(builtin.Interfaces(), testutil.DeepContains, s.iface)
+}
@@ -0,0 +1,74 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2019 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package builtin
+
+const microstackOvsSummary = `allows managing Open vSwitch through its sockets`
+
+const microstackOvsBaseDeclarationSlots = `
+  microstack-ovs:
+    allow-installation:
+      slot-snap-type:
+        - core
+    deny-auto-connection: true
+`
+
+const microstackOvsConnectedPlugAppArmor = `
+# Description: allow managing Open vSwitch via OVSDB, OpenFlow and the
+# ovs-vsctl/ovs-ofctl/ovs-appctl control sockets.
+
+# OVSDB server socket
+/run/openvswitch/db.sock rw,
+# OpenFlow management sockets (one per bridge) and the unixctl control
+# sockets of ovsdb-server and ovs-vswitchd
+/run/openvswitch/*.mgmt rw,
+/run/openvswitch/*.ctl rw,
+/run/openvswitch/*.snoop rw,
+/run/openvswitch/*.pid r,
+/run/openvswitch/ r,
+
+# ovs-vsctl and friends create their own reply sockets next to the
+# server ones
+/run/openvswitch/ovs-{vsctl,ofctl,appctl}.*.ctl rw,
+
+# configuration and the OVSDB database itself
+/etc/openvswitch/{,**} r,
+/var/lib/openvswitch/{,**} r,
+/var/log/openvswitch/{,**} r,
+
+# tun/tap devices used for OVS internal and tap ports
+/dev/net/tun rw,
+`
+
+var microstackOvsConnectedPlugUDev = []string{
+	`KERNEL=="tun"`,
+}
+
+func init() {
+	registerIface(&commonInterface{
+		name:                  "microstack-ovs",
+		summary:               microstackOvsSummary,
+		implicitOnCore:        true,
+		implicitOnClassic:     true,
+		reservedForOS:         true,
+		baseDeclarationSlots:  microstackOvsBaseDeclarationSlots,
+		connectedPlugAppArmor: microstackOvsConnectedPlugAppArmor,
+		connectedPlugUDev:     microstackOvsConnectedPlugUDev,
+	})
+}
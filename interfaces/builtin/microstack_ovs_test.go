@@ -0,0 +1,120 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2019 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package builtin_test
+
+import (
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/interfaces"
+	"github.com/snapcore/snapd/interfaces/apparmor"
+	"github.com/snapcore/snapd/interfaces/builtin"
+	"github.com/snapcore/snapd/interfaces/udev"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/snap/snaptest"
+	"github.com/snapcore/snapd/testutil"
+)
+
+type MicrostackOvsInterfaceSuite struct {
+	iface    interfaces.Interface
+	slotInfo *snap.SlotInfo
+	slot     *interfaces.ConnectedSlot
+	plugInfo *snap.PlugInfo
+	plug     *interfaces.ConnectedPlug
+}
+
+var _ = Suite(&MicrostackOvsInterfaceSuite{
+	iface: builtin.MustInterface("microstack-ovs"),
+})
+
+func (s *MicrostackOvsInterfaceSuite) SetUpTest(c *C) {
+	const producerYaml = `name: core
+version: 0
+type: os
+slots:
+  microstack-ovs:
+`
+	info := snaptest.MockInfo(c, producerYaml, nil)
+	s.slotInfo = info.Slots["microstack-ovs"]
+	s.slot = interfaces.NewConnectedSlot(s.slotInfo, nil, nil)
+
+	const consumerYaml = `name: consumer
+version: 0
+apps:
+ app:
+  plugs: [microstack-ovs]
+`
+	info = snaptest.MockInfo(c, consumerYaml, nil)
+	s.plugInfo = info.Plugs["microstack-ovs"]
+	s.plug = interfaces.NewConnectedPlug(s.plugInfo, nil, nil)
+}
+
+func (s *MicrostackOvsInterfaceSuite) TestName(c *C) {
+	c.Assert(s.iface.Name(), Equals, "microstack-ovs")
+}
+
+func (s *MicrostackOvsInterfaceSuite) TestSanitizeSlot(c *C) {
+	c.Assert(interfaces.BeforePrepareSlot(s.iface, s.slotInfo), IsNil)
+	slot := &snap.SlotInfo{
+		Snap:      &snap.Info{SuggestedName: "some-snap"},
+		Name:      "microstack-ovs",
+		Interface: "microstack-ovs",
+	}
+	c.Assert(interfaces.BeforePrepareSlot(s.iface, slot), ErrorMatches,
+		"microstack-ovs slots are reserved for the core snap")
+}
+
+func (s *MicrostackOvsInterfaceSuite) TestSanitizePlug(c *C) {
+	c.Assert(interfaces.BeforePreparePlug(s.iface, s.plugInfo), IsNil)
+}
+
+func (s *MicrostackOvsInterfaceSuite) TestAppArmorSpec(c *C) {
+	spec := &apparmor.Specification{}
+	c.Assert(spec.AddConnectedPlug(s.iface, s.plug, s.slot), IsNil)
+	c.Assert(spec.SecurityTags(), DeepEquals, []string{"snap.consumer.app"})
+	c.Check(spec.SnippetForTag("snap.consumer.app"), testutil.Contains, "/run/openvswitch/db.sock rw,")
+	c.Check(spec.SnippetForTag("snap.consumer.app"), testutil.Contains, "/run/openvswitch/*.mgmt rw,")
+	c.Check(spec.SnippetForTag("snap.consumer.app"), testutil.Contains, "/run/openvswitch/*.ctl rw,")
+	c.Check(spec.SnippetForTag("snap.consumer.app"), testutil.Contains, "/dev/net/tun rw,")
+}
+
+func (s *MicrostackOvsInterfaceSuite) TestUDevSpec(c *C) {
+	spec := &udev.Specification{}
+	c.Assert(spec.AddConnectedPlug(s.iface, s.plug, s.slot), IsNil)
+	c.Assert(spec.Snippets(), HasLen, 2)
+	c.Assert(spec.Snippets(), testutil.Contains, `# microstack-ovs
+KERNEL=="tun", TAG+="snap_consumer_app"`)
+	c.Assert(spec.Snippets(), testutil.Contains, `TAG=="snap_consumer_app", RUN+="/usr/lib/snapd/snap-device-helper $env{ACTION} snap_consumer_app $devpath $major:$minor"`)
+}
+
+func (s *MicrostackOvsInterfaceSuite) TestStaticInfo(c *C) {
+	si := interfaces.StaticInfoOf(s.iface)
+	c.Assert(si.ImplicitOnCore, Equals, true)
+	c.Assert(si.ImplicitOnClassic, Equals, true)
+	c.Assert(si.Summary, Equals, "allows managing Open vSwitch through its sockets")
+	c.Assert(si.BaseDeclarationSlots, testutil.Contains, "microstack-ovs")
+}
+
+func (s *MicrostackOvsInterfaceSuite) TestAutoConnect(c *C) {
+	c.Assert(s.iface.AutoConnect(s.plugInfo, s.slotInfo), Equals, true)
+}
+
+func (s *MicrostackOvsInterfaceSuite) TestInterfaces(c *C) {
+	c.Check(builtin.Interfaces(), testutil.DeepContains, s.iface)
+}
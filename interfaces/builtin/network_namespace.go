@@ -0,0 +1,245 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2018 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package builtin
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/snapcore/snapd/interfaces"
+	"github.com/snapcore/snapd/interfaces/systemd"
+	"github.com/snapcore/snapd/snap"
+)
+
+const networkNamespaceSummary = `runs the apps of the snap in a dedicated network namespace`
+
+// The apps of a snap with a connected network-namespace plug run in a
+// network namespace of their own, which snap-confine joins when it exists.
+// The namespace is only reachable from the host through a veth pair and the
+// ports forwarded to it, declared with the "forward-ports" plug attribute as
+// "<host-port>[:<snap-port>]/<tcp|udp>". Privileged host ports cannot be
+// forwarded and the namespace is not set up if a host port is already in use.
+// The ports are reachable from other hosts only if IP forwarding is enabled
+// on the host, which is left to the administrator. For example:
+//
+//	plugs:
+//	  network-namespace:
+//	    forward-ports:
+//	      - 8080:80/tcp
+//	      - 5353/udp
+const networkNamespaceBaseDeclarationSlots = `
+  network-namespace:
+    allow-installation:
+      slot-snap-type:
+        - core
+    deny-auto-connection: true
+`
+
+type networkNamespaceInterface struct {
+	commonInterface
+}
+
+// portForward describes a port of the host forwarded to the network namespace
+// of a snap.
+type portForward struct {
+	hostPort int
+	snapPort int
+	proto    string
+}
+
+func parsePortNumber(s string) (int, error) {
+	port, err := strconv.Atoi(s)
+	if err != nil || port < 1 || port > 65535 {
+		return 0, fmt.Errorf("invalid port %q", s)
+	}
+	return port, nil
+}
+
+func parsePortForward(s string) (*portForward, error) {
+	idx := strings.LastIndex(s, "/")
+	if idx < 0 {
+		return nil, fmt.Errorf("cannot parse port forward %q: missing protocol", s)
+	}
+	ports, proto := s[:idx], s[idx+1:]
+	if proto != "tcp" && proto != "udp" {
+		return nil, fmt.Errorf("cannot parse port forward %q: unsupported protocol %q", s, proto)
+	}
+	hostPort, snapPort := ports, ports
+	if idx := strings.IndexRune(ports, ':'); idx >= 0 {
+		hostPort, snapPort = ports[:idx], ports[idx+1:]
+	}
+	fwd := &portForward{proto: proto}
+	var err error
+	if fwd.hostPort, err = parsePortNumber(hostPort); err != nil {
+		return nil, fmt.Errorf("cannot parse port forward %q: %v", s, err)
+	}
+	if fwd.snapPort, err = parsePortNumber(snapPort); err != nil {
+		return nil, fmt.Errorf("cannot parse port forward %q: %v", s, err)
+	}
+	return fwd, nil
+}
+
+func networkNamespacePortForwards(attrs interfaces.Attrer) ([]*portForward, error) {
+	var rawForwards []interface{}
+	if v, ok := attrs.Lookup("forward-ports"); ok {
+		if rawForwards, ok = v.([]interface{}); !ok {
+			return nil, fmt.Errorf(`"forward-ports" attribute must be a list of strings`)
+		}
+	}
+	seen := make(map[string]bool, len(rawForwards))
+	forwards := make([]*portForward, 0, len(rawForwards))
+	for _, raw := range rawForwards {
+		s, ok := raw.(string)
+		if !ok {
+			return nil, fmt.Errorf(`"forward-ports" attribute must be a list of strings`)
+		}
+		fwd, err := parsePortForward(s)
+		if err != nil {
+			return nil, err
+		}
+		key := fmt.Sprintf("%d/%s", fwd.hostPort, fwd.proto)
+		if fwd.hostPort < 1024 {
+			return nil, fmt.Errorf("cannot forward privileged host port %s", key)
+		}
+		if seen[key] {
+			return nil, fmt.Errorf("cannot forward host port %s more than once", key)
+		}
+		seen[key] = true
+		forwards = append(forwards, fwd)
+	}
+	return forwards, nil
+}
+
+func (iface *networkNamespaceInterface) BeforePreparePlug(plug *snap.PlugInfo) error {
+	_, err := networkNamespacePortForwards(plug)
+	return err
+}
+
+// networkNamespaceName returns the name of the network namespace of the given
+// snap instance, as known to ip-netns(8) and snap-confine.
+func networkNamespaceName(instanceName string) string {
+	return "snap." + instanceName
+}
+
+// networkNamespaceAddrs returns the name of the host side of the veth pair
+// of the given snap instance and the /30 subnet of 10.177.0.0/16 its
+// addresses are preferably taken from, as the third octet and the first
+// address of the subnet. They are derived from the instance name so that
+// they are stable across reboots.
+func networkNamespaceAddrs(instanceName string) (hostVeth string, net, first int) {
+	h := sha256.Sum256([]byte(instanceName))
+	// interface names are limited to 15 characters
+	hostVeth = fmt.Sprintf("snap%x", h[:4])
+	return hostVeth, int(h[4]), int(h[5]) &^ 3
+}
+
+// systemdShellCommand returns the command line running the given shell
+// script from a systemd unit, which must not contain single quotes.
+func systemdShellCommand(script []string) string {
+	// systemd expands specifiers and variables in command lines
+	escaped := strings.NewReplacer("%", "%%", "$", "$$").Replace(strings.Join(script, "; "))
+	return fmt.Sprintf("/bin/sh -c '%s'", escaped)
+}
+
+func (iface *networkNamespaceInterface) SystemdConnectedPlug(spec *systemd.Specification, plug *interfaces.ConnectedPlug, slot *interfaces.ConnectedSlot) error {
+	forwards, err := networkNamespacePortForwards(plug)
+	if err != nil {
+		return err
+	}
+	instanceName := plug.Snap().InstanceName()
+	netns := networkNamespaceName(instanceName)
+	hostVeth, net, first := networkNamespaceAddrs(instanceName)
+	// all the firewall rules are tagged with the namespace so that they
+	// can all be removed, including the ones of a previous setup
+	tag := fmt.Sprintf("-m comment --comment %s", netns)
+	// the tag must match as a whole, so that the rules of snap.foo-bar
+	// are not taken for the ones of snap.foo; backslashes are avoided
+	// as systemd unescapes command lines
+	tagRegexp := strings.Replace(tag, ".", "[.]", -1) + "( |$)"
+
+	// tearing down cleans up as much as possible, the veth pair goes
+	// away together with the namespace
+	stop := []string{
+		fmt.Sprintf("ip netns del %s 2>/dev/null", netns),
+		fmt.Sprintf("ip link del %s 2>/dev/null", hostVeth),
+		fmt.Sprintf(`for c in iptables ip6tables; do for t in nat filter; do $c -t $t -S 2>/dev/null | grep -E -e "%s" | sed -e s/^-A/-D/ | xargs -r -L1 $c -t $t; done; done`, tagRegexp),
+	}
+
+	// setting up starts from scratch and stops on the first failure
+	start := append([]string(nil), stop...)
+	start = append(start, "set -e")
+	for _, fwd := range forwards {
+		start = append(start, fmt.Sprintf(`if ss -l -n -%c "sport = :%d" | tail -n +2 | grep -q .; then echo "cannot forward host port %d/%s: the port is in use" >&2; exit 1; fi`, fwd.proto[0], fwd.hostPort, fwd.hostPort, fwd.proto))
+	}
+	// pick the first /30 subnet not in use on the host, starting from
+	// the preferred one
+	subnet := fmt.Sprintf("10.177.$n.%d/30", first)
+	start = append(start,
+		"net=",
+		fmt.Sprintf(`for n in $(seq %d 255) $(seq 0 %d); do if [ -z "$(ip -o -4 addr show to %s; ip -4 route show to root %s)" ]; then net=10.177.$n; break; fi; done`, net, net-1, subnet, subnet),
+		`if [ -z "$net" ]; then echo "cannot find free addresses for the network namespace" >&2; exit 1; fi`)
+	hostAddr := fmt.Sprintf("$net.%d", first+1)
+	snapAddr := fmt.Sprintf("$net.%d", first+2)
+	start = append(start,
+		fmt.Sprintf("ip netns add %s", netns),
+		fmt.Sprintf("ip link add %s type veth peer name eth0 netns %s", hostVeth, netns),
+		fmt.Sprintf("ip addr add %s/30 dev %s", hostAddr, hostVeth),
+		fmt.Sprintf("ip link set %s up", hostVeth),
+		// only the traffic coming from the namespace is forwarded, IP
+		// forwarding is not enabled for the whole host
+		fmt.Sprintf("sysctl -q -w net.ipv4.conf.%s.forwarding=1", hostVeth),
+		fmt.Sprintf("ip -n %s link set lo up", netns),
+		fmt.Sprintf("ip -n %s addr add %s/30 dev eth0", netns, snapAddr),
+		fmt.Sprintf("ip -n %s link set eth0 up", netns),
+		fmt.Sprintf("ip -n %s route add default via %s", netns, hostAddr),
+		fmt.Sprintf("iptables -t nat -A POSTROUTING -s %s/30 ! -o %s %s -j MASQUERADE", snapAddr, hostVeth, tag),
+		fmt.Sprintf("iptables -A FORWARD -i %s %s -j ACCEPT", hostVeth, tag),
+		fmt.Sprintf("iptables -A FORWARD -o %s -m conntrack --ctstate RELATED,ESTABLISHED %s -j ACCEPT", hostVeth, tag))
+	for _, fwd := range forwards {
+		dnat := fmt.Sprintf("-p %s -m addrtype --dst-type LOCAL --dport %d %s -j DNAT --to-destination %s:%d", fwd.proto, fwd.hostPort, tag, snapAddr, fwd.snapPort)
+		start = append(start,
+			"iptables -t nat -A PREROUTING "+dnat,
+			"iptables -t nat -A OUTPUT "+dnat,
+			fmt.Sprintf("iptables -A FORWARD -o %s -p %s -d %s --dport %d %s -j ACCEPT", hostVeth, fwd.proto, snapAddr, fwd.snapPort, tag))
+	}
+
+	service := &systemd.Service{
+		Description:     fmt.Sprintf("Network namespace of snap %s", instanceName),
+		Type:            "oneshot",
+		RemainAfterExit: true,
+		ExecStart:       systemdShellCommand(start),
+		ExecStop:        systemdShellCommand(stop),
+	}
+	serviceName := interfaces.InterfaceServiceName(instanceName, "network-namespace")
+	return spec.AddService(serviceName, service)
+}
+
+func init() {
+	registerIface(&networkNamespaceInterface{commonInterface{
+		name:                 "network-namespace",
+		summary:              networkNamespaceSummary,
+		implicitOnCore:       true,
+		implicitOnClassic:    true,
+		baseDeclarationSlots: networkNamespaceBaseDeclarationSlots,
+		reservedForOS:        true,
+	}})
+}
@@ -0,0 +1,191 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2018 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package builtin_test
+
+import (
+	"strings"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/interfaces"
+	"github.com/snapcore/snapd/interfaces/builtin"
+	"github.com/snapcore/snapd/interfaces/systemd"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/snap/snaptest"
+	"github.com/snapcore/snapd/testutil"
+)
+
+type networkNamespaceInterfaceSuite struct {
+	iface    interfaces.Interface
+	slot     *interfaces.ConnectedSlot
+	slotInfo *snap.SlotInfo
+	plug     *interfaces.ConnectedPlug
+	plugInfo *snap.PlugInfo
+}
+
+var _ = Suite(&networkNamespaceInterfaceSuite{
+	iface: builtin.MustInterface("network-namespace"),
+})
+
+func (s *networkNamespaceInterfaceSuite) SetUpTest(c *C) {
+	const mockPlugSnapInfo = `name: other
+version: 1.0
+plugs:
+ network-namespace:
+  forward-ports: [8080:80/tcp, 5353/udp]
+apps:
+ app:
+  command: foo
+  plugs: [network-namespace]
+`
+	s.slotInfo = &snap.SlotInfo{
+		Snap:      &snap.Info{SuggestedName: "core", SnapType: snap.TypeOS},
+		Name:      "network-namespace",
+		Interface: "network-namespace",
+	}
+	s.slot = interfaces.NewConnectedSlot(s.slotInfo, nil, nil)
+	plugSnap := snaptest.MockInfo(c, mockPlugSnapInfo, nil)
+	s.plugInfo = plugSnap.Plugs["network-namespace"]
+	s.plug = interfaces.NewConnectedPlug(s.plugInfo, nil, nil)
+}
+
+func (s *networkNamespaceInterfaceSuite) TestName(c *C) {
+	c.Assert(s.iface.Name(), Equals, "network-namespace")
+}
+
+func (s *networkNamespaceInterfaceSuite) TestSanitizeSlot(c *C) {
+	c.Assert(interfaces.BeforePrepareSlot(s.iface, s.slotInfo), IsNil)
+	slot := &snap.SlotInfo{
+		Snap:      &snap.Info{SuggestedName: "some-snap"},
+		Name:      "network-namespace",
+		Interface: "network-namespace",
+	}
+	c.Assert(interfaces.BeforePrepareSlot(s.iface, slot), ErrorMatches,
+		"network-namespace slots are reserved for the core snap")
+}
+
+func (s *networkNamespaceInterfaceSuite) TestSanitizePlug(c *C) {
+	c.Assert(interfaces.BeforePreparePlug(s.iface, s.plugInfo), IsNil)
+}
+
+func (s *networkNamespaceInterfaceSuite) TestSanitizePlugUnhappy(c *C) {
+	const mockSnapYaml = `name: network-namespace-plug-snap
+version: 1.0
+plugs:
+ network-namespace:
+  $t
+`
+	var testCases = []struct {
+		inp    string
+		errStr string
+	}{
+		{`forward-ports: 80/tcp`, `"forward-ports" attribute must be a list of strings`},
+		{`forward-ports: [ 80 ]`, `"forward-ports" attribute must be a list of strings`},
+		{`forward-ports: [ "80" ]`, `cannot parse port forward "80": missing protocol`},
+		{`forward-ports: [ "80/sctp" ]`, `cannot parse port forward "80/sctp": unsupported protocol "sctp"`},
+		{`forward-ports: [ "0/tcp" ]`, `cannot parse port forward "0/tcp": invalid port "0"`},
+		{`forward-ports: [ "80:65536/tcp" ]`, `cannot parse port forward "80:65536/tcp": invalid port "65536"`},
+		{`forward-ports: [ "http/tcp" ]`, `cannot parse port forward "http/tcp": invalid port "http"`},
+		{`forward-ports: [ "8080/tcp", "8080:80/tcp" ]`, `cannot forward host port 8080/tcp more than once`},
+		{`forward-ports: [ "80/tcp" ]`, `cannot forward privileged host port 80/tcp`},
+	}
+
+	for _, t := range testCases {
+		yml := strings.Replace(mockSnapYaml, "$t", t.inp, -1)
+		info := snaptest.MockInfo(c, yml, nil)
+		plug := info.Plugs["network-namespace"]
+
+		c.Check(interfaces.BeforePreparePlug(s.iface, plug), ErrorMatches, t.errStr, Commentf("unexpected error for %q", t.inp))
+	}
+}
+
+const networkNamespaceTeardown = "ip netns del snap.other 2>/dev/null; " +
+	"ip link del snapd9298a10 2>/dev/null; " +
+	`for c in iptables ip6tables; do for t in nat filter; do $$c -t $$t -S 2>/dev/null | grep -E -e "-m comment --comment snap[.]other( |$$)" | sed -e s/^-A/-D/ | xargs -r -L1 $$c -t $$t; done; done`
+
+func (s *networkNamespaceInterfaceSuite) TestSystemdConnectedPlug(c *C) {
+	spec := &systemd.Specification{}
+	err := spec.AddConnectedPlug(s.iface, s.plug, s.slot)
+	c.Assert(err, IsNil)
+	c.Assert(spec.Services(), DeepEquals, map[string]*systemd.Service{
+		"snap.other.interface.network-namespace.service": {
+			Description:     "Network namespace of snap other",
+			Type:            "oneshot",
+			RemainAfterExit: true,
+			ExecStart: "/bin/sh -c '" + networkNamespaceTeardown + "; " +
+				"set -e; " +
+				`if ss -l -n -t "sport = :8080" | tail -n +2 | grep -q .; then echo "cannot forward host port 8080/tcp: the port is in use" >&2; exit 1; fi; ` +
+				`if ss -l -n -u "sport = :5353" | tail -n +2 | grep -q .; then echo "cannot forward host port 5353/udp: the port is in use" >&2; exit 1; fi; ` +
+				"net=; " +
+				`for n in $$(seq 209 255) $$(seq 0 208); do if [ -z "$$(ip -o -4 addr show to 10.177.$$n.176/30; ip -4 route show to root 10.177.$$n.176/30)" ]; then net=10.177.$$n; break; fi; done; ` +
+				`if [ -z "$$net" ]; then echo "cannot find free addresses for the network namespace" >&2; exit 1; fi; ` +
+				"ip netns add snap.other; " +
+				"ip link add snapd9298a10 type veth peer name eth0 netns snap.other; " +
+				"ip addr add $$net.177/30 dev snapd9298a10; " +
+				"ip link set snapd9298a10 up; " +
+				"sysctl -q -w net.ipv4.conf.snapd9298a10.forwarding=1; " +
+				"ip -n snap.other link set lo up; " +
+				"ip -n snap.other addr add $$net.178/30 dev eth0; " +
+				"ip -n snap.other link set eth0 up; " +
+				"ip -n snap.other route add default via $$net.177; " +
+				"iptables -t nat -A POSTROUTING -s $$net.178/30 ! -o snapd9298a10 -m comment --comment snap.other -j MASQUERADE; " +
+				"iptables -A FORWARD -i snapd9298a10 -m comment --comment snap.other -j ACCEPT; " +
+				"iptables -A FORWARD -o snapd9298a10 -m conntrack --ctstate RELATED,ESTABLISHED -m comment --comment snap.other -j ACCEPT; " +
+				"iptables -t nat -A PREROUTING -p tcp -m addrtype --dst-type LOCAL --dport 8080 -m comment --comment snap.other -j DNAT --to-destination $$net.178:80; " +
+				"iptables -t nat -A OUTPUT -p tcp -m addrtype --dst-type LOCAL --dport 8080 -m comment --comment snap.other -j DNAT --to-destination $$net.178:80; " +
+				"iptables -A FORWARD -o snapd9298a10 -p tcp -d $$net.178 --dport 80 -m comment --comment snap.other -j ACCEPT; " +
+				"iptables -t nat -A PREROUTING -p udp -m addrtype --dst-type LOCAL --dport 5353 -m comment --comment snap.other -j DNAT --to-destination $$net.178:5353; " +
+				"iptables -t nat -A OUTPUT -p udp -m addrtype --dst-type LOCAL --dport 5353 -m comment --comment snap.other -j DNAT --to-destination $$net.178:5353; " +
+				"iptables -A FORWARD -o snapd9298a10 -p udp -d $$net.178 --dport 5353 -m comment --comment snap.other -j ACCEPT'",
+			ExecStop: "/bin/sh -c '" + networkNamespaceTeardown + "'",
+		},
+	})
+}
+
+func (s *networkNamespaceInterfaceSuite) TestSystemdConnectedPlugNoForwards(c *C) {
+	plugSnap := snaptest.MockInfo(c, `name: other
+version: 1.0
+plugs:
+ network-namespace:
+apps:
+ app:
+  command: foo
+  plugs: [network-namespace]
+`, nil)
+	plug := interfaces.NewConnectedPlug(plugSnap.Plugs["network-namespace"], nil, nil)
+
+	spec := &systemd.Specification{}
+	c.Assert(spec.AddConnectedPlug(s.iface, plug, s.slot), IsNil)
+	svc := spec.Services()["snap.other.interface.network-namespace.service"]
+	c.Assert(svc, NotNil)
+	c.Check(svc.ExecStart, Not(testutil.Contains), "DNAT")
+	c.Check(svc.ExecStart, Not(testutil.Contains), "ss -l")
+	c.Check(svc.ExecStart, Not(testutil.Contains), "ip_forward")
+	c.Check(svc.ExecStart, testutil.Contains, "MASQUERADE")
+	c.Check(svc.ExecStop, Equals, "/bin/sh -c '"+networkNamespaceTeardown+"'")
+}
+
+func (s *networkNamespaceInterfaceSuite) TestAutoConnect(c *C) {
+	c.Check(s.iface.AutoConnect(s.plugInfo, s.slotInfo), Equals, true)
+}
+
+func (s *networkNamespaceInterfaceSuite) TestInterfaces(c *C) {
+	c.Check(builtin.Interfaces(), testutil.DeepContains, s.iface)
+}
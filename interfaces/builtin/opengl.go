@@ -19,6 +19,20 @@
 
 package builtin
 
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/snapcore/snapd/interfaces"
+	"github.com/snapcore/snapd/interfaces/apparmor"
+	"github.com/snapcore/snapd/interfaces/mount"
+	"github.com/snapcore/snapd/osutil"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/strutil"
+)
+
 const openglSummary = `allows access to OpenGL stack`
 
 const openglBaseDeclarationSlots = `
@@ -26,6 +40,7 @@ const openglBaseDeclarationSlots = `
     allow-installation:
       slot-snap-type:
         - core
+        - gadget
 `
 
 const openglConnectedPlugAppArmor = `
@@ -123,8 +138,192 @@ var openglConnectedPlugUDev = []string{
 	`KERNEL=="nvmap"`,
 }
 
+// openglVendors are the vendors of userspace drivers that a non-core
+// opengl slot may provide.
+var openglVendors = []string{"mesa", "nvidia"}
+
+// openglVendorDirs maps the slot attributes a gadget snap uses to
+// point at its userspace drivers to the directories these are made
+// available at for the consuming snaps. They are all part of the
+// SNAP_LIBRARY_PATH or the loader search paths set up by snap-confine.
+var openglVendorDirs = []struct {
+	attr string
+	dir  string
+}{
+	{"libraries", "/var/lib/snapd/lib/gl"},
+	{"libraries32", "/var/lib/snapd/lib/gl32"},
+	{"vulkan-icd", "/var/lib/snapd/lib/vulkan"},
+	{"glvnd", "/var/lib/snapd/lib/glvnd"},
+}
+
+type openglInterface struct {
+	commonInterface
+}
+
+// isVendorSlot returns whether the slot provides vendor userspace drivers
+// from a snap instead of relying on what the host provides.
+func (iface *openglInterface) isVendorSlot(slotSnap *snap.Info) bool {
+	t := slotSnap.GetType()
+	return t != snap.TypeOS && t != snap.TypeSnapd
+}
+
+func (iface *openglInterface) BeforePrepareSlot(slot *snap.SlotInfo) error {
+	if !iface.isVendorSlot(slot.Snap) {
+		return nil
+	}
+	if slot.Snap.GetType() != snap.TypeGadget {
+		return fmt.Errorf("opengl slots are reserved for the core and gadget snaps")
+	}
+
+	vendor, ok := slot.Attrs["vendor"].(string)
+	if !ok || vendor == "" {
+		return fmt.Errorf("opengl slot must have a vendor attribute")
+	}
+	if !strutil.ListContains(openglVendors, vendor) {
+		return fmt.Errorf("opengl slot vendor must be one of %s, not %q", strings.Join(openglVendors, ", "), vendor)
+	}
+
+	if _, ok := slot.Attrs["libraries"]; !ok {
+		return fmt.Errorf("opengl slot must have a libraries attribute")
+	}
+	for _, vd := range openglVendorDirs {
+		v, ok := slot.Attrs[vd.attr]
+		if !ok {
+			continue
+		}
+		path, ok := v.(string)
+		if !ok || path == "" {
+			return fmt.Errorf("opengl slot %s attribute must be a non-empty string", vd.attr)
+		}
+		if err := validateOpenglVendorPath(path); err != nil {
+			return fmt.Errorf("opengl slot %s attribute %v", vd.attr, err)
+		}
+	}
+	return nil
+}
+
+// validateOpenglVendorPath checks that a path to vendor drivers refers to
+// a location inside the slot snap itself.
+func validateOpenglVendorPath(path string) error {
+	rel := strings.TrimPrefix(path, "$SNAP/")
+	if strings.Contains(rel, "$") {
+		return fmt.Errorf("%q must be relative to $SNAP", path)
+	}
+	if filepath.IsAbs(rel) || rel != filepath.Clean(rel) || rel == ".." || strings.HasPrefix(rel, "../") {
+		return fmt.Errorf("%q must be a clean path inside the snap", path)
+	}
+	return nil
+}
+
+func (iface *openglInterface) BeforePreparePlug(plug *snap.PlugInfo) error {
+	v, ok := plug.Attrs["vendors"]
+	if !ok {
+		return nil
+	}
+	vendors, ok := v.([]interface{})
+	if !ok || len(vendors) == 0 {
+		return fmt.Errorf("opengl plug vendors attribute must be a non-empty list of strings")
+	}
+	for _, vendor := range vendors {
+		vendor, ok := vendor.(string)
+		if !ok || !strutil.ListContains(openglVendors, vendor) {
+			return fmt.Errorf("opengl plug vendors must be among %s, not %v", strings.Join(openglVendors, ", "), vendor)
+		}
+	}
+	return nil
+}
+
+// acceptsVendor returns whether the plug is happy to use drivers from the
+// given vendor. Plugs that do not express a preference take any vendor.
+func (iface *openglInterface) acceptsVendor(plug *snap.PlugInfo, vendor string) bool {
+	vendors, ok := plug.Attrs["vendors"].([]interface{})
+	if !ok {
+		return true
+	}
+	for _, v := range vendors {
+		if v == vendor {
+			return true
+		}
+	}
+	return false
+}
+
+func (iface *openglInterface) AutoConnect(plug *snap.PlugInfo, slot *snap.SlotInfo) bool {
+	if slot == nil || !iface.isVendorSlot(slot.Snap) {
+		return true
+	}
+	vendor, _ := slot.Attrs["vendor"].(string)
+	return iface.acceptsVendor(plug, vendor)
+}
+
+// vendorSources returns the directories of the vendor drivers provided by
+// the slot, keyed by the directory they are made available at.
+func (iface *openglInterface) vendorSources(slot *interfaces.ConnectedSlot) map[string]string {
+	sources := make(map[string]string)
+	for _, vd := range openglVendorDirs {
+		var path string
+		if err := slot.Attr(vd.attr, &path); err != nil || path == "" {
+			continue
+		}
+		sources[vd.dir] = resolveSpecialVariable(path, slot.Snap())
+	}
+	return sources
+}
+
+func (iface *openglInterface) AppArmorConnectedPlug(spec *apparmor.Specification, plug *interfaces.ConnectedPlug, slot *interfaces.ConnectedSlot) error {
+	if err := iface.commonInterface.AppArmorConnectedPlug(spec, plug, slot); err != nil {
+		return err
+	}
+	if !iface.isVendorSlot(slot.Snap()) {
+		return nil
+	}
+
+	var vendor string
+	slot.Attr("vendor", &vendor)
+	sources := iface.vendorSources(slot)
+	for _, vd := range openglVendorDirs {
+		source, ok := sources[vd.dir]
+		if !ok {
+			continue
+		}
+		// The drivers are bind mounted, so apparmor sees the paths
+		// inside the slot snap.
+		spec.AddSnippet(fmt.Sprintf("# %s userspace drivers from %s\n%s/ r,\n%s/** rm,\n", vendor, slot.Snap().InstanceName(), source, source))
+
+		var buf bytes.Buffer
+		fmt.Fprintf(&buf, "  # Read-only %s userspace drivers %s -> %s\n", vendor, slot.Ref(), vd.dir)
+		fmt.Fprintf(&buf, "  mount options=(bind) %s/ -> %s/,\n", source, vd.dir)
+		fmt.Fprintf(&buf, "  remount options=(bind, ro) %s/,\n", vd.dir)
+		fmt.Fprintf(&buf, "  umount %s/,\n", vd.dir)
+		apparmor.WritableProfile(&buf, vd.dir, 1)
+		spec.AddUpdateNS(buf.String())
+	}
+	return nil
+}
+
+func (iface *openglInterface) MountConnectedPlug(spec *mount.Specification, plug *interfaces.ConnectedPlug, slot *interfaces.ConnectedSlot) error {
+	if !iface.isVendorSlot(slot.Snap()) {
+		return nil
+	}
+	sources := iface.vendorSources(slot)
+	for _, vd := range openglVendorDirs {
+		source, ok := sources[vd.dir]
+		if !ok {
+			continue
+		}
+		if err := spec.AddMountEntry(osutil.MountEntry{
+			Name:    source,
+			Dir:     vd.dir,
+			Options: []string{"bind", "ro"},
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func init() {
-	registerIface(&commonInterface{
+	registerIface(&openglInterface{commonInterface{
 		name:                  "opengl",
 		summary:               openglSummary,
 		implicitOnCore:        true,
@@ -132,6 +331,5 @@ func init() {
 		baseDeclarationSlots:  openglBaseDeclarationSlots,
 		connectedPlugAppArmor: openglConnectedPlugAppArmor,
 		connectedPlugUDev:     openglConnectedPlugUDev,
-		reservedForOS:         true,
-	})
+	}})
 }
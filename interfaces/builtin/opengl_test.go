@@ -20,22 +20,28 @@
 package builtin_test
 
 import (
+	"strings"
+
 	. "gopkg.in/check.v1"
 
 	"github.com/snapcore/snapd/interfaces"
 	"github.com/snapcore/snapd/interfaces/apparmor"
 	"github.com/snapcore/snapd/interfaces/builtin"
+	"github.com/snapcore/snapd/interfaces/mount"
 	"github.com/snapcore/snapd/interfaces/udev"
+	"github.com/snapcore/snapd/osutil"
 	"github.com/snapcore/snapd/snap"
 	"github.com/snapcore/snapd/testutil"
 )
 
 type OpenglInterfaceSuite struct {
-	iface    interfaces.Interface
-	slotInfo *snap.SlotInfo
-	slot     *interfaces.ConnectedSlot
-	plugInfo *snap.PlugInfo
-	plug     *interfaces.ConnectedPlug
+	iface          interfaces.Interface
+	slotInfo       *snap.SlotInfo
+	slot           *interfaces.ConnectedSlot
+	plugInfo       *snap.PlugInfo
+	plug           *interfaces.ConnectedPlug
+	vendorSlotInfo *snap.SlotInfo
+	vendorSlot     *interfaces.ConnectedSlot
 }
 
 var _ = Suite(&OpenglInterfaceSuite{
@@ -56,9 +62,20 @@ slots:
   opengl:
 `
 
+const openglVendorYaml = `name: gpu-drivers
+version: 0
+type: gadget
+slots:
+  opengl:
+    vendor: nvidia
+    libraries: $SNAP/usr/lib/x86_64-linux-gnu
+    vulkan-icd: usr/share/vulkan/icd.d
+`
+
 func (s *OpenglInterfaceSuite) SetUpTest(c *C) {
 	s.plug, s.plugInfo = MockConnectedPlug(c, openglConsumerYaml, nil, "opengl")
 	s.slot, s.slotInfo = MockConnectedSlot(c, openglCoreYaml, nil, "opengl")
+	s.vendorSlot, s.vendorSlotInfo = MockConnectedSlot(c, openglVendorYaml, &snap.SideInfo{Revision: snap.R(2)}, "opengl")
 }
 
 func (s *OpenglInterfaceSuite) TestName(c *C) {
@@ -74,11 +91,97 @@ func (s *OpenglInterfaceSuite) TestSanitizeSlot(c *C) {
 		Interface: "opengl",
 	}
 	c.Assert(interfaces.BeforePrepareSlot(s.iface, slot), ErrorMatches,
-		"opengl slots are reserved for the core snap")
+		"opengl slots are reserved for the core and gadget snaps")
+
+	slot.Snap.SnapType = snap.TypeApp
+	c.Assert(interfaces.BeforePrepareSlot(s.iface, slot), ErrorMatches,
+		"opengl slots are reserved for the core and gadget snaps")
+
+	slot.Snap.SnapType = snap.TypeGadget
+	c.Assert(interfaces.BeforePrepareSlot(s.iface, slot), ErrorMatches,
+		"opengl slot must have a vendor attribute")
+}
+
+func (s *OpenglInterfaceSuite) TestSanitizeVendorSlot(c *C) {
+	c.Assert(interfaces.BeforePrepareSlot(s.iface, s.vendorSlotInfo), IsNil)
+
+	for _, t := range []struct {
+		attrs map[string]interface{}
+		err   string
+	}{
+		{map[string]interface{}{"libraries": "lib"}, "opengl slot must have a vendor attribute"},
+		{map[string]interface{}{"vendor": "acme", "libraries": "lib"}, `opengl slot vendor must be one of mesa, nvidia, not "acme"`},
+		{map[string]interface{}{"vendor": "mesa"}, "opengl slot must have a libraries attribute"},
+		{map[string]interface{}{"vendor": "mesa", "libraries": 1}, "opengl slot libraries attribute must be a non-empty string"},
+		{map[string]interface{}{"vendor": "mesa", "libraries": "$SNAP_DATA/lib"}, `opengl slot libraries attribute "\$SNAP_DATA/lib" must be relative to \$SNAP`},
+		{map[string]interface{}{"vendor": "mesa", "libraries": "../lib"}, `opengl slot libraries attribute "../lib" must be a clean path inside the snap`},
+		{map[string]interface{}{"vendor": "mesa", "libraries": "/usr/lib"}, `opengl slot libraries attribute "/usr/lib" must be a clean path inside the snap`},
+		{map[string]interface{}{"vendor": "mesa", "libraries": "lib", "glvnd": "a//b"}, `opengl slot glvnd attribute "a//b" must be a clean path inside the snap`},
+	} {
+		slot := &snap.SlotInfo{
+			Snap:      &snap.Info{SuggestedName: "some-snap", SnapType: snap.TypeGadget},
+			Name:      "opengl",
+			Interface: "opengl",
+			Attrs:     t.attrs,
+		}
+		c.Check(interfaces.BeforePrepareSlot(s.iface, slot), ErrorMatches, t.err, Commentf("%v", t.attrs))
+	}
 }
 
 func (s *OpenglInterfaceSuite) TestSanitizePlug(c *C) {
 	c.Assert(interfaces.BeforePreparePlug(s.iface, s.plugInfo), IsNil)
+
+	plug := &snap.PlugInfo{
+		Snap:      &snap.Info{SuggestedName: "some-snap"},
+		Name:      "opengl",
+		Interface: "opengl",
+		Attrs:     map[string]interface{}{"vendors": []interface{}{"mesa"}},
+	}
+	c.Assert(interfaces.BeforePreparePlug(s.iface, plug), IsNil)
+
+	plug.Attrs["vendors"] = []interface{}{"mesa", "acme"}
+	c.Assert(interfaces.BeforePreparePlug(s.iface, plug), ErrorMatches,
+		"opengl plug vendors must be among mesa, nvidia, not acme")
+	plug.Attrs["vendors"] = "mesa"
+	c.Assert(interfaces.BeforePreparePlug(s.iface, plug), ErrorMatches,
+		"opengl plug vendors attribute must be a non-empty list of strings")
+}
+
+func (s *OpenglInterfaceSuite) TestVendorAppArmorSpec(c *C) {
+	spec := &apparmor.Specification{}
+	c.Assert(spec.AddConnectedPlug(s.iface, s.plug, s.vendorSlot), IsNil)
+	snippet := spec.SnippetForTag("snap.consumer.app")
+	c.Check(snippet, testutil.Contains, `/dev/nvidia* rw,`)
+	c.Check(snippet, testutil.Contains, "/snap/gpu-drivers/2/usr/lib/x86_64-linux-gnu/** rm,\n")
+	c.Check(snippet, testutil.Contains, "/snap/gpu-drivers/2/usr/share/vulkan/icd.d/** rm,\n")
+
+	updateNS := strings.Join(spec.UpdateNS(), "")
+	c.Check(updateNS, testutil.Contains, "  mount options=(bind) /snap/gpu-drivers/2/usr/lib/x86_64-linux-gnu/ -> /var/lib/snapd/lib/gl/,\n")
+	c.Check(updateNS, testutil.Contains, "  mount options=(bind) /snap/gpu-drivers/2/usr/share/vulkan/icd.d/ -> /var/lib/snapd/lib/vulkan/,\n")
+	c.Check(updateNS, Not(testutil.Contains), "/var/lib/snapd/lib/gl32/")
+
+	// no mount rules when the host provides the drivers
+	spec = &apparmor.Specification{}
+	c.Assert(spec.AddConnectedPlug(s.iface, s.plug, s.slot), IsNil)
+	c.Check(spec.UpdateNS(), HasLen, 0)
+}
+
+func (s *OpenglInterfaceSuite) TestVendorMountSpec(c *C) {
+	spec := &mount.Specification{}
+	c.Assert(spec.AddConnectedPlug(s.iface, s.plug, s.vendorSlot), IsNil)
+	c.Check(spec.MountEntries(), DeepEquals, []osutil.MountEntry{{
+		Name:    "/snap/gpu-drivers/2/usr/lib/x86_64-linux-gnu",
+		Dir:     "/var/lib/snapd/lib/gl",
+		Options: []string{"bind", "ro"},
+	}, {
+		Name:    "/snap/gpu-drivers/2/usr/share/vulkan/icd.d",
+		Dir:     "/var/lib/snapd/lib/vulkan",
+		Options: []string{"bind", "ro"},
+	}})
+
+	spec = &mount.Specification{}
+	c.Assert(spec.AddConnectedPlug(s.iface, s.plug, s.slot), IsNil)
+	c.Check(spec.MountEntries(), HasLen, 0)
 }
 
 func (s *OpenglInterfaceSuite) TestAppArmorSpec(c *C) {
@@ -114,6 +217,19 @@ func (s *OpenglInterfaceSuite) TestStaticInfo(c *C) {
 
 func (s *OpenglInterfaceSuite) TestAutoConnect(c *C) {
 	c.Assert(s.iface.AutoConnect(s.plugInfo, s.slotInfo), Equals, true)
+	c.Assert(s.iface.AutoConnect(s.plugInfo, s.vendorSlotInfo), Equals, true)
+
+	// plugs may restrict the vendors they accept
+	plug := &snap.PlugInfo{
+		Snap:      &snap.Info{SuggestedName: "some-snap"},
+		Name:      "opengl",
+		Interface: "opengl",
+		Attrs:     map[string]interface{}{"vendors": []interface{}{"mesa"}},
+	}
+	c.Check(s.iface.AutoConnect(plug, s.vendorSlotInfo), Equals, false)
+	c.Check(s.iface.AutoConnect(plug, s.slotInfo), Equals, true)
+	plug.Attrs["vendors"] = []interface{}{"mesa", "nvidia"}
+	c.Check(s.iface.AutoConnect(plug, s.vendorSlotInfo), Equals, true)
 }
 
 func (s *OpenglInterfaceSuite) TestInterfaces(c *C) {
@@ -23,6 +23,7 @@ import (
 	"fmt"
 	"path/filepath"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 
@@ -79,6 +80,19 @@ var serialDeviceNodePattern = regexp.MustCompile("^/dev/tty(mxc|USB|ACM|AMA|XRUS
 // are also specified
 var serialUDevSymlinkPattern = regexp.MustCompile("^/dev/serial-port-[a-z0-9]+$")
 
+// Pattern of the stable symlinks placed under /dev/serial-ports/, path
+// attributes will be compared to this for validity when udev attributes
+// are used to identify the serial device
+var serialPortsSymlinkPattern = regexp.MustCompile("^/dev/serial-ports/[a-z0-9]+(-[a-z0-9]+)*$")
+
+// Patterns for the keys and values of the udev-attributes slot attribute,
+// keys are sysfs attribute names and values must not be able to escape
+// the quoting of the generated udev rules
+var (
+	serialUDevAttributeKeyPattern   = regexp.MustCompile("^[a-zA-Z0-9_][a-zA-Z0-9_.-]*$")
+	serialUDevAttributeValuePattern = regexp.MustCompile(`^[^"\\\x00-\x1f]+$`)
+)
+
 // BeforePrepareSlot checks validity of the defined slot
 func (iface *serialPortInterface) BeforePrepareSlot(slot *snap.SlotInfo) error {
 	if err := sanitizeSlotReservedForOSOrGadget(iface, slot); err != nil {
@@ -94,13 +108,21 @@ func (iface *serialPortInterface) BeforePrepareSlot(slot *snap.SlotInfo) error {
 	// Clean the path before further checks
 	path = filepath.Clean(path)
 
-	if iface.hasUsbAttrs(slot) {
-		// Must be path attribute where symlink will be placed and usb vendor and product identifiers
+	udevAttrs, err := iface.udevAttributes(slot)
+	if err != nil {
+		return err
+	}
+
+	if iface.hasUsbAttrs(slot) || len(udevAttrs) > 0 {
+		// Must be path attribute where symlink will be placed and usb
+		// identifiers or udev attributes identifying the device
 		// Check the path attribute is in the allowable pattern
-		if !serialUDevSymlinkPattern.MatchString(path) {
+		if !serialUDevSymlinkPattern.MatchString(path) && !serialPortsSymlinkPattern.MatchString(path) {
 			return fmt.Errorf("serial-port path attribute specifies invalid symlink location")
 		}
+	}
 
+	if iface.hasUsbAttrs(slot) {
 		usbVendor, vOk := slot.Attrs["usb-vendor"].(int64)
 		if !vOk {
 			return fmt.Errorf("serial-port slot failed to find usb-vendor attribute")
@@ -121,7 +143,7 @@ func (iface *serialPortInterface) BeforePrepareSlot(slot *snap.SlotInfo) error {
 		if ok && (usbInterfaceNumber < 0 || usbInterfaceNumber >= UsbMaxInterfaces) {
 			return fmt.Errorf("serial-port usb-interface-number attribute cannot be negative or larger than %d", UsbMaxInterfaces-1)
 		}
-	} else {
+	} else if len(udevAttrs) == 0 {
 		// Just a path attribute - must be a valid usb device node
 		// Check the path attribute is in the allowable pattern
 		if !serialDeviceNodePattern.MatchString(path) {
@@ -132,31 +154,21 @@ func (iface *serialPortInterface) BeforePrepareSlot(slot *snap.SlotInfo) error {
 }
 
 func (iface *serialPortInterface) UDevPermanentSlot(spec *udev.Specification, slot *snap.SlotInfo) error {
-	var usbVendor, usbProduct, usbInterfaceNumber int64
 	var path string
-	if err := slot.Attr("usb-vendor", &usbVendor); err != nil {
-		return nil
-	}
-	if err := slot.Attr("usb-product", &usbProduct); err != nil {
-		return nil
-	}
 	if err := slot.Attr("path", &path); err != nil || path == "" {
 		return nil
 	}
-	if err := slot.Attr("usb-interface-number", &usbInterfaceNumber); err == nil {
-		spec.AddSnippet(fmt.Sprintf(`# serial-port
-IMPORT{builtin}="usb_id"
-SUBSYSTEM=="tty", SUBSYSTEMS=="usb", ATTRS{idVendor}=="%04x", ATTRS{idProduct}=="%04x", ENV{ID_USB_INTERFACE_NUM}=="%02x", SYMLINK+="%s"`, usbVendor, usbProduct, usbInterfaceNumber, strings.TrimPrefix(path, "/dev/")))
-	} else {
-		spec.AddSnippet(fmt.Sprintf(`# serial-port
-IMPORT{builtin}="usb_id"
-SUBSYSTEM=="tty", SUBSYSTEMS=="usb", ATTRS{idVendor}=="%04x", ATTRS{idProduct}=="%04x", SYMLINK+="%s"`, usbVendor, usbProduct, strings.TrimPrefix(path, "/dev/")))
+	match, ok := iface.udevMatch(slot)
+	if !ok {
+		return nil
 	}
+	spec.AddSnippet(fmt.Sprintf(`# serial-port
+%s, SYMLINK+="%s"`, match, strings.TrimPrefix(filepath.Clean(path), "/dev/")))
 	return nil
 }
 
 func (iface *serialPortInterface) AppArmorConnectedPlug(spec *apparmor.Specification, plug *interfaces.ConnectedPlug, slot *interfaces.ConnectedSlot) error {
-	if iface.hasUsbAttrs(slot) {
+	if iface.hasUsbAttrs(slot) || iface.hasUDevAttrs(slot) {
 		// This apparmor rule is an approximation of serialDeviceNodePattern
 		// (AARE is different than regex, so we must approximate).
 		// UDev tagging and device cgroups will restrict down to the specific device
@@ -175,34 +187,97 @@ func (iface *serialPortInterface) AppArmorConnectedPlug(spec *apparmor.Specifica
 }
 
 func (iface *serialPortInterface) UDevConnectedPlug(spec *udev.Specification, plug *interfaces.ConnectedPlug, slot *interfaces.ConnectedSlot) error {
-	// For connected plugs, we use vendor and product ids if available,
-	// otherwise add the kernel device
-	hasOnlyPath := !iface.hasUsbAttrs(slot)
-	var usbVendor, usbProduct int64
-	var path string
-	if err := slot.Attr("usb-vendor", &usbVendor); err != nil && !hasOnlyPath {
+	// For connected plugs, we use vendor and product ids or udev
+	// attributes if available, otherwise add the kernel device
+	if iface.hasUsbAttrs(slot) || iface.hasUDevAttrs(slot) {
+		if match, ok := iface.udevMatch(slot); ok {
+			spec.TagDevice(match)
+		}
 		return nil
 	}
-	if err := slot.Attr("usb-product", &usbProduct); err != nil && !hasOnlyPath {
+
+	var path string
+	if err := slot.Attr("path", &path); err != nil {
 		return nil
 	}
-	if err := slot.Attr("path", &path); err != nil && hasOnlyPath {
-		return nil
+	spec.TagDevice(fmt.Sprintf(`SUBSYSTEM=="tty", KERNEL=="%s"`, strings.TrimPrefix(path, "/dev/")))
+	return nil
+}
+
+// udevMatch returns the udev rule keys matching the serial device
+// identified by the usb identifiers and/or udev attributes of the slot.
+func (iface *serialPortInterface) udevMatch(attrs interfaces.Attrer) (match string, ok bool) {
+	var keys []string
+	var prefix string
+	if iface.hasUsbAttrs(attrs) {
+		var usbVendor, usbProduct, usbInterfaceNumber int64
+		if err := attrs.Attr("usb-vendor", &usbVendor); err != nil {
+			return "", false
+		}
+		if err := attrs.Attr("usb-product", &usbProduct); err != nil {
+			return "", false
+		}
+		prefix = `IMPORT{builtin}="usb_id"` + "\n"
+		keys = append(keys, `SUBSYSTEM=="tty"`, `SUBSYSTEMS=="usb"`,
+			fmt.Sprintf(`ATTRS{idVendor}=="%04x"`, usbVendor),
+			fmt.Sprintf(`ATTRS{idProduct}=="%04x"`, usbProduct))
+		if err := attrs.Attr("usb-interface-number", &usbInterfaceNumber); err == nil {
+			keys = append(keys, fmt.Sprintf(`ENV{ID_USB_INTERFACE_NUM}=="%02x"`, usbInterfaceNumber))
+		}
 	}
 
-	if hasOnlyPath {
-		spec.TagDevice(fmt.Sprintf(`SUBSYSTEM=="tty", KERNEL=="%s"`, strings.TrimPrefix(path, "/dev/")))
-	} else {
-		var usbInterfaceNumber int64
-		if err := slot.Attr("usb-interface-number", &usbInterfaceNumber); err == nil {
-			spec.TagDevice(fmt.Sprintf(`IMPORT{builtin}="usb_id"
-SUBSYSTEM=="tty", SUBSYSTEMS=="usb", ATTRS{idVendor}=="%04x", ATTRS{idProduct}=="%04x", ENV{ID_USB_INTERFACE_NUM}=="%02x"`, usbVendor, usbProduct, usbInterfaceNumber))
-		} else {
-			spec.TagDevice(fmt.Sprintf(`IMPORT{builtin}="usb_id"
-SUBSYSTEM=="tty", SUBSYSTEMS=="usb", ATTRS{idVendor}=="%04x", ATTRS{idProduct}=="%04x"`, usbVendor, usbProduct))
+	udevAttrs, err := iface.udevAttributes(attrs)
+	if err != nil {
+		return "", false
+	}
+	if len(keys) == 0 {
+		if len(udevAttrs) == 0 {
+			return "", false
 		}
+		keys = append(keys, `SUBSYSTEM=="tty"`)
 	}
-	return nil
+	names := make([]string, 0, len(udevAttrs))
+	for name := range udevAttrs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		keys = append(keys, fmt.Sprintf(`ATTRS{%s}=="%s"`, name, udevAttrs[name]))
+	}
+	return prefix + strings.Join(keys, ", "), true
+}
+
+// udevAttributes returns the validated udev-attributes slot attribute,
+// mapping sysfs attribute names to the values they must have.
+func (iface *serialPortInterface) udevAttributes(attrs interfaces.Attrer) (map[string]string, error) {
+	v, ok := attrs.Lookup("udev-attributes")
+	if !ok {
+		return nil, nil
+	}
+	m, ok := v.(map[string]interface{})
+	if !ok || len(m) == 0 {
+		return nil, fmt.Errorf("serial-port udev-attributes attribute must be a non-empty map")
+	}
+	udevAttrs := make(map[string]string, len(m))
+	for name, val := range m {
+		if !serialUDevAttributeKeyPattern.MatchString(name) {
+			return nil, fmt.Errorf("serial-port udev-attributes attribute has invalid name %q", name)
+		}
+		var value string
+		switch val := val.(type) {
+		case string:
+			value = val
+		case int64:
+			value = strconv.FormatInt(val, 10)
+		default:
+			return nil, fmt.Errorf("serial-port udev-attributes attribute %q must be a string", name)
+		}
+		if !serialUDevAttributeValuePattern.MatchString(value) {
+			return nil, fmt.Errorf("serial-port udev-attributes attribute %q has invalid value %q", name, value)
+		}
+		udevAttrs[name] = value
+	}
+	return udevAttrs, nil
 }
 
 func (iface *serialPortInterface) AutoConnect(*snap.PlugInfo, *snap.SlotInfo) bool {
@@ -282,6 +357,11 @@ func (iface *serialPortInterface) hasUsbAttrs(attrs interfaces.Attrer) bool {
 	return false
 }
 
+func (iface *serialPortInterface) hasUDevAttrs(attrs interfaces.Attrer) bool {
+	_, ok := attrs.Lookup("udev-attributes")
+	return ok
+}
+
 func init() {
 	registerIface(&serialPortInterface{})
 }
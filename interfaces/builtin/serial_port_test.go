@@ -85,6 +85,10 @@ type SerialPortInterfaceSuite struct {
 	testUDev2Info         *snap.SlotInfo
 	testUDev3             *interfaces.ConnectedSlot
 	testUDev3Info         *snap.SlotInfo
+	testUDev4             *interfaces.ConnectedSlot
+	testUDev4Info         *snap.SlotInfo
+	testUDev5             *interfaces.ConnectedSlot
+	testUDev5Info         *snap.SlotInfo
 	testUDevBadValue1     *interfaces.ConnectedSlot
 	testUDevBadValue1Info *snap.SlotInfo
 	testUDevBadValue2     *interfaces.ConnectedSlot
@@ -95,6 +99,9 @@ type SerialPortInterfaceSuite struct {
 	testUDevBadValue4Info *snap.SlotInfo
 	testUDevBadValue5     *interfaces.ConnectedSlot
 	testUDevBadValue5Info *snap.SlotInfo
+	testUDevBadValue6Info *snap.SlotInfo
+	testUDevBadValue7Info *snap.SlotInfo
+	testUDevBadValue8Info *snap.SlotInfo
 
 	// Consuming Snap
 	testPlugPort1     *interfaces.ConnectedPlug
@@ -234,6 +241,19 @@ slots:
       usb-product: 0x1234
       usb-interface-number: 0
       path: /dev/serial-port-myserial
+  test-udev-4:
+      interface: serial-port
+      udev-attributes:
+        serial: "A12B-34"
+        product: GPS receiver
+      path: /dev/serial-ports/gps
+  test-udev-5:
+      interface: serial-port
+      usb-vendor: 0xabcd
+      usb-product: 0x1234
+      udev-attributes:
+        serial: "0042"
+      path: /dev/serial-ports/modem-0
   test-udev-bad-value-1:
       interface: serial-port
       usb-vendor: -1
@@ -261,6 +281,21 @@ slots:
       usb-product: 0x4321
       usb-interface-number: 32
       path: /dev/serial-port-overinterfacenumber
+  test-udev-bad-value-6:
+      interface: serial-port
+      udev-attributes:
+        serial: "A12B-34"
+      path: /dev/ttyS0
+  test-udev-bad-value-7:
+      interface: serial-port
+      udev-attributes:
+        serial: 'A12B", RUN+="/bin/evil'
+      path: /dev/serial-ports/gps
+  test-udev-bad-value-8:
+      interface: serial-port
+      udev-attributes:
+        "serial}": "A12B-34"
+      path: /dev/serial-ports/gps
 `, nil)
 	s.testUDev1Info = gadgetSnapInfo.Slots["test-udev-1"]
 	s.testUDev1 = interfaces.NewConnectedSlot(s.testUDev1Info, nil, nil)
@@ -268,6 +303,10 @@ slots:
 	s.testUDev2 = interfaces.NewConnectedSlot(s.testUDev2Info, nil, nil)
 	s.testUDev3Info = gadgetSnapInfo.Slots["test-udev-3"]
 	s.testUDev3 = interfaces.NewConnectedSlot(s.testUDev3Info, nil, nil)
+	s.testUDev4Info = gadgetSnapInfo.Slots["test-udev-4"]
+	s.testUDev4 = interfaces.NewConnectedSlot(s.testUDev4Info, nil, nil)
+	s.testUDev5Info = gadgetSnapInfo.Slots["test-udev-5"]
+	s.testUDev5 = interfaces.NewConnectedSlot(s.testUDev5Info, nil, nil)
 	s.testUDevBadValue1Info = gadgetSnapInfo.Slots["test-udev-bad-value-1"]
 	s.testUDevBadValue1 = interfaces.NewConnectedSlot(s.testUDevBadValue1Info, nil, nil)
 	s.testUDevBadValue2Info = gadgetSnapInfo.Slots["test-udev-bad-value-2"]
@@ -278,6 +317,9 @@ slots:
 	s.testUDevBadValue4 = interfaces.NewConnectedSlot(s.testUDevBadValue4Info, nil, nil)
 	s.testUDevBadValue5Info = gadgetSnapInfo.Slots["test-udev-bad-value-5"]
 	s.testUDevBadValue5 = interfaces.NewConnectedSlot(s.testUDevBadValue5Info, nil, nil)
+	s.testUDevBadValue6Info = gadgetSnapInfo.Slots["test-udev-bad-value-6"]
+	s.testUDevBadValue7Info = gadgetSnapInfo.Slots["test-udev-bad-value-7"]
+	s.testUDevBadValue8Info = gadgetSnapInfo.Slots["test-udev-bad-value-8"]
 
 	consumingSnapInfo := snaptest.MockInfo(c, `
 name: client-snap
@@ -333,6 +375,8 @@ func (s *SerialPortInterfaceSuite) TestSanitizeGadgetSnapSlots(c *C) {
 	c.Assert(interfaces.BeforePrepareSlot(s.iface, s.testUDev1Info), IsNil)
 	c.Assert(interfaces.BeforePrepareSlot(s.iface, s.testUDev2Info), IsNil)
 	c.Assert(interfaces.BeforePrepareSlot(s.iface, s.testUDev3Info), IsNil)
+	c.Assert(interfaces.BeforePrepareSlot(s.iface, s.testUDev4Info), IsNil)
+	c.Assert(interfaces.BeforePrepareSlot(s.iface, s.testUDev5Info), IsNil)
 }
 
 func (s *SerialPortInterfaceSuite) TestSanitizeBadGadgetSnapSlots(c *C) {
@@ -341,6 +385,9 @@ func (s *SerialPortInterfaceSuite) TestSanitizeBadGadgetSnapSlots(c *C) {
 	c.Assert(interfaces.BeforePrepareSlot(s.iface, s.testUDevBadValue3Info), ErrorMatches, "serial-port path attribute specifies invalid symlink location")
 	c.Assert(interfaces.BeforePrepareSlot(s.iface, s.testUDevBadValue4Info), ErrorMatches, "serial-port usb-interface-number attribute cannot be negative or larger than 31")
 	c.Assert(interfaces.BeforePrepareSlot(s.iface, s.testUDevBadValue5Info), ErrorMatches, "serial-port usb-interface-number attribute cannot be negative or larger than 31")
+	c.Assert(interfaces.BeforePrepareSlot(s.iface, s.testUDevBadValue6Info), ErrorMatches, "serial-port path attribute specifies invalid symlink location")
+	c.Assert(interfaces.BeforePrepareSlot(s.iface, s.testUDevBadValue7Info), ErrorMatches, `serial-port udev-attributes attribute "serial" has invalid value .*`)
+	c.Assert(interfaces.BeforePrepareSlot(s.iface, s.testUDevBadValue8Info), ErrorMatches, `serial-port udev-attributes attribute has invalid name "serial}"`)
 }
 
 func (s *SerialPortInterfaceSuite) TestPermanentSlotUDevSnippets(c *C) {
@@ -381,6 +428,22 @@ SUBSYSTEM=="tty", SUBSYSTEMS=="usb", ATTRS{idVendor}=="abcd", ATTRS{idProduct}==
 	c.Assert(spec.Snippets(), HasLen, 1)
 	snippet = spec.Snippets()[0]
 	c.Assert(snippet, Equals, expectedSnippet3)
+
+	spec = &udev.Specification{}
+	// udev attributes are matched in a stable order
+	expectedSnippet4 := `# serial-port
+SUBSYSTEM=="tty", ATTRS{product}=="GPS receiver", ATTRS{serial}=="A12B-34", SYMLINK+="serial-ports/gps"`
+	err = spec.AddPermanentSlot(s.iface, s.testUDev4Info)
+	c.Assert(err, IsNil)
+	c.Assert(spec.Snippets(), DeepEquals, []string{expectedSnippet4})
+
+	spec = &udev.Specification{}
+	expectedSnippet5 := `# serial-port
+IMPORT{builtin}="usb_id"
+SUBSYSTEM=="tty", SUBSYSTEMS=="usb", ATTRS{idVendor}=="abcd", ATTRS{idProduct}=="1234", ATTRS{serial}=="0042", SYMLINK+="serial-ports/modem-0"`
+	err = spec.AddPermanentSlot(s.iface, s.testUDev5Info)
+	c.Assert(err, IsNil)
+	c.Assert(spec.Snippets(), DeepEquals, []string{expectedSnippet5})
 }
 
 func (s *SerialPortInterfaceSuite) TestConnectedPlugUDevSnippets(c *C) {
@@ -482,6 +545,9 @@ func (s *SerialPortInterfaceSuite) TestConnectedPlugAppArmorSnippets(c *C) {
 
 	expectedSnippet11 := `/dev/tty[A-Z]*[0-9] rw,`
 	checkConnectedPlugSnippet(s.testPlugPort2, s.testUDev3, expectedSnippet11)
+
+	expectedSnippet12 := `/dev/tty[A-Z]*[0-9] rw,`
+	checkConnectedPlugSnippet(s.testPlugPort2, s.testUDev4, expectedSnippet12)
 }
 
 func (s *SerialPortInterfaceSuite) TestConnectedPlugUDevSnippetsForPath(c *C) {
@@ -550,6 +616,18 @@ IMPORT{builtin}="usb_id"
 SUBSYSTEM=="tty", SUBSYSTEMS=="usb", ATTRS{idVendor}=="ffff", ATTRS{idProduct}=="ffff", TAG+="snap_client-snap_app-accessing-3rd-port"`
 	expectedExtraSnippet10 := `TAG=="snap_client-snap_app-accessing-3rd-port", RUN+="/usr/lib/snapd/snap-device-helper $env{ACTION} snap_client-snap_app-accessing-3rd-port $devpath $major:$minor"`
 	checkConnectedPlugSnippet(s.testPlugPort3, s.testUDev2, expectedSnippet10, expectedExtraSnippet10)
+
+	// these have udev attributes
+	expectedSnippet11 := `# serial-port
+SUBSYSTEM=="tty", ATTRS{product}=="GPS receiver", ATTRS{serial}=="A12B-34", TAG+="snap_client-snap_app-accessing-3rd-port"`
+	expectedExtraSnippet11 := `TAG=="snap_client-snap_app-accessing-3rd-port", RUN+="/usr/lib/snapd/snap-device-helper $env{ACTION} snap_client-snap_app-accessing-3rd-port $devpath $major:$minor"`
+	checkConnectedPlugSnippet(s.testPlugPort3, s.testUDev4, expectedSnippet11, expectedExtraSnippet11)
+
+	expectedSnippet12 := `# serial-port
+IMPORT{builtin}="usb_id"
+SUBSYSTEM=="tty", SUBSYSTEMS=="usb", ATTRS{idVendor}=="abcd", ATTRS{idProduct}=="1234", ATTRS{serial}=="0042", TAG+="snap_client-snap_app-accessing-3rd-port"`
+	expectedExtraSnippet12 := `TAG=="snap_client-snap_app-accessing-3rd-port", RUN+="/usr/lib/snapd/snap-device-helper $env{ACTION} snap_client-snap_app-accessing-3rd-port $devpath $major:$minor"`
+	checkConnectedPlugSnippet(s.testPlugPort3, s.testUDev5, expectedSnippet12, expectedExtraSnippet12)
 }
 
 func (s *SerialPortInterfaceSuite) TestHotplugDeviceDetected(c *C) {
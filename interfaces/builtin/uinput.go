@@ -0,0 +1,55 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2018 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package builtin
+
+const uinputSummary = `allows injecting input events via uinput`
+
+const uinputBaseDeclarationSlots = `
+  uinput:
+    allow-installation:
+      slot-snap-type:
+        - core
+    deny-auto-connection: true
+`
+
+const uinputConnectedPlugAppArmor = `
+# Description: Allows creating virtual input devices with uinput and
+# injecting input events through them, including keyboard and mouse events
+# received by any other application.
+
+  # Requires CONFIG_INPUT_UINPUT
+  /dev/uinput rw,
+  /dev/input/uinput rw,
+`
+
+var uinputConnectedPlugUDev = []string{`KERNEL=="uinput"`}
+
+func init() {
+	registerIface(&commonInterface{
+		name:                  "uinput",
+		summary:               uinputSummary,
+		implicitOnCore:        true,
+		implicitOnClassic:     true,
+		baseDeclarationSlots:  uinputBaseDeclarationSlots,
+		connectedPlugAppArmor: uinputConnectedPlugAppArmor,
+		connectedPlugUDev:     uinputConnectedPlugUDev,
+		reservedForOS:         true,
+	})
+}
@@ -38,6 +38,8 @@ type TestSecurityBackend struct {
 	RemoveCallback func(snapName string) error
 	// SandboxFeaturesCallback is a callback that is optionally called in SandboxFeatures
 	SandboxFeaturesCallback func() []string
+	// ProfilesDryRunCallback is a callback that is optionally called in ProfilesDryRun
+	ProfilesDryRunCallback func(snapInfo *snap.Info, opts interfaces.ConfinementOptions, repo *interfaces.Repository) (map[string]string, error)
 }
 
 // TestSetupCall stores details about calls to TestSecurityBackend.Setup
@@ -86,3 +88,11 @@ func (b *TestSecurityBackend) SandboxFeatures() []string {
 	}
 	return b.SandboxFeaturesCallback()
 }
+
+// ProfilesDryRun calls the dry-run callback if one is defined.
+func (b *TestSecurityBackend) ProfilesDryRun(snapInfo *snap.Info, opts interfaces.ConfinementOptions, repo *interfaces.Repository) (map[string]string, error) {
+	if b.ProfilesDryRunCallback == nil {
+		return nil, nil
+	}
+	return b.ProfilesDryRunCallback(snapInfo, opts, repo)
+}
@@ -0,0 +1,159 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2019 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package mount
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/interfaces"
+	"github.com/snapcore/snapd/osutil"
+	"github.com/snapcore/snapd/snap"
+)
+
+// AuditEntry is an entry of the mount table of a snap annotated with
+// where it comes from and whether it was applied.
+type AuditEntry struct {
+	Entry osutil.MountEntry
+	// Origin is "overname", "layout" or the name of the interface
+	// that produced the entry. Entries found only in the applied
+	// profiles are "synthetic", when snap-update-ns made them up to
+	// perform the other ones, or "stale" otherwise.
+	Origin string
+	// Applied is whether a system entry is in the mount namespace of
+	// the snap.
+	Applied bool
+	// AppliedFor lists the users in whose mount namespace of the snap
+	// a user entry is, by uid.
+	AppliedFor []int
+}
+
+// Audit holds the annotated system and per-user mount entries of a
+// snap.
+type Audit struct {
+	Mounts     []*AuditEntry
+	UserMounts []*AuditEntry
+}
+
+func annotateEntries(desired []osutil.MountEntry, origins []string) []*AuditEntry {
+	audit := make([]*AuditEntry, 0, len(desired))
+	for i, e := range desired {
+		audit = append(audit, &AuditEntry{Entry: e, Origin: origins[i]})
+	}
+	return audit
+}
+
+// markApplied marks the audited entries found in the applied profile,
+// for the given user unless uid is negative. The applied entries that
+// are not audited yet are added.
+func markApplied(audit []*AuditEntry, applied *osutil.MountProfile, uid int) []*AuditEntry {
+outer:
+	for i := range applied.Entries {
+		e := &applied.Entries[i]
+		for _, ae := range audit {
+			if ae.Entry.Equal(e) {
+				if uid >= 0 {
+					ae.AppliedFor = append(ae.AppliedFor, uid)
+				} else {
+					ae.Applied = true
+				}
+				continue outer
+			}
+		}
+		origin := "stale"
+		if e.XSnapdSynthetic() {
+			origin = "synthetic"
+		}
+		ae := &AuditEntry{Entry: *e, Origin: origin}
+		if uid >= 0 {
+			ae.AppliedFor = []int{uid}
+		} else {
+			ae.Applied = true
+		}
+		audit = append(audit, ae)
+	}
+	return audit
+}
+
+// userProfiles returns the applied user mount profiles of the snap, by
+// uid.
+func userProfiles(snapName string) (map[int]*osutil.MountProfile, error) {
+	prefix := fmt.Sprintf("snap.%s.", snapName)
+	const suffix = ".user-fstab"
+	matches, err := filepath.Glob(filepath.Join(dirs.SnapRunNsDir, prefix+"*"+suffix))
+	if err != nil {
+		return nil, err
+	}
+	profiles := make(map[int]*osutil.MountProfile, len(matches))
+	for _, fn := range matches {
+		uidStr := strings.TrimSuffix(strings.TrimPrefix(filepath.Base(fn), prefix), suffix)
+		uid, err := strconv.Atoi(uidStr)
+		if err != nil {
+			// not a per-user profile, e.g. of an instance of the snap
+			continue
+		}
+		profile, err := osutil.LoadMountProfile(fn)
+		if err != nil {
+			return nil, err
+		}
+		profiles[uid] = profile
+	}
+	return profiles, nil
+}
+
+// Audit returns the mount entries of the given snap with its current
+// connections, annotated with the interface or layout that produced
+// them and checked against the profiles snap-update-ns applied to the
+// mount namespaces of the snap, system-wide and per user.
+func (b *Backend) Audit(snapInfo *snap.Info, repo *interfaces.Repository) (*Audit, error) {
+	spec, err := b.snapSpecification(snapInfo, repo)
+	if err != nil {
+		return nil, err
+	}
+	snapName := snapInfo.InstanceName()
+
+	applied, err := osutil.LoadMountProfile(filepath.Join(dirs.SnapRunNsDir, fmt.Sprintf("snap.%s.fstab", snapName)))
+	if err != nil {
+		return nil, err
+	}
+	audit := &Audit{
+		Mounts: markApplied(annotateEntries(spec.MountEntries(), spec.MountEntryOrigins()), applied, -1),
+	}
+
+	userApplied, err := userProfiles(snapName)
+	if err != nil {
+		return nil, err
+	}
+	uids := make([]int, 0, len(userApplied))
+	for uid := range userApplied {
+		uids = append(uids, uid)
+	}
+	sort.Ints(uids)
+	userMounts := annotateEntries(spec.UserMountEntries(), spec.UserMountEntryOrigins())
+	for _, uid := range uids {
+		userMounts = markApplied(userMounts, userApplied[uid], uid)
+	}
+	audit.UserMounts = userMounts
+	return audit, nil
+}
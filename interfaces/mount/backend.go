@@ -33,6 +33,7 @@ import (
 	"bytes"
 	"fmt"
 	"os"
+	"path/filepath"
 
 	"github.com/snapcore/snapd/dirs"
 	"github.com/snapcore/snapd/interfaces"
@@ -58,20 +59,17 @@ func (b *Backend) Name() interfaces.SecuritySystem {
 func (b *Backend) Setup(snapInfo *snap.Info, confinement interfaces.ConfinementOptions, repo *interfaces.Repository, tm timings.Measurer) error {
 	// Record all changes to the mount system for this snap.
 	snapName := snapInfo.InstanceName()
-	spec, err := repo.SnapSpecification(b.Name(), snapName)
+	spec, err := b.snapSpecification(snapInfo, repo)
 	if err != nil {
-		return fmt.Errorf("cannot obtain mount security snippets for snap %q: %s", snapName, err)
+		return err
 	}
-	spec.(*Specification).AddOvername(snapInfo)
-	spec.(*Specification).AddLayout(snapInfo)
-	content := deriveContent(spec.(*Specification), snapInfo)
+	content := deriveContent(spec, snapInfo)
 	// synchronize the content with the filesystem
-	glob := fmt.Sprintf("snap.%s.*fstab", snapName)
 	dir := dirs.SnapMountPolicyDir
 	if err := os.MkdirAll(dir, 0755); err != nil {
 		return fmt.Errorf("cannot create directory for mount configuration files %q: %s", dir, err)
 	}
-	if _, _, err := osutil.EnsureDirState(dir, glob, content); err != nil {
+	if _, _, err := osutil.EnsureDirStateGlobs(dir, profileGlobs(snapName), content); err != nil {
 		return fmt.Errorf("cannot synchronize mount configuration files for snap %q: %s", snapName, err)
 	}
 	if err := UpdateSnapNamespace(snapName); err != nil {
@@ -80,18 +78,53 @@ func (b *Backend) Setup(snapInfo *snap.Info, confinement interfaces.ConfinementO
 	return nil
 }
 
+// snapSpecification returns the mount specification of the given snap,
+// including the entries derived from the snap itself.
+func (b *Backend) snapSpecification(snapInfo *snap.Info, repo *interfaces.Repository) (*Specification, error) {
+	snapName := snapInfo.InstanceName()
+	spec, err := repo.SnapSpecification(b.Name(), snapName)
+	if err != nil {
+		return nil, fmt.Errorf("cannot obtain mount security snippets for snap %q: %s", snapName, err)
+	}
+	spec.(*Specification).AddOvername(snapInfo)
+	spec.(*Specification).AddLayout(snapInfo)
+	return spec.(*Specification), nil
+}
+
+// ProfilesDryRun returns the mount profiles that Setup would write for the
+// given snap, without writing them or updating the mount namespace.
+func (b *Backend) ProfilesDryRun(snapInfo *snap.Info, confinement interfaces.ConfinementOptions, repo *interfaces.Repository) (map[string]string, error) {
+	spec, err := b.snapSpecification(snapInfo, repo)
+	if err != nil {
+		return nil, err
+	}
+	content := deriveContent(spec, snapInfo)
+	profiles := make(map[string]string, len(content))
+	for name, fileState := range content {
+		profiles[filepath.Join(dirs.SnapMountPolicyDir, name)] = string(fileState.Content)
+	}
+	return profiles, nil
+}
+
 // Remove removes mount configuration files of a given snap.
 //
 // This method should be called after removing a snap.
 func (b *Backend) Remove(snapName string) error {
-	glob := fmt.Sprintf("snap.%s.*fstab", snapName)
-	_, _, err := osutil.EnsureDirState(dirs.SnapMountPolicyDir, glob, nil)
+	_, _, err := osutil.EnsureDirStateGlobs(dirs.SnapMountPolicyDir, profileGlobs(snapName), nil)
 	if err != nil {
 		return fmt.Errorf("cannot synchronize mount configuration files for snap %q: %s", snapName, err)
 	}
 	return nil
 }
 
+// profileGlobs returns the globs matching the files written for the given snap.
+func profileGlobs(snapName string) []string {
+	return []string{
+		fmt.Sprintf("snap.%s.*fstab", snapName),
+		fmt.Sprintf("snap.%s.environment", snapName),
+	}
+}
+
 // addMountProfile adds a mount profile with the given name, based on the given entries.
 //
 // If there are no entries no profile is generated.
@@ -106,9 +139,10 @@ func addMountProfile(content map[string]*osutil.FileState, fname string, entries
 	content[fname] = &osutil.FileState{Content: buffer.Bytes(), Mode: 0644}
 }
 
-// deriveContent computes .fstab tables based on requests made to the specification.
+// deriveContent computes .fstab tables and the environment file based on
+// requests made to the specification.
 func deriveContent(spec *Specification, snapInfo *snap.Info) map[string]*osutil.FileState {
-	content := make(map[string]*osutil.FileState, 2)
+	content := make(map[string]*osutil.FileState, 3)
 	snapName := snapInfo.InstanceName()
 	// Add the per-snap fstab file.
 	// This file is read by snap-update-ns in the global pass.
@@ -116,6 +150,15 @@ func deriveContent(spec *Specification, snapInfo *snap.Info) map[string]*osutil.
 	// Add the per-snap user-fstab file.
 	// This file will be read by snap-update-ns in the per-user pass.
 	addMountProfile(content, fmt.Sprintf("snap.%s.user-fstab", snapName), spec.UserMountEntries())
+	// Add the per-snap environment file.
+	// This file is read by snap-exec when starting apps and hooks.
+	if env := spec.Environment(); len(env) > 0 {
+		var buffer bytes.Buffer
+		for _, kv := range env {
+			fmt.Fprintf(&buffer, "%s\n", kv)
+		}
+		content[fmt.Sprintf("snap.%s.environment", snapName)] = &osutil.FileState{Content: buffer.Bytes(), Mode: 0644}
+	}
 	return content
 }
 
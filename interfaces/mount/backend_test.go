@@ -87,6 +87,10 @@ func (s *backendSuite) TestRemove(c *C) {
 	err = ioutil.WriteFile(snapCanaryToGo, []byte("ni! ni! ni!"), 0644)
 	c.Assert(err, IsNil)
 
+	envCanaryToGo := filepath.Join(dirs.SnapMountPolicyDir, "snap.hello-world.environment")
+	err = ioutil.WriteFile(envCanaryToGo, []byte("ni! ni! ni!"), 0644)
+	c.Assert(err, IsNil)
+
 	appCanaryToStay := filepath.Join(dirs.SnapMountPolicyDir, "snap.i-stay.really.fstab")
 	err = ioutil.WriteFile(appCanaryToStay, []byte("stay!"), 0644)
 	c.Assert(err, IsNil)
@@ -101,6 +105,7 @@ func (s *backendSuite) TestRemove(c *C) {
 	c.Assert(osutil.FileExists(snapCanaryToGo), Equals, false)
 	c.Assert(osutil.FileExists(appCanaryToGo), Equals, false)
 	c.Assert(osutil.FileExists(hookCanaryToGo), Equals, false)
+	c.Assert(osutil.FileExists(envCanaryToGo), Equals, false)
 	c.Assert(appCanaryToStay, testutil.FileEquals, "stay!")
 	c.Assert(snapCanaryToStay, testutil.FileEquals, "stay!")
 }
@@ -160,6 +165,92 @@ func (s *backendSuite) TestSetupSetsupSimple(c *C) {
 	c.Check(string(content), Equals, fsEntry3.String()+"\n")
 }
 
+func (s *backendSuite) TestSetupEnvironment(c *C) {
+	s.Iface.MountPermanentPlugCallback = func(spec *mount.Specification, plug *snap.PlugInfo) error {
+		spec.AddEnvironment("SNAP_CONTENT_FOO_PATH", "/snap/snap-name/1/foo")
+		return nil
+	}
+	s.iface2.MountPermanentSlotCallback = func(spec *mount.Specification, slot *snap.SlotInfo) error {
+		spec.AddEnvironment("SNAP_CONTENT_BAR_PATH", "/snap/snap-name/1/bar")
+		return nil
+	}
+	snapInfo := s.InstallSnap(c, interfaces.ConfinementOptions{}, "", mockSnapYaml, 0)
+
+	fn := filepath.Join(dirs.SnapMountPolicyDir, "snap.snap-name.environment")
+	c.Check(fn, testutil.FileEquals, "SNAP_CONTENT_BAR_PATH=/snap/snap-name/1/bar\nSNAP_CONTENT_FOO_PATH=/snap/snap-name/1/foo\n")
+	// no mount profiles without mount entries
+	c.Check(filepath.Join(dirs.SnapMountPolicyDir, "snap.snap-name.fstab"), testutil.FileAbsent)
+
+	// the file goes away with the variables
+	s.Iface.MountPermanentPlugCallback = nil
+	s.iface2.MountPermanentSlotCallback = nil
+	s.UpdateSnap(c, snapInfo, interfaces.ConfinementOptions{}, mockSnapYaml, 0)
+	c.Check(fn, testutil.FileAbsent)
+}
+
+func (s *backendSuite) TestProfilesDryRun(c *C) {
+	fsEntry := osutil.MountEntry{Name: "/src-1", Dir: "/dst-1", Type: "none", Options: []string{"bind", "ro"}}
+	s.Iface.MountPermanentPlugCallback = func(spec *mount.Specification, plug *snap.PlugInfo) error {
+		return spec.AddMountEntry(fsEntry)
+	}
+	snapInfo := s.InstallSnap(c, interfaces.ConfinementOptions{}, "", mockSnapYaml, 0)
+	fn := filepath.Join(dirs.SnapMountPolicyDir, "snap.snap-name.fstab")
+
+	profiles, err := s.Backend.(interfaces.SecurityBackendDryRun).ProfilesDryRun(snapInfo, interfaces.ConfinementOptions{}, s.Repo)
+	c.Assert(err, IsNil)
+	c.Check(profiles, DeepEquals, map[string]string{
+		fn: fsEntry.String() + "\n",
+	})
+	c.Check(fn, testutil.FileEquals, profiles[fn])
+
+	// nothing is written
+	c.Assert(os.Remove(fn), IsNil)
+	_, err = s.Backend.(interfaces.SecurityBackendDryRun).ProfilesDryRun(snapInfo, interfaces.ConfinementOptions{}, s.Repo)
+	c.Assert(err, IsNil)
+	c.Check(fn, testutil.FileAbsent)
+}
+
+func (s *backendSuite) TestAudit(c *C) {
+	fsEntry1 := osutil.MountEntry{Name: "/src-1", Dir: "/dst-1", Type: "none", Options: []string{"bind", "ro"}}
+	fsEntry2 := osutil.MountEntry{Name: "/src-2", Dir: "/dst-2", Type: "none", Options: []string{"bind", "ro"}}
+	userFsEntry := osutil.MountEntry{Name: "/src-3", Dir: "/dst-3", Type: "none", Options: []string{"bind", "ro"}}
+	s.Iface.MountPermanentPlugCallback = func(spec *mount.Specification, plug *snap.PlugInfo) error {
+		if err := spec.AddMountEntry(fsEntry1); err != nil {
+			return err
+		}
+		return spec.AddUserMountEntry(userFsEntry)
+	}
+	s.iface2.MountPermanentSlotCallback = func(spec *mount.Specification, slot *snap.SlotInfo) error {
+		return spec.AddMountEntry(fsEntry2)
+	}
+	snapInfo := s.InstallSnap(c, interfaces.ConfinementOptions{}, "", mockSnapYaml, 0)
+
+	// what snap-update-ns applied
+	synthEntry := osutil.MountEntry{Name: "tmpfs", Dir: "/dst", Type: "tmpfs", Options: []string{osutil.XSnapdSynthetic(), osutil.XSnapdNeededBy("/dst-1")}}
+	staleEntry := osutil.MountEntry{Name: "/src-old", Dir: "/dst-old", Type: "none", Options: []string{"bind", "ro"}}
+	c.Assert(os.MkdirAll(dirs.SnapRunNsDir, 0755), IsNil)
+	err := ioutil.WriteFile(filepath.Join(dirs.SnapRunNsDir, "snap.snap-name.fstab"), []byte(synthEntry.String()+"\n"+fsEntry1.String()+"\n"+staleEntry.String()+"\n"), 0644)
+	c.Assert(err, IsNil)
+	err = ioutil.WriteFile(filepath.Join(dirs.SnapRunNsDir, "snap.snap-name.1000.user-fstab"), []byte(userFsEntry.String()+"\n"), 0644)
+	c.Assert(err, IsNil)
+	err = ioutil.WriteFile(filepath.Join(dirs.SnapRunNsDir, "snap.snap-name.1001.user-fstab"), nil, 0644)
+	c.Assert(err, IsNil)
+	err = ioutil.WriteFile(filepath.Join(dirs.SnapRunNsDir, "snap.snap-name.2000.user-fstab"), []byte(userFsEntry.String()+"\n"), 0644)
+	c.Assert(err, IsNil)
+
+	audit, err := s.Backend.(*mount.Backend).Audit(snapInfo, s.Repo)
+	c.Assert(err, IsNil)
+	c.Check(audit.Mounts, DeepEquals, []*mount.AuditEntry{
+		{Entry: fsEntry2, Origin: "iface2"},
+		{Entry: fsEntry1, Origin: "iface", Applied: true},
+		{Entry: synthEntry, Origin: "synthetic", Applied: true},
+		{Entry: staleEntry, Origin: "stale", Applied: true},
+	})
+	c.Check(audit.UserMounts, DeepEquals, []*mount.AuditEntry{
+		{Entry: userFsEntry, Origin: "iface", AppliedFor: []int{1000, 2000}},
+	})
+}
+
 func (s *backendSuite) TestSetupSetsupWithoutDir(c *C) {
 	s.Iface.MountPermanentPlugCallback = func(spec *mount.Specification, plug *snap.PlugInfo) error {
 		return spec.AddMountEntry(osutil.MountEntry{})
@@ -45,17 +45,30 @@ type Specification struct {
 	general  []osutil.MountEntry
 	user     []osutil.MountEntry
 	overname []osutil.MountEntry
+
+	// origin is the name of the interface whose snippets are being
+	// added, generalOrigins and userOrigins record it for each of
+	// the general and user entries, for debugging.
+	origin         string
+	generalOrigins []string
+	userOrigins    []string
+
+	// environment holds the variables exported to the apps of the
+	// snap, they describe where connected content can be found.
+	environment map[string]string
 }
 
 // AddMountEntry adds a new mount entry.
 func (spec *Specification) AddMountEntry(e osutil.MountEntry) error {
 	spec.general = append(spec.general, e)
+	spec.generalOrigins = append(spec.generalOrigins, spec.origin)
 	return nil
 }
 
 //AddUserMountEntry adds a new user mount entry.
 func (spec *Specification) AddUserMountEntry(e osutil.MountEntry) error {
 	spec.user = append(spec.user, e)
+	spec.userOrigins = append(spec.userOrigins, spec.origin)
 	return nil
 }
 
@@ -65,6 +78,32 @@ func (spec *Specification) AddOvernameMountEntry(e osutil.MountEntry) error {
 	return nil
 }
 
+// AddEnvironment adds an environment variable exported to the apps of the
+// snap. The first value set for a given variable is kept.
+func (spec *Specification) AddEnvironment(name, value string) {
+	if spec.environment == nil {
+		spec.environment = make(map[string]string)
+	}
+	if old, ok := spec.environment[name]; ok {
+		if old != value {
+			logger.Noticef("cannot set %s to %q, already set to %q", name, value, old)
+		}
+		return
+	}
+	spec.environment[name] = value
+}
+
+// Environment returns the added environment variables, as sorted
+// "name=value" entries.
+func (spec *Specification) Environment() []string {
+	result := make([]string, 0, len(spec.environment))
+	for name, value := range spec.environment {
+		result = append(result, name+"="+value)
+	}
+	sort.Strings(result)
+	return result
+}
+
 func mountEntryFromLayout(layout *snap.Layout) osutil.MountEntry {
 	var entry osutil.MountEntry
 
@@ -93,28 +132,45 @@ func mountEntryFromLayout(layout *snap.Layout) osutil.MountEntry {
 		entry.Options = []string{osutil.XSnapdKindSymlink(), osutil.XSnapdSymlink(oldname)}
 	}
 
+	// The ownership and mode of a tmpfs are set with its own mount
+	// options.
+	isTmpfs := layout.Type == "tmpfs"
+
 	var uid uint32
-	// Only root is allowed here until we support custom users. Root is default.
+	// Only root and daemon are allowed here until we support custom users. Root is default.
 	switch layout.User {
 	case "root", "":
 		uid = 0
+	case "daemon":
+		uid = 1
 	}
 	if uid != 0 {
+		if isTmpfs {
+			entry.Options = append(entry.Options, fmt.Sprintf("uid=%d", uid))
+		}
 		entry.Options = append(entry.Options, osutil.XSnapdUser(uid))
 	}
 
 	var gid uint32
-	// Only root is allowed here until we support custom groups. Root is default.
-	// This is validated in spec.go.
+	// Only root and daemon are allowed here until we support custom groups. Root is default.
+	// This is validated in snap/validate.go.
 	switch layout.Group {
 	case "root", "":
 		gid = 0
+	case "daemon":
+		gid = 1
 	}
 	if gid != 0 {
+		if isTmpfs {
+			entry.Options = append(entry.Options, fmt.Sprintf("gid=%d", gid))
+		}
 		entry.Options = append(entry.Options, osutil.XSnapdGroup(gid))
 	}
 
 	if layout.Mode != 0755 {
+		if isTmpfs {
+			entry.Options = append(entry.Options, fmt.Sprintf("mode=%#o", uint32(layout.Mode)))
+		}
 		entry.Options = append(entry.Options, osutil.XSnapdMode(uint32(layout.Mode)))
 	}
 
@@ -161,6 +217,29 @@ func (spec *Specification) UserMountEntries() []osutil.MountEntry {
 	return result
 }
 
+// MountEntryOrigins returns where each of the entries returned by
+// MountEntries comes from: "overname", "layout" or the name of the
+// interface that added it.
+func (spec *Specification) MountEntryOrigins() []string {
+	result := make([]string, 0, len(spec.overname)+len(spec.layout)+len(spec.general))
+	for range spec.overname {
+		result = append(result, "overname")
+	}
+	for range spec.layout {
+		result = append(result, "layout")
+	}
+	result = append(result, spec.generalOrigins...)
+	return result
+}
+
+// UserMountEntryOrigins returns the name of the interface that added
+// each of the entries returned by UserMountEntries.
+func (spec *Specification) UserMountEntryOrigins() []string {
+	result := make([]string, len(spec.userOrigins))
+	copy(result, spec.userOrigins)
+	return result
+}
+
 // unclashMountEntries renames mount points if they clash with other entries.
 //
 // Subsequent entries get suffixed with -2, -3, etc.
@@ -185,6 +264,8 @@ func (spec *Specification) AddConnectedPlug(iface interfaces.Interface, plug *in
 	type definer interface {
 		MountConnectedPlug(spec *Specification, plug *interfaces.ConnectedPlug, slot *interfaces.ConnectedSlot) error
 	}
+	spec.origin = iface.Name()
+	defer func() { spec.origin = "" }()
 	if iface, ok := iface.(definer); ok {
 		return iface.MountConnectedPlug(spec, plug, slot)
 	}
@@ -196,6 +277,8 @@ func (spec *Specification) AddConnectedSlot(iface interfaces.Interface, plug *in
 	type definer interface {
 		MountConnectedSlot(spec *Specification, plug *interfaces.ConnectedPlug, slot *interfaces.ConnectedSlot) error
 	}
+	spec.origin = iface.Name()
+	defer func() { spec.origin = "" }()
 	if iface, ok := iface.(definer); ok {
 		return iface.MountConnectedSlot(spec, plug, slot)
 	}
@@ -207,6 +290,8 @@ func (spec *Specification) AddPermanentPlug(iface interfaces.Interface, plug *sn
 	type definer interface {
 		MountPermanentPlug(spec *Specification, plug *snap.PlugInfo) error
 	}
+	spec.origin = iface.Name()
+	defer func() { spec.origin = "" }()
 	if iface, ok := iface.(definer); ok {
 		return iface.MountPermanentPlug(spec, plug)
 	}
@@ -218,6 +303,8 @@ func (spec *Specification) AddPermanentSlot(iface interfaces.Interface, slot *sn
 	type definer interface {
 		MountPermanentSlot(spec *Specification, slot *snap.SlotInfo) error
 	}
+	spec.origin = iface.Name()
+	defer func() { spec.origin = "" }()
 	if iface, ok := iface.(definer); ok {
 		return iface.MountPermanentSlot(spec, slot)
 	}
@@ -97,6 +97,19 @@ func (s *specSuite) TestSmoke(c *C) {
 }
 
 // Added entries can clash and are automatically renamed by MountEntries
+func (s *specSuite) TestEnvironment(c *C) {
+	c.Check(s.spec.Environment(), HasLen, 0)
+
+	s.spec.AddEnvironment("SNAP_CONTENT_B_PATH", "/snap/foo/1/b")
+	s.spec.AddEnvironment("SNAP_CONTENT_A_PATH", "/snap/foo/1/a")
+	// the first value is kept
+	s.spec.AddEnvironment("SNAP_CONTENT_A_PATH", "/snap/foo/1/other")
+	c.Check(s.spec.Environment(), DeepEquals, []string{
+		"SNAP_CONTENT_A_PATH=/snap/foo/1/a",
+		"SNAP_CONTENT_B_PATH=/snap/foo/1/b",
+	})
+}
+
 func (s *specSuite) TestMountEntriesDeclash(c *C) {
 	buf, restore := logger.MockLogger()
 	defer restore()
@@ -137,6 +150,26 @@ func (s *specSuite) TestSpecificationIface(c *C) {
 		{Dir: "dir-d", Name: "permanent-slot"}})
 }
 
+func (s *specSuite) TestMountEntryOrigins(c *C) {
+	var r interfaces.Specification = s.spec
+	c.Assert(r.AddConnectedPlug(s.iface, s.plug, s.slot), IsNil)
+	// entries added directly have no origin
+	c.Assert(s.spec.AddMountEntry(osutil.MountEntry{Dir: "dir-e", Name: "direct"}), IsNil)
+	c.Assert(s.spec.AddUserMountEntry(osutil.MountEntry{Dir: "dir-f", Name: "direct"}), IsNil)
+	snapInfo := snaptest.MockInfo(c, snapWithLayout, &snap.SideInfo{Revision: snap.R(42)})
+	snapInfo.InstanceKey = "instance"
+	s.spec.AddLayout(snapInfo)
+	s.spec.AddOvername(snapInfo)
+
+	c.Check(s.spec.MountEntries(), HasLen, 8)
+	c.Check(s.spec.MountEntryOrigins(), DeepEquals, []string{
+		"overname", "overname",
+		"layout", "layout", "layout", "layout",
+		"test", "",
+	})
+	c.Check(s.spec.UserMountEntryOrigins(), DeepEquals, []string{""})
+}
+
 const snapWithLayout = `
 name: vanguard
 version: 0
@@ -159,7 +192,7 @@ func (s *specSuite) TestMountEntryFromLayout(c *C) {
 		// Layout result is sorted by mount path.
 		{Dir: "/etc/foo.conf", Name: "/snap/vanguard/42/foo.conf", Options: []string{"bind", "rw", "x-snapd.kind=file", "x-snapd.origin=layout"}},
 		{Dir: "/mylink", Options: []string{"x-snapd.kind=symlink", "x-snapd.symlink=/snap/vanguard/42/link/target", "x-snapd.origin=layout"}},
-		{Dir: "/mytmp", Name: "tmpfs", Type: "tmpfs", Options: []string{"x-snapd.mode=01777", "x-snapd.origin=layout"}},
+		{Dir: "/mytmp", Name: "tmpfs", Type: "tmpfs", Options: []string{"mode=01777", "x-snapd.mode=01777", "x-snapd.origin=layout"}},
 		{Dir: "/usr", Name: "/snap/vanguard/42/usr", Options: []string{"rbind", "rw", "x-snapd.origin=layout"}},
 	})
 }
@@ -176,11 +209,34 @@ func (s *specSuite) TestParallelInstanceMountEntryFromLayout(c *C) {
 		// Layout result is sorted by mount path.
 		{Dir: "/etc/foo.conf", Name: "/snap/vanguard/42/foo.conf", Options: []string{"bind", "rw", "x-snapd.kind=file", "x-snapd.origin=layout"}},
 		{Dir: "/mylink", Options: []string{"x-snapd.kind=symlink", "x-snapd.symlink=/snap/vanguard/42/link/target", "x-snapd.origin=layout"}},
-		{Dir: "/mytmp", Name: "tmpfs", Type: "tmpfs", Options: []string{"x-snapd.mode=01777", "x-snapd.origin=layout"}},
+		{Dir: "/mytmp", Name: "tmpfs", Type: "tmpfs", Options: []string{"mode=01777", "x-snapd.mode=01777", "x-snapd.origin=layout"}},
 		{Dir: "/usr", Name: "/snap/vanguard/42/usr", Options: []string{"rbind", "rw", "x-snapd.origin=layout"}},
 	})
 }
 
+const snapWithTmpfsLayout = `
+name: vanguard
+version: 0
+layout:
+  /var/lib/vanguard:
+    type: tmpfs
+    user: daemon
+    group: daemon
+    mode: 0750
+  /var/cache/$SNAP_INSTANCE_NAME:
+    type: tmpfs
+`
+
+func (s *specSuite) TestMountEntryFromTmpfsLayout(c *C) {
+	snapInfo := snaptest.MockInfo(c, snapWithTmpfsLayout, &snap.SideInfo{Revision: snap.R(42)})
+	snapInfo.InstanceKey = "instance"
+	s.spec.AddLayout(snapInfo)
+	c.Assert(s.spec.MountEntries(), DeepEquals, []osutil.MountEntry{
+		{Dir: "/var/cache/vanguard_instance", Name: "tmpfs", Type: "tmpfs", Options: []string{"x-snapd.origin=layout"}},
+		{Dir: "/var/lib/vanguard", Name: "tmpfs", Type: "tmpfs", Options: []string{"uid=1", "x-snapd.user=1", "gid=1", "x-snapd.group=1", "mode=0750", "x-snapd.mode=0750", "x-snapd.origin=layout"}},
+	})
+}
+
 func (s *specSuite) TestSpecificationUberclash(c *C) {
 	// When everything clashes for access to /foo, what happens?
 	const uberclashYaml = `name: uberclash
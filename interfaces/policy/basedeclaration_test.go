@@ -152,8 +152,10 @@ func (s *baseDeclSuite) TestAutoConnection(c *C) {
 	// these simply auto-connect, anything else doesn't
 	autoconnect := map[string]bool{
 		"browser-support":         true,
+		"connectivity-check":      true,
 		"desktop":                 true,
 		"desktop-legacy":          true,
+		"dns-resolution":          true,
 		"gsettings":               true,
 		"media-hub":               true,
 		"mir":                     true,
@@ -568,8 +570,10 @@ var (
 		"browser-support":         {"core"},
 		"content":                 {"app", "gadget"},
 		"core-support":            {"core"},
+		"custom-device":             {"gadget"},
 		"dbus":                    {"app"},
 		"docker-support":          {"core"},
+		"evdev-write":               {"core", "gadget"},
 		"fwupd":                   {"app"},
 		"gpio":                    {"core", "gadget"},
 		"greengrass-support":      {"core"},
@@ -589,6 +593,7 @@ var (
 		"network-manager-observe": {"app", "core"},
 		"network-status":          {"app"},
 		"ofono":                   {"app", "core"},
+		"opengl":                  {"core", "gadget"},
 		"online-accounts-service": {"app"},
 		"ppp":         {"core"},
 		"pulseaudio":  {"app", "core"},
@@ -682,6 +687,7 @@ func (s *baseDeclSuite) TestPlugInstallation(c *C) {
 		"classic-support":       true,
 		"docker-support":        true,
 		"greengrass-support":    true,
+		"host-files-read":       true,
 		"kernel-module-control": true,
 		"kubernetes-support":    true,
 		"lxd-support":           true,
@@ -729,6 +735,7 @@ func (s *baseDeclSuite) TestConnection(c *C) {
 	// case-by-case basis
 	noconnect := map[string]bool{
 		"content":                   true,
+		"custom-device":             true,
 		"docker":                    true,
 		"fwupd":                     true,
 		"location-control":          true,
@@ -811,6 +818,7 @@ func (s *baseDeclSuite) TestSanity(c *C) {
 		"core-support":          true,
 		"docker-support":        true,
 		"greengrass-support":    true,
+		"host-files-read":       true,
 		"kernel-module-control": true,
 		"kubernetes-support":    true,
 		"lxd-support":           true,
@@ -899,6 +907,48 @@ plugs:
 	c.Check(err, NotNil)
 }
 
+func (s *baseDeclSuite) TestConnectionCustomDevice(c *C) {
+	// we let connect explicitly as long as custom-device matches
+
+	cand := s.connectCand(c, "stuff", `
+name: slot-snap
+type: gadget
+version: 0
+slots:
+  stuff:
+    interface: custom-device
+    custom-device: board-leds
+`, `
+name: plug-snap
+version: 0
+plugs:
+  stuff:
+    interface: custom-device
+    custom-device: board-leds
+`)
+	err := cand.Check()
+	c.Check(err, IsNil)
+
+	cand = s.connectCand(c, "stuff", `
+name: slot-snap
+type: gadget
+version: 0
+slots:
+  stuff:
+    interface: custom-device
+    custom-device: board-leds
+`, `
+name: plug-snap
+version: 0
+plugs:
+  stuff:
+    interface: custom-device
+    custom-device: board-buttons
+`)
+	err = cand.Check()
+	c.Check(err, NotNil)
+}
+
 func (s *baseDeclSuite) TestComposeBaseDeclaration(c *C) {
 	decl, err := policy.ComposeBaseDeclaration(nil)
 	c.Assert(err, IsNil)
@@ -932,6 +982,21 @@ plugs:
 	c.Check(err, NotNil)
 }
 
+func (s *baseDeclSuite) TestDNSResolutionCustomDNS(c *C) {
+	const plugYaml = `name: plug-snap
+version: 0
+plugs:
+  dns-resolution:
+   custom-dns: true
+`
+	cand := s.connectCand(c, "dns-resolution", "", plugYaml)
+	err := cand.Check()
+	c.Check(err, NotNil)
+
+	err = cand.CheckAutoConnect()
+	c.Check(err, NotNil)
+}
+
 func (s *baseDeclSuite) TestOpticalDriveWrite(c *C) {
 	type options struct {
 		readonlyYamls []string
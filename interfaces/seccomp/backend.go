@@ -225,6 +225,25 @@ func (b *Backend) Setup(snapInfo *snap.Info, opts interfaces.ConfinementOptions,
 	return nil
 }
 
+// ProfilesDryRun returns the seccomp profile sources that Setup would write
+// for the given snap, without writing or compiling them.
+func (b *Backend) ProfilesDryRun(snapInfo *snap.Info, opts interfaces.ConfinementOptions, repo *interfaces.Repository) (map[string]string, error) {
+	snapName := snapInfo.InstanceName()
+	spec, err := repo.SnapSpecification(b.Name(), snapName)
+	if err != nil {
+		return nil, fmt.Errorf("cannot obtain seccomp specification for snap %q: %s", snapName, err)
+	}
+	content, err := b.deriveContent(spec.(*Specification), opts, snapInfo)
+	if err != nil {
+		return nil, fmt.Errorf("cannot obtain expected security files for snap %q: %s", snapName, err)
+	}
+	profiles := make(map[string]string, len(content))
+	for name, fileState := range content {
+		profiles[filepath.Join(dirs.SnapSeccompDir, name)] = string(fileState.Content)
+	}
+	return profiles, nil
+}
+
 // Remove removes seccomp profiles of a given snap.
 func (b *Backend) Remove(snapName string) error {
 	glob := interfaces.SecurityTagGlob(snapName)
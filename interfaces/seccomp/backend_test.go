@@ -183,6 +183,26 @@ fi`)
 `)), Equals, true)
 }
 
+func (s *backendSuite) TestProfilesDryRun(c *C) {
+	snapInfo := s.InstallSnap(c, interfaces.ConfinementOptions{}, "", ifacetest.SambaYamlV1, 0)
+	profile := filepath.Join(dirs.SnapSeccompDir, "snap.samba.smbd.src")
+	s.snapSeccomp.ForgetCalls()
+
+	profiles, err := s.Backend.(interfaces.SecurityBackendDryRun).ProfilesDryRun(snapInfo, interfaces.ConfinementOptions{}, s.Repo)
+	c.Assert(err, IsNil)
+	c.Check(profiles, HasLen, 1)
+	// the profile is the source written by Setup
+	c.Check(profile, testutil.FileEquals, profiles[profile])
+
+	// and nothing is written or compiled
+	c.Assert(os.Remove(profile), IsNil)
+	profiles, err = s.Backend.(interfaces.SecurityBackendDryRun).ProfilesDryRun(snapInfo, interfaces.ConfinementOptions{DevMode: true}, s.Repo)
+	c.Assert(err, IsNil)
+	c.Check(profiles[profile], testutil.Contains, "@complain\n")
+	c.Check(profile, testutil.FileAbsent)
+	c.Check(s.snapSeccomp.Calls(), HasLen, 0)
+}
+
 func (s *backendSuite) TestRemovingSnapRemovesProfiles(c *C) {
 	for _, opts := range testedConfinementOpts {
 		snapInfo := s.InstallSnap(c, opts, "", ifacetest.SambaYamlV1, 0)
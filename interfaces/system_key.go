@@ -200,12 +200,19 @@ func WriteSystemKey() error {
 // snap run only has to obtain the mtime of apparmor_parser and
 // doesn't have to invoke it)
 func SystemKeyMismatch() (bool, error) {
+	return SystemKeyFileMismatch(dirs.SnapSystemKeyFile)
+}
+
+// SystemKeyFileMismatch is like SystemKeyMismatch but compares with
+// the system-key stored in the given file, e.g. one recorded when
+// preseeding an image.
+func SystemKeyFileMismatch(fn string) (bool, error) {
 	mySystemKey, err := generateSystemKey()
 	if err != nil {
 		return false, err
 	}
 
-	raw, err := ioutil.ReadFile(dirs.SnapSystemKeyFile)
+	raw, err := ioutil.ReadFile(fn)
 	if err != nil && os.IsNotExist(err) {
 		return false, ErrSystemKeyMissing
 	}
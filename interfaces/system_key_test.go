@@ -175,6 +175,34 @@ func (s *systemKeySuite) TestInterfaceSystemKeyMismatchHappy(c *C) {
 	c.Check(mismatch, Equals, true)
 }
 
+func (s *systemKeySuite) TestInterfaceSystemKeyFileMismatch(c *C) {
+	s.AddCleanup(interfaces.MockSystemKey(`
+{
+"build-id": "7a94e9736c091b3984bd63f5aebfc883c4d859e0",
+"apparmor-features": ["caps", "dbus"]
+}
+`))
+
+	fn := filepath.Join(c.MkDir(), "system-key")
+	_, err := interfaces.SystemKeyFileMismatch(fn)
+	c.Assert(err, Equals, interfaces.ErrSystemKeyMissing)
+
+	err = ioutil.WriteFile(fn, []byte(`{"build-id": "7a94e9736c091b3984bd63f5aebfc883c4d859e0", "apparmor-features": ["caps", "dbus"]}`), 0644)
+	c.Assert(err, IsNil)
+	mismatch, err := interfaces.SystemKeyFileMismatch(fn)
+	c.Assert(err, IsNil)
+	c.Check(mismatch, Equals, false)
+
+	err = ioutil.WriteFile(fn, []byte(`{"build-id": "7a94e9736c091b3984bd63f5aebfc883c4d859e0", "apparmor-features": ["caps"]}`), 0644)
+	c.Assert(err, IsNil)
+	mismatch, err = interfaces.SystemKeyFileMismatch(fn)
+	c.Assert(err, IsNil)
+	c.Check(mismatch, Equals, true)
+
+	// the system-key on disk is not involved
+	c.Check(osutil.FileExists(dirs.SnapSystemKeyFile), Equals, false)
+}
+
 func (s *systemKeySuite) TestInterfaceSystemKeyMismatchParserMtimeHappy(c *C) {
 	s.AddCleanup(interfaces.MockSystemKey(`
 {
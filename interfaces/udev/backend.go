@@ -27,6 +27,7 @@ package udev
 import (
 	"bytes"
 	"fmt"
+	"io/ioutil"
 	"os"
 	"path/filepath"
 	"strings"
@@ -69,7 +70,7 @@ func (b *Backend) Setup(snapInfo *snap.Info, opts interfaces.ConfinementOptions,
 	if err != nil {
 		return fmt.Errorf("cannot obtain udev specification for snap %q: %s", snapName, err)
 	}
-	content := b.deriveContent(spec.(*Specification), snapInfo)
+	content := b.rulesContent(spec.(*Specification), snapInfo, opts)
 	subsystemTriggers := spec.(*Specification).TriggeredSubsystems()
 
 	dir := dirs.SnapUdevRulesDir
@@ -78,38 +79,25 @@ func (b *Backend) Setup(snapInfo *snap.Info, opts interfaces.ConfinementOptions,
 	}
 
 	rulesFilePath := snapRulesFilePath(snapInfo.InstanceName())
+	oldContent, err := ioutil.ReadFile(rulesFilePath)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
 
-	if len(content) == 0 {
+	if content == nil {
 		// Make sure that the rules file gets removed when we don't have any
 		// content and exists.
 		err = os.Remove(rulesFilePath)
 		if err != nil && !os.IsNotExist(err) {
 			return err
 		} else if err == nil {
-			// FIXME: somehow detect the interfaces that were
-			// disconnected and set subsystemTriggers appropriately.
-			// ATM, it is always going to be empty on disconnect.
-			return ReloadRules(subsystemTriggers)
+			return reloadChangedRules(oldContent, nil, subsystemTriggers)
 		}
 		return nil
 	}
 
-	var buffer bytes.Buffer
-	buffer.WriteString("# This file is automatically generated.\n")
-	if (opts.DevMode || opts.Classic) && !opts.JailMode {
-		buffer.WriteString("# udev tagging/device cgroups disabled with non-strict mode snaps\n")
-	}
-	for _, snippet := range content {
-		if (opts.DevMode || opts.Classic) && !opts.JailMode {
-			buffer.WriteRune('#')
-			snippet = strings.Replace(snippet, "\n", "\n#", -1)
-		}
-		buffer.WriteString(snippet)
-		buffer.WriteByte('\n')
-	}
-
 	rulesFileState := &osutil.FileState{
-		Content: buffer.Bytes(),
+		Content: content,
 		Mode:    0644,
 	}
 
@@ -123,10 +111,7 @@ func (b *Backend) Setup(snapInfo *snap.Info, opts interfaces.ConfinementOptions,
 		return err
 	}
 
-	// FIXME: somehow detect the interfaces that were disconnected and set
-	// subsystemTriggers appropriately. ATM, it is always going to be empty
-	// on disconnect.
-	return ReloadRules(subsystemTriggers)
+	return reloadChangedRules(oldContent, content, subsystemTriggers)
 }
 
 // Remove removes udev rules specific to a given snap.
@@ -137,18 +122,68 @@ func (b *Backend) Setup(snapInfo *snap.Info, opts interfaces.ConfinementOptions,
 // If the method fails it should be re-tried (with a sensible strategy) by the caller.
 func (b *Backend) Remove(snapName string) error {
 	rulesFilePath := snapRulesFilePath(snapName)
-	err := os.Remove(rulesFilePath)
+	oldContent, err := ioutil.ReadFile(rulesFilePath)
 	if os.IsNotExist(err) {
 		// If file doesn't exist we avoid reloading the udev rules when we return here
 		return nil
 	} else if err != nil {
 		return err
 	}
+	if err := os.Remove(rulesFilePath); err != nil {
+		return err
+	}
+
+	return reloadChangedRules(oldContent, nil, nil)
+}
+
+// reloadChangedRules reloads the udev rules after they changed from
+// oldContent to newContent, triggering only the devices affected by
+// the change when they can be determined.
+func reloadChangedRules(oldContent, newContent []byte, subsystemTriggers []string) error {
+	subsystems, all := ChangedSubsystems(oldContent, newContent)
+	if all {
+		return ReloadRules(subsystemTriggers)
+	}
+	return ReloadRulesForSubsystems(subsystems, subsystemTriggers)
+}
+
+// ProfilesDryRun returns the udev rules that Setup would write for the
+// given snap, without writing them or reloading udev.
+func (b *Backend) ProfilesDryRun(snapInfo *snap.Info, opts interfaces.ConfinementOptions, repo *interfaces.Repository) (map[string]string, error) {
+	snapName := snapInfo.InstanceName()
+	spec, err := repo.SnapSpecification(b.Name(), snapName)
+	if err != nil {
+		return nil, fmt.Errorf("cannot obtain udev specification for snap %q: %s", snapName, err)
+	}
+	profiles := make(map[string]string, 1)
+	if content := b.rulesContent(spec.(*Specification), snapInfo, opts); content != nil {
+		profiles[snapRulesFilePath(snapName)] = string(content)
+	}
+	return profiles, nil
+}
+
+// rulesContent returns the content of the udev rules file of the given
+// snap, or nil if the snap needs no rules.
+func (b *Backend) rulesContent(spec *Specification, snapInfo *snap.Info, opts interfaces.ConfinementOptions) []byte {
+	content := b.deriveContent(spec, snapInfo)
+	if len(content) == 0 {
+		return nil
+	}
 
-	// FIXME: somehow detect the interfaces that were disconnected and set
-	// subsystemTriggers appropriately. ATM, it is always going to be empty
-	// on disconnect.
-	return ReloadRules(nil)
+	var buffer bytes.Buffer
+	buffer.WriteString("# This file is automatically generated.\n")
+	if (opts.DevMode || opts.Classic) && !opts.JailMode {
+		buffer.WriteString("# udev tagging/device cgroups disabled with non-strict mode snaps\n")
+	}
+	for _, snippet := range content {
+		if (opts.DevMode || opts.Classic) && !opts.JailMode {
+			buffer.WriteRune('#')
+			snippet = strings.Replace(snippet, "\n", "\n#", -1)
+		}
+		buffer.WriteString(snippet)
+		buffer.WriteByte('\n')
+	}
+	return buffer.Bytes()
 }
 
 func (b *Backend) deriveContent(spec *Specification, snapInfo *snap.Info) (content []string) {
@@ -76,6 +76,26 @@ func (s *backendSuite) SetUpTest(c *C) {
 	s.meas = perf.StartSpan("", "")
 }
 
+// checkRulesReloaded checks that udevadm was used to reload the rules
+// and, unless they are commented out as for non-strict snaps and so
+// affect no device, to re-run all the triggers.
+func (s *backendSuite) checkRulesReloaded(c *C, opts interfaces.ConfinementOptions) {
+	if (opts.DevMode || opts.Classic) && !opts.JailMode {
+		c.Check(s.udevadmCmd.Calls(), DeepEquals, [][]string{
+			{"udevadm", "control", "--reload-rules"},
+		})
+		return
+	}
+	c.Check(s.udevadmCmd.Calls(), DeepEquals, [][]string{
+		{"udevadm", "control", "--reload-rules"},
+		{"udevadm", "trigger", "--subsystem-nomatch=input"},
+		// FIXME: temporary until spec.TriggerSubsystem() can
+		// be called during disconnect
+		{"udevadm", "trigger", "--property-match=ID_INPUT_JOYSTICK=1"},
+		{"udevadm", "settle", "--timeout=10"},
+	})
+}
+
 func (s *backendSuite) TearDownTest(c *C) {
 	s.udevadmCmd.Restore()
 
@@ -101,14 +121,7 @@ func (s *backendSuite) TestInstallingSnapWritesAndLoadsRules(c *C) {
 		_, err := os.Stat(fname)
 		c.Check(err, IsNil)
 		// udevadm was used to reload rules and re-run triggers
-		c.Check(s.udevadmCmd.Calls(), DeepEquals, [][]string{
-			{"udevadm", "control", "--reload-rules"},
-			{"udevadm", "trigger", "--subsystem-nomatch=input"},
-			// FIXME: temporary until spec.TriggerSubsystem() can
-			// be called during disconnect
-			{"udevadm", "trigger", "--property-match=ID_INPUT_JOYSTICK=1"},
-			{"udevadm", "settle", "--timeout=10"},
-		})
+		s.checkRulesReloaded(c, opts)
 		s.RemoveSnap(c, snapInfo)
 	}
 }
@@ -133,18 +146,34 @@ func (s *backendSuite) TestInstallingSnapWithHookWritesAndLoadsRules(c *C) {
 		c.Check(err, IsNil)
 
 		// Verify that udevadm was used to reload rules and re-run triggers.
-		c.Check(s.udevadmCmd.Calls(), DeepEquals, [][]string{
-			{"udevadm", "control", "--reload-rules"},
-			{"udevadm", "trigger", "--subsystem-nomatch=input"},
-			// FIXME: temporary until spec.TriggerSubsystem() can
-			// be called during disconnect
-			{"udevadm", "trigger", "--property-match=ID_INPUT_JOYSTICK=1"},
-			{"udevadm", "settle", "--timeout=10"},
-		})
+		s.checkRulesReloaded(c, opts)
 		s.RemoveSnap(c, snapInfo)
 	}
 }
 
+func (s *backendSuite) TestInstallingSnapTriggersOnlyChangedSubsystems(c *C) {
+	s.Iface.UDevPermanentSlotCallback = func(spec *udev.Specification, slot *snap.SlotInfo) error {
+		spec.AddSnippet(`SUBSYSTEM=="tty", KERNEL=="ttyS0"`)
+		return nil
+	}
+	opts := interfaces.ConfinementOptions{}
+	snapInfo := s.InstallSnap(c, opts, "", ifacetest.SambaYamlV1, 0)
+	// only the devices of the subsystem of the new rules were triggered
+	c.Check(s.udevadmCmd.Calls(), DeepEquals, [][]string{
+		{"udevadm", "control", "--reload-rules"},
+		{"udevadm", "trigger", "--subsystem-match=tty"},
+		{"udevadm", "settle", "--timeout=10"},
+	})
+
+	s.udevadmCmd.ForgetCalls()
+	s.RemoveSnap(c, snapInfo)
+	c.Check(s.udevadmCmd.Calls(), DeepEquals, [][]string{
+		{"udevadm", "control", "--reload-rules"},
+		{"udevadm", "trigger", "--subsystem-match=tty"},
+		{"udevadm", "settle", "--timeout=10"},
+	})
+}
+
 func (s *backendSuite) TestSecurityIsStable(c *C) {
 	// NOTE: Hand out a permanent snippet so that .rules file is generated.
 	s.Iface.UDevPermanentSlotCallback = func(spec *udev.Specification, slot *snap.SlotInfo) error {
@@ -162,6 +191,40 @@ func (s *backendSuite) TestSecurityIsStable(c *C) {
 	}
 }
 
+func (s *backendSuite) TestProfilesDryRun(c *C) {
+	s.Iface.UDevPermanentSlotCallback = func(spec *udev.Specification, slot *snap.SlotInfo) error {
+		spec.AddSnippet("dummy")
+		return nil
+	}
+	snapInfo := s.InstallSnap(c, interfaces.ConfinementOptions{}, "", ifacetest.SambaYamlV1, 0)
+	fname := filepath.Join(dirs.SnapUdevRulesDir, "70-snap.samba.rules")
+	s.udevadmCmd.ForgetCalls()
+
+	profiles, err := s.Backend.(interfaces.SecurityBackendDryRun).ProfilesDryRun(snapInfo, interfaces.ConfinementOptions{}, s.Repo)
+	c.Assert(err, IsNil)
+	c.Check(profiles, DeepEquals, map[string]string{
+		fname: "# This file is automatically generated.\ndummy\n",
+	})
+	c.Check(fname, testutil.FileEquals, profiles[fname])
+
+	// nothing is written and udev is not reloaded
+	c.Assert(os.Remove(fname), IsNil)
+	profiles, err = s.Backend.(interfaces.SecurityBackendDryRun).ProfilesDryRun(snapInfo, interfaces.ConfinementOptions{DevMode: true}, s.Repo)
+	c.Assert(err, IsNil)
+	c.Check(profiles, DeepEquals, map[string]string{
+		fname: "# This file is automatically generated.\n# udev tagging/device cgroups disabled with non-strict mode snaps\n#dummy\n",
+	})
+	c.Check(fname, testutil.FileAbsent)
+	c.Check(s.udevadmCmd.Calls(), HasLen, 0)
+}
+
+func (s *backendSuite) TestProfilesDryRunNoRules(c *C) {
+	snapInfo := s.InstallSnap(c, interfaces.ConfinementOptions{}, "", ifacetest.SambaYamlV1, 0)
+	profiles, err := s.Backend.(interfaces.SecurityBackendDryRun).ProfilesDryRun(snapInfo, interfaces.ConfinementOptions{}, s.Repo)
+	c.Assert(err, IsNil)
+	c.Check(profiles, HasLen, 0)
+}
+
 func (s *backendSuite) TestRemovingSnapRemovesAndReloadsRules(c *C) {
 	// NOTE: Hand out a permanent snippet so that .rules file is generated.
 	s.Iface.UDevPermanentSlotCallback = func(spec *udev.Specification, slot *snap.SlotInfo) error {
@@ -177,14 +240,7 @@ func (s *backendSuite) TestRemovingSnapRemovesAndReloadsRules(c *C) {
 		_, err := os.Stat(fname)
 		c.Check(os.IsNotExist(err), Equals, true)
 		// udevadm was used to reload rules and re-run triggers
-		c.Check(s.udevadmCmd.Calls(), DeepEquals, [][]string{
-			{"udevadm", "control", "--reload-rules"},
-			{"udevadm", "trigger", "--subsystem-nomatch=input"},
-			// FIXME: temporary until spec.TriggerSubsystem() can
-			// be called during disconnect
-			{"udevadm", "trigger", "--property-match=ID_INPUT_JOYSTICK=1"},
-			{"udevadm", "settle", "--timeout=10"},
-		})
+		s.checkRulesReloaded(c, opts)
 	}
 }
 
@@ -203,14 +259,7 @@ func (s *backendSuite) TestUpdatingSnapToOneWithMoreApps(c *C) {
 		_, err := os.Stat(fname)
 		c.Check(err, IsNil)
 		// udevadm was used to reload rules and re-run triggers
-		c.Check(s.udevadmCmd.Calls(), DeepEquals, [][]string{
-			{"udevadm", "control", "--reload-rules"},
-			{"udevadm", "trigger", "--subsystem-nomatch=input"},
-			// FIXME: temporary until spec.TriggerSubsystem() can
-			// be called during disconnect
-			{"udevadm", "trigger", "--property-match=ID_INPUT_JOYSTICK=1"},
-			{"udevadm", "settle", "--timeout=10"},
-		})
+		s.checkRulesReloaded(c, opts)
 		s.RemoveSnap(c, snapInfo)
 	}
 }
@@ -236,14 +285,7 @@ func (s *backendSuite) TestUpdatingSnapToOneWithMoreHooks(c *C) {
 		c.Check(err, IsNil)
 
 		// Verify that udevadm was used to reload rules and re-run triggers
-		c.Check(s.udevadmCmd.Calls(), DeepEquals, [][]string{
-			{"udevadm", "control", "--reload-rules"},
-			{"udevadm", "trigger", "--subsystem-nomatch=input"},
-			// FIXME: temporary until spec.TriggerSubsystem() can
-			// be called during disconnect
-			{"udevadm", "trigger", "--property-match=ID_INPUT_JOYSTICK=1"},
-			{"udevadm", "settle", "--timeout=10"},
-		})
+		s.checkRulesReloaded(c, opts)
 		s.RemoveSnap(c, snapInfo)
 	}
 }
@@ -263,14 +305,7 @@ func (s *backendSuite) TestUpdatingSnapToOneWithFewerApps(c *C) {
 		_, err := os.Stat(fname)
 		c.Check(err, IsNil)
 		// udevadm was used to reload rules and re-run triggers
-		c.Check(s.udevadmCmd.Calls(), DeepEquals, [][]string{
-			{"udevadm", "control", "--reload-rules"},
-			{"udevadm", "trigger", "--subsystem-nomatch=input"},
-			// FIXME: temporary until spec.TriggerSubsystem() can
-			// be called during disconnect
-			{"udevadm", "trigger", "--property-match=ID_INPUT_JOYSTICK=1"},
-			{"udevadm", "settle", "--timeout=10"},
-		})
+		s.checkRulesReloaded(c, opts)
 		s.RemoveSnap(c, snapInfo)
 	}
 }
@@ -294,14 +329,7 @@ func (s *backendSuite) TestUpdatingSnapToOneWithFewerHooks(c *C) {
 		_, err := os.Stat(fname)
 		c.Check(err, IsNil)
 		// Verify that udevadm was used to reload rules and re-run triggers
-		c.Check(s.udevadmCmd.Calls(), DeepEquals, [][]string{
-			{"udevadm", "control", "--reload-rules"},
-			{"udevadm", "trigger", "--subsystem-nomatch=input"},
-			// FIXME: temporary until spec.TriggerSubsystem() can
-			// be called during disconnect
-			{"udevadm", "trigger", "--property-match=ID_INPUT_JOYSTICK=1"},
-			{"udevadm", "settle", "--timeout=10"},
-		})
+		s.checkRulesReloaded(c, opts)
 		s.RemoveSnap(c, snapInfo)
 	}
 }
@@ -430,14 +458,7 @@ func (s *backendSuite) TestUpdatingSnapToOneWithoutSlots(c *C) {
 		_, err := os.Stat(fname)
 		c.Check(os.IsNotExist(err), Equals, true)
 		// Verify that udevadm was used to reload rules and re-run triggers
-		c.Check(s.udevadmCmd.Calls(), DeepEquals, [][]string{
-			{"udevadm", "control", "--reload-rules"},
-			{"udevadm", "trigger", "--subsystem-nomatch=input"},
-			// FIXME: temporary until spec.TriggerSubsystem() can
-			// be called during disconnect
-			{"udevadm", "trigger", "--property-match=ID_INPUT_JOYSTICK=1"},
-			{"udevadm", "settle", "--timeout=10"},
-		})
+		s.checkRulesReloaded(c, opts)
 		s.RemoveSnap(c, snapInfo)
 	}
 }
@@ -481,12 +502,22 @@ func (s *backendSuite) TestInstallingSnapWritesAndLoadsRulesWithInputSubsystem(c
 		_, err := os.Stat(fname)
 		c.Check(err, IsNil)
 		// udevadm was used to reload rules and re-run triggers
-		c.Check(s.udevadmCmd.Calls(), DeepEquals, [][]string{
-			{"udevadm", "control", "--reload-rules"},
-			{"udevadm", "trigger", "--subsystem-nomatch=input"},
-			{"udevadm", "trigger", "--subsystem-match=input"},
-			{"udevadm", "settle", "--timeout=10"},
-		})
+		if (opts.DevMode || opts.Classic) && !opts.JailMode {
+			// only the explicitly requested ones as the
+			// rules are commented out
+			c.Check(s.udevadmCmd.Calls(), DeepEquals, [][]string{
+				{"udevadm", "control", "--reload-rules"},
+				{"udevadm", "trigger", "--subsystem-match=input"},
+				{"udevadm", "settle", "--timeout=10"},
+			})
+		} else {
+			c.Check(s.udevadmCmd.Calls(), DeepEquals, [][]string{
+				{"udevadm", "control", "--reload-rules"},
+				{"udevadm", "trigger", "--subsystem-nomatch=input"},
+				{"udevadm", "trigger", "--subsystem-match=input"},
+				{"udevadm", "settle", "--timeout=10"},
+			})
+		}
 		s.RemoveSnap(c, snapInfo)
 	}
 }
@@ -506,12 +537,22 @@ func (s *backendSuite) TestInstallingSnapWritesAndLoadsRulesWithInputJoystickSub
 		_, err := os.Stat(fname)
 		c.Check(err, IsNil)
 		// udevadm was used to reload rules and re-run triggers
-		c.Check(s.udevadmCmd.Calls(), DeepEquals, [][]string{
-			{"udevadm", "control", "--reload-rules"},
-			{"udevadm", "trigger", "--subsystem-nomatch=input"},
-			{"udevadm", "trigger", "--property-match=ID_INPUT_JOYSTICK=1"},
-			{"udevadm", "settle", "--timeout=10"},
-		})
+		if (opts.DevMode || opts.Classic) && !opts.JailMode {
+			// only the explicitly requested ones as the
+			// rules are commented out
+			c.Check(s.udevadmCmd.Calls(), DeepEquals, [][]string{
+				{"udevadm", "control", "--reload-rules"},
+				{"udevadm", "trigger", "--property-match=ID_INPUT_JOYSTICK=1"},
+				{"udevadm", "settle", "--timeout=10"},
+			})
+		} else {
+			c.Check(s.udevadmCmd.Calls(), DeepEquals, [][]string{
+				{"udevadm", "control", "--reload-rules"},
+				{"udevadm", "trigger", "--subsystem-nomatch=input"},
+				{"udevadm", "trigger", "--property-match=ID_INPUT_JOYSTICK=1"},
+				{"udevadm", "settle", "--timeout=10"},
+			})
+		}
 		s.RemoveSnap(c, snapInfo)
 	}
 }
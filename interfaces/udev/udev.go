@@ -22,6 +22,11 @@ package udev
 import (
 	"fmt"
 	"os/exec"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/snapcore/snapd/strutil"
 )
 
 // ReloadRules runs three commands that reload udev rule database.
@@ -46,22 +51,43 @@ func ReloadRules(subsystemTriggers []string) error {
 		return fmt.Errorf("cannot run udev triggers: %s\nudev output:\n%s", err, string(output))
 	}
 
-	// FIXME: track if also should trigger the joystick property if it
+	inputJoystickTriggered, err := triggerSubsystems(subsystemTriggers)
+	if err != nil {
+		return err
+	}
+
+	// FIXME: if not already triggered, trigger the joystick property if it
 	// wasn't already since we are not able to detect interfaces that are
 	// removed and set subsystemTriggers correctly. When we can, remove
 	// this. Allows joysticks to be removed from the device cgroup on
 	// interface disconnect.
-	inputJoystickTriggered := false
+	if !inputJoystickTriggered {
+		output, err = exec.Command("udevadm", "trigger", "--property-match=ID_INPUT_JOYSTICK=1").CombinedOutput()
+		if err != nil {
+			return fmt.Errorf("cannot run udev triggers for joysticks: %s\nudev output:\n%s", err, string(output))
+		}
+	}
+
+	// give our triggered events a chance to be handled before exiting.
+	// Ignore errors since we don't want to error on still pending events.
+	_ = exec.Command("udevadm", "settle", "--timeout=10").Run()
+
+	return nil
+}
 
+// triggerSubsystems triggers the subsystems requested by the
+// interfaces, it returns whether the joystick devices were triggered
+// in the process.
+func triggerSubsystems(subsystemTriggers []string) (inputJoystickTriggered bool, err error) {
 	for _, subsystem := range subsystemTriggers {
 		if subsystem == "input/joystick" {
 			// If one of the interfaces said it uses the input
 			// subsystem for joysticks, then trigger the joystick
 			// events in a way that is specific to joysticks to not
 			// block other inputs.
-			output, err = exec.Command("udevadm", "trigger", "--property-match=ID_INPUT_JOYSTICK=1").CombinedOutput()
+			output, err := exec.Command("udevadm", "trigger", "--property-match=ID_INPUT_JOYSTICK=1").CombinedOutput()
 			if err != nil {
-				return fmt.Errorf("cannot run udev triggers for joysticks: %s\nudev output:\n%s", err, string(output))
+				return false, fmt.Errorf("cannot run udev triggers for joysticks: %s\nudev output:\n%s", err, string(output))
 			}
 			inputJoystickTriggered = true
 		} else if subsystem == "input/key" {
@@ -69,16 +95,16 @@ func ReloadRules(subsystemTriggers []string) error {
 			// subsystem for input keys, then trigger the keys
 			// events in a way that is specific to input keys
 			// to not block other inputs.
-			output, err = exec.Command("udevadm", "trigger", "--property-match=ID_INPUT_KEY=1", "--property-match=ID_INPUT_KEYBOARD!=1").CombinedOutput()
+			output, err := exec.Command("udevadm", "trigger", "--property-match=ID_INPUT_KEY=1", "--property-match=ID_INPUT_KEYBOARD!=1").CombinedOutput()
 			if err != nil {
-				return fmt.Errorf("cannot run udev triggers for keys: %s\nudev output:\n%s", err, string(output))
+				return false, fmt.Errorf("cannot run udev triggers for keys: %s\nudev output:\n%s", err, string(output))
 			}
 		} else if subsystem != "" {
 			// If one of the interfaces said it uses a subsystem,
 			// then do it too.
-			output, err = exec.Command("udevadm", "trigger", "--subsystem-match="+subsystem).CombinedOutput()
+			output, err := exec.Command("udevadm", "trigger", "--subsystem-match="+subsystem).CombinedOutput()
 			if err != nil {
-				return fmt.Errorf("cannot run udev triggers for %s subsystem: %s\nudev output:\n%s", subsystem, err, string(output))
+				return false, fmt.Errorf("cannot run udev triggers for %s subsystem: %s\nudev output:\n%s", subsystem, err, string(output))
 			}
 
 			if subsystem == "input" {
@@ -86,13 +112,47 @@ func ReloadRules(subsystemTriggers []string) error {
 			}
 		}
 	}
+	return inputJoystickTriggered, nil
+}
 
-	// FIXME: if not already triggered, trigger the joystick property if it
-	// wasn't already since we are not able to detect interfaces that are
-	// removed and set subsystemTriggers correctly. When we can, remove
-	// this. Allows joysticks to be removed from the device cgroup on
-	// interface disconnect.
-	if !inputJoystickTriggered {
+// ReloadRulesForSubsystems reloads the udev rule database like
+// ReloadRules but, instead of triggering all devices, only triggers
+// the devices of the given subsystems, typically the ones whose rules
+// changed as reported by ChangedSubsystems, and the subsystems
+// requested by the interfaces. This avoids spurious events for
+// unrelated devices, which can for example reset network devices.
+//
+// Devices of the input subsystem are not triggered wholesale, as in
+// ReloadRules, but only joysticks are, unless requested otherwise by
+// the interfaces.
+func ReloadRulesForSubsystems(subsystems []string, subsystemTriggers []string) error {
+	output, err := exec.Command("udevadm", "control", "--reload-rules").CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("cannot reload udev rules: %s\nudev output:\n%s", err, string(output))
+	}
+
+	inputChanged := false
+	var triggers []string
+	for _, subsystem := range subsystems {
+		if subsystem == "input" {
+			inputChanged = true
+			continue
+		}
+		if !strutil.ListContains(subsystemTriggers, subsystem) {
+			triggers = append(triggers, subsystem)
+		}
+	}
+	triggers = append(triggers, subsystemTriggers...)
+	if len(triggers) == 0 && !inputChanged {
+		// no device is affected
+		return nil
+	}
+
+	inputJoystickTriggered, err := triggerSubsystems(triggers)
+	if err != nil {
+		return err
+	}
+	if inputChanged && !inputJoystickTriggered {
 		output, err = exec.Command("udevadm", "trigger", "--property-match=ID_INPUT_JOYSTICK=1").CombinedOutput()
 		if err != nil {
 			return fmt.Errorf("cannot run udev triggers for joysticks: %s\nudev output:\n%s", err, string(output))
@@ -105,3 +165,90 @@ func ReloadRules(subsystemTriggers []string) error {
 
 	return nil
 }
+
+var (
+	subsystemMatchRe = regexp.MustCompile(`(?:^|[\s,])SUBSYSTEM\s*([=!]=)\s*"([^"]*)"`)
+	// the hotplug rules are only about devices tagged by other rules
+	tagRunRuleRe = regexp.MustCompile(`^TAG=="[^"]*", RUN\+=`)
+)
+
+// effectiveRules returns the rules of the given udev rules content,
+// that is its lines that are neither blank nor comments, with
+// continued lines joined.
+func effectiveRules(content []byte) map[string]bool {
+	rules := make(map[string]bool)
+	var cont string
+	for _, line := range strings.Split(string(content), "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasSuffix(line, "\\") {
+			cont += strings.TrimSuffix(line, "\\")
+			continue
+		}
+		line = cont + line
+		cont = ""
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		rules[line] = true
+	}
+	if cont != "" {
+		rules[cont] = true
+	}
+	return rules
+}
+
+// ruleSubsystems returns the subsystems of the devices the given rule
+// matches, or ok set to false if they cannot be determined.
+func ruleSubsystems(rule string) (subsystems []string, ok bool) {
+	matches := subsystemMatchRe.FindAllStringSubmatch(rule, -1)
+	if len(matches) != 1 || matches[0][1] != "==" {
+		return nil, false
+	}
+	for _, subsystem := range strings.Split(matches[0][2], "|") {
+		if subsystem == "" || strings.ContainsAny(subsystem, "*?[") {
+			return nil, false
+		}
+		subsystems = append(subsystems, subsystem)
+	}
+	return subsystems, true
+}
+
+// ChangedSubsystems compares two versions of udev rules and returns
+// the sorted subsystems of the devices affected by the rules that
+// were added or removed. Comments and blank lines are ignored. all is
+// set if some of the changed rules cannot be narrowed down to specific
+// subsystems, in which case all devices are potentially affected.
+func ChangedSubsystems(oldContent, newContent []byte) (subsystems []string, all bool) {
+	oldRules := effectiveRules(oldContent)
+	newRules := effectiveRules(newContent)
+	var changed []string
+	for rule := range oldRules {
+		if !newRules[rule] {
+			changed = append(changed, rule)
+		}
+	}
+	for rule := range newRules {
+		if !oldRules[rule] {
+			changed = append(changed, rule)
+		}
+	}
+
+	seen := make(map[string]bool)
+	for _, rule := range changed {
+		if tagRunRuleRe.MatchString(rule) {
+			continue
+		}
+		ruleSubsystems, ok := ruleSubsystems(rule)
+		if !ok {
+			return nil, true
+		}
+		for _, subsystem := range ruleSubsystems {
+			if !seen[subsystem] {
+				seen[subsystem] = true
+				subsystems = append(subsystems, subsystem)
+			}
+		}
+	}
+	sort.Strings(subsystems)
+	return subsystems, false
+}
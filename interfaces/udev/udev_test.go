@@ -169,3 +169,107 @@ func (s *uDevSuite) TestReloadUDevRulesRunsUDevAdmWithTwoSubsystems(c *C) {
 		{"udevadm", "settle", "--timeout=10"},
 	})
 }
+
+// Tests for ReloadRulesForSubsystems()
+
+func (s *uDevSuite) TestReloadRulesForSubsystems(c *C) {
+	cmd := testutil.MockCommand(c, "udevadm", "")
+	defer cmd.Restore()
+	err := udev.ReloadRulesForSubsystems([]string{"tty", "usb"}, nil)
+	c.Assert(err, IsNil)
+	c.Assert(cmd.Calls(), DeepEquals, [][]string{
+		{"udevadm", "control", "--reload-rules"},
+		{"udevadm", "trigger", "--subsystem-match=tty"},
+		{"udevadm", "trigger", "--subsystem-match=usb"},
+		{"udevadm", "settle", "--timeout=10"},
+	})
+}
+
+func (s *uDevSuite) TestReloadRulesForSubsystemsNothingChanged(c *C) {
+	cmd := testutil.MockCommand(c, "udevadm", "")
+	defer cmd.Restore()
+	err := udev.ReloadRulesForSubsystems(nil, nil)
+	c.Assert(err, IsNil)
+	c.Assert(cmd.Calls(), DeepEquals, [][]string{
+		{"udevadm", "control", "--reload-rules"},
+	})
+}
+
+func (s *uDevSuite) TestReloadRulesForSubsystemsInput(c *C) {
+	cmd := testutil.MockCommand(c, "udevadm", "")
+	defer cmd.Restore()
+	err := udev.ReloadRulesForSubsystems([]string{"input"}, nil)
+	c.Assert(err, IsNil)
+	// only joysticks are triggered
+	c.Assert(cmd.Calls(), DeepEquals, [][]string{
+		{"udevadm", "control", "--reload-rules"},
+		{"udevadm", "trigger", "--property-match=ID_INPUT_JOYSTICK=1"},
+		{"udevadm", "settle", "--timeout=10"},
+	})
+}
+
+func (s *uDevSuite) TestReloadRulesForSubsystemsWithTriggers(c *C) {
+	cmd := testutil.MockCommand(c, "udevadm", "")
+	defer cmd.Restore()
+	err := udev.ReloadRulesForSubsystems([]string{"input", "tty"}, []string{"tty", "input/joystick"})
+	c.Assert(err, IsNil)
+	c.Assert(cmd.Calls(), DeepEquals, [][]string{
+		{"udevadm", "control", "--reload-rules"},
+		{"udevadm", "trigger", "--subsystem-match=tty"},
+		{"udevadm", "trigger", "--property-match=ID_INPUT_JOYSTICK=1"},
+		{"udevadm", "settle", "--timeout=10"},
+	})
+}
+
+func (s *uDevSuite) TestReloadRulesForSubsystemsReportsErrors(c *C) {
+	cmd := testutil.MockCommand(c, "udevadm", `
+if [ "$1" = "trigger" ]; then
+	echo "failure 2"
+	exit 2
+fi
+	`)
+	defer cmd.Restore()
+	err := udev.ReloadRulesForSubsystems([]string{"tty"}, nil)
+	c.Assert(err.Error(), Equals, ""+
+		"cannot run udev triggers for tty subsystem: exit status 2\n"+
+		"udev output:\n"+
+		"failure 2\n")
+	c.Assert(cmd.Calls(), DeepEquals, [][]string{
+		{"udevadm", "control", "--reload-rules"},
+		{"udevadm", "trigger", "--subsystem-match=tty"},
+	})
+}
+
+// Tests for ChangedSubsystems()
+
+func (s *uDevSuite) TestChangedSubsystems(c *C) {
+	for _, t := range []struct {
+		old, new   string
+		subsystems []string
+		all        bool
+	}{
+		// no change
+		{"", "", nil, false},
+		{`SUBSYSTEM=="tty", KERNEL=="ttyS0"`, `SUBSYSTEM=="tty", KERNEL=="ttyS0"`, nil, false},
+		// comments and blank lines are ignored
+		{"# a comment\n", "# another comment\n\n", nil, false},
+		{"", `# SUBSYSTEM=="tty", KERNEL=="ttyS0"`, nil, false},
+		// added, removed and changed rules
+		{"", `SUBSYSTEM=="tty", KERNEL=="ttyS0"`, []string{"tty"}, false},
+		{`SUBSYSTEM=="tty", KERNEL=="ttyS0"`, "", []string{"tty"}, false},
+		{`SUBSYSTEM=="usb", ATTR{idVendor}=="0001"`, `SUBSYSTEM=="tty", KERNEL=="ttyS0"`, []string{"tty", "usb"}, false},
+		{"", `SUBSYSTEM=="tty|usb", KERNEL=="ttyUSB0"`, []string{"tty", "usb"}, false},
+		{"", "SUBSYSTEM==\"tty\", \\\n  KERNEL==\"ttyS0\"", []string{"tty"}, false},
+		// the hotplug rules are ignored
+		{"", `TAG=="snap_foo_app", RUN+="/usr/lib/snapd/snap-device-helper $env{ACTION} snap_foo_app $devpath $major:$minor"`, nil, false},
+		// rules that cannot be narrowed down
+		{"", `KERNEL=="ttyS0"`, nil, true},
+		{"", `SUBSYSTEM!="tty", KERNEL=="ttyS0"`, nil, true},
+		{"", `SUBSYSTEM=="tty*", KERNEL=="ttyS0"`, nil, true},
+		{"", `SUBSYSTEM=="tty", SUBSYSTEM=="usb"`, nil, true},
+	} {
+		subsystems, all := udev.ChangedSubsystems([]byte(t.old), []byte(t.new))
+		c.Check(subsystems, DeepEquals, t.subsystems, Commentf("%q -> %q", t.old, t.new))
+		c.Check(all, Equals, t.all, Commentf("%q -> %q", t.old, t.new))
+	}
+}
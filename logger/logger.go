@@ -25,6 +25,8 @@ import (
 	"io"
 	"log"
 	"os"
+	"strconv"
+	"strings"
 	"sync"
 
 	"github.com/snapcore/snapd/osutil"
@@ -79,6 +81,9 @@ func Noticef(format string, v ...interface{}) {
 
 // Debugf records something in the debug log
 func Debugf(format string, v ...interface{}) {
+	if !DebugEnabled("") {
+		return
+	}
 	msg := fmt.Sprintf(format, v...)
 
 	lock.Lock()
@@ -87,6 +92,107 @@ func Debugf(format string, v ...interface{}) {
 	logger.Debug(msg)
 }
 
+var (
+	debugLock       sync.Mutex
+	debugSubsystems map[string]bool
+)
+
+// DebugAllSubsystems is the pseudo subsystem that enables all debug
+// messages with SetDebugSubsystems.
+const DebugAllSubsystems = "all"
+
+// SetDebugSubsystems enables at runtime the debug messages of the
+// given subsystems, see Subsystem, replacing the previously enabled
+// ones. This is in addition to SNAPD_DEBUG which enables all of them.
+func SetDebugSubsystems(subsystems []string) {
+	enabled := make(map[string]bool, len(subsystems))
+	for _, subsystem := range subsystems {
+		enabled[subsystem] = true
+	}
+
+	debugLock.Lock()
+	defer debugLock.Unlock()
+
+	debugSubsystems = enabled
+}
+
+// DebugEnabled returns whether the debug messages of the given
+// subsystem are recorded, an empty subsystem is for the messages of
+// Debugf.
+func DebugEnabled(subsystem string) bool {
+	if osutil.GetenvBool("SNAPD_DEBUG") {
+		return true
+	}
+
+	debugLock.Lock()
+	defer debugLock.Unlock()
+
+	return debugSubsystems[DebugAllSubsystems] || (subsystem != "" && debugSubsystems[subsystem])
+}
+
+// Subsystem records messages on behalf of a subsystem of snapd
+// (e.g. "store"), prefixing them with its name. Its debug messages
+// can be enabled on their own with SetDebugSubsystems.
+type Subsystem string
+
+// Noticef notifies the user of something.
+func (s Subsystem) Noticef(format string, v ...interface{}) {
+	msg := fmt.Sprintf(format, v...)
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	logger.Notice(string(s) + ": " + msg)
+}
+
+// Debugf records something in the debug log if debug messages of the
+// subsystem are enabled.
+func (s Subsystem) Debugf(format string, v ...interface{}) {
+	if !DebugEnabled(string(s)) {
+		return
+	}
+	msg := fmt.Sprintf(format, v...)
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	logger.Debug(string(s) + ": " + msg)
+}
+
+// Debugw records msg followed by the given key/value pairs, as
+// key=value, in the debug log if debug messages of the subsystem are
+// enabled. Values are quoted if needed. There is deliberately no JSON
+// output: messages end up as single text lines in the journal, which
+// already records them with their own structured metadata.
+func (s Subsystem) Debugw(msg string, keysAndValues ...interface{}) {
+	if !DebugEnabled(string(s)) {
+		return
+	}
+	msg = withFields(msg, keysAndValues)
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	logger.Debug(string(s) + ": " + msg)
+}
+
+func withFields(msg string, keysAndValues []interface{}) string {
+	var buf bytes.Buffer
+	buf.WriteString(msg)
+	for i := 0; i < len(keysAndValues); i += 2 {
+		key := fmt.Sprint(keysAndValues[i])
+		value := "<missing>"
+		if i+1 < len(keysAndValues) {
+			value = fmt.Sprint(keysAndValues[i+1])
+		}
+		if value == "" || strings.ContainsAny(value, " =\"\t\n") {
+			value = strconv.Quote(value)
+		}
+		fmt.Fprintf(&buf, " %s=%s", key, value)
+	}
+	return buf.String()
+}
+
 // MockLogger replaces the exiting logger with a buffer and returns
 // the log buffer and a restore function.
 func MockLogger() (buf *bytes.Buffer, restore func()) {
@@ -114,11 +220,10 @@ type Log struct {
 	log *log.Logger
 }
 
-// Debug only prints if SNAPD_DEBUG is set
+// Debug records a debug message, whether debug messages are recorded
+// at all is decided by Debugf and Subsystem.Debugf, see DebugEnabled.
 func (l Log) Debug(msg string) {
-	if osutil.GetenvBool("SNAPD_DEBUG") {
-		l.log.Output(3, "DEBUG: "+msg)
-	}
+	l.log.Output(3, "DEBUG: "+msg)
 }
 
 // Notice alerts the user about something, as well as putting it syslog
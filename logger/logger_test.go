@@ -111,3 +111,51 @@ func (s *LogSuite) TestPanicf(c *C) {
 	c.Check(func() { logger.Panicf("xyzzy") }, Panics, "xyzzy")
 	c.Check(s.logbuf.String(), Matches, `(?m).*logger_test\.go:\d+: PANIC xyzzy`)
 }
+
+func (s *LogSuite) TestSubsystemDebugf(c *C) {
+	defer logger.SetDebugSubsystems(nil)
+
+	logger.Subsystem("store").Debugf("xyzzy")
+	c.Check(s.logbuf.String(), Equals, "")
+
+	logger.SetDebugSubsystems([]string{"store", "ifacestate"})
+	logger.Subsystem("store").Debugf("xyzzy")
+	logger.Subsystem("hookstate").Debugf("plugh")
+	logger.Debugf("foo")
+	c.Check(s.logbuf.String(), Matches, `(?m).*logger_test\.go:\d+: DEBUG: store: xyzzy\n`)
+	c.Check(logger.DebugEnabled("ifacestate"), Equals, true)
+	c.Check(logger.DebugEnabled("hookstate"), Equals, false)
+
+	logger.SetDebugSubsystems(nil)
+	c.Check(logger.DebugEnabled("store"), Equals, false)
+}
+
+func (s *LogSuite) TestSubsystemDebugfAll(c *C) {
+	defer logger.SetDebugSubsystems(nil)
+
+	logger.SetDebugSubsystems([]string{"all"})
+	logger.Subsystem("store").Debugf("xyzzy")
+	logger.Debugf("foo")
+	c.Check(s.logbuf.String(), Matches, `(?ms).*DEBUG: store: xyzzy\n.*DEBUG: foo\n`)
+}
+
+func (s *LogSuite) TestSubsystemDebugfEnv(c *C) {
+	os.Setenv("SNAPD_DEBUG", "1")
+	defer os.Unsetenv("SNAPD_DEBUG")
+
+	logger.Subsystem("store").Debugf("xyzzy")
+	c.Check(s.logbuf.String(), testutil.Contains, `DEBUG: store: xyzzy`)
+}
+
+func (s *LogSuite) TestSubsystemDebugw(c *C) {
+	defer logger.SetDebugSubsystems(nil)
+	logger.SetDebugSubsystems([]string{"store"})
+
+	logger.Subsystem("store").Debugw("download done", "snap", "foo", "size", 42, "error", `no "space" left`, "empty", "", "odd")
+	c.Check(s.logbuf.String(), Matches, `(?m).*logger_test\.go:\d+: DEBUG: store: download done snap=foo size=42 error="no \\"space\\" left" empty="" odd=<missing>\n`)
+}
+
+func (s *LogSuite) TestSubsystemNoticef(c *C) {
+	logger.Subsystem("store").Noticef("xyzzy")
+	c.Check(s.logbuf.String(), Matches, `(?m).*logger_test\.go:\d+: store: xyzzy\n`)
+}
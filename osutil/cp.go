@@ -39,11 +39,16 @@ const (
 	CopyFlagOverwrite
 	// CopyFlagPreserveAll preserves mode,owner,time attributes
 	CopyFlagPreserveAll
+	// CopyFlagReflink makes a copy-on-write clone of the data where
+	// the filesystem supports it (e.g. btrfs, xfs), falling back to
+	// a regular copy otherwise
+	CopyFlagReflink
 )
 
 var (
-	openfile = doOpenFile
-	copyfile = doCopyFile
+	openfile  = doOpenFile
+	copyfile  = doCopyFile
+	clonefile = doCloneFile
 )
 
 type fileish interface {
@@ -65,7 +70,7 @@ func CopyFile(src, dst string, flags CopyFlag) (err error) {
 		// Our native copy code does not preserve all attributes
 		// (yet). If the user needs this functionatlity we just
 		// fallback to use the system's "cp" binary to do the copy.
-		if err := runCpPreserveAll(src, dst, "copy all"); err != nil {
+		if err := runCpPreserveAll(src, dst, "copy all", flags); err != nil {
 			return err
 		}
 		if flags&CopyFlagSync != 0 {
@@ -104,8 +109,16 @@ func CopyFile(src, dst string, flags CopyFlag) (err error) {
 		}
 	}()
 
-	if err := copyfile(fin, fout, fi); err != nil {
-		return fmt.Errorf("unable to copy %s to %s: %v", src, dst, err)
+	cloned := false
+	if flags&CopyFlagReflink != 0 {
+		// cloning either shares all the data or fails without
+		// touching the target, in which case do a regular copy
+		cloned = clonefile(fin, fout) == nil
+	}
+	if !cloned {
+		if err := copyfile(fin, fout, fi); err != nil {
+			return fmt.Errorf("unable to copy %s to %s: %v", src, dst, err)
+		}
 	}
 
 	if flags&CopyFlagSync != 0 {
@@ -141,14 +154,20 @@ func runSync(args ...string) error {
 	return runCmd(exec.Command("sync", args...), "sync")
 }
 
-func runCpPreserveAll(path, dest, errdesc string) error {
-	return runCmd(exec.Command("cp", "-av", path, dest), errdesc)
+func runCpPreserveAll(path, dest, errdesc string, flags CopyFlag) error {
+	args := []string{"-av"}
+	if flags&CopyFlagReflink != 0 {
+		// cp falls back to a regular copy if cloning is not supported
+		args = append(args, "--reflink=auto")
+	}
+	args = append(args, path, dest)
+	return runCmd(exec.Command("cp", args...), errdesc)
 }
 
 // CopySpecialFile is used to copy all the things that are not files
 // (like device nodes, named pipes etc)
 func CopySpecialFile(path, dest string) error {
-	if err := runCpPreserveAll(path, dest, "copy device node"); err != nil {
+	if err := runCpPreserveAll(path, dest, "copy device node", CopyFlagDefault); err != nil {
 		return err
 	}
 	return runSync(filepath.Dir(dest))
@@ -43,3 +43,10 @@ func (s *cpSuite) TestDoCpErr(c *C) {
 	// force an error by asking it to write to a readonly stream
 	c.Check(doCopyFile(f1, os.Stdin, st), NotNil)
 }
+
+func (s *cpSuite) TestCpReflinkReal(c *C) {
+	// whether or not the filesystem of the test directory supports
+	// reflinks the data ends up in the copy
+	c.Check(CopyFile(s.f1, s.f2, CopyFlagReflink), IsNil)
+	c.Check(s.f2, testutil.FileEquals, s.data)
+}
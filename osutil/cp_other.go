@@ -21,6 +21,7 @@
 package osutil
 
 import (
+	"errors"
 	"io"
 	"os"
 )
@@ -29,3 +30,7 @@ func doCopyFile(fin, fout fileish, fi os.FileInfo) error {
 	_, err := io.Copy(fout, fin)
 	return err
 }
+
+func doCloneFile(fin, fout fileish) error {
+	return errors.New("cloning files is not supported")
+}
@@ -50,6 +50,10 @@ func (s *cpSuite) mockCopyFile(fin, fout fileish, fi os.FileInfo) error {
 	return s.µ("copyfile")
 }
 
+func (s *cpSuite) mockCloneFile(fin, fout fileish) error {
+	return s.µ("clonefile")
+}
+
 func (s *cpSuite) mockOpenFile(name string, flag int, perm os.FileMode) (fileish, error) {
 	return &mockfile{s}, s.µ("open")
 }
@@ -78,11 +82,13 @@ func (s *cpSuite) SetUpTest(c *C) {
 
 func (s *cpSuite) mock() {
 	copyfile = s.mockCopyFile
+	clonefile = s.mockCloneFile
 	openfile = s.mockOpenFile
 }
 
 func (s *cpSuite) TearDownTest(c *C) {
 	copyfile = doCopyFile
+	clonefile = doCloneFile
 	openfile = doOpenFile
 }
 
@@ -110,6 +116,25 @@ func (s *cpSuite) TestCpOverwriteTruncates(c *C) {
 	c.Check(s.f2, testutil.FileEquals, s.data)
 }
 
+func (s *cpSuite) TestCpReflink(c *C) {
+	s.mock()
+	c.Check(CopyFile(s.f1, s.f2, CopyFlagReflink), IsNil)
+	c.Check(s.log, DeepEquals, []string{"open", "stat", "open", "clonefile", "close", "close"})
+}
+
+func (s *cpSuite) TestCpReflinkFallback(c *C) {
+	s.mock()
+	s.errs = []error{nil, nil, nil, syscall.EOPNOTSUPP, nil}
+	c.Check(CopyFile(s.f1, s.f2, CopyFlagReflink), IsNil)
+	c.Check(s.log, DeepEquals, []string{"open", "stat", "open", "clonefile", "copyfile", "close", "close"})
+}
+
+func (s *cpSuite) TestCpNoReflink(c *C) {
+	s.mock()
+	c.Check(CopyFile(s.f1, s.f2, CopyFlagDefault), IsNil)
+	c.Check(s.log, DeepEquals, []string{"open", "stat", "open", "copyfile", "close", "close"})
+}
+
 func (s *cpSuite) TestCpSync(c *C) {
 	s.mock()
 	c.Check(CopyFile(s.f1, s.f2, CopyFlagDefault), IsNil)
@@ -263,6 +288,26 @@ func (s *cpSuite) TestCopyPreserveAllSync(c *C) {
 	})
 }
 
+func (s *cpSuite) TestCopyPreserveAllReflink(c *C) {
+	dir := c.MkDir()
+	mocked := testutil.MockCommand(c, "cp", "").Also("sync", "")
+	defer mocked.Restore()
+
+	src := filepath.Join(dir, "meep")
+	dst := filepath.Join(dir, "copied-meep")
+
+	err := ioutil.WriteFile(src, []byte(nil), 0644)
+	c.Assert(err, IsNil)
+
+	err = CopyFile(src, dst, CopyFlagPreserveAll|CopyFlagSync|CopyFlagReflink)
+	c.Assert(err, IsNil)
+
+	c.Check(mocked.Calls(), DeepEquals, [][]string{
+		{"cp", "-av", "--reflink=auto", src, dst},
+		{"sync"},
+	})
+}
+
 func (s *cpSuite) TestCopyPreserveAllSyncCpFailure(c *C) {
 	dir := c.MkDir()
 	mocked := testutil.MockCommand(c, "cp", "echo OUCH: cp failed.;exit 42").Also("sync", "")
@@ -179,6 +179,29 @@ func RefreshSnapDeclarations(s *state.State, userID int) error {
 	return doFetch(s, userID, deviceCtx, fetching)
 }
 
+// FetchSnapAssertions fetches from the store into the system assertion
+// database the snap-declaration and its prerequisites for the snap with
+// the given snap-id or, if snapSHA3_384 is set instead, the snap-revision
+// and the other assertions needed to install the snap file with that
+// digest.
+func FetchSnapAssertions(s *state.State, snapID, snapSHA3_384 string, userID int) error {
+	if (snapID == "") == (snapSHA3_384 == "") {
+		return fmt.Errorf("internal error: exactly one of snap-id and snap digest must be given")
+	}
+	deviceCtx, err := snapstate.DevicePastSeeding(s, nil)
+	if err != nil {
+		return err
+	}
+
+	fetching := func(f asserts.Fetcher) error {
+		if snapSHA3_384 != "" {
+			return snapasserts.FetchSnapAssertions(f, snapSHA3_384)
+		}
+		return snapasserts.FetchSnapDeclaration(f, snapID)
+	}
+	return doFetch(s, userID, deviceCtx, fetching)
+}
+
 type refreshControlError struct {
 	errs []error
 }
@@ -240,12 +263,21 @@ func ValidateRefreshes(s *state.State, snapInfos []*snap.Info, ignoreValidation
 		}
 	}
 
+	enforced, err := EnforcedValidationSets(s)
+	if err != nil {
+		return nil, err
+	}
+
 	var errs []error
 	for _, candInfo := range snapInfos {
 		if ignoreValidation[candInfo.InstanceName()] {
 			validated = append(validated, candInfo)
 			continue
 		}
+		if err := checkValidationSetsRevision(enforced, candInfo); err != nil {
+			errs = append(errs, err)
+			continue
+		}
 		gatedID := candInfo.SnapID
 		gating := controlled[gatedID]
 		if len(gating) == 0 { // easy case, no refresh control
@@ -304,6 +336,20 @@ func ValidateRefreshes(s *state.State, snapInfos []*snap.Info, ignoreValidation
 	return validated, nil
 }
 
+// checkValidationSetsRevision checks that the refresh candidate is at
+// the revision required by the enforced validation sets, if any.
+func checkValidationSetsRevision(enforced []*asserts.ValidationSet, candInfo *snap.Info) error {
+	sn := &snapasserts.InstalledSnap{Name: candInfo.SnapName(), SnapID: candInfo.SnapID, Revision: candInfo.Revision}
+	required, keys, err := snapasserts.RequiredRevision(enforced, sn)
+	if err != nil {
+		return fmt.Errorf("cannot refresh %q: %v", candInfo.InstanceName(), err)
+	}
+	if !required.Unset() && required != candInfo.Revision {
+		return fmt.Errorf("cannot refresh %q to revision %s: enforced validation sets %s require revision %s", candInfo.InstanceName(), candInfo.Revision, strings.Join(keys, ","), required)
+	}
+	return nil
+}
+
 // BaseDeclaration returns the base-declaration assertion with policies governing all snaps.
 func BaseDeclaration(s *state.State) (*asserts.BaseDeclaration, error) {
 	// TODO: switch keeping this in the DB and have it revisioned/updated
@@ -348,6 +394,68 @@ func Publisher(s *state.State, snapID string) (*asserts.Account, error) {
 	return a.(*asserts.Account), nil
 }
 
+func snapRevisionDigest(db asserts.RODatabase, snapID string, revision snap.Revision) (string, error) {
+	a, err := db.FindMany(asserts.SnapRevisionType, map[string]string{
+		"snap-id":       snapID,
+		"snap-revision": revision.String(),
+	})
+	if err != nil {
+		return "", err
+	}
+	return a[0].(*asserts.SnapRevision).SnapSHA3_384(), nil
+}
+
+// SnapBuild returns the snap-build assertion for the given snap-id
+// and revision if it and the matching snap-revision are present in the
+// system assertion database. The snap-build assertion must be signed by
+// the publisher of the snap.
+func SnapBuild(s *state.State, snapID string, revision snap.Revision) (*asserts.SnapBuild, error) {
+	db := DB(s)
+	digest, err := snapRevisionDigest(db, snapID, revision)
+	if err != nil {
+		return nil, err
+	}
+	a, err := db.Find(asserts.SnapBuildType, map[string]string{
+		"snap-sha3-384": digest,
+	})
+	if err != nil {
+		return nil, err
+	}
+	snapBuild := a.(*asserts.SnapBuild)
+	if snapBuild.SnapID() != snapID {
+		return nil, fmt.Errorf("snap-build assertion for snap id %q does not match snap id %q", snapBuild.SnapID(), snapID)
+	}
+	snapDecl, err := SnapDeclaration(s, snapID)
+	if err != nil {
+		return nil, err
+	}
+	if snapBuild.AuthorityID() != snapDecl.PublisherID() {
+		return nil, fmt.Errorf("snap-build assertion for snap id %q is not signed by its publisher %q but by %q", snapID, snapDecl.PublisherID(), snapBuild.AuthorityID())
+	}
+	return snapBuild, nil
+}
+
+// FetchSnapBuild fetches from the store into the system assertion
+// database the snap-build assertion, if any, for the given snap-id and
+// revision, whose snap-revision must be already present. Snap-build
+// assertions are informational only, so they are not fetched when
+// installing snaps but only when their content is asked for.
+func FetchSnapBuild(s *state.State, snapID string, revision snap.Revision, userID int) error {
+	deviceCtx, err := snapstate.DevicePastSeeding(s, nil)
+	if err != nil {
+		return err
+	}
+	digest, err := snapRevisionDigest(DB(s), snapID, revision)
+	if err != nil {
+		return err
+	}
+
+	fetching := func(f asserts.Fetcher) error {
+		return snapasserts.FetchSnapBuild(f, digest)
+	}
+	return doFetch(s, userID, deviceCtx, fetching)
+}
+
 // Store returns the store assertion with the given name/id if it is
 // present in the system assertion database.
 func Store(s *state.State, store string) (*asserts.Store, error) {
@@ -401,6 +509,8 @@ func delayedCrossMgrInit() {
 	snapstate.AutoRefreshAssertions = AutoRefreshAssertions
 	// hook retrieving auto-aliases into snapstate logic
 	snapstate.AutoAliases = AutoAliases
+	// hook enforced validation sets into snapstate logic
+	snapstate.EnforcedValidationSets = EnforcedValidationSets
 }
 
 // AutoRefreshAssertions tries to refresh all assertions
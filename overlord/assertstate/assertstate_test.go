@@ -83,10 +83,31 @@ func (sto *fakeStore) pokeStateLock() {
 
 func (sto *fakeStore) Assertion(assertType *asserts.AssertionType, key []string, _ *auth.UserState) (asserts.Assertion, error) {
 	sto.pokeStateLock()
+	if assertType == asserts.ValidationSetType && len(key) == 3 {
+		return sto.latestValidationSet(key)
+	}
 	ref := &asserts.Ref{Type: assertType, PrimaryKey: key}
 	return ref.Resolve(sto.db.Find)
 }
 
+func (sto *fakeStore) latestValidationSet(key []string) (asserts.Assertion, error) {
+	all, err := sto.db.FindMany(asserts.ValidationSetType, map[string]string{
+		"series":     key[0],
+		"account-id": key[1],
+		"name":       key[2],
+	})
+	if err != nil {
+		return nil, err
+	}
+	var latest *asserts.ValidationSet
+	for _, a := range all {
+		if vs := a.(*asserts.ValidationSet); latest == nil || vs.Sequence() > latest.Sequence() {
+			latest = vs
+		}
+	}
+	return latest, nil
+}
+
 var (
 	dev1PrivKey, _ = assertstest.GenerateKey(752)
 )
@@ -505,6 +526,100 @@ func (s *assertMgrSuite) TestValidateSnap(c *C) {
 	c.Assert(err, IsNil)
 }
 
+func (s *assertMgrSuite) signSnapBuild(c *C, signer *assertstest.SigningDB, authorityID string) {
+	snapBuild, err := signer.Sign(asserts.SnapBuildType, map[string]interface{}{
+		"authority-id":  authorityID,
+		"snap-sha3-384": makeDigest(10),
+		"snap-id":       "snap-id-1",
+		"snap-size":     fmt.Sprintf("%d", len(fakeSnap(10))),
+		"grade":         "stable",
+		"timestamp":     time.Now().Format(time.RFC3339),
+	}, nil, "")
+	c.Assert(err, IsNil)
+	c.Assert(s.storeSigning.Add(snapBuild), IsNil)
+}
+
+func (s *assertMgrSuite) TestValidateSnapDoesNotFetchSnapBuild(c *C) {
+	s.prereqSnapAssertions(c, 10)
+	s.signSnapBuild(c, s.dev1Signing, s.dev1Acct.AccountID())
+
+	tempdir := c.MkDir()
+	snapPath := filepath.Join(tempdir, "foo.snap")
+	err := ioutil.WriteFile(snapPath, fakeSnap(10), 0644)
+	c.Assert(err, IsNil)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	s.setModel(sysdb.GenericClassicModel())
+
+	chg := s.state.NewChange("install", "...")
+	t := s.state.NewTask("validate-snap", "Fetch and check snap assertions")
+	snapsup := snapstate.SnapSetup{
+		SnapPath: snapPath,
+		UserID:   0,
+		SideInfo: &snap.SideInfo{
+			RealName: "foo",
+			SnapID:   "snap-id-1",
+			Revision: snap.R(10),
+		},
+	}
+	t.Set("snap-setup", snapsup)
+	chg.AddTask(t)
+
+	s.state.Unlock()
+	defer s.se.Stop()
+	s.settle(c)
+	s.state.Lock()
+
+	c.Assert(chg.Err(), IsNil)
+
+	// the snap-build assertion is only fetched when asked for
+	_, err = assertstate.SnapBuild(s.state, "snap-id-1", snap.R(10))
+	c.Check(asserts.IsNotFound(err), Equals, true)
+
+	err = assertstate.FetchSnapBuild(s.state, "snap-id-1", snap.R(10), 0)
+	c.Assert(err, IsNil)
+
+	build, err := assertstate.SnapBuild(s.state, "snap-id-1", snap.R(10))
+	c.Assert(err, IsNil)
+	c.Check(build.Grade(), Equals, "stable")
+	c.Check(build.AuthorityID(), Equals, s.dev1Acct.AccountID())
+}
+
+func (s *assertMgrSuite) TestFetchSnapBuildNotFound(c *C) {
+	s.prereqSnapAssertions(c, 10)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	s.setModel(sysdb.GenericClassicModel())
+
+	// the snap-revision must be known already
+	err := assertstate.FetchSnapBuild(s.state, "snap-id-1", snap.R(10), 0)
+	c.Check(asserts.IsNotFound(err), Equals, true)
+
+	c.Assert(assertstate.FetchSnapAssertions(s.state, "", makeDigest(10), 0), IsNil)
+	err = assertstate.FetchSnapBuild(s.state, "snap-id-1", snap.R(10), 0)
+	c.Check(asserts.IsNotFound(err), Equals, true)
+}
+
+func (s *assertMgrSuite) TestSnapBuildNotSignedByPublisher(c *C) {
+	s.prereqSnapAssertions(c, 10)
+	s.signSnapBuild(c, s.storeSigning.SigningDB, "can0nical")
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	s.setModel(sysdb.GenericClassicModel())
+
+	c.Assert(assertstate.FetchSnapAssertions(s.state, "", makeDigest(10), 0), IsNil)
+	c.Assert(assertstate.FetchSnapBuild(s.state, "snap-id-1", snap.R(10), 0), IsNil)
+
+	_, err := assertstate.SnapBuild(s.state, "snap-id-1", snap.R(10))
+	c.Check(err, ErrorMatches, `snap-build assertion for snap id "snap-id-1" is not signed by its publisher "`+s.dev1Acct.AccountID()+`" but by "can0nical"`)
+}
+
 func (s *assertMgrSuite) TestValidateSnapStoreNotFound(c *C) {
 	s.prereqSnapAssertions(c, 10)
 
@@ -730,6 +845,79 @@ func (s *assertMgrSuite) stateFromDecl(c *C, decl *asserts.SnapDeclaration, inst
 	})
 }
 
+func (s *assertMgrSuite) TestFetchSnapAssertionsBySnapID(c *C) {
+	s.prereqSnapAssertions(c, 10)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	s.setModel(sysdb.GenericClassicModel())
+
+	err := assertstate.FetchSnapAssertions(s.state, "snap-id-1", "", 0)
+	c.Assert(err, IsNil)
+
+	db := assertstate.DB(s.state)
+	_, err = db.Find(asserts.SnapDeclarationType, map[string]string{
+		"series":  "16",
+		"snap-id": "snap-id-1",
+	})
+	c.Check(err, IsNil)
+	_, err = db.Find(asserts.AccountType, map[string]string{
+		"account-id": s.dev1Acct.AccountID(),
+	})
+	c.Check(err, IsNil)
+	// no snap-revision was fetched
+	_, err = db.Find(asserts.SnapRevisionType, map[string]string{
+		"snap-sha3-384": makeDigest(10),
+	})
+	c.Check(asserts.IsNotFound(err), Equals, true)
+}
+
+func (s *assertMgrSuite) TestFetchSnapAssertionsByDigest(c *C) {
+	s.prereqSnapAssertions(c, 10)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	s.setModel(sysdb.GenericClassicModel())
+
+	err := assertstate.FetchSnapAssertions(s.state, "", makeDigest(10), 0)
+	c.Assert(err, IsNil)
+
+	db := assertstate.DB(s.state)
+	a, err := db.Find(asserts.SnapRevisionType, map[string]string{
+		"snap-sha3-384": makeDigest(10),
+	})
+	c.Assert(err, IsNil)
+	c.Check(a.(*asserts.SnapRevision).SnapRevision(), Equals, 10)
+	_, err = db.Find(asserts.SnapDeclarationType, map[string]string{
+		"series":  "16",
+		"snap-id": "snap-id-1",
+	})
+	c.Check(err, IsNil)
+}
+
+func (s *assertMgrSuite) TestFetchSnapAssertionsNotFound(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	s.setModel(sysdb.GenericClassicModel())
+
+	err := assertstate.FetchSnapAssertions(s.state, "snap-id-1", "", 0)
+	c.Check(asserts.IsNotFound(err), Equals, true)
+}
+
+func (s *assertMgrSuite) TestFetchSnapAssertionsTooEarly(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	r := snapstatetest.MockDeviceModel(nil)
+	defer r()
+
+	err := assertstate.FetchSnapAssertions(s.state, "snap-id-1", "", 0)
+	c.Check(err, FitsTypeOf, &snapstate.ChangeConflictError{})
+}
+
 func (s *assertMgrSuite) TestRefreshSnapDeclarationsTooEarly(c *C) {
 	s.state.Lock()
 	defer s.state.Unlock()
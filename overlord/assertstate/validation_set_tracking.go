@@ -0,0 +1,269 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2019 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package assertstate
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/snapcore/snapd/asserts"
+	"github.com/snapcore/snapd/asserts/snapasserts"
+	"github.com/snapcore/snapd/overlord/snapstate"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/release"
+)
+
+// ValidationSetMode reflects the mode of respective validation set, which is
+// either monitoring or enforcing.
+type ValidationSetMode int
+
+const (
+	Monitor ValidationSetMode = iota
+	Enforce
+)
+
+func (m ValidationSetMode) String() string {
+	if m == Enforce {
+		return "enforce"
+	}
+	return "monitor"
+}
+
+// ValidationSetTracking holds tracking parameters for associated validation set.
+type ValidationSetTracking struct {
+	AccountID string            `json:"account-id"`
+	Name      string            `json:"name"`
+	Mode      ValidationSetMode `json:"mode"`
+
+	// PinnedAt records the requested sequence, 0 if the set tracks
+	// the latest sequence.
+	PinnedAt int `json:"pinned-at,omitempty"`
+
+	// Current is the sequence of the assertion in use.
+	Current int `json:"current,omitempty"`
+}
+
+// ValidationSetKey formats the given account id and name into a
+// validation set key.
+func ValidationSetKey(accountID, name string) string {
+	return fmt.Sprintf("%s/%s", accountID, name)
+}
+
+// UpdateValidationSet updates the ValidationSetTracking. The method
+// assumes valid tr fields.
+func UpdateValidationSet(st *state.State, tr *ValidationSetTracking) error {
+	vsmap, err := ValidationSets(st)
+	if err != nil {
+		return err
+	}
+	if vsmap == nil {
+		vsmap = make(map[string]*ValidationSetTracking)
+	}
+	vsmap[ValidationSetKey(tr.AccountID, tr.Name)] = tr
+	st.Set("validation-sets", vsmap)
+	return nil
+}
+
+// DeleteValidationSet deletes the validation set tracking for the given
+// account and name. It is not an error to delete a non-existing one.
+func DeleteValidationSet(st *state.State, accountID, name string) error {
+	vsmap, err := ValidationSets(st)
+	if err != nil {
+		return err
+	}
+	key := ValidationSetKey(accountID, name)
+	if _, ok := vsmap[key]; !ok {
+		return nil
+	}
+	delete(vsmap, key)
+	st.Set("validation-sets", vsmap)
+	return nil
+}
+
+// GetValidationSet retrieves the ValidationSetTracking for the given
+// account and name.
+func GetValidationSet(st *state.State, accountID, name string, tr *ValidationSetTracking) error {
+	vsmap, err := ValidationSets(st)
+	if err != nil {
+		return err
+	}
+	cur, ok := vsmap[ValidationSetKey(accountID, name)]
+	if !ok {
+		return state.ErrNoState
+	}
+	*tr = *cur
+	return nil
+}
+
+// ValidationSets retrieves all the ValidationSetTracking data, keyed by
+// account-id/name.
+func ValidationSets(st *state.State) (map[string]*ValidationSetTracking, error) {
+	var vsmap map[string]*ValidationSetTracking
+	if err := st.Get("validation-sets", &vsmap); err != nil && err != state.ErrNoState {
+		return nil, err
+	}
+	return vsmap, nil
+}
+
+// ValidationSetAssertion returns the validation-set assertion in use by
+// the given tracking from the system assertion database.
+func ValidationSetAssertion(st *state.State, tr *ValidationSetTracking) (*asserts.ValidationSet, error) {
+	a, err := DB(st).Find(asserts.ValidationSetType, map[string]string{
+		"series":     release.Series,
+		"account-id": tr.AccountID,
+		"name":       tr.Name,
+		"sequence":   strconv.Itoa(tr.Current),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return a.(*asserts.ValidationSet), nil
+}
+
+// EnforcedValidationSets returns the validation-set assertions of all
+// the validation sets tracked in enforce mode.
+func EnforcedValidationSets(st *state.State) ([]*asserts.ValidationSet, error) {
+	vsmap, err := ValidationSets(st)
+	if err != nil {
+		return nil, err
+	}
+	var sets []*asserts.ValidationSet
+	for key, tr := range vsmap {
+		if tr.Mode != Enforce {
+			continue
+		}
+		vs, err := ValidationSetAssertion(st, tr)
+		if err != nil {
+			return nil, fmt.Errorf("cannot find validation-set assertion for %q: %v", key, err)
+		}
+		sets = append(sets, vs)
+	}
+	return sets, nil
+}
+
+// InstalledSnaps returns the details of the installed snaps needed to
+// check them against validation-sets.
+func InstalledSnaps(st *state.State) ([]*snapasserts.InstalledSnap, error) {
+	snapStates, err := snapstate.All(st)
+	if err != nil {
+		return nil, err
+	}
+	snaps := make([]*snapasserts.InstalledSnap, 0, len(snapStates))
+	for _, snapst := range snapStates {
+		info, err := snapst.CurrentInfo()
+		if err != nil {
+			return nil, err
+		}
+		snaps = append(snaps, &snapasserts.InstalledSnap{
+			Name:     info.SnapName(),
+			SnapID:   info.SnapID,
+			Revision: info.Revision,
+		})
+	}
+	return snaps, nil
+}
+
+// FetchValidationSet fetches the validation-set assertion with the
+// given sequence, or the latest one if sequence is 0, together with
+// its prerequisites into the system assertion database.
+func FetchValidationSet(st *state.State, accountID, name string, sequence int, userID int) (*asserts.ValidationSet, error) {
+	deviceCtx, err := snapstate.DevicePastSeeding(st, nil)
+	if err != nil {
+		return nil, err
+	}
+	user, err := userFromUserID(st, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	// a primary key without the sequence asks the store for the
+	// latest one
+	primaryKey := []string{release.Series, accountID, name}
+	if sequence > 0 {
+		primaryKey = append(primaryKey, strconv.Itoa(sequence))
+	}
+
+	sto := snapstate.Store(st, deviceCtx)
+	st.Unlock()
+	a, err := sto.Assertion(asserts.ValidationSetType, primaryKey, user)
+	st.Lock()
+	if err != nil {
+		return nil, err
+	}
+	vs, ok := a.(*asserts.ValidationSet)
+	if !ok || vs.AccountID() != accountID || vs.Name() != name || (sequence > 0 && vs.Sequence() != sequence) {
+		return nil, fmt.Errorf("internal error: store returned unexpected assertion %v", a.Ref())
+	}
+
+	fetching := func(f asserts.Fetcher) error {
+		return f.Save(vs)
+	}
+	if err := doFetch(st, userID, deviceCtx, fetching); err != nil {
+		return nil, err
+	}
+	return vs, nil
+}
+
+// ApplyValidationSet fetches the validation-set assertion for the given
+// account and name, at the given sequence or the latest one if it is 0,
+// and starts tracking it in the given mode. In enforce mode the
+// installed snaps must comply with the set and with the other enforced
+// sets, a *snapasserts.ValidationSetsValidationError is returned
+// otherwise.
+func ApplyValidationSet(st *state.State, accountID, name string, sequence int, mode ValidationSetMode, userID int) (*ValidationSetTracking, error) {
+	vs, err := FetchValidationSet(st, accountID, name, sequence, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if mode == Enforce {
+		sets, err := EnforcedValidationSets(st)
+		if err != nil {
+			return nil, err
+		}
+		// replace any previously enforced sequence of the same set
+		key := snapasserts.ValidationSetKey(vs)
+		others := sets[:0]
+		for _, other := range sets {
+			if snapasserts.ValidationSetKey(other) != key {
+				others = append(others, other)
+			}
+		}
+		snaps, err := InstalledSnaps(st)
+		if err != nil {
+			return nil, err
+		}
+		if err := snapasserts.CheckInstalledSnaps(append(others, vs), snaps); err != nil {
+			return nil, err
+		}
+	}
+
+	tr := &ValidationSetTracking{
+		AccountID: accountID,
+		Name:      name,
+		Mode:      mode,
+		PinnedAt:  sequence,
+		Current:   vs.Sequence(),
+	}
+	if err := UpdateValidationSet(st, tr); err != nil {
+		return nil, err
+	}
+	return tr, nil
+}
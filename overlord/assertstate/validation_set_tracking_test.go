@@ -0,0 +1,180 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2019 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package assertstate_test
+
+import (
+	"strconv"
+	"time"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/asserts"
+	"github.com/snapcore/snapd/asserts/snapasserts"
+	"github.com/snapcore/snapd/asserts/sysdb"
+	"github.com/snapcore/snapd/overlord/assertstate"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/snap"
+)
+
+func (s *assertMgrSuite) validationSet(c *C, name string, seq int, snaps ...interface{}) *asserts.ValidationSet {
+	headers := map[string]interface{}{
+		"authority-id": s.dev1Acct.AccountID(),
+		"account-id":   s.dev1Acct.AccountID(),
+		"series":       "16",
+		"name":         name,
+		"sequence":     strconv.Itoa(seq),
+		"snaps":        snaps,
+		"timestamp":    time.Now().Format(time.RFC3339),
+	}
+	a, err := s.dev1Signing.Sign(asserts.ValidationSetType, headers, nil, "")
+	c.Assert(err, IsNil)
+	c.Assert(s.storeSigning.Add(a), IsNil)
+	return a.(*asserts.ValidationSet)
+}
+
+func (s *assertMgrSuite) TestValidationSetTracking(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	var tr assertstate.ValidationSetTracking
+	err := assertstate.GetValidationSet(s.state, "foo", "bar", &tr)
+	c.Check(err, Equals, state.ErrNoState)
+
+	tr1 := &assertstate.ValidationSetTracking{AccountID: "foo", Name: "bar", Mode: assertstate.Enforce, PinnedAt: 2, Current: 2}
+	tr2 := &assertstate.ValidationSetTracking{AccountID: "foo", Name: "baz", Mode: assertstate.Monitor, Current: 5}
+	c.Assert(assertstate.UpdateValidationSet(s.state, tr1), IsNil)
+	c.Assert(assertstate.UpdateValidationSet(s.state, tr2), IsNil)
+
+	c.Assert(assertstate.GetValidationSet(s.state, "foo", "bar", &tr), IsNil)
+	c.Check(&tr, DeepEquals, tr1)
+
+	all, err := assertstate.ValidationSets(s.state)
+	c.Assert(err, IsNil)
+	c.Check(all, DeepEquals, map[string]*assertstate.ValidationSetTracking{
+		"foo/bar": tr1,
+		"foo/baz": tr2,
+	})
+
+	c.Assert(assertstate.DeleteValidationSet(s.state, "foo", "bar"), IsNil)
+	// deleting an unknown one is fine
+	c.Assert(assertstate.DeleteValidationSet(s.state, "foo", "other"), IsNil)
+	all, err = assertstate.ValidationSets(s.state)
+	c.Assert(err, IsNil)
+	c.Check(all, DeepEquals, map[string]*assertstate.ValidationSetTracking{
+		"foo/baz": tr2,
+	})
+
+	c.Check(assertstate.Monitor.String(), Equals, "monitor")
+	c.Check(assertstate.Enforce.String(), Equals, "enforce")
+}
+
+// validation-sets require proper snap ids
+const fooSnapID = "fooididididididididididididididi"
+
+func (s *assertMgrSuite) prepareValidationSets(c *C) {
+	s.setModel(sysdb.GenericClassicModel())
+
+	snapDeclFoo := s.snapDecl(c, "foo", map[string]interface{}{
+		"snap-id": fooSnapID,
+	})
+	s.stateFromDecl(c, snapDeclFoo, "", snap.R(7))
+
+	c.Assert(assertstate.Add(s.state, s.storeSigning.StoreAccountKey("")), IsNil)
+	c.Assert(assertstate.Add(s.state, s.dev1Acct), IsNil)
+	c.Assert(assertstate.Add(s.state, snapDeclFoo), IsNil)
+
+	s.validationSet(c, "base-set", 1, map[string]interface{}{
+		"name":     "foo",
+		"id":       snapDeclFoo.SnapID(),
+		"revision": "7",
+	})
+	s.validationSet(c, "base-set", 2, map[string]interface{}{
+		"name":     "foo",
+		"id":       snapDeclFoo.SnapID(),
+		"revision": "9",
+	})
+}
+
+func (s *assertMgrSuite) TestApplyValidationSetMonitorLatest(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+	s.prepareValidationSets(c)
+
+	tr, err := assertstate.ApplyValidationSet(s.state, s.dev1Acct.AccountID(), "base-set", 0, assertstate.Monitor, 0)
+	c.Assert(err, IsNil)
+	c.Check(tr, DeepEquals, &assertstate.ValidationSetTracking{
+		AccountID: s.dev1Acct.AccountID(),
+		Name:      "base-set",
+		Mode:      assertstate.Monitor,
+		Current:   2,
+	})
+
+	vs, err := assertstate.ValidationSetAssertion(s.state, tr)
+	c.Assert(err, IsNil)
+	c.Check(vs.Sequence(), Equals, 2)
+
+	// only enforced sets are reported
+	sets, err := assertstate.EnforcedValidationSets(s.state)
+	c.Assert(err, IsNil)
+	c.Check(sets, HasLen, 0)
+}
+
+func (s *assertMgrSuite) TestApplyValidationSetEnforceNotMet(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+	s.prepareValidationSets(c)
+
+	_, err := assertstate.ApplyValidationSet(s.state, s.dev1Acct.AccountID(), "base-set", 0, assertstate.Enforce, 0)
+	c.Assert(err, FitsTypeOf, &snapasserts.ValidationSetsValidationError{})
+	c.Check(err, ErrorMatches, `(?s)validation sets assertions are not met:.*- foo \(required at revision 9 by sets `+s.dev1Acct.AccountID()+`/base-set\)`)
+
+	all, err := assertstate.ValidationSets(s.state)
+	c.Assert(err, IsNil)
+	c.Check(all, HasLen, 0)
+}
+
+func (s *assertMgrSuite) TestApplyValidationSetEnforcePinned(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+	s.prepareValidationSets(c)
+
+	tr, err := assertstate.ApplyValidationSet(s.state, s.dev1Acct.AccountID(), "base-set", 1, assertstate.Enforce, 0)
+	c.Assert(err, IsNil)
+	c.Check(tr.PinnedAt, Equals, 1)
+	c.Check(tr.Current, Equals, 1)
+
+	sets, err := assertstate.EnforcedValidationSets(s.state)
+	c.Assert(err, IsNil)
+	c.Assert(sets, HasLen, 1)
+	c.Check(sets[0].Sequence(), Equals, 1)
+
+	// refreshes away from the required revision are refused
+	fooRefresh := &snap.Info{
+		SideInfo: snap.SideInfo{RealName: "foo", SnapID: fooSnapID, Revision: snap.R(9)},
+	}
+	validated, err := assertstate.ValidateRefreshes(s.state, []*snap.Info{fooRefresh}, nil, 0, s.trivialDeviceCtx)
+	c.Check(err, ErrorMatches, `cannot refresh "foo" to revision 9: enforced validation sets `+s.dev1Acct.AccountID()+`/base-set require revision 7`)
+	c.Check(validated, HasLen, 0)
+
+	// unless validation is ignored
+	validated, err = assertstate.ValidateRefreshes(s.state, []*snap.Info{fooRefresh}, map[string]bool{"foo": true}, 0, s.trivialDeviceCtx)
+	c.Check(err, IsNil)
+	c.Check(validated, DeepEquals, []*snap.Info{fooRefresh})
+}
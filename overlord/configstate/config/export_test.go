@@ -28,3 +28,11 @@ var PurgeNulls = purgeNulls
 func (t *Transaction) PristineConfig() map[string]map[string]*json.RawMessage {
 	return t.pristine
 }
+
+func MockUserLimits(options, size int) (restore func()) {
+	oldOptions, oldSize := maxUserOptions, maxUserConfigSize
+	maxUserOptions, maxUserConfigSize = options, size
+	return func() {
+		maxUserOptions, maxUserConfigSize = oldOptions, oldSize
+	}
+}
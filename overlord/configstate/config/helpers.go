@@ -256,15 +256,30 @@ func DeleteSnapConfig(st *state.State, snapName string) error {
 	var config map[string]map[string]*json.RawMessage // snap => key => value
 
 	err := st.Get("config", &config)
-	if err == state.ErrNoState {
-		return nil
-	} else if err != nil {
+	if err != nil && err != state.ErrNoState {
 		return fmt.Errorf("internal error: cannot unmarshal configuration: %v", err)
 	}
 	if _, ok := config[snapName]; ok {
 		delete(config, snapName)
 		st.Set("config", config)
 	}
+
+	// the configuration scoped to users goes away as well
+	var userConfig map[string]map[string]map[string]*json.RawMessage // uid => snap => key => value
+	err = st.Get("user-config", &userConfig)
+	if err != nil && err != state.ErrNoState {
+		return fmt.Errorf("internal error: cannot unmarshal user configuration: %v", err)
+	}
+	changed := false
+	for _, config := range userConfig {
+		if _, ok := config[snapName]; ok {
+			delete(config, snapName)
+			changed = true
+		}
+	}
+	if changed {
+		st.Set("user-config", userConfig)
+	}
 	return nil
 }
 
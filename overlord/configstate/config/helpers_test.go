@@ -266,3 +266,28 @@ func (s *configHelpersSuite) TestPurgeNulls(c *C) {
 		"baz": map[string]interface{}{},
 	})
 }
+
+func (s *configHelpersSuite) TestDeleteSnapConfigWithUserConfig(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	tr := config.NewTransaction(s.state)
+	c.Assert(tr.Set("some-snap", "foo", "bar"), IsNil)
+	c.Assert(tr.Set("other-snap", "foo", "bar"), IsNil)
+	tr.Commit()
+	tr = config.NewUserTransaction(s.state, 1000)
+	c.Assert(tr.Set("some-snap", "foo", "baz"), IsNil)
+	c.Assert(tr.Set("other-snap", "foo", "baz"), IsNil)
+	tr.Commit()
+
+	c.Assert(config.DeleteSnapConfig(s.state, "some-snap"), IsNil)
+
+	var value interface{}
+	tr = config.NewTransaction(s.state)
+	c.Check(config.IsNoOption(tr.Get("some-snap", "foo", &value)), Equals, true)
+	c.Check(tr.Get("other-snap", "foo", &value), IsNil)
+	tr = config.NewUserTransaction(s.state, 1000)
+	c.Check(config.IsNoOption(tr.Get("some-snap", "foo", &value)), Equals, true)
+	c.Assert(tr.Get("other-snap", "foo", &value), IsNil)
+	c.Check(value, Equals, "baz")
+}
@@ -24,6 +24,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"sort"
+	"strconv"
 	"strings"
 	"sync"
 
@@ -42,6 +43,10 @@ type Transaction struct {
 	state    *state.State
 	pristine map[string]map[string]*json.RawMessage // snap => key => value
 	changes  map[string]map[string]interface{}
+
+	// user is the uid, as a string, whose configuration the transaction
+	// is scoped to, or empty for the system-wide configuration
+	user string
 }
 
 // NewTransaction creates a new configuration transaction initialized with the given state.
@@ -53,13 +58,56 @@ func NewTransaction(st *state.State) *Transaction {
 
 	// Record the current state of the map containing the config of every snap
 	// in the system. We'll use it for this transaction.
-	err := st.Get("config", &transaction.pristine)
+	transaction.loadPristine()
+	return transaction
+}
+
+// NewUserTransaction creates a new configuration transaction on the
+// configuration of the snaps scoped to the user with the given uid,
+// initialized with the given state. It is kept separately from the
+// system-wide configuration and does not involve the configure hook.
+//
+// The provided state must be locked by the caller.
+func NewUserTransaction(st *state.State, uid uint32) *Transaction {
+	transaction := &Transaction{state: st, user: strconv.FormatUint(uint64(uid), 10)}
+	transaction.changes = make(map[string]map[string]interface{})
+	transaction.loadPristine()
+	return transaction
+}
+
+// loadPristine updates our copy of the config with the one from the state.
+func (t *Transaction) loadPristine() {
+	var err error
+	if t.user == "" {
+		err = t.state.Get("config", &t.pristine)
+	} else {
+		var userConfig map[string]map[string]map[string]*json.RawMessage
+		err = t.state.Get("user-config", &userConfig)
+		t.pristine = userConfig[t.user]
+	}
+	if err != nil && err != state.ErrNoState {
+		panic(fmt.Errorf("internal error: cannot unmarshal configuration: %v", err))
+	}
+	if t.pristine == nil {
+		t.pristine = make(map[string]map[string]*json.RawMessage)
+	}
+}
+
+// savePristine stores our copy of the config into the state.
+func (t *Transaction) savePristine() {
+	if t.user == "" {
+		t.state.Set("config", t.pristine)
+		return
+	}
+	var userConfig map[string]map[string]map[string]*json.RawMessage
+	err := t.state.Get("user-config", &userConfig)
 	if err == state.ErrNoState {
-		transaction.pristine = make(map[string]map[string]*json.RawMessage)
+		userConfig = make(map[string]map[string]map[string]*json.RawMessage, 1)
 	} else if err != nil {
 		panic(fmt.Errorf("internal error: cannot unmarshal configuration: %v", err))
 	}
-	return transaction
+	userConfig[t.user] = t.pristine
+	t.state.Set("user-config", userConfig)
 }
 
 // State returns the system State
@@ -242,12 +290,7 @@ func (t *Transaction) Commit() {
 	}
 
 	// Update our copy of the config with the most recent one from the state.
-	err := t.state.Get("config", &t.pristine)
-	if err == state.ErrNoState {
-		t.pristine = make(map[string]map[string]*json.RawMessage)
-	} else if err != nil {
-		panic(fmt.Errorf("internal error: cannot unmarshal configuration: %v", err))
-	}
+	t.loadPristine()
 
 	// Iterate through the write cache and save each item.
 	for instanceName, snapChanges := range t.changes {
@@ -260,7 +303,7 @@ func (t *Transaction) Commit() {
 		t.pristine[instanceName] = config
 	}
 
-	t.state.Set("config", t.pristine)
+	t.savePristine()
 
 	// The cache has been flushed, reset it.
 	t.changes = make(map[string]map[string]interface{})
@@ -302,6 +345,48 @@ func commitChange(pristine *json.RawMessage, change interface{}) *json.RawMessag
 	panic(fmt.Errorf("internal error: unexpected configuration type %T", change))
 }
 
+var (
+	// maxUserOptions is the number of options that the configuration
+	// of a snap scoped to a single user may hold
+	maxUserOptions = 256
+	// maxUserConfigSize is the size, once encoded as JSON, that the
+	// configuration of a snap scoped to a single user may reach
+	maxUserConfigSize = 64 * 1024
+)
+
+// CheckUserLimits returns an error if committing the transaction would
+// make the configuration of the given snap, as scoped to the user of the
+// transaction, exceed the number of options or the size allowed for it.
+// The system-wide configuration is not limited.
+func (t *Transaction) CheckUserLimits(instanceName string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.user == "" {
+		return nil
+	}
+
+	config := t.copyPristine(instanceName)
+	applyChanges(config, t.changes[instanceName])
+	purgeNulls(config)
+
+	options := make(map[string]interface{}, len(config))
+	for k, v := range config {
+		options[k] = v
+	}
+	if n := len(changes(instanceName, options)); n > maxUserOptions {
+		return fmt.Errorf("cannot set more than %d options of snap %q for a single user", maxUserOptions, instanceName)
+	}
+	data, err := json.Marshal(config)
+	if err != nil {
+		return fmt.Errorf("cannot marshal snap %q configuration: %v", instanceName, err)
+	}
+	if len(data) > maxUserConfigSize {
+		return fmt.Errorf("cannot store more than %d bytes of configuration of snap %q for a single user", maxUserConfigSize, instanceName)
+	}
+	return nil
+}
+
 // IsNoOption returns whether the provided error is a *NoOptionError.
 func IsNoOption(err error) bool {
 	_, ok := err.(*NoOptionError)
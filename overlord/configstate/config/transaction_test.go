@@ -441,6 +441,87 @@ func (s *transactionSuite) TestNoConfiguration(c *C) {
 	c.Assert(err, ErrorMatches, `snap "some-snap" has no configuration`)
 }
 
+func (s *transactionSuite) TestUserTransaction(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	tr := config.NewTransaction(s.state)
+	c.Assert(tr.Set("test-snap", "foo", "system"), IsNil)
+	tr.Commit()
+
+	tr = config.NewUserTransaction(s.state, 1000)
+	c.Assert(tr.Set("test-snap", "foo", "user-1000"), IsNil)
+	c.Assert(tr.Set("test-snap", "bar.baz", 42), IsNil)
+	tr.Commit()
+
+	tr = config.NewUserTransaction(s.state, 1001)
+	c.Assert(tr.Set("test-snap", "foo", "user-1001"), IsNil)
+	tr.Commit()
+
+	var value interface{}
+	tr = config.NewTransaction(s.state)
+	c.Assert(tr.Get("test-snap", "foo", &value), IsNil)
+	c.Check(value, Equals, "system")
+	c.Check(config.IsNoOption(tr.Get("test-snap", "bar", &value)), Equals, true)
+
+	tr = config.NewUserTransaction(s.state, 1000)
+	c.Assert(tr.Get("test-snap", "foo", &value), IsNil)
+	c.Check(value, Equals, "user-1000")
+	c.Assert(tr.Get("test-snap", "bar.baz", &value), IsNil)
+	c.Check(value, Equals, json.Number("42"))
+
+	tr = config.NewUserTransaction(s.state, 1001)
+	c.Assert(tr.Get("test-snap", "foo", &value), IsNil)
+	c.Check(value, Equals, "user-1001")
+
+	tr = config.NewUserTransaction(s.state, 1002)
+	c.Check(config.IsNoOption(tr.Get("test-snap", "foo", &value)), Equals, true)
+}
+
+func (s *transactionSuite) TestCheckUserLimits(c *C) {
+	restore := config.MockUserLimits(3, 40)
+	defer restore()
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	tr := config.NewUserTransaction(s.state, 1000)
+	c.Assert(tr.Set("test-snap", "foo", "bar"), IsNil)
+	c.Assert(tr.Set("test-snap", "baz.a", 1), IsNil)
+	c.Check(tr.CheckUserLimits("test-snap"), IsNil)
+	tr.Commit()
+
+	// options already committed count against the limits
+	tr = config.NewUserTransaction(s.state, 1000)
+	c.Assert(tr.Set("test-snap", "baz.b", 2), IsNil)
+	c.Check(tr.CheckUserLimits("test-snap"), IsNil)
+	c.Assert(tr.Set("test-snap", "baz.c", 3), IsNil)
+	c.Check(tr.CheckUserLimits("test-snap"), ErrorMatches, `cannot set more than 3 options of snap "test-snap" for a single user`)
+
+	// unsetting options makes room again
+	c.Assert(tr.Set("test-snap", "foo", nil), IsNil)
+	c.Check(tr.CheckUserLimits("test-snap"), IsNil)
+
+	c.Assert(tr.Set("test-snap", "foo", strings.Repeat("x", 40)), IsNil)
+	c.Assert(tr.Set("test-snap", "baz", nil), IsNil)
+	c.Check(tr.CheckUserLimits("test-snap"), ErrorMatches, `cannot store more than 40 bytes of configuration of snap "test-snap" for a single user`)
+
+	// the limits apply to each user and snap on their own
+	tr = config.NewUserTransaction(s.state, 1001)
+	c.Assert(tr.Set("test-snap", "foo", "bar"), IsNil)
+	c.Assert(tr.Set("other-snap", "a", 1), IsNil)
+	c.Assert(tr.Set("other-snap", "b", 2), IsNil)
+	c.Check(tr.CheckUserLimits("test-snap"), IsNil)
+	c.Check(tr.CheckUserLimits("other-snap"), IsNil)
+
+	// and not to the system configuration
+	tr = config.NewTransaction(s.state)
+	for _, k := range []string{"a", "b", "c", "d"} {
+		c.Assert(tr.Set("test-snap", k, strings.Repeat("x", 40)), IsNil)
+	}
+	c.Check(tr.CheckUserLimits("test-snap"), IsNil)
+}
+
 func (s *transactionSuite) TestState(c *C) {
 	s.state.Lock()
 	defer s.state.Unlock()
@@ -0,0 +1,45 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2020 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package configcore
+
+import (
+	"fmt"
+
+	"github.com/snapcore/snapd/overlord/configstate/config"
+)
+
+func init() {
+	// add supported configuration of this module
+	supportedConfigurations["core.aliases.conflict-policy"] = true
+}
+
+func validateAliasesConflictPolicy(tr config.Conf) error {
+	policy, err := coreCfg(tr, "aliases.conflict-policy")
+	if err != nil {
+		return err
+	}
+	switch policy {
+	case "", "fail", "prefer-installed", "prefer-new":
+		// noop
+	default:
+		return fmt.Errorf("aliases.conflict-policy can only be set to 'fail', 'prefer-installed' or 'prefer-new'")
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2020 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package configcore_test
+
+import (
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/overlord/configstate/configcore"
+)
+
+type aliasesSuite struct {
+	configcoreSuite
+}
+
+var _ = Suite(&aliasesSuite{})
+
+func (s *aliasesSuite) TestConfigureAliasesConflictPolicyHappy(c *C) {
+	for _, policy := range []string{"fail", "prefer-installed", "prefer-new"} {
+		err := configcore.Run(&mockConf{
+			state: s.state,
+			conf: map[string]interface{}{
+				"aliases.conflict-policy": policy,
+			},
+		})
+		c.Assert(err, IsNil)
+	}
+}
+
+func (s *aliasesSuite) TestConfigureAliasesConflictPolicyInvalid(c *C) {
+	err := configcore.Run(&mockConf{
+		state: s.state,
+		conf: map[string]interface{}{
+			"aliases.conflict-policy": "prefer-everything",
+		},
+	})
+	c.Assert(err, ErrorMatches, `aliases.conflict-policy can only be set to 'fail', 'prefer-installed' or 'prefer-new'`)
+}
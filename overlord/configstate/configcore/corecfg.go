@@ -70,6 +70,9 @@ func Run(tr config.Conf) error {
 		}
 	}
 
+	if err := validateProxyNoProxy(tr); err != nil {
+		return err
+	}
 	if err := validateProxyStore(tr); err != nil {
 		return err
 	}
@@ -91,6 +94,30 @@ func Run(tr config.Conf) error {
 	if err := validateAutomaticSnapshotsExpiration(tr); err != nil {
 		return err
 	}
+	if err := validateAliasesConflictPolicy(tr); err != nil {
+		return err
+	}
+	if err := validateDesktopCacheGeneration(tr); err != nil {
+		return err
+	}
+	if err := validateIdleExit(tr); err != nil {
+		return err
+	}
+	if err := validateAPIRateLimits(tr); err != nil {
+		return err
+	}
+	if err := validateJournalSettings(tr); err != nil {
+		return err
+	}
+	if err := validateStoreCacheSize(tr); err != nil {
+		return err
+	}
+	if err := validateStoreTLS(tr); err != nil {
+		return err
+	}
+	if err := validateDebugLog(tr); err != nil {
+		return err
+	}
 	// FIXME: ensure the user cannot set "core seed.loaded"
 
 	// capture cloud information
@@ -103,6 +130,16 @@ func Run(tr config.Conf) error {
 		return err
 	}
 
+	// desktop.cache-generation is mostly relevant on classic
+	if err := handleDesktopCacheGeneration(tr); err != nil {
+		return err
+	}
+
+	// daemon.idle-exit
+	if err := handleIdleExit(tr); err != nil {
+		return err
+	}
+
 	// see if it makes sense to run at all
 	if release.OnClassic {
 		// nothing to do
@@ -136,6 +173,10 @@ func Run(tr config.Conf) error {
 	if err := handleNetworkConfiguration(tr); err != nil {
 		return err
 	}
+	// journal.{max-size,persistent,rate-limit-interval,rate-limit-burst}
+	if err := handleJournalConfiguration(tr); err != nil {
+		return err
+	}
 
 	return nil
 }
@@ -0,0 +1,106 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2019 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package configcore
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/overlord/configstate/config"
+	"github.com/snapcore/snapd/strutil"
+	"github.com/snapcore/snapd/systemd"
+)
+
+// idleWakeupTimer periodically starts snapd again after it exited
+// while idle, so that it can look after the installed snaps.
+const idleWakeupTimer = "snapd.idle-wakeup.timer"
+
+// apiRateLimitOptions are the options overriding the default limits
+// of the requests of a single local client of the snapd API.
+var apiRateLimitOptions = []string{
+	"daemon.api-max-concurrent",
+	"daemon.api-rate",
+	"daemon.api-burst",
+}
+
+func init() {
+	supportedConfigurations["core.daemon.idle-exit"] = true
+	for _, name := range apiRateLimitOptions {
+		supportedConfigurations["core."+name] = true
+	}
+}
+
+func validateAPIRateLimits(tr config.Conf) error {
+	for _, name := range apiRateLimitOptions {
+		value, err := coreCfg(tr, name)
+		if err != nil {
+			return err
+		}
+		if value == "" {
+			continue
+		}
+		if n, err := strconv.Atoi(value); err != nil || n <= 0 {
+			return fmt.Errorf("%s must be a positive integer", name)
+		}
+	}
+	return nil
+}
+
+func validateIdleExit(tr config.Conf) error {
+	idleExit, err := coreCfg(tr, "daemon.idle-exit")
+	if err != nil {
+		return err
+	}
+	if idleExit == "" {
+		return nil
+	}
+	dur, err := time.ParseDuration(idleExit)
+	if err != nil {
+		return fmt.Errorf("daemon.idle-exit cannot be parsed: %v", err)
+	}
+	if dur < time.Minute {
+		return fmt.Errorf("daemon.idle-exit must be at least 1 minute")
+	}
+	return nil
+}
+
+func handleIdleExit(tr config.Conf) error {
+	if !strutil.ListContains(tr.Changes(), "core.daemon.idle-exit") {
+		return nil
+	}
+	idleExit, err := coreCfg(tr, "daemon.idle-exit")
+	if err != nil {
+		return err
+	}
+
+	sysd := systemd.New(dirs.GlobalRootDir, systemd.SystemMode, &sysdLogger{})
+	if idleExit == "" {
+		if err := sysd.Disable(idleWakeupTimer); err != nil {
+			return err
+		}
+		return sysd.Stop(idleWakeupTimer, 5*time.Minute)
+	}
+	if err := sysd.Enable(idleWakeupTimer); err != nil {
+		return err
+	}
+	return sysd.Start(idleWakeupTimer)
+}
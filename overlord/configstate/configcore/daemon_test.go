@@ -0,0 +1,127 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2019 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package configcore_test
+
+import (
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/overlord/configstate/configcore"
+)
+
+type daemonSuite struct {
+	configcoreSuite
+}
+
+var _ = Suite(&daemonSuite{})
+
+func (s *daemonSuite) SetUpTest(c *C) {
+	s.configcoreSuite.SetUpTest(c)
+	s.systemctlArgs = nil
+}
+
+func (s *daemonSuite) TestConfigureIdleExitEnables(c *C) {
+	err := configcore.Run(&mockConf{
+		state: s.state,
+		conf: map[string]interface{}{
+			"daemon.idle-exit": "15m",
+		},
+		changes: map[string]interface{}{
+			"daemon.idle-exit": "15m",
+		},
+	})
+	c.Assert(err, IsNil)
+	c.Check(s.systemctlArgs, DeepEquals, [][]string{
+		{"--root", dirs.GlobalRootDir, "enable", "snapd.idle-wakeup.timer"},
+		{"start", "snapd.idle-wakeup.timer"},
+	})
+}
+
+func (s *daemonSuite) TestConfigureIdleExitDisables(c *C) {
+	err := configcore.Run(&mockConf{
+		state: s.state,
+		conf:  map[string]interface{}{},
+		changes: map[string]interface{}{
+			"daemon.idle-exit": "",
+		},
+	})
+	c.Assert(err, IsNil)
+	c.Check(s.systemctlArgs, DeepEquals, [][]string{
+		{"--root", dirs.GlobalRootDir, "disable", "snapd.idle-wakeup.timer"},
+		{"stop", "snapd.idle-wakeup.timer"},
+		{"show", "--property=ActiveState", "snapd.idle-wakeup.timer"},
+	})
+}
+
+func (s *daemonSuite) TestConfigureIdleExitUnchanged(c *C) {
+	err := configcore.Run(&mockConf{
+		state: s.state,
+		conf: map[string]interface{}{
+			"daemon.idle-exit": "15m",
+		},
+	})
+	c.Assert(err, IsNil)
+	c.Check(s.systemctlArgs, HasLen, 0)
+}
+
+func (s *daemonSuite) TestConfigureIdleExitInvalid(c *C) {
+	for _, t := range []struct {
+		value  string
+		errMsg string
+	}{
+		{"soon", `daemon.idle-exit cannot be parsed: .*`},
+		{"30s", `daemon.idle-exit must be at least 1 minute`},
+	} {
+		err := configcore.Run(&mockConf{
+			state: s.state,
+			conf: map[string]interface{}{
+				"daemon.idle-exit": t.value,
+			},
+		})
+		c.Check(err, ErrorMatches, t.errMsg)
+	}
+	c.Check(s.systemctlArgs, HasLen, 0)
+}
+
+func (s *daemonSuite) TestConfigureAPIRateLimits(c *C) {
+	err := configcore.Run(&mockConf{
+		state: s.state,
+		conf: map[string]interface{}{
+			"daemon.api-max-concurrent": "5",
+			"daemon.api-rate":           "10",
+			"daemon.api-burst":          "20",
+		},
+	})
+	c.Assert(err, IsNil)
+}
+
+func (s *daemonSuite) TestConfigureAPIRateLimitsInvalid(c *C) {
+	for _, name := range []string{"daemon.api-max-concurrent", "daemon.api-rate", "daemon.api-burst"} {
+		for _, value := range []string{"lots", "0", "-1", "1.5"} {
+			err := configcore.Run(&mockConf{
+				state: s.state,
+				conf: map[string]interface{}{
+					name: value,
+				},
+			})
+			c.Check(err, ErrorMatches, name+" must be a positive integer", Commentf("%s=%s", name, value))
+		}
+	}
+}
@@ -0,0 +1,79 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2019 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package configcore
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/snapcore/snapd/logger"
+	"github.com/snapcore/snapd/overlord/configstate/config"
+)
+
+func init() {
+	supportedConfigurations["core.debug.log"] = true
+}
+
+var validDebugSubsystem = regexp.MustCompile(`^[a-z][a-z0-9-]*$`)
+
+// debugSubsystems returns the subsystems listed, comma separated, in
+// the debug.log option.
+func debugSubsystems(tr config.Conf) ([]string, error) {
+	debugLog, err := coreCfg(tr, "debug.log")
+	if err != nil {
+		return nil, err
+	}
+	var subsystems []string
+	for _, subsystem := range strings.Split(debugLog, ",") {
+		subsystem = strings.TrimSpace(subsystem)
+		if subsystem == "" {
+			continue
+		}
+		subsystems = append(subsystems, subsystem)
+	}
+	return subsystems, nil
+}
+
+func validateDebugLog(tr config.Conf) error {
+	subsystems, err := debugSubsystems(tr)
+	if err != nil {
+		return err
+	}
+	for _, subsystem := range subsystems {
+		if !validDebugSubsystem.MatchString(subsystem) {
+			return fmt.Errorf("cannot set debug.log: invalid subsystem %q", subsystem)
+		}
+	}
+	return nil
+}
+
+// SetupDebugLog enables the debug messages of the subsystems listed
+// in the debug.log option. Unlike the other options it is not applied
+// by Run, but only once the change of the option is committed, so
+// that a failing configuration does not leave it applied.
+func SetupDebugLog(tr config.Conf) error {
+	subsystems, err := debugSubsystems(tr)
+	if err != nil {
+		return err
+	}
+	logger.SetDebugSubsystems(subsystems)
+	return nil
+}
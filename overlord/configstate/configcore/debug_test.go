@@ -0,0 +1,83 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2019 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package configcore_test
+
+import (
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/logger"
+	"github.com/snapcore/snapd/overlord/configstate/configcore"
+)
+
+type debugSuite struct {
+	configcoreSuite
+}
+
+var _ = Suite(&debugSuite{})
+
+func (s *debugSuite) TearDownTest(c *C) {
+	logger.SetDebugSubsystems(nil)
+	s.configcoreSuite.TearDownTest(c)
+}
+
+func (s *debugSuite) TestConfigureDebugLogNotAppliedByRun(c *C) {
+	// debug.log is only applied once committed, see SetupDebugLog
+	err := configcore.Run(&mockConf{
+		state: s.state,
+		conf: map[string]interface{}{
+			"debug.log": "store, ifacestate",
+		},
+		changes: map[string]interface{}{
+			"debug.log": "store, ifacestate",
+		},
+	})
+	c.Assert(err, IsNil)
+	c.Check(logger.DebugEnabled("store"), Equals, false)
+}
+
+func (s *debugSuite) TestSetupDebugLog(c *C) {
+	err := configcore.SetupDebugLog(&mockConf{
+		state: s.state,
+		conf: map[string]interface{}{
+			"debug.log": "store, ifacestate",
+		},
+	})
+	c.Assert(err, IsNil)
+	c.Check(logger.DebugEnabled("store"), Equals, true)
+	c.Check(logger.DebugEnabled("ifacestate"), Equals, true)
+	c.Check(logger.DebugEnabled("hookstate"), Equals, false)
+
+	err = configcore.SetupDebugLog(&mockConf{
+		state: s.state,
+		conf:  map[string]interface{}{},
+	})
+	c.Assert(err, IsNil)
+	c.Check(logger.DebugEnabled("store"), Equals, false)
+}
+
+func (s *debugSuite) TestConfigureDebugLogInvalid(c *C) {
+	err := configcore.Run(&mockConf{
+		state: s.state,
+		conf: map[string]interface{}{
+			"debug.log": "store,Iface State",
+		},
+	})
+	c.Check(err, ErrorMatches, `cannot set debug.log: invalid subsystem "Iface State"`)
+}
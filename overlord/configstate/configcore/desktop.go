@@ -0,0 +1,72 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2019 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package configcore
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/osutil"
+	"github.com/snapcore/snapd/overlord/configstate/config"
+)
+
+func init() {
+	supportedConfigurations["core.desktop.cache-generation"] = true
+}
+
+func validateDesktopCacheGeneration(tr config.Conf) error {
+	mode, err := coreCfg(tr, "desktop.cache-generation")
+	if err != nil {
+		return err
+	}
+	switch mode {
+	case "", "launch", "async", "install":
+		return nil
+	}
+	return fmt.Errorf("desktop.cache-generation can only be set to 'launch', 'async' or 'install'")
+}
+
+// handleDesktopCacheGeneration exports the system-wide cache generation
+// mode, which controls when snapd updates the fontconfig caches for app
+// snaps on classic: "install" has snapd update them when the snap is
+// linked, before its apps are runnable, "async" has snapd update them
+// in a separate task once the snap is linked, and "launch" leaves them
+// to the desktop helpers on first launch. The mode is system-wide only,
+// and the caches that the desktop helpers generate within the snap
+// (gtk icon cache, ld cache, ...) are not affected by it.
+func handleDesktopCacheGeneration(tr config.Conf) error {
+	mode, err := coreCfg(tr, "desktop.cache-generation")
+	if err != nil {
+		return err
+	}
+	fn := dirs.SnapDesktopCacheGenerationFile
+	if mode == "" {
+		if err := os.Remove(fn); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(fn), 0755); err != nil {
+		return err
+	}
+	return osutil.AtomicWriteFile(fn, []byte(mode+"\n"), 0644, 0)
+}
@@ -0,0 +1,71 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2019 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package configcore_test
+
+import (
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/overlord/configstate/configcore"
+	"github.com/snapcore/snapd/release"
+	"github.com/snapcore/snapd/testutil"
+)
+
+type desktopSuite struct {
+	configcoreSuite
+}
+
+var _ = Suite(&desktopSuite{})
+
+func (s *desktopSuite) TestConfigureDesktopCacheGeneration(c *C) {
+	restore := release.MockOnClassic(true)
+	defer restore()
+
+	for _, mode := range []string{"launch", "async", "install"} {
+		err := configcore.Run(&mockConf{
+			state: s.state,
+			conf: map[string]interface{}{
+				"desktop.cache-generation": mode,
+			},
+		})
+		c.Assert(err, IsNil)
+		c.Check(dirs.SnapDesktopCacheGenerationFile, testutil.FileEquals, mode+"\n")
+	}
+
+	err := configcore.Run(&mockConf{
+		state: s.state,
+		conf: map[string]interface{}{
+			"desktop.cache-generation": "",
+		},
+	})
+	c.Assert(err, IsNil)
+	c.Check(dirs.SnapDesktopCacheGenerationFile, testutil.FileAbsent)
+}
+
+func (s *desktopSuite) TestConfigureDesktopCacheGenerationInvalid(c *C) {
+	err := configcore.Run(&mockConf{
+		state: s.state,
+		conf: map[string]interface{}{
+			"desktop.cache-generation": "sometimes",
+		},
+	})
+	c.Assert(err, ErrorMatches, `desktop.cache-generation can only be set to 'launch', 'async' or 'install'`)
+	c.Check(dirs.SnapDesktopCacheGenerationFile, testutil.FileAbsent)
+}
@@ -0,0 +1,158 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2018 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package configcore
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/osutil"
+	"github.com/snapcore/snapd/overlord/configstate/config"
+	"github.com/snapcore/snapd/strutil"
+	"github.com/snapcore/snapd/systemd"
+)
+
+func init() {
+	// add supported configuration of this module
+	supportedConfigurations["core.journal.max-size"] = true
+	supportedConfigurations["core.journal.persistent"] = true
+	supportedConfigurations["core.journal.rate-limit-interval"] = true
+	supportedConfigurations["core.journal.rate-limit-burst"] = true
+}
+
+const journalRestartTimeout = 30 * time.Second
+
+func validateJournalSettings(tr config.Conf) error {
+	_, err := journalConfig(tr)
+	return err
+}
+
+// journalConfig returns the journald settings for the journal.* options.
+func journalConfig(tr config.Conf) (map[string]string, error) {
+	config := map[string]string{}
+
+	maxSize, err := coreCfg(tr, "journal.max-size")
+	if err != nil {
+		return nil, err
+	}
+	if maxSize != "" {
+		size, err := strutil.ParseByteSize(maxSize)
+		if err != nil {
+			return nil, fmt.Errorf("journal.max-size %v", err)
+		}
+		config["SystemMaxUse"] = strconv.FormatInt(size, 10)
+	}
+
+	if err := validateBoolFlag(tr, "journal.persistent"); err != nil {
+		return nil, err
+	}
+	persistent, err := coreCfg(tr, "journal.persistent")
+	if err != nil {
+		return nil, err
+	}
+	switch persistent {
+	case "true":
+		config["Storage"] = "persistent"
+	case "false":
+		config["Storage"] = "volatile"
+	}
+
+	interval, err := coreCfg(tr, "journal.rate-limit-interval")
+	if err != nil {
+		return nil, err
+	}
+	if interval != "" {
+		dur, err := time.ParseDuration(interval)
+		if err != nil {
+			return nil, fmt.Errorf("cannot set journal rate limit interval to %q: %v", interval, err)
+		}
+		// journald only takes whole seconds, and 0 disables the
+		// rate limiting
+		if dur < 0 || (dur > 0 && dur < time.Second) {
+			return nil, fmt.Errorf("cannot set journal rate limit interval to %q: must be 0 or at least 1s", interval)
+		}
+		secs := (dur + time.Second - 1) / time.Second
+		// RateLimitIntervalSec= is only known by newer journald,
+		// the older name is understood by all versions
+		config["RateLimitInterval"] = strconv.FormatInt(int64(secs), 10)
+	}
+
+	burst, err := coreCfg(tr, "journal.rate-limit-burst")
+	if err != nil {
+		return nil, err
+	}
+	if burst != "" {
+		if _, err := strconv.ParseUint(burst, 10, 32); err != nil {
+			return nil, fmt.Errorf("journal.rate-limit-burst must be a non-negative number, not %q", burst)
+		}
+		config["RateLimitBurst"] = burst
+	}
+
+	return config, nil
+}
+
+func handleJournalConfiguration(tr config.Conf) error {
+	config, err := journalConfig(tr)
+	if err != nil {
+		return err
+	}
+
+	dir := filepath.Join(dirs.GlobalRootDir, "/etc/systemd/journald.conf.d")
+	name := "10-snapd-journal.conf"
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	dirContent := make(map[string]*osutil.FileState, 1)
+	if len(config) > 0 {
+		configStr := make([]string, 0, len(config))
+		for k, v := range config {
+			configStr = append(configStr, fmt.Sprintf("%s=%s\n", k, v))
+		}
+		// We order the variables to have predictable output
+		sort.Strings(configStr)
+		dirContent[name] = &osutil.FileState{
+			Content: []byte("[Journal]\n" + strings.Join(configStr, "")),
+			Mode:    0644,
+		}
+	}
+
+	changed, removed, err := osutil.EnsureDirState(dir, name, dirContent)
+	if err != nil {
+		return err
+	}
+	if len(changed) == 0 && len(removed) == 0 {
+		return nil
+	}
+
+	// the values were validated already, so a failure to restart
+	// journald is not caused by the new configuration
+	sysd := systemd.New(dirs.GlobalRootDir, systemd.SystemMode, &sysdLogger{})
+	if err := sysd.Restart("systemd-journald.service", journalRestartTimeout); err != nil {
+		return fmt.Errorf("cannot apply journal configuration: %v", err)
+	}
+	return nil
+}
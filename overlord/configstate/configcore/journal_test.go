@@ -0,0 +1,226 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2018 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package configcore_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/overlord/configstate/configcore"
+	"github.com/snapcore/snapd/release"
+	"github.com/snapcore/snapd/systemd"
+	"github.com/snapcore/snapd/testutil"
+)
+
+type journalSuite struct {
+	configcoreSuite
+
+	journalConf string
+}
+
+var _ = Suite(&journalSuite{})
+
+func (s *journalSuite) SetUpTest(c *C) {
+	s.configcoreSuite.SetUpTest(c)
+
+	dirs.SetRootDir(c.MkDir())
+	s.journalConf = filepath.Join(dirs.GlobalRootDir, "/etc/systemd/journald.conf.d/10-snapd-journal.conf")
+	s.systemctlArgs = nil
+}
+
+func (s *journalSuite) TearDownTest(c *C) {
+	dirs.SetRootDir("/")
+}
+
+func (s *journalSuite) TestConfigureJournal(c *C) {
+	restore := release.MockOnClassic(false)
+	defer restore()
+
+	err := configcore.Run(&mockConf{
+		state: s.state,
+		conf: map[string]interface{}{
+			"journal.max-size":            "100MB",
+			"journal.persistent":          "true",
+			"journal.rate-limit-interval": "1m",
+			"journal.rate-limit-burst":    "2000",
+		},
+	})
+	c.Assert(err, IsNil)
+	c.Check(s.journalConf, testutil.FileEquals, `[Journal]
+RateLimitBurst=2000
+RateLimitInterval=60
+Storage=persistent
+SystemMaxUse=100000000
+`)
+	c.Check(s.systemctlArgs, DeepEquals, [][]string{
+		{"stop", "systemd-journald.service"},
+		{"show", "--property=ActiveState", "systemd-journald.service"},
+		{"start", "systemd-journald.service"},
+	})
+
+	// setting the same again does not restart journald
+	s.systemctlArgs = nil
+	err = configcore.Run(&mockConf{
+		state: s.state,
+		conf: map[string]interface{}{
+			"journal.max-size":            "100MB",
+			"journal.persistent":          "true",
+			"journal.rate-limit-interval": "1m",
+			"journal.rate-limit-burst":    "2000",
+		},
+	})
+	c.Assert(err, IsNil)
+	c.Check(s.systemctlArgs, HasLen, 0)
+}
+
+func (s *journalSuite) TestConfigureJournalVolatile(c *C) {
+	restore := release.MockOnClassic(false)
+	defer restore()
+
+	err := configcore.Run(&mockConf{
+		state: s.state,
+		conf: map[string]interface{}{
+			"journal.persistent": "false",
+		},
+	})
+	c.Assert(err, IsNil)
+	c.Check(s.journalConf, testutil.FileEquals, "[Journal]\nStorage=volatile\n")
+}
+
+func (s *journalSuite) TestConfigureJournalUnset(c *C) {
+	restore := release.MockOnClassic(false)
+	defer restore()
+
+	err := os.MkdirAll(filepath.Dir(s.journalConf), 0755)
+	c.Assert(err, IsNil)
+	err = ioutil.WriteFile(s.journalConf, []byte("[Journal]\nStorage=volatile\n"), 0644)
+	c.Assert(err, IsNil)
+
+	err = configcore.Run(&mockConf{
+		state: s.state,
+		conf: map[string]interface{}{
+			"journal.persistent": "",
+		},
+	})
+	c.Assert(err, IsNil)
+	c.Check(s.journalConf, testutil.FileAbsent)
+	c.Check(s.systemctlArgs, HasLen, 3)
+}
+
+func (s *journalSuite) TestConfigureJournalBadValues(c *C) {
+	restore := release.MockOnClassic(false)
+	defer restore()
+
+	for _, t := range []struct {
+		key, val, err string
+	}{
+		{"journal.max-size", "lots", `journal.max-size cannot parse "lots": .*`},
+		{"journal.max-size", "-1MB", `journal.max-size cannot parse "-1MB": size cannot be negative`},
+		{"journal.persistent", "maybe", `journal.persistent can only be set to 'true' or 'false'`},
+		{"journal.rate-limit-interval", "-5s", `cannot set journal rate limit interval to "-5s": must be 0 or at least 1s`},
+		{"journal.rate-limit-interval", "500ms", `cannot set journal rate limit interval to "500ms": must be 0 or at least 1s`},
+		{"journal.rate-limit-interval", "soon", `cannot set journal rate limit interval to "soon": .*invalid duration.*`},
+		{"journal.rate-limit-burst", "many", `journal.rate-limit-burst must be a non-negative number, not "many"`},
+	} {
+		err := configcore.Run(&mockConf{
+			state: s.state,
+			conf: map[string]interface{}{
+				t.key: t.val,
+			},
+		})
+		c.Check(err, ErrorMatches, t.err)
+	}
+	c.Check(s.journalConf, testutil.FileAbsent)
+	c.Check(s.systemctlArgs, HasLen, 0)
+}
+
+func (s *journalSuite) TestConfigureJournalRateLimitInterval(c *C) {
+	restore := release.MockOnClassic(false)
+	defer restore()
+
+	for _, t := range []struct {
+		interval, secs string
+	}{
+		{"0", "0"},
+		{"1s", "1"},
+		// rounded up to whole seconds
+		{"1500ms", "2"},
+		{"30m", "1800"},
+	} {
+		err := configcore.Run(&mockConf{
+			state: s.state,
+			conf: map[string]interface{}{
+				"journal.rate-limit-interval": t.interval,
+			},
+		})
+		c.Assert(err, IsNil)
+		c.Check(s.journalConf, testutil.FileEquals, "[Journal]\nRateLimitInterval="+t.secs+"\n")
+	}
+}
+
+func (s *journalSuite) TestConfigureJournalRestartFailure(c *C) {
+	restore := release.MockOnClassic(false)
+	defer restore()
+
+	err := os.MkdirAll(filepath.Dir(s.journalConf), 0755)
+	c.Assert(err, IsNil)
+	err = ioutil.WriteFile(s.journalConf, []byte("[Journal]\nStorage=volatile\n"), 0644)
+	c.Assert(err, IsNil)
+
+	starts := 0
+	r := systemd.MockSystemctl(func(args ...string) ([]byte, error) {
+		if args[0] == "start" {
+			starts++
+			return nil, fmt.Errorf("boom")
+		}
+		return []byte("ActiveState=inactive"), nil
+	})
+	defer r()
+
+	err = configcore.Run(&mockConf{
+		state: s.state,
+		conf: map[string]interface{}{
+			"journal.persistent": "true",
+		},
+	})
+	c.Assert(err, ErrorMatches, `cannot apply journal configuration: boom`)
+	// the validated configuration is kept
+	c.Check(s.journalConf, testutil.FileEquals, "[Journal]\nStorage=persistent\n")
+	c.Check(starts, Equals, 1)
+}
+
+func (s *journalSuite) TestConfigureJournalClassic(c *C) {
+	restore := release.MockOnClassic(true)
+	defer restore()
+
+	err := configcore.Run(&mockConf{
+		state: s.state,
+		conf: map[string]interface{}{
+			"journal.persistent": "true",
+		},
+	})
+	c.Assert(err, IsNil)
+	c.Check(s.journalConf, testutil.FileAbsent)
+}
@@ -30,6 +30,7 @@ import (
 	"github.com/snapcore/snapd/dirs"
 	"github.com/snapcore/snapd/overlord/assertstate"
 	"github.com/snapcore/snapd/overlord/configstate/config"
+	"github.com/snapcore/snapd/overlord/configstate/proxyconf"
 )
 
 var proxyConfigKeys = map[string]bool{
@@ -94,6 +95,17 @@ func handleProxyConfiguration(tr config.Conf) error {
 	return nil
 }
 
+func validateProxyNoProxy(tr config.Conf) error {
+	noProxy, err := coreCfg(tr, "proxy.no-proxy")
+	if err != nil {
+		return err
+	}
+	if err := proxyconf.ValidateNoProxy(noProxy); err != nil {
+		return fmt.Errorf("cannot set proxy.no-proxy: %v", err)
+	}
+	return nil
+}
+
 func validateProxyStore(tr config.Conf) error {
 	proxyStore, err := coreCfg(tr, "proxy.store")
 	if err != nil {
@@ -125,6 +125,34 @@ PATH="/usr/bin"
 no_proxy=example.com,bar.com`)
 }
 
+func (s *proxySuite) TestConfigureNoProxyCIDR(c *C) {
+	restore := release.MockOnClassic(false)
+	defer restore()
+
+	s.makeMockEtcEnvironment(c)
+	err := configcore.Run(&mockConf{
+		state: s.state,
+		conf: map[string]interface{}{
+			"proxy.no-proxy": "example.com,10.0.0.0/8",
+		},
+	})
+	c.Assert(err, IsNil)
+
+	c.Check(s.mockEtcEnvironment, testutil.FileEquals, `
+PATH="/usr/bin"
+no_proxy=example.com,10.0.0.0/8`)
+}
+
+func (s *proxySuite) TestConfigureNoProxyInvalidCIDR(c *C) {
+	err := configcore.Run(&mockConf{
+		state: s.state,
+		conf: map[string]interface{}{
+			"proxy.no-proxy": "example.com,10.0.0.0/33",
+		},
+	})
+	c.Assert(err, ErrorMatches, `cannot set proxy.no-proxy: invalid CIDR range "10.0.0.0/33" in no-proxy list`)
+}
+
 func (s *proxySuite) TestConfigureProxyStore(c *C) {
 	// set to ""
 	err := configcore.Run(&mockConf{
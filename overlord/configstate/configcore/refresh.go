@@ -37,6 +37,9 @@ func init() {
 	supportedConfigurations["core.refresh.metered"] = true
 	supportedConfigurations["core.refresh.retain"] = true
 	supportedConfigurations["core.refresh.rate-limit"] = true
+	supportedConfigurations["core.refresh.blackout"] = true
+	supportedConfigurations["core.refresh.timezone"] = true
+	supportedConfigurations["core.refresh.risk-fallback"] = true
 }
 
 func validateRefreshSchedule(tr config.Conf) error {
@@ -71,6 +74,37 @@ func validateRefreshSchedule(tr config.Conf) error {
 		return fmt.Errorf("refresh.metered value %q is invalid", refreshOnMeteredStr)
 	}
 
+	refreshBlackoutStr, err := coreCfg(tr, "refresh.blackout")
+	if err != nil {
+		return err
+	}
+	if refreshBlackoutStr != "" {
+		if _, err := timeutil.ParseBlackoutWindows(refreshBlackoutStr); err != nil {
+			return fmt.Errorf("refresh.blackout cannot be parsed: %v", err)
+		}
+	}
+
+	refreshTimezoneStr, err := coreCfg(tr, "refresh.timezone")
+	if err != nil {
+		return err
+	}
+	if refreshTimezoneStr != "" {
+		if _, err := timeutil.ParseTimezoneOffset(refreshTimezoneStr); err != nil {
+			return fmt.Errorf("refresh.timezone cannot be parsed: %v", err)
+		}
+	}
+
+	refreshRiskFallbackStr, err := coreCfg(tr, "refresh.risk-fallback")
+	if err != nil {
+		return err
+	}
+	switch refreshRiskFallbackStr {
+	case "", "stable", "candidate", "beta", "edge":
+		// noop
+	default:
+		return fmt.Errorf("refresh.risk-fallback value %q is invalid", refreshRiskFallbackStr)
+	}
+
 	// check (new) refresh.timer
 	refreshTimerStr, err := coreCfg(tr, "refresh.timer")
 	if err != nil {
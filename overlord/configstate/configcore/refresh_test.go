@@ -128,6 +128,26 @@ func (s *refreshSuite) TestConfigureRefreshHoldOnMeteredHappy(c *C) {
 	c.Assert(err, IsNil)
 }
 
+func (s *refreshSuite) TestConfigureRefreshRiskFallback(c *C) {
+	for _, risk := range []string{"stable", "candidate", "beta", "edge", ""} {
+		err := configcore.Run(&mockConf{
+			state: s.state,
+			conf: map[string]interface{}{
+				"refresh.risk-fallback": risk,
+			},
+		})
+		c.Check(err, IsNil, Commentf(risk))
+	}
+
+	err := configcore.Run(&mockConf{
+		state: s.state,
+		conf: map[string]interface{}{
+			"refresh.risk-fallback": "latest/candidate",
+		},
+	})
+	c.Assert(err, ErrorMatches, `refresh\.risk-fallback value "latest/candidate" is invalid`)
+}
+
 func (s *refreshSuite) TestConfigureRefreshRetainHappy(c *C) {
 	err := configcore.Run(&mockConf{
 		state: s.state,
@@ -167,3 +187,34 @@ func (s *refreshSuite) TestConfigureRefreshRetainInvalid(c *C) {
 	})
 	c.Assert(err, ErrorMatches, `retain must be a number between 2 and 20, not "invalid"`)
 }
+
+func (s *refreshSuite) TestConfigureRefreshBlackoutHappy(c *C) {
+	err := configcore.Run(&mockConf{
+		state: s.state,
+		conf: map[string]interface{}{
+			"refresh.blackout": "2019-11-29..2019-12-02,12-20..01-02",
+			"refresh.timezone": "-05:00",
+		},
+	})
+	c.Assert(err, IsNil)
+}
+
+func (s *refreshSuite) TestConfigureRefreshBlackoutInvalid(c *C) {
+	err := configcore.Run(&mockConf{
+		state: s.state,
+		conf: map[string]interface{}{
+			"refresh.blackout": "12-24..christmas",
+		},
+	})
+	c.Assert(err, ErrorMatches, `refresh\.blackout cannot be parsed: cannot parse "christmas": not a valid date`)
+}
+
+func (s *refreshSuite) TestConfigureRefreshTimezoneInvalid(c *C) {
+	err := configcore.Run(&mockConf{
+		state: s.state,
+		conf: map[string]interface{}{
+			"refresh.timezone": "Europe/London",
+		},
+	})
+	c.Assert(err, ErrorMatches, `refresh\.timezone cannot be parsed: cannot parse "Europe/London": not a valid timezone offset`)
+}
@@ -0,0 +1,47 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2018 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package configcore
+
+import (
+	"fmt"
+
+	"github.com/snapcore/snapd/overlord/configstate/config"
+	"github.com/snapcore/snapd/strutil"
+)
+
+func init() {
+	// add supported configuration of this module
+	supportedConfigurations["core.store.cache-size"] = true
+}
+
+func validateStoreCacheSize(tr config.Conf) error {
+	cacheSize, err := coreCfg(tr, "store.cache-size")
+	if err != nil {
+		return err
+	}
+	if cacheSize == "" {
+		return nil
+	}
+	// NOTE ParseByteSize errors on negative sizes
+	if _, err := strutil.ParseByteSize(cacheSize); err != nil {
+		return fmt.Errorf("store.cache-size %v", err)
+	}
+	return nil
+}
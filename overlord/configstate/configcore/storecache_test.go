@@ -0,0 +1,56 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2018 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package configcore_test
+
+import (
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/overlord/configstate/configcore"
+)
+
+type storeCacheSuite struct {
+	configcoreSuite
+}
+
+var _ = Suite(&storeCacheSuite{})
+
+func (s *storeCacheSuite) TestConfigureStoreCacheSizeHappy(c *C) {
+	for _, size := range []string{"", "512MB", "2GB"} {
+		err := configcore.Run(&mockConf{
+			state: s.state,
+			conf: map[string]interface{}{
+				"store.cache-size": size,
+			},
+		})
+		c.Check(err, IsNil, Commentf("%q", size))
+	}
+}
+
+func (s *storeCacheSuite) TestConfigureStoreCacheSizeInvalid(c *C) {
+	for _, size := range []string{"-1", "foo", "10XB"} {
+		err := configcore.Run(&mockConf{
+			state: s.state,
+			conf: map[string]interface{}{
+				"store.cache-size": size,
+			},
+		})
+		c.Check(err, ErrorMatches, `store.cache-size .*`, Commentf("%q", size))
+	}
+}
@@ -0,0 +1,64 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2019 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package configcore
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/snapcore/snapd/overlord/configstate/config"
+	"github.com/snapcore/snapd/store"
+)
+
+func init() {
+	// add supported configuration of this module
+	supportedConfigurations["core.store.ca-certs"] = true
+	supportedConfigurations["core.store.client-cert"] = true
+	supportedConfigurations["core.store.client-key"] = true
+}
+
+func validateStoreTLS(tr config.Conf) error {
+	caCerts, err := coreCfg(tr, "store.ca-certs")
+	if err != nil {
+		return err
+	}
+	certFile, err := coreCfg(tr, "store.client-cert")
+	if err != nil {
+		return err
+	}
+	keyFile, err := coreCfg(tr, "store.client-key")
+	if err != nil {
+		return err
+	}
+	if (certFile == "") != (keyFile == "") {
+		return fmt.Errorf("store.client-cert and store.client-key must be set together")
+	}
+	for _, fn := range []string{certFile, keyFile} {
+		if fn != "" && !filepath.IsAbs(fn) {
+			return fmt.Errorf("store client certificate and key must be absolute paths, not %q", fn)
+		}
+	}
+	opts := &store.TLSOptions{
+		CACerts:        []byte(caCerts),
+		ClientCertFile: certFile,
+		ClientKeyFile:  keyFile,
+	}
+	return opts.Validate()
+}
@@ -0,0 +1,66 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2019 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package configcore_test
+
+import (
+	"path/filepath"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/overlord/configstate/configcore"
+)
+
+type storeTLSSuite struct {
+	configcoreSuite
+}
+
+var _ = Suite(&storeTLSSuite{})
+
+func (s *storeTLSSuite) TestConfigureStoreTLSUnset(c *C) {
+	err := configcore.Run(&mockConf{
+		state: s.state,
+		conf: map[string]interface{}{
+			"store.ca-certs":    "",
+			"store.client-cert": "",
+			"store.client-key":  "",
+		},
+	})
+	c.Check(err, IsNil)
+}
+
+func (s *storeTLSSuite) TestConfigureStoreTLSInvalid(c *C) {
+	missing := filepath.Join(c.MkDir(), "missing")
+	for _, t := range []struct {
+		conf map[string]interface{}
+		err  string
+	}{
+		{map[string]interface{}{"store.ca-certs": "foo"}, `cannot use store CA certificates: no PEM encoded certificates found`},
+		{map[string]interface{}{"store.client-cert": "/foo.pem"}, `store.client-cert and store.client-key must be set together`},
+		{map[string]interface{}{"store.client-key": "/foo.key"}, `store.client-cert and store.client-key must be set together`},
+		{map[string]interface{}{"store.client-cert": "foo.pem", "store.client-key": "/foo.key"}, `store client certificate and key must be absolute paths, not "foo.pem"`},
+		{map[string]interface{}{"store.client-cert": missing + ".pem", "store.client-key": missing + ".key"}, `cannot use store client certificate: open .*/missing.pem: no such file or directory`},
+	} {
+		err := configcore.Run(&mockConf{
+			state: s.state,
+			conf:  t.conf,
+		})
+		c.Check(err, ErrorMatches, t.err, Commentf("%v", t.conf))
+	}
+}
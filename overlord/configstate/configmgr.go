@@ -25,6 +25,7 @@ import (
 	"github.com/snapcore/snapd/overlord/configstate/config"
 	"github.com/snapcore/snapd/overlord/configstate/configcore"
 	"github.com/snapcore/snapd/overlord/hookstate"
+	"github.com/snapcore/snapd/overlord/state"
 )
 
 var configcoreRun = configcore.Run
@@ -51,3 +52,11 @@ func Init(hookManager *hookstate.HookManager) {
 		return configcoreRun(tr)
 	})
 }
+
+// SetupDebugLog enables the debug messages of the subsystems listed
+// in the debug.log system option, as the option is applied only when
+// it changes this is needed when snapd starts.
+func SetupDebugLog(st *state.State) error {
+	tr := config.NewTransaction(st)
+	return configcore.SetupDebugLog(tr)
+}
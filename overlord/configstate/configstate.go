@@ -35,6 +35,7 @@ import (
 
 func init() {
 	snapstate.Configure = Configure
+	hookstate.RegisterHookTimeout("configure", ConfigureHookTimeout)
 }
 
 func ConfigureHookTimeout() time.Duration {
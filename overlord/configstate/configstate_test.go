@@ -20,10 +20,13 @@
 package configstate_test
 
 import (
+	"errors"
+	"os"
 	"time"
 
 	. "gopkg.in/check.v1"
 
+	"github.com/snapcore/snapd/logger"
 	"github.com/snapcore/snapd/overlord"
 	"github.com/snapcore/snapd/overlord/configstate"
 	"github.com/snapcore/snapd/overlord/configstate/config"
@@ -72,6 +75,14 @@ var configureTests = []struct {
 	useDefaults: true,
 }}
 
+func (s *tasksetsSuite) TestConfigureHookTimeout(c *C) {
+	c.Check(hookstate.HookTimeout("configure"), Equals, 5*time.Minute)
+
+	os.Setenv("SNAPD_CONFIGURE_HOOK_TIMEOUT", "30s")
+	defer os.Unsetenv("SNAPD_CONFIGURE_HOOK_TIMEOUT")
+	c.Check(hookstate.HookTimeout("configure"), Equals, 30*time.Second)
+}
+
 func (s *tasksetsSuite) TestConfigureInstalled(c *C) {
 	s.state.Lock()
 	snapstate.Set(s.state, "test-snap", &snapstate.SnapState{
@@ -293,6 +304,48 @@ func (s *configcoreHijackSuite) TestHijack(c *C) {
 	c.Check(configcoreRan, Equals, true)
 }
 
+func (s *configcoreHijackSuite) testDebugLog(c *C, runErr error) {
+	defer logger.SetDebugSubsystems(nil)
+
+	var enabledDuringRun bool
+	r := configstate.MockConfigcoreRun(func(conf config.Conf) error {
+		enabledDuringRun = logger.DebugEnabled("store")
+		return runErr
+	})
+	defer r()
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	ts := configstate.Configure(s.state, "core", map[string]interface{}{
+		"debug.log": "store",
+	}, 0)
+	chg := s.state.NewChange("configure-core", "configure core")
+	chg.AddAll(ts)
+
+	s.state.Unlock()
+	err := s.o.Settle(5 * time.Second)
+	s.state.Lock()
+	c.Assert(err, IsNil)
+
+	c.Check(enabledDuringRun, Equals, false)
+	if runErr == nil {
+		c.Check(chg.Err(), IsNil)
+		c.Check(logger.DebugEnabled("store"), Equals, true)
+	} else {
+		c.Check(chg.Err(), ErrorMatches, `(?s).*boom.*`)
+		c.Check(logger.DebugEnabled("store"), Equals, false)
+	}
+}
+
+func (s *configcoreHijackSuite) TestDebugLogAppliedOnCommit(c *C) {
+	s.testDebugLog(c, nil)
+}
+
+func (s *configcoreHijackSuite) TestDebugLogNotAppliedOnError(c *C) {
+	s.testDebugLog(c, errors.New("boom"))
+}
+
 type miscSuite struct{}
 
 var _ = Suite(&miscSuite{})
@@ -309,3 +362,21 @@ func (s *miscSuite) TestRemappingFuncs(c *C) {
 	c.Assert(configstate.RemapSnapFromRequest("system"), Equals, "core")
 	c.Assert(configstate.RemapSnapToResponse("core"), Equals, "system")
 }
+
+func (s *miscSuite) TestSetupDebugLog(c *C) {
+	defer logger.SetDebugSubsystems(nil)
+
+	st := state.New(nil)
+	st.Lock()
+	defer st.Unlock()
+
+	c.Assert(configstate.SetupDebugLog(st), IsNil)
+	c.Check(logger.DebugEnabled("store"), Equals, false)
+
+	tr := config.NewTransaction(st)
+	c.Assert(tr.Set("core", "debug.log", "store"), IsNil)
+	tr.Commit()
+
+	c.Assert(configstate.SetupDebugLog(st), IsNil)
+	c.Check(logger.DebugEnabled("store"), Equals, true)
+}
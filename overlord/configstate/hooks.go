@@ -23,9 +23,11 @@ import (
 	"fmt"
 
 	"github.com/snapcore/snapd/overlord/configstate/config"
+	"github.com/snapcore/snapd/overlord/configstate/configcore"
 	"github.com/snapcore/snapd/overlord/hookstate"
 	"github.com/snapcore/snapd/overlord/snapstate"
 	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/strutil"
 )
 
 // configureHandler is the handler for the configure hook.
@@ -50,12 +52,18 @@ func ContextTransaction(context *hookstate.Context) *config.Transaction {
 	tr = config.NewTransaction(context.State())
 
 	context.OnDone(func() error {
+		isCore := context.InstanceName() == "core"
+		debugLogChanged := isCore && strutil.ListContains(tr.Changes(), "core.debug.log")
 		tr.Commit()
-		if context.InstanceName() == "core" {
+		if isCore {
 			// make sure the Ensure logic can process
 			// system configuration changes as soon as possible
 			context.State().EnsureBefore(0)
 		}
+		if debugLogChanged {
+			// debug.log is applied only once committed
+			return configcore.SetupDebugLog(tr)
+		}
 		return nil
 	})
 
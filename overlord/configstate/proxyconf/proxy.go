@@ -21,8 +21,10 @@ package proxyconf
 
 import (
 	"fmt"
+	"net"
 	"net/http"
 	"net/url"
+	"strings"
 
 	"github.com/snapcore/snapd/overlord/configstate/config"
 	"github.com/snapcore/snapd/overlord/state"
@@ -49,9 +51,76 @@ func (p *ProxySettings) Conf(req *http.Request) (*url.URL, error) {
 	if err != nil {
 		return nil, err
 	}
+
+	var noProxy string
+	err = tr.Get("core", "proxy.no-proxy", &noProxy)
+	if err != nil && !config.IsNoOption(err) {
+		return nil, err
+	}
+	if MatchesNoProxy(noProxy, req.URL) {
+		return nil, nil
+	}
+
 	url, err := url.Parse(proxy)
 	if err != nil {
 		return nil, err
 	}
 	return url, nil
 }
+
+// ValidateNoProxy checks that the given comma separated list of hosts
+// not to be proxied is well formed. Besides host names, which also
+// cover their subdomains, entries can be IP addresses or CIDR ranges
+// optionally followed by a port, or "*" to not proxy anything.
+func ValidateNoProxy(noProxy string) error {
+	for _, entry := range strings.Split(noProxy, ",") {
+		entry = strings.TrimSpace(entry)
+		if !strings.Contains(entry, "/") {
+			continue
+		}
+		if _, _, err := net.ParseCIDR(entry); err != nil {
+			return fmt.Errorf("invalid CIDR range %q in no-proxy list", entry)
+		}
+	}
+	return nil
+}
+
+// MatchesNoProxy returns whether the given URL should not be proxied
+// according to the given no-proxy list, see ValidateNoProxy.
+func MatchesNoProxy(noProxy string, u *url.URL) bool {
+	host := strings.ToLower(u.Hostname())
+	port := u.Port()
+	ip := net.ParseIP(host)
+	for _, entry := range strings.Split(noProxy, ",") {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+		switch {
+		case entry == "":
+			continue
+		case entry == "*":
+			return true
+		case strings.Contains(entry, "/"):
+			_, ipNet, err := net.ParseCIDR(entry)
+			if err == nil && ip != nil && ipNet.Contains(ip) {
+				return true
+			}
+			continue
+		}
+		if h, p, err := net.SplitHostPort(entry); err == nil {
+			if p != port {
+				continue
+			}
+			entry = h
+		}
+		if entryIP := net.ParseIP(entry); entryIP != nil {
+			if ip != nil && entryIP.Equal(ip) {
+				return true
+			}
+			continue
+		}
+		entry = strings.TrimPrefix(strings.TrimPrefix(entry, "*"), ".")
+		if entry != "" && (host == entry || strings.HasSuffix(host, "."+entry)) {
+			return true
+		}
+	}
+	return false
+}
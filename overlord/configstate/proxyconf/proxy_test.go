@@ -72,3 +72,98 @@ func (s *proxyconfSuite) TestProxySettings(c *C) {
 		Host:   "some-proxy:3128",
 	})
 }
+
+func (s *proxyconfSuite) TestProxySettingsPerScheme(c *C) {
+	st := state.New(nil)
+
+	st.Lock()
+	tr := config.NewTransaction(st)
+	tr.Set("core", "proxy.http", "http://some-proxy:3128")
+	tr.Set("core", "proxy.https", "http://other-proxy:8080")
+	tr.Commit()
+	st.Unlock()
+
+	proxyConf := proxyconf.New(st)
+	for _, t := range []struct {
+		url   string
+		proxy string
+	}{
+		{"http://example.com", "some-proxy:3128"},
+		{"https://example.com", "other-proxy:8080"},
+	} {
+		req, err := http.NewRequest("GET", t.url, nil)
+		c.Assert(err, IsNil)
+		proxy, err := proxyConf.Conf(req)
+		c.Assert(err, IsNil)
+		c.Check(proxy, DeepEquals, &url.URL{
+			Scheme: "http",
+			Host:   t.proxy,
+		})
+	}
+}
+
+func (s *proxyconfSuite) TestProxySettingsNoProxy(c *C) {
+	st := state.New(nil)
+
+	st.Lock()
+	tr := config.NewTransaction(st)
+	tr.Set("core", "proxy.http", "http://some-proxy:3128")
+	tr.Set("core", "proxy.no-proxy", "example.com,10.0.0.0/8")
+	tr.Commit()
+	st.Unlock()
+
+	proxyConf := proxyconf.New(st)
+	for _, t := range []struct {
+		url     string
+		proxied bool
+	}{
+		{"http://example.com", false},
+		{"http://www.example.com", false},
+		{"http://10.1.2.3:8080/foo", false},
+		{"http://example.org", true},
+		{"http://192.168.1.1", true},
+	} {
+		req, err := http.NewRequest("GET", t.url, nil)
+		c.Assert(err, IsNil)
+		proxy, err := proxyConf.Conf(req)
+		c.Assert(err, IsNil)
+		c.Check(proxy != nil, Equals, t.proxied, Commentf(t.url))
+	}
+}
+
+func (s *proxyconfSuite) TestMatchesNoProxy(c *C) {
+	for _, t := range []struct {
+		noProxy string
+		url     string
+		matches bool
+	}{
+		{"", "http://example.com", false},
+		{"*", "http://example.com", true},
+		{"example.com", "http://example.com", true},
+		{"example.com", "http://EXAMPLE.com", true},
+		{"example.com", "http://foo.example.com", true},
+		{".example.com", "http://foo.example.com", true},
+		{"*.example.com", "http://foo.example.com", true},
+		{"example.com", "http://notexample.com", false},
+		{"foo.com, example.com", "http://example.com", true},
+		{"example.com:8080", "http://example.com:8080", true},
+		{"example.com:8080", "http://example.com", false},
+		{"192.168.1.1", "http://192.168.1.1", true},
+		{"192.168.1.1", "http://192.168.1.2", false},
+		{"192.168.0.0/16", "http://192.168.1.2:3128", true},
+		{"192.168.0.0/16", "http://10.0.0.1", false},
+		{"192.168.0.0/16", "http://example.com", false},
+		{"::1", "http://[::1]:8080", true},
+		{"fd00::/8", "http://[fd12::1]", true},
+	} {
+		u, err := url.Parse(t.url)
+		c.Assert(err, IsNil)
+		c.Check(proxyconf.MatchesNoProxy(t.noProxy, u), Equals, t.matches, Commentf("%q %q", t.noProxy, t.url))
+	}
+}
+
+func (s *proxyconfSuite) TestValidateNoProxy(c *C) {
+	c.Check(proxyconf.ValidateNoProxy(""), IsNil)
+	c.Check(proxyconf.ValidateNoProxy("example.com, 10.0.0.0/8,fd00::/8"), IsNil)
+	c.Check(proxyconf.ValidateNoProxy("example.com,10.0.0.0/33"), ErrorMatches, `invalid CIDR range "10.0.0.0/33" in no-proxy list`)
+}
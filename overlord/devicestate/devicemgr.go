@@ -20,16 +20,20 @@
 package devicestate
 
 import (
+	"context"
 	"fmt"
 	"regexp"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/snapcore/snapd/asserts"
 	"github.com/snapcore/snapd/asserts/sysdb"
+	"github.com/snapcore/snapd/boot"
 	"github.com/snapcore/snapd/bootloader"
 	"github.com/snapcore/snapd/dirs"
 	"github.com/snapcore/snapd/i18n"
+	"github.com/snapcore/snapd/logger"
 	"github.com/snapcore/snapd/overlord/assertstate"
 	"github.com/snapcore/snapd/overlord/auth"
 	"github.com/snapcore/snapd/overlord/configstate/config"
@@ -39,6 +43,7 @@ import (
 	"github.com/snapcore/snapd/overlord/state"
 	"github.com/snapcore/snapd/overlord/storecontext"
 	"github.com/snapcore/snapd/release"
+	"github.com/snapcore/snapd/snap"
 	"github.com/snapcore/snapd/timings"
 )
 
@@ -46,6 +51,7 @@ import (
 // policies.
 type DeviceManager struct {
 	state      *state.State
+	hookMgr    *hookstate.HookManager
 	keypairMgr asserts.KeypairManager
 
 	// newStore can make new stores for remodeling
@@ -60,6 +66,24 @@ type DeviceManager struct {
 	becomeOperationalBackoff     time.Duration
 	registered                   bool
 	reg                          chan struct{}
+
+	notifying         bool
+	lastNotifyAttempt time.Time
+	notifyWaitGroup   sync.WaitGroup
+
+	fdeLockRan       bool
+	fdeLockWaitGroup sync.WaitGroup
+
+	bootChainWaitGroup sync.WaitGroup
+
+	// ctx is cancelled when the manager is stopped, it bounds the
+	// activities the manager runs in the background
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	// the gadget whose provisioning steps are registered
+	provisioningGadget    string
+	provisioningGadgetRev snap.Revision
 }
 
 // Manager returns a new device manager.
@@ -72,8 +96,12 @@ func Manager(s *state.State, hookManager *hookstate.HookManager, runner *state.T
 
 	}
 
+	ctx, cancel := context.WithCancel(context.Background())
 	m := &DeviceManager{
 		state:      s,
+		ctx:        ctx,
+		cancel:     cancel,
+		hookMgr:    hookManager,
 		keypairMgr: keypairMgr,
 		newStore:   newStore,
 		reg:        make(chan struct{}),
@@ -88,6 +116,8 @@ func Manager(s *state.State, hookManager *hookstate.HookManager, runner *state.T
 	}
 
 	hookManager.Register(regexp.MustCompile("^prepare-device$"), newPrepareDeviceHandler)
+	hookManager.Register(regexp.MustCompile("^fde$"), newFDEHandler)
+	hookManager.Register(regexp.MustCompile("^action-[a-z0-9](?:-?[a-z0-9])*$"), newGadgetActionHandler)
 
 	runner.AddHandler("generate-device-key", m.doGenerateDeviceKey, nil)
 	runner.AddHandler("request-serial", m.doRequestSerial, nil)
@@ -97,13 +127,13 @@ func Manager(s *state.State, hookManager *hookstate.HookManager, runner *state.T
 	// this *must* always run last and finalizes a remodel
 	runner.AddHandler("set-model", m.doSetModel, nil)
 	runner.AddCleanup("set-model", m.cleanupRemodel)
-	// There is no undo for successful gadget updates. The system is
-	// rebooted during update, if it boots up to the point where snapd runs
-	// we deem the new assets (be it bootloader or firmware) functional. The
-	// deployed boot assets must be backward compatible with reverted kernel
-	// or gadget snaps. There are no further changes to the boot assets,
-	// unless a new gadget update is deployed.
-	runner.AddHandler("update-gadget-assets", m.doUpdateGadgetAssets, nil)
+	// The system is rebooted during update, if it boots up to the point
+	// where snapd runs we deem the new assets (be it bootloader or
+	// firmware) functional. Should a later task of the change fail, the
+	// previous assets are restored from the backup copies, which are kept
+	// until the change is ready, and the system is rebooted again.
+	runner.AddHandler("update-gadget-assets", m.doUpdateGadgetAssets, m.undoUpdateGadgetAssets)
+	runner.AddCleanup("update-gadget-assets", m.cleanupUpdateGadgetAssets)
 
 	runner.AddBlocked(gadgetUpdateBlocked)
 
@@ -320,6 +350,7 @@ func (m *DeviceManager) ensureOperational() error {
 		}
 	}
 
+	var gadgetInfo *snap.Info
 	var hasPrepareDeviceHook bool
 	// if there's a gadget specified wait for it
 	if gadget != "" {
@@ -331,7 +362,7 @@ func (m *DeviceManager) ensureOperational() error {
 
 		}
 
-		gadgetInfo, err := snapstate.CurrentInfo(m.state, gadget)
+		gadgetInfo, err = snapstate.CurrentInfo(m.state, gadget)
 		if err != nil {
 			return err
 		}
@@ -365,9 +396,27 @@ func (m *DeviceManager) ensureOperational() error {
 		m.state.EnsureBefore(0)
 	}
 
+	// the device-specific provisioning steps of the gadget run
+	// after prepare-device and before registering
+	var provisioning []*state.Task
+	if gadgetInfo != nil {
+		provisioning, err = m.provisioningTasks(gadgetInfo)
+		if err != nil {
+			return err
+		}
+	}
+	lastPrepare := prepareDevice
+	for _, t := range provisioning {
+		if lastPrepare != nil {
+			t.WaitFor(lastPrepare)
+		}
+		tasks = append(tasks, t)
+		lastPrepare = t
+	}
+
 	genKey := m.state.NewTask("generate-device-key", i18n.G("Generate device key"))
-	if prepareDevice != nil {
-		genKey.WaitFor(prepareDevice)
+	if lastPrepare != nil {
+		genKey.WaitFor(lastPrepare)
 	}
 	tasks = append(tasks, genKey)
 	requestSerial := m.state.NewTask("request-serial", i18n.G("Request device serial"))
@@ -429,6 +478,8 @@ func (m *DeviceManager) ensureSeedYaml() error {
 	return nil
 }
 
+var bootRecordBootChain = boot.RecordBootChain
+
 func (m *DeviceManager) ensureBootOk() error {
 	m.state.Lock()
 	defer m.state.Unlock()
@@ -446,6 +497,16 @@ func (m *DeviceManager) ensureBootOk() error {
 			return err
 		}
 		m.bootOkRan = true
+		// record what was booted, for attestation; hashing the
+		// kernel and base snaps takes a while and does not need
+		// the state
+		m.bootChainWaitGroup.Add(1)
+		go func() {
+			defer m.bootChainWaitGroup.Done()
+			if err := bootRecordBootChain(); err != nil {
+				logger.Noticef("cannot record the boot chain: %v", err)
+			}
+		}()
 	}
 
 	if !m.bootRevisionsUpdated {
@@ -525,6 +586,9 @@ func (m *DeviceManager) Ensure() error {
 	if err := m.ensureSeedYaml(); err != nil {
 		errs = append(errs, err)
 	}
+	if err := m.ensureProvisioningSteps(); err != nil {
+		errs = append(errs, err)
+	}
 	if err := m.ensureOperational(); err != nil {
 		errs = append(errs, err)
 	}
@@ -533,10 +597,18 @@ func (m *DeviceManager) Ensure() error {
 		errs = append(errs, err)
 	}
 
+	if err := m.ensureFDELocked(); err != nil {
+		errs = append(errs, err)
+	}
+
 	if err := m.ensureSeedInConfig(); err != nil {
 		errs = append(errs, err)
 	}
 
+	if err := m.ensureDeviceServiceNotified(); err != nil {
+		errs = append(errs, err)
+	}
+
 	if len(errs) > 0 {
 		return &ensureError{errs}
 	}
@@ -544,6 +616,15 @@ func (m *DeviceManager) Ensure() error {
 	return nil
 }
 
+// Stop implements StateStopper. It cancels the activities the manager
+// runs in the background and waits for them to finish.
+func (m *DeviceManager) Stop() {
+	m.cancel()
+	m.notifyWaitGroup.Wait()
+	m.fdeLockWaitGroup.Wait()
+	m.bootChainWaitGroup.Wait()
+}
+
 func (m *DeviceManager) keyPair() (asserts.PrivateKey, error) {
 	device, err := m.device()
 	if err != nil {
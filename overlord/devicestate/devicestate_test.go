@@ -1713,6 +1713,26 @@ func (s *deviceMgrSuite) TestDeviceManagerEnsureBootOkBootloaderHappy(c *C) {
 	c.Assert(m, DeepEquals, map[string]string{"snap_mode": ""})
 }
 
+func (s *deviceMgrSuite) TestDeviceManagerEnsureBootOkRecordsBootChain(c *C) {
+	recorded := 0
+	restore := devicestate.MockBootRecordBootChain(func() error {
+		// the state is not locked while measuring
+		s.state.Lock()
+		s.state.Unlock()
+		recorded++
+		return fmt.Errorf("boom")
+	})
+	defer restore()
+
+	// errors recording the boot chain are not fatal
+	c.Assert(devicestate.EnsureBootOk(s.mgr), IsNil)
+	c.Check(recorded, Equals, 1)
+
+	// and it is recorded only once
+	c.Assert(devicestate.EnsureBootOk(s.mgr), IsNil)
+	c.Check(recorded, Equals, 1)
+}
+
 func (s *deviceMgrSuite) TestDeviceManagerEnsureBootOkUpdateBootRevisionsHappy(c *C) {
 	// simulate that we have a new core_2, tried to boot it but that failed
 	s.bootloader.SetBootVars(map[string]string{
@@ -3196,9 +3216,12 @@ func setupGadgetUpdate(c *C, st *state.State) (chg *state.Change, tsk *state.Tas
 func (s *deviceMgrSuite) TestUpdateGadgetOnCoreSimple(c *C) {
 	var updateCalled bool
 	var passedRollbackDir string
-	restore := devicestate.MockGadgetUpdate(func(current, update *gadget.Info, path string) error {
+	restore := devicestate.MockGadgetUpdate(func(current, update gadget.GadgetData, path string, observer gadget.UpdateObserver) error {
 		updateCalled = true
 		passedRollbackDir = path
+		c.Check(current.RootDir, Equals, filepath.Join(dirs.SnapMountDir, "foo-gadget/33"))
+		c.Check(update.RootDir, Equals, filepath.Join(dirs.SnapMountDir, "foo-gadget/34"))
+		c.Check(observer, IsNil)
 		st, err := os.Stat(path)
 		c.Assert(err, IsNil)
 		m := st.Mode()
@@ -3223,14 +3246,118 @@ func (s *deviceMgrSuite) TestUpdateGadgetOnCoreSimple(c *C) {
 	c.Check(updateCalled, Equals, true)
 	rollbackDir := filepath.Join(dirs.SnapRollbackDir, "foo-gadget_34")
 	c.Check(rollbackDir, Equals, passedRollbackDir)
-	// should have been removed right after update
+	// should have been removed once the change is ready
 	c.Check(osutil.IsDirectory(rollbackDir), Equals, false)
 	c.Check(s.restartRequests, DeepEquals, []state.RestartType{state.RestartSystem})
 }
 
+func (s *deviceMgrSuite) TestUpdateGadgetOnCoreUndo(c *C) {
+	restore := devicestate.MockGadgetUpdate(func(current, update gadget.GadgetData, path string, observer gadget.UpdateObserver) error {
+		return nil
+	})
+	defer restore()
+	var rollbackCalled bool
+	restore = devicestate.MockGadgetRollback(func(current, update gadget.GadgetData, path string, observer gadget.UpdateObserver) error {
+		rollbackCalled = true
+		c.Check(current.RootDir, Equals, filepath.Join(dirs.SnapMountDir, "foo-gadget/33"))
+		c.Check(update.RootDir, Equals, filepath.Join(dirs.SnapMountDir, "foo-gadget/34"))
+		c.Check(path, Equals, filepath.Join(dirs.SnapRollbackDir, "foo-gadget_34"))
+		// the backup copies are still around
+		c.Check(osutil.IsDirectory(path), Equals, true)
+		return nil
+	})
+	defer restore()
+
+	chg, t := setupGadgetUpdate(c, s.state)
+
+	s.state.Lock()
+	terr := s.state.NewTask("error-trigger", "provoking total undo")
+	terr.WaitFor(t)
+	chg.AddTask(terr)
+	s.state.Unlock()
+
+	for i := 0; i < 10; i++ {
+		s.se.Ensure()
+		s.se.Wait()
+	}
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	c.Assert(chg.IsReady(), Equals, true)
+	c.Check(chg.Err(), ErrorMatches, `(?s).*provoking total undo.*`)
+	c.Check(t.Status(), Equals, state.UndoneStatus)
+	c.Check(rollbackCalled, Equals, true)
+	c.Check(osutil.IsDirectory(filepath.Join(dirs.SnapRollbackDir, "foo-gadget_34")), Equals, false)
+	// rebooted into the new assets and back into the old ones
+	c.Check(s.restartRequests, DeepEquals, []state.RestartType{state.RestartSystem, state.RestartSystem})
+}
+
+func (s *deviceMgrSuite) TestUpdateGadgetOnCoreUndoNoUpdateNeeded(c *C) {
+	restore := devicestate.MockGadgetUpdate(func(current, update gadget.GadgetData, path string, observer gadget.UpdateObserver) error {
+		return gadget.ErrNoUpdate
+	})
+	defer restore()
+	restore = devicestate.MockGadgetRollback(func(current, update gadget.GadgetData, path string, observer gadget.UpdateObserver) error {
+		return errors.New("unexpected call")
+	})
+	defer restore()
+
+	chg, t := setupGadgetUpdate(c, s.state)
+
+	s.state.Lock()
+	terr := s.state.NewTask("error-trigger", "provoking total undo")
+	terr.WaitFor(t)
+	chg.AddTask(terr)
+	s.state.Unlock()
+
+	for i := 0; i < 10; i++ {
+		s.se.Ensure()
+		s.se.Wait()
+	}
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	c.Assert(chg.IsReady(), Equals, true)
+	c.Check(t.Status(), Equals, state.UndoneStatus)
+	c.Check(s.restartRequests, HasLen, 0)
+}
+
+func (s *deviceMgrSuite) TestUpdateGadgetOnCoreUndoRollbackFailed(c *C) {
+	restore := devicestate.MockGadgetUpdate(func(current, update gadget.GadgetData, path string, observer gadget.UpdateObserver) error {
+		return nil
+	})
+	defer restore()
+	restore = devicestate.MockGadgetRollback(func(current, update gadget.GadgetData, path string, observer gadget.UpdateObserver) error {
+		return errors.New("rollback exploded")
+	})
+	defer restore()
+
+	chg, t := setupGadgetUpdate(c, s.state)
+
+	s.state.Lock()
+	terr := s.state.NewTask("error-trigger", "provoking total undo")
+	terr.WaitFor(t)
+	chg.AddTask(terr)
+	s.state.Unlock()
+
+	for i := 0; i < 10; i++ {
+		s.se.Ensure()
+		s.se.Wait()
+	}
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	c.Assert(chg.IsReady(), Equals, true)
+	c.Check(chg.Err(), ErrorMatches, `(?s).*cannot restore the gadget assets: rollback exploded.*`)
+	c.Check(t.Status(), Equals, state.ErrorStatus)
+	// left for inspection
+	c.Check(osutil.IsDirectory(filepath.Join(dirs.SnapRollbackDir, "foo-gadget_34")), Equals, true)
+	c.Check(s.restartRequests, DeepEquals, []state.RestartType{state.RestartSystem})
+}
+
 func (s *deviceMgrSuite) TestUpdateGadgetOnCoreNoUpdateNeeded(c *C) {
 	var called bool
-	restore := devicestate.MockGadgetUpdate(func(current, update *gadget.Info, path string) error {
+	restore := devicestate.MockGadgetUpdate(func(current, update gadget.GadgetData, path string, observer gadget.UpdateObserver) error {
 		called = true
 		return gadget.ErrNoUpdate
 	})
@@ -3257,7 +3384,7 @@ func (s *deviceMgrSuite) TestUpdateGadgetOnCoreRollbackDirCreateFailed(c *C) {
 		c.Skip("this test cannot run as root (permissions are not honored)")
 	}
 
-	restore := devicestate.MockGadgetUpdate(func(current, update *gadget.Info, path string) error {
+	restore := devicestate.MockGadgetUpdate(func(current, update gadget.GadgetData, path string, observer gadget.UpdateObserver) error {
 		return errors.New("unexpected call")
 	})
 	defer restore()
@@ -3283,7 +3410,7 @@ func (s *deviceMgrSuite) TestUpdateGadgetOnCoreRollbackDirCreateFailed(c *C) {
 }
 
 func (s *deviceMgrSuite) TestUpdateGadgetOnCoreUpdateFailed(c *C) {
-	restore := devicestate.MockGadgetUpdate(func(current, update *gadget.Info, path string) error {
+	restore := devicestate.MockGadgetUpdate(func(current, update gadget.GadgetData, path string, observer gadget.UpdateObserver) error {
 		return errors.New("gadget exploded")
 	})
 	defer restore()
@@ -3306,7 +3433,7 @@ func (s *deviceMgrSuite) TestUpdateGadgetOnCoreUpdateFailed(c *C) {
 }
 
 func (s *deviceMgrSuite) TestUpdateGadgetOnCoreNotDuringFirstboot(c *C) {
-	restore := devicestate.MockGadgetUpdate(func(current, update *gadget.Info, path string) error {
+	restore := devicestate.MockGadgetUpdate(func(current, update gadget.GadgetData, path string, observer gadget.UpdateObserver) error {
 		return errors.New("unexpected call")
 	})
 	defer restore()
@@ -3350,7 +3477,7 @@ func (s *deviceMgrSuite) TestUpdateGadgetOnCoreNotDuringFirstboot(c *C) {
 }
 
 func (s *deviceMgrSuite) TestUpdateGadgetOnCoreBadGadgetYaml(c *C) {
-	restore := devicestate.MockGadgetUpdate(func(current, update *gadget.Info, path string) error {
+	restore := devicestate.MockGadgetUpdate(func(current, update gadget.GadgetData, path string, observer gadget.UpdateObserver) error {
 		return errors.New("unexpected call")
 	})
 	defer restore()
@@ -3410,7 +3537,7 @@ func (s *deviceMgrSuite) TestUpdateGadgetOnClassicErrorsOut(c *C) {
 	restore := release.MockOnClassic(true)
 	defer restore()
 
-	restore = devicestate.MockGadgetUpdate(func(current, update *gadget.Info, path string) error {
+	restore = devicestate.MockGadgetUpdate(func(current, update gadget.GadgetData, path string, observer gadget.UpdateObserver) error {
 		return errors.New("unexpected call")
 	})
 	defer restore()
@@ -3511,20 +3638,26 @@ volumes:
 
 	current, update, err = devicestate.GadgetCurrentAndUpdate(s.state, snapsup)
 	c.Assert(err, IsNil)
-	c.Assert(current, DeepEquals, &gadget.Info{
-		Volumes: map[string]gadget.Volume{
-			"pc": {
-				Bootloader: "grub",
+	c.Assert(current, DeepEquals, &gadget.GadgetData{
+		Info: &gadget.Info{
+			Volumes: map[string]gadget.Volume{
+				"pc": {
+					Bootloader: "grub",
+				},
 			},
 		},
-	})
-	c.Assert(update, DeepEquals, &gadget.Info{
-		Volumes: map[string]gadget.Volume{
-			"pc": {
-				Bootloader: "grub",
-				ID:         "123",
+		RootDir: ci.MountDir(),
+	})
+	c.Assert(update, DeepEquals, &gadget.GadgetData{
+		Info: &gadget.Info{
+			Volumes: map[string]gadget.Volume{
+				"pc": {
+					Bootloader: "grub",
+					ID:         "123",
+				},
 			},
 		},
+		RootDir: ui.MountDir(),
 	})
 }
 
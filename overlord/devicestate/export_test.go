@@ -21,6 +21,7 @@ package devicestate
 
 import (
 	"context"
+	"net/http"
 	"time"
 
 	"github.com/snapcore/snapd/asserts"
@@ -122,7 +123,17 @@ func MockPopulateStateFromSeed(f func(*state.State, timings.Measurer) ([]*state.
 }
 
 func EnsureBootOk(m *DeviceManager) error {
-	return m.ensureBootOk()
+	err := m.ensureBootOk()
+	m.bootChainWaitGroup.Wait()
+	return err
+}
+
+func MockBootRecordBootChain(f func() error) (restore func()) {
+	old := bootRecordBootChain
+	bootRecordBootChain = f
+	return func() {
+		bootRecordBootChain = old
+	}
 }
 
 func SetBootOkRan(m *DeviceManager, b bool) {
@@ -161,10 +172,38 @@ var (
 	GadgetCurrentAndUpdate = gadgetCurrentAndUpdate
 )
 
-func MockGadgetUpdate(mock func(current, update *gadget.Info, path string) error) (restore func()) {
+func MockGadgetUpdate(mock func(current, update gadget.GadgetData, path string, observer gadget.UpdateObserver) error) (restore func()) {
 	old := gadgetUpdate
 	gadgetUpdate = mock
 	return func() {
 		gadgetUpdate = old
 	}
 }
+
+func MockGadgetRollback(mock func(current, update gadget.GadgetData, path string, observer gadget.UpdateObserver) error) (restore func()) {
+	old := gadgetRollback
+	gadgetRollback = mock
+	return func() {
+		gadgetRollback = old
+	}
+}
+
+func MockNotifyHTTPClient(f func(*state.State) *http.Client) (restore func()) {
+	old := newNotifyHTTPClient
+	newNotifyHTTPClient = f
+	return func() {
+		newNotifyHTTPClient = old
+	}
+}
+
+func (m *DeviceManager) WaitDeviceNotifications() {
+	m.notifyWaitGroup.Wait()
+}
+
+func (m *DeviceManager) EnsureFDELocked() error {
+	err := m.ensureFDELocked()
+	m.fdeLockWaitGroup.Wait()
+	return err
+}
+
+var QueueDeviceNotification = queueDeviceNotification
@@ -0,0 +1,221 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2019 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package devicestate
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/snapcore/snapd/logger"
+	"github.com/snapcore/snapd/overlord/hookstate"
+	"github.com/snapcore/snapd/overlord/snapstate"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/release"
+	"github.com/snapcore/snapd/snap"
+)
+
+// The operations snapd asks an FDE helper snap to perform via its
+// "fde" hook.
+const (
+	// FDEOpSetup asks the helper to seal the given key, it must
+	// reply with the sealed key
+	FDEOpSetup = "setup"
+	// FDEOpReveal asks the helper to unseal the given sealed key,
+	// it must reply with the key
+	FDEOpReveal = "reveal"
+	// FDEOpLock asks the helper to prevent any further reveal
+	// until the next boot, it replies with nothing
+	FDEOpLock = "lock"
+)
+
+// FDERequest is the request passed to the "fde" hook of the FDE
+// helper snap, which reads it with "snapctl fde request".
+type FDERequest struct {
+	Op string `json:"op"`
+
+	Key       []byte `json:"key,omitempty"`
+	SealedKey []byte `json:"sealed-key,omitempty"`
+	KeyName   string `json:"key-name,omitempty"`
+}
+
+func (req *FDERequest) validate() error {
+	switch req.Op {
+	case FDEOpSetup:
+		if len(req.Key) == 0 {
+			return fmt.Errorf("fde %s request needs a key", req.Op)
+		}
+	case FDEOpReveal:
+		if len(req.SealedKey) == 0 {
+			return fmt.Errorf("fde %s request needs a sealed key", req.Op)
+		}
+	case FDEOpLock:
+	default:
+		return fmt.Errorf("unknown fde operation %q", req.Op)
+	}
+	return nil
+}
+
+// FDEHelperSnap returns the snap implementing device-specific full
+// disk encryption, that is the kernel or the gadget snap of the model
+// if they have an "fde" hook, with the kernel taking precedence.
+func FDEHelperSnap(st *state.State) (*snap.Info, error) {
+	deviceCtx, err := DeviceCtx(st, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	info, err := fdeHelperSnap(st, deviceCtx)
+	if err != nil {
+		return nil, err
+	}
+	if info == nil {
+		model := deviceCtx.Model()
+		return nil, fmt.Errorf("no snap provides full disk encryption support for model %s/%s", model.BrandID(), model.Model())
+	}
+	return info, nil
+}
+
+func fdeHelperSnap(st *state.State, deviceCtx snapstate.DeviceContext) (*snap.Info, error) {
+	model := deviceCtx.Model()
+	for _, name := range []string{model.Kernel(), model.Gadget()} {
+		if name == "" {
+			continue
+		}
+		info, err := snapstate.CurrentInfo(st, name)
+		if err != nil {
+			if _, ok := err.(*snap.NotInstalledError); ok {
+				continue
+			}
+			return nil, err
+		}
+		if info.Hooks["fde"] != nil {
+			return info, nil
+		}
+	}
+	return nil, nil
+}
+
+// RunFDEHook runs the "fde" hook of the FDE helper snap with the given
+// request and returns the result the hook set with "snapctl fde
+// result". The state must not be locked by the caller.
+//
+// snapd itself locks the keys once the system has booted, the setup
+// and reveal operations are for the code installing the system and
+// unlocking its disks at boot.
+func RunFDEHook(ctx context.Context, st *state.State, req *FDERequest) ([]byte, error) {
+	if err := req.validate(); err != nil {
+		return nil, err
+	}
+
+	st.Lock()
+	hookMgr := deviceMgr(st).hookMgr
+	info, err := FDEHelperSnap(st)
+	st.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	hooksup := &hookstate.HookSetup{
+		Snap:     info.InstanceName(),
+		Revision: info.Revision,
+		Hook:     "fde",
+	}
+	contextData := map[string]interface{}{
+		"fde-request": req,
+	}
+	hookCtx, err := hookMgr.EphemeralRunHook(ctx, hooksup, contextData)
+	if err != nil {
+		return nil, fmt.Errorf("cannot run fde %s: %v", req.Op, err)
+	}
+
+	hookCtx.Lock()
+	defer hookCtx.Unlock()
+	var result []byte
+	if err := hookCtx.Get("fde-result", &result); err != nil && err != state.ErrNoState {
+		return nil, err
+	}
+	if result == nil && req.Op != FDEOpLock {
+		return nil, fmt.Errorf("fde %s hook of snap %q did not set a result", req.Op, info.InstanceName())
+	}
+	return result, nil
+}
+
+// ensureFDELocked asks the FDE helper snap, if any, once per boot to
+// lock the disk encryption keys so that they cannot be revealed
+// anymore until the next boot. The hook runs in the background, not
+// to hold up the other managers.
+func (m *DeviceManager) ensureFDELocked() error {
+	if release.OnClassic || m.fdeLockRan {
+		return nil
+	}
+
+	m.state.Lock()
+	defer m.state.Unlock()
+
+	var seeded bool
+	err := m.state.Get("seeded", &seeded)
+	if err != nil && err != state.ErrNoState {
+		return err
+	}
+	if !seeded {
+		return nil
+	}
+	deviceCtx, err := DeviceCtx(m.state, nil, nil)
+	if err == state.ErrNoState {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	info, err := fdeHelperSnap(m.state, deviceCtx)
+	if err != nil {
+		return err
+	}
+	m.fdeLockRan = true
+	if info == nil {
+		return nil
+	}
+
+	m.fdeLockWaitGroup.Add(1)
+	go func() {
+		defer m.fdeLockWaitGroup.Done()
+		if _, err := RunFDEHook(m.ctx, m.state, &FDERequest{Op: FDEOpLock}); err != nil {
+			logger.Noticef("cannot lock full disk encryption keys: %v", err)
+		}
+	}()
+	return nil
+}
+
+type fdeHandler struct{}
+
+func newFDEHandler(context *hookstate.Context) hookstate.Handler {
+	return fdeHandler{}
+}
+
+func (h fdeHandler) Before() error {
+	return nil
+}
+
+func (h fdeHandler) Done() error {
+	return nil
+}
+
+func (h fdeHandler) Error(err error) error {
+	return nil
+}
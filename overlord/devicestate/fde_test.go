@@ -0,0 +1,250 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2019 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package devicestate_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	. "gopkg.in/check.v1"
+	"gopkg.in/tomb.v2"
+
+	"github.com/snapcore/snapd/overlord/auth"
+	"github.com/snapcore/snapd/overlord/devicestate"
+	"github.com/snapcore/snapd/overlord/devicestate/devicestatetest"
+	"github.com/snapcore/snapd/overlord/hookstate"
+	"github.com/snapcore/snapd/overlord/snapstate"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/snap/snaptest"
+)
+
+func (s *deviceMgrSuite) setupFDEModel(c *C, kernelYaml, gadgetYaml string) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	s.makeModelAssertionInState(c, "canonical", "pc", map[string]interface{}{
+		"architecture": "amd64",
+		"kernel":       "pc-kernel",
+		"gadget":       "pc",
+	})
+	devicestatetest.SetDevice(s.state, &auth.DeviceState{
+		Brand: "canonical",
+		Model: "pc",
+	})
+
+	for _, t := range []struct {
+		name     string
+		snapType snap.Type
+		yaml     string
+	}{
+		{"pc-kernel", snap.TypeKernel, kernelYaml},
+		{"pc", snap.TypeGadget, gadgetYaml},
+	} {
+		si := &snap.SideInfo{RealName: t.name, Revision: snap.R(1)}
+		snaptest.MockSnap(c, t.yaml, si)
+		snapstate.Set(s.state, t.name, &snapstate.SnapState{
+			SnapType: string(t.snapType),
+			Active:   true,
+			Sequence: []*snap.SideInfo{si},
+			Current:  si.Revision,
+		})
+	}
+}
+
+const (
+	fdeKernelYaml   = "name: pc-kernel\nversion: 1\ntype: kernel\nhooks:\n fde:\n"
+	fdeGadgetYaml   = "name: pc\nversion: 1\ntype: gadget\nhooks:\n fde:\n"
+	noFDEKernelYaml = "name: pc-kernel\nversion: 1\ntype: kernel\n"
+	noFDEGadgetYaml = "name: pc\nversion: 1\ntype: gadget\n"
+)
+
+func (s *deviceMgrSuite) TestFDEHelperSnap(c *C) {
+	s.setupFDEModel(c, fdeKernelYaml, fdeGadgetYaml)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	info, err := devicestate.FDEHelperSnap(s.state)
+	c.Assert(err, IsNil)
+	c.Check(info.InstanceName(), Equals, "pc-kernel")
+}
+
+func (s *deviceMgrSuite) TestFDEHelperSnapGadget(c *C) {
+	s.setupFDEModel(c, noFDEKernelYaml, fdeGadgetYaml)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	info, err := devicestate.FDEHelperSnap(s.state)
+	c.Assert(err, IsNil)
+	c.Check(info.InstanceName(), Equals, "pc")
+}
+
+func (s *deviceMgrSuite) TestFDEHelperSnapNone(c *C) {
+	s.setupFDEModel(c, noFDEKernelYaml, noFDEGadgetYaml)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	_, err := devicestate.FDEHelperSnap(s.state)
+	c.Check(err, ErrorMatches, "no snap provides full disk encryption support for model canonical/pc")
+}
+
+func (s *deviceMgrSuite) TestRunFDEHookSetup(c *C) {
+	s.setupFDEModel(c, fdeKernelYaml, noFDEGadgetYaml)
+
+	var gotReq map[string]interface{}
+	restore := hookstate.MockRunHook(func(ctx *hookstate.Context, _ *tomb.Tomb) ([]byte, error) {
+		c.Check(ctx.InstanceName(), Equals, "pc-kernel")
+		c.Check(ctx.HookName(), Equals, "fde")
+		ctx.Lock()
+		defer ctx.Unlock()
+		var raw json.RawMessage
+		c.Assert(ctx.Get("fde-request", &raw), IsNil)
+		c.Assert(json.Unmarshal(raw, &gotReq), IsNil)
+		ctx.Set("fde-result", []byte("sealed-key"))
+		return nil, nil
+	})
+	defer restore()
+
+	result, err := devicestate.RunFDEHook(context.Background(), s.state, &devicestate.FDERequest{
+		Op:      devicestate.FDEOpSetup,
+		Key:     []byte("key"),
+		KeyName: "ubuntu-data",
+	})
+	c.Assert(err, IsNil)
+	c.Check(string(result), Equals, "sealed-key")
+	c.Check(gotReq, DeepEquals, map[string]interface{}{
+		"op":       "setup",
+		"key":      "a2V5",
+		"key-name": "ubuntu-data",
+	})
+}
+
+func (s *deviceMgrSuite) TestRunFDEHookLock(c *C) {
+	s.setupFDEModel(c, fdeKernelYaml, noFDEGadgetYaml)
+
+	restore := hookstate.MockRunHook(func(ctx *hookstate.Context, _ *tomb.Tomb) ([]byte, error) {
+		return nil, nil
+	})
+	defer restore()
+
+	result, err := devicestate.RunFDEHook(context.Background(), s.state, &devicestate.FDERequest{
+		Op: devicestate.FDEOpLock,
+	})
+	c.Assert(err, IsNil)
+	c.Check(result, IsNil)
+}
+
+func (s *deviceMgrSuite) TestRunFDEHookErrors(c *C) {
+	s.setupFDEModel(c, fdeKernelYaml, noFDEGadgetYaml)
+
+	hookErr := errors.New("boom")
+	restore := hookstate.MockRunHook(func(ctx *hookstate.Context, _ *tomb.Tomb) ([]byte, error) {
+		return []byte("output"), hookErr
+	})
+	defer restore()
+
+	_, err := devicestate.RunFDEHook(context.Background(), s.state, &devicestate.FDERequest{
+		Op:        devicestate.FDEOpReveal,
+		SealedKey: []byte("sealed"),
+	})
+	c.Check(err, ErrorMatches, `cannot run fde reveal: run hook "fde": .*`)
+
+	// no result set
+	hookErr = nil
+	_, err = devicestate.RunFDEHook(context.Background(), s.state, &devicestate.FDERequest{
+		Op:        devicestate.FDEOpReveal,
+		SealedKey: []byte("sealed"),
+	})
+	c.Check(err, ErrorMatches, `fde reveal hook of snap "pc-kernel" did not set a result`)
+
+	// invalid requests
+	_, err = devicestate.RunFDEHook(context.Background(), s.state, &devicestate.FDERequest{Op: devicestate.FDEOpSetup})
+	c.Check(err, ErrorMatches, "fde setup request needs a key")
+	_, err = devicestate.RunFDEHook(context.Background(), s.state, &devicestate.FDERequest{Op: devicestate.FDEOpReveal})
+	c.Check(err, ErrorMatches, "fde reveal request needs a sealed key")
+	_, err = devicestate.RunFDEHook(context.Background(), s.state, &devicestate.FDERequest{Op: "frob"})
+	c.Check(err, ErrorMatches, `unknown fde operation "frob"`)
+}
+
+func (s *deviceMgrSuite) TestEnsureFDELocked(c *C) {
+	s.setupFDEModel(c, fdeKernelYaml, noFDEGadgetYaml)
+
+	var ops []string
+	restore := hookstate.MockRunHook(func(ctx *hookstate.Context, _ *tomb.Tomb) ([]byte, error) {
+		c.Check(ctx.InstanceName(), Equals, "pc-kernel")
+		ctx.Lock()
+		defer ctx.Unlock()
+		var req map[string]interface{}
+		c.Assert(ctx.Get("fde-request", &req), IsNil)
+		ops = append(ops, req["op"].(string))
+		return nil, nil
+	})
+	defer restore()
+
+	// nothing before seeding
+	c.Assert(s.mgr.EnsureFDELocked(), IsNil)
+	c.Check(ops, HasLen, 0)
+
+	s.state.Lock()
+	s.state.Set("seeded", true)
+	s.state.Unlock()
+
+	c.Assert(s.mgr.EnsureFDELocked(), IsNil)
+	c.Check(ops, DeepEquals, []string{"lock"})
+
+	// only once per boot
+	c.Assert(s.mgr.EnsureFDELocked(), IsNil)
+	c.Check(ops, DeepEquals, []string{"lock"})
+}
+
+func (s *deviceMgrSuite) TestEnsureFDELockedNoHelper(c *C) {
+	s.setupFDEModel(c, noFDEKernelYaml, noFDEGadgetYaml)
+
+	restore := hookstate.MockRunHook(func(ctx *hookstate.Context, _ *tomb.Tomb) ([]byte, error) {
+		c.Fatalf("unexpected hook %q", ctx.HookName())
+		return nil, nil
+	})
+	defer restore()
+
+	s.state.Lock()
+	s.state.Set("seeded", true)
+	s.state.Unlock()
+
+	c.Assert(s.mgr.EnsureFDELocked(), IsNil)
+}
+
+func (s *deviceMgrSuite) TestEnsureFDELockedHookFails(c *C) {
+	s.setupFDEModel(c, fdeKernelYaml, noFDEGadgetYaml)
+
+	calls := 0
+	restore := hookstate.MockRunHook(func(ctx *hookstate.Context, _ *tomb.Tomb) ([]byte, error) {
+		calls++
+		return nil, errors.New("boom")
+	})
+	defer restore()
+
+	s.state.Lock()
+	s.state.Set("seeded", true)
+	s.state.Unlock()
+
+	// failures are only logged
+	c.Assert(s.mgr.EnsureFDELocked(), IsNil)
+	c.Check(calls, Equals, 1)
+}
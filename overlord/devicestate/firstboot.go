@@ -27,10 +27,12 @@ import (
 	"path/filepath"
 	"sort"
 
+	"github.com/snapcore/snapd/arch"
 	"github.com/snapcore/snapd/asserts"
 	"github.com/snapcore/snapd/asserts/snapasserts"
 	"github.com/snapcore/snapd/dirs"
 	"github.com/snapcore/snapd/i18n"
+	"github.com/snapcore/snapd/logger"
 	"github.com/snapcore/snapd/osutil"
 	"github.com/snapcore/snapd/overlord/assertstate"
 	"github.com/snapcore/snapd/overlord/devicestate/internal"
@@ -50,6 +52,9 @@ func installSeedSnap(st *state.State, sn *snap.SeedSnap, flags snapstate.Flags,
 	if sn.DevMode {
 		flags.DevMode = true
 	}
+	if sn.Architecture != "" && sn.Architecture != arch.UbuntuArchitecture() {
+		flags.CompatibleArchitecture = true
+	}
 
 	path := filepath.Join(dirs.SnapSeedDir, "snaps", sn.File)
 
@@ -73,7 +78,7 @@ func installSeedSnap(st *state.State, sn *snap.SeedSnap, flags snapstate.Flags,
 		sideInfo.Contact = sn.Contact
 	}
 
-	return snapstate.InstallPath(st, &sideInfo, path, "", sn.Channel, flags)
+	return snapstate.InstallPathWithCohort(st, &sideInfo, path, "", sn.Channel, sn.CohortKey, flags)
 }
 
 func trivialSeeding(st *state.State, markSeeded *state.Task) []*state.TaskSet {
@@ -120,9 +125,28 @@ func populateStateFromSeedImpl(st *state.State, tm timings.Measurer) ([]*state.T
 		return nil, err
 	}
 
+	if err := enforceSeedValidationSets(st, seed.ValidationSets); err != nil {
+		return nil, err
+	}
+
+	// the preseeded artifacts are only an optimization, when they
+	// diverge from the seed seeding happens as usual
+	preseeded, err := checkPreseededArtifacts(st, model, seed)
+	if preseeded {
+		if err != nil {
+			logger.Noticef("cannot use the preseeded artifacts, seeding normally: %v", err)
+			markSeeded.Logf("Cannot use the preseeded artifacts, seeding normally: %v", err)
+		} else {
+			markSeeded.Logf("Preseeded artifacts match the seed")
+		}
+	}
+
 	required := getAllRequiredSnapsForModel(model)
 	seeding := make(map[string]*snap.SeedSnap, len(seed.Snaps))
 	for _, sn := range seed.Snaps {
+		if sn.Architecture != "" && !arch.IsCompatibleArchitecture(model.Architecture(), sn.Architecture) {
+			return nil, fmt.Errorf("cannot seed snap %q: architecture %q is incompatible with the model architecture %q", sn.Name, sn.Architecture, model.Architecture())
+		}
 		seeding[sn.Name] = sn
 	}
 	alreadySeeded := make(map[string]bool, 3)
@@ -271,6 +295,29 @@ func populateStateFromSeedImpl(st *state.State, tm timings.Measurer) ([]*state.T
 	return tsAll, nil
 }
 
+// enforceSeedValidationSets tracks in enforce mode the validation sets
+// the seed was prepared against, their assertions come with the seed.
+func enforceSeedValidationSets(st *state.State, sets []*snap.SeedValidationSet) error {
+	for _, vs := range sets {
+		tr := &assertstate.ValidationSetTracking{
+			AccountID: vs.AccountID,
+			Name:      vs.Name,
+			Mode:      assertstate.Enforce,
+			Current:   vs.Sequence,
+		}
+		if vs.Pinned {
+			tr.PinnedAt = vs.Sequence
+		}
+		if _, err := assertstate.ValidationSetAssertion(st, tr); err != nil {
+			return fmt.Errorf("cannot find validation set %s/%s at sequence %d in the seed: %v", vs.AccountID, vs.Name, vs.Sequence, err)
+		}
+		if err := assertstate.UpdateValidationSet(st, tr); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func readAsserts(fn string, batch *assertstate.Batch) ([]*asserts.Ref, error) {
 	f, err := os.Open(fn)
 	if err != nil {
@@ -50,6 +50,7 @@ import (
 	"github.com/snapcore/snapd/overlord/snapstate"
 	"github.com/snapcore/snapd/overlord/state"
 	"github.com/snapcore/snapd/release"
+	"github.com/snapcore/snapd/seed/seedtest"
 	"github.com/snapcore/snapd/snap"
 	"github.com/snapcore/snapd/snap/snaptest"
 	"github.com/snapcore/snapd/systemd"
@@ -65,6 +66,8 @@ type FirstBootTestSuite struct {
 
 	brands *assertstest.SigningAccounts
 
+	seed *seedtest.TestingSeed
+
 	overlord *overlord.Overlord
 
 	perfTimings timings.Measurer
@@ -102,6 +105,14 @@ func (s *FirstBootTestSuite) SetUpTest(c *C) {
 		"verification": "verified",
 	})
 
+	s.seed = &seedtest.TestingSeed{
+		SeedSnaps: seedtest.SeedSnaps{
+			StoreSigning: s.storeSigning,
+			Brands:       s.brands,
+		},
+		SeedDir: dirs.SnapSeedDir,
+	}
+
 	s.restoreBackends = ifacestate.MockSecurityBackends(nil)
 
 	ovld, err := overlord.New(nil)
@@ -235,6 +246,108 @@ func (s *FirstBootTestSuite) TestPopulateFromSeedOnClassicEmptySeedYaml(c *C) {
 	c.Assert(err, ErrorMatches, "cannot proceed, no snaps to seed")
 }
 
+func (s *FirstBootTestSuite) TestPopulateFromSeedValidationSets(c *C) {
+	restore := release.MockOnClassic(true)
+	defer restore()
+
+	ovld, err := overlord.New(nil)
+	c.Assert(err, IsNil)
+	st := ovld.State()
+
+	assertsChain := s.makeModelAssertionChain(c, "my-model-classic", nil)
+	for i, as := range assertsChain {
+		fn := filepath.Join(dirs.SnapSeedDir, "assertions", strconv.Itoa(i))
+		err := ioutil.WriteFile(fn, asserts.Encode(as), 0644)
+		c.Assert(err, IsNil)
+	}
+	for _, seq := range []string{"2", "5"} {
+		vs, err := s.storeSigning.Sign(asserts.ValidationSetType, map[string]interface{}{
+			"series":     "16",
+			"account-id": "can0nical",
+			"name":       "set-" + seq,
+			"sequence":   seq,
+			"snaps": []interface{}{
+				map[string]interface{}{
+					"name": "foo",
+					"id":   "foosnapidididididididididididid1",
+				},
+			},
+			"timestamp": time.Now().UTC().Format(time.RFC3339),
+		}, nil, "")
+		c.Assert(err, IsNil)
+		fn := filepath.Join(dirs.SnapSeedDir, "assertions", "set-"+seq)
+		err = ioutil.WriteFile(fn, asserts.Encode(vs), 0644)
+		c.Assert(err, IsNil)
+	}
+
+	err = ioutil.WriteFile(filepath.Join(dirs.SnapSeedDir, "seed.yaml"), []byte(`
+validation-sets:
+ - account-id: can0nical
+   name: set-2
+   sequence: 2
+   pinned: true
+ - account-id: can0nical
+   name: set-5
+   sequence: 5
+`), 0644)
+	c.Assert(err, IsNil)
+
+	st.Lock()
+	defer st.Unlock()
+
+	// no snaps, but the validation sets are tracked before that
+	_, err = devicestate.PopulateStateFromSeedImpl(st, s.perfTimings)
+	c.Assert(err, ErrorMatches, "cannot proceed, no snaps to seed")
+
+	vsmap, err := assertstate.ValidationSets(st)
+	c.Assert(err, IsNil)
+	c.Check(vsmap, DeepEquals, map[string]*assertstate.ValidationSetTracking{
+		"can0nical/set-2": {
+			AccountID: "can0nical",
+			Name:      "set-2",
+			Mode:      assertstate.Enforce,
+			PinnedAt:  2,
+			Current:   2,
+		},
+		"can0nical/set-5": {
+			AccountID: "can0nical",
+			Name:      "set-5",
+			Mode:      assertstate.Enforce,
+			Current:   5,
+		},
+	})
+}
+
+func (s *FirstBootTestSuite) TestPopulateFromSeedValidationSetMissing(c *C) {
+	restore := release.MockOnClassic(true)
+	defer restore()
+
+	ovld, err := overlord.New(nil)
+	c.Assert(err, IsNil)
+	st := ovld.State()
+
+	assertsChain := s.makeModelAssertionChain(c, "my-model-classic", nil)
+	for i, as := range assertsChain {
+		fn := filepath.Join(dirs.SnapSeedDir, "assertions", strconv.Itoa(i))
+		err := ioutil.WriteFile(fn, asserts.Encode(as), 0644)
+		c.Assert(err, IsNil)
+	}
+
+	err = ioutil.WriteFile(filepath.Join(dirs.SnapSeedDir, "seed.yaml"), []byte(`
+validation-sets:
+ - account-id: can0nical
+   name: base-set
+   sequence: 1
+`), 0644)
+	c.Assert(err, IsNil)
+
+	st.Lock()
+	defer st.Unlock()
+
+	_, err = devicestate.PopulateStateFromSeedImpl(st, s.perfTimings)
+	c.Assert(err, ErrorMatches, "cannot find validation set can0nical/base-set at sequence 1 in the seed: .*")
+}
+
 func (s *FirstBootTestSuite) TestPopulateFromSeedOnClassicNoSeedYamlWithCloudInstanceData(c *C) {
 	restore := release.MockOnClassic(true)
 	defer restore()
@@ -324,48 +437,7 @@ func (s *FirstBootTestSuite) TestPopulateFromSeedErrorsOnState(c *C) {
 }
 
 func (s *FirstBootTestSuite) makeAssertedSnap(c *C, snapYaml string, files [][]string, revision snap.Revision, developerID string) (snapFname string, snapDecl *asserts.SnapDeclaration, snapRev *asserts.SnapRevision) {
-	info, err := snap.InfoFromSnapYaml([]byte(snapYaml))
-	c.Assert(err, IsNil)
-	snapName := info.InstanceName()
-
-	mockSnapFile := snaptest.MakeTestSnapWithFiles(c, snapYaml, files)
-	snapFname = filepath.Base(mockSnapFile)
-
-	targetFile := filepath.Join(dirs.SnapSeedDir, "snaps", snapFname)
-	err = os.Rename(mockSnapFile, targetFile)
-	c.Assert(err, IsNil)
-
-	snapID := (snapName + "-snap-" + strings.Repeat("id", 20))[:32]
-	// FIXME: snapd is special in the interface policy code and it
-	//        identified by its snap-id. so we fake the real snap-id
-	//        here. Instead we should add a "type: snapd" for snaps.
-	if snapName == "snapd" {
-		snapID = "PMrrV4ml8uWuEUDBT8dSGnKUYbevVhc4"
-	}
-
-	declA, err := s.storeSigning.Sign(asserts.SnapDeclarationType, map[string]interface{}{
-		"series":       "16",
-		"snap-id":      snapID,
-		"publisher-id": developerID,
-		"snap-name":    snapName,
-		"timestamp":    time.Now().UTC().Format(time.RFC3339),
-	}, nil, "")
-	c.Assert(err, IsNil)
-
-	sha3_384, size, err := asserts.SnapFileSHA3_384(targetFile)
-	c.Assert(err, IsNil)
-
-	revA, err := s.storeSigning.Sign(asserts.SnapRevisionType, map[string]interface{}{
-		"snap-sha3-384": sha3_384,
-		"snap-size":     fmt.Sprintf("%d", size),
-		"snap-id":       snapID,
-		"developer-id":  developerID,
-		"snap-revision": revision.String(),
-		"timestamp":     time.Now().UTC().Format(time.RFC3339),
-	}, nil, "")
-	c.Assert(err, IsNil)
-
-	return snapFname, declA.(*asserts.SnapDeclaration), revA.(*asserts.SnapRevision)
+	return s.seed.MakeAssertedSnap(c, snapYaml, files, revision, developerID)
 }
 
 func checkSeedTasks(c *C, tsAll []*state.TaskSet) {
@@ -478,6 +550,7 @@ snaps:
    file: %s
    devmode: true
    contact: mailto:some.guy@example.com
+   cohort-key: COHORT
  - name: local
    unasserted: true
    file: %s
@@ -583,6 +656,7 @@ func (s *FirstBootTestSuite) TestPopulateFromSeedHappy(c *C) {
 	c.Assert(err, IsNil)
 	c.Assert(snapst.DevMode, Equals, true)
 	c.Assert(snapst.Required, Equals, true)
+	c.Check(snapst.CohortKey, Equals, "COHORT")
 
 	// check local
 	info, err = snapstate.CurrentInfo(state, "local")
@@ -647,19 +721,7 @@ func (s *FirstBootTestSuite) TestPopulateFromSeedMissingBootloader(c *C) {
 }
 
 func writeAssertionsToFile(fn string, assertions []asserts.Assertion) {
-	multifn := filepath.Join(dirs.SnapSeedDir, "assertions", fn)
-	f, err := os.Create(multifn)
-	if err != nil {
-		panic(err)
-	}
-	defer f.Close()
-	enc := asserts.NewEncoder(f)
-	for _, a := range assertions {
-		err := enc.Encode(a)
-		if err != nil {
-			panic(err)
-		}
-	}
+	seedtest.WriteAssertions(filepath.Join(dirs.SnapSeedDir, "assertions", fn), assertions...)
 }
 
 func (s *FirstBootTestSuite) TestPopulateFromSeedHappyMultiAssertsFiles(c *C) {
@@ -0,0 +1,136 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2019 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package devicestate
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/snapcore/snapd/i18n"
+	"github.com/snapcore/snapd/overlord/hookstate"
+	"github.com/snapcore/snapd/overlord/snapstate"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/release"
+	"github.com/snapcore/snapd/snap"
+)
+
+// GadgetAction is a maintenance action of the device declared by the
+// gadget and implemented by its action-<name> hook.
+type GadgetAction struct {
+	Name    string
+	Summary string
+}
+
+type byGadgetActionName []*GadgetAction
+
+func (a byGadgetActionName) Len() int           { return len(a) }
+func (a byGadgetActionName) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
+func (a byGadgetActionName) Less(i, j int) bool { return a[i].Name < a[j].Name }
+
+func gadgetActionHook(name string) string {
+	return "action-" + name
+}
+
+// gadgetWithActions returns the gadget snap of the device together
+// with the actions it declares that it also implements.
+func gadgetWithActions(st *state.State) (*snap.Info, []*GadgetAction, error) {
+	deviceCtx, err := DeviceCtx(st, nil, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	info, err := snapstate.GadgetInfo(st, deviceCtx)
+	if err != nil {
+		return nil, nil, err
+	}
+	gi, err := snap.ReadGadgetInfo(info, release.OnClassic)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	actions := make([]*GadgetAction, 0, len(gi.Actions))
+	for name, action := range gi.Actions {
+		if info.Hooks[gadgetActionHook(name)] == nil {
+			// declared but not implemented
+			continue
+		}
+		actions = append(actions, &GadgetAction{
+			Name:    name,
+			Summary: action.Summary,
+		})
+	}
+	sort.Sort(byGadgetActionName(actions))
+	return info, actions, nil
+}
+
+// GadgetActions returns the maintenance actions declared and
+// implemented by the gadget of the device, sorted by name.
+func GadgetActions(st *state.State) ([]*GadgetAction, error) {
+	_, actions, err := gadgetWithActions(st)
+	return actions, err
+}
+
+// RunGadgetAction returns a task set running the hook of the gadget
+// implementing the given maintenance action.
+func RunGadgetAction(st *state.State, name string) (*state.TaskSet, error) {
+	info, actions, err := gadgetWithActions(st)
+	if err != nil {
+		return nil, err
+	}
+	found := false
+	for _, action := range actions {
+		if action.Name == name {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("gadget %q has no action %q", info.InstanceName(), name)
+	}
+
+	if err := snapstate.CheckChangeConflict(st, info.InstanceName(), nil); err != nil {
+		return nil, err
+	}
+
+	summary := fmt.Sprintf(i18n.G("Run %q action of gadget %q"), name, info.InstanceName())
+	hooksup := &hookstate.HookSetup{
+		Snap: info.InstanceName(),
+		Hook: gadgetActionHook(name),
+	}
+	task := hookstate.HookTask(st, summary, hooksup, nil)
+	return state.NewTaskSet(task), nil
+}
+
+type gadgetActionHandler struct{}
+
+func newGadgetActionHandler(context *hookstate.Context) hookstate.Handler {
+	return gadgetActionHandler{}
+}
+
+func (h gadgetActionHandler) Before() error {
+	return nil
+}
+
+func (h gadgetActionHandler) Done() error {
+	return nil
+}
+
+func (h gadgetActionHandler) Error(err error) error {
+	return nil
+}
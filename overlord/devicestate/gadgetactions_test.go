@@ -0,0 +1,149 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2019 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package devicestate_test
+
+import (
+	. "gopkg.in/check.v1"
+	"gopkg.in/tomb.v2"
+
+	"github.com/snapcore/snapd/overlord/auth"
+	"github.com/snapcore/snapd/overlord/devicestate"
+	"github.com/snapcore/snapd/overlord/devicestate/devicestatetest"
+	"github.com/snapcore/snapd/overlord/hookstate"
+	"github.com/snapcore/snapd/overlord/snapstate"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/snap/snaptest"
+)
+
+const actionsGadgetYaml = `
+volumes:
+  pc:
+    bootloader: grub
+actions:
+  blink-led:
+    summary: Blink the identification LED
+  export-diagnostics:
+    summary: Export diagnostics
+  not-implemented:
+    summary: Declared without a hook
+`
+
+func (s *deviceMgrSuite) setupGadgetActions(c *C) {
+	// avoid seeding and registration
+	s.state.Set("seeded", true)
+	s.makeModelAssertionInState(c, "canonical", "pc", map[string]interface{}{
+		"architecture": "amd64",
+		"kernel":       "pc-kernel",
+		"gadget":       "pc",
+	})
+	devicestatetest.SetDevice(s.state, &auth.DeviceState{
+		Brand:  "canonical",
+		Model:  "pc",
+		Serial: "serial",
+	})
+
+	si := &snap.SideInfo{RealName: "pc", Revision: snap.R(1)}
+	snaptest.MockSnapWithFiles(c, `name: pc
+version: 1
+type: gadget
+hooks:
+ action-export-diagnostics:
+ action-blink-led:
+`, si, [][]string{
+		{"meta/gadget.yaml", actionsGadgetYaml},
+	})
+	snapstate.Set(s.state, "pc", &snapstate.SnapState{
+		SnapType: "gadget",
+		Active:   true,
+		Sequence: []*snap.SideInfo{si},
+		Current:  si.Revision,
+	})
+}
+
+func (s *deviceMgrSuite) TestGadgetActions(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+	s.setupGadgetActions(c)
+
+	actions, err := devicestate.GadgetActions(s.state)
+	c.Assert(err, IsNil)
+	c.Check(actions, DeepEquals, []*devicestate.GadgetAction{
+		{Name: "blink-led", Summary: "Blink the identification LED"},
+		{Name: "export-diagnostics", Summary: "Export diagnostics"},
+	})
+}
+
+func (s *deviceMgrSuite) TestGadgetActionsNoGadget(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	s.makeModelAssertionInState(c, "canonical", "pc", map[string]interface{}{
+		"architecture": "amd64",
+		"classic":      "true",
+	})
+	devicestatetest.SetDevice(s.state, &auth.DeviceState{
+		Brand: "canonical",
+		Model: "pc",
+	})
+
+	_, err := devicestate.GadgetActions(s.state)
+	c.Check(err, Equals, state.ErrNoState)
+}
+
+func (s *deviceMgrSuite) TestRunGadgetAction(c *C) {
+	var hooks []string
+	restore := hookstate.MockRunHook(func(ctx *hookstate.Context, _ *tomb.Tomb) ([]byte, error) {
+		c.Check(ctx.InstanceName(), Equals, "pc")
+		hooks = append(hooks, ctx.HookName())
+		return nil, nil
+	})
+	defer restore()
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	s.setupGadgetActions(c)
+
+	ts, err := devicestate.RunGadgetAction(s.state, "blink-led")
+	c.Assert(err, IsNil)
+	c.Assert(ts.Tasks(), HasLen, 1)
+	c.Check(ts.Tasks()[0].Summary(), Equals, `Run "blink-led" action of gadget "pc"`)
+	chg := s.state.NewChange("gadget-action", "...")
+	chg.AddAll(ts)
+
+	s.state.Unlock()
+	err = s.o.Settle(settleTimeout)
+	s.state.Lock()
+	c.Assert(err, IsNil)
+
+	c.Check(chg.Status(), Equals, state.DoneStatus, Commentf("%v", chg.Err()))
+	c.Check(hooks, DeepEquals, []string{"action-blink-led"})
+}
+
+func (s *deviceMgrSuite) TestRunGadgetActionUnknown(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+	s.setupGadgetActions(c)
+
+	for _, name := range []string{"reboot-bmc", "not-implemented"} {
+		_, err := devicestate.RunGadgetAction(s.state, name)
+		c.Check(err, ErrorMatches, `gadget "pc" has no action "`+name+`"`)
+	}
+}
@@ -117,7 +117,11 @@ func (m *DeviceManager) doSetModel(t *state.Task, _ *tomb.Tomb) error {
 		//       bootable base snap.
 	}
 
-	return remodCtx.Finish()
+	if err := remodCtx.Finish(); err != nil {
+		return err
+	}
+	queueDeviceNotification(st, deviceEventRemodel)
+	return nil
 }
 
 func (m *DeviceManager) cleanupRemodel(t *state.Task, _ *tomb.Tomb) error {
@@ -312,6 +316,7 @@ func (rc *initialRegistrationContext) FinishRegistration(serial *asserts.Serial)
 		return err
 	}
 	rc.deviceMgr.markRegistered()
+	queueDeviceNotification(rc.deviceMgr.state, deviceEventRegistration)
 
 	// make sure we timely consider anything that was blocked on
 	// registration
@@ -872,24 +877,23 @@ func makeRollbackDir(name string) (string, error) {
 	return rollbackDir, nil
 }
 
-func currentGadgetInfo(snapst *snapstate.SnapState) (*gadget.Info, error) {
-	var gi *gadget.Info
-
+func currentGadgetInfo(snapst *snapstate.SnapState) (*gadget.GadgetData, error) {
 	currentInfo, err := snapst.CurrentInfo()
 	if err != nil && err != snapstate.ErrNoCurrent {
 		return nil, err
 	}
-	if currentInfo != nil {
-		const onClassic = false
-		gi, err = snap.ReadGadgetInfo(currentInfo, onClassic)
-		if err != nil {
-			return nil, err
-		}
+	if currentInfo == nil {
+		return nil, nil
 	}
-	return gi, nil
+	const onClassic = false
+	gi, err := snap.ReadGadgetInfo(currentInfo, onClassic)
+	if err != nil {
+		return nil, err
+	}
+	return &gadget.GadgetData{Info: gi, RootDir: currentInfo.MountDir()}, nil
 }
 
-func pendingGadgetInfo(snapsup *snapstate.SnapSetup) (*gadget.Info, error) {
+func pendingGadgetInfo(snapsup *snapstate.SnapSetup) (*gadget.GadgetData, error) {
 	info, err := snap.ReadInfo(snapsup.InstanceName(), snapsup.SideInfo)
 	if err != nil {
 		return nil, err
@@ -899,39 +903,40 @@ func pendingGadgetInfo(snapsup *snapstate.SnapSetup) (*gadget.Info, error) {
 	if err != nil {
 		return nil, err
 	}
-	return update, nil
+	return &gadget.GadgetData{Info: update, RootDir: info.MountDir()}, nil
 }
 
-func gadgetCurrentAndUpdate(st *state.State, snapsup *snapstate.SnapSetup) (current *gadget.Info, update *gadget.Info, err error) {
+func gadgetCurrentAndUpdate(st *state.State, snapsup *snapstate.SnapSetup) (current *gadget.GadgetData, update *gadget.GadgetData, err error) {
 	snapst, err := snapState(st, snapsup.InstanceName())
 	if err != nil {
 		return nil, nil, err
 	}
 
-	currentInfo, err := currentGadgetInfo(snapst)
+	currentData, err := currentGadgetInfo(snapst)
 	if err != nil {
 		return nil, nil, err
 	}
 
-	if currentInfo == nil {
+	if currentData == nil {
 		// don't bother reading update if there is no current
 		return nil, nil, nil
 	}
 
-	newInfo, err := pendingGadgetInfo(snapsup)
+	newData, err := pendingGadgetInfo(snapsup)
 	if err != nil {
 		return nil, nil, err
 	}
 
-	return currentInfo, newInfo, nil
+	return currentData, newData, nil
 }
 
 var (
-	gadgetUpdate = nopGadgetOp
+	gadgetUpdate   = gadget.Update
+	gadgetRollback = gadget.Rollback
 )
 
-func nopGadgetOp(current, update *gadget.Info, rollbackRootDir string) error {
-	return nil
+func gadgetRollbackDirName(snapsup *snapstate.SnapSetup) string {
+	return fmt.Sprintf("%v_%v", snapsup.InstanceName(), snapsup.SideInfo.Revision)
 }
 
 func (m *DeviceManager) doUpdateGadgetAssets(t *state.Task, _ *tomb.Tomb) error {
@@ -957,30 +962,97 @@ func (m *DeviceManager) doUpdateGadgetAssets(t *state.Task, _ *tomb.Tomb) error
 		return nil
 	}
 
-	snapRollbackDir, err := makeRollbackDir(fmt.Sprintf("%v_%v", snapsup.InstanceName(), snapsup.SideInfo.Revision))
+	snapRollbackDir, err := makeRollbackDir(gadgetRollbackDirName(snapsup))
 	if err != nil {
 		return fmt.Errorf("cannot prepare update rollback directory: %v", err)
 	}
 
 	st.Unlock()
-	err = gadgetUpdate(current, update, snapRollbackDir)
+	// TODO: pass an observer resealing the encryption keys once the
+	// boot assets are sealed to, there are no sealed keys yet
+	err = gadgetUpdate(*current, *update, snapRollbackDir, nil)
 	st.Lock()
 	if err != nil {
 		if err == gadget.ErrNoUpdate {
 			// no update needed
 			t.Logf("No gadget assets update needed")
+			removeGadgetRollbackDir(snapRollbackDir)
 			return nil
 		}
 		return err
 	}
 
+	// the backup copies are kept until the change is ready so that
+	// the update can be undone
+	t.Set("gadget-assets-updated", true)
 	t.SetStatus(state.DoneStatus)
 
-	if err := os.RemoveAll(snapRollbackDir); err != nil && !os.IsNotExist(err) {
-		logger.Noticef("failed to remove gadget update rollback directory %q: %v", snapRollbackDir, err)
+	st.RequestRestart(state.RestartSystem)
+
+	return nil
+}
+
+func (m *DeviceManager) undoUpdateGadgetAssets(t *state.Task, _ *tomb.Tomb) error {
+	st := t.State()
+	st.Lock()
+	defer st.Unlock()
+
+	var updated bool
+	if err := t.Get("gadget-assets-updated", &updated); err != nil && err != state.ErrNoState {
+		return err
+	}
+	if !updated {
+		return nil
+	}
+
+	snapsup, err := snapstate.TaskSnapSetup(t)
+	if err != nil {
+		return err
+	}
+	// the old gadget is current again by now
+	current, update, err := gadgetCurrentAndUpdate(st, snapsup)
+	if err != nil {
+		return err
+	}
+	if current == nil {
+		return fmt.Errorf("internal error: cannot undo gadget assets update without a current gadget")
+	}
+	snapRollbackDir := filepath.Join(dirs.SnapRollbackDir, gadgetRollbackDirName(snapsup))
+
+	st.Unlock()
+	err = gadgetRollback(*current, *update, snapRollbackDir, nil)
+	st.Lock()
+	if err != nil {
+		return fmt.Errorf("cannot restore the gadget assets: %v", err)
 	}
 
+	t.Set("gadget-assets-updated", false)
+	t.SetStatus(state.UndoneStatus)
+
 	st.RequestRestart(state.RestartSystem)
 
 	return nil
 }
+
+func (m *DeviceManager) cleanupUpdateGadgetAssets(t *state.Task, _ *tomb.Tomb) error {
+	st := t.State()
+	st.Lock()
+	defer st.Unlock()
+
+	snapsup, err := snapstate.TaskSnapSetup(t)
+	if err != nil {
+		return err
+	}
+	if t.Status() == state.ErrorStatus {
+		// left for inspection
+		return nil
+	}
+	removeGadgetRollbackDir(filepath.Join(dirs.SnapRollbackDir, gadgetRollbackDirName(snapsup)))
+	return nil
+}
+
+func removeGadgetRollbackDir(dir string) {
+	if err := os.RemoveAll(dir); err != nil && !os.IsNotExist(err) {
+		logger.Noticef("failed to remove gadget update rollback directory %q: %v", dir, err)
+	}
+}
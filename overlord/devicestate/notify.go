@@ -0,0 +1,263 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2018 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package devicestate
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/snapcore/snapd/asserts"
+	"github.com/snapcore/snapd/httputil"
+	"github.com/snapcore/snapd/logger"
+	"github.com/snapcore/snapd/overlord/configstate/config"
+	"github.com/snapcore/snapd/overlord/configstate/proxyconf"
+	"github.com/snapcore/snapd/overlord/snapstate"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/strutil"
+)
+
+// device events the device service can be notified about; there is no
+// notification for factory resets as those are not supported by snapd
+const (
+	deviceEventRegistration = "registration"
+	deviceEventRemodel      = "remodel"
+)
+
+// deviceNotification is a notification to the device service pending
+// delivery.
+type deviceNotification struct {
+	Event      string `json:"event"`
+	Tentatives int    `json:"tentatives,omitempty"`
+}
+
+// queueDeviceNotification records that the device service is to be
+// notified about the given event. Notifications are sent in the
+// background by the device manager, independently of the change that
+// caused them, so that an unreachable device service never holds up
+// or fails a change.
+func queueDeviceNotification(st *state.State, event string) {
+	var pending []deviceNotification
+	if err := st.Get("device-notifications", &pending); err != nil && err != state.ErrNoState {
+		logger.Noticef("cannot queue device service notification of %s: %v", event, err)
+		return
+	}
+	pending = append(pending, deviceNotification{Event: event})
+	st.Set("device-notifications", pending)
+	st.EnsureBefore(0)
+}
+
+// notifyURL returns the URL of the callback configured via the
+// device-service.notify-url option of the installed gadget, or nil
+// if there is none.
+func notifyURL(st *state.State) (*url.URL, error) {
+	deviceCtx, err := DeviceCtx(st, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	gadgetInfo, err := snapstate.GadgetInfo(st, deviceCtx)
+	if err == state.ErrNoState {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var notifyURI string
+	tr := config.NewTransaction(st)
+	if err := tr.GetMaybe(gadgetInfo.InstanceName(), "device-service.notify-url", &notifyURI); err != nil {
+		return nil, err
+	}
+	if notifyURI == "" {
+		return nil, nil
+	}
+	u, err := url.Parse(notifyURI)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse device service notification URL %q: %v", notifyURI, err)
+	}
+	if u.Scheme != "https" {
+		return nil, fmt.Errorf("cannot use device service notification URL %q: only https is supported", notifyURI)
+	}
+	return u, nil
+}
+
+// deviceNotificationPayload returns the model and serial assertions of
+// the device, which are signed by the brand or its serial vault, followed
+// by a device-session-request for the event signed with the device key,
+// so that the receiver can check that the notification comes from the
+// device itself.
+func (m *DeviceManager) deviceNotificationPayload(event string) ([]byte, error) {
+	model, err := findModel(m.state)
+	if err != nil {
+		return nil, err
+	}
+	serial, err := findSerial(m.state, nil)
+	if err != nil {
+		return nil, err
+	}
+	privKey, err := m.keyPair()
+	if err == state.ErrNoState {
+		return nil, fmt.Errorf("internal error: inconsistent state with serial but no device key")
+	}
+	if err != nil {
+		return nil, err
+	}
+	req, err := asserts.SignWithoutAuthority(asserts.DeviceSessionRequestType, map[string]interface{}{
+		"brand-id":     serial.BrandID(),
+		"model":        serial.Model(),
+		"serial":       serial.Serial(),
+		"nonce":        strutil.MakeRandomString(32),
+		"device-event": event,
+		"timestamp":    time.Now().UTC().Format(time.RFC3339),
+	}, nil, privKey)
+	if err != nil {
+		return nil, fmt.Errorf("cannot sign device notification: %v", err)
+	}
+
+	buf := new(bytes.Buffer)
+	enc := asserts.NewEncoder(buf)
+	for _, a := range []asserts.Assertion{model, serial, req} {
+		if err := enc.Encode(a); err != nil {
+			return nil, fmt.Errorf("cannot encode %s assertion: %v", a.Type().Name, err)
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// ensureDeviceServiceNotified sends in the background the oldest pending
+// device service notification, if any, unless one is already in flight or
+// the last attempt failed less than the retry interval ago.
+func (m *DeviceManager) ensureDeviceServiceNotified() error {
+	m.state.Lock()
+	defer m.state.Unlock()
+
+	if m.notifying || m.ctx.Err() != nil {
+		return nil
+	}
+	if !m.lastNotifyAttempt.IsZero() && m.lastNotifyAttempt.Add(retryInterval).After(time.Now()) {
+		return nil
+	}
+
+	var pending []deviceNotification
+	err := m.state.Get("device-notifications", &pending)
+	if err == state.ErrNoState || len(pending) == 0 {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	event := pending[0].Event
+
+	u, err := notifyURL(m.state)
+	if err == nil && u == nil {
+		// nothing to notify
+		m.deviceNotificationDone(nil)
+		return nil
+	}
+	var payload []byte
+	if err == nil {
+		payload, err = m.deviceNotificationPayload(event)
+	}
+	if err != nil {
+		logger.Noticef("cannot notify device service of %s: %v", event, err)
+		m.deviceNotificationDone(nil)
+		return nil
+	}
+
+	client := newNotifyHTTPClient(m.state)
+	m.notifying = true
+	m.lastNotifyAttempt = time.Now()
+	ctx := m.ctx
+	m.notifyWaitGroup.Add(1)
+	go func() {
+		defer m.notifyWaitGroup.Done()
+		err := postDeviceNotification(ctx, client, u, event, payload)
+		if ctx.Err() != nil {
+			// the manager is stopping, the notification is
+			// still pending and is sent again on the next start
+			return
+		}
+
+		m.state.Lock()
+		defer m.state.Unlock()
+		m.notifying = false
+		if err != nil {
+			logger.Noticef("cannot notify device service of %s: %v", event, err)
+		}
+		m.deviceNotificationDone(err)
+	}()
+	return nil
+}
+
+// deviceNotificationDone drops the oldest pending device service
+// notification, unless sending it failed with sendErr and it can still
+// be retried.
+func (m *DeviceManager) deviceNotificationDone(sendErr error) {
+	var pending []deviceNotification
+	if err := m.state.Get("device-notifications", &pending); err != nil || len(pending) == 0 {
+		return
+	}
+	if sendErr != nil {
+		pending[0].Tentatives++
+		if pending[0].Tentatives < maxTentatives {
+			m.state.Set("device-notifications", pending)
+			m.state.EnsureBefore(retryInterval)
+			return
+		}
+		logger.Noticef("cannot notify device service of %s, giving up", pending[0].Event)
+	}
+	pending = pending[1:]
+	m.state.Set("device-notifications", pending)
+	m.lastNotifyAttempt = time.Time{}
+	if len(pending) > 0 {
+		m.state.EnsureBefore(0)
+	}
+}
+
+var newNotifyHTTPClient = func(st *state.State) *http.Client {
+	return httputil.NewHTTPClient(&httputil.ClientOptions{
+		Timeout:    30 * time.Second,
+		MayLogBody: true,
+		Proxy:      proxyconf.New(st).Conf,
+	})
+}
+
+func postDeviceNotification(ctx context.Context, client *http.Client, u *url.URL, event string, body []byte) error {
+	req, err := http.NewRequest("POST", u.String(), bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("internal error: cannot create device notification request %q", u)
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("User-Agent", httputil.UserAgent())
+	req.Header.Set("Content-Type", asserts.MediaType)
+	req.Header.Set("Snap-Device-Event", event)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,350 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2018 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package devicestate_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/asserts"
+	"github.com/snapcore/snapd/overlord/auth"
+	"github.com/snapcore/snapd/overlord/configstate/config"
+	"github.com/snapcore/snapd/overlord/devicestate"
+	"github.com/snapcore/snapd/overlord/devicestate/devicestatetest"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/snap"
+)
+
+type deviceNotification struct {
+	event       string
+	contentType string
+	assertions  []asserts.Assertion
+}
+
+// mockNotifyServer returns a TLS server recording the device
+// notifications it receives and replying with the given status.
+func (s *deviceMgrSuite) mockNotifyServer(c *C, status int) (srv *httptest.Server, notifications func() []deviceNotification, restore func()) {
+	var mu sync.Mutex
+	var received []deviceNotification
+	srv = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c.Check(r.Method, Equals, "POST")
+		c.Check(r.URL.Path, Equals, "/notify")
+		body, err := ioutil.ReadAll(r.Body)
+		c.Assert(err, IsNil)
+		n := deviceNotification{
+			event:       r.Header.Get("Snap-Device-Event"),
+			contentType: r.Header.Get("Content-Type"),
+		}
+		dec := asserts.NewDecoder(bytes.NewReader(body))
+		for {
+			a, err := dec.Decode()
+			if err != nil {
+				break
+			}
+			n.assertions = append(n.assertions, a)
+		}
+		mu.Lock()
+		received = append(received, n)
+		mu.Unlock()
+		w.WriteHeader(status)
+	}))
+	restoreClient := devicestate.MockNotifyHTTPClient(func(*state.State) *http.Client {
+		return srv.Client()
+	})
+	notifications = func() []deviceNotification {
+		mu.Lock()
+		defer mu.Unlock()
+		return received
+	}
+	restore = func() {
+		restoreClient()
+		srv.Close()
+	}
+	return srv, notifications, restore
+}
+
+func (s *deviceMgrSuite) setNotifyURL(c *C, gadgetName, url string) {
+	tr := config.NewTransaction(s.state)
+	c.Assert(tr.Set(gadgetName, "device-service.notify-url", url), IsNil)
+	tr.Commit()
+}
+
+func (s *deviceMgrSuite) checkDeviceNotification(c *C, n deviceNotification, event, serialN string) {
+	c.Check(n.event, Equals, event)
+	c.Check(n.contentType, Equals, asserts.MediaType)
+	c.Assert(n.assertions, HasLen, 3)
+	c.Check(n.assertions[0].Type(), Equals, asserts.ModelType)
+	c.Check(n.assertions[0].HeaderString("model"), Equals, "pc")
+	c.Assert(n.assertions[1].Type(), Equals, asserts.SerialType)
+	serial := n.assertions[1].(*asserts.Serial)
+	c.Check(serial.Serial(), Equals, serialN)
+	// the notification is signed with the device key
+	c.Assert(n.assertions[2].Type(), Equals, asserts.DeviceSessionRequestType)
+	req := n.assertions[2].(*asserts.DeviceSessionRequest)
+	c.Check(asserts.SignatureCheck(req, serial.DeviceKey()), IsNil)
+	c.Check(req.Serial(), Equals, serialN)
+	c.Check(req.HeaderString("device-event"), Equals, event)
+	c.Check(req.Nonce(), Not(Equals), "")
+}
+
+func (s *deviceMgrSuite) pendingDeviceNotifications(c *C) []map[string]interface{} {
+	var pending []map[string]interface{}
+	err := s.state.Get("device-notifications", &pending)
+	if err != state.ErrNoState {
+		c.Assert(err, IsNil)
+	}
+	return pending
+}
+
+func (s *deviceMgrSuite) TestFullDeviceRegistrationNotifiesDeviceService(c *C) {
+	r1 := devicestate.MockKeyLength(testKeyLength)
+	defer r1()
+
+	mockServer := s.mockServer(c, "REQID-1", nil)
+	defer mockServer.Close()
+
+	r2 := devicestate.MockBaseStoreURL(mockServer.URL)
+	defer r2()
+
+	notifyServer, notifications, r3 := s.mockNotifyServer(c, 200)
+	defer r3()
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	s.makeModelAssertionInState(c, "canonical", "pc", map[string]interface{}{
+		"architecture": "amd64",
+		"kernel":       "pc-kernel",
+		"gadget":       "pc",
+	})
+	devicestatetest.SetDevice(s.state, &auth.DeviceState{
+		Brand: "canonical",
+		Model: "pc",
+	})
+	devicestatetest.MockGadget(c, s.state, "pc", snap.R(2), nil)
+	s.setNotifyURL(c, "pc", notifyServer.URL+"/notify")
+	s.state.Set("seeded", true)
+
+	// runs the whole device registration process
+	s.state.Unlock()
+	s.settle(c)
+	s.mgr.WaitDeviceNotifications()
+	s.state.Lock()
+
+	becomeOperational := s.findBecomeOperationalChange()
+	c.Assert(becomeOperational, NotNil)
+	c.Check(becomeOperational.Err(), IsNil)
+	// the notification is sent outside of the change
+	for _, t := range becomeOperational.Tasks() {
+		c.Check(t.Kind(), Not(Equals), "notify-device-service")
+	}
+
+	got := notifications()
+	c.Assert(got, HasLen, 1)
+	s.checkDeviceNotification(c, got[0], "registration", "9999")
+	c.Check(s.pendingDeviceNotifications(c), HasLen, 0)
+}
+
+func (s *deviceMgrSuite) setupDeviceForNotification(c *C) {
+	s.makeModelAssertionInState(c, "canonical", "pc", map[string]interface{}{
+		"architecture": "amd64",
+		"kernel":       "pc-kernel",
+		"gadget":       "pc",
+	})
+	s.makeSerialAssertionInState(c, "canonical", "pc", "serialserialserial")
+	devicestate.KeypairManager(s.mgr).Put(devKey)
+	devicestatetest.SetDevice(s.state, &auth.DeviceState{
+		Brand:  "canonical",
+		Model:  "pc",
+		Serial: "serialserialserial",
+		KeyID:  devKey.PublicKey().ID(),
+	})
+	devicestatetest.MockGadget(c, s.state, "pc", snap.R(2), nil)
+	s.state.Set("seeded", true)
+}
+
+func (s *deviceMgrSuite) ensureDeviceNotified(c *C) {
+	s.state.Unlock()
+	defer s.state.Lock()
+	c.Assert(s.mgr.Ensure(), IsNil)
+	s.mgr.WaitDeviceNotifications()
+}
+
+func (s *deviceMgrSuite) TestNotifyDeviceServiceRetriesAndGivesUp(c *C) {
+	r1 := devicestate.MockRetryInterval(0)
+	defer r1()
+	r2 := devicestate.MockMaxTentatives(2)
+	defer r2()
+
+	notifyServer, notifications, r3 := s.mockNotifyServer(c, 503)
+	defer r3()
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	s.setupDeviceForNotification(c)
+	s.setNotifyURL(c, "pc", notifyServer.URL+"/notify")
+	devicestate.QueueDeviceNotification(s.state, "remodel")
+
+	s.ensureDeviceNotified(c)
+	c.Check(notifications(), HasLen, 1)
+	c.Check(s.pendingDeviceNotifications(c), DeepEquals, []map[string]interface{}{
+		{"event": "remodel", "tentatives": 1.0},
+	})
+
+	s.ensureDeviceNotified(c)
+	got := notifications()
+	c.Assert(got, HasLen, 2)
+	s.checkDeviceNotification(c, got[1], "remodel", "serialserialserial")
+	c.Check(s.pendingDeviceNotifications(c), HasLen, 0)
+
+	// nothing more is sent
+	s.ensureDeviceNotified(c)
+	c.Check(notifications(), HasLen, 2)
+}
+
+func (s *deviceMgrSuite) TestNotifyDeviceServiceStoppedManager(c *C) {
+	received := make(chan struct{}, 1)
+	done := make(chan struct{})
+	notifyServer := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received <- struct{}{}
+		// the device service is slow
+		<-done
+	}))
+	defer notifyServer.Close()
+	defer close(done)
+	restore := devicestate.MockNotifyHTTPClient(func(*state.State) *http.Client {
+		return notifyServer.Client()
+	})
+	defer restore()
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	s.setupDeviceForNotification(c)
+	s.setNotifyURL(c, "pc", notifyServer.URL+"/notify")
+	devicestate.QueueDeviceNotification(s.state, "remodel")
+
+	s.state.Unlock()
+	c.Assert(s.mgr.Ensure(), IsNil)
+	<-received
+	// stopping the manager cancels the notification in flight and
+	// waits for it
+	s.mgr.Stop()
+	c.Assert(s.mgr.Ensure(), IsNil)
+	s.state.Lock()
+
+	// the notification is still pending and was not counted as a
+	// failed attempt
+	c.Check(s.pendingDeviceNotifications(c), DeepEquals, []map[string]interface{}{
+		{"event": "remodel"},
+	})
+	// and nothing else was sent
+	c.Check(received, HasLen, 0)
+}
+
+func (s *deviceMgrSuite) TestNotifyDeviceServiceInOrder(c *C) {
+	notifyServer, notifications, r1 := s.mockNotifyServer(c, 200)
+	defer r1()
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	s.setupDeviceForNotification(c)
+	s.setNotifyURL(c, "pc", notifyServer.URL+"/notify")
+	devicestate.QueueDeviceNotification(s.state, "registration")
+	devicestate.QueueDeviceNotification(s.state, "remodel")
+
+	s.ensureDeviceNotified(c)
+	s.ensureDeviceNotified(c)
+
+	got := notifications()
+	c.Assert(got, HasLen, 2)
+	s.checkDeviceNotification(c, got[0], "registration", "serialserialserial")
+	s.checkDeviceNotification(c, got[1], "remodel", "serialserialserial")
+	c.Check(s.pendingDeviceNotifications(c), HasLen, 0)
+}
+
+func (s *deviceMgrSuite) TestNotifyDeviceServiceUsesInstalledGadget(c *C) {
+	notifyServer, notifications, r1 := s.mockNotifyServer(c, 200)
+	defer r1()
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	s.setupDeviceForNotification(c)
+	// only the configuration of the gadget of the device counts
+	s.setNotifyURL(c, "other-gadget", notifyServer.URL+"/notify")
+	devicestate.QueueDeviceNotification(s.state, "registration")
+
+	s.ensureDeviceNotified(c)
+	c.Check(notifications(), HasLen, 0)
+	c.Check(s.pendingDeviceNotifications(c), HasLen, 0)
+
+	s.setNotifyURL(c, "pc", "http://example.com/notify")
+	devicestate.QueueDeviceNotification(s.state, "registration")
+	s.ensureDeviceNotified(c)
+	c.Check(notifications(), HasLen, 0)
+	c.Check(s.pendingDeviceNotifications(c), HasLen, 0)
+}
+
+func (s *deviceMgrSuite) TestRemodelNotifiesDeviceService(c *C) {
+	notifyServer, notifications, r1 := s.mockNotifyServer(c, 200)
+	defer r1()
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	s.state.Set("refresh-privacy-key", "some-privacy-key")
+
+	s.setupDeviceForNotification(c)
+	s.setNotifyURL(c, "pc", notifyServer.URL+"/notify")
+
+	new := s.brands.Model("canonical", "pc", map[string]interface{}{
+		"architecture": "amd64",
+		"kernel":       "pc-kernel",
+		"gadget":       "pc",
+		"revision":     "1",
+	})
+	chg, err := devicestate.Remodel(s.state, new)
+	c.Assert(err, IsNil)
+
+	// the notification is not part of the change
+	tl := chg.Tasks()
+	c.Assert(tl, HasLen, 1)
+	c.Assert(tl[0].Kind(), Equals, "set-model")
+	c.Check(s.pendingDeviceNotifications(c), HasLen, 0)
+
+	s.state.Unlock()
+	s.settle(c)
+	s.mgr.WaitDeviceNotifications()
+	s.state.Lock()
+
+	c.Assert(chg.Err(), IsNil)
+	got := notifications()
+	c.Assert(got, HasLen, 1)
+	s.checkDeviceNotification(c, got[0], "remodel", "serialserialserial")
+	c.Check(got[0].assertions[0].Revision(), Equals, 1)
+	c.Check(s.pendingDeviceNotifications(c), HasLen, 0)
+}
@@ -0,0 +1,197 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2019 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package devicestate
+
+import (
+	"crypto"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/snapcore/snapd/asserts"
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/interfaces"
+	"github.com/snapcore/snapd/osutil"
+	"github.com/snapcore/snapd/overlord/assertstate"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/release"
+	"github.com/snapcore/snapd/snap"
+)
+
+const (
+	// preseedSystemKeyArtifact is the system-key the security
+	// profiles were generated for when preseeding.
+	preseedSystemKeyArtifact = "system-key"
+	// preseedSnapRevisionsArtifact maps the names of the preseeded
+	// snaps to their revisions, as YAML.
+	preseedSnapRevisionsArtifact = "snap-revisions"
+)
+
+// preseedArtifactsDir returns the directory of the seed with the
+// artifacts produced by preseeding the image.
+func preseedArtifactsDir() string {
+	return filepath.Join(dirs.SnapSeedDir, "preseed")
+}
+
+// checkPreseededArtifacts cross-checks the artifacts produced by
+// preseeding the image against their preseed assertion, the running
+// system and the seed. It returns false if the image was not
+// preseeded, otherwise any error describes why the preseeded
+// artifacts cannot be used.
+func checkPreseededArtifacts(st *state.State, model *asserts.Model, seed *snap.Seed) (preseeded bool, err error) {
+	dir := preseedArtifactsDir()
+	dc, err := ioutil.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return true, fmt.Errorf("cannot read preseed artifacts: %v", err)
+	}
+
+	digests := make(map[string]string, len(dc))
+	for _, fi := range dc {
+		if !fi.Mode().IsRegular() {
+			return true, fmt.Errorf("preseed artifact %q is not a regular file", fi.Name())
+		}
+		d, _, err := osutil.FileDigest(filepath.Join(dir, fi.Name()), crypto.SHA3_384)
+		if err != nil {
+			return true, fmt.Errorf("cannot compute the digest of preseed artifact %q: %v", fi.Name(), err)
+		}
+		digests[fi.Name()], err = asserts.EncodeDigest(crypto.SHA3_384, d)
+		if err != nil {
+			return true, err
+		}
+	}
+
+	preseed, err := findPreseedAssertion(st, model)
+	if err != nil {
+		return true, err
+	}
+	if err := preseed.CheckArtifacts(digests); err != nil {
+		return true, err
+	}
+	for _, name := range []string{preseedSystemKeyArtifact, preseedSnapRevisionsArtifact} {
+		if _, ok := digests[name]; !ok {
+			return true, fmt.Errorf("preseed artifact %q is missing", name)
+		}
+	}
+
+	mismatch, err := interfaces.SystemKeyFileMismatch(filepath.Join(dir, preseedSystemKeyArtifact))
+	if err != nil {
+		return true, fmt.Errorf("cannot check the preseeded system-key: %v", err)
+	}
+	if mismatch {
+		return true, fmt.Errorf("preseeded system-key does not match the system")
+	}
+
+	if err := checkPreseededSnapRevisions(st, filepath.Join(dir, preseedSnapRevisionsArtifact), seed); err != nil {
+		return true, err
+	}
+	return true, nil
+}
+
+func findPreseedAssertion(st *state.State, model *asserts.Model) (*asserts.Preseed, error) {
+	as, err := assertstate.DB(st).FindMany(asserts.PreseedType, map[string]string{
+		"series":   model.Series(),
+		"brand-id": model.BrandID(),
+		"model":    model.Model(),
+	})
+	if asserts.IsNotFound(err) {
+		return nil, fmt.Errorf("no preseed assertion for model %s/%s in the seed", model.BrandID(), model.Model())
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(as) != 1 {
+		return nil, fmt.Errorf("cannot use more than one preseed assertion for model %s/%s", model.BrandID(), model.Model())
+	}
+	return as[0].(*asserts.Preseed), nil
+}
+
+// checkPreseededSnapRevisions checks that exactly the snaps of the
+// seed were preseeded and at the same revisions.
+func checkPreseededSnapRevisions(st *state.State, fn string, seed *snap.Seed) error {
+	data, err := ioutil.ReadFile(fn)
+	if err != nil {
+		return err
+	}
+	var preseeded map[string]snap.Revision
+	if err := yaml.UnmarshalStrict(data, &preseeded); err != nil {
+		return fmt.Errorf("cannot read preseeded snap revisions: %v", err)
+	}
+
+	db := assertstate.DB(st)
+	for _, sn := range seed.Snaps {
+		rev, ok := preseeded[sn.Name]
+		if !ok {
+			return fmt.Errorf("snap %q is in the seed but was not preseeded", sn.Name)
+		}
+		delete(preseeded, sn.Name)
+		if sn.Unasserted {
+			if !rev.Local() {
+				return fmt.Errorf("snap %q was preseeded at revision %s, the seed has it unasserted", sn.Name, rev)
+			}
+			continue
+		}
+		seeded, err := seededSnapRevision(db, sn.Name)
+		if err != nil {
+			return err
+		}
+		if rev != seeded {
+			return fmt.Errorf("snap %q was preseeded at revision %s, the seed has revision %s", sn.Name, rev, seeded)
+		}
+	}
+	if len(preseeded) != 0 {
+		extra := make([]string, 0, len(preseeded))
+		for name := range preseeded {
+			extra = append(extra, name)
+		}
+		sort.Strings(extra)
+		return fmt.Errorf("snap %q was preseeded but is not in the seed", extra[0])
+	}
+	return nil
+}
+
+// seededSnapRevision returns the revision of the given snap according
+// to the assertions that come with the seed.
+func seededSnapRevision(db asserts.RODatabase, name string) (snap.Revision, error) {
+	decls, err := db.FindMany(asserts.SnapDeclarationType, map[string]string{
+		"series":    release.Series,
+		"snap-name": name,
+	})
+	if err != nil {
+		return snap.Revision{}, fmt.Errorf("cannot find the snap declaration of seeded snap %q: %v", name, err)
+	}
+	snapID := decls[0].(*asserts.SnapDeclaration).SnapID()
+	revs, err := db.FindMany(asserts.SnapRevisionType, map[string]string{
+		"snap-id": snapID,
+	})
+	if err != nil {
+		return snap.Revision{}, fmt.Errorf("cannot find the snap revision of seeded snap %q: %v", name, err)
+	}
+	if len(revs) != 1 {
+		return snap.Revision{}, fmt.Errorf("cannot determine the seeded revision of snap %q", name)
+	}
+	return snap.R(revs[0].(*asserts.SnapRevision).SnapRevision()), nil
+}
@@ -0,0 +1,219 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2019 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package devicestate_test
+
+import (
+	"crypto"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/asserts"
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/interfaces"
+	"github.com/snapcore/snapd/osutil"
+	"github.com/snapcore/snapd/overlord"
+	"github.com/snapcore/snapd/overlord/devicestate"
+	"github.com/snapcore/snapd/overlord/state"
+)
+
+const preseedSystemKey = `{"build-id": "7a94e9736c091b3984bd63f5aebfc883c4d859e0", "apparmor-features": ["caps", "dbus"]}`
+
+// makePreseededSeed makes a seed with core, pc-kernel and pc at
+// revision 1 together with the given preseed artifacts, all of them
+// listed by a preseed assertion.
+func (s *FirstBootTestSuite) makePreseededSeed(c *C, artifacts map[string]string) {
+	coreFname, kernelFname, gadgetFname := s.makeCoreSnaps(c, "")
+
+	assertsChain := s.makeModelAssertionChain(c, "my-model", nil)
+	for i, as := range assertsChain {
+		fn := filepath.Join(dirs.SnapSeedDir, "assertions", strconv.Itoa(i))
+		err := ioutil.WriteFile(fn, asserts.Encode(as), 0644)
+		c.Assert(err, IsNil)
+	}
+
+	content := []byte(fmt.Sprintf(`
+snaps:
+ - name: core
+   file: %s
+ - name: pc-kernel
+   file: %s
+ - name: pc
+   file: %s
+`, coreFname, kernelFname, gadgetFname))
+	err := ioutil.WriteFile(filepath.Join(dirs.SnapSeedDir, "seed.yaml"), content, 0644)
+	c.Assert(err, IsNil)
+
+	preseedDir := filepath.Join(dirs.SnapSeedDir, "preseed")
+	err = os.MkdirAll(preseedDir, 0755)
+	c.Assert(err, IsNil)
+	var listed []interface{}
+	for name, content := range artifacts {
+		fn := filepath.Join(preseedDir, name)
+		err := ioutil.WriteFile(fn, []byte(content), 0644)
+		c.Assert(err, IsNil)
+		d, _, err := osutil.FileDigest(fn, crypto.SHA3_384)
+		c.Assert(err, IsNil)
+		digest, err := asserts.EncodeDigest(crypto.SHA3_384, d)
+		c.Assert(err, IsNil)
+		listed = append(listed, map[string]interface{}{
+			"name":     name,
+			"sha3-384": digest,
+		})
+	}
+	preseed, err := s.brands.Signing("my-brand").Sign(asserts.PreseedType, map[string]interface{}{
+		"authority-id": "my-brand",
+		"series":       "16",
+		"brand-id":     "my-brand",
+		"model":        "my-model",
+		"system-label": "20191119",
+		"artifacts":    listed,
+		"timestamp":    time.Now().UTC().Format(time.RFC3339),
+	}, nil, "")
+	c.Assert(err, IsNil)
+	writeAssertionsToFile("preseed", []asserts.Assertion{preseed})
+}
+
+// populatePreseeded populates a fresh state from the seed and returns
+// the log of the mark-seeded task.
+func (s *FirstBootTestSuite) populatePreseeded(c *C) []string {
+	ovld, err := overlord.New(nil)
+	c.Assert(err, IsNil)
+	st := ovld.State()
+	st.Lock()
+	defer st.Unlock()
+
+	tsAll, err := devicestate.PopulateStateFromSeedImpl(st, s.perfTimings)
+	c.Assert(err, IsNil)
+
+	// seeding happens as usual in any case
+	var markSeeded *state.Task
+	for _, t := range tsAll[len(tsAll)-1].Tasks() {
+		if t.Kind() == "mark-seeded" {
+			markSeeded = t
+		}
+	}
+	c.Assert(markSeeded, NotNil)
+	return markSeeded.Log()
+}
+
+func (s *FirstBootTestSuite) TestPopulateFromSeedPreseededHappy(c *C) {
+	restore := interfaces.MockSystemKey(preseedSystemKey)
+	defer restore()
+
+	s.makePreseededSeed(c, map[string]string{
+		"system-key":        preseedSystemKey,
+		"snap-revisions":    "core: 1\npc-kernel: 1\npc: 1\n",
+		"apparmor-profiles": "profiles",
+	})
+
+	log := s.populatePreseeded(c)
+	c.Assert(log, HasLen, 1)
+	c.Check(log[0], Matches, `.* Preseeded artifacts match the seed`)
+}
+
+func (s *FirstBootTestSuite) TestPopulateFromSeedNotPreseeded(c *C) {
+	s.makePreseededSeed(c, map[string]string{
+		"system-key": preseedSystemKey,
+	})
+	err := os.RemoveAll(filepath.Join(dirs.SnapSeedDir, "preseed"))
+	c.Assert(err, IsNil)
+
+	c.Check(s.populatePreseeded(c), HasLen, 0)
+}
+
+func (s *FirstBootTestSuite) testPopulateFromSeedPreseededFallback(c *C, artifacts map[string]string, reason string) {
+	restore := interfaces.MockSystemKey(preseedSystemKey)
+	defer restore()
+
+	s.makePreseededSeed(c, artifacts)
+
+	log := s.populatePreseeded(c)
+	c.Assert(log, HasLen, 1)
+	c.Check(log[0], Matches, `.* Cannot use the preseeded artifacts, seeding normally: `+reason)
+}
+
+func (s *FirstBootTestSuite) TestPopulateFromSeedPreseededSystemKeyMismatch(c *C) {
+	s.testPopulateFromSeedPreseededFallback(c, map[string]string{
+		"system-key":     `{"build-id": "7a94e9736c091b3984bd63f5aebfc883c4d859e0", "apparmor-features": ["caps"]}`,
+		"snap-revisions": "core: 1\npc-kernel: 1\npc: 1\n",
+	}, `preseeded system-key does not match the system`)
+}
+
+func (s *FirstBootTestSuite) TestPopulateFromSeedPreseededSnapRevisionMismatch(c *C) {
+	s.testPopulateFromSeedPreseededFallback(c, map[string]string{
+		"system-key":     preseedSystemKey,
+		"snap-revisions": "core: 1\npc-kernel: 2\npc: 1\n",
+	}, `snap "pc-kernel" was preseeded at revision 2, the seed has revision 1`)
+}
+
+func (s *FirstBootTestSuite) TestPopulateFromSeedPreseededSnapNotPreseeded(c *C) {
+	s.testPopulateFromSeedPreseededFallback(c, map[string]string{
+		"system-key":     preseedSystemKey,
+		"snap-revisions": "core: 1\npc-kernel: 1\n",
+	}, `snap "pc" is in the seed but was not preseeded`)
+}
+
+func (s *FirstBootTestSuite) TestPopulateFromSeedPreseededSnapNotInSeed(c *C) {
+	s.testPopulateFromSeedPreseededFallback(c, map[string]string{
+		"system-key":     preseedSystemKey,
+		"snap-revisions": "core: 1\npc-kernel: 1\npc: 1\nfoo: 3\n",
+	}, `snap "foo" was preseeded but is not in the seed`)
+}
+
+func (s *FirstBootTestSuite) TestPopulateFromSeedPreseededMissingArtifact(c *C) {
+	s.testPopulateFromSeedPreseededFallback(c, map[string]string{
+		"snap-revisions": "core: 1\npc-kernel: 1\npc: 1\n",
+	}, `preseed artifact "system-key" is missing`)
+}
+
+func (s *FirstBootTestSuite) TestPopulateFromSeedPreseededArtifactChanged(c *C) {
+	restore := interfaces.MockSystemKey(preseedSystemKey)
+	defer restore()
+
+	s.makePreseededSeed(c, map[string]string{
+		"system-key":     preseedSystemKey,
+		"snap-revisions": "core: 1\npc-kernel: 1\npc: 1\n",
+	})
+	err := ioutil.WriteFile(filepath.Join(dirs.SnapSeedDir, "preseed", "snap-revisions"), []byte("core: 1\npc-kernel: 1\npc: 2\n"), 0644)
+	c.Assert(err, IsNil)
+
+	log := s.populatePreseeded(c)
+	c.Assert(log, HasLen, 1)
+	c.Check(log[0], Matches, `.* Cannot use the preseeded artifacts, seeding normally: preseed artifact "snap-revisions" does not have the digest recorded by the preseed assertion`)
+}
+
+func (s *FirstBootTestSuite) TestPopulateFromSeedPreseededNoAssertion(c *C) {
+	s.makePreseededSeed(c, map[string]string{
+		"system-key":     preseedSystemKey,
+		"snap-revisions": "core: 1\npc-kernel: 1\npc: 1\n",
+	})
+	err := os.Remove(filepath.Join(dirs.SnapSeedDir, "assertions", "preseed"))
+	c.Assert(err, IsNil)
+
+	log := s.populatePreseeded(c)
+	c.Assert(log, HasLen, 1)
+	c.Check(log[0], Matches, `.* Cannot use the preseeded artifacts, seeding normally: no preseed assertion for model my-brand/my-model in the seed`)
+}
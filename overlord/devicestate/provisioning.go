@@ -0,0 +1,154 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2019 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package devicestate
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/snapcore/snapd/i18n"
+	"github.com/snapcore/snapd/overlord/hookstate"
+	"github.com/snapcore/snapd/overlord/snapstate"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/release"
+	"github.com/snapcore/snapd/snap"
+)
+
+func provisioningTaskKind(name string) string {
+	return "ext-" + name
+}
+
+func provisioningHook(name string) string {
+	return "task-" + name
+}
+
+func provisioningUndoHook(name string) string {
+	return "task-" + name + "-undo"
+}
+
+type provisioningStep struct {
+	kind    string
+	summary string
+}
+
+// registerProvisioningSteps registers the provisioning steps declared
+// and implemented by the given gadget as external task kinds, and
+// returns them sorted by name.
+func (m *DeviceManager) registerProvisioningSteps(gadgetInfo *snap.Info) ([]provisioningStep, error) {
+	hasTaskHooks := false
+	for name := range gadgetInfo.Hooks {
+		if strings.HasPrefix(name, "task-") {
+			hasTaskHooks = true
+			break
+		}
+	}
+	if !hasTaskHooks {
+		// nothing to read gadget.yaml for
+		return nil, nil
+	}
+
+	gi, err := snap.ReadGadgetInfo(gadgetInfo, release.OnClassic)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(gi.Provisioning))
+	for name := range gi.Provisioning {
+		if gadgetInfo.Hooks[provisioningHook(name)] == nil {
+			// declared but not implemented
+			continue
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	steps := make([]provisioningStep, 0, len(names))
+	for _, name := range names {
+		step := gi.Provisioning[name]
+		ext := &hookstate.ExternalTaskKind{
+			Kind: provisioningTaskKind(name),
+			Snap: gadgetInfo.InstanceName(),
+			Hook: provisioningHook(name),
+		}
+		if step.Undo {
+			ext.UndoHook = provisioningUndoHook(name)
+		}
+		if err := m.hookMgr.RegisterExternalTaskKind(ext); err != nil {
+			return nil, err
+		}
+		summary := step.Summary
+		if summary == "" {
+			summary = fmt.Sprintf(i18n.G("Run %q provisioning step of gadget %q"), name, gadgetInfo.InstanceName())
+		}
+		steps = append(steps, provisioningStep{kind: ext.Kind, summary: summary})
+	}
+	return steps, nil
+}
+
+// ensureProvisioningSteps registers the provisioning steps of the
+// gadget again after a restart, so that the tasks of changes in
+// flight can run.
+func (m *DeviceManager) ensureProvisioningSteps() error {
+	m.state.Lock()
+	defer m.state.Unlock()
+
+	deviceCtx, err := DeviceCtx(m.state, nil, nil)
+	if err == state.ErrNoState {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	gadgetInfo, err := snapstate.GadgetInfo(m.state, deviceCtx)
+	if err == state.ErrNoState {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if gadgetInfo.InstanceName() == m.provisioningGadget && gadgetInfo.Revision == m.provisioningGadgetRev {
+		return nil
+	}
+
+	if _, err := m.registerProvisioningSteps(gadgetInfo); err != nil {
+		return err
+	}
+	m.provisioningGadget = gadgetInfo.InstanceName()
+	m.provisioningGadgetRev = gadgetInfo.Revision
+	return nil
+}
+
+// provisioningTasks returns the tasks running the provisioning steps
+// of the given gadget, in order.
+func (m *DeviceManager) provisioningTasks(gadgetInfo *snap.Info) ([]*state.Task, error) {
+	steps, err := m.registerProvisioningSteps(gadgetInfo)
+	if err != nil {
+		return nil, err
+	}
+	tasks := make([]*state.Task, 0, len(steps))
+	for _, step := range steps {
+		t, err := m.hookMgr.ExternalTask(step.kind, step.summary)
+		if err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, t)
+	}
+	return tasks, nil
+}
@@ -0,0 +1,197 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2019 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package devicestate_test
+
+import (
+	"errors"
+
+	. "gopkg.in/check.v1"
+	"gopkg.in/tomb.v2"
+
+	"github.com/snapcore/snapd/overlord/auth"
+	"github.com/snapcore/snapd/overlord/devicestate"
+	"github.com/snapcore/snapd/overlord/devicestate/devicestatetest"
+	"github.com/snapcore/snapd/overlord/hookstate"
+	"github.com/snapcore/snapd/overlord/snapstate"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/snap/snaptest"
+)
+
+const provisioningGadgetYaml = `
+volumes:
+  pc:
+    bootloader: grub
+provisioning:
+  setup-modem:
+    summary: Set up the modem
+  calibrate:
+    undo: true
+  not-implemented:
+    summary: Declared without a hook
+`
+
+func (s *deviceMgrSuite) setupProvisioning(c *C, serial string) {
+	s.makeModelAssertionInState(c, "canonical", "pc", map[string]interface{}{
+		"architecture": "amd64",
+		"kernel":       "pc-kernel",
+		"gadget":       "pc",
+	})
+	devicestatetest.SetDevice(s.state, &auth.DeviceState{
+		Brand:  "canonical",
+		Model:  "pc",
+		Serial: serial,
+	})
+
+	si := &snap.SideInfo{RealName: "pc", Revision: snap.R(1)}
+	snaptest.MockSnapWithFiles(c, `name: pc
+version: 1
+type: gadget
+hooks:
+ task-setup-modem:
+ task-calibrate:
+ task-calibrate-undo:
+`, si, [][]string{
+		{"meta/gadget.yaml", provisioningGadgetYaml},
+	})
+	snapstate.Set(s.state, "pc", &snapstate.SnapState{
+		SnapType: "gadget",
+		Active:   true,
+		Sequence: []*snap.SideInfo{si},
+		Current:  si.Revision,
+	})
+	s.state.Set("seeded", true)
+}
+
+func (s *deviceMgrSuite) TestFullDeviceRegistrationProvisioningSteps(c *C) {
+	r1 := devicestate.MockKeyLength(testKeyLength)
+	defer r1()
+
+	mockServer := s.mockServer(c, "REQID-1", nil)
+	defer mockServer.Close()
+
+	r2 := devicestate.MockBaseStoreURL(mockServer.URL)
+	defer r2()
+
+	var hooks []string
+	r3 := hookstate.MockRunHook(func(ctx *hookstate.Context, _ *tomb.Tomb) ([]byte, error) {
+		c.Check(ctx.InstanceName(), Equals, "pc")
+		hooks = append(hooks, ctx.HookName())
+		return nil, nil
+	})
+	defer r3()
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	s.setupProvisioning(c, "")
+
+	s.state.Unlock()
+	s.settle(c)
+	s.state.Lock()
+
+	becomeOperational := s.findBecomeOperationalChange()
+	c.Assert(becomeOperational, NotNil)
+	c.Check(becomeOperational.Err(), IsNil)
+
+	var kinds, summaries []string
+	for _, t := range becomeOperational.Tasks() {
+		kinds = append(kinds, t.Kind())
+		summaries = append(summaries, t.Summary())
+	}
+	c.Check(kinds, DeepEquals, []string{"ext-calibrate", "ext-setup-modem", "generate-device-key", "request-serial"})
+	c.Check(summaries[:2], DeepEquals, []string{`Run "calibrate" provisioning step of gadget "pc"`, "Set up the modem"})
+	c.Check(hooks, DeepEquals, []string{"task-calibrate", "task-setup-modem"})
+
+	device, err := devicestatetest.Device(s.state)
+	c.Assert(err, IsNil)
+	c.Check(device.Serial, Equals, "9999")
+}
+
+func (s *deviceMgrSuite) TestFullDeviceRegistrationProvisioningStepFails(c *C) {
+	r1 := devicestate.MockKeyLength(testKeyLength)
+	defer r1()
+
+	mockServer := s.mockServer(c, "REQID-1", nil)
+	defer mockServer.Close()
+
+	r2 := devicestate.MockBaseStoreURL(mockServer.URL)
+	defer r2()
+
+	var hooks []string
+	r3 := hookstate.MockRunHook(func(ctx *hookstate.Context, _ *tomb.Tomb) ([]byte, error) {
+		hooks = append(hooks, ctx.HookName())
+		if ctx.HookName() == "task-setup-modem" {
+			return nil, errors.New("no modem")
+		}
+		return nil, nil
+	})
+	defer r3()
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	s.setupProvisioning(c, "")
+
+	s.state.Unlock()
+	s.settle(c)
+	s.state.Lock()
+
+	becomeOperational := s.findBecomeOperationalChange()
+	c.Assert(becomeOperational, NotNil)
+	c.Check(becomeOperational.Err(), ErrorMatches, `(?s).*no modem.*`)
+	// the completed steps are undone
+	c.Check(hooks, DeepEquals, []string{"task-calibrate", "task-setup-modem", "task-calibrate-undo"})
+
+	device, err := devicestatetest.Device(s.state)
+	c.Assert(err, IsNil)
+	c.Check(device.Serial, Equals, "")
+}
+
+func (s *deviceMgrSuite) TestProvisioningStepsRegisteredAgainAtStart(c *C) {
+	var hooks []string
+	restore := hookstate.MockRunHook(func(ctx *hookstate.Context, _ *tomb.Tomb) ([]byte, error) {
+		hooks = append(hooks, ctx.HookName())
+		return nil, nil
+	})
+	defer restore()
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	// registered already, no become-operational change
+	s.setupProvisioning(c, "serial")
+
+	// a task of a change in flight from before a restart
+	t := s.state.NewTask("ext-setup-modem", "Set up the modem")
+	t.Set("hook-setup", &hookstate.HookSetup{Snap: "pc", Hook: "task-setup-modem"})
+	chg := s.state.NewChange("become-operational", "...")
+	chg.AddTask(t)
+	// and one of a step the gadget does not provide anymore
+	t = s.state.NewTask("ext-gone", "Gone")
+	t.Set("hook-setup", &hookstate.HookSetup{Snap: "pc", Hook: "task-gone"})
+	chg2 := s.state.NewChange("become-operational", "...")
+	chg2.AddTask(t)
+
+	s.state.Unlock()
+	s.settle(c)
+	s.state.Lock()
+
+	c.Check(chg.Status(), Equals, state.DoneStatus, Commentf("%v", chg.Err()))
+	c.Check(hooks, DeepEquals, []string{"task-setup-modem"})
+	c.Check(chg2.Err(), ErrorMatches, `(?s).*external task kind "ext-gone" is not registered.*`)
+}
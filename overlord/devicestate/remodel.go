@@ -452,3 +452,117 @@ func (rc *reregRemodelContext) FinishRegistration(serial *asserts.Serial) error
 	rc.setCtxDevice(device)
 	return nil
 }
+
+// RemodelPhase describes the estimated and done work of one phase of a
+// remodel change.
+type RemodelPhase struct {
+	Name string
+	// Status summarizes the status of the tasks of the phase.
+	Status state.Status
+	// Unit is the unit of Done and Total, either "bytes" for the
+	// download phase or "tasks" otherwise.
+	Unit  string
+	Done  int64
+	Total int64
+}
+
+var remodelPhaseNames = []string{"registration", "download", "install", "set-model"}
+
+func remodelPhaseOf(t *state.Task) string {
+	switch t.Kind() {
+	case "request-serial", "prepare-remodeling":
+		return "registration"
+	case "download-snap":
+		return "download"
+	case "set-model":
+		return "set-model"
+	}
+	return "install"
+}
+
+// downloadWork returns the estimated and done number of bytes of a
+// download-snap task.
+func downloadWork(t *state.Task) (done, total int64) {
+	_, progressDone, progressTotal := t.Progress()
+	if snapsup, err := snapstate.TaskSnapSetup(t); err == nil && snapsup.DownloadInfo != nil {
+		total = snapsup.DownloadInfo.Size
+	}
+	if total == 0 {
+		total = int64(progressTotal)
+	}
+	switch {
+	case t.Status() == state.DoneStatus:
+		done = total
+	case t.Status() == state.DoingStatus && progressTotal > 1:
+		// the progress is only meaningful once the download started
+		done = int64(progressDone)
+	}
+	return done, total
+}
+
+// RemodelPhases returns the phases of the given remodel change, in the order
+// in which they run, with the work each is estimated to require and the work
+// done so far. Phases without tasks are omitted. As a remodel change can gain
+// tasks along the way (e.g. after re-registration), the estimate can grow.
+func RemodelPhases(chg *state.Change) ([]*RemodelPhase, error) {
+	if chg.Kind() != "remodel" {
+		return nil, fmt.Errorf("internal error: cannot get remodel phases of %q change", chg.Kind())
+	}
+	phases := make(map[string]*RemodelPhase, len(remodelPhaseNames))
+	count := make(map[string]int)
+	ready := make(map[string]int)
+	started := make(map[string]bool)
+	failed := make(map[string]bool)
+	for _, t := range chg.Tasks() {
+		name := remodelPhaseOf(t)
+		phase := phases[name]
+		if phase == nil {
+			phase = &RemodelPhase{Name: name, Unit: "tasks"}
+			if name == "download" {
+				phase.Unit = "bytes"
+			}
+			phases[name] = phase
+		}
+		status := t.Status()
+		count[name]++
+		if status.Ready() {
+			ready[name]++
+		}
+		if status != state.DoStatus {
+			started[name] = true
+		}
+		if status == state.ErrorStatus {
+			failed[name] = true
+		}
+		if name == "download" {
+			done, total := downloadWork(t)
+			phase.Done += done
+			phase.Total += total
+			continue
+		}
+		phase.Total++
+		if status.Ready() {
+			phase.Done++
+		}
+	}
+
+	result := make([]*RemodelPhase, 0, len(phases))
+	for _, name := range remodelPhaseNames {
+		phase := phases[name]
+		if phase == nil {
+			continue
+		}
+		switch {
+		case failed[name]:
+			phase.Status = state.ErrorStatus
+		case ready[name] == count[name]:
+			phase.Status = state.DoneStatus
+		case started[name]:
+			phase.Status = state.DoingStatus
+		default:
+			phase.Status = state.DoStatus
+		}
+		result = append(result, phase)
+	}
+	return result, nil
+}
@@ -37,6 +37,7 @@ import (
 	"github.com/snapcore/snapd/overlord/snapstate"
 	"github.com/snapcore/snapd/overlord/state"
 	"github.com/snapcore/snapd/overlord/storecontext"
+	"github.com/snapcore/snapd/snap"
 	"github.com/snapcore/snapd/store/storetest"
 )
 
@@ -880,3 +881,53 @@ func (s *remodelLogicSuite) TestReregRemodelContextNewSerial(c *C) {
 	c.Check(serial.Model(), Equals, "other-model")
 	c.Check(serial.Serial(), Equals, "serialserialserial2")
 }
+
+func (s *remodelLogicSuite) TestRemodelPhases(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	chg := s.state.NewChange("remodel", "...")
+
+	prepare := s.state.NewTask("prepare-remodeling", "...")
+	prepare.SetStatus(state.DoneStatus)
+	chg.AddTask(prepare)
+
+	dl1 := s.state.NewTask("download-snap", "...")
+	dl1.Set("snap-setup", &snapstate.SnapSetup{
+		SideInfo:     &snap.SideInfo{RealName: "foo"},
+		DownloadInfo: &snap.DownloadInfo{Size: 1000},
+	})
+	dl1.SetStatus(state.DoneStatus)
+	chg.AddTask(dl1)
+	dl2 := s.state.NewTask("download-snap", "...")
+	dl2.Set("snap-setup", &snapstate.SnapSetup{
+		SideInfo:     &snap.SideInfo{RealName: "bar"},
+		DownloadInfo: &snap.DownloadInfo{Size: 500},
+	})
+	dl2.SetStatus(state.DoingStatus)
+	dl2.SetProgress("", 200, 500)
+	chg.AddTask(dl2)
+
+	for _, kind := range []string{"mount-snap", "link-snap"} {
+		chg.AddTask(s.state.NewTask(kind, "..."))
+	}
+	chg.AddTask(s.state.NewTask("set-model", "..."))
+
+	phases, err := devicestate.RemodelPhases(chg)
+	c.Assert(err, IsNil)
+	c.Check(phases, DeepEquals, []*devicestate.RemodelPhase{
+		{Name: "registration", Status: state.DoneStatus, Unit: "tasks", Done: 1, Total: 1},
+		{Name: "download", Status: state.DoingStatus, Unit: "bytes", Done: 1200, Total: 1500},
+		{Name: "install", Status: state.DoStatus, Unit: "tasks", Done: 0, Total: 2},
+		{Name: "set-model", Status: state.DoStatus, Unit: "tasks", Done: 0, Total: 1},
+	})
+
+	// a failed task fails its phase
+	dl2.SetStatus(state.ErrorStatus)
+	phases, err = devicestate.RemodelPhases(chg)
+	c.Assert(err, IsNil)
+	c.Check(phases[1].Status, Equals, state.ErrorStatus)
+
+	_, err = devicestate.RemodelPhases(s.state.NewChange("install-snap", "..."))
+	c.Check(err, ErrorMatches, `internal error: cannot get remodel phases of "install-snap" change`)
+}
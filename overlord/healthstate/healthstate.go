@@ -46,6 +46,7 @@ func init() {
 	}
 
 	snapstate.CheckHealthHook = Hook
+	hookstate.RegisterHookTimeout("check-health", func() time.Duration { return checkTimeout })
 }
 
 func Hook(st *state.State, snapName string, snapRev snap.Revision) *state.Task {
@@ -142,7 +143,34 @@ func (h *healthHandler) Done() error {
 		}
 	}
 
-	return h.appendHealth(&health)
+	if err := h.appendHealth(&health); err != nil {
+		return err
+	}
+	if health.Status == ErrorStatus && h.undoIfUnhealthy() {
+		msg := health.Message
+		if msg == "" {
+			msg = health.Code
+		}
+		return fmt.Errorf("snap %q reported an error from its health check: %s", h.context.InstanceName(), msg)
+	}
+	return nil
+}
+
+// undoIfUnhealthy returns whether an error reported by the health
+// check should undo the change, as set for refreshes moving a snap to
+// a different base.
+func (h *healthHandler) undoIfUnhealthy() bool {
+	task, ok := h.context.Task()
+	if !ok {
+		return false
+	}
+	h.context.Lock()
+	defer h.context.Unlock()
+	var undo bool
+	if err := task.Get("undo-if-unhealthy", &undo); err != nil && err != state.ErrNoState {
+		logger.Noticef("cannot get undo-if-unhealthy of %s: %v", task.Kind(), err)
+	}
+	return undo
 }
 
 func (h *healthHandler) Error(err error) error {
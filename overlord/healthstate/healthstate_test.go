@@ -99,6 +99,10 @@ const (
 	captainHook
 )
 
+func (s *healthSuite) TestHookTimeout(c *check.C) {
+	c.Check(hookstate.HookTimeout("check-health"), check.Equals, time.Second)
+}
+
 func (s *healthSuite) TestHealthNoHook(c *check.C) {
 	s.testHealth(c, noHook)
 }
@@ -251,3 +255,50 @@ func (s *healthSuite) TestSetFromHookContextEmpty(c *check.C) {
 	// no health in the context -> no health in state
 	c.Check(s.state.Get("health", &hs), check.Equals, state.ErrNoState)
 }
+
+func (s *healthSuite) TestUndoIfUnhealthy(c *check.C) {
+	for _, t := range []struct {
+		undo   bool
+		status healthstate.HealthStatus
+		err    string
+	}{
+		{false, healthstate.ErrorStatus, ""},
+		{true, healthstate.OkayStatus, ""},
+		{true, healthstate.WaitingStatus, ""},
+		{true, healthstate.ErrorStatus, `snap "test-snap" reported an error from its health check: cannot frobnicate`},
+	} {
+		s.state.Lock()
+		task := healthstate.Hook(s.state, "test-snap", snap.R(42))
+		if t.undo {
+			task.Set("undo-if-unhealthy", true)
+		}
+		s.state.Unlock()
+
+		ctx, err := hookstate.NewContext(task, s.state, &hookstate.HookSetup{Snap: "test-snap", Revision: snap.R(42), Hook: "check-health"}, nil, "")
+		c.Assert(err, check.IsNil)
+		handler := healthstate.NewHealthHandler(ctx)
+		c.Assert(handler.Before(), check.IsNil)
+
+		ctx.Lock()
+		ctx.Set("health", &healthstate.HealthState{
+			Revision:  snap.R(42),
+			Timestamp: time.Now(),
+			Status:    t.status,
+			Message:   "cannot frobnicate",
+		})
+		ctx.Unlock()
+
+		err = handler.Done()
+		if t.err == "" {
+			c.Check(err, check.IsNil)
+		} else {
+			c.Check(err, check.ErrorMatches, t.err)
+		}
+
+		s.state.Lock()
+		health, err := healthstate.Get(s.state, "test-snap")
+		s.state.Unlock()
+		c.Assert(err, check.IsNil)
+		c.Check(health.Status, check.Equals, t.status)
+	}
+}
@@ -35,6 +35,7 @@ type baseCommand struct {
 	stdout io.Writer
 	stderr io.Writer
 	c      *hookstate.Context
+	uid    uint32
 }
 
 func (c *baseCommand) setStdout(w io.Writer) {
@@ -65,6 +66,10 @@ func (c *baseCommand) context() *hookstate.Context {
 	return c.c
 }
 
+func (c *baseCommand) setUid(uid uint32) {
+	c.uid = uid
+}
+
 type command interface {
 	setStdout(w io.Writer)
 	setStderr(w io.Writer)
@@ -72,6 +77,8 @@ type command interface {
 	setContext(context *hookstate.Context)
 	context() *hookstate.Context
 
+	setUid(uid uint32)
+
 	Execute(args []string) error
 }
 
@@ -124,11 +131,13 @@ func Run(context *hookstate.Context, args []string, uid uint32) (stdout, stderr
 		var data interface{}
 		// commands listed here will be allowed for regular users
 		// note: commands still need valid context and snaps can only access own config.
-		if uid == 0 || name == "get" || name == "services" || name == "set-health" {
+		// "set" is further restricted to the --user scope for regular users.
+		if uid == 0 || name == "get" || name == "set" || name == "services" || name == "set-health" {
 			cmd := cmdInfo.generator()
 			cmd.setStdout(&stdoutBuffer)
 			cmd.setStderr(&stderrBuffer)
 			cmd.setContext(context)
+			cmd.setUid(uid)
 			data = cmd
 		} else {
 			data = &ForbiddenCommand{Uid: uid, Name: name}
@@ -0,0 +1,97 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2019 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package ctlcmd
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+
+	"github.com/snapcore/snapd/i18n"
+	"github.com/snapcore/snapd/overlord/state"
+)
+
+var (
+	shortFDEHelp = i18n.G("Exchange data with snapd during full disk encryption operations")
+	longFDEHelp  = i18n.G(`
+The fde command is used from the "fde" hook of a snap implementing
+device-specific full disk encryption.
+
+"snapctl fde request" prints the JSON request snapd made, its "op" is one of:
+
+- setup: seal the base64 encoded "key", the result is the sealed key.
+- reveal: unseal the base64 encoded "sealed-key", the result is the key.
+- lock: prevent revealing keys until the next boot, there is no result.
+
+"snapctl fde result <base64-data>" passes the result of the operation back to
+snapd.
+`)
+)
+
+func init() {
+	addCommand("fde", shortFDEHelp, longFDEHelp, func() command { return &fdeCommand{} })
+}
+
+type fdeCommand struct {
+	baseCommand
+	Positional struct {
+		Action string `positional-arg-name:"<action>" required:"yes" description:"request or result"`
+		Data   string `positional-arg-name:"<base64-data>" description:"the base64 encoded result, for result"`
+	} `positional-args:"yes"`
+}
+
+func (c *fdeCommand) Execute(args []string) error {
+	ctx := c.context()
+	if ctx == nil {
+		return fmt.Errorf(i18n.G("cannot %s without a context"), "fde")
+	}
+	if ctx.HookName() != "fde" {
+		return fmt.Errorf("cannot use fde outside of the fde hook")
+	}
+
+	switch c.Positional.Action {
+	case "request":
+		if c.Positional.Data != "" {
+			return fmt.Errorf("too many arguments for fde request")
+		}
+		ctx.Lock()
+		var req json.RawMessage
+		err := ctx.Get("fde-request", &req)
+		ctx.Unlock()
+		if err == state.ErrNoState {
+			return fmt.Errorf("no fde request is pending")
+		}
+		if err != nil {
+			return err
+		}
+		c.printf("%s\n", req)
+	case "result":
+		result, err := base64.StdEncoding.DecodeString(c.Positional.Data)
+		if err != nil {
+			return fmt.Errorf("cannot decode fde result: %v", err)
+		}
+		ctx.Lock()
+		ctx.Set("fde-result", result)
+		ctx.Unlock()
+	default:
+		return fmt.Errorf("unknown fde action %q, expected request or result", c.Positional.Action)
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2019 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package ctlcmd_test
+
+import (
+	"gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/overlord/hookstate"
+	"github.com/snapcore/snapd/overlord/hookstate/ctlcmd"
+	"github.com/snapcore/snapd/overlord/hookstate/hooktest"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/snap"
+)
+
+type fdeSuite struct {
+	state       *state.State
+	mockContext *hookstate.Context
+}
+
+var _ = check.Suite(&fdeSuite{})
+
+func (s *fdeSuite) SetUpTest(c *check.C) {
+	s.state = state.New(nil)
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	setup := &hookstate.HookSetup{Snap: "pc-kernel", Revision: snap.R(1), Hook: "fde"}
+	ctx, err := hookstate.NewContext(nil, s.state, setup, hooktest.NewMockHandler(), "")
+	c.Assert(err, check.IsNil)
+	s.mockContext = ctx
+}
+
+func (s *fdeSuite) TestRequest(c *check.C) {
+	s.mockContext.Lock()
+	s.mockContext.Set("fde-request", map[string]interface{}{
+		"op":  "setup",
+		"key": []byte("secret"),
+	})
+	s.mockContext.Unlock()
+
+	stdout, stderr, err := ctlcmd.Run(s.mockContext, []string{"fde", "request"}, 0)
+	c.Assert(err, check.IsNil)
+	c.Check(string(stdout), check.Equals, `{"key":"c2VjcmV0","op":"setup"}`+"\n")
+	c.Check(string(stderr), check.Equals, "")
+}
+
+func (s *fdeSuite) TestResult(c *check.C) {
+	_, _, err := ctlcmd.Run(s.mockContext, []string{"fde", "result", "c2VhbGVk"}, 0)
+	c.Assert(err, check.IsNil)
+
+	s.mockContext.Lock()
+	defer s.mockContext.Unlock()
+	var result []byte
+	c.Assert(s.mockContext.Get("fde-result", &result), check.IsNil)
+	c.Check(string(result), check.Equals, "sealed")
+}
+
+func (s *fdeSuite) TestErrors(c *check.C) {
+	_, _, err := ctlcmd.Run(nil, []string{"fde", "request"}, 0)
+	c.Check(err, check.ErrorMatches, "cannot fde without a context")
+
+	_, _, err = ctlcmd.Run(s.mockContext, []string{"fde", "request"}, 0)
+	c.Check(err, check.ErrorMatches, "no fde request is pending")
+
+	_, _, err = ctlcmd.Run(s.mockContext, []string{"fde", "result", "%%%"}, 0)
+	c.Check(err, check.ErrorMatches, "cannot decode fde result: .*")
+
+	_, _, err = ctlcmd.Run(s.mockContext, []string{"fde", "frob"}, 0)
+	c.Check(err, check.ErrorMatches, `unknown fde action "frob", expected request or result`)
+
+	_, _, err = ctlcmd.Run(s.mockContext, []string{"fde", "request"}, 1000)
+	c.Check(err, check.FitsTypeOf, &ctlcmd.ForbiddenCommandError{})
+
+	s.state.Lock()
+	setup := &hookstate.HookSetup{Snap: "pc-kernel", Revision: snap.R(1), Hook: "configure"}
+	ctx, err := hookstate.NewContext(nil, s.state, setup, hooktest.NewMockHandler(), "")
+	s.state.Unlock()
+	c.Assert(err, check.IsNil)
+	_, _, err = ctlcmd.Run(ctx, []string{"fde", "request"}, 0)
+	c.Check(err, check.ErrorMatches, "cannot use fde outside of the fde hook")
+}
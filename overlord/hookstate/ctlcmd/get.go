@@ -46,6 +46,7 @@ type getCommand struct {
 
 	Document bool `short:"d" description:"always return document, even with single key"`
 	Typed    bool `short:"t" description:"strict typing with nulls and quoted strings"`
+	User     bool `long:"user" description:"return options from the configuration of the calling user"`
 }
 
 var shortGetHelp = i18n.G("The get command prints configuration and interface connection settings.")
@@ -80,6 +81,15 @@ by explicitly requesting that via the --plug and --slot command line options:
     $ snapctl get :myplug --slot usb-vendor
 
 This requests the "usb-vendor" setting from the slot that is connected to "myplug".
+
+All the attributes of an endpoint, including those set by the prepare hooks,
+are printed as a document when no setting is named:
+
+    $ snapctl get :myplug --slot
+
+Options scoped to the calling user may be printed with --user:
+
+    $ snapctl get --user theme
 `)
 
 func init() {
@@ -153,8 +163,8 @@ func (c *getCommand) Execute(args []string) error {
 		if snap != "" {
 			return fmt.Errorf(`"snapctl get %s" not supported, use "snapctl get :%s" instead`, c.Positional.PlugOrSlotSpec, parts[1])
 		}
-		if len(c.Positional.Keys) == 0 {
-			return fmt.Errorf(i18n.G("get which attribute?"))
+		if c.User {
+			return fmt.Errorf("cannot use --user with <snap>:<plug|slot> argument")
 		}
 
 		return c.getInterfaceSetting(context, name)
@@ -173,7 +183,12 @@ func (c *getCommand) getConfigSetting(context *hookstate.Context) error {
 	}
 
 	context.Lock()
-	transaction := configstate.ContextTransaction(context)
+	var transaction *config.Transaction
+	if c.User {
+		transaction = config.NewUserTransaction(context.State(), c.uid)
+	} else {
+		transaction = configstate.ContextTransaction(context)
+	}
 	context.Unlock()
 
 	return c.printValues(func(key string) (interface{}, bool, error) {
@@ -316,6 +331,23 @@ func (c *getCommand) getInterfaceSetting(context *hookstate.Context, plugOrSlot
 		return fmt.Errorf(i18n.G("internal error: cannot get %s from appropriate task"), which)
 	}
 
+	if len(c.Positional.Keys) == 0 {
+		// print all the attributes of the endpoint
+		all := make(map[string]interface{}, len(staticAttrs)+len(dynamicAttrs))
+		for k, v := range dynamicAttrs {
+			all[k] = v
+		}
+		for k, v := range staticAttrs {
+			all[k] = v
+		}
+		bytes, err := json.MarshalIndent(all, "", "\t")
+		if err != nil {
+			return err
+		}
+		c.printf("%s\n", string(bytes))
+		return nil
+	}
+
 	return c.printValues(func(key string) (interface{}, bool, error) {
 		subkeys, err := config.ParseKey(key)
 		if err != nil {
@@ -245,6 +245,30 @@ func (s *getSuite) TestGetRegularUser(c *C) {
 	c.Assert(string(stderr), Equals, "")
 }
 
+func (s *getSuite) TestGetUser(c *C) {
+	st := s.mockContext.State()
+	st.Lock()
+	tr := config.NewUserTransaction(st, 1000)
+	tr.Set("test-snap", "initial-key", "user-value")
+	tr.Commit()
+	st.Unlock()
+
+	stdout, stderr, err := ctlcmd.Run(s.mockContext, []string{"get", "--user", "initial-key"}, 1000)
+	c.Assert(err, IsNil)
+	c.Check(string(stdout), Equals, "user-value\n")
+	c.Check(string(stderr), Equals, "")
+
+	// other users do not see it
+	stdout, _, err = ctlcmd.Run(s.mockContext, []string{"get", "--user", "initial-key"}, 1001)
+	c.Assert(err, IsNil)
+	c.Check(string(stdout), Equals, "\n")
+
+	// and the system configuration is unaffected
+	stdout, _, err = ctlcmd.Run(s.mockContext, []string{"get", "initial-key"}, 1000)
+	c.Assert(err, IsNil)
+	c.Check(string(stdout), Equals, "initial-value\n")
+}
+
 func (s *getSuite) TestCommandWithoutContext(c *C) {
 	_, _, err := ctlcmd.Run(nil, []string{"get", "foo"}, 0)
 	c.Check(err, ErrorMatches, ".*cannot get without a context.*")
@@ -349,8 +373,11 @@ var getPlugAttributesTests = []struct {
 	args:   "get -d :aplug baz",
 	stdout: "{\n\t\"baz\": [\n\t\t\"a\",\n\t\t\"b\"\n\t]\n}\n",
 }, {
-	args:  "get :aplug",
-	error: `.*get which attribute.*`,
+	args:   "get :aplug",
+	stdout: "{\n\t\"aattr\": \"foo\",\n\t\"baz\": [\n\t\t\"a\",\n\t\t\"b\"\n\t],\n\t\"dyn-plug-attr\": \"c\",\n\t\"mapattr\": {\n\t\t\"mapattr1\": \"mapval1\",\n\t\t\"mapattr2\": \"mapval2\"\n\t},\n\t\"nilattr\": null\n}\n",
+}, {
+	args:   "get --slot :aplug",
+	stdout: "{\n\t\"battr\": \"bar\",\n\t\"dyn-slot-attr\": \"d\"\n}\n",
 }, {
 	args:   "get :aplug mapattr.mapattr1",
 	stdout: "mapval1\n",
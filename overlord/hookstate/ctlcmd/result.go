@@ -0,0 +1,106 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2019 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package ctlcmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/snapcore/snapd/i18n"
+	"github.com/snapcore/snapd/jsonutil"
+	"github.com/snapcore/snapd/overlord/state"
+)
+
+var (
+	shortSetResultHelp = i18n.G("Report results of the running hook")
+	longSetResultHelp  = i18n.G(`
+The set-result command reports structured results of the running hook, which
+are made available to clients in the data of the change the hook is part of
+once the hook completes successfully.
+
+    $ snapctl set-result backup-file=/var/snap/foo/common/backup.tar files=42
+
+As with the set command, values are parsed as JSON if possible and are
+otherwise kept as strings. Setting a result again replaces its previous value.
+`)
+)
+
+func init() {
+	addCommand("set-result", shortSetResultHelp, longSetResultHelp, func() command { return &setResultCommand{} })
+}
+
+type setResultCommand struct {
+	baseCommand
+
+	Positional struct {
+		Results []string `positional-arg-name:"key=value" required:"1"`
+	} `positional-args:"yes" required:"yes"`
+}
+
+var validResultKey = regexp.MustCompile(`^[a-z0-9](?:-?[a-z0-9])*$`).MatchString
+
+func (c *setResultCommand) Execute([]string) error {
+	context := c.context()
+	if context == nil {
+		return fmt.Errorf(i18n.G("cannot %s without a context"), "set-result")
+	}
+
+	results := make(map[string]*json.RawMessage, len(c.Positional.Results))
+	for _, kv := range c.Positional.Results {
+		parts := strings.SplitN(kv, "=", 2)
+		if len(parts) != 2 {
+			return fmt.Errorf(i18n.G("invalid parameter: %q (want key=value)"), kv)
+		}
+		key := parts[0]
+		if !validResultKey(key) {
+			return fmt.Errorf(i18n.G("invalid result key %q"), key)
+		}
+		var value interface{}
+		if err := jsonutil.DecodeWithNumber(strings.NewReader(parts[1]), &value); err != nil {
+			// not valid JSON, just keep the string as-is
+			value = parts[1]
+		}
+		data, err := json.Marshal(value)
+		if err != nil {
+			return fmt.Errorf("cannot marshal result %q: %v", key, err)
+		}
+		raw := json.RawMessage(data)
+		results[key] = &raw
+	}
+
+	context.Lock()
+	defer context.Unlock()
+
+	var current map[string]*json.RawMessage
+	if err := context.Get("hook-result", &current); err != nil && err != state.ErrNoState {
+		return err
+	}
+	if current == nil {
+		current = results
+	} else {
+		for k, v := range results {
+			current[k] = v
+		}
+	}
+	context.Set("hook-result", current)
+	return nil
+}
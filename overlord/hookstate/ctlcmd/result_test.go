@@ -0,0 +1,113 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2019 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package ctlcmd_test
+
+import (
+	"encoding/json"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/overlord/hookstate"
+	"github.com/snapcore/snapd/overlord/hookstate/ctlcmd"
+	"github.com/snapcore/snapd/overlord/hookstate/hooktest"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/snap"
+)
+
+type setResultSuite struct {
+	mockContext *hookstate.Context
+}
+
+var _ = Suite(&setResultSuite{})
+
+func (s *setResultSuite) SetUpTest(c *C) {
+	st := state.New(nil)
+	st.Lock()
+	defer st.Unlock()
+
+	task := st.NewTask("test-task", "my test task")
+	setup := &hookstate.HookSetup{Snap: "test-snap", Revision: snap.R(1), Hook: "action-backup"}
+
+	var err error
+	s.mockContext, err = hookstate.NewContext(task, st, setup, hooktest.NewMockHandler(), "")
+	c.Assert(err, IsNil)
+}
+
+func (s *setResultSuite) result(c *C) map[string]interface{} {
+	s.mockContext.Lock()
+	defer s.mockContext.Unlock()
+
+	var result map[string]interface{}
+	c.Assert(s.mockContext.Get("hook-result", &result), IsNil)
+	return result
+}
+
+func (s *setResultSuite) TestSetResult(c *C) {
+	stdout, stderr, err := ctlcmd.Run(s.mockContext, []string{"set-result", "files=42", "file=/some/path", `info={"size": 1}`}, 0)
+	c.Assert(err, IsNil)
+	c.Check(string(stdout), Equals, "")
+	c.Check(string(stderr), Equals, "")
+	c.Check(s.result(c), DeepEquals, map[string]interface{}{
+		"files": json.Number("42"),
+		"file":  "/some/path",
+		"info":  map[string]interface{}{"size": json.Number("1")},
+	})
+
+	// results are merged
+	_, _, err = ctlcmd.Run(s.mockContext, []string{"set-result", "files=43", "other=true"}, 0)
+	c.Assert(err, IsNil)
+	c.Check(s.result(c), DeepEquals, map[string]interface{}{
+		"files": json.Number("43"),
+		"file":  "/some/path",
+		"info":  map[string]interface{}{"size": json.Number("1")},
+		"other": true,
+	})
+}
+
+func (s *setResultSuite) TestSetResultKeepsBigNumbers(c *C) {
+	_, _, err := ctlcmd.Run(s.mockContext, []string{"set-result", "size=12345678901234567890"}, 0)
+	c.Assert(err, IsNil)
+
+	s.mockContext.Lock()
+	defer s.mockContext.Unlock()
+	var result map[string]*json.RawMessage
+	c.Assert(s.mockContext.Get("hook-result", &result), IsNil)
+	c.Check(string(*result["size"]), Equals, "12345678901234567890")
+}
+
+func (s *setResultSuite) TestSetResultErrors(c *C) {
+	for _, t := range []struct {
+		args []string
+		err  string
+	}{
+		{[]string{"set-result"}, "the required argument `key=value \\(at least 1 argument\\)` was not provided"},
+		{[]string{"set-result", "foo"}, `invalid parameter: "foo" \(want key=value\)`},
+		{[]string{"set-result", "Foo=1"}, `invalid result key "Foo"`},
+		{[]string{"set-result", "foo.bar=1"}, `invalid result key "foo.bar"`},
+	} {
+		_, _, err := ctlcmd.Run(s.mockContext, t.args, 0)
+		c.Check(err, ErrorMatches, t.err, Commentf("%q", t.args))
+	}
+}
+
+func (s *setResultSuite) TestSetResultNoContext(c *C) {
+	_, _, err := ctlcmd.Run(nil, []string{"set-result", "foo=1"}, 0)
+	c.Check(err, ErrorMatches, "cannot set-result without a context")
+}
@@ -34,6 +34,8 @@ import (
 type setCommand struct {
 	baseCommand
 
+	User bool `long:"user" description:"set options in the configuration of the calling user"`
+
 	Positional struct {
 		PlugOrSlotSpec string   `positional-arg-name:":<plug|slot>"`
 		ConfValues     []string `positional-arg-name:"key=value"`
@@ -60,6 +62,14 @@ Plug and slot attributes may be set in the respective prepare and connect hooks
 naming the respective plug or slot:
 
     $ snapctl set :myplug path=/dev/ttyS0
+
+Attributes set in prepare hooks are checked by the interface once the hook
+completes, and the hook fails if they are not valid for the connection.
+
+Options scoped to the calling user may be set with --user. These are kept
+separately from the snap configuration and are persisted immediately:
+
+    $ snapctl set --user theme=dark
 `)
 
 func init() {
@@ -76,14 +86,25 @@ func (s *setCommand) Execute(args []string) error {
 		return fmt.Errorf("cannot set without a context")
 	}
 
+	if !s.User && s.uid != 0 {
+		return &ForbiddenCommandError{Message: fmt.Sprintf("cannot use %q with uid %d, try with sudo", "set", s.uid)}
+	}
+
 	// treat PlugOrSlotSpec argument as key=value if it contans '=' or doesn't contain ':' - this is to support
 	// values such as "device-service.url=192.168.0.1:5555" and error out on invalid key=value if only "key" is given.
 	if strings.Contains(s.Positional.PlugOrSlotSpec, "=") || !strings.Contains(s.Positional.PlugOrSlotSpec, ":") {
 		s.Positional.ConfValues = append([]string{s.Positional.PlugOrSlotSpec}, s.Positional.ConfValues[0:]...)
 		s.Positional.PlugOrSlotSpec = ""
+		if s.User {
+			return s.setUserConfigSetting(context)
+		}
 		return s.setConfigSetting(context)
 	}
 
+	if s.User {
+		return fmt.Errorf("cannot use --user with <snap>:<plug|slot> argument")
+	}
+
 	parts := strings.SplitN(s.Positional.PlugOrSlotSpec, ":", 2)
 	snap, name := parts[0], parts[1]
 	if name == "" {
@@ -100,6 +121,25 @@ func (s *setCommand) setConfigSetting(context *hookstate.Context) error {
 	tr := configstate.ContextTransaction(context)
 	context.Unlock()
 
+	return s.patchConfig(tr)
+}
+
+func (s *setCommand) setUserConfigSetting(context *hookstate.Context) error {
+	context.Lock()
+	defer context.Unlock()
+
+	tr := config.NewUserTransaction(context.State(), s.uid)
+	if err := s.patchConfig(tr); err != nil {
+		return err
+	}
+	if err := tr.CheckUserLimits(s.context().InstanceName()); err != nil {
+		return err
+	}
+	tr.Commit()
+	return nil
+}
+
+func (s *setCommand) patchConfig(tr *config.Transaction) error {
 	for _, patchValue := range s.Positional.ConfValues {
 		parts := strings.SplitN(patchValue, "=", 2)
 		if len(parts) == 1 && strings.HasSuffix(patchValue, "!") {
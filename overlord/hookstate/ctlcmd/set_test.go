@@ -135,6 +135,46 @@ func (s *getSuite) TestSetHelpRegularUserAllowed(c *C) {
 	c.Assert(strings.HasPrefix(err.Error(), "Usage:"), Equals, true)
 }
 
+func (s *setSuite) TestSetUserRegularUser(c *C) {
+	stdout, stderr, err := ctlcmd.Run(s.mockContext, []string{"set", "--user", "theme=dark", "size=12"}, 1000)
+	c.Check(err, IsNil)
+	c.Check(string(stdout), Equals, "")
+	c.Check(string(stderr), Equals, "")
+
+	s.mockContext.Lock()
+	defer s.mockContext.Unlock()
+
+	// the user configuration is persisted immediately
+	tr := config.NewUserTransaction(s.mockContext.State(), 1000)
+	var value interface{}
+	c.Check(tr.Get("test-snap", "theme", &value), IsNil)
+	c.Check(value, Equals, "dark")
+	c.Check(tr.Get("test-snap", "size", &value), IsNil)
+	c.Check(value, Equals, json.Number("12"))
+
+	// and does not leak into the configuration of other users or the system
+	tr = config.NewUserTransaction(s.mockContext.State(), 1001)
+	c.Check(tr.Get("test-snap", "theme", &value), ErrorMatches, ".*snap.*has no.*configuration.*")
+	tr = config.NewTransaction(s.mockContext.State())
+	c.Check(tr.Get("test-snap", "theme", &value), ErrorMatches, ".*snap.*has no.*configuration.*")
+}
+
+func (s *setSuite) TestSetUserTooLarge(c *C) {
+	_, _, err := ctlcmd.Run(s.mockContext, []string{"set", "--user", "theme=" + strings.Repeat("x", 64*1024)}, 1000)
+	c.Check(err, ErrorMatches, `cannot store more than 65536 bytes of configuration of snap "test-snap" for a single user`)
+
+	s.mockContext.Lock()
+	defer s.mockContext.Unlock()
+	tr := config.NewUserTransaction(s.mockContext.State(), 1000)
+	var value interface{}
+	c.Check(config.IsNoOption(tr.Get("test-snap", "theme", &value)), Equals, true)
+}
+
+func (s *setSuite) TestSetUserWithPlugOrSlot(c *C) {
+	_, _, err := ctlcmd.Run(s.mockContext, []string{"set", "--user", ":foo", "bar=baz"}, 0)
+	c.Check(err, ErrorMatches, "cannot use --user with <snap>:<plug|slot> argument")
+}
+
 func (s *setSuite) TestSetConfigOptionWithColon(c *C) {
 	stdout, stderr, err := ctlcmd.Run(s.mockContext, []string{"set", "device-service.url=192.168.0.1:5555"}, 0)
 	c.Check(err, IsNil)
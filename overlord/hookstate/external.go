@@ -0,0 +1,191 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2019 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package hookstate
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"gopkg.in/tomb.v2"
+
+	"github.com/snapcore/snapd/overlord/assertstate"
+	"github.com/snapcore/snapd/overlord/snapstate"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/snap"
+)
+
+// ExternalTaskKind describes a task kind whose implementation is
+// provided by a snapd extension, that is by a task-* hook of a snap
+// from the brand of the device (e.g. its gadget). This allows
+// device-specific steps to participate in changes.
+type ExternalTaskKind struct {
+	// Kind is the task kind, it must start with "ext-".
+	Kind string
+	// Snap is the snap providing the implementation.
+	Snap string
+	// Hook is the hook run to perform the task.
+	Hook string
+	// UndoHook optionally is the hook run to undo the task.
+	UndoHook string
+	// Timeout for running the hooks, defaults to the hook timeout.
+	Timeout time.Duration
+}
+
+var validExternalTaskKind = regexp.MustCompile("^ext-[a-z0-9](?:-?[a-z0-9])*$")
+var validExternalTaskHook = regexp.MustCompile("^task-[a-z0-9](?:-?[a-z0-9])*$")
+
+// RegisterExternalTaskKind registers a task kind implemented by a
+// snapd extension. Tasks of the kind are then run as the given hooks,
+// with all the constraints of hooks, but only if the snap providing
+// them comes from the brand of the device.
+//
+// Registrations are not persisted, the providers need to register
+// their kinds again at every start of snapd, a kind can then be
+// registered again by the same snap. Tasks whose kind is not
+// registered anymore fail.
+func (m *HookManager) RegisterExternalTaskKind(ext *ExternalTaskKind) error {
+	if !validExternalTaskKind.MatchString(ext.Kind) {
+		return fmt.Errorf("invalid external task kind %q: must start with \"ext-\"", ext.Kind)
+	}
+	if err := snap.ValidateName(ext.Snap); err != nil {
+		return fmt.Errorf("invalid snap for external task kind %q: %v", ext.Kind, err)
+	}
+	hooks := []string{ext.Hook}
+	if ext.UndoHook != "" {
+		hooks = append(hooks, ext.UndoHook)
+	}
+	for _, hook := range hooks {
+		if !validExternalTaskHook.MatchString(hook) {
+			return fmt.Errorf("invalid hook %q for external task kind %q: must start with \"task-\"", hook, ext.Kind)
+		}
+	}
+
+	m.externalKindsMu.Lock()
+	defer m.externalKindsMu.Unlock()
+	if old := m.externalKinds[ext.Kind]; old != nil && old.Snap != ext.Snap {
+		return fmt.Errorf("external task kind %q is already registered by snap %q", ext.Kind, old.Snap)
+	}
+	m.externalKinds[ext.Kind] = ext
+	return nil
+}
+
+func (m *HookManager) externalKind(kind string) *ExternalTaskKind {
+	m.externalKindsMu.RLock()
+	defer m.externalKindsMu.RUnlock()
+	return m.externalKinds[kind]
+}
+
+// isExternalTask matches the tasks of all the external kinds, also
+// the ones not registered (anymore) so that they fail instead of
+// waiting forever for a handler.
+func isExternalTask(t *state.Task) bool {
+	return strings.HasPrefix(t.Kind(), "ext-")
+}
+
+// ExternalTask returns a task of the given registered external kind,
+// ready to be added to a change.
+func (m *HookManager) ExternalTask(kind, summary string) (*state.Task, error) {
+	ext := m.externalKind(kind)
+	if ext == nil {
+		return nil, fmt.Errorf("external task kind %q is not registered", kind)
+	}
+	task := m.state.NewTask(kind, summary)
+	task.Set("hook-setup", &HookSetup{Snap: ext.Snap, Hook: ext.Hook, Timeout: ext.Timeout})
+	if ext.UndoHook != "" {
+		task.Set("undo-hook-setup", &HookSetup{Snap: ext.Snap, Hook: ext.UndoHook, Timeout: ext.Timeout})
+	}
+	return task, nil
+}
+
+func (m *HookManager) isHookTask(t *state.Task) bool {
+	if t.Kind() == "run-hook" {
+		return true
+	}
+	return m.externalKind(t.Kind()) != nil
+}
+
+// checkExternalTaskSnap checks that the snap implementing an external
+// task is either the gadget or published by the brand of the device.
+func checkExternalTaskSnap(task *state.Task, snapst *snapstate.SnapState, snapName string) error {
+	st := task.State()
+	deviceCtx, err := snapstate.DeviceCtx(st, task, nil)
+	if err != nil {
+		return err
+	}
+	model := deviceCtx.Model()
+	if snapName == model.Gadget() {
+		return nil
+	}
+	if snapst.IsInstalled() {
+		info, err := snapst.CurrentInfo()
+		if err != nil {
+			return err
+		}
+		if info.SnapID != "" {
+			decl, err := assertstate.SnapDeclaration(st, info.SnapID)
+			if err != nil {
+				return fmt.Errorf("cannot find snap declaration for %q: %v", snapName, err)
+			}
+			if decl.PublisherID() == model.BrandID() {
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf("cannot run %q task: snap %q is neither the gadget nor from the brand of the device", task.Kind(), snapName)
+}
+
+func (m *HookManager) doExternalTask(task *state.Task, tomb *tomb.Tomb) error {
+	return m.runExternalTask(task, tomb, "hook-setup")
+}
+
+func (m *HookManager) undoExternalTask(task *state.Task, tomb *tomb.Tomb) error {
+	return m.runExternalTask(task, tomb, "undo-hook-setup")
+}
+
+func (m *HookManager) runExternalTask(task *state.Task, tomb *tomb.Tomb, key string) error {
+	if m.externalKind(task.Kind()) == nil {
+		return fmt.Errorf("external task kind %q is not registered", task.Kind())
+	}
+
+	st := task.State()
+	st.Lock()
+	hooksup, snapst, err := hookSetup(task, key)
+	if err == state.ErrNoState && key == "undo-hook-setup" {
+		// nothing to undo
+		st.Unlock()
+		return nil
+	}
+	if err == nil {
+		err = checkExternalTaskSnap(task, snapst, hooksup.Snap)
+	}
+	if err == nil && hooksup.Revision.Unset() {
+		// the snap might only have been installed by an earlier
+		// task of the change, so run the current revision
+		hooksup.Revision = snapst.Current
+	}
+	st.Unlock()
+	if err != nil {
+		return err
+	}
+
+	return m.runHook(task, tomb, snapst, hooksup)
+}
@@ -0,0 +1,246 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2019 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package hookstate_test
+
+import (
+	"time"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/asserts"
+	"github.com/snapcore/snapd/asserts/assertstest"
+	"github.com/snapcore/snapd/overlord/assertstate"
+	"github.com/snapcore/snapd/overlord/hookstate"
+	"github.com/snapcore/snapd/overlord/snapstate/snapstatetest"
+	"github.com/snapcore/snapd/overlord/state"
+)
+
+type externalTaskSuite struct {
+	baseHookManagerSuite
+
+	storeSigning *assertstest.StoreStack
+	brands       *assertstest.SigningAccounts
+	db           *asserts.Database
+}
+
+var _ = Suite(&externalTaskSuite{})
+
+var brandPrivKey, _ = assertstest.GenerateKey(752)
+
+var externalSnapYaml = `
+name: test-snap
+version: 1.0
+hooks:
+    configure:
+    task-setup:
+    task-cleanup:
+`
+
+func (s *externalTaskSuite) SetUpTest(c *C) {
+	s.commonSetUpTest(c)
+	s.setUpSnap(c, "test-snap", externalSnapYaml)
+
+	s.storeSigning = assertstest.NewStoreStack("can0nical", nil)
+	s.brands = assertstest.NewSigningAccounts(s.storeSigning)
+	s.brands.Register("my-brand", brandPrivKey, nil)
+
+	db, err := asserts.OpenDatabase(&asserts.DatabaseConfig{
+		Backstore: asserts.NewMemoryBackstore(),
+		Trusted:   s.storeSigning.Trusted,
+	})
+	c.Assert(err, IsNil)
+	s.db = db
+	c.Assert(db.Add(s.storeSigning.StoreAccountKey("")), IsNil)
+
+	s.state.Lock()
+	assertstate.ReplaceDB(s.state, db)
+	// only the external task is of interest here
+	s.task.SetStatus(state.DoneStatus)
+	s.state.Unlock()
+}
+
+func (s *externalTaskSuite) TearDownTest(c *C) {
+	s.commonTearDownTest(c)
+}
+
+func (s *externalTaskSuite) mockModel(gadget string) {
+	model := s.brands.Model("my-brand", "my-model", map[string]interface{}{
+		"architecture": "amd64",
+		"gadget":       gadget,
+		"kernel":       "pc-kernel",
+	})
+	s.AddCleanup(snapstatetest.MockDeviceModel(model))
+}
+
+func (s *externalTaskSuite) addSnapDeclaration(c *C, publisherID string) {
+	c.Assert(s.db.Add(s.brands.Account("my-brand")), IsNil)
+	c.Assert(s.db.Add(s.brands.AccountKey("my-brand")), IsNil)
+	decl, err := s.storeSigning.Sign(asserts.SnapDeclarationType, map[string]interface{}{
+		"series":       "16",
+		"snap-id":      "some-snap-id",
+		"snap-name":    "test-snap",
+		"publisher-id": publisherID,
+		"timestamp":    time.Now().Format(time.RFC3339),
+	}, nil, "")
+	c.Assert(err, IsNil)
+	c.Assert(s.db.Add(decl), IsNil)
+}
+
+func (s *externalTaskSuite) TestRegisterExternalTaskKindErrors(c *C) {
+	for _, t := range []struct {
+		ext *hookstate.ExternalTaskKind
+		err string
+	}{
+		{&hookstate.ExternalTaskKind{Kind: "do-thing", Snap: "test-snap", Hook: "task-setup"}, `invalid external task kind "do-thing": must start with "ext-"`},
+		{&hookstate.ExternalTaskKind{Kind: "ext-thing", Snap: "Test", Hook: "task-setup"}, `invalid snap for external task kind "ext-thing": .*`},
+		{&hookstate.ExternalTaskKind{Kind: "ext-thing", Snap: "test-snap"}, `invalid hook "" for external task kind "ext-thing": must start with "task-"`},
+		{&hookstate.ExternalTaskKind{Kind: "ext-thing", Snap: "test-snap", Hook: "configure"}, `invalid hook "configure" for external task kind "ext-thing": must start with "task-"`},
+		{&hookstate.ExternalTaskKind{Kind: "ext-thing", Snap: "test-snap", Hook: "task-setup", UndoHook: "remove"}, `invalid hook "remove" for external task kind "ext-thing": must start with "task-"`},
+	} {
+		c.Check(s.manager.RegisterExternalTaskKind(t.ext), ErrorMatches, t.err)
+	}
+
+	ext := &hookstate.ExternalTaskKind{Kind: "ext-thing", Snap: "test-snap", Hook: "task-setup"}
+	c.Assert(s.manager.RegisterExternalTaskKind(ext), IsNil)
+	// the same snap can register the kind again
+	c.Check(s.manager.RegisterExternalTaskKind(ext), IsNil)
+	other := &hookstate.ExternalTaskKind{Kind: "ext-thing", Snap: "other-snap", Hook: "task-setup"}
+	c.Check(s.manager.RegisterExternalTaskKind(other), ErrorMatches, `external task kind "ext-thing" is already registered by snap "test-snap"`)
+}
+
+func (s *externalTaskSuite) TestExternalTask(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	_, err := s.manager.ExternalTask("ext-thing", "summary")
+	c.Check(err, ErrorMatches, `external task kind "ext-thing" is not registered`)
+
+	err = s.manager.RegisterExternalTaskKind(&hookstate.ExternalTaskKind{
+		Kind:     "ext-thing",
+		Snap:     "test-snap",
+		Hook:     "task-setup",
+		UndoHook: "task-cleanup",
+		Timeout:  time.Minute,
+	})
+	c.Assert(err, IsNil)
+
+	task, err := s.manager.ExternalTask("ext-thing", "summary")
+	c.Assert(err, IsNil)
+	c.Check(task.Kind(), Equals, "ext-thing")
+
+	var hooksup hookstate.HookSetup
+	c.Assert(task.Get("hook-setup", &hooksup), IsNil)
+	c.Check(hooksup, DeepEquals, hookstate.HookSetup{Snap: "test-snap", Hook: "task-setup", Timeout: time.Minute})
+	c.Assert(task.Get("undo-hook-setup", &hooksup), IsNil)
+	c.Check(hooksup, DeepEquals, hookstate.HookSetup{Snap: "test-snap", Hook: "task-cleanup", Timeout: time.Minute})
+}
+
+func (s *externalTaskSuite) runExternalTask(c *C) *state.Change {
+	err := s.manager.RegisterExternalTaskKind(&hookstate.ExternalTaskKind{
+		Kind:     "ext-thing",
+		Snap:     "test-snap",
+		Hook:     "task-setup",
+		UndoHook: "task-cleanup",
+	})
+	c.Assert(err, IsNil)
+
+	s.state.Lock()
+	task, err := s.manager.ExternalTask("ext-thing", "summary")
+	c.Assert(err, IsNil)
+	chg := s.state.NewChange("ext", "...")
+	chg.AddTask(task)
+	s.state.Unlock()
+
+	s.se.Ensure()
+	s.se.Wait()
+	return chg
+}
+
+func (s *externalTaskSuite) TestRunExternalTaskGadget(c *C) {
+	s.mockModel("test-snap")
+
+	chg := s.runExternalTask(c)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	c.Check(chg.Err(), IsNil)
+	c.Check(chg.Status(), Equals, state.DoneStatus)
+	c.Check(s.command.Calls(), DeepEquals, [][]string{{
+		"snap", "run", "--hook", "task-setup", "-r", "1", "test-snap",
+	}})
+}
+
+func (s *externalTaskSuite) TestRunExternalTaskBrandSnap(c *C) {
+	s.mockModel("pc")
+	s.addSnapDeclaration(c, "my-brand")
+
+	chg := s.runExternalTask(c)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	c.Check(chg.Err(), IsNil)
+	c.Check(chg.Status(), Equals, state.DoneStatus)
+	c.Check(s.command.Calls(), HasLen, 1)
+}
+
+func (s *externalTaskSuite) TestRunExternalTaskOtherPublisher(c *C) {
+	s.mockModel("pc")
+	s.addSnapDeclaration(c, "can0nical")
+
+	chg := s.runExternalTask(c)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	c.Check(chg.Err(), ErrorMatches, `(?s).*cannot run "ext-thing" task: snap "test-snap" is neither the gadget nor from the brand of the device.*`)
+	c.Check(s.command.Calls(), HasLen, 0)
+}
+
+func (s *externalTaskSuite) TestRunExternalTaskNoSnapDeclaration(c *C) {
+	s.mockModel("pc")
+
+	chg := s.runExternalTask(c)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	c.Check(chg.Err(), ErrorMatches, `(?s).*cannot find snap declaration for "test-snap".*`)
+	c.Check(s.command.Calls(), HasLen, 0)
+}
+
+func (s *externalTaskSuite) TestRunExternalTaskNotRegistered(c *C) {
+	s.mockModel("test-snap")
+
+	// as after a restart when the provider did not register the
+	// kind again
+	s.state.Lock()
+	task := s.state.NewTask("ext-gone", "summary")
+	task.Set("hook-setup", &hookstate.HookSetup{Snap: "test-snap", Hook: "task-setup"})
+	chg := s.state.NewChange("ext", "...")
+	chg.AddTask(task)
+	s.state.Unlock()
+
+	s.se.Ensure()
+	s.se.Wait()
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	c.Check(chg.Err(), ErrorMatches, `(?s).*external task kind "ext-gone" is not registered.*`)
+	c.Check(chg.Status(), Equals, state.ErrorStatus)
+	c.Check(s.command.Calls(), HasLen, 0)
+}
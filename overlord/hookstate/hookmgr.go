@@ -20,6 +20,8 @@
 package hookstate
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -56,6 +58,9 @@ type HookManager struct {
 
 	hijackMap map[hijackKey]hijackFunc
 
+	externalKindsMu sync.RWMutex
+	externalKinds   map[string]*ExternalTaskKind
+
 	runningHooks int32
 	runner       *state.TaskRunner
 }
@@ -89,10 +94,19 @@ type HookSetup struct {
 
 // Manager returns a new HookManager.
 func Manager(s *state.State, runner *state.TaskRunner) (*HookManager, error) {
+	manager := &HookManager{
+		state:         s,
+		repository:    newRepository(),
+		contexts:      make(map[string]*Context),
+		hijackMap:     make(map[hijackKey]hijackFunc),
+		externalKinds: make(map[string]*ExternalTaskKind),
+		runner:        runner,
+	}
+
 	// Make sure we only run 1 hook task for given snap at a time
 	runner.AddBlocked(func(thisTask *state.Task, running []*state.Task) bool {
 		// check if we're a hook task, probably not needed but let's take extra care
-		if thisTask.Kind() != "run-hook" {
+		if !manager.isHookTask(thisTask) {
 			return false
 		}
 		var hooksup HookSetup
@@ -102,7 +116,7 @@ func Manager(s *state.State, runner *state.TaskRunner) (*HookManager, error) {
 		thisSnapName := hooksup.Snap
 		// examine all hook tasks, block thisTask if we find any other hook task affecting same snap
 		for _, t := range running {
-			if t.Kind() != "run-hook" || t.Get("hook-setup", &hooksup) != nil {
+			if !manager.isHookTask(t) || t.Get("hook-setup", &hooksup) != nil {
 				continue // ignore errors and continue checking remaining tasks
 			}
 			if hooksup.Snap == thisSnapName {
@@ -113,15 +127,8 @@ func Manager(s *state.State, runner *state.TaskRunner) (*HookManager, error) {
 		return false
 	})
 
-	manager := &HookManager{
-		state:      s,
-		repository: newRepository(),
-		contexts:   make(map[string]*Context),
-		hijackMap:  make(map[hijackKey]hijackFunc),
-		runner:     runner,
-	}
-
 	runner.AddHandler("run-hook", manager.doRunHook, manager.undoRunHook)
+	runner.AddOptionalHandler(isExternalTask, manager.doExternalTask, manager.undoExternalTask)
 	// Compatibility with snapd between 2.29 and 2.30 in edge only.
 	// We generated a configure-snapd task on core refreshes and
 	// for compatibility we need to handle those.
@@ -362,6 +369,12 @@ func (m *HookManager) runHook(task *state.Task, tomb *tomb.Tomb, snapst *snapsta
 		err = f(context)
 	} else if hookExists {
 		output, err = runHook(context, tomb)
+		if len(output) > 0 {
+			// the output is bounded to its tail by runHook
+			task.State().Lock()
+			task.Set("hook-output", string(output))
+			task.State().Unlock()
+		}
 	}
 	if err != nil {
 		if hooksup.TrackError {
@@ -391,9 +404,133 @@ func (m *HookManager) runHook(task *state.Task, tomb *tomb.Tomb, snapst *snapsta
 		return err
 	}
 
+	return publishHookResult(context)
+}
+
+type hookResult struct {
+	Snap   string                      `json:"snap"`
+	Hook   string                      `json:"hook"`
+	Result map[string]*json.RawMessage `json:"result"`
+}
+
+// publishHookResult adds the results the hook reported with snapctl
+// set-result, if any, to the data of its change that is exposed to
+// clients.
+func publishHookResult(context *Context) error {
+	var result map[string]*json.RawMessage
+	if err := context.Get("hook-result", &result); err != nil {
+		if err == state.ErrNoState {
+			return nil
+		}
+		return err
+	}
+	chg := context.task.Change()
+	if chg == nil {
+		return nil
+	}
+
+	var data map[string]interface{}
+	err := chg.Get("api-data", &data)
+	if err != nil && err != state.ErrNoState {
+		return err
+	}
+	if len(data) == 0 {
+		data = make(map[string]interface{})
+	}
+	results, _ := data["hook-results"].([]interface{})
+	data["hook-results"] = append(results, &hookResult{
+		Snap:   context.InstanceName(),
+		Hook:   context.HookName(),
+		Result: result,
+	})
+	chg.Set("api-data", data)
 	return nil
 }
 
+// EphemeralRunHook runs the given hook synchronously without a backing
+// task. The given context data is available to the hook via snapctl
+// and the context is returned once the hook finished successfully, so
+// that callers can retrieve what the hook set. The state must not be
+// locked by the caller.
+func (m *HookManager) EphemeralRunHook(ctx context.Context, hooksup *HookSetup, contextData map[string]interface{}) (*Context, error) {
+	var snapst snapstate.SnapState
+	m.state.Lock()
+	err := snapstate.Get(m.state, hooksup.Snap, &snapst)
+	m.state.Unlock()
+	if err != nil {
+		return nil, fmt.Errorf("cannot run hook %q of snap %q: %v", hooksup.Hook, hooksup.Snap, err)
+	}
+	info, err := snapst.CurrentInfo()
+	if err != nil {
+		return nil, fmt.Errorf("cannot read %q snap details: %v", hooksup.Snap, err)
+	}
+	if info.Hooks[hooksup.Hook] == nil {
+		return nil, fmt.Errorf("snap %q has no %q hook", hooksup.Snap, hooksup.Hook)
+	}
+	setup := *hooksup
+	if setup.Revision.Unset() {
+		setup.Revision = snapst.Current
+	}
+
+	context, err := NewContext(nil, m.state, &setup, nil, "")
+	if err != nil {
+		return nil, err
+	}
+	context.Lock()
+	for k, v := range contextData {
+		context.Set(k, v)
+	}
+	context.Unlock()
+
+	handlers := m.repository.generateHandlers(context)
+	if len(handlers) != 1 {
+		return nil, fmt.Errorf("internal error: %d handlers registered for hook %q, expected 1", len(handlers), setup.Hook)
+	}
+	context.handler = handlers[0]
+
+	contextID := context.ID()
+	m.contextsMutex.Lock()
+	m.contexts[contextID] = context
+	m.contextsMutex.Unlock()
+
+	defer func() {
+		m.contextsMutex.Lock()
+		delete(m.contexts, contextID)
+		m.contextsMutex.Unlock()
+	}()
+
+	if err := context.Handler().Before(); err != nil {
+		return nil, err
+	}
+
+	atomic.AddInt32(&m.runningHooks, 1)
+	defer atomic.AddInt32(&m.runningHooks, -1)
+
+	tb, _ := tomb.WithContext(ctx)
+	// make sure the tomb goroutine goes away
+	defer tb.Kill(nil)
+	output, err := runHook(context, tb)
+	if err != nil {
+		err = osutil.OutputErr(output, err)
+		if handlerErr := context.Handler().Error(err); handlerErr != nil {
+			return nil, handlerErr
+		}
+		return nil, fmt.Errorf("run hook %q: %v", setup.Hook, err)
+	}
+
+	if err := context.Handler().Done(); err != nil {
+		return nil, err
+	}
+
+	context.Lock()
+	defer context.Unlock()
+	if err := context.Done(); err != nil {
+		return nil, err
+	}
+
+	return context, nil
+}
+
 func runHookImpl(c *Context, tomb *tomb.Tomb) ([]byte, error) {
 	return runHookAndWait(c.InstanceName(), c.SnapRevision(), c.HookName(), c.ID(), c.Timeout(), tomb)
 }
@@ -434,6 +571,25 @@ func snapCmd() string {
 
 var defaultHookTimeout = 10 * time.Minute
 
+// hookTimeouts holds the timeouts of the hooks that are not run with
+// the default hook timeout.
+var hookTimeouts = map[string]func() time.Duration{}
+
+// RegisterHookTimeout records the timeout the given hook is run with
+// when that is not the default hook timeout.
+func RegisterHookTimeout(hookName string, timeout func() time.Duration) {
+	hookTimeouts[hookName] = timeout
+}
+
+// HookTimeout returns how long the given hook is allowed to run for
+// before being killed.
+func HookTimeout(hookName string) time.Duration {
+	if timeout := hookTimeouts[hookName]; timeout != nil {
+		return timeout()
+	}
+	return defaultHookTimeout
+}
+
 func runHookAndWait(snapName string, revision snap.Revision, hookName, hookContext string, timeout time.Duration, tomb *tomb.Tomb) ([]byte, error) {
 	argv := []string{snapCmd(), "run", "--hook", hookName, "-r", revision.String(), snapName}
 	if timeout == 0 {
@@ -30,6 +30,7 @@ func init() {
 	snapstate.SetupInstallHook = SetupInstallHook
 	snapstate.SetupPreRefreshHook = SetupPreRefreshHook
 	snapstate.SetupPostRefreshHook = SetupPostRefreshHook
+	snapstate.SetupDataMigrateHook = SetupDataMigrateHook
 	snapstate.SetupRemoveHook = SetupRemoveHook
 }
 
@@ -57,6 +58,20 @@ func SetupPostRefreshHook(st *state.State, snapName string) *state.Task {
 	return HookTask(st, summary, hooksup, nil)
 }
 
+// SetupDataMigrateHook returns a task to run the data-migrate hook of
+// the snap when refreshing across epochs or bases, failure of the hook aborts
+// the refresh and restores the data saved before the migration.
+func SetupDataMigrateHook(st *state.State, snapName string) *state.Task {
+	hooksup := &HookSetup{
+		Snap:     snapName,
+		Hook:     "data-migrate",
+		Optional: true,
+	}
+
+	summary := fmt.Sprintf(i18n.G("Run data-migrate hook of %q snap if present"), hooksup.Snap)
+	return HookTask(st, summary, hooksup, nil)
+}
+
 func SetupPreRefreshHook(st *state.State, snapName string) *state.Task {
 	hooksup := &HookSetup{
 		Snap:     snapName,
@@ -70,6 +85,34 @@ func SetupPreRefreshHook(st *state.State, snapName string) *state.Task {
 	return task
 }
 
+// SetupPreRestoreHook returns a task to run the pre-restore hook of the
+// snap, failure of the hook aborts the restore of the snapshot.
+func SetupPreRestoreHook(st *state.State, snapName string) *state.Task {
+	hooksup := &HookSetup{
+		Snap:     snapName,
+		Hook:     "pre-restore",
+		Optional: true,
+	}
+
+	summary := fmt.Sprintf(i18n.G("Run pre-restore hook of %q snap if present"), hooksup.Snap)
+	return HookTask(st, summary, hooksup, nil)
+}
+
+// SetupPostRestoreHook returns a task to run the post-restore hook of
+// the snap, failure of the hook is only reported as the snapshot data
+// was already restored.
+func SetupPostRestoreHook(st *state.State, snapName string) *state.Task {
+	hooksup := &HookSetup{
+		Snap:        snapName,
+		Hook:        "post-restore",
+		Optional:    true,
+		IgnoreError: true,
+	}
+
+	summary := fmt.Sprintf(i18n.G("Run post-restore hook of %q snap if present"), hooksup.Snap)
+	return HookTask(st, summary, hooksup, nil)
+}
+
 type snapHookHandler struct {
 }
 
@@ -107,5 +150,9 @@ func setupHooks(hookMgr *HookManager) {
 	hookMgr.Register(regexp.MustCompile("^install$"), handlerGenerator)
 	hookMgr.Register(regexp.MustCompile("^post-refresh$"), handlerGenerator)
 	hookMgr.Register(regexp.MustCompile("^pre-refresh$"), handlerGenerator)
+	hookMgr.Register(regexp.MustCompile("^data-migrate$"), handlerGenerator)
 	hookMgr.Register(regexp.MustCompile("^remove$"), handlerGenerator)
+	hookMgr.Register(regexp.MustCompile("^pre-restore$"), handlerGenerator)
+	hookMgr.Register(regexp.MustCompile("^post-restore$"), handlerGenerator)
+	hookMgr.Register(validExternalTaskHook, handlerGenerator)
 }
@@ -30,6 +30,7 @@ import (
 	"time"
 
 	. "gopkg.in/check.v1"
+	"gopkg.in/tomb.v2"
 
 	"github.com/snapcore/snapd/dirs"
 	"github.com/snapcore/snapd/overlord"
@@ -434,6 +435,76 @@ func (s *hookManagerSuite) TestHookTaskHandleIgnoreErrorWorks(c *C) {
 	checkTaskLogContains(c, s.task, ".*ignoring failure in hook.*")
 }
 
+func (s *hookManagerSuite) TestHookTaskKeepsOutput(c *C) {
+	cmd := testutil.MockCommand(c, "snap", "echo 'some output'; >&2 echo 'some error'")
+	defer cmd.Restore()
+
+	s.se.Ensure()
+	s.se.Wait()
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	c.Check(s.task.Status(), Equals, state.DoneStatus)
+	var output string
+	c.Assert(s.task.Get("hook-output", &output), IsNil)
+	c.Check(output, Equals, "some output\nsome error\n")
+}
+
+func (s *hookManagerSuite) TestHookTaskKeepsOutputOnError(c *C) {
+	cmd := testutil.MockCommand(c, "snap", ">&2 echo 'hook failed at user request'; exit 1")
+	defer cmd.Restore()
+
+	s.se.Ensure()
+	s.se.Wait()
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	c.Check(s.task.Status(), Equals, state.ErrorStatus)
+	var output string
+	c.Assert(s.task.Get("hook-output", &output), IsNil)
+	c.Check(output, Equals, "hook failed at user request\n")
+}
+
+func (s *hookManagerSuite) TestHookTaskPublishesResult(c *C) {
+	restore := hookstate.MockRunHook(func(ctx *hookstate.Context, _ *tomb.Tomb) ([]byte, error) {
+		ctx.Lock()
+		defer ctx.Unlock()
+		// as done by snapctl set-result
+		ctx.Set("hook-result", map[string]interface{}{"files": 42})
+		return nil, nil
+	})
+	defer restore()
+
+	s.state.Lock()
+	s.change.Set("api-data", map[string]interface{}{"snap-names": []string{"test-snap"}})
+	s.state.Unlock()
+
+	s.se.Ensure()
+	s.se.Wait()
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	c.Check(s.task.Status(), Equals, state.DoneStatus)
+	var data map[string]interface{}
+	c.Assert(s.change.Get("api-data", &data), IsNil)
+	c.Check(data, DeepEquals, map[string]interface{}{
+		"snap-names": []interface{}{"test-snap"},
+		"hook-results": []interface{}{
+			map[string]interface{}{
+				"snap":   "test-snap",
+				"hook":   "configure",
+				"result": map[string]interface{}{"files": 42.0},
+			},
+		},
+	})
+	// no output
+	var output string
+	c.Check(s.task.Get("hook-output", &output), Equals, state.ErrNoState)
+}
+
 func (s *hookManagerSuite) TestHookTaskEnforcesTimeout(c *C) {
 	var hooksup hookstate.HookSetup
 
@@ -464,6 +535,17 @@ func (s *hookManagerSuite) TestHookTaskEnforcesTimeout(c *C) {
 	checkTaskLogContains(c, s.task, `.*exceeded maximum runtime of 200ms`)
 }
 
+func (s *hookManagerSuite) TestHookTimeout(c *C) {
+	restore := hookstate.MockDefaultHookTimeout(150 * time.Millisecond)
+	defer restore()
+
+	c.Check(hookstate.HookTimeout("install"), Equals, 150*time.Millisecond)
+
+	hookstate.RegisterHookTimeout("test-hook", func() time.Duration { return time.Second })
+	defer hookstate.RegisterHookTimeout("test-hook", nil)
+	c.Check(hookstate.HookTimeout("test-hook"), Equals, time.Second)
+}
+
 func (s *hookManagerSuite) TestHookTaskEnforcesDefaultTimeout(c *C) {
 	restore := hookstate.MockDefaultHookTimeout(150 * time.Millisecond)
 	defer restore()
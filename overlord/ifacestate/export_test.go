@@ -21,6 +21,7 @@ import (
 	"time"
 
 	"github.com/snapcore/snapd/interfaces"
+	"github.com/snapcore/snapd/overlord/hookstate"
 	"github.com/snapcore/snapd/overlord/ifacestate/udevmonitor"
 	"github.com/snapcore/snapd/overlord/state"
 )
@@ -55,6 +56,7 @@ var (
 	AllocHotplugSeq              = allocHotplugSeq
 	AddHotplugSeqWaitTask        = addHotplugSeqWaitTask
 	AddHotplugSlot               = addHotplugSlot
+	SnapFileAccess               = snapFileAccess
 )
 
 func NewConnectOptsWithAutoSet() connectOpts {
@@ -156,3 +158,8 @@ func MockWriteSystemKey(fn func() error) func() {
 func (m *InterfaceManager) TransitionConnectionsCoreMigration(st *state.State, oldName, newName string) error {
 	return m.transitionConnectionsCoreMigration(st, oldName, newName)
 }
+
+// NewInterfaceHookHandler returns the handler of interface hooks for the given context.
+func NewInterfaceHookHandler(context *hookstate.Context) hookstate.Handler {
+	return &interfaceHookHandler{context: context}
+}
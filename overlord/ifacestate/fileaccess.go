@@ -0,0 +1,256 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2019 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package ifacestate
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/snapcore/snapd/interfaces"
+	"github.com/snapcore/snapd/interfaces/mount"
+	"github.com/snapcore/snapd/osutil"
+	"github.com/snapcore/snapd/overlord/snapstate"
+	"github.com/snapcore/snapd/snap"
+)
+
+// FileAccess describes the access a snap has to a host path.
+type FileAccess string
+
+const (
+	FileAccessHidden    FileAccess = "hidden"
+	FileAccessReadOnly  FileAccess = "read-only"
+	FileAccessReadWrite FileAccess = "read-write"
+)
+
+func (a FileAccess) level() int {
+	switch a {
+	case FileAccessReadWrite:
+		return 2
+	case FileAccessReadOnly:
+		return 1
+	}
+	return 0
+}
+
+func maxFileAccess(a, b FileAccess) FileAccess {
+	if b.level() > a.level() {
+		return b
+	}
+	return a
+}
+
+// pathIsBelow returns whether the given clean absolute path is dir or
+// is below it.
+func pathIsBelow(path, dir string) bool {
+	dir = filepath.Clean(dir)
+	return path == dir || strings.HasPrefix(path, strings.TrimSuffix(dir, "/")+"/")
+}
+
+// dataDirAccess returns the access a snap has to the given path below
+// one of its data directories: it can write its common data and the
+// data of its current revision, and read the data of other revisions.
+func dataDirAccess(snapInfo *snap.Info, dataDir, path string) FileAccess {
+	rel := strings.TrimPrefix(strings.TrimPrefix(path, dataDir), "/")
+	if rel == "" {
+		return FileAccessReadOnly
+	}
+	switch strings.SplitN(rel, "/", 2)[0] {
+	case "common", "current", snapInfo.Revision.String():
+		return FileAccessReadWrite
+	}
+	return FileAccessReadOnly
+}
+
+// filesAttrAccess returns the access granted to path by the "read" and
+// "write" attributes of a personal-files or system-files plug.
+func filesAttrAccess(plug *interfaces.ConnectedPlug, homeDir, path string) FileAccess {
+	access := FileAccessHidden
+	for _, attr := range []struct {
+		name   string
+		access FileAccess
+	}{
+		{"read", FileAccessReadOnly},
+		{"write", FileAccessReadWrite},
+	} {
+		var paths []interface{}
+		if err := plug.Attr(attr.name, &paths); err != nil {
+			continue
+		}
+		for _, p := range paths {
+			s, ok := p.(string)
+			if !ok {
+				continue
+			}
+			if strings.HasPrefix(s, "$HOME/") {
+				if homeDir == "" {
+					continue
+				}
+				s = filepath.Join(homeDir, strings.TrimPrefix(s, "$HOME/"))
+			}
+			if pathIsBelow(path, s) {
+				access = maxFileAccess(access, attr.access)
+			}
+		}
+	}
+	return access
+}
+
+// connectedPlugAccess returns the access granted to path by a
+// connection of one of the plugs of the snap.
+func connectedPlugAccess(plug *interfaces.ConnectedPlug, homeDir, path string) FileAccess {
+	switch plug.Interface() {
+	case "home":
+		// the snap can use the home directory, except for
+		// top-level hidden files
+		if homeDir == "" || !pathIsBelow(path, homeDir) {
+			return FileAccessHidden
+		}
+		rel := strings.TrimPrefix(strings.TrimPrefix(path, homeDir), "/")
+		if strings.HasPrefix(rel, ".") {
+			return FileAccessHidden
+		}
+		return FileAccessReadWrite
+	case "removable-media":
+		for _, mountPoint := range []string{"/mnt", "/media", "/run/media"} {
+			if pathIsBelow(path, mountPoint) {
+				return FileAccessReadWrite
+			}
+		}
+	case "personal-files", "system-files":
+		return filesAttrAccess(plug, homeDir, path)
+	}
+	return FileAccessHidden
+}
+
+// mountEntryAccess returns the access granted to path by a bind mount
+// of the mount namespace of the snap, when the source of the mount is
+// a host path, such as the content shared by another snap.
+func mountEntryAccess(entry osutil.MountEntry, path string) FileAccess {
+	if strings.Contains(entry.Name, "$") || !filepath.IsAbs(entry.Name) || !pathIsBelow(path, entry.Name) {
+		return FileAccessHidden
+	}
+	bind, ro := false, false
+	for _, opt := range entry.Options {
+		switch opt {
+		case "bind", "rbind":
+			bind = true
+		case "ro":
+			ro = true
+		}
+	}
+	switch {
+	case !bind:
+		return FileAccessHidden
+	case ro:
+		return FileAccessReadOnly
+	}
+	return FileAccessReadWrite
+}
+
+// snapFileAccess returns the access the given snap has to the given
+// absolute host path, for the user with the given home directory.
+//
+// The answer is derived from the data directories of the snap, its
+// plugs connected to the home, removable-media, personal-files and
+// system-files interfaces as found in the repository, and the bind
+// mounts that its connections add to its mount namespace. Access granted by other interfaces
+// is not evaluated, those paths are reported as hidden so that callers
+// err on the side of exporting them to the snap.
+func snapFileAccess(repo *interfaces.Repository, snapInfo *snap.Info, opts interfaces.ConfinementOptions, path, homeDir string) (FileAccess, error) {
+	if !filepath.IsAbs(path) {
+		return FileAccessHidden, fmt.Errorf("cannot check the access to relative path %q", path)
+	}
+	// classic snaps run in the host mount namespace and devmode
+	// snaps are not denied access, so they can use everything
+	if opts.Classic || opts.DevMode {
+		return FileAccessReadWrite, nil
+	}
+	path = filepath.Clean(path)
+	if homeDir != "" {
+		homeDir = filepath.Clean(homeDir)
+	}
+	instanceName := snapInfo.InstanceName()
+
+	// the snap can read its own files and use its data directories
+	if pathIsBelow(path, filepath.Dir(snapInfo.MountDir())) {
+		return FileAccessReadOnly, nil
+	}
+	if dataDir := snap.BaseDataDir(instanceName); pathIsBelow(path, dataDir) {
+		return dataDirAccess(snapInfo, dataDir, path), nil
+	}
+	if homeDir != "" {
+		if userSnapDir := filepath.Join(homeDir, "snap"); pathIsBelow(path, userSnapDir) {
+			// but not the data of other snaps
+			if userDataDir := snap.UserSnapDir(homeDir, instanceName); pathIsBelow(path, userDataDir) {
+				return dataDirAccess(snapInfo, userDataDir, path), nil
+			}
+			return FileAccessHidden, nil
+		}
+	}
+
+	access := FileAccessHidden
+	spec := &mount.Specification{}
+	conns, err := repo.Connections(instanceName)
+	if err != nil {
+		return FileAccessHidden, err
+	}
+	for _, ref := range conns {
+		if ref.PlugRef.Snap != instanceName {
+			continue
+		}
+		conn, err := repo.Connection(ref)
+		if err != nil {
+			return FileAccessHidden, err
+		}
+		access = maxFileAccess(access, connectedPlugAccess(conn.Plug, homeDir, path))
+		iface := repo.Interface(conn.Plug.Interface())
+		if iface == nil {
+			continue
+		}
+		if err := spec.AddConnectedPlug(iface, conn.Plug, conn.Slot); err != nil {
+			return FileAccessHidden, err
+		}
+	}
+	for _, entry := range spec.MountEntries() {
+		access = maxFileAccess(access, mountEntryAccess(entry, path))
+	}
+	return access, nil
+}
+
+// FileAccess returns the access the given snap, with its current
+// connections, has to the given absolute host path when run by the
+// user with the given home directory. Only the data directories of the
+// snap, the home, removable-media, personal-files and system-files
+// interfaces and the bind mounts of its mount namespace are evaluated,
+// other paths are reported as hidden.
+//
+// The state must be locked by the caller.
+func (m *InterfaceManager) FileAccess(instanceName, path, homeDir string) (FileAccess, error) {
+	var snapst snapstate.SnapState
+	if err := snapstate.Get(m.state, instanceName, &snapst); err != nil {
+		return FileAccessHidden, err
+	}
+	snapInfo, err := snapst.CurrentInfo()
+	if err != nil {
+		return FileAccessHidden, err
+	}
+	return snapFileAccess(m.repo, snapInfo, confinementOptions(snapst.Flags), path, homeDir)
+}
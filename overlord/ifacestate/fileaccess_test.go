@@ -0,0 +1,173 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2019 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package ifacestate_test
+
+import (
+	"path/filepath"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/interfaces"
+	"github.com/snapcore/snapd/interfaces/builtin"
+	"github.com/snapcore/snapd/overlord/ifacestate"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/snap/snaptest"
+)
+
+type fileAccessSuite struct {
+	repo     *interfaces.Repository
+	consumer *snap.Info
+}
+
+var _ = Suite(&fileAccessSuite{})
+
+const fileAccessCoreYaml = `name: core
+version: 0
+type: os
+slots:
+  home:
+  removable-media:
+  personal-files:
+  system-files:
+`
+
+const fileAccessProducerYaml = `name: producer
+version: 0
+slots:
+  stuff:
+    interface: content
+    read: [$SNAP/lib]
+    write: [$SNAP_DATA/out]
+`
+
+const fileAccessConsumerYaml = `name: consumer
+version: 0
+plugs:
+  home:
+  removable-media:
+  dot-foo:
+    interface: personal-files
+    read: [$HOME/.foo]
+    write: [$HOME/.foo/cache]
+  etc-bar:
+    interface: system-files
+    read: [/etc/bar]
+  stuff:
+    interface: content
+    target: $SNAP/stuff
+`
+
+func (s *fileAccessSuite) SetUpTest(c *C) {
+	dirs.SetRootDir(c.MkDir())
+
+	s.repo = interfaces.NewRepository()
+	for _, iface := range builtin.Interfaces() {
+		c.Assert(s.repo.AddInterface(iface), IsNil)
+	}
+	core := snaptest.MockInfo(c, fileAccessCoreYaml, &snap.SideInfo{Revision: snap.R(1)})
+	producer := snaptest.MockInfo(c, fileAccessProducerYaml, &snap.SideInfo{Revision: snap.R(2)})
+	s.consumer = snaptest.MockInfo(c, fileAccessConsumerYaml, &snap.SideInfo{Revision: snap.R(3)})
+	for _, info := range []*snap.Info{core, producer, s.consumer} {
+		c.Assert(s.repo.AddSnap(info), IsNil)
+	}
+}
+
+func (s *fileAccessSuite) TearDownTest(c *C) {
+	dirs.SetRootDir("")
+}
+
+func (s *fileAccessSuite) connect(c *C, plugName, slotSnap, slotName string) {
+	ref := &interfaces.ConnRef{
+		PlugRef: interfaces.PlugRef{Snap: "consumer", Name: plugName},
+		SlotRef: interfaces.SlotRef{Snap: slotSnap, Name: slotName},
+	}
+	_, err := s.repo.Connect(ref, nil, nil, nil, nil, nil)
+	c.Assert(err, IsNil)
+}
+
+func (s *fileAccessSuite) checkAccess(c *C, opts interfaces.ConfinementOptions, path string, expected ifacestate.FileAccess) {
+	access, err := ifacestate.SnapFileAccess(s.repo, s.consumer, opts, path, "/home/user")
+	c.Assert(err, IsNil)
+	c.Check(access, Equals, expected, Commentf("%s", path))
+}
+
+func (s *fileAccessSuite) TestSnapFileAccessRelativePath(c *C) {
+	_, err := ifacestate.SnapFileAccess(s.repo, s.consumer, interfaces.ConfinementOptions{}, "foo/bar", "/home/user")
+	c.Assert(err, ErrorMatches, `cannot check the access to relative path "foo/bar"`)
+}
+
+func (s *fileAccessSuite) TestSnapFileAccessClassicAndDevMode(c *C) {
+	s.checkAccess(c, interfaces.ConfinementOptions{Classic: true}, "/etc/passwd", ifacestate.FileAccessReadWrite)
+	s.checkAccess(c, interfaces.ConfinementOptions{DevMode: true}, "/home/user/.ssh", ifacestate.FileAccessReadWrite)
+}
+
+func (s *fileAccessSuite) TestSnapFileAccessSnapDirs(c *C) {
+	opts := interfaces.ConfinementOptions{}
+	for _, t := range []struct {
+		path   string
+		access ifacestate.FileAccess
+	}{
+		{filepath.Join(dirs.SnapMountDir, "consumer/3/bin"), ifacestate.FileAccessReadOnly},
+		{filepath.Join(dirs.SnapMountDir, "other/1/bin"), ifacestate.FileAccessHidden},
+		{filepath.Join(dirs.SnapDataDir, "consumer/common/foo"), ifacestate.FileAccessReadWrite},
+		{filepath.Join(dirs.SnapDataDir, "consumer/current/foo"), ifacestate.FileAccessReadWrite},
+		{filepath.Join(dirs.SnapDataDir, "consumer/3/foo"), ifacestate.FileAccessReadWrite},
+		{filepath.Join(dirs.SnapDataDir, "consumer/2/foo"), ifacestate.FileAccessReadOnly},
+		{filepath.Join(dirs.SnapDataDir, "other/common"), ifacestate.FileAccessHidden},
+		{"/home/user/snap/consumer/common/foo", ifacestate.FileAccessReadWrite},
+		{"/home/user/snap/consumer/3/../2", ifacestate.FileAccessReadOnly},
+		{"/home/user/snap/other/common", ifacestate.FileAccessHidden},
+		{"/home/user/Documents", ifacestate.FileAccessHidden},
+		{"/etc/bar", ifacestate.FileAccessHidden},
+	} {
+		s.checkAccess(c, opts, t.path, t.access)
+	}
+}
+
+func (s *fileAccessSuite) TestSnapFileAccessConnections(c *C) {
+	s.connect(c, "home", "core", "home")
+	s.connect(c, "removable-media", "core", "removable-media")
+	s.connect(c, "dot-foo", "core", "personal-files")
+	s.connect(c, "etc-bar", "core", "system-files")
+	s.connect(c, "stuff", "producer", "stuff")
+
+	opts := interfaces.ConfinementOptions{}
+	for _, t := range []struct {
+		path   string
+		access ifacestate.FileAccess
+	}{
+		{"/home/user/Documents/foo.txt", ifacestate.FileAccessReadWrite},
+		{"/home/user/.ssh/id_rsa", ifacestate.FileAccessHidden},
+		{"/home/user/.foo/config", ifacestate.FileAccessReadOnly},
+		{"/home/user/.foo/cache/data", ifacestate.FileAccessReadWrite},
+		{"/home/user/snap/other/common", ifacestate.FileAccessHidden},
+		{"/media/user/disk/file", ifacestate.FileAccessReadWrite},
+		{"/run/media/user/disk", ifacestate.FileAccessReadWrite},
+		{"/mnt", ifacestate.FileAccessReadWrite},
+		{"/etc/bar", ifacestate.FileAccessReadOnly},
+		{"/etc/barbaz", ifacestate.FileAccessHidden},
+		{filepath.Join(dirs.CoreSnapMountDir, "producer/2/lib/libfoo.so"), ifacestate.FileAccessReadOnly},
+		{filepath.Join(dirs.SnapDataDir, "producer/2/out/data"), ifacestate.FileAccessReadWrite},
+		{filepath.Join(dirs.SnapDataDir, "producer/2/other"), ifacestate.FileAccessHidden},
+	} {
+		s.checkAccess(c, opts, t.path, t.access)
+	}
+}
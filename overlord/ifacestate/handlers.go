@@ -30,7 +30,6 @@ import (
 
 	"github.com/snapcore/snapd/interfaces"
 	"github.com/snapcore/snapd/interfaces/hotplug"
-	"github.com/snapcore/snapd/logger"
 	"github.com/snapcore/snapd/overlord/snapstate"
 	"github.com/snapcore/snapd/overlord/state"
 	"github.com/snapcore/snapd/snap"
@@ -401,6 +400,10 @@ func (m *InterfaceManager) doConnect(task *state.Task, _ *tomb.Tomb) error {
 	if err := task.Get("by-gadget", &byGadget); err != nil && err != state.ErrNoState {
 		return err
 	}
+	var duration time.Duration
+	if err := task.Get("duration", &duration); err != nil && err != state.ErrNoState {
+		return err
+	}
 
 	deviceCtx, err := snapstate.DeviceCtx(st, task, nil)
 	if err != nil {
@@ -485,6 +488,14 @@ func (m *InterfaceManager) doConnect(task *state.Task, _ *tomb.Tomb) error {
 		return err
 	}
 
+	var expiry *time.Time
+	if duration > 0 {
+		t := time.Now().Add(duration)
+		expiry = &t
+		// make sure the connection expires on time
+		st.EnsureBefore(duration)
+	}
+
 	conns[connRef.ID()] = &connState{
 		Interface:        conn.Interface(),
 		StaticPlugAttrs:  conn.Plug.StaticAttrs(),
@@ -494,6 +505,7 @@ func (m *InterfaceManager) doConnect(task *state.Task, _ *tomb.Tomb) error {
 		Auto:             autoConnect,
 		ByGadget:         byGadget,
 		HotplugKey:       slot.HotplugKey,
+		Expiry:           expiry,
 	}
 	setConns(st, conns)
 
@@ -964,7 +976,7 @@ func (m *InterfaceManager) doAutoConnect(task *state.Task, _ *tomb.Tomb) error {
 
 	// Auto-connect all the plugs
 	for _, plug := range plugs {
-		candidates := m.repo.AutoConnectCandidateSlots(snapName, plug.Name, autochecker.check)
+		candidates := preferGadgetSlots(m.repo.AutoConnectCandidateSlots(snapName, plug.Name, autochecker.check))
 		if len(candidates) == 0 {
 			continue
 		}
@@ -1026,7 +1038,7 @@ func (m *InterfaceManager) doAutoConnect(task *state.Task, _ *tomb.Tomb) error {
 			// make sure slot is the only viable
 			// connection for plug, same check as if we were
 			// considering auto-connections from plug
-			candSlots := m.repo.AutoConnectCandidateSlots(plug.Snap.InstanceName(), plug.Name, autochecker.check)
+			candSlots := preferGadgetSlots(m.repo.AutoConnectCandidateSlots(plug.Snap.InstanceName(), plug.Name, autochecker.check))
 
 			if len(candSlots) != 1 || candSlots[0].String() != slot.String() {
 				crefs := make([]string, len(candSlots))
@@ -1108,7 +1120,7 @@ func (m *InterfaceManager) doAutoDisconnect(task *state.Task, _ *tomb.Tomb) erro
 	for _, connRef := range connections {
 		if err := checkDisconnectConflicts(st, snapName, connRef.PlugRef.Snap, connRef.SlotRef.Snap); err != nil {
 			if _, retry := err.(*state.Retry); retry {
-				logger.Debugf("disconnecting interfaces of snap %q will be retried because of %q - %q conflict", snapName, connRef.PlugRef.Snap, connRef.SlotRef.Snap)
+				ifacestateLog.Debugf("disconnecting interfaces of snap %q will be retried because of %q - %q conflict", snapName, connRef.PlugRef.Snap, connRef.SlotRef.Snap)
 				task.Logf("Waiting for conflicting change in progress...")
 				return err // will retry
 			}
@@ -1265,6 +1277,10 @@ func (m *InterfaceManager) doGadgetConnect(task *state.Task, _ *tomb.Tomb) error
 			task.Logf("gadget connect: ignoring missing slot %s:%s", gconn.Slot.SnapID, gconn.Slot.Slot)
 			continue
 		}
+		if err := gconn.CheckSlotAttributes(slot.Attrs); err != nil {
+			task.Logf("gadget connect: ignoring slot %s:%s: %v", gconn.Slot.SnapID, gconn.Slot.Slot, err)
+			continue
+		}
 
 		connRef := interfaces.NewConnRef(plug, slot)
 		key := connRef.ID()
@@ -1377,7 +1393,7 @@ func (m *InterfaceManager) doHotplugConnect(task *state.Task, _ *tomb.Tomb) erro
 		// make sure slot is the only viable
 		// connection for plug, same check as if we were
 		// considering auto-connections from plug
-		candSlots := m.repo.AutoConnectCandidateSlots(plug.Snap.InstanceName(), plug.Name, autochecker.check)
+		candSlots := preferGadgetSlots(m.repo.AutoConnectCandidateSlots(plug.Snap.InstanceName(), plug.Name, autochecker.check))
 		if len(candSlots) != 1 || candSlots[0].String() != slot.String() {
 			crefs := make([]string, len(candSlots))
 			for i, candidate := range candSlots {
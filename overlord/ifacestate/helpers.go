@@ -26,6 +26,7 @@ import (
 	"os"
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/snapcore/snapd/asserts"
 	"github.com/snapcore/snapd/dirs"
@@ -482,7 +483,7 @@ func addHotplugSlot(st *state.State, repo *interfaces.Repository, stateSlots map
 		HotplugGone: false,
 	}
 	setHotplugSlots(st, stateSlots)
-	logger.Debugf("added hotplug slot %s:%s of interface %s, hotplug key %q", slot.Snap.InstanceName(), slot.Name, slot.Interface, slot.HotplugKey)
+	ifacestateLog.Debugf("added hotplug slot %s:%s of interface %s, hotplug key %q", slot.Snap.InstanceName(), slot.Name, slot.Interface, slot.HotplugKey)
 	return nil
 }
 
@@ -504,6 +505,9 @@ type connState struct {
 	// slots.
 	HotplugGone bool            `json:"hotplug-gone,omitempty"`
 	HotplugKey  snap.HotplugKey `json:"hotplug-key,omitempty"`
+	// Expiry is set for temporary connections, they get disconnected
+	// automatically once it has passed.
+	Expiry *time.Time `json:"expiry,omitempty"`
 }
 
 type autoConnectChecker struct {
@@ -513,6 +517,38 @@ type autoConnectChecker struct {
 	baseDecl  *asserts.BaseDeclaration
 }
 
+// gadgetPreferredInterfaces are the interfaces whose slots a gadget can
+// provide in place of the implicit ones of the system, e.g. to bring
+// the vendor userspace drivers for opengl.
+var gadgetPreferredInterfaces = map[string]bool{
+	"opengl": true,
+}
+
+// preferGadgetSlots returns only the slots of the gadget out of the
+// auto-connection candidate slots of a plug of one of the
+// gadgetPreferredInterfaces when the other candidates are the implicit
+// slots of the system snaps.
+func preferGadgetSlots(candidates []*snap.SlotInfo) []*snap.SlotInfo {
+	var gadgetSlots []*snap.SlotInfo
+	for _, slot := range candidates {
+		if !gadgetPreferredInterfaces[slot.Interface] {
+			return candidates
+		}
+		switch slot.Snap.GetType() {
+		case snap.TypeGadget:
+			gadgetSlots = append(gadgetSlots, slot)
+		case snap.TypeOS, snap.TypeSnapd:
+			// implicit slots of the system
+		default:
+			return candidates
+		}
+	}
+	if len(gadgetSlots) == 0 {
+		return candidates
+	}
+	return gadgetSlots
+}
+
 func newAutoConnectChecker(s *state.State, deviceCtx snapstate.DeviceContext) (*autoConnectChecker, error) {
 	baseDecl, err := assertstate.BaseDeclaration(s)
 	if err != nil {
@@ -1051,3 +1087,66 @@ func findConnsForHotplugKey(conns map[string]*connState, ifaceName string, hotpl
 	sort.Strings(connsForDevice)
 	return connsForDevice
 }
+
+// disconnectExpired creates changes disconnecting the temporary
+// connections whose expiry time has passed and makes sure Ensure runs
+// again when the next one expires.
+func (m *InterfaceManager) disconnectExpired() error {
+	st := m.state
+	st.Lock()
+	defer st.Unlock()
+
+	conns, err := getConns(st)
+	if err != nil {
+		return err
+	}
+
+	ids := make([]string, 0, len(conns))
+	for id := range conns {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	now := time.Now()
+	var next time.Time
+	for _, id := range ids {
+		cstate := conns[id]
+		if cstate.Expiry == nil || cstate.Undesired || cstate.HotplugGone {
+			continue
+		}
+		if cstate.Expiry.After(now) {
+			if next.IsZero() || cstate.Expiry.Before(next) {
+				next = *cstate.Expiry
+			}
+			continue
+		}
+
+		connRef, err := interfaces.ParseConnRef(id)
+		if err != nil {
+			return err
+		}
+		conn, err := m.repo.Connection(connRef)
+		if err != nil {
+			logger.Noticef("cannot disconnect expired connection %s: %v", id, err)
+			continue
+		}
+		ts, err := Disconnect(st, conn)
+		if err != nil {
+			if _, ok := err.(*snapstate.ChangeConflictError); ok {
+				// try again on a later Ensure, this is also the
+				// case while the disconnect is in progress
+				continue
+			}
+			return err
+		}
+		chg := st.NewChange("disconnect-snap", fmt.Sprintf("Disconnect expired connection %s:%s from %s:%s",
+			connRef.PlugRef.Snap, connRef.PlugRef.Name, connRef.SlotRef.Snap, connRef.SlotRef.Name))
+		chg.AddAll(ts)
+		st.EnsureBefore(0)
+	}
+
+	if !next.IsZero() {
+		st.EnsureBefore(next.Sub(now))
+	}
+	return nil
+}
@@ -20,9 +20,14 @@
 package ifacestate
 
 import (
+	"fmt"
 	"regexp"
+	"strings"
 
+	"github.com/snapcore/snapd/interfaces"
 	"github.com/snapcore/snapd/overlord/hookstate"
+	"github.com/snapcore/snapd/overlord/ifacestate/ifacerepo"
+	"github.com/snapcore/snapd/overlord/state"
 )
 
 type interfaceHookHandler struct {
@@ -34,6 +39,81 @@ func (h *interfaceHookHandler) Before() error {
 }
 
 func (h *interfaceHookHandler) Done() error {
+	hookName := h.context.HookName()
+	switch {
+	case strings.HasPrefix(hookName, "prepare-plug-"):
+		return h.validateNegotiatedAttrs(true)
+	case strings.HasPrefix(hookName, "prepare-slot-"):
+		return h.validateNegotiatedAttrs(false)
+	}
+	return nil
+}
+
+type plugValidator interface {
+	BeforeConnectPlug(plug *interfaces.ConnectedPlug) error
+}
+
+type slotValidator interface {
+	BeforeConnectSlot(slot *interfaces.ConnectedSlot) error
+}
+
+// validateNegotiatedAttrs checks the attributes set by a prepare hook
+// against the interface, so that invalid ones fail the hook that set
+// them instead of the connection later on.
+func (h *interfaceHookHandler) validateNegotiatedAttrs(plugSide bool) error {
+	h.context.Lock()
+	defer h.context.Unlock()
+
+	var attrsTaskID string
+	if err := h.context.Get("attrs-task", &attrsTaskID); err != nil {
+		if err == state.ErrNoState {
+			return nil
+		}
+		return err
+	}
+	st := h.context.State()
+	attrsTask := st.Task(attrsTaskID)
+	if attrsTask == nil {
+		return fmt.Errorf("internal error: cannot find attrs task")
+	}
+	plugRef, slotRef, err := getPlugAndSlotRefs(attrsTask)
+	if err != nil {
+		return err
+	}
+	plugAttrs, slotAttrs, err := getDynamicHookAttributes(attrsTask)
+	if err != nil {
+		return fmt.Errorf("internal error: cannot get hook attributes: %v", err)
+	}
+	if plugAttrs == nil {
+		plugAttrs = map[string]interface{}{}
+	}
+	if slotAttrs == nil {
+		slotAttrs = map[string]interface{}{}
+	}
+
+	repo := ifacerepo.Get(st)
+	if plugSide {
+		plug := repo.Plug(plugRef.Snap, plugRef.Name)
+		if plug == nil {
+			return fmt.Errorf("snap %q has no %q plug", plugRef.Snap, plugRef.Name)
+		}
+		if v, ok := repo.Interface(plug.Interface).(plugValidator); ok {
+			if err := v.BeforeConnectPlug(interfaces.NewConnectedPlug(plug, nil, plugAttrs)); err != nil {
+				return fmt.Errorf("invalid attributes of plug %q of snap %q: %v", plug.Name, plug.Snap.InstanceName(), err)
+			}
+		}
+		return nil
+	}
+
+	slot := repo.Slot(slotRef.Snap, slotRef.Name)
+	if slot == nil {
+		return fmt.Errorf("snap %q has no %q slot", slotRef.Snap, slotRef.Name)
+	}
+	if v, ok := repo.Interface(slot.Interface).(slotValidator); ok {
+		if err := v.BeforeConnectSlot(interfaces.NewConnectedSlot(slot, nil, slotAttrs)); err != nil {
+			return fmt.Errorf("invalid attributes of slot %q of snap %q: %v", slot.Name, slot.Snap.InstanceName(), err)
+		}
+	}
 	return nil
 }
 
@@ -157,7 +157,7 @@ InterfacesLoop:
 			for _, gslot := range gadgetSlots {
 				if pred, ok := iface.(hotplug.HandledByGadgetPredicate); ok {
 					if pred.HandledByGadget(devinfo, gslot) {
-						logger.Debugf("ignoring device %s, interface %q (handled by gadget slot %s)", devinfo, iface.Name(), gslot.Name)
+						ifacestateLog.Debugf("ignoring device %s, interface %q (handled by gadget slot %s)", devinfo, iface.Name(), gslot.Name)
 						continue InterfacesLoop
 					}
 				}
@@ -200,7 +200,7 @@ InterfacesLoop:
 			return
 		}
 
-		logger.Debugf("adding hotplug device %s for interface %q, hotplug key %q", devinfo, iface.Name(), key)
+		ifacestateLog.Debugf("adding hotplug device %s for interface %q, hotplug key %q", devinfo, iface.Name(), key)
 
 		seq, err := allocHotplugSeq(st)
 		if err != nil {
@@ -272,7 +272,7 @@ func (m *InterfaceManager) hotplugDeviceRemoved(devinfo *hotplug.HotplugDeviceIn
 			return
 		}
 
-		logger.Debugf("removing hotplug device %s for interface %q, hotplug key %q", devinfo, ifaceName, hotplugKey)
+		ifacestateLog.Debugf("removing hotplug device %s for interface %q, hotplug key %q", devinfo, ifaceName, hotplugKey)
 
 		seq, err := allocHotplugSeq(st)
 		if err != nil {
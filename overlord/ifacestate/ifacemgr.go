@@ -20,15 +20,18 @@
 package ifacestate
 
 import (
+	"fmt"
 	"sync"
 	"time"
 
 	"github.com/snapcore/snapd/interfaces"
 	"github.com/snapcore/snapd/interfaces/backends"
+	"github.com/snapcore/snapd/interfaces/mount"
 	"github.com/snapcore/snapd/logger"
 	"github.com/snapcore/snapd/overlord/hookstate"
 	"github.com/snapcore/snapd/overlord/ifacestate/ifacerepo"
 	"github.com/snapcore/snapd/overlord/ifacestate/udevmonitor"
+	"github.com/snapcore/snapd/overlord/snapstate"
 	"github.com/snapcore/snapd/overlord/state"
 	"github.com/snapcore/snapd/snap"
 	"github.com/snapcore/snapd/timings"
@@ -136,6 +139,10 @@ func Manager(s *state.State, hookManager *hookstate.HookManager, runner *state.T
 
 // Ensure implements StateManager.Ensure.
 func (m *InterfaceManager) Ensure() error {
+	if err := m.disconnectExpired(); err != nil {
+		return err
+	}
+
 	if m.udevMonitorDisabled {
 		return nil
 	}
@@ -193,6 +200,64 @@ func (m *InterfaceManager) Repository() *interfaces.Repository {
 	return m.repo
 }
 
+// SecurityProfiles returns the security profiles that the security
+// backends would generate for the given snap with its current
+// connections, by backend name and then by the path of their file.
+// Nothing is written or loaded. Backends that cannot report their
+// profiles are skipped.
+//
+// The state must be locked by the caller.
+func (m *InterfaceManager) SecurityProfiles(instanceName string) (map[string]map[string]string, error) {
+	var snapst snapstate.SnapState
+	if err := snapstate.Get(m.state, instanceName, &snapst); err != nil {
+		return nil, err
+	}
+	snapInfo, err := snapst.CurrentInfo()
+	if err != nil {
+		return nil, err
+	}
+	opts := confinementOptions(snapst.Flags)
+
+	profiles := make(map[string]map[string]string)
+	for _, backend := range m.repo.Backends() {
+		dryRunner, ok := backend.(interfaces.SecurityBackendDryRun)
+		if !ok {
+			continue
+		}
+		backendProfiles, err := dryRunner.ProfilesDryRun(snapInfo, opts, m.repo)
+		if err != nil {
+			return nil, err
+		}
+		profiles[string(backend.Name())] = backendProfiles
+	}
+	return profiles, nil
+}
+
+// MountAudit returns the mount entries of the given snap with its
+// current connections, annotated with the interface or layout that
+// produced them and with whether they are applied to the mount
+// namespaces of the snap.
+//
+// The state must be locked by the caller.
+func (m *InterfaceManager) MountAudit(instanceName string) (*mount.Audit, error) {
+	var snapst snapstate.SnapState
+	if err := snapstate.Get(m.state, instanceName, &snapst); err != nil {
+		return nil, err
+	}
+	snapInfo, err := snapst.CurrentInfo()
+	if err != nil {
+		return nil, err
+	}
+	for _, backend := range m.repo.Backends() {
+		if auditor, ok := backend.(interface {
+			Audit(*snap.Info, *interfaces.Repository) (*mount.Audit, error)
+		}); ok {
+			return auditor.Audit(snapInfo, m.repo)
+		}
+	}
+	return nil, fmt.Errorf("internal error: no mount backend")
+}
+
 type ConnectionState struct {
 	// Auto indicates whether the connection was established automatically
 	Auto bool
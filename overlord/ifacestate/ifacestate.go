@@ -30,6 +30,7 @@ import (
 	"github.com/snapcore/snapd/i18n"
 	"github.com/snapcore/snapd/interfaces"
 	"github.com/snapcore/snapd/interfaces/policy"
+	"github.com/snapcore/snapd/logger"
 	"github.com/snapcore/snapd/overlord/assertstate"
 	"github.com/snapcore/snapd/overlord/hookstate"
 	"github.com/snapcore/snapd/overlord/snapstate"
@@ -39,6 +40,10 @@ import (
 
 var connectRetryTimeout = time.Second * 5
 
+// ifacestateLog records the debug messages of the interface manager,
+// which can be enabled with the "ifacestate" subsystem.
+var ifacestateLog = logger.Subsystem("ifacestate")
+
 // ErrAlreadyConnected describes the error that occurs when attempting to connect already connected interface.
 type ErrAlreadyConnected struct {
 	Connection interfaces.ConnRef
@@ -77,10 +82,12 @@ func findSymmetricAutoconnectTask(st *state.State, plugSnap, slotSnap string, in
 type connectOpts struct {
 	ByGadget    bool
 	AutoConnect bool
+	// Duration, if set, makes the connection temporary, it is
+	// disconnected automatically once the duration has passed.
+	Duration time.Duration
 }
 
 // Connect returns a set of tasks for connecting an interface.
-//
 func Connect(st *state.State, plugSnap, plugName, slotSnap, slotName string) (*state.TaskSet, error) {
 	if err := snapstate.CheckChangeConflictMany(st, []string{plugSnap, slotSnap}, ""); err != nil {
 		return nil, err
@@ -89,6 +96,20 @@ func Connect(st *state.State, plugSnap, plugName, slotSnap, slotName string) (*s
 	return connect(st, plugSnap, plugName, slotSnap, slotName, connectOpts{})
 }
 
+// ConnectFor returns a set of tasks for connecting an interface
+// temporarily, the connection is automatically disconnected once the
+// given duration has passed since it was established.
+func ConnectFor(st *state.State, plugSnap, plugName, slotSnap, slotName string, duration time.Duration) (*state.TaskSet, error) {
+	if duration <= 0 {
+		return nil, fmt.Errorf("cannot connect for a duration of %s", duration)
+	}
+	if err := snapstate.CheckChangeConflictMany(st, []string{plugSnap, slotSnap}, ""); err != nil {
+		return nil, err
+	}
+
+	return connect(st, plugSnap, plugName, slotSnap, slotName, connectOpts{Duration: duration})
+}
+
 func connect(st *state.State, plugSnap, plugName, slotSnap, slotName string, flags connectOpts) (*state.TaskSet, error) {
 	// TODO: Store the intent-to-connect in the state so that we automatically
 	// try to reconnect on reboot (reconnection can fail or can connect with
@@ -198,6 +219,9 @@ func connect(st *state.State, plugSnap, plugName, slotSnap, slotName string, fla
 	if flags.ByGadget {
 		connectInterface.Set("by-gadget", true)
 	}
+	if flags.Duration > 0 {
+		connectInterface.Set("duration", flags.Duration)
+	}
 
 	// Expose a copy of all plug and slot attributes coming from yaml to interface hooks. The hooks will be able
 	// to modify them but all attributes will be checked against assertions after the hooks are run.
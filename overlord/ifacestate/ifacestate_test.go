@@ -284,6 +284,73 @@ func (s *interfaceManagerSuite) TestRepoAvailable(c *C) {
 	c.Check(repo, FitsTypeOf, &interfaces.Repository{})
 }
 
+func (s *interfaceManagerSuite) TestPrepareHooksValidateNegotiatedAttrs(c *C) {
+	s.mockIfaces(c, &ifacetest.TestInterface{
+		InterfaceName: "test",
+		BeforeConnectPlugCallback: func(plug *interfaces.ConnectedPlug) error {
+			var path string
+			if err := plug.Attr("path", &path); err == nil && !strings.HasPrefix(path, "/") {
+				return fmt.Errorf("path %q is not absolute", path)
+			}
+			return nil
+		},
+		BeforeConnectSlotCallback: func(slot *interfaces.ConnectedSlot) error {
+			var mode string
+			if err := slot.Attr("mode", &mode); err == nil && mode != "ro" && mode != "rw" {
+				return fmt.Errorf("unsupported mode %q", mode)
+			}
+			return nil
+		},
+	}, &ifacetest.TestInterface{InterfaceName: "test2"})
+	s.mockSnap(c, consumerYaml)
+	s.mockSnap(c, producerYaml)
+	_ = s.manager(c)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	ts, err := ifacestate.Connect(s.state, "consumer", "plug", "producer", "slot")
+	c.Assert(err, IsNil)
+	chg := s.state.NewChange("connect", "...")
+	chg.AddAll(ts)
+	tasks := ts.Tasks()
+	preparePlug, prepareSlot, connect := tasks[0], tasks[1], tasks[2]
+	c.Assert(connect.Kind(), Equals, "connect")
+
+	handlerFor := func(task *state.Task) hookstate.Handler {
+		var hooksup hookstate.HookSetup
+		c.Assert(task.Get("hook-setup", &hooksup), IsNil)
+		context, err := hookstate.NewContext(task, s.state, &hooksup, nil, "")
+		c.Assert(err, IsNil)
+		return ifacestate.NewInterfaceHookHandler(context)
+	}
+
+	plugHandler := handlerFor(preparePlug)
+	slotHandler := handlerFor(prepareSlot)
+	runHandlers := func() (plugErr, slotErr error) {
+		s.state.Unlock()
+		defer s.state.Lock()
+		return plugHandler.Done(), slotHandler.Done()
+	}
+
+	// no attributes set by hooks is fine
+	plugErr, slotErr := runHandlers()
+	c.Check(plugErr, IsNil)
+	c.Check(slotErr, IsNil)
+
+	connect.Set("plug-dynamic", map[string]interface{}{"path": "relative/path"})
+	connect.Set("slot-dynamic", map[string]interface{}{"mode": "rx"})
+	plugErr, slotErr = runHandlers()
+	c.Check(plugErr, ErrorMatches, `invalid attributes of plug "plug" of snap "consumer": path "relative/path" is not absolute`)
+	c.Check(slotErr, ErrorMatches, `invalid attributes of slot "slot" of snap "producer": unsupported mode "rx"`)
+
+	connect.Set("plug-dynamic", map[string]interface{}{"path": "/srv/data"})
+	connect.Set("slot-dynamic", map[string]interface{}{"mode": "ro"})
+	plugErr, slotErr = runHandlers()
+	c.Check(plugErr, IsNil)
+	c.Check(slotErr, IsNil)
+}
+
 func (s *interfaceManagerSuite) TestConnectTask(c *C) {
 	s.mockIfaces(c, &ifacetest.TestInterface{InterfaceName: "test"}, &ifacetest.TestInterface{InterfaceName: "test2"})
 	s.mockSnap(c, consumerYaml)
@@ -3224,6 +3291,108 @@ func (s *interfaceManagerSuite) TestConnectTracksConnectionsInState(c *C) {
 	})
 }
 
+func (s *interfaceManagerSuite) TestConnectForTracksExpiryInState(c *C) {
+	s.MockModel(c, nil)
+
+	s.mockIfaces(c, &ifacetest.TestInterface{InterfaceName: "test"}, &ifacetest.TestInterface{InterfaceName: "test2"})
+	s.mockSnap(c, consumerYaml)
+	s.mockSnap(c, producerYaml)
+
+	_ = s.manager(c)
+
+	s.state.Lock()
+
+	_, err := ifacestate.ConnectFor(s.state, "consumer", "plug", "producer", "slot", 0)
+	c.Assert(err, ErrorMatches, "cannot connect for a duration of 0s")
+
+	ts, err := ifacestate.ConnectFor(s.state, "consumer", "plug", "producer", "slot", 2*time.Hour)
+	c.Assert(err, IsNil)
+	c.Assert(ts.Tasks(), HasLen, 5)
+
+	var duration time.Duration
+	c.Assert(ts.Tasks()[2].Get("duration", &duration), IsNil)
+	c.Check(duration, Equals, 2*time.Hour)
+
+	change := s.state.NewChange("connect", "")
+	change.AddAll(ts)
+	s.state.Unlock()
+
+	before := time.Now()
+	s.settle(c)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	c.Assert(change.Err(), IsNil)
+	c.Check(change.Status(), Equals, state.DoneStatus)
+	var conns map[string]struct {
+		Interface string     `json:"interface"`
+		Expiry    *time.Time `json:"expiry"`
+	}
+	err = s.state.Get("conns", &conns)
+	c.Assert(err, IsNil)
+	c.Assert(conns, HasLen, 1)
+	conn := conns["consumer:plug producer:slot"]
+	c.Check(conn.Interface, Equals, "test")
+	c.Assert(conn.Expiry, NotNil)
+	c.Check(conn.Expiry.Before(before.Add(2*time.Hour)), Equals, false)
+	c.Check(conn.Expiry.After(time.Now().Add(2*time.Hour)), Equals, false)
+
+	// the connection has not expired yet, nothing disconnects it
+	c.Check(s.state.Changes(), HasLen, 1)
+}
+
+func (s *interfaceManagerSuite) TestEnsureDisconnectsExpired(c *C) {
+	s.MockModel(c, nil)
+
+	s.mockIfaces(c, &ifacetest.TestInterface{InterfaceName: "test"}, &ifacetest.TestInterface{InterfaceName: "test2"})
+	s.mockSnap(c, consumerYaml)
+	s.mockSnap(c, producerYaml)
+	s.mockSnap(c, producer2Yaml)
+
+	s.state.Lock()
+	s.state.Set("conns", map[string]interface{}{
+		"consumer:plug producer:slot": map[string]interface{}{
+			"interface": "test",
+			"expiry":    time.Now().Add(-time.Minute),
+		},
+		"consumer:plug producer2:slot": map[string]interface{}{
+			"interface": "test",
+			"expiry":    time.Now().Add(time.Hour),
+		},
+	})
+	s.state.Unlock()
+
+	mgr := s.manager(c)
+	c.Assert(mgr.Ensure(), IsNil)
+
+	s.state.Lock()
+	changes := s.state.Changes()
+	c.Assert(changes, HasLen, 1)
+	c.Check(changes[0].Kind(), Equals, "disconnect-snap")
+	c.Check(changes[0].Summary(), Equals, "Disconnect expired connection consumer:plug from producer:slot")
+	s.state.Unlock()
+
+	// the disconnect in progress is not duplicated
+	c.Assert(mgr.Ensure(), IsNil)
+	s.state.Lock()
+	c.Check(s.state.Changes(), HasLen, 1)
+	s.state.Unlock()
+
+	s.settle(c)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	c.Assert(changes[0].Err(), IsNil)
+	c.Check(changes[0].Status(), Equals, state.DoneStatus)
+	var conns map[string]interface{}
+	err := s.state.Get("conns", &conns)
+	c.Assert(err, IsNil)
+	c.Check(conns, HasLen, 1)
+	c.Check(conns["consumer:plug producer2:slot"], NotNil)
+}
+
 func (s *interfaceManagerSuite) TestConnectSetsUpSecurity(c *C) {
 	s.MockModel(c, nil)
 
@@ -4230,6 +4399,113 @@ func (s *interfaceManagerSuite) TestAutoConnectDuringCoreTransition(c *C) {
 		SlotRef: interfaces.SlotRef{Snap: "core", Name: "network"}}})
 }
 
+func (s *interfaceManagerSuite) TestAutoConnectPrefersGadgetSlot(c *C) {
+	s.MockModel(c, nil)
+
+	// both the core and the gadget snaps provide the interface
+	s.mockSnap(c, `name: core
+version: 1
+type: os
+slots:
+ opengl:
+`)
+	s.mockSnap(c, `name: gadget
+version: 1
+type: gadget
+slots:
+ opengl:
+  vendor: mesa
+  libraries: usr/lib
+`)
+
+	mgr := s.manager(c)
+
+	snapInfo := s.mockSnap(c, `name: consumer
+version: 1
+plugs:
+ plug:
+  interface: opengl
+`)
+	change := s.addSetupSnapSecurityChange(c, &snapstate.SnapSetup{
+		SideInfo: &snap.SideInfo{
+			RealName: snapInfo.SnapName(),
+			Revision: snapInfo.Revision,
+		},
+	})
+	s.settle(c)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	c.Assert(change.Status(), Equals, state.DoneStatus)
+
+	// the plug is connected to the slot of the gadget instead of the
+	// one of the core snap
+	var conns map[string]interface{}
+	err := s.state.Get("conns", &conns)
+	c.Assert(err, IsNil)
+	c.Check(conns, DeepEquals, map[string]interface{}{
+		"consumer:plug gadget:opengl": map[string]interface{}{
+			"interface": "opengl", "auto": true,
+			"slot-static": map[string]interface{}{
+				"vendor":    "mesa",
+				"libraries": "usr/lib",
+			},
+		},
+	})
+	ifaces := mgr.Repository().Interfaces()
+	c.Check(ifaces.Connections, DeepEquals, []*interfaces.ConnRef{{
+		PlugRef: interfaces.PlugRef{Snap: "consumer", Name: "plug"},
+		SlotRef: interfaces.SlotRef{Snap: "gadget", Name: "opengl"}}})
+}
+
+func (s *interfaceManagerSuite) TestAutoConnectPrefersGadgetSlotOnlyForOpengl(c *C) {
+	s.MockModel(c, nil)
+	s.mockIfaces(c, &ifacetest.TestInterface{InterfaceName: "test"})
+
+	// both the core and the gadget snaps provide the interface
+	s.mockSnap(c, `name: core
+version: 1
+type: os
+slots:
+ test:
+`)
+	s.mockSnap(c, `name: gadget
+version: 1
+type: gadget
+slots:
+ test:
+`)
+
+	mgr := s.manager(c)
+
+	snapInfo := s.mockSnap(c, `name: consumer
+version: 1
+plugs:
+ plug:
+  interface: test
+`)
+	change := s.addSetupSnapSecurityChange(c, &snapstate.SnapSetup{
+		SideInfo: &snap.SideInfo{
+			RealName: snapInfo.SnapName(),
+			Revision: snapInfo.Revision,
+		},
+	})
+	s.settle(c)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	c.Assert(change.Status(), Equals, state.DoneStatus)
+
+	// the gadget does not take over the interface, the plug is left
+	// alone as there is more than one candidate
+	var conns map[string]interface{}
+	err := s.state.Get("conns", &conns)
+	c.Assert(err, Equals, state.ErrNoState)
+	c.Check(mgr.Repository().Interfaces().Connections, HasLen, 0)
+}
+
 func makeAutoConnectChange(st *state.State, plugSnap, plug, slotSnap, slot string) *state.Change {
 	chg := st.NewChange("connect...", "...")
 
@@ -5184,6 +5460,60 @@ volumes:
 	c.Check(logs[1], Matches, `.* ignoring missing plug unknownididididididididididididi:plug`)
 }
 
+func (s *interfaceManagerSuite) TestGadgetConnectSlotAttributes(c *C) {
+	r1 := release.MockOnClassic(false)
+	defer r1()
+
+	s.mockIfaces(c, &ifacetest.TestInterface{InterfaceName: "test"})
+	s.MockSnapDecl(c, "consumer", "publisher1", nil)
+	s.mockSnap(c, consumerYaml)
+	s.MockSnapDecl(c, "producer", "publisher2", nil)
+	s.mockSnap(c, producerYaml)
+
+	s.MockModel(c, nil)
+
+	s.manager(c)
+
+	gadgetInfo := s.mockSnap(c, `name: gadget
+type: gadget
+`)
+
+	gadgetYaml := []byte(`
+connections:
+   - plug: consumeridididididididididididid:plug
+     slot: produceridididididididididididid:slot
+     slot-attributes:
+       attr2: other-value
+
+volumes:
+    volume-id:
+        bootloader: grub
+`)
+
+	err := ioutil.WriteFile(filepath.Join(gadgetInfo.MountDir(), "meta", "gadget.yaml"), gadgetYaml, 0644)
+	c.Assert(err, IsNil)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	chg := s.state.NewChange("setting-up", "...")
+	t := s.state.NewTask("gadget-connect", "gadget connections")
+	chg.AddTask(t)
+
+	s.state.Unlock()
+	s.se.Ensure()
+	s.se.Wait()
+	s.state.Lock()
+
+	c.Assert(chg.Err(), IsNil)
+	tasks := chg.Tasks()
+	c.Assert(tasks, HasLen, 1)
+
+	logs := t.Log()
+	c.Assert(logs, HasLen, 1)
+	c.Check(logs[0], Matches, `.*ignoring slot produceridididididididididididid:slot: slot attribute "attr2" is value2, expected other-value`)
+}
+
 func (s *interfaceManagerSuite) TestGadgetConnectHappyPolicyChecks(c *C) {
 	// network-control does not auto-connect so this test also
 	// checks that the right policy checker (for "*-connection"
@@ -6579,6 +6909,50 @@ func (s *interfaceManagerSuite) testConnectionStates(c *C, auto, byGadget, undes
 	c.Check(conns, DeepEquals, expected)
 }
 
+func (s *interfaceManagerSuite) TestSecurityProfiles(c *C) {
+	s.secBackend.BackendName = "test"
+	s.secBackend.ProfilesDryRunCallback = func(snapInfo *snap.Info, opts interfaces.ConfinementOptions, repo *interfaces.Repository) (map[string]string, error) {
+		c.Check(snapInfo.InstanceName(), Equals, "consumer")
+		c.Check(opts, Equals, interfaces.ConfinementOptions{})
+		return map[string]string{"/path/to/profile": "profile of " + snapInfo.InstanceName()}, nil
+	}
+	mgr := s.manager(c)
+	s.mockSnap(c, consumerYaml)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	profiles, err := mgr.SecurityProfiles("consumer")
+	c.Assert(err, IsNil)
+	c.Check(profiles, DeepEquals, map[string]map[string]string{
+		"test": {"/path/to/profile": "profile of consumer"},
+	})
+	// nothing was set up
+	c.Check(s.secBackend.SetupCalls, HasLen, 0)
+
+	_, err = mgr.SecurityProfiles("unknown")
+	c.Check(err, Equals, state.ErrNoState)
+}
+
+func (s *interfaceManagerSuite) TestFileAccess(c *C) {
+	mgr := s.manager(c)
+	s.mockSnap(c, consumerYaml)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	access, err := mgr.FileAccess("consumer", filepath.Join(dirs.SnapDataDir, "consumer/common/foo"), "/home/user")
+	c.Assert(err, IsNil)
+	c.Check(access, Equals, ifacestate.FileAccessReadWrite)
+
+	access, err = mgr.FileAccess("consumer", "/home/user/Documents", "/home/user")
+	c.Assert(err, IsNil)
+	c.Check(access, Equals, ifacestate.FileAccessHidden)
+
+	_, err = mgr.FileAccess("unknown", "/", "/home/user")
+	c.Check(err, Equals, state.ErrNoState)
+}
+
 func (s *interfaceManagerSuite) TestConnectionStatesAutoManual(c *C) {
 	var isAuto, byGadget, isUndesired, hotplugGone bool = true, false, false, false
 	s.testConnectionStates(c, isAuto, byGadget, isUndesired, hotplugGone, map[string]ifacestate.ConnectionState{
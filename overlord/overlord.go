@@ -39,6 +39,7 @@ import (
 	"github.com/snapcore/snapd/overlord/assertstate"
 	"github.com/snapcore/snapd/overlord/cmdstate"
 	"github.com/snapcore/snapd/overlord/configstate"
+	"github.com/snapcore/snapd/overlord/configstate/config"
 	"github.com/snapcore/snapd/overlord/configstate/proxyconf"
 	"github.com/snapcore/snapd/overlord/devicestate"
 	"github.com/snapcore/snapd/overlord/healthstate"
@@ -50,6 +51,7 @@ import (
 	"github.com/snapcore/snapd/overlord/state"
 	"github.com/snapcore/snapd/overlord/storecontext"
 	"github.com/snapcore/snapd/store"
+	"github.com/snapcore/snapd/strutil"
 	"github.com/snapcore/snapd/timings"
 )
 
@@ -187,6 +189,10 @@ func New(restartBehavior RestartBehavior) (*Overlord, error) {
 		return nil, fmt.Errorf("failed to generate cookies: %q", err)
 	}
 
+	if err := configstate.SetupDebugLog(s); err != nil {
+		logger.Noticef("cannot enable debug logging of subsystems: %v", err)
+	}
+
 	return o, nil
 }
 
@@ -255,6 +261,15 @@ func loadState(backend state.Backend, restartBehavior RestartBehavior) (*state.S
 		return nil, err
 	}
 
+	if osutil.GetenvBool("SNAPD_PATCH_DRY_RUN") {
+		// only report what the state patches would do, the
+		// state on disk is left as it is
+		if _, err := patch.DryRun(s); err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("not applying state patches: dry-run requested via SNAPD_PATCH_DRY_RUN")
+	}
+
 	// one-shot migrations
 	err = patch.Apply(s)
 	if err != nil {
@@ -286,13 +301,72 @@ func verifyReboot(s *state.State, curBootID string, restartBehavior RestartBehav
 func (o *Overlord) newStoreWithContext(storeCtx store.DeviceAndAuthContext) snapstate.StoreService {
 	cfg := store.DefaultConfig()
 	cfg.Proxy = o.proxyConf
+	cfg.CacheSize = o.storeCacheSize
+	cfg.TLS = o.storeTLSOptions()
 	sto := storeNew(cfg, storeCtx)
 	sto.SetCacheDownloads(defaultCachedDownloads)
 	return sto
 }
 
+// storeCacheSize returns the size limit of the store download cache
+// as set via the store.cache-size system option.
+func (o *Overlord) storeCacheSize() int64 {
+	st := o.State()
+	st.Lock()
+	tr := config.NewTransaction(st)
+	st.Unlock()
+
+	var cacheSize string
+	if err := tr.Get("core", "store.cache-size", &cacheSize); err != nil {
+		if !config.IsNoOption(err) {
+			logger.Noticef("cannot get store.cache-size: %v", err)
+		}
+		return 0
+	}
+	if cacheSize == "" {
+		return 0
+	}
+	size, err := strutil.ParseByteSize(cacheSize)
+	if err != nil {
+		logger.Noticef("cannot use store.cache-size %q: %v", cacheSize, err)
+		return 0
+	}
+	return size
+}
+
+// storeTLSOptions returns the additional TLS trust and credentials to
+// use with the store as set via the store.ca-certs, store.client-cert
+// and store.client-key system options. The state must be locked.
+func (o *Overlord) storeTLSOptions() *store.TLSOptions {
+	tr := config.NewTransaction(o.State())
+
+	var opts store.TLSOptions
+	for _, opt := range []struct {
+		name string
+		dest *string
+	}{
+		{"store.client-cert", &opts.ClientCertFile},
+		{"store.client-key", &opts.ClientKeyFile},
+	} {
+		if err := tr.Get("core", opt.name, opt.dest); err != nil && !config.IsNoOption(err) {
+			logger.Noticef("cannot get %s: %v", opt.name, err)
+		}
+	}
+	var caCerts string
+	if err := tr.Get("core", "store.ca-certs", &caCerts); err != nil && !config.IsNoOption(err) {
+		logger.Noticef("cannot get store.ca-certs: %v", err)
+	}
+	opts.CACerts = []byte(caCerts)
+
+	if len(opts.CACerts) == 0 && opts.ClientCertFile == "" && opts.ClientKeyFile == "" {
+		return nil
+	}
+	return &opts
+}
+
 // newStore can make new stores for use during remodeling.
 // The device backend will tie them to the remodeling device state.
+// The state must be locked.
 func (o *Overlord) newStore(devBE storecontext.DeviceBackend) snapstate.StoreService {
 	scb := o.deviceMgr.StoreContextBackend()
 	stoCtx := storecontext.NewComposed(o.State(), devBE, scb, scb)
@@ -38,6 +38,7 @@ import (
 	"github.com/snapcore/snapd/osutil"
 	"github.com/snapcore/snapd/overlord"
 	"github.com/snapcore/snapd/overlord/auth"
+	"github.com/snapcore/snapd/overlord/configstate/config"
 	"github.com/snapcore/snapd/overlord/devicestate/devicestatetest"
 	"github.com/snapcore/snapd/overlord/hookstate"
 	"github.com/snapcore/snapd/overlord/ifacestate"
@@ -116,6 +117,63 @@ func (ovs *overlordSuite) TestNew(c *C) {
 	c.Check(sto.(*store.Store).CacheDownloads(), Equals, 5)
 }
 
+func (ovs *overlordSuite) TestNewStoreCacheSize(c *C) {
+	var cfg *store.Config
+	restore := overlord.MockStoreNew(func(storeCfg *store.Config, stoCtx store.DeviceAndAuthContext) *store.Store {
+		cfg = storeCfg
+		return store.New(storeCfg, stoCtx)
+	})
+	defer restore()
+
+	o, err := overlord.New(nil)
+	c.Assert(err, IsNil)
+	c.Assert(cfg, NotNil)
+	c.Assert(cfg.CacheSize, NotNil)
+
+	// unset means no limit
+	c.Check(cfg.CacheSize(), Equals, int64(0))
+
+	st := o.State()
+	st.Lock()
+	tr := config.NewTransaction(st)
+	tr.Set("core", "store.cache-size", "2MB")
+	tr.Commit()
+	st.Unlock()
+
+	c.Check(cfg.CacheSize(), Equals, int64(2*1000*1000))
+}
+
+func (ovs *overlordSuite) TestNewStoreTLSOptions(c *C) {
+	var cfg *store.Config
+	restore := overlord.MockStoreNew(func(storeCfg *store.Config, stoCtx store.DeviceAndAuthContext) *store.Store {
+		cfg = storeCfg
+		return store.New(storeCfg, stoCtx)
+	})
+	defer restore()
+
+	o, err := overlord.New(nil)
+	c.Assert(err, IsNil)
+	c.Assert(cfg, NotNil)
+	// unset means the default TLS configuration
+	c.Check(cfg.TLS, IsNil)
+
+	st := o.State()
+	st.Lock()
+	defer st.Unlock()
+	tr := config.NewTransaction(st)
+	tr.Set("core", "store.ca-certs", "certs")
+	tr.Set("core", "store.client-cert", "/cert.pem")
+	tr.Set("core", "store.client-key", "/cert.key")
+	tr.Commit()
+
+	o.NewStore(o.DeviceManager().StoreContextBackend())
+	c.Check(cfg.TLS, DeepEquals, &store.TLSOptions{
+		CACerts:        []byte("certs"),
+		ClientCertFile: "/cert.pem",
+		ClientKeyFile:  "/cert.key",
+	})
+}
+
 func (ovs *overlordSuite) TestNewStore(c *C) {
 	// this is a shallow test, the deep testing happens in the
 	// remodeling tests in managers_test.go
@@ -124,6 +182,9 @@ func (ovs *overlordSuite) TestNewStore(c *C) {
 
 	devBE := o.DeviceManager().StoreContextBackend()
 
+	st := o.State()
+	st.Lock()
+	defer st.Unlock()
 	sto := o.NewStore(devBE)
 	c.Check(sto, FitsTypeOf, &store.Store{})
 	c.Check(sto.(*store.Store).CacheDownloads(), Equals, 5)
@@ -184,6 +245,44 @@ func (ovs *overlordSuite) TestNewWithInvalidState(c *C) {
 	c.Assert(err, ErrorMatches, "cannot read state: EOF")
 }
 
+func (ovs *overlordSuite) TestNewWithPatchesDryRun(c *C) {
+	os.Setenv("SNAPD_PATCH_DRY_RUN", "1")
+	defer os.Unsetenv("SNAPD_PATCH_DRY_RUN")
+
+	p := func(s *state.State) error {
+		s.Set("patched", true)
+		return nil
+	}
+	patch.Mock(1, 0, map[int][]patch.PatchFunc{1: {p}})
+
+	fakeState := []byte(`{"data":{"patch-level":0, "patch-sublevel":0}}`)
+	err := ioutil.WriteFile(dirs.SnapStateFile, fakeState, 0600)
+	c.Assert(err, IsNil)
+
+	_, err = overlord.New(nil)
+	c.Assert(err, ErrorMatches, "not applying state patches: dry-run requested via SNAPD_PATCH_DRY_RUN")
+	c.Check(dirs.SnapStateFile, testutil.FileEquals, fakeState)
+	c.Check(patch.BackupFile(), testutil.FileAbsent)
+}
+
+func (ovs *overlordSuite) TestNewWithPatchesBackup(c *C) {
+	p := func(s *state.State) error {
+		s.Set("patched", true)
+		return nil
+	}
+	patch.Mock(1, 0, map[int][]patch.PatchFunc{1: {p}})
+
+	fakeState := []byte(`{"data":{"patch-level":0,"patch-sublevel":0},"changes":{},"tasks":{},"last-change-id":0,"last-task-id":0,"last-lane-id":0}`)
+	err := ioutil.WriteFile(dirs.SnapStateFile, fakeState, 0600)
+	c.Assert(err, IsNil)
+
+	_, err = overlord.New(nil)
+	c.Assert(err, IsNil)
+	c.Check(patch.BackupFile(), testutil.FileContains, `"patch-level":0`)
+	c.Check(patch.BackupFile(), testutil.FileContains, `"patch-sublevel":0`)
+	c.Check(patch.BackupFile(), Not(testutil.FileContains), `patched`)
+}
+
 func (ovs *overlordSuite) TestNewWithPatches(c *C) {
 	p := func(s *state.State) error {
 		s.Set("patched", true)
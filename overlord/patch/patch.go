@@ -20,10 +20,16 @@
 package patch
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
+	"sort"
+	"strings"
 
 	"github.com/snapcore/snapd/cmd"
+	"github.com/snapcore/snapd/dirs"
 	"github.com/snapcore/snapd/logger"
+	"github.com/snapcore/snapd/osutil"
 	"github.com/snapcore/snapd/overlord/state"
 	"github.com/snapcore/snapd/snap"
 )
@@ -56,22 +62,6 @@ func Init(s *state.State) {
 	s.Set("patch-sublevel", Sublevel)
 }
 
-// applySublevelPatches applies all sublevel patches for given level, starting
-// from firstSublevel index.
-func applySublevelPatches(level, firstSublevel int, s *state.State) error {
-	for sublevel := firstSublevel; sublevel < len(patches[level]); sublevel++ {
-		if sublevel > 0 {
-			logger.Noticef("Patching system state level %d to sublevel %d...", level, sublevel)
-		}
-		err := applyOne(patches[level][sublevel], s, level, sublevel)
-		if err != nil {
-			logger.Noticef("Cannot patch: %v", err)
-			return fmt.Errorf("cannot patch system state to level %d, sublevel %d: %v", level, sublevel, err)
-		}
-	}
-	return nil
-}
-
 type patchSnapState struct {
 	Sequence []*patchSideInfo `json:"sequence"`
 	Current  snap.Revision    `json:"current"`
@@ -114,34 +104,43 @@ func maybeResetSublevelForLevel60(s *state.State, sublevel *int) error {
 	return nil
 }
 
-// Apply applies any necessary patches to update the provided state to
-// conventions required by the current patch level of the system.
-func Apply(s *state.State) error {
+// pendingPatch is a patch still to be applied to reach the current
+// implemented patch level.
+type pendingPatch struct {
+	level    int
+	sublevel int
+	patch    PatchFunc
+}
+
+// pendingPatches returns the patches needed to update the provided state
+// to the current patch level, upToDate is set when there is nothing left
+// to do at all.
+func pendingPatches(s *state.State) (todo []pendingPatch, upToDate bool, err error) {
 	var stateLevel, stateSublevel int
 	s.Lock()
-	err := s.Get("patch-level", &stateLevel)
+	err = s.Get("patch-level", &stateLevel)
 	if err == nil || err == state.ErrNoState {
 		err = s.Get("patch-sublevel", &stateSublevel)
 	}
 	s.Unlock()
 
 	if err != nil && err != state.ErrNoState {
-		return err
+		return nil, false, err
 	}
 
 	if stateLevel > Level {
-		return fmt.Errorf("cannot downgrade: snapd is too old for the current system state (patch level %d)", stateLevel)
+		return nil, false, fmt.Errorf("cannot downgrade: snapd is too old for the current system state (patch level %d)", stateLevel)
 	}
 
 	// check if we refreshed from 6.0 which was not aware of sublevels
 	if stateLevel == 6 && stateSublevel > 0 {
 		if err := maybeResetSublevelForLevel60(s, &stateSublevel); err != nil {
-			return err
+			return nil, false, err
 		}
 	}
 
 	if stateLevel == Level && stateSublevel == Sublevel {
-		return nil
+		return nil, true, nil
 	}
 
 	// downgrade within same level; update sublevel in the state so that sublevel patches
@@ -150,30 +149,78 @@ func Apply(s *state.State) error {
 		s.Lock()
 		s.Set("patch-sublevel", Sublevel)
 		s.Unlock()
-		return nil
+		return nil, true, nil
 	}
 
 	// apply any missing sublevel patches for current state level before upgrading to new levels.
 	// the 0th sublevel patch is a patch for major level update (e.g. 7.0),
 	// therefore there is +1 for the indices.
-	if stateSublevel+1 < len(patches[stateLevel]) {
-		if err := applySublevelPatches(stateLevel, stateSublevel+1, s); err != nil {
-			return err
-		}
+	for sublevel := stateSublevel + 1; sublevel < len(patches[stateLevel]); sublevel++ {
+		todo = append(todo, pendingPatch{stateLevel, sublevel, patches[stateLevel][sublevel]})
 	}
 
 	// at the lower Level - apply all new level and sublevel patches
 	for level := stateLevel + 1; level <= Level; level++ {
 		sublevels := patches[level]
-		logger.Noticef("Patching system state from level %d to %d", level-1, level)
 		if sublevels == nil {
-			return fmt.Errorf("cannot upgrade: snapd is too new for the current system state (patch level %d)", level-1)
+			return nil, false, fmt.Errorf("cannot upgrade: snapd is too new for the current system state (patch level %d)", level-1)
+		}
+		for sublevel, patch := range sublevels {
+			todo = append(todo, pendingPatch{level, sublevel, patch})
 		}
-		if err := applySublevelPatches(level, 0, s); err != nil {
+	}
+
+	return todo, false, nil
+}
+
+// BackupFile returns the path of the copy of the state taken before
+// applying any patches to it.
+func BackupFile() string {
+	return dirs.SnapStateFile + ".pre-patch"
+}
+
+func backupState(s *state.State) error {
+	s.Lock()
+	data, err := s.MarshalJSON()
+	s.Unlock()
+	if err != nil {
+		return fmt.Errorf("cannot backup the system state before patching: %v", err)
+	}
+	if err := osutil.AtomicWriteFile(BackupFile(), data, 0600, 0); err != nil {
+		return fmt.Errorf("cannot backup the system state before patching: %v", err)
+	}
+	return nil
+}
+
+// Apply applies any necessary patches to update the provided state to
+// conventions required by the current patch level of the system.
+func Apply(s *state.State) error {
+	todo, upToDate, err := pendingPatches(s)
+	if err != nil {
+		return err
+	}
+	if upToDate {
+		return nil
+	}
+
+	if len(todo) > 0 {
+		if err := backupState(s); err != nil {
 			return err
 		}
 	}
 
+	for _, p := range todo {
+		if p.sublevel == 0 {
+			logger.Noticef("Patching system state from level %d to %d", p.level-1, p.level)
+		} else {
+			logger.Noticef("Patching system state level %d to sublevel %d...", p.level, p.sublevel)
+		}
+		if err := applyOne(p.patch, s, p.level, p.sublevel); err != nil {
+			logger.Noticef("Cannot patch: %v", err)
+			return fmt.Errorf("cannot patch system state to level %d, sublevel %d: %v", p.level, p.sublevel, err)
+		}
+	}
+
 	s.Lock()
 	// store last snapd version last in case system is restarted before patches are applied
 	s.Set("patch-sublevel-last-version", cmd.Version)
@@ -182,14 +229,115 @@ func Apply(s *state.State) error {
 	return nil
 }
 
-func applyOne(patch func(s *state.State) error, s *state.State, newLevel, newSublevel int) error {
+// DryRun applies the necessary patches to a copy of the provided state,
+// logging and returning a description of what each of them would
+// change. The provided state itself is left untouched.
+func DryRun(s *state.State) (report []string, err error) {
+	s.Lock()
+	data, err := s.MarshalJSON()
+	s.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	scratch, err := state.ReadState(nil, bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	todo, _, err := pendingPatches(scratch)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, p := range todo {
+		before, err := stateSections(scratch)
+		if err != nil {
+			return report, err
+		}
+		if err := applyOne(p.patch, scratch, p.level, p.sublevel); err != nil {
+			msg := fmt.Sprintf("patch %d.%d would fail: %v", p.level, p.sublevel, err)
+			logger.Noticef("Dry-run: %s", msg)
+			return append(report, msg), fmt.Errorf("cannot patch system state to level %d, sublevel %d: %v", p.level, p.sublevel, err)
+		}
+		after, err := stateSections(scratch)
+		if err != nil {
+			return report, err
+		}
+		msg := fmt.Sprintf("patch %d.%d would change: %s", p.level, p.sublevel, strings.Join(changedSections(before, after), ", "))
+		logger.Noticef("Dry-run: %s", msg)
+		report = append(report, msg)
+	}
+
+	return report, nil
+}
+
+// stateSections returns the serialized form of the top-level data keys,
+// changes and tasks of the state.
+func stateSections(s *state.State) (map[string]string, error) {
+	s.Lock()
+	data, err := s.MarshalJSON()
+	s.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	var top map[string]json.RawMessage
+	if err := json.Unmarshal(data, &top); err != nil {
+		return nil, err
+	}
+	var stateData map[string]json.RawMessage
+	if err := json.Unmarshal(top["data"], &stateData); err != nil {
+		return nil, err
+	}
+	sections := make(map[string]string, len(stateData)+2)
+	for k, v := range stateData {
+		sections[k] = string(v)
+	}
+	sections["changes"] = string(top["changes"])
+	sections["tasks"] = string(top["tasks"])
+	return sections, nil
+}
+
+func changedSections(before, after map[string]string) []string {
+	var changed []string
+	for k, v := range after {
+		if old, ok := before[k]; !ok || old != v {
+			changed = append(changed, k)
+		}
+	}
+	for k := range before {
+		if _, ok := after[k]; !ok {
+			changed = append(changed, k)
+		}
+	}
+	if len(changed) == 0 {
+		return []string{"nothing"}
+	}
+	sort.Strings(changed)
+	return changed
+}
+
+func applyOne(patch func(s *state.State) error, s *state.State, newLevel, newSublevel int) (err error) {
 	s.Lock()
 	defer s.Unlock()
 
-	err := patch(s)
+	before, err := s.MarshalJSON()
 	if err != nil {
 		return err
 	}
+	defer func() {
+		if r := recover(); r != nil {
+			// put the state back as it was before the patch, it is
+			// checkpointed again on unlock
+			if uerr := s.UnmarshalJSON(before); uerr != nil {
+				panic(fmt.Sprintf("cannot restore system state after patch panic %v: %v", r, uerr))
+			}
+			err = fmt.Errorf("internal error: patch panicked: %v", r)
+		}
+	}()
+
+	if err := patch(s); err != nil {
+		return err
+	}
 
 	s.Set("patch-level", newLevel)
 	s.Set("patch-sublevel", newSublevel)
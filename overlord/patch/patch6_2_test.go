@@ -21,6 +21,8 @@ package patch_test
 
 import (
 	"bytes"
+	"os"
+	"path/filepath"
 
 	. "gopkg.in/check.v1"
 
@@ -291,6 +293,7 @@ var statePatch6_2JSONWithSnapd2 = []byte(`
 
 func (s *patch62Suite) SetUpTest(c *C) {
 	dirs.SetRootDir(c.MkDir())
+	c.Assert(os.MkdirAll(filepath.Dir(dirs.SnapStateFile), 0755), IsNil)
 	snap.MockSanitizePlugsSlots(func(*snap.Info) {})
 }
 
@@ -20,14 +20,20 @@
 package patch_test
 
 import (
+	"bytes"
 	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"sort"
 	"testing"
 
 	"github.com/snapcore/snapd/cmd"
+	"github.com/snapcore/snapd/dirs"
 	"github.com/snapcore/snapd/overlord/patch"
 	"github.com/snapcore/snapd/overlord/state"
 	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/testutil"
 
 	. "gopkg.in/check.v1"
 )
@@ -42,10 +48,13 @@ var _ = Suite(&patchSuite{})
 
 func (s *patchSuite) SetUpTest(c *C) {
 	s.restoreSanitize = snap.MockSanitizePlugsSlots(func(snapInfo *snap.Info) {})
+	dirs.SetRootDir(c.MkDir())
+	c.Assert(os.MkdirAll(filepath.Dir(dirs.SnapStateFile), 0755), IsNil)
 }
 
 func (s *patchSuite) TearDownTest(c *C) {
 	s.restoreSanitize()
+	dirs.SetRootDir("/")
 }
 
 func (s *patchSuite) TestInit(c *C) {
@@ -314,6 +323,155 @@ func (s *patchSuite) TestError(c *C) {
 	c.Check(n, Equals, 10)
 }
 
+func (s *patchSuite) TestApplyWritesBackup(c *C) {
+	p12 := func(st *state.State) error {
+		st.Set("n", 1)
+		return nil
+	}
+	restore := patch.Mock(2, 0, map[int][]patch.PatchFunc{
+		2: {p12},
+	})
+	defer restore()
+
+	st := state.New(nil)
+	st.Lock()
+	st.Set("patch-level", 1)
+	st.Set("foo", "bar")
+	st.Unlock()
+	c.Assert(patch.Apply(st), IsNil)
+
+	data, err := ioutil.ReadFile(patch.BackupFile())
+	c.Assert(err, IsNil)
+	backup, err := state.ReadState(nil, bytes.NewReader(data))
+	c.Assert(err, IsNil)
+	backup.Lock()
+	defer backup.Unlock()
+	var level int
+	c.Assert(backup.Get("patch-level", &level), IsNil)
+	c.Check(level, Equals, 1)
+	var foo string
+	c.Assert(backup.Get("foo", &foo), IsNil)
+	c.Check(foo, Equals, "bar")
+	var n int
+	c.Check(backup.Get("n", &n), Equals, state.ErrNoState)
+}
+
+func (s *patchSuite) TestNothingToDoNoBackup(c *C) {
+	restore := patch.Mock(2, 0, nil)
+	defer restore()
+
+	st := state.New(nil)
+	st.Lock()
+	st.Set("patch-level", 2)
+	st.Unlock()
+	c.Assert(patch.Apply(st), IsNil)
+	c.Check(patch.BackupFile(), testutil.FileAbsent)
+}
+
+func (s *patchSuite) TestPanicRestoresState(c *C) {
+	p12 := func(st *state.State) error {
+		st.Set("n", 1)
+		return nil
+	}
+	p23 := func(st *state.State) error {
+		st.Set("n", 2)
+		st.Set("half-done", true)
+		panic("boom")
+	}
+	restore := patch.Mock(3, 0, map[int][]patch.PatchFunc{
+		2: {p12},
+		3: {p23},
+	})
+	defer restore()
+
+	st := state.New(nil)
+	st.Lock()
+	st.Set("patch-level", 1)
+	st.Unlock()
+	err := patch.Apply(st)
+	c.Assert(err, ErrorMatches, `cannot patch system state to level 3, sublevel 0: internal error: patch panicked: boom`)
+
+	st.Lock()
+	defer st.Unlock()
+
+	// the state is as it was after the last successful patch
+	var level, n int
+	c.Assert(st.Get("patch-level", &level), IsNil)
+	c.Check(level, Equals, 2)
+	c.Assert(st.Get("n", &n), IsNil)
+	c.Check(n, Equals, 1)
+	var halfDone bool
+	c.Check(st.Get("half-done", &halfDone), Equals, state.ErrNoState)
+}
+
+func (s *patchSuite) TestDryRun(c *C) {
+	p12 := func(st *state.State) error {
+		st.Set("n", 1)
+		return nil
+	}
+	p121 := func(st *state.State) error {
+		return nil
+	}
+	p23 := func(st *state.State) error {
+		st.Set("foo", nil)
+		chg := st.NewChange("foo", "...")
+		chg.AddTask(st.NewTask("bar", "..."))
+		return nil
+	}
+	restore := patch.Mock(3, 0, map[int][]patch.PatchFunc{
+		2: {p12, p121},
+		3: {p23},
+	})
+	defer restore()
+
+	st := state.New(nil)
+	st.Lock()
+	st.Set("patch-level", 1)
+	st.Set("foo", "bar")
+	st.Unlock()
+
+	report, err := patch.DryRun(st)
+	c.Assert(err, IsNil)
+	c.Check(report, DeepEquals, []string{
+		"patch 2.0 would change: n, patch-level, patch-sublevel",
+		"patch 2.1 would change: patch-sublevel",
+		"patch 3.0 would change: changes, foo, patch-level, patch-sublevel, tasks",
+	})
+
+	// nothing was applied, nor backed up
+	st.Lock()
+	defer st.Unlock()
+	var level int
+	c.Assert(st.Get("patch-level", &level), IsNil)
+	c.Check(level, Equals, 1)
+	var foo string
+	c.Assert(st.Get("foo", &foo), IsNil)
+	c.Check(foo, Equals, "bar")
+	c.Check(st.Changes(), HasLen, 0)
+	c.Check(patch.BackupFile(), testutil.FileAbsent)
+}
+
+func (s *patchSuite) TestDryRunFailure(c *C) {
+	p12 := func(st *state.State) error {
+		panic("boom")
+	}
+	restore := patch.Mock(2, 0, map[int][]patch.PatchFunc{
+		2: {p12},
+	})
+	defer restore()
+
+	st := state.New(nil)
+	st.Lock()
+	st.Set("patch-level", 1)
+	st.Unlock()
+
+	report, err := patch.DryRun(st)
+	c.Assert(err, ErrorMatches, `cannot patch system state to level 2, sublevel 0: internal error: patch panicked: boom`)
+	c.Check(report, DeepEquals, []string{
+		"patch 2.0 would fail: internal error: patch panicked: boom",
+	})
+}
+
 const coreYaml = `name: core
 version: 1
 type: os
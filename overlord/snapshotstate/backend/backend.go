@@ -27,6 +27,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"os"
 	"path/filepath"
 	"sort"
@@ -47,6 +48,10 @@ const (
 
 	userArchivePrefix = "user/"
 	userArchiveSuffix = ".tgz"
+
+	// the state tar keeps of an archive, to create incremental
+	// archives relative to it
+	incrementalStateSuffix = ".snar"
 )
 
 var (
@@ -61,6 +66,9 @@ var (
 // Flags encompasses extra flags for snapshots backend Save.
 type Flags struct {
 	Auto bool
+	// Parent is the ID of the snapshot set to save only the changes
+	// relative to, if any.
+	Parent uint64
 }
 
 // Iter loops over all snapshots in the snapshots directory, applying the given
@@ -150,6 +158,25 @@ func List(ctx context.Context, setID uint64, snapNames []string) ([]client.Snaps
 	return sets, err
 }
 
+// openSnapshot opens the snapshot of the given snap in the given set.
+func openSnapshot(ctx context.Context, setID uint64, snapName string) (*Reader, error) {
+	var filename string
+	err := Iter(ctx, func(reader *Reader) error {
+		if reader.SetID == setID && reader.Snap == snapName {
+			filename = reader.Name()
+			return Stop
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if filename == "" {
+		return nil, fmt.Errorf("cannot find a snapshot of %q in snapshot set #%d", snapName, setID)
+	}
+	return backendOpen(filename)
+}
+
 // Filename of the given client.Snapshot in this backend.
 func Filename(snapshot *client.Snapshot) string {
 	// this _needs_ the snap name and version to be valid
@@ -163,8 +190,10 @@ func Save(ctx context.Context, id uint64, si *snap.Info, cfg map[string]interfac
 	}
 
 	var auto bool
+	var parentID uint64
 	if flags != nil {
 		auto = flags.Auto
+		parentID = flags.Parent
 	}
 
 	snapshot := &client.Snapshot{
@@ -179,6 +208,17 @@ func Save(ctx context.Context, id uint64, si *snap.Info, cfg map[string]interfac
 		Size:     0,
 		Conf:     cfg,
 		Auto:     auto,
+		Parent:   parentID,
+	}
+
+	var parent *Reader
+	if parentID != 0 {
+		var err error
+		parent, err = openSnapshot(ctx, parentID, snapshot.Snap)
+		if err != nil {
+			return nil, fmt.Errorf("cannot save snapshot relative to snapshot set #%d: %v", parentID, err)
+		}
+		defer parent.Close()
 	}
 
 	aw, err := osutil.NewAtomicFile(Filename(snapshot), 0600, 0, osutil.NoChown, osutil.NoChown)
@@ -190,7 +230,7 @@ func Save(ctx context.Context, id uint64, si *snap.Info, cfg map[string]interfac
 
 	w := zip.NewWriter(aw)
 	defer w.Close() // note this does not close the file descriptor (that's done by hand on the atomic writer, above)
-	if err := addDirToZip(ctx, snapshot, w, "root", archiveName, si.DataDir()); err != nil {
+	if err := addDirToZip(ctx, snapshot, w, parent, "root", archiveName, si.DataDir()); err != nil {
 		return nil, err
 	}
 
@@ -200,7 +240,7 @@ func Save(ctx context.Context, id uint64, si *snap.Info, cfg map[string]interfac
 	}
 
 	for _, usr := range users {
-		if err := addDirToZip(ctx, snapshot, w, usr.Username, userArchiveName(usr), si.UserDataDir(usr.HomeDir)); err != nil {
+		if err := addDirToZip(ctx, snapshot, w, parent, usr.Username, userArchiveName(usr), si.UserDataDir(usr.HomeDir)); err != nil {
 			return nil, err
 		}
 	}
@@ -238,7 +278,11 @@ func Save(ctx context.Context, id uint64, si *snap.Info, cfg map[string]interfac
 
 var isTesting = osutil.GetenvBool("SNAPPY_TESTING")
 
-func addDirToZip(ctx context.Context, snapshot *client.Snapshot, w *zip.Writer, username string, entry, dir string) error {
+// addDirToZip adds an archive of the given directory and of the common
+// directory next to it to the zip, as the given entry. If parentSnapshot
+// is not nil the archive only holds what changed since the same entry of
+// it was archived.
+func addDirToZip(ctx context.Context, snapshot *client.Snapshot, w *zip.Writer, parentSnapshot *Reader, username string, entry, dir string) error {
 	parent, revdir := filepath.Split(dir)
 	exists, isDir, err := osutil.DirExists(parent)
 	if err != nil {
@@ -293,6 +337,15 @@ func addDirToZip(ctx context.Context, snapshot *client.Snapshot, w *zip.Writer,
 		return nil
 	}
 
+	// tar records the state of what it archived in this file, for
+	// later archives to be created relative to this one
+	stateFile, err := incrementalStateFile(parentSnapshot, username, entry)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(stateFile)
+	tarArgs = append(tarArgs[:1], append([]string{"--listed-incremental", stateFile}, tarArgs[1:]...)...)
+
 	archiveWriter, err := w.CreateHeader(&zip.FileHeader{Name: entry})
 	if err != nil {
 		return err
@@ -320,5 +373,54 @@ func addDirToZip(ctx context.Context, snapshot *client.Snapshot, w *zip.Writer,
 	snapshot.SHA3_384[entry] = fmt.Sprintf("%x", hasher.Sum(nil))
 	snapshot.Size += sz.size
 
+	stateWriter, err := w.CreateHeader(&zip.FileHeader{Name: incrementalStateName(entry)})
+	if err != nil {
+		return err
+	}
+	f, err := os.Open(stateFile)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if _, err := io.Copy(stateWriter, f); err != nil {
+		return err
+	}
+
 	return nil
 }
+
+// incrementalStateFile returns the name of a temporary file, owned by
+// the given user, for tar to keep the state of the archive of the given
+// entry in. If the parent has that state for the entry it is copied
+// over, so that only what changed since is archived; otherwise the
+// archive will be a full one.
+func incrementalStateFile(parent *Reader, username, entry string) (name string, e error) {
+	f, err := ioutil.TempFile("", "snapshot-")
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		f.Close()
+		if e != nil {
+			os.Remove(f.Name())
+		}
+	}()
+
+	if parent != nil {
+		stateReader, _, err := zipMember(parent.File, incrementalStateName(entry))
+		if err == nil {
+			defer stateReader.Close()
+			if _, err := io.Copy(f, stateReader); err != nil {
+				return "", err
+			}
+		} else {
+			logger.Debugf("Saving %q in full as snapshot #%d of %q has no incremental state for it: %v.", entry, parent.SetID, parent.Snap, err)
+		}
+	}
+
+	if err := chownForUser(f.Name(), username); err != nil {
+		return "", err
+	}
+
+	return f.Name(), nil
+}
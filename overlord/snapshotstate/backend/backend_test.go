@@ -43,6 +43,7 @@ import (
 	"github.com/snapcore/snapd/osutil/sys"
 	"github.com/snapcore/snapd/overlord/snapshotstate/backend"
 	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/testutil"
 )
 
 type snapshotSuite struct {
@@ -408,11 +409,11 @@ func (s *snapshotSuite) TestAddDirToZipBails(c *check.C) {
 	buf, restore := logger.MockLogger()
 	defer restore()
 	// note as the zip is nil this would panic if it didn't bail
-	c.Check(backend.AddDirToZip(nil, snapshot, nil, "", "an/entry", filepath.Join(s.root, "nonexistent")), check.IsNil)
+	c.Check(backend.AddDirToZip(nil, snapshot, nil, nil, "", "an/entry", filepath.Join(s.root, "nonexistent")), check.IsNil)
 	// no log for the non-existent case
 	c.Check(buf.String(), check.Equals, "")
 	buf.Reset()
-	c.Check(backend.AddDirToZip(nil, snapshot, nil, "", "an/entry", "/etc/passwd"), check.IsNil)
+	c.Check(backend.AddDirToZip(nil, snapshot, nil, nil, "", "an/entry", "/etc/passwd"), check.IsNil)
 	c.Check(buf.String(), check.Matches, "(?m).* is not a directory.")
 }
 
@@ -421,12 +422,15 @@ func (s *snapshotSuite) TestAddDirToZipTarFails(c *check.C) {
 	c.Assert(os.MkdirAll(filepath.Join(d, "bar"), 0755), check.IsNil)
 	c.Assert(os.MkdirAll(filepath.Join(s.root, "common"), 0755), check.IsNil)
 
+	// run tar as the current user
+	defer backend.MockSysGeteuid(func() sys.UserID { return 1000 })()
+
 	ctx, cancel := context.WithCancel(context.Background())
 	cancel()
 
 	var buf bytes.Buffer
 	z := zip.NewWriter(&buf)
-	c.Assert(backend.AddDirToZip(ctx, nil, z, "", "an/entry", d), check.ErrorMatches, ".* context canceled")
+	c.Assert(backend.AddDirToZip(ctx, nil, z, nil, "", "an/entry", d), check.ErrorMatches, ".* context canceled")
 }
 
 func (s *snapshotSuite) TestAddDirToZip(c *check.C) {
@@ -440,7 +444,7 @@ func (s *snapshotSuite) TestAddDirToZip(c *check.C) {
 	snapshot := &client.Snapshot{
 		SHA3_384: map[string]string{},
 	}
-	c.Assert(backend.AddDirToZip(context.Background(), snapshot, z, "", "an/entry", d), check.IsNil)
+	c.Assert(backend.AddDirToZip(context.Background(), snapshot, z, nil, "", "an/entry", d), check.IsNil)
 	z.Close() // write out the central directory
 
 	c.Check(snapshot.SHA3_384, check.HasLen, 1)
@@ -449,8 +453,9 @@ func (s *snapshotSuite) TestAddDirToZip(c *check.C) {
 	br := bytes.NewReader(buf.Bytes())
 	r, err := zip.NewReader(br, int64(br.Len()))
 	c.Assert(err, check.IsNil)
-	c.Check(r.File, check.HasLen, 1)
+	c.Assert(r.File, check.HasLen, 2)
 	c.Check(r.File[0].Name, check.Equals, "an/entry")
+	c.Check(r.File[1].Name, check.Equals, "an/entry.snar")
 }
 
 func (s *snapshotSuite) TestHappyRoundtrip(c *check.C) {
@@ -604,6 +609,66 @@ func (s *snapshotSuite) TestRestoreRoundtripDifferentRevision(c *check.C) {
 	c.Check(diff().Run(), check.IsNil)
 }
 
+func (s *snapshotSuite) TestIncrementalRoundtrip(c *check.C) {
+	if os.Geteuid() == 0 {
+		c.Skip("this test cannot run as root (runuser will fail)")
+	}
+	logger.SimpleSetup()
+
+	info := &snap.Info{SideInfo: snap.SideInfo{RealName: "hello-snap", Revision: snap.R(42), SnapID: "hello-id"}, Version: "v1.33"}
+
+	full, err := backend.Save(context.TODO(), 12, info, nil, []string{"snapuser"}, nil)
+	c.Assert(err, check.IsNil)
+	c.Check(full.Parent, check.Equals, uint64(0))
+
+	// change, remove and add some data
+	c.Assert(ioutil.WriteFile(filepath.Join(info.DataDir(), "foo"), []byte("changed system canary\n"), 0644), check.IsNil)
+	c.Assert(os.Remove(filepath.Join(info.CommonDataDir(), "bar")), check.IsNil)
+	c.Assert(ioutil.WriteFile(filepath.Join(info.UserDataDir(filepath.Join(dirs.GlobalRootDir, "home/snapuser")), "new"), []byte("new user canary\n"), 0644), check.IsNil)
+
+	incr, err := backend.Save(context.TODO(), 13, info, nil, []string{"snapuser"}, &backend.Flags{Parent: 12})
+	c.Assert(err, check.IsNil)
+	c.Check(incr.Parent, check.Equals, uint64(12))
+	c.Check(hashkeys(incr), check.DeepEquals, []string{"archive.tgz", "user/snapuser.tgz"})
+
+	shr, err := backend.Open(backend.Filename(incr))
+	c.Assert(err, check.IsNil)
+	defer shr.Close()
+	c.Check(shr.Parent, check.Equals, uint64(12))
+	c.Check(shr.Check(context.TODO(), nil), check.IsNil)
+
+	// restoring needs the parent around
+	newroot := c.MkDir()
+	c.Assert(os.MkdirAll(filepath.Join(newroot, "home/snapuser"), 0755), check.IsNil)
+	oldSnapshotsDir := dirs.SnapshotsDir
+	dirs.SetRootDir(newroot)
+	c.Assert(os.MkdirAll(dirs.SnapshotsDir, 0700), check.IsNil)
+	for _, sh := range []*client.Snapshot{full, incr} {
+		c.Assert(os.Link(filepath.Join(oldSnapshotsDir, filepath.Base(backend.Filename(sh))), backend.Filename(sh)), check.IsNil)
+	}
+
+	rs, err := shr.Restore(context.TODO(), snap.R(0), nil, logger.Debugf)
+	c.Assert(err, check.IsNil)
+	rs.Cleanup()
+	out, err := exec.Command("diff", "-urN", "-x*.zip", s.root, newroot).CombinedOutput()
+	c.Check(err, check.IsNil, check.Commentf("%s", out))
+	c.Check(filepath.Join(info.CommonDataDir(), "bar"), testutil.FileAbsent)
+	c.Check(filepath.Join(info.DataDir(), "foo"), testutil.FileEquals, "changed system canary\n")
+
+	// without its parent the snapshot is no good
+	c.Assert(os.Remove(backend.Filename(full)), check.IsNil)
+	c.Check(shr.Check(context.TODO(), nil), check.ErrorMatches, `cannot open parent of snapshot .*: cannot find a snapshot of "hello-snap" in snapshot set #12`)
+	_, err = shr.Restore(context.TODO(), snap.R(0), nil, logger.Debugf)
+	c.Check(err, check.ErrorMatches, `cannot open parent of snapshot .*: cannot find a snapshot of "hello-snap" in snapshot set #12`)
+}
+
+func (s *snapshotSuite) TestSaveUnknownParent(c *check.C) {
+	info := &snap.Info{SideInfo: snap.SideInfo{RealName: "hello-snap", Revision: snap.R(42), SnapID: "hello-id"}, Version: "v1.33"}
+
+	_, err := backend.Save(context.TODO(), 13, info, nil, []string{"snapuser"}, &backend.Flags{Parent: 12})
+	c.Check(err, check.ErrorMatches, `cannot save snapshot relative to snapshot set #12: cannot find a snapshot of "hello-snap" in snapshot set #12`)
+}
+
 func (s *snapshotSuite) TestPickUserWrapperRunuser(c *check.C) {
 	n := 0
 	defer backend.MockExecLookPath(func(s string) (string, error) {
@@ -77,6 +77,10 @@ func entryUsername(entry string) string {
 	return entry[len(userArchivePrefix) : len(entry)-len(userArchiveSuffix)]
 }
 
+func incrementalStateName(entry string) string {
+	return strings.TrimSuffix(entry, filepath.Ext(entry)) + incrementalStateSuffix
+}
+
 type bySnap []*client.Snapshot
 
 func (a bySnap) Len() int           { return len(a) }
@@ -187,6 +191,27 @@ func pickUserWrapper() string {
 
 var userWrapper = pickUserWrapper()
 
+// chownForUser gives the file to the given user if tar will be run as
+// that user (see tarAsUser), so that it can be used by it.
+func chownForUser(path, username string) error {
+	if sysGeteuid() != 0 || username == "root" {
+		return nil
+	}
+	usr, err := userLookup(username)
+	if err != nil {
+		return err
+	}
+	uid, err := strconv.ParseUint(usr.Uid, 10, 32)
+	if err != nil {
+		return err
+	}
+	gid, err := strconv.ParseUint(usr.Gid, 10, 32)
+	if err != nil {
+		return err
+	}
+	return sys.ChownPath(path, sys.UserID(uid), sys.GroupID(gid))
+}
+
 // tarAsUser returns an exec.Cmd that will, if the current effective user id is
 // 0 and username is not "root", and if either runuser(1) or sudo(8) are found
 // on the PATH, run tar as the given user.
@@ -145,29 +145,72 @@ func (r *Reader) checkOne(ctx context.Context, entry string, hasher hash.Hash) e
 	return nil
 }
 
-// Check that the data contained in the snapshot matches its hashsums.
+// Check that the data contained in the snapshot, and in the snapshots
+// it was saved relative to, matches its hashsums.
 func (r *Reader) Check(ctx context.Context, usernames []string) error {
 	sort.Strings(usernames)
 
+	parents, err := r.parents(ctx)
+	if err != nil {
+		return err
+	}
+	defer closeAll(parents)
+
 	hasher := crypto.SHA3_384.New()
-	for entry := range r.SHA3_384 {
-		if len(usernames) > 0 && isUserArchive(entry) {
-			username := entryUsername(entry)
-			if !strutil.SortedListContains(usernames, username) {
-				logger.Debugf("In checking snapshot %q, skipping entry %q by user request.", r.Name(), username)
-				continue
+	for _, sh := range append(parents, r) {
+		for entry := range sh.SHA3_384 {
+			if len(usernames) > 0 && isUserArchive(entry) {
+				username := entryUsername(entry)
+				if !strutil.SortedListContains(usernames, username) {
+					logger.Debugf("In checking snapshot %q, skipping entry %q by user request.", sh.Name(), username)
+					continue
+				}
 			}
-		}
 
-		if err := r.checkOne(ctx, entry, hasher); err != nil {
-			return err
+			if err := sh.checkOne(ctx, entry, hasher); err != nil {
+				return err
+			}
+			hasher.Reset()
 		}
-		hasher.Reset()
 	}
 
 	return nil
 }
 
+// parents returns the snapshots this snapshot was saved relative to,
+// oldest first.
+//
+// If the returned error is nil, the caller must close the returned
+// readers when done with them.
+func (r *Reader) parents(ctx context.Context) (parents []*Reader, e error) {
+	defer func() {
+		if e != nil {
+			closeAll(parents)
+		}
+	}()
+
+	seen := map[uint64]bool{r.SetID: true}
+	for sh := r; sh.Parent != 0; sh = parents[0] {
+		if seen[sh.Parent] {
+			return parents, fmt.Errorf("snapshot %q has a loop of parents", r.Name())
+		}
+		seen[sh.Parent] = true
+		parent, err := openSnapshot(ctx, sh.Parent, r.Snap)
+		if err != nil {
+			return parents, fmt.Errorf("cannot open parent of snapshot %q: %v", sh.Name(), err)
+		}
+		parents = append([]*Reader{parent}, parents...)
+	}
+
+	return parents, nil
+}
+
+func closeAll(readers []*Reader) {
+	for _, reader := range readers {
+		reader.Close()
+	}
+}
+
 // Logf is the type implemented by logging functions.
 type Logf func(format string, args ...interface{})
 
@@ -189,8 +232,14 @@ func (r *Reader) Restore(ctx context.Context, current snap.Revision, usernames [
 	sort.Strings(usernames)
 	isRoot := sys.Geteuid() == 0
 	si := snap.MinimalPlaceInfo(r.Snap, r.Revision)
-	hasher := crypto.SHA3_384.New()
-	var sz sizer
+
+	// the data of an incremental snapshot is restored by unpacking
+	// the archives it was saved relative to first
+	parents, err := r.parents(ctx)
+	if err != nil {
+		return rs, err
+	}
+	defer closeAll(parents)
 
 	var curdir string
 	if !current.Unset() {
@@ -288,50 +337,14 @@ func (r *Reader) Restore(ctx context.Context, current snap.Revision, usernames [
 			}
 		}()
 
-		logger.Debugf("Restoring %q from %q into %q.", entry, r.Name(), tempdir)
-
-		body, expectedSize, err := zipMember(r.File, entry)
-		if err != nil {
-			return rs, err
-		}
-
-		expectedHash := r.SHA3_384[entry]
-
-		tr := io.TeeReader(body, io.MultiWriter(hasher, &sz))
-
-		// resist the temptation of using archive/tar unless it's proven
-		// that calling out to tar has issues -- there are a lot of
-		// special cases we'd need to consider otherwise
-		cmd := tarAsUser(username,
-			"--extract",
-			"--preserve-permissions", "--preserve-order", "--gunzip",
-			"--directory", tempdir)
-		cmd.Env = []string{}
-		cmd.Stdin = tr
-		matchCounter := &strutil.MatchCounter{N: 1}
-		cmd.Stderr = matchCounter
-		cmd.Stdout = os.Stderr
-		if isTesting {
-			matchCounter.N = -1
-			cmd.Stderr = io.MultiWriter(os.Stderr, matchCounter)
-		}
-
-		if err = osutil.RunWithContext(ctx, cmd); err != nil {
-			matches, count := matchCounter.Matches()
-			if count > 0 {
-				return rs, fmt.Errorf("cannot unpack archive: %s (and %d more)", matches[0], count-1)
+		for _, sh := range append(parents, r) {
+			if _, ok := sh.SHA3_384[entry]; !ok {
+				continue
+			}
+			logger.Debugf("Restoring %q from %q into %q.", entry, sh.Name(), tempdir)
+			if err := sh.unpack(ctx, entry, username, tempdir, len(parents) > 0); err != nil {
+				return rs, err
 			}
-			return rs, fmt.Errorf("tar failed: %v", err)
-		}
-
-		if sz.size != expectedSize {
-			return rs, fmt.Errorf("snapshot %q entry %q expected size (%d) does not match actual (%d)",
-				r.Name(), entry, expectedSize, sz.size)
-		}
-
-		if actualHash := fmt.Sprintf("%x", hasher.Sum(nil)); actualHash != expectedHash {
-			return rs, fmt.Errorf("snapshot %q entry %q expected hash (%.7s…) does not match actual (%.7s…)",
-				r.Name(), entry, expectedHash, actualHash)
 		}
 
 		if curdir != "" && curdir != revdir {
@@ -368,10 +381,69 @@ func (r *Reader) Restore(ctx context.Context, current snap.Revision, usernames [
 			}
 			rs.Created = append(rs.Created, target)
 		}
-
-		sz.Reset()
-		hasher.Reset()
 	}
 
 	return rs, nil
 }
+
+// unpack the given entry of the snapshot into dir, checking it matches
+// its hashsum. The entries of incremental snapshots need to be unpacked
+// as such, for files removed since their parent to be removed.
+func (r *Reader) unpack(ctx context.Context, entry, username, dir string, incremental bool) error {
+	body, expectedSize, err := zipMember(r.File, entry)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	expectedHash := r.SHA3_384[entry]
+
+	hasher := crypto.SHA3_384.New()
+	var sz sizer
+	tr := io.TeeReader(body, io.MultiWriter(hasher, &sz))
+
+	// resist the temptation of using archive/tar unless it's proven
+	// that calling out to tar has issues -- there are a lot of
+	// special cases we'd need to consider otherwise
+	tarArgs := []string{
+		"--extract",
+		"--preserve-permissions", "--gunzip",
+		"--directory", dir,
+	}
+	if incremental {
+		// tar does not support preserving the order here
+		tarArgs = append(tarArgs, "--listed-incremental", "/dev/null")
+	} else {
+		tarArgs = append(tarArgs, "--preserve-order")
+	}
+	cmd := tarAsUser(username, tarArgs...)
+	cmd.Env = []string{}
+	cmd.Stdin = tr
+	matchCounter := &strutil.MatchCounter{N: 1}
+	cmd.Stderr = matchCounter
+	cmd.Stdout = os.Stderr
+	if isTesting {
+		matchCounter.N = -1
+		cmd.Stderr = io.MultiWriter(os.Stderr, matchCounter)
+	}
+
+	if err = osutil.RunWithContext(ctx, cmd); err != nil {
+		matches, count := matchCounter.Matches()
+		if count > 0 {
+			return fmt.Errorf("cannot unpack archive: %s (and %d more)", matches[0], count-1)
+		}
+		return fmt.Errorf("tar failed: %v", err)
+	}
+
+	if sz.size != expectedSize {
+		return fmt.Errorf("snapshot %q entry %q expected size (%d) does not match actual (%d)",
+			r.Name(), entry, expectedSize, sz.size)
+	}
+
+	if actualHash := fmt.Sprintf("%x", hasher.Sum(nil)); actualHash != expectedHash {
+		return fmt.Errorf("snapshot %q entry %q expected hash (%.7s…) does not match actual (%.7s…)",
+			r.Name(), entry, expectedHash, actualHash)
+	}
+
+	return nil
+}
@@ -42,7 +42,9 @@ var (
 	CleanupRestore             = cleanupRestore
 	DoCheck                    = doCheck
 	DoForget                   = doForget
+	UndoSave                   = undoSave
 	SaveExpiration             = saveExpiration
+	SaveKeptData               = saveKeptData
 	ExpiredSnapshotSets        = expiredSnapshotSets
 	RemoveSnapshotState        = removeSnapshotState
 
@@ -63,7 +63,7 @@ type SnapshotManager struct {
 func Manager(st *state.State, runner *state.TaskRunner) *SnapshotManager {
 	delayedCrossMgrInit()
 
-	runner.AddHandler("save-snapshot", doSave, doForget)
+	runner.AddHandler("save-snapshot", doSave, undoSave)
 	runner.AddHandler("forget-snapshot", doForget, nil)
 	runner.AddHandler("check-snapshot", doCheck, nil)
 	runner.AddHandler("restore-snapshot", doRestore, undoRestore)
@@ -100,8 +100,9 @@ func (mgr *SnapshotManager) forgetExpiredSnapshots() error {
 	}
 
 	err = backendIter(context.TODO(), func(r *backend.Reader) error {
-		// forget needs to conflict with check and restore
-		if err := checkSnapshotTaskConflict(mgr.state, r.SetID, "check-snapshot", "restore-snapshot"); err != nil {
+		// forget needs to conflict with check and restore, and with
+		// saves whose change may still need them to undo a migration
+		if err := checkSnapshotTaskConflict(mgr.state, r.SetID, "check-snapshot", "restore-snapshot", "save-snapshot"); err != nil {
 			// there is a conflict, do nothing and we will retry this set on next Ensure().
 			return nil
 		}
@@ -154,6 +155,15 @@ type snapshotSetup struct {
 	Filename string        `json:"filename,omitempty"`
 	Current  snap.Revision `json:"current"`
 	Auto     bool          `json:"auto,omitempty"`
+	// RestoreOnUndo is set for snapshots taken before a data
+	// migration, undoing them puts the saved data back in place.
+	RestoreOnUndo bool `json:"restore-on-undo,omitempty"`
+	// KeepFor is set for snapshots retaining the data of a removed
+	// snap, it overrides the automatic snapshot expiration.
+	KeepFor time.Duration `json:"keep-for,omitempty"`
+	// Parent is set for snapshots only holding the changes relative
+	// to the given snapshot set.
+	Parent uint64 `json:"parent,omitempty"`
 }
 
 func filename(setID uint64, si *snap.Info) string {
@@ -195,7 +205,12 @@ func prepareSave(task *state.Task) (snapshot *snapshotSetup, cur *snap.Info, cfg
 	}
 
 	// this should be done last because of it modifies the state and the caller needs to undo this if other operation fails.
-	if snapshot.Auto {
+	switch {
+	case snapshot.KeepFor != 0:
+		if err := saveKeptData(st, snapshot.SetID, snapshot.Snap, time.Now().Add(snapshot.KeepFor)); err != nil {
+			return nil, nil, nil, err
+		}
+	case snapshot.Auto:
 		expiration, err := AutomaticSnapshotExpiration(st)
 		if err != nil {
 			return nil, nil, nil, err
@@ -213,7 +228,7 @@ func doSave(task *state.Task, tomb *tomb.Tomb) error {
 	if err != nil {
 		return err
 	}
-	_, err = backendSave(tomb.Context(nil), snapshot.SetID, cur, cfg, snapshot.Users, &backend.Flags{Auto: snapshot.Auto})
+	_, err = backendSave(tomb.Context(nil), snapshot.SetID, cur, cfg, snapshot.Users, &backend.Flags{Auto: snapshot.Auto, Parent: snapshot.Parent})
 	if err != nil {
 		st := task.State()
 		st.Lock()
@@ -370,8 +385,69 @@ func doCheck(task *state.Task, tomb *tomb.Tomb) error {
 	return backendCheck(reader, tomb.Context(nil), snapshot.Users)
 }
 
+func undoSave(task *state.Task, tomb *tomb.Tomb) error {
+	st := task.State()
+	st.Lock()
+	var snapshot snapshotSetup
+	err := task.Get("snapshot-setup", &snapshot)
+	st.Unlock()
+	if err != nil {
+		return taskGetErrMsg(task, err, "snapshot")
+	}
+
+	if snapshot.RestoreOnUndo {
+		if err := restoreMigrationSnapshot(task, tomb, &snapshot); err != nil {
+			return err
+		}
+	}
+
+	return doForget(task, tomb)
+}
+
+// restoreMigrationSnapshot puts back the data and configuration saved
+// in the snapshot before a data migration.
+func restoreMigrationSnapshot(task *state.Task, tomb *tomb.Tomb, snapshot *snapshotSetup) error {
+	if snapshot.Filename == "" {
+		return fmt.Errorf("internal error: task %s (%s) snapshot info is missing the filename", task.ID(), task.Kind())
+	}
+
+	reader, err := backendOpen(snapshot.Filename)
+	if err != nil {
+		return fmt.Errorf("cannot open snapshot: %v", err)
+	}
+	defer reader.Close()
+
+	st := task.State()
+	logf := func(format string, args ...interface{}) {
+		st.Lock()
+		defer st.Unlock()
+		task.Logf(format, args...)
+	}
+
+	restoreState, err := backendRestore(reader, tomb.Context(nil), reader.Revision, nil, logf)
+	if err != nil {
+		return err
+	}
+
+	buf, err := json.Marshal(reader.Conf)
+	if err != nil {
+		backendRevert(restoreState)
+		return fmt.Errorf("cannot marshal saved config: %v", err)
+	}
+
+	st.Lock()
+	defer st.Unlock()
+
+	if err := configSetSnapConfig(st, snapshot.Snap, (*json.RawMessage)(&buf)); err != nil {
+		backendRevert(restoreState)
+		return fmt.Errorf("cannot set snap config: %v", err)
+	}
+	backendCleanup(restoreState)
+
+	return nil
+}
+
 func doForget(task *state.Task, _ *tomb.Tomb) error {
-	// note this is also undoSave
 	st := task.State()
 	st.Lock()
 	defer st.Unlock()
@@ -399,6 +475,9 @@ func delayedCrossMgrInit() {
 	// hook automatic snapshots into snapstate logic
 	snapstate.AutomaticSnapshot = AutomaticSnapshot
 	snapstate.AutomaticSnapshotExpiration = AutomaticSnapshotExpiration
+	snapstate.MigrationSnapshot = MigrationSnapshot
+	snapstate.RestoreMigrationSnapshot = RestoreMigrationSnapshot
+	snapstate.KeepDataSnapshot = KeepDataSnapshot
 }
 
 func MockBackendSave(f func(context.Context, uint64, *snap.Info, map[string]interface{}, []string, *backend.Flags) (*client.Snapshot, error)) (restore func()) {
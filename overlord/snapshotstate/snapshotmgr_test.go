@@ -33,6 +33,7 @@ import (
 
 	"github.com/snapcore/snapd/client"
 	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/overlord/configstate/config"
 	"github.com/snapcore/snapd/overlord/snapshotstate"
 	"github.com/snapcore/snapd/overlord/snapshotstate/backend"
 	"github.com/snapcore/snapd/overlord/state"
@@ -282,6 +283,30 @@ func (snapshotSuite) TestDoSave(c *check.C) {
 	c.Assert(err, check.IsNil)
 }
 
+func (snapshotSuite) TestDoSaveIncremental(c *check.C) {
+	defer snapshotstate.MockSnapstateCurrentInfo(func(_ *state.State, snapname string) (*snap.Info, error) {
+		return &snap.Info{SideInfo: snap.SideInfo{RealName: "a-snap", Revision: snap.R(-1)}, Version: "1.33"}, nil
+	})()
+	defer snapshotstate.MockConfigGetSnapConfig(func(*state.State, string) (*json.RawMessage, error) { return nil, nil })()
+	defer snapshotstate.MockBackendSave(func(_ context.Context, id uint64, si *snap.Info, cfg map[string]interface{}, usernames []string, flags *backend.Flags) (*client.Snapshot, error) {
+		c.Check(id, check.Equals, uint64(42))
+		c.Check(flags, check.DeepEquals, &backend.Flags{Parent: 12})
+		return nil, nil
+	})()
+
+	st := state.New(nil)
+	st.Lock()
+	task := st.NewTask("save-snapshot", "...")
+	task.Set("snapshot-setup", map[string]interface{}{
+		"set-id": 42,
+		"snap":   "a-snap",
+		"parent": 12,
+	})
+	st.Unlock()
+	err := snapshotstate.DoSave(task, &tomb.Tomb{})
+	c.Assert(err, check.IsNil)
+}
+
 func (snapshotSuite) TestDoSaveFailsWithNoSnap(c *check.C) {
 	defer snapshotstate.MockSnapstateCurrentInfo(func(*state.State, string) (*snap.Info, error) {
 		return nil, errors.New("bzzt")
@@ -463,6 +488,53 @@ func (snapshotSuite) TestDoSaveFailureRemovesStateEntry(c *check.C) {
 	c.Check(expirations, check.HasLen, 0)
 }
 
+func (snapshotSuite) TestDoSaveKeepData(c *check.C) {
+	st := state.New(nil)
+
+	snapInfo := snap.Info{
+		SideInfo: snap.SideInfo{
+			RealName: "a-snap",
+			Revision: snap.R(-1),
+		},
+		Version: "1.33",
+	}
+	defer snapshotstate.MockSnapstateCurrentInfo(func(_ *state.State, snapname string) (*snap.Info, error) {
+		return &snapInfo, nil
+	})()
+	defer snapshotstate.MockConfigGetSnapConfig(func(_ *state.State, snapname string) (*json.RawMessage, error) {
+		return nil, nil
+	})()
+	defer snapshotstate.MockBackendSave(func(_ context.Context, id uint64, si *snap.Info, cfg map[string]interface{}, usernames []string, flags *backend.Flags) (*client.Snapshot, error) {
+		c.Check(flags.Auto, check.Equals, true)
+		return nil, nil
+	})()
+
+	st.Lock()
+	defer st.Unlock()
+	// automatic snapshots disabled, the kept data expiry is used instead
+	tr := config.NewTransaction(st)
+	tr.Set("core", "snapshots.automatic.retention", "no")
+	tr.Commit()
+
+	task := st.NewTask("save-snapshot", "...")
+	task.Set("snapshot-setup", map[string]interface{}{
+		"set-id":   42,
+		"snap":     "a-snap",
+		"auto":     true,
+		"keep-for": 3 * time.Hour,
+	})
+	st.Unlock()
+	err := snapshotstate.DoSave(task, &tomb.Tomb{})
+	st.Lock()
+	c.Assert(err, check.IsNil)
+
+	setID, expiry, err := snapshotstate.KeptDataSnapshot(st, "a-snap")
+	c.Assert(err, check.IsNil)
+	c.Check(setID, check.Equals, uint64(42))
+	c.Check(expiry.After(time.Now().Add(2*time.Hour)), check.Equals, true)
+	c.Check(expiry.Before(time.Now().Add(4*time.Hour)), check.Equals, true)
+}
+
 type readerSuite struct {
 	task     *state.Task
 	calls    []string
@@ -705,6 +777,68 @@ func (rs *readerSuite) TestDoRemove(c *check.C) {
 	c.Check(rs.calls, check.DeepEquals, []string{"remove"})
 }
 
+func (rs *readerSuite) TestUndoSave(c *check.C) {
+	err := snapshotstate.UndoSave(rs.task, &tomb.Tomb{})
+	c.Assert(err, check.IsNil)
+	c.Check(rs.calls, check.DeepEquals, []string{"remove"})
+}
+
+func (rs *readerSuite) TestUndoSaveRestoresMigrationSnapshot(c *check.C) {
+	st := rs.task.State()
+	st.Lock()
+	rs.task.Set("snapshot-setup", map[string]interface{}{
+		"snap":            "a-snap",
+		"filename":        "/some/file.zip",
+		"restore-on-undo": true,
+	})
+	st.Unlock()
+
+	defer snapshotstate.MockBackendOpen(func(filename string) (*backend.Reader, error) {
+		rs.calls = append(rs.calls, "open")
+		c.Check(filename, check.Equals, "/some/file.zip")
+		return &backend.Reader{
+			Snapshot: client.Snapshot{Revision: snap.R(7), Conf: map[string]interface{}{"hello": "there"}},
+		}, nil
+	})()
+	defer snapshotstate.MockBackendRestore(func(_ *backend.Reader, _ context.Context, rev snap.Revision, users []string, _ backend.Logf) (*backend.RestoreState, error) {
+		rs.calls = append(rs.calls, "restore")
+		c.Check(rev, check.Equals, snap.R(7))
+		c.Check(users, check.IsNil)
+		return &backend.RestoreState{}, nil
+	})()
+	defer snapshotstate.MockConfigSetSnapConfig(func(_ *state.State, snapname string, conf *json.RawMessage) error {
+		rs.calls = append(rs.calls, "set config")
+		c.Check(snapname, check.Equals, "a-snap")
+		c.Check(string(*conf), check.Equals, `{"hello":"there"}`)
+		return nil
+	})()
+
+	err := snapshotstate.UndoSave(rs.task, &tomb.Tomb{})
+	c.Assert(err, check.IsNil)
+	c.Check(rs.calls, check.DeepEquals, []string{"open", "restore", "set config", "cleanup", "remove"})
+}
+
+func (rs *readerSuite) TestUndoSaveMigrationSnapshotRestoreFails(c *check.C) {
+	st := rs.task.State()
+	st.Lock()
+	rs.task.Set("snapshot-setup", map[string]interface{}{
+		"snap":            "a-snap",
+		"filename":        "/some/file.zip",
+		"restore-on-undo": true,
+	})
+	st.Unlock()
+
+	defer snapshotstate.MockBackendRestore(func(*backend.Reader, context.Context, snap.Revision, []string, backend.Logf) (*backend.RestoreState, error) {
+		rs.calls = append(rs.calls, "restore")
+		return nil, errors.New("bzzt")
+	})()
+
+	err := snapshotstate.UndoSave(rs.task, &tomb.Tomb{})
+	c.Assert(err, check.ErrorMatches, "bzzt")
+	// the snapshot is kept around so the data can still be recovered
+	c.Check(rs.calls, check.DeepEquals, []string{"open", "restore"})
+}
+
 func (rs *readerSuite) TestDoForgetRemovesAutomaticSnapshotExpiry(c *check.C) {
 	defer snapshotstate.MockOsRemove(func(filename string) error {
 		return nil
@@ -29,6 +29,7 @@ import (
 	"github.com/snapcore/snapd/client"
 	"github.com/snapcore/snapd/logger"
 	"github.com/snapcore/snapd/overlord/configstate/config"
+	"github.com/snapcore/snapd/overlord/hookstate"
 	"github.com/snapcore/snapd/overlord/snapshotstate/backend"
 	"github.com/snapcore/snapd/overlord/snapstate"
 	"github.com/snapcore/snapd/overlord/state"
@@ -41,6 +42,8 @@ var (
 	snapstateAll                     = snapstate.All
 	snapstateCheckChangeConflictMany = snapstate.CheckChangeConflictMany
 	backendIter                      = backend.Iter
+	setupPreRestoreHook              = hookstate.SetupPreRestoreHook
+	setupPostRestoreHook             = hookstate.SetupPostRestoreHook
 
 	// Default expiration time for automatic snapshots, if not set by the user
 	defaultAutomaticSnapshotExpiration = time.Hour * 24 * 31
@@ -48,6 +51,9 @@ var (
 
 type snapshotState struct {
 	ExpiryTime time.Time `json:"expiry-time"`
+	// KeptDataOf is set for snapshots retaining the data of a snap
+	// removed with a data retention window.
+	KeptDataOf string `json:"kept-data-of,omitempty"`
 }
 
 func newSnapshotSetID(st *state.State) (uint64, error) {
@@ -109,6 +115,22 @@ func AutomaticSnapshotExpiration(st *state.State) (time.Duration, error) {
 // saveExpiration saves expiration date of the given snapshot set, in the state.
 // The state needs to be locked by the caller.
 func saveExpiration(st *state.State, setID uint64, expiryTime time.Time) error {
+	return saveSnapshotState(st, setID, &snapshotState{
+		ExpiryTime: expiryTime,
+	})
+}
+
+// saveKeptData saves the expiration date of a snapshot set retaining the
+// data of a removed snap, in the state.
+// The state needs to be locked by the caller.
+func saveKeptData(st *state.State, setID uint64, snapName string, expiryTime time.Time) error {
+	return saveSnapshotState(st, setID, &snapshotState{
+		ExpiryTime: expiryTime,
+		KeptDataOf: snapName,
+	})
+}
+
+func saveSnapshotState(st *state.State, setID uint64, snapshotSt *snapshotState) error {
 	var snapshots map[uint64]*json.RawMessage
 	err := st.Get("snapshots", &snapshots)
 	if err != nil && err != state.ErrNoState {
@@ -117,9 +139,7 @@ func saveExpiration(st *state.State, setID uint64, expiryTime time.Time) error {
 	if snapshots == nil {
 		snapshots = make(map[uint64]*json.RawMessage)
 	}
-	data, err := json.Marshal(&snapshotState{
-		ExpiryTime: expiryTime,
-	})
+	data, err := json.Marshal(snapshotSt)
 	if err != nil {
 		return err
 	}
@@ -170,6 +190,34 @@ func expiredSnapshotSets(st *state.State, cutoffTime time.Time) (map[uint64]bool
 	return expired, nil
 }
 
+// KeptDataSnapshot returns the most recent unexpired snapshot set
+// retaining the data of the given removed snap, together with its expiry
+// time. A zero set ID is returned if there is no such set.
+// The state needs to be locked by the caller.
+func KeptDataSnapshot(st *state.State, snapName string) (setID uint64, expiryTime time.Time, err error) {
+	var snapshots map[uint64]*snapshotState
+	err = st.Get("snapshots", &snapshots)
+	if err != nil {
+		if err != state.ErrNoState {
+			return 0, time.Time{}, err
+		}
+		return 0, time.Time{}, nil
+	}
+
+	now := time.Now()
+	for id, snapshotSet := range snapshots {
+		if snapshotSet.KeptDataOf != snapName || snapshotSet.ExpiryTime.Before(now) {
+			continue
+		}
+		if id > setID {
+			setID = id
+			expiryTime = snapshotSet.ExpiryTime
+		}
+	}
+
+	return setID, expiryTime, nil
+}
+
 // snapshotSnapSummaries are used internally to get useful data from a
 // snapshot set when deciding whether to check/forget/restore it.
 type snapshotSnapSummaries []*snapshotSnapSummary
@@ -259,6 +307,43 @@ var List = backend.List
 // Save creates a taskset for taking snapshots of snaps' data.
 // Note that the state must be locked by the caller.
 func Save(st *state.State, instanceNames []string, users []string) (setID uint64, snapsSaved []string, ts *state.TaskSet, err error) {
+	return save(st, 0, instanceNames, users)
+}
+
+// SaveIncremental creates a taskset for taking snapshots of snaps' data
+// that only hold what changed since the given snapshot set was taken.
+// If no snaps are given, the snaps in that snapshot set are snapshotted.
+// Note that the state must be locked by the caller.
+func SaveIncremental(st *state.State, parentID uint64, instanceNames []string, users []string) (setID uint64, snapsSaved []string, ts *state.TaskSet, err error) {
+	// automatic snapshots expire, and would take the snapshots saved
+	// relative to them along
+	var snapshots map[uint64]*snapshotState
+	if err := st.Get("snapshots", &snapshots); err != nil && err != state.ErrNoState {
+		return 0, nil, nil, err
+	}
+	if _, ok := snapshots[parentID]; ok {
+		return 0, nil, nil, fmt.Errorf("cannot save snapshots relative to snapshot set #%d: it is an automatic snapshot", parentID)
+	}
+
+	summaries, err := snapSummariesInSnapshotSet(parentID, instanceNames)
+	if err != nil {
+		return 0, nil, nil, err
+	}
+	if len(instanceNames) == 0 {
+		instanceNames = summaries.snapNames()
+	} else if len(summaries) != len(instanceNames) {
+		found := summaries.snapNames()
+		for _, name := range instanceNames {
+			if !strutil.ListContains(found, name) {
+				return 0, nil, nil, fmt.Errorf("cannot save snapshot of %q relative to snapshot set #%d: the set has no snapshot of it", name, parentID)
+			}
+		}
+	}
+
+	return save(st, parentID, instanceNames, users)
+}
+
+func save(st *state.State, parentID uint64, instanceNames []string, users []string) (setID uint64, snapsSaved []string, ts *state.TaskSet, err error) {
 	if len(instanceNames) == 0 {
 		instanceNames, err = allActiveSnapNames(st)
 		if err != nil {
@@ -280,11 +365,15 @@ func Save(st *state.State, instanceNames []string, users []string) (setID uint64
 
 	for _, name := range instanceNames {
 		desc := fmt.Sprintf("Save data of snap %q in snapshot set #%d", name, setID)
+		if parentID != 0 {
+			desc = fmt.Sprintf("Save data of snap %q changed since snapshot set #%d in snapshot set #%d", name, parentID, setID)
+		}
 		task := st.NewTask("save-snapshot", desc)
 		snapshot := snapshotSetup{
-			SetID: setID,
-			Snap:  name,
-			Users: users,
+			SetID:  setID,
+			Snap:   name,
+			Users:  users,
+			Parent: parentID,
 		}
 		task.Set("snapshot-setup", &snapshot)
 		// Here, note that a snapshot set behaves as a unit: it either
@@ -331,6 +420,85 @@ func AutomaticSnapshot(st *state.State, snapName string) (ts *state.TaskSet, err
 	return ts, nil
 }
 
+// MigrationSnapshot returns a taskset saving the data of the snap
+// before it gets migrated across epochs during a refresh, and the id
+// of the snapshot set it goes in. Unlike AutomaticSnapshot it is
+// always taken, and undoing it restores the saved data so that a
+// failed migration leaves the snap as it was.
+func MigrationSnapshot(st *state.State, snapName string) (setID uint64, ts *state.TaskSet, err error) {
+	setID, err = newSnapshotSetID(st)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	desc := fmt.Sprintf("Save data of snap %q before migration in snapshot set #%d", snapName, setID)
+	task := st.NewTask("save-snapshot", desc)
+	snapshot := snapshotSetup{
+		SetID:         setID,
+		Snap:          snapName,
+		Auto:          true,
+		RestoreOnUndo: true,
+	}
+	task.Set("snapshot-setup", &snapshot)
+
+	return setID, state.NewTaskSet(task), nil
+}
+
+// RestoreMigrationSnapshot returns a taskset restoring into the given
+// revision the data saved by MigrationSnapshot, for when the snap is
+// reverted to the revision it was migrated from. A nil taskset is
+// returned if the snapshot is gone, e.g. because it expired.
+func RestoreMigrationSnapshot(st *state.State, snapName string, setID uint64, rev snap.Revision) (ts *state.TaskSet, err error) {
+	summaries, err := snapSummariesInSnapshotSet(setID, []string{snapName})
+	if err == client.ErrSnapshotSetNotFound || err == client.ErrSnapshotSnapsNotFound {
+		logger.Noticef("Cannot find the data of snap %q saved before migration in snapshot set #%d, not restoring it.", snapName, setID)
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// restore needs to conflict with forget of itself
+	if err := checkSnapshotTaskConflict(st, setID, "forget-snapshot"); err != nil {
+		return nil, err
+	}
+
+	desc := fmt.Sprintf("Restore data of snap %q saved before migration from snapshot set #%d", snapName, setID)
+	task := st.NewTask("restore-snapshot", desc)
+	snapshot := snapshotSetup{
+		SetID:    setID,
+		Snap:     snapName,
+		Filename: summaries[0].filename,
+		Current:  rev,
+	}
+	task.Set("snapshot-setup", &snapshot)
+
+	return state.NewTaskSet(task), nil
+}
+
+// KeepDataSnapshot returns a taskset saving the data of the snap
+// before it gets removed, keeping it around for the given duration so
+// that it can be restored if the snap gets installed again. Unlike
+// AutomaticSnapshot it is always taken.
+func KeepDataSnapshot(st *state.State, snapName string, keepFor time.Duration) (ts *state.TaskSet, err error) {
+	setID, err := newSnapshotSetID(st)
+	if err != nil {
+		return nil, err
+	}
+
+	desc := fmt.Sprintf("Save data of snap %q in snapshot set #%d kept for %v", snapName, setID, keepFor)
+	task := st.NewTask("save-snapshot", desc)
+	snapshot := snapshotSetup{
+		SetID:   setID,
+		Snap:    snapName,
+		Auto:    true,
+		KeepFor: keepFor,
+	}
+	task.Set("snapshot-setup", &snapshot)
+
+	return state.NewTaskSet(task), nil
+}
+
 // Restore creates a taskset for restoring a snapshot's data.
 // Note that the state must be locked by the caller.
 func Restore(st *state.State, setID uint64, snapNames []string, users []string) (snapsFound []string, ts *state.TaskSet, err error) {
@@ -358,6 +526,7 @@ func Restore(st *state.State, setID uint64, snapNames []string, users []string)
 
 	for _, summary := range summaries {
 		var current snap.Revision
+		var hooks map[string]*snap.HookInfo
 		if snapst, ok := all[summary.snap]; ok {
 			info, err := snapst.CurrentInfo()
 			if err != nil {
@@ -373,6 +542,7 @@ func Restore(st *state.State, setID uint64, snapNames []string, users []string)
 				return nil, nil, fmt.Errorf(tpl, summary.snap, info.SnapID, summary.snapID)
 			}
 			current = snapst.Current
+			hooks = info.Hooks
 		}
 
 		desc := fmt.Sprintf("Restore data of snap %q from snapshot set #%d", summary.snap, setID)
@@ -385,8 +555,22 @@ func Restore(st *state.State, setID uint64, snapNames []string, users []string)
 			Current:  current,
 		}
 		task.Set("snapshot-setup", &snapshot)
+		// the restore hooks let the snap quiesce before and migrate
+		// after its data is restored; a failing pre-restore hook
+		// aborts the restore while a failing post-restore one is
+		// only reported.
+		if hooks["pre-restore"] != nil {
+			preRestore := setupPreRestoreHook(st, summary.snap)
+			task.WaitFor(preRestore)
+			ts.AddTask(preRestore)
+		}
 		// see the note about snapshots not using lanes, above.
 		ts.AddTask(task)
+		if hooks["post-restore"] != nil {
+			postRestore := setupPostRestoreHook(st, summary.snap)
+			postRestore.WaitFor(task)
+			ts.AddTask(postRestore)
+		}
 	}
 
 	return snapsFound, ts, nil
@@ -436,6 +620,17 @@ func Forget(st *state.State, setID uint64, snapNames []string) (snapsFound []str
 		return nil, nil, err
 	}
 
+	// snapshots saved relative to the ones to forget cannot do without them
+	err = backendIter(context.TODO(), func(r *backend.Reader) error {
+		if r.Parent == setID && strutil.ListContains(summaries.snapNames(), r.Snap) {
+			return fmt.Errorf("cannot forget the snapshot of %q in snapshot set #%d: the one in snapshot set #%d was saved relative to it", r.Snap, setID, r.SetID)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
 	ts = state.NewTaskSet()
 	for _, summary := range summaries {
 		desc := fmt.Sprintf("Drop data of snap %q from snapshot set #%d", summary.snap, setID)
@@ -39,6 +39,7 @@ import (
 	"github.com/snapcore/snapd/osutil/sys"
 	"github.com/snapcore/snapd/overlord"
 	"github.com/snapcore/snapd/overlord/configstate/config"
+	"github.com/snapcore/snapd/overlord/hookstate"
 	"github.com/snapcore/snapd/overlord/snapshotstate"
 	"github.com/snapcore/snapd/overlord/snapshotstate/backend"
 	"github.com/snapcore/snapd/overlord/snapstate"
@@ -495,6 +496,87 @@ func (snapshotSuite) TestSaveOneSnap(c *check.C) {
 	})
 }
 
+func (snapshotSuite) TestSaveIncremental(c *check.C) {
+	shotfile, err := os.Create(filepath.Join(c.MkDir(), "yadda.zip"))
+	c.Assert(err, check.IsNil)
+	defer shotfile.Close()
+	fakeIter := func(_ context.Context, f func(*backend.Reader) error) error {
+		for _, name := range []string{"a-snap", "b-snap"} {
+			if err := f(&backend.Reader{
+				Snapshot: client.Snapshot{SetID: 12, Snap: name},
+				File:     shotfile,
+			}); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	defer snapshotstate.MockBackendIter(fakeIter)()
+
+	st := state.New(nil)
+	st.Lock()
+	defer st.Unlock()
+	st.Set("last-snapshot-set-id", 12)
+
+	// the snaps of the parent set are saved by default
+	setID, saved, taskset, err := snapshotstate.SaveIncremental(st, 12, nil, nil)
+	c.Assert(err, check.IsNil)
+	c.Check(setID, check.Equals, uint64(13))
+	c.Check(saved, check.DeepEquals, []string{"a-snap", "b-snap"})
+	tasks := taskset.Tasks()
+	c.Assert(tasks, check.HasLen, 2)
+	c.Check(tasks[0].Kind(), check.Equals, "save-snapshot")
+	c.Check(tasks[0].Summary(), check.Equals, `Save data of snap "a-snap" changed since snapshot set #12 in snapshot set #13`)
+	var snapshot map[string]interface{}
+	c.Check(tasks[0].Get("snapshot-setup", &snapshot), check.IsNil)
+	c.Check(snapshot, check.DeepEquals, map[string]interface{}{
+		"set-id":  13.,
+		"snap":    "a-snap",
+		"parent":  12.,
+		"current": "unset",
+	})
+
+	setID, saved, _, err = snapshotstate.SaveIncremental(st, 12, []string{"b-snap"}, nil)
+	c.Assert(err, check.IsNil)
+	c.Check(setID, check.Equals, uint64(14))
+	c.Check(saved, check.DeepEquals, []string{"b-snap"})
+}
+
+func (snapshotSuite) TestSaveIncrementalErrors(c *check.C) {
+	shotfile, err := os.Create(filepath.Join(c.MkDir(), "yadda.zip"))
+	c.Assert(err, check.IsNil)
+	defer shotfile.Close()
+	fakeIter := func(_ context.Context, f func(*backend.Reader) error) error {
+		for _, setID := range []uint64{12, 20} {
+			if err := f(&backend.Reader{
+				Snapshot: client.Snapshot{SetID: setID, Snap: "a-snap"},
+				File:     shotfile,
+			}); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	defer snapshotstate.MockBackendIter(fakeIter)()
+
+	st := state.New(nil)
+	st.Lock()
+	defer st.Unlock()
+	c.Assert(snapshotstate.SaveExpiration(st, 20, time.Now().Add(time.Hour)), check.IsNil)
+
+	_, _, _, err = snapshotstate.SaveIncremental(st, 11, nil, nil)
+	c.Check(err, check.Equals, client.ErrSnapshotSetNotFound)
+
+	_, _, _, err = snapshotstate.SaveIncremental(st, 12, []string{"b-snap"}, nil)
+	c.Check(err, check.Equals, client.ErrSnapshotSnapsNotFound)
+
+	_, _, _, err = snapshotstate.SaveIncremental(st, 12, []string{"a-snap", "b-snap"}, nil)
+	c.Check(err, check.ErrorMatches, `cannot save snapshot of "b-snap" relative to snapshot set #12: the set has no snapshot of it`)
+
+	_, _, _, err = snapshotstate.SaveIncremental(st, 20, nil, nil)
+	c.Check(err, check.ErrorMatches, `cannot save snapshots relative to snapshot set #20: it is an automatic snapshot`)
+}
+
 func (snapshotSuite) TestSaveIntegration(c *check.C) {
 	if os.Geteuid() == 0 {
 		c.Skip("this test cannot run as root (runuser will fail)")
@@ -932,6 +1014,60 @@ func (snapshotSuite) TestRestoreWorksWithCompatibleEpoch(c *check.C) {
 	})
 }
 
+func (snapshotSuite) TestRestoreWithHooks(c *check.C) {
+	shotfile, err := os.Create(filepath.Join(c.MkDir(), "yadda.zip"))
+	c.Assert(err, check.IsNil)
+	defer shotfile.Close()
+
+	sideInfo := &snap.SideInfo{RealName: "a-snap", Revision: snap.R(1)}
+	fakeSnapstateAll := func(*state.State) (map[string]*snapstate.SnapState, error) {
+		return map[string]*snapstate.SnapState{
+			"a-snap": {
+				Active:   true,
+				Sequence: []*snap.SideInfo{sideInfo},
+				Current:  sideInfo.Revision,
+			},
+		}, nil
+	}
+	defer snapshotstate.MockSnapstateAll(fakeSnapstateAll)()
+	snaptest.MockSnap(c, "{name: a-snap, version: v1, hooks: {pre-restore: null, post-restore: null}}", sideInfo)
+
+	fakeIter := func(_ context.Context, f func(*backend.Reader) error) error {
+		c.Assert(f(&backend.Reader{
+			Snapshot: client.Snapshot{SetID: 42, Snap: "a-snap"},
+			File:     shotfile,
+		}), check.IsNil)
+
+		return nil
+	}
+	defer snapshotstate.MockBackendIter(fakeIter)()
+
+	st := state.New(nil)
+	st.Lock()
+	defer st.Unlock()
+
+	found, taskset, err := snapshotstate.Restore(st, 42, nil, nil)
+	c.Assert(err, check.IsNil)
+	c.Check(found, check.DeepEquals, []string{"a-snap"})
+	tasks := taskset.Tasks()
+	c.Assert(tasks, check.HasLen, 3)
+
+	c.Check(tasks[0].Kind(), check.Equals, "run-hook")
+	c.Check(tasks[0].Summary(), check.Equals, `Run pre-restore hook of "a-snap" snap if present`)
+	var hooksup hookstate.HookSetup
+	c.Assert(tasks[0].Get("hook-setup", &hooksup), check.IsNil)
+	c.Check(hooksup, check.DeepEquals, hookstate.HookSetup{Snap: "a-snap", Hook: "pre-restore", Optional: true})
+
+	c.Check(tasks[1].Kind(), check.Equals, "restore-snapshot")
+	c.Check(tasks[1].WaitTasks(), check.DeepEquals, []*state.Task{tasks[0]})
+
+	c.Check(tasks[2].Kind(), check.Equals, "run-hook")
+	c.Check(tasks[2].Summary(), check.Equals, `Run post-restore hook of "a-snap" snap if present`)
+	c.Assert(tasks[2].Get("hook-setup", &hooksup), check.IsNil)
+	c.Check(hooksup, check.DeepEquals, hookstate.HookSetup{Snap: "a-snap", Hook: "post-restore", Optional: true, IgnoreError: true})
+	c.Check(tasks[2].WaitTasks(), check.DeepEquals, []*state.Task{tasks[1]})
+}
+
 func (snapshotSuite) TestRestore(c *check.C) {
 	shotfile, err := os.Create(filepath.Join(c.MkDir(), "yadda.zip"))
 	c.Assert(err, check.IsNil)
@@ -1348,6 +1484,36 @@ func (snapshotSuite) TestForget(c *check.C) {
 	})
 }
 
+func (snapshotSuite) TestForgetChecksIncrementalSnapshots(c *check.C) {
+	shotfile, err := os.Create(filepath.Join(c.MkDir(), "yadda.zip"))
+	c.Assert(err, check.IsNil)
+	defer shotfile.Close()
+	fakeIter := func(_ context.Context, f func(*backend.Reader) error) error {
+		for _, sh := range []client.Snapshot{
+			{SetID: 42, Snap: "a-snap"},
+			{SetID: 42, Snap: "b-snap"},
+			{SetID: 43, Snap: "b-snap", Parent: 42},
+		} {
+			if err := f(&backend.Reader{Snapshot: sh, File: shotfile}); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	defer snapshotstate.MockBackendIter(fakeIter)()
+
+	st := state.New(nil)
+	st.Lock()
+	defer st.Unlock()
+
+	_, _, err = snapshotstate.Forget(st, 42, nil)
+	c.Check(err, check.ErrorMatches, `cannot forget the snapshot of "b-snap" in snapshot set #42: the one in snapshot set #43 was saved relative to it`)
+
+	found, _, err := snapshotstate.Forget(st, 42, []string{"a-snap"})
+	c.Assert(err, check.IsNil)
+	c.Check(found, check.DeepEquals, []string{"a-snap"})
+}
+
 func (snapshotSuite) TestSaveExpiration(c *check.C) {
 	st := state.New(nil)
 	st.Lock()
@@ -1454,6 +1620,131 @@ func (snapshotSuite) TestAutomaticSnapshot(c *check.C) {
 	})
 }
 
+func (snapshotSuite) TestMigrationSnapshot(c *check.C) {
+	st := state.New(nil)
+	st.Lock()
+	defer st.Unlock()
+
+	// taken even with automatic snapshots disabled
+	tr := config.NewTransaction(st)
+	tr.Set("core", "snapshots.automatic.retention", "no")
+	tr.Commit()
+
+	setID, ts, err := snapshotstate.MigrationSnapshot(st, "foo")
+	c.Assert(err, check.IsNil)
+	c.Check(setID, check.Equals, uint64(1))
+
+	tasks := ts.Tasks()
+	c.Assert(tasks, check.HasLen, 1)
+	c.Check(tasks[0].Kind(), check.Equals, "save-snapshot")
+	c.Check(tasks[0].Summary(), check.Equals, `Save data of snap "foo" before migration in snapshot set #1`)
+	var snapshot map[string]interface{}
+	c.Check(tasks[0].Get("snapshot-setup", &snapshot), check.IsNil)
+	c.Check(snapshot, check.DeepEquals, map[string]interface{}{
+		"set-id":          1.,
+		"snap":            "foo",
+		"current":         "unset",
+		"auto":            true,
+		"restore-on-undo": true,
+	})
+}
+
+func (snapshotSuite) TestRestoreMigrationSnapshot(c *check.C) {
+	shotfile, err := os.Create(filepath.Join(c.MkDir(), "yadda.zip"))
+	c.Assert(err, check.IsNil)
+	defer shotfile.Close()
+	fakeIter := func(_ context.Context, f func(*backend.Reader) error) error {
+		c.Assert(f(&backend.Reader{
+			Snapshot: client.Snapshot{SetID: 42, Snap: "a-snap"},
+			File:     shotfile,
+		}), check.IsNil)
+
+		return nil
+	}
+	defer snapshotstate.MockBackendIter(fakeIter)()
+
+	st := state.New(nil)
+	st.Lock()
+	defer st.Unlock()
+
+	ts, err := snapshotstate.RestoreMigrationSnapshot(st, "a-snap", 42, snap.R(7))
+	c.Assert(err, check.IsNil)
+	tasks := ts.Tasks()
+	c.Assert(tasks, check.HasLen, 1)
+	c.Check(tasks[0].Kind(), check.Equals, "restore-snapshot")
+	c.Check(tasks[0].Summary(), check.Equals, `Restore data of snap "a-snap" saved before migration from snapshot set #42`)
+	var snapshot map[string]interface{}
+	c.Check(tasks[0].Get("snapshot-setup", &snapshot), check.IsNil)
+	c.Check(snapshot, check.DeepEquals, map[string]interface{}{
+		"set-id":   42.,
+		"snap":     "a-snap",
+		"filename": shotfile.Name(),
+		"current":  "7",
+	})
+
+	// the snapshot expired
+	ts, err = snapshotstate.RestoreMigrationSnapshot(st, "a-snap", 43, snap.R(7))
+	c.Assert(err, check.IsNil)
+	c.Check(ts, check.IsNil)
+}
+
+func (snapshotSuite) TestKeepDataSnapshot(c *check.C) {
+	st := state.New(nil)
+	st.Lock()
+	defer st.Unlock()
+
+	// taken even with automatic snapshots disabled
+	tr := config.NewTransaction(st)
+	tr.Set("core", "snapshots.automatic.retention", "no")
+	tr.Commit()
+
+	ts, err := snapshotstate.KeepDataSnapshot(st, "foo", 48*time.Hour)
+	c.Assert(err, check.IsNil)
+
+	tasks := ts.Tasks()
+	c.Assert(tasks, check.HasLen, 1)
+	c.Check(tasks[0].Kind(), check.Equals, "save-snapshot")
+	c.Check(tasks[0].Summary(), check.Equals, `Save data of snap "foo" in snapshot set #1 kept for 48h0m0s`)
+	var snapshot map[string]interface{}
+	c.Check(tasks[0].Get("snapshot-setup", &snapshot), check.IsNil)
+	c.Check(snapshot, check.DeepEquals, map[string]interface{}{
+		"set-id":   1.,
+		"snap":     "foo",
+		"current":  "unset",
+		"auto":     true,
+		"keep-for": float64(48 * time.Hour),
+	})
+}
+
+func (snapshotSuite) TestKeptDataSnapshot(c *check.C) {
+	st := state.New(nil)
+	st.Lock()
+	defer st.Unlock()
+
+	setID, _, err := snapshotstate.KeptDataSnapshot(st, "foo")
+	c.Assert(err, check.IsNil)
+	c.Check(setID, check.Equals, uint64(0))
+
+	future := time.Now().Add(time.Hour).Truncate(time.Second)
+	c.Assert(snapshotstate.SaveKeptData(st, 10, "foo", future.Add(-time.Minute)), check.IsNil)
+	c.Assert(snapshotstate.SaveKeptData(st, 11, "foo", future), check.IsNil)
+	// expired
+	c.Assert(snapshotstate.SaveKeptData(st, 12, "foo", time.Now().Add(-time.Hour)), check.IsNil)
+	// other snap
+	c.Assert(snapshotstate.SaveKeptData(st, 13, "bar", future), check.IsNil)
+	// plain automatic snapshot
+	c.Assert(snapshotstate.SaveExpiration(st, 14, future), check.IsNil)
+
+	setID, expiry, err := snapshotstate.KeptDataSnapshot(st, "foo")
+	c.Assert(err, check.IsNil)
+	c.Check(setID, check.Equals, uint64(11))
+	c.Check(expiry.Equal(future), check.Equals, true)
+
+	setID, _, err = snapshotstate.KeptDataSnapshot(st, "baz")
+	c.Assert(err, check.IsNil)
+	c.Check(setID, check.Equals, uint64(0))
+}
+
 func (snapshotSuite) TestAutomaticSnapshotDefaultClassic(c *check.C) {
 	release.MockOnClassic(true)
 
@@ -22,10 +22,12 @@ package snapstate
 import (
 	"encoding/json"
 	"fmt"
+	"sort"
 	"strings"
 
 	"github.com/snapcore/snapd/i18n"
 	"github.com/snapcore/snapd/logger"
+	"github.com/snapcore/snapd/overlord/configstate/config"
 	"github.com/snapcore/snapd/overlord/snapstate/backend"
 	"github.com/snapcore/snapd/overlord/state"
 	"github.com/snapcore/snapd/snap"
@@ -352,6 +354,142 @@ func checkAliasesConflicts(st *state.State, snapName string, candAutoDisabled bo
 	return nil, nil
 }
 
+// Policies to resolve conflicts of automatic aliases against the
+// enabled aliases of other snaps, configured via the core
+// aliases.conflict-policy option.
+const (
+	// AliasConflictFail fails the operation (the default).
+	AliasConflictFail = "fail"
+	// AliasConflictPreferInstalled keeps the aliases of the installed
+	// snaps and leaves the conflicting automatic aliases out.
+	AliasConflictPreferInstalled = "prefer-installed"
+	// AliasConflictPreferNew disables the aliases of the conflicting
+	// snaps, as "snap prefer" would.
+	AliasConflictPreferNew = "prefer-new"
+)
+
+// aliasConflictPolicy returns the configured policy to resolve
+// conflicts of automatic aliases.
+func aliasConflictPolicy(st *state.State) string {
+	tr := config.NewTransaction(st)
+
+	var policy string
+	err := tr.Get("core", "aliases.conflict-policy", &policy)
+	if err != nil || policy == "" {
+		return AliasConflictFail
+	}
+	return policy
+}
+
+// resolveAliasesConflicts checks candAliases considering
+// candAutoDisabled for conflicts against other snap aliases and
+// resolves conflicts of automatic aliases according to policy. It
+// returns the aliases to set for snapName and, with prefer-new, the
+// conflicting snaps and aliases whose snaps need their aliases disabled.
+func resolveAliasesConflicts(st *state.State, snapName string, candAutoDisabled bool, candAliases map[string]*AliasTarget, policy string) (newAliases map[string]*AliasTarget, disableOthers map[string][]string, err error) {
+	conflicts, err := checkAliasesConflicts(st, snapName, candAutoDisabled, candAliases, nil)
+	conflErr, isConflErr := err.(*AliasConflictError)
+	if !isConflErr || conflErr.Conflicts == nil {
+		// no conflicts, other errors or a snap command namespace
+		// conflict which we cannot remedy
+		return candAliases, nil, err
+	}
+
+	switch policy {
+	case AliasConflictPreferInstalled:
+		newAliases = make(map[string]*AliasTarget, len(candAliases))
+		for alias, target := range candAliases {
+			newAliases[alias] = target
+		}
+		var dropped []string
+		for _, aliases := range conflicts {
+			for _, alias := range aliases {
+				if target := newAliases[alias]; target != nil && target.Manual != "" {
+					// manual aliases were explicitly asked for
+					return nil, nil, conflErr
+				}
+				delete(newAliases, alias)
+				dropped = append(dropped, alias)
+			}
+		}
+		sort.Strings(dropped)
+		logger.Noticef("not enabling automatic aliases %s for %q, they conflict with aliases of installed snaps", strutil.Quoted(dropped), snapName)
+		return newAliases, nil, nil
+	case AliasConflictPreferNew:
+		return candAliases, conflicts, nil
+	}
+	return nil, nil, conflErr
+}
+
+// AliasesPreview describes the changes to the automatic aliases of a
+// snap that a refresh would make.
+type AliasesPreview struct {
+	// Added lists the automatic aliases that would be added or
+	// retargeted.
+	Added []string `json:"added,omitempty"`
+	// Removed lists the automatic aliases that would be dropped.
+	Removed []string `json:"removed,omitempty"`
+	// Conflicts maps other snaps to their enabled aliases the added
+	// aliases conflict with.
+	Conflicts map[string][]string `json:"conflicts,omitempty"`
+	// Policy is the configured aliases.conflict-policy that would be
+	// used to resolve the conflicts.
+	Policy string `json:"policy,omitempty"`
+}
+
+// PreviewAutoAliasesRefresh returns by snap name the changes to the
+// automatic aliases a refresh of the installed snaps with the given
+// names (or all if names is empty) would make based on their current
+// snap declarations, without changing anything.
+func PreviewAutoAliasesRefresh(st *state.State, names []string) (map[string]*AliasesPreview, error) {
+	changed, dropped, err := autoAliasesDelta(st, names)
+	if err != nil {
+		return nil, err
+	}
+	policy := aliasConflictPolicy(st)
+
+	preview := make(map[string]*AliasesPreview, len(changed)+len(dropped))
+	for instanceName, aliases := range changed {
+		var snapst SnapState
+		if err := Get(st, instanceName, &snapst); err != nil {
+			return nil, err
+		}
+		info, err := snapst.CurrentInfo()
+		if err != nil {
+			return nil, err
+		}
+		newAliases, err := refreshAliases(st, info, snapst.Aliases)
+		if err != nil {
+			return nil, err
+		}
+		p := &AliasesPreview{Added: aliases}
+		sort.Strings(p.Added)
+		conflicts, err := checkAliasesConflicts(st, instanceName, snapst.AutoAliasesDisabled, newAliases, nil)
+		if _, ok := err.(*AliasConflictError); ok {
+			if len(conflicts) == 0 {
+				// snap command namespace conflicts cannot be
+				// resolved, report them as is
+				return nil, err
+			}
+			p.Conflicts = conflicts
+			p.Policy = policy
+		} else if err != nil {
+			return nil, err
+		}
+		preview[instanceName] = p
+	}
+	for instanceName, aliases := range dropped {
+		p := preview[instanceName]
+		if p == nil {
+			p = &AliasesPreview{}
+			preview[instanceName] = p
+		}
+		p.Removed = aliases
+		sort.Strings(p.Removed)
+	}
+	return preview, nil
+}
+
 // checkSnapAliasConflict checks whether instanceName and its command
 // namepsace conflicts against installed snap aliases.
 func checkSnapAliasConflict(st *state.State, instanceName string) error {
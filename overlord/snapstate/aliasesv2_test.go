@@ -26,6 +26,7 @@ import (
 
 	. "gopkg.in/check.v1"
 
+	"github.com/snapcore/snapd/overlord/configstate/config"
 	"github.com/snapcore/snapd/overlord/snapstate"
 	"github.com/snapcore/snapd/overlord/snapstate/backend"
 	"github.com/snapcore/snapd/overlord/state"
@@ -185,6 +186,65 @@ func (s *snapmgrTestSuite) TestAutoAliasesDelta(c *C) {
 	})
 }
 
+func (s *snapmgrTestSuite) TestPreviewAutoAliasesRefresh(c *C) {
+	snapstate.AutoAliases = func(st *state.State, info *snap.Info) (map[string]string, error) {
+		if info.InstanceName() == "alias-snap" {
+			return map[string]string{
+				"alias1": "cmd1",
+				"alias4": "cmd4",
+				"alias5": "cmd5",
+			}, nil
+		}
+		return map[string]string{"alias4": "cmd4"}, nil
+	}
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	tr := config.NewTransaction(s.state)
+	tr.Set("core", "aliases.conflict-policy", "prefer-new")
+	tr.Commit()
+
+	snapstate.Set(s.state, "alias-snap", &snapstate.SnapState{
+		Sequence: []*snap.SideInfo{
+			{RealName: "alias-snap", Revision: snap.R(11)},
+		},
+		Current: snap.R(11),
+		Active:  true,
+		Aliases: map[string]*snapstate.AliasTarget{
+			"alias1": {Auto: "cmd1"},
+			"alias3": {Auto: "cmd3"},
+		},
+	})
+	snapstate.Set(s.state, "other-snap", &snapstate.SnapState{
+		Sequence: []*snap.SideInfo{
+			{RealName: "other-snap", Revision: snap.R(2)},
+		},
+		Current: snap.R(2),
+		Active:  true,
+		Aliases: map[string]*snapstate.AliasTarget{
+			"alias4": {Auto: "cmd4"},
+		},
+	})
+
+	preview, err := snapstate.PreviewAutoAliasesRefresh(s.state, []string{"alias-snap"})
+	c.Assert(err, IsNil)
+	c.Check(preview, DeepEquals, map[string]*snapstate.AliasesPreview{
+		"alias-snap": {
+			Added:     []string{"alias4", "alias5"},
+			Removed:   []string{"alias3"},
+			Conflicts: map[string][]string{"other-snap": {"alias4"}},
+			Policy:    "prefer-new",
+		},
+	})
+
+	// nothing was changed
+	var snapst snapstate.SnapState
+	err = snapstate.Get(s.state, "alias-snap", &snapst)
+	c.Assert(err, IsNil)
+	c.Check(snapst.Aliases, HasLen, 2)
+}
+
 func (s *snapmgrTestSuite) TestAutoAliasesDeltaAll(c *C) {
 	seen := make(map[string]bool)
 	snapstate.AutoAliases = func(st *state.State, info *snap.Info) (map[string]string, error) {
@@ -0,0 +1,119 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2019 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package snapstate
+
+import (
+	"time"
+
+	"github.com/snapcore/snapd/logger"
+	"github.com/snapcore/snapd/overlord/auth"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/store"
+)
+
+var (
+	// userAuthRefreshInterval is how often the expiry of the store
+	// credentials of the users is checked
+	userAuthRefreshInterval = time.Hour
+	// userAuthRefreshAhead is how long ahead of their expiry the store
+	// credentials of the users get refreshed
+	userAuthRefreshAhead = 24 * time.Hour
+)
+
+// userAuthRefresh proactively refreshes the store discharge macaroons of
+// the users before they expire, so that a later refresh does not fail
+// with an opaque authorization error.
+type userAuthRefresh struct {
+	state *state.State
+
+	nextCheck time.Time
+}
+
+func newUserAuthRefresh(st *state.State) *userAuthRefresh {
+	return &userAuthRefresh{state: st}
+}
+
+// Ensure will ensure that the store credentials of the users are
+// refreshed ahead of their expiry.
+func (r *userAuthRefresh) Ensure() error {
+	r.state.Lock()
+	defer r.state.Unlock()
+
+	// sneakily don't do anything if in testing
+	if CanAutoRefresh == nil {
+		return nil
+	}
+
+	now := time.Now()
+	if now.Before(r.nextCheck) {
+		return nil
+	}
+	r.nextCheck = now.Add(userAuthRefreshInterval)
+
+	users, err := auth.Users(r.state)
+	if err != nil {
+		return err
+	}
+
+	// degradation is recomputed on every check so that it clears
+	// itself once the user logs in again
+	degraded := make(map[int]string)
+	for _, user := range users {
+		if !user.HasStoreAuth() {
+			continue
+		}
+		expiry, ok, err := store.DischargesExpiry(user.StoreDischarges)
+		if err != nil {
+			degraded[user.ID] = err.Error()
+			continue
+		}
+		if !ok || expiry.Sub(now) > userAuthRefreshAhead {
+			continue
+		}
+
+		logger.Debugf("Refreshing store credentials of user %q expiring at %s.", user.Username, expiry.Format(time.RFC3339))
+		theStore := Store(r.state, nil)
+		r.state.Unlock()
+		err = theStore.RefreshUserAuth(user)
+		r.state.Lock()
+		if err != nil {
+			degraded[user.ID] = err.Error()
+			r.state.Warnf("cannot refresh store credentials of user %q, log in again with \"snap login\": %v", user.Username, err)
+		}
+	}
+
+	if len(degraded) == 0 {
+		r.state.Set("store-auth-degraded", nil)
+	} else {
+		r.state.Set("store-auth-degraded", degraded)
+	}
+	return nil
+}
+
+// StoreAuthDegraded returns whether the store credentials of some users
+// could not be refreshed and will need them to log in again.
+func StoreAuthDegraded(st *state.State) bool {
+	var degraded map[int]string
+	if err := st.Get("store-auth-degraded", &degraded); err != nil && err != state.ErrNoState {
+		logger.Noticef("cannot get store credentials status: %v", err)
+		return false
+	}
+	return len(degraded) > 0
+}
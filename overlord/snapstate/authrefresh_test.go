@@ -0,0 +1,128 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2019 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package snapstate_test
+
+import (
+	"errors"
+	"time"
+
+	. "gopkg.in/check.v1"
+	"gopkg.in/macaroon.v1"
+
+	"github.com/snapcore/snapd/overlord/auth"
+	"github.com/snapcore/snapd/overlord/snapstate"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/store"
+	"github.com/snapcore/snapd/store/storetest"
+)
+
+type authRefreshStore struct {
+	storetest.Store
+
+	refreshed []string
+	err       error
+}
+
+func (r *authRefreshStore) RefreshUserAuth(user *auth.UserState) error {
+	r.refreshed = append(r.refreshed, user.Username)
+	return r.err
+}
+
+type userAuthRefreshTestSuite struct {
+	state *state.State
+	store *authRefreshStore
+}
+
+var _ = Suite(&userAuthRefreshTestSuite{})
+
+func (s *userAuthRefreshTestSuite) SetUpTest(c *C) {
+	s.state = state.New(nil)
+
+	s.store = &authRefreshStore{}
+	s.state.Lock()
+	snapstate.ReplaceStore(s.state, s.store)
+	s.state.Unlock()
+
+	snapstate.CanAutoRefresh = func(*state.State) (bool, error) { return true, nil }
+}
+
+func (s *userAuthRefreshTestSuite) TearDownTest(c *C) {
+	snapstate.CanAutoRefresh = nil
+}
+
+func (s *userAuthRefreshTestSuite) addUser(c *C, username string, expires time.Time) {
+	m, err := macaroon.New([]byte("shared-key"), "third-party-caveat", store.UbuntuoneLocation)
+	c.Assert(err, IsNil)
+	c.Assert(m.AddFirstPartyCaveat(store.UbuntuoneLocation+"|expires|"+expires.UTC().Format(time.RFC3339)), IsNil)
+	discharge, err := auth.MacaroonSerialize(m)
+	c.Assert(err, IsNil)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	_, err = auth.NewUser(s.state, username, username+"@example.com", "macaroon", []string{discharge})
+	c.Assert(err, IsNil)
+}
+
+func (s *userAuthRefreshTestSuite) TestRefreshesExpiringOnly(c *C) {
+	s.addUser(c, "soon", time.Now().Add(time.Hour))
+	s.addUser(c, "later", time.Now().Add(30*24*time.Hour))
+
+	r := snapstate.NewUserAuthRefresh(s.state)
+	c.Assert(r.Ensure(), IsNil)
+	c.Check(s.store.refreshed, DeepEquals, []string{"soon"})
+
+	s.state.Lock()
+	c.Check(snapstate.StoreAuthDegraded(s.state), Equals, false)
+	c.Check(s.state.AllWarnings(), HasLen, 0)
+	s.state.Unlock()
+
+	// not checked again until the next check is due
+	c.Assert(r.Ensure(), IsNil)
+	c.Check(s.store.refreshed, HasLen, 1)
+
+	snapstate.MockUserAuthRefreshNextCheck(r, time.Now().Add(-time.Minute))
+	c.Assert(r.Ensure(), IsNil)
+	c.Check(s.store.refreshed, DeepEquals, []string{"soon", "soon"})
+}
+
+func (s *userAuthRefreshTestSuite) TestRefreshFailureSurfaced(c *C) {
+	s.addUser(c, "soon", time.Now().Add(time.Hour))
+	s.store.err = errors.New("boom")
+
+	r := snapstate.NewUserAuthRefresh(s.state)
+	c.Assert(r.Ensure(), IsNil)
+	c.Check(s.store.refreshed, DeepEquals, []string{"soon"})
+
+	s.state.Lock()
+	c.Check(snapstate.StoreAuthDegraded(s.state), Equals, true)
+	warns := s.state.AllWarnings()
+	c.Assert(warns, HasLen, 1)
+	c.Check(warns[0].String(), Equals, `cannot refresh store credentials of user "soon", log in again with "snap login": boom`)
+	s.state.Unlock()
+
+	// a later successful refresh clears the degradation
+	s.store.err = nil
+	snapstate.MockUserAuthRefreshNextCheck(r, time.Now().Add(-time.Minute))
+	c.Assert(r.Ensure(), IsNil)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	c.Check(snapstate.StoreAuthDegraded(s.state), Equals, false)
+}
@@ -72,6 +72,29 @@ func newAutoRefresh(st *state.State) *autoRefresh {
 	}
 }
 
+// autoRefreshSnapshot holds the in memory state of autoRefresh across
+// the restarts of snapd when it goes into socket activation mode, so
+// that e.g. the backoff of a failed refresh attempt is not lost.
+type autoRefreshSnapshot struct {
+	LastRefreshSchedule string    `json:"last-refresh-schedule,omitempty"`
+	NextRefresh         time.Time `json:"next-refresh"`
+	LastRefreshAttempt  time.Time `json:"last-refresh-attempt"`
+}
+
+func (m *autoRefresh) snapshot() *autoRefreshSnapshot {
+	return &autoRefreshSnapshot{
+		LastRefreshSchedule: m.lastRefreshSchedule,
+		NextRefresh:         m.nextRefresh,
+		LastRefreshAttempt:  m.lastRefreshAttempt,
+	}
+}
+
+func (m *autoRefresh) restore(snapshot *autoRefreshSnapshot) {
+	m.lastRefreshSchedule = snapshot.LastRefreshSchedule
+	m.nextRefresh = snapshot.NextRefresh
+	m.lastRefreshAttempt = snapshot.LastRefreshAttempt
+}
+
 // RefreshSchedule will return a user visible string with the current schedule
 // for the automatic refreshes and a flag indicating whether the schedule is a
 // legacy one.
@@ -155,6 +178,37 @@ func (m *autoRefresh) AtSeed() error {
 	return nil
 }
 
+// refreshBlackoutEnd returns the time at which the blackout window
+// covering now, if any, configured via refresh.blackout ends. Days are
+// taken in the refresh.timezone offset, or local time if unset.
+func (m *autoRefresh) refreshBlackoutEnd(now time.Time) (time.Time, error) {
+	var blackoutStr, timezoneStr string
+	tr := config.NewTransaction(m.state)
+	if err := tr.Get("core", "refresh.blackout", &blackoutStr); err != nil && !config.IsNoOption(err) {
+		return time.Time{}, err
+	}
+	if blackoutStr == "" {
+		return time.Time{}, nil
+	}
+	if err := tr.Get("core", "refresh.timezone", &timezoneStr); err != nil && !config.IsNoOption(err) {
+		return time.Time{}, err
+	}
+
+	windows, err := timeutil.ParseBlackoutWindows(blackoutStr)
+	if err != nil {
+		return time.Time{}, err
+	}
+	loc := time.Local
+	if timezoneStr != "" {
+		loc, err = timeutil.ParseTimezoneOffset(timezoneStr)
+		if err != nil {
+			return time.Time{}, err
+		}
+	}
+	_, end := timeutil.InBlackout(windows, now.In(loc))
+	return end, nil
+}
+
 func canRefreshOnMeteredConnection(st *state.State) (bool, error) {
 	tr := config.NewTransaction(st)
 	var onMetered string
@@ -274,6 +328,19 @@ func (m *autoRefresh) Ensure() error {
 
 	// do refresh attempt (if needed)
 	if !m.nextRefresh.After(now) {
+		// no refreshes during blackout windows
+		var blackoutEnd time.Time
+		blackoutEnd, err = m.refreshBlackoutEnd(now)
+		if err != nil {
+			return err
+		}
+		if !blackoutEnd.IsZero() {
+			delta := timeutil.Next(refreshSchedule, blackoutEnd, maxPostponement)
+			m.nextRefresh = time.Now().Add(delta)
+			logger.Debugf("Refresh blacked out until %s, next refresh scheduled for %s.", blackoutEnd.Format(time.RFC3339), m.nextRefresh.Format(time.RFC3339))
+			return nil
+		}
+
 		var can bool
 		can, err = m.canRefreshRespectingMetered(now, lastRefresh)
 		if err != nil {
@@ -649,3 +649,50 @@ func (s *autoRefreshTestSuite) TestRefreshOnMeteredConnNotMetered(c *C) {
 	c.Check(err, IsNil)
 	c.Check(s.store.ops, DeepEquals, []string{"list-refresh"})
 }
+
+func (s *autoRefreshTestSuite) TestRefreshBlackout(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	t0 := time.Now().UTC()
+	s.state.Set("last-refresh", t0.Add(-5*24*time.Hour))
+
+	tr := config.NewTransaction(s.state)
+	tr.Set("core", "refresh.blackout", t0.Format("2006-01-02"))
+	tr.Set("core", "refresh.timezone", "UTC")
+	tr.Commit()
+
+	af := snapstate.NewAutoRefresh(s.state)
+	s.state.Unlock()
+	err := af.Ensure()
+	s.state.Lock()
+	c.Check(err, IsNil)
+
+	// no refresh
+	c.Check(s.store.ops, HasLen, 0)
+
+	// next refresh is pushed past the end of the blackout
+	blackoutEnd := time.Date(t0.Year(), t0.Month(), t0.Day()+1, 0, 0, 0, 0, time.UTC)
+	c.Check(af.NextRefresh().Before(blackoutEnd), Equals, false)
+}
+
+func (s *autoRefreshTestSuite) TestRefreshBlackoutOver(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	t0 := time.Now().UTC()
+	s.state.Set("last-refresh", t0.Add(-5*24*time.Hour))
+
+	yesterday := t0.AddDate(0, 0, -1)
+	tr := config.NewTransaction(s.state)
+	tr.Set("core", "refresh.blackout", yesterday.Format("01-02"))
+	tr.Set("core", "refresh.timezone", "UTC")
+	tr.Commit()
+
+	af := snapstate.NewAutoRefresh(s.state)
+	s.state.Unlock()
+	err := af.Ensure()
+	s.state.Lock()
+	c.Check(err, IsNil)
+	c.Check(s.store.ops, DeepEquals, []string{"list-refresh"})
+}
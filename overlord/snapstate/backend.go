@@ -57,16 +57,20 @@ type StoreService interface {
 	CreateCohorts(context.Context, []string) (map[string]string, error)
 
 	LoginUser(username, password, otp string) (string, string, error)
+	LoginUserWithSecurityKey(username, password, response string) (string, string, error)
 	UserInfo(email string) (userinfo *store.User, err error)
+	RefreshUserAuth(user *auth.UserState) error
 }
 
 type managerBackend interface {
 	// install related
 	SetupSnap(snapFilePath, instanceName string, si *snap.SideInfo, meter progress.Meter) (snap.Type, error)
 	CopySnapData(newSnap, oldSnap *snap.Info, meter progress.Meter) error
-	LinkSnap(info *snap.Info, model *asserts.Model, tm timings.Measurer) error
+	LinkSnap(info *snap.Info, model *asserts.Model, disabledSvcs []string, tm timings.Measurer) error
 	StartServices(svcs []*snap.AppInfo, meter progress.Meter, tm timings.Measurer) error
 	StopServices(svcs []*snap.AppInfo, reason snap.ServiceStopReason, meter progress.Meter, tm timings.Measurer) error
+	QueryDisabledServices(info *snap.Info, meter progress.Meter) ([]string, error)
+	UpdateFontconfigCaches() error
 
 	// the undoers for install
 	UndoSetupSnap(s snap.PlaceInfo, typ snap.Type, meter progress.Meter) error
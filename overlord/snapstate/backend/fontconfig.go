@@ -21,8 +21,11 @@ package backend
 
 import (
 	"fmt"
+	"io/ioutil"
+	"strings"
 
 	"github.com/snapcore/snapd/cmd/cmdutil"
+	"github.com/snapcore/snapd/dirs"
 	"github.com/snapcore/snapd/osutil"
 )
 
@@ -42,3 +45,20 @@ func updateFontconfigCachesImpl() error {
 	}
 	return nil
 }
+
+// UpdateFontconfigCaches updates the fontconfig caches of the system.
+// It is used for app snaps whose caches were not updated while
+// linking them, see desktop.cache-generation.
+func (b Backend) UpdateFontconfigCaches() error {
+	return updateFontconfigCaches()
+}
+
+// DesktopCacheGeneration returns the system-wide desktop.cache-generation
+// mode as exported by the core configuration, or "" if it is not set.
+func DesktopCacheGeneration() string {
+	content, err := ioutil.ReadFile(dirs.SnapDesktopCacheGenerationFile)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(content))
+}
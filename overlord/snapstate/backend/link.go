@@ -79,14 +79,15 @@ func hasFontConfigCache(info *snap.Info) bool {
 }
 
 // LinkSnap makes the snap available by generating wrappers and setting the current symlinks.
-func (b Backend) LinkSnap(info *snap.Info, model *asserts.Model, tm timings.Measurer) (e error) {
+// The services named in disabledSvcs are left disabled.
+func (b Backend) LinkSnap(info *snap.Info, model *asserts.Model, disabledSvcs []string, tm timings.Measurer) (e error) {
 	if info.Revision.Unset() {
 		return fmt.Errorf("cannot link snap %q with unset revision", info.InstanceName())
 	}
 
 	var err error
 	timings.Run(tm, "generate-wrappers", fmt.Sprintf("generate wrappers for snap %s", info.InstanceName()), func(timings.Measurer) {
-		err = generateWrappers(info)
+		err = generateWrappers(info, disabledSvcs)
 	})
 	if err != nil {
 		return err
@@ -103,14 +104,21 @@ func (b Backend) LinkSnap(info *snap.Info, model *asserts.Model, tm timings.Meas
 	// fontconfig is only relevant on classic and is carried by 'core' or
 	// 'snapd' snaps
 	// for non-core snaps, fontconfig cache needs to be updated before the
-	// snap applications are runnable
+	// snap applications are runnable, unless the system is configured
+	// to do that after linking (with the update-desktop-caches task) or
+	// at launch time instead
 	if release.OnClassic && !hasFontConfigCache(info) {
-		timings.Run(tm, "update-fc-cache", "update font config caches", func(timings.Measurer) {
-			// XXX: does this need cleaning up? (afaict no)
-			if err := updateFontconfigCaches(); err != nil {
-				logger.Noticef("cannot update fontconfig cache: %v", err)
-			}
-		})
+		switch mode := DesktopCacheGeneration(); mode {
+		case "async", "launch":
+			logger.Debugf("not updating fontconfig cache for %q when linking, desktop.cache-generation is %q", info.InstanceName(), mode)
+		default:
+			timings.Run(tm, "update-fc-cache", "update font config caches", func(timings.Measurer) {
+				// XXX: does this need cleaning up? (afaict no)
+				if err := updateFontconfigCaches(); err != nil {
+					logger.Noticef("cannot update fontconfig cache: %v", err)
+				}
+			})
+		}
 	}
 
 	// XXX/TODO: this needs to be a task with proper undo and tests!
@@ -154,13 +162,18 @@ func (b Backend) StopServices(apps []*snap.AppInfo, reason snap.ServiceStopReaso
 	return wrappers.StopServices(apps, reason, meter, tm)
 }
 
-func generateWrappers(s *snap.Info) error {
+// QueryDisabledServices returns the names of the disabled services of the snap.
+func (b Backend) QueryDisabledServices(info *snap.Info, meter progress.Meter) ([]string, error) {
+	return wrappers.QueryDisabledServices(info, meter)
+}
+
+func generateWrappers(s *snap.Info, disabledSvcs []string) error {
 	// add the CLI apps from the snap.yaml
 	if err := wrappers.AddSnapBinaries(s); err != nil {
 		return err
 	}
 	// add the daemons from the snap.yaml
-	if err := wrappers.AddSnapServices(s, progress.Null); err != nil {
+	if err := wrappers.AddSnapServices(s, disabledSvcs, progress.Null); err != nil {
 		wrappers.RemoveSnapBinaries(s)
 		return err
 	}
@@ -80,7 +80,7 @@ apps:
 `
 	info := snaptest.MockSnap(c, yaml, &snap.SideInfo{Revision: snap.R(11)})
 
-	err := s.be.LinkSnap(info, nil, s.perfTimings)
+	err := s.be.LinkSnap(info, nil, nil, s.perfTimings)
 	c.Assert(err, IsNil)
 
 	l, err := filepath.Glob(filepath.Join(dirs.SnapBinariesDir, "*"))
@@ -102,6 +102,39 @@ apps:
 	c.Assert(l, HasLen, 0)
 }
 
+func (s *linkSuite) TestLinkSnapKeepsDisabledServicesDisabled(c *C) {
+	var sysdLog [][]string
+	r := systemd.MockSystemctl(func(cmd ...string) ([]byte, error) {
+		sysdLog = append(sysdLog, cmd)
+		return []byte("ActiveState=inactive\n"), nil
+	})
+	defer r()
+
+	const yaml = `name: hello
+version: 1.0
+
+apps:
+ svc:
+   command: svc
+   daemon: simple
+ other-svc:
+   command: svc
+   daemon: simple
+`
+	info := snaptest.MockSnap(c, yaml, &snap.SideInfo{Revision: snap.R(11)})
+
+	err := s.be.LinkSnap(info, nil, []string{"svc"}, s.perfTimings)
+	c.Assert(err, IsNil)
+
+	c.Check(sysdLog, DeepEquals, [][]string{
+		{"--root", dirs.GlobalRootDir, "enable", "snap.hello.other-svc.service"},
+		{"daemon-reload"},
+	})
+	l, err := filepath.Glob(filepath.Join(dirs.SnapServicesDir, "*.service"))
+	c.Assert(err, IsNil)
+	c.Check(l, HasLen, 2)
+}
+
 func (s *linkSuite) TestLinkDoUndoCurrentSymlink(c *C) {
 	const yaml = `name: hello
 version: 1.0
@@ -110,7 +143,7 @@ version: 1.0
 
 	info := snaptest.MockSnap(c, yaml, &snap.SideInfo{Revision: snap.R(11)})
 
-	err := s.be.LinkSnap(info, nil, s.perfTimings)
+	err := s.be.LinkSnap(info, nil, nil, s.perfTimings)
 	c.Assert(err, IsNil)
 
 	mountDir := info.MountDir()
@@ -152,10 +185,10 @@ apps:
 
 	info := snaptest.MockSnap(c, yaml, &snap.SideInfo{Revision: snap.R(11)})
 
-	err := s.be.LinkSnap(info, nil, s.perfTimings)
+	err := s.be.LinkSnap(info, nil, nil, s.perfTimings)
 	c.Assert(err, IsNil)
 
-	err = s.be.LinkSnap(info, nil, s.perfTimings)
+	err = s.be.LinkSnap(info, nil, nil, s.perfTimings)
 	c.Assert(err, IsNil)
 
 	l, err := filepath.Glob(filepath.Join(dirs.SnapBinariesDir, "*"))
@@ -194,7 +227,7 @@ apps:
 
 	info := snaptest.MockSnap(c, yaml, &snap.SideInfo{Revision: snap.R(11)})
 
-	err := s.be.LinkSnap(info, nil, s.perfTimings)
+	err := s.be.LinkSnap(info, nil, nil, s.perfTimings)
 	c.Assert(err, IsNil)
 
 	err = s.be.UnlinkSnap(info, progress.Null)
@@ -222,7 +255,7 @@ func (s *linkSuite) TestLinkFailsForUnsetRevision(c *C) {
 	info := &snap.Info{
 		SuggestedName: "foo",
 	}
-	err := s.be.LinkSnap(info, nil, s.perfTimings)
+	err := s.be.LinkSnap(info, nil, nil, s.perfTimings)
 	c.Assert(err, ErrorMatches, `cannot link snap "foo" with unset revision`)
 }
 
@@ -279,7 +312,7 @@ func (s *linkCleanupSuite) testLinkCleanupDirOnFail(c *C, dir string) {
 	c.Assert(os.Chmod(dir, 0), IsNil)
 	defer os.Chmod(dir, 0755)
 
-	err := s.be.LinkSnap(s.info, nil, s.perfTimings)
+	err := s.be.LinkSnap(s.info, nil, nil, s.perfTimings)
 	c.Assert(err, NotNil)
 	_, isPathError := err.(*os.PathError)
 	_, isLinkError := err.(*os.LinkError)
@@ -316,7 +349,7 @@ func (s *linkCleanupSuite) TestLinkCleanupOnSystemctlFail(c *C) {
 	})
 	defer r()
 
-	err := s.be.LinkSnap(s.info, nil, s.perfTimings)
+	err := s.be.LinkSnap(s.info, nil, nil, s.perfTimings)
 	c.Assert(err, ErrorMatches, "ouchie")
 
 	for _, d := range []string{dirs.SnapBinariesDir, dirs.SnapDesktopFilesDir, dirs.SnapServicesDir} {
@@ -336,7 +369,7 @@ func (s *linkCleanupSuite) TestLinkCleansUpDataDirAndSymlinksOnSymlinkFail(c *C)
 	c.Assert(os.Chmod(d, 0), IsNil)
 	defer os.Chmod(d, 0755)
 
-	err := s.be.LinkSnap(s.info, nil, s.perfTimings)
+	err := s.be.LinkSnap(s.info, nil, nil, s.perfTimings)
 	c.Assert(err, ErrorMatches, `(?i).*symlink.*permission denied.*`)
 
 	c.Check(s.info.DataDir(), testutil.FileAbsent)
@@ -359,7 +392,7 @@ func (s *linkCleanupSuite) TestLinkRunsUpdateFontconfigCachesClassic(c *C) {
 		})
 		defer restore()
 
-		err := s.be.LinkSnap(s.info, nil, s.perfTimings)
+		err := s.be.LinkSnap(s.info, nil, nil, s.perfTimings)
 		c.Assert(err, IsNil)
 		if onClassic {
 			c.Assert(updateFontconfigCaches, Equals, 1)
@@ -407,7 +440,7 @@ type: os
 	})
 	defer restore()
 
-	err = s.be.LinkSnap(infoNew, nil, s.perfTimings)
+	err = s.be.LinkSnap(infoNew, nil, nil, s.perfTimings)
 	c.Assert(err, IsNil)
 
 	c.Check(oldCmdV6.Calls(), HasLen, 0)
@@ -416,3 +449,36 @@ type: os
 	c.Check(newCmdV6.Calls(), HasLen, 1)
 	c.Check(newCmdV7.Calls(), HasLen, 1)
 }
+
+func (s *linkCleanupSuite) TestLinkUpdateFontconfigCachesDesktopCacheGeneration(c *C) {
+	restore := release.MockOnClassic(true)
+	defer restore()
+
+	current := filepath.Join(s.info.MountDir(), "..", "current")
+	c.Assert(os.MkdirAll(filepath.Dir(dirs.SnapDesktopCacheGenerationFile), 0755), IsNil)
+
+	for _, t := range []struct {
+		mode  string
+		calls int
+	}{
+		{"", 1},
+		{"install", 1},
+		// updated by the update-desktop-caches task instead
+		{"async", 0},
+		{"launch", 0},
+	} {
+		c.Assert(ioutil.WriteFile(dirs.SnapDesktopCacheGenerationFile, []byte(t.mode+"\n"), 0644), IsNil)
+
+		var updateFontconfigCaches int
+		restore = backend.MockUpdateFontconfigCaches(func() error {
+			updateFontconfigCaches += 1
+			return nil
+		})
+		defer restore()
+
+		err := s.be.LinkSnap(s.info, nil, nil, s.perfTimings)
+		c.Assert(err, IsNil)
+		c.Check(updateFontconfigCaches, Equals, t.calls, Commentf("mode %q", t.mode))
+		c.Assert(os.Remove(current), IsNil)
+	}
+}
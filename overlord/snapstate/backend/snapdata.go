@@ -236,7 +236,7 @@ func copySnapDataDirectory(oldPath, newPath string) (err error) {
 		}
 
 		if _, err := os.Stat(newPath); err != nil {
-			if err := osutil.CopyFile(oldPath, newPath, osutil.CopyFlagPreserveAll|osutil.CopyFlagSync); err != nil {
+			if err := osutil.CopyFile(oldPath, newPath, osutil.CopyFlagPreserveAll|osutil.CopyFlagSync|osutil.CopyFlagReflink); err != nil {
 				msg := fmt.Sprintf("cannot copy %q to %q: %v", oldPath, newPath, err)
 				// remove the directory, in case it was a partial success
 				if e := os.RemoveAll(newPath); e != nil && !os.IsNotExist(e) {
@@ -29,6 +29,7 @@ import (
 	"strings"
 	"sync"
 
+	"github.com/snapcore/snapd/arch"
 	"github.com/snapcore/snapd/asserts"
 	"github.com/snapcore/snapd/osutil"
 	"github.com/snapcore/snapd/overlord/auth"
@@ -37,6 +38,7 @@ import (
 	"github.com/snapcore/snapd/overlord/state"
 	"github.com/snapcore/snapd/progress"
 	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/snap/snaptest"
 	"github.com/snapcore/snapd/store"
 	"github.com/snapcore/snapd/store/storetest"
 	"github.com/snapcore/snapd/timings"
@@ -64,7 +66,8 @@ type fakeOp struct {
 
 	otherInstances bool
 
-	services []string
+	services         []string
+	disabledServices []string
 }
 
 type fakeOps []fakeOp
@@ -138,6 +141,12 @@ type fakeStore struct {
 	fakeTotalProgress   int
 	state               *state.State
 	seenPrivacyKeys     map[string]bool
+	// interruptDownloadAt, if set, makes the next resumable download
+	// fail after downloading that many bytes
+	interruptDownloadAt int64
+	// downloadInterrupted, if set, is called when interrupting a
+	// download
+	downloadInterrupted func()
 }
 
 func (f *fakeStore) pokeStateLock() {
@@ -213,6 +222,9 @@ func (f *fakeStore) snap(spec snapSpec, user *auth.UserState) (*snap.Info, error
 	if spec.Name == "snap-unknown" {
 		return nil, store.ErrSnapNotFound
 	}
+	if spec.Name == "some-snap-with-license" {
+		spec.Channel = "channel-for-license"
+	}
 
 	info := &snap.Info{
 		Architectures: []string{"all"},
@@ -240,6 +252,9 @@ func (f *fakeStore) snap(spec snapSpec, user *auth.UserState) (*snap.Info, error
 		info.SideInfo.Paid = true
 	case "channel-for-private":
 		info.SideInfo.Private = true
+	case "channel-for-license":
+		info.LicenseAgreement = "explicit"
+		info.LicenseVersion = "2"
 	case "channel-for-layout":
 		info.Layout = map[string]*snap.Layout{
 			"/usr": {
@@ -315,6 +330,18 @@ func (f *fakeStore) lookupRefresh(cand refreshCand) (*snap.Info, error) {
 		panic(fmt.Sprintf("refresh: unknown snap-id: %s", cand.snapID))
 	}
 
+	if cand.channel == "2.0/beta" {
+		// no revision for the device architecture in this channel
+		return nil, &store.RevisionNotAvailableError{
+			Action:  "refresh",
+			Channel: cand.channel,
+			Releases: []snap.Channel{
+				snaptest.MustParseChannel("2.0/beta", "some-other-arch"),
+				snaptest.MustParseChannel("2.0/candidate", arch.UbuntuArchitecture()),
+			},
+		}
+	}
+
 	revno := snap.R(11)
 	if r := f.refreshRevnos[cand.snapID]; !r.Unset() {
 		revno = r
@@ -504,7 +531,7 @@ func (f *fakeStore) SnapAction(ctx context.Context, currentSnaps []*store.Curren
 			revno:  hit,
 			userID: userID,
 		})
-		if err == store.ErrNoUpdateAvailable {
+		if _, ok := err.(*store.RevisionNotAvailableError); ok || err == store.ErrNoUpdateAvailable {
 			refreshErrors[cur.InstanceName] = err
 			continue
 		}
@@ -551,7 +578,19 @@ func (f *fakeStore) Download(ctx context.Context, name, targetFn string, snapInf
 	if user != nil {
 		macaroon = user.StoreMacaroon
 	}
+	var interrupted bool
+	if f.interruptDownloadAt > 0 && dlOpts.Resume != nil {
+		dlOpts.Resume.Offset = f.interruptDownloadAt
+		dlOpts.Resume.ETag = "etag"
+		f.interruptDownloadAt = 0
+		interrupted = true
+	}
 	// only add the options if they contain anything interesting
+	if dlOpts.Resume != nil && *dlOpts.Resume == (store.PartialDownload{}) {
+		opts := *dlOpts
+		opts.Resume = nil
+		dlOpts = &opts
+	}
 	if *dlOpts == (store.DownloadOptions{}) {
 		dlOpts = nil
 	}
@@ -563,6 +602,13 @@ func (f *fakeStore) Download(ctx context.Context, name, targetFn string, snapInf
 	})
 	f.fakeBackend.appendOp(&fakeOp{op: "storesvc-download", name: name})
 
+	if interrupted {
+		if f.downloadInterrupted != nil {
+			f.downloadInterrupted()
+		}
+		return fmt.Errorf("download interrupted")
+	}
+
 	pb.SetTotal(float64(f.fakeTotalProgress))
 	pb.Set(float64(f.fakeCurrentProgress))
 
@@ -605,6 +651,10 @@ type fakeSnappyBackend struct {
 	linkSnapFailTrigger     string
 	copySnapDataFailTrigger string
 	emptyContainer          snap.Container
+
+	// disabledServices are the services reported as disabled by
+	// QueryDisabledServices, by snap instance name
+	disabledServices map[string][]string
 }
 
 func (f *fakeSnappyBackend) OpenSnapFile(snapFilePath string, si *snap.SideInfo) (*snap.Info, snap.Container, error) {
@@ -793,7 +843,7 @@ func (f *fakeSnappyBackend) CopySnapData(newInfo, oldInfo *snap.Info, p progress
 	return nil
 }
 
-func (f *fakeSnappyBackend) LinkSnap(info *snap.Info, model *asserts.Model, tm timings.Measurer) error {
+func (f *fakeSnappyBackend) LinkSnap(info *snap.Info, model *asserts.Model, disabledSvcs []string, tm timings.Measurer) error {
 	if info.MountDir() == f.linkSnapWaitTrigger {
 		f.linkSnapWaitCh <- 1
 		<-f.linkSnapWaitCh
@@ -808,8 +858,9 @@ func (f *fakeSnappyBackend) LinkSnap(info *snap.Info, model *asserts.Model, tm t
 	}
 
 	f.appendOp(&fakeOp{
-		op:   "link-snap",
-		path: info.MountDir(),
+		op:               "link-snap",
+		path:             info.MountDir(),
+		disabledServices: disabledSvcs,
 	})
 	return nil
 }
@@ -845,6 +896,15 @@ func (f *fakeSnappyBackend) StopServices(svcs []*snap.AppInfo, reason snap.Servi
 	return nil
 }
 
+func (f *fakeSnappyBackend) UpdateFontconfigCaches() error {
+	f.appendOp(&fakeOp{op: "update-fontconfig-caches"})
+	return nil
+}
+
+func (f *fakeSnappyBackend) QueryDisabledServices(info *snap.Info, meter progress.Meter) ([]string, error) {
+	return f.disabledServices[info.InstanceName()], nil
+}
+
 func (f *fakeSnappyBackend) UndoSetupSnap(s snap.PlaceInfo, typ snap.Type, p progress.Meter) error {
 	p.Notify("setup-snap")
 	f.appendOp(&fakeOp{
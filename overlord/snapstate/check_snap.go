@@ -175,6 +175,18 @@ func validateFlagsForInfo(info *snap.Info, snapst *SnapState, flags Flags) error
 	}
 }
 
+// isCompatibleArchitecture returns true if any of the given
+// architectures can be run by the system, see
+// arch.IsCompatibleArchitecture.
+func isCompatibleArchitecture(architectures []string) bool {
+	for _, a := range architectures {
+		if arch.IsCompatibleArchitecture(arch.UbuntuArchitecture(), a) {
+			return true
+		}
+	}
+	return false
+}
+
 // do a reasonably lightweight check that a snap described by Info,
 // with the given SnapState and the user-specified Flags should be
 // installable on the current system.
@@ -184,7 +196,7 @@ func validateInfoAndFlags(info *snap.Info, snapst *SnapState, flags Flags) error
 	}
 
 	// verify we have a valid architecture
-	if !arch.IsSupportedArchitecture(info.Architectures) {
+	if !arch.IsSupportedArchitecture(info.Architectures) && !(flags.CompatibleArchitecture && isCompatibleArchitecture(info.Architectures)) {
 		return fmt.Errorf("snap %q supported architectures (%s) are incompatible with this system (%s)", info.InstanceName(), strings.Join(info.Architectures, ", "), arch.UbuntuArchitecture())
 	}
 
@@ -433,6 +445,51 @@ func earlyEpochCheck(info *snap.Info, snapst *SnapState) error {
 	return checkEpochs(nil, info, cur, Flags{}, nil)
 }
 
+// crossesEpoch returns whether refreshing the snap installed in the
+// system (via snapst) to info changes its epoch, in which case the
+// data of the snap needs migrating.
+func crossesEpoch(info *snap.Info, snapst *SnapState) (bool, error) {
+	if snapst == nil || !snapst.IsInstalled() {
+		return false, nil
+	}
+	cur, err := snapst.CurrentInfo()
+	if err != nil {
+		if err == ErrNoCurrent {
+			return false, nil
+		}
+		return false, err
+	}
+
+	return !info.Epoch.Equal(&cur.Epoch), nil
+}
+
+// effectiveBase returns the base the given snap runs on, which is
+// "core" for applications that declare none.
+func effectiveBase(info *snap.Info) string {
+	if info.Base == "" && info.GetType() == snap.TypeApp {
+		return defaultCoreSnapName
+	}
+	return info.Base
+}
+
+// crossesBase returns whether refreshing the application installed in
+// the system (via snapst) to info moves it to a different base, e.g.
+// from core18 to core22.
+func crossesBase(info *snap.Info, snapst *SnapState) (bool, error) {
+	if info.GetType() != snap.TypeApp || snapst == nil || !snapst.IsInstalled() {
+		return false, nil
+	}
+	cur, err := snapst.CurrentInfo()
+	if err != nil {
+		if err == ErrNoCurrent {
+			return false, nil
+		}
+		return false, err
+	}
+
+	return effectiveBase(info) != effectiveBase(cur), nil
+}
+
 func init() {
 	AddCheckSnapCallback(checkCoreName)
 	AddCheckSnapCallback(checkSnapdName)
@@ -90,6 +90,33 @@ architectures:
 	c.Assert(err.Error(), Equals, errorMsg)
 }
 
+func (s *checkSnapSuite) TestCheckSnapCompatibleArchitecture(c *C) {
+	oldArch := arch.UbuntuArchitecture()
+	arch.SetArchitecture("arm64")
+	defer arch.SetArchitecture(arch.ArchitectureType(oldArch))
+
+	const yaml = `name: hello
+version: 1.10
+architectures:
+    - armhf
+`
+	info, err := snap.InfoFromSnapYaml([]byte(yaml))
+	c.Assert(err, IsNil)
+
+	var openSnapFile = func(path string, si *snap.SideInfo) (*snap.Info, snap.Container, error) {
+		return info, emptyContainer(c), nil
+	}
+	restore := snapstate.MockOpenSnapFile(openSnapFile)
+	defer restore()
+
+	err = snapstate.CheckSnap(s.st, "snap-path", "hello", nil, nil, snapstate.Flags{}, nil)
+	c.Check(err, ErrorMatches, `snap "hello" supported architectures \(armhf\) are incompatible with this system \(arm64\)`)
+
+	// but it can be seeded as a compatible architecture
+	err = snapstate.CheckSnap(s.st, "snap-path", "hello", nil, nil, snapstate.Flags{CompatibleArchitecture: true}, nil)
+	c.Check(err, IsNil)
+}
+
 var assumesTests = []struct {
 	version string
 	assumes string
@@ -21,6 +21,7 @@ package snapstate
 
 import (
 	"context"
+	"syscall"
 	"time"
 
 	"github.com/snapcore/snapd/overlord/state"
@@ -119,6 +120,8 @@ var (
 	NewCatalogRefresh            = newCatalogRefresh
 	CatalogRefreshDelayBase      = catalogRefreshDelayBase
 	CatalogRefreshDelayWithDelta = catalogRefreshDelayWithDelta
+
+	NewUserAuthRefresh = newUserAuthRefresh
 )
 
 func MockNextRefresh(ar *autoRefresh, when time.Time) {
@@ -137,6 +140,10 @@ func NextCatalogRefresh(cr *catalogRefresh) time.Time {
 	return cr.nextCatalogRefresh
 }
 
+func MockUserAuthRefreshNextCheck(r *userAuthRefresh, when time.Time) {
+	r.nextCheck = when
+}
+
 func MockRefreshRetryDelay(d time.Duration) func() {
 	origRefreshRetryDelay := refreshRetryDelay
 	refreshRetryDelay = d
@@ -193,6 +200,24 @@ func MockReRefreshRetryTimeout(d time.Duration) (restore func()) {
 	}
 }
 
+var KillRunning = killRunning
+
+func MockSyscallKill(f func(pid int, sig syscall.Signal) error) (restore func()) {
+	old := syscallKill
+	syscallKill = f
+	return func() {
+		syscallKill = old
+	}
+}
+
+func MockKillRunningWait(wait, pollInterval time.Duration) (restore func()) {
+	oldWait, oldPollInterval := killRunningWait, killRunningPollInterval
+	killRunningWait, killRunningPollInterval = wait, pollInterval
+	return func() {
+		killRunningWait, killRunningPollInterval = oldWait, oldPollInterval
+	}
+}
+
 // aux store info
 var (
 	AuxStoreInfoFilename = auxStoreInfoFilename
@@ -68,6 +68,27 @@ type Flags struct {
 
 	// RequireTypeBase is set to mark that a snap needs to be of type: base, otherwise installation fails.
 	RequireTypeBase bool `json:"require-base-type,omitempty"`
+
+	// CompatibleArchitecture is set when seeding a snap picked for an
+	// architecture that the system can run but that is not its own
+	// (e.g. armhf userspace on arm64).
+	CompatibleArchitecture bool `json:"compatible-architecture,omitempty"`
+
+	// IgnoreRunning is set to refresh the snap regardless of its
+	// running apps and hooks.
+	IgnoreRunning bool `json:"ignore-running,omitempty"`
+
+	// KillRunning is set to terminate the running apps and hooks of
+	// the snap before refreshing it, instead of being blocked by them.
+	KillRunning bool `json:"kill-running,omitempty"`
+
+	// CheckTerms is set when installing a snap on behalf of a user,
+	// who must have accepted the license agreement of the snap.
+	CheckTerms bool `json:"check-terms,omitempty"`
+
+	// AcceptTerms is set when the user has accepted the license
+	// agreement of the snap being installed.
+	AcceptTerms bool `json:"accept-terms,omitempty"`
 }
 
 // DevModeAllowed returns whether a snap can be installed with devmode confinement (either set or overridden)
@@ -80,5 +101,8 @@ func (f Flags) ForSnapSetup() Flags {
 	f.SkipConfigure = false
 	f.NoReRefresh = false
 	f.RequireTypeBase = false
+	f.CompatibleArchitecture = false
+	f.CheckTerms = false
+	f.AcceptTerms = false
 	return f
 }
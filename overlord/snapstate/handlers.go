@@ -25,6 +25,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -515,6 +516,7 @@ func downloadSnapParams(st *state.State, t *state.Task) (*SnapSetup, StoreServic
 func (m *SnapManager) doDownloadSnap(t *state.Task, tomb *tomb.Tomb) error {
 	st := t.State()
 	var rate int64
+	var resume store.PartialDownload
 
 	st.Lock()
 	perfTimings := timings.NewForTask(t)
@@ -523,6 +525,13 @@ func (m *SnapManager) doDownloadSnap(t *state.Task, tomb *tomb.Tomb) error {
 		// NOTE rate is never negative
 		rate = autoRefreshRateLimited(st)
 	}
+	if err == nil {
+		// a previous run of the task might have been interrupted
+		err = t.Get("download-resume", &resume)
+		if err == state.ErrNoState {
+			err = nil
+		}
+	}
 	st.Unlock()
 	if err != nil {
 		return err
@@ -534,6 +543,7 @@ func (m *SnapManager) doDownloadSnap(t *state.Task, tomb *tomb.Tomb) error {
 	dlOpts := &store.DownloadOptions{
 		IsAutoRefresh: snapsup.IsAutoRefresh,
 		RateLimit:     rate,
+		Resume:        &resume,
 	}
 	if snapsup.DownloadInfo == nil {
 		var storeInfo *snap.Info
@@ -554,6 +564,21 @@ func (m *SnapManager) doDownloadSnap(t *state.Task, tomb *tomb.Tomb) error {
 		})
 	}
 	if err != nil {
+		st.Lock()
+		defer st.Unlock()
+		switch {
+		case t.Status() == state.AbortStatus:
+			// the change got aborted, the download will not
+			// be resumed
+			if rerr := os.Remove(targetFn + ".partial"); rerr != nil && !os.IsNotExist(rerr) {
+				logger.Noticef("cannot remove partial download of snap %q: %v", snapsup.InstanceName(), rerr)
+			}
+			t.Clear("download-resume")
+		case resume.Offset > 0:
+			// the download got interrupted (e.g. snapd is
+			// restarting), remember where to resume it from
+			t.Set("download-resume", &resume)
+		}
 		return err
 	}
 
@@ -562,6 +587,7 @@ func (m *SnapManager) doDownloadSnap(t *state.Task, tomb *tomb.Tomb) error {
 	// update the snap setup for the follow up tasks
 	st.Lock()
 	t.Set("snap-setup", snapsup)
+	t.Clear("download-resume")
 	perfTimings.Save(st)
 	st.Unlock()
 
@@ -758,7 +784,18 @@ func (m *SnapManager) doUnlinkCurrentSnap(t *state.Task, _ *tomb.Tomb) error {
 		return err
 	}
 
-	if experimentalRefreshAppAwareness {
+	if snapsup.KillRunning {
+		// the processes are given some time to exit, do not block
+		// the state meanwhile
+		st.Unlock()
+		err := killRunning(oldInfo)
+		st.Lock()
+		if err != nil {
+			return err
+		}
+	}
+
+	if experimentalRefreshAppAwareness && !snapsup.IgnoreRunning {
 		// A process may be created after the soft refresh done upon
 		// the request to refresh a snap. If such process is alive by
 		// the time this code is reached the refresh process is stopped.
@@ -813,7 +850,7 @@ func (m *SnapManager) undoUnlinkCurrentSnap(t *state.Task, _ *tomb.Tomb) error {
 	}
 
 	snapst.Active = true
-	err = m.backend.LinkSnap(oldInfo, model, perfTimings)
+	err = m.backend.LinkSnap(oldInfo, model, snapst.LastActiveDisabledServices, perfTimings)
 	if err != nil {
 		return err
 	}
@@ -1014,6 +1051,16 @@ func (m *SnapManager) doLinkSnap(t *state.Task, _ *tomb.Tomb) (err error) {
 		snapst.Required = true
 	}
 	oldRefreshInhibitedTime := snapst.RefreshInhibitedTime
+	oldDataMigration := snapst.DataMigration
+	if snapsup.MigrationSnapshot != 0 {
+		snapst.DataMigration = &DataMigration{
+			Revision:      oldCurrent,
+			SnapshotSetID: snapsup.MigrationSnapshot,
+		}
+	} else if snapsup.Revert && snapst.DataMigration != nil && snapst.DataMigration.Revision == cand.Revision {
+		// the saved data was restored
+		snapst.DataMigration = nil
+	}
 	// only set userID if unset or logged out in snapst and if we
 	// actually have an associated user
 	if snapsup.UserID > 0 {
@@ -1044,7 +1091,7 @@ func (m *SnapManager) doLinkSnap(t *state.Task, _ *tomb.Tomb) (err error) {
 
 	// XXX: this block is slightly ugly, find a pattern when we have more examples
 	model, _ := ModelFromTask(t)
-	err = m.backend.LinkSnap(newInfo, model, perfTimings)
+	err = m.backend.LinkSnap(newInfo, model, snapst.LastActiveDisabledServices, perfTimings)
 	if err != nil {
 		pb := NewTaskProgressAdapterLocked(t)
 		err := m.backend.UnlinkSnap(newInfo, pb)
@@ -1068,6 +1115,15 @@ func (m *SnapManager) doLinkSnap(t *state.Task, _ *tomb.Tomb) (err error) {
 			return fmt.Errorf("cannot create snap cookie: %v", err)
 		}
 	}
+	// the terms accepted for the installation are kept from now on
+	if snapsup.AcceptedTerms != nil {
+		oldTerms, err := setTermsAcceptance(st, snapsup.SideInfo.SnapID, snapsup.AcceptedTerms)
+		if err != nil {
+			return err
+		}
+		t.Set("old-terms-acceptance", oldTerms)
+	}
+
 	// save for undoLinkSnap
 	t.Set("old-trymode", oldTryMode)
 	t.Set("old-devmode", oldDevMode)
@@ -1079,6 +1135,7 @@ func (m *SnapManager) doLinkSnap(t *state.Task, _ *tomb.Tomb) (err error) {
 	t.Set("old-candidate-index", oldCandidateIndex)
 	t.Set("old-refresh-inhibited-time", oldRefreshInhibitedTime)
 	t.Set("old-cohort-key", oldCohortKey)
+	t.Set("old-data-migration", oldDataMigration)
 
 	// Record the fact that the snap was refreshed successfully.
 	snapst.RefreshInhibitedTime = nil
@@ -1240,6 +1297,10 @@ func (m *SnapManager) undoLinkSnap(t *state.Task, _ *tomb.Tomb) error {
 	if err := t.Get("old-cohort-key", &oldCohortKey); err != nil && err != state.ErrNoState {
 		return err
 	}
+	var oldDataMigration *DataMigration
+	if err := t.Get("old-data-migration", &oldDataMigration); err != nil && err != state.ErrNoState {
+		return err
+	}
 
 	if len(snapst.Sequence) == 1 {
 		// XXX: shouldn't these two just log and carry on? this is an undo handler...
@@ -1284,6 +1345,17 @@ func (m *SnapManager) undoLinkSnap(t *state.Task, _ *tomb.Tomb) error {
 	snapst.Classic = oldClassic
 	snapst.RefreshInhibitedTime = oldRefreshInhibitedTime
 	snapst.CohortKey = oldCohortKey
+	snapst.DataMigration = oldDataMigration
+
+	if snapsup.AcceptedTerms != nil {
+		var oldTerms *termsAcceptance
+		if err := t.Get("old-terms-acceptance", &oldTerms); err != nil && err != state.ErrNoState {
+			return err
+		}
+		if _, err := setTermsAcceptance(st, snapsup.SideInfo.SnapID, oldTerms); err != nil {
+			return err
+		}
+	}
 
 	newInfo, err := readInfo(snapsup.InstanceName(), snapsup.SideInfo, 0)
 	if err != nil {
@@ -1403,10 +1475,17 @@ func (m *SnapManager) startSnapServices(t *state.Task, _ *tomb.Tomb) error {
 		return nil
 	}
 
-	startupOrdered, err := snap.SortServices(svcs)
+	sorted, err := snap.SortServices(svcs)
 	if err != nil {
 		return err
 	}
+	startupOrdered := make([]*snap.AppInfo, 0, len(sorted))
+	for _, app := range sorted {
+		// services the user disabled are neither enabled nor started
+		if !strutil.ListContains(snapst.LastActiveDisabledServices, app.Name) {
+			startupOrdered = append(startupOrdered, app)
+		}
+	}
 
 	pb := NewTaskProgressAdapterUnlocked(t)
 	st.Unlock()
@@ -1415,6 +1494,21 @@ func (m *SnapManager) startSnapServices(t *state.Task, _ *tomb.Tomb) error {
 	return err
 }
 
+// doUpdateDesktopCaches updates the fontconfig caches after the snap
+// was linked without them, as asked by desktop.cache-generation. A
+// failure does not fail the change, the desktop helpers generate the
+// missing caches at launch.
+func (m *SnapManager) doUpdateDesktopCaches(t *state.Task, _ *tomb.Tomb) error {
+	err := m.backend.UpdateFontconfigCaches()
+	if err != nil {
+		st := t.State()
+		st.Lock()
+		t.Logf("cannot update fontconfig caches: %v", err)
+		st.Unlock()
+	}
+	return nil
+}
+
 func (m *SnapManager) stopSnapServices(t *state.Task, _ *tomb.Tomb) error {
 	st := t.State()
 	st.Lock()
@@ -1423,7 +1517,7 @@ func (m *SnapManager) stopSnapServices(t *state.Task, _ *tomb.Tomb) error {
 	perfTimings := timings.NewForTask(t)
 	defer perfTimings.Save(st)
 
-	_, snapst, err := snapSetupAndState(t)
+	snapsup, snapst, err := snapSetupAndState(t)
 	if err != nil {
 		return err
 	}
@@ -1444,9 +1538,35 @@ func (m *SnapManager) stopSnapServices(t *state.Task, _ *tomb.Tomb) error {
 
 	pb := NewTaskProgressAdapterUnlocked(t)
 	st.Unlock()
-	err = m.backend.StopServices(svcs, stopReason, pb, perfTimings)
+	// remember which services the user disabled, to keep them
+	// disabled when the snap gets linked and started again
+	disabled, err := m.backend.QueryDisabledServices(currentInfo, pb)
+	if err == nil {
+		err = m.backend.StopServices(svcs, stopReason, pb, perfTimings)
+	}
 	st.Lock()
-	return err
+	if err != nil {
+		return err
+	}
+
+	snapst.LastActiveDisabledServices = mergeDisabledServices(snapst.LastActiveDisabledServices, currentInfo, disabled)
+	Set(st, snapsup.InstanceName(), snapst)
+	return nil
+}
+
+// mergeDisabledServices returns the disabled services of info, plus
+// the previously disabled ones info does not have as services.
+func mergeDisabledServices(previous []string, info *snap.Info, disabled []string) []string {
+	var merged []string
+	for _, name := range previous {
+		if app := info.Apps[name]; app != nil && app.IsService() {
+			continue
+		}
+		merged = append(merged, name)
+	}
+	merged = append(merged, disabled...)
+	sort.Strings(merged)
+	return merged
 }
 
 func (m *SnapManager) doUnlinkSnap(t *state.Task, _ *tomb.Tomb) error {
@@ -1664,11 +1784,16 @@ func (m *SnapManager) doSetAutoAliases(t *state.Task, _ *tomb.Tomb) error {
 	if err != nil {
 		return err
 	}
-	_, err = checkAliasesConflicts(st, snapName, snapst.AutoAliasesDisabled, newAliases, nil)
+	newAliases, disableOthers, err := resolveAliasesConflicts(st, snapName, snapst.AutoAliasesDisabled, newAliases, aliasConflictPolicy(st))
+	if err != nil {
+		return err
+	}
+	otherSnapStates, otherSnapDisabled, err := m.disableOtherAliases(t, disableOthers)
 	if err != nil {
 		return err
 	}
 
+	setOtherDisabledAliases(t, otherSnapStates, otherSnapDisabled)
 	t.Set("old-aliases-v2", curAliases)
 	// noop, except on first install where we need to set this here
 	snapst.AliasesPending = true
@@ -1738,7 +1863,11 @@ func (m *SnapManager) doRefreshAliases(t *state.Task, _ *tomb.Tomb) error {
 	if err != nil {
 		return err
 	}
-	_, err = checkAliasesConflicts(st, snapName, autoDisabled, newAliases, nil)
+	newAliases, disableOthers, err := resolveAliasesConflicts(st, snapName, autoDisabled, newAliases, aliasConflictPolicy(st))
+	if err != nil {
+		return err
+	}
+	otherSnapStates, otherSnapDisabled, err := m.disableOtherAliases(t, disableOthers)
 	if err != nil {
 		return err
 	}
@@ -1749,6 +1878,7 @@ func (m *SnapManager) doRefreshAliases(t *state.Task, _ *tomb.Tomb) error {
 		}
 	}
 
+	setOtherDisabledAliases(t, otherSnapStates, otherSnapDisabled)
 	t.Set("old-aliases-v2", curAliases)
 	snapst.Aliases = newAliases
 	Set(st, snapName, snapst)
@@ -2097,24 +2227,49 @@ func (m *SnapManager) doPreferAliases(t *state.Task, _ *tomb.Tomb) error {
 	}
 	// proceed to disable conflicting aliases as needed
 	// before re-enabling instanceName aliases
+	otherSnapStates, otherSnapDisabled, err := m.disableOtherAliases(t, aliasConflicts)
+	if err != nil {
+		return err
+	}
+
+	added, removed, err := applyAliasesChange(instanceName, autoDis, curAliases, autoEn, curAliases, m.backend, snapst.AliasesPending)
+	if err != nil {
+		return err
+	}
+	if err := aliasesTrace(t, added, removed); err != nil {
+		return err
+	}
 
-	otherSnapStates := make(map[string]*SnapState, len(aliasConflicts))
-	otherSnapDisabled := make(map[string]*otherDisabledAliases, len(aliasConflicts))
+	setOtherDisabledAliases(t, otherSnapStates, otherSnapDisabled)
+	t.Set("old-auto-aliases-disabled", true)
+	t.Set("old-aliases-v2", curAliases)
+	snapst.AutoAliasesDisabled = false
+	Set(st, instanceName, snapst)
+	return nil
+}
+
+// disableOtherAliases disables, also on disk, all the aliases of the
+// given other snaps that have conflicting aliases, returning their new
+// states and what was disabled for them without setting them yet.
+func (m *SnapManager) disableOtherAliases(t *state.Task, aliasConflicts map[string][]string) (otherSnapStates map[string]*SnapState, otherSnapDisabled map[string]*otherDisabledAliases, err error) {
+	st := t.State()
+	otherSnapStates = make(map[string]*SnapState, len(aliasConflicts))
+	otherSnapDisabled = make(map[string]*otherDisabledAliases, len(aliasConflicts))
 	for otherSnap := range aliasConflicts {
 		var otherSnapState SnapState
 		err := Get(st, otherSnap, &otherSnapState)
 		if err != nil {
-			return err
+			return nil, nil, err
 		}
 
 		otherAliases, disabledManual := disableAliases(otherSnapState.Aliases)
 
 		added, removed, err := applyAliasesChange(otherSnap, otherSnapState.AutoAliasesDisabled, otherSnapState.Aliases, autoDis, otherAliases, m.backend, otherSnapState.AliasesPending)
 		if err != nil {
-			return err
+			return nil, nil, err
 		}
 		if err := aliasesTrace(t, added, removed); err != nil {
-			return err
+			return nil, nil, err
 		}
 
 		var otherDisabled otherDisabledAliases
@@ -2129,26 +2284,20 @@ func (m *SnapManager) doPreferAliases(t *state.Task, _ *tomb.Tomb) error {
 		otherSnapDisabled[otherSnap] = &otherDisabled
 		otherSnapStates[otherSnap] = &otherSnapState
 	}
+	return otherSnapStates, otherSnapDisabled, nil
+}
 
-	added, removed, err := applyAliasesChange(instanceName, autoDis, curAliases, autoEn, curAliases, m.backend, snapst.AliasesPending)
-	if err != nil {
-		return err
-	}
-	if err := aliasesTrace(t, added, removed); err != nil {
-		return err
-	}
-
+// setOtherDisabledAliases sets the states of other snaps whose aliases
+// were disabled by disableOtherAliases and records what was disabled
+// on the task for undo.
+func setOtherDisabledAliases(t *state.Task, otherSnapStates map[string]*SnapState, otherSnapDisabled map[string]*otherDisabledAliases) {
+	st := t.State()
 	for otherSnap, otherSnapState := range otherSnapStates {
 		Set(st, otherSnap, otherSnapState)
 	}
 	if len(otherSnapDisabled) != 0 {
 		t.Set("other-disabled-aliases", otherSnapDisabled)
 	}
-	t.Set("old-auto-aliases-disabled", true)
-	t.Set("old-aliases-v2", curAliases)
-	snapst.AutoAliasesDisabled = false
-	Set(st, instanceName, snapst)
-	return nil
 }
 
 // changeReadyUpToTask returns whether all other change's tasks are Ready.
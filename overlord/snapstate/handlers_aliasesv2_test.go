@@ -23,6 +23,7 @@ import (
 	. "gopkg.in/check.v1"
 	"gopkg.in/tomb.v2"
 
+	"github.com/snapcore/snapd/overlord/configstate/config"
 	"github.com/snapcore/snapd/overlord/snapstate"
 	"github.com/snapcore/snapd/overlord/snapstate/backend"
 	"github.com/snapcore/snapd/overlord/state"
@@ -1350,6 +1351,151 @@ func (s *snapmgrTestSuite) TestDoRefreshAliasesConflict(c *C) {
 	c.Check(chg.Err(), ErrorMatches, `(?s).*cannot enable alias "alias4" for "alias-snap", already enabled for "other-snap".*`)
 }
 
+func (s *snapmgrTestSuite) setupRefreshAliasesConflict(c *C, policy string) (*state.Change, *state.Task) {
+	tr := config.NewTransaction(s.state)
+	tr.Set("core", "aliases.conflict-policy", policy)
+	tr.Commit()
+
+	snapstate.AutoAliases = func(st *state.State, info *snap.Info) (map[string]string, error) {
+		c.Check(info.InstanceName(), Equals, "alias-snap")
+		return map[string]string{
+			"alias1": "cmd1",
+			"alias4": "cmd4",
+		}, nil
+	}
+
+	snapstate.Set(s.state, "alias-snap", &snapstate.SnapState{
+		Sequence: []*snap.SideInfo{
+			{RealName: "alias-snap", Revision: snap.R(11)},
+		},
+		Current: snap.R(11),
+		Active:  true,
+		Aliases: map[string]*snapstate.AliasTarget{
+			"alias1": {Auto: "cmd1"},
+		},
+	})
+	snapstate.Set(s.state, "other-snap", &snapstate.SnapState{
+		Sequence: []*snap.SideInfo{
+			{RealName: "other-snap", Revision: snap.R(3)},
+		},
+		Current: snap.R(3),
+		Active:  true,
+		Aliases: map[string]*snapstate.AliasTarget{
+			"alias4": {Auto: "cmd4"},
+		},
+	})
+
+	t := s.state.NewTask("refresh-aliases", "test")
+	t.Set("snap-setup", &snapstate.SnapSetup{
+		SideInfo: &snap.SideInfo{RealName: "alias-snap"},
+	})
+	chg := s.state.NewChange("dummy", "...")
+	chg.AddTask(t)
+
+	s.state.Unlock()
+	defer s.state.Lock()
+
+	s.se.Ensure()
+	s.se.Wait()
+
+	return chg, t
+}
+
+func (s *snapmgrTestSuite) TestDoRefreshAliasesConflictPolicyFail(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	chg, t := s.setupRefreshAliasesConflict(c, "fail")
+
+	c.Check(t.Status(), Equals, state.ErrorStatus, Commentf("%v", chg.Err()))
+	c.Check(chg.Err(), ErrorMatches, `(?s).*cannot enable alias "alias4" for "alias-snap", already enabled for "other-snap".*`)
+}
+
+func (s *snapmgrTestSuite) TestDoRefreshAliasesConflictPolicyPreferInstalled(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	chg, t := s.setupRefreshAliasesConflict(c, "prefer-installed")
+
+	c.Check(t.Status(), Equals, state.DoneStatus, Commentf("%v", chg.Err()))
+	// nothing to change on disk, alias4 stays with other-snap
+	c.Check(s.fakeBackend.ops, DeepEquals, fakeOps{
+		{op: "update-aliases"},
+	})
+
+	var snapst snapstate.SnapState
+	err := snapstate.Get(s.state, "alias-snap", &snapst)
+	c.Assert(err, IsNil)
+	c.Check(snapst.Aliases, DeepEquals, map[string]*snapstate.AliasTarget{
+		"alias1": {Auto: "cmd1"},
+	})
+
+	var otherst snapstate.SnapState
+	err = snapstate.Get(s.state, "other-snap", &otherst)
+	c.Assert(err, IsNil)
+	c.Check(otherst.AutoAliasesDisabled, Equals, false)
+	c.Check(otherst.Aliases, DeepEquals, map[string]*snapstate.AliasTarget{
+		"alias4": {Auto: "cmd4"},
+	})
+}
+
+func (s *snapmgrTestSuite) TestDoRefreshAliasesConflictPolicyPreferNew(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	chg, t := s.setupRefreshAliasesConflict(c, "prefer-new")
+
+	c.Check(t.Status(), Equals, state.DoneStatus, Commentf("%v", chg.Err()))
+	c.Assert(s.fakeBackend.ops, DeepEquals, fakeOps{
+		{
+			op:        "update-aliases",
+			rmAliases: []*backend.Alias{{Name: "alias4", Target: "other-snap.cmd4"}},
+		},
+		{
+			op:      "update-aliases",
+			aliases: []*backend.Alias{{Name: "alias4", Target: "alias-snap.cmd4"}},
+		},
+	})
+
+	var snapst snapstate.SnapState
+	err := snapstate.Get(s.state, "alias-snap", &snapst)
+	c.Assert(err, IsNil)
+	c.Check(snapst.Aliases, DeepEquals, map[string]*snapstate.AliasTarget{
+		"alias1": {Auto: "cmd1"},
+		"alias4": {Auto: "cmd4"},
+	})
+
+	var otherst snapstate.SnapState
+	err = snapstate.Get(s.state, "other-snap", &otherst)
+	c.Assert(err, IsNil)
+	c.Check(otherst.AutoAliasesDisabled, Equals, true)
+
+	// undo restores the aliases of other-snap
+	terr := s.state.NewTask("error-trigger", "provoking total undo")
+	terr.WaitFor(t)
+	chg.AddTask(terr)
+
+	s.state.Unlock()
+	for i := 0; i < 3; i++ {
+		s.se.Ensure()
+		s.se.Wait()
+	}
+	s.state.Lock()
+
+	c.Check(t.Status(), Equals, state.UndoneStatus, Commentf("%v", chg.Err()))
+	err = snapstate.Get(s.state, "other-snap", &otherst)
+	c.Assert(err, IsNil)
+	c.Check(otherst.AutoAliasesDisabled, Equals, false)
+	c.Check(otherst.Aliases, DeepEquals, map[string]*snapstate.AliasTarget{
+		"alias4": {Auto: "cmd4"},
+	})
+	err = snapstate.Get(s.state, "alias-snap", &snapst)
+	c.Assert(err, IsNil)
+	c.Check(snapst.Aliases, DeepEquals, map[string]*snapstate.AliasTarget{
+		"alias1": {Auto: "cmd1"},
+	})
+}
+
 func (s *snapmgrTestSuite) TestDoUndoRefreshAliasesConflict(c *C) {
 	s.state.Lock()
 	defer s.state.Unlock()
@@ -20,6 +20,8 @@
 package snapstate_test
 
 import (
+	"io/ioutil"
+	"os"
 	"path/filepath"
 
 	. "gopkg.in/check.v1"
@@ -31,6 +33,7 @@ import (
 	"github.com/snapcore/snapd/overlord/state"
 	"github.com/snapcore/snapd/snap"
 	"github.com/snapcore/snapd/store"
+	"github.com/snapcore/snapd/testutil"
 )
 
 type downloadSnapSuite struct {
@@ -295,3 +298,117 @@ func (s *downloadSnapSuite) TestDoDownloadRateLimitedIntegration(c *C) {
 	})
 
 }
+
+func (s *downloadSnapSuite) TestDoDownloadSnapInterruptedRemembersResume(c *C) {
+	s.fakeStore.interruptDownloadAt = 1024
+
+	s.state.Lock()
+	t := s.state.NewTask("download-snap", "test")
+	t.Set("snap-setup", &snapstate.SnapSetup{
+		SideInfo: &snap.SideInfo{
+			RealName: "foo",
+			SnapID:   "foo-id",
+			Revision: snap.R(11),
+		},
+		DownloadInfo: &snap.DownloadInfo{
+			DownloadURL: "http://some-url.com/snap",
+		},
+	})
+	s.state.NewChange("dummy", "...").AddTask(t)
+	s.state.Unlock()
+
+	s.se.Ensure()
+	s.se.Wait()
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	var resume store.PartialDownload
+	c.Assert(t.Get("download-resume", &resume), IsNil)
+	c.Check(resume, DeepEquals, store.PartialDownload{
+		Offset: 1024,
+		ETag:   "etag",
+	})
+}
+
+func (s *downloadSnapSuite) TestDoDownloadSnapAbortedRemovesPartial(c *C) {
+	s.fakeStore.interruptDownloadAt = 1024
+
+	s.state.Lock()
+	t := s.state.NewTask("download-snap", "test")
+	t.Set("snap-setup", &snapstate.SnapSetup{
+		SideInfo: &snap.SideInfo{
+			RealName: "foo",
+			SnapID:   "foo-id",
+			Revision: snap.R(11),
+		},
+		DownloadInfo: &snap.DownloadInfo{
+			DownloadURL: "http://some-url.com/snap",
+		},
+	})
+	chg := s.state.NewChange("dummy", "...")
+	chg.AddTask(t)
+	s.state.Unlock()
+
+	partial := filepath.Join(dirs.SnapBlobDir, "foo_11.snap.partial")
+	c.Assert(os.MkdirAll(dirs.SnapBlobDir, 0755), IsNil)
+	c.Assert(ioutil.WriteFile(partial, []byte("partial"), 0644), IsNil)
+	s.fakeStore.downloadInterrupted = func() {
+		s.state.Lock()
+		defer s.state.Unlock()
+		chg.Abort()
+	}
+
+	s.se.Ensure()
+	s.se.Wait()
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	// the aborted download is not going to be resumed
+	c.Check(t.Has("download-resume"), Equals, false)
+	c.Check(partial, testutil.FileAbsent)
+}
+
+func (s *downloadSnapSuite) TestDoDownloadSnapResumes(c *C) {
+	s.state.Lock()
+	t := s.state.NewTask("download-snap", "test")
+	t.Set("snap-setup", &snapstate.SnapSetup{
+		SideInfo: &snap.SideInfo{
+			RealName: "foo",
+			SnapID:   "foo-id",
+			Revision: snap.R(11),
+		},
+		DownloadInfo: &snap.DownloadInfo{
+			DownloadURL: "http://some-url.com/snap",
+		},
+	})
+	// as left behind by an interrupted run of the task
+	t.Set("download-resume", &store.PartialDownload{
+		Offset: 1024,
+		ETag:   "etag",
+	})
+	s.state.NewChange("dummy", "...").AddTask(t)
+	s.state.Unlock()
+
+	s.se.Ensure()
+	s.se.Wait()
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	c.Assert(s.fakeStore.downloads, DeepEquals, []fakeDownload{
+		{
+			name:   "foo",
+			target: filepath.Join(dirs.SnapBlobDir, "foo_11.snap"),
+			opts: &store.DownloadOptions{
+				Resume: &store.PartialDownload{
+					Offset: 1024,
+					ETag:   "etag",
+				},
+			},
+		},
+	})
+	c.Check(t.Status(), Equals, state.DoneStatus)
+	c.Check(t.Has("download-resume"), Equals, false)
+}
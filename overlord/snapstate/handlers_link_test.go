@@ -24,6 +24,7 @@ import (
 	"fmt"
 	"io/ioutil"
 	"path/filepath"
+	"syscall"
 	"time"
 
 	. "gopkg.in/check.v1"
@@ -939,7 +940,7 @@ func (s *linkSnapSuite) TestDoUnlinkSnapRefreshAwarenessHardCheck(c *C) {
 	tr.Set("core", "experimental.refresh-app-awareness", true)
 	tr.Commit()
 
-	chg := s.testDoUnlinkSnapRefreshAwareness(c)
+	chg := s.testDoUnlinkSnapRefreshAwareness(c, snapstate.Flags{})
 
 	c.Check(chg.Err(), ErrorMatches, `(?ms).*^- some-change-descr \(snap "some-snap" has running apps \(some-app\)\).*`)
 }
@@ -948,12 +949,75 @@ func (s *linkSnapSuite) TestDoUnlinkSnapRefreshHardCheckOff(c *C) {
 	s.state.Lock()
 	defer s.state.Unlock()
 
-	chg := s.testDoUnlinkSnapRefreshAwareness(c)
+	chg := s.testDoUnlinkSnapRefreshAwareness(c, snapstate.Flags{})
 
 	c.Check(chg.Err(), IsNil)
 }
 
-func (s *linkSnapSuite) testDoUnlinkSnapRefreshAwareness(c *C) *state.Change {
+func (s *linkSnapSuite) TestDoUnlinkSnapRefreshAwarenessIgnoreRunning(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	tr := config.NewTransaction(s.state)
+	tr.Set("core", "experimental.refresh-app-awareness", true)
+	tr.Commit()
+
+	chg := s.testDoUnlinkSnapRefreshAwareness(c, snapstate.Flags{IgnoreRunning: true})
+
+	c.Check(chg.Err(), IsNil)
+}
+
+func (s *linkSnapSuite) TestDoUnlinkSnapRefreshAwarenessKillRunning(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	tr := config.NewTransaction(s.state)
+	tr.Set("core", "experimental.refresh-app-awareness", true)
+	tr.Commit()
+
+	freezerPath := filepath.Join(dirs.FreezerCgroupDir, "snap.some-snap")
+	writePids(c, freezerPath, []int{1234})
+	var signals []string
+	restore := snapstate.MockSyscallKill(func(pid int, sig syscall.Signal) error {
+		signals = append(signals, fmt.Sprintf("%s %d", sig, pid))
+		// the app exits
+		writePids(c, freezerPath, nil)
+		writePids(c, filepath.Join(dirs.PidsCgroupDir, "snap.some-snap.some-app"), nil)
+		return nil
+	})
+	defer restore()
+
+	chg := s.testDoUnlinkSnapRefreshAwareness(c, snapstate.Flags{KillRunning: true})
+
+	c.Check(chg.Err(), IsNil)
+	c.Check(signals, DeepEquals, []string{"terminated 1234"})
+}
+
+func (s *linkSnapSuite) TestDoUnlinkSnapKillRunningRefreshAwarenessOff(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	// without refresh app awareness the pids cgroups are not populated,
+	// the service enduring refresh is found from its unit instead
+	freezerPath := filepath.Join(dirs.FreezerCgroupDir, "snap.some-snap")
+	writePids(c, freezerPath, []int{1000, 1234})
+	writePids(c, filepath.Join(dirs.SystemdCgroupDir, "system.slice", "snap.some-snap.some-daemon.service"), []int{1000})
+	var signals []string
+	restore := snapstate.MockSyscallKill(func(pid int, sig syscall.Signal) error {
+		signals = append(signals, fmt.Sprintf("%s %d", sig, pid))
+		// the app exits
+		writePids(c, freezerPath, []int{1000})
+		return nil
+	})
+	defer restore()
+
+	chg := s.testDoUnlinkSnapRefreshAwareness(c, snapstate.Flags{KillRunning: true})
+
+	c.Check(chg.Err(), IsNil)
+	c.Check(signals, DeepEquals, []string{"terminated 1234"})
+}
+
+func (s *linkSnapSuite) testDoUnlinkSnapRefreshAwareness(c *C, flags snapstate.Flags) *state.Change {
 	restore := release.MockOnClassic(true)
 	defer restore()
 
@@ -962,7 +1026,8 @@ func (s *linkSnapSuite) testDoUnlinkSnapRefreshAwareness(c *C) *state.Change {
 	snapstate.MockSnapReadInfo(func(name string, si *snap.SideInfo) (*snap.Info, error) {
 		info := &snap.Info{SuggestedName: name, SideInfo: *si, SnapType: snap.TypeApp}
 		info.Apps = map[string]*snap.AppInfo{
-			"some-app": {Snap: info, Name: "some-app"},
+			"some-app":    {Snap: info, Name: "some-app"},
+			"some-daemon": {Snap: info, Name: "some-daemon", Daemon: "simple", RefreshMode: "endure"},
 		}
 		return info, nil
 	})
@@ -979,6 +1044,7 @@ func (s *linkSnapSuite) testDoUnlinkSnapRefreshAwareness(c *C) *state.Change {
 	t := s.state.NewTask("unlink-current-snap", "some-change-descr")
 	t.Set("snap-setup", &snapstate.SnapSetup{
 		SideInfo: si1,
+		Flags:    flags,
 	})
 	chg := s.state.NewChange("dummy", "...")
 	chg.AddTask(t)
@@ -0,0 +1,321 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2018 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package snapstate
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+
+	"gopkg.in/tomb.v2"
+
+	"github.com/snapcore/snapd/i18n"
+	"github.com/snapcore/snapd/logger"
+	"github.com/snapcore/snapd/osutil"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/store"
+)
+
+// prefetchedSnaps returns the setup of the snap updates that were
+// downloaded ahead of their installation, by instance name.
+func prefetchedSnaps(st *state.State) (map[string]*SnapSetup, error) {
+	var prefetched map[string]*SnapSetup
+	err := st.Get("prefetched-snaps", &prefetched)
+	if err != nil && err != state.ErrNoState {
+		return nil, fmt.Errorf("cannot get prefetched snaps: %v", err)
+	}
+	if prefetched == nil {
+		prefetched = make(map[string]*SnapSetup)
+	}
+	return prefetched, nil
+}
+
+// DownloadMany downloads the updates of the snaps from the given list
+// of names that the store says are updateable, together with their
+// assertions, without installing them. If the list is empty, all the
+// updates are downloaded. The downloads can be installed later with
+// ApplyPrefetched.
+// Note that the state must be locked by the caller.
+func DownloadMany(ctx context.Context, st *state.State, names []string, userID int) ([]string, []*state.TaskSet, error) {
+	user, err := userFromUserID(st, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// need to have a model set before trying to talk the store
+	deviceCtx, err := DevicePastSeeding(st, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	updates, stateByInstanceName, ignoreValidation, err := refreshCandidates(ctx, st, names, user, &store.RefreshOptions{})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if ValidateRefreshes != nil && len(updates) != 0 {
+		updates, err = ValidateRefreshes(st, updates, ignoreValidation, userID, deviceCtx)
+		if err != nil {
+			if len(names) != 0 {
+				return nil, nil, err
+			}
+			logger.Noticef("cannot download some snaps: %v", err)
+		}
+	}
+
+	refreshAll := len(names) == 0
+	downloaded := make([]string, 0, len(updates))
+	tasksets := make([]*state.TaskSet, 0, len(updates))
+	for _, update := range updates {
+		snapst := stateByInstanceName[update.InstanceName()]
+		flags := snapst.Flags
+		if !update.NeedsClassic() && flags.Classic {
+			// allow updating from classic to strict
+			flags.Classic = false
+		}
+
+		if err := checkInstallPreconditions(st, update, flags, snapst, deviceCtx); err != nil {
+			if refreshAll {
+				logger.Noticef("cannot download %q: %v", update.InstanceName(), err)
+				continue
+			}
+			return nil, nil, err
+		}
+		if err := earlyEpochCheck(update, snapst); err != nil {
+			if refreshAll {
+				logger.Noticef("cannot download %q: %v", update.InstanceName(), err)
+				continue
+			}
+			return nil, nil, err
+		}
+
+		snapUserID, err := userIDForSnap(st, snapst, userID)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		migrateData, err := crossesEpoch(update, snapst)
+		if err != nil {
+			return nil, nil, err
+		}
+		migrateBase, err := crossesBase(update, snapst)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		snapsup := &SnapSetup{
+			Base:         update.Base,
+			Prereq:       defaultContentPlugProviders(st, update),
+			Channel:      snapst.Channel,
+			CohortKey:    snapst.CohortKey,
+			UserID:       snapUserID,
+			Flags:        flags.ForSnapSetup(),
+			DownloadInfo: &update.DownloadInfo,
+			SideInfo:     &update.SideInfo,
+			Type:         update.GetType(),
+			PlugsOnly:    len(update.Slots) == 0,
+			InstanceKey:  update.InstanceKey,
+			MigrateData:  migrateData,
+			MigrateBase:  migrateBase,
+			auxStoreInfo: auxStoreInfo{
+				Media: update.Media,
+			},
+		}
+
+		ts := doDownload(st, snapsup)
+		ts.JoinLane(st.NewLane())
+		downloaded = append(downloaded, update.InstanceName())
+		tasksets = append(tasksets, ts)
+	}
+
+	return downloaded, tasksets, nil
+}
+
+// doDownload creates the tasks to download a snap and its assertions
+// and to remember it for a later installation.
+func doDownload(st *state.State, snapsup *SnapSetup) *state.TaskSet {
+	revisionStr := fmt.Sprintf(" (%s)", snapsup.Revision())
+
+	download := st.NewTask("download-snap", fmt.Sprintf(i18n.G("Download snap %q%s from channel %q"), snapsup.InstanceName(), revisionStr, snapsup.Channel))
+	download.Set("snap-setup", snapsup)
+
+	checkAsserts := st.NewTask("validate-snap", fmt.Sprintf(i18n.G("Fetch and check assertions for snap %q%s"), snapsup.InstanceName(), revisionStr))
+	checkAsserts.Set("snap-setup-task", download.ID())
+	checkAsserts.WaitFor(download)
+
+	prefetch := st.NewTask("prefetch-snap", fmt.Sprintf(i18n.G("Keep snap %q%s for a later refresh"), snapsup.InstanceName(), revisionStr))
+	prefetch.Set("snap-setup-task", download.ID())
+	prefetch.WaitFor(checkAsserts)
+
+	return state.NewTaskSet(download, checkAsserts, prefetch)
+}
+
+func (m *SnapManager) doPrefetchSnap(t *state.Task, _ *tomb.Tomb) error {
+	st := t.State()
+	st.Lock()
+	defer st.Unlock()
+
+	snapsup, err := TaskSnapSetup(t)
+	if err != nil {
+		return err
+	}
+	prefetched, err := prefetchedSnaps(st)
+	if err != nil {
+		return err
+	}
+	prefetched[snapsup.InstanceName()] = snapsup
+	st.Set("prefetched-snaps", prefetched)
+	return nil
+}
+
+func (m *SnapManager) undoPrefetchSnap(t *state.Task, _ *tomb.Tomb) error {
+	st := t.State()
+	st.Lock()
+	defer st.Unlock()
+
+	snapsup, err := TaskSnapSetup(t)
+	if err != nil {
+		return err
+	}
+	prefetched, err := prefetchedSnaps(st)
+	if err != nil {
+		return err
+	}
+	if cur := prefetched[snapsup.InstanceName()]; cur != nil && cur.Revision() == snapsup.Revision() {
+		delete(prefetched, snapsup.InstanceName())
+		st.Set("prefetched-snaps", prefetched)
+	}
+	if err := os.Remove(snapsup.SnapPath); err != nil && !os.IsNotExist(err) {
+		logger.Noticef("cannot remove prefetched snap %q: %v", snapsup.SnapPath, err)
+	}
+	return nil
+}
+
+// ApplyPrefetched installs the snap updates from the given list of
+// names that were downloaded before with DownloadMany. If the list
+// is empty, all the downloaded updates are installed. The updates
+// keep the channel the snaps track at this point. Downloads that
+// became obsolete, because the snap was removed or refreshed to the
+// same or a newer revision meanwhile, are forgotten and removed.
+// Note that the state must be locked by the caller.
+func ApplyPrefetched(st *state.State, names []string) ([]string, []*state.TaskSet, error) {
+	prefetched, err := prefetchedSnaps(st)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var nameSet map[string]bool
+	if len(names) != 0 {
+		nameSet = make(map[string]bool, len(names))
+		for _, name := range names {
+			nameSet[name] = true
+		}
+	}
+
+	toApply := make([]*SnapSetup, 0, len(prefetched))
+	stateByInstanceName := make(map[string]*SnapState, len(prefetched))
+	obsolete := false
+	for instanceName, snapsup := range prefetched {
+		var snapst SnapState
+		err := Get(st, instanceName, &snapst)
+		if err != nil && err != state.ErrNoState {
+			return nil, nil, err
+		}
+		inSequence := snapst.LastIndex(snapsup.Revision()) >= 0
+		if !snapst.IsInstalled() || inSequence || snapsup.Revision().N <= snapst.Current.N || !osutil.FileExists(snapsup.SnapPath) {
+			delete(prefetched, instanceName)
+			obsolete = true
+			// the file of a revision in the sequence is in use
+			if !inSequence {
+				if err := os.Remove(snapsup.SnapPath); err != nil && !os.IsNotExist(err) {
+					logger.Noticef("cannot remove prefetched snap %q: %v", snapsup.SnapPath, err)
+				}
+			}
+			continue
+		}
+		if nameSet != nil && !nameSet[instanceName] {
+			continue
+		}
+		// the channel might have been switched since the download
+		snapsup.Channel = snapst.Channel
+		snapsup.CohortKey = snapst.CohortKey
+		toApply = append(toApply, snapsup)
+		stateByInstanceName[instanceName] = &snapst
+	}
+	if obsolete {
+		st.Set("prefetched-snaps", prefetched)
+	}
+
+	for _, name := range names {
+		if stateByInstanceName[name] == nil {
+			return nil, nil, fmt.Errorf("snap %q has no prefetched refresh", name)
+		}
+	}
+
+	// first snapd, core, bases, then rest
+	sort.Sort(byTypeAndName(toApply))
+	prereqs := make(map[string]*state.TaskSet)
+	waitPrereq := func(ts *state.TaskSet, prereqName string) {
+		if preTs := prereqs[prereqName]; preTs != nil {
+			ts.WaitAll(preTs)
+		}
+	}
+
+	applied := make([]string, 0, len(toApply))
+	tasksets := make([]*state.TaskSet, 0, len(toApply))
+	for _, snapsup := range toApply {
+		ts, err := doInstall(st, stateByInstanceName[snapsup.InstanceName()], snapsup, 0, "")
+		if err != nil {
+			return nil, nil, err
+		}
+		ts.JoinLane(st.NewLane())
+
+		if t := snapsup.Type; t == snap.TypeOS || t == snap.TypeBase || t == snap.TypeSnapd {
+			prereqs[snapsup.InstanceName()] = ts
+		} else {
+			waitPrereq(ts, defaultCoreSnapName)
+			waitPrereq(ts, "snapd")
+			if snapsup.Base != "" {
+				waitPrereq(ts, snapsup.Base)
+			}
+		}
+
+		applied = append(applied, snapsup.InstanceName())
+		tasksets = append(tasksets, ts)
+	}
+
+	return applied, tasksets, nil
+}
+
+type byTypeAndName []*SnapSetup
+
+func (r byTypeAndName) Len() int      { return len(r) }
+func (r byTypeAndName) Swap(i, j int) { r[i], r[j] = r[j], r[i] }
+func (r byTypeAndName) Less(i, j int) bool {
+	if r[i].Type.SortsBefore(r[j].Type) {
+		return true
+	}
+	if r[j].Type.SortsBefore(r[i].Type) {
+		return false
+	}
+	return r[i].InstanceName() < r[j].InstanceName()
+}
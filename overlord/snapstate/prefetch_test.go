@@ -0,0 +1,207 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2018 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package snapstate_test
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/overlord/snapstate"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/testutil"
+)
+
+func (s *snapmgrTestSuite) setupPrefetchSnap() {
+	snapstate.Set(s.state, "some-snap", &snapstate.SnapState{
+		Active: true,
+		Sequence: []*snap.SideInfo{
+			{RealName: "some-snap", SnapID: "some-snap-id", Revision: snap.R(7)},
+		},
+		Current:  snap.R(7),
+		Channel:  "stable",
+		SnapType: "app",
+	})
+}
+
+func (s *snapmgrTestSuite) TestDownloadManyTasks(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+	s.setupPrefetchSnap()
+
+	downloaded, tss, err := snapstate.DownloadMany(context.Background(), s.state, nil, 0)
+	c.Assert(err, IsNil)
+	c.Check(downloaded, DeepEquals, []string{"some-snap"})
+	c.Assert(tss, HasLen, 1)
+
+	tasks := tss[0].Tasks()
+	c.Check(taskKinds(tasks), DeepEquals, []string{
+		"download-snap",
+		"validate-snap",
+		"prefetch-snap",
+	})
+	snapsup, err := snapstate.TaskSnapSetup(tasks[0])
+	c.Assert(err, IsNil)
+	c.Check(snapsup.InstanceName(), Equals, "some-snap")
+	c.Check(snapsup.Revision(), Equals, snap.R(11))
+}
+
+func (s *snapmgrTestSuite) TestDownloadManyThenApplyPrefetched(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+	s.setupPrefetchSnap()
+
+	_, tss, err := snapstate.DownloadMany(context.Background(), s.state, []string{"some-snap"}, 0)
+	c.Assert(err, IsNil)
+	chg := s.state.NewChange("refresh-snap", "download some-snap")
+	for _, ts := range tss {
+		chg.AddAll(ts)
+	}
+
+	s.state.Unlock()
+	defer s.se.Stop()
+	s.settle(c)
+	s.state.Lock()
+	c.Assert(chg.Err(), IsNil)
+
+	// nothing was installed
+	var snapst snapstate.SnapState
+	c.Assert(snapstate.Get(s.state, "some-snap", &snapst), IsNil)
+	c.Check(snapst.Current, Equals, snap.R(7))
+	c.Check(snapst.Sequence, HasLen, 1)
+
+	// the fake store does not write the downloaded file
+	snapPath := snap.MinimalPlaceInfo("some-snap", snap.R(11)).MountFile()
+	c.Assert(os.MkdirAll(filepath.Dir(snapPath), 0755), IsNil)
+	c.Assert(ioutil.WriteFile(snapPath, nil, 0644), IsNil)
+
+	applied, tss, err := snapstate.ApplyPrefetched(s.state, nil)
+	c.Assert(err, IsNil)
+	c.Check(applied, DeepEquals, []string{"some-snap"})
+	c.Assert(tss, HasLen, 1)
+	tasks := tss[0].Tasks()
+	// no download is needed anymore
+	c.Check(tasks[0].Kind(), Equals, "prerequisites")
+	c.Check(tasks[1].Kind(), Equals, "prepare-snap")
+	snapsup, err := snapstate.TaskSnapSetup(tasks[1])
+	c.Assert(err, IsNil)
+	c.Check(snapsup.SnapPath, Equals, snapPath)
+
+	chg = s.state.NewChange("refresh-snap", "apply some-snap")
+	for _, ts := range tss {
+		chg.AddAll(ts)
+	}
+	s.state.Unlock()
+	s.settle(c)
+	s.state.Lock()
+	c.Assert(chg.Err(), IsNil)
+
+	c.Assert(snapstate.Get(s.state, "some-snap", &snapst), IsNil)
+	c.Check(snapst.Current, Equals, snap.R(11))
+	c.Check(snapst.Channel, Equals, "stable")
+
+	// the prefetched refresh is now obsolete
+	applied, tss, err = snapstate.ApplyPrefetched(s.state, nil)
+	c.Assert(err, IsNil)
+	c.Check(applied, HasLen, 0)
+	c.Check(tss, HasLen, 0)
+	var prefetched map[string]interface{}
+	c.Assert(s.state.Get("prefetched-snaps", &prefetched), IsNil)
+	c.Check(prefetched, HasLen, 0)
+	// but its file is the one of the current revision
+	c.Check(snapPath, testutil.FilePresent)
+}
+
+func (s *snapmgrTestSuite) setupPrefetched(c *C, rev snap.Revision, channel string) string {
+	snapPath := snap.MinimalPlaceInfo("some-snap", rev).MountFile()
+	c.Assert(os.MkdirAll(filepath.Dir(snapPath), 0755), IsNil)
+	c.Assert(ioutil.WriteFile(snapPath, nil, 0644), IsNil)
+	s.state.Set("prefetched-snaps", map[string]*snapstate.SnapSetup{
+		"some-snap": {
+			Channel:  channel,
+			SnapPath: snapPath,
+			SideInfo: &snap.SideInfo{RealName: "some-snap", SnapID: "some-snap-id", Revision: rev},
+			Type:     snap.TypeApp,
+		},
+	})
+	return snapPath
+}
+
+func (s *snapmgrTestSuite) TestApplyPrefetchedDiscardsOlderRevision(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+	s.setupPrefetchSnap()
+
+	for _, rev := range []snap.Revision{snap.R(5), snap.R(7)} {
+		snapPath := s.setupPrefetched(c, rev, "stable")
+
+		applied, tss, err := snapstate.ApplyPrefetched(s.state, nil)
+		c.Assert(err, IsNil)
+		c.Check(applied, HasLen, 0)
+		c.Check(tss, HasLen, 0)
+
+		var prefetched map[string]interface{}
+		c.Assert(s.state.Get("prefetched-snaps", &prefetched), IsNil)
+		c.Check(prefetched, HasLen, 0)
+		if rev == snap.R(7) {
+			// the file is the one of the current revision
+			c.Check(snapPath, testutil.FilePresent)
+		} else {
+			c.Check(snapPath, testutil.FileAbsent)
+		}
+	}
+
+	_, _, err := snapstate.ApplyPrefetched(s.state, []string{"some-snap"})
+	c.Assert(err, ErrorMatches, `snap "some-snap" has no prefetched refresh`)
+}
+
+func (s *snapmgrTestSuite) TestApplyPrefetchedKeepsChannel(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+	s.setupPrefetchSnap()
+	// the snap was switched to another channel after the download
+	var snapst snapstate.SnapState
+	c.Assert(snapstate.Get(s.state, "some-snap", &snapst), IsNil)
+	snapst.Channel = "beta"
+	snapstate.Set(s.state, "some-snap", &snapst)
+
+	s.setupPrefetched(c, snap.R(11), "stable")
+
+	applied, tss, err := snapstate.ApplyPrefetched(s.state, nil)
+	c.Assert(err, IsNil)
+	c.Check(applied, DeepEquals, []string{"some-snap"})
+	c.Assert(tss, HasLen, 1)
+	snapsup, err := snapstate.TaskSnapSetup(tss[0].Tasks()[0])
+	c.Assert(err, IsNil)
+	c.Check(snapsup.Channel, Equals, "beta")
+	c.Check(snapsup.Revision(), Equals, snap.R(11))
+}
+
+func (s *snapmgrTestSuite) TestApplyPrefetchedNotPrefetched(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+	s.setupPrefetchSnap()
+
+	_, _, err := snapstate.ApplyPrefetched(s.state, []string{"some-snap"})
+	c.Assert(err, ErrorMatches, `snap "some-snap" has no prefetched refresh`)
+}
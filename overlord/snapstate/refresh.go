@@ -21,16 +21,21 @@ package snapstate
 
 import (
 	"bufio"
+	"bytes"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"os"
 	"path/filepath"
 	"sort"
 	"strconv"
 	"strings"
+	"syscall"
+	"time"
 
 	"github.com/snapcore/snapd/cmd/snaplock"
 	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/logger"
 	"github.com/snapcore/snapd/snap"
 )
 
@@ -204,7 +209,28 @@ func parsePids(reader io.Reader) ([]int, error) {
 //
 // The list is obtained from a pids cgroup.
 func pidsOfSecurityTag(securityTag string) ([]int, error) {
-	fname := filepath.Join(dirs.PidsCgroupDir, securityTag, "cgroup.procs")
+	return pidsOfCgroup(filepath.Join(dirs.PidsCgroupDir, securityTag))
+}
+
+// pidsOfSnap returns a list of PIDs belonging to a given snap, from all
+// of its apps and hooks.
+//
+// The list is obtained from the freezer cgroup of the snap.
+func pidsOfSnap(instanceName string) ([]int, error) {
+	return pidsOfCgroup(filepath.Join(dirs.FreezerCgroupDir, "snap."+instanceName))
+}
+
+// pidsOfService returns a list of PIDs belonging to the given service.
+//
+// The list is obtained from the cgroup systemd runs the service unit
+// in, which unlike the pids cgroup of the app does not depend on
+// refresh app awareness.
+func pidsOfService(app *snap.AppInfo) ([]int, error) {
+	return pidsOfCgroup(filepath.Join(dirs.SystemdCgroupDir, "system.slice", app.ServiceName()))
+}
+
+func pidsOfCgroup(dir string) ([]int, error) {
+	fname := filepath.Join(dir, "cgroup.procs")
 	file, err := os.Open(fname)
 	if os.IsNotExist(err) {
 		return nil, nil
@@ -215,3 +241,127 @@ func pidsOfSecurityTag(securityTag string) ([]int, error) {
 	defer file.Close()
 	return parsePids(bufio.NewReader(file))
 }
+
+var (
+	// killRunningWait is how long the processes of a snap have to
+	// exit after SIGTERM before they are killed
+	killRunningWait = 10 * time.Second
+	// killRunningPollInterval is how often killRunning looks for
+	// processes still alive
+	killRunningPollInterval = 100 * time.Millisecond
+
+	syscallKill = syscall.Kill
+)
+
+// pidsToKill returns the processes of the given snap that are not
+// services enduring refresh.
+func pidsToKill(info *snap.Info) ([]int, error) {
+	pids, err := pidsOfSnap(info.InstanceName())
+	if err != nil || len(pids) == 0 {
+		return nil, err
+	}
+	spared := make(map[int]bool)
+	for _, app := range info.Apps {
+		// TODO: use a constant instead of "endure"
+		if !app.IsService() || app.RefreshMode != "endure" {
+			continue
+		}
+		endurePIDs, err := pidsOfService(app)
+		if err != nil {
+			return nil, err
+		}
+		for _, pid := range endurePIDs {
+			spared[pid] = true
+		}
+	}
+	toKill := pids[:0]
+	for _, pid := range pids {
+		if !spared[pid] {
+			toKill = append(toKill, pid)
+		}
+	}
+	return toKill, nil
+}
+
+func signalPids(info *snap.Info, pids []int, sig syscall.Signal) error {
+	for _, pid := range pids {
+		if err := syscallKill(pid, sig); err != nil && err != syscall.ESRCH {
+			return fmt.Errorf("cannot signal process %d of snap %q: %v", pid, info.InstanceName(), err)
+		}
+	}
+	return nil
+}
+
+// killRunning terminates the processes of the given snap, apart from
+// services enduring refresh, so that it can be refreshed.
+//
+// The processes are first sent SIGTERM. Those still alive after
+// killRunningWait are frozen, so that they cannot fork anymore, and
+// killed with SIGKILL.
+func killRunning(info *snap.Info) error {
+	pids, err := pidsToKill(info)
+	if err != nil || len(pids) == 0 {
+		return err
+	}
+	if err := signalPids(info, pids, syscall.SIGTERM); err != nil {
+		return err
+	}
+
+	for waited := time.Duration(0); waited < killRunningWait; waited += killRunningPollInterval {
+		time.Sleep(killRunningPollInterval)
+		pids, err = pidsToKill(info)
+		if err != nil || len(pids) == 0 {
+			return err
+		}
+	}
+
+	if err := freezeSnapProcesses(info.InstanceName()); err != nil {
+		return err
+	}
+	// the killed processes only go away once thawed
+	defer thawSnapProcesses(info.InstanceName())
+	pids, err = pidsToKill(info)
+	if err != nil {
+		return err
+	}
+	logger.Noticef("killing the processes of snap %q still running after %v: %v", info.InstanceName(), killRunningWait, pids)
+	return signalPids(info, pids, syscall.SIGKILL)
+}
+
+func freezerStateFile(instanceName string) string {
+	return filepath.Join(dirs.FreezerCgroupDir, "snap."+instanceName, "freezer.state")
+}
+
+// freezeSnapProcesses freezes all the processes of the given snap.
+func freezeSnapProcesses(instanceName string) error {
+	fname := freezerStateFile(instanceName)
+	if err := ioutil.WriteFile(fname, []byte("FROZEN"), 0644); err != nil {
+		if os.IsNotExist(err) {
+			// no process of the snap was ever started
+			return nil
+		}
+		return fmt.Errorf("cannot freeze processes of snap %q: %v", instanceName, err)
+	}
+	for i := 0; i < 30; i++ {
+		data, err := ioutil.ReadFile(fname)
+		if err != nil {
+			return fmt.Errorf("cannot determine the freeze state of processes of snap %q: %v", instanceName, err)
+		}
+		// if the cgroup is still freezing wait a moment and try again
+		if !bytes.Equal(bytes.TrimSpace(data), []byte("FREEZING")) {
+			return nil
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	thawSnapProcesses(instanceName)
+	return fmt.Errorf("cannot finish freezing processes of snap %q", instanceName)
+}
+
+// thawSnapProcesses thaws all the processes of the given snap.
+func thawSnapProcesses(instanceName string) error {
+	err := ioutil.WriteFile(freezerStateFile(instanceName), []byte("THAWED"), 0644)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("cannot thaw processes of snap %q: %v", instanceName, err)
+	}
+	return nil
+}
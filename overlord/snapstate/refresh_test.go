@@ -25,6 +25,8 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"syscall"
+	"time"
 
 	. "gopkg.in/check.v1"
 
@@ -33,6 +35,7 @@ import (
 	"github.com/snapcore/snapd/overlord/state"
 	"github.com/snapcore/snapd/snap"
 	"github.com/snapcore/snapd/snap/snaptest"
+	"github.com/snapcore/snapd/testutil"
 )
 
 type refreshSuite struct {
@@ -161,3 +164,89 @@ func (s *refreshSuite) TestHardNothingRunningRefreshCheck(c *C) {
 	c.Check(err.Error(), Equals, `snap "foo" has running hooks (configure)`)
 	c.Check(err.(*snapstate.BusySnapError).Pids(), DeepEquals, []int{105})
 }
+
+func (s *refreshSuite) TestKillRunningNothingRunning(c *C) {
+	restore := snapstate.MockSyscallKill(func(pid int, sig syscall.Signal) error {
+		c.Fatalf("unexpected signal %s to %d", sig, pid)
+		return nil
+	})
+	defer restore()
+
+	// There are no errors when the freezer cgroup is absent.
+	c.Check(snapstate.KillRunning(s.info), IsNil)
+
+	writePids(c, filepath.Join(dirs.FreezerCgroupDir, "snap.foo"), nil)
+	c.Check(snapstate.KillRunning(s.info), IsNil)
+}
+
+func (s *refreshSuite) TestKillRunningTerminated(c *C) {
+	freezerPath := filepath.Join(dirs.FreezerCgroupDir, "snap.foo")
+	writePids(c, freezerPath, []int{100, 101, 105})
+	// The daemon endures refresh, it is found in the cgroup of its
+	// service unit as refresh app awareness is disabled and the pids
+	// cgroups are not populated.
+	s.info.Apps["daemon"].RefreshMode = "endure"
+	writePids(c, filepath.Join(dirs.SystemdCgroupDir, "system.slice", "snap.foo.daemon.service"), []int{100})
+	c.Check(s.daemonPath, testutil.FileAbsent)
+
+	var signals []string
+	restore := snapstate.MockSyscallKill(func(pid int, sig syscall.Signal) error {
+		signals = append(signals, fmt.Sprintf("%s %d", sig, pid))
+		if pid == 105 {
+			// The hook is already gone.
+			return syscall.ESRCH
+		}
+		writePids(c, freezerPath, []int{100})
+		return nil
+	})
+	defer restore()
+
+	c.Assert(snapstate.KillRunning(s.info), IsNil)
+	c.Check(signals, DeepEquals, []string{"terminated 101", "terminated 105"})
+	// Nothing was frozen.
+	c.Check(filepath.Join(freezerPath, "freezer.state"), testutil.FileAbsent)
+}
+
+func (s *refreshSuite) TestKillRunningKilled(c *C) {
+	restore := snapstate.MockKillRunningWait(30*time.Millisecond, 10*time.Millisecond)
+	defer restore()
+
+	freezerPath := filepath.Join(dirs.FreezerCgroupDir, "snap.foo")
+	writePids(c, freezerPath, []int{101})
+	freezerState := filepath.Join(freezerPath, "freezer.state")
+	err := ioutil.WriteFile(freezerState, []byte("THAWED"), 0644)
+	c.Assert(err, IsNil)
+
+	var signals []string
+	restore = snapstate.MockSyscallKill(func(pid int, sig syscall.Signal) error {
+		data, err := ioutil.ReadFile(freezerState)
+		c.Assert(err, IsNil)
+		signals = append(signals, fmt.Sprintf("%s %d %s", sig, pid, data))
+		if sig == syscall.SIGTERM {
+			// The process ignores SIGTERM and forks.
+			writePids(c, freezerPath, []int{101, 102})
+		}
+		return nil
+	})
+	defer restore()
+
+	c.Assert(snapstate.KillRunning(s.info), IsNil)
+	c.Check(signals, DeepEquals, []string{
+		"terminated 101 THAWED",
+		"killed 101 FROZEN",
+		"killed 102 FROZEN",
+	})
+	// The processes were thawed to let them die.
+	c.Check(freezerState, testutil.FileEquals, "THAWED")
+}
+
+func (s *refreshSuite) TestKillRunningError(c *C) {
+	writePids(c, filepath.Join(dirs.FreezerCgroupDir, "snap.foo"), []int{101})
+	restore := snapstate.MockSyscallKill(func(pid int, sig syscall.Signal) error {
+		return syscall.EPERM
+	})
+	defer restore()
+
+	err := snapstate.KillRunning(s.info)
+	c.Check(err, ErrorMatches, `cannot signal process 101 of snap "foo": operation not permitted`)
+}
@@ -0,0 +1,137 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2019 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package snapstate
+
+import (
+	"strings"
+	"time"
+
+	"gopkg.in/tomb.v2"
+
+	"github.com/snapcore/snapd/overlord/state"
+)
+
+// RefreshOutcome holds the outcome of the last refresh of a snap.
+type RefreshOutcome struct {
+	// LastAttempt is when the snap was last tried to be refreshed.
+	LastAttempt time.Time `json:"last-attempt"`
+	// LastSuccess is when the snap was last refreshed successfully.
+	LastSuccess *time.Time `json:"last-success,omitempty"`
+	// LastError is why the last attempt failed, if it did.
+	LastError string `json:"last-error,omitempty"`
+}
+
+// RefreshOutcomes returns the outcomes of the last refreshes of the
+// installed snaps, by instance name.
+func RefreshOutcomes(st *state.State) (map[string]*RefreshOutcome, error) {
+	var outcomes map[string]*RefreshOutcome
+	err := st.Get("refresh-outcomes", &outcomes)
+	if err != nil && err != state.ErrNoState {
+		return nil, err
+	}
+	return outcomes, nil
+}
+
+// refreshError returns why the refresh of the given snap in the change
+// failed, preferring the errors of the tasks of the snap itself.
+func refreshError(chg *state.Change, instanceName string) string {
+	var reason string
+	for _, t := range chg.Tasks() {
+		if t.Status() != state.ErrorStatus {
+			continue
+		}
+		if snapsup, err := TaskSnapSetup(t); err != nil || snapsup.InstanceName() != instanceName {
+			continue
+		}
+		for _, msg := range t.Log() {
+			// log entries are "<timestamp> <level> <message>"
+			parts := strings.SplitN(msg, " ", 3)
+			if len(parts) == 3 && parts[1] == state.LogError {
+				reason = parts[2]
+			}
+		}
+	}
+	if reason != "" {
+		return reason
+	}
+	if err := chg.Err(); err != nil {
+		return err.Error()
+	}
+	return "refresh did not complete"
+}
+
+// cleanupLinkSnap records the outcome of refreshes once their change
+// is ready.
+func (m *SnapManager) cleanupLinkSnap(t *state.Task, _ *tomb.Tomb) error {
+	st := t.State()
+	st.Lock()
+	defer st.Unlock()
+
+	var record bool
+	if err := t.Get("record-refresh-outcome", &record); err != nil && err != state.ErrNoState {
+		return err
+	}
+	if !record {
+		return nil
+	}
+
+	snapsup, err := TaskSnapSetup(t)
+	if err != nil {
+		return err
+	}
+	outcomes, err := RefreshOutcomes(st)
+	if err != nil {
+		return err
+	}
+	if outcomes == nil {
+		outcomes = make(map[string]*RefreshOutcome)
+	}
+	// forget about snaps removed meanwhile
+	all, err := All(st)
+	if err != nil {
+		return err
+	}
+	for name := range outcomes {
+		if all[name] == nil {
+			delete(outcomes, name)
+		}
+	}
+	if all[snapsup.InstanceName()] == nil {
+		st.Set("refresh-outcomes", outcomes)
+		return nil
+	}
+
+	chg := t.Change()
+	outcome := outcomes[snapsup.InstanceName()]
+	if outcome == nil {
+		outcome = &RefreshOutcome{}
+		outcomes[snapsup.InstanceName()] = outcome
+	}
+	outcome.LastAttempt = chg.SpawnTime()
+	if t.Status() == state.DoneStatus {
+		readyTime := chg.ReadyTime()
+		outcome.LastSuccess = &readyTime
+		outcome.LastError = ""
+	} else {
+		outcome.LastError = refreshError(chg, snapsup.InstanceName())
+	}
+	st.Set("refresh-outcomes", outcomes)
+	return nil
+}
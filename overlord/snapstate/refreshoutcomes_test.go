@@ -0,0 +1,121 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2019 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package snapstate_test
+
+import (
+	"context"
+	"path/filepath"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/overlord/snapstate"
+	"github.com/snapcore/snapd/snap"
+)
+
+func (s *snapmgrTestSuite) setupRefreshOutcomeSnap() {
+	si := snap.SideInfo{
+		RealName: "some-snap",
+		SnapID:   "some-snap-id",
+		Revision: snap.R(7),
+	}
+	snapstate.Set(s.state, "some-snap", &snapstate.SnapState{
+		Active:   true,
+		Sequence: []*snap.SideInfo{&si},
+		Current:  si.Revision,
+		SnapType: "app",
+	})
+}
+
+func (s *snapmgrTestSuite) TestRefreshOutcomeSuccess(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	s.setupRefreshOutcomeSnap()
+
+	chg := s.state.NewChange("refresh", "refresh a snap")
+	ts, err := snapstate.Update(s.state, "some-snap", &snapstate.RevisionOptions{Channel: "some-channel"}, s.user.ID, snapstate.Flags{})
+	c.Assert(err, IsNil)
+	chg.AddAll(ts)
+
+	s.state.Unlock()
+	defer s.se.Stop()
+	s.settle(c)
+	s.state.Lock()
+
+	c.Assert(chg.Err(), IsNil)
+	outcomes, err := snapstate.RefreshOutcomes(s.state)
+	c.Assert(err, IsNil)
+	c.Assert(outcomes, HasLen, 1)
+	outcome := outcomes["some-snap"]
+	c.Assert(outcome, NotNil)
+	c.Check(outcome.LastAttempt.Equal(chg.SpawnTime()), Equals, true)
+	c.Assert(outcome.LastSuccess, NotNil)
+	c.Check(outcome.LastSuccess.Equal(chg.ReadyTime()), Equals, true)
+	c.Check(outcome.LastError, Equals, "")
+}
+
+func (s *snapmgrTestSuite) TestRefreshOutcomeFailure(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	s.setupRefreshOutcomeSnap()
+
+	chg := s.state.NewChange("refresh", "refresh a snap")
+	ts, err := snapstate.Update(s.state, "some-snap", &snapstate.RevisionOptions{Channel: "some-channel"}, s.user.ID, snapstate.Flags{})
+	c.Assert(err, IsNil)
+	chg.AddAll(ts)
+
+	s.fakeBackend.linkSnapFailTrigger = filepath.Join(dirs.SnapMountDir, "/some-snap/11")
+
+	s.state.Unlock()
+	defer s.se.Stop()
+	s.settle(c)
+	s.state.Lock()
+
+	c.Assert(chg.Err(), NotNil)
+	outcomes, err := snapstate.RefreshOutcomes(s.state)
+	c.Assert(err, IsNil)
+	outcome := outcomes["some-snap"]
+	c.Assert(outcome, NotNil)
+	c.Check(outcome.LastAttempt.Equal(chg.SpawnTime()), Equals, true)
+	c.Check(outcome.LastSuccess, IsNil)
+	c.Check(outcome.LastError, Equals, "fail")
+}
+
+func (s *snapmgrTestSuite) TestRefreshOutcomeNotForInstalls(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	chg := s.state.NewChange("install", "install a snap")
+	ts, err := snapstate.Install(context.Background(), s.state, "some-snap", &snapstate.RevisionOptions{Channel: "some-channel"}, s.user.ID, snapstate.Flags{})
+	c.Assert(err, IsNil)
+	chg.AddAll(ts)
+
+	s.state.Unlock()
+	defer s.se.Stop()
+	s.settle(c)
+	s.state.Lock()
+
+	c.Assert(chg.Err(), IsNil)
+	outcomes, err := snapstate.RefreshOutcomes(s.state)
+	c.Assert(err, IsNil)
+	c.Check(outcomes, HasLen, 0)
+}
@@ -35,6 +35,7 @@ import (
 	"github.com/snapcore/snapd/logger"
 	"github.com/snapcore/snapd/osutil"
 	"github.com/snapcore/snapd/overlord/snapstate/backend"
+	"github.com/snapcore/snapd/overlord/standby"
 	"github.com/snapcore/snapd/overlord/state"
 	"github.com/snapcore/snapd/release"
 	"github.com/snapcore/snapd/snap"
@@ -53,6 +54,7 @@ type SnapManager struct {
 	autoRefresh    *autoRefresh
 	refreshHints   *refreshHints
 	catalogRefresh *catalogRefresh
+	authRefresh    *userAuthRefresh
 
 	lastUbuntuCoreTransitionAttempt time.Time
 }
@@ -71,6 +73,23 @@ type SnapSetup struct {
 
 	CohortKey string `json:"cohort-key,omitempty"`
 
+	// MigrateData indicates that the refresh crosses epochs and the
+	// data-migrate hook of the new revision needs to run, guarded by
+	// a snapshot of the data of the current revision.
+	MigrateData bool `json:"migrate-data,omitempty"`
+
+	// MigrateBase indicates that the refresh moves the snap to the
+	// different base published with the new revision. The data of
+	// the current revision is saved as for MigrateData, and the
+	// refresh is undone if the new revision reports an error from
+	// its health check.
+	MigrateBase bool `json:"migrate-base,omitempty"`
+
+	// MigrationSnapshot is the snapshot set holding the data saved
+	// before migrating it, recorded in SnapState.DataMigration once
+	// the new revision is linked.
+	MigrationSnapshot uint64 `json:"migration-snapshot,omitempty"`
+
 	// FIXME: implement rename of this as suggested in
 	//  https://github.com/snapcore/snapd/pull/4103#discussion_r169569717
 	//
@@ -90,6 +109,10 @@ type SnapSetup struct {
 	// InstanceKey is set by the user during installation and differs for
 	// each instance of given snap
 	InstanceKey string `json:"instance-key,omitempty"`
+
+	// AcceptedTerms is the acceptance of the license agreement of the
+	// snap given for this installation, recorded once the snap is linked.
+	AcceptedTerms *termsAcceptance `json:"accepted-terms,omitempty"`
 }
 
 func (snapsup *SnapSetup) InstanceName() string {
@@ -147,6 +170,27 @@ type SnapState struct {
 	// attempted but inhibited because the snap was busy. This value is
 	// reset on each successful refresh.
 	RefreshInhibitedTime *time.Time `json:"refresh-inhibited-time,omitempty"`
+
+	// LastActiveDisabledServices lists the services of the snap that
+	// were disabled (or masked) by the user when its services were
+	// last stopped. They are kept disabled across refresh, revert and
+	// disable/enable, also for revisions that drop and later bring
+	// back a service.
+	LastActiveDisabledServices []string `json:"last-active-disabled-services,omitempty"`
+
+	// DataMigration records the data saved before the last refresh
+	// that migrated it, to restore it when reverting.
+	DataMigration *DataMigration `json:"data-migration,omitempty"`
+}
+
+// DataMigration records where the data of a snap was saved before it
+// got migrated by a refresh across epochs or bases.
+type DataMigration struct {
+	// Revision is the revision the snap was refreshed from, whose
+	// data was saved.
+	Revision snap.Revision `json:"revision"`
+	// SnapshotSetID is the snapshot set holding the saved data.
+	SnapshotSetID uint64 `json:"snapshot-set-id"`
 }
 
 // Type returns the type of the snap or an error.
@@ -252,6 +296,15 @@ var snapReadInfo = snap.ReadInfo
 var AutomaticSnapshot func(st *state.State, instanceName string) (ts *state.TaskSet, err error)
 var AutomaticSnapshotExpiration func(st *state.State) (time.Duration, error)
 
+// MigrationSnapshot allows to hook snapshot manager's MigrationSnapshot.
+var MigrationSnapshot func(st *state.State, instanceName string) (setID uint64, ts *state.TaskSet, err error)
+
+// RestoreMigrationSnapshot allows to hook snapshot manager's RestoreMigrationSnapshot.
+var RestoreMigrationSnapshot func(st *state.State, instanceName string, setID uint64, rev snap.Revision) (ts *state.TaskSet, err error)
+
+// KeepDataSnapshot allows to hook snapshot manager's KeepDataSnapshot.
+var KeepDataSnapshot func(st *state.State, instanceName string, keepFor time.Duration) (ts *state.TaskSet, err error)
+
 func readInfo(name string, si *snap.SideInfo, flags int) (*snap.Info, error) {
 	info, err := snapReadInfo(name, si)
 	if err != nil && flags&errorOnBroken != 0 {
@@ -354,6 +407,7 @@ func Manager(st *state.State, runner *state.TaskRunner) (*SnapManager, error) {
 		autoRefresh:    newAutoRefresh(st),
 		refreshHints:   newRefreshHints(st),
 		catalogRefresh: newCatalogRefresh(st),
+		authRefresh:    newUserAuthRefresh(st),
 	}
 
 	if err := os.MkdirAll(dirs.SnapCookieDir, 0700); err != nil {
@@ -364,6 +418,10 @@ func Manager(st *state.State, runner *state.TaskRunner) (*SnapManager, error) {
 		return nil, fmt.Errorf("cannot generate request salt: %v", err)
 	}
 
+	st.Lock()
+	m.loadQuickStart()
+	st.Unlock()
+
 	// this handler does nothing
 	runner.AddHandler("nop", func(t *state.Task, _ *tomb.Tomb) error {
 		return nil
@@ -376,12 +434,15 @@ func Manager(st *state.State, runner *state.TaskRunner) (*SnapManager, error) {
 	runner.AddHandler("prerequisites", m.doPrerequisites, nil)
 	runner.AddHandler("prepare-snap", m.doPrepareSnap, m.undoPrepareSnap)
 	runner.AddHandler("download-snap", m.doDownloadSnap, m.undoPrepareSnap)
+	runner.AddHandler("prefetch-snap", m.doPrefetchSnap, m.undoPrefetchSnap)
 	runner.AddHandler("mount-snap", m.doMountSnap, m.undoMountSnap)
 	runner.AddHandler("unlink-current-snap", m.doUnlinkCurrentSnap, m.undoUnlinkCurrentSnap)
 	runner.AddHandler("copy-snap-data", m.doCopySnapData, m.undoCopySnapData)
 	runner.AddCleanup("copy-snap-data", m.cleanupCopySnapData)
 	runner.AddHandler("link-snap", m.doLinkSnap, m.undoLinkSnap)
+	runner.AddCleanup("link-snap", m.cleanupLinkSnap)
 	runner.AddHandler("start-snap-services", m.startSnapServices, m.stopSnapServices)
+	runner.AddHandler("update-desktop-caches", m.doUpdateDesktopCaches, nil)
 	runner.AddHandler("switch-snap-channel", m.doSwitchSnapChannel, nil)
 	runner.AddHandler("toggle-snap-flags", m.doToggleSnapFlags, nil)
 	runner.AddHandler("check-rerefresh", m.doCheckReRefresh, nil)
@@ -424,7 +485,33 @@ func (m *SnapManager) CanStandby() bool {
 	if n, err := NumSnaps(m.state); err == nil && n == 0 {
 		return true
 	}
-	return false
+	// with idle exit enabled snapd gets woken up periodically to
+	// look after the installed snaps (e.g. to refresh them)
+	return standby.IdleExitTimeout(m.state) > 0
+}
+
+// quickStartKey is where SnapManager keeps its quick-start snapshot
+const quickStartKey = "snapstate"
+
+// QuickStartSnapshot returns a snapshot of the in memory state of the
+// automatic refreshes to be kept while snapd is in socket activation
+// mode.
+func (m *SnapManager) QuickStartSnapshot() (key string, snapshot interface{}) {
+	return quickStartKey, m.autoRefresh.snapshot()
+}
+
+func (m *SnapManager) loadQuickStart() {
+	var snapshot autoRefreshSnapshot
+	err := standby.LoadQuickStart(m.state, quickStartKey, &snapshot)
+	if err == state.ErrNoState {
+		return
+	}
+	if err != nil {
+		// the snapshot is only an optimization, start afresh
+		logger.Noticef("Cannot load quick-start snapshot: %v", err)
+		return
+	}
+	m.autoRefresh.restore(&snapshot)
 }
 
 func genRefreshRequestSalt(st *state.State) error {
@@ -681,6 +768,7 @@ func (m *SnapManager) Ensure() error {
 		m.autoRefresh.Ensure(),
 		m.refreshHints.Ensure(),
 		m.catalogRefresh.Ensure(),
+		m.authRefresh.Ensure(),
 		m.localInstallCleanup(),
 	}
 
@@ -31,6 +31,7 @@ import (
 	"time"
 
 	"github.com/snapcore/snapd/asserts"
+	"github.com/snapcore/snapd/asserts/snapasserts"
 	"github.com/snapcore/snapd/boot"
 	"github.com/snapcore/snapd/dirs"
 	"github.com/snapcore/snapd/features"
@@ -125,7 +126,7 @@ func doInstall(st *state.State, snapst *SnapState, snapsup *SnapSetup, flags int
 		}
 		snapsup.PlugsOnly = snapsup.PlugsOnly && (len(info.Slots) == 0)
 
-		if experimentalRefreshAppAwareness {
+		if experimentalRefreshAppAwareness && !snapsup.IgnoreRunning && !snapsup.KillRunning {
 			// Note that because we are modifying the snap state this block
 			// must be located after the conflict check done above.
 			if err := inhibitRefresh(st, snapst, info, SoftNothingRunningRefreshCheck); err != nil {
@@ -145,6 +146,30 @@ func doInstall(st *state.State, snapst *SnapState, snapsup *SnapSetup, flags int
 	// check if we already have the revision locally (alters tasks)
 	revisionIsLocal := snapst.LastIndex(targetRevision) >= 0
 
+	// run refresh hooks when updating existing snap, otherwise run install hook further down.
+	runRefreshHooks := (snapst.IsInstalled() && !snapsup.Flags.Revert)
+
+	var dataSnapshot *state.TaskSet
+	if runRefreshHooks && (snapsup.MigrateData || snapsup.MigrateBase) {
+		// save the data of the current revision before it gets
+		// migrated, undoing the save restores it
+		setID, snapshot, err := MigrationSnapshot(st, snapsup.InstanceName())
+		if err != nil {
+			return nil, err
+		}
+		snapsup.MigrationSnapshot = setID
+		dataSnapshot = snapshot
+	}
+	if snapsup.Flags.Revert && snapst.DataMigration != nil && snapst.DataMigration.Revision == targetRevision {
+		// put back the data the revision had before it got
+		// migrated
+		restore, err := RestoreMigrationSnapshot(st, snapsup.InstanceName(), snapst.DataMigration.SnapshotSetID, targetRevision)
+		if err != nil {
+			return nil, err
+		}
+		dataSnapshot = restore
+	}
+
 	prereq := st.NewTask("prerequisites", fmt.Sprintf(i18n.G("Ensure prerequisites for %q are available"), snapsup.InstanceName()))
 	prereq.Set("snap-setup", snapsup)
 
@@ -183,8 +208,6 @@ func doInstall(st *state.State, snapst *SnapState, snapsup *SnapSetup, flags int
 		prev = mount
 	}
 
-	// run refresh hooks when updating existing snap, otherwise run install hook further down.
-	runRefreshHooks := (snapst.IsInstalled() && !snapsup.Flags.Revert)
 	if runRefreshHooks {
 		preRefreshHook := SetupPreRefreshHook(st, snapsup.InstanceName())
 		addTask(preRefreshHook)
@@ -198,6 +221,13 @@ func doInstall(st *state.State, snapst *SnapState, snapsup *SnapSetup, flags int
 		addTask(stop)
 		prev = stop
 
+		if dataSnapshot != nil {
+			for _, t := range dataSnapshot.Tasks() {
+				addTask(t)
+				prev = t
+			}
+		}
+
 		removeAliases := st.NewTask("remove-aliases", fmt.Sprintf(i18n.G("Remove aliases for snap %q"), snapsup.InstanceName()))
 		addTask(removeAliases)
 		prev = removeAliases
@@ -228,6 +258,10 @@ func doInstall(st *state.State, snapst *SnapState, snapsup *SnapSetup, flags int
 
 	// finalize (wrappers+current symlink)
 	linkSnap := st.NewTask("link-snap", fmt.Sprintf(i18n.G("Make snap %q%s available to the system"), snapsup.InstanceName(), revisionStr))
+	if runRefreshHooks {
+		// remember how the refresh went once the change is ready
+		linkSnap.Set("record-refresh-outcome", true)
+	}
 	addTask(linkSnap)
 	prev = linkSnap
 
@@ -245,6 +279,12 @@ func doInstall(st *state.State, snapst *SnapState, snapsup *SnapSetup, flags int
 	addTask(setupAliases)
 	prev = setupAliases
 
+	if runRefreshHooks && (snapsup.MigrateData || snapsup.MigrateBase) {
+		dataMigrateHook := SetupDataMigrateHook(st, snapsup.InstanceName())
+		addTask(dataMigrateHook)
+		prev = dataMigrateHook
+	}
+
 	if runRefreshHooks {
 		postRefreshHook := SetupPostRefreshHook(st, snapsup.InstanceName())
 		addTask(postRefreshHook)
@@ -263,6 +303,14 @@ func doInstall(st *state.State, snapst *SnapState, snapsup *SnapSetup, flags int
 	addTask(startSnapServices)
 	prev = startSnapServices
 
+	// the fontconfig caches are updated once the snap is runnable when
+	// linking was asked not to wait for them
+	if release.OnClassic && snapsup.Type != snap.TypeOS && snapsup.Type != snap.TypeSnapd && backend.DesktopCacheGeneration() == "async" {
+		updateDesktopCaches := st.NewTask("update-desktop-caches", fmt.Sprintf(i18n.G("Update desktop caches for snap %q%s"), snapsup.InstanceName(), revisionStr))
+		addTask(updateDesktopCaches)
+		prev = updateDesktopCaches
+	}
+
 	// Do not do that if we are reverting to a local revision
 	if snapst.IsInstalled() && !snapsup.Flags.Revert {
 		var retain int
@@ -347,6 +395,10 @@ func doInstall(st *state.State, snapst *SnapState, snapsup *SnapSetup, flags int
 	}
 
 	healthCheck := CheckHealthHook(st, snapsup.InstanceName(), snapsup.Revision())
+	if runRefreshHooks && snapsup.MigrateBase {
+		// an unhealthy snap on its new base is rolled back
+		healthCheck.Set("undo-if-unhealthy", true)
+	}
 	healthCheck.WaitAll(ts)
 	ts.AddTask(healthCheck)
 
@@ -381,6 +433,10 @@ var SetupPostRefreshHook = func(st *state.State, snapName string) *state.Task {
 	panic("internal error: snapstate.SetupPostRefreshHook is unset")
 }
 
+var SetupDataMigrateHook = func(st *state.State, snapName string) *state.Task {
+	panic("internal error: snapstate.SetupDataMigrateHook is unset")
+}
+
 var SetupRemoveHook = func(st *state.State, snapName string) *state.Task {
 	panic("internal error: snapstate.SetupRemoveHook is unset")
 }
@@ -568,9 +624,19 @@ func checkInstallPreconditions(st *state.State, info *snap.Info, flags Flags, sn
 // local revision and sideloading, or full metadata in which case it
 // the snap will appear as installed from the store.
 func InstallPath(st *state.State, si *snap.SideInfo, path, instanceName, channel string, flags Flags) (*state.TaskSet, *snap.Info, error) {
+	return InstallPathWithCohort(st, si, path, instanceName, channel, "", flags)
+}
+
+// InstallPathWithCohort is like InstallPath but the installed snap
+// also joins the given cohort, so that it refreshes along with it. A
+// cohort can only be given for a snap with store metadata.
+func InstallPathWithCohort(st *state.State, si *snap.SideInfo, path, instanceName, channel, cohortKey string, flags Flags) (*state.TaskSet, *snap.Info, error) {
 	if si.RealName == "" {
 		return nil, nil, fmt.Errorf("internal error: snap name to install %q not provided", path)
 	}
+	if cohortKey != "" && si.SnapID == "" {
+		return nil, nil, fmt.Errorf("cannot install local snap %q into a cohort", si.RealName)
+	}
 
 	if instanceName == "" {
 		instanceName = si.RealName
@@ -637,6 +703,14 @@ func InstallPath(st *state.State, si *snap.SideInfo, path, instanceName, channel
 	if err := earlyEpochCheck(info, &snapst); err != nil {
 		return nil, nil, err
 	}
+	migrateData, err := crossesEpoch(info, &snapst)
+	if err != nil {
+		return nil, nil, err
+	}
+	migrateBase, err := crossesBase(info, &snapst)
+	if err != nil {
+		return nil, nil, err
+	}
 
 	snapsup := &SnapSetup{
 		Base:        info.Base,
@@ -644,10 +718,13 @@ func InstallPath(st *state.State, si *snap.SideInfo, path, instanceName, channel
 		SideInfo:    si,
 		SnapPath:    path,
 		Channel:     channel,
+		CohortKey:   cohortKey,
 		Flags:       flags.ForSnapSetup(),
 		Type:        info.GetType(),
 		PlugsOnly:   len(info.Slots) == 0,
 		InstanceKey: info.InstanceKey,
+		MigrateData: migrateData,
+		MigrateBase: migrateBase,
 	}
 
 	ts, err := doInstall(st, &snapst, snapsup, instFlags, "")
@@ -723,6 +800,10 @@ func InstallWithDeviceContext(ctx context.Context, st *state.State, name string,
 	if err := checkInstallPreconditions(st, info, flags, &snapst, deviceCtx); err != nil {
 		return nil, err
 	}
+	acceptedTerms, err := checkTerms(st, info, flags)
+	if err != nil {
+		return nil, err
+	}
 
 	snapsup := &SnapSetup{
 		Channel:      opts.Channel,
@@ -738,7 +819,8 @@ func InstallWithDeviceContext(ctx context.Context, st *state.State, name string,
 		auxStoreInfo: auxStoreInfo{
 			Media: info.Media,
 		},
-		CohortKey: opts.CohortKey,
+		CohortKey:     opts.CohortKey,
+		AcceptedTerms: acceptedTerms,
 	}
 
 	return doInstall(st, &snapst, snapsup, 0, fromChange)
@@ -746,7 +828,11 @@ func InstallWithDeviceContext(ctx context.Context, st *state.State, name string,
 
 // InstallMany installs everything from the given list of names.
 // Note that the state must be locked by the caller.
-func InstallMany(st *state.State, names []string, userID int) ([]string, []*state.TaskSet, error) {
+func InstallMany(st *state.State, names []string, userID int, flags *Flags) ([]string, []*state.TaskSet, error) {
+	if flags == nil {
+		flags = &Flags{}
+	}
+
 	// need to have a model set before trying to talk the store
 	deviceCtx, err := DevicePastSeeding(st, nil)
 	if err != nil {
@@ -784,23 +870,27 @@ func InstallMany(st *state.State, names []string, userID int) ([]string, []*stat
 	tasksets := make([]*state.TaskSet, 0, len(installs))
 	for _, info := range installs {
 		var snapst SnapState
-		var flags Flags
 
-		if err := checkInstallPreconditions(st, info, flags, &snapst, deviceCtx); err != nil {
+		if err := checkInstallPreconditions(st, info, *flags, &snapst, deviceCtx); err != nil {
+			return nil, nil, err
+		}
+		acceptedTerms, err := checkTerms(st, info, *flags)
+		if err != nil {
 			return nil, nil, err
 		}
 
 		snapsup := &SnapSetup{
-			Channel:      "stable",
-			Base:         info.Base,
-			Prereq:       defaultContentPlugProviders(st, info),
-			UserID:       userID,
-			Flags:        flags.ForSnapSetup(),
-			DownloadInfo: &info.DownloadInfo,
-			SideInfo:     &info.SideInfo,
-			Type:         info.GetType(),
-			PlugsOnly:    len(info.Slots) == 0,
-			InstanceKey:  info.InstanceKey,
+			Channel:       "stable",
+			Base:          info.Base,
+			Prereq:        defaultContentPlugProviders(st, info),
+			UserID:        userID,
+			Flags:         flags.ForSnapSetup(),
+			DownloadInfo:  &info.DownloadInfo,
+			SideInfo:      &info.SideInfo,
+			Type:          info.GetType(),
+			PlugsOnly:     len(info.Slots) == 0,
+			InstanceKey:   info.InstanceKey,
+			AcceptedTerms: acceptedTerms,
 		}
 
 		ts, err := doInstall(st, &snapst, snapsup, 0, "")
@@ -821,6 +911,10 @@ func RefreshCandidates(st *state.State, user *auth.UserState) ([]*snap.Info, err
 	return updates, err
 }
 
+// EnforcedValidationSets allows to hook retrieving the validation-sets
+// currently in enforce mode.
+var EnforcedValidationSets func(st *state.State) ([]*asserts.ValidationSet, error)
+
 // ValidateRefreshes allows to hook validation into the handling of refresh candidates.
 var ValidateRefreshes func(st *state.State, refreshes []*snap.Info, ignoreValidation map[string]bool, userID int, deviceCtx DeviceContext) (validated []*snap.Info, err error)
 
@@ -961,6 +1055,8 @@ func doUpdate(ctx context.Context, st *state.State, names []string, updates []*s
 	for _, update := range updates {
 		revnoOpts, flags, snapst := params(update)
 		flags.IsAutoRefresh = globalFlags.IsAutoRefresh
+		flags.IgnoreRunning = globalFlags.IgnoreRunning
+		flags.KillRunning = globalFlags.KillRunning
 
 		if err := checkInstallPreconditions(st, update, flags, snapst, deviceCtx); err != nil {
 			if refreshAll {
@@ -983,6 +1079,23 @@ func doUpdate(ctx context.Context, st *state.State, names []string, updates []*s
 			return nil, nil, err
 		}
 
+		migrateData, err := crossesEpoch(update, snapst)
+		if err != nil {
+			if refreshAll {
+				logger.Noticef("cannot update %q: %v", update.InstanceName(), err)
+				continue
+			}
+			return nil, nil, err
+		}
+		migrateBase, err := crossesBase(update, snapst)
+		if err != nil {
+			if refreshAll {
+				logger.Noticef("cannot update %q: %v", update.InstanceName(), err)
+				continue
+			}
+			return nil, nil, err
+		}
+
 		snapsup := &SnapSetup{
 			Base:         update.Base,
 			Prereq:       defaultContentPlugProviders(st, update),
@@ -995,6 +1108,8 @@ func doUpdate(ctx context.Context, st *state.State, names []string, updates []*s
 			Type:         update.GetType(),
 			PlugsOnly:    len(update.Slots) == 0,
 			InstanceKey:  update.InstanceKey,
+			MigrateData:  migrateData,
+			MigrateBase:  migrateBase,
 			auxStoreInfo: auxStoreInfo{
 				Media: update.Media,
 			},
@@ -1796,6 +1911,9 @@ func canRemove(st *state.State, si *snap.Info, snapst *SnapState, removeAll bool
 type RemoveFlags struct {
 	// Remove the snap without creating snapshot data
 	Purge bool
+	// KeepDataFor keeps a snapshot of the snap data for the given
+	// duration so that it can be restored on reinstall
+	KeepDataFor time.Duration
 }
 
 // Remove returns a set of tasks for removing snap.
@@ -1849,11 +1967,37 @@ func Remove(st *state.State, name string, revision snap.Revision, flags *RemoveF
 		return nil, err
 	}
 
+	if flags != nil && flags.KeepDataFor != 0 {
+		switch {
+		case flags.KeepDataFor < 0:
+			return nil, fmt.Errorf("cannot keep data of snap %q for a negative duration", name)
+		case flags.Purge:
+			return nil, fmt.Errorf("cannot keep data of snap %q and purge it at the same time", name)
+		case !removeAll:
+			return nil, fmt.Errorf("cannot keep data of snap %q when removing only revision %s", name, revision)
+		case info.GetType() != snap.TypeApp:
+			return nil, fmt.Errorf("cannot keep data of snap %q of type %q", name, info.GetType())
+		case KeepDataSnapshot == nil:
+			return nil, fmt.Errorf("internal error: cannot keep data of snap %q without snapshot support", name)
+		}
+	}
+
 	// check if this is something that can be removed
 	if !canRemove(st, info, &snapst, removeAll, deviceCtx) {
 		return nil, fmt.Errorf("snap %q is not removable", name)
 	}
 
+	if removeAll && EnforcedValidationSets != nil {
+		sets, err := EnforcedValidationSets(st)
+		if err != nil {
+			return nil, err
+		}
+		sn := &snapasserts.InstalledSnap{Name: info.SnapName(), SnapID: info.SnapID, Revision: info.Revision}
+		if keys := snapasserts.RequiredBy(sets, sn); len(keys) != 0 {
+			return nil, fmt.Errorf("cannot remove snap %q: required by enforced validation sets: %s", name, strings.Join(keys, ","))
+		}
+	}
+
 	// main/current SnapSetup
 	snapsup := SnapSetup{
 		SideInfo: &snap.SideInfo{
@@ -1910,7 +2054,13 @@ func Remove(st *state.State, name string, revision snap.Revision, flags *RemoveF
 	// 'purge' flag disables automatic snapshot for given remove op
 	if flags == nil || !flags.Purge {
 		if tp, _ := snapst.Type(); tp == snap.TypeApp && removeAll {
-			ts, err := AutomaticSnapshot(st, name)
+			var ts *state.TaskSet
+			var err error
+			if flags != nil && flags.KeepDataFor != 0 {
+				ts, err = KeepDataSnapshot(st, name, flags.KeepDataFor)
+			} else {
+				ts, err = AutomaticSnapshot(st, name)
+			}
 			if err == nil {
 				addNext(ts)
 			} else {
@@ -37,6 +37,7 @@ import (
 	"gopkg.in/tomb.v2"
 
 	"github.com/snapcore/snapd/asserts"
+	"github.com/snapcore/snapd/asserts/assertstest"
 	"github.com/snapcore/snapd/boot/boottest"
 	"github.com/snapcore/snapd/bootloader"
 	"github.com/snapcore/snapd/dirs"
@@ -110,10 +111,12 @@ func (s *snapmgrTestSuite) SetUpTest(c *C) {
 	oldSetupInstallHook := snapstate.SetupInstallHook
 	oldSetupPreRefreshHook := snapstate.SetupPreRefreshHook
 	oldSetupPostRefreshHook := snapstate.SetupPostRefreshHook
+	oldSetupDataMigrateHook := snapstate.SetupDataMigrateHook
 	oldSetupRemoveHook := snapstate.SetupRemoveHook
 	snapstate.SetupInstallHook = hookstate.SetupInstallHook
 	snapstate.SetupPreRefreshHook = hookstate.SetupPreRefreshHook
 	snapstate.SetupPostRefreshHook = hookstate.SetupPostRefreshHook
+	snapstate.SetupDataMigrateHook = hookstate.SetupDataMigrateHook
 	snapstate.SetupRemoveHook = hookstate.SetupRemoveHook
 
 	var err error
@@ -143,6 +146,7 @@ func (s *snapmgrTestSuite) SetUpTest(c *C) {
 		snapstate.SetupInstallHook = oldSetupInstallHook
 		snapstate.SetupPreRefreshHook = oldSetupPreRefreshHook
 		snapstate.SetupPostRefreshHook = oldSetupPostRefreshHook
+		snapstate.SetupDataMigrateHook = oldSetupDataMigrateHook
 		snapstate.SetupRemoveHook = oldSetupRemoveHook
 
 		dirs.SetRootDir("/")
@@ -160,11 +164,36 @@ func (s *snapmgrTestSuite) SetUpTest(c *C) {
 		return ts, nil
 	}
 
+	oldMigrationSnapshot := snapstate.MigrationSnapshot
+	snapstate.MigrationSnapshot = func(st *state.State, instanceName string) (setID uint64, ts *state.TaskSet, err error) {
+		task := st.NewTask("save-snapshot", "...")
+		ts = state.NewTaskSet(task)
+		return 42, ts, nil
+	}
+
+	oldRestoreMigrationSnapshot := snapstate.RestoreMigrationSnapshot
+	snapstate.RestoreMigrationSnapshot = func(st *state.State, instanceName string, setID uint64, rev snap.Revision) (ts *state.TaskSet, err error) {
+		task := st.NewTask("restore-snapshot", fmt.Sprintf("restore set #%d into %s", setID, rev))
+		ts = state.NewTaskSet(task)
+		return ts, nil
+	}
+
+	oldKeepDataSnapshot := snapstate.KeepDataSnapshot
+	snapstate.KeepDataSnapshot = func(st *state.State, instanceName string, keepFor time.Duration) (ts *state.TaskSet, err error) {
+		task := st.NewTask("save-snapshot", "...")
+		task.Set("keep-for", keepFor)
+		ts = state.NewTaskSet(task)
+		return ts, nil
+	}
+
 	oldAutomaticSnapshotExpiration := snapstate.AutomaticSnapshotExpiration
 	snapstate.AutomaticSnapshotExpiration = func(st *state.State) (time.Duration, error) { return 1, nil }
 	s.BaseTest.AddCleanup(func() {
 		snapstate.AutomaticSnapshot = oldAutomaticSnapshot
 		snapstate.AutomaticSnapshotExpiration = oldAutomaticSnapshotExpiration
+		snapstate.MigrationSnapshot = oldMigrationSnapshot
+		snapstate.RestoreMigrationSnapshot = oldRestoreMigrationSnapshot
+		snapstate.KeepDataSnapshot = oldKeepDataSnapshot
 	})
 
 	s.state.Lock()
@@ -242,7 +271,14 @@ func AddForeignTaskHandlers(runner *state.TaskRunner, tracker ForeignTaskTracker
 
 	runner.AddHandler("save-snapshot", func(task *state.Task, _ *tomb.Tomb) error {
 		return nil
-	}, nil)
+	}, func(task *state.Task, _ *tomb.Tomb) error {
+		return nil
+	})
+	runner.AddHandler("restore-snapshot", func(task *state.Task, _ *tomb.Tomb) error {
+		return nil
+	}, func(task *state.Task, _ *tomb.Tomb) error {
+		return nil
+	})
 	runner.AddHandler("run-hook", func(task *state.Task, _ *tomb.Tomb) error {
 		return nil
 	}, nil)
@@ -938,6 +974,53 @@ func (s snapmgrTestSuite) TestInstallFailsOnBusySnap(c *C) {
 	c.Check(snapst.RefreshInhibitedTime, NotNil)
 }
 
+func (s snapmgrTestSuite) TestInstallBusySnapIgnoreOrKillRunning(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	// With the refresh-app-awareness feature enabled.
+	tr := config.NewTransaction(s.state)
+	tr.Set("core", "experimental.refresh-app-awareness", true)
+	tr.Commit()
+
+	snapst := &snapstate.SnapState{
+		Active: true,
+		Sequence: []*snap.SideInfo{
+			{RealName: "some-snap", SnapID: "some-snap-id", Revision: snap.R(1)},
+		},
+		Current:  snap.R(1),
+		SnapType: "app",
+	}
+	snapstate.Set(s.state, "some-snap", snapst)
+
+	snapstate.MockSnapReadInfo(func(name string, si *snap.SideInfo) (*snap.Info, error) {
+		if name != "some-snap" {
+			return s.fakeBackend.ReadInfo(name, si)
+		}
+		info := &snap.Info{SuggestedName: name, SideInfo: *si, SnapType: snap.TypeApp}
+		info.Apps = map[string]*snap.AppInfo{
+			"app": {Snap: info, Name: "app"},
+		}
+		return info, nil
+	})
+	writePids(c, filepath.Join(dirs.PidsCgroupDir, "snap.some-snap.app"), []int{1234})
+
+	for _, flags := range []snapstate.Flags{{IgnoreRunning: true}, {KillRunning: true}} {
+		snapsup := &snapstate.SnapSetup{
+			SideInfo: &snap.SideInfo{RealName: "some-snap", SnapID: "some-snap-id", Revision: snap.R(2)},
+			Flags:    flags,
+		}
+
+		// The running app does not prevent the refresh.
+		_, err := snapstate.DoInstall(s.state, snapst, snapsup, 0, "")
+		c.Assert(err, IsNil)
+
+		err = snapstate.Get(s.state, "some-snap", snapst)
+		c.Assert(err, IsNil)
+		c.Check(snapst.RefreshInhibitedTime, IsNil)
+	}
+}
+
 func (s snapmgrTestSuite) TestInstallDespiteBusySnap(c *C) {
 	s.state.Lock()
 	defer s.state.Unlock()
@@ -1077,6 +1160,75 @@ func (s *snapmgrTestSuite) TestUpdateMany(c *C) {
 	checkIsAutoRefresh(c, ts.Tasks(), false)
 }
 
+func (s *snapmgrTestSuite) TestUpdateManyKillRunning(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	snapstate.Set(s.state, "some-snap", &snapstate.SnapState{
+		Active: true,
+		Sequence: []*snap.SideInfo{
+			{RealName: "some-snap", SnapID: "some-snap-id", Revision: snap.R(1)},
+		},
+		Current:  snap.R(1),
+		SnapType: "app",
+	})
+
+	updates, tts, err := snapstate.UpdateMany(context.Background(), s.state, []string{"some-snap"}, 0, &snapstate.Flags{KillRunning: true})
+	c.Assert(err, IsNil)
+	c.Check(updates, DeepEquals, []string{"some-snap"})
+
+	snapsup, err := snapstate.TaskSnapSetup(tts[0].Tasks()[0])
+	c.Assert(err, IsNil)
+	c.Check(snapsup.KillRunning, Equals, true)
+	c.Check(snapsup.IgnoreRunning, Equals, false)
+}
+
+func (s *snapmgrTestSuite) TestUpdateManyRiskFallback(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	snapstate.Set(s.state, "some-snap", &snapstate.SnapState{
+		Active:   true,
+		Channel:  "2.0/beta",
+		Sequence: []*snap.SideInfo{{RealName: "some-snap", SnapID: "some-snap-id", Revision: snap.R(1)}},
+		Current:  snap.R(1),
+		SnapType: "app",
+	})
+
+	// the tracked channel has no revision for the architecture
+	updates, _, err := snapstate.UpdateMany(context.Background(), s.state, nil, 0, nil)
+	c.Assert(err, IsNil)
+	c.Check(updates, HasLen, 0)
+	c.Check(s.state.AllWarnings(), HasLen, 0)
+
+	tr := config.NewTransaction(s.state)
+	tr.Set("core", "refresh.risk-fallback", "candidate")
+	tr.Commit()
+	s.fakeBackend.ops = nil
+
+	updates, tts, err := snapstate.UpdateMany(context.Background(), s.state, nil, 0, nil)
+	c.Assert(err, IsNil)
+	c.Check(updates, DeepEquals, []string{"some-snap"})
+	c.Assert(tts, HasLen, 2)
+
+	var fallbackChannels []string
+	for _, op := range s.fakeBackend.ops {
+		if op.op == "storesvc-snap-action:action" && op.action.Channel != "" {
+			fallbackChannels = append(fallbackChannels, op.action.Channel)
+		}
+	}
+	c.Check(fallbackChannels, DeepEquals, []string{"2.0/candidate"})
+
+	// the snap keeps tracking its channel
+	snapsup, err := snapstate.TaskSnapSetup(tts[0].Tasks()[0])
+	c.Assert(err, IsNil)
+	c.Check(snapsup.Channel, Equals, "2.0/beta")
+
+	warns := s.state.AllWarnings()
+	c.Assert(warns, HasLen, 1)
+	c.Check(warns[0].String(), Equals, `snap "some-snap" has no revision in channel "2.0/beta" for this architecture, refreshing it from "2.0/candidate" instead`)
+}
+
 func (s *snapmgrTestSuite) TestParallelInstanceUpdateMany(c *C) {
 	restore := release.MockOnClassic(false)
 	defer restore()
@@ -2080,6 +2232,30 @@ func (s *snapmgrTestSuite) TestInstallPathSnapIDRevisionUnset(c *C) {
 	c.Assert(err, ErrorMatches, fmt.Sprintf(`internal error: snap id set to install %q but revision is unset`, mockSnap))
 }
 
+func (s *snapmgrTestSuite) TestInstallPathWithCohort(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	s.prepareGadget(c)
+
+	mockSnap := makeTestSnap(c, "name: some-snap\nversion: 1.0")
+	ts, _, err := snapstate.InstallPathWithCohort(s.state, &snap.SideInfo{RealName: "some-snap", SnapID: "some-snap-id", Revision: snap.R(1)}, mockSnap, "", "stable", "COHORT", snapstate.Flags{})
+	c.Assert(err, IsNil)
+
+	snapsup, err := snapstate.TaskSnapSetup(ts.Tasks()[0])
+	c.Assert(err, IsNil)
+	c.Check(snapsup.CohortKey, Equals, "COHORT")
+}
+
+func (s *snapmgrTestSuite) TestInstallPathWithCohortLocalSnap(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	mockSnap := makeTestSnap(c, "name: some-snap\nversion: 1.0")
+	_, _, err := snapstate.InstallPathWithCohort(s.state, &snap.SideInfo{RealName: "some-snap"}, mockSnap, "", "", "COHORT", snapstate.Flags{})
+	c.Assert(err, ErrorMatches, `cannot install local snap "some-snap" into a cohort`)
+}
+
 func (s *snapmgrTestSuite) TestInstallPathValidateFlags(c *C) {
 	s.state.Lock()
 	defer s.state.Unlock()
@@ -2140,84 +2316,528 @@ func (s *snapmgrTestSuite) TestParallelInstanceInstallNotAllowed(c *C) {
 	_, err := snapstate.Install(context.Background(), s.state, "core_foo", nil, 0, snapstate.Flags{})
 	c.Check(err, ErrorMatches, `cannot install snap of type os as "core_foo"`)
 
-	_, err = snapstate.Install(context.Background(), s.state, "some-base_foo", nil, 0, snapstate.Flags{})
-	c.Check(err, ErrorMatches, `cannot install snap of type base as "some-base_foo"`)
+	_, err = snapstate.Install(context.Background(), s.state, "some-base_foo", nil, 0, snapstate.Flags{})
+	c.Check(err, ErrorMatches, `cannot install snap of type base as "some-base_foo"`)
+
+	_, err = snapstate.Install(context.Background(), s.state, "some-gadget_foo", nil, 0, snapstate.Flags{})
+	c.Check(err, ErrorMatches, `cannot install snap of type gadget as "some-gadget_foo"`)
+
+	_, err = snapstate.Install(context.Background(), s.state, "some-kernel_foo", nil, 0, snapstate.Flags{})
+	c.Check(err, ErrorMatches, `cannot install snap of type kernel as "some-kernel_foo"`)
+
+	_, err = snapstate.Install(context.Background(), s.state, "some-snapd_foo", nil, 0, snapstate.Flags{})
+	c.Check(err, ErrorMatches, `cannot install snap of type snapd as "some-snapd_foo"`)
+}
+
+func (s *snapmgrTestSuite) TestInstallPathFailsEarlyOnEpochMismatch(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	// have epoch 1* installed
+	snapstate.Set(s.state, "some-snap", &snapstate.SnapState{
+		Active:   true,
+		Channel:  "edge",
+		Sequence: []*snap.SideInfo{{RealName: "some-snap", Revision: snap.R(7)}},
+		Current:  snap.R(7),
+	})
+
+	// try to install epoch 42
+	mockSnap := makeTestSnap(c, "name: some-snap\nversion: 1.0\nepoch: 42\n")
+	_, _, err := snapstate.InstallPath(s.state, &snap.SideInfo{RealName: "some-snap"}, mockSnap, "", "", snapstate.Flags{})
+	c.Assert(err, ErrorMatches, `cannot refresh "some-snap" to local snap with epoch 42, because it can't read the current epoch of 1\*`)
+}
+
+func (s *snapmgrTestSuite) TestUpdateTasksPropagatesErrors(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	snapstate.Set(s.state, "some-snap", &snapstate.SnapState{
+		Active:   true,
+		Channel:  "edge",
+		Sequence: []*snap.SideInfo{{RealName: "some-snap", SnapID: "fakestore-please-error-on-refresh", Revision: snap.R(7)}},
+		Current:  snap.R(7),
+	})
+
+	_, err := snapstate.Update(s.state, "some-snap", &snapstate.RevisionOptions{Channel: "some-channel"}, s.user.ID, snapstate.Flags{})
+	c.Assert(err, ErrorMatches, `failing as requested`)
+}
+
+func (s *snapmgrTestSuite) TestUpdateTasks(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	snapstate.Set(s.state, "some-snap", &snapstate.SnapState{
+		Active:   true,
+		Channel:  "edge",
+		Sequence: []*snap.SideInfo{{RealName: "some-snap", SnapID: "some-snap-id", Revision: snap.R(7)}},
+		Current:  snap.R(7),
+		SnapType: "app",
+	})
+
+	validateCalled := false
+	happyValidateRefreshes := func(st *state.State, refreshes []*snap.Info, ignoreValidation map[string]bool, userID int, deviceCtx snapstate.DeviceContext) ([]*snap.Info, error) {
+		validateCalled = true
+		return refreshes, nil
+	}
+	// hook it up
+	snapstate.ValidateRefreshes = happyValidateRefreshes
+
+	ts, err := snapstate.Update(s.state, "some-snap", &snapstate.RevisionOptions{Channel: "some-channel"}, s.user.ID, snapstate.Flags{})
+	c.Assert(err, IsNil)
+	verifyUpdateTasks(c, unlinkBefore|cleanupAfter|doesReRefresh, 0, ts, s.state)
+	c.Assert(s.state.TaskCount(), Equals, len(ts.Tasks()))
+
+	c.Check(validateCalled, Equals, true)
+
+	var snapsup snapstate.SnapSetup
+	err = ts.Tasks()[0].Get("snap-setup", &snapsup)
+	c.Assert(err, IsNil)
+
+	c.Check(snapsup.Channel, Equals, "some-channel")
+}
+
+func (s *snapmgrTestSuite) TestUpdateTasksCrossingEpoch(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	// services-snap is at epoch 0, the store has it at epoch 1*
+	snapstate.Set(s.state, "services-snap", &snapstate.SnapState{
+		Active:   true,
+		Sequence: []*snap.SideInfo{{RealName: "services-snap", SnapID: "services-snap-id", Revision: snap.R(7)}},
+		Current:  snap.R(7),
+		SnapType: "app",
+	})
+
+	ts, err := snapstate.Update(s.state, "services-snap", &snapstate.RevisionOptions{Channel: "some-channel"}, s.user.ID, snapstate.Flags{})
+	c.Assert(err, IsNil)
+
+	c.Assert(taskKinds(ts.Tasks()), DeepEquals, []string{
+		"prerequisites",
+		"download-snap",
+		"validate-snap",
+		"mount-snap",
+		"run-hook[pre-refresh]",
+		"stop-snap-services",
+		"save-snapshot",
+		"remove-aliases",
+		"unlink-current-snap",
+		"copy-snap-data",
+		"setup-profiles",
+		"link-snap",
+		"auto-connect",
+		"set-auto-aliases",
+		"setup-aliases",
+		"run-hook[data-migrate]",
+		"run-hook[post-refresh]",
+		"start-snap-services",
+		"cleanup",
+		"run-hook[configure]",
+		"run-hook[check-health]",
+		"check-rerefresh",
+	})
+
+	var snapsup snapstate.SnapSetup
+	err = ts.Tasks()[0].Get("snap-setup", &snapsup)
+	c.Assert(err, IsNil)
+	c.Check(snapsup.MigrateData, Equals, true)
+	c.Check(snapsup.MigrationSnapshot, Equals, uint64(42))
+}
+
+func (s *snapmgrTestSuite) TestUpdateCrossingEpochRecordsDataMigration(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	snapstate.Set(s.state, "services-snap", &snapstate.SnapState{
+		Active:   true,
+		Sequence: []*snap.SideInfo{{RealName: "services-snap", SnapID: "services-snap-id", Revision: snap.R(7)}},
+		Current:  snap.R(7),
+		SnapType: "app",
+	})
+
+	chg := s.state.NewChange("refresh", "refresh a snap")
+	ts, err := snapstate.Update(s.state, "services-snap", &snapstate.RevisionOptions{Channel: "some-channel"}, s.user.ID, snapstate.Flags{})
+	c.Assert(err, IsNil)
+	chg.AddAll(ts)
+
+	s.state.Unlock()
+	defer s.se.Stop()
+	s.settle(c)
+	s.state.Lock()
+
+	c.Assert(chg.Err(), IsNil)
+
+	var snapst snapstate.SnapState
+	err = snapstate.Get(s.state, "services-snap", &snapst)
+	c.Assert(err, IsNil)
+	c.Check(snapst.Current, Equals, snap.R(11))
+	c.Check(snapst.DataMigration, DeepEquals, &snapstate.DataMigration{
+		Revision:      snap.R(7),
+		SnapshotSetID: 42,
+	})
+}
+
+func (s *snapmgrTestSuite) TestRevertRestoresMigratedData(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	siOld := &snap.SideInfo{RealName: "some-snap", Revision: snap.R(2)}
+	si := &snap.SideInfo{RealName: "some-snap", Revision: snap.R(7)}
+	snapstate.Set(s.state, "some-snap", &snapstate.SnapState{
+		Active:   true,
+		SnapType: "app",
+		Sequence: []*snap.SideInfo{siOld, si},
+		Current:  si.Revision,
+		DataMigration: &snapstate.DataMigration{
+			Revision:      snap.R(2),
+			SnapshotSetID: 42,
+		},
+	})
+
+	chg := s.state.NewChange("revert", "revert a snap backwards")
+	ts, err := snapstate.Revert(s.state, "some-snap", snapstate.Flags{})
+	c.Assert(err, IsNil)
+	chg.AddAll(ts)
+
+	c.Assert(taskKinds(ts.Tasks())[:5], DeepEquals, []string{
+		"prerequisites",
+		"prepare-snap",
+		"stop-snap-services",
+		"restore-snapshot",
+		"remove-aliases",
+	})
+	c.Check(ts.Tasks()[3].Summary(), Equals, "restore set #42 into 2")
+
+	s.state.Unlock()
+	defer s.se.Stop()
+	s.settle(c)
+	s.state.Lock()
+
+	c.Assert(chg.Err(), IsNil)
+
+	var snapst snapstate.SnapState
+	err = snapstate.Get(s.state, "some-snap", &snapst)
+	c.Assert(err, IsNil)
+	c.Check(snapst.Current, Equals, snap.R(2))
+	// the saved data is not restored again
+	c.Check(snapst.DataMigration, IsNil)
+}
+
+func (s *snapmgrTestSuite) TestRevertRestoresMigratedDataUndo(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	siOld := &snap.SideInfo{RealName: "some-snap", Revision: snap.R(2)}
+	si := &snap.SideInfo{RealName: "some-snap", Revision: snap.R(7)}
+	dataMigration := &snapstate.DataMigration{
+		Revision:      snap.R(2),
+		SnapshotSetID: 42,
+	}
+	snapstate.Set(s.state, "some-snap", &snapstate.SnapState{
+		Active:        true,
+		SnapType:      "app",
+		Sequence:      []*snap.SideInfo{siOld, si},
+		Current:       si.Revision,
+		DataMigration: dataMigration,
+	})
+
+	chg := s.state.NewChange("revert", "revert a snap backwards")
+	ts, err := snapstate.Revert(s.state, "some-snap", snapstate.Flags{})
+	c.Assert(err, IsNil)
+	chg.AddAll(ts)
+
+	var restoreSnapshot *state.Task
+	for _, t := range ts.Tasks() {
+		if t.Kind() == "restore-snapshot" {
+			restoreSnapshot = t
+		}
+	}
+	c.Assert(restoreSnapshot, NotNil)
+
+	tasks := ts.Tasks()
+	last := tasks[len(tasks)-1]
+	terr := s.state.NewTask("error-trigger", "provoking total undo")
+	terr.WaitFor(last)
+	chg.AddTask(terr)
+
+	s.state.Unlock()
+	defer s.se.Stop()
+	s.settle(c)
+	s.state.Lock()
+
+	c.Check(chg.Status(), Equals, state.ErrorStatus)
+	c.Check(restoreSnapshot.Status(), Equals, state.UndoneStatus)
+
+	var snapst snapstate.SnapState
+	err = snapstate.Get(s.state, "some-snap", &snapst)
+	c.Assert(err, IsNil)
+	c.Check(snapst.Current, Equals, snap.R(7))
+	c.Check(snapst.DataMigration, DeepEquals, dataMigration)
+}
+
+func (s *snapmgrTestSuite) TestUpdateTasksCrossingEpochUndoRestoresData(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	snapstate.Set(s.state, "services-snap", &snapstate.SnapState{
+		Active:   true,
+		Sequence: []*snap.SideInfo{{RealName: "services-snap", SnapID: "services-snap-id", Revision: snap.R(7)}},
+		Current:  snap.R(7),
+		SnapType: "app",
+	})
+
+	chg := s.state.NewChange("refresh", "refresh a snap")
+	ts, err := snapstate.Update(s.state, "services-snap", &snapstate.RevisionOptions{Channel: "some-channel"}, s.user.ID, snapstate.Flags{})
+	c.Assert(err, IsNil)
+	chg.AddAll(ts)
+
+	var saveSnapshot, dataMigrate *state.Task
+	for _, t := range ts.Tasks() {
+		switch {
+		case t.Kind() == "save-snapshot":
+			saveSnapshot = t
+		case strings.Contains(t.Summary(), "data-migrate"):
+			dataMigrate = t
+		}
+	}
+	c.Assert(saveSnapshot, NotNil)
+	c.Assert(dataMigrate, NotNil)
+	// the migration fails
+	terr := s.state.NewTask("error-trigger", "provoking total undo")
+	terr.WaitFor(dataMigrate)
+	for _, lane := range dataMigrate.Lanes() {
+		terr.JoinLane(lane)
+	}
+	chg.AddTask(terr)
+
+	s.state.Unlock()
+	defer s.se.Stop()
+	s.settle(c)
+	s.state.Lock()
+
+	c.Check(chg.Status(), Equals, state.ErrorStatus)
+	c.Check(saveSnapshot.Status(), Equals, state.UndoneStatus)
+
+	var snapst snapstate.SnapState
+	err = snapstate.Get(s.state, "services-snap", &snapst)
+	c.Assert(err, IsNil)
+	c.Check(snapst.Current, Equals, snap.R(7))
+}
+
+func (s *snapmgrTestSuite) TestUpdateKeepsDisabledServicesDisabled(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	snapstate.Set(s.state, "services-snap", &snapstate.SnapState{
+		Active:   true,
+		Sequence: []*snap.SideInfo{{RealName: "services-snap", SnapID: "services-snap-id", Revision: snap.R(7)}},
+		Current:  snap.R(7),
+		SnapType: "app",
+		// svc-gone was disabled in a revision that still had it
+		LastActiveDisabledServices: []string{"svc-gone"},
+	})
+	s.fakeBackend.disabledServices = map[string][]string{
+		"services-snap": {"svc2"},
+	}
+
+	chg := s.state.NewChange("refresh", "refresh a snap")
+	ts, err := snapstate.Update(s.state, "services-snap", &snapstate.RevisionOptions{Channel: "some-channel"}, s.user.ID, snapstate.Flags{})
+	c.Assert(err, IsNil)
+	chg.AddAll(ts)
+
+	s.state.Unlock()
+	defer s.se.Stop()
+	s.settle(c)
+	s.state.Lock()
+
+	c.Assert(chg.Err(), IsNil)
+	c.Assert(chg.IsReady(), Equals, true)
+
+	linkOp := s.fakeBackend.ops.First("link-snap")
+	c.Assert(linkOp, NotNil)
+	c.Check(linkOp.disabledServices, DeepEquals, []string{"svc-gone", "svc2"})
+	startOp := s.fakeBackend.ops.First("start-snap-services")
+	c.Assert(startOp, NotNil)
+	c.Check(startOp.services, DeepEquals, []string{"svc1", "svc3"})
+
+	var snapst snapstate.SnapState
+	err = snapstate.Get(s.state, "services-snap", &snapst)
+	c.Assert(err, IsNil)
+	c.Check(snapst.LastActiveDisabledServices, DeepEquals, []string{"svc-gone", "svc2"})
+}
+
+func (s *snapmgrTestSuite) TestDisableEnableKeepsDisabledServicesDisabled(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	snapstate.Set(s.state, "services-snap", &snapstate.SnapState{
+		Active:   true,
+		Sequence: []*snap.SideInfo{{RealName: "services-snap", SnapID: "services-snap-id", Revision: snap.R(7)}},
+		Current:  snap.R(7),
+		SnapType: "app",
+	})
+	s.fakeBackend.disabledServices = map[string][]string{
+		"services-snap": {"svc1"},
+	}
+
+	chg := s.state.NewChange("disable", "disable a snap")
+	ts, err := snapstate.Disable(s.state, "services-snap")
+	c.Assert(err, IsNil)
+	chg.AddAll(ts)
+
+	s.state.Unlock()
+	defer s.se.Stop()
+	s.settle(c)
+	s.state.Lock()
+	c.Assert(chg.Err(), IsNil)
+
+	var snapst snapstate.SnapState
+	err = snapstate.Get(s.state, "services-snap", &snapst)
+	c.Assert(err, IsNil)
+	c.Check(snapst.LastActiveDisabledServices, DeepEquals, []string{"svc1"})
 
-	_, err = snapstate.Install(context.Background(), s.state, "some-gadget_foo", nil, 0, snapstate.Flags{})
-	c.Check(err, ErrorMatches, `cannot install snap of type gadget as "some-gadget_foo"`)
+	s.fakeBackend.ops = nil
+	chg = s.state.NewChange("enable", "enable a snap")
+	ts, err = snapstate.Enable(s.state, "services-snap")
+	c.Assert(err, IsNil)
+	chg.AddAll(ts)
 
-	_, err = snapstate.Install(context.Background(), s.state, "some-kernel_foo", nil, 0, snapstate.Flags{})
-	c.Check(err, ErrorMatches, `cannot install snap of type kernel as "some-kernel_foo"`)
+	s.state.Unlock()
+	s.settle(c)
+	s.state.Lock()
+	c.Assert(chg.Err(), IsNil)
 
-	_, err = snapstate.Install(context.Background(), s.state, "some-snapd_foo", nil, 0, snapstate.Flags{})
-	c.Check(err, ErrorMatches, `cannot install snap of type snapd as "some-snapd_foo"`)
+	linkOp := s.fakeBackend.ops.First("link-snap")
+	c.Assert(linkOp, NotNil)
+	c.Check(linkOp.disabledServices, DeepEquals, []string{"svc1"})
+	startOp := s.fakeBackend.ops.First("start-snap-services")
+	c.Assert(startOp, NotNil)
+	c.Check(startOp.services, DeepEquals, []string{"svc3", "svc2"})
 }
 
-func (s *snapmgrTestSuite) TestInstallPathFailsEarlyOnEpochMismatch(c *C) {
+func (s *snapmgrTestSuite) TestUpdateTasksCrossingBase(c *C) {
 	s.state.Lock()
 	defer s.state.Unlock()
 
-	// have epoch 1* installed
+	// some-snap runs on core, the store has it on some-base
 	snapstate.Set(s.state, "some-snap", &snapstate.SnapState{
 		Active:   true,
-		Channel:  "edge",
-		Sequence: []*snap.SideInfo{{RealName: "some-snap", Revision: snap.R(7)}},
+		Sequence: []*snap.SideInfo{{RealName: "some-snap", SnapID: "some-snap-id", Revision: snap.R(7)}},
 		Current:  snap.R(7),
+		SnapType: "app",
 	})
 
-	// try to install epoch 42
-	mockSnap := makeTestSnap(c, "name: some-snap\nversion: 1.0\nepoch: 42\n")
-	_, _, err := snapstate.InstallPath(s.state, &snap.SideInfo{RealName: "some-snap"}, mockSnap, "", "", snapstate.Flags{})
-	c.Assert(err, ErrorMatches, `cannot refresh "some-snap" to local snap with epoch 42, because it can't read the current epoch of 1\*`)
+	ts, err := snapstate.Update(s.state, "some-snap", &snapstate.RevisionOptions{Channel: "channel-for-base"}, s.user.ID, snapstate.Flags{})
+	c.Assert(err, IsNil)
+
+	c.Assert(taskKinds(ts.Tasks()), DeepEquals, []string{
+		"prerequisites",
+		"download-snap",
+		"validate-snap",
+		"mount-snap",
+		"run-hook[pre-refresh]",
+		"stop-snap-services",
+		"save-snapshot",
+		"remove-aliases",
+		"unlink-current-snap",
+		"copy-snap-data",
+		"setup-profiles",
+		"link-snap",
+		"auto-connect",
+		"set-auto-aliases",
+		"setup-aliases",
+		"run-hook[data-migrate]",
+		"run-hook[post-refresh]",
+		"start-snap-services",
+		"cleanup",
+		"run-hook[configure]",
+		"run-hook[check-health]",
+		"check-rerefresh",
+	})
+
+	var snapsup snapstate.SnapSetup
+	err = ts.Tasks()[0].Get("snap-setup", &snapsup)
+	c.Assert(err, IsNil)
+	c.Check(snapsup.Base, Equals, "some-base")
+	c.Check(snapsup.MigrateBase, Equals, true)
+	c.Check(snapsup.MigrateData, Equals, false)
+
+	var undo bool
+	healthCheck := ts.Tasks()[len(ts.Tasks())-2]
+	c.Assert(healthCheck.Get("undo-if-unhealthy", &undo), IsNil)
+	c.Check(undo, Equals, true)
 }
 
-func (s *snapmgrTestSuite) TestUpdateTasksPropagatesErrors(c *C) {
+func (s *snapmgrTestSuite) TestUpdateTasksSameBase(c *C) {
 	s.state.Lock()
 	defer s.state.Unlock()
 
 	snapstate.Set(s.state, "some-snap", &snapstate.SnapState{
 		Active:   true,
-		Channel:  "edge",
-		Sequence: []*snap.SideInfo{{RealName: "some-snap", SnapID: "fakestore-please-error-on-refresh", Revision: snap.R(7)}},
+		Sequence: []*snap.SideInfo{{RealName: "some-snap", SnapID: "some-snap-id", Revision: snap.R(7)}},
 		Current:  snap.R(7),
+		SnapType: "app",
 	})
 
-	_, err := snapstate.Update(s.state, "some-snap", &snapstate.RevisionOptions{Channel: "some-channel"}, s.user.ID, snapstate.Flags{})
-	c.Assert(err, ErrorMatches, `failing as requested`)
+	ts, err := snapstate.Update(s.state, "some-snap", &snapstate.RevisionOptions{Channel: "some-channel"}, s.user.ID, snapstate.Flags{})
+	c.Assert(err, IsNil)
+
+	var snapsup snapstate.SnapSetup
+	err = ts.Tasks()[0].Get("snap-setup", &snapsup)
+	c.Assert(err, IsNil)
+	c.Check(snapsup.MigrateBase, Equals, false)
+
+	var undo bool
+	healthCheck := ts.Tasks()[len(ts.Tasks())-2]
+	c.Check(healthCheck.Get("undo-if-unhealthy", &undo), Equals, state.ErrNoState)
 }
 
-func (s *snapmgrTestSuite) TestUpdateTasks(c *C) {
+func (s *snapmgrTestSuite) TestUpdateTasksCrossingBaseUnhealthyUndo(c *C) {
 	s.state.Lock()
 	defer s.state.Unlock()
 
 	snapstate.Set(s.state, "some-snap", &snapstate.SnapState{
 		Active:   true,
-		Channel:  "edge",
 		Sequence: []*snap.SideInfo{{RealName: "some-snap", SnapID: "some-snap-id", Revision: snap.R(7)}},
 		Current:  snap.R(7),
 		SnapType: "app",
 	})
 
-	validateCalled := false
-	happyValidateRefreshes := func(st *state.State, refreshes []*snap.Info, ignoreValidation map[string]bool, userID int, deviceCtx snapstate.DeviceContext) ([]*snap.Info, error) {
-		validateCalled = true
-		return refreshes, nil
+	chg := s.state.NewChange("refresh", "refresh a snap")
+	ts, err := snapstate.Update(s.state, "some-snap", &snapstate.RevisionOptions{Channel: "channel-for-base"}, s.user.ID, snapstate.Flags{})
+	c.Assert(err, IsNil)
+	chg.AddAll(ts)
+
+	var saveSnapshot, healthCheck *state.Task
+	for _, t := range ts.Tasks() {
+		switch {
+		case t.Kind() == "save-snapshot":
+			saveSnapshot = t
+		case strings.Contains(t.Summary(), "health check"):
+			healthCheck = t
+		}
 	}
-	// hook it up
-	snapstate.ValidateRefreshes = happyValidateRefreshes
+	c.Assert(saveSnapshot, NotNil)
+	c.Assert(healthCheck, NotNil)
+	// the new revision is unhealthy on its new base
+	terr := s.state.NewTask("error-trigger", "provoking total undo")
+	terr.WaitFor(healthCheck)
+	for _, lane := range healthCheck.Lanes() {
+		terr.JoinLane(lane)
+	}
+	chg.AddTask(terr)
 
-	ts, err := snapstate.Update(s.state, "some-snap", &snapstate.RevisionOptions{Channel: "some-channel"}, s.user.ID, snapstate.Flags{})
-	c.Assert(err, IsNil)
-	verifyUpdateTasks(c, unlinkBefore|cleanupAfter|doesReRefresh, 0, ts, s.state)
-	c.Assert(s.state.TaskCount(), Equals, len(ts.Tasks()))
+	s.state.Unlock()
+	defer s.se.Stop()
+	s.settle(c)
+	s.state.Lock()
 
-	c.Check(validateCalled, Equals, true)
+	c.Check(chg.Status(), Equals, state.ErrorStatus)
+	c.Check(saveSnapshot.Status(), Equals, state.UndoneStatus)
 
-	var snapsup snapstate.SnapSetup
-	err = ts.Tasks()[0].Get("snap-setup", &snapsup)
+	var snapst snapstate.SnapState
+	err = snapstate.Get(s.state, "some-snap", &snapst)
 	c.Assert(err, IsNil)
-
-	c.Check(snapsup.Channel, Equals, "some-channel")
+	c.Check(snapst.Current, Equals, snap.R(7))
 }
 
 func (s *snapmgrTestSuite) TestUpdateWithDeviceContext(c *C) {
@@ -2556,6 +3176,40 @@ func (s *snapmgrTestSuite) TestRemoveTasks(c *C) {
 	verifyRemoveTasks(c, ts)
 }
 
+func (s *snapmgrTestSuite) TestRemoveRequiredByEnforcedValidationSet(c *C) {
+	vs := assertstest.FakeAssertion(map[string]interface{}{
+		"type":         "validation-set",
+		"authority-id": "acme",
+		"series":       "16",
+		"account-id":   "acme",
+		"name":         "base",
+		"sequence":     "1",
+		"snaps": []interface{}{
+			map[string]interface{}{"name": "foo", "id": "fooididididididididididididididi"},
+		},
+	}).(*asserts.ValidationSet)
+	old := snapstate.EnforcedValidationSets
+	snapstate.EnforcedValidationSets = func(st *state.State) ([]*asserts.ValidationSet, error) {
+		return []*asserts.ValidationSet{vs}, nil
+	}
+	s.BaseTest.AddCleanup(func() { snapstate.EnforcedValidationSets = old })
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	snapstate.Set(s.state, "foo", &snapstate.SnapState{
+		Active: true,
+		Sequence: []*snap.SideInfo{
+			{RealName: "foo", SnapID: "fooididididididididididididididi", Revision: snap.R(11)},
+		},
+		Current:  snap.R(11),
+		SnapType: "app",
+	})
+
+	_, err := snapstate.Remove(s.state, "foo", snap.R(0), nil)
+	c.Assert(err, ErrorMatches, `cannot remove snap "foo": required by enforced validation sets: acme/base`)
+}
+
 func (s *snapmgrTestSuite) TestRemoveTasksAutoSnapshotDisabled(c *C) {
 	snapstate.AutomaticSnapshot = func(st *state.State, instanceName string) (ts *state.TaskSet, err error) {
 		return nil, snapstate.ErrNothingToDo
@@ -2588,6 +3242,67 @@ func (s *snapmgrTestSuite) TestRemoveTasksAutoSnapshotDisabled(c *C) {
 	})
 }
 
+func (s *snapmgrTestSuite) TestRemoveTasksKeepData(c *C) {
+	snapstate.AutomaticSnapshot = func(st *state.State, instanceName string) (ts *state.TaskSet, err error) {
+		c.Fatalf("unexpected automatic snapshot")
+		return nil, nil
+	}
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	snapstate.Set(s.state, "foo", &snapstate.SnapState{
+		Active: true,
+		Sequence: []*snap.SideInfo{
+			{RealName: "foo", Revision: snap.R(11)},
+		},
+		Current:  snap.R(11),
+		SnapType: "app",
+	})
+
+	ts, err := snapstate.Remove(s.state, "foo", snap.R(0), &snapstate.RemoveFlags{KeepDataFor: 7 * 24 * time.Hour})
+	c.Assert(err, IsNil)
+
+	c.Assert(taskKinds(ts.Tasks()), DeepEquals, []string{
+		"stop-snap-services",
+		"run-hook[remove]",
+		"auto-disconnect",
+		"save-snapshot",
+		"remove-aliases",
+		"unlink-snap",
+		"remove-profiles",
+		"clear-snap",
+		"discard-snap",
+	})
+	var keepFor time.Duration
+	c.Assert(ts.Tasks()[3].Get("keep-for", &keepFor), IsNil)
+	c.Check(keepFor, Equals, 7*24*time.Hour)
+}
+
+func (s *snapmgrTestSuite) TestRemoveKeepDataErrors(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	snapstate.Set(s.state, "foo", &snapstate.SnapState{
+		Active: true,
+		Sequence: []*snap.SideInfo{
+			{RealName: "foo", Revision: snap.R(7)},
+			{RealName: "foo", Revision: snap.R(11)},
+		},
+		Current:  snap.R(11),
+		SnapType: "app",
+	})
+
+	_, err := snapstate.Remove(s.state, "foo", snap.R(0), &snapstate.RemoveFlags{KeepDataFor: time.Hour, Purge: true})
+	c.Check(err, ErrorMatches, `cannot keep data of snap "foo" and purge it at the same time`)
+
+	_, err = snapstate.Remove(s.state, "foo", snap.R(0), &snapstate.RemoveFlags{KeepDataFor: -time.Hour})
+	c.Check(err, ErrorMatches, `cannot keep data of snap "foo" for a negative duration`)
+
+	_, err = snapstate.Remove(s.state, "foo", snap.R(7), &snapstate.RemoveFlags{KeepDataFor: time.Hour})
+	c.Check(err, ErrorMatches, `cannot keep data of snap "foo" when removing only revision 7`)
+}
+
 func (s *snapmgrTestSuite) TestRemoveTasksAutoSnapshotDisabledByPurgeFlag(c *C) {
 	s.state.Lock()
 	defer s.state.Unlock()
@@ -3831,6 +4546,9 @@ func (s *snapmgrTestSuite) TestUpdateRunThrough(c *C) {
 		SideInfo:  snapsup.SideInfo,
 		Type:      snap.TypeApp,
 		PlugsOnly: true,
+		// services-snap goes from epoch 0 to 1*
+		MigrateData:       true,
+		MigrationSnapshot: 42,
 	})
 	c.Assert(snapsup.SideInfo, DeepEquals, &snap.SideInfo{
 		RealName: "services-snap",
@@ -3843,7 +4561,7 @@ func (s *snapmgrTestSuite) TestUpdateRunThrough(c *C) {
 	verifyStopReason(c, ts, "refresh")
 
 	// check post-refresh hook
-	task = ts.Tasks()[14]
+	task = ts.Tasks()[16]
 	c.Assert(task.Kind(), Equals, "run-hook")
 	c.Assert(task.Summary(), Matches, `Run post-refresh hook of "services-snap" snap if present`)
 
@@ -4054,6 +4772,9 @@ func (s *snapmgrTestSuite) TestParallelInstanceUpdateRunThrough(c *C) {
 		Type:        snap.TypeApp,
 		PlugsOnly:   true,
 		InstanceKey: "instance",
+		// services-snap goes from epoch 0 to 1*
+		MigrateData:       true,
+		MigrationSnapshot: 42,
 	})
 	c.Assert(snapsup.SideInfo, DeepEquals, &snap.SideInfo{
 		RealName: "services-snap",
@@ -4066,7 +4787,7 @@ func (s *snapmgrTestSuite) TestParallelInstanceUpdateRunThrough(c *C) {
 	verifyStopReason(c, ts, "refresh")
 
 	// check post-refresh hook
-	task = ts.Tasks()[14]
+	task = ts.Tasks()[16]
 	c.Assert(task.Kind(), Equals, "run-hook")
 	c.Assert(task.Summary(), Matches, `Run post-refresh hook of "services-snap_instance" snap if present`)
 
@@ -9947,6 +10668,52 @@ func (s *snapmgrTestSuite) TestEnsureRefreshesAlreadyRanInThisInterval(c *C) {
 	c.Check(s.snapmgr.NextRefresh(), Equals, nextRefresh)
 }
 
+func (s *snapmgrTestSuite) TestQuickStartSnapshotRestoresNextRefresh(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	snapstate.CanAutoRefresh = func(*state.State) (bool, error) {
+		return true, nil
+	}
+	now := time.Now()
+	s.state.Set("last-refresh", now.Add(-1*time.Hour))
+
+	tr := config.NewTransaction(s.state)
+	tr.Set("core", "refresh.timer", fmt.Sprintf("00:00-%02d:%02d", now.Hour(), now.Minute()))
+	tr.Commit()
+
+	s.state.Unlock()
+	s.snapmgr.Ensure()
+	s.state.Lock()
+
+	nextRefresh := s.snapmgr.NextRefresh()
+	c.Assert(nextRefresh.IsZero(), Equals, false)
+
+	// snapd goes into socket activation mode
+	key, snapshot := s.snapmgr.QuickStartSnapshot()
+	c.Check(key, Equals, "snapstate")
+	s.state.Set("quick-start", map[string]interface{}{key: snapshot})
+
+	// and starts again
+	s.state.Unlock()
+	snapmgr, err := snapstate.Manager(s.state, s.o.TaskRunner())
+	s.state.Lock()
+	c.Assert(err, IsNil)
+	c.Check(snapmgr.NextRefresh().Equal(nextRefresh), Equals, true)
+
+	// the refresh time is not calculated again
+	s.state.Unlock()
+	snapmgr.Ensure()
+	s.state.Lock()
+	c.Check(snapmgr.NextRefresh().Equal(nextRefresh), Equals, true)
+}
+
+func (s *snapmgrTestSuite) TestQuickStartSnapshotUnset(c *C) {
+	snapmgr, err := snapstate.Manager(s.state, s.o.TaskRunner())
+	c.Assert(err, IsNil)
+	c.Check(snapmgr.NextRefresh().IsZero(), Equals, true)
+}
+
 func (s *snapmgrTestSuite) TestEnsureRefreshesWithUpdate(c *C) {
 	s.state.Lock()
 	defer s.state.Unlock()
@@ -11679,7 +12446,7 @@ func (s *snapmgrTestSuite) TestInstallMany(c *C) {
 	s.state.Lock()
 	defer s.state.Unlock()
 
-	installed, tts, err := snapstate.InstallMany(s.state, []string{"one", "two"}, 0)
+	installed, tts, err := snapstate.InstallMany(s.state, []string{"one", "two"}, 0, nil)
 	c.Assert(err, IsNil)
 	c.Assert(tts, HasLen, 2)
 	c.Check(installed, DeepEquals, []string{"one", "two"})
@@ -11701,7 +12468,7 @@ func (s *snapmgrTestSuite) TestInstallManyTooEarly(c *C) {
 
 	s.state.Set("seeded", nil)
 
-	_, _, err := snapstate.InstallMany(s.state, []string{"one", "two"}, 0)
+	_, _, err := snapstate.InstallMany(s.state, []string{"one", "two"}, 0, nil)
 	c.Check(err, FitsTypeOf, &snapstate.ChangeConflictError{})
 	c.Assert(err, ErrorMatches, `too early for operation, device not yet seeded or device model not acknowledged`)
 }
@@ -11710,11 +12477,11 @@ func (s *snapmgrTestSuite) TestInstallManyChecksPreconditions(c *C) {
 	s.state.Lock()
 	defer s.state.Unlock()
 
-	_, _, err := snapstate.InstallMany(s.state, []string{"some-snap-now-classic"}, 0)
+	_, _, err := snapstate.InstallMany(s.state, []string{"some-snap-now-classic"}, 0, nil)
 	c.Assert(err, NotNil)
 	c.Check(err, DeepEquals, &snapstate.SnapNeedsClassicError{Snap: "some-snap-now-classic"})
 
-	_, _, err = snapstate.InstallMany(s.state, []string{"some-snap_foo"}, 0)
+	_, _, err = snapstate.InstallMany(s.state, []string{"some-snap_foo"}, 0, nil)
 	c.Assert(err, ErrorMatches, "experimental feature disabled - test it by setting 'experimental.parallel-instances' to true")
 }
 
@@ -14508,6 +15275,27 @@ func (s *snapmgrTestSuite) TestSnapManagerCanStandby(c *C) {
 	c.Assert(s.snapmgr.CanStandby(), Equals, false)
 }
 
+func (s *snapmgrTestSuite) TestSnapManagerCanStandbyIdleExit(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	snapstate.Set(s.state, "core", &snapstate.SnapState{
+		Active: true,
+		Sequence: []*snap.SideInfo{
+			{RealName: "core", Revision: snap.R(1)},
+		},
+		Current:  snap.R(1),
+		SnapType: "os",
+	})
+	c.Assert(s.snapmgr.CanStandby(), Equals, false)
+
+	// with idle exit enabled snaps do not prevent standby
+	tr := config.NewTransaction(s.state)
+	tr.Set("core", "daemon.idle-exit", "10m")
+	tr.Commit()
+	c.Assert(s.snapmgr.CanStandby(), Equals, true)
+}
+
 func (s *snapmgrTestSuite) TestResolveChannelPinnedTrack(c *C) {
 	for _, tc := range []struct {
 		snap        string
@@ -14589,10 +15377,10 @@ func (s *snapmgrTestSuite) TestInstallValidatesInstanceNames(c *C) {
 	_, err = snapstate.Install(context.Background(), s.state, "foo_123_456", nil, 0, snapstate.Flags{})
 	c.Assert(err, ErrorMatches, `invalid instance name: invalid instance key: "123_456"`)
 
-	_, _, err = snapstate.InstallMany(s.state, []string{"foo--invalid"}, 0)
+	_, _, err = snapstate.InstallMany(s.state, []string{"foo--invalid"}, 0, nil)
 	c.Assert(err, ErrorMatches, `invalid instance name: invalid snap name: "foo--invalid"`)
 
-	_, _, err = snapstate.InstallMany(s.state, []string{"foo_123_456"}, 0)
+	_, _, err = snapstate.InstallMany(s.state, []string{"foo_123_456"}, 0, nil)
 	c.Assert(err, ErrorMatches, `invalid instance name: invalid instance key: "123_456"`)
 
 	mockSnap := makeTestSnap(c, `name: some-snap
@@ -14681,3 +15469,57 @@ func (s *snapmgrTestSuite) TestForSnapSetupResetsFlags(c *C) {
 		RequireTypeBase:  false,
 	})
 }
+
+func (s *snapmgrTestSuite) TestInstallDesktopCacheGenerationAsync(c *C) {
+	restore := release.MockOnClassic(true)
+	defer restore()
+
+	c.Assert(os.MkdirAll(filepath.Dir(dirs.SnapDesktopCacheGenerationFile), 0755), IsNil)
+	c.Assert(ioutil.WriteFile(dirs.SnapDesktopCacheGenerationFile, []byte("async\n"), 0644), IsNil)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	chg := s.state.NewChange("install", "install a snap")
+	ts, err := snapstate.Install(context.Background(), s.state, "some-snap", nil, s.user.ID, snapstate.Flags{})
+	c.Assert(err, IsNil)
+	chg.AddAll(ts)
+
+	// the caches are updated once the snap is runnable
+	tasks := ts.Tasks()
+	var updateDesktopCaches *state.Task
+	for _, t := range tasks {
+		if t.Kind() == "update-desktop-caches" {
+			updateDesktopCaches = t
+		}
+	}
+	c.Assert(updateDesktopCaches, NotNil)
+	c.Assert(updateDesktopCaches.WaitTasks(), HasLen, 1)
+	c.Check(updateDesktopCaches.WaitTasks()[0].Kind(), Equals, "start-snap-services")
+
+	s.state.Unlock()
+	defer s.se.Stop()
+	s.settle(c)
+	s.state.Lock()
+
+	c.Assert(chg.Err(), IsNil)
+	c.Check(updateDesktopCaches.Status(), Equals, state.DoneStatus)
+	c.Check(s.fakeBackend.ops.Ops(), testutil.Contains, "update-fontconfig-caches")
+}
+
+func (s *snapmgrTestSuite) TestInstallDesktopCacheGenerationNotAsync(c *C) {
+	restore := release.MockOnClassic(true)
+	defer restore()
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	c.Assert(os.MkdirAll(filepath.Dir(dirs.SnapDesktopCacheGenerationFile), 0755), IsNil)
+	for _, mode := range []string{"", "install", "launch"} {
+		c.Assert(ioutil.WriteFile(dirs.SnapDesktopCacheGenerationFile, []byte(mode+"\n"), 0644), IsNil)
+
+		ts, err := snapstate.Install(context.Background(), s.state, "some-snap", nil, s.user.ID, snapstate.Flags{})
+		c.Assert(err, IsNil)
+		c.Check(taskKinds(ts.Tasks()), Not(testutil.Contains), "update-desktop-caches", Commentf("mode %q", mode))
+	}
+}
@@ -24,8 +24,10 @@ import (
 	"fmt"
 	"sort"
 
+	"github.com/snapcore/snapd/arch"
 	"github.com/snapcore/snapd/logger"
 	"github.com/snapcore/snapd/overlord/auth"
+	"github.com/snapcore/snapd/overlord/configstate/config"
 	"github.com/snapcore/snapd/overlord/state"
 	"github.com/snapcore/snapd/snap"
 	"github.com/snapcore/snapd/store"
@@ -434,6 +436,31 @@ func refreshCandidates(ctx context.Context, st *state.State, names []string, use
 			}
 			// TODO: use the warning infra here when we have it
 			logger.Noticef("%v", saErr)
+
+			fallbackActions, err := riskFallbackActions(st, saErr, actions, stateByInstanceName)
+			if err != nil {
+				return nil, nil, nil, err
+			}
+			if len(fallbackActions) > 0 {
+				st.Unlock()
+				fallbackUpdates, err := theStore.SnapAction(ctx, curSnaps, fallbackActions, u, opts)
+				st.Lock()
+				if err != nil {
+					if _, ok := err.(*store.SnapActionError); !ok {
+						return nil, nil, nil, err
+					}
+					logger.Noticef("%v", err)
+				}
+				fallbackChannels := make(map[string]string, len(fallbackActions))
+				for _, action := range fallbackActions {
+					fallbackChannels[action.InstanceName] = action.Channel
+				}
+				for _, update := range fallbackUpdates {
+					name := update.InstanceName()
+					st.Warnf("snap %q has no revision in channel %q for this architecture, refreshing it from %q instead", name, stateByInstanceName[name].Channel, fallbackChannels[name])
+				}
+				updatesForUser = append(updatesForUser, fallbackUpdates...)
+			}
 		}
 
 		updates = append(updates, updatesForUser...)
@@ -442,6 +469,62 @@ func refreshCandidates(ctx context.Context, st *state.State, names []string, use
 	return updates, stateByInstanceName, ignoreValidationByInstanceName, nil
 }
 
+// riskFallbackActions returns the refresh actions to retry, from the risk
+// configured with refresh.risk-fallback within the same track, the
+// refreshes that failed because the tracked channel has no revision for
+// the device architecture.
+func riskFallbackActions(st *state.State, saErr *store.SnapActionError, actions []*store.SnapAction, stateByInstanceName map[string]*SnapState) ([]*store.SnapAction, error) {
+	var risk string
+	tr := config.NewTransaction(st)
+	if err := tr.Get("core", "refresh.risk-fallback", &risk); err != nil && !config.IsNoOption(err) {
+		return nil, err
+	}
+	if risk == "" {
+		return nil, nil
+	}
+
+	var fallbackActions []*store.SnapAction
+	for _, action := range actions {
+		notAvailable, ok := saErr.Refresh[action.InstanceName].(*store.RevisionNotAvailableError)
+		if !ok {
+			continue
+		}
+		snapst := stateByInstanceName[action.InstanceName]
+		if snapst == nil || snapst.Channel == "" {
+			continue
+		}
+		tracking, err := snap.ParseChannel(snapst.Channel, "")
+		if err != nil || tracking.Risk == risk {
+			continue
+		}
+		if len(notAvailable.Releases) > 0 && !hasRelease(notAvailable.Releases, tracking.Track, risk) {
+			continue
+		}
+		fallback := risk
+		if tracking.Track != "" {
+			fallback = tracking.Track + "/" + risk
+		}
+		fallbackActions = append(fallbackActions, &store.SnapAction{
+			Action:       "refresh",
+			SnapID:       action.SnapID,
+			InstanceName: action.InstanceName,
+			Channel:      fallback,
+		})
+	}
+	return fallbackActions, nil
+}
+
+// hasRelease returns whether the given releases include the given track
+// and risk for the device architecture.
+func hasRelease(releases []snap.Channel, track, risk string) bool {
+	for _, r := range releases {
+		if r.Architecture == arch.UbuntuArchitecture() && r.Track == track && r.Risk == risk {
+			return true
+		}
+	}
+	return false
+}
+
 func installCandidates(st *state.State, names []string, channel string, user *auth.UserState) ([]*snap.Info, error) {
 	curSnaps, err := currentSnaps(st)
 	if err != nil {
@@ -0,0 +1,152 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2019 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package snapstate
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/snap"
+)
+
+// SnapNeedsTermsAcceptedError is returned when installing a snap whose
+// license agreement has not been accepted yet.
+type SnapNeedsTermsAcceptedError struct {
+	Snap string
+	// License is the license of the snap, if known.
+	License string
+	// LicenseVersion is the version of the license agreement to accept.
+	LicenseVersion string
+}
+
+func (e *SnapNeedsTermsAcceptedError) Error() string {
+	return fmt.Sprintf("snap %q requires its terms to be accepted", e.Snap)
+}
+
+// termsAcceptance records the acceptance of the terms of a snap.
+type termsAcceptance struct {
+	LicenseVersion string    `json:"license-version,omitempty"`
+	Time           time.Time `json:"time"`
+}
+
+// NeedsTermsAccepted returns whether installing the given snap
+// requires an explicit acceptance of its terms, that is if its store
+// metadata declares an explicit license agreement. Paid snaps are not
+// gated here: their purchase terms are accepted when buying them (see
+// the /v2/buy API) and the store does not let them be installed
+// otherwise.
+func NeedsTermsAccepted(info *snap.Info) bool {
+	if info.SnapID == "" {
+		// terms are tracked per snap-id, local snaps have none
+		return false
+	}
+	return info.LicenseAgreement == "explicit"
+}
+
+func termsAcceptances(st *state.State) (map[string]*termsAcceptance, error) {
+	var acceptances map[string]*termsAcceptance
+	err := st.Get("terms-accepted", &acceptances)
+	if err != nil && err != state.ErrNoState {
+		return nil, fmt.Errorf("cannot get accepted snap terms: %v", err)
+	}
+	if acceptances == nil {
+		acceptances = make(map[string]*termsAcceptance)
+	}
+	return acceptances, nil
+}
+
+// TermsAccepted returns whether the terms of the given snap were
+// already accepted. Accepting a different license version than the
+// one currently declared by the snap does not count.
+func TermsAccepted(st *state.State, info *snap.Info) (bool, error) {
+	if !NeedsTermsAccepted(info) {
+		return true, nil
+	}
+	acceptances, err := termsAcceptances(st)
+	if err != nil {
+		return false, err
+	}
+	acc := acceptances[info.SnapID]
+	if acc == nil {
+		return false, nil
+	}
+	return acc.LicenseVersion == info.LicenseVersion, nil
+}
+
+// setTermsAcceptance records the acceptance of the terms of the snap
+// with the given snap-id, or forgets it if nil, and returns the
+// acceptance recorded before.
+func setTermsAcceptance(st *state.State, snapID string, acc *termsAcceptance) (old *termsAcceptance, err error) {
+	acceptances, err := termsAcceptances(st)
+	if err != nil {
+		return nil, err
+	}
+	old = acceptances[snapID]
+	if acc != nil {
+		acceptances[snapID] = acc
+	} else {
+		delete(acceptances, snapID)
+	}
+	st.Set("terms-accepted", acceptances)
+	return old, nil
+}
+
+func newTermsAcceptance(info *snap.Info) *termsAcceptance {
+	return &termsAcceptance{
+		LicenseVersion: info.LicenseVersion,
+		Time:           time.Now(),
+	}
+}
+
+// AcceptTerms records in the state that the terms of the given snap
+// were accepted, ahead of installing it.
+func AcceptTerms(st *state.State, info *snap.Info) error {
+	if !NeedsTermsAccepted(info) {
+		return nil
+	}
+	_, err := setTermsAcceptance(st, info.SnapID, newTermsAcceptance(info))
+	return err
+}
+
+// checkTerms checks, when installing on behalf of a user, that the
+// terms of the given snap were accepted before or that the user
+// accepts them now, as requested via the flags. It returns the new
+// acceptance, if any, which is recorded only once the snap is linked.
+func checkTerms(st *state.State, info *snap.Info, flags Flags) (*termsAcceptance, error) {
+	if !flags.CheckTerms {
+		return nil, nil
+	}
+	accepted, err := TermsAccepted(st, info)
+	if err != nil {
+		return nil, err
+	}
+	if accepted {
+		return nil, nil
+	}
+	if !flags.AcceptTerms {
+		return nil, &SnapNeedsTermsAcceptedError{
+			Snap:           info.InstanceName(),
+			License:        info.License,
+			LicenseVersion: info.LicenseVersion,
+		}
+	}
+	return newTermsAcceptance(info), nil
+}
@@ -0,0 +1,206 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2019 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package snapstate_test
+
+import (
+	"context"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/overlord/snapstate"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/snap"
+)
+
+func (s *snapmgrTestSuite) TestInstallNeedsTermsAccepted(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	opts := &snapstate.RevisionOptions{Channel: "channel-for-license"}
+	_, err := snapstate.Install(context.Background(), s.state, "some-snap", opts, s.user.ID, snapstate.Flags{CheckTerms: true})
+	c.Check(err, DeepEquals, &snapstate.SnapNeedsTermsAcceptedError{Snap: "some-snap", LicenseVersion: "2"})
+	c.Check(err, ErrorMatches, `snap "some-snap" requires its terms to be accepted`)
+
+	// nothing was recorded
+	var accepted map[string]interface{}
+	c.Check(s.state.Get("terms-accepted", &accepted), NotNil)
+}
+
+func (s *snapmgrTestSuite) TestInstallTermsNotChecked(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	for _, t := range []struct {
+		channel string
+		flags   snapstate.Flags
+	}{
+		// paid snaps that can be installed were bought already
+		{"channel-for-paid", snapstate.Flags{CheckTerms: true}},
+		// automated installations are not gated
+		{"channel-for-license", snapstate.Flags{}},
+	} {
+		opts := &snapstate.RevisionOptions{Channel: t.channel}
+		ts, err := snapstate.Install(context.Background(), s.state, "some-snap", opts, s.user.ID, t.flags)
+		c.Assert(err, IsNil, Commentf("%s", t.channel))
+		snapsup, err := snapstate.TaskSnapSetup(ts.Tasks()[0])
+		c.Assert(err, IsNil)
+		c.Check(snapsup.AcceptedTerms, IsNil)
+		s.state.NewChange("install", "...").AddAll(ts)
+		s.state.Unlock()
+		s.settle(c)
+		s.state.Lock()
+		snapstate.Set(s.state, "some-snap", nil)
+	}
+}
+
+func (s *snapmgrTestSuite) TestInstallAcceptTermsRecordsAcceptance(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	opts := &snapstate.RevisionOptions{Channel: "channel-for-license"}
+	ts, err := snapstate.Install(context.Background(), s.state, "some-snap", opts, s.user.ID, snapstate.Flags{CheckTerms: true, AcceptTerms: true})
+	c.Assert(err, IsNil)
+
+	info := &snap.Info{
+		SideInfo:         snap.SideInfo{RealName: "some-snap", SnapID: "some-snap-id"},
+		LicenseAgreement: "explicit",
+		LicenseVersion:   "2",
+	}
+	// the acceptance is only recorded once the snap is linked
+	accepted, err := snapstate.TermsAccepted(s.state, info)
+	c.Assert(err, IsNil)
+	c.Check(accepted, Equals, false)
+	snapsup, err := snapstate.TaskSnapSetup(ts.Tasks()[0])
+	c.Assert(err, IsNil)
+	c.Check(snapsup.Flags.CheckTerms, Equals, false)
+	c.Check(snapsup.Flags.AcceptTerms, Equals, false)
+	c.Check(snapsup.AcceptedTerms, NotNil)
+
+	chg := s.state.NewChange("install", "install a snap")
+	chg.AddAll(ts)
+	s.state.Unlock()
+	defer s.se.Stop()
+	s.settle(c)
+	s.state.Lock()
+	c.Assert(chg.Err(), IsNil)
+
+	accepted, err = snapstate.TermsAccepted(s.state, info)
+	c.Assert(err, IsNil)
+	c.Check(accepted, Equals, true)
+
+	// a new license version needs to be accepted again
+	info.LicenseVersion = "3"
+	accepted, err = snapstate.TermsAccepted(s.state, info)
+	c.Assert(err, IsNil)
+	c.Check(accepted, Equals, false)
+}
+
+func (s *snapmgrTestSuite) TestInstallAcceptTermsUndone(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	opts := &snapstate.RevisionOptions{Channel: "channel-for-license"}
+	ts, err := snapstate.Install(context.Background(), s.state, "some-snap", opts, s.user.ID, snapstate.Flags{CheckTerms: true, AcceptTerms: true})
+	c.Assert(err, IsNil)
+	chg := s.state.NewChange("install", "install a snap")
+	chg.AddAll(ts)
+	last := ts.Tasks()[len(ts.Tasks())-1]
+	terr := s.state.NewTask("error-trigger", "provoking total undo")
+	terr.WaitFor(last)
+	terr.JoinLane(last.Lanes()[0])
+	chg.AddTask(terr)
+
+	s.state.Unlock()
+	defer s.se.Stop()
+	s.settle(c)
+	s.state.Lock()
+	c.Assert(chg.Status(), Equals, state.ErrorStatus)
+
+	// the acceptance was forgotten together with the installation
+	var accepted map[string]interface{}
+	c.Assert(s.state.Get("terms-accepted", &accepted), IsNil)
+	c.Check(accepted, HasLen, 0)
+}
+
+func (s *snapmgrTestSuite) TestNeedsTermsAccepted(c *C) {
+	c.Check(snapstate.NeedsTermsAccepted(&snap.Info{SideInfo: snap.SideInfo{SnapID: "id"}}), Equals, false)
+	c.Check(snapstate.NeedsTermsAccepted(&snap.Info{SideInfo: snap.SideInfo{SnapID: "id", Paid: true}}), Equals, false)
+	c.Check(snapstate.NeedsTermsAccepted(&snap.Info{SideInfo: snap.SideInfo{SnapID: "id"}, LicenseAgreement: "explicit"}), Equals, true)
+	// local snaps are never gated
+	c.Check(snapstate.NeedsTermsAccepted(&snap.Info{LicenseAgreement: "explicit"}), Equals, false)
+}
+
+func (s *snapmgrTestSuite) TestInstallManyNeedsTermsAccepted(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	_, _, err := snapstate.InstallMany(s.state, []string{"one", "some-snap-with-license"}, s.user.ID, &snapstate.Flags{CheckTerms: true})
+	c.Check(err, DeepEquals, &snapstate.SnapNeedsTermsAcceptedError{Snap: "some-snap-with-license", LicenseVersion: "2"})
+
+	// automated installations are not gated
+	_, tts, err := snapstate.InstallMany(s.state, []string{"one", "some-snap-with-license"}, s.user.ID, nil)
+	c.Assert(err, IsNil)
+	c.Assert(tts, HasLen, 2)
+}
+
+func (s *snapmgrTestSuite) TestInstallManyAcceptTerms(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	_, tts, err := snapstate.InstallMany(s.state, []string{"one", "some-snap-with-license"}, s.user.ID, &snapstate.Flags{CheckTerms: true, AcceptTerms: true})
+	c.Assert(err, IsNil)
+	c.Assert(tts, HasLen, 2)
+
+	snapsup, err := snapstate.TaskSnapSetup(tts[0].Tasks()[0])
+	c.Assert(err, IsNil)
+	c.Check(snapsup.AcceptedTerms, IsNil)
+	snapsup, err = snapstate.TaskSnapSetup(tts[1].Tasks()[0])
+	c.Assert(err, IsNil)
+	c.Check(snapsup.Flags.CheckTerms, Equals, false)
+	c.Check(snapsup.Flags.AcceptTerms, Equals, false)
+	c.Check(snapsup.AcceptedTerms, NotNil)
+}
+
+func (s *snapmgrTestSuite) TestAcceptTerms(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	info := &snap.Info{
+		SideInfo:         snap.SideInfo{RealName: "some-snap-with-license", SnapID: "some-snap-with-license-id"},
+		LicenseAgreement: "explicit",
+		LicenseVersion:   "2",
+	}
+	c.Assert(snapstate.AcceptTerms(s.state, info), IsNil)
+	accepted, err := snapstate.TermsAccepted(s.state, info)
+	c.Assert(err, IsNil)
+	c.Check(accepted, Equals, true)
+
+	// the snap can now be installed without accepting its terms again
+	_, tts, err := snapstate.InstallMany(s.state, []string{"some-snap-with-license"}, s.user.ID, &snapstate.Flags{CheckTerms: true})
+	c.Assert(err, IsNil)
+	c.Assert(tts, HasLen, 1)
+
+	// nothing is recorded for snaps without a license agreement
+	other := &snap.Info{SideInfo: snap.SideInfo{RealName: "other-snap", SnapID: "other-snap-id"}}
+	c.Assert(snapstate.AcceptTerms(s.state, other), IsNil)
+	var acceptances map[string]interface{}
+	c.Assert(s.state.Get("terms-accepted", &acceptances), IsNil)
+	c.Check(acceptances, HasLen, 1)
+}
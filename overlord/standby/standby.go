@@ -19,8 +19,11 @@
 package standby
 
 import (
+	"encoding/json"
 	"time"
 
+	"github.com/snapcore/snapd/logger"
+	"github.com/snapcore/snapd/overlord/configstate/config"
 	"github.com/snapcore/snapd/overlord/state"
 )
 
@@ -33,6 +36,90 @@ type Opinionator interface {
 	CanStandby() bool
 }
 
+// ActivityTracker is an Opinionator that also knows when it last saw
+// some activity, e.g. an API request. When idle exit is enabled snapd
+// only goes into socket activation mode once all the trackers have
+// been quiet for the idle exit timeout.
+type ActivityTracker interface {
+	Opinionator
+	LastActivity() time.Time
+}
+
+// QuickStarter is an Opinionator that keeps in memory caches worth
+// preserving when snapd goes into socket activation mode, e.g. to not
+// forget the backoff of failed attempts. A snapshot of them is saved
+// in the state under the given key and can be read back with
+// LoadQuickStart when snapd starts again.
+type QuickStarter interface {
+	Opinionator
+	// QuickStartSnapshot returns the key and a snapshot of the
+	// caches, which must marshal with encoding/json. The state is
+	// locked.
+	QuickStartSnapshot() (key string, snapshot interface{})
+}
+
+// quickStartStateKey is where the snapshots are kept until the next
+// start of snapd
+const quickStartStateKey = "quick-start"
+
+// LoadQuickStart reads into snapshot the caches saved under the
+// given key before snapd last went into socket activation mode. It
+// returns state.ErrNoState if there is no such snapshot. Snapshots are
+// dropped once the standby opinions are started. The state must be
+// locked by the caller.
+func LoadQuickStart(st *state.State, key string, snapshot interface{}) error {
+	var snapshots map[string]*json.RawMessage
+	if err := st.Get(quickStartStateKey, &snapshots); err != nil {
+		return err
+	}
+	raw := snapshots[key]
+	if raw == nil {
+		return state.ErrNoState
+	}
+	return json.Unmarshal(*raw, snapshot)
+}
+
+// saveQuickStart saves in the state the snapshots of the caches of
+// the QuickStarter opinions.
+func (m *StandbyOpinions) saveQuickStart() {
+	st := m.state
+	st.Lock()
+	defer st.Unlock()
+
+	snapshots := make(map[string]interface{})
+	for _, opi := range m.opinions {
+		if qs, ok := opi.(QuickStarter); ok {
+			key, snapshot := qs.QuickStartSnapshot()
+			snapshots[key] = snapshot
+		}
+	}
+	if len(snapshots) == 0 {
+		return
+	}
+	logger.Debugf("Saving quick-start snapshot before going into socket activation mode.")
+	st.Set(quickStartStateKey, snapshots)
+}
+
+// IdleExitTimeout returns for how long snapd needs to be idle before
+// it goes into socket activation mode even with snaps installed, as
+// set via the daemon.idle-exit system option. Zero means idle exit is
+// disabled. The state must be locked by the caller.
+func IdleExitTimeout(st *state.State) time.Duration {
+	var value string
+	tr := config.NewTransaction(st)
+	if err := tr.Get("core", "daemon.idle-exit", &value); err != nil {
+		return 0
+	}
+	if value == "" {
+		return 0
+	}
+	timeout, err := time.ParseDuration(value)
+	if err != nil {
+		return 0
+	}
+	return timeout
+}
+
 // StandbyOpinions tracks if snapd can go into socket activation mode
 type StandbyOpinions struct {
 	state     *state.State
@@ -45,15 +132,22 @@ type StandbyOpinions struct {
 
 // CanStandby returns true if the main ensure loop can go into
 // "socket-activation" mode. This is only possible once seeding is done
-// and there are no snaps on the system. This is to reduce the memory
-// footprint on e.g. containers.
+// and there are no snaps on the system, or idle exit is enabled and
+// snapd has been idle long enough. This is to reduce the memory
+// footprint on e.g. containers and small devices.
 func (m *StandbyOpinions) CanStandby() bool {
 	st := m.state
 	st.Lock()
 	defer st.Unlock()
 
+	now := time.Now()
+	wait := standbyWait
+	idleExit := IdleExitTimeout(st)
+	if idleExit > wait {
+		wait = idleExit
+	}
 	// check if enough time has passed
-	if m.startTime.Add(standbyWait).After(time.Now()) {
+	if m.startTime.Add(wait).After(now) {
 		return false
 	}
 	// check if there are any changes in flight
@@ -67,6 +161,11 @@ func (m *StandbyOpinions) CanStandby() bool {
 		if !ct.CanStandby() {
 			return false
 		}
+		if at, ok := ct.(ActivityTracker); ok && idleExit > 0 {
+			if at.LastActivity().Add(idleExit).After(now) {
+				return false
+			}
+		}
 	}
 	return true
 }
@@ -81,11 +180,17 @@ func New(st *state.State) *StandbyOpinions {
 }
 
 func (m *StandbyOpinions) Start() {
+	// the managers have read the quick-start snapshot, if any, by now
+	m.state.Lock()
+	m.state.Set(quickStartStateKey, nil)
+	m.state.Unlock()
+
 	go func() {
 		wait := standbyWait
 		timer := time.NewTimer(wait)
 		for {
 			if m.CanStandby() {
+				m.saveQuickStart()
 				stateRequestRestart(m.state, state.RestartSocket)
 			}
 			select {
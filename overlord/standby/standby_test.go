@@ -23,6 +23,7 @@ import (
 
 	. "gopkg.in/check.v1"
 
+	"github.com/snapcore/snapd/overlord/configstate/config"
 	"github.com/snapcore/snapd/overlord/standby"
 	"github.com/snapcore/snapd/overlord/state"
 )
@@ -112,6 +113,57 @@ func (s *standbySuite) TestCanStandbyWithOpinion(c *C) {
 	c.Check(m.CanStandby(), Equals, false)
 }
 
+type activityTracker struct {
+	lastActivity time.Time
+}
+
+func (a *activityTracker) CanStandby() bool {
+	return true
+}
+
+func (a *activityTracker) LastActivity() time.Time {
+	return a.lastActivity
+}
+
+func (s *standbySuite) TestCanStandbyIdleExit(c *C) {
+	tracker := &activityTracker{}
+	m := standby.New(s.state)
+	m.AddOpinion(tracker)
+
+	s.state.Lock()
+	tr := config.NewTransaction(s.state)
+	tr.Set("core", "daemon.idle-exit", "10m")
+	tr.Commit()
+	c.Check(standby.IdleExitTimeout(s.state), Equals, 10*time.Minute)
+	s.state.Unlock()
+
+	// not long enough since the start
+	m.SetStartTime(time.Now().Add(-5 * time.Minute))
+	c.Check(m.CanStandby(), Equals, false)
+
+	m.SetStartTime(time.Now().Add(-15 * time.Minute))
+	c.Check(m.CanStandby(), Equals, true)
+
+	// recent activity
+	tracker.lastActivity = time.Now().Add(-time.Minute)
+	c.Check(m.CanStandby(), Equals, false)
+
+	tracker.lastActivity = time.Now().Add(-11 * time.Minute)
+	c.Check(m.CanStandby(), Equals, true)
+}
+
+func (s *standbySuite) TestIdleExitTimeoutUnset(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	c.Check(standby.IdleExitTimeout(s.state), Equals, time.Duration(0))
+
+	tr := config.NewTransaction(s.state)
+	tr.Set("core", "daemon.idle-exit", "bogus")
+	tr.Commit()
+	c.Check(standby.IdleExitTimeout(s.state), Equals, time.Duration(0))
+}
+
 type opine func() bool
 
 func (f opine) CanStandby() bool {
@@ -201,3 +253,66 @@ func (s *standbySuite) TestStopWaits(c *C) {
 		c.Fatal("stop did not complete")
 	}
 }
+
+type quickStarter struct {
+	n int
+}
+
+func (q *quickStarter) CanStandby() bool {
+	return true
+}
+
+func (q *quickStarter) QuickStartSnapshot() (string, interface{}) {
+	return "quick-starter", map[string]int{"n": q.n}
+}
+
+func (s *standbySuite) TestStandbySavesQuickStart(c *C) {
+	restarted := make(chan struct{})
+	defer standby.MockStandbyWait(time.Millisecond)()
+	defer standby.MockStateRequestRestart(func(st *state.State, t state.RestartType) {
+		c.Check(t, Equals, state.RestartSocket)
+		close(restarted)
+	})()
+
+	m := standby.New(s.state)
+	m.AddOpinion(&quickStarter{n: 42})
+	m.AddOpinion(opine(func() bool { return true }))
+
+	m.Start()
+	select {
+	case <-restarted:
+	case <-time.After(5 * time.Second):
+		c.Fatal("standby did not request a restart")
+	}
+	m.Stop()
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	var snapshot map[string]int
+	err := standby.LoadQuickStart(s.state, "quick-starter", &snapshot)
+	c.Assert(err, IsNil)
+	c.Check(snapshot, DeepEquals, map[string]int{"n": 42})
+
+	err = standby.LoadQuickStart(s.state, "other", &snapshot)
+	c.Check(err, Equals, state.ErrNoState)
+}
+
+func (s *standbySuite) TestStartDropsQuickStart(c *C) {
+	defer standby.MockStandbyWait(time.Hour)()
+
+	s.state.Lock()
+	s.state.Set("quick-start", map[string]interface{}{"quick-starter": 1})
+	var n int
+	c.Assert(standby.LoadQuickStart(s.state, "quick-starter", &n), IsNil)
+	c.Check(n, Equals, 1)
+	s.state.Unlock()
+
+	m := standby.New(s.state)
+	m.Start()
+	m.Stop()
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	err := standby.LoadQuickStart(s.state, "quick-starter", &n)
+	c.Check(err, Equals, state.ErrNoState)
+}
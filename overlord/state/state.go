@@ -21,6 +21,7 @@
 package state
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -354,6 +355,26 @@ func (s *State) Cache(key, value interface{}) {
 	}
 }
 
+// Copy returns a copy of the state that has no backend, and so is
+// never persisted, and that starts out with the same cached values.
+// It can be used to compute what an operation would do without
+// affecting the state. The state must be locked.
+func (s *State) Copy() (*State, error) {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return nil, fmt.Errorf("cannot copy state: %v", err)
+	}
+	cpy, err := ReadState(nil, bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range s.cache {
+		cpy.cache[k] = v
+	}
+	cpy.bootID = s.bootID
+	return cpy, nil
+}
+
 // NewChange adds a new change to the state.
 func (s *State) NewChange(kind, summary string) *Change {
 	s.writing()
@@ -399,6 +420,33 @@ func (s *State) NewTask(kind, summary string) *Task {
 	return t
 }
 
+// DiscardTasks removes the given tasks from the state. The tasks must
+// not be linked to a change, it is meant to drop the tasks built for
+// an operation that ends up not being performed, e.g. when only
+// simulating it.
+func (s *State) DiscardTasks(tasks []*Task) {
+	s.writing()
+	for _, t := range tasks {
+		if t.Change() != nil {
+			panic(fmt.Sprintf("internal error: cannot discard task %s linked to change %s", t.ID(), t.Change().ID()))
+		}
+	}
+	for _, t := range tasks {
+		// unlink the task from the tasks that are kept
+		for _, tid := range t.waitTasks {
+			if wt := s.tasks[tid]; wt != nil {
+				wt.haltTasks = removeOnce(wt.haltTasks, t.id)
+			}
+		}
+		for _, tid := range t.haltTasks {
+			if ht := s.tasks[tid]; ht != nil {
+				ht.waitTasks = removeOnce(ht.waitTasks, t.id)
+			}
+		}
+		delete(s.tasks, t.id)
+	}
+}
+
 // Tasks returns all tasks currently known to the state and linked to changes.
 func (s *State) Tasks() []*Task {
 	s.reading()
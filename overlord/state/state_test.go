@@ -167,6 +167,40 @@ func (ss *stateSuite) TestCache(c *C) {
 	c.Assert(ok, Equals, false)
 }
 
+func (ss *stateSuite) TestCopy(c *C) {
+	b := new(fakeStateBackend)
+	st := state.New(b)
+	st.Lock()
+	defer st.Unlock()
+
+	type key1 struct{}
+	st.Cache(key1{}, "value1")
+	st.Set("k", "v")
+	chg := st.NewChange("install", "...")
+	chg.AddTask(st.NewTask("download", "..."))
+
+	cpy, err := st.Copy()
+	c.Assert(err, IsNil)
+
+	cpy.Lock()
+	var v string
+	c.Check(cpy.Get("k", &v), IsNil)
+	c.Check(v, Equals, "v")
+	c.Check(cpy.Cached(key1{}), Equals, "value1")
+	c.Assert(cpy.Change(chg.ID()), NotNil)
+	c.Check(cpy.Change(chg.ID()).Tasks(), HasLen, 1)
+
+	cpy.Set("k", "other")
+	cpy.NewChange("remove", "...")
+	cpy.Unlock()
+
+	// the state is left untouched
+	c.Check(st.Get("k", &v), IsNil)
+	c.Check(v, Equals, "v")
+	c.Check(st.Changes(), HasLen, 1)
+	c.Check(b.checkpoints, HasLen, 0)
+}
+
 type fakeStateBackend struct {
 	checkpoints      [][]byte
 	error            func() error
@@ -680,6 +714,47 @@ func (ss *stateSuite) TestNewTaskHiddenUntilLinked(c *C) {
 	c.Check(st.Task(t1.ID()), IsNil)
 }
 
+func (ss *stateSuite) TestDiscardTasks(c *C) {
+	st := state.New(nil)
+	st.Lock()
+	defer st.Unlock()
+
+	t1 := st.NewTask("check", "...")
+	t2 := st.NewTask("check", "...")
+	chg := st.NewChange("install", "...")
+	t3 := st.NewTask("check", "...")
+	chg.AddTask(t3)
+	c.Check(st.TaskCount(), Equals, 3)
+
+	st.DiscardTasks([]*state.Task{t1, t2})
+	c.Check(st.TaskCount(), Equals, 1)
+
+	c.Check(func() { st.DiscardTasks([]*state.Task{t3}) }, PanicMatches, `internal error: cannot discard task 3 linked to change 1`)
+	c.Check(st.Task(t3.ID()), Equals, t3)
+}
+
+func (ss *stateSuite) TestDiscardTasksUnlinksKeptTasks(c *C) {
+	st := state.New(nil)
+	st.Lock()
+	defer st.Unlock()
+
+	chg := st.NewChange("install", "...")
+	t1 := st.NewTask("check", "...")
+	t2 := st.NewTask("check", "...")
+	chg.AddTask(t1)
+	chg.AddTask(t2)
+
+	t3 := st.NewTask("check", "...")
+	t3.WaitFor(t1)
+	t2.WaitFor(t3)
+	c.Check(t1.HaltTasks(), DeepEquals, []*state.Task{t3})
+	c.Check(t2.WaitTasks(), DeepEquals, []*state.Task{t3})
+
+	st.DiscardTasks([]*state.Task{t3})
+	c.Check(t1.HaltTasks(), HasLen, 0)
+	c.Check(t2.WaitTasks(), HasLen, 0)
+}
+
 func (ss *stateSuite) TestMethodEntrance(c *C) {
 	st := state.New(&fakeStateBackend{})
 
@@ -691,6 +766,7 @@ func (ss *stateSuite) TestMethodEntrance(c *C) {
 		func() { st.Set("foo", 1) },
 		func() { st.NewChange("install", "...") },
 		func() { st.NewTask("download", "...") },
+		func() { st.DiscardTasks(nil) },
 		func() { st.UnmarshalJSON(nil) },
 		func() { st.NewLane() },
 		func() { st.Warnf("hello") },
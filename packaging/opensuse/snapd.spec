@@ -28,7 +28,7 @@
 
 # The list of systemd services we are expected to ship. Note that this does
 # not include services that are only required on core systems.
-%global systemd_services_list snapd.socket snapd.service snapd.seeded.service snapd.failure.service %{?with_apparmor:snapd.apparmor.service}
+%global systemd_services_list snapd.socket snapd.service snapd.seeded.service snapd.failure.service snapd.network-status.service %{?with_apparmor:snapd.apparmor.service}
 
 # Alternate snap mount directory: not used by openSUSE.
 # If this spec file is integrated into Fedora then consider
@@ -279,6 +279,7 @@ install -m 644 -D %{indigo_srcdir}/packaging/opensuse/permissions.paranoid %{bui
 install -d %{buildroot}%{_sbindir}
 ln -sf %{_sbindir}/service %{buildroot}%{_sbindir}/rcsnapd
 ln -sf %{_sbindir}/service %{buildroot}%{_sbindir}/rcsnapd.seeded
+ln -sf %{_sbindir}/service %{buildroot}%{_sbindir}/rcsnapd.network-status
 %if %{with apparmor}
 ln -sf %{_sbindir}/service %{buildroot}%{_sbindir}/rcsnapd.apparmor
 %endif
@@ -338,6 +339,7 @@ fi
 %dir %attr(0111,root,root) %{_sharedstatedir}/snapd/void
 %dir %{_datadir}/dbus-1
 %dir %{_datadir}/dbus-1/services
+%dir %{_datadir}/dbus-1/system.d
 %dir %{_datadir}/polkit-1
 %dir %{_datadir}/polkit-1/actions
 %dir %{_environmentdir}
@@ -385,6 +387,7 @@ fi
 %{_datadir}/bash-completion/completions/snap
 %{_datadir}/dbus-1/services/io.snapcraft.Launcher.service
 %{_datadir}/dbus-1/services/io.snapcraft.Settings.service
+%{_datadir}/dbus-1/system.d/io.snapcraft.NetworkStatus.conf
 %{_datadir}/polkit-1/actions/io.snapcraft.snapd.policy
 %{_environmentdir}/990-snapd.conf
 %{_libexecdir}/snapd/complete.sh
@@ -405,12 +408,15 @@ fi
 %{_mandir}/man8/snap.8*
 %{_mandir}/man8/snapd-env-generator.8*
 %{_sbindir}/rcsnapd
+%{_sbindir}/rcsnapd.network-status
 %{_sbindir}/rcsnapd.seeded
 %{_sysconfdir}/xdg/autostart/snap-userd-autostart.desktop
 %{_systemd_system_env_generator_dir}/snapd-env-generator
 %{_systemdgeneratordir}/snapd-generator
 %{_unitdir}/snapd.failure.service
 %{_unitdir}/snapd.seeded.service
+%{_unitdir}/snapd.idle-wakeup.timer
+%{_unitdir}/snapd.network-status.service
 %{_unitdir}/snapd.service
 %{_unitdir}/snapd.socket
 
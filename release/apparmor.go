@@ -288,10 +288,13 @@ func probeAppArmorParserFeatures() ([]string, error) {
 	if err != nil {
 		return []string{}, err
 	}
-	features := make([]string, 0, 1)
-	if tryAppArmorParserFeature(parser, "change_profile unsafe /**,") {
+	features := make([]string, 0, 2)
+	if tryAppArmorParserFeature(parser, "profile snap-test {\n change_profile unsafe /**,\n}") {
 		features = append(features, "unsafe")
 	}
+	if tryAppArmorParserFeature(parser, "profile snap-test flags=(unconfined) {\n}") {
+		features = append(features, "unconfined")
+	}
 	sort.Strings(features)
 	return features, nil
 }
@@ -308,9 +311,9 @@ func findAppArmorParser() (string, error) {
 }
 
 // tryAppArmorParserFeature attempts to pre-process a bit of apparmor syntax with a given parser.
-func tryAppArmorParserFeature(parser, rule string) bool {
+func tryAppArmorParserFeature(parser, profile string) bool {
 	cmd := exec.Command(parser, "--preprocess")
-	cmd.Stdin = bytes.NewBufferString(fmt.Sprintf("profile snap-test {\n %s\n}", rule))
+	cmd.Stdin = bytes.NewBufferString(profile)
 	if err := cmd.Run(); err != nil {
 		return false
 	}
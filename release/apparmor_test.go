@@ -168,11 +168,12 @@ func (s *apparmorSuite) TestProbeAppArmorParserFeatures(c *C) {
 		features []string
 	}{
 		{"exit 1", []string{}},
-		{"exit 0", []string{"unsafe"}},
+		{"exit 0", []string{"unconfined", "unsafe"}},
 	}
 
 	for _, t := range testcases {
-		mockParserCmd := testutil.MockCommand(c, "apparmor_parser", fmt.Sprintf("cat > %s/stdin; %s", d, t.exit))
+		os.Remove(filepath.Join(d, "stdin"))
+		mockParserCmd := testutil.MockCommand(c, "apparmor_parser", fmt.Sprintf("cat >> %s/stdin; echo >> %s/stdin; %s", d, d, t.exit))
 		defer mockParserCmd.Restore()
 		restore := release.MockAppArmorParserSearchPath(mockParserCmd.BinDir())
 		defer restore()
@@ -180,10 +181,10 @@ func (s *apparmorSuite) TestProbeAppArmorParserFeatures(c *C) {
 		features, err := release.ProbeAppArmorParserFeatures()
 		c.Assert(err, IsNil)
 		c.Check(features, DeepEquals, t.features)
-		c.Check(mockParserCmd.Calls(), DeepEquals, [][]string{{"apparmor_parser", "--preprocess"}})
+		c.Check(mockParserCmd.Calls(), DeepEquals, [][]string{{"apparmor_parser", "--preprocess"}, {"apparmor_parser", "--preprocess"}})
 		data, err := ioutil.ReadFile(filepath.Join(d, "stdin"))
 		c.Assert(err, IsNil)
-		c.Check(string(data), Equals, "profile snap-test {\n change_profile unsafe /**,\n}")
+		c.Check(string(data), Equals, "profile snap-test {\n change_profile unsafe /**,\n}\nprofile snap-test flags=(unconfined) {\n}\n")
 	}
 
 	// Pretend that we just don't have apparmor_parser at all.
@@ -215,7 +216,7 @@ func (s *apparmorSuite) TestInterfaceSystemKey(c *C) {
 	c.Check(features, DeepEquals, []string{"network", "policy"})
 	features, err = release.AppArmorParserFeatures()
 	c.Assert(err, IsNil)
-	c.Check(features, DeepEquals, []string{"unsafe"})
+	c.Check(features, DeepEquals, []string{"unconfined", "unsafe"})
 }
 
 func (s *apparmorSuite) TestAppArmorParserMtime(c *C) {
@@ -255,7 +256,7 @@ func (s *apparmorSuite) TestFeaturesProbedOnce(c *C) {
 	c.Check(features, DeepEquals, []string{"network", "policy"})
 	features, err = release.AppArmorParserFeatures()
 	c.Assert(err, IsNil)
-	c.Check(features, DeepEquals, []string{"unsafe"})
+	c.Check(features, DeepEquals, []string{"unconfined", "unsafe"})
 
 	// this makes probing fails but is not done again
 	err = os.RemoveAll(d)
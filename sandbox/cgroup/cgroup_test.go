@@ -17,22 +17,36 @@
  *
  */
 
-package userd_test
+package cgroup_test
 
 import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"testing"
 
 	. "gopkg.in/check.v1"
 
 	"github.com/snapcore/snapd/dirs"
-	"github.com/snapcore/snapd/userd"
+	"github.com/snapcore/snapd/sandbox/cgroup"
 )
 
-type helpersSuite struct{}
+func Test(t *testing.T) { TestingT(t) }
 
-var _ = Suite(&helpersSuite{})
+type cgroupSuite struct {
+	root string
+}
+
+var _ = Suite(&cgroupSuite{})
+
+func (s *cgroupSuite) SetUpTest(c *C) {
+	s.root = c.MkDir()
+	dirs.SetRootDir(s.root)
+}
+
+func (s *cgroupSuite) TearDownTest(c *C) {
+	dirs.SetRootDir("")
+}
 
 var mockCgroup = []byte(`
 10:devices:/user.slice
@@ -48,16 +62,27 @@ var mockCgroup = []byte(`
 0::/user.slice/user-1000.slice/user@1000.service/gnome-terminal-server.service
 `)
 
-func (s *helpersSuite) TestSnapFromPid(c *C) {
-	root := c.MkDir()
-	dirs.SetRootDir(root)
-
-	err := os.MkdirAll(filepath.Join(root, "proc/333"), 0755)
+func (s *cgroupSuite) mockProcCgroup(c *C, pid string, content []byte) {
+	err := os.MkdirAll(filepath.Join(s.root, "proc", pid), 0755)
 	c.Assert(err, IsNil)
-	err = ioutil.WriteFile(filepath.Join(root, "proc/333/cgroup"), mockCgroup, 0755)
+	err = ioutil.WriteFile(filepath.Join(s.root, "proc", pid, "cgroup"), content, 0644)
 	c.Assert(err, IsNil)
+}
+
+func (s *cgroupSuite) TestSnapNameFromPid(c *C) {
+	s.mockProcCgroup(c, "333", mockCgroup)
 
-	snap, err := userd.SnapFromPid(333)
+	snap, err := cgroup.SnapNameFromPid(333)
 	c.Assert(err, IsNil)
 	c.Check(snap, Equals, "hello-world")
 }
+
+func (s *cgroupSuite) TestSnapNameFromPidNotASnap(c *C) {
+	s.mockProcCgroup(c, "333", []byte("7:freezer:/\n0::/user.slice\n"))
+
+	_, err := cgroup.SnapNameFromPid(333)
+	c.Check(err, ErrorMatches, "cannot find a snap for pid 333")
+
+	_, err = cgroup.SnapNameFromPid(444)
+	c.Check(err, ErrorMatches, "open .*/proc/444/cgroup: no such file or directory")
+}
@@ -0,0 +1,216 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2019 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+// Package seedtest provides helpers to fabricate valid seeds, with
+// signed models, fake store assertions and asserted snaps, for testing
+// both snapd and third-party seed tooling.
+package seedtest
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/asserts"
+	"github.com/snapcore/snapd/asserts/assertstest"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/snap/snaptest"
+)
+
+// SeedSnaps helps creating snaps for a seed, together with their
+// snap-declaration and snap-revision assertions.
+type SeedSnaps struct {
+	StoreSigning *assertstest.StoreStack
+	Brands       *assertstest.SigningAccounts
+
+	snaps map[string]string
+	infos map[string]*snap.Info
+}
+
+// SetupAssertSigning initializes StoreSigning for storeBrandID and
+// Brands, with a "my-brand" account registered.
+func (ss *SeedSnaps) SetupAssertSigning(storeBrandID string) {
+	ss.StoreSigning = assertstest.NewStoreStack(storeBrandID, nil)
+	ss.Brands = assertstest.NewSigningAccounts(ss.StoreSigning)
+	brandPrivKey, _ := assertstest.GenerateKey(752)
+	ss.Brands.Register("my-brand", brandPrivKey, map[string]interface{}{
+		"verification": "verified",
+	})
+}
+
+// AssertedSnapID returns the fake snap-id used for snapName.
+func (ss *SeedSnaps) AssertedSnapID(snapName string) string {
+	// FIXME: snapd is special in the interface policy code and it
+	//        identified by its snap-id. so we fake the real snap-id
+	//        here. Instead we should add a "type: snapd" for snaps.
+	if snapName == "snapd" {
+		return "PMrrV4ml8uWuEUDBT8dSGnKUYbevVhc4"
+	}
+	return (snapName + "-snap-" + strings.Repeat("id", 20))[:32]
+}
+
+// MakeAssertedSnap creates a snap file out of snapYaml and files, and
+// signs a snap-declaration and a snap-revision for it with the store
+// key. The snap file can then be retrieved with AssertedSnap.
+func (ss *SeedSnaps) MakeAssertedSnap(c *check.C, snapYaml string, files [][]string, revision snap.Revision, developerID string) (*asserts.SnapDeclaration, *asserts.SnapRevision) {
+	info, err := snap.InfoFromSnapYaml([]byte(snapYaml))
+	c.Assert(err, check.IsNil)
+	snapName := info.SnapName()
+
+	snapFile := snaptest.MakeTestSnapWithFiles(c, snapYaml, files)
+
+	snapID := ss.AssertedSnapID(snapName)
+	declA, err := ss.StoreSigning.Sign(asserts.SnapDeclarationType, map[string]interface{}{
+		"series":       "16",
+		"snap-id":      snapID,
+		"publisher-id": developerID,
+		"snap-name":    snapName,
+		"timestamp":    time.Now().UTC().Format(time.RFC3339),
+	}, nil, "")
+	c.Assert(err, check.IsNil)
+
+	sha3_384, size, err := asserts.SnapFileSHA3_384(snapFile)
+	c.Assert(err, check.IsNil)
+
+	revA, err := ss.StoreSigning.Sign(asserts.SnapRevisionType, map[string]interface{}{
+		"snap-sha3-384": sha3_384,
+		"snap-size":     fmt.Sprintf("%d", size),
+		"snap-id":       snapID,
+		"developer-id":  developerID,
+		"snap-revision": revision.String(),
+		"timestamp":     time.Now().UTC().Format(time.RFC3339),
+	}, nil, "")
+	c.Assert(err, check.IsNil)
+
+	info.SideInfo = snap.SideInfo{
+		RealName: snapName,
+		SnapID:   snapID,
+		Revision: revision,
+	}
+
+	if ss.snaps == nil {
+		ss.snaps = make(map[string]string)
+		ss.infos = make(map[string]*snap.Info)
+	}
+	ss.snaps[snapName] = snapFile
+	ss.infos[snapName] = info
+
+	return declA.(*asserts.SnapDeclaration), revA.(*asserts.SnapRevision)
+}
+
+// AssertedSnap returns the path of the snap file created for snapName
+// by MakeAssertedSnap.
+func (ss *SeedSnaps) AssertedSnap(snapName string) (snapFile string) {
+	return ss.snaps[snapName]
+}
+
+// AssertedSnapInfo returns the snap.Info of the snap created for
+// snapName by MakeAssertedSnap.
+func (ss *SeedSnaps) AssertedSnapInfo(snapName string) *snap.Info {
+	return ss.infos[snapName]
+}
+
+// TestingSeed helps setting up a seed in SeedDir, laid out with a
+// seed.yaml and snaps and assertions subdirectories.
+type TestingSeed struct {
+	SeedSnaps
+
+	SeedDir string
+}
+
+// SnapsDir returns the directory holding the seed snaps.
+func (s *TestingSeed) SnapsDir() string {
+	return filepath.Join(s.SeedDir, "snaps")
+}
+
+// AssertsDir returns the directory holding the seed assertions.
+func (s *TestingSeed) AssertsDir() string {
+	return filepath.Join(s.SeedDir, "assertions")
+}
+
+// MakeAssertedSnap creates an asserted snap like
+// SeedSnaps.MakeAssertedSnap does and puts it in the seed snaps
+// directory, returning its file name there.
+func (s *TestingSeed) MakeAssertedSnap(c *check.C, snapYaml string, files [][]string, revision snap.Revision, developerID string) (snapFname string, snapDecl *asserts.SnapDeclaration, snapRev *asserts.SnapRevision) {
+	snapDecl, snapRev = s.SeedSnaps.MakeAssertedSnap(c, snapYaml, files, revision, developerID)
+
+	snapName := snapDecl.SnapName()
+	snapFile := s.AssertedSnap(snapName)
+	snapFname = filepath.Base(snapFile)
+
+	c.Assert(os.MkdirAll(s.SnapsDir(), 0755), check.IsNil)
+	targetFile := filepath.Join(s.SnapsDir(), snapFname)
+	c.Assert(os.Rename(snapFile, targetFile), check.IsNil)
+	s.snaps[snapName] = targetFile
+
+	return snapFname, snapDecl, snapRev
+}
+
+// MakeModelAssertionChain returns the assertions needed to use a model
+// for model signed by brandID in a seed: the brand account and
+// account-key, the model itself and the store account-key.
+func (s *TestingSeed) MakeModelAssertionChain(brandID, model string, extras ...map[string]interface{}) []asserts.Assertion {
+	assertChain := []asserts.Assertion{}
+
+	assertChain = append(assertChain, s.Brands.Account(brandID))
+	assertChain = append(assertChain, s.Brands.AccountKey(brandID))
+
+	modelA := s.Brands.Model(brandID, model, extras...)
+	assertChain = append(assertChain, modelA)
+
+	storeAccountKey := s.StoreSigning.StoreAccountKey("")
+	assertChain = append(assertChain, storeAccountKey)
+	return assertChain
+}
+
+// WriteAssertions writes the given assertions to fn in the seed
+// assertions directory.
+func (s *TestingSeed) WriteAssertions(fn string, assertions ...asserts.Assertion) {
+	if err := os.MkdirAll(s.AssertsDir(), 0755); err != nil {
+		panic(err)
+	}
+	WriteAssertions(filepath.Join(s.AssertsDir(), fn), assertions...)
+}
+
+// WriteSeedYaml writes the seed.yaml of the seed listing the given
+// snaps.
+func (s *TestingSeed) WriteSeedYaml(c *check.C, snaps ...*snap.SeedSnap) {
+	seed := &snap.Seed{Snaps: snaps}
+	c.Assert(seed.Write(filepath.Join(s.SeedDir, "seed.yaml")), check.IsNil)
+}
+
+// WriteAssertions writes the given assertions to fn.
+// It panics on error.
+func WriteAssertions(fn string, assertions ...asserts.Assertion) {
+	f, err := os.Create(fn)
+	if err != nil {
+		panic(err)
+	}
+	defer f.Close()
+	enc := asserts.NewEncoder(f)
+	for _, a := range assertions {
+		if err := enc.Encode(a); err != nil {
+			panic(err)
+		}
+	}
+}
@@ -0,0 +1,127 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2019 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package seedtest_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/asserts"
+	"github.com/snapcore/snapd/seed/seedtest"
+	"github.com/snapcore/snapd/snap"
+)
+
+func Test(t *testing.T) { TestingT(t) }
+
+type seedtestSuite struct {
+	seedtest.TestingSeed
+
+	restoreSanitize func()
+}
+
+var _ = Suite(&seedtestSuite{})
+
+func (s *seedtestSuite) SetUpTest(c *C) {
+	s.SeedDir = c.MkDir()
+	s.SetupAssertSigning("canonical")
+	s.restoreSanitize = snap.MockSanitizePlugsSlots(func(snapInfo *snap.Info) {})
+}
+
+func (s *seedtestSuite) TearDownTest(c *C) {
+	s.restoreSanitize()
+}
+
+func (s *seedtestSuite) TestAssertedSnapID(c *C) {
+	c.Check(s.AssertedSnapID("foo"), Equals, "foo-snap-idididididididididididi")
+	c.Check(s.AssertedSnapID("snapd"), Equals, "PMrrV4ml8uWuEUDBT8dSGnKUYbevVhc4")
+}
+
+func (s *seedtestSuite) TestMakeModelAssertionChain(c *C) {
+	chain := s.MakeModelAssertionChain("my-brand", "my-model", map[string]interface{}{
+		"architecture": "amd64",
+		"gadget":       "pc",
+		"kernel":       "pc-kernel",
+	})
+	c.Assert(chain, HasLen, 4)
+	c.Check(chain[0].Type(), Equals, asserts.AccountType)
+	c.Check(chain[1].Type(), Equals, asserts.AccountKeyType)
+	c.Check(chain[3].Type(), Equals, asserts.AccountKeyType)
+	model := chain[2].(*asserts.Model)
+	c.Check(model.BrandID(), Equals, "my-brand")
+	c.Check(model.Model(), Equals, "my-model")
+	c.Check(model.Gadget(), Equals, "pc")
+
+	// the chain is valid against the store trusted assertions
+	db, err := asserts.OpenDatabase(&asserts.DatabaseConfig{
+		Backstore: asserts.NewMemoryBackstore(),
+		Trusted:   s.StoreSigning.Trusted,
+	})
+	c.Assert(err, IsNil)
+	// the store account-key comes last in the chain but signs the rest
+	c.Check(db.Add(chain[3]), IsNil)
+	for _, a := range chain[:3] {
+		c.Check(db.Add(a), IsNil)
+	}
+}
+
+func (s *seedtestSuite) TestWriteAssertions(c *C) {
+	chain := s.MakeModelAssertionChain("my-brand", "my-model", map[string]interface{}{
+		"architecture": "amd64",
+		"classic":      "true",
+	})
+	s.WriteAssertions("model.asserts", chain...)
+
+	f, err := os.Open(filepath.Join(s.AssertsDir(), "model.asserts"))
+	c.Assert(err, IsNil)
+	defer f.Close()
+	dec := asserts.NewDecoder(f)
+	for _, a := range chain {
+		got, err := dec.Decode()
+		c.Assert(err, IsNil)
+		c.Check(got.Headers(), DeepEquals, a.Headers())
+	}
+}
+
+func (s *seedtestSuite) TestWriteSeedYaml(c *C) {
+	s.WriteSeedYaml(c, &snap.SeedSnap{Name: "core", SnapID: s.AssertedSnapID("core"), File: "core_1.snap"})
+
+	seed, err := snap.ReadSeedYaml(filepath.Join(s.SeedDir, "seed.yaml"))
+	c.Assert(err, IsNil)
+	c.Check(seed.Snaps, DeepEquals, []*snap.SeedSnap{
+		{Name: "core", SnapID: "core-snap-ididididididididididid", File: "core_1.snap"},
+	})
+}
+
+func (s *seedtestSuite) TestMakeAssertedSnap(c *C) {
+	fname, decl, rev := s.MakeAssertedSnap(c, "name: foo\nversion: 1.0", nil, snap.R(3), "canonical")
+	c.Check(fname, Equals, "foo_1.0_all.snap")
+	c.Check(decl.SnapName(), Equals, "foo")
+	c.Check(decl.SnapID(), Equals, s.AssertedSnapID("foo"))
+	c.Check(rev.SnapRevision(), Equals, 3)
+	c.Check(s.AssertedSnap("foo"), Equals, filepath.Join(s.SnapsDir(), fname))
+	c.Check(s.AssertedSnapInfo("foo").SnapID, Equals, s.AssertedSnapID("foo"))
+
+	sha3_384, _, err := asserts.SnapFileSHA3_384(s.AssertedSnap("foo"))
+	c.Assert(err, IsNil)
+	c.Check(rev.SnapSHA3_384(), Equals, sha3_384)
+}
@@ -103,6 +103,8 @@ var (
 	ErrBadModes = errors.New("snap is unusable due to bad permissions")
 	// ErrMissingPaths is returned by ValidateContainer when the container is missing required files or directories
 	ErrMissingPaths = errors.New("snap is unusable due to missing files")
+	// ErrBadHooks is returned by ValidateHooks when the container has files in meta/hooks that are not supported hooks
+	ErrBadHooks = errors.New("snap is unusable due to unsupported hooks")
 )
 
 // ValidateContainer does a minimal sanity check on the container.
@@ -252,6 +254,40 @@ func ValidateContainer(c Container, s *Info, logf func(format string, v ...inter
 	return nil
 }
 
+// ValidateHooks checks that every file in meta/hooks of the container is
+// a hook supported by this version of snapd. It is meant to be used when
+// building a snap: at install time files that are not supported hooks are
+// simply never run, so that snaps shipping hooks added in later versions
+// of snapd remain installable.
+func ValidateHooks(c Container, s *Info, logf func(format string, v ...interface{})) error {
+	hasBadHooks := false
+	err := c.Walk(".", func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if filepath.Dir(path) != "meta/hooks" {
+			return nil
+		}
+		if !IsHookSupported(info.Name()) {
+			logf("in snap %q: %q is not a supported hook", s.InstanceName(), path)
+			hasBadHooks = true
+		}
+		if info.IsDir() {
+			logf("in snap %q: %q should be a regular file (or a symlink) and isn't", s.InstanceName(), path)
+			hasBadHooks = true
+			return filepath.SkipDir
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if hasBadHooks {
+		return ErrBadHooks
+	}
+	return nil
+}
+
 // normPath is a helper for validateContainer. It takes a relative path (e.g. an
 // app's RestartCommand, which might be empty to mean there is no such thing),
 // and cleans it.
@@ -20,6 +20,7 @@
 package snap_test
 
 import (
+	"fmt"
 	"io/ioutil"
 	"os"
 	"path/filepath"
@@ -355,6 +356,60 @@ apps:
 	c.Check(err, IsNil)
 }
 
+func (s *validateSuite) TestValidateHooksOK(c *C) {
+	const yaml = `name: empty-snap
+version: 1
+`
+	d := emptyContainer(c)
+	c.Assert(os.Mkdir(filepath.Join(d.Path(), "meta", "hooks"), 0755), IsNil)
+	c.Assert(ioutil.WriteFile(filepath.Join(d.Path(), "meta", "hooks", "configure"), nil, 0555), IsNil)
+	c.Assert(ioutil.WriteFile(filepath.Join(d.Path(), "meta", "hooks", "connect-plug-foo"), nil, 0555), IsNil)
+
+	info, err := snap.InfoFromSnapYaml([]byte(yaml))
+	c.Assert(err, IsNil)
+
+	err = snap.ValidateHooks(d, info, discard)
+	c.Check(err, IsNil)
+}
+
+func (s *validateSuite) TestValidateHooksUnsupportedHookFails(c *C) {
+	const yaml = `name: empty-snap
+version: 1
+`
+	d := emptyContainer(c)
+	c.Assert(os.Mkdir(filepath.Join(d.Path(), "meta", "hooks"), 0755), IsNil)
+	c.Assert(ioutil.WriteFile(filepath.Join(d.Path(), "meta", "hooks", "configure"), nil, 0555), IsNil)
+	c.Assert(ioutil.WriteFile(filepath.Join(d.Path(), "meta", "hooks", "confgure"), nil, 0555), IsNil)
+
+	info, err := snap.InfoFromSnapYaml([]byte(yaml))
+	c.Assert(err, IsNil)
+
+	var logs []string
+	err = snap.ValidateHooks(d, info, func(format string, v ...interface{}) {
+		logs = append(logs, fmt.Sprintf(format, v...))
+	})
+	c.Check(err, Equals, snap.ErrBadHooks)
+	c.Check(logs, DeepEquals, []string{`in snap "empty-snap": "meta/hooks/confgure" is not a supported hook`})
+
+	// unsupported hooks do not make the snap unusable
+	err = snap.ValidateContainer(d, info, discard)
+	c.Check(err, IsNil)
+}
+
+func (s *validateSuite) TestValidateHooksHookDirFails(c *C) {
+	const yaml = `name: empty-snap
+version: 1
+`
+	d := emptyContainer(c)
+	c.Assert(os.MkdirAll(filepath.Join(d.Path(), "meta", "hooks", "install"), 0755), IsNil)
+
+	info, err := snap.InfoFromSnapYaml([]byte(yaml))
+	c.Assert(err, IsNil)
+
+	err = snap.ValidateHooks(d, info, discard)
+	c.Check(err, Equals, snap.ErrBadHooks)
+}
+
 func (s *validateSuite) TestValidateContainerAppsOK(c *C) {
 	const yaml = `name: empty-snap
 version: 1
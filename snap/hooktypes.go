@@ -29,12 +29,18 @@ var supportedHooks = []*HookType{
 	NewHookType(regexp.MustCompile("^install$")),
 	NewHookType(regexp.MustCompile("^pre-refresh$")),
 	NewHookType(regexp.MustCompile("^post-refresh$")),
+	NewHookType(regexp.MustCompile("^data-migrate$")),
 	NewHookType(regexp.MustCompile("^remove$")),
+	NewHookType(regexp.MustCompile("^pre-restore$")),
+	NewHookType(regexp.MustCompile("^post-restore$")),
 	NewHookType(regexp.MustCompile("^prepare-(?:plug|slot)-[-a-z0-9]+$")),
 	NewHookType(regexp.MustCompile("^unprepare-(?:plug|slot)-[-a-z0-9]+$")),
 	NewHookType(regexp.MustCompile("^connect-(?:plug|slot)-[-a-z0-9]+$")),
 	NewHookType(regexp.MustCompile("^disconnect-(?:plug|slot)-[-a-z0-9]+$")),
 	NewHookType(regexp.MustCompile("^check-health$")),
+	NewHookType(regexp.MustCompile("^task-[a-z0-9](?:-?[a-z0-9])*$")),
+	NewHookType(regexp.MustCompile("^fde$")),
+	NewHookType(regexp.MustCompile("^action-[a-z0-9](?:-?[a-z0-9])*$")),
 }
 
 // HookType represents a pattern of supported hook names.
@@ -442,8 +442,8 @@ func (s *Info) Services() []*AppInfo {
 	return svcs
 }
 
-// ExpandSnapVariables resolves $SNAP, $SNAP_DATA and $SNAP_COMMON inside the
-// snap's mount namespace.
+// ExpandSnapVariables resolves $SNAP, $SNAP_DATA, $SNAP_COMMON and
+// $SNAP_INSTANCE_NAME inside the snap's mount namespace.
 func (s *Info) ExpandSnapVariables(path string) string {
 	return os.Expand(path, func(v string) string {
 		switch v {
@@ -457,6 +457,11 @@ func (s *Info) ExpandSnapVariables(path string) string {
 			return DataDir(s.SnapName(), s.Revision)
 		case "SNAP_COMMON":
 			return CommonDataDir(s.SnapName())
+		case "SNAP_INSTANCE_NAME":
+			// Unlike the directories above, which are remapped for
+			// parallel instances, this keeps the instance key so that
+			// each instance can use its own path.
+			return s.InstanceName()
 		}
 		return ""
 	})
@@ -1161,6 +1161,17 @@ apps:
 	})
 }
 
+func (s *YamlSuite) TestUnmarshalLicenseAgreement(c *C) {
+	info, err := snap.InfoFromSnapYaml([]byte(`name: foo
+version: 1.0
+license-agreement: explicit
+license-version: "2"
+`))
+	c.Assert(err, IsNil)
+	c.Check(info.LicenseAgreement, Equals, "explicit")
+	c.Check(info.LicenseVersion, Equals, "2")
+}
+
 func (s *YamlSuite) TestUnmarshalComplexExample(c *C) {
 	// NOTE: yaml content cannot use tabs, indent the section with spaces.
 	info, err := snap.InfoFromSnapYaml([]byte(`
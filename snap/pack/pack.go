@@ -113,7 +113,11 @@ func loadAndValidate(sourceDir string) (*snap.Info, error) {
 		return nil, fmt.Errorf("cannot validate snap %q: %v", info.InstanceName(), err)
 	}
 
-	if err := snap.ValidateContainer(snapdir.New(sourceDir), info, logger.Noticef); err != nil {
+	container := snapdir.New(sourceDir)
+	if err := snap.ValidateContainer(container, info, logger.Noticef); err != nil {
+		return nil, err
+	}
+	if err := snap.ValidateHooks(container, info, logger.Noticef); err != nil {
 		return nil, err
 	}
 	return info, nil
@@ -140,6 +140,14 @@ apps:
 	c.Assert(err, Equals, snap.ErrMissingPaths)
 }
 
+func (s *packSuite) TestPackUnsupportedHookFails(c *C) {
+	sourceDir := makeExampleSnapSourceDir(c, "{name: hello, version: 0}")
+	c.Assert(os.MkdirAll(filepath.Join(sourceDir, "meta", "hooks"), 0755), IsNil)
+	c.Assert(ioutil.WriteFile(filepath.Join(sourceDir, "meta", "hooks", "confgure"), nil, 0755), IsNil)
+	_, err := pack.Snap(sourceDir, "", "")
+	c.Assert(err, Equals, snap.ErrBadHooks)
+}
+
 func (s *packSuite) TestPackExcludesBackups(c *C) {
 	sourceDir := makeExampleSnapSourceDir(c, "{name: hello, version: 0}")
 	target := c.MkDir()
@@ -43,6 +43,10 @@ type SeedSnap struct {
 	DevMode bool   `yaml:"devmode,omitempty"`
 	Classic bool   `yaml:"classic,omitempty"`
 
+	// CohortKey is the cohort the snap was fetched from, the
+	// installed snap keeps refreshing along with it
+	CohortKey string `yaml:"cohort-key,omitempty"`
+
 	Private bool `yaml:"private,omitempty"`
 
 	Contact string `yaml:"contact,omitempty"`
@@ -50,14 +54,57 @@ type SeedSnap struct {
 	// no assertions are available in the seed for this snap
 	Unasserted bool `yaml:"unasserted,omitempty"`
 
+	// Architecture the snap was picked for, in seeds mixing
+	// architectures (e.g. armhf userspace on arm64), defaults to
+	// the model architecture
+	Architecture string `yaml:"architecture,omitempty"`
+
 	File string `yaml:"file"`
 }
 
+// SeedClassicPackage describes a package that was installed in a classic
+// image at the time its seed was prepared.
+type SeedClassicPackage struct {
+	Name         string `yaml:"name"`
+	Version      string `yaml:"version"`
+	Architecture string `yaml:"architecture,omitempty"`
+}
+
 type Seed struct {
 	Snaps []*SeedSnap `yaml:"snaps"`
+
+	// ClassicPackages is the manifest of the packages of a classic
+	// image, recorded for provenance of the first-boot system.
+	ClassicPackages []*SeedClassicPackage `yaml:"classic-packages,omitempty"`
+
+	// ValidationSets lists the validation-sets the seeded snaps were
+	// resolved against, they are enforced on the first-boot system.
+	ValidationSets []*SeedValidationSet `yaml:"validation-sets,omitempty"`
 }
 
 func ReadSeedYaml(fn string) (*Seed, error) {
+	return readSeedYaml(fn, false)
+}
+
+// ReadSeedYamlStrict reads and validates the given seed.yaml like
+// ReadSeedYaml but also rejects unknown keys, as from a typo, and
+// asserted snaps without a well-formed snap-id.
+func ReadSeedYamlStrict(fn string) (*Seed, error) {
+	return readSeedYaml(fn, true)
+}
+
+// SeedValidationSet references a validation-set assertion in the seed
+// to be enforced on the first-boot system.
+type SeedValidationSet struct {
+	AccountID string `yaml:"account-id"`
+	Name      string `yaml:"name"`
+	Sequence  int    `yaml:"sequence"`
+	// Pinned is whether the system stays at Sequence instead of
+	// tracking the latest sequence of the set.
+	Pinned bool `yaml:"pinned,omitempty"`
+}
+
+func readSeedYaml(fn string, strict bool) (*Seed, error) {
 	errPrefix := "cannot read seed yaml"
 
 	yamlData, err := ioutil.ReadFile(fn)
@@ -65,8 +112,12 @@ func ReadSeedYaml(fn string) (*Seed, error) {
 		return nil, fmt.Errorf("%s: %v", errPrefix, err)
 	}
 
+	unmarshal := yaml.Unmarshal
+	if strict {
+		unmarshal = yaml.UnmarshalStrict
+	}
 	var seed Seed
-	if err := yaml.Unmarshal(yamlData, &seed); err != nil {
+	if err := unmarshal(yamlData, &seed); err != nil {
 		return nil, fmt.Errorf("%s: cannot unmarshal %q: %s", errPrefix, yamlData, err)
 	}
 
@@ -89,6 +140,30 @@ func ReadSeedYaml(fn string) (*Seed, error) {
 		if strings.Contains(sn.File, "/") {
 			return nil, fmt.Errorf("%s: %q must be a filename, not a path", errPrefix, sn.File)
 		}
+		if sn.CohortKey != "" && sn.Unasserted {
+			return nil, fmt.Errorf("%s: unasserted snap %q cannot have a cohort key", errPrefix, sn.Name)
+		}
+		if sn.Architecture == "all" {
+			return nil, fmt.Errorf(`%s: architecture of %q must be a specific one, not "all"`, errPrefix, sn.Name)
+		}
+		if strict {
+			if sn.SnapID == "" && !sn.Unasserted {
+				return nil, fmt.Errorf(`%s: "snap-id" attribute for asserted snap %q cannot be empty`, errPrefix, sn.Name)
+			}
+			if sn.SnapID != "" && !validSnapID.MatchString(sn.SnapID) {
+				return nil, fmt.Errorf("%s: invalid snap-id %q for %q", errPrefix, sn.SnapID, sn.Name)
+			}
+		}
+	}
+	for _, pkg := range seed.ClassicPackages {
+		if pkg == nil || pkg.Name == "" || pkg.Version == "" {
+			return nil, fmt.Errorf("%s: classic packages must have a name and a version", errPrefix)
+		}
+	}
+	for _, vs := range seed.ValidationSets {
+		if vs == nil || vs.AccountID == "" || vs.Name == "" || vs.Sequence < 1 {
+			return nil, fmt.Errorf("%s: validation sets must have an account-id, a name and a sequence", errPrefix)
+		}
 	}
 
 	return &seed, nil
@@ -82,6 +82,39 @@ func (s *seedYamlTestSuite) TestNoPathAllowed(c *C) {
 	c.Assert(err, ErrorMatches, `cannot read seed yaml: "foo/bar.snap" must be a filename, not a path`)
 }
 
+func (s *seedYamlTestSuite) TestCohortKey(c *C) {
+	fn := filepath.Join(c.MkDir(), "seed.yaml")
+	err := ioutil.WriteFile(fn, []byte(`
+snaps:
+ - name: foo
+   snap-id: snapidsnapidsnapid
+   channel: stable
+   cohort-key: COHORT
+   file: foo_1.0_all.snap
+`), 0644)
+	c.Assert(err, IsNil)
+
+	seed, err := snap.ReadSeedYaml(fn)
+	c.Assert(err, IsNil)
+	c.Assert(seed.Snaps, HasLen, 1)
+	c.Check(seed.Snaps[0].CohortKey, Equals, "COHORT")
+}
+
+func (s *seedYamlTestSuite) TestValidateCohortKeyUnasserted(c *C) {
+	fn := filepath.Join(c.MkDir(), "seed.yaml")
+	err := ioutil.WriteFile(fn, []byte(`
+snaps:
+ - name: local
+   unasserted: true
+   cohort-key: COHORT
+   file: local.snap
+`), 0644)
+	c.Assert(err, IsNil)
+
+	_, err = snap.ReadSeedYaml(fn)
+	c.Assert(err, ErrorMatches, `cannot read seed yaml: unasserted snap "local" cannot have a cohort key`)
+}
+
 func (s *seedYamlTestSuite) TestValidateChannelUnhappy(c *C) {
 	fn := filepath.Join(c.MkDir(), "seed.yaml")
 	err := ioutil.WriteFile(fn, []byte(`
@@ -131,3 +164,162 @@ snaps:
 	_, err = snap.ReadSeedYaml(fn)
 	c.Assert(err, ErrorMatches, `cannot read seed yaml: "file" attribute for "foo" cannot be empty`)
 }
+
+func (s *seedYamlTestSuite) TestStrict(c *C) {
+	fn := filepath.Join(c.MkDir(), "seed.yaml")
+	err := ioutil.WriteFile(fn, []byte(`
+snaps:
+ - name: foo
+   snap-id: snapidsnapidsnapidsnapidsnapid12
+   channel: stable
+   file: foo_1.snap
+ - name: local
+   unasserted: true
+   file: local.snap
+`), 0644)
+	c.Assert(err, IsNil)
+
+	seed, err := snap.ReadSeedYamlStrict(fn)
+	c.Assert(err, IsNil)
+	c.Check(seed.Snaps, HasLen, 2)
+}
+
+func (s *seedYamlTestSuite) TestStrictUnhappy(c *C) {
+	for _, t := range []struct {
+		seedYaml string
+		err      string
+	}{
+		{`
+snaps:
+ - name: foo
+   snap-id: snapidsnapidsnapidsnapidsnapid12
+   chanel: stable
+   file: foo_1.snap
+`, `(?s)cannot read seed yaml: cannot unmarshal .*field chanel not found.*`},
+		{`
+snaps:
+ - name: foo
+   file: foo_1.snap
+`, `cannot read seed yaml: "snap-id" attribute for asserted snap "foo" cannot be empty`},
+		{`
+snaps:
+ - name: foo
+   snap-id: snapidsnapidsnapid
+   file: foo_1.snap
+`, `cannot read seed yaml: invalid snap-id "snapidsnapidsnapid" for "foo"`},
+	} {
+		fn := filepath.Join(c.MkDir(), "seed.yaml")
+		err := ioutil.WriteFile(fn, []byte(t.seedYaml), 0644)
+		c.Assert(err, IsNil)
+
+		_, err = snap.ReadSeedYamlStrict(fn)
+		c.Check(err, ErrorMatches, t.err)
+		// the lenient reader accepts them
+		_, err = snap.ReadSeedYaml(fn)
+		c.Check(err, IsNil)
+	}
+}
+
+func (s *seedYamlTestSuite) TestClassicPackages(c *C) {
+	fn := filepath.Join(c.MkDir(), "seed.yaml")
+	err := ioutil.WriteFile(fn, []byte(`
+snaps:
+ - name: foo
+   file: foo_1.0_all.snap
+classic-packages:
+ - name: bash
+   version: 4.4.18-2ubuntu1
+   architecture: amd64
+`), 0644)
+	c.Assert(err, IsNil)
+
+	seed, err := snap.ReadSeedYaml(fn)
+	c.Assert(err, IsNil)
+	c.Check(seed.ClassicPackages, DeepEquals, []*snap.SeedClassicPackage{
+		{Name: "bash", Version: "4.4.18-2ubuntu1", Architecture: "amd64"},
+	})
+}
+
+func (s *seedYamlTestSuite) TestClassicPackagesVersionMissing(c *C) {
+	fn := filepath.Join(c.MkDir(), "seed.yaml")
+	err := ioutil.WriteFile(fn, []byte(`
+classic-packages:
+ - name: bash
+`), 0644)
+	c.Assert(err, IsNil)
+
+	_, err = snap.ReadSeedYaml(fn)
+	c.Assert(err, ErrorMatches, `cannot read seed yaml: classic packages must have a name and a version`)
+}
+
+func (s *seedYamlTestSuite) TestValidationSets(c *C) {
+	fn := filepath.Join(c.MkDir(), "seed.yaml")
+	err := ioutil.WriteFile(fn, []byte(`
+snaps:
+ - name: foo
+   unasserted: true
+   file: foo_1.0_all.snap
+validation-sets:
+ - account-id: acme
+   name: base-set
+   sequence: 3
+   pinned: true
+ - account-id: acme
+   name: other-set
+   sequence: 1
+`), 0644)
+	c.Assert(err, IsNil)
+
+	seed, err := snap.ReadSeedYamlStrict(fn)
+	c.Assert(err, IsNil)
+	c.Check(seed.ValidationSets, DeepEquals, []*snap.SeedValidationSet{
+		{AccountID: "acme", Name: "base-set", Sequence: 3, Pinned: true},
+		{AccountID: "acme", Name: "other-set", Sequence: 1},
+	})
+}
+
+func (s *seedYamlTestSuite) TestValidationSetsSequenceMissing(c *C) {
+	fn := filepath.Join(c.MkDir(), "seed.yaml")
+	err := ioutil.WriteFile(fn, []byte(`
+validation-sets:
+ - account-id: acme
+   name: base-set
+`), 0644)
+	c.Assert(err, IsNil)
+
+	_, err = snap.ReadSeedYaml(fn)
+	c.Assert(err, ErrorMatches, `cannot read seed yaml: validation sets must have an account-id, a name and a sequence`)
+}
+
+func (s *seedYamlTestSuite) TestArchitecture(c *C) {
+	fn := filepath.Join(c.MkDir(), "seed.yaml")
+	err := ioutil.WriteFile(fn, []byte(`
+snaps:
+ - name: foo
+   file: foo_1.0_arm64.snap
+ - name: foo-armhf
+   file: foo-armhf_1.0_armhf.snap
+   architecture: armhf
+`), 0644)
+	c.Assert(err, IsNil)
+
+	seed, err := snap.ReadSeedYaml(fn)
+	c.Assert(err, IsNil)
+	c.Assert(seed.Snaps, HasLen, 2)
+	c.Check(seed.Snaps[0].Architecture, Equals, "")
+	c.Check(seed.Snaps[1].Architecture, Equals, "armhf")
+}
+
+func (s *seedYamlTestSuite) TestArchitectureAll(c *C) {
+	fn := filepath.Join(c.MkDir(), "seed.yaml")
+	err := ioutil.WriteFile(fn, []byte(`
+snaps:
+ - name: foo
+   file: foo_1.0_all.snap
+   architecture: all
+`), 0644)
+	c.Assert(err, IsNil)
+
+	_, err = snap.ReadSeedYaml(fn)
+	c.Assert(err, ErrorMatches, `cannot read seed yaml: architecture of "foo" must be a specific one, not "all"`)
+}
@@ -20,6 +20,7 @@
 package snapenv
 
 import (
+	"bufio"
 	"fmt"
 	"os"
 	"os/user"
@@ -208,3 +209,29 @@ func envFromMap(em map[string]string) []string {
 	}
 	return out
 }
+
+// ConnectionsEnv returns the environment derived by snapd from the
+// interface connections of the given snap instance, e.g. the paths
+// where connected content can be found.
+func ConnectionsEnv(snapName string) ([]string, error) {
+	f, err := os.Open(filepath.Join(dirs.SnapMountPolicyDir, fmt.Sprintf("snap.%s.environment", snapName)))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var env []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if line := scanner.Text(); strings.Contains(line, "=") {
+			env = append(env, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return env, nil
+}
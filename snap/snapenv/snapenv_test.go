@@ -21,8 +21,10 @@ package snapenv
 
 import (
 	"fmt"
+	"io/ioutil"
 	"os"
 	"os/user"
+	"path/filepath"
 	"strings"
 	"testing"
 
@@ -284,3 +286,21 @@ func (s *HTestSuite) TestExecEnvRenameTMPDIRForClassic(c *C) {
 	c.Assert(found, Equals, true)
 	c.Assert(val, Equals, "/var/tmp")
 }
+
+func (s *HTestSuite) TestConnectionsEnv(c *C) {
+	dirs.SetRootDir(c.MkDir())
+	defer dirs.SetRootDir("")
+
+	env, err := ConnectionsEnv("snapname")
+	c.Assert(err, IsNil)
+	c.Check(env, HasLen, 0)
+
+	c.Assert(os.MkdirAll(dirs.SnapMountPolicyDir, 0755), IsNil)
+	envFile := filepath.Join(dirs.SnapMountPolicyDir, "snap.snapname.environment")
+	err = ioutil.WriteFile(envFile, []byte("SNAP_CONTENT_THEMES_PATH=/snap/snapname/42/themes\nbogus\n"), 0644)
+	c.Assert(err, IsNil)
+
+	env, err = ConnectionsEnv("snapname")
+	c.Assert(err, IsNil)
+	c.Check(env, DeepEquals, []string{"SNAP_CONTENT_THEMES_PATH=/snap/snapname/42/themes"})
+}
@@ -157,6 +157,27 @@ func (s *Snap) Unpack(src, dstDir string) error {
 	return nil
 }
 
+// Check verifies the consistency of the squashfs metadata of the snap
+// by listing its content, which reads and decompresses its superblock
+// and all of its inode and directory tables without extracting
+// anything. The data blocks of the files are not read, they are
+// covered by checking the digest of the snap against its assertions.
+func (s *Snap) Check() error {
+	usw := newUnsquashfsStderrWriter()
+	cmd := exec.Command("unsquashfs", "-n", "-l", s.path)
+	cmd.Stderr = usw
+	if err := cmd.Run(); err != nil {
+		if usw.Err() != nil {
+			err = usw.Err()
+		}
+		return fmt.Errorf("cannot check %q: %v", s.path, err)
+	}
+	if usw.Err() != nil {
+		return fmt.Errorf("cannot check %q: %v", s.path, usw.Err())
+	}
+	return nil
+}
+
 // Size returns the size of a squashfs snap.
 func (s *Snap) Size() (size int64, err error) {
 	st, err := os.Stat(s.path)
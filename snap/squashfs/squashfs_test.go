@@ -344,6 +344,35 @@ EOF
 	c.Check(err.Error(), Equals, `cannot extract "*" to "some-output-dir": failed: "Failed to write /tmp/1/modules/4.4.0-112-generic/modules.symbols, skipping", "Write on output file failed because No space left on device", "writer: failed to write data block 0", "Failed to write /tmp/1/modules/4.4.0-112-generic/modules.symbols.bin, skipping", and 15 more`)
 }
 
+func (s *SquashfsTestSuite) TestCheck(c *C) {
+	snap := makeSnap(c, "", "some random data")
+	c.Check(snap.Check(), IsNil)
+}
+
+func (s *SquashfsTestSuite) TestCheckDetectsFailures(c *C) {
+	snap := squashfs.New(filepath.Join(c.MkDir(), "foo.snap"))
+
+	mockUnsquashfs := testutil.MockCommand(c, "unsquashfs", `
+echo "read_block: failed to read block @0x1234" >&2
+`)
+	defer mockUnsquashfs.Restore()
+
+	err := snap.Check()
+	c.Check(err, ErrorMatches, `cannot check ".*/foo.snap": failed: "read_block: failed to read block @0x1234"`)
+	c.Assert(mockUnsquashfs.Calls(), HasLen, 1)
+	c.Check(mockUnsquashfs.Calls()[0], DeepEquals, []string{"unsquashfs", "-n", "-l", snap.Path()})
+}
+
+func (s *SquashfsTestSuite) TestCheckReportsExitStatus(c *C) {
+	snap := squashfs.New(filepath.Join(c.MkDir(), "foo.snap"))
+
+	mockUnsquashfs := testutil.MockCommand(c, "unsquashfs", "exit 1")
+	defer mockUnsquashfs.Restore()
+
+	err := snap.Check()
+	c.Check(err, ErrorMatches, `cannot check ".*/foo.snap": exit status 1`)
+}
+
 func (s *SquashfsTestSuite) TestBuild(c *C) {
 	// please keep TestBuildUsesExcludes in sync with this one so it makes sense.
 	buildDir := c.MkDir()
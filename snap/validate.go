@@ -40,6 +40,8 @@ import (
 // The fixed length of valid snap IDs.
 const validSnapIDLength = 32
 
+var validSnapID = regexp.MustCompile(fmt.Sprintf("^[a-zA-Z0-9]{%d}$", validSnapIDLength))
+
 // ValidateInstanceName checks if a string can be used as a snap instance name.
 func ValidateInstanceName(instanceName string) error {
 	return naming.ValidateInstance(instanceName)
@@ -154,11 +156,63 @@ func ValidateHook(hook *HookInfo) error {
 		if !commandChainContentWhitelist.MatchString(value) {
 			return fmt.Errorf("hook command-chain contains illegal %q (legal: '%s')", value, commandChainContentWhitelist)
 		}
+		if err := validateCommandChainEntry(value); err != nil {
+			return fmt.Errorf("hook command-chain %v", err)
+		}
+	}
+
+	if err := validateEnvironment(&hook.Environment); err != nil {
+		return fmt.Errorf("invalid environment of hook %q: %v", hook.Name, err)
 	}
 
 	return nil
 }
 
+// validEnvName matches the environment variable names that can be
+// set in the "environment" sections of snap.yaml.
+var validEnvName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// validEnvRef matches what the values in the "environment" sections
+// can refer to: variable names, positional parameters like $1 and the
+// special parameters like $$ understood by os.Expand.
+var validEnvRef = regexp.MustCompile(`^([A-Za-z_][A-Za-z0-9_]*|[0-9]+|[*#$@!?-])$`)
+
+// validateEnvironment checks the names of the variables of an
+// "environment" section, and that the values only refer to variables
+// with valid names.
+func validateEnvironment(env *strutil.OrderedMap) error {
+	for _, k := range env.Keys() {
+		if !validEnvName.MatchString(k) {
+			return fmt.Errorf("invalid variable name %q", k)
+		}
+		var invalid string
+		os.Expand(env.Get(k), func(ref string) string {
+			if invalid == "" && !validEnvRef.MatchString(ref) {
+				invalid = ref
+			}
+			return ""
+		})
+		if invalid != "" {
+			return fmt.Errorf("value of %q refers to invalid variable name %q", k, invalid)
+		}
+	}
+	return nil
+}
+
+// validateCommandChainEntry checks that a command-chain entry names a
+// path inside the snap.
+func validateCommandChainEntry(value string) error {
+	if value == "" {
+		return fmt.Errorf("cannot contain empty entries")
+	}
+	for _, elem := range strings.Split(value, "/") {
+		if elem == ".." {
+			return fmt.Errorf("entry %q cannot point outside of the snap", value)
+		}
+	}
+	return nil
+}
+
 // ValidateAlias checks if a string can be used as an alias name.
 func ValidateAlias(alias string) error {
 	return naming.ValidateAlias(alias)
@@ -305,6 +359,20 @@ func Validate(info *Info) error {
 		}
 	}
 
+	switch info.LicenseAgreement {
+	case "", "explicit":
+		// ok
+	default:
+		return fmt.Errorf("invalid license-agreement: %q (only \"explicit\" is supported)", info.LicenseAgreement)
+	}
+	if info.LicenseVersion != "" && info.LicenseAgreement == "" {
+		return fmt.Errorf("cannot specify license-version without license-agreement")
+	}
+
+	if err := validateEnvironment(&info.Environment); err != nil {
+		return fmt.Errorf("invalid environment: %v", err)
+	}
+
 	// validate app entries
 	for _, app := range info.Apps {
 		if err := ValidateApp(app); err != nil {
@@ -621,6 +689,13 @@ func ValidateApp(app *AppInfo) error {
 		if err := validateField("command-chain", value, commandChainContentWhitelist); err != nil {
 			return err
 		}
+		if err := validateCommandChainEntry(value); err != nil {
+			return fmt.Errorf("command-chain %v", err)
+		}
+	}
+
+	if err := validateEnvironment(&app.Environment); err != nil {
+		return fmt.Errorf("invalid environment: %v", err)
 	}
 
 	// Socket activation requires the "network-bind" plug
@@ -673,6 +748,16 @@ func ValidateApp(app *AppInfo) error {
 
 // ValidatePathVariables ensures that given path contains only $SNAP, $SNAP_DATA or $SNAP_COMMON.
 func ValidatePathVariables(path string) error {
+	return validatePathVariables(path, "SNAP", "SNAP_DATA", "SNAP_COMMON")
+}
+
+// validateLayoutPathVariables ensures that the given layout mount point
+// contains only $SNAP, $SNAP_DATA, $SNAP_COMMON or $SNAP_INSTANCE_NAME.
+func validateLayoutPathVariables(path string) error {
+	return validatePathVariables(path, "SNAP", "SNAP_DATA", "SNAP_COMMON", "SNAP_INSTANCE_NAME")
+}
+
+func validatePathVariables(path string, known ...string) error {
 	for path != "" {
 		start := strings.IndexRune(path, '$')
 		if start < 0 {
@@ -686,7 +771,7 @@ func ValidatePathVariables(path string) error {
 			end = len(path)
 		}
 		v := path[:end]
-		if v != "SNAP" && v != "SNAP_DATA" && v != "SNAP_COMMON" {
+		if !strutil.ListContains(known, v) {
 			return fmt.Errorf("reference to unknown variable %q", "$"+v)
 		}
 		path = path[end:]
@@ -818,7 +903,7 @@ func ValidateLayout(layout *Layout, constraints []LayoutConstraint) error {
 		return errors.New("layout cannot use an empty path")
 	}
 
-	if err := ValidatePathVariables(mountPoint); err != nil {
+	if err := validateLayoutPathVariables(mountPoint); err != nil {
 		return fmt.Errorf("layout %q uses invalid mount point: %s", layout.Path, err)
 	}
 	mountPoint = si.ExpandSnapVariables(mountPoint)
@@ -903,16 +988,25 @@ func ValidateLayout(layout *Layout, constraints []LayoutConstraint) error {
 	}
 
 	// When new users and groups are supported those must be added to interfaces/mount/spec.go as well.
-	// For now only "root" is allowed (and default).
+	// For now only "root" (the default) and "daemon" are allowed, the
+	// latter only for tmpfs as bind mounts cannot change ownership.
 
 	switch layout.User {
 	case "root", "":
+	case "daemon":
+		if layout.Type != "tmpfs" {
+			return fmt.Errorf("layout %q cannot use user %q, only tmpfs layouts can change ownership", layout.Path, layout.User)
+		}
 	// TODO: allow declared snap user and group names.
 	default:
 		return fmt.Errorf("layout %q uses invalid user %q", layout.Path, layout.User)
 	}
 	switch layout.Group {
 	case "root", "":
+	case "daemon":
+		if layout.Type != "tmpfs" {
+			return fmt.Errorf("layout %q cannot use group %q, only tmpfs layouts can change ownership", layout.Path, layout.Group)
+		}
 	default:
 		return fmt.Errorf("layout %q uses invalid group %q", layout.Path, layout.Group)
 	}
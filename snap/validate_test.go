@@ -29,6 +29,7 @@ import (
 
 	. "github.com/snapcore/snapd/snap"
 
+	"github.com/snapcore/snapd/strutil"
 	"github.com/snapcore/snapd/testutil"
 )
 
@@ -130,6 +131,32 @@ func (s *ValidateSuite) TestValidateLicense(c *C) {
 	}
 }
 
+func (s *ValidateSuite) TestValidateLicenseAgreement(c *C) {
+	for _, t := range []struct {
+		agreement, version string
+		err                string
+	}{
+		{"", "", ""},
+		{"explicit", "", ""},
+		{"explicit", "2", ""},
+		{"implicit", "", `invalid license-agreement: "implicit" \(only "explicit" is supported\)`},
+		{"", "2", "cannot specify license-version without license-agreement"},
+	} {
+		info := &Info{
+			SuggestedName:    "foo",
+			Version:          "1.0",
+			LicenseAgreement: t.agreement,
+			LicenseVersion:   t.version,
+		}
+		err := Validate(info)
+		if t.err == "" {
+			c.Check(err, IsNil)
+		} else {
+			c.Check(err, ErrorMatches, t.err)
+		}
+	}
+}
+
 func (s *ValidateSuite) TestValidateHook(c *C) {
 	validHooks := []*HookInfo{
 		{Name: "a"},
@@ -167,6 +194,23 @@ func (s *ValidateSuite) TestValidateHook(c *C) {
 		err := ValidateHook(hook)
 		c.Assert(err, ErrorMatches, `hook command-chain contains illegal.*`)
 	}
+	invalidHooks = []*HookInfo{
+		{Name: "valid", CommandChain: []string{""}},
+		{Name: "valid", CommandChain: []string{"../outside"}},
+		{Name: "valid", CommandChain: []string{"bin/../../outside"}},
+	}
+	for _, hook := range invalidHooks {
+		err := ValidateHook(hook)
+		c.Assert(err, ErrorMatches, `hook command-chain (cannot contain empty entries|entry ".*" cannot point outside of the snap)`)
+	}
+}
+
+func (s *ValidateSuite) TestValidateHookEnvironment(c *C) {
+	hook := &HookInfo{Name: "configure", Environment: *strutil.NewOrderedMap("FOO", "$SNAP/bar")}
+	c.Check(ValidateHook(hook), IsNil)
+
+	hook = &HookInfo{Name: "configure", Environment: *strutil.NewOrderedMap("FOO-BAR", "baz")}
+	c.Check(ValidateHook(hook), ErrorMatches, `invalid environment of hook "configure": invalid variable name "FOO-BAR"`)
 }
 
 // ValidateApp
@@ -355,6 +399,49 @@ func (s *ValidateSuite) TestAppWhitelistIllegal(c *C) {
 	c.Check(ValidateApp(&AppInfo{Name: "foo", CommandChain: []string{"bar baz"}}), NotNil)
 }
 
+func (s *ValidateSuite) TestAppCommandChainStrict(c *C) {
+	c.Check(ValidateApp(&AppInfo{Name: "foo", CommandChain: []string{"bin/wrapper", "$SNAP/bin/other"}}), IsNil)
+	c.Check(ValidateApp(&AppInfo{Name: "foo", CommandChain: []string{""}}), ErrorMatches, `command-chain cannot contain empty entries`)
+	c.Check(ValidateApp(&AppInfo{Name: "foo", CommandChain: []string{"../wrapper"}}), ErrorMatches, `command-chain entry "../wrapper" cannot point outside of the snap`)
+}
+
+func (s *ValidateSuite) TestAppEnvironment(c *C) {
+	for _, t := range []struct {
+		k, v string
+		err  string
+	}{
+		{"FOO", "bar", ""},
+		{"_FOO_1", "$SNAP/bar:${SNAP_DATA}/baz", ""},
+		{"foo", "$FOO$", ""},
+		{"1FOO", "bar", `invalid environment: invalid variable name "1FOO"`},
+		{"FOO BAR", "bar", `invalid environment: invalid variable name "FOO BAR"`},
+		{"FOO", "${FOO-BAR}", `invalid environment: value of "FOO" refers to invalid variable name "FOO-BAR"`},
+		{"FOO", "$1", ""},
+		{"FOO", "${10}", ""},
+		{"FOO", "pid-$$", ""},
+		{"FOO", "$@", ""},
+		{"FOO", "${1FOO}", `invalid environment: value of "FOO" refers to invalid variable name "1FOO"`},
+	} {
+		app := &AppInfo{Name: "foo", Environment: *strutil.NewOrderedMap(t.k, t.v)}
+		err := ValidateApp(app)
+		if t.err == "" {
+			c.Check(err, IsNil, Commentf("%s=%s", t.k, t.v))
+		} else {
+			c.Check(err, ErrorMatches, t.err, Commentf("%s=%s", t.k, t.v))
+		}
+	}
+}
+
+func (s *ValidateSuite) TestValidateSnapEnvironment(c *C) {
+	info, err := InfoFromSnapYaml([]byte(`name: foo
+version: 1.0
+environment:
+  FOO-BAR: baz
+`))
+	c.Assert(err, IsNil)
+	c.Check(Validate(info), ErrorMatches, `invalid environment: invalid variable name "FOO-BAR"`)
+}
+
 func (s *ValidateSuite) TestAppDaemonValue(c *C) {
 	for _, t := range []struct {
 		daemon string
@@ -693,10 +780,16 @@ func (s *ValidateSuite) TestValidateLayout(c *C) {
 		ErrorMatches, `layout "/foo/bar" uses invalid user "foo"`)
 	c.Check(ValidateLayout(&Layout{Snap: si, Path: "/foo/bar", Type: "tmpfs", Group: "foo"}, nil),
 		ErrorMatches, `layout "/foo/bar" uses invalid group "foo"`)
+	c.Check(ValidateLayout(&Layout{Snap: si, Path: "/foo/bar", Bind: "$SNAP/bar", User: "daemon"}, nil),
+		ErrorMatches, `layout "/foo/bar" cannot use user "daemon", only tmpfs layouts can change ownership`)
+	c.Check(ValidateLayout(&Layout{Snap: si, Path: "/foo/bar", Symlink: "$SNAP/bar", Group: "daemon"}, nil),
+		ErrorMatches, `layout "/foo/bar" cannot use group "daemon", only tmpfs layouts can change ownership`)
 	c.Check(ValidateLayout(&Layout{Snap: si, Path: "/foo", Type: "tmpfs", Mode: 02755}, nil),
 		ErrorMatches, `layout "/foo" uses invalid mode 02755`)
 	c.Check(ValidateLayout(&Layout{Snap: si, Path: "$FOO", Type: "tmpfs"}, nil),
 		ErrorMatches, `layout "\$FOO" uses invalid mount point: reference to unknown variable "\$FOO"`)
+	c.Check(ValidateLayout(&Layout{Snap: si, Path: "/foo", Bind: "$SNAP_INSTANCE_NAME"}, nil),
+		ErrorMatches, `layout "/foo" uses invalid bind mount source "\$SNAP_INSTANCE_NAME": reference to unknown variable "\$SNAP_INSTANCE_NAME"`)
 	c.Check(ValidateLayout(&Layout{Snap: si, Path: "/foo", Bind: "$BAR"}, nil),
 		ErrorMatches, `layout "/foo" uses invalid bind mount source "\$BAR": reference to unknown variable "\$BAR"`)
 	c.Check(ValidateLayout(&Layout{Snap: si, Path: "$SNAP/evil", Bind: "/etc"}, nil),
@@ -748,6 +841,9 @@ func (s *ValidateSuite) TestValidateLayout(c *C) {
 	c.Check(ValidateLayout(&Layout{Snap: si, Path: "/a/b", Type: "tmpfs", User: "root"}, nil), IsNil)
 	c.Check(ValidateLayout(&Layout{Snap: si, Path: "/a/b", Type: "tmpfs", Group: "root"}, nil), IsNil)
 	c.Check(ValidateLayout(&Layout{Snap: si, Path: "/a/b", Type: "tmpfs", Mode: 0655}, nil), IsNil)
+	c.Check(ValidateLayout(&Layout{Snap: si, Path: "/var/lib/foo", Type: "tmpfs", User: "daemon", Group: "daemon", Mode: 0750}, nil), IsNil)
+	c.Check(ValidateLayout(&Layout{Snap: si, Path: "/var/lib/$SNAP_INSTANCE_NAME", Type: "tmpfs"}, nil), IsNil)
+	c.Check(ValidateLayout(&Layout{Snap: si, Path: "/var/lib/$SNAP_INSTANCE_NAME", Bind: "$SNAP_DATA/lib"}, nil), IsNil)
 	c.Check(ValidateLayout(&Layout{Snap: si, Path: "/usr", Symlink: "$SNAP/usr"}, nil), IsNil)
 	c.Check(ValidateLayout(&Layout{Snap: si, Path: "/var", Symlink: "$SNAP_DATA/var"}, nil), IsNil)
 	c.Check(ValidateLayout(&Layout{Snap: si, Path: "/var", Symlink: "$SNAP_COMMON/var"}, nil), IsNil)
@@ -26,10 +26,13 @@ import (
 	"io"
 	"io/ioutil"
 	"net/http"
+	"strings"
+	"time"
 
 	"gopkg.in/macaroon.v1"
 
 	"github.com/snapcore/snapd/httputil"
+	"github.com/snapcore/snapd/overlord/auth"
 )
 
 var (
@@ -99,6 +102,36 @@ func loginCaveatID(m *macaroon.Macaroon) (string, error) {
 	return caveatID, nil
 }
 
+// DischargesExpiry returns the earliest expiry among the Ubuntuone
+// discharge macaroons given, as conveyed by their "expires" first party
+// caveats. ok is false if none of them carries an expiry.
+func DischargesExpiry(discharges []string) (expiry time.Time, ok bool, err error) {
+	prefix := UbuntuoneLocation + "|expires|"
+	for _, d := range discharges {
+		discharge, err := auth.MacaroonDeserialize(d)
+		if err != nil {
+			return time.Time{}, false, err
+		}
+		if discharge.Location() != UbuntuoneLocation {
+			continue
+		}
+		for _, caveat := range discharge.Caveats() {
+			if caveat.Location != "" || !strings.HasPrefix(caveat.Id, prefix) {
+				continue
+			}
+			t, err := time.Parse(time.RFC3339, strings.TrimPrefix(caveat.Id, prefix))
+			if err != nil {
+				return time.Time{}, false, fmt.Errorf("cannot parse discharge macaroon expiry: %v", err)
+			}
+			if !ok || t.Before(expiry) {
+				expiry = t
+				ok = true
+			}
+		}
+	}
+	return expiry, ok, nil
+}
+
 // retryPostRequestDecodeJSON calls retryPostRequest and decodes the response into either success or failure.
 func retryPostRequestDecodeJSON(httpClient *http.Client, endpoint string, headers map[string]string, data []byte, success interface{}, failure interface{}) (resp *http.Response, err error) {
 	return retryPostRequest(httpClient, endpoint, headers, data, func(resp *http.Response) error {
@@ -189,6 +222,9 @@ func requestDischargeMacaroon(httpClient *http.Client, endpoint string, data map
 	case httpStatusCodeClientError(resp.StatusCode):
 		switch msg.Code {
 		case "TWOFACTOR_REQUIRED":
+			if challenge := msg.Extra["webauthn_challenge"]; len(challenge) == 1 {
+				return "", &SecurityKeyRequiredError{Challenge: challenge[0]}
+			}
 			return "", ErrAuthenticationNeeds2fa
 		case "TWOFACTOR_FAILURE":
 			return "", Err2faFailed
@@ -229,6 +265,20 @@ func dischargeAuthCaveat(httpClient *http.Client, caveat, username, password, ot
 	return requestDischargeMacaroon(httpClient, UbuntuoneDischargeAPI, data)
 }
 
+// dischargeAuthCaveatWithSecurityKey returns a macaroon with the store
+// auth caveat discharged, using the response of a security key to the
+// challenge of a previous attempt as second factor.
+func dischargeAuthCaveatWithSecurityKey(httpClient *http.Client, caveat, username, password, response string) (string, error) {
+	data := map[string]string{
+		"email":             username,
+		"password":          password,
+		"caveat_id":         caveat,
+		"webauthn_response": response,
+	}
+
+	return requestDischargeMacaroon(httpClient, UbuntuoneDischargeAPI, data)
+}
+
 // refreshDischargeMacaroon returns a soft-refreshed discharge macaroon.
 func refreshDischargeMacaroon(httpClient *http.Client, discharge string) (string, error) {
 	data := map[string]string{
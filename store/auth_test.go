@@ -20,6 +20,7 @@
 package store_test
 
 import (
+	"encoding/json"
 	"io"
 	"io/ioutil"
 	"net/http"
@@ -30,6 +31,7 @@ import (
 	"gopkg.in/macaroon.v1"
 	"gopkg.in/retry.v1"
 
+	"github.com/snapcore/snapd/overlord/auth"
 	"github.com/snapcore/snapd/store"
 	"github.com/snapcore/snapd/testutil"
 )
@@ -58,6 +60,14 @@ const mockStoreNeeds2fa = `
 }
 `
 
+const mockStoreNeedsSecurityKey = `
+{
+    "message": "2-factor authentication required.",
+    "code": "TWOFACTOR_REQUIRED",
+    "extra": {"webauthn_challenge": "the-challenge"}
+}
+`
+
 const mockStore2faFailedHTTPCode = 403
 const mockStore2faFailedResponse = `
 {
@@ -150,6 +160,39 @@ func (s *authTestSuite) TestDischargeAuthCaveatNeeds2fa(c *C) {
 	c.Assert(discharge, Equals, "")
 }
 
+func (s *authTestSuite) TestDischargeAuthCaveatNeedsSecurityKey(c *C) {
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(mockStoreNeeds2faHTTPCode)
+		io.WriteString(w, mockStoreNeedsSecurityKey)
+	}))
+	defer mockServer.Close()
+	store.UbuntuoneDischargeAPI = mockServer.URL + "/tokens/discharge"
+
+	discharge, err := store.DischargeAuthCaveat(&http.Client{}, "third-party-caveat", "foo@example.com", "passwd", "")
+	c.Assert(err, DeepEquals, &store.SecurityKeyRequiredError{Challenge: "the-challenge"})
+	c.Assert(discharge, Equals, "")
+}
+
+func (s *authTestSuite) TestDischargeAuthCaveatWithSecurityKey(c *C) {
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var data map[string]string
+		c.Assert(json.NewDecoder(r.Body).Decode(&data), IsNil)
+		c.Check(data, DeepEquals, map[string]string{
+			"email":             "guy@example.com",
+			"password":          "passwd",
+			"caveat_id":         "third-party-caveat",
+			"webauthn_response": "the-response",
+		})
+		io.WriteString(w, mockStoreReturnDischarge)
+	}))
+	defer mockServer.Close()
+	store.UbuntuoneDischargeAPI = mockServer.URL + "/tokens/discharge"
+
+	discharge, err := store.DischargeAuthCaveatWithSecurityKey(&http.Client{}, "third-party-caveat", "guy@example.com", "passwd", "the-response")
+	c.Assert(err, IsNil)
+	c.Assert(discharge, Equals, "the-discharge-macaroon-serialized-data")
+}
+
 func (s *authTestSuite) TestDischargeAuthCaveatFails2fa(c *C) {
 	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(mockStore2faFailedHTTPCode)
@@ -433,3 +476,40 @@ func (s *authTestSuite) TestRequestDeviceSessionError(c *C) {
 	c.Assert(n, Equals, 5)
 	c.Assert(macaroon, Equals, "")
 }
+
+func makeTestDischargeExpiring(c *C, location string, expires ...string) string {
+	m, err := macaroon.New([]byte("shared-key"), "third-party-caveat", location)
+	c.Assert(err, IsNil)
+	for _, e := range expires {
+		c.Assert(m.AddFirstPartyCaveat(location+"|expires|"+e), IsNil)
+	}
+	d, err := auth.MacaroonSerialize(m)
+	c.Assert(err, IsNil)
+	return d
+}
+
+func (s *authTestSuite) TestDischargesExpiry(c *C) {
+	d1 := makeTestDischargeExpiring(c, store.UbuntuoneLocation, "2019-12-01T10:00:00Z")
+	d2 := makeTestDischargeExpiring(c, store.UbuntuoneLocation, "2019-11-01T10:00:00Z", "2019-11-20T10:00:00Z")
+	// not from Ubuntuone, ignored
+	d3 := makeTestDischargeExpiring(c, "other-location", "2019-01-01T10:00:00Z")
+
+	expiry, ok, err := store.DischargesExpiry([]string{d1, d2, d3})
+	c.Assert(err, IsNil)
+	c.Check(ok, Equals, true)
+	c.Check(expiry.Equal(time.Date(2019, 11, 1, 10, 0, 0, 0, time.UTC)), Equals, true)
+
+	// no expiry known
+	expiry, ok, err = store.DischargesExpiry([]string{d3, makeTestDischargeExpiring(c, store.UbuntuoneLocation)})
+	c.Assert(err, IsNil)
+	c.Check(ok, Equals, false)
+	c.Check(expiry.IsZero(), Equals, true)
+}
+
+func (s *authTestSuite) TestDischargesExpiryErrors(c *C) {
+	_, _, err := store.DischargesExpiry([]string{"invalid-macaroon"})
+	c.Check(err, NotNil)
+
+	_, _, err = store.DischargesExpiry([]string{makeTestDischargeExpiring(c, store.UbuntuoneLocation, "tomorrow")})
+	c.Check(err, ErrorMatches, "cannot parse discharge macaroon expiry: .*")
+}
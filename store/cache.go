@@ -67,6 +67,9 @@ func (s changesByMtime) Less(i, j int) bool { return s[i].ModTime().Before(s[j].
 type CacheManager struct {
 	cacheDir string
 	maxItems int
+	// maxSize returns the maximum total size in bytes of the
+	// entries owned by the cache, 0 means no limit
+	maxSize func() int64
 }
 
 // NewCacheManager returns a new CacheManager with the given cacheDir
@@ -78,9 +81,12 @@ type CacheManager struct {
 //    return success
 // 3. If not found, download the snap
 // 4. On success, hardlink into $cacheDir/<digest>
-// 5. If cache dir has more than maxItems entries, remove oldest mtimes
-//    until it has maxItems
+// 5. If cache dir has more than maxItems entries, or if they use more
+//    than the size limit, remove oldest mtimes until it has maxItems
+//    and fits the limit
 //
+// Entries are keyed by their sha3-384 digest so the same content is
+// shared between parallel instances and repeated installs of a snap.
 // The caching part is done here, the downloading happens in the store.go
 // code.
 func NewCacheManager(cacheDir string, maxItems int) *CacheManager {
@@ -90,6 +96,19 @@ func NewCacheManager(cacheDir string, maxItems int) *CacheManager {
 	}
 }
 
+// SetSizeLimit sets the function returning the maximum total size in
+// bytes of the cache, a limit of 0 means the size is unbounded.
+func (cm *CacheManager) SetSizeLimit(maxSize func() int64) {
+	cm.maxSize = maxSize
+}
+
+func (cm *CacheManager) sizeLimit() int64 {
+	if cm.maxSize == nil {
+		return 0
+	}
+	return cm.maxSize()
+}
+
 // GetPath returns the full path of the given content in the cache
 // or empty string
 func (cm *CacheManager) GetPath(cacheKey string) string {
@@ -104,7 +123,7 @@ func (cm *CacheManager) Get(cacheKey, targetPath string) error {
 	if err := os.Link(cm.path(cacheKey), targetPath); err != nil {
 		return err
 	}
-	logger.Debugf("using cache for %s", targetPath)
+	storeLog.Debugf("using cache for %s", targetPath)
 	now := time.Now()
 	return os.Chtimes(targetPath, now, now)
 }
@@ -153,17 +172,21 @@ func (cm *CacheManager) path(cacheKey string) string {
 	return filepath.Join(cm.cacheDir, cacheKey)
 }
 
-// cleanup ensures that only maxItems are stored in the cache
+// cleanup ensures that only maxItems are stored in the cache and
+// that they fit the size limit, least recently used entries are
+// removed first
 func (cm *CacheManager) cleanup() error {
 	fil, err := ioutil.ReadDir(cm.cacheDir)
 	if err != nil {
 		return err
 	}
-	if len(fil) <= cm.maxItems {
+	maxSize := cm.sizeLimit()
+	if len(fil) <= cm.maxItems && maxSize <= 0 {
 		return nil
 	}
 
 	numOwned := 0
+	var sizeOwned int64
 	for _, fi := range fil {
 		n, err := hardLinkCount(fi)
 		if err != nil {
@@ -172,16 +195,19 @@ func (cm *CacheManager) cleanup() error {
 		// Only count the file if it is not referenced elsewhere in the filesystem
 		if n <= 1 {
 			numOwned++
+			sizeOwned += fi.Size()
 		}
 	}
 
-	if numOwned <= cm.maxItems {
+	fits := func() bool {
+		return numOwned <= cm.maxItems && (maxSize <= 0 || sizeOwned <= maxSize)
+	}
+	if fits() {
 		return nil
 	}
 
 	var lastErr error
 	sort.Sort(changesByMtime(fil))
-	deleted := 0
 	for _, fi := range fil {
 		path := cm.path(fi.Name())
 		n, err := hardLinkCount(fi)
@@ -201,8 +227,9 @@ func (cm *CacheManager) cleanup() error {
 			}
 			continue
 		}
-		deleted++
-		if numOwned-deleted <= cm.maxItems {
+		numOwned--
+		sizeOwned -= fi.Size()
+		if fits() {
 			break
 		}
 	}
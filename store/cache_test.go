@@ -156,6 +156,39 @@ func (s *cacheSuite) TestClenaup(c *C) {
 	c.Check(osutil.FileExists(filepath.Join(s.cm.CacheDir(), cacheKeys[len(cacheKeys)-1])), Equals, true)
 }
 
+func (s *cacheSuite) TestCleanupSizeLimit(c *C) {
+	s.cm.SetSizeLimit(func() int64 { return 3 })
+
+	cacheKeys, testFiles := s.makeTestFiles(c, s.maxItems)
+	for _, p := range testFiles {
+		err := os.Remove(p)
+		c.Assert(err, IsNil)
+	}
+	err := s.cm.Cleanup()
+	c.Assert(err, IsNil)
+
+	// each entry is 1 byte, the least recently used ones are removed
+	// until the cache fits the limit
+	c.Check(s.cm.Count(), Equals, 3)
+	c.Check(osutil.FileExists(filepath.Join(s.cm.CacheDir(), cacheKeys[0])), Equals, false)
+	c.Check(osutil.FileExists(filepath.Join(s.cm.CacheDir(), cacheKeys[1])), Equals, false)
+	c.Check(osutil.FileExists(filepath.Join(s.cm.CacheDir(), cacheKeys[2])), Equals, true)
+	c.Check(osutil.FileExists(filepath.Join(s.cm.CacheDir(), cacheKeys[len(cacheKeys)-1])), Equals, true)
+}
+
+func (s *cacheSuite) TestCleanupSizeLimitUnbounded(c *C) {
+	s.cm.SetSizeLimit(func() int64 { return 0 })
+
+	_, testFiles := s.makeTestFiles(c, s.maxItems)
+	for _, p := range testFiles {
+		err := os.Remove(p)
+		c.Assert(err, IsNil)
+	}
+	err := s.cm.Cleanup()
+	c.Assert(err, IsNil)
+	c.Check(s.cm.Count(), Equals, s.maxItems)
+}
+
 func (s *cacheSuite) TestClenaupContinuesOnError(c *C) {
 	cacheKeys, testFiles := s.makeTestFiles(c, s.maxItems+2)
 	for _, p := range testFiles {
@@ -259,8 +259,10 @@ func infoFromStoreSnap(d *storeSnap) (*snap.Info, error) {
 	}
 	info.CommonIDs = d.CommonIDs
 
-	// fill in the plug/slot data
+	// fill in the plug/slot and license agreement data
 	if rawYamlInfo, err := snap.InfoFromSnapYaml([]byte(d.SnapYAML)); err == nil {
+		info.LicenseAgreement = rawYamlInfo.LicenseAgreement
+		info.LicenseVersion = rawYamlInfo.LicenseVersion
 		if info.Plugs == nil {
 			info.Plugs = make(map[string]*snap.PlugInfo)
 		}
@@ -119,7 +119,7 @@ const (
   },
   "revision": 21,
   "snap-id": "XYZEfjn4WJYnm0FzDKwqqRZZI77awQEV",
-  "snap-yaml": "name: test-snapd-content-plug\nversion: 1.0\napps:\n    content-plug:\n        command: bin/content-plug\n        plugs: [shared-content-plug]\nplugs:\n    shared-content-plug:\n        interface: content\n        target: import\n        content: mylib\n        default-provider: test-snapd-content-slot\nslots:\n    shared-content-slot:\n        interface: content\n        content: mylib\n        read:\n            - /\n",
+  "snap-yaml": "name: test-snapd-content-plug\nversion: 1.0\nlicense-agreement: explicit\nlicense-version: \"2\"\napps:\n    content-plug:\n        command: bin/content-plug\n        plugs: [shared-content-plug]\nplugs:\n    shared-content-plug:\n        interface: content\n        target: import\n        content: mylib\n        default-provider: test-snapd-content-slot\nslots:\n    shared-content-slot:\n        interface: content\n        content: mylib\n        read:\n            - /\n",
   "summary": "useful thingy",
   "title": "This Is The Most Fantastical Snap of Thingy",
   "type": "app",
@@ -215,10 +215,12 @@ func (s *detailsV2Suite) TestInfoFromStoreSnap(c *C) {
 			Read:  []uint32{0, 1},
 			Write: []uint32{1},
 		},
-		SnapType:    snap.TypeApp,
-		Version:     "9.50",
-		Confinement: snap.StrictConfinement,
-		License:     "Proprietary",
+		SnapType:         snap.TypeApp,
+		Version:          "9.50",
+		Confinement:      snap.StrictConfinement,
+		License:          "Proprietary",
+		LicenseAgreement: "explicit",
+		LicenseVersion:   "2",
 		Publisher: snap.StoreAccount{
 			ID:          "ZvtzsxbsHivZLdvzrt0iqW529riGLfXJ",
 			Username:    "thingyinc",
@@ -285,8 +287,6 @@ func (s *detailsV2Suite) TestInfoFromStoreSnap(c *C) {
 		"OriginalSummary",
 		"OriginalDescription",
 		"Environment",
-		"LicenseAgreement", // XXX go away?
-		"LicenseVersion",   // XXX go away?
 		"Apps",
 		"LegacyAliases",
 		"Hooks",
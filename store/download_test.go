@@ -353,6 +353,56 @@ func (s *downloadSuite) TestActualDownloadResume(c *C) {
 	c.Check(n, Equals, 1)
 }
 
+func (s *downloadSuite) TestActualDownloadResumeIfRange(c *C) {
+	n := 0
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n++
+		c.Check(r.Header.Get("Range"), Equals, "bytes=5-")
+		c.Check(r.Header.Get("If-Range"), Equals, `"etag"`)
+		w.Header().Set("ETag", `"etag"`)
+		w.WriteHeader(206)
+		io.WriteString(w, "data")
+	}))
+	c.Assert(mockServer, NotNil)
+	defer mockServer.Close()
+
+	theStore := store.New(&store.Config{}, nil)
+	buf := NewSillyBufferString("some ")
+	h := crypto.SHA3_384.New()
+	h.Write([]byte("some data"))
+	sha3 := fmt.Sprintf("%x", h.Sum(nil))
+	resume := &store.PartialDownload{Offset: 5, ETag: `"etag"`}
+	err := store.Download(context.TODO(), "foo", sha3, mockServer.URL, nil, theStore, buf, 5, nil, &store.DownloadOptions{Resume: resume})
+	c.Check(err, IsNil)
+	c.Check(buf.String(), Equals, "some data")
+	c.Check(n, Equals, 1)
+}
+
+func (s *downloadSuite) TestActualDownloadResumeRemoteChanged(c *C) {
+	n := 0
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n++
+		c.Check(r.Header.Get("If-Range"), Equals, `"old-etag"`)
+		// the file changed, the whole of it is sent
+		w.Header().Set("ETag", `"new-etag"`)
+		io.WriteString(w, "other data")
+	}))
+	c.Assert(mockServer, NotNil)
+	defer mockServer.Close()
+
+	theStore := store.New(&store.Config{}, nil)
+	buf := NewSillyBufferString("some ")
+	h := crypto.SHA3_384.New()
+	h.Write([]byte("other data"))
+	sha3 := fmt.Sprintf("%x", h.Sum(nil))
+	resume := &store.PartialDownload{Offset: 5, ETag: `"old-etag"`}
+	err := store.Download(context.TODO(), "foo", sha3, mockServer.URL, nil, theStore, buf, 5, nil, &store.DownloadOptions{Resume: resume})
+	c.Check(err, IsNil)
+	c.Check(buf.String(), Equals, "other data")
+	c.Check(resume.ETag, Equals, `"new-etag"`)
+	c.Check(n, Equals, 1)
+}
+
 func (s *downloadSuite) TestUseDeltas(c *C) {
 	origPath := os.Getenv("PATH")
 	defer os.Setenv("PATH", origPath)
@@ -492,7 +542,7 @@ func (s *downloadSuite) TestDownloadWithDelta(c *C) {
 			return nil
 		})
 		defer restore()
-		restore = store.MockApplyDelta(func(name string, deltaPath string, deltaInfo *snap.DeltaInfo, targetPath string, targetSha3_384 string) error {
+		restore = store.MockApplyDelta(func(name string, snapPath string, deltaPath string, deltaInfo *snap.DeltaInfo, targetPath string, targetSha3_384 string) error {
 			c.Check(deltaInfo, Equals, &testCase.info.Deltas[0])
 			err := ioutil.WriteFile(targetPath, []byte("snap-content-via-delta"), 0644)
 			c.Assert(err, IsNil)
@@ -510,6 +560,38 @@ func (s *downloadSuite) TestDownloadWithDelta(c *C) {
 	}
 }
 
+func (s *downloadSuite) TestDownloadWithDeltaSource(c *C) {
+	origUseDeltas := os.Getenv("SNAPD_USE_DELTAS_EXPERIMENTAL")
+	defer os.Setenv("SNAPD_USE_DELTAS_EXPERIMENTAL", origUseDeltas)
+	c.Assert(os.Setenv("SNAPD_USE_DELTAS_EXPERIMENTAL", "1"), IsNil)
+
+	info := snap.DownloadInfo{
+		AnonDownloadURL: "full-snap-url",
+		Deltas: []snap.DeltaInfo{
+			{AnonDownloadURL: "delta-url", Format: "xdelta3", FromRevision: 24, ToRevision: 26},
+		},
+	}
+	restore := store.MockDownload(func(ctx context.Context, name, sha3, url string, user *auth.UserState, s *store.Store, w io.ReadWriteSeeker, resume int64, pbar progress.Meter, dlOpts *store.DownloadOptions) error {
+		c.Check(url, Equals, "delta-url")
+		w.Write([]byte("delta-content"))
+		return nil
+	})
+	defer restore()
+	var appliedTo string
+	restore = store.MockApplyDelta(func(name string, snapPath string, deltaPath string, deltaInfo *snap.DeltaInfo, targetPath string, targetSha3_384 string) error {
+		appliedTo = snapPath
+		return ioutil.WriteFile(targetPath, []byte("snap-content-via-delta"), 0644)
+	})
+	defer restore()
+
+	theStore := store.New(&store.Config{}, nil)
+	path := filepath.Join(c.MkDir(), "downloaded-file")
+	err := theStore.Download(context.TODO(), "foo", path, &info, nil, nil, &store.DownloadOptions{DeltaSource: "/previous/foo_24.snap"})
+	c.Assert(err, IsNil)
+	c.Check(appliedTo, Equals, "/previous/foo_24.snap")
+	c.Check(path, testutil.FileEquals, "snap-content-via-delta")
+}
+
 func (s *downloadSuite) TestActualDownloadRateLimited(c *C) {
 	var ratelimitReaderUsed bool
 	restore := store.MockRatelimitReader(func(r io.Reader, bucket *ratelimit.Bucket) io.Reader {
@@ -88,6 +88,18 @@ func (e *DownloadError) Error() string {
 	return fmt.Sprintf("received an unexpected http response code (%v) when trying to download %s", e.Code, e.URL)
 }
 
+// SecurityKeyRequiredError is returned if the authentication needs to
+// be completed with a FIDO2/U2F security key.
+type SecurityKeyRequiredError struct {
+	// Challenge is the opaque security key challenge as sent by the
+	// SSO, it needs to be signed by the security key.
+	Challenge string
+}
+
+func (e *SecurityKeyRequiredError) Error() string {
+	return "two factor authentication with a security key required"
+}
+
 // PasswordPolicyError is returned in a few corner cases, most notably
 // when the password has been force-reset.
 type PasswordPolicyError map[string]stringList
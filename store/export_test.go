@@ -49,12 +49,13 @@ var (
 	StoreDeveloperURL = storeDeveloperURL
 	MustBuy           = mustBuy
 
-	RequestStoreMacaroon     = requestStoreMacaroon
-	DischargeAuthCaveat      = dischargeAuthCaveat
-	RefreshDischargeMacaroon = refreshDischargeMacaroon
-	RequestStoreDeviceNonce  = requestStoreDeviceNonce
-	RequestDeviceSession     = requestDeviceSession
-	LoginCaveatID            = loginCaveatID
+	RequestStoreMacaroon               = requestStoreMacaroon
+	DischargeAuthCaveat                = dischargeAuthCaveat
+	DischargeAuthCaveatWithSecurityKey = dischargeAuthCaveatWithSecurityKey
+	RefreshDischargeMacaroon           = refreshDischargeMacaroon
+	RequestStoreDeviceNonce            = requestStoreDeviceNonce
+	RequestDeviceSession               = requestDeviceSession
+	LoginCaveatID                      = loginCaveatID
 
 	JsonContentType  = jsonContentType
 	SnapActionFields = snapActionFields
@@ -121,7 +122,7 @@ func MockDoDownloadReq(f func(ctx context.Context, storeURL *url.URL, cdnHeader
 	}
 }
 
-func MockApplyDelta(f func(name string, deltaPath string, deltaInfo *snap.DeltaInfo, targetPath string, targetSha3_384 string) error) (restore func()) {
+func MockApplyDelta(f func(name string, snapPath string, deltaPath string, deltaInfo *snap.DeltaInfo, targetPath string, targetSha3_384 string) error) (restore func()) {
 	origApplyDelta := applyDelta
 	applyDelta = f
 	return func() {
@@ -24,6 +24,7 @@ import (
 	"bytes"
 	"context"
 	"crypto"
+	"crypto/tls"
 	"encoding/base64"
 	"encoding/json"
 	"errors"
@@ -73,6 +74,10 @@ const (
 	UbuntuCoreWireProtocol = "1"
 )
 
+// storeLog records the debug messages of the store, which can be
+// enabled with the "store" subsystem.
+var storeLog = logger.Subsystem("store")
+
 type RefreshOptions struct {
 	// RefreshManaged indicates to the store that the refresh is
 	// managed via snapd-control.
@@ -124,9 +129,15 @@ type Config struct {
 
 	// CacheDownloads is the number of downloads that should be cached
 	CacheDownloads int
+	// CacheSize returns the maximum total size in bytes of the
+	// cached downloads, 0 means no limit beyond CacheDownloads
+	CacheSize func() int64
 
 	// Proxy returns the HTTP proxy to use when talking to the store
 	Proxy func(*http.Request) (*url.URL, error)
+
+	// TLS holds additional TLS trust and credentials for the store
+	TLS *TLSOptions
 }
 
 // setBaseURL updates the store API's base URL in the Config. Must not be used
@@ -173,6 +184,9 @@ type Store struct {
 
 	cacher downloadCache
 	proxy  func(*http.Request) (*url.URL, error)
+	// tlsConfig is used for all the store requests, nil means
+	// the default configuration
+	tlsConfig *tls.Config
 }
 
 func respToError(resp *http.Response, msg string) error {
@@ -355,6 +369,15 @@ func New(cfg *Config, dauthCtx DeviceAndAuthContext) *Store {
 		deltaFormat = defaultSupportedDeltaFormat
 	}
 
+	var tlsConfig *tls.Config
+	if cfg.TLS != nil {
+		var err error
+		tlsConfig, err = cfg.TLS.tlsConfig()
+		if err != nil {
+			logger.Noticef("cannot use the store TLS options: %v", err)
+		}
+	}
+
 	store := &Store{
 		cfg:             cfg,
 		series:          series,
@@ -366,9 +389,11 @@ func New(cfg *Config, dauthCtx DeviceAndAuthContext) *Store {
 		dauthCtx:        dauthCtx,
 		deltaFormat:     deltaFormat,
 		proxy:           cfg.Proxy,
+		tlsConfig:       tlsConfig,
 
 		client: httputil.NewHTTPClient(&httputil.ClientOptions{
 			Timeout:    10 * time.Second,
+			TLSConfig:  tlsConfig,
 			MayLogBody: true,
 			Proxy:      cfg.Proxy,
 		}),
@@ -417,7 +442,7 @@ func (s *Store) baseURL(defaultURL *url.URL) *url.URL {
 		var err error
 		_, u, err = s.dauthCtx.ProxyStoreParams(defaultURL)
 		if err != nil {
-			logger.Debugf("cannot get proxy store parameters from state: %v", err)
+			storeLog.Debugf("cannot get proxy store parameters from state: %v", err)
 		}
 	}
 	if u != nil {
@@ -441,6 +466,21 @@ func (s *Store) assertionsEndpointURL(p string, query url.Values) *url.URL {
 
 // LoginUser logs user in the store and returns the authentication macaroons.
 func (s *Store) LoginUser(username, password, otp string) (string, string, error) {
+	return s.loginUser(func(loginCaveat string) (string, error) {
+		return dischargeAuthCaveat(s.client, loginCaveat, username, password, otp)
+	})
+}
+
+// LoginUserWithSecurityKey logs user in the store answering the
+// challenge of a SecurityKeyRequiredError with the given security key
+// response, and returns the authentication macaroons.
+func (s *Store) LoginUserWithSecurityKey(username, password, response string) (string, string, error) {
+	return s.loginUser(func(loginCaveat string) (string, error) {
+		return dischargeAuthCaveatWithSecurityKey(s.client, loginCaveat, username, password, response)
+	})
+}
+
+func (s *Store) loginUser(discharge func(loginCaveat string) (string, error)) (string, string, error) {
 	macaroon, err := requestStoreMacaroon(s.client)
 	if err != nil {
 		return "", "", err
@@ -456,12 +496,12 @@ func (s *Store) LoginUser(username, password, otp string) (string, string, error
 		return "", "", err
 	}
 
-	discharge, err := dischargeAuthCaveat(s.client, loginCaveat, username, password, otp)
+	dischargeMacaroon, err := discharge(loginCaveat)
 	if err != nil {
 		return "", "", err
 	}
 
-	return macaroon, discharge, nil
+	return macaroon, dischargeMacaroon, nil
 }
 
 // authAvailable returns true if there is a user and/or device session setup
@@ -489,7 +529,7 @@ func authenticateUser(r *http.Request, user *auth.UserState) {
 	// deserialize root macaroon (we need its signature to do the discharge binding)
 	root, err := auth.MacaroonDeserialize(user.StoreMacaroon)
 	if err != nil {
-		logger.Debugf("cannot deserialize root macaroon: %v", err)
+		storeLog.Debugf("cannot deserialize root macaroon: %v", err)
 		return
 	}
 
@@ -497,14 +537,14 @@ func authenticateUser(r *http.Request, user *auth.UserState) {
 		// prepare discharge for request
 		discharge, err := auth.MacaroonDeserialize(d)
 		if err != nil {
-			logger.Debugf("cannot deserialize discharge macaroon: %v", err)
+			storeLog.Debugf("cannot deserialize discharge macaroon: %v", err)
 			return
 		}
 		discharge.Bind(root.Signature())
 
 		serializedDischarge, err := auth.MacaroonSerialize(discharge)
 		if err != nil {
-			logger.Debugf("cannot re-serialize discharge macaroon: %v", err)
+			storeLog.Debugf("cannot re-serialize discharge macaroon: %v", err)
 			return
 		}
 		fmt.Fprintf(&buf, `, discharge="%s"`, serializedDischarge)
@@ -554,6 +594,13 @@ func (s *Store) refreshUser(user *auth.UserState) error {
 	return nil
 }
 
+// RefreshUserAuth soft-refreshes the store discharge macaroons of the
+// user, e.g. ahead of their expiry, updating them in place and in the
+// state.
+func (s *Store) RefreshUserAuth(user *auth.UserState) error {
+	return s.refreshUser(user)
+}
+
 // refreshDeviceSession will set or refresh the device session in the state
 func (s *Store) refreshDeviceSession(device *auth.DeviceState) error {
 	if s.dauthCtx == nil {
@@ -642,7 +689,7 @@ func (s *Store) setStoreID(r *http.Request, apiLevel apiLevel) (customStore bool
 	if s.dauthCtx != nil {
 		cand, err := s.dauthCtx.StoreID(storeID)
 		if err != nil {
-			logger.Debugf("cannot get store ID from state: %v", err)
+			storeLog.Debugf("cannot get store ID from state: %v", err)
 		} else {
 			storeID = cand
 		}
@@ -907,7 +954,7 @@ func (s *Store) newRequest(ctx context.Context, reqOptions *requestOptions, user
 		}
 		if err == ErrNoSerial {
 			// missing serial assertion, log and continue without device authentication
-			logger.Debugf("cannot set device session: %v", err)
+			storeLog.Debugf("cannot set device session: %v", err)
 		} else {
 			authenticateDevice(req, device, reqOptions.APILevel)
 		}
@@ -1288,6 +1335,7 @@ func (s *Store) WriteCatalogs(ctx context.Context, names io.Writer, adder SnapAd
 	client := httputil.NewHTTPClient(&httputil.ClientOptions{
 		MayLogBody: false,
 		Timeout:    10 * time.Second,
+		TLSConfig:  s.tlsConfig,
 		Proxy:      s.proxy,
 	})
 	doRequest := func() (*http.Response, error) {
@@ -1327,9 +1375,34 @@ func (e HashError) Error() string {
 	return fmt.Sprintf("sha3-384 mismatch for %q: got %s but expected %s", e.name, e.sha3_384, e.targetSha3_384)
 }
 
+// PartialDownload holds the progress of an interrupted download, so
+// that it can be resumed later, possibly after a restart of snapd.
+type PartialDownload struct {
+	// Offset is the size of the already downloaded data.
+	Offset int64 `json:"offset"`
+	// ETag is the entity tag of the remote file as reported by the
+	// store, used to make sure it did not change meanwhile.
+	ETag string `json:"etag,omitempty"`
+}
+
 type DownloadOptions struct {
 	RateLimit     int64
 	IsAutoRefresh bool
+	// Resume, if set, makes the download resumable: a partial
+	// download is kept around when the download gets cancelled and
+	// Resume is updated with its progress, so that the caller can
+	// persist it and pass it again to continue where it left off.
+	// Without it a cancelled download is removed.
+	Resume *PartialDownload
+	// LeavePartialOnError makes the download keep the partial
+	// download around when it fails for any reason other than a
+	// hash mismatch, so that downloading again to the same target
+	// resumes from it.
+	LeavePartialOnError bool
+	// DeltaSource, if set, is the path of the snap revision that a
+	// delta offered by the store is applied to, instead of the
+	// installed revision in the snap blob directory.
+	DeltaSource string
 }
 
 // Download downloads the snap addressed by download info and returns its
@@ -1342,15 +1415,15 @@ func (s *Store) Download(ctx context.Context, name string, targetPath string, do
 	}
 
 	if err := s.cacher.Get(downloadInfo.Sha3_384, targetPath); err == nil {
-		logger.Debugf("Cache hit for SHA3_384 …%.5s.", downloadInfo.Sha3_384)
+		storeLog.Debugf("Cache hit for SHA3_384 …%.5s.", downloadInfo.Sha3_384)
 		return nil
 	}
 
 	if useDeltas() {
-		logger.Debugf("Available deltas returned by store: %v", downloadInfo.Deltas)
+		storeLog.Debugf("Available deltas returned by store: %v", downloadInfo.Deltas)
 
 		if len(downloadInfo.Deltas) == 1 {
-			err := s.downloadAndApplyDelta(name, targetPath, downloadInfo, pbar, user)
+			err := s.downloadAndApplyDelta(name, targetPath, downloadInfo, pbar, user, dlOpts)
 			if err == nil {
 				return nil
 			}
@@ -1368,18 +1441,54 @@ func (s *Store) Download(ctx context.Context, name string, targetPath string, do
 	if err != nil {
 		return err
 	}
+	var partial *PartialDownload
+	if dlOpts != nil {
+		partial = dlOpts.Resume
+	}
 	defer func() {
+		keep := err != nil && partial != nil && cancelled(ctx)
+		if keep {
+			// remember how far we got so that the download can
+			// be resumed
+			if offset, serr := w.Seek(0, os.SEEK_END); serr == nil {
+				partial.Offset = offset
+			} else {
+				keep = false
+			}
+		}
+		if _, hashErr := err.(HashError); err != nil && !hashErr && dlOpts != nil && dlOpts.LeavePartialOnError {
+			keep = true
+		}
 		if cerr := w.Close(); cerr != nil && err == nil {
 			err = cerr
 		}
-		if err != nil {
+		if err != nil && !keep {
 			os.Remove(w.Name())
 		}
 	}()
+	if partial != nil {
+		// only what was recorded as downloaded is resumed from,
+		// the partial file is not trusted beyond it
+		if partial.Offset > resume {
+			partial.Offset = 0
+		}
+		if resume != partial.Offset {
+			if err = w.Truncate(partial.Offset); err != nil {
+				return err
+			}
+			if resume, err = w.Seek(partial.Offset, os.SEEK_SET); err != nil {
+				return err
+			}
+		}
+		if resume == 0 {
+			// nothing to resume from, a previous entity tag is stale
+			partial.ETag = ""
+		}
+	}
 	if resume > 0 {
-		logger.Debugf("Resuming download of %q at %d.", partialPath, resume)
+		storeLog.Debugf("Resuming download of %q at %d.", partialPath, resume)
 	} else {
-		logger.Debugf("Starting download of %q.", partialPath)
+		storeLog.Debugf("Starting download of %q.", partialPath)
 	}
 
 	authAvail, err := s.authAvailable(user)
@@ -1395,7 +1504,7 @@ func (s *Store) Download(ctx context.Context, name string, targetPath string, do
 	if downloadInfo.Size == 0 || resume < downloadInfo.Size {
 		err = download(ctx, name, downloadInfo.Sha3_384, url, user, s, w, resume, pbar, dlOpts)
 		if err != nil {
-			logger.Debugf("download of %q failed: %#v", url, err)
+			storeLog.Debugf("download of %q failed: %#v", url, err)
 		}
 	} else {
 		// we're done! check the hash though
@@ -1413,8 +1522,8 @@ func (s *Store) Download(ctx context.Context, name string, targetPath string, do
 	}
 	// If hashsum is incorrect retry once
 	if _, ok := err.(HashError); ok {
-		logger.Debugf("Hashsum error on download: %v", err.Error())
-		logger.Debugf("Truncating and trying again from scratch.")
+		storeLog.Debugf("Hashsum error on download: %v", err.Error())
+		storeLog.Debugf("Truncating and trying again from scratch.")
 		err = w.Truncate(0)
 		if err != nil {
 			return err
@@ -1425,7 +1534,7 @@ func (s *Store) Download(ctx context.Context, name string, targetPath string, do
 		}
 		err = download(ctx, name, downloadInfo.Sha3_384, url, user, s, w, 0, pbar, nil)
 		if err != nil {
-			logger.Debugf("download of %q failed: %#v", url, err)
+			storeLog.Debugf("download of %q failed: %#v", url, err)
 		}
 	}
 
@@ -1462,6 +1571,10 @@ func downloadReqOpts(storeURL *url.URL, cdnHeader string, opts *DownloadOptions)
 
 var ratelimitReader = ratelimit.Reader
 
+type truncater interface {
+	Truncate(size int64) error
+}
+
 var download = downloadImpl
 
 // download writes an http.Request showing a progress.Meter
@@ -1490,8 +1603,15 @@ func downloadImpl(ctx context.Context, name, sha3_384, downloadURL string, user
 
 		h := crypto.SHA3_384.New()
 
+		ifRange := false
 		if resume > 0 {
 			reqOptions.ExtraHeaders["Range"] = fmt.Sprintf("bytes=%d-", resume)
+			if dlOpts.Resume != nil && dlOpts.Resume.ETag != "" {
+				// only get the missing bytes if the remote file
+				// is still the same, the whole file otherwise
+				reqOptions.ExtraHeaders["If-Range"] = dlOpts.Resume.ETag
+				ifRange = true
+			}
 			// seed the sha3 with the already local file
 			if _, err := w.Seek(0, os.SEEK_SET); err != nil {
 				return err
@@ -1509,7 +1629,7 @@ func downloadImpl(ctx context.Context, name, sha3_384, downloadURL string, user
 			return fmt.Errorf("The download has been cancelled: %s", ctx.Err())
 		}
 		var resp *http.Response
-		resp, finalErr = s.doRequest(ctx, httputil.NewHTTPClient(&httputil.ClientOptions{Proxy: s.proxy}), reqOptions, user)
+		resp, finalErr = s.doRequest(ctx, httputil.NewHTTPClient(&httputil.ClientOptions{Proxy: s.proxy, TLSConfig: s.tlsConfig}), reqOptions, user)
 
 		if cancelled(ctx) {
 			return fmt.Errorf("The download has been cancelled: %s", ctx.Err())
@@ -1537,6 +1657,24 @@ func downloadImpl(ctx context.Context, name, sha3_384, downloadURL string, user
 			return &DownloadError{Code: resp.StatusCode, URL: resp.Request.URL}
 		}
 
+		if ifRange && resp.StatusCode == 200 {
+			// the remote file changed, start over
+			storeLog.Debugf("Remote file for %q changed, restarting download.", name)
+			if _, err := w.Seek(0, os.SEEK_SET); err != nil {
+				return err
+			}
+			if t, ok := w.(truncater); ok {
+				if err := t.Truncate(0); err != nil {
+					return err
+				}
+			}
+			h.Reset()
+			resume = 0
+		}
+		if dlOpts.Resume != nil {
+			dlOpts.Resume.ETag = resp.Header.Get("ETag")
+		}
+
 		if pbar == nil {
 			pbar = progress.Null
 		}
@@ -1586,7 +1724,7 @@ func downloadImpl(ctx context.Context, name, sha3_384, downloadURL string, user
 			r /= 1000
 		}
 
-		logger.Debugf("Download succeeded in %.03fs (%.0f%cB/s).", dt.Seconds(), r, p)
+		storeLog.Debugf("Download succeeded in %.03fs (%.0f%cB/s).", dt.Seconds(), r, p)
 	}
 	return finalErr
 }
@@ -1594,7 +1732,7 @@ func downloadImpl(ctx context.Context, name, sha3_384, downloadURL string, user
 // DownloadStream will copy the snap from the request to the io.Reader
 func (s *Store) DownloadStream(ctx context.Context, name string, downloadInfo *snap.DownloadInfo, user *auth.UserState) (io.ReadCloser, error) {
 	if path := s.cacher.GetPath(downloadInfo.Sha3_384); path != "" {
-		logger.Debugf("Cache hit for SHA3_384 …%.5s.", downloadInfo.Sha3_384)
+		storeLog.Debugf("Cache hit for SHA3_384 …%.5s.", downloadInfo.Sha3_384)
 		file, err := os.OpenFile(path, os.O_RDONLY, 0600)
 		if err != nil {
 			return nil, err
@@ -1633,7 +1771,7 @@ var doDownloadReq = doDowloadReqImpl
 
 func doDowloadReqImpl(ctx context.Context, storeURL *url.URL, cdnHeader string, s *Store, user *auth.UserState) (*http.Response, error) {
 	reqOptions := downloadReqOpts(storeURL, cdnHeader, nil)
-	return s.doRequest(ctx, httputil.NewHTTPClient(&httputil.ClientOptions{Proxy: s.proxy}), reqOptions, user)
+	return s.doRequest(ctx, httputil.NewHTTPClient(&httputil.ClientOptions{Proxy: s.proxy, TLSConfig: s.tlsConfig}), reqOptions, user)
 }
 
 // downloadDelta downloads the delta for the preferred format, returning the path.
@@ -1673,10 +1811,7 @@ func getXdelta3Cmd(args ...string) (*exec.Cmd, error) {
 }
 
 // applyDelta generates a target snap from a previously downloaded snap and a downloaded delta.
-var applyDelta = func(name string, deltaPath string, deltaInfo *snap.DeltaInfo, targetPath string, targetSha3_384 string) error {
-	snapBase := fmt.Sprintf("%s_%d.snap", name, deltaInfo.FromRevision)
-	snapPath := filepath.Join(dirs.SnapBlobDir, snapBase)
-
+var applyDelta = func(name string, snapPath string, deltaPath string, deltaInfo *snap.DeltaInfo, targetPath string, targetSha3_384 string) error {
 	if !osutil.FileExists(snapPath) {
 		return fmt.Errorf("snap %q revision %d not found at %s", name, deltaInfo.FromRevision, snapPath)
 	}
@@ -1724,9 +1859,14 @@ var applyDelta = func(name string, deltaPath string, deltaInfo *snap.DeltaInfo,
 }
 
 // downloadAndApplyDelta downloads and then applies the delta to the current snap.
-func (s *Store) downloadAndApplyDelta(name, targetPath string, downloadInfo *snap.DownloadInfo, pbar progress.Meter, user *auth.UserState) error {
+func (s *Store) downloadAndApplyDelta(name, targetPath string, downloadInfo *snap.DownloadInfo, pbar progress.Meter, user *auth.UserState, dlOpts *DownloadOptions) error {
 	deltaInfo := &downloadInfo.Deltas[0]
 
+	snapPath := filepath.Join(dirs.SnapBlobDir, fmt.Sprintf("%s_%d.snap", name, deltaInfo.FromRevision))
+	if dlOpts != nil && dlOpts.DeltaSource != "" {
+		snapPath = dlOpts.DeltaSource
+	}
+
 	deltaPath := fmt.Sprintf("%s.%s-%d-to-%d.partial", targetPath, deltaInfo.Format, deltaInfo.FromRevision, deltaInfo.ToRevision)
 	deltaName := fmt.Sprintf(i18n.G("%s (delta)"), name)
 
@@ -1746,12 +1886,12 @@ func (s *Store) downloadAndApplyDelta(name, targetPath string, downloadInfo *sna
 		return err
 	}
 
-	logger.Debugf("Successfully downloaded delta for %q at %s", name, deltaPath)
-	if err := applyDelta(name, deltaPath, deltaInfo, targetPath, downloadInfo.Sha3_384); err != nil {
+	storeLog.Debugf("Successfully downloaded delta for %q at %s", name, deltaPath)
+	if err := applyDelta(name, snapPath, deltaPath, deltaInfo, targetPath, downloadInfo.Sha3_384); err != nil {
 		return err
 	}
 
-	logger.Debugf("Successfully applied delta for %q at %s, saving %d bytes.", name, deltaPath, downloadInfo.Size-deltaInfo.Size)
+	storeLog.Debugf("Successfully applied delta for %q at %s, saving %d bytes.", name, deltaPath, downloadInfo.Size-deltaInfo.Size)
 	return nil
 }
 
@@ -1999,7 +2139,9 @@ func (s *Store) CacheDownloads() int {
 func (s *Store) SetCacheDownloads(fileCount int) {
 	s.cfg.CacheDownloads = fileCount
 	if fileCount > 0 {
-		s.cacher = NewCacheManager(dirs.SnapDownloadCacheDir, fileCount)
+		cm := NewCacheManager(dirs.SnapDownloadCacheDir, fileCount)
+		cm.SetSizeLimit(s.cfg.CacheSize)
+		s.cacher = cm
 	} else {
 		s.cacher = &nullCache{}
 	}
@@ -2316,12 +2458,12 @@ func (s *Store) snapAction(ctx context.Context, currentSnaps []*CurrentSnap, act
 	}
 
 	if opts.IsAutoRefresh {
-		logger.Debugf("Auto-refresh; adding header Snap-Refresh-Reason: scheduled")
+		storeLog.Debugf("Auto-refresh; adding header Snap-Refresh-Reason: scheduled")
 		reqOptions.addHeader("Snap-Refresh-Reason", "scheduled")
 	}
 
 	if useDeltas() {
-		logger.Debugf("Deltas enabled. Adding header Snap-Accept-Delta-Format: %v", s.deltaFormat)
+		storeLog.Debugf("Deltas enabled. Adding header Snap-Accept-Delta-Format: %v", s.deltaFormat)
 		reqOptions.addHeader("Snap-Accept-Delta-Format", s.deltaFormat)
 	}
 	if opts.RefreshManaged {
@@ -2364,7 +2506,7 @@ func (s *Store) snapAction(ctx context.Context, currentSnaps []*CurrentSnap, act
 					if a == nil {
 						// got an error for a snap that was not part of an 'action'
 						otherErrors = append(otherErrors, translateSnapActionError("", "", res.Error.Code, fmt.Sprintf("snap %q: %s", cur.InstanceName, res.Error.Message), nil))
-						logger.Debugf("Unexpected error for snap %q, instance key %v: [%v] %v", cur.InstanceName, res.InstanceKey, res.Error.Code, res.Error.Message)
+						storeLog.Debugf("Unexpected error for snap %q, instance key %v: [%v] %v", cur.InstanceName, res.InstanceKey, res.Error.Code, res.Error.Message)
 						continue
 					}
 					channel := a.Channel
@@ -530,6 +530,140 @@ func (s *storeTestSuite) TestDownloadRangeRequest(c *C) {
 	c.Assert(targetFn, testutil.FileEquals, expectedContentStr)
 }
 
+func (s *storeTestSuite) TestDownloadCancelledKeepsPartialForResume(c *C) {
+	ctx, cancel := context.WithCancel(s.ctx)
+	restore := store.MockDownload(func(ctx context.Context, name, sha3, url string, user *auth.UserState, s *store.Store, w io.ReadWriteSeeker, resume int64, pbar progress.Meter, dlOpts *store.DownloadOptions) error {
+		w.Write([]byte("partial"))
+		dlOpts.Resume.ETag = "etag"
+		cancel()
+		return fmt.Errorf("The download has been cancelled: %s", ctx.Err())
+	})
+	defer restore()
+
+	snap := &snap.Info{}
+	snap.RealName = "foo"
+	snap.AnonDownloadURL = "anon-url"
+	snap.Sha3_384 = "abcdabcd"
+	snap.Size = 100
+
+	targetFn := filepath.Join(c.MkDir(), "foo_1.0_all.snap")
+	var resume store.PartialDownload
+	err := s.store.Download(ctx, "foo", targetFn, &snap.DownloadInfo, nil, nil, &store.DownloadOptions{Resume: &resume})
+	c.Assert(err, ErrorMatches, "The download has been cancelled: context canceled")
+
+	c.Check(targetFn+".partial", testutil.FileEquals, "partial")
+	c.Check(resume, DeepEquals, store.PartialDownload{
+		Offset: int64(len("partial")),
+		ETag:   "etag",
+	})
+}
+
+func (s *storeTestSuite) TestDownloadCancelledNotResumableRemovesPartial(c *C) {
+	ctx, cancel := context.WithCancel(s.ctx)
+	restore := store.MockDownload(func(ctx context.Context, name, sha3, url string, user *auth.UserState, s *store.Store, w io.ReadWriteSeeker, resume int64, pbar progress.Meter, dlOpts *store.DownloadOptions) error {
+		w.Write([]byte("partial"))
+		cancel()
+		return fmt.Errorf("The download has been cancelled: %s", ctx.Err())
+	})
+	defer restore()
+
+	snap := &snap.Info{}
+	snap.RealName = "foo"
+	snap.AnonDownloadURL = "anon-url"
+	snap.Sha3_384 = "abcdabcd"
+	snap.Size = 100
+
+	targetFn := filepath.Join(c.MkDir(), "foo_1.0_all.snap")
+	err := s.store.Download(ctx, "foo", targetFn, &snap.DownloadInfo, nil, nil, nil)
+	c.Assert(err, ErrorMatches, "The download has been cancelled: context canceled")
+
+	c.Check(osutil.FileExists(targetFn+".partial"), Equals, false)
+}
+
+func (s *storeTestSuite) TestDownloadResumesFromRecordedOffset(c *C) {
+	for _, t := range []struct {
+		partial string
+		resume  store.PartialDownload
+		offset  int64
+		etag    string
+	}{
+		// data past the recorded offset is not trusted
+		{"partial-garbage", store.PartialDownload{Offset: 7, ETag: "etag"}, 7, "etag"},
+		{"partial", store.PartialDownload{Offset: 7, ETag: "etag"}, 7, "etag"},
+		// the partial file is not the recorded one
+		{"part", store.PartialDownload{Offset: 7, ETag: "etag"}, 0, ""},
+		// nothing was recorded
+		{"partial", store.PartialDownload{}, 0, ""},
+	} {
+		restore := store.MockDownload(func(ctx context.Context, name, sha3, url string, user *auth.UserState, s *store.Store, w io.ReadWriteSeeker, resume int64, pbar progress.Meter, dlOpts *store.DownloadOptions) error {
+			c.Check(resume, Equals, t.offset)
+			c.Check(dlOpts.Resume.ETag, Equals, t.etag)
+			pos, err := w.Seek(0, os.SEEK_CUR)
+			c.Assert(err, IsNil)
+			c.Check(pos, Equals, t.offset)
+			w.Write([]byte("partial data"[pos:]))
+			return nil
+		})
+
+		snap := &snap.Info{}
+		snap.RealName = "foo"
+		snap.AnonDownloadURL = "anon-url"
+		snap.Sha3_384 = "abcdabcd"
+		snap.Size = 100
+
+		targetFn := filepath.Join(c.MkDir(), "foo_1.0_all.snap")
+		c.Assert(ioutil.WriteFile(targetFn+".partial", []byte(t.partial), 0644), IsNil)
+		resume := t.resume
+		err := s.store.Download(s.ctx, "foo", targetFn, &snap.DownloadInfo, nil, nil, &store.DownloadOptions{Resume: &resume})
+		c.Assert(err, IsNil)
+		c.Check(targetFn, testutil.FileEquals, "partial data")
+		restore()
+	}
+}
+
+func (s *storeTestSuite) TestDownloadFailedLeavePartialOnError(c *C) {
+	restore := store.MockDownload(func(ctx context.Context, name, sha3, url string, user *auth.UserState, s *store.Store, w io.ReadWriteSeeker, resume int64, pbar progress.Meter, dlOpts *store.DownloadOptions) error {
+		w.Write([]byte("partial"))
+		return fmt.Errorf("connection reset")
+	})
+	defer restore()
+
+	snap := &snap.Info{}
+	snap.RealName = "foo"
+	snap.AnonDownloadURL = "anon-url"
+	snap.Sha3_384 = "abcdabcd"
+	snap.Size = 100
+
+	targetFn := filepath.Join(c.MkDir(), "foo_1.0_all.snap")
+	err := s.store.Download(s.ctx, "foo", targetFn, &snap.DownloadInfo, nil, nil, &store.DownloadOptions{LeavePartialOnError: true})
+	c.Assert(err, ErrorMatches, "connection reset")
+	c.Check(targetFn+".partial", testutil.FileEquals, "partial")
+
+	// without the option the partial download is removed
+	err = s.store.Download(s.ctx, "foo", targetFn, &snap.DownloadInfo, nil, nil, nil)
+	c.Assert(err, ErrorMatches, "connection reset")
+	c.Check(osutil.FileExists(targetFn+".partial"), Equals, false)
+}
+
+func (s *storeTestSuite) TestDownloadHashErrorRemovesPartialEvenIfLeavingPartialOnError(c *C) {
+	restore := store.MockDownload(func(ctx context.Context, name, sha3, url string, user *auth.UserState, s *store.Store, w io.ReadWriteSeeker, resume int64, pbar progress.Meter, dlOpts *store.DownloadOptions) error {
+		w.Write([]byte("corrupted"))
+		return store.NewHashError("foo", "1234", sha3)
+	})
+	defer restore()
+
+	snap := &snap.Info{}
+	snap.RealName = "foo"
+	snap.AnonDownloadURL = "anon-url"
+	snap.Sha3_384 = "abcdabcd"
+	snap.Size = 100
+
+	targetFn := filepath.Join(c.MkDir(), "foo_1.0_all.snap")
+	err := s.store.Download(s.ctx, "foo", targetFn, &snap.DownloadInfo, nil, nil, &store.DownloadOptions{LeavePartialOnError: true})
+	c.Assert(err, ErrorMatches, "sha3-384 mismatch for .*")
+	c.Check(osutil.FileExists(targetFn+".partial"), Equals, false)
+}
+
 func (s *storeTestSuite) TestResumeOfCompleted(c *C) {
 	expectedContentStr := "nothing downloaded"
 
@@ -1078,7 +1212,8 @@ func (s *storeTestSuite) TestApplyDelta(c *C) {
 			c.Assert(err, IsNil)
 		}
 
-		err = store.ApplyDelta(name, deltaPath, &testCase.deltaInfo, targetSnapPath, "")
+		snapPath := filepath.Join(dirs.SnapBlobDir, fmt.Sprintf("%s_%d.snap", name, testCase.deltaInfo.FromRevision))
+		err = store.ApplyDelta(name, snapPath, deltaPath, &testCase.deltaInfo, targetSnapPath, "")
 
 		if testCase.error == "" {
 			c.Assert(err, IsNil)
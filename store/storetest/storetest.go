@@ -101,6 +101,14 @@ func (Store) LoginUser(username, password, otp string) (string, string, error) {
 	panic("LoginUser not expected")
 }
 
+func (Store) LoginUserWithSecurityKey(username, password, response string) (string, string, error) {
+	panic("LoginUserWithSecurityKey not expected")
+}
+
+func (Store) RefreshUserAuth(user *auth.UserState) error {
+	panic("RefreshUserAuth not expected")
+}
+
 func (Store) UserInfo(email string) (userinfo *store.User, err error) {
 	panic("UserInfo not expected")
 }
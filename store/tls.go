@@ -0,0 +1,73 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2019 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package store
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+)
+
+// TLSOptions holds the additional TLS trust and credentials to use
+// when talking to the store, e.g. for a brand store behind a private
+// PKI. They only apply to the store transport.
+type TLSOptions struct {
+	// CACerts are PEM encoded CA certificates trusted in addition
+	// to the system ones.
+	CACerts []byte
+
+	// ClientCertFile and ClientKeyFile are the paths to the PEM
+	// encoded client certificate and key presented to the store.
+	ClientCertFile string
+	ClientKeyFile  string
+}
+
+var systemCertPool = x509.SystemCertPool
+
+// tlsConfig builds the TLS configuration described by the options.
+func (opts *TLSOptions) tlsConfig() (*tls.Config, error) {
+	cfg := &tls.Config{}
+	if len(opts.CACerts) > 0 {
+		pool, err := systemCertPool()
+		if err != nil || pool == nil {
+			// be lenient, the extra certificates may be all
+			// that is needed
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(opts.CACerts) {
+			return nil, fmt.Errorf("cannot use store CA certificates: no PEM encoded certificates found")
+		}
+		cfg.RootCAs = pool
+	}
+	if opts.ClientCertFile != "" || opts.ClientKeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(opts.ClientCertFile, opts.ClientKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("cannot use store client certificate: %v", err)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+	return cfg, nil
+}
+
+// Validate checks that the options can be used to talk to the store.
+func (opts *TLSOptions) Validate() error {
+	_, err := opts.tlsConfig()
+	return err
+}
@@ -0,0 +1,134 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2019 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package store_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"time"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/store"
+)
+
+type tlsSuite struct{}
+
+var _ = Suite(&tlsSuite{})
+
+func serverCertPEM(srv *httptest.Server) []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})
+}
+
+// writeClientCert writes a self-signed client certificate and its key
+// in the given directory.
+func writeClientCert(c *C, dir string) (certFile, keyFile string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	c.Assert(err, IsNil)
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "my-device"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	c.Assert(err, IsNil)
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	c.Assert(err, IsNil)
+
+	certFile = filepath.Join(dir, "client.pem")
+	keyFile = filepath.Join(dir, "client.key")
+	err = ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0644)
+	c.Assert(err, IsNil)
+	err = ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600)
+	c.Assert(err, IsNil)
+	return certFile, keyFile
+}
+
+func (s *tlsSuite) TestCACerts(c *C) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(200)
+	}))
+	defer srv.Close()
+
+	// the server certificate is not trusted by default
+	sto := store.New(store.DefaultConfig(), nil)
+	_, err := sto.Client().Get(srv.URL)
+	c.Check(err, ErrorMatches, `.*x509: certificate signed by unknown authority`)
+
+	cfg := store.DefaultConfig()
+	cfg.TLS = &store.TLSOptions{CACerts: serverCertPEM(srv)}
+	sto = store.New(cfg, nil)
+	resp, err := sto.Client().Get(srv.URL)
+	c.Assert(err, IsNil)
+	resp.Body.Close()
+	c.Check(resp.StatusCode, Equals, 200)
+}
+
+func (s *tlsSuite) TestClientCert(c *C) {
+	var peerCerts []*x509.Certificate
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		peerCerts = r.TLS.PeerCertificates
+		w.WriteHeader(200)
+	}))
+	srv.TLS = &tls.Config{ClientAuth: tls.RequireAnyClientCert}
+	srv.StartTLS()
+	defer srv.Close()
+
+	certFile, keyFile := writeClientCert(c, c.MkDir())
+	cfg := store.DefaultConfig()
+	cfg.TLS = &store.TLSOptions{
+		CACerts:        serverCertPEM(srv),
+		ClientCertFile: certFile,
+		ClientKeyFile:  keyFile,
+	}
+	sto := store.New(cfg, nil)
+	resp, err := sto.Client().Get(srv.URL)
+	c.Assert(err, IsNil)
+	resp.Body.Close()
+	c.Check(resp.StatusCode, Equals, 200)
+	c.Assert(peerCerts, HasLen, 1)
+	c.Check(peerCerts[0].Subject.CommonName, Equals, "my-device")
+}
+
+func (s *tlsSuite) TestValidate(c *C) {
+	dir := c.MkDir()
+	certFile, keyFile := writeClientCert(c, dir)
+
+	c.Check((&store.TLSOptions{}).Validate(), IsNil)
+	c.Check((&store.TLSOptions{ClientCertFile: certFile, ClientKeyFile: keyFile}).Validate(), IsNil)
+
+	err := (&store.TLSOptions{CACerts: []byte("not a certificate")}).Validate()
+	c.Check(err, ErrorMatches, `cannot use store CA certificates: no PEM encoded certificates found`)
+
+	err = (&store.TLSOptions{ClientCertFile: certFile, ClientKeyFile: filepath.Join(dir, "missing.key")}).Validate()
+	c.Check(err, ErrorMatches, `cannot use store client certificate: open .*/missing.key: no such file or directory`)
+}
@@ -470,11 +470,15 @@ func (s *systemd) IsEnabled(serviceName string) (bool, error) {
 	if err == nil {
 		return true, nil
 	}
-	// "systemctl is-enabled <name>" prints `disabled\n` to stderr and returns exit code 1
-	// for disabled services
+	// "systemctl is-enabled <name>" prints `disabled\n` (or
+	// `masked\n`) to stderr and returns exit code 1 for disabled
+	// (or masked) services
 	sysdErr, ok := err.(*Error)
-	if ok && sysdErr.exitCode == 1 && strings.TrimSpace(string(sysdErr.msg)) == "disabled" {
-		return false, nil
+	if ok && sysdErr.exitCode == 1 {
+		switch strings.TrimSpace(string(sysdErr.msg)) {
+		case "disabled", "masked":
+			return false, nil
+		}
 	}
 	return false, err
 }
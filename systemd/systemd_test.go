@@ -701,6 +701,40 @@ func (s *SystemdTestSuite) TestJctl(c *C) {
 	c.Check(args, DeepEquals, []string{"-o", "json", "--no-pager", "--no-tail", "-u", "foo", "-u", "bar"})
 }
 
+func (s *SystemdTestSuite) TestIsEnabled(c *C) {
+	s.errors = []error{nil}
+
+	enabled, err := New("xyzzy", SystemMode, s.rep).IsEnabled("foo")
+	c.Assert(enabled, Equals, true)
+	c.Assert(err, IsNil)
+	c.Check(s.argses, DeepEquals, [][]string{{"--root", "xyzzy", "is-enabled", "foo"}})
+}
+
+func (s *SystemdTestSuite) TestIsEnabledDisabledOrMasked(c *C) {
+	for _, status := range []string{"disabled", "masked"} {
+		sysErr := &Error{}
+		sysErr.SetExitCode(1)
+		sysErr.SetMsg([]byte(status + "\n"))
+		s.i = 0
+		s.errors = []error{sysErr}
+
+		enabled, err := New("xyzzy", SystemMode, s.rep).IsEnabled("foo")
+		c.Check(enabled, Equals, false, Commentf(status))
+		c.Check(err, IsNil, Commentf(status))
+	}
+}
+
+func (s *SystemdTestSuite) TestIsEnabledErr(c *C) {
+	sysErr := &Error{}
+	sysErr.SetExitCode(1)
+	sysErr.SetMsg([]byte("random-failure\n"))
+	s.errors = []error{sysErr}
+
+	enabled, err := New("xyzzy", SystemMode, s.rep).IsEnabled("foo")
+	c.Assert(enabled, Equals, false)
+	c.Assert(err, ErrorMatches, ".* failed with exit status 1: random-failure\n")
+}
+
 func (s *SystemdTestSuite) TestIsActiveIsInactive(c *C) {
 	sysErr := &Error{}
 	sysErr.SetExitCode(1)
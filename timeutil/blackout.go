@@ -0,0 +1,198 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2018 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package timeutil
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// blackoutDate is a calendar day, year is 0 for days that recur every
+// year.
+type blackoutDate struct {
+	year  int
+	month time.Month
+	day   int
+}
+
+func (d blackoutDate) String() string {
+	if d.year == 0 {
+		return fmt.Sprintf("%02d-%02d", d.month, d.day)
+	}
+	return fmt.Sprintf("%04d-%02d-%02d", d.year, d.month, d.day)
+}
+
+func (d blackoutDate) before(other blackoutDate) bool {
+	if d.year != other.year {
+		return d.year < other.year
+	}
+	if d.month != other.month {
+		return d.month < other.month
+	}
+	return d.day < other.day
+}
+
+// BlackoutWindow is a span of whole days, both ends included, during
+// which no refreshes should happen. Windows given without a year recur
+// every year and may wrap around the end of the year.
+type BlackoutWindow struct {
+	start, end blackoutDate
+}
+
+func (w *BlackoutWindow) String() string {
+	if w.start == w.end {
+		return w.start.String()
+	}
+	return fmt.Sprintf("%s..%s", w.start, w.end)
+}
+
+// Recurring returns whether the window repeats every year.
+func (w *BlackoutWindow) Recurring() bool {
+	return w.start.year == 0
+}
+
+// Contains returns whether t falls inside the window, using the location
+// of t to determine the day, and if so when the window ends.
+func (w *BlackoutWindow) Contains(t time.Time) (bool, time.Time) {
+	loc := t.Location()
+	if !w.Recurring() {
+		start := time.Date(w.start.year, w.start.month, w.start.day, 0, 0, 0, 0, loc)
+		end := time.Date(w.end.year, w.end.month, w.end.day+1, 0, 0, 0, 0, loc)
+		if !t.Before(start) && t.Before(end) {
+			return true, end
+		}
+		return false, time.Time{}
+	}
+
+	// a recurring window that started last year may still be active
+	for _, year := range []int{t.Year() - 1, t.Year()} {
+		endYear := year
+		if w.end.before(w.start) {
+			endYear++
+		}
+		start := time.Date(year, w.start.month, w.start.day, 0, 0, 0, 0, loc)
+		end := time.Date(endYear, w.end.month, w.end.day+1, 0, 0, 0, 0, loc)
+		if !t.Before(start) && t.Before(end) {
+			return true, end
+		}
+	}
+	return false, time.Time{}
+}
+
+// InBlackout returns whether t falls inside any of the given windows
+// and, if so, the time at which refreshes are allowed again. Windows
+// that overlap or directly follow each other are treated as one.
+func InBlackout(windows []*BlackoutWindow, t time.Time) (bool, time.Time) {
+	var end time.Time
+	for found := true; found; {
+		found = false
+		for _, w := range windows {
+			at := t
+			if !end.IsZero() {
+				at = end
+			}
+			if in, wEnd := w.Contains(at); in && wEnd.After(end) {
+				end = wEnd
+				found = true
+			}
+		}
+	}
+	return !end.IsZero(), end
+}
+
+func parseBlackoutDate(s string) (blackoutDate, error) {
+	var d blackoutDate
+	full := s
+	recurring := strings.Count(s, "-") == 1
+	if recurring {
+		// use a leap year so that 02-29 is accepted
+		full = "2000-" + s
+	}
+	t, err := time.Parse("2006-01-02", full)
+	if err != nil {
+		return d, fmt.Errorf("cannot parse %q: not a valid date", s)
+	}
+	if !recurring {
+		d.year = t.Year()
+	}
+	d.month = t.Month()
+	d.day = t.Day()
+	return d, nil
+}
+
+// ParseBlackoutWindows parses a comma separated list of blackout
+// windows. Each window is either a single day or a range of days
+// separated by "..", with days given as YYYY-MM-DD, or as MM-DD for
+// windows that recur every year, e.g.:
+//
+//	2019-11-29,12-20..01-02
+func ParseBlackoutWindows(s string) ([]*BlackoutWindow, error) {
+	var windows []*BlackoutWindow
+	for _, ws := range strings.Split(s, ",") {
+		ws = strings.TrimSpace(ws)
+		span := strings.Split(ws, "..")
+		if len(span) > 2 {
+			return nil, fmt.Errorf("cannot parse %q: not a valid blackout window", ws)
+		}
+		start, err := parseBlackoutDate(span[0])
+		if err != nil {
+			return nil, err
+		}
+		end := start
+		if len(span) == 2 {
+			end, err = parseBlackoutDate(span[1])
+			if err != nil {
+				return nil, err
+			}
+		}
+		w := &BlackoutWindow{start: start, end: end}
+		if (start.year == 0) != (end.year == 0) {
+			return nil, fmt.Errorf("cannot parse %q: cannot mix recurring and fixed dates", ws)
+		}
+		if !w.Recurring() && end.before(start) {
+			return nil, fmt.Errorf("cannot parse %q: end before start", ws)
+		}
+		windows = append(windows, w)
+	}
+	return windows, nil
+}
+
+// ParseTimezoneOffset parses a timezone offset from UTC, given either as
+// "UTC" or as "+HH:MM" or "-HH:MM", and returns the matching location.
+func ParseTimezoneOffset(s string) (*time.Location, error) {
+	if s == "UTC" {
+		return time.UTC, nil
+	}
+	if len(s) != 6 || (s[0] != '+' && s[0] != '-') || s[3] != ':' {
+		return nil, fmt.Errorf("cannot parse %q: not a valid timezone offset", s)
+	}
+	hours, err1 := strconv.Atoi(s[1:3])
+	minutes, err2 := strconv.Atoi(s[4:6])
+	if err1 != nil || err2 != nil || hours > 14 || minutes > 59 {
+		return nil, fmt.Errorf("cannot parse %q: not a valid timezone offset", s)
+	}
+	offset := (hours*60 + minutes) * 60
+	if s[0] == '-' {
+		offset = -offset
+	}
+	return time.FixedZone(s, offset), nil
+}
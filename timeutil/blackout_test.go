@@ -0,0 +1,139 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2018 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package timeutil_test
+
+import (
+	"time"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/timeutil"
+)
+
+type blackoutSuite struct{}
+
+var _ = Suite(&blackoutSuite{})
+
+func (s *blackoutSuite) TestParseBlackoutWindowsHappy(c *C) {
+	for _, t := range []struct {
+		in        string
+		out       []string
+		recurring []bool
+	}{
+		{"2019-11-29", []string{"2019-11-29"}, []bool{false}},
+		{"2019-11-29..2019-12-02", []string{"2019-11-29..2019-12-02"}, []bool{false}},
+		{"12-24", []string{"12-24"}, []bool{true}},
+		{"02-29", []string{"02-29"}, []bool{true}},
+		{"12-20..01-02", []string{"12-20..01-02"}, []bool{true}},
+		{"2019-11-29, 12-20..01-02", []string{"2019-11-29", "12-20..01-02"}, []bool{false, true}},
+	} {
+		windows, err := timeutil.ParseBlackoutWindows(t.in)
+		c.Assert(err, IsNil, Commentf("%q", t.in))
+		c.Assert(windows, HasLen, len(t.out))
+		for i, w := range windows {
+			c.Check(w.String(), Equals, t.out[i])
+			c.Check(w.Recurring(), Equals, t.recurring[i])
+		}
+	}
+}
+
+func (s *blackoutSuite) TestParseBlackoutWindowsUnhappy(c *C) {
+	for _, t := range []struct {
+		in  string
+		err string
+	}{
+		{"", `cannot parse "": not a valid date`},
+		{"2019-13-01", `cannot parse "2019-13-01": not a valid date`},
+		{"02-30", `cannot parse "02-30": not a valid date`},
+		{"christmas", `cannot parse "christmas": not a valid date`},
+		{"12-24..12-25..12-26", `cannot parse "12-24..12-25..12-26": not a valid blackout window`},
+		{"2019-12-24..12-26", `cannot parse "2019-12-24..12-26": cannot mix recurring and fixed dates`},
+		{"2019-12-24..2019-12-20", `cannot parse "2019-12-24..2019-12-20": end before start`},
+	} {
+		_, err := timeutil.ParseBlackoutWindows(t.in)
+		c.Check(err, ErrorMatches, t.err, Commentf("%q", t.in))
+	}
+}
+
+func (s *blackoutSuite) TestParseTimezoneOffset(c *C) {
+	loc, err := timeutil.ParseTimezoneOffset("UTC")
+	c.Assert(err, IsNil)
+	c.Check(loc, Equals, time.UTC)
+
+	for _, t := range []struct {
+		in     string
+		offset int
+	}{
+		{"+02:00", 2 * 60 * 60},
+		{"-05:30", -(5*60 + 30) * 60},
+		{"+00:00", 0},
+		{"+14:00", 14 * 60 * 60},
+	} {
+		loc, err := timeutil.ParseTimezoneOffset(t.in)
+		c.Assert(err, IsNil)
+		_, offset := time.Date(2019, 1, 1, 0, 0, 0, 0, loc).Zone()
+		c.Check(offset, Equals, t.offset, Commentf("%q", t.in))
+	}
+
+	for _, in := range []string{"", "utc", "02:00", "+2:00", "+15:00", "+02:60", "+0a:00", "+02-00"} {
+		_, err := timeutil.ParseTimezoneOffset(in)
+		c.Check(err, ErrorMatches, `cannot parse ".*": not a valid timezone offset`, Commentf("%q", in))
+	}
+}
+
+func (s *blackoutSuite) TestInBlackout(c *C) {
+	windows, err := timeutil.ParseBlackoutWindows("2019-11-29..2019-11-30,12-20..01-02,01-03")
+	c.Assert(err, IsNil)
+
+	for _, t := range []struct {
+		at  time.Time
+		in  bool
+		end time.Time
+	}{
+		{time.Date(2019, 11, 28, 23, 59, 0, 0, time.UTC), false, time.Time{}},
+		{time.Date(2019, 11, 29, 0, 0, 0, 0, time.UTC), true, time.Date(2019, 12, 1, 0, 0, 0, 0, time.UTC)},
+		{time.Date(2019, 11, 30, 23, 59, 0, 0, time.UTC), true, time.Date(2019, 12, 1, 0, 0, 0, 0, time.UTC)},
+		{time.Date(2020, 11, 29, 12, 0, 0, 0, time.UTC), false, time.Time{}},
+		// recurring window wrapping the end of the year, directly
+		// followed by another one
+		{time.Date(2019, 12, 25, 12, 0, 0, 0, time.UTC), true, time.Date(2020, 1, 4, 0, 0, 0, 0, time.UTC)},
+		{time.Date(2021, 1, 1, 12, 0, 0, 0, time.UTC), true, time.Date(2021, 1, 4, 0, 0, 0, 0, time.UTC)},
+		{time.Date(2021, 1, 4, 0, 0, 0, 0, time.UTC), false, time.Time{}},
+	} {
+		in, end := timeutil.InBlackout(windows, t.at)
+		c.Check(in, Equals, t.in, Commentf("%s", t.at))
+		c.Check(end.Equal(t.end), Equals, true, Commentf("%s: %s", t.at, end))
+	}
+}
+
+func (s *blackoutSuite) TestInBlackoutTimezone(c *C) {
+	windows, err := timeutil.ParseBlackoutWindows("12-24")
+	c.Assert(err, IsNil)
+	loc, err := timeutil.ParseTimezoneOffset("-05:00")
+	c.Assert(err, IsNil)
+
+	// 2am UTC on the 25th is still the 24th five hours west
+	at := time.Date(2019, 12, 25, 2, 0, 0, 0, time.UTC)
+	in, _ := timeutil.InBlackout(windows, at)
+	c.Check(in, Equals, false)
+	in, end := timeutil.InBlackout(windows, at.In(loc))
+	c.Check(in, Equals, true)
+	c.Check(end.Equal(time.Date(2019, 12, 25, 5, 0, 0, 0, time.UTC)), Equals, true)
+}
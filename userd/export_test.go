@@ -21,13 +21,12 @@ package userd
 
 import (
 	"os/user"
+	"time"
 
 	"github.com/godbus/dbus"
 )
 
 var (
-	SnapFromPid = snapFromPid
-
 	LoadAutostartDesktopFile = loadAutostartDesktopFile
 	AutostartCmd             = autostartCmd
 )
@@ -55,3 +54,29 @@ func MockCurrentDesktop(current string) func() {
 		currentDesktop = old
 	}
 }
+
+func MockConnectivityCheckURL(url string) func() {
+	old := connectivityCheckURL
+	connectivityCheckURL = url
+	return func() {
+		connectivityCheckURL = old
+	}
+}
+
+func MockCheckConnectivity(f func() string) func() {
+	old := checkConnectivity
+	checkConnectivity = f
+	return func() {
+		checkConnectivity = old
+	}
+}
+
+func MockTimeNow(f func() time.Time) func() {
+	old := timeNow
+	timeNow = f
+	return func() {
+		timeNow = old
+	}
+}
+
+var CheckConnectivity = checkConnectivity
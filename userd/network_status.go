@@ -0,0 +1,166 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2019 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package userd
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/godbus/dbus"
+
+	"github.com/snapcore/snapd/logger"
+)
+
+const networkStatusIntrospectionXML = `
+<interface name="org.freedesktop.DBus.Peer">
+	<method name='Ping'>
+	</method>
+	<method name='GetMachineId'>
+               <arg type='s' name='machine_uuid' direction='out'/>
+	</method>
+</interface>
+<interface name='io.snapcraft.NetworkStatus'>
+	<method name='GetConnectivity'>
+		<arg type='s' name='state' direction='out'/>
+	</method>
+</interface>`
+
+const (
+	// ConnectivityOnline is reported when the connectivity check URL
+	// answered as expected.
+	ConnectivityOnline = "online"
+	// ConnectivityOffline is reported when the connectivity check URL
+	// could not be reached at all.
+	ConnectivityOffline = "offline"
+	// ConnectivityCaptive is reported when something (typically a
+	// captive portal) answered in place of the connectivity check URL.
+	ConnectivityCaptive = "captive"
+)
+
+var (
+	// connectivityCheckURL must answer with "204 No Content"; a redirect
+	// or a page served instead means the request was intercepted on the
+	// way.
+	connectivityCheckURL = "http://connectivity-check.ubuntu.com/"
+	// connectivityCacheTime is how long a connectivity result is reused
+	// before probing again, so that chatty clients do not turn into
+	// network traffic.
+	connectivityCacheTime = 30 * time.Second
+
+	timeNow = time.Now
+)
+
+// checkConnectivity probes the connectivity check URL once.
+var checkConnectivity = func() string {
+	client := &http.Client{
+		Timeout: 10 * time.Second,
+		// a redirect is the usual sign of a captive portal, do not
+		// follow it
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	resp, err := client.Get(connectivityCheckURL)
+	if err != nil {
+		logger.Debugf("connectivity check failed: %v", err)
+		return ConnectivityOffline
+	}
+	resp.Body.Close()
+	switch {
+	case resp.StatusCode == http.StatusNoContent:
+		return ConnectivityOnline
+	case resp.StatusCode == http.StatusOK, resp.StatusCode >= 300 && resp.StatusCode < 400:
+		logger.Debugf("connectivity check intercepted: %s", resp.Status)
+		return ConnectivityCaptive
+	default:
+		// the check itself failed, nothing tells that the
+		// network is usable
+		logger.Debugf("connectivity check got unexpected status: %s", resp.Status)
+		return ConnectivityOffline
+	}
+}
+
+// NetworkStatus implements the 'io.snapcraft.NetworkStatus' DBus
+// interface, letting confined snaps know whether the system is online
+// without probing the network themselves.
+type NetworkStatus struct {
+	conn *dbus.Conn
+
+	mu        sync.Mutex
+	state     string
+	checkedAt time.Time
+	// probing is closed once the probe in flight, if any, is done
+	probing chan struct{}
+}
+
+// Name returns the name of the interface this object implements
+func (s *NetworkStatus) Name() string {
+	return "io.snapcraft.NetworkStatus"
+}
+
+// BasePath returns the base path of the object
+func (s *NetworkStatus) BasePath() dbus.ObjectPath {
+	return "/io/snapcraft/NetworkStatus"
+}
+
+// IntrospectionData gives the XML formatted introspection description
+// of the DBus service.
+func (s *NetworkStatus) IntrospectionData() string {
+	return networkStatusIntrospectionXML
+}
+
+// GetConnectivity implements the 'GetConnectivity' method of the
+// 'io.snapcraft.NetworkStatus' DBus interface. It returns one of
+// "online", "offline" or "captive". While the network is being probed
+// callers get the previous result, if there is one, or wait for the
+// probe otherwise; there is only ever one probe in flight.
+//
+// Example usage: dbus-send --system --dest=io.snapcraft.NetworkStatus --type=method_call --print-reply /io/snapcraft/NetworkStatus io.snapcraft.NetworkStatus.GetConnectivity
+func (s *NetworkStatus) GetConnectivity() (string, *dbus.Error) {
+	s.mu.Lock()
+	if s.state != "" && (s.probing != nil || timeNow().Sub(s.checkedAt) < connectivityCacheTime) {
+		state := s.state
+		s.mu.Unlock()
+		return state, nil
+	}
+	if s.probing != nil {
+		probing := s.probing
+		s.mu.Unlock()
+		<-probing
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		return s.state, nil
+	}
+	probing := make(chan struct{})
+	s.probing = probing
+	s.mu.Unlock()
+
+	state := checkConnectivity()
+
+	s.mu.Lock()
+	s.state = state
+	s.checkedAt = timeNow()
+	s.probing = nil
+	s.mu.Unlock()
+	close(probing)
+
+	return state, nil
+}
@@ -0,0 +1,162 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2019 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package userd_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"time"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/userd"
+)
+
+type networkStatusSuite struct{}
+
+var _ = Suite(&networkStatusSuite{})
+
+func (s *networkStatusSuite) TestBasics(c *C) {
+	ns := &userd.NetworkStatus{}
+	c.Check(ns.Name(), Equals, "io.snapcraft.NetworkStatus")
+	c.Check(string(ns.BasePath()), Equals, "/io/snapcraft/NetworkStatus")
+	c.Check(ns.IntrospectionData(), Matches, `(?s).*<method name='GetConnectivity'>.*`)
+}
+
+func (s *networkStatusSuite) TestCheckConnectivity(c *C) {
+	status := http.StatusNoContent
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if status >= 300 && status < 400 {
+			w.Header().Set("Location", "http://portal.example.com/login")
+		}
+		w.WriteHeader(status)
+	}))
+	defer mockServer.Close()
+	restore := userd.MockConnectivityCheckURL(mockServer.URL)
+	defer restore()
+
+	c.Check(userd.CheckConnectivity(), Equals, userd.ConnectivityOnline)
+
+	// a captive portal redirecting to its login page
+	status = http.StatusFound
+	c.Check(userd.CheckConnectivity(), Equals, userd.ConnectivityCaptive)
+	status = http.StatusTemporaryRedirect
+	c.Check(userd.CheckConnectivity(), Equals, userd.ConnectivityCaptive)
+
+	// or serving its own page instead
+	status = http.StatusOK
+	c.Check(userd.CheckConnectivity(), Equals, userd.ConnectivityCaptive)
+
+	// other replies do not tell anything about the network
+	status = http.StatusServiceUnavailable
+	c.Check(userd.CheckConnectivity(), Equals, userd.ConnectivityOffline)
+	status = http.StatusNotFound
+	c.Check(userd.CheckConnectivity(), Equals, userd.ConnectivityOffline)
+
+	mockServer.Close()
+	c.Check(userd.CheckConnectivity(), Equals, userd.ConnectivityOffline)
+}
+
+func (s *networkStatusSuite) TestGetConnectivityCached(c *C) {
+	now := time.Date(2019, 5, 1, 12, 0, 0, 0, time.UTC)
+	restore := userd.MockTimeNow(func() time.Time { return now })
+	defer restore()
+
+	n := 0
+	state := userd.ConnectivityOffline
+	restore = userd.MockCheckConnectivity(func() string {
+		n++
+		return state
+	})
+	defer restore()
+
+	ns := &userd.NetworkStatus{}
+	res, err := ns.GetConnectivity()
+	c.Assert(err, IsNil)
+	c.Check(res, Equals, userd.ConnectivityOffline)
+	c.Check(n, Equals, 1)
+
+	// within the cache window the previous result is reused
+	state = userd.ConnectivityOnline
+	now = now.Add(10 * time.Second)
+	res, err = ns.GetConnectivity()
+	c.Assert(err, IsNil)
+	c.Check(res, Equals, userd.ConnectivityOffline)
+	c.Check(n, Equals, 1)
+
+	// after it the network is probed again
+	now = now.Add(30 * time.Second)
+	res, err = ns.GetConnectivity()
+	c.Assert(err, IsNil)
+	c.Check(res, Equals, userd.ConnectivityOnline)
+	c.Check(n, Equals, 2)
+}
+
+func (s *networkStatusSuite) TestGetConnectivityProbesOutsideLock(c *C) {
+	now := time.Date(2019, 5, 1, 12, 0, 0, 0, time.UTC)
+	restore := userd.MockTimeNow(func() time.Time { return now })
+	defer restore()
+
+	var n int32
+	probing := make(chan bool)
+	release := make(chan string)
+	restore = userd.MockCheckConnectivity(func() string {
+		atomic.AddInt32(&n, 1)
+		probing <- true
+		return <-release
+	})
+	defer restore()
+
+	ns := &userd.NetworkStatus{}
+	getConnectivity := func() <-chan string {
+		ch := make(chan string, 1)
+		go func() {
+			res, err := ns.GetConnectivity()
+			c.Check(err, IsNil)
+			ch <- res
+		}()
+		return ch
+	}
+
+	// without a previous result callers wait for the probe in flight
+	first := getConnectivity()
+	<-probing
+	second := getConnectivity()
+	release <- userd.ConnectivityOnline
+	c.Check(<-first, Equals, userd.ConnectivityOnline)
+	c.Check(<-second, Equals, userd.ConnectivityOnline)
+	c.Check(atomic.LoadInt32(&n), Equals, int32(1))
+
+	// otherwise they get the previous result right away
+	now = now.Add(time.Minute)
+	third := getConnectivity()
+	<-probing
+	res, err := ns.GetConnectivity()
+	c.Assert(err, IsNil)
+	c.Check(res, Equals, userd.ConnectivityOnline)
+	release <- userd.ConnectivityCaptive
+	c.Check(<-third, Equals, userd.ConnectivityCaptive)
+	c.Check(atomic.LoadInt32(&n), Equals, int32(2))
+
+	res, err = ns.GetConnectivity()
+	c.Assert(err, IsNil)
+	c.Check(res, Equals, userd.ConnectivityCaptive)
+}
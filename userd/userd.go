@@ -59,6 +59,24 @@ func dbusSessionBus() (*dbus.Conn, error) {
 	return conn, nil
 }
 
+func dbusSystemBus() (*dbus.Conn, error) {
+	// like for the session bus, use a private connection
+	conn, err := dbus.SystemBusPrivate()
+	if err != nil {
+		return nil, err
+	}
+	if err := conn.Auth(nil); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if err := conn.Hello(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return conn, nil
+}
+
+// Init connects to the session bus and exports the per-user services.
 func (ud *Userd) Init() error {
 	var err error
 
@@ -71,6 +89,26 @@ func (ud *Userd) Init() error {
 		&Launcher{ud.conn},
 		&Settings{ud.conn},
 	}
+	return ud.exportIfaces()
+}
+
+// InitSystem connects to the system bus and exports the services that
+// snapd provides system-wide, like the network status one.
+func (ud *Userd) InitSystem() error {
+	var err error
+
+	ud.conn, err = dbusSystemBus()
+	if err != nil {
+		return err
+	}
+
+	ud.dbusIfaces = []dbusInterface{
+		&NetworkStatus{conn: ud.conn},
+	}
+	return ud.exportIfaces()
+}
+
+func (ud *Userd) exportIfaces() error {
 	for _, iface := range ud.dbusIfaces {
 		reply, err := ud.conn.RequestName(iface.Name(), dbus.NameFlagDoNotQueue)
 		if err != nil {
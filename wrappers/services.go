@@ -25,6 +25,7 @@ import (
 	"math/rand"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"text/template"
 	"time"
@@ -34,6 +35,7 @@ import (
 	"github.com/snapcore/snapd/osutil"
 	"github.com/snapcore/snapd/osutil/sys"
 	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/strutil"
 	"github.com/snapcore/snapd/systemd"
 	"github.com/snapcore/snapd/timeout"
 	"github.com/snapcore/snapd/timeutil"
@@ -199,7 +201,8 @@ func StartServices(apps []*snap.AppInfo, inter interacter, tm timings.Measurer)
 }
 
 // AddSnapServices adds service units for the applications from the snap which are services.
-func AddSnapServices(s *snap.Info, inter interacter) (err error) {
+// The services named in disabledSvcs are not enabled.
+func AddSnapServices(s *snap.Info, disabledSvcs []string, inter interacter) (err error) {
 	if s.GetType() == snap.TypeSnapd {
 		return writeSnapdServicesOnCore(s, inter)
 	}
@@ -276,6 +279,10 @@ func AddSnapServices(s *snap.Info, inter interacter) (err error) {
 			continue
 		}
 
+		if strutil.ListContains(disabledSvcs, app.Name) {
+			continue
+		}
+
 		svcName := app.ServiceName()
 		if err := sysd.Enable(svcName); err != nil {
 			return err
@@ -292,6 +299,53 @@ func AddSnapServices(s *snap.Info, inter interacter) (err error) {
 	return nil
 }
 
+// QueryDisabledServices returns the names of the services of the snap
+// that are disabled (or masked). Socket and timer activated services
+// count as disabled when none of their sockets or their timer is
+// enabled.
+func QueryDisabledServices(s *snap.Info, inter interacter) ([]string, error) {
+	sysd := systemd.New(dirs.GlobalRootDir, systemd.SystemMode, inter)
+
+	var names []string
+	for _, app := range s.Services() {
+		names = append(names, app.Name)
+	}
+	sort.Strings(names)
+
+	var disabled []string
+	for _, name := range names {
+		app := s.Apps[name]
+		if !osutil.FileExists(app.ServiceFile()) {
+			continue
+		}
+		var units []string
+		for _, socket := range app.Sockets {
+			units = append(units, filepath.Base(socket.File()))
+		}
+		if app.Timer != nil {
+			units = append(units, filepath.Base(app.Timer.File()))
+		}
+		if len(units) == 0 {
+			units = append(units, app.ServiceName())
+		}
+		isEnabled := false
+		for _, unit := range units {
+			enabled, err := sysd.IsEnabled(unit)
+			if err != nil {
+				return nil, err
+			}
+			if enabled {
+				isEnabled = true
+				break
+			}
+		}
+		if !isEnabled {
+			disabled = append(disabled, app.Name)
+		}
+	}
+	return disabled, nil
+}
+
 // StopServices stops service units for the applications from the snap which are services.
 func StopServices(apps []*snap.AppInfo, reason snap.ServiceStopReason, inter interacter, tm timings.Measurer) error {
 	sysd := systemd.New(dirs.GlobalRootDir, systemd.SystemMode, inter)